@@ -0,0 +1,228 @@
+// Package accounting turns paid sales into double-entry journal entries and
+// writes them in formats accounting software can import: CSV, ledger-cli
+// journals and QuickBooks IIF. Amounts are whole satoshis throughout.
+package accounting
+
+import (
+	"fmt"
+	"time"
+
+	"tickets-by-uma/models"
+)
+
+// Accounts used by the journal entries. Organizer, affiliate, refund and
+// jurisdiction accounts are sub-accounts named after the organizer ID,
+// affiliate ID, payment ID and tax jurisdiction.
+const (
+	AccountWallet               = "Assets:Lightning Wallet"
+	AccountTicketSales          = "Income:Ticket Sales"
+	AccountAddOnSales           = "Income:Add-on Sales"
+	AccountDiscounts            = "Income:Discounts"
+	AccountPlatformFees         = "Income:Platform Fees"
+	AccountAffiliateCommissions = "Expenses:Affiliate Commissions"
+	AccountOrganizerPayable     = "Liabilities:Organizer Payable"
+	AccountAffiliatePayable     = "Liabilities:Affiliate Payable"
+	AccountRefundsPayable       = "Liabilities:Refunds Payable"
+	AccountSalesTax             = "Liabilities:Sales Tax"
+)
+
+// OrganizerPayable is the account of what the platform owes an organizer
+func OrganizerPayable(organizerID int) string {
+	return fmt.Sprintf("%s:%d", AccountOrganizerPayable, organizerID)
+}
+
+// AffiliatePayable is the account of the commission owed to an affiliate
+func AffiliatePayable(affiliateID int) string {
+	return fmt.Sprintf("%s:%d", AccountAffiliatePayable, affiliateID)
+}
+
+// RefundPayable is the account of a refund owed to the buyer of a payment
+func RefundPayable(paymentID int) string {
+	return fmt.Sprintf("%s:%d", AccountRefundsPayable, paymentID)
+}
+
+// Posting is one line of a journal entry; exactly one of DebitSats and
+// CreditSats is set
+type Posting struct {
+	Account    string
+	DebitSats  int64
+	CreditSats int64
+}
+
+// JournalEntry is a balanced set of postings: debits equal credits
+type JournalEntry struct {
+	Date        time.Time
+	Reference   string
+	Description string
+	Postings    []Posting
+}
+
+// SaleEntry books a paid sale. The payment is debited to the wallet; ticket
+// and add-on revenue is credited to the platform's income, or owed to the
+// organizer when the event has one. Fees are platform income and tax is a
+// liability to its jurisdiction. Tax included in the price is split out of
+// the sales lines in proportion to their amounts. An affiliate's commission
+// on a referred sale is owed to them out of the organizer's share, or is a
+// platform expense for events without an organizer.
+func SaleEntry(sale models.Sale) JournalEntry {
+	entry := JournalEntry{
+		Date:        sale.CreatedAt.UTC(),
+		Reference:   fmt.Sprintf("payment %d", sale.ID),
+		Description: sale.EventTitle,
+	}
+	if sale.PaidAt != nil {
+		entry.Date = sale.PaidAt.UTC()
+	}
+	if sale.ReceiptNumber != nil {
+		entry.Reference = models.Receipt{Number: *sale.ReceiptNumber}.FormattedNumber()
+	}
+
+	items := saleItems(sale)
+
+	salesAccount := func(income string) string {
+		if sale.OrganizerID != nil {
+			return OrganizerPayable(*sale.OrganizerID)
+		}
+		return income
+	}
+	taxAccount := AccountSalesTax
+	if sale.TaxJurisdiction != "" {
+		taxAccount += ":" + sale.TaxJurisdiction
+	}
+
+	b := newBuilder()
+	b.debit(AccountWallet, sale.Amount)
+	var sales []int // indexes of sales postings, for inclusive tax
+	for _, item := range items {
+		switch item.Kind {
+		case models.LineItemTicket:
+			sales = append(sales, b.credit(salesAccount(AccountTicketSales), item.AmountSats))
+		case models.LineItemAddOn:
+			sales = append(sales, b.credit(salesAccount(AccountAddOnSales), item.AmountSats))
+		case models.LineItemDiscount:
+			b.debit(salesAccount(AccountDiscounts), -item.AmountSats)
+		case models.LineItemFee:
+			b.credit(AccountPlatformFees, item.AmountSats)
+		case models.LineItemTax:
+			b.credit(taxAccount, item.AmountSats)
+		default:
+			b.credit(salesAccount(AccountTicketSales), item.AmountSats)
+		}
+	}
+	if sale.TaxInclusive && sale.TaxSats > 0 {
+		b.splitOut(sales, taxAccount, sale.TaxSats)
+	}
+	if commission := Commission(sale); commission > 0 {
+		b.debit(salesAccount(AccountAffiliateCommissions), commission)
+		b.credit(AffiliatePayable(*sale.AffiliateID), commission)
+	}
+
+	entry.Postings = b.postings()
+	return entry
+}
+
+// Commission is what the affiliate who referred a sale earns on it: their
+// rate, rounded down, of the tickets and add-ons sold net of discounts,
+// leaving out tax and the platform fee. It is 0 for sales without one.
+func Commission(sale models.Sale) int64 {
+	if sale.AffiliateID == nil || sale.CommissionBasisPoints <= 0 {
+		return 0
+	}
+	var net int64
+	for _, item := range saleItems(sale) {
+		if item.Kind != models.LineItemFee && item.Kind != models.LineItemTax {
+			net += item.AmountSats
+		}
+	}
+	if sale.TaxInclusive {
+		net -= sale.TaxSats
+	}
+	if net <= 0 {
+		return 0
+	}
+	return net * sale.CommissionBasisPoints / 10_000
+}
+
+// saleItems returns what a sale's payment covered
+func saleItems(sale models.Sale) []models.PaymentLineItem {
+	if len(sale.LineItems) == 0 {
+		// Payments made before line items were recorded were for the ticket alone
+		return []models.PaymentLineItem{{Kind: models.LineItemTicket, AmountSats: sale.Amount}}
+	}
+	return sale.LineItems
+}
+
+// builder collects postings, merging those to the same account and side
+type builder struct {
+	lines []Posting
+	index map[string]int
+}
+
+func newBuilder() *builder {
+	return &builder{index: make(map[string]int)}
+}
+
+func (b *builder) debit(account string, sats int64) int {
+	return b.add(account, sats, true)
+}
+
+func (b *builder) credit(account string, sats int64) int {
+	return b.add(account, sats, false)
+}
+
+func (b *builder) add(account string, sats int64, debit bool) int {
+	key := fmt.Sprintf("%t %s", debit, account)
+	i, ok := b.index[key]
+	if !ok {
+		i = len(b.lines)
+		b.index[key] = i
+		b.lines = append(b.lines, Posting{Account: account})
+	}
+	if debit {
+		b.lines[i].DebitSats += sats
+	} else {
+		b.lines[i].CreditSats += sats
+	}
+	return i
+}
+
+// splitOut moves sats from the credits at indexes to account, in proportion
+// to their amounts, with the rounding remainder taken from the last one
+func (b *builder) splitOut(indexes []int, account string, sats int64) {
+	seen := make(map[int]bool)
+	var unique []int
+	var total int64
+	for _, i := range indexes {
+		if !seen[i] {
+			seen[i] = true
+			unique = append(unique, i)
+			total += b.lines[i].CreditSats
+		}
+	}
+	if total <= 0 {
+		b.credit(account, sats)
+		return
+	}
+
+	remaining := sats
+	for n, i := range unique {
+		share := sats * b.lines[i].CreditSats / total
+		if n == len(unique)-1 {
+			share = remaining
+		}
+		b.lines[i].CreditSats -= share
+		remaining -= share
+	}
+	b.credit(account, sats)
+}
+
+// postings returns the collected postings without any that came to zero
+func (b *builder) postings() []Posting {
+	out := make([]Posting, 0, len(b.lines))
+	for _, p := range b.lines {
+		if p.DebitSats != 0 || p.CreditSats != 0 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
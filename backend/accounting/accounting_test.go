@@ -0,0 +1,212 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/models"
+)
+
+var paidAt = time.Date(2026, 10, 1, 18, 30, 0, 0, time.UTC)
+
+func sale(id int, amount int64, items ...models.PaymentLineItem) models.Sale {
+	return models.Sale{
+		Payment:    models.Payment{ID: id, Amount: amount, Status: "paid", PaidAt: &paidAt},
+		EventID:    1,
+		EventTitle: "Bitcoin Meetup",
+		LineItems:  items,
+	}
+}
+
+func item(kind string, sats int64) models.PaymentLineItem {
+	return models.PaymentLineItem{Kind: kind, Quantity: 1, UnitAmountSats: sats, AmountSats: sats}
+}
+
+func TestSaleEntry(t *testing.T) {
+	organizer := 7
+	receipt := int64(3)
+
+	exclusive := sale(1, 1260,
+		item(models.LineItemTicket, 1000),
+		item(models.LineItemAddOn, 200),
+		item(models.LineItemDiscount, -100),
+		item(models.LineItemTax, 110),
+		item(models.LineItemFee, 50),
+	)
+	exclusive.OrganizerID = &organizer
+	exclusive.TaxSats = 110
+	exclusive.TaxJurisdiction = "FR"
+
+	inclusive := sale(2, 1200, item(models.LineItemTicket, 1000), item(models.LineItemAddOn, 200))
+	inclusive.ReceiptNumber = &receipt
+	inclusive.TaxSats = 200
+	inclusive.TaxInclusive = true
+	inclusive.TaxJurisdiction = "DE"
+
+	// Referred sales: commission on the tickets and add-ons, net of
+	// discounts and tax
+	affiliate := 4
+	referred := exclusive
+	referred.AffiliateID = &affiliate
+	referred.CommissionBasisPoints = 1000
+	referredInclusive := inclusive
+	referredInclusive.AffiliateID = &affiliate
+	referredInclusive.CommissionBasisPoints = 500
+
+	tests := []struct {
+		name      string
+		sale      models.Sale
+		reference string
+		want      []Posting
+	}{
+		{
+			name:      "organizer event with tax, fee and discount",
+			sale:      exclusive,
+			reference: "payment 1",
+			want: []Posting{
+				{Account: AccountWallet, DebitSats: 1260},
+				{Account: "Liabilities:Organizer Payable:7", CreditSats: 1200},
+				{Account: "Liabilities:Organizer Payable:7", DebitSats: 100},
+				{Account: "Liabilities:Sales Tax:FR", CreditSats: 110},
+				{Account: AccountPlatformFees, CreditSats: 50},
+			},
+		},
+		{
+			name:      "tax included in the price",
+			sale:      inclusive,
+			reference: "R-000003",
+			want: []Posting{
+				{Account: AccountWallet, DebitSats: 1200},
+				{Account: AccountTicketSales, CreditSats: 834},
+				{Account: AccountAddOnSales, CreditSats: 166},
+				{Account: "Liabilities:Sales Tax:DE", CreditSats: 200},
+			},
+		},
+		{
+			name:      "referred organizer event",
+			sale:      referred,
+			reference: "payment 1",
+			want: []Posting{
+				{Account: AccountWallet, DebitSats: 1260},
+				{Account: "Liabilities:Organizer Payable:7", CreditSats: 1200},
+				{Account: "Liabilities:Organizer Payable:7", DebitSats: 210},
+				{Account: "Liabilities:Sales Tax:FR", CreditSats: 110},
+				{Account: AccountPlatformFees, CreditSats: 50},
+				{Account: "Liabilities:Affiliate Payable:4", CreditSats: 110},
+			},
+		},
+		{
+			name:      "referred platform event with tax included",
+			sale:      referredInclusive,
+			reference: "R-000003",
+			want: []Posting{
+				{Account: AccountWallet, DebitSats: 1200},
+				{Account: AccountTicketSales, CreditSats: 834},
+				{Account: AccountAddOnSales, CreditSats: 166},
+				{Account: "Liabilities:Sales Tax:DE", CreditSats: 200},
+				{Account: AccountAffiliateCommissions, DebitSats: 50},
+				{Account: "Liabilities:Affiliate Payable:4", CreditSats: 50},
+			},
+		},
+		{
+			name:      "payment without line items",
+			sale:      sale(3, 500),
+			reference: "payment 3",
+			want: []Posting{
+				{Account: AccountWallet, DebitSats: 500},
+				{Account: AccountTicketSales, CreditSats: 500},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := SaleEntry(tt.sale)
+			if !entry.Date.Equal(paidAt) || entry.Reference != tt.reference || entry.Description != "Bitcoin Meetup" {
+				t.Errorf("Unexpected entry header: %v %q %q", entry.Date, entry.Reference, entry.Description)
+			}
+			if !reflect.DeepEqual(entry.Postings, tt.want) {
+				t.Errorf("Expected postings %+v, got %+v", tt.want, entry.Postings)
+			}
+
+			var debits, credits int64
+			for _, p := range entry.Postings {
+				debits += p.DebitSats
+				credits += p.CreditSats
+			}
+			if debits != credits {
+				t.Errorf("Entry does not balance: debits %d, credits %d", debits, credits)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := sale(1, 1000, item(models.LineItemTicket, 950), item(models.LineItemFee, 50))
+	s.EventTitle = "=HYPERLINK(\"x\")\tNight"
+	entries := []JournalEntry{SaleEntry(s)}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(&buf, FormatCSV, entries); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		want := [][]string{
+			{"date", "entry", "reference", "description", "account", "debit_sats", "credit_sats"},
+			{"2026-10-01", "1", "payment 1", "'=HYPERLINK(\"x\")\tNight", AccountWallet, "1000", ""},
+			{"2026-10-01", "1", "payment 1", "'=HYPERLINK(\"x\")\tNight", AccountTicketSales, "", "950"},
+			{"2026-10-01", "1", "payment 1", "'=HYPERLINK(\"x\")\tNight", AccountPlatformFees, "", "50"},
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("Expected rows %q, got %q", want, rows)
+		}
+	})
+
+	t.Run("ledger", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(&buf, FormatLedger, entries); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, want := range []string{
+			"2026/10/01 (payment 1) =HYPERLINK(\"x\") Night\n",
+			"    Assets:Lightning Wallet                   1000 SAT\n",
+			"    Income:Ticket Sales                       -950 SAT\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in ledger output:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("quickbooks", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(&buf, FormatQuickBooks, entries); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != 7 {
+			t.Fatalf("Expected 3 header, 3 posting and 1 end lines, got %q", lines)
+		}
+		for i, prefix := range []string{"!TRNS\t", "!SPL\t", "!ENDTRNS", "TRNS\t", "SPL\t", "SPL\t", "ENDTRNS"} {
+			if !strings.HasPrefix(lines[i], prefix) {
+				t.Errorf("Line %d: expected prefix %q, got %q", i, prefix, lines[i])
+			}
+		}
+		if got := strings.Split(lines[4], "\t"); len(got) != 7 || got[2] != "10/01/2026" || got[4] != "-950" {
+			t.Errorf("Unexpected SPL line %q", lines[4])
+		}
+	})
+
+	if err := Write(&bytes.Buffer{}, "xlsx", entries); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,139 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Export formats accepted by Write
+const (
+	FormatCSV        = "csv"
+	FormatLedger     = "ledger"
+	FormatQuickBooks = "quickbooks"
+)
+
+// ContentType returns the MIME type and file extension of a format, and
+// false for an unknown format
+func ContentType(format string) (mimeType, ext string, ok bool) {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8", "csv", true
+	case FormatLedger:
+		return "text/plain; charset=utf-8", "ledger", true
+	case FormatQuickBooks:
+		return "application/octet-stream", "iif", true
+	}
+	return "", "", false
+}
+
+// Write writes entries to w in the given format
+func Write(w io.Writer, format string, entries []JournalEntry) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, entries)
+	case FormatLedger:
+		return WriteLedger(w, entries)
+	case FormatQuickBooks:
+		return WriteIIF(w, entries)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteCSV writes one row per posting, numbering rows by entry so a
+// spreadsheet can group them
+func WriteCSV(w io.Writer, entries []JournalEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "entry", "reference", "description", "account", "debit_sats", "credit_sats"}); err != nil {
+		return err
+	}
+	for n, entry := range entries {
+		for _, p := range entry.Postings {
+			row := []string{
+				entry.Date.Format("2006-01-02"),
+				strconv.Itoa(n + 1),
+				cell(entry.Reference),
+				cell(entry.Description),
+				cell(p.Account),
+				amount(p.DebitSats),
+				amount(p.CreditSats),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteLedger writes a ledger-cli journal in the SAT commodity, debits
+// positive and credits negative
+func WriteLedger(w io.Writer, entries []JournalEntry) error {
+	if _, err := fmt.Fprint(w, "commodity SAT\n    format 1 SAT\n"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "\n%s (%s) %s\n", entry.Date.Format("2006/01/02"), oneLine(entry.Reference), oneLine(entry.Description)); err != nil {
+			return err
+		}
+		for _, p := range entry.Postings {
+			if _, err := fmt.Fprintf(w, "    %-40s  %d SAT\n", oneLine(p.Account), p.DebitSats-p.CreditSats); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteIIF writes QuickBooks IIF general journal transactions. The first
+// posting of each entry is its TRNS line and the rest are SPL lines; debits
+// are positive and credits negative.
+func WriteIIF(w io.Writer, entries []JournalEntry) error {
+	header := "!TRNS\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tDOCNUM\tMEMO\n" +
+		"!SPL\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tDOCNUM\tMEMO\n" +
+		"!ENDTRNS\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		for i, p := range entry.Postings {
+			kind := "SPL"
+			if i == 0 {
+				kind = "TRNS"
+			}
+			if _, err := fmt.Fprintf(w, "%s\tGENERAL JOURNAL\t%s\t%s\t%d\t%s\t%s\n",
+				kind, entry.Date.Format("01/02/2006"), oneLine(p.Account), p.DebitSats-p.CreditSats,
+				oneLine(entry.Reference), oneLine(entry.Description)); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "ENDTRNS\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func amount(sats int64) string {
+	if sats == 0 {
+		return ""
+	}
+	return strconv.FormatInt(sats, 10)
+}
+
+// oneLine replaces tabs and line breaks, which separate fields and records
+// in the text formats
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cell stops spreadsheets from evaluating text that looks like a formula
+func cell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
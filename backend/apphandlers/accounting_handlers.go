@@ -0,0 +1,69 @@
+package apphandlers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/repositories"
+)
+
+type AccountingHandlers struct {
+	receiptRepo repositories.ReceiptRepository
+	logger      *slog.Logger
+}
+
+func NewAccountingHandlers(receiptRepo repositories.ReceiptRepository, logger *slog.Logger) *AccountingHandlers {
+	return &AccountingHandlers{
+		receiptRepo: receiptRepo,
+		logger:      logger,
+	}
+}
+
+// HandleExport downloads the journal entries for payments paid between from
+// (inclusive) and to (exclusive), both RFC 3339, as csv (the default), a
+// ledger journal or a QuickBooks IIF file (admin only)
+func (h *AccountingHandlers) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = accounting.FormatCSV
+	}
+	contentType, ext, ok := accounting.ContentType(format)
+	if !ok {
+		middleware.WriteError(w, http.StatusBadRequest, "format must be csv, ledger or quickbooks")
+		return
+	}
+
+	from, to, ok := reportPeriod(w, r, true)
+	if !ok {
+		return
+	}
+
+	sales, err := h.receiptRepo.GetSales(*from, *to)
+	if err != nil {
+		h.logger.Error("Failed to get sales for accounting export", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export accounting entries")
+		return
+	}
+
+	entries := make([]accounting.JournalEntry, 0, len(sales))
+	for _, sale := range sales {
+		entries = append(entries, accounting.SaleEntry(sale))
+	}
+
+	var buf bytes.Buffer
+	if err := accounting.Write(&buf, format, entries); err != nil {
+		h.logger.Error("Failed to write accounting export", "format", format, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export accounting entries")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="accounting-%s-%s.%s"`,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), ext))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
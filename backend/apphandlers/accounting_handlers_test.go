@@ -0,0 +1,83 @@
+package apphandlers
+
+import (
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestHandleAccountingExport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewAccountingHandlers(store.Receipts(), logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "ACCT-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-acct-1", Amount: 1050, Status: "pending"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Receipts().CreateLineItems([]models.PaymentLineItem{
+		{PaymentID: payment.ID, Kind: models.LineItemTicket, Description: "Ticket", Quantity: 1, UnitAmountSats: 1000, AmountSats: 1000},
+		{PaymentID: payment.ID, Kind: models.LineItemFee, Description: "Service fee", Quantity: 1, UnitAmountSats: 50, AmountSats: 50},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.HandleExport(rec, httptest.NewRequest("GET", "/api/admin/accounting/export"+query, nil))
+		return rec
+	}
+
+	const period = "from=2026-10-01T00:00:00Z&to=2026-11-01T00:00:00Z"
+	for _, query := range []string{"", "?from=2026-10-01T00:00:00Z", "?format=xlsx&" + period} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, rec.Code)
+		}
+	}
+
+	rec := get("?" + period)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") ||
+		rec.Header().Get("Content-Disposition") != `attachment; filename="accounting-2026-10-01-2026-11-01.csv"` {
+		t.Fatalf("Expected a CSV download, got %d %v", rec.Code, rec.Header())
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[1][4] != "Assets:Lightning Wallet" || rows[1][5] != "1050" ||
+		rows[2][4] != "Income:Ticket Sales" || rows[3][4] != "Income:Platform Fees" || rows[3][6] != "50" {
+		t.Errorf("Unexpected CSV rows %q", rows)
+	}
+
+	rec = get("?format=ledger&" + period)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "2026/10/16 (payment 1) Meetup\n") {
+		t.Errorf("Unexpected ledger export %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = get("?format=quickbooks&from=2026-11-01T00:00:00Z&to=2026-12-01T00:00:00Z")
+	if rec.Code != http.StatusOK || !strings.HasSuffix(rec.Header().Get("Content-Disposition"), `.iif"`) ||
+		strings.Contains(rec.Body.String(), "TRNS\tGENERAL JOURNAL") {
+		t.Errorf("Expected an empty IIF export outside the period, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,229 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type AddOnHandlers struct {
+	addOnRepo repositories.AddOnRepository
+	eventRepo repositories.EventRepository
+	logger    *slog.Logger
+}
+
+func NewAddOnHandlers(addOnRepo repositories.AddOnRepository, eventRepo repositories.EventRepository, logger *slog.Logger) *AddOnHandlers {
+	return &AddOnHandlers{
+		addOnRepo: addOnRepo,
+		eventRepo: eventRepo,
+		logger:    logger,
+	}
+}
+
+// HandleListAddOns lists the add-ons on sale for an event
+func (h *AddOnHandlers) HandleListAddOns(w http.ResponseWriter, r *http.Request) {
+	h.listAddOns(w, r, true)
+}
+
+// HandleListAllAddOns lists every add-on of an event, including inactive
+// ones (admin only)
+func (h *AddOnHandlers) HandleListAllAddOns(w http.ResponseWriter, r *http.Request) {
+	h.listAddOns(w, r, false)
+}
+
+func (h *AddOnHandlers) listAddOns(w http.ResponseWriter, r *http.Request, activeOnly bool) {
+	eventID, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	addOns, err := h.addOnRepo.GetByEventID(eventID, activeOnly)
+	if err != nil {
+		h.logger.Error("Failed to fetch add-ons", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch add-ons")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Add-ons retrieved successfully",
+		Data:    addOns,
+	})
+}
+
+// HandleCreateAddOn adds an add-on to an event (admin only)
+func (h *AddOnHandlers) HandleCreateAddOn(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAddOnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	addOn := &models.AddOn{
+		EventID:         eventID,
+		Name:            strings.TrimSpace(req.Name),
+		Description:     req.Description,
+		PriceSats:       req.PriceSats,
+		IsActive:        req.IsActive == nil || *req.IsActive,
+		FlexCutoffHours: req.FlexCutoffHours,
+	}
+	if err := validateAddOn(addOn); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.addOnRepo.Create(addOn); err != nil {
+		h.logger.Error("Failed to create add-on", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create add-on")
+		return
+	}
+
+	h.logger.Info("Add-on created", "addon_id", addOn.ID, "event_id", eventID)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Add-on created successfully",
+		Data:    addOn,
+	})
+}
+
+// HandleUpdateAddOn changes an add-on. Tickets already sold keep the name
+// and price they were bought at (admin only)
+func (h *AddOnHandlers) HandleUpdateAddOn(w http.ResponseWriter, r *http.Request) {
+	addOn, ok := h.addOn(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAddOnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name != nil {
+		addOn.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		addOn.Description = *req.Description
+	}
+	if req.PriceSats != nil {
+		addOn.PriceSats = *req.PriceSats
+	}
+	if req.IsActive != nil {
+		addOn.IsActive = *req.IsActive
+	}
+	if req.FlexCutoffHours != nil {
+		if addOn.FlexCutoffHours == nil {
+			middleware.WriteError(w, http.StatusBadRequest, "Only flex add-ons have a flex_cutoff_hours")
+			return
+		}
+		addOn.FlexCutoffHours = req.FlexCutoffHours
+	}
+	if err := validateAddOn(addOn); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.addOnRepo.Update(addOn); err != nil {
+		h.logger.Error("Failed to update add-on", "addon_id", addOn.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update add-on")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Add-on updated successfully",
+		Data:    addOn,
+	})
+}
+
+// HandleDeleteAddOn removes an add-on from the catalog; line items already
+// sold are kept (admin only)
+func (h *AddOnHandlers) HandleDeleteAddOn(w http.ResponseWriter, r *http.Request) {
+	addOn, ok := h.addOn(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.addOnRepo.Delete(addOn.ID); err != nil {
+		h.logger.Error("Failed to delete add-on", "addon_id", addOn.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete add-on")
+		return
+	}
+
+	h.logger.Info("Add-on deleted", "addon_id", addOn.ID, "event_id", addOn.EventID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Add-on deleted successfully",
+	})
+}
+
+// event checks that the event named in the route exists, writing the error
+// response itself when it does not.
+func (h *AddOnHandlers) event(w http.ResponseWriter, r *http.Request) (int, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return 0, false
+	}
+
+	_, err = h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return 0, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return 0, false
+	}
+	return eventID, true
+}
+
+// addOn loads the add-on named in the route, writing the error response
+// itself when it is missing.
+func (h *AddOnHandlers) addOn(w http.ResponseWriter, r *http.Request) (*models.AddOn, bool) {
+	addOnID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid add-on ID")
+		return nil, false
+	}
+
+	addOn, err := h.addOnRepo.GetByID(addOnID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Add-on not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch add-on", "addon_id", addOnID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch add-on")
+		return nil, false
+	}
+	return addOn, true
+}
+
+func validateAddOn(addOn *models.AddOn) error {
+	if addOn.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if addOn.PriceSats < 0 {
+		return fmt.Errorf("price cannot be negative")
+	}
+	if addOn.FlexCutoffHours != nil && *addOn.FlexCutoffHours < 0 {
+		return fmt.Errorf("flex_cutoff_hours cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestAddOnCheckout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	addOns := NewAddOnHandlers(store.AddOns(), store.Events(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events/{id:[0-9]+}/addons", addOns.HandleListAddOns).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/addons", addOns.HandleCreateAddOn).Methods("POST")
+	router.HandleFunc("/api/admin/addons/{id:[0-9]+}", addOns.HandleUpdateAddOn).Methods("PUT")
+	router.HandleFunc("/api/admin/addons/{id:[0-9]+}", addOns.HandleDeleteAddOn).Methods("DELETE")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/status", tickets.HandleTicketStatus).Methods("GET")
+
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 10000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	eventPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/addons"
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	create := func(req models.CreateAddOnRequest) models.AddOn {
+		t.Helper()
+		status, data := do("POST", eventPath, req)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 creating add-on, got %d", status)
+		}
+		var addOn models.AddOn
+		json.Unmarshal(data, &addOn)
+		return addOn
+	}
+
+	if status, _ := do("POST", eventPath, models.CreateAddOnRequest{Name: " ", PriceSats: 100}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for add-on without a name, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/events/999/addons", models.CreateAddOnRequest{Name: "Poster"}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown event, got %d", status)
+	}
+
+	shirt := create(models.CreateAddOnRequest{Name: "T-shirt", PriceSats: 20000})
+	poster := create(models.CreateAddOnRequest{Name: "Poster", PriceSats: 5000})
+
+	// Deactivated add-ons are hidden and cannot be bought
+	inactive := false
+	if status, _ := do("PUT", "/api/admin/addons/"+strconv.Itoa(poster.ID), models.UpdateAddOnRequest{IsActive: &inactive}); status != http.StatusOK {
+		t.Fatalf("Expected 200 updating add-on, got %d", status)
+	}
+	status, data := do("GET", "/api/events/"+strconv.Itoa(event.ID)+"/addons", nil)
+	var listed []models.AddOn
+	json.Unmarshal(data, &listed)
+	if status != http.StatusOK || len(listed) != 1 || listed[0].ID != shirt.ID {
+		t.Fatalf("Expected only the active add-on, got %d %s", status, data)
+	}
+
+	purchase := func(userID int, selections ...models.AddOnSelection) (int, json.RawMessage) {
+		return do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com", AddOns: selections,
+		})
+	}
+
+	for name, selections := range map[string][]models.AddOnSelection{
+		"inactive add-on":  {{AddOnID: poster.ID, Quantity: 1}},
+		"unknown add-on":   {{AddOnID: 999, Quantity: 1}},
+		"zero quantity":    {{AddOnID: shirt.ID, Quantity: 0}},
+		"duplicate add-on": {{AddOnID: shirt.ID, Quantity: 1}, {AddOnID: shirt.ID, Quantity: 1}},
+	} {
+		if status, _ := purchase(1, selections...); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	// Add-ons roll into the ticket's invoice
+	status, data = purchase(1, models.AddOnSelection{AddOnID: shirt.ID, Quantity: 2})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", status, data)
+	}
+	var bought struct {
+		Ticket     struct{ ID int }     `json:"ticket"`
+		AmountSats int64                `json:"amount_sats"`
+		AddOns     []models.TicketAddOn `json:"addons"`
+	}
+	json.Unmarshal(data, &bought)
+	if bought.AmountSats != 50000 || len(bought.AddOns) != 1 || bought.AddOns[0].TotalSats() != 40000 {
+		t.Fatalf("Expected ticket plus two shirts, got %s", data)
+	}
+	payment, err := store.Payments().GetByTicketID(bought.Ticket.ID)
+	if err != nil || payment.Amount != 50000 {
+		t.Fatalf("Expected a single 50000 sat payment, got %+v (%v)", payment, err)
+	}
+	items, err := store.Receipts().GetLineItems(payment.ID)
+	if err != nil || len(items) != 2 || items[0].Kind != models.LineItemTicket || items[0].AmountSats != 10000 ||
+		items[1].Kind != models.LineItemAddOn || items[1].AmountSats != 40000 {
+		t.Errorf("Expected ticket and add-on line items on the payment, got %+v (%v)", items, err)
+	}
+
+	// The ticket lists its line items, even after the catalog changes
+	if status, _ := do("DELETE", "/api/admin/addons/"+strconv.Itoa(shirt.ID), nil); status != http.StatusOK {
+		t.Fatalf("Expected 200 deleting add-on, got %d", status)
+	}
+	status, data = do("GET", "/api/tickets/"+strconv.Itoa(bought.Ticket.ID)+"/status", nil)
+	var ticketStatus struct {
+		AddOns []models.TicketAddOn `json:"addons"`
+	}
+	json.Unmarshal(data, &ticketStatus)
+	if status != http.StatusOK || len(ticketStatus.AddOns) != 1 || ticketStatus.AddOns[0].Name != "T-shirt" {
+		t.Errorf("Expected ticket status to list the T-shirt, got %d %s", status, data)
+	}
+}
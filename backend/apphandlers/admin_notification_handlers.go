@@ -0,0 +1,207 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// notificationStreamBatch is how many notifications a stream sends per read
+const notificationStreamBatch = 100
+
+type AdminNotificationHandlers struct {
+	notifications *services.AdminNotificationService
+	logger        *slog.Logger
+}
+
+func NewAdminNotificationHandlers(notifications *services.AdminNotificationService, logger *slog.Logger) *AdminNotificationHandlers {
+	return &AdminNotificationHandlers{
+		notifications: notifications,
+		logger:        logger,
+	}
+}
+
+// HandleListNotifications lists the admin notification feed newest first,
+// only unread notifications with ?unread=true, with the number unread
+// (admin only)
+func (h *AdminNotificationHandlers) HandleListNotifications(w http.ResponseWriter, r *http.Request) {
+	unreadOnly := false
+	if raw := r.URL.Query().Get("unread"); raw != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(raw); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, "unread must be true or false")
+			return
+		}
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	feed, err := h.notifications.Feed(unreadOnly, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch admin notifications", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notifications retrieved successfully",
+		Data:    feed,
+	})
+}
+
+// HandleMarkRead marks a notification read for every admin (admin only)
+func (h *AdminNotificationHandlers) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+	admin := middleware.GetUserFromContext(r.Context())
+
+	notification, err := h.notifications.MarkRead(id, admin.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to mark admin notification read", "notification_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to mark notification read")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification marked read",
+		Data:    notification,
+	})
+}
+
+// HandleMarkAllRead marks every unread notification read (admin only)
+func (h *AdminNotificationHandlers) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	admin := middleware.GetUserFromContext(r.Context())
+
+	marked, err := h.notifications.MarkAllRead(admin.ID)
+	if err != nil {
+		h.logger.Error("Failed to mark admin notifications read", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to mark notifications read")
+		return
+	}
+	h.logger.Info("Admin notifications marked read", "admin_id", admin.ID, "marked", marked)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notifications marked read",
+		Data:    map[string]int{"marked": marked},
+	})
+}
+
+// HandleStreamNotifications streams new admin notifications as server-sent
+// events, a "notification" event each with its ID as the event ID, and a
+// comment every checkInHeartbeat while there are none. A reconnecting
+// client resumes after its Last-Event-ID, or ?after= a notification ID;
+// otherwise the stream starts with the next notification (admin only).
+func (h *AdminNotificationHandlers) HandleStreamNotifications(w http.ResponseWriter, r *http.Request) {
+	after := r.Header.Get("Last-Event-ID")
+	if after == "" {
+		after = r.URL.Query().Get("after")
+	}
+	var cursor int
+	if after != "" {
+		var err error
+		if cursor, err = strconv.Atoi(after); err != nil || cursor < 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid notification ID to resume after")
+			return
+		}
+	} else {
+		latest, err := h.notifications.LatestID()
+		if err != nil {
+			h.logger.Error("Failed to fetch admin notifications", "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+			return
+		}
+		cursor = latest
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(message string) bool {
+		// The server's write timeout is shorter than a stream's life
+		_ = rc.SetWriteDeadline(time.Now().Add(checkInHeartbeat + streamWriteMargin))
+		if _, err := fmt.Fprint(w, message); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	heartbeat := time.NewTicker(checkInHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		changed, stop := h.notifications.Subscribe()
+		notifications, err := h.notifications.After(cursor, notificationStreamBatch)
+		if err != nil {
+			stop()
+			h.logger.Error("Failed to fetch admin notifications", "error", err)
+			send("event: error\ndata: {\"error\":\"Failed to fetch notifications\"}\n\n")
+			return
+		}
+		for _, notification := range notifications {
+			data, _ := json.Marshal(notification)
+			if !send(fmt.Sprintf("id: %d\nevent: notification\ndata: %s\n\n", notification.ID, data)) {
+				stop()
+				return
+			}
+			cursor = notification.ID
+		}
+
+		// A full batch may have more behind it
+		if len(notifications) < notificationStreamBatch && !h.awaitNotification(r, changed, heartbeat.C, send) {
+			stop()
+			return
+		}
+		stop()
+	}
+}
+
+// awaitNotification waits for the next notification, sending heartbeats
+// meanwhile. It reports false once the client is gone.
+func (h *AdminNotificationHandlers) awaitNotification(r *http.Request, changed <-chan struct{}, heartbeat <-chan time.Time, send func(string) bool) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-heartbeat:
+			if !send(": heartbeat\n\n") {
+				return false
+			}
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package apphandlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestAdminNotificationHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	admins := services.NewAdminNotificationService(store.AdminNotifications(), store.Events(), logger)
+	handler := NewAdminNotificationHandlers(admins, logger)
+	tickets := admins.Tickets(store.Tickets())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/notifications", handler.HandleListNotifications).Methods("GET")
+	router.HandleFunc("/api/admin/notifications/stream", handler.HandleStreamNotifications).Methods("GET")
+	router.HandleFunc("/api/admin/notifications/read-all", handler.HandleMarkAllRead).Methods("POST")
+	router.HandleFunc("/api/admin/notifications/{id:[0-9]+}/read", handler.HandleMarkRead).Methods("POST")
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: 1, Email: "admin@example.com"}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(path string) models.AdminNotificationFeed {
+		t.Helper()
+		rec := serve("GET", path)
+		var result struct {
+			Data models.AdminNotificationFeed `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("Expected the feed, got %d %s", rec.Code, rec.Body.String())
+		}
+		return result.Data
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	sell := func(code string) *models.Ticket {
+		t.Helper()
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: code, PaymentStatus: models.TicketPending, AmountSats: 1000}
+		if err := tickets.Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		if err := tickets.UpdatePaymentStatus(ticket.ID, models.TicketPaid); err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+	sell("ALERT-1")
+	sell("ALERT-2")
+
+	feed := list("/api/admin/notifications")
+	if len(feed.Notifications) != 2 || feed.UnreadCount != 2 || feed.Notifications[0].Kind != models.AdminNotificationSale ||
+		!strings.Contains(feed.Notifications[0].Message, "ALERT-2") {
+		t.Fatalf("Expected both sales newest first, got %+v", feed)
+	}
+	if rec := serve("GET", "/api/admin/notifications?unread=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad unread filter, got %d", rec.Code)
+	}
+
+	if rec := serve("POST", "/api/admin/notifications/9999/read"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown notification, got %d", rec.Code)
+	}
+	rec := serve("POST", "/api/admin/notifications/"+strconv.Itoa(feed.Notifications[1].ID)+"/read")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"read_by":1`) {
+		t.Fatalf("Expected the notification marked read, got %d %s", rec.Code, rec.Body.String())
+	}
+	if unread := list("/api/admin/notifications?unread=true"); len(unread.Notifications) != 1 || unread.UnreadCount != 1 || unread.Notifications[0].ID != feed.Notifications[0].ID {
+		t.Errorf("Expected only the other sale unread, got %+v", unread)
+	}
+	if rec := serve("POST", "/api/admin/notifications/read-all"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"marked":1`) {
+		t.Errorf("Expected one marked read, got %d %s", rec.Code, rec.Body.String())
+	}
+	if unread := list("/api/admin/notifications?unread=true"); len(unread.Notifications) != 0 || unread.UnreadCount != 0 {
+		t.Errorf("Expected none unread, got %+v", unread)
+	}
+
+	// The stream resumes after the last notification the client saw, then
+	// sends new ones as they come
+	server := httptest.NewServer(router)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/admin/notifications/stream", nil)
+	req.Header.Set("Last-Event-ID", strconv.Itoa(feed.Notifications[1].ID))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() (string, models.AdminNotification) {
+		t.Helper()
+		var id string
+		var notification models.AdminNotification
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Stream ended: %v", err)
+			}
+			if value, ok := strings.CutPrefix(line, "id: "); ok {
+				id = strings.TrimSpace(value)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				if err := json.Unmarshal([]byte(data), &notification); err != nil {
+					t.Fatal(err)
+				}
+				return id, notification
+			}
+		}
+	}
+	if id, notification := next(); notification.ID != feed.Notifications[0].ID || id != strconv.Itoa(notification.ID) {
+		t.Errorf("Expected the missed sale resent with its ID, got %s %+v", id, notification)
+	}
+	third := sell("ALERT-3")
+	if _, notification := next(); notification.EntityID == nil || *notification.EntityID != third.ID {
+		t.Errorf("Expected the new sale streamed, got %+v", notification)
+	}
+
+	if rec := serve("GET", "/api/admin/notifications/stream?after=soon"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad resume ID, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type AffiliateHandlers struct {
+	affiliates    *services.AffiliateService
+	affiliateRepo repositories.AffiliateRepository
+	userRepo      repositories.UserRepository
+	payouts       *services.PayoutService
+	logger        *slog.Logger
+}
+
+func NewAffiliateHandlers(affiliates *services.AffiliateService, affiliateRepo repositories.AffiliateRepository, userRepo repositories.UserRepository, payouts *services.PayoutService, logger *slog.Logger) *AffiliateHandlers {
+	return &AffiliateHandlers{
+		affiliates:    affiliates,
+		affiliateRepo: affiliateRepo,
+		userRepo:      userRepo,
+		payouts:       payouts,
+		logger:        logger,
+	}
+}
+
+// HandleGetMyAffiliate returns the user's affiliate link with the orders
+// and commission it has earned
+func (h *AffiliateHandlers) HandleGetMyAffiliate(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	affiliate, err := h.affiliateRepo.GetByUserID(user.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Affiliate account not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch affiliate", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch affiliate")
+		return
+	}
+
+	report, err := h.affiliates.Report(affiliate.ID)
+	if err != nil {
+		h.logger.Error("Failed to report affiliate commission", "affiliate_id", affiliate.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch affiliate")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Affiliate account retrieved successfully",
+		Data:    report,
+	})
+}
+
+// HandleListAffiliates lists every affiliate with their referred orders and
+// accrued, paid and outstanding commission (admin only)
+func (h *AffiliateHandlers) HandleListAffiliates(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.affiliates.Reports()
+	if err != nil {
+		h.logger.Error("Failed to report affiliate commission", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch affiliates")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Affiliates retrieved successfully",
+		Data:    reports,
+	})
+}
+
+// HandleCreateAffiliate enrolls a user as an affiliate, with a generated
+// code and the default commission unless given. A payout address is checked
+// against its LNURL-pay service before it is saved (admin only)
+func (h *AffiliateHandlers) HandleCreateAffiliate(w http.ResponseWriter, r *http.Request) {
+	var req models.AffiliateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := h.userRepo.GetByID(req.UserID); errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to fetch user", "user_id", req.UserID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save affiliate")
+		return
+	}
+	if !h.validatePayoutAddress(w, r, &req) {
+		return
+	}
+
+	payoutAddress := ""
+	if req.PayoutAddress != nil {
+		payoutAddress = *req.PayoutAddress
+	}
+	affiliate, err := h.affiliates.Enroll(req.UserID, req.Code, req.BasisPoints, payoutAddress)
+	if !h.writeAffiliateError(w, err, "user_id", req.UserID) {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Affiliate created successfully",
+		Data:    affiliate,
+	})
+}
+
+// HandleUpdateAffiliate changes an affiliate's code, commission, status or
+// payout address; a new commission applies to orders placed from then on
+// (admin only)
+func (h *AffiliateHandlers) HandleUpdateAffiliate(w http.ResponseWriter, r *http.Request) {
+	affiliateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid affiliate ID")
+		return
+	}
+
+	var req models.AffiliateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.validatePayoutAddress(w, r, &req) {
+		return
+	}
+
+	affiliate, err := h.affiliates.Update(affiliateID, req)
+	if !h.writeAffiliateError(w, err, "affiliate_id", affiliateID) {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Affiliate updated successfully",
+		Data:    affiliate,
+	})
+}
+
+// validatePayoutAddress normalizes the request's payout address, if any,
+// writing a 400 and reporting false when it cannot be paid
+func (h *AffiliateHandlers) validatePayoutAddress(w http.ResponseWriter, r *http.Request, req *models.AffiliateRequest) bool {
+	if req.PayoutAddress == nil {
+		return true
+	}
+	address, err := h.payouts.ValidateAddress(r.Context(), *req.PayoutAddress)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	req.PayoutAddress = &address
+	return true
+}
+
+// writeAffiliateError writes the response for a failed affiliate change,
+// reporting whether err was nil
+func (h *AffiliateHandlers) writeAffiliateError(w http.ResponseWriter, err error, key string, id int) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrAffiliateCode), errors.Is(err, services.ErrAffiliateRate):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Affiliate not found")
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "User is already an affiliate or the code is taken")
+	default:
+		h.logger.Error("Failed to save affiliate", key, id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save affiliate")
+	}
+	return false
+}
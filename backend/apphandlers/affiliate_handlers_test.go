@@ -0,0 +1,148 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestAffiliateHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	affiliates := services.NewAffiliateService(store.Affiliates(), ledger, store.Ledger(), 1000, "tickets.example", logger)
+	handler := NewAffiliateHandlers(affiliates, store.Affiliates(), store.Users(), services.NewPayoutService(ledger, store.OrganizerProfiles(), store.Affiliates(), store.Payments(), store.Tickets(), nil, &http.Client{}, logger), logger)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, affiliates, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users/me/affiliate", handler.HandleGetMyAffiliate).Methods("GET")
+	router.HandleFunc("/api/admin/affiliates", handler.HandleListAffiliates).Methods("GET")
+	router.HandleFunc("/api/admin/affiliates", handler.HandleCreateAffiliate).Methods("POST")
+	router.HandleFunc("/api/admin/affiliates/{id:[0-9]+}", handler.HandleUpdateAffiliate).Methods("PUT")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	var users []*models.User
+	for _, name := range []string{"Promoter", "Buyer"} {
+		user := &models.User{Email: name + "@example.com", Name: name}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	promoter, buyer := users[0], users[1]
+
+	code := "Promo-Team"
+	status, data := do("POST", "/api/admin/affiliates", nil, models.AffiliateRequest{UserID: promoter.ID, Code: &code})
+	var affiliate models.Affiliate
+	json.Unmarshal(data, &affiliate)
+	if status != http.StatusCreated || affiliate.Code != "promo-team" || affiliate.BasisPoints != 1000 {
+		t.Fatalf("Expected the affiliate enrolled at the default rate, got %d %s", status, data)
+	}
+
+	bad, rate := "x", int64(-1)
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		body   models.AffiliateRequest
+		want   int
+	}{
+		{"unknown user", "POST", "/api/admin/affiliates", models.AffiliateRequest{UserID: 99}, http.StatusNotFound},
+		{"enrolled twice", "POST", "/api/admin/affiliates", models.AffiliateRequest{UserID: promoter.ID}, http.StatusConflict},
+		{"taken code", "POST", "/api/admin/affiliates", models.AffiliateRequest{UserID: buyer.ID, Code: &code}, http.StatusConflict},
+		{"bad code", "POST", "/api/admin/affiliates", models.AffiliateRequest{UserID: buyer.ID, Code: &bad}, http.StatusBadRequest},
+		{"bad rate", "PUT", "/api/admin/affiliates/" + strconv.Itoa(affiliate.ID), models.AffiliateRequest{BasisPoints: &rate}, http.StatusBadRequest},
+		{"unknown affiliate", "PUT", "/api/admin/affiliates/99", models.AffiliateRequest{}, http.StatusNotFound},
+		{"bad payout address", "PUT", "/api/admin/affiliates/" + strconv.Itoa(affiliate.ID), models.AffiliateRequest{PayoutAddress: &bad}, http.StatusBadRequest},
+	} {
+		if status, data := do(tc.method, tc.path, nil, tc.body); status != tc.want {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.want, status, data)
+		}
+	}
+
+	// Orders placed with the code are attributed at the rate in force
+	rate = 500
+	if status, data := do("PUT", "/api/admin/affiliates/"+strconv.Itoa(affiliate.ID), nil, models.AffiliateRequest{BasisPoints: &rate}); status != http.StatusOK {
+		t.Fatalf("Expected the rate updated, got %d %s", status, data)
+	}
+	event := &models.Event{Title: "Launch Party", Capacity: 10, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"PROMO-TEAM", "someone-else"} {
+		status, data := do("POST", "/api/tickets/purchase?ref="+ref, nil, models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: buyer.ID, UMAAddress: "$buyer@wallet.example.com",
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 for the purchase, got %d %s", status, data)
+		}
+	}
+	orders, err := store.Orders().GetByUserID(buyer.ID)
+	if err != nil || len(orders) != 2 {
+		t.Fatalf("Expected 2 orders, got %+v (%v)", orders, err)
+	}
+	attributed := 0
+	for _, order := range orders {
+		if order.AffiliateID != nil {
+			attributed++
+			if *order.AffiliateID != affiliate.ID || order.CommissionBasisPoints != 500 {
+				t.Errorf("Expected the order attributed at 5%%, got %+v", order)
+			}
+		}
+	}
+	if attributed != 1 {
+		t.Errorf("Expected one attributed order, got %d", attributed)
+	}
+
+	status, data = do("GET", "/api/users/me/affiliate", promoter, nil)
+	var report models.AffiliateReport
+	json.Unmarshal(data, &report)
+	if status != http.StatusOK || report.ID != affiliate.ID || report.ReferredOrders != 1 || report.Link != "https://tickets.example/?ref=promo-team" {
+		t.Errorf("Expected the promoter's report, got %d %s", status, data)
+	}
+	if status, _ := do("GET", "/api/users/me/affiliate", buyer, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a user who is not an affiliate, got %d", status)
+	}
+
+	status, data = do("GET", "/api/admin/affiliates", nil, nil)
+	var reports []models.AffiliateReport
+	json.Unmarshal(data, &reports)
+	if status != http.StatusOK || len(reports) != 1 || reports[0].UserName != "Promoter" {
+		t.Errorf("Expected the affiliate listed, got %d %s", status, data)
+	}
+}
@@ -0,0 +1,207 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// AlertConnectorHandlers manages the Slack, Discord and Telegram channels
+// admin notifications are posted to (admin only). Connector targets are
+// write-only.
+type AlertConnectorHandlers struct {
+	connectorRepo repositories.AlertConnectorRepository
+	alerts        *services.AlertService
+	logger        *slog.Logger
+}
+
+func NewAlertConnectorHandlers(connectorRepo repositories.AlertConnectorRepository, alerts *services.AlertService, logger *slog.Logger) *AlertConnectorHandlers {
+	return &AlertConnectorHandlers{
+		connectorRepo: connectorRepo,
+		alerts:        alerts,
+		logger:        logger,
+	}
+}
+
+// HandleListConnectors lists every alert connector
+func (h *AlertConnectorHandlers) HandleListConnectors(w http.ResponseWriter, r *http.Request) {
+	connectors, err := h.connectorRepo.List(false)
+	if err != nil {
+		h.logger.Error("Failed to fetch alert connectors", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch alert connectors")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Alert connectors retrieved successfully",
+		Data:    connectors,
+	})
+}
+
+// HandleCreateConnector adds an alert connector, active unless is_active is
+// false
+func (h *AlertConnectorHandlers) HandleCreateConnector(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAlertConnectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	connector := &models.AlertConnector{
+		Name:     req.Name,
+		Kind:     req.Kind,
+		Target:   req.Target,
+		ChatID:   req.ChatID,
+		Events:   req.Events,
+		IsActive: req.IsActive == nil || *req.IsActive,
+	}
+	if err := services.ValidateAlertConnector(connector); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.connectorRepo.Create(connector); err != nil {
+		h.logger.Error("Failed to create alert connector", "kind", connector.Kind, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create alert connector")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Alert connector created", "audit", true, "admin_id", admin.ID,
+		"connector_id", connector.ID, "kind", connector.Kind, "events", connector.Events)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Alert connector created successfully",
+		Data:    connector,
+	})
+}
+
+// HandleUpdateConnector changes the fields of an alert connector that are
+// set
+func (h *AlertConnectorHandlers) HandleUpdateConnector(w http.ResponseWriter, r *http.Request) {
+	connector, ok := h.connector(w, r)
+	if !ok {
+		return
+	}
+	var req models.UpdateAlertConnectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name != nil {
+		connector.Name = *req.Name
+	}
+	if req.Target != nil {
+		connector.Target = *req.Target
+	}
+	if req.ChatID != nil {
+		connector.ChatID = *req.ChatID
+	}
+	if req.Events != nil {
+		connector.Events = *req.Events
+	}
+	if req.IsActive != nil {
+		connector.IsActive = *req.IsActive
+	}
+	if err := services.ValidateAlertConnector(connector); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.connectorRepo.Update(connector)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Alert connector not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update alert connector", "connector_id", connector.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update alert connector")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Alert connector updated", "audit", true, "admin_id", admin.ID,
+		"connector_id", connector.ID, "target_changed", req.Target != nil, "events", connector.Events, "is_active", connector.IsActive)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Alert connector updated successfully",
+		Data:    connector,
+	})
+}
+
+// HandleDeleteConnector deletes an alert connector
+func (h *AlertConnectorHandlers) HandleDeleteConnector(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid connector ID")
+		return
+	}
+
+	err = h.connectorRepo.Delete(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Alert connector not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete alert connector", "connector_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete alert connector")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Alert connector deleted", "audit", true, "admin_id", admin.ID, "connector_id", id)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Alert connector deleted successfully",
+	})
+}
+
+// HandleTestConnector posts a test message to an alert connector's channel,
+// answering 502 with the reason when it cannot be posted
+func (h *AlertConnectorHandlers) HandleTestConnector(w http.ResponseWriter, r *http.Request) {
+	connector, ok := h.connector(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.alerts.Test(connector); err != nil {
+		middleware.WriteError(w, http.StatusBadGateway, "Test alert failed: "+err.Error())
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Test alert sent",
+	})
+}
+
+// connector loads the alert connector named in the route, writing the
+// error response when it cannot
+func (h *AlertConnectorHandlers) connector(w http.ResponseWriter, r *http.Request) (*models.AlertConnector, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid connector ID")
+		return nil, false
+	}
+
+	connector, err := h.connectorRepo.GetByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Alert connector not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch alert connector", "connector_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch alert connector")
+		return nil, false
+	}
+	return connector, true
+}
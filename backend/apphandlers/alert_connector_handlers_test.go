@@ -0,0 +1,107 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type alertTransport func(*http.Request) (*http.Response, error)
+
+func (f alertTransport) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAlertConnectorHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	status := http.StatusOK
+	client := &http.Client{Transport: alertTransport(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	})}
+	alerts := services.NewAlertService(store.AlertConnectors(), client, services.TelegramAPIURL, clk, logger)
+	handler := NewAlertConnectorHandlers(store.AlertConnectors(), alerts, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/alert-connectors", handler.HandleListConnectors).Methods("GET")
+	router.HandleFunc("/api/admin/alert-connectors", handler.HandleCreateConnector).Methods("POST")
+	router.HandleFunc("/api/admin/alert-connectors/{id:[0-9]+}", handler.HandleUpdateConnector).Methods("PUT")
+	router.HandleFunc("/api/admin/alert-connectors/{id:[0-9]+}", handler.HandleDeleteConnector).Methods("DELETE")
+	router.HandleFunc("/api/admin/alert-connectors/{id:[0-9]+}/test", handler.HandleTestConnector).Methods("POST")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: 1, Email: "admin@example.com"}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("POST", "/api/admin/alert-connectors", `{"name":"On call","kind":"telegram","target":"123:bot-secret","chat_id":"-100","events":["sold_out","backend_down"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "bot-secret") {
+		t.Errorf("Expected the bot token left out of the response, got %s", rec.Body.String())
+	}
+	var created struct {
+		Data models.AlertConnector `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || !created.Data.IsActive || len(created.Data.Events) != 2 {
+		t.Fatalf("Unexpected connector %s (%v)", rec.Body.String(), err)
+	}
+	path := "/api/admin/alert-connectors/" + strconv.Itoa(created.Data.ID)
+
+	if rec := serve("POST", "/api/admin/alert-connectors", `{"name":"Spam","kind":"slack","target":"https://example.com/hook","events":["sale"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a target that is no Slack webhook refused, got %d", rec.Code)
+	}
+	if rec := serve("PUT", path, `{"events":["sales"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown event refused, got %d", rec.Code)
+	}
+	if rec := serve("PUT", path, `{"events":["sale"],"is_active":false}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	connector, _ := store.AlertConnectors().GetByID(created.Data.ID)
+	if connector.IsActive || len(connector.Events) != 1 || connector.Target != "123:bot-secret" {
+		t.Errorf("Expected the events and state changed, keeping the token, got %+v", connector)
+	}
+
+	if rec := serve("POST", path+"/test", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the test sent, got %d %s", rec.Code, rec.Body.String())
+	}
+	status = http.StatusUnauthorized
+	if rec := serve("POST", path+"/test", ""); rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "401") {
+		t.Errorf("Expected 502 with the reason, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve("GET", "/api/admin/alert-connectors", "")
+	var listed struct {
+		Data []models.AlertConnector `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed.Data) != 1 || listed.Data[0].LastError != "post returned 401" {
+		t.Errorf("Expected the connector with its last error, got %s", rec.Body.String())
+	}
+
+	if rec := serve("DELETE", path, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if rec := serve("DELETE", path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", rec.Code)
+	}
+	if rec := serve("POST", path+"/test", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 testing a deleted connector, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type AnalyticsHandlers struct {
+	orderRepo repositories.OrderRepository
+	addOnRepo repositories.AddOnRepository
+	logger    *slog.Logger
+}
+
+func NewAnalyticsHandlers(orderRepo repositories.OrderRepository, addOnRepo repositories.AddOnRepository, logger *slog.Logger) *AnalyticsHandlers {
+	return &AnalyticsHandlers{
+		orderRepo: orderRepo,
+		addOnRepo: addOnRepo,
+		logger:    logger,
+	}
+}
+
+// HandleReferralReport reports how the orders from each referral source
+// converted, optionally for one event or organizer and period, grouped by
+// ?group_by= source (the default), medium, campaign or ref (admin only)
+func (h *AnalyticsHandlers) HandleReferralReport(w http.ResponseWriter, r *http.Request) {
+	filter := models.ReferralFilter{GroupBy: models.ReferralGroupSource}
+	query := r.URL.Query()
+
+	for param, id := range map[string]*int{"event_id": &filter.EventID, "organizer_id": &filter.OrganizerID} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid "+param)
+			return
+		}
+		*id = parsed
+	}
+	switch groupBy := query.Get("group_by"); groupBy {
+	case "":
+	case models.ReferralGroupSource, models.ReferralGroupMedium, models.ReferralGroupCampaign, models.ReferralGroupRef:
+		filter.GroupBy = groupBy
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "group_by must be source, medium, campaign or ref")
+		return
+	}
+	var ok bool
+	if filter.From, filter.To, ok = reportPeriod(w, r, false); !ok {
+		return
+	}
+
+	sources, err := h.orderRepo.ReferralConversions(filter)
+	if err != nil {
+		h.logger.Error("Failed to compute referral conversions", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to compute referral conversions")
+		return
+	}
+
+	var totals models.ReferralConversion
+	for _, source := range sources {
+		totals.Orders += source.Orders
+		totals.ConvertedOrders += source.ConvertedOrders
+		totals.TicketsSold += source.TicketsSold
+		totals.RevenueSats += source.RevenueSats
+	}
+	if totals.Orders > 0 {
+		totals.ConversionRate = float64(totals.ConvertedOrders) / float64(totals.Orders)
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Referral conversions retrieved successfully",
+		Data: map[string]interface{}{
+			"group_by": filter.GroupBy,
+			"sources":  sources,
+			"totals": map[string]interface{}{
+				"orders":           totals.Orders,
+				"converted_orders": totals.ConvertedOrders,
+				"tickets_sold":     totals.TicketsSold,
+				"revenue_sats":     totals.RevenueSats,
+				"conversion_rate":  totals.ConversionRate,
+			},
+		},
+	})
+}
+
+// HandleFlexReport reports how many sold tickets were bought with a flex
+// add-on and cancelled with one, per event, optionally for one event or
+// organizer (admin only)
+func (h *AnalyticsHandlers) HandleFlexReport(w http.ResponseWriter, r *http.Request) {
+	var filter models.FlexFilter
+	for param, id := range map[string]*int{"event_id": &filter.EventID, "organizer_id": &filter.OrganizerID} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid "+param)
+			return
+		}
+		*id = parsed
+	}
+
+	events, err := h.addOnRepo.FlexUptake(filter)
+	if err != nil {
+		h.logger.Error("Failed to compute flex uptake", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to compute flex uptake")
+		return
+	}
+
+	var totals models.FlexUptake
+	for _, event := range events {
+		totals.TicketsSold += event.TicketsSold
+		totals.FlexTickets += event.FlexTickets
+		totals.FlexRevenueSats += event.FlexRevenueSats
+		totals.FlexCancellations += event.FlexCancellations
+	}
+	if totals.TicketsSold > 0 {
+		totals.UptakeRate = float64(totals.FlexTickets) / float64(totals.TicketsSold)
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Flex uptake retrieved successfully",
+		Data: map[string]interface{}{
+			"events": events,
+			"totals": map[string]interface{}{
+				"tickets_sold":       totals.TicketsSold,
+				"flex_tickets":       totals.FlexTickets,
+				"flex_revenue_sats":  totals.FlexRevenueSats,
+				"flex_cancellations": totals.FlexCancellations,
+				"uptake_rate":        totals.UptakeRate,
+			},
+		},
+	})
+}
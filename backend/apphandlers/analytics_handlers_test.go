@@ -0,0 +1,105 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestReferralReport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	analytics := NewAnalyticsHandlers(store.Orders(), store.AddOns(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/analytics/referrals", analytics.HandleReferralReport).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	user := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Launch Party", Capacity: 10, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	purchase := func(query string, referral models.Referral) {
+		t.Helper()
+		status, _ := do("POST", "/api/tickets/purchase"+query, models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: user.ID, UMAAddress: "$buyer@wallet.example.com", Referral: referral,
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 for the purchase, got %d", status)
+		}
+	}
+	// The body's fields win over the query's; the rest come from the query
+	purchase("?ref=ignored&utm_source=newsletter&utm_campaign=launch", models.Referral{Ref: " promoter-7 ", UTMMedium: strings.Repeat("m", 150)})
+	purchase("?utm_source=newsletter", models.Referral{})
+	purchase("", models.Referral{Ref: "friend"})
+
+	orders, err := store.Orders().GetByUserID(user.ID)
+	if err != nil || len(orders) != 3 {
+		t.Fatalf("Expected 3 orders, got %+v (%v)", orders, err)
+	}
+	want := models.Referral{Ref: "promoter-7", UTMSource: "newsletter", UTMMedium: strings.Repeat("m", maxReferralLength), UTMCampaign: "launch"}
+	if orders[2].Referral != want {
+		t.Errorf("Expected the referral %+v on the order, got %+v", want, orders[2].Referral)
+	}
+
+	status, data := do("GET", "/api/admin/analytics/referrals?event_id="+strconv.Itoa(event.ID), nil)
+	var report struct {
+		GroupBy string                      `json:"group_by"`
+		Sources []models.ReferralConversion `json:"sources"`
+		Totals  map[string]float64          `json:"totals"`
+	}
+	json.Unmarshal(data, &report)
+	if status != http.StatusOK || report.GroupBy != models.ReferralGroupSource || len(report.Sources) != 2 ||
+		report.Sources[0].Source != "newsletter" || report.Sources[0].Orders != 2 || report.Sources[0].TicketsSold != 2 ||
+		report.Sources[1].Source != "friend" || report.Totals["orders"] != 3 || report.Totals["conversion_rate"] != 1 {
+		t.Errorf("Expected conversions by source, got %d %s", status, data)
+	}
+
+	_, data = do("GET", "/api/admin/analytics/referrals?group_by=ref", nil)
+	json.Unmarshal(data, &report)
+	if len(report.Sources) != 3 || report.GroupBy != models.ReferralGroupRef {
+		t.Errorf("Expected conversions by ref code, got %s", data)
+	}
+
+	for _, query := range []string{"group_by=country", "event_id=abc", "organizer_id=0", "from=yesterday"} {
+		if status, _ := do("GET", "/api/admin/analytics/referrals?"+query, nil); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, status)
+		}
+	}
+}
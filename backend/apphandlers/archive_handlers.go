@@ -0,0 +1,78 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// ArchiveHandlers read back the rows the retention archiver moved out of
+// the hot tables
+type ArchiveHandlers struct {
+	repo   repositories.ArchiveRepository
+	logger *slog.Logger
+}
+
+func NewArchiveHandlers(repo repositories.ArchiveRepository, logger *slog.Logger) *ArchiveHandlers {
+	return &ArchiveHandlers{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// HandleGetArchivedRecord returns an archived row by its original table and
+// ID, e.g. /api/admin/archive/payments/42 (admin only)
+func (h *ArchiveHandlers) HandleGetArchivedRecord(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid record ID")
+		return
+	}
+
+	record, err := h.repo.Get(vars["table"], id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Archived record not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get archived record", "table", vars["table"], "id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to get archived record")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Archived record retrieved successfully",
+		Data:    record,
+	})
+}
+
+// HandleGetEventArchive returns everything archived for an event: the event
+// itself once archived, and its tickets, payments and their rows (admin
+// only)
+func (h *ArchiveHandlers) HandleGetEventArchive(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	records, err := h.repo.ListByEvent(eventID)
+	if err != nil {
+		h.logger.Error("Failed to list archived records", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to list archived records")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Archived records retrieved successfully",
+		Data:    records,
+	})
+}
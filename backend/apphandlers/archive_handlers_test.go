@@ -0,0 +1,70 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestArchiveHandlers(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewArchiveHandlers(store.Archive(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/archive/{table:[a-z_]+}/{id:[0-9]+}", handler.HandleGetArchivedRecord).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/archive", handler.HandleGetEventArchive).Methods("GET")
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	event := &models.Event{Title: "Last Year's Meetup", StartTime: clk.Now().AddDate(-1, 0, 0), EndTime: clk.Now().AddDate(-1, 0, 0), Capacity: 10}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	if archived, err := store.Archive().ArchiveEvents(clk.Now(), 10); err != nil || archived != 1 {
+		t.Fatalf("Failed to archive event: %d (%v)", archived, err)
+	}
+
+	rec := get(fmt.Sprintf("/api/admin/archive/events/%d", event.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var record struct {
+		Data models.ArchivedRecord `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&record); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if record.Data.TableName != "events" || record.Data.RecordID != event.ID || record.Data.Data["title"] != event.Title {
+		t.Errorf("Unexpected archived record %+v", record.Data)
+	}
+	if rec := get(fmt.Sprintf("/api/admin/archive/payments/%d", event.ID)); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a record never archived, got %d", rec.Code)
+	}
+
+	rec = get(fmt.Sprintf("/api/admin/events/%d/archive", event.ID))
+	var records struct {
+		Data []models.ArchivedRecord `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&records); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if rec.Code != http.StatusOK || len(records.Data) != 1 || records.Data[0].TableName != "events" {
+		t.Errorf("Expected the archived event, got %d %+v", rec.Code, records.Data)
+	}
+}
@@ -0,0 +1,90 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// AttestationHandlers exposes the age and terms attestations given with
+// purchases for compliance review (admin only)
+type AttestationHandlers struct {
+	attestRepo repositories.AttestationRepository
+	eventRepo  repositories.EventRepository
+	logger     *slog.Logger
+}
+
+func NewAttestationHandlers(
+	attestRepo repositories.AttestationRepository,
+	eventRepo repositories.EventRepository,
+	logger *slog.Logger,
+) *AttestationHandlers {
+	return &AttestationHandlers{
+		attestRepo: attestRepo,
+		eventRepo:  eventRepo,
+		logger:     logger,
+	}
+}
+
+// HandleGetEventAttestations lists the attestations given with an event's
+// tickets
+func (h *AttestationHandlers) HandleGetEventAttestations(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	if _, err := h.eventRepo.GetByID(eventID); errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	attestations, err := h.attestRepo.GetByEventID(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch attestations", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch attestations")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Attestations retrieved successfully",
+		Data:    attestations,
+	})
+}
+
+// HandleGetTicketAttestation returns the attestation given with a ticket
+func (h *AttestationHandlers) HandleGetTicketAttestation(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	attestation, err := h.attestRepo.GetByTicketID(ticketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket has no attestation")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch attestation", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch attestation")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Attestation retrieved successfully",
+		Data:    attestation,
+	})
+}
@@ -0,0 +1,153 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestPurchaseAttestations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	attestations := NewAttestationHandlers(store.Attestations(), store.Events(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/attestations", attestations.HandleGetEventAttestations).Methods("GET")
+	router.HandleFunc("/api/admin/tickets/{id:[0-9]+}/attestation", attestations.HandleGetTicketAttestation).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	type response struct {
+		Data      json.RawMessage `json:"data"`
+		ErrorCode string          `json:"error_code"`
+	}
+	do := func(method, path string, body interface{}) (int, response) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("User-Agent", "TestBrowser/1.0")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp response
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	user := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	restricted := &models.Event{Title: "Late Show", Capacity: 10, IsActive: true, MinAge: 18, TermsVersion: "v2"}
+	open := &models.Event{Title: "Matinee", Capacity: 10, IsActive: true}
+	for _, event := range []*models.Event{restricted, open} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purchase := func(eventID int, ageConfirmed bool, termsVersion string) (int, response) {
+		return do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: eventID, UserID: user.ID, UMAAddress: "$buyer@wallet.example.com",
+			AgeConfirmed: ageConfirmed, AcceptedTermsVersion: termsVersion,
+		})
+	}
+
+	for name, attempt := range map[string]struct {
+		ageConfirmed bool
+		termsVersion string
+	}{
+		"nothing":        {},
+		"age only":       {ageConfirmed: true},
+		"terms only":     {termsVersion: "v2"},
+		"outdated terms": {ageConfirmed: true, termsVersion: "v1"},
+	} {
+		status, resp := purchase(restricted.ID, attempt.ageConfirmed, attempt.termsVersion)
+		if status != http.StatusBadRequest || resp.ErrorCode != models.ErrorCodeAttestationRequired {
+			t.Errorf("%s: expected 400 %s, got %d %q", name, models.ErrorCodeAttestationRequired, status, resp.ErrorCode)
+		}
+	}
+	if tickets, _ := store.Tickets().GetByEventID(restricted.ID); len(tickets) != 0 {
+		t.Errorf("Expected no tickets without an attestation, got %d", len(tickets))
+	}
+
+	status, resp := purchase(restricted.ID, true, "v2")
+	var bought struct {
+		Ticket struct {
+			ID int `json:"id"`
+		} `json:"ticket"`
+	}
+	json.Unmarshal(resp.Data, &bought)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 with both attestations, got %d", status)
+	}
+
+	status, resp = do("GET", "/api/admin/tickets/"+strconv.Itoa(bought.Ticket.ID)+"/attestation", nil)
+	var attestation models.PurchaseAttestation
+	json.Unmarshal(resp.Data, &attestation)
+	if status != http.StatusOK || attestation.MinAge != 18 || attestation.TermsVersion != "v2" ||
+		attestation.ClientIP != "192.0.2.1" || attestation.UserAgent != "TestBrowser/1.0" || !attestation.AttestedAt.Equal(clk.Now()) {
+		t.Errorf("Expected the attestation to be recorded, got %d %s", status, resp.Data)
+	}
+	_, resp = do("GET", "/api/admin/events/"+strconv.Itoa(restricted.ID)+"/attestations", nil)
+	var listed []models.PurchaseAttestation
+	json.Unmarshal(resp.Data, &listed)
+	if len(listed) != 1 || listed[0].TicketID != bought.Ticket.ID {
+		t.Errorf("Expected the event's one attestation, got %s", resp.Data)
+	}
+
+	// Events without restrictions need and record nothing
+	status, resp = purchase(open.ID, false, "")
+	json.Unmarshal(resp.Data, &bought)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 for an unrestricted event, got %d", status)
+	}
+	if status, _ := do("GET", "/api/admin/tickets/"+strconv.Itoa(bought.Ticket.ID)+"/attestation", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a ticket without an attestation, got %d", status)
+	}
+	if status, _ := do("GET", "/api/admin/events/999/attestations", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown event, got %d", status)
+	}
+}
+
+func TestValidateRestrictions(t *testing.T) {
+	tests := []struct {
+		name         string
+		minAge       int
+		termsVersion string
+		termsURL     string
+		wantErr      bool
+	}{
+		{"none", 0, "", "", false},
+		{"age and terms", 21, "2026-10", "https://example.com/terms", false},
+		{"terms without URL", 0, "v1", "", false},
+		{"negative age", -1, "", "", true},
+		{"age too high", 100, "", "", true},
+		{"URL without version", 0, "", "https://example.com/terms", true},
+		{"not a web URL", 0, "v1", "ftp://example.com/terms", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRestrictions(tt.minAge, tt.termsVersion, tt.termsURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRestrictions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
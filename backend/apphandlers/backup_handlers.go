@@ -0,0 +1,91 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/services"
+)
+
+// BackupHandlers take database backups for operators without managed ones
+type BackupHandlers struct {
+	backups *services.BackupService
+	logger  *slog.Logger
+}
+
+func NewBackupHandlers(backups *services.BackupService, logger *slog.Logger) *BackupHandlers {
+	return &BackupHandlers{
+		backups: backups,
+		logger:  logger,
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// HandleBackup takes a consistent backup of the database (admin only). By
+// default it is streamed back as an attachment; with ?to=storage it is
+// uploaded to the configured backup bucket and its location returned.
+// `tickets-by-uma restore <file>` restores either. Every backup is audit
+// logged.
+func (h *BackupHandlers) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	admin := middleware.GetUserFromContext(r.Context())
+	if !h.backups.Enabled() {
+		middleware.WriteError(w, http.StatusNotImplemented, "Backups need a database; memory storage has none")
+		return
+	}
+	// The server's write timeout is shorter than a large backup takes
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	switch to := r.URL.Query().Get("to"); to {
+	case "storage":
+		backup, err := h.backups.Upload(r.Context())
+		if errors.Is(err, services.ErrBackupNoStorage) {
+			middleware.WriteError(w, http.StatusBadRequest, "No backup storage is configured")
+			return
+		}
+		if err != nil {
+			h.logger.Error("Backup failed", "audit", true, "admin_id", admin.ID, "to", to, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to back up the database")
+			return
+		}
+		h.logger.Info("Backup uploaded", "audit", true,
+			"admin_id", admin.ID, "location", backup.Location, "bytes", backup.Bytes)
+		middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+			Message: "Backup uploaded successfully",
+			Data:    backup,
+		})
+	case "", "download":
+		filename := h.backups.Filename()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		body := &countingWriter{w: w}
+		if err := h.backups.Write(r.Context(), body); err != nil {
+			h.logger.Error("Backup failed", "audit", true, "admin_id", admin.ID, "filename", filename, "bytes", body.n, "error", err)
+			if body.n == 0 {
+				w.Header().Del("Content-Disposition")
+				middleware.WriteError(w, http.StatusInternalServerError, "Failed to back up the database")
+				return
+			}
+			// Part of the backup is out: break the connection so the
+			// caller cannot mistake it for a whole one
+			panic(http.ErrAbortHandler)
+		}
+		h.logger.Info("Backup downloaded", "audit", true, "admin_id", admin.ID, "filename", filename, "bytes", body.n)
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "to must be download or storage")
+	}
+}
@@ -0,0 +1,83 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/services"
+)
+
+// backupBucket records what was uploaded to it
+type backupBucket struct {
+	uploaded map[string]int64
+}
+
+func (b *backupBucket) Put(ctx context.Context, name string, body io.Reader, size int64) (string, error) {
+	b.uploaded[name] = size
+	return "s3://backups/" + name, nil
+}
+
+func TestBackupHandlers(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	admin := &models.User{ID: 1, Email: "admin@example.com"}
+	dump := func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "SQLite format 3\x00...")
+		return err
+	}
+	post := func(backups *services.BackupService, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		NewBackupHandlers(backups, logger).HandleBackup(rec, req)
+		return rec
+	}
+
+	bucket := &backupBucket{uploaded: map[string]int64{}}
+	backups := services.NewBackupService(dump, "db", bucket, clk)
+	rec := post(backups, "/api/admin/backup")
+	if rec.Code != http.StatusOK || rec.Body.String() != "SQLite format 3\x00..." {
+		t.Fatalf("Expected the backup streamed, got %d %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="tickets-20261016T120000Z.db"` {
+		t.Errorf("Unexpected Content-Disposition %s", got)
+	}
+
+	rec = post(backups, "/api/admin/backup?to=storage")
+	var uploaded struct {
+		Data models.Backup `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&uploaded); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if rec.Code != http.StatusCreated || uploaded.Data.Location != "s3://backups/tickets-20261016T120000Z.db" || bucket.uploaded[uploaded.Data.Filename] != 19 {
+		t.Errorf("Expected the backup uploaded, got %d %+v", rec.Code, uploaded.Data)
+	}
+
+	if rec := post(backups, "/api/admin/backup?to=ftp"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown target, got %d", rec.Code)
+	}
+	if rec := post(services.NewBackupService(dump, "db", nil, clk), "/api/admin/backup?to=storage"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without backup storage, got %d", rec.Code)
+	}
+	if rec := post(services.NewBackupService(nil, "dump", bucket, clk), "/api/admin/backup"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without a database, got %d", rec.Code)
+	}
+
+	// A dump failing before writing anything is reported as an error
+	failing := services.NewBackupService(func(ctx context.Context, w io.Writer) error { return errors.New("pg_dump: not found") }, "dump", nil, clk)
+	rec = post(failing, "/api/admin/backup")
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected 500 without an attachment, got %d %v", rec.Code, rec.Header())
+	}
+}
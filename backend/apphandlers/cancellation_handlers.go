@@ -0,0 +1,95 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type CancellationHandlers struct {
+	cancellations    *services.CancellationService
+	cancellationRepo repositories.EventCancellationRepository
+	logger           *slog.Logger
+}
+
+func NewCancellationHandlers(cancellations *services.CancellationService, cancellationRepo repositories.EventCancellationRepository, logger *slog.Logger) *CancellationHandlers {
+	return &CancellationHandlers{
+		cancellations:    cancellations,
+		cancellationRepo: cancellationRepo,
+		logger:           logger,
+	}
+}
+
+// HandleCancelEvent cancels an event, stopping its sales, and queues the
+// refund or voiding of its tickets. Cancelling an event whose cancellation
+// failed retries it (admin only).
+func (h *CancellationHandlers) HandleCancelEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	// The reason is optional, so an empty body is accepted
+	var req models.CancelEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cancellation, err := h.cancellations.Cancel(eventID, strings.TrimSpace(req.Reason), adminID(r))
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Event is already cancelled")
+		return
+	case err != nil:
+		h.logger.Error("Failed to cancel event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to cancel event")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusAccepted, models.SuccessResponse{
+		Message: "Event cancelled, its tickets are being refunded",
+		Data:    cancellation,
+	})
+}
+
+// HandleGetCancellation reports the progress of an event's cancellation
+// (admin only)
+func (h *CancellationHandlers) HandleGetCancellation(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	cancellation, err := h.cancellationRepo.GetByEventID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event has not been cancelled")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch cancellation", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch cancellation")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Cancellation retrieved successfully",
+		Data:    cancellation,
+	})
+}
@@ -0,0 +1,101 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestCancellationHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &recordingNotifier{subjects: make(map[int][]string)}
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	cancellations := services.NewCancellationService(store.Events(), store.EventCancellations(), store.Tickets(), store.Payments(), ledger, notifier, clk, logger)
+	handler := NewCancellationHandlers(cancellations, store.EventCancellations(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/cancel", handler.HandleCancelEvent).Methods("POST")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/cancellation", handler.HandleGetCancellation).Methods("GET")
+
+	admin := &models.User{ID: 42}
+	do := func(method, path string, body interface{}) (int, []byte) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: 7, TicketCode: "END-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-end-1", Amount: 1000, Status: "pending"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/cancel"
+	progressPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/cancellation"
+
+	if status, _ := do("GET", progressPath, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 before the event is cancelled, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/events/9999/cancel", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown event, got %d", status)
+	}
+
+	var result struct {
+		Data models.EventCancellation `json:"data"`
+	}
+	status, body := do("POST", cancelPath, models.CancelEventRequest{Reason: " Venue closed "})
+	json.Unmarshal(body, &result)
+	if status != http.StatusAccepted || result.Data.Status != models.CancellationRunning || result.Data.Total != 1 ||
+		result.Data.Reason != "Venue closed" || *result.Data.CancelledBy != admin.ID {
+		t.Fatalf("Expected the cancellation queued, got %d %s", status, body)
+	}
+	if status, _ := do("POST", cancelPath, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling twice, got %d", status)
+	}
+
+	if _, err := cancellations.ProcessRunning(); err != nil {
+		t.Fatal(err)
+	}
+	status, body = do("GET", progressPath, nil)
+	json.Unmarshal(body, &result)
+	if status != http.StatusOK || result.Data.Status != models.CancellationDone || result.Data.Refunded != 1 {
+		t.Errorf("Expected the cancellation done, got %d %s", status, body)
+	}
+	if got := notifier.subjects[ticket.UserID]; len(got) != 1 || got[0] != "Event cancelled" {
+		t.Errorf("Expected the buyer notified, got %v", got)
+	}
+}
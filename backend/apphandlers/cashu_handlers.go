@@ -0,0 +1,124 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"tickets-by-uma/cashu"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type CashuHandlers struct {
+	cashu  *services.CashuService
+	logger *slog.Logger
+}
+
+func NewCashuHandlers(cashu *services.CashuService, logger *slog.Logger) *CashuHandlers {
+	return &CashuHandlers{
+		cashu:  cashu,
+		logger: logger,
+	}
+}
+
+// HandlePayWithCashu pays a pending ticket invoice with a Cashu token of a
+// trusted mint. The token must hold exactly the invoice amount plus the
+// mint's fee reserve. Responds 200 once the ticket is paid, or 202 while
+// the mint is still paying the invoice.
+func (h *CashuHandlers) HandlePayWithCashu(w http.ResponseWriter, r *http.Request) {
+	if !h.cashu.Enabled() {
+		middleware.WriteError(w, http.StatusForbidden, "Cashu payments are not enabled")
+		return
+	}
+
+	var req models.CashuPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Invoice == "" || req.Token == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	redemption, err := h.cashu.Redeem(r.Context(), req.Invoice, req.Token)
+	var amountErr *services.CashuAmountError
+	var mintErr *cashu.MintError
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrCashuDisabled):
+		middleware.WriteError(w, http.StatusForbidden, "Cashu payments are not enabled")
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+		return
+	case errors.Is(err, services.ErrCashuNotPending):
+		middleware.WriteError(w, http.StatusConflict, "Invoice is not awaiting payment")
+		return
+	case errors.Is(err, services.ErrCashuInProgress):
+		middleware.WriteError(w, http.StatusConflict, "A Cashu payment of this invoice is already in progress")
+		return
+	case errors.Is(err, cashu.ErrInvalidToken):
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid Cashu token")
+		return
+	case errors.Is(err, services.ErrCashuMint):
+		middleware.WriteError(w, http.StatusBadRequest, "Tokens of this mint are not accepted")
+		return
+	case errors.As(err, &amountErr):
+		middleware.WriteErrorDetails(w, http.StatusBadRequest, models.ErrorCodeCashuAmount,
+			"The token must hold exactly the invoice amount plus the mint's fee reserve", amountErr.Amount)
+		return
+	case errors.As(err, &mintErr):
+		middleware.WriteErrorDetails(w, http.StatusBadRequest, models.ErrorCodeCashuMintRefused,
+			"The mint refused the token", mintErr)
+		return
+	default:
+		h.logger.Error("Failed to redeem Cashu token", "error", err)
+		middleware.WriteError(w, http.StatusBadGateway, "Failed to pay with the mint")
+		return
+	}
+
+	if redemption.State != models.CashuPaid {
+		middleware.WriteJSON(w, http.StatusAccepted, models.SuccessResponse{
+			Message: "Cashu payment is pending",
+			Data:    redemption,
+		})
+		return
+	}
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket paid successfully",
+		Data:    redemption,
+	})
+}
+
+// HandleListCashuRedemptions lists Cashu redemptions newest first (admin
+// only)
+func (h *CashuHandlers) HandleListCashuRedemptions(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	redemptions, err := h.cashu.List(limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch Cashu redemptions", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch Cashu redemptions")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Cashu redemptions retrieved successfully",
+		Data:    redemptions,
+	})
+}
@@ -0,0 +1,149 @@
+package apphandlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/cashu"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestCashuHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+
+	preimage := strings.Repeat("07", 32)
+	hash := sha256.Sum256(bytes.Repeat([]byte{7}, 32))
+	spent := false
+	mint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/melt/quote/bolt11":
+			w.Write([]byte(`{"quote":"q1","amount":1000,"fee_reserve":2,"state":"UNPAID"}`))
+		case "/v1/melt/bolt11":
+			if spent {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":11001,"detail":"Token already spent."}`))
+				return
+			}
+			w.Write([]byte(`{"quote":"q1","amount":1000,"fee_reserve":2,"state":"PAID","payment_preimage":"` + preimage + `"}`))
+		}
+	}))
+	defer mint.Close()
+	service := services.NewCashuService(store.CashuRedemptions(), store.Payments(), store.Tickets(),
+		settings, cashu.NewClient(mint.Client()), []string{mint.URL}, logger)
+	handlers := NewCashuHandlers(service, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/payments/cashu", handlers.HandlePayWithCashu).Methods("POST")
+	router.HandleFunc("/api/admin/cashu/redemptions", handlers.HandleListCashuRedemptions).Methods("GET")
+
+	do := func(method, path string, body interface{}) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		return rec.Code, rec.Body.Bytes()
+	}
+	token := func(mintURL string, amounts ...uint64) string {
+		proofs := make([]cashu.Proof, len(amounts))
+		for i, amount := range amounts {
+			proofs[i] = cashu.Proof{Amount: amount, ID: "009a1f293253e41e", Secret: "s" + strconv.Itoa(i), C: "02ab"}
+		}
+		data, _ := json.Marshal(map[string]interface{}{"token": []map[string]interface{}{{"mint": mintURL, "proofs": proofs}}})
+		return "cashuA" + base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "CASHU-1", PaymentStatus: "pending"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	bolt11 := "lnbc10u1cashu"
+	if err := store.Payments().Create(&models.Payment{TicketID: ticket.ID, InvoiceID: "inv-cashu-1", Bolt11: bolt11,
+		PaymentHash: hex.EncodeToString(hash[:]), Amount: 1000, Status: "pending"}); err != nil {
+		t.Fatal(err)
+	}
+	exact := token(mint.URL, 512, 256, 128, 64, 32, 8, 2)
+
+	if status, _ := do("POST", "/api/payments/cashu", models.CashuPaymentRequest{Invoice: bolt11, Token: exact}); status != http.StatusForbidden {
+		t.Errorf("Expected 403 with the feature off, got %d", status)
+	}
+	if err := settings.Set("feature."+services.FeatureCashu, json.RawMessage("true"), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		req  models.CashuPaymentRequest
+		want int
+	}{
+		"no token":        {models.CashuPaymentRequest{Invoice: bolt11}, http.StatusBadRequest},
+		"unknown invoice": {models.CashuPaymentRequest{Invoice: "lnbc1other", Token: exact}, http.StatusNotFound},
+		"bad token":       {models.CashuPaymentRequest{Invoice: bolt11, Token: "cashuBxyz"}, http.StatusBadRequest},
+		"untrusted mint":  {models.CashuPaymentRequest{Invoice: bolt11, Token: token("https://other.example.com", 1002)}, http.StatusBadRequest},
+	} {
+		if status, body := do("POST", "/api/payments/cashu", tc.req); status != tc.want {
+			t.Errorf("%s: expected %d, got %d %s", name, tc.want, status, body)
+		}
+	}
+
+	status, body := do("POST", "/api/payments/cashu", models.CashuPaymentRequest{Invoice: bolt11, Token: token(mint.URL, 1000)})
+	var refused models.ErrorResponse
+	json.Unmarshal(body, &refused)
+	details, _ := json.Marshal(refused.Details)
+	if status != http.StatusBadRequest || refused.ErrorCode != models.ErrorCodeCashuAmount || string(details) != `{"required_sats":1002,"token_sats":1000}` {
+		t.Errorf("Expected the amount needed, got %d %s", status, body)
+	}
+
+	spent = true
+	status, body = do("POST", "/api/payments/cashu", models.CashuPaymentRequest{Invoice: bolt11, Token: exact})
+	json.Unmarshal(body, &refused)
+	if status != http.StatusBadRequest || refused.ErrorCode != models.ErrorCodeCashuMintRefused || !bytes.Contains(body, []byte("Token already spent.")) {
+		t.Errorf("Expected the mint's refusal, got %d %s", status, body)
+	}
+
+	spent = false
+	if status, body := do("POST", "/api/payments/cashu", models.CashuPaymentRequest{Invoice: bolt11, Token: exact}); status != http.StatusOK {
+		t.Fatalf("Expected 200 paying with an exact token, got %d %s", status, body)
+	}
+	if paid, err := store.Tickets().GetByID(ticket.ID); err != nil || paid.PaymentStatus != "paid" {
+		t.Errorf("Expected the ticket paid, got %+v (%v)", paid, err)
+	}
+	if status, _ := do("POST", "/api/payments/cashu", models.CashuPaymentRequest{Invoice: bolt11, Token: exact}); status != http.StatusConflict {
+		t.Errorf("Expected 409 paying a paid invoice, got %d", status)
+	}
+
+	status, body = do("GET", "/api/admin/cashu/redemptions?limit=1", nil)
+	var listed struct {
+		Data []models.CashuRedemption `json:"data"`
+	}
+	json.Unmarshal(body, &listed)
+	if status != http.StatusOK || len(listed.Data) != 1 || listed.Data[0].State != models.CashuPaid || bytes.Contains(body, []byte("cashuA")) {
+		t.Errorf("Expected the paid redemption listed without the token, got %d %s", status, body)
+	}
+}
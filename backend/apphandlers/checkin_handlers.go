@@ -0,0 +1,162 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+const (
+	// defaultCheckInInterval is the admission rate interval when none is
+	// asked for
+	defaultCheckInInterval = 5 * time.Minute
+	// checkInHeartbeat is how often an idle check-in stream sends a comment,
+	// so proxies and the server's write timeout do not close it
+	checkInHeartbeat = 15 * time.Second
+	// streamWriteMargin is the time left to write each stream message
+	streamWriteMargin = 10 * time.Second
+)
+
+type CheckInHandlers struct {
+	checkIns  *services.CheckInService
+	eventRepo repositories.EventRepository
+	logger    *slog.Logger
+}
+
+func NewCheckInHandlers(checkIns *services.CheckInService, eventRepo repositories.EventRepository, logger *slog.Logger) *CheckInHandlers {
+	return &CheckInHandlers{
+		checkIns:  checkIns,
+		eventRepo: eventRepo,
+		logger:    logger,
+	}
+}
+
+// HandleGetCheckIns returns an event's check-in dashboard: tickets admitted,
+// paid tickets still to arrive, admissions per interval (?interval=5m by
+// default) and the latest scans (admin only)
+func (h *CheckInHandlers) HandleGetCheckIns(w http.ResponseWriter, r *http.Request) {
+	eventID, interval, ok := h.dashboardRequest(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.checkIns.Stats(eventID, interval)
+	if err != nil {
+		h.logger.Error("Failed to build check-in stats", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch check-ins")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Check-ins retrieved successfully",
+		Data:    stats,
+	})
+}
+
+// HandleStreamCheckIns streams an event's check-in dashboard as server-sent
+// events: a "checkins" event with the stats on connect and after each scan,
+// and a comment every checkInHeartbeat while the door is quiet (admin only)
+func (h *CheckInHandlers) HandleStreamCheckIns(w http.ResponseWriter, r *http.Request) {
+	eventID, interval, ok := h.dashboardRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(message string) bool {
+		// The server's write timeout is shorter than a stream's life
+		_ = rc.SetWriteDeadline(time.Now().Add(checkInHeartbeat + streamWriteMargin))
+		if _, err := fmt.Fprint(w, message); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	heartbeat := time.NewTicker(checkInHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		changed, stop := h.checkIns.Subscribe(eventID)
+		stats, err := h.checkIns.Stats(eventID, interval)
+		if err != nil {
+			stop()
+			h.logger.Error("Failed to build check-in stats", "event_id", eventID, "error", err)
+			send("event: error\ndata: {\"error\":\"Failed to fetch check-ins\"}\n\n")
+			return
+		}
+		data, _ := json.Marshal(stats)
+		if !send("event: checkins\ndata: " + string(data) + "\n\n") {
+			stop()
+			return
+		}
+
+		if !h.awaitScan(r, changed, heartbeat.C, send) {
+			stop()
+			return
+		}
+		stop()
+	}
+}
+
+// awaitScan waits for the event's next scan, sending heartbeats meanwhile.
+// It reports false once the client is gone.
+func (h *CheckInHandlers) awaitScan(r *http.Request, changed <-chan struct{}, heartbeat <-chan time.Time, send func(string) bool) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-heartbeat:
+			if !send(": heartbeat\n\n") {
+				return false
+			}
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+// dashboardRequest reads the event and rate interval of a dashboard request,
+// writing the error response when they are not usable
+func (h *CheckInHandlers) dashboardRequest(w http.ResponseWriter, r *http.Request) (int, time.Duration, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return 0, 0, false
+	}
+
+	interval := defaultCheckInInterval
+	if raw := r.URL.Query().Get("interval"); raw != "" {
+		interval, err = time.ParseDuration(raw)
+		if err != nil || interval < time.Minute || interval > 24*time.Hour {
+			middleware.WriteError(w, http.StatusBadRequest, "interval must be a duration between 1m and 24h")
+			return 0, 0, false
+		}
+	}
+
+	if _, err := h.eventRepo.GetByID(eventID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			middleware.WriteError(w, http.StatusNotFound, "Event not found")
+			return 0, 0, false
+		}
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return 0, 0, false
+	}
+	return eventID, interval, true
+}
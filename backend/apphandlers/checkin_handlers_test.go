@@ -0,0 +1,117 @@
+package apphandlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestCheckInHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	checkIns := services.NewCheckInService(store.TicketScans(), store.Tickets(), clk, logger)
+	handler := NewCheckInHandlers(checkIns, store.Events(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/checkins", handler.HandleGetCheckIns).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/checkins/stream", handler.HandleStreamCheckIns).Methods("GET")
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(3 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []*models.Ticket
+	for i := 0; i < 2; i++ {
+		ticket := &models.Ticket{EventID: event.ID, UserID: i + 1, TicketCode: "DOOR-" + strconv.Itoa(i), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	door := httptest.NewRequest("POST", "/api/tickets/validate", nil)
+	recordScan(door, checkIns, event.ID, tickets[0], tickets[0].TicketCode, "")
+	recordScan(door, checkIns, event.ID, nil, "NOPE", "Ticket not found")
+
+	path := "/api/admin/events/" + strconv.Itoa(event.ID) + "/checkins"
+	for name, tt := range map[string]struct {
+		path string
+		want int
+	}{
+		"unknown event":  {"/api/admin/events/9999/checkins", http.StatusNotFound},
+		"bad interval":   {path + "?interval=soon", http.StatusBadRequest},
+		"short interval": {path + "?interval=10s", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", path+"?interval=15m", nil))
+	var result struct {
+		Data models.CheckInStats `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.Data.Admitted != 1 || result.Data.Remaining != 1 || result.Data.Interval != "15m0s" ||
+		len(result.Data.Rate) != 1 || len(result.Data.RecentScans) != 2 || result.Data.RecentScans[0].TicketID != nil {
+		t.Fatalf("Unexpected check-in stats %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The stream sends the stats on connect and again after each scan
+	server := httptest.NewServer(router)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+path+"/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() models.CheckInStats {
+		t.Helper()
+		var stats models.CheckInStats
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Stream ended: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				if err := json.Unmarshal([]byte(data), &stats); err != nil {
+					t.Fatal(err)
+				}
+				return stats
+			}
+		}
+	}
+	if stats := next(); stats.Admitted != 1 {
+		t.Errorf("Expected one admitted on connect, got %+v", stats)
+	}
+	recordScan(door, checkIns, event.ID, tickets[1], tickets[1].TicketCode, "")
+	if stats := next(); stats.Admitted != 2 || stats.Remaining != 0 {
+		t.Errorf("Expected the new admission streamed, got %+v", stats)
+	}
+}
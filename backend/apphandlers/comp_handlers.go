@@ -0,0 +1,67 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type CompHandlers struct {
+	comps  *services.CompService
+	logger *slog.Logger
+}
+
+func NewCompHandlers(comps *services.CompService, logger *slog.Logger) *CompHandlers {
+	return &CompHandlers{
+		comps:  comps,
+		logger: logger,
+	}
+}
+
+// HandleBulkIssueTickets issues comp tickets to a list of recipients and
+// emails them out, reporting each row's outcome (admin only)
+func (h *CompHandlers) HandleBulkIssueTickets(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.BulkTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.comps.Issue(eventID, req.Recipients, adminID(r))
+	var invalid *services.InvalidCompError
+	switch {
+	case errors.As(err, &invalid):
+		middleware.WriteError(w, http.StatusBadRequest, invalid.Reason)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, services.ErrCompEventCancelled):
+		middleware.WriteError(w, http.StatusConflict, "Event is cancelled")
+		return
+	case err != nil:
+		h.logger.Error("Failed to issue comp tickets", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to issue tickets")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Tickets issued",
+		Data:    result,
+	})
+}
@@ -0,0 +1,82 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestCompHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := services.NewLogNotifier(store.Users(), services.NewTemplateService(store.NotificationTemplates(), store.Events(), logger), logger)
+	guests := services.NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	comps := services.NewCompService(store.Users(), guests, store.Events(), guests.Tickets(store.Tickets()), notifier, logger)
+	handler := NewCompHandlers(comps, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/tickets/bulk", handler.HandleBulkIssueTickets).Methods("POST")
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/admin/events/" + strconv.Itoa(event.ID) + "/tickets/bulk"
+
+	do := func(path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", path, bytes.NewReader(payload)))
+		return rec
+	}
+
+	recipients := models.BulkTicketRequest{Recipients: []models.CompTicketRecipient{
+		{Email: "vip@example.com", Name: "VIP"},
+		{Email: "nobody"},
+	}}
+	for name, tt := range map[string]struct {
+		path string
+		body interface{}
+		want int
+	}{
+		"unknown event": {"/api/admin/events/9999/tickets/bulk", recipients, http.StatusNotFound},
+		"bad body":      {path, "recipients", http.StatusBadRequest},
+		"no recipients": {path, models.BulkTicketRequest{}, http.StatusBadRequest},
+	} {
+		if rec := do(tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d %s", name, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := do(path, recipients)
+	var result struct {
+		Data models.BulkTicketResult `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.Data.Issued != 1 || result.Data.Failed != 1 ||
+		result.Data.Results[0].TicketCode == "" || !result.Data.Results[0].Notified || result.Data.Results[1].Error != "invalid email format" {
+		t.Fatalf("Unexpected bulk result %d %s", rec.Code, rec.Body.String())
+	}
+
+	if err := store.Events().Cancel(event.ID, clk.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if rec := do(path, recipients); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a cancelled event refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// Console row caps: a view returns defaultConsoleRows rows unless ?limit=
+// asks for more, up to maxConsoleRows
+const (
+	defaultConsoleRows = 100
+	maxConsoleRows     = 1000
+)
+
+// ConsoleHandlers let support staff run the admin console's canned
+// read-only queries instead of asking engineers for SELECTs
+type ConsoleHandlers struct {
+	repo   repositories.ConsoleRepository
+	logger *slog.Logger
+}
+
+func NewConsoleHandlers(repo repositories.ConsoleRepository, logger *slog.Logger) *ConsoleHandlers {
+	return &ConsoleHandlers{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// HandleListViews lists the console views and the params each takes
+// (admin only)
+func (h *ConsoleHandlers) HandleListViews(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Console views retrieved successfully",
+		Data:    repositories.ConsoleViews(),
+	})
+}
+
+// HandleRunView runs a console view with its params from the query string,
+// e.g. /api/admin/console/attendees?event_id=7&limit=500, returning at most
+// ?limit= rows (default 100, at most 1000). Every run is audit logged with
+// its params (admin only).
+func (h *ConsoleHandlers) HandleRunView(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["view"]
+	var view *models.ConsoleView
+	for _, v := range repositories.ConsoleViews() {
+		if v.Name == name {
+			view = &v
+			break
+		}
+	}
+	if view == nil {
+		middleware.WriteError(w, http.StatusNotFound, "Console view not found")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultConsoleRows
+	if raw := query.Get("limit"); raw != "" {
+		l, err := strconv.Atoi(raw)
+		if err != nil || l < 1 || l > maxConsoleRows {
+			middleware.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = l
+	}
+
+	args := make([]interface{}, len(view.Params))
+	params := map[string]string{}
+	for i, param := range view.Params {
+		raw := query.Get(param.Name)
+		if raw == "" && param.Required {
+			middleware.WriteError(w, http.StatusBadRequest, param.Name+" is required")
+			return
+		}
+		params[param.Name] = raw
+		switch param.Type {
+		case models.ConsoleInt:
+			n := 0
+			if raw != "" {
+				parsed, err := strconv.Atoi(raw)
+				if err != nil {
+					middleware.WriteError(w, http.StatusBadRequest, param.Name+" must be an integer")
+					return
+				}
+				n = parsed
+			}
+			args[i] = n
+		default:
+			args[i] = raw
+		}
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	result, err := h.repo.Run(view.Name, args, limit)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Console view not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Console query failed", "audit", true, "admin_id", admin.ID, "view", view.Name, "params", params, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to run console view")
+		return
+	}
+	h.logger.Info("Console query", "audit", true,
+		"admin_id", admin.ID, "view", view.Name, "params", params, "rows", len(result.Rows), "truncated", result.Truncated)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Console view run successfully",
+		Data:    result,
+	})
+}
@@ -0,0 +1,104 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestConsoleHandlers(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewConsoleHandlers(store.Console(), logger)
+	admin := &models.User{ID: 1, Email: "admin@example.com"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/console", handler.HandleListViews).Methods("GET")
+	router.HandleFunc("/api/admin/console/{view:[a-z_]+}", handler.HandleRunView).Methods("GET")
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	user := &models.User{Email: "Buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Console Night", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), Capacity: 10}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		ticket := &models.Ticket{EventID: event.ID, UserID: user.ID, TicketCode: fmt.Sprintf("CONSOLE-%d", i), PaymentStatus: models.TicketPaid}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := get("/api/admin/console")
+	var views struct {
+		Data []models.ConsoleView `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&views); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if rec.Code != http.StatusOK || len(views.Data) != len(repositories.ConsoleViews()) {
+		t.Errorf("Expected the console views, got %d %+v", rec.Code, views.Data)
+	}
+
+	rec = get(fmt.Sprintf("/api/admin/console/attendees?event_id=%d&limit=2", event.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var result struct {
+		Data models.ConsoleResult `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if len(result.Data.Rows) != 2 || !result.Data.Truncated || result.Data.Rows[0]["ticket_code"] != "CONSOLE-0" {
+		t.Errorf("Expected the first 2 of 3 attendees, truncated, got %+v", result.Data)
+	}
+
+	rec = get("/api/admin/console/user_lookup?email=buyer@EXAMPLE.com")
+	result.Data = models.ConsoleResult{}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if rec.Code != http.StatusOK || len(result.Data.Rows) != 1 || result.Data.Rows[0]["paid_tickets"] != float64(3) {
+		t.Errorf("Expected the buyer with 3 paid tickets, got %d %+v", rec.Code, result.Data)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/admin/console/attendees", http.StatusBadRequest},
+		{"/api/admin/console/attendees?event_id=abc", http.StatusBadRequest},
+		{"/api/admin/console/attendees?event_id=1&limit=1001", http.StatusBadRequest},
+		{"/api/admin/console/attendees?event_id=1&limit=0", http.StatusBadRequest},
+		{"/api/admin/console/users", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := get(tt.path); rec.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+}
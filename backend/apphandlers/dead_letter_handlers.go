@@ -0,0 +1,102 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// DeadLetterHandlers let admins resolve async work that ran out of retries.
+// Payment webhooks are the only queued work; notifications are sent inline
+// and failed event cancellations are rerun by cancelling again.
+type DeadLetterHandlers struct {
+	queue  *services.WebhookQueue
+	logger *slog.Logger
+}
+
+func NewDeadLetterHandlers(queue *services.WebhookQueue, logger *slog.Logger) *DeadLetterHandlers {
+	return &DeadLetterHandlers{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// HandleListDeadLetters lists the webhook events the queue gave up on, with
+// their attempts and last error, most recent first (admin only)
+func (h *DeadLetterHandlers) HandleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	events, err := h.queue.DeadLetters()
+	if err != nil {
+		h.logger.Error("Failed to list dead letters", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to list dead letters")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Dead letters retrieved successfully",
+		Data:    events,
+	})
+}
+
+// HandleRetryDeadLetter puts a dead-lettered webhook event back in the
+// queue with a fresh set of attempts (admin only)
+func (h *DeadLetterHandlers) HandleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	err = h.queue.Retry(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to retry dead letter", "id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to retry dead letter")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Dead letter requeued", "audit", true, "admin_id", admin.ID, "id", id)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Dead letter requeued successfully",
+		Data:    map[string]interface{}{"id": id},
+	})
+}
+
+// HandleDeleteDeadLetter discards a dead-lettered webhook event (admin only)
+func (h *DeadLetterHandlers) HandleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	err = h.queue.Discard(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete dead letter", "id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete dead letter")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Dead letter deleted", "audit", true, "admin_id", admin.ID, "id", id)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Dead letter deleted successfully",
+	})
+}
@@ -0,0 +1,109 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestDeadLetterHandlers(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := repositories.NewMemoryStore(clk).WebhookEvents()
+	handler := NewDeadLetterHandlers(services.NewWebhookQueue(repo, 1, 1, clk, logger), logger)
+	admin := &models.User{ID: 1, Email: "admin@example.com"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/dead-letters", handler.HandleListDeadLetters).Methods("GET")
+	router.HandleFunc("/api/admin/dead-letters/{id:[0-9]+}/retry", handler.HandleRetryDeadLetter).Methods("POST")
+	router.HandleFunc("/api/admin/dead-letters/{id:[0-9]+}", handler.HandleDeleteDeadLetter).Methods("DELETE")
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	listed := func() []models.WebhookEvent {
+		rec := send("GET", "/api/admin/dead-letters")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 listing dead letters, got %d", rec.Code)
+		}
+		var resp struct {
+			Data []models.WebhookEvent `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal("Failed to decode response:", err)
+		}
+		return resp.Data
+	}
+
+	var ids []int
+	for _, eventID := range []string{"evt-1", "evt-2"} {
+		event := &models.WebhookEvent{Source: models.WebhookSourceLightspark, EventID: eventID, EventType: "PAYMENT_FINISHED", EntityID: "payment-" + eventID, Payload: "{}"}
+		if err := repo.Enqueue(event); err != nil {
+			t.Fatal("Failed to enqueue event:", err)
+		}
+		ids = append(ids, event.ID)
+	}
+	if _, err := repo.Claim(10, time.Minute); err != nil {
+		t.Fatal("Failed to claim events:", err)
+	}
+	if err := repo.Fail(ids[0], "lightspark unavailable", nil); err != nil {
+		t.Fatal("Failed to fail event:", err)
+	}
+	if err := repo.Complete(ids[1]); err != nil {
+		t.Fatal("Failed to complete event:", err)
+	}
+
+	dead := listed()
+	if len(dead) != 1 || dead[0].ID != ids[0] || dead[0].LastError != "lightspark unavailable" {
+		t.Fatalf("Expected the failed event as the only dead letter, got %+v", dead)
+	}
+
+	// Only dead letters can be retried or deleted
+	if rec := send("POST", fmt.Sprintf("/api/admin/dead-letters/%d/retry", ids[1])); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 retrying a completed event, got %d", rec.Code)
+	}
+	if rec := send("DELETE", fmt.Sprintf("/api/admin/dead-letters/%d", ids[1])); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting a completed event, got %d", rec.Code)
+	}
+
+	if rec := send("POST", fmt.Sprintf("/api/admin/dead-letters/%d/retry", ids[0])); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 retrying a dead letter, got %d", rec.Code)
+	}
+	if dead := listed(); len(dead) != 0 {
+		t.Errorf("Expected the retried event to leave the dead letters, got %+v", dead)
+	}
+	if backlog, _ := repo.Backlog(); backlog.Pending != 1 {
+		t.Errorf("Expected the retried event to be pending, got %+v", backlog)
+	}
+
+	claimed, _ := repo.Claim(10, time.Minute)
+	if len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Fatalf("Expected the retried event to be claimed with fresh attempts, got %+v", claimed)
+	}
+	if err := repo.Fail(ids[0], "still unavailable", nil); err != nil {
+		t.Fatal("Failed to fail event:", err)
+	}
+	if rec := send("DELETE", fmt.Sprintf("/api/admin/dead-letters/%d", ids[0])); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 deleting a dead letter, got %d", rec.Code)
+	}
+	if dead := listed(); len(dead) != 0 {
+		t.Errorf("Expected no dead letters after deleting, got %+v", dead)
+	}
+}
@@ -0,0 +1,64 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+)
+
+type DebugHandlers struct {
+	capture *middleware.RequestCapture
+	logger  *slog.Logger
+}
+
+func NewDebugHandlers(capture *middleware.RequestCapture, logger *slog.Logger) *DebugHandlers {
+	return &DebugHandlers{
+		capture: capture,
+		logger:  logger,
+	}
+}
+
+// HandleListCapturedRequests lists the captured failed requests, newest
+// first, optionally filtered by ?status= (admin only)
+func (h *DebugHandlers) HandleListCapturedRequests(w http.ResponseWriter, r *http.Request) {
+	status := 0
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid status")
+			return
+		}
+		status = parsed
+	}
+
+	entries := h.capture.Entries()
+	if status != 0 {
+		filtered := entries[:0]
+		for _, entry := range entries {
+			if entry.Status == status {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = filtered
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Captured requests retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// HandleClearCapturedRequests empties the capture buffer (admin only)
+func (h *DebugHandlers) HandleClearCapturedRequests(w http.ResponseWriter, r *http.Request) {
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Clearing captured requests", "admin_id", admin.ID)
+
+	h.capture.Clear()
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Captured requests cleared successfully",
+	})
+}
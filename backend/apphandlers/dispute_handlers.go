@@ -0,0 +1,190 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type DisputeHandlers struct {
+	disputes    *services.DisputeService
+	disputeRepo repositories.DisputeRepository
+	logger      *slog.Logger
+}
+
+func NewDisputeHandlers(disputes *services.DisputeService, disputeRepo repositories.DisputeRepository, logger *slog.Logger) *DisputeHandlers {
+	return &DisputeHandlers{
+		disputes:    disputes,
+		disputeRepo: disputeRepo,
+		logger:      logger,
+	}
+}
+
+// HandleOpenDispute opens a dispute on a paid payment and suspends its
+// ticket (admin only)
+func (h *DisputeHandlers) HandleOpenDispute(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var req models.OpenDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	dispute, err := h.disputes.Open(paymentID, req.Reason, adminID(r))
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+		return
+	case errors.Is(err, services.ErrNotDisputable):
+		middleware.WriteError(w, http.StatusConflict, "Only paid payments can be disputed")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Payment already has an open dispute")
+		return
+	case err != nil && dispute == nil:
+		h.logger.Error("Failed to open dispute", "payment_id", paymentID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to open dispute")
+		return
+	case err != nil:
+		h.logger.Error("Dispute opened with errors", "dispute_id", dispute.ID, "error", err)
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Dispute opened successfully",
+		Data:    dispute,
+	})
+}
+
+// HandleListDisputes lists disputes newest first, optionally filtered by
+// status (admin only)
+func (h *DisputeHandlers) HandleListDisputes(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DisputeOpen, models.DisputeWon, models.DisputeLost:
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "status must be open, won or lost")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	disputes, err := h.disputeRepo.List(status, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch disputes", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch disputes")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Disputes retrieved successfully",
+		Data:    disputes,
+	})
+}
+
+// HandleGetDispute returns a dispute (admin only)
+func (h *DisputeHandlers) HandleGetDispute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid dispute ID")
+		return
+	}
+
+	dispute, err := h.disputeRepo.GetByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Dispute not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch dispute", "dispute_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch dispute")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Dispute retrieved successfully",
+		Data:    dispute,
+	})
+}
+
+// HandleResolveDispute closes an open dispute. A won dispute reinstates the
+// ticket; a lost one cancels it and reverses the sale (admin only)
+func (h *DisputeHandlers) HandleResolveDispute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid dispute ID")
+		return
+	}
+
+	var req models.ResolveDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Outcome != models.DisputeWon && req.Outcome != models.DisputeLost {
+		middleware.WriteError(w, http.StatusBadRequest, "outcome must be won or lost")
+		return
+	}
+
+	dispute, err := h.disputes.Resolve(id, req.Outcome, strings.TrimSpace(req.Resolution), adminID(r))
+	switch {
+	case errors.Is(err, repositories.ErrNotFound) && dispute == nil:
+		middleware.WriteError(w, http.StatusNotFound, "Dispute not found")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Dispute is already resolved")
+		return
+	case err != nil && dispute == nil:
+		h.logger.Error("Failed to resolve dispute", "dispute_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to resolve dispute")
+		return
+	case err != nil:
+		h.logger.Error("Dispute resolved with errors", "dispute_id", id, "outcome", req.Outcome, "error", err)
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Dispute resolved successfully",
+		Data:    dispute,
+	})
+}
+
+// adminID returns the ID of the signed-in user making the request, if any
+func adminID(r *http.Request) *int {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		return nil
+	}
+	return &user.ID
+}
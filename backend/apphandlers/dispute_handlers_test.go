@@ -0,0 +1,132 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestDisputeHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &recordingNotifier{subjects: make(map[int][]string)}
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	disputes := services.NewDisputeService(store.Disputes(), store.Payments(), store.Tickets(), ledger, notifier, logger)
+	handler := NewDisputeHandlers(disputes, store.Disputes(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/payments/{id:[0-9]+}/disputes", handler.HandleOpenDispute).Methods("POST")
+	router.HandleFunc("/api/admin/disputes", handler.HandleListDisputes).Methods("GET")
+	router.HandleFunc("/api/admin/disputes/{id:[0-9]+}", handler.HandleGetDispute).Methods("GET")
+	router.HandleFunc("/api/admin/disputes/{id:[0-9]+}/resolve", handler.HandleResolveDispute).Methods("POST")
+
+	admin := &models.User{ID: 42}
+	do := func(method, path string, body interface{}) (int, *models.Dispute) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data *models.Dispute `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var payments []*models.Payment
+	for i, status := range []string{"paid", "pending"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: 7, TicketCode: "DISPUTE-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-dispute-" + strconv.Itoa(i), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+	paid := "/api/admin/payments/" + strconv.Itoa(payments[0].ID) + "/disputes"
+
+	if status, _ := do("POST", paid, map[string]string{"reason": " "}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a reason, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/payments/99/disputes", map[string]string{"reason": "Reversed"}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown payment, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/payments/"+strconv.Itoa(payments[1].ID)+"/disputes", map[string]string{"reason": "Reversed"}); status != http.StatusConflict {
+		t.Errorf("Expected 409 for an unpaid payment, got %d", status)
+	}
+
+	status, dispute := do("POST", paid, map[string]string{"reason": "Funds clawed back"})
+	if status != http.StatusCreated || dispute == nil || dispute.Status != models.DisputeOpen || dispute.OpenedBy == nil || *dispute.OpenedBy != admin.ID {
+		t.Fatalf("Expected an open dispute, got %d %+v", status, dispute)
+	}
+	if status, _ := do("POST", paid, map[string]string{"reason": "Again"}); status != http.StatusConflict {
+		t.Errorf("Expected 409 for a second open dispute, got %d", status)
+	}
+	if len(notifier.subjects[7]) != 1 {
+		t.Errorf("Expected the buyer to be notified, got %v", notifier.subjects)
+	}
+
+	resolve := "/api/admin/disputes/" + strconv.Itoa(dispute.ID) + "/resolve"
+	if status, _ := do("POST", resolve, map[string]string{"outcome": "open"}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid outcome, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/disputes/99/resolve", map[string]string{"outcome": "won"}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown dispute, got %d", status)
+	}
+	status, resolved := do("POST", resolve, map[string]string{"outcome": "lost", "resolution": "Clawback confirmed"})
+	if status != http.StatusOK || resolved == nil || resolved.Status != models.DisputeLost || resolved.ResolvedBy == nil || *resolved.ResolvedBy != admin.ID {
+		t.Fatalf("Expected a lost dispute, got %d %+v", status, resolved)
+	}
+	if status, _ := do("POST", resolve, map[string]string{"outcome": "won"}); status != http.StatusConflict {
+		t.Errorf("Expected 409 resolving twice, got %d", status)
+	}
+
+	if status, got := do("GET", "/api/admin/disputes/"+strconv.Itoa(dispute.ID), nil); status != http.StatusOK || got.Resolution != "Clawback confirmed" {
+		t.Errorf("Unexpected dispute %d %+v", status, got)
+	}
+	if status, _ := do("GET", "/api/admin/disputes/99", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown dispute, got %d", status)
+	}
+	if status, _ := do("GET", "/api/admin/disputes?status=closed", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid status filter, got %d", status)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/disputes?status=lost", nil))
+	var list struct {
+		Data []models.Dispute `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK || len(list.Data) != 1 {
+		t.Errorf("Expected one lost dispute, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,334 @@
+package apphandlers
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// EventAccessHandlers manages the invite codes and allowlists of private
+// events (admin only)
+type EventAccessHandlers struct {
+	accessRepo repositories.EventAccessRepository
+	eventRepo  repositories.EventRepository
+	clock      clock.Clock
+	logger     *slog.Logger
+}
+
+func NewEventAccessHandlers(
+	accessRepo repositories.EventAccessRepository,
+	eventRepo repositories.EventRepository,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *EventAccessHandlers {
+	return &EventAccessHandlers{
+		accessRepo: accessRepo,
+		eventRepo:  eventRepo,
+		clock:      clk,
+		logger:     logger,
+	}
+}
+
+// HandleListAccessCodes lists an event's invite codes with their use counts
+func (h *EventAccessHandlers) HandleListAccessCodes(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.accessRepo.GetCodes(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch access codes", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch access codes")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Access codes retrieved successfully",
+		Data:    codes,
+	})
+}
+
+// HandleCreateAccessCode adds an invite code to an event, generating one
+// when the request does not name it
+func (h *EventAccessHandlers) HandleCreateAccessCode(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAccessCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	code := &models.EventAccessCode{
+		EventID:   event.ID,
+		Code:      normalizeAccessCode(req.Code),
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if code.Code == "" {
+		generated, err := generateAccessCode()
+		if err != nil {
+			h.logger.Error("Failed to generate access code", "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate access code")
+			return
+		}
+		code.Code = generated
+	}
+	if err := validateAccessCode(code.Code); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if code.MaxUses != nil && *code.MaxUses <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "max uses must be greater than 0")
+		return
+	}
+	if code.ExpiresAt != nil && !code.ExpiresAt.After(h.clock.Now()) {
+		middleware.WriteError(w, http.StatusBadRequest, "expiry must be in the future")
+		return
+	}
+
+	err := h.accessRepo.CreateCode(code)
+	if errors.Is(err, repositories.ErrConflict) {
+		middleware.WriteError(w, http.StatusConflict, "Event already has this access code")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create access code", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create access code")
+		return
+	}
+
+	h.logger.Info("Access code created", "code_id", code.ID, "event_id", event.ID)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Access code created successfully",
+		Data:    code,
+	})
+}
+
+// HandleDeleteAccessCode revokes an invite code; tickets already bought
+// with it are kept
+func (h *EventAccessHandlers) HandleDeleteAccessCode(w http.ResponseWriter, r *http.Request) {
+	codeID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid access code ID")
+		return
+	}
+
+	code, err := h.accessRepo.GetCode(codeID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Access code not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch access code", "code_id", codeID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch access code")
+		return
+	}
+
+	if err := h.accessRepo.DeleteCode(code.ID); err != nil {
+		h.logger.Error("Failed to delete access code", "code_id", code.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete access code")
+		return
+	}
+
+	h.logger.Info("Access code deleted", "code_id", code.ID, "event_id", code.EventID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Access code deleted successfully",
+	})
+}
+
+// HandleGetAllowlist lists the emails and UMA addresses allowed to buy
+// tickets for an event
+func (h *EventAccessHandlers) HandleGetAllowlist(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.accessRepo.GetAllowlist(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch allowlist", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch allowlist")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Allowlist retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// maxAllowlistBatch caps how many entries one request can add
+const maxAllowlistBatch = 500
+
+// HandleAddToAllowlist adds emails and UMA addresses to an event's
+// allowlist and returns the whole list
+func (h *EventAccessHandlers) HandleAddToAllowlist(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AllowlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Entries) == 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "At least one entry is required")
+		return
+	}
+	if len(req.Entries) > maxAllowlistBatch {
+		middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("At most %d entries can be added at once", maxAllowlistBatch))
+		return
+	}
+
+	entries := make([]string, 0, len(req.Entries))
+	for _, entry := range req.Entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if err := validateAllowlistEntry(entry); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := h.accessRepo.AddToAllowlist(event.ID, entries); err != nil {
+		h.logger.Error("Failed to add to allowlist", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update allowlist")
+		return
+	}
+	allowlist, err := h.accessRepo.GetAllowlist(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch allowlist", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch allowlist")
+		return
+	}
+
+	h.logger.Info("Allowlist updated", "event_id", event.ID, "entries", len(allowlist))
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Allowlist updated successfully",
+		Data:    allowlist,
+	})
+}
+
+// HandleDeleteAllowlistEntry removes an email or UMA address from an
+// event's allowlist
+func (h *EventAccessHandlers) HandleDeleteAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid allowlist entry ID")
+		return
+	}
+
+	entry, err := h.accessRepo.GetAllowlistEntry(entryID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Allowlist entry not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch allowlist entry", "entry_id", entryID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch allowlist entry")
+		return
+	}
+
+	if err := h.accessRepo.DeleteAllowlistEntry(entry.ID); err != nil {
+		h.logger.Error("Failed to delete allowlist entry", "entry_id", entry.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete allowlist entry")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Allowlist entry deleted successfully",
+	})
+}
+
+// event loads the event named in the route, writing the error response
+// itself when it is missing.
+func (h *EventAccessHandlers) event(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return nil, false
+	}
+
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, false
+	}
+	return event, true
+}
+
+// accessCodeAlphabet is Crockford's base32 alphabet, which leaves out
+// letters easily misread for digits
+const accessCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// generateAccessCode returns a random eight character invite code
+func generateAccessCode() (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	for i, b := range random {
+		random[i] = accessCodeAlphabet[b&31]
+	}
+	return string(random), nil
+}
+
+// normalizeAccessCode makes codes case-insensitive for buyers typing them in
+func normalizeAccessCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateAccessCode(code string) error {
+	if len(code) < 4 || len(code) > 32 {
+		return fmt.Errorf("access code must be 4 to 32 characters")
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+			return fmt.Errorf("access code may only contain letters, digits and dashes")
+		}
+	}
+	return nil
+}
+
+// validateAllowlistEntry checks an allowlist entry looks like an email or a
+// UMA address ($user@domain)
+func validateAllowlistEntry(entry string) error {
+	if entry == "" {
+		return fmt.Errorf("allowlist entries cannot be blank")
+	}
+	if len(entry) > 255 {
+		return fmt.Errorf("allowlist entries must be at most 255 characters")
+	}
+	local, domain, ok := strings.Cut(strings.TrimPrefix(entry, "$"), "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(entry, " \t,;") {
+		return fmt.Errorf("%q is not an email or UMA address", entry)
+	}
+	return nil
+}
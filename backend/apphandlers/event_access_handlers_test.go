@@ -0,0 +1,200 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// failingTicketRepository fails to create tickets while fail is set
+type failingTicketRepository struct {
+	repositories.TicketRepository
+	fail bool
+}
+
+func (r *failingTicketRepository) Create(ticket *models.Ticket) error {
+	if r.fail {
+		return errors.New("insert failed")
+	}
+	return r.TicketRepository.Create(ticket)
+}
+
+func TestPrivateEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	access := NewEventAccessHandlers(store.EventAccess(), store.Events(), clk, logger)
+	events := &EventHandlers{eventRepo: store.Events(), ticketRepo: store.Tickets(), translationRepo: store.EventTranslations(), relationRepo: store.EventRelations(), clock: clk, logger: logger}
+	ticketRepo := &failingTicketRepository{TicketRepository: store.Tickets()}
+	tickets := NewTicketHandlers(ticketRepo, store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events", events.HandleGetEvents).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/access-codes", access.HandleListAccessCodes).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/access-codes", access.HandleCreateAccessCode).Methods("POST")
+	router.HandleFunc("/api/admin/access-codes/{id:[0-9]+}", access.HandleDeleteAccessCode).Methods("DELETE")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/allowlist", access.HandleGetAllowlist).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/allowlist", access.HandleAddToAllowlist).Methods("POST")
+	router.HandleFunc("/api/admin/allowlist/{id:[0-9]+}", access.HandleDeleteAllowlistEntry).Methods("DELETE")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	public := &models.Event{Title: "Open House", Capacity: 10, IsActive: true}
+	private := &models.Event{Title: "Launch Party", Capacity: 10, IsActive: true, IsPrivate: true}
+	for _, event := range []*models.Event{public, private} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eventPath := "/api/admin/events/" + strconv.Itoa(private.ID)
+
+	_, data := do("GET", "/api/events", nil)
+	var listed []struct {
+		ID int `json:"id"`
+	}
+	json.Unmarshal(data, &listed)
+	if len(listed) != 1 || listed[0].ID != public.ID {
+		t.Errorf("Expected only the public event to be listed, got %s", data)
+	}
+
+	// Codes
+	for name, req := range map[string]models.CreateAccessCodeRequest{
+		"too short":    {Code: "ab"},
+		"punctuation":  {Code: "VIP!"},
+		"zero uses":    {Code: "VIP1", MaxUses: new(int)},
+		"already over": {Code: "VIP2", ExpiresAt: &time.Time{}},
+	} {
+		if status, _ := do("POST", eventPath+"/access-codes", req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+	one := 1
+	status, data := do("POST", eventPath+"/access-codes", models.CreateAccessCodeRequest{Code: " launch-vip ", MaxUses: &one})
+	var code models.EventAccessCode
+	json.Unmarshal(data, &code)
+	if status != http.StatusCreated || code.Code != "LAUNCH-VIP" {
+		t.Fatalf("Expected the code to be created uppercased, got %d %s", status, data)
+	}
+	if status, _ := do("POST", eventPath+"/access-codes", models.CreateAccessCodeRequest{Code: "launch-vip"}); status != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate code, got %d", status)
+	}
+	status, data = do("POST", eventPath+"/access-codes", models.CreateAccessCodeRequest{})
+	var generated models.EventAccessCode
+	json.Unmarshal(data, &generated)
+	if status != http.StatusCreated || validateAccessCode(generated.Code) != nil || len(generated.Code) != 8 {
+		t.Fatalf("Expected a generated code, got %d %s", status, data)
+	}
+
+	// Allowlist
+	if status, _ := do("POST", eventPath+"/allowlist", models.AllowlistRequest{Entries: []string{"not an address"}}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed entry, got %d", status)
+	}
+	guest := &models.User{Email: "guest@example.com", Name: "Guest"}
+	stranger := &models.User{Email: "stranger@example.com", Name: "Stranger"}
+	for _, user := range []*models.User{guest, stranger} {
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, data = do("POST", eventPath+"/allowlist", models.AllowlistRequest{Entries: []string{" Guest@Example.com", "$plusone@wallet.example.com"}})
+	var allowlist []models.EventAllowlistEntry
+	json.Unmarshal(data, &allowlist)
+	if status != http.StatusOK || len(allowlist) != 2 {
+		t.Fatalf("Expected two allowlist entries, got %d %s", status, data)
+	}
+
+	purchase := func(eventID int, user *models.User, umaAddress, code string) int {
+		status, _ := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: eventID, UserID: user.ID, UMAAddress: umaAddress, AccessCode: code,
+		})
+		return status
+	}
+
+	if status := purchase(public.ID, stranger, "$stranger@wallet.example.com", ""); status != http.StatusCreated {
+		t.Errorf("Expected public events to stay open, got %d", status)
+	}
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", ""); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without an invitation, got %d", status)
+	}
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", "WRONG"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for an unknown code, got %d", status)
+	}
+	if status := purchase(private.ID, guest, "$guest@wallet.example.com", ""); status != http.StatusCreated {
+		t.Errorf("Expected an allowlisted email to buy, got %d", status)
+	}
+	if status := purchase(private.ID, stranger, "$PlusOne@wallet.example.com", ""); status != http.StatusCreated {
+		t.Errorf("Expected an allowlisted UMA address to buy, got %d", status)
+	}
+
+	// A purchase that fails to store its ticket keeps neither the code's
+	// use nor its order
+	orders, _ := store.Orders().GetByUserID(stranger.ID)
+	ticketRepo.fail = true
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", "launch-vip"); status != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when the ticket cannot be stored, got %d", status)
+	}
+	ticketRepo.fail = false
+	if after, _ := store.Orders().GetByUserID(stranger.ID); len(after) != len(orders) {
+		t.Errorf("Expected no order left without a ticket, got %d orders, had %d", len(after), len(orders))
+	}
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", "launch-vip"); status != http.StatusCreated {
+		t.Errorf("Expected the access code to admit the buyer, got %d", status)
+	}
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", "LAUNCH-VIP"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 once the code is used up, got %d", status)
+	}
+
+	_, data = do("GET", eventPath+"/access-codes", nil)
+	var codes []models.EventAccessCode
+	json.Unmarshal(data, &codes)
+	if len(codes) != 2 || codes[0].Uses != 1 {
+		t.Errorf("Expected the used code to count one use, got %s", data)
+	}
+
+	// Revoked codes and removed entries stop working
+	if status, _ := do("DELETE", "/api/admin/access-codes/"+strconv.Itoa(generated.ID), nil); status != http.StatusOK {
+		t.Fatalf("Expected 200 deleting a code, got %d", status)
+	}
+	if status := purchase(private.ID, stranger, "$stranger@wallet.example.com", generated.Code); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a revoked code, got %d", status)
+	}
+	for _, entry := range allowlist {
+		if status, _ := do("DELETE", "/api/admin/allowlist/"+strconv.Itoa(entry.ID), nil); status != http.StatusOK {
+			t.Fatalf("Expected 200 deleting an allowlist entry, got %d", status)
+		}
+	}
+	if status, _ := do("DELETE", "/api/admin/allowlist/"+strconv.Itoa(allowlist[0].ID), nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted entry, got %d", status)
+	}
+	if status := purchase(private.ID, guest, "$guest@wallet.example.com", ""); status != http.StatusForbidden {
+		t.Errorf("Expected 403 once removed from the allowlist, got %d", status)
+	}
+}
@@ -2,15 +2,20 @@ package apphandlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"tickets-by-uma/clock"
 	"tickets-by-uma/config"
+	"tickets-by-uma/listquery"
 	"tickets-by-uma/middleware"
 	"tickets-by-uma/models"
 	"tickets-by-uma/repositories"
@@ -18,13 +23,20 @@ import (
 )
 
 type EventHandlers struct {
-	eventRepo   repositories.EventRepository
-	paymentRepo repositories.PaymentRepository
-	ticketRepo  repositories.TicketRepository
-	umaService  services.UMAService
-	umaRepo     repositories.UMARequestInvoiceRepository
-	logger      *slog.Logger
-	config      *config.Config
+	eventRepo       repositories.EventRepository
+	paymentRepo     repositories.PaymentRepository
+	ticketRepo      repositories.TicketRepository
+	umaService      services.UMAService
+	umaRepo         repositories.UMARequestInvoiceRepository
+	translationRepo repositories.EventTranslationRepository
+	relationRepo    repositories.EventRelationRepository
+	profileRepo     repositories.OrganizerProfileRepository
+	capacity        *services.CapacityService
+	invoices        *services.EventInvoiceService
+	priceChanges    *services.PriceChangeService
+	clock           clock.Clock
+	logger          *slog.Logger
+	config          *config.Config
 }
 
 func NewEventHandlers(
@@ -33,41 +45,59 @@ func NewEventHandlers(
 	ticketRepo repositories.TicketRepository,
 	umaService services.UMAService,
 	umaRepo repositories.UMARequestInvoiceRepository,
+	translationRepo repositories.EventTranslationRepository,
+	relationRepo repositories.EventRelationRepository,
+	profileRepo repositories.OrganizerProfileRepository,
+	capacity *services.CapacityService,
+	invoices *services.EventInvoiceService,
+	priceChanges *services.PriceChangeService,
+	clk clock.Clock,
 	logger *slog.Logger,
 	config *config.Config,
 ) *EventHandlers {
 	return &EventHandlers{
-		eventRepo:   eventRepo,
-		paymentRepo: paymentRepo,
-		ticketRepo:  ticketRepo,
-		umaService:  umaService,
-		umaRepo:     umaRepo,
-		logger:      logger,
-		config:      config,
+		eventRepo:       eventRepo,
+		paymentRepo:     paymentRepo,
+		ticketRepo:      ticketRepo,
+		umaService:      umaService,
+		umaRepo:         umaRepo,
+		translationRepo: translationRepo,
+		relationRepo:    relationRepo,
+		profileRepo:     profileRepo,
+		capacity:        capacity,
+		invoices:        invoices,
+		priceChanges:    priceChanges,
+		clock:           clk,
+		logger:          logger,
+		config:          config,
 	}
 }
 
-// HandleGetEvents lists all active events
+// parseListQuery reads a list endpoint's sort, filter and paging
+// parameters against fields, answering 400 when they do not fit
+func parseListQuery(w http.ResponseWriter, r *http.Request, fields listquery.Fields) (listquery.Query, bool) {
+	list, err := listquery.Parse(r.URL.Query(), fields)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid list parameters: "+err.Error())
+		return listquery.Query{}, false
+	}
+	return list, true
+}
+
+// HandleGetEvents lists active events, sorted and filtered on
+// repositories.EventListFields
 func (h *EventHandlers) HandleGetEvents(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Fetching events")
 
-	// Parse query parameters
-	limit := 50
-	offset := 0
-
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	list, ok := parseListQuery(w, r, repositories.EventListFields)
+	if !ok {
+		return
 	}
-
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	if list.Limit == 0 {
+		list.Limit = 50
 	}
 
-	events, err := h.eventRepo.GetActive(limit, offset)
+	events, err := h.eventRepo.ListActive(list)
 	if err != nil {
 		h.logger.Error("Failed to fetch events", "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch events")
@@ -80,21 +110,35 @@ func (h *EventHandlers) HandleGetEvents(w http.ResponseWriter, r *http.Request)
 		currentUser = user
 	}
 
-	// Enrich events with user ticket status
-	enrichedEvents := make([]map[string]interface{}, 0, len(events))
+	// Enrich events with user ticket status, streaming each one as it is ready
+	stream := middleware.NewJSONListStream(w, http.StatusOK, "Events retrieved successfully")
 	for _, event := range events {
+		h.localize(w, &event)
 		enrichedEvent := map[string]interface{}{
-			"id":          event.ID,
-			"title":       event.Title,
-			"description": event.Description,
-			"start_time":  event.StartTime,
-			"end_time":    event.EndTime,
-			"capacity":    event.Capacity,
-			"price_sats":  event.PriceSats,
-			"stream_url":  event.StreamURL,
-			"is_active":   event.IsActive,
-			"created_at":  event.CreatedAt,
-			"updated_at":  event.UpdatedAt,
+			"id":                     event.ID,
+			"title":                  event.Title,
+			"description":            event.Description,
+			"start_time":             event.StartTime,
+			"end_time":               event.EndTime,
+			"capacity":               event.Capacity,
+			"price_sats":             event.PriceSats,
+			"pricing_mode":           event.PricingMode,
+			"min_price_sats":         event.MinPriceSats,
+			"stream_url":             event.StreamURL,
+			"tax_basis_points":       event.TaxBasisPoints,
+			"tax_inclusive":          event.TaxInclusive,
+			"tax_jurisdiction":       event.TaxJurisdiction,
+			"is_private":             event.IsPrivate,
+			"min_age":                event.MinAge,
+			"terms_version":          event.TermsVersion,
+			"terms_url":              event.TermsURL,
+			"category":               event.Category,
+			"invoice_expiry_seconds": event.InvoiceExpirySeconds,
+			"one_ticket_per_user":    event.OneTicketPerUser,
+			"is_active":              event.IsActive,
+			"cancelled_at":           event.CancelledAt,
+			"created_at":             event.CreatedAt,
+			"updated_at":             event.UpdatedAt,
 		}
 
 		// Add UMA invoice information if available
@@ -124,12 +168,40 @@ func (h *EventHandlers) HandleGetEvents(w http.ResponseWriter, r *http.Request)
 			enrichedEvent["user_has_ticket"] = false
 		}
 
-		enrichedEvents = append(enrichedEvents, enrichedEvent)
+		if err := stream.Encode(enrichedEvent); err != nil {
+			h.logger.Error("Failed to write events", "error", err)
+			return
+		}
 	}
+	stream.Close()
+}
 
+// HandleGetEventAvailability returns live capacity, sold, pending and remaining
+// counts so buyers see the server's view of inventory rather than a stale one
+func (h *EventHandlers) HandleGetEventAvailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	availability, err := h.eventRepo.GetAvailability(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event availability", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event availability")
+		return
+	}
+
+	// Counts change with every purchase; never serve them from a cache
+	w.Header().Set("Cache-Control", "no-store")
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
-		Message: "Events retrieved successfully",
-		Data:    enrichedEvents,
+		Message: "Event availability retrieved successfully",
+		Data:    availability,
 	})
 }
 
@@ -146,36 +218,52 @@ func (h *EventHandlers) HandleGetEvent(w http.ResponseWriter, r *http.Request) {
 
 	// Use GetByIDWithUMAInvoice to include UMA invoice data that the frontend expects
 	event, err := h.eventRepo.GetByIDWithUMAInvoice(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
 		return
 	}
 
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
-		return
-	}
-
 	// Get current user from context (if authenticated)
 	var currentUser *models.User
 	if user := middleware.GetUserFromContext(r.Context()); user != nil {
 		currentUser = user
 	}
 
+	h.localize(w, event)
+
 	// Enrich event with user ticket status
 	enrichedEvent := map[string]interface{}{
-		"id":          event.ID,
-		"title":       event.Title,
-		"description": event.Description,
-		"start_time":  event.StartTime,
-		"end_time":    event.EndTime,
-		"capacity":    event.Capacity,
-		"price_sats":  event.PriceSats,
-		"stream_url":  event.StreamURL,
-		"is_active":   event.IsActive,
-		"created_at":  event.CreatedAt,
-		"updated_at":  event.UpdatedAt,
+		"id":                     event.ID,
+		"title":                  event.Title,
+		"description":            event.Description,
+		"start_time":             event.StartTime,
+		"end_time":               event.EndTime,
+		"capacity":               event.Capacity,
+		"price_sats":             event.PriceSats,
+		"pricing_mode":           event.PricingMode,
+		"min_price_sats":         event.MinPriceSats,
+		"stream_url":             event.StreamURL,
+		"tax_basis_points":       event.TaxBasisPoints,
+		"tax_inclusive":          event.TaxInclusive,
+		"tax_jurisdiction":       event.TaxJurisdiction,
+		"is_private":             event.IsPrivate,
+		"min_age":                event.MinAge,
+		"terms_version":          event.TermsVersion,
+		"terms_url":              event.TermsURL,
+		"category":               event.Category,
+		"invoice_expiry_seconds": event.InvoiceExpirySeconds,
+		"one_ticket_per_user":    event.OneTicketPerUser,
+		"is_active":              event.IsActive,
+		"related_events":         h.relatedEvents(w, event),
+		"organizer":              h.organizer(event),
+		"cancelled_at":           event.CancelledAt,
+		"created_at":             event.CreatedAt,
+		"updated_at":             event.UpdatedAt,
 	}
 
 	// Add UMA invoice information if available
@@ -211,6 +299,98 @@ func (h *EventHandlers) HandleGetEvent(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// relatedEvents lists the events to show with event: those an admin picked,
+// then others of the same category or organizer, up to maxRelatedEvents.
+// Only events on public sale are listed.
+func (h *EventHandlers) relatedEvents(w http.ResponseWriter, event *models.Event) []models.RelatedEvent {
+	related := []models.RelatedEvent{}
+	now := h.clock.Now()
+	curated, err := h.relationRepo.GetCurated(event.ID, now)
+	if err != nil {
+		// Serve the event without its picks rather than fail the request
+		h.logger.Error("Failed to fetch related events", "event_id", event.ID, "error", err)
+	}
+	similar, err := h.relationRepo.GetSimilar(event, now, maxRelatedEvents+len(curated))
+	if err != nil {
+		h.logger.Error("Failed to fetch similar events", "event_id", event.ID, "error", err)
+	}
+
+	listed := map[int]bool{event.ID: true}
+	add := func(other models.Event, curated bool) {
+		if len(related) == maxRelatedEvents || listed[other.ID] {
+			return
+		}
+		listed[other.ID] = true
+		h.localize(w, &other)
+		related = append(related, models.RelatedEvent{
+			ID:          other.ID,
+			Title:       other.Title,
+			StartTime:   other.StartTime,
+			EndTime:     other.EndTime,
+			PriceSats:   other.PriceSats,
+			PricingMode: other.PricingMode,
+			Category:    other.Category,
+			Curated:     curated,
+		})
+	}
+	for _, other := range curated {
+		add(other, true)
+	}
+	for _, other := range similar {
+		add(other, false)
+	}
+	return related
+}
+
+// organizer returns the slug and name of the event organizer's profile page
+// for ticket pages to link to, or nil when the organizer has none
+func (h *EventHandlers) organizer(event *models.Event) map[string]interface{} {
+	if event.OrganizerID == nil {
+		return nil
+	}
+	profile, err := h.profileRepo.GetByUserID(*event.OrganizerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		// Serve the event without the link rather than fail the request
+		h.logger.Error("Failed to fetch organizer profile", "event_id", event.ID, "error", err)
+		return nil
+	}
+	return map[string]interface{}{
+		"slug":         profile.Slug,
+		"display_name": profile.DisplayName,
+	}
+}
+
+func (h *EventHandlers) localize(w http.ResponseWriter, event *models.Event) {
+	localizeEvent(h.translationRepo, h.logger, w, event)
+}
+
+// localizeEvent replaces the event's title and description with its
+// translation for the negotiated locale. Events without one keep their own
+// content, as does a translation's empty description. Handlers built without
+// a translation repository serve events as stored.
+func localizeEvent(translationRepo repositories.EventTranslationRepository, logger *slog.Logger, w http.ResponseWriter, event *models.Event) {
+	if translationRepo == nil {
+		return
+	}
+	translation, err := translationRepo.Get(event.ID, middleware.LocaleOf(w))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		// Serve the untranslated event rather than fail the request
+		logger.Error("Failed to fetch event translation", "event_id", event.ID, "error", err)
+		return
+	}
+
+	event.Title = translation.Title
+	if translation.Description != "" {
+		event.Description = translation.Description
+	}
+}
+
 // HandleCreateEvent creates a new event (admin only)
 func (h *EventHandlers) HandleCreateEvent(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateEventRequest
@@ -228,14 +408,35 @@ func (h *EventHandlers) HandleCreateEvent(w http.ResponseWriter, r *http.Request
 	h.logger.Info("Creating new event", "title", req.Title)
 
 	event := &models.Event{
-		Title:       req.Title,
-		Description: req.Description,
-		StartTime:   req.StartTime,
-		EndTime:     req.EndTime,
-		Capacity:    req.Capacity,
-		PriceSats:   req.PriceSats,
-		StreamURL:   req.StreamURL,
-		IsActive:    true,
+		Title:        req.Title,
+		Description:  req.Description,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+		Capacity:     req.Capacity,
+		PriceSats:    req.PriceSats,
+		StreamURL:    req.StreamURL,
+		IsActive:     true,
+		PricingMode:  req.PricingMode,
+		MinPriceSats: req.MinPriceSats,
+		OrganizerID:  req.OrganizerID,
+
+		TaxBasisPoints:  req.TaxBasisPoints,
+		TaxInclusive:    req.TaxInclusive,
+		TaxJurisdiction: strings.TrimSpace(req.TaxJurisdiction),
+
+		IsPrivate: req.IsPrivate,
+
+		MinAge:       req.MinAge,
+		TermsVersion: strings.TrimSpace(req.TermsVersion),
+		TermsURL:     strings.TrimSpace(req.TermsURL),
+
+		Category: normalizeCategory(req.Category),
+
+		InvoiceExpirySeconds: req.InvoiceExpirySeconds,
+		OneTicketPerUser:     req.OneTicketPerUser,
+	}
+	if event.PricingMode == "" {
+		event.PricingMode = models.PricingFixed
 	}
 
 	if err := h.eventRepo.Create(event); err != nil {
@@ -271,17 +472,22 @@ func (h *EventHandlers) HandleUpdateEvent(w http.ResponseWriter, r *http.Request
 
 	// Get existing event with UMA invoice data
 	event, err := h.eventRepo.GetByIDWithUMAInvoice(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch event for update", "event_id", eventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
 		return
 	}
-
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+	if event.CancelledAt != nil {
+		middleware.WriteError(w, http.StatusConflict, "Cancelled events cannot be changed")
 		return
 	}
 
+	oldPrice := event.PriceSats
+
 	// Update fields if provided
 	if req.Title != nil {
 		event.Title = *req.Title
@@ -296,9 +502,26 @@ func (h *EventHandlers) HandleUpdateEvent(w http.ResponseWriter, r *http.Request
 		event.EndTime = *req.EndTime
 	}
 	if req.Capacity != nil {
+		if *req.Capacity <= 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "capacity must be greater than 0")
+			return
+		}
 		event.Capacity = *req.Capacity
 	}
+	switch req.CapacityMode {
+	case "", models.CapacityModeStrict, models.CapacityModeRefundNewest:
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "capacity_mode must be strict or refund_newest")
+		return
+	}
 	if req.PriceSats != nil {
+		// Zero makes the event free; paid prices must stay within the limits
+		if *req.PriceSats != 0 {
+			if err := h.priceLimits().CheckTicketPrice(*req.PriceSats); err != nil {
+				middleware.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
 		event.PriceSats = *req.PriceSats
 	}
 	if req.StreamURL != nil {
@@ -307,6 +530,86 @@ func (h *EventHandlers) HandleUpdateEvent(w http.ResponseWriter, r *http.Request
 	if req.IsActive != nil {
 		event.IsActive = *req.IsActive
 	}
+	if req.PricingMode != nil {
+		event.PricingMode = *req.PricingMode
+	}
+	if req.MinPriceSats != nil {
+		event.MinPriceSats = *req.MinPriceSats
+	}
+	if req.OrganizerID != nil {
+		event.OrganizerID = req.OrganizerID
+		if *req.OrganizerID == 0 {
+			event.OrganizerID = nil
+		}
+	}
+	if req.TaxBasisPoints != nil {
+		event.TaxBasisPoints = *req.TaxBasisPoints
+	}
+	if req.TaxInclusive != nil {
+		event.TaxInclusive = *req.TaxInclusive
+	}
+	if req.TaxJurisdiction != nil {
+		event.TaxJurisdiction = strings.TrimSpace(*req.TaxJurisdiction)
+	}
+	if req.IsPrivate != nil {
+		event.IsPrivate = *req.IsPrivate
+	}
+	if req.MinAge != nil {
+		event.MinAge = *req.MinAge
+	}
+	if req.TermsVersion != nil {
+		event.TermsVersion = strings.TrimSpace(*req.TermsVersion)
+	}
+	if req.TermsURL != nil {
+		event.TermsURL = strings.TrimSpace(*req.TermsURL)
+	}
+	if req.Category != nil {
+		event.Category = normalizeCategory(*req.Category)
+	}
+	if req.InvoiceExpirySeconds != nil {
+		event.InvoiceExpirySeconds = *req.InvoiceExpirySeconds
+	}
+	if req.OneTicketPerUser != nil {
+		event.OneTicketPerUser = *req.OneTicketPerUser
+	}
+	if err := validateCategory(event.Category); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateInvoiceExpiry(event.InvoiceExpirySeconds); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateRestrictions(event.MinAge, event.TermsVersion, event.TermsURL); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateTax(event.TaxBasisPoints, event.TaxJurisdiction); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validatePricing(event.PricingMode, event.PriceSats, event.MinPriceSats); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A capacity below the sold and pending tickets is refused, unless the
+	// newest of them are to be cancelled to make room
+	var cancel []models.Ticket
+	if req.Capacity != nil {
+		cancel, err = h.capacity.Plan(eventID, event.Capacity, req.CapacityMode == models.CapacityModeRefundNewest)
+		var conflict *services.CapacityConflictError
+		if errors.As(err, &conflict) {
+			middleware.WriteErrorDetails(w, http.StatusConflict, models.ErrorCodeCapacityConflict,
+				"Capacity is below the tickets already sold or pending", conflict.Conflict)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to check event capacity", "event_id", eventID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to check event capacity")
+			return
+		}
+	}
 
 	// Note: UMA Request invoices are only needed for paid events (price > 0)
 	// Free events (price = 0) don't need UMA invoices since tickets are free
@@ -319,11 +622,52 @@ func (h *EventHandlers) HandleUpdateEvent(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.logger.Info("Event updated successfully", "event_id", eventID)
+	// A pending standing invoice at the old price is regenerated, or
+	// revoked when the event became free. The event is saved either way.
+	if event.UMARequestInvoice != nil && event.UMARequestInvoice.AmountSats != event.PriceSats {
+		invoice, _, err := h.invoices.Current(event)
+		switch {
+		case errors.Is(err, repositories.ErrNotFound):
+			event.UMARequestInvoice = nil
+		case err != nil:
+			h.logger.Error("Failed to regenerate UMA invoice after price change", "event_id", eventID, "error", err)
+		default:
+			event.UMARequestInvoice = invoice
+		}
+	}
+
+	// The reduced capacity is saved first so the freed seats are not sold
+	// again while the tickets are cancelled
+	updated := models.UpdatedEvent{Event: event}
+	if len(cancel) > 0 {
+		if err := h.capacity.Cancel(event, cancel); err != nil {
+			h.logger.Error("Failed to cancel tickets over capacity", "event_id", eventID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Event updated but its excess tickets could not all be cancelled")
+			return
+		}
+		for _, ticket := range cancel {
+			updated.CancelledTicketIDs = append(updated.CancelledTicketIDs, ticket.ID)
+		}
+	}
+
+	// Unpaid purchases keep the old price while their invoice is payable;
+	// those whose invoice lapsed are re-invoiced at the new one
+	if event.PriceSats != oldPrice {
+		updated.LockedTicketIDs, updated.RepricedTicketIDs, err = h.priceChanges.Apply(event)
+		if err != nil {
+			h.logger.Error("Failed to reprice unpaid tickets", "event_id", eventID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Event updated but its unpaid tickets could not all be repriced")
+			return
+		}
+		h.logger.Info("Event price changed", "event_id", eventID, "old_price_sats", oldPrice, "price_sats", event.PriceSats,
+			"locked_tickets", len(updated.LockedTicketIDs), "repriced_tickets", len(updated.RepricedTicketIDs))
+	}
+
+	h.logger.Info("Event updated successfully", "event_id", eventID, "cancelled_tickets", len(updated.CancelledTicketIDs))
 
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 		Message: "Event updated successfully",
-		Data:    event,
+		Data:    updated,
 	})
 }
 
@@ -339,18 +683,17 @@ func (h *EventHandlers) HandleDeleteEvent(w http.ResponseWriter, r *http.Request
 	h.logger.Info("Deleting event", "event_id", eventID)
 
 	// Check if event exists
-	event, err := h.eventRepo.GetByID(eventID)
+	_, err = h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch event for deletion", "event_id", eventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
 		return
 	}
 
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
-		return
-	}
-
 	if err := h.eventRepo.Delete(eventID); err != nil {
 		h.logger.Error("Failed to delete event", "event_id", eventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete event")
@@ -369,6 +712,9 @@ func (h *EventHandlers) HandleGetNodeBalance(w http.ResponseWriter, r *http.Requ
 	h.logger.Info("Admin requesting node balance")
 
 	balance, err := h.umaService.GetNodeBalance()
+	if paymentBackendUnavailable(w, err) {
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get node balance", "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to retrieve node balance")
@@ -387,7 +733,9 @@ func (h *EventHandlers) HandleGetNodeBalance(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-// HandleCreateEventUMAInvoice creates a UMA Request invoice for a specific event (admin only)
+// HandleCreateEventUMAInvoice creates a UMA Request invoice for a specific
+// event (admin only). It becomes the event's standing invoice, replacing
+// any earlier one.
 func (h *EventHandlers) HandleCreateEventUMAInvoice(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	eventID, err := strconv.Atoi(vars["id"])
@@ -398,30 +746,20 @@ func (h *EventHandlers) HandleCreateEventUMAInvoice(w http.ResponseWriter, r *ht
 
 	h.logger.Info("Creating UMA Request invoice for event", "event_id", eventID)
 
-	// Get the event with UMA invoice data
-	event, err := h.eventRepo.GetByIDWithUMAInvoice(eventID)
-	if err != nil {
-		h.logger.Error("Failed to fetch event for UMA invoice creation", "event_id", eventID, "error", err)
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+	event, ok := h.umaInvoiceEvent(w, eventID)
+	if !ok {
 		return
 	}
 
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+	if err := h.priceLimits().CheckInvoice(event.PriceSats); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Generate UMA address for the event
-	umaAddress := "$event@" + h.getDomainFromConfig()
-	description := fmt.Sprintf("Event Ticket: %s", event.Title)
-
-	// Create UMA Request invoice for the event
-	umaInvoice, err := h.umaService.CreateUMARequest(
-		umaAddress,
-		event.PriceSats,
-		description,
-		true, // isAdmin = true for admin endpoints
-	)
+	umaInvoice, err := h.invoices.Issue(event)
+	if paymentBackendUnavailable(w, err) {
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to create UMA Request invoice for event",
 			"event_id", eventID,
@@ -430,58 +768,125 @@ func (h *EventHandlers) HandleCreateEventUMAInvoice(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Store the UMA Request invoice in the separate table
-	umaInvoiceRecord := &models.UMARequestInvoice{
-		EventID:     &eventID,
-		InvoiceID:   umaInvoice.ID,
-		PaymentHash: umaInvoice.PaymentHash,
-		Bolt11:      umaInvoice.Bolt11,
-		AmountSats:  umaInvoice.AmountSats,
-		Status:      umaInvoice.Status,
-		UMAAddress:  umaAddress,
-		Description: description,
-		ExpiresAt:   umaInvoice.ExpiresAt,
+	h.logger.Info("UMA Request invoice created for event",
+		"event_id", eventID,
+		"invoice_id", umaInvoice.InvoiceID,
+		"uma_address", umaInvoice.UMAAddress)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "UMA Request invoice created successfully for event",
+		Data:    eventUMAInvoiceData(event, umaInvoice, false),
+	})
+}
+
+// HandleGetEventUMAInvoice returns the event's standing UMA Request invoice
+// (admin only), its status synced with the Lightning backend. An expired
+// invoice, or a pending one at an old price, is regenerated first.
+func (h *EventHandlers) HandleGetEventUMAInvoice(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	event, ok := h.umaInvoiceEvent(w, eventID)
+	if !ok {
+		return
 	}
 
-	if err := h.umaRepo.Create(umaInvoiceRecord); err != nil {
-		h.logger.Error("Failed to save UMA Request invoice to database",
-			"event_id", eventID,
-			"invoice_id", umaInvoice.ID,
-			"error", err)
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save UMA Request invoice")
+	umaInvoice, regenerated, err := h.invoices.Current(event)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event has no UMA Request invoice")
+		return
+	}
+	if paymentBackendUnavailable(w, err) {
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch UMA Request invoice for event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch UMA Request invoice")
 		return
 	}
 
-	h.logger.Info("UMA Request invoice saved to database successfully",
-		"event_id", eventID,
-		"invoice_id", umaInvoice.ID,
-		"uma_address", umaAddress)
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "UMA Request invoice retrieved successfully",
+		Data:    eventUMAInvoiceData(event, umaInvoice, regenerated),
+	})
+}
 
-	h.logger.Info("UMA Request invoice created for event",
-		"event_id", eventID,
-		"invoice_id", umaInvoice.ID,
-		"uma_address", umaAddress)
+// HandleDeleteEventUMAInvoice revokes the event's standing UMA Request
+// invoice (admin only). The invoice is cancelled rather than deleted, so a
+// payment to it that still arrives is attributed to the event.
+func (h *EventHandlers) HandleDeleteEventUMAInvoice(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	if _, ok := h.umaInvoiceEvent(w, eventID); !ok {
+		return
+	}
 
-	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
-		Message: "UMA Request invoice created successfully for event",
-		Data: map[string]interface{}{
-			"event": map[string]interface{}{
-				"id":    event.ID,
-				"title": event.Title,
-			},
-			"invoice": map[string]interface{}{
-				"id":           umaInvoice.ID,
-				"payment_hash": umaInvoice.PaymentHash,
-				"bolt11":       umaInvoice.Bolt11,
-				"amount_sats":  umaInvoice.AmountSats,
-				"status":       umaInvoice.Status,
-				"expires_at":   umaInvoice.ExpiresAt,
-			},
-			"uma_address": umaAddress,
-		},
+	admin := middleware.GetUserFromContext(r.Context())
+	umaInvoice, err := h.invoices.Revoke(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event has no UMA Request invoice")
+		return
+	}
+	if errors.Is(err, services.ErrEventInvoicePaid) {
+		middleware.WriteError(w, http.StatusConflict, "The UMA Request invoice has been paid and cannot be revoked")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to revoke UMA Request invoice", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to revoke UMA Request invoice")
+		return
+	}
+
+	h.logger.Info("UMA Request invoice revoked", "audit", true, "admin_id", admin.ID,
+		"event_id", eventID, "invoice_id", umaInvoice.InvoiceID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "UMA Request invoice revoked successfully",
+		Data:    umaInvoice,
 	})
 }
 
+// umaInvoiceEvent loads the event of a UMA invoice endpoint, writing a 404
+// and reporting false when there is none
+func (h *EventHandlers) umaInvoiceEvent(w http.ResponseWriter, eventID int) (*models.Event, bool) {
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event for UMA invoice", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, false
+	}
+	return event, true
+}
+
+// eventUMAInvoiceData is the response body describing an event's invoice
+func eventUMAInvoiceData(event *models.Event, invoice *models.UMARequestInvoice, regenerated bool) map[string]interface{} {
+	return map[string]interface{}{
+		"event": map[string]interface{}{
+			"id":    event.ID,
+			"title": event.Title,
+		},
+		"invoice": map[string]interface{}{
+			"id":           invoice.InvoiceID,
+			"payment_hash": invoice.PaymentHash,
+			"bolt11":       invoice.Bolt11,
+			"amount_sats":  invoice.AmountSats,
+			"status":       invoice.Status,
+			"expires_at":   invoice.ExpiresAt,
+		},
+		"uma_address": invoice.UMAAddress,
+		"regenerated": regenerated,
+	}
+}
+
 // validateCreateEventRequest validates the create event request
 func (h *EventHandlers) validateCreateEventRequest(req *models.CreateEventRequest) error {
 	if req.Title == "" {
@@ -500,7 +905,7 @@ func (h *EventHandlers) validateCreateEventRequest(req *models.CreateEventReques
 		return fmt.Errorf("start time must be before end time")
 	}
 
-	if req.StartTime.Before(time.Now()) {
+	if req.StartTime.Before(h.clock.Now()) {
 		return fmt.Errorf("start time cannot be in the past")
 	}
 
@@ -512,9 +917,119 @@ func (h *EventHandlers) validateCreateEventRequest(req *models.CreateEventReques
 		return fmt.Errorf("price must be greater than 0")
 	}
 
+	if err := validatePricing(req.PricingMode, req.PriceSats, req.MinPriceSats); err != nil {
+		return err
+	}
+
+	if err := validateTax(req.TaxBasisPoints, strings.TrimSpace(req.TaxJurisdiction)); err != nil {
+		return err
+	}
+
+	if err := validateRestrictions(req.MinAge, strings.TrimSpace(req.TermsVersion), strings.TrimSpace(req.TermsURL)); err != nil {
+		return err
+	}
+
+	if err := validateCategory(normalizeCategory(req.Category)); err != nil {
+		return err
+	}
+
+	if err := validateInvoiceExpiry(req.InvoiceExpirySeconds); err != nil {
+		return err
+	}
+
+	return h.priceLimits().CheckTicketPrice(req.PriceSats)
+}
+
+// validatePricing checks the pricing mode and, for pay what you want, that
+// the minimum does not exceed the suggested price.
+func validatePricing(mode string, priceSats, minPriceSats int64) error {
+	switch mode {
+	case "", models.PricingFixed:
+		return nil
+	case models.PricingPayWhatYouWant:
+		if minPriceSats < 0 {
+			return fmt.Errorf("minimum price cannot be negative")
+		}
+		if minPriceSats > priceSats {
+			return fmt.Errorf("minimum price (%d sats) cannot exceed the suggested price (%d sats)", minPriceSats, priceSats)
+		}
+		return nil
+	}
+	return fmt.Errorf("pricing mode must be %s or %s", models.PricingFixed, models.PricingPayWhatYouWant)
+}
+
+// validateTax checks the tax rate, which needs a jurisdiction to be reported
+// under.
+func validateTax(basisPoints int64, jurisdiction string) error {
+	if basisPoints < 0 || basisPoints > 10_000 {
+		return fmt.Errorf("tax rate must be between 0 and 10000 basis points")
+	}
+	if basisPoints > 0 && jurisdiction == "" {
+		return fmt.Errorf("tax jurisdiction is required when a tax rate is set")
+	}
+	if len(jurisdiction) > 100 {
+		return fmt.Errorf("tax jurisdiction must be at most 100 characters")
+	}
+	return nil
+}
+
+// validateRestrictions checks the minimum age and the terms buyers accept,
+// which need a version for buyers to attest to.
+func validateRestrictions(minAge int, termsVersion, termsURL string) error {
+	if minAge < 0 || minAge > 99 {
+		return fmt.Errorf("minimum age must be between 0 and 99")
+	}
+	if len(termsVersion) > 50 {
+		return fmt.Errorf("terms version must be at most 50 characters")
+	}
+	if termsURL == "" {
+		return nil
+	}
+	if termsVersion == "" {
+		return fmt.Errorf("terms version is required when a terms URL is set")
+	}
+	if len(termsURL) > 500 {
+		return fmt.Errorf("terms URL must be at most 500 characters")
+	}
+	if u, err := url.Parse(termsURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("terms URL must be an http or https URL")
+	}
+	return nil
+}
+
+// normalizeCategory trims and lowercases a category so events match on it
+// regardless of how it was typed
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+func validateCategory(category string) error {
+	if len(category) > 50 {
+		return fmt.Errorf("category must be at most 50 characters")
+	}
 	return nil
 }
 
+// validateInvoiceExpiry checks an event's invoice expiry in seconds, 0 for
+// the default
+func validateInvoiceExpiry(seconds int) error {
+	if seconds == 0 {
+		return nil
+	}
+	expiry := time.Duration(seconds) * time.Second
+	if expiry < models.MinInvoiceExpiry || expiry > models.MaxInvoiceExpiry {
+		return fmt.Errorf("invoice expiry must be between %d and %d seconds", int(models.MinInvoiceExpiry/time.Second), int(models.MaxInvoiceExpiry/time.Second))
+	}
+	return nil
+}
+
+func (h *EventHandlers) priceLimits() config.PriceLimits {
+	if h.config == nil {
+		return config.PriceLimits{}
+	}
+	return h.config.PriceLimits()
+}
+
 func (h *EventHandlers) getDomainFromConfig() string {
 	if h.config == nil {
 		return "localhost" // Fallback if config is not available
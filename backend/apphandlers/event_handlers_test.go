@@ -1,14 +1,29 @@
 package apphandlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
 	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
 )
 
 func TestValidateCreateEventRequest(t *testing.T) {
-	handler := &EventHandlers{}
+	handler := &EventHandlers{clock: clock.System(), config: &config.Config{MinTicketPriceSats: 1, MaxTicketPriceSats: 10_000_000}}
 
 	tests := []struct {
 		name    string
@@ -63,6 +78,87 @@ func TestValidateCreateEventRequest(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "price above maximum",
+			request: models.CreateEventRequest{
+				Title:       "Test Event",
+				Description: "Test Description",
+				StartTime:   time.Now().Add(1 * time.Hour),
+				EndTime:     time.Now().Add(2 * time.Hour),
+				Capacity:    100,
+				PriceSats:   2_100_000_000_000_000, // 21M BTC
+			},
+			wantErr: true,
+		},
+		{
+			name: "pay what you want minimum above suggested",
+			request: models.CreateEventRequest{
+				Title:        "Test Event",
+				Description:  "Test Description",
+				StartTime:    time.Now().Add(1 * time.Hour),
+				EndTime:      time.Now().Add(2 * time.Hour),
+				Capacity:     100,
+				PriceSats:    1000,
+				PricingMode:  models.PricingPayWhatYouWant,
+				MinPriceSats: 5000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown pricing mode",
+			request: models.CreateEventRequest{
+				Title:       "Test Event",
+				Description: "Test Description",
+				StartTime:   time.Now().Add(1 * time.Hour),
+				EndTime:     time.Now().Add(2 * time.Hour),
+				Capacity:    100,
+				PriceSats:   1000,
+				PricingMode: "auction",
+			},
+			wantErr: true,
+		},
+		{
+			name: "tax included in the price",
+			request: models.CreateEventRequest{
+				Title:           "Test Event",
+				Description:     "Test Description",
+				StartTime:       time.Now().Add(1 * time.Hour),
+				EndTime:         time.Now().Add(2 * time.Hour),
+				Capacity:        100,
+				PriceSats:       1000,
+				TaxBasisPoints:  1900,
+				TaxInclusive:    true,
+				TaxJurisdiction: "DE VAT",
+			},
+			wantErr: false,
+		},
+		{
+			name: "tax rate without jurisdiction",
+			request: models.CreateEventRequest{
+				Title:          "Test Event",
+				Description:    "Test Description",
+				StartTime:      time.Now().Add(1 * time.Hour),
+				EndTime:        time.Now().Add(2 * time.Hour),
+				Capacity:       100,
+				PriceSats:      1000,
+				TaxBasisPoints: 1900,
+			},
+			wantErr: true,
+		},
+		{
+			name: "tax rate above 100%",
+			request: models.CreateEventRequest{
+				Title:           "Test Event",
+				Description:     "Test Description",
+				StartTime:       time.Now().Add(1 * time.Hour),
+				EndTime:         time.Now().Add(2 * time.Hour),
+				Capacity:        100,
+				PriceSats:       1000,
+				TaxBasisPoints:  10_001,
+				TaxJurisdiction: "DE VAT",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -74,3 +170,226 @@ func TestValidateCreateEventRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateEventCapacity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	capacity := services.NewCapacityService(store.Events(), store.Tickets(), store.Payments(), ledger, nil, logger)
+	events := NewEventHandlers(store.Events(), store.Payments(), store.Tickets(), services.NewSimulatedUMAService(0, clk, logger),
+		store.UMARequestInvoices(), store.EventTranslations(), store.EventRelations(), store.OrganizerProfiles(), capacity, nil, nil, clk, logger, &config.Config{})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}", events.HandleUpdateEvent).Methods("PUT")
+
+	event := &models.Event{Title: "Meetup", Capacity: 5, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []*models.Ticket
+	for i, status := range []string{"paid", "paid", "pending"} {
+		clk.Advance(time.Minute)
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "SEAT-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	update := func(body map[string]interface{}) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/admin/events/"+strconv.Itoa(event.ID), bytes.NewReader(payload)))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	// Three seats are held, so two is refused with the counts
+	status, body := update(map[string]interface{}{"capacity": 2})
+	var refused struct {
+		ErrorCode string                  `json:"error_code"`
+		Details   models.CapacityConflict `json:"details"`
+	}
+	json.Unmarshal(body, &refused)
+	want := models.CapacityConflict{RequestedCapacity: 2, Sold: 2, Pending: 1, Excess: 1}
+	if status != http.StatusConflict || refused.ErrorCode != models.ErrorCodeCapacityConflict || refused.Details != want {
+		t.Fatalf("Expected a capacity conflict with %+v, got %d %s", want, status, body)
+	}
+	if current, _ := store.Events().GetByID(event.ID); current.Capacity != 5 {
+		t.Errorf("Expected the refused capacity not to be saved, got %d", current.Capacity)
+	}
+
+	for name, body := range map[string]map[string]interface{}{
+		"zero capacity": {"capacity": 0},
+		"unknown mode":  {"capacity": 2, "capacity_mode": "oldest"},
+	} {
+		if status, _ := update(body); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	if status, body := update(map[string]interface{}{"capacity": 3}); status != http.StatusOK {
+		t.Errorf("Expected a capacity matching the held seats to be saved, got %d %s", status, body)
+	}
+
+	// Refunding the newest cancels the pending ticket bought last
+	status, body = update(map[string]interface{}{"capacity": 2, "capacity_mode": models.CapacityModeRefundNewest})
+	var updated struct {
+		Data struct {
+			Capacity           int   `json:"capacity"`
+			CancelledTicketIDs []int `json:"cancelled_ticket_ids"`
+		} `json:"data"`
+	}
+	json.Unmarshal(body, &updated)
+	if status != http.StatusOK || updated.Data.Capacity != 2 || len(updated.Data.CancelledTicketIDs) != 1 || updated.Data.CancelledTicketIDs[0] != tickets[2].ID {
+		t.Fatalf("Expected the newest ticket cancelled, got %d %s", status, body)
+	}
+	if ticket, _ := store.Tickets().GetByID(tickets[2].ID); ticket.PaymentStatus != "cancelled" {
+		t.Errorf("Expected the ticket to be cancelled, got %s", ticket.PaymentStatus)
+	}
+}
+
+func TestListEventsQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := &EventHandlers{eventRepo: store.Events(), ticketRepo: store.Tickets(), translationRepo: store.EventTranslations(), clock: clk, logger: logger}
+
+	for i, title := range []string{"Jazz Night", "Rock Show", "Jazz Brunch"} {
+		event := &models.Event{Title: title, StartTime: clk.Now().AddDate(0, 0, i), Capacity: 10, PriceSats: int64(1000 * (i + 1)), IsActive: true}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) (int, []string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.HandleGetEvents(rec, httptest.NewRequest("GET", "/api/events?"+query, nil))
+		var resp struct {
+			Data []struct {
+				Title string `json:"title"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		var titles []string
+		for _, event := range resp.Data {
+			titles = append(titles, event.Title)
+		}
+		return rec.Code, titles
+	}
+
+	status, titles := list("filter[title][contains]=jazz&sort=-price_sats")
+	if status != http.StatusOK || len(titles) != 2 || titles[0] != "Jazz Brunch" || titles[1] != "Jazz Night" {
+		t.Errorf("Expected the jazz events priciest first, got %d %v", status, titles)
+	}
+	status, titles = list("filter[price_sats][lt]=2000")
+	if status != http.StatusOK || len(titles) != 1 || titles[0] != "Jazz Night" {
+		t.Errorf("Expected the cheapest event, got %d %v", status, titles)
+	}
+	for _, query := range []string{"sort=is_private", "filter[stream_url]=x", "filter[price_sats]=free"} {
+		if status, _ := list(query); status != http.StatusBadRequest {
+			t.Errorf("Expected %q to be refused, got %d", query, status)
+		}
+	}
+}
+
+func TestEventUMAInvoiceLifecycle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	umaService := services.NewSimulatedUMAService(0, clk, logger)
+	cfg := &config.Config{Domain: "tickets.example.com"}
+	invoices := services.NewEventInvoiceService(store.UMARequestInvoices(), umaService, cfg.Domain, clk, logger)
+	pricing := services.NewPricingService(store.PricingRules(), store.Events(), clk, logger)
+	priceChanges := services.NewPriceChangeService(store.Tickets(), store.Payments(), store.AddOns(), pricing, nil, clk, logger)
+	events := NewEventHandlers(store.Events(), store.Payments(), store.Tickets(), umaService,
+		store.UMARequestInvoices(), store.EventTranslations(), store.EventRelations(), store.OrganizerProfiles(), nil, invoices, priceChanges, clk, logger, cfg)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}", events.HandleUpdateEvent).Methods("PUT")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/uma-invoice", events.HandleCreateEventUMAInvoice).Methods("POST")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/uma-invoice", events.HandleGetEventUMAInvoice).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/uma-invoice", events.HandleDeleteEventUMAInvoice).Methods("DELETE")
+
+	event := &models.Event{Title: "Gig", StartTime: clk.Now().Add(24 * time.Hour), EndTime: clk.Now().Add(25 * time.Hour),
+		Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/admin/events/" + strconv.Itoa(event.ID) + "/uma-invoice"
+	admin := &models.User{ID: 1, Email: "admin@example.com"}
+	type invoiceData struct {
+		Invoice struct {
+			ID         string `json:"id"`
+			AmountSats int64  `json:"amount_sats"`
+			Status     string `json:"status"`
+		} `json:"invoice"`
+		UMAAddress  string `json:"uma_address"`
+		Regenerated bool   `json:"regenerated"`
+	}
+	do := func(method, path string, body interface{}) (int, invoiceData) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var response struct {
+			Data invoiceData `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response.Data
+	}
+
+	if status, _ := do("GET", path, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 before an invoice is created, got %d", status)
+	}
+	status, created := do("POST", path, nil)
+	if status != http.StatusCreated || created.UMAAddress != "$event-1@tickets.example.com" {
+		t.Fatalf("Expected the invoice created for the event's address, got %d %+v", status, created)
+	}
+	if status, got := do("GET", path, nil); status != http.StatusOK || got.Invoice.ID != created.Invoice.ID || got.Regenerated {
+		t.Errorf("Expected the created invoice back, got %d %+v", status, got)
+	}
+
+	// Changing the price regenerates the pending invoice
+	if status, _ := do("PUT", "/api/admin/events/"+strconv.Itoa(event.ID), map[string]interface{}{"price_sats": 2000}); status != http.StatusOK {
+		t.Fatalf("Expected the price change saved, got %d", status)
+	}
+	if current, err := store.UMARequestInvoices().GetByEventID(event.ID); err != nil || current.AmountSats != 2000 {
+		t.Errorf("Expected the update to regenerate the invoice, got %+v, %v", current, err)
+	}
+	status, repriced := do("GET", path, nil)
+	if status != http.StatusOK || repriced.Invoice.ID == created.Invoice.ID || repriced.Invoice.AmountSats != 2000 {
+		t.Errorf("Expected an invoice at the new price, got %d %+v", status, repriced)
+	}
+
+	// Expiry regenerates it when it is next read
+	clk.Advance(models.DefaultInvoiceExpiry)
+	if status, renewed := do("GET", path, nil); status != http.StatusOK || !renewed.Regenerated || renewed.Invoice.ID == repriced.Invoice.ID {
+		t.Errorf("Expected the expired invoice regenerated, got %d %+v", status, renewed)
+	}
+
+	if status, _ := do("DELETE", path, nil); status != http.StatusOK {
+		t.Errorf("Expected the invoice revoked, got %d", status)
+	}
+	if status, _ := do("GET", path, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 after revoking, got %d", status)
+	}
+	if status, _ := do("DELETE", path, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking again, got %d", status)
+	}
+}
+
+func TestLocalizeEventWithoutRepository(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	event := &models.Event{ID: 1, Title: "Launch", Description: "Original"}
+
+	localizeEvent(nil, logger, httptest.NewRecorder(), event)
+
+	if event.Title != "Launch" || event.Description != "Original" {
+		t.Errorf("event = %q/%q, want it unchanged", event.Title, event.Description)
+	}
+}
@@ -0,0 +1,175 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// maxEventTitleLength matches events.title and event_translations.title
+const maxEventTitleLength = 255
+
+// EventTranslationHandlers manages events' titles and descriptions in other
+// locales (admin only)
+type EventTranslationHandlers struct {
+	translationRepo repositories.EventTranslationRepository
+	eventRepo       repositories.EventRepository
+	logger          *slog.Logger
+}
+
+func NewEventTranslationHandlers(
+	translationRepo repositories.EventTranslationRepository,
+	eventRepo repositories.EventRepository,
+	logger *slog.Logger,
+) *EventTranslationHandlers {
+	return &EventTranslationHandlers{
+		translationRepo: translationRepo,
+		eventRepo:       eventRepo,
+		logger:          logger,
+	}
+}
+
+// HandleListTranslations lists an event's translations
+func (h *EventTranslationHandlers) HandleListTranslations(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := h.translationRepo.GetByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event translations", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event translations")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Event translations retrieved successfully",
+		Data:    translations,
+	})
+}
+
+// HandlePutTranslation sets an event's title and description in a locale,
+// replacing any earlier translation
+func (h *EventTranslationHandlers) HandlePutTranslation(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+	locale, ok := i18n.Normalize(mux.Vars(r)["locale"])
+	if !ok {
+		middleware.WriteError(w, http.StatusBadRequest, "locale is not supported")
+		return
+	}
+
+	var req models.EventTranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if err := validateEventTranslation(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	translation := &models.EventTranslation{
+		EventID:     event.ID,
+		Locale:      locale,
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if err := h.translationRepo.Upsert(translation); err != nil {
+		h.logger.Error("Failed to save event translation", "event_id", event.ID, "locale", locale, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save event translation")
+		return
+	}
+
+	h.logger.Info("Event translation saved", "event_id", event.ID, "locale", locale)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Event translation saved successfully",
+		Data:    translation,
+	})
+}
+
+// HandleDeleteTranslation removes an event's translation; the event is then
+// shown with its own content in that locale
+func (h *EventTranslationHandlers) HandleDeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+	locale, ok := i18n.Normalize(mux.Vars(r)["locale"])
+	if !ok {
+		middleware.WriteError(w, http.StatusBadRequest, "locale is not supported")
+		return
+	}
+
+	_, err := h.translationRepo.Get(event.ID, locale)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event translation not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event translation", "event_id", event.ID, "locale", locale, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event translation")
+		return
+	}
+
+	if err := h.translationRepo.Delete(event.ID, locale); err != nil {
+		h.logger.Error("Failed to delete event translation", "event_id", event.ID, "locale", locale, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete event translation")
+		return
+	}
+
+	h.logger.Info("Event translation deleted", "event_id", event.ID, "locale", locale)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Event translation deleted successfully",
+	})
+}
+
+// event loads the event named in the URL, writing the error response when
+// it cannot
+func (h *EventTranslationHandlers) event(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return nil, false
+	}
+
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, false
+	}
+	return event, true
+}
+
+func validateEventTranslation(req *models.EventTranslationRequest) error {
+	if req.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if utf8.RuneCountInString(req.Title) > maxEventTitleLength {
+		return fmt.Errorf("title must be at most %d characters", maxEventTitleLength)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestEventTranslations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	translations := NewEventTranslationHandlers(store.EventTranslations(), store.Events(), logger)
+	events := NewEventHandlers(store.Events(), store.Payments(), store.Tickets(), services.NewSimulatedUMAService(0, clk, logger),
+		store.UMARequestInvoices(), store.EventTranslations(), store.EventRelations(), store.OrganizerProfiles(), nil, nil, nil, clk, logger, &config.Config{})
+
+	router := mux.NewRouter()
+	router.Use(middleware.Locale)
+	router.HandleFunc("/api/events", events.HandleGetEvents).Methods("GET")
+	router.HandleFunc("/api/events/{id:[0-9]+}", events.HandleGetEvent).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/translations", translations.HandleListTranslations).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/translations/{locale}", translations.HandlePutTranslation).Methods("PUT")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/translations/{locale}", translations.HandleDeleteTranslation).Methods("DELETE")
+
+	do := func(method, path, acceptLanguage string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	event := &models.Event{Title: "Jazz Night", Description: "Live jazz", StartTime: clk.Now().Add(24 * time.Hour),
+		EndTime: clk.Now().Add(26 * time.Hour), Capacity: 10, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	translationsPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/translations"
+
+	for _, tt := range []struct {
+		locale string
+		body   models.EventTranslationRequest
+		want   int
+	}{
+		{"ko", models.EventTranslationRequest{Title: "재즈의 밤", Description: "라이브 재즈 공연"}, http.StatusOK},
+		{"ES", models.EventTranslationRequest{Title: "Noche de jazz"}, http.StatusOK},
+		{"fr", models.EventTranslationRequest{Title: "Soirée jazz"}, http.StatusBadRequest},
+		{"ko", models.EventTranslationRequest{Title: "  "}, http.StatusBadRequest},
+	} {
+		if code, _ := do("PUT", translationsPath+"/"+tt.locale, "", tt.body); code != tt.want {
+			t.Errorf("PUT %s %q: expected %d, got %d", tt.locale, tt.body.Title, tt.want, code)
+		}
+	}
+	if code, _ := do("PUT", "/api/admin/events/999/translations/ko", "", models.EventTranslationRequest{Title: "없음"}); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing event, got %d", code)
+	}
+
+	code, data := do("GET", translationsPath, "", nil)
+	var stored []models.EventTranslation
+	json.Unmarshal(data, &stored)
+	if code != http.StatusOK || len(stored) != 2 || stored[0].Locale != "es" || stored[1].Locale != "ko" {
+		t.Fatalf("Expected the es and ko translations, got %d %s", code, data)
+	}
+
+	type content struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	tests := []struct {
+		acceptLanguage string
+		want           content
+	}{
+		{"ko-KR,ko;q=0.9", content{"재즈의 밤", "라이브 재즈 공연"}},
+		// The Spanish translation has no description of its own
+		{"es-MX", content{"Noche de jazz", "Live jazz"}},
+		{"fr, es;q=0.5", content{"Noche de jazz", "Live jazz"}},
+		{"en", content{"Jazz Night", "Live jazz"}},
+		{"", content{"Jazz Night", "Live jazz"}},
+	}
+	for _, tt := range tests {
+		_, data := do("GET", "/api/events/"+strconv.Itoa(event.ID), tt.acceptLanguage, nil)
+		var detail content
+		json.Unmarshal(data, &detail)
+		if detail != tt.want {
+			t.Errorf("Detail with %q: got %+v, want %+v", tt.acceptLanguage, detail, tt.want)
+		}
+
+		_, data = do("GET", "/api/events", tt.acceptLanguage, nil)
+		var listing []content
+		json.Unmarshal(data, &listing)
+		if len(listing) != 1 || listing[0] != tt.want {
+			t.Errorf("Listing with %q: got %+v, want %+v", tt.acceptLanguage, listing, tt.want)
+		}
+	}
+
+	if code, _ := do("DELETE", translationsPath+"/ko", "", nil); code != http.StatusOK {
+		t.Fatalf("Expected the translation to be deleted, got %d", code)
+	}
+	if code, _ := do("DELETE", translationsPath+"/ko", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted translation, got %d", code)
+	}
+	_, data = do("GET", "/api/events/"+strconv.Itoa(event.ID), "ko", nil)
+	var detail content
+	json.Unmarshal(data, &detail)
+	if detail.Title != "Jazz Night" {
+		t.Errorf("Expected the event's own title once untranslated, got %q", detail.Title)
+	}
+}
@@ -0,0 +1,70 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// currencyCode matches an ISO 4217 currency code
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type ExchangeRateHandlers struct {
+	rateRepo     repositories.ExchangeRateRepository
+	fiatCurrency string
+	logger       *slog.Logger
+}
+
+func NewExchangeRateHandlers(rateRepo repositories.ExchangeRateRepository, fiatCurrency string, logger *slog.Logger) *ExchangeRateHandlers {
+	return &ExchangeRateHandlers{
+		rateRepo:     rateRepo,
+		fiatCurrency: fiatCurrency,
+		logger:       logger,
+	}
+}
+
+// HandleListExchangeRates lists the stored exchange rates of the currency
+// of record, or of the currency given, newest first (admin only)
+func (h *ExchangeRateHandlers) HandleListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	currency := h.fiatCurrency
+	if value := r.URL.Query().Get("currency"); value != "" {
+		currency = strings.ToUpper(value)
+		if !currencyCode.MatchString(currency) {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid currency")
+			return
+		}
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	rates, err := h.rateRepo.List(currency, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch exchange rates", "currency", currency, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch exchange rates")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Exchange rates retrieved successfully",
+		Data:    rates,
+	})
+}
@@ -0,0 +1,223 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type FeeHandlers struct {
+	fees         *services.FeeService
+	feeRepo      repositories.FeeRepository
+	userRepo     repositories.UserRepository
+	fiatCurrency string
+	logger       *slog.Logger
+}
+
+// NewFeeHandlers creates the fee handlers. Revenue is also reported in
+// fiatCurrency, the currency of record.
+func NewFeeHandlers(fees *services.FeeService, feeRepo repositories.FeeRepository, userRepo repositories.UserRepository, fiatCurrency string, logger *slog.Logger) *FeeHandlers {
+	return &FeeHandlers{
+		fees:         fees,
+		feeRepo:      feeRepo,
+		userRepo:     userRepo,
+		fiatCurrency: fiatCurrency,
+		logger:       logger,
+	}
+}
+
+// HandleListFees returns the default platform fee and every organizer
+// override (admin only)
+func (h *FeeHandlers) HandleListFees(w http.ResponseWriter, r *http.Request) {
+	overrides, err := h.feeRepo.ListOverrides()
+	if err != nil {
+		h.logger.Error("Failed to fetch fee overrides", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch fee overrides")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Platform fees retrieved successfully",
+		Data: map[string]interface{}{
+			"default":   h.fees.Default(),
+			"overrides": overrides,
+		},
+	})
+}
+
+// HandleSetOrganizerFee sets the platform fee charged on an organizer's
+// events, replacing any previous override. Invoices already issued keep
+// their fee (admin only)
+func (h *FeeHandlers) HandleSetOrganizerFee(w http.ResponseWriter, r *http.Request) {
+	organizerID, ok := h.organizer(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SetOrganizerFeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.BasisPoints < 0 || req.BasisPoints > config.MaxFeeBasisPoints {
+		middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("basis_points must be between 0 and %d", config.MaxFeeBasisPoints))
+		return
+	}
+	if req.FixedSats < 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "fixed_sats cannot be negative")
+		return
+	}
+
+	fee := &models.OrganizerFee{OrganizerID: organizerID, BasisPoints: req.BasisPoints, FixedSats: req.FixedSats}
+	if err := h.feeRepo.SetOverride(fee); err != nil {
+		h.logger.Error("Failed to set fee override", "organizer_id", organizerID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to set fee override")
+		return
+	}
+
+	h.logger.Info("Organizer fee set", "organizer_id", organizerID, "basis_points", fee.BasisPoints, "fixed_sats", fee.FixedSats)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Organizer fee updated successfully",
+		Data:    fee,
+	})
+}
+
+// HandleDeleteOrganizerFee returns an organizer to the default platform fee
+// (admin only)
+func (h *FeeHandlers) HandleDeleteOrganizerFee(w http.ResponseWriter, r *http.Request) {
+	organizerID, ok := h.organizer(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.feeRepo.DeleteOverride(organizerID); err != nil {
+		h.logger.Error("Failed to delete fee override", "organizer_id", organizerID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete fee override")
+		return
+	}
+
+	h.logger.Info("Organizer fee override removed", "organizer_id", organizerID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Organizer fee override removed successfully",
+	})
+}
+
+// HandleRevenueReport totals paid sales per event: gross, platform fees and
+// the organizer payout, and the gross in the currency of record at the
+// rates locked when the payments were paid. Filters: organizer_id, and from/to (RFC 3339) on the
+// time payments were paid (admin only)
+func (h *FeeHandlers) HandleRevenueReport(w http.ResponseWriter, r *http.Request) {
+	filter := models.RevenueFilter{FiatCurrency: h.fiatCurrency}
+	query := r.URL.Query()
+
+	if organizerStr := query.Get("organizer_id"); organizerStr != "" {
+		organizerID, err := strconv.Atoi(organizerStr)
+		if err != nil || organizerID <= 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid organizer_id")
+			return
+		}
+		filter.OrganizerID = organizerID
+	}
+	var ok bool
+	if filter.From, filter.To, ok = reportPeriod(w, r, false); !ok {
+		return
+	}
+
+	events, err := h.feeRepo.Revenue(filter)
+	if err != nil {
+		h.logger.Error("Failed to compute revenue", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to compute revenue")
+		return
+	}
+
+	var totals models.EventRevenue
+	for _, event := range events {
+		totals.Payments += event.Payments
+		totals.GrossSats += event.GrossSats
+		totals.FeeSats += event.FeeSats
+		totals.PayoutSats += event.PayoutSats
+		totals.GrossFiat += event.GrossFiat
+		totals.UnvaluedPayments += event.UnvaluedPayments
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Revenue retrieved successfully",
+		Data: map[string]interface{}{
+			"events": events,
+			"totals": map[string]interface{}{
+				"payments":    totals.Payments,
+				"gross_sats":  totals.GrossSats,
+				"fee_sats":    totals.FeeSats,
+				"payout_sats": totals.PayoutSats,
+
+				"fiat_currency":     h.fiatCurrency,
+				"gross_fiat":        totals.GrossFiat,
+				"unvalued_payments": totals.UnvaluedPayments,
+			},
+		},
+	})
+}
+
+// reportPeriod parses a report's from and to query parameters (RFC 3339),
+// writing the error response itself when they are invalid or, if required,
+// missing.
+func reportPeriod(w http.ResponseWriter, r *http.Request, required bool) (from, to *time.Time, ok bool) {
+	for param, bound := range map[string]**time.Time{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			if required {
+				middleware.WriteError(w, http.StatusBadRequest, param+" is required")
+				return nil, nil, false
+			}
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s: use RFC 3339, e.g. 2026-10-01T00:00:00Z", param))
+			return nil, nil, false
+		}
+		t = t.UTC()
+		*bound = &t
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		middleware.WriteError(w, http.StatusBadRequest, "from must be before to")
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+// organizer checks that the user named in the route exists, writing the
+// error response itself when it does not.
+func (h *FeeHandlers) organizer(w http.ResponseWriter, r *http.Request) (int, bool) {
+	organizerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid organizer ID")
+		return 0, false
+	}
+
+	_, err = h.userRepo.GetByID(organizerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Organizer not found")
+		return 0, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch organizer", "organizer_id", organizerID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer")
+		return 0, false
+	}
+	return organizerID, true
+}
@@ -0,0 +1,182 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestPlatformFees(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	fees := services.NewFeeService(store.Fees(), config.FeeSchedule{BasisPoints: 200, FixedSats: 10})
+	feeHandlers := NewFeeHandlers(fees, store.Fees(), store.Users(), "USD", logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), services.NewSettingsService(store.Settings(), logger), nil, nil, fees, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/fees", feeHandlers.HandleListFees).Methods("GET")
+	router.HandleFunc("/api/admin/organizers/{id:[0-9]+}/fee", feeHandlers.HandleSetOrganizerFee).Methods("PUT")
+	router.HandleFunc("/api/admin/organizers/{id:[0-9]+}/fee", feeHandlers.HandleDeleteOrganizerFee).Methods("DELETE")
+	router.HandleFunc("/api/admin/revenue", feeHandlers.HandleRevenueReport).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	organizer := &models.User{Email: "organizer@example.com", Name: "Organizer"}
+	if err := store.Users().Create(organizer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 10000, IsActive: true, OrganizerID: &organizer.ID}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	feePath := "/api/admin/organizers/" + strconv.Itoa(organizer.ID) + "/fee"
+
+	// purchase buys a ticket and checks the fee on its invoice and line items
+	purchase := func(userID int, wantFee int64) {
+		t.Helper()
+		status, data := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com",
+		})
+		var bought struct {
+			Ticket  struct{ ID int } `json:"ticket"`
+			FeeSats int64            `json:"fee_sats"`
+		}
+		json.Unmarshal(data, &bought)
+		if status != http.StatusCreated || bought.FeeSats != wantFee {
+			t.Fatalf("Expected a %d sat fee, got %d %s", wantFee, status, data)
+		}
+		payment, err := store.Payments().GetByTicketID(bought.Ticket.ID)
+		if err != nil || payment.Amount != 10000+wantFee {
+			t.Fatalf("Expected the fee on top of the ticket price, got %+v (%v)", payment, err)
+		}
+		items, err := store.Receipts().GetLineItems(payment.ID)
+		if err != nil || len(items) != 2 || items[0].AmountSats != 10000 ||
+			items[1].Kind != models.LineItemFee || items[1].AmountSats != wantFee {
+			t.Errorf("Expected ticket and fee line items, got %+v (%v)", items, err)
+		}
+	}
+
+	// The default schedule applies until the organizer has an override
+	purchase(1, 210)
+
+	for name, req := range map[string]models.SetOrganizerFeeRequest{
+		"negative fixed": {BasisPoints: 100, FixedSats: -1},
+		"over 100%":      {BasisPoints: 10_001},
+	} {
+		if status, _ := do("PUT", feePath, req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+	if status, _ := do("PUT", "/api/admin/organizers/999/fee", models.SetOrganizerFeeRequest{}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown organizer, got %d", status)
+	}
+	if status, data := do("PUT", feePath, models.SetOrganizerFeeRequest{BasisPoints: 100}); status != http.StatusOK {
+		t.Fatalf("Expected 200 setting override, got %d %s", status, data)
+	}
+	purchase(2, 100)
+
+	status, data := do("GET", "/api/admin/fees", nil)
+	var listed struct {
+		Default   config.FeeSchedule    `json:"default"`
+		Overrides []models.OrganizerFee `json:"overrides"`
+	}
+	json.Unmarshal(data, &listed)
+	if status != http.StatusOK || listed.Default.BasisPoints != 200 || len(listed.Overrides) != 1 || listed.Overrides[0].BasisPoints != 100 {
+		t.Errorf("Expected default and override, got %d %s", status, data)
+	}
+
+	if status, _ := do("DELETE", feePath, nil); status != http.StatusOK {
+		t.Fatalf("Expected 200 removing override, got %d", status)
+	}
+	purchase(3, 210)
+
+	// Revenue counts paid payments only, with fees taken from their line items
+	house := &models.Event{Title: "House show", Capacity: 10, PriceSats: 5000, IsActive: true}
+	if err := store.Events().Create(house); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first is valued in the currency of record
+	rate := &models.ExchangeRate{Currency: "USD", MinorUnitsPerBTC: 6_000_000, Source: "fixed"}
+	if err := store.ExchangeRates().Create(rate); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range []*models.Event{event, event, house} {
+		ticket := &models.Ticket{EventID: e.ID, UserID: 10 + i, TicketCode: "REV-" + strconv.Itoa(i), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-rev-" + strconv.Itoa(i), Amount: e.PriceSats + 100, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		store.Receipts().CreateLineItems([]models.PaymentLineItem{
+			{PaymentID: payment.ID, Kind: models.LineItemTicket, Quantity: 1, UnitAmountSats: e.PriceSats, AmountSats: e.PriceSats},
+			{PaymentID: payment.ID, Kind: models.LineItemFee, Quantity: 1, UnitAmountSats: 100, AmountSats: 100},
+		})
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := store.ExchangeRates().SetFiatValue(payment.ID, rate.ID, 606); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var report struct {
+		Events []models.EventRevenue `json:"events"`
+		Totals struct {
+			Payments   int   `json:"payments"`
+			FeeSats    int64 `json:"fee_sats"`
+			PayoutSats int64 `json:"payout_sats"`
+
+			FiatCurrency     string `json:"fiat_currency"`
+			GrossFiat        int64  `json:"gross_fiat"`
+			UnvaluedPayments int    `json:"unvalued_payments"`
+		} `json:"totals"`
+	}
+	status, data = do("GET", "/api/admin/revenue?organizer_id="+strconv.Itoa(organizer.ID), nil)
+	json.Unmarshal(data, &report)
+	if status != http.StatusOK || len(report.Events) != 1 || report.Totals.Payments != 2 ||
+		report.Totals.FeeSats != 200 || report.Totals.PayoutSats != 20000 ||
+		report.Totals.FiatCurrency != "USD" || report.Totals.GrossFiat != 606 || report.Totals.UnvaluedPayments != 1 {
+		t.Errorf("Expected the organizer's two paid payments, got %d %s", status, data)
+	}
+
+	status, data = do("GET", "/api/admin/revenue?from=2026-10-17T00:00:00Z", nil)
+	report.Events = nil
+	json.Unmarshal(data, &report)
+	if status != http.StatusOK || len(report.Events) != 0 {
+		t.Errorf("Expected no revenue after the period, got %d %s", status, data)
+	}
+	if status, _ := do("GET", "/api/admin/revenue?to=yesterday", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid period, got %d", status)
+	}
+}
@@ -0,0 +1,78 @@
+package apphandlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/services"
+)
+
+// maxFeedCategories caps the categories one feed can be filtered to
+const maxFeedCategories = 20
+
+type FeedHandlers struct {
+	feeds  *services.FeedService
+	logger *slog.Logger
+}
+
+func NewFeedHandlers(feeds *services.FeedService, logger *slog.Logger) *FeedHandlers {
+	return &FeedHandlers{
+		feeds:  feeds,
+		logger: logger,
+	}
+}
+
+// HandleGetFeed serves upcoming public events as an RSS (feed.rss) or Atom
+// (feed.atom) feed. ?category= limits it to some categories, given
+// comma-separated or repeated.
+func (h *FeedHandlers) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	categories, err := feedCategories(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var feed []byte
+	contentType := "application/rss+xml; charset=utf-8"
+	if mux.Vars(r)["format"] == "atom" {
+		contentType = "application/atom+xml; charset=utf-8"
+		feed, err = h.feeds.Atom(categories)
+	} else {
+		feed, err = h.feeds.RSS(categories)
+	}
+	if err != nil {
+		h.logger.Error("Failed to build event feed", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch events")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	// Feed readers poll; a few minutes' delay is fine
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(feed)
+}
+
+// feedCategories reads the categories a feed is filtered to, normalized
+// like event categories
+func feedCategories(r *http.Request) ([]string, error) {
+	var categories []string
+	for _, value := range r.URL.Query()["category"] {
+		for _, category := range strings.Split(value, ",") {
+			category = normalizeCategory(category)
+			if category != "" && !slices.Contains(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+	}
+	if len(categories) > maxFeedCategories {
+		return nil, fmt.Errorf("at most %d categories can be given", maxFeedCategories)
+	}
+	return categories, nil
+}
@@ -0,0 +1,78 @@
+package apphandlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestFeedHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewFeedHandlers(services.NewFeedService(store.Events(), "tickets.example", clk), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events/feed.{format:rss|atom}", handler.HandleGetFeed).Methods("GET")
+
+	start := clk.Now().Add(24 * time.Hour)
+	for _, category := range []string{"music", "tech", "food"} {
+		event := &models.Event{Title: "A " + category + " event", Category: category, StartTime: start, EndTime: start.Add(time.Hour), IsActive: true}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tooManyCategories []string
+	for i := 0; i <= maxFeedCategories; i++ {
+		tooManyCategories = append(tooManyCategories, fmt.Sprintf("c%d", i))
+	}
+
+	for name, tt := range map[string]struct {
+		path        string
+		want        int
+		contentType string
+		titles      []string
+	}{
+		"rss":            {"/api/events/feed.rss", http.StatusOK, "application/rss+xml; charset=utf-8", []string{"music", "tech", "food"}},
+		"atom":           {"/api/events/feed.atom", http.StatusOK, "application/atom+xml; charset=utf-8", []string{"music", "tech", "food"}},
+		"comma filter":   {"/api/events/feed.rss?category=Music,%20tech", http.StatusOK, "application/rss+xml; charset=utf-8", []string{"music", "tech"}},
+		"repeated":       {"/api/events/feed.atom?category=food&category=tech", http.StatusOK, "application/atom+xml; charset=utf-8", []string{"tech", "food"}},
+		"unknown format": {"/api/events/feed.json", http.StatusNotFound, "", nil},
+		"too many":       {"/api/events/feed.rss?category=" + strings.Join(tooManyCategories, ","), http.StatusBadRequest, "", nil},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, rec.Code)
+			continue
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: expected %s, got %s", name, tt.contentType, got)
+		}
+		body := rec.Body.String()
+		if n := strings.Count(body, "<title>A "); n != len(tt.titles) {
+			t.Errorf("%s: expected %d events, got %d", name, len(tt.titles), n)
+		}
+		for _, category := range tt.titles {
+			if !strings.Contains(body, "A "+category+" event") {
+				t.Errorf("%s: expected the %s event, got %s", name, category, body)
+			}
+		}
+	}
+}
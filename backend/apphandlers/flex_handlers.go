@@ -0,0 +1,74 @@
+package apphandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type FlexHandlers struct {
+	flex       *services.FlexService
+	ticketRepo repositories.TicketRepository
+	logger     *slog.Logger
+}
+
+func NewFlexHandlers(flex *services.FlexService, ticketRepo repositories.TicketRepository, logger *slog.Logger) *FlexHandlers {
+	return &FlexHandlers{
+		flex:       flex,
+		ticketRepo: ticketRepo,
+		logger:     logger,
+	}
+}
+
+// HandleCancelTicket cancels the user's paid ticket and refunds it, when it
+// was bought with a flex add-on whose cutoff has not passed
+func (h *FlexHandlers) HandleCancelTicket(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	_, err = h.flex.Cancel(ticket)
+	switch {
+	case errors.Is(err, services.ErrFlexNotBought):
+		middleware.WriteError(w, http.StatusForbidden, "This ticket was not bought with flex cancellation")
+		return
+	case errors.Is(err, services.ErrFlexUnpaid):
+		middleware.WriteError(w, http.StatusConflict, "Only paid tickets can be cancelled")
+		return
+	case errors.Is(err, services.ErrFlexClosed):
+		middleware.WriteError(w, http.StatusConflict, "The flex cancellation window has closed")
+		return
+	case err != nil:
+		h.logger.Error("Failed to cancel ticket", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to cancel ticket")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket cancelled successfully",
+		Data:    ticket,
+	})
+}
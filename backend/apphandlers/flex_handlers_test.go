@@ -0,0 +1,170 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestFlexCancellation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	addOns := NewAddOnHandlers(store.AddOns(), store.Events(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+	flex := NewFlexHandlers(services.NewFlexService(store.AddOns(), store.Events(), store.Tickets(), store.Payments(), nil, nil, nil, clk, logger), store.Tickets(), logger)
+	analytics := NewAnalyticsHandlers(store.Orders(), store.AddOns(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/addons", addOns.HandleCreateAddOn).Methods("POST")
+	router.HandleFunc("/api/admin/addons/{id:[0-9]+}", addOns.HandleUpdateAddOn).Methods("PUT")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/cancel", flex.HandleCancelTicket).Methods("POST")
+	router.HandleFunc("/api/admin/analytics/flex", analytics.HandleFlexReport).Methods("GET")
+
+	do := func(method, path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	var users []*models.User
+	for _, name := range []string{"Buyer", "Other"} {
+		user := &models.User{Email: name + "@example.com", Name: name}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	buyer, other := users[0], users[1]
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 10000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	eventPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/addons"
+	cutoff, earlyCutoff, day, negative := 48, 96, 24, -1
+	var created []models.AddOn
+	for _, req := range []models.CreateAddOnRequest{
+		{Name: "Flex", PriceSats: 1000, FlexCutoffHours: &cutoff},
+		{Name: "Late flex", PriceSats: 500, FlexCutoffHours: &earlyCutoff},
+		{Name: "Poster", PriceSats: 2000},
+	} {
+		status, data := do("POST", eventPath, nil, req)
+		var addOn models.AddOn
+		json.Unmarshal(data, &addOn)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 creating %s, got %d %s", req.Name, status, data)
+		}
+		created = append(created, addOn)
+	}
+	flexAddOn, lateFlex, poster := created[0], created[1], created[2]
+	if status, _ := do("POST", eventPath, nil, models.CreateAddOnRequest{Name: "Bad", FlexCutoffHours: &negative}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative cutoff, got %d", status)
+	}
+	if status, _ := do("PUT", "/api/admin/addons/"+strconv.Itoa(poster.ID), nil, models.UpdateAddOnRequest{FlexCutoffHours: &day}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 giving a cutoff to a regular add-on, got %d", status)
+	}
+
+	purchase := func(selections ...models.AddOnSelection) (int, models.Ticket) {
+		t.Helper()
+		status, data := do("POST", "/api/tickets/purchase", nil, models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: buyer.ID, UMAAddress: "$buyer@wallet.example.com", AddOns: selections,
+		})
+		var resp struct {
+			Ticket models.Ticket `json:"ticket"`
+		}
+		json.Unmarshal(data, &resp)
+		return status, resp.Ticket
+	}
+	for name, selections := range map[string][]models.AddOnSelection{
+		"two of it":        {{AddOnID: flexAddOn.ID, Quantity: 2}},
+		"two flex add-ons": {{AddOnID: flexAddOn.ID, Quantity: 1}, {AddOnID: lateFlex.ID, Quantity: 1}},
+		"past its cutoff":  {{AddOnID: lateFlex.ID, Quantity: 1}},
+	} {
+		if status, _ := purchase(selections...); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	var flexed, plain models.Ticket
+	var status int
+	if status, flexed = purchase(models.AddOnSelection{AddOnID: flexAddOn.ID, Quantity: 1}, models.AddOnSelection{AddOnID: poster.ID, Quantity: 1}); status != http.StatusCreated {
+		t.Fatalf("Expected 201 buying with flex, got %d", status)
+	}
+	if status, plain = purchase(); status != http.StatusCreated {
+		t.Fatalf("Expected 201 buying without flex, got %d", status)
+	}
+	cancel := func(ticket models.Ticket, user *models.User) int {
+		t.Helper()
+		status, _ := do("POST", "/api/tickets/"+strconv.Itoa(ticket.ID)+"/cancel", user, nil)
+		return status
+	}
+	if status := cancel(flexed, buyer); status != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling an unpaid ticket, got %d", status)
+	}
+	for _, ticket := range []models.Ticket{flexed, plain} {
+		if err := store.Tickets().UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if status := cancel(flexed, other); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for someone else's ticket, got %d", status)
+	}
+	if status := cancel(plain, buyer); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without flex, got %d", status)
+	}
+	if status := cancel(flexed, buyer); status != http.StatusOK {
+		t.Fatalf("Expected 200 cancelling with flex, got %d", status)
+	}
+	if ticket, _ := store.Tickets().GetByID(flexed.ID); ticket.PaymentStatus != "cancelled" {
+		t.Errorf("Expected the ticket cancelled, got %q", ticket.PaymentStatus)
+	}
+	if status := cancel(flexed, buyer); status != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling twice, got %d", status)
+	}
+
+	status, data := do("GET", "/api/admin/analytics/flex?event_id="+strconv.Itoa(event.ID), nil, nil)
+	var report struct {
+		Events []models.FlexUptake `json:"events"`
+		Totals models.FlexUptake   `json:"totals"`
+	}
+	json.Unmarshal(data, &report)
+	if status != http.StatusOK || len(report.Events) != 1 || report.Totals.TicketsSold != 2 || report.Totals.FlexTickets != 1 ||
+		report.Totals.FlexCancellations != 1 || report.Totals.UptakeRate != 0.5 {
+		t.Errorf("Expected half the sold tickets with flex, got %d %s", status, data)
+	}
+	if status, _ := do("GET", "/api/admin/analytics/flex?organizer_id=x", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid organizer_id, got %d", status)
+	}
+}
@@ -0,0 +1,413 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// FormFieldHandlers manages the registration questions events ask at
+// purchase and exports attendees with their answers
+type FormFieldHandlers struct {
+	fieldRepo  repositories.FormFieldRepository
+	eventRepo  repositories.EventRepository
+	ticketRepo repositories.TicketRepository
+	userRepo   repositories.UserRepository
+	logger     *slog.Logger
+}
+
+func NewFormFieldHandlers(
+	fieldRepo repositories.FormFieldRepository,
+	eventRepo repositories.EventRepository,
+	ticketRepo repositories.TicketRepository,
+	userRepo repositories.UserRepository,
+	logger *slog.Logger,
+) *FormFieldHandlers {
+	return &FormFieldHandlers{
+		fieldRepo:  fieldRepo,
+		eventRepo:  eventRepo,
+		ticketRepo: ticketRepo,
+		userRepo:   userRepo,
+		logger:     logger,
+	}
+}
+
+// HandleListFormFields lists the questions asked when buying a ticket for
+// an event
+func (h *FormFieldHandlers) HandleListFormFields(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := h.fieldRepo.GetByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch form fields", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch form fields")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Form fields retrieved successfully",
+		Data:    fields,
+	})
+}
+
+// HandleCreateFormField adds a question to an event's form (admin only)
+func (h *FormFieldHandlers) HandleCreateFormField(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateFormFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	field := &models.FormField{
+		EventID:   event.ID,
+		Label:     strings.TrimSpace(req.Label),
+		FieldType: req.FieldType,
+		Options:   req.Options,
+		Required:  req.Required,
+		Position:  req.Position,
+	}
+	if err := validateFormField(field); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.fieldRepo.Create(field); err != nil {
+		h.logger.Error("Failed to create form field", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create form field")
+		return
+	}
+
+	h.logger.Info("Form field created", "field_id", field.ID, "event_id", event.ID)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Form field created successfully",
+		Data:    field,
+	})
+}
+
+// HandleUpdateFormField changes a question. Answers already given keep the
+// label they were given under (admin only)
+func (h *FormFieldHandlers) HandleUpdateFormField(w http.ResponseWriter, r *http.Request) {
+	field, ok := h.field(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFormFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Label != nil {
+		field.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.FieldType != nil {
+		field.FieldType = *req.FieldType
+	}
+	if req.Options != nil {
+		field.Options = *req.Options
+	}
+	if req.Required != nil {
+		field.Required = *req.Required
+	}
+	if req.Position != nil {
+		field.Position = *req.Position
+	}
+	if err := validateFormField(field); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.fieldRepo.Update(field); err != nil {
+		h.logger.Error("Failed to update form field", "field_id", field.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update form field")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Form field updated successfully",
+		Data:    field,
+	})
+}
+
+// HandleDeleteFormField removes a question from an event's form; answers
+// already given are kept (admin only)
+func (h *FormFieldHandlers) HandleDeleteFormField(w http.ResponseWriter, r *http.Request) {
+	field, ok := h.field(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.fieldRepo.Delete(field.ID); err != nil {
+		h.logger.Error("Failed to delete form field", "field_id", field.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete form field")
+		return
+	}
+
+	h.logger.Info("Form field deleted", "field_id", field.ID, "event_id", field.EventID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Form field deleted successfully",
+	})
+}
+
+// HandleExportAttendees downloads an event's paid tickets as CSV, one row
+// per ticket with the buyer and a column per form field (admin only)
+func (h *FormFieldHandlers) HandleExportAttendees(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := h.fieldRepo.GetByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch form fields", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export attendees")
+		return
+	}
+	tickets, err := h.ticketRepo.GetByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event tickets", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export attendees")
+		return
+	}
+	// Oldest purchase first
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].ID < tickets[j].ID })
+	answers, err := h.fieldRepo.GetAnswersByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch form answers", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export attendees")
+		return
+	}
+
+	// ticket ID -> field ID -> value; answers to deleted fields have no column
+	byTicket := make(map[int]map[int]string)
+	for _, answer := range answers {
+		if answer.FieldID == nil {
+			continue
+		}
+		if byTicket[answer.TicketID] == nil {
+			byTicket[answer.TicketID] = make(map[int]string)
+		}
+		byTicket[answer.TicketID][*answer.FieldID] = answer.Value
+	}
+
+	header := []string{"ticket_id", "ticket_code", "name", "email", "uma_address", "purchased_at"}
+	for _, field := range fields {
+		header = append(header, csvCell(field.Label))
+	}
+
+	users, err := repositories.Preload(tickets, func(t models.Ticket) int { return t.UserID }, h.userRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket holders", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export attendees")
+		return
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write(header)
+	for _, ticket := range tickets {
+		if ticket.PaymentStatus != "paid" {
+			continue
+		}
+
+		row := []string{strconv.Itoa(ticket.ID), ticket.TicketCode, "", "", csvCell(ticket.UMAAddress), ticket.CreatedAt.UTC().Format(time.RFC3339)}
+		if user := users[ticket.UserID]; user != nil {
+			row[2], row[3] = csvCell(user.Name), csvCell(user.Email)
+		}
+		for _, field := range fields {
+			row = append(row, csvCell(byTicket[ticket.ID][field.ID]))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		h.logger.Error("Failed to write attendee export", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export attendees")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="attendees-event-%d.csv"`, event.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// event loads the event named in the route, writing the error response
+// itself when it is missing.
+func (h *FormFieldHandlers) event(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return nil, false
+	}
+
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, false
+	}
+	return event, true
+}
+
+// field loads the form field named in the route, writing the error
+// response itself when it is missing.
+func (h *FormFieldHandlers) field(w http.ResponseWriter, r *http.Request) (*models.FormField, bool) {
+	fieldID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid form field ID")
+		return nil, false
+	}
+
+	field, err := h.fieldRepo.GetByID(fieldID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Form field not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch form field", "field_id", fieldID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch form field")
+		return nil, false
+	}
+	return field, true
+}
+
+func validateFormField(field *models.FormField) error {
+	if field.Label == "" {
+		return fmt.Errorf("label is required")
+	}
+	if utf8.RuneCountInString(field.Label) > 255 {
+		return fmt.Errorf("label must be at most 255 characters")
+	}
+	switch field.FieldType {
+	case models.FormFieldText, models.FormFieldNumber, models.FormFieldCheckbox:
+		if len(field.Options) > 0 {
+			return fmt.Errorf("options are only allowed on select fields")
+		}
+	case models.FormFieldSelect:
+		if len(field.Options) == 0 {
+			return fmt.Errorf("select fields need at least one option")
+		}
+		seen := make(map[string]bool, len(field.Options))
+		for i, option := range field.Options {
+			option = strings.TrimSpace(option)
+			if option == "" {
+				return fmt.Errorf("options cannot be blank")
+			}
+			if seen[option] {
+				return fmt.Errorf("option %q is listed more than once", option)
+			}
+			seen[option] = true
+			field.Options[i] = option
+		}
+	default:
+		return fmt.Errorf("field type must be text, number, select or checkbox")
+	}
+	return nil
+}
+
+// maxAnswerLength caps a single form answer, in characters
+const maxAnswerLength = 1000
+
+// answerForm checks the buyer's answers against an event's form and turns
+// them into answers to store with the ticket. Blank answers count as
+// unanswered; a required checkbox must be checked.
+func answerForm(fields []models.FormField, given []models.FormAnswer) ([]models.TicketAnswer, error) {
+	byID := make(map[int]models.FormField, len(fields))
+	for _, field := range fields {
+		byID[field.ID] = field
+	}
+
+	values := make(map[int]string, len(given))
+	for _, answer := range given {
+		if _, ok := byID[answer.FieldID]; !ok {
+			return nil, fmt.Errorf("field %d is not part of this event's form", answer.FieldID)
+		}
+		if _, dup := values[answer.FieldID]; dup {
+			return nil, fmt.Errorf("field %d is answered more than once", answer.FieldID)
+		}
+		values[answer.FieldID] = strings.TrimSpace(answer.Value)
+	}
+
+	var answers []models.TicketAnswer
+	for _, field := range fields {
+		value := values[field.ID]
+		if utf8.RuneCountInString(value) > maxAnswerLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", field.Label, maxAnswerLength)
+		}
+
+		switch field.FieldType {
+		case models.FormFieldNumber:
+			if value != "" {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return nil, fmt.Errorf("%s must be a number", field.Label)
+				}
+			}
+		case models.FormFieldSelect:
+			if value != "" && !slices.Contains(field.Options, value) {
+				return nil, fmt.Errorf("%s must be one of: %s", field.Label, strings.Join(field.Options, ", "))
+			}
+		case models.FormFieldCheckbox:
+			if value != "" {
+				checked, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s must be true or false", field.Label)
+				}
+				value = strconv.FormatBool(checked)
+			}
+			if field.Required && value != "true" {
+				return nil, fmt.Errorf("%s must be checked", field.Label)
+			}
+		}
+		if value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("%s is required", field.Label)
+			}
+			continue
+		}
+
+		fieldID := field.ID
+		answers = append(answers, models.TicketAnswer{FieldID: &fieldID, Label: field.Label, Value: value})
+	}
+	return answers, nil
+}
+
+// csvCell keeps buyer-entered text from being run as a formula when the
+// export is opened in a spreadsheet
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestRegistrationForm(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	forms := NewFormFieldHandlers(store.FormFields(), store.Events(), store.Tickets(), store.Users(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events/{id:[0-9]+}/form-fields", forms.HandleListFormFields).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/form-fields", forms.HandleCreateFormField).Methods("POST")
+	router.HandleFunc("/api/admin/form-fields/{id:[0-9]+}", forms.HandleUpdateFormField).Methods("PUT")
+	router.HandleFunc("/api/admin/form-fields/{id:[0-9]+}", forms.HandleDeleteFormField).Methods("DELETE")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/attendees.csv", forms.HandleExportAttendees).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	event := &models.Event{Title: "Workshop", Capacity: 10, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	eventPath := "/api/admin/events/" + strconv.Itoa(event.ID)
+
+	do := func(method, path string, body interface{}) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		return rec.Code, rec.Body.Bytes()
+	}
+	create := func(req models.CreateFormFieldRequest) models.FormField {
+		t.Helper()
+		status, body := do("POST", eventPath+"/form-fields", req)
+		var resp struct {
+			Data models.FormField `json:"data"`
+		}
+		json.Unmarshal(body, &resp)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 creating form field, got %d %s", status, body)
+		}
+		return resp.Data
+	}
+
+	for name, req := range map[string]models.CreateFormFieldRequest{
+		"no label":           {FieldType: models.FormFieldText},
+		"unknown type":       {Label: "Age", FieldType: "date"},
+		"select, no options": {Label: "Size", FieldType: models.FormFieldSelect},
+		"duplicate options":  {Label: "Size", FieldType: models.FormFieldSelect, Options: []string{"S", "S"}},
+		"options on text":    {Label: "Diet", FieldType: models.FormFieldText, Options: []string{"Vegan"}},
+	} {
+		if status, _ := do("POST", eventPath+"/form-fields", req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	size := create(models.CreateFormFieldRequest{Label: "Shirt size", FieldType: models.FormFieldSelect, Options: []string{"S", "M", "L"}, Required: true})
+	diet := create(models.CreateFormFieldRequest{Label: "Dietary needs", FieldType: models.FormFieldText, Position: 1})
+	age := create(models.CreateFormFieldRequest{Label: "Age", FieldType: models.FormFieldNumber, Position: 2})
+	terms := create(models.CreateFormFieldRequest{Label: "Accept terms", FieldType: models.FormFieldCheckbox, Required: true, Position: 3})
+
+	status, body := do("GET", "/api/events/"+strconv.Itoa(event.ID)+"/form-fields", nil)
+	var listed struct {
+		Data []models.FormField `json:"data"`
+	}
+	json.Unmarshal(body, &listed)
+	if status != http.StatusOK || len(listed.Data) != 4 || listed.Data[0].ID != size.ID {
+		t.Fatalf("Expected the four fields by position, got %d %s", status, body)
+	}
+
+	purchase := func(userID int, answers ...models.FormAnswer) (int, []byte) {
+		return do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com", Answers: answers,
+		})
+	}
+	valid := []models.FormAnswer{{FieldID: size.ID, Value: "M"}, {FieldID: terms.ID, Value: "true"}}
+
+	for name, answers := range map[string][]models.FormAnswer{
+		"missing required": {{FieldID: terms.ID, Value: "true"}},
+		"unchecked terms":  {{FieldID: size.ID, Value: "M"}, {FieldID: terms.ID, Value: "false"}},
+		"unknown option":   {{FieldID: size.ID, Value: "XXL"}, {FieldID: terms.ID, Value: "true"}},
+		"not a number":     append([]models.FormAnswer{{FieldID: age.ID, Value: "thirty"}}, valid...),
+		"unknown field":    append([]models.FormAnswer{{FieldID: 999, Value: "x"}}, valid...),
+		"answered twice":   append([]models.FormAnswer{{FieldID: size.ID, Value: "S"}}, valid...),
+	} {
+		if status, _ := purchase(1, answers...); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	alice := &models.User{Email: "alice@example.com", Name: "Alice"}
+	bob := &models.User{Email: "bob@example.com", Name: "=HYPERLINK(\"x\")"}
+	for _, user := range []*models.User{alice, bob} {
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, body = purchase(alice.ID, append([]models.FormAnswer{{FieldID: diet.ID, Value: " Vegetarian "}, {FieldID: age.ID, Value: "31"}}, valid...)...)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", status, body)
+	}
+	var bought struct {
+		Data struct {
+			Answers []models.TicketAnswer `json:"answers"`
+		} `json:"data"`
+	}
+	json.Unmarshal(body, &bought)
+	if len(bought.Data.Answers) != 4 || bought.Data.Answers[1].Value != "Vegetarian" {
+		t.Errorf("Expected the four answers in form order, got %s", body)
+	}
+	if status, body := purchase(bob.ID, models.FormAnswer{FieldID: size.ID, Value: "L"}, models.FormAnswer{FieldID: terms.ID, Value: "1"}); status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", status, body)
+	}
+
+	// Removed fields drop out of the export; renamed ones follow the form
+	if status, _ := do("DELETE", "/api/admin/form-fields/"+strconv.Itoa(age.ID), nil); status != http.StatusOK {
+		t.Fatalf("Expected 200 deleting form field, got %d", status)
+	}
+	label := "T-shirt size"
+	if status, _ := do("PUT", "/api/admin/form-fields/"+strconv.Itoa(size.ID), models.UpdateFormFieldRequest{Label: &label}); status != http.StatusOK {
+		t.Fatalf("Expected 200 updating form field, got %d", status)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", eventPath+"/attendees.csv", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("Expected a CSV export, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"ticket_id", "ticket_code", "name", "email", "uma_address", "purchased_at", "T-shirt size", "Dietary needs", "Accept terms"},
+		{"", "", "Alice", "alice@example.com", "$buyer@wallet.example.com", "2026-10-16T12:00:00Z", "M", "Vegetarian", "true"},
+		{"", "", "'=HYPERLINK(\"x\")", "bob@example.com", "$buyer@wallet.example.com", "2026-10-16T12:00:00Z", "L", "", "true"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %q", len(want), rows)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if j < 2 && i > 0 {
+				continue // generated ticket ID and code
+			}
+			if rows[i][j] != want[i][j] {
+				t.Errorf("Row %d column %d = %q, want %q", i, j, rows[i][j], want[i][j])
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/services"
+)
+
+type FraudHandlers struct {
+	fraud  *services.FraudService
+	logger *slog.Logger
+}
+
+func NewFraudHandlers(fraud *services.FraudService, logger *slog.Logger) *FraudHandlers {
+	return &FraudHandlers{
+		fraud:  fraud,
+		logger: logger,
+	}
+}
+
+// HandleListFlags lists purchases flagged by fraud checks, newest first,
+// optionally filtered by action (admin only)
+func (h *FraudHandlers) HandleListFlags(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	switch action {
+	case "", services.FraudActionReview, services.FraudActionBlock:
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "action must be review or block")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	flags, err := h.fraud.List(action, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch fraud flags", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch fraud flags")
+		return
+	}
+	if flags == nil {
+		flags = []models.FraudFlag{}
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Fraud flags retrieved successfully",
+		Data:    flags,
+	})
+}
@@ -0,0 +1,102 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/services"
+)
+
+type GiftHandlers struct {
+	gifts  *services.GiftService
+	logger *slog.Logger
+}
+
+func NewGiftHandlers(gifts *services.GiftService, logger *slog.Logger) *GiftHandlers {
+	return &GiftHandlers{
+		gifts:  gifts,
+		logger: logger,
+	}
+}
+
+// HandleGetGift shows who sent the gift with ?token= from its claim link,
+// for which event, and whether it was already accepted
+func (h *GiftHandlers) HandleGetGift(w http.ResponseWriter, r *http.Request) {
+	preview, err := h.gifts.Preview(r.URL.Query().Get("token"))
+	if errors.Is(err, services.ErrGiftClaimInvalid) {
+		middleware.WriteError(w, http.StatusNotFound, "Gift link is invalid or already used")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch gift", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch gift")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Gift retrieved successfully",
+		Data:    preview,
+	})
+}
+
+// HandleClaimGift accepts a gift with the token from its claim link,
+// moving the ticket to the caller's account
+func (h *GiftHandlers) HandleClaimGift(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.ClaimGiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ticket, err := h.gifts.Claim(req.Token, user.ID)
+	if errors.Is(err, services.ErrGiftClaimInvalid) {
+		middleware.WriteError(w, http.StatusBadRequest, "Gift link is invalid or already used")
+		return
+	}
+	if errors.Is(err, services.ErrGiftOwnClaim) {
+		middleware.WriteError(w, http.StatusConflict, "You cannot accept a gift you bought")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to claim gift", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to accept gift")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Gift accepted successfully",
+		Data:    ticket,
+	})
+}
+
+// HandleGetSentGifts lists the gifts the caller bought with their delivery
+// status, newest first
+func (h *GiftHandlers) HandleGetSentGifts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	gifts, err := h.gifts.Sent(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to list sent gifts", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch gifts")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Gifts retrieved successfully",
+		Data:    gifts,
+	})
+}
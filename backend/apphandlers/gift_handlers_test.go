@@ -0,0 +1,165 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// giftLinkNotifier keeps the gift links sent to each address
+type giftLinkNotifier struct {
+	links map[string]string
+}
+
+func (n *giftLinkNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	if notification.Key == i18n.GiftReceived {
+		n.links[notification.Address] = notification.Args[2].(string)
+	}
+	return nil
+}
+
+func TestGiftHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &giftLinkNotifier{links: make(map[string]string)}
+	gifts := services.NewGiftService(store.TicketGifts(), store.Users(), store.Tickets(), store.Events(), notifier, "tickets.example", clk, logger)
+	handler := NewGiftHandlers(gifts, logger)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, gifts, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/gifts", handler.HandleGetGift).Methods("GET")
+	router.HandleFunc("/api/gifts/claim", handler.HandleClaimGift).Methods("POST")
+	router.HandleFunc("/api/users/me/gifts", handler.HandleGetSentGifts).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	var users []*models.User
+	for _, name := range []string{"Sender", "Friend"} {
+		user := &models.User{Email: strings.ToLower(name) + "@example.com", Name: name}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	sender, friend := users[0], users[1]
+	event := &models.Event{Title: "Gala", Capacity: 10, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	purchase := func(gift *models.GiftRequest) (int, json.RawMessage) {
+		return do("POST", "/api/tickets/purchase", nil, models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: sender.ID, UMAAddress: "$sender@wallet.example.com", Gift: gift,
+		})
+	}
+	late := event.StartTime.Add(time.Hour)
+	for _, tc := range []struct {
+		name string
+		gift models.GiftRequest
+	}{
+		{"no recipient", models.GiftRequest{Message: "Hi"}},
+		{"bad UMA address", models.GiftRequest{RecipientUMAAddress: "friend"}},
+		{"after the event", models.GiftRequest{RecipientEmail: friend.Email, DeliverAt: &late}},
+	} {
+		if status, data := purchase(&tc.gift); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %s", tc.name, status, data)
+		}
+	}
+
+	tomorrow := clk.Now().Add(24 * time.Hour)
+	status, data := purchase(&models.GiftRequest{RecipientEmail: friend.Email, Message: "Enjoy!", DeliverAt: &tomorrow})
+	var resp struct {
+		Ticket models.Ticket     `json:"ticket"`
+		Gift   models.TicketGift `json:"gift"`
+	}
+	json.Unmarshal(data, &resp)
+	if status != http.StatusCreated || resp.Gift.TicketID != resp.Ticket.ID || resp.Gift.Status != models.GiftScheduled {
+		t.Fatalf("Expected the gift scheduled with the ticket, got %d %s", status, data)
+	}
+
+	// Nothing goes out until the ticket is paid and the day has come
+	if err := store.Tickets().UpdatePaymentStatus(resp.Ticket.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if delivered, _ := gifts.DeliverDue(); delivered != 0 {
+		t.Fatalf("Expected no delivery before tomorrow, got %d", delivered)
+	}
+	clk.Advance(24 * time.Hour)
+	if delivered, err := gifts.DeliverDue(); err != nil || delivered != 1 {
+		t.Fatalf("Expected the gift delivered, got %d (%v)", delivered, err)
+	}
+	link := notifier.links[friend.Email]
+	token := strings.TrimPrefix(link, "https://tickets.example/gift?token=")
+	if token == "" || token == link {
+		t.Fatalf("Expected a gift link for the friend, got %q", link)
+	}
+
+	status, data = do("GET", "/api/gifts?token="+token, nil, nil)
+	var preview models.GiftPreview
+	json.Unmarshal(data, &preview)
+	if status != http.StatusOK || preview.SenderName != "Sender" || preview.Message != "Enjoy!" || preview.EventID != event.ID {
+		t.Errorf("Expected the gift preview, got %d %s", status, data)
+	}
+	if status, _ := do("GET", "/api/gifts?token=wrong", nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown token, got %d", status)
+	}
+
+	if status, _ := do("POST", "/api/gifts/claim", nil, models.ClaimGiftRequest{Token: token}); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a user, got %d", status)
+	}
+	if status, _ := do("POST", "/api/gifts/claim", sender, models.ClaimGiftRequest{Token: token}); status != http.StatusConflict {
+		t.Errorf("Expected 409 for the sender, got %d", status)
+	}
+	status, data = do("POST", "/api/gifts/claim", friend, models.ClaimGiftRequest{Token: token})
+	var ticket models.Ticket
+	json.Unmarshal(data, &ticket)
+	if status != http.StatusOK || ticket.ID != resp.Ticket.ID || ticket.UserID != friend.ID {
+		t.Fatalf("Expected the ticket given to the friend, got %d %s", status, data)
+	}
+	if status, _ := do("POST", "/api/gifts/claim", friend, models.ClaimGiftRequest{Token: token}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a used link, got %d", status)
+	}
+
+	status, data = do("GET", "/api/users/me/gifts", sender, nil)
+	var sent []models.TicketGift
+	json.Unmarshal(data, &sent)
+	if status != http.StatusOK || len(sent) != 1 || sent[0].Status != models.GiftClaimed {
+		t.Errorf("Expected the claimed gift listed for the sender, got %d %s", status, data)
+	}
+}
@@ -0,0 +1,143 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type HoldHandlers struct {
+	holdRepo  repositories.InventoryHoldRepository
+	eventRepo repositories.EventRepository
+	logger    *slog.Logger
+}
+
+func NewHoldHandlers(holdRepo repositories.InventoryHoldRepository, eventRepo repositories.EventRepository, logger *slog.Logger) *HoldHandlers {
+	return &HoldHandlers{
+		holdRepo:  holdRepo,
+		eventRepo: eventRepo,
+		logger:    logger,
+	}
+}
+
+// HandleCreateHold sets seats of an event aside so they are not sold
+// publicly, such as for a sponsor block or production holds (admin only)
+func (h *HoldHandlers) HandleCreateHold(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	hold := &models.InventoryHold{
+		EventID:   eventID,
+		Name:      strings.TrimSpace(req.Name),
+		Seats:     req.Seats,
+		CreatedBy: adminID(r),
+	}
+	if hold.Name == "" || utf8.RuneCountInString(hold.Name) > 100 {
+		middleware.WriteError(w, http.StatusBadRequest, "name is required and must be at most 100 characters")
+		return
+	}
+	if hold.Seats <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "seats must be positive")
+		return
+	}
+
+	err = h.holdRepo.Create(hold)
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Event has fewer unsold seats than the hold")
+		return
+	case err != nil:
+		h.logger.Error("Failed to create hold", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create hold")
+		return
+	}
+
+	h.logger.Info("Seats held", "hold_id", hold.ID, "event_id", eventID, "seats", hold.Seats)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Hold created successfully",
+		Data:    hold,
+	})
+}
+
+// HandleListHolds lists an event's holds, released ones included (admin
+// only)
+func (h *HoldHandlers) HandleListHolds(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	if _, err := h.eventRepo.GetByID(eventID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			middleware.WriteError(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	holds, err := h.holdRepo.GetByEventID(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch holds", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch holds")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Holds retrieved successfully",
+		Data:    holds,
+	})
+}
+
+// HandleReleaseHold returns a hold's seats to public sale (admin only)
+func (h *HoldHandlers) HandleReleaseHold(w http.ResponseWriter, r *http.Request) {
+	holdID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	hold, err := h.holdRepo.Release(holdID, adminID(r))
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Hold not found")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Hold is already released")
+		return
+	case err != nil:
+		h.logger.Error("Failed to release hold", "hold_id", holdID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to release hold")
+		return
+	}
+
+	h.logger.Info("Hold released", "hold_id", hold.ID, "event_id", hold.EventID, "seats", hold.Seats)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Hold released successfully",
+		Data:    hold,
+	})
+}
@@ -0,0 +1,119 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestHoldHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	holds := NewHoldHandlers(store.InventoryHolds(), store.Events(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/holds", holds.HandleCreateHold).Methods("POST")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/holds", holds.HandleListHolds).Methods("GET")
+	router.HandleFunc("/api/admin/holds/{id:[0-9]+}", holds.HandleReleaseHold).Methods("DELETE")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	event := &models.Event{Title: "Gala", Capacity: 3, PriceSats: 10000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	sold := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "GALA-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(sold); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/admin/events/" + strconv.Itoa(event.ID) + "/holds"
+
+	admin := &models.User{ID: 42}
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	purchase := func() int {
+		t.Helper()
+		status, _ := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: 2, UMAAddress: "$buyer@wallet.example.com",
+		})
+		return status
+	}
+
+	for name, tt := range map[string]struct {
+		path string
+		body models.CreateHoldRequest
+		want int
+	}{
+		"unknown event": {"/api/admin/events/9999/holds", models.CreateHoldRequest{Name: "Sponsor", Seats: 1}, http.StatusNotFound},
+		"no name":       {path, models.CreateHoldRequest{Name: " ", Seats: 1}, http.StatusBadRequest},
+		"no seats":      {path, models.CreateHoldRequest{Name: "Sponsor"}, http.StatusBadRequest},
+		"too many":      {path, models.CreateHoldRequest{Name: "Sponsor", Seats: 3}, http.StatusConflict},
+	} {
+		if status, data := do("POST", tt.path, tt.body); status != tt.want {
+			t.Errorf("%s: expected %d, got %d %s", name, tt.want, status, data)
+		}
+	}
+
+	status, data := do("POST", path, models.CreateHoldRequest{Name: "Sponsor block", Seats: 2})
+	var hold models.InventoryHold
+	json.Unmarshal(data, &hold)
+	if status != http.StatusCreated || hold.Seats != 2 || hold.CreatedBy == nil || *hold.CreatedBy != admin.ID {
+		t.Fatalf("Expected the hold created, got %d %s", status, data)
+	}
+
+	// Held seats are not sold publicly
+	if status := purchase(); status != http.StatusBadRequest {
+		t.Errorf("Expected the held event sold out, got %d", status)
+	}
+
+	release := "/api/admin/holds/" + strconv.Itoa(hold.ID)
+	if status, _ := do("DELETE", release, nil); status != http.StatusOK {
+		t.Fatalf("Expected the hold released, got %d", status)
+	}
+	if status, _ := do("DELETE", release, nil); status != http.StatusConflict {
+		t.Errorf("Expected a second release refused, got %d", status)
+	}
+	if status, _ := do("DELETE", "/api/admin/holds/9999", nil); status != http.StatusNotFound {
+		t.Errorf("Expected an unknown hold refused, got %d", status)
+	}
+	if status := purchase(); status != http.StatusCreated {
+		t.Errorf("Expected released seats on sale, got %d", status)
+	}
+
+	status, data = do("GET", path, nil)
+	var listed []models.InventoryHold
+	json.Unmarshal(data, &listed)
+	if status != http.StatusOK || len(listed) != 1 || listed[0].ReleasedAt == nil || *listed[0].ReleasedBy != admin.ID {
+		t.Errorf("Expected the released hold listed, got %d %s", status, data)
+	}
+}
@@ -0,0 +1,110 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// ImpersonationHandlers let support staff see the site exactly as a buyer
+// does, through a short-lived token that can only read
+type ImpersonationHandlers struct {
+	userRepo repositories.UserRepository
+	tokens   *middleware.Tokens
+	isAdmin  func(email string) bool
+	ttl      time.Duration
+	logger   *slog.Logger
+}
+
+// NewImpersonationHandlers creates impersonation handlers issuing tokens
+// lasting ttl. isAdmin reports the admins, who cannot be impersonated.
+func NewImpersonationHandlers(userRepo repositories.UserRepository, tokens *middleware.Tokens, isAdmin func(email string) bool, ttl time.Duration, logger *slog.Logger) *ImpersonationHandlers {
+	return &ImpersonationHandlers{
+		userRepo: userRepo,
+		tokens:   tokens,
+		isAdmin:  isAdmin,
+		ttl:      ttl,
+		logger:   logger,
+	}
+}
+
+// HandleImpersonate issues a read-only token acting as the user in the path
+// (admin only). The reason is required for the audit log.
+func (h *ImpersonationHandlers) HandleImpersonate(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Reason is required")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	if admin.ID == userID {
+		middleware.WriteError(w, http.StatusBadRequest, "Cannot impersonate yourself")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch user", "user_id", userID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+	if h.isAdmin(user.Email) {
+		h.logger.Warn("Impersonation of an admin refused", "audit", true, "admin_id", admin.ID, "user_id", user.ID)
+		middleware.WriteError(w, http.StatusForbidden, "Admins cannot be impersonated")
+		return
+	}
+
+	imp := &middleware.Impersonation{
+		AdminID:    admin.ID,
+		AdminEmail: admin.Email,
+		Reason:     req.Reason,
+		Banner:     fmt.Sprintf("You are viewing as %s, impersonated by %s. Changes are disabled.", user.Email, admin.Email),
+	}
+	token, expiresAt, err := h.tokens.GenerateImpersonation(user, imp, h.ttl)
+	if err != nil {
+		h.logger.Error("Failed to generate token", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.logger.Info("Impersonation started", "audit", true,
+		"admin_id", admin.ID, "admin_email", admin.Email, "user_id", user.ID,
+		"reason", req.Reason, "expires_at", expiresAt)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Impersonation started",
+		Data: models.ImpersonationResponse{
+			Token:     token,
+			User:      user,
+			Banner:    imp.Banner,
+			ExpiresAt: expiresAt,
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestImpersonationHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	tokens := middleware.NewTokens(func() string { return "test-secret" }, nil, time.Time{})
+	isAdmin := func(email string) bool { return strings.HasPrefix(email, "admin") }
+	handler := NewImpersonationHandlers(store.Users(), tokens, isAdmin, 15*time.Minute, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/impersonate/{user_id:[0-9]+}", handler.HandleImpersonate).Methods("POST")
+
+	admin := &models.User{Email: "admin@example.com", Name: "Admin"}
+	otherAdmin := &models.User{Email: "admin2@example.com", Name: "Other Admin"}
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	for _, user := range []*models.User{admin, otherAdmin, buyer} {
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do := func(userID int, reason string) (int, *models.ImpersonationResponse) {
+		t.Helper()
+		payload, _ := json.Marshal(models.ImpersonateRequest{Reason: reason})
+		req := httptest.NewRequest("POST", "/api/admin/impersonate/"+strconv.Itoa(userID), bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data *models.ImpersonationResponse `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	status, data := do(buyer.ID, "Support ticket 1234")
+	if status != http.StatusOK || data == nil || data.User.ID != buyer.ID || !strings.Contains(data.Banner, "buyer@example.com") {
+		t.Fatalf("Expected an impersonation token for the buyer, got %d %+v", status, data)
+	}
+	if until := time.Until(data.ExpiresAt); until > 15*time.Minute || until < 14*time.Minute {
+		t.Errorf("Expected a 15 minute token, expires in %v", until)
+	}
+	claims, err := tokens.Validate(data.Token)
+	if err != nil || claims.UserID != buyer.ID || claims.Impersonation == nil ||
+		claims.Impersonation.AdminID != admin.ID || claims.Impersonation.Reason != "Support ticket 1234" || claims.Impersonation.Banner != data.Banner {
+		t.Errorf("Expected the token to name the admin and carry the banner, got %+v (%v)", claims, err)
+	}
+
+	for name, tt := range map[string]struct {
+		userID int
+		reason string
+		want   int
+	}{
+		"no reason":     {buyer.ID, "  ", http.StatusBadRequest},
+		"self":          {admin.ID, "Testing", http.StatusBadRequest},
+		"unknown user":  {buyer.ID + 100, "Testing", http.StatusNotFound},
+		"another admin": {otherAdmin.ID, "Testing", http.StatusForbidden},
+	} {
+		if status, _ := do(tt.userID, tt.reason); status != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, status)
+		}
+	}
+}
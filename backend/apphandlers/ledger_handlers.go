@@ -0,0 +1,314 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type LedgerHandlers struct {
+	ledger        *services.LedgerService
+	ledgerRepo    repositories.LedgerRepository
+	userRepo      repositories.UserRepository
+	affiliateRepo repositories.AffiliateRepository
+	payouts       *services.PayoutService
+	logger        *slog.Logger
+}
+
+func NewLedgerHandlers(ledger *services.LedgerService, ledgerRepo repositories.LedgerRepository, userRepo repositories.UserRepository, affiliateRepo repositories.AffiliateRepository, payouts *services.PayoutService, logger *slog.Logger) *LedgerHandlers {
+	return &LedgerHandlers{
+		ledger:        ledger,
+		ledgerRepo:    ledgerRepo,
+		userRepo:      userRepo,
+		affiliateRepo: affiliateRepo,
+		payouts:       payouts,
+		logger:        logger,
+	}
+}
+
+// HandleListAccounts returns every ledger account with its debits, credits
+// and balance (admin only)
+func (h *LedgerHandlers) HandleListAccounts(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.ledgerRepo.Balances()
+	if err != nil {
+		h.logger.Error("Failed to fetch ledger balances", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ledger accounts")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ledger accounts retrieved successfully",
+		Data:    balances,
+	})
+}
+
+// HandleListEntries lists ledger entries with their postings, newest first,
+// optionally only those posting to account_id (admin only)
+func (h *LedgerHandlers) HandleListEntries(w http.ResponseWriter, r *http.Request) {
+	accountID := 0
+	if accountStr := r.URL.Query().Get("account_id"); accountStr != "" {
+		id, err := strconv.Atoi(accountStr)
+		if err != nil || id <= 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid account ID")
+			return
+		}
+		accountID = id
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	entries, err := h.ledgerRepo.ListEntries(accountID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch ledger entries", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ledger entries")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ledger entries retrieved successfully",
+		Data:    entries,
+	})
+}
+
+// HandleRecordRefund books the full refund of a paid payment, sent to the
+// buyer outside the app, settling a refund owed for a cancelled ticket
+// (admin only)
+func (h *LedgerHandlers) HandleRecordRefund(w http.ResponseWriter, r *http.Request) {
+	var req models.RecordRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PaymentID <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "payment_id is required")
+		return
+	}
+
+	entry, err := h.ledger.RecordRefund(req.PaymentID, req.Reference)
+	switch {
+	case errors.Is(err, services.ErrNotPosted):
+		middleware.WriteError(w, http.StatusNotFound, "Payment has no sale to refund")
+		return
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Payment is already refunded")
+		return
+	case err != nil:
+		h.logger.Error("Failed to record refund", "payment_id", req.PaymentID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to record refund")
+		return
+	}
+
+	h.logger.Info("Refund recorded", "payment_id", req.PaymentID, "entry_id", entry.ID)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Refund recorded successfully",
+		Data:    entry,
+	})
+}
+
+// HandleRecordPayout books sats sent to an organizer, or commission sent
+// to an affiliate, outside the app, up to what the ledger says they are
+// owed (admin only)
+func (h *LedgerHandlers) HandleRecordPayout(w http.ResponseWriter, r *http.Request) {
+	var req models.RecordPayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.AmountSats <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "amount_sats must be positive")
+		return
+	}
+	if (req.OrganizerID == 0) == (req.AffiliateID == 0) {
+		middleware.WriteError(w, http.StatusBadRequest, "Exactly one of organizer_id and affiliate_id is required")
+		return
+	}
+
+	payee, id, notFound := "organizer", req.OrganizerID, "Organizer not found"
+	var err error
+	if req.AffiliateID != 0 {
+		payee, id, notFound = "affiliate", req.AffiliateID, "Affiliate not found"
+		_, err = h.affiliateRepo.GetByID(id)
+	} else {
+		_, err = h.userRepo.GetByID(id)
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, notFound)
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to fetch payee", payee+"_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to record payout")
+		return
+	}
+
+	var entry *models.LedgerEntry
+	if req.AffiliateID != 0 {
+		entry, err = h.ledger.RecordAffiliatePayout(id, req.AmountSats, req.Reference)
+	} else {
+		entry, err = h.ledger.RecordPayout(id, req.AmountSats, req.Reference)
+	}
+	if errors.Is(err, services.ErrPayoutExceedsBalance) {
+		middleware.WriteError(w, http.StatusConflict, "Payout exceeds what the "+payee+" is owed")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to record payout", payee+"_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to record payout")
+		return
+	}
+
+	h.logger.Info("Payout recorded", payee+"_id", id, "amount_sats", req.AmountSats, "entry_id", entry.ID)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Payout recorded successfully",
+		Data:    entry,
+	})
+}
+
+// HandleSendPayout pays an organizer, or an affiliate their commission,
+// over Lightning and books the payout, up to what the ledger says they are
+// owed. The sats go to the destination given, or else to the payee's
+// payout address (admin only).
+func (h *LedgerHandlers) HandleSendPayout(w http.ResponseWriter, r *http.Request) {
+	var req models.SendPayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.AmountSats <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "amount_sats must be positive")
+		return
+	}
+	if (req.OrganizerID == 0) == (req.AffiliateID == 0) {
+		middleware.WriteError(w, http.StatusBadRequest, "Exactly one of organizer_id and affiliate_id is required")
+		return
+	}
+
+	payee, id, notFound := "organizer", req.OrganizerID, "Organizer not found"
+	var err error
+	if req.AffiliateID != 0 {
+		payee, id, notFound = "affiliate", req.AffiliateID, "Affiliate not found"
+		_, err = h.affiliateRepo.GetByID(id)
+	} else {
+		_, err = h.userRepo.GetByID(id)
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, notFound)
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to fetch payee", payee+"_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to send payout")
+		return
+	}
+
+	var entry *models.LedgerEntry
+	if req.AffiliateID != 0 {
+		entry, err = h.payouts.PayAffiliate(r.Context(), id, req.AmountSats, req.Destination)
+	} else {
+		entry, err = h.payouts.PayOrganizer(r.Context(), id, req.AmountSats, req.Destination)
+	}
+	switch {
+	case errors.Is(err, services.ErrPayoutExceedsBalance):
+		middleware.WriteError(w, http.StatusConflict, "Payout exceeds what the "+payee+" is owed")
+		return
+	case errors.Is(err, services.ErrNoPayoutAddress), errors.Is(err, services.ErrPayoutDestination):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, lnurl.ErrPayRequest), errors.Is(err, lnurl.ErrAmountOutOfRange), errors.Is(err, services.ErrPayoutFailed):
+		h.logger.Warn("Payout not sent", payee+"_id", id, "amount_sats", req.AmountSats, "error", err)
+		middleware.WriteError(w, http.StatusBadGateway, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("Failed to send payout", payee+"_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to send payout")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Payout sent successfully",
+		Data:    entry,
+	})
+}
+
+// HandleSendRefund sends the refund owed for a cancelled ticket's payment
+// over Lightning and books it. The sats go to the destination given, or
+// else to the address the ticket was bought from (admin only).
+func (h *LedgerHandlers) HandleSendRefund(w http.ResponseWriter, r *http.Request) {
+	var req models.SendRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PaymentID <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "payment_id is required")
+		return
+	}
+
+	entry, err := h.payouts.PayRefund(r.Context(), req.PaymentID, req.Destination)
+	switch {
+	case errors.Is(err, services.ErrNoRefundOwed):
+		middleware.WriteError(w, http.StatusConflict, "No refund is owed for this payment")
+		return
+	case errors.Is(err, services.ErrNoPayoutAddress), errors.Is(err, services.ErrPayoutDestination):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, lnurl.ErrPayRequest), errors.Is(err, lnurl.ErrAmountOutOfRange), errors.Is(err, services.ErrPayoutFailed):
+		h.logger.Warn("Refund not sent", "payment_id", req.PaymentID, "error", err)
+		middleware.WriteError(w, http.StatusBadGateway, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("Failed to send refund", "payment_id", req.PaymentID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to send refund")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Refund sent successfully",
+		Data:    entry,
+	})
+}
+
+// HandleCheck books any new sales and returns the ledger's broken
+// invariants, the same check the background job logs (admin only)
+func (h *LedgerHandlers) HandleCheck(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.ledger.Sync(); err != nil {
+		h.logger.Error("Failed to book sales in the ledger", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check the ledger")
+		return
+	}
+	issues, err := h.ledger.Check()
+	if err != nil {
+		h.logger.Error("Failed to check the ledger", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check the ledger")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ledger checked successfully",
+		Data: map[string]interface{}{
+			"consistent": len(issues) == 0,
+			"issues":     issues,
+		},
+	})
+}
@@ -0,0 +1,186 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestLedgerHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	uma := services.NewSimulatedUMAService(0, clk, logger)
+	payouts := services.NewPayoutService(ledger, store.OrganizerProfiles(), store.Affiliates(), store.Payments(), store.Tickets(), uma, &http.Client{}, logger)
+	handler := NewLedgerHandlers(ledger, store.Ledger(), store.Users(), store.Affiliates(), payouts, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/ledger/accounts", handler.HandleListAccounts).Methods("GET")
+	router.HandleFunc("/api/admin/ledger/entries", handler.HandleListEntries).Methods("GET")
+	router.HandleFunc("/api/admin/ledger/refunds", handler.HandleRecordRefund).Methods("POST")
+	router.HandleFunc("/api/admin/ledger/payouts", handler.HandleRecordPayout).Methods("POST")
+	router.HandleFunc("/api/admin/ledger/payouts/send", handler.HandleSendPayout).Methods("POST")
+	router.HandleFunc("/api/admin/ledger/refunds/send", handler.HandleSendRefund).Methods("POST")
+	router.HandleFunc("/api/admin/ledger/check", handler.HandleCheck).Methods("GET")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	organizer := &models.User{Email: "organizer@example.com", Name: "Organizer"}
+	if err := store.Users().Create(organizer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true, OrganizerID: &organizer.ID}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var payments []*models.Payment
+	for i := 0; i < 2; i++ {
+		ticket := &models.Ticket{EventID: event.ID, UserID: organizer.ID, TicketCode: "LEDGER-" + strconv.Itoa(i), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-ledger-" + strconv.Itoa(i), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+
+	// The check books the paid sales first
+	status, data := do("GET", "/api/admin/ledger/check", nil)
+	if status != http.StatusOK || string(data) != `{"consistent":true,"issues":[]}` {
+		t.Fatalf("Expected a consistent ledger, got %d %s", status, data)
+	}
+
+	status, data = do("GET", "/api/admin/ledger/accounts", nil)
+	var accounts []models.LedgerBalance
+	json.Unmarshal(data, &accounts)
+	if status != http.StatusOK || len(accounts) != 2 || accounts[0].Name != "Assets:Lightning Wallet" || accounts[0].BalanceSats != 2000 ||
+		accounts[1].Name != "Liabilities:Organizer Payable:1" || accounts[1].BalanceSats != 2000 {
+		t.Fatalf("Unexpected accounts %d %s", status, data)
+	}
+
+	// The organizer's wallet hands out invoices from the simulated node,
+	// which pays its own invoices
+	invoice := func(amountSats int64) string {
+		t.Helper()
+		inv, err := uma.CreateTicketInvoice("$organizer@wallet.example", amountSats, "payout", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inv.Bolt11
+	}
+
+	for _, tt := range []struct {
+		name   string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"refund without payment", "/api/admin/ledger/refunds", models.RecordRefundRequest{}, http.StatusBadRequest},
+		{"refund of unknown payment", "/api/admin/ledger/refunds", models.RecordRefundRequest{PaymentID: 99}, http.StatusNotFound},
+		{"refund", "/api/admin/ledger/refunds", models.RecordRefundRequest{PaymentID: payments[0].ID, Reference: "refund-1"}, http.StatusCreated},
+		{"second refund", "/api/admin/ledger/refunds", models.RecordRefundRequest{PaymentID: payments[0].ID}, http.StatusConflict},
+		{"payout of nothing", "/api/admin/ledger/payouts", models.RecordPayoutRequest{OrganizerID: organizer.ID}, http.StatusBadRequest},
+		{"payout to unknown organizer", "/api/admin/ledger/payouts", models.RecordPayoutRequest{OrganizerID: 99, AmountSats: 1}, http.StatusNotFound},
+		{"payout to both", "/api/admin/ledger/payouts", models.RecordPayoutRequest{OrganizerID: organizer.ID, AffiliateID: 1, AmountSats: 1}, http.StatusBadRequest},
+		{"payout to unknown affiliate", "/api/admin/ledger/payouts", models.RecordPayoutRequest{AffiliateID: 99, AmountSats: 1}, http.StatusNotFound},
+		{"payout over balance", "/api/admin/ledger/payouts", models.RecordPayoutRequest{OrganizerID: organizer.ID, AmountSats: 1001}, http.StatusConflict},
+		{"send without address", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{OrganizerID: organizer.ID, AmountSats: 400}, http.StatusBadRequest},
+		{"send to unknown organizer", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{OrganizerID: 99, AmountSats: 400, Destination: invoice(400)}, http.StatusNotFound},
+		{"send to unknown affiliate", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{AffiliateID: 99, AmountSats: 400, Destination: invoice(400)}, http.StatusNotFound},
+		{"send to invoice for another amount", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{OrganizerID: organizer.ID, AmountSats: 400, Destination: invoice(300)}, http.StatusBadRequest},
+		{"send over balance", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{OrganizerID: organizer.ID, AmountSats: 1001, Destination: invoice(1001)}, http.StatusConflict},
+		{"send", "/api/admin/ledger/payouts/send", models.SendPayoutRequest{OrganizerID: organizer.ID, AmountSats: 400, Destination: invoice(400)}, http.StatusCreated},
+		{"payout", "/api/admin/ledger/payouts", models.RecordPayoutRequest{OrganizerID: organizer.ID, AmountSats: 600, Reference: "tx-1"}, http.StatusCreated},
+	} {
+		if status, data := do("POST", tt.path, tt.body); status != tt.status {
+			t.Errorf("%s: expected %d, got %d %s", tt.name, tt.status, status, data)
+		}
+	}
+
+	if status, _ := do("GET", "/api/admin/ledger/entries?account_id=x", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid account, got %d", status)
+	}
+	status, data = do("GET", "/api/admin/ledger/entries?account_id="+strconv.Itoa(accounts[1].ID)+"&limit=3", nil)
+	var entries []models.LedgerEntry
+	json.Unmarshal(data, &entries)
+	if status != http.StatusOK || len(entries) != 3 || entries[0].Kind != models.LedgerEntryPayout ||
+		entries[1].Kind != models.LedgerEntryPayout || !strings.HasPrefix(entries[1].Reference, "lightning:sim_pay_") ||
+		entries[2].Kind != models.LedgerEntryRefund || entries[2].Reference != "refund-1" || len(entries[2].Postings) != 2 {
+		t.Errorf("Expected the payouts and refund, got %d %s", status, data)
+	}
+
+	// A cancelled ticket's refund is owed until it is sent
+	ticket := &models.Ticket{EventID: event.ID, UserID: organizer.ID, TicketCode: "LEDGER-2", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	cancelled := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-ledger-2", Amount: 1000, Status: "pending"}
+	if err := store.Payments().Create(cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(cancelled.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.OweRefund(cancelled.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		body   interface{}
+		status int
+	}{
+		{"refund without payment", models.SendRefundRequest{}, http.StatusBadRequest},
+		{"refund sent outside the app", models.SendRefundRequest{PaymentID: payments[0].ID, Destination: invoice(1000)}, http.StatusConflict},
+		{"refund without address", models.SendRefundRequest{PaymentID: cancelled.ID}, http.StatusBadRequest},
+		{"refund to invoice for another amount", models.SendRefundRequest{PaymentID: cancelled.ID, Destination: invoice(900)}, http.StatusBadRequest},
+		{"refund", models.SendRefundRequest{PaymentID: cancelled.ID, Destination: invoice(1000)}, http.StatusCreated},
+		{"second refund", models.SendRefundRequest{PaymentID: cancelled.ID, Destination: invoice(1000)}, http.StatusConflict},
+	} {
+		if status, data := do("POST", "/api/admin/ledger/refunds/send", tt.body); status != tt.status {
+			t.Errorf("%s: expected %d, got %d %s", tt.name, tt.status, status, data)
+		}
+	}
+	if status, _ := do("POST", "/api/admin/ledger/refunds", models.RecordRefundRequest{PaymentID: cancelled.ID}); status != http.StatusConflict {
+		t.Errorf("Expected 409 recording a refund already sent, got %d", status)
+	}
+
+	status, data = do("GET", "/api/admin/ledger/accounts", nil)
+	json.Unmarshal(data, &accounts)
+	if status != http.StatusOK || len(accounts) != 3 || accounts[0].BalanceSats != 0 || accounts[1].BalanceSats != 0 ||
+		accounts[2].Name != "Liabilities:Refunds Payable:3" || accounts[2].BalanceSats != 0 {
+		t.Errorf("Expected empty balances after refund and payout, got %s", data)
+	}
+}
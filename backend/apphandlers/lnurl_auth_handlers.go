@@ -0,0 +1,135 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/services"
+)
+
+type LNURLAuthHandlers struct {
+	auth   *services.LNURLAuthService
+	tokens *middleware.Tokens
+	logger *slog.Logger
+}
+
+func NewLNURLAuthHandlers(auth *services.LNURLAuthService, tokens *middleware.Tokens, logger *slog.Logger) *LNURLAuthHandlers {
+	return &LNURLAuthHandlers{
+		auth:   auth,
+		tokens: tokens,
+		logger: logger,
+	}
+}
+
+// HandleGetChallenge returns a login challenge for a Lightning wallet: the
+// LNURL to show as a QR code and the session secret to poll with
+func (h *LNURLAuthHandlers) HandleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	login, err := h.auth.Challenge(nil)
+	if err != nil {
+		h.logger.Error("Failed to create LNURL-auth challenge", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create login challenge")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Login challenge created successfully",
+		Data:    login,
+	})
+}
+
+// HandleLinkChallenge returns a challenge that adds the wallet signing it
+// as a login method of the authenticated user
+func (h *LNURLAuthHandlers) HandleLinkChallenge(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	login, err := h.auth.Challenge(&user.ID)
+	if err != nil {
+		h.logger.Error("Failed to create LNURL-auth challenge", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create login challenge")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Login challenge created successfully",
+		Data:    login,
+	})
+}
+
+// HandleCallback is called by the wallet with its signature of k1 and its
+// linking key (LUD-04). Wallets show the reason of an ERROR status to their
+// user, so refusals are answered with 200 like LNURL services do.
+func (h *LNURLAuthHandlers) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("tag") != "login" || query.Get("k1") == "" || query.Get("sig") == "" || query.Get("key") == "" {
+		middleware.WriteJSON(w, http.StatusOK, models.LNURLResponse{Status: "ERROR", Reason: "Missing login parameters"})
+		return
+	}
+
+	err := h.auth.Callback(query.Get("k1"), query.Get("sig"), query.Get("key"))
+	switch {
+	case err == nil:
+		middleware.WriteJSON(w, http.StatusOK, models.LNURLResponse{Status: "OK"})
+	case errors.Is(err, lnurl.ErrInvalidSignature):
+		middleware.WriteJSON(w, http.StatusOK, models.LNURLResponse{Status: "ERROR", Reason: "Invalid signature"})
+	case errors.Is(err, services.ErrLNURLAuthInvalid):
+		middleware.WriteJSON(w, http.StatusOK, models.LNURLResponse{Status: "ERROR", Reason: "Login challenge is invalid or expired"})
+	case errors.Is(err, services.ErrLNURLAuthKeyTaken):
+		middleware.WriteJSON(w, http.StatusOK, models.LNURLResponse{Status: "ERROR", Reason: "This wallet is linked to another account"})
+	default:
+		h.logger.Error("Failed to log in with LNURL-auth", "error", err)
+		middleware.WriteJSON(w, http.StatusInternalServerError, models.LNURLResponse{Status: "ERROR", Reason: "Failed to log in"})
+	}
+}
+
+// HandlePoll logs the browser in once the wallet has signed the challenge
+// of its session. Responds 202 until then.
+func (h *LNURLAuthHandlers) HandlePoll(w http.ResponseWriter, r *http.Request) {
+	var req models.LNURLAuthPollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Session == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.auth.Poll(req.Session)
+	if errors.Is(err, services.ErrLNURLAuthPending) {
+		middleware.WriteJSON(w, http.StatusAccepted, models.SuccessResponse{
+			Message: "Waiting for the wallet to sign",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrLNURLAuthInvalid) {
+		middleware.WriteError(w, http.StatusNotFound, "Login challenge is invalid, expired or already used")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to log in with LNURL-auth", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	token, err := h.tokens.Generate(user)
+	if err != nil {
+		h.logger.Error("Failed to generate token", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.logger.Info("User logged in with a Lightning wallet", "user_id", user.ID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Login successful",
+		Data: models.AuthResponse{
+			Token: token,
+			User:  user,
+		},
+	})
+}
@@ -0,0 +1,185 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestLNURLAuthHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	secret := func() string { return "test-secret" }
+	tokens := middleware.NewTokens(secret, nil, time.Time{})
+	auth := services.NewLNURLAuthService(store.LNURLAuth(), store.Users(), "tickets.example.com", clk, logger)
+	handlers := NewLNURLAuthHandlers(auth, tokens, logger)
+	users := NewUserHandlers(store.Users(), store.NWCConnections(), nil, nil, auth, newTestPasswordPolicy(logger), logger, tokens)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/lnurl", handlers.HandleGetChallenge).Methods("GET")
+	router.HandleFunc("/api/auth/lnurl/callback", handlers.HandleCallback).Methods("GET")
+	router.HandleFunc("/api/auth/lnurl/session", handlers.HandlePoll).Methods("POST")
+	router.HandleFunc("/api/users/login", users.HandleLogin).Methods("POST")
+	protected := router.PathPrefix("/api").Subrouter()
+	protected.Use(middleware.RotatingAuthMiddleware(secret, func(userID int) (int, error) {
+		user, err := store.Users().GetByID(userID)
+		if err != nil {
+			return 0, err
+		}
+		return user.SessionVersion, nil
+	}))
+	protected.HandleFunc("/users/me/lnurl-auth", handlers.HandleLinkChallenge).Methods("POST")
+	protected.HandleFunc("/users/me/credentials", users.HandleGetCredentials).Methods("GET")
+	protected.HandleFunc("/users/me/credentials/{method}", users.HandleDeleteCredential).Methods("DELETE")
+
+	do := func(method, path, token string, body interface{}) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+	challenge := func(method, path, token string) models.LNURLAuthLogin {
+		t.Helper()
+		status, body := do(method, path, token, nil)
+		var resp struct {
+			Data models.LNURLAuthLogin `json:"data"`
+		}
+		json.Unmarshal(body, &resp)
+		if status != http.StatusOK || resp.Data.LNURL == "" || resp.Data.Session == "" {
+			t.Fatalf("Expected a challenge, got %d %s", status, body)
+		}
+		return resp.Data
+	}
+	// sign calls back as the wallet, returning the LNURL status
+	sign := func(login models.LNURLAuthLogin, key *secp256k1.PrivateKey) models.LNURLResponse {
+		t.Helper()
+		callback, err := url.Parse(login.Callback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k1, _ := hex.DecodeString(login.K1)
+		query := callback.Query()
+		query.Set("sig", hex.EncodeToString(ecdsa.Sign(key, k1).Serialize()))
+		query.Set("key", hex.EncodeToString(key.PubKey().SerializeCompressed()))
+		_, body := do("GET", "/api/auth/lnurl/callback?"+query.Encode(), "", nil)
+		var resp models.LNURLResponse
+		json.Unmarshal(body, &resp)
+		return resp
+	}
+	poll := func(session string) (int, models.AuthResponse) {
+		t.Helper()
+		status, body := do("POST", "/api/auth/lnurl/session", "", models.LNURLAuthPollRequest{Session: session})
+		var resp struct {
+			Data models.AuthResponse `json:"data"`
+		}
+		json.Unmarshal(body, &resp)
+		return status, resp.Data
+	}
+	credentials := func(token string) []models.Credential {
+		t.Helper()
+		_, body := do("GET", "/api/users/me/credentials", token, nil)
+		var resp struct {
+			Data []models.Credential `json:"data"`
+		}
+		json.Unmarshal(body, &resp)
+		return resp.Data
+	}
+	wallet, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new wallet signs up without an email
+	login := challenge("GET", "/api/auth/lnurl", "")
+	if status, _ := poll(login.Session); status != http.StatusAccepted {
+		t.Errorf("Expected 202 before the wallet signs, got %d", status)
+	}
+	if status, body := do("GET", "/api/auth/lnurl/callback?tag=login&k1="+login.K1+"&sig=3006020101020101&key=02ab", "", nil); status != http.StatusOK || !bytes.Contains(body, []byte(`"status":"ERROR"`)) {
+		t.Errorf("Expected a bad signature answered with an ERROR status, got %d %s", status, body)
+	}
+	if resp := sign(login, wallet); resp.Status != "OK" {
+		t.Fatalf("Expected the wallet's signature accepted, got %+v", resp)
+	}
+	if resp := sign(login, wallet); resp.Status != "ERROR" || resp.Reason == "" {
+		t.Errorf("Expected a signed challenge refused, got %+v", resp)
+	}
+	status, signedUp := poll(login.Session)
+	if status != http.StatusOK || signedUp.Token == "" || signedUp.User == nil {
+		t.Fatalf("Expected a token once the wallet signed, got %d %+v", status, signedUp)
+	}
+	if status, _ := poll(login.Session); status != http.StatusNotFound {
+		t.Errorf("Expected a session to log in once, got %d", status)
+	}
+	if got := credentials(signedUp.Token); len(got) != 1 || got[0].Method != models.CredentialLNURLAuth || got[0].Removable {
+		t.Errorf("Expected the wallet as the only login method, got %+v", got)
+	}
+
+	// A password user links a wallet, then removes it again
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := &models.User{Email: "member@example.com", Name: "Member", PasswordHash: string(hash)}
+	if err := store.Users().Create(member); err != nil {
+		t.Fatal(err)
+	}
+	_, body := do("POST", "/api/users/login", "", models.LoginRequest{Email: member.Email, Password: "password123"})
+	var loggedIn struct {
+		Data models.AuthResponse `json:"data"`
+	}
+	json.Unmarshal(body, &loggedIn)
+
+	if status, _ := do("POST", "/api/users/me/lnurl-auth", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected linking to need a login, got %d", status)
+	}
+	link := challenge("POST", "/api/users/me/lnurl-auth", loggedIn.Data.Token)
+	if resp := sign(link, wallet); resp.Status != "ERROR" {
+		t.Errorf("Expected a wallet of another account refused, got %+v", resp)
+	}
+	other, _ := secp256k1.GeneratePrivateKey()
+	link = challenge("POST", "/api/users/me/lnurl-auth", loggedIn.Data.Token)
+	if resp := sign(link, other); resp.Status != "OK" {
+		t.Fatalf("Expected the wallet linked, got %+v", resp)
+	}
+	if got := credentials(loggedIn.Data.Token); len(got) != 2 || !got[0].Removable || got[1].Method != models.CredentialLNURLAuth {
+		t.Errorf("Expected a password and a wallet, got %+v", got)
+	}
+	login = challenge("GET", "/api/auth/lnurl", "")
+	sign(login, other)
+	if status, resp := poll(login.Session); status != http.StatusOK || resp.User == nil || resp.User.ID != member.ID {
+		t.Errorf("Expected the linked wallet to log in the member, got %d %+v", status, resp)
+	}
+
+	if status, _ := do("DELETE", "/api/users/me/credentials/"+models.CredentialLNURLAuth, loggedIn.Data.Token, nil); status != http.StatusOK {
+		t.Fatalf("Expected the wallet removed, got %d", status)
+	}
+	_, body = do("POST", "/api/users/login", "", models.LoginRequest{Email: member.Email, Password: "password123"})
+	json.Unmarshal(body, &loggedIn)
+	if got := credentials(loggedIn.Data.Token); len(got) != 1 || got[0].Method != models.CredentialPassword {
+		t.Errorf("Expected only the password left, got %+v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/metrics"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+)
+
+type MetricsHandlers struct {
+	registry *metrics.Registry
+	logger   *slog.Logger
+}
+
+func NewMetricsHandlers(registry *metrics.Registry, logger *slog.Logger) *MetricsHandlers {
+	return &MetricsHandlers{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// HandleMetrics returns a snapshot of the server's operational counters,
+// keyed by component (admin only)
+func (h *MetricsHandlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Metrics retrieved successfully",
+		Data:    h.registry.Snapshot(),
+	})
+}
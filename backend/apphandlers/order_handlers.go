@@ -0,0 +1,167 @@
+package apphandlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// OrderHandlers serves buyers' order history: each checkout with the
+// tickets and add-ons bought in it, rather than one row per ticket
+type OrderHandlers struct {
+	orderRepo   repositories.OrderRepository
+	ticketRepo  repositories.TicketRepository
+	eventRepo   repositories.EventRepository
+	paymentRepo repositories.PaymentRepository
+	addOnRepo   repositories.AddOnRepository
+	receiptRepo repositories.ReceiptRepository
+	logger      *slog.Logger
+}
+
+func NewOrderHandlers(
+	orderRepo repositories.OrderRepository,
+	ticketRepo repositories.TicketRepository,
+	eventRepo repositories.EventRepository,
+	paymentRepo repositories.PaymentRepository,
+	addOnRepo repositories.AddOnRepository,
+	receiptRepo repositories.ReceiptRepository,
+	logger *slog.Logger,
+) *OrderHandlers {
+	return &OrderHandlers{
+		orderRepo:   orderRepo,
+		ticketRepo:  ticketRepo,
+		eventRepo:   eventRepo,
+		paymentRepo: paymentRepo,
+		addOnRepo:   addOnRepo,
+		receiptRepo: receiptRepo,
+		logger:      logger,
+	}
+}
+
+// HandleGetMyOrders lists the caller's orders, newest first, each with its
+// event, tickets and add-ons, an overall status and, for paid payments,
+// where to get the receipt
+func (h *OrderHandlers) HandleGetMyOrders(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	orders, err := h.orderRepo.GetByUserID(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch orders", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch orders")
+		return
+	}
+	tickets, err := h.ticketRepo.GetByUserID(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch user tickets", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch orders")
+		return
+	}
+	byOrder := make(map[int][]models.Ticket)
+	for _, ticket := range tickets {
+		if ticket.OrderID != nil {
+			byOrder[*ticket.OrderID] = append(byOrder[*ticket.OrderID], ticket)
+		}
+	}
+
+	events, err := repositories.Preload(orders, func(o models.Order) int { return o.EventID }, h.eventRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch events for orders", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch orders")
+		return
+	}
+	response := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		event, ok := events[order.EventID]
+		if !ok {
+			h.logger.Error("Event for order not found", "order_id", order.ID, "event_id", order.EventID)
+			continue
+		}
+
+		var statuses []string
+		var totalSats int64
+		orderTickets := make([]map[string]interface{}, 0, len(byOrder[order.ID]))
+		// Tickets come newest first; list them in the order they were bought
+		for i := len(byOrder[order.ID]) - 1; i >= 0; i-- {
+			ticket := byOrder[order.ID][i]
+			statuses = append(statuses, ticket.PaymentStatus)
+			totalSats += ticket.AmountSats
+			orderTickets = append(orderTickets, h.orderTicket(ticket))
+		}
+
+		response = append(response, map[string]interface{}{
+			"id":         order.ID,
+			"status":     models.OrderStatus(statuses),
+			"total_sats": totalSats,
+			"created_at": order.CreatedAt,
+			"event": map[string]interface{}{
+				"id":         event.ID,
+				"title":      event.Title,
+				"start_time": event.StartTime,
+				"end_time":   event.EndTime,
+			},
+			"tickets": orderTickets,
+		})
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Orders retrieved successfully",
+		Data:    response,
+	})
+}
+
+// orderTicket describes one ticket of an order with its add-ons and payment
+func (h *OrderHandlers) orderTicket(ticket models.Ticket) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":             ticket.ID,
+		"ticket_code":    ticket.TicketCode,
+		"payment_status": ticket.PaymentStatus,
+		"amount_sats":    ticket.AmountSats,
+		"paid_at":        ticket.PaidAt,
+	}
+
+	addOns, err := h.addOnRepo.GetLineItems(ticket.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket add-ons", "ticket_id", ticket.ID, "error", err)
+	}
+	if len(addOns) > 0 {
+		item["addons"] = addOns
+	}
+
+	payment, err := h.paymentRepo.GetByTicketID(ticket.ID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			h.logger.Error("Failed to fetch payment for ticket", "ticket_id", ticket.ID, "error", err)
+		}
+		return item
+	}
+	paymentInfo := map[string]interface{}{
+		"id":          payment.ID,
+		"status":      payment.Status,
+		"amount_sats": payment.Amount,
+	}
+	if payment.Status == "paid" {
+		// The receipt is numbered the first time it is fetched
+		receipt := map[string]interface{}{
+			"url": fmt.Sprintf("/api/payments/%d/receipt", payment.ID),
+		}
+		issued, err := h.receiptRepo.GetByPaymentID(payment.ID)
+		if err == nil {
+			receipt["receipt_number"] = issued.FormattedNumber()
+			receipt["issued_at"] = issued.IssuedAt
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			h.logger.Error("Failed to fetch receipt", "payment_id", payment.ID, "error", err)
+		}
+		paymentInfo["receipt"] = receipt
+	}
+	item["payment"] = paymentInfo
+	return item
+}
@@ -0,0 +1,140 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestOrderHistory(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+	handler := NewOrderHandlers(store.Orders(), store.Tickets(), store.Events(), store.Payments(), store.AddOns(), store.Receipts(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/users/me/orders", handler.HandleGetMyOrders).Methods("GET")
+
+	type order struct {
+		ID        int    `json:"id"`
+		Status    string `json:"status"`
+		TotalSats int64  `json:"total_sats"`
+		Event     struct {
+			Title string `json:"title"`
+		} `json:"event"`
+		Tickets []struct {
+			ID      int                  `json:"id"`
+			AddOns  []models.TicketAddOn `json:"addons"`
+			Payment *struct {
+				ID      int    `json:"id"`
+				Status  string `json:"status"`
+				Receipt *struct {
+					URL    string `json:"url"`
+					Number string `json:"receipt_number"`
+				} `json:"receipt"`
+			} `json:"payment"`
+		} `json:"tickets"`
+	}
+	do := func(method, path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	history := func(user *models.User) []order {
+		t.Helper()
+		status, data := do("GET", "/api/users/me/orders", user, nil)
+		if status != http.StatusOK {
+			t.Fatalf("Expected 200 listing orders, got %d", status)
+		}
+		var orders []order
+		json.Unmarshal(data, &orders)
+		return orders
+	}
+
+	buyer := &models.User{ID: 1}
+	concert := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 10000, IsActive: true}
+	meetup := &models.Event{Title: "Meetup", Capacity: 10, IsActive: true}
+	for _, event := range []*models.Event{concert, meetup} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shirt := &models.AddOn{EventID: concert.ID, Name: "T-shirt", PriceSats: 20000, IsActive: true}
+	if err := store.AddOns().Create(shirt); err != nil {
+		t.Fatal(err)
+	}
+
+	status, data := do("POST", "/api/tickets/purchase", nil, models.TicketPurchaseRequest{EventID: concert.ID, UserID: buyer.ID,
+		UMAAddress: "$buyer@wallet.example.com", AddOns: []models.AddOnSelection{{AddOnID: shirt.ID, Quantity: 1}}})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 buying a ticket, got %d %s", status, data)
+	}
+	orders := history(buyer)
+	if len(orders) != 1 || orders[0].Status != "pending" || orders[0].TotalSats != 30000 || len(orders[0].Tickets) != 1 ||
+		len(orders[0].Tickets[0].AddOns) != 1 || orders[0].Tickets[0].Payment == nil || orders[0].Tickets[0].Payment.Receipt != nil {
+		t.Fatalf("Expected one pending order with the ticket and its add-on, got %+v", orders)
+	}
+
+	// Once paid the order links its receipt
+	ticketID, paymentID := orders[0].Tickets[0].ID, orders[0].Tickets[0].Payment.ID
+	if err := store.Tickets().UpdatePaymentStatus(ticketID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(paymentID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Receipts().Issue(paymentID); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(time.Minute)
+	if status, data := do("POST", "/api/tickets/purchase", nil, models.TicketPurchaseRequest{EventID: meetup.ID, UserID: buyer.ID,
+		UMAAddress: "$buyer@wallet.example.com"}); status != http.StatusCreated {
+		t.Fatalf("Expected 201 for a free ticket, got %d %s", status, data)
+	}
+
+	orders = history(buyer)
+	if len(orders) != 2 || orders[0].Event.Title != "Meetup" || orders[0].Status != "paid" || orders[0].Tickets[0].Payment != nil {
+		t.Fatalf("Expected the free order first, got %+v", orders)
+	}
+	receipt := orders[1].Tickets[0].Payment.Receipt
+	if orders[1].Status != "paid" || receipt == nil || receipt.Number != "R-000001" || receipt.URL != "/api/payments/1/receipt" {
+		t.Errorf("Expected the paid order with its receipt, got %+v", orders[1])
+	}
+
+	if orders := history(&models.User{ID: 2}); len(orders) != 0 {
+		t.Errorf("Expected no orders for another user, got %+v", orders)
+	}
+	if status, _ := do("GET", "/api/users/me/orders", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a user, got %d", status)
+	}
+}
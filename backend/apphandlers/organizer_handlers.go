@@ -0,0 +1,229 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// maxOrganizerEvents is how many upcoming events an organizer's page lists
+const maxOrganizerEvents = 50
+
+type OrganizerHandlers struct {
+	profileRepo     repositories.OrganizerProfileRepository
+	translationRepo repositories.EventTranslationRepository
+	payouts         *services.PayoutService
+	clock           clock.Clock
+	logger          *slog.Logger
+}
+
+func NewOrganizerHandlers(profileRepo repositories.OrganizerProfileRepository, translationRepo repositories.EventTranslationRepository, payouts *services.PayoutService, clk clock.Clock, logger *slog.Logger) *OrganizerHandlers {
+	return &OrganizerHandlers{
+		profileRepo:     profileRepo,
+		translationRepo: translationRepo,
+		payouts:         payouts,
+		clock:           clk,
+		logger:          logger,
+	}
+}
+
+// HandleGetOrganizer returns an organizer's public page: their profile, the
+// events they have on public sale, soonest first, and how many of their
+// public events have taken place. Event titles are localized per
+// Accept-Language.
+func (h *OrganizerHandlers) HandleGetOrganizer(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(mux.Vars(r)["slug"])
+	profile, err := h.profileRepo.GetBySlug(slug)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Organizer not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch organizer profile", "slug", slug, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer")
+		return
+	}
+
+	now := h.clock.Now()
+	events, err := h.profileRepo.GetUpcomingEvents(profile.UserID, now, maxOrganizerEvents)
+	if err != nil {
+		h.logger.Error("Failed to fetch organizer events", "organizer_id", profile.UserID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer")
+		return
+	}
+	past, err := h.profileRepo.CountPastEvents(profile.UserID, now)
+	if err != nil {
+		h.logger.Error("Failed to count organizer events", "organizer_id", profile.UserID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer")
+		return
+	}
+
+	page := models.OrganizerPage{
+		Slug:           profile.Slug,
+		DisplayName:    profile.DisplayName,
+		Bio:            profile.Bio,
+		WebsiteURL:     profile.WebsiteURL,
+		UpcomingEvents: make([]models.OrganizerEvent, 0, len(events)),
+		PastEventCount: past,
+	}
+	for _, event := range events {
+		localizeEvent(h.translationRepo, h.logger, w, &event)
+		page.UpcomingEvents = append(page.UpcomingEvents, models.OrganizerEvent{
+			ID:          event.ID,
+			Title:       event.Title,
+			StartTime:   event.StartTime,
+			EndTime:     event.EndTime,
+			PriceSats:   event.PriceSats,
+			PricingMode: event.PricingMode,
+			Category:    event.Category,
+		})
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Organizer retrieved successfully",
+		Data:    page,
+	})
+}
+
+// HandleGetMyProfile returns the authenticated user's organizer profile
+func (h *OrganizerHandlers) HandleGetMyProfile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	profile, err := h.profileRepo.GetByUserID(user.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Organizer profile not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch organizer profile", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer profile")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Organizer profile retrieved successfully",
+		Data:    profile,
+	})
+}
+
+// HandleSaveMyProfile creates or replaces the authenticated user's
+// organizer profile. Only users who organize an event can have one. A
+// payout address is checked against its LNURL-pay service before it is
+// saved.
+func (h *OrganizerHandlers) HandleSaveMyProfile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	organizer, err := h.profileRepo.IsOrganizer(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to check organizer", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save organizer profile")
+		return
+	}
+	if !organizer {
+		middleware.WriteError(w, http.StatusForbidden, "Only event organizers can have a profile")
+		return
+	}
+
+	var req models.OrganizerProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	profile := &models.OrganizerProfile{
+		UserID:      user.ID,
+		Slug:        strings.ToLower(strings.TrimSpace(req.Slug)),
+		DisplayName: strings.TrimSpace(req.DisplayName),
+		Bio:         strings.TrimSpace(req.Bio),
+		WebsiteURL:  strings.TrimSpace(req.WebsiteURL),
+	}
+	if err := validateOrganizerProfile(profile); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	profile.PayoutAddress, err = h.payouts.ValidateAddress(r.Context(), req.PayoutAddress)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.profileRepo.Save(profile)
+	if errors.Is(err, repositories.ErrConflict) {
+		middleware.WriteError(w, http.StatusConflict, "Slug is already taken")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save organizer profile", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save organizer profile")
+		return
+	}
+
+	h.logger.Info("Organizer profile saved", "user_id", user.ID, "slug", profile.Slug)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Organizer profile saved successfully",
+		Data:    profile,
+	})
+}
+
+func validateOrganizerProfile(profile *models.OrganizerProfile) error {
+	if err := validateOrganizerSlug(profile.Slug); err != nil {
+		return err
+	}
+	if profile.DisplayName == "" {
+		return fmt.Errorf("display name is required")
+	}
+	if utf8.RuneCountInString(profile.DisplayName) > 100 {
+		return fmt.Errorf("display name must be at most 100 characters")
+	}
+	if utf8.RuneCountInString(profile.Bio) > 2000 {
+		return fmt.Errorf("bio must be at most 2000 characters")
+	}
+	if profile.WebsiteURL == "" {
+		return nil
+	}
+	if len(profile.WebsiteURL) > 500 {
+		return fmt.Errorf("website URL must be at most 500 characters")
+	}
+	if u, err := url.Parse(profile.WebsiteURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("website URL must be an http or https URL")
+	}
+	return nil
+}
+
+// validateOrganizerSlug checks a slug is usable in a page URL: lowercase
+// letters, digits and dashes, not starting or ending with a dash
+func validateOrganizerSlug(slug string) error {
+	if len(slug) < 3 || len(slug) > 60 {
+		return fmt.Errorf("slug must be 3 to 60 characters")
+	}
+	for _, c := range slug {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return fmt.Errorf("slug may only contain letters, digits and dashes")
+		}
+	}
+	if strings.HasPrefix(slug, "-") || strings.HasSuffix(slug, "-") {
+		return fmt.Errorf("slug cannot start or end with a dash")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestOrganizerHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	// Only wallet.example runs a LNURL-pay service
+	client := &http.Client{Transport: alertTransport(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host != "wallet.example" {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+		}
+		body := `{"tag":"payRequest","callback":"https://wallet.example/lnurlp/acme/callback","minSendable":1000,"maxSendable":100000000,"metadata":"[]"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	payouts := services.NewPayoutService(nil, store.OrganizerProfiles(), store.Affiliates(), store.Payments(), store.Tickets(), nil, client, logger)
+	organizers := NewOrganizerHandlers(store.OrganizerProfiles(), store.EventTranslations(), payouts, clk, logger)
+	events := &EventHandlers{eventRepo: store.Events(), ticketRepo: store.Tickets(), translationRepo: store.EventTranslations(),
+		relationRepo: store.EventRelations(), profileRepo: store.OrganizerProfiles(), clock: clk, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/organizers/{slug}", organizers.HandleGetOrganizer).Methods("GET")
+	router.HandleFunc("/api/users/me/organizer-profile", organizers.HandleGetMyProfile).Methods("GET")
+	router.HandleFunc("/api/users/me/organizer-profile", organizers.HandleSaveMyProfile).Methods("PUT")
+	router.HandleFunc("/api/events/{id:[0-9]+}", events.HandleGetEvent).Methods("GET")
+
+	do := func(user *models.User, method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	organizer := &models.User{ID: 7}
+	rival := &models.User{ID: 8}
+	fan := &models.User{ID: 9}
+	var upcoming *models.Event
+	for i, user := range []*models.User{organizer, rival, organizer} {
+		start := clk.Now().Add(time.Duration(i-1) * 48 * time.Hour)
+		event := &models.Event{Title: "Show " + strconv.Itoa(i), StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 10, IsActive: true, OrganizerID: &user.ID}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+		upcoming = event
+	}
+
+	if code, _ := do(fan, "PUT", "/api/users/me/organizer-profile", models.OrganizerProfileRequest{Slug: "fan", DisplayName: "Fan"}); code != http.StatusForbidden {
+		t.Errorf("Expected a user without events refused, got %d", code)
+	}
+	// In order: the rival cannot take a slug once it is saved
+	for _, tt := range []struct {
+		name string
+		user *models.User
+		req  models.OrganizerProfileRequest
+		want int
+	}{
+		{"short slug", organizer, models.OrganizerProfileRequest{Slug: "ab", DisplayName: "Acme"}, http.StatusBadRequest},
+		{"slug spaces", organizer, models.OrganizerProfileRequest{Slug: "acme live", DisplayName: "Acme"}, http.StatusBadRequest},
+		{"slug dash", organizer, models.OrganizerProfileRequest{Slug: "-acme", DisplayName: "Acme"}, http.StatusBadRequest},
+		{"no name", organizer, models.OrganizerProfileRequest{Slug: "acme"}, http.StatusBadRequest},
+		{"website", organizer, models.OrganizerProfileRequest{Slug: "acme", DisplayName: "Acme", WebsiteURL: "javascript:alert(1)"}, http.StatusBadRequest},
+		{"valid", organizer, models.OrganizerProfileRequest{Slug: " Acme-Live ", DisplayName: "Acme", WebsiteURL: "https://acme.example"}, http.StatusOK},
+		{"resave", organizer, models.OrganizerProfileRequest{Slug: "acme-live", DisplayName: "Acme Live", Bio: "Live shows"}, http.StatusOK},
+		{"taken slug", rival, models.OrganizerProfileRequest{Slug: "acme-live", DisplayName: "Rival"}, http.StatusConflict},
+		{"payout invoice", rival, models.OrganizerProfileRequest{Slug: "rival", DisplayName: "Rival", PayoutAddress: "lnbc10u1pjexample"}, http.StatusBadRequest},
+		{"payout address without service", rival, models.OrganizerProfileRequest{Slug: "rival", DisplayName: "Rival", PayoutAddress: "rival@nowhere.example"}, http.StatusBadRequest},
+		{"payout address", organizer, models.OrganizerProfileRequest{Slug: "acme-live", DisplayName: "Acme Live", Bio: "Live shows", PayoutAddress: " Acme@Wallet.Example "}, http.StatusOK},
+	} {
+		if code, _ := do(tt.user, "PUT", "/api/users/me/organizer-profile", tt.req); code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, code)
+		}
+	}
+	var profile models.OrganizerProfile
+	_, data := do(organizer, "GET", "/api/users/me/organizer-profile", nil)
+	json.Unmarshal(data, &profile)
+	if profile.DisplayName != "Acme Live" || profile.WebsiteURL != "" || profile.PayoutAddress != "acme@wallet.example" {
+		t.Errorf("Expected the profile replaced, got %+v", profile)
+	}
+	if code, _ := do(rival, "GET", "/api/users/me/organizer-profile", nil); code != http.StatusNotFound {
+		t.Errorf("Expected no profile for the rival, got %d", code)
+	}
+
+	if code, _ := do(nil, "GET", "/api/organizers/nobody", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown slug, got %d", code)
+	}
+	code, data := do(nil, "GET", "/api/organizers/ACME-LIVE", nil)
+	var page models.OrganizerPage
+	json.Unmarshal(data, &page)
+	if code != http.StatusOK || page.Slug != "acme-live" || page.PastEventCount != 1 ||
+		len(page.UpcomingEvents) != 1 || page.UpcomingEvents[0].ID != upcoming.ID {
+		t.Errorf("Unexpected organizer page %d %+v", code, page)
+	}
+
+	// Ticket pages link to the organizer
+	var detail struct {
+		Organizer struct {
+			Slug string `json:"slug"`
+		} `json:"organizer"`
+	}
+	_, data = do(nil, "GET", "/api/events/"+strconv.Itoa(upcoming.ID), nil)
+	json.Unmarshal(data, &detail)
+	if detail.Organizer.Slug != "acme-live" {
+		t.Errorf("Expected the event linked to its organizer, got %s", data)
+	}
+}
@@ -1,13 +1,15 @@
 package apphandlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/lightsparkdev/go-sdk/objects"
@@ -23,30 +25,50 @@ import (
 type PaymentHandlers struct {
 	paymentRepo repositories.PaymentRepository
 	ticketRepo  repositories.TicketRepository
+	eventRepo   repositories.EventRepository
+	umaRepo     repositories.UMARequestInvoiceRepository
 	umaService  services.UMAService
 	client      *uma_services.LightsparkClient
+	breaker     *services.CircuitBreaker
+	queue       *services.WebhookQueue
+	admins      services.AdminNotifier
+	signingKey  middleware.SecretFunc
 	logger      *slog.Logger
 }
 
 func NewPaymentHandlers(
 	paymentRepo repositories.PaymentRepository,
 	ticketRepo repositories.TicketRepository,
+	eventRepo repositories.EventRepository,
+	umaRepo repositories.UMARequestInvoiceRepository,
 	umaService services.UMAService,
 	client *uma_services.LightsparkClient,
+	breaker *services.CircuitBreaker,
+	queue *services.WebhookQueue,
+	admins services.AdminNotifier,
+	signingKey middleware.SecretFunc,
 	logger *slog.Logger,
 ) *PaymentHandlers {
 	return &PaymentHandlers{
 		paymentRepo: paymentRepo,
 		ticketRepo:  ticketRepo,
+		eventRepo:   eventRepo,
+		umaRepo:     umaRepo,
 		umaService:  umaService,
 		client:      client,
+		breaker:     breaker,
+		queue:       queue,
+		admins:      admins,
+		signingKey:  signingKey,
 		logger:      logger,
 	}
 }
 
-// HandlePaymentWebhook processes Lightning payment webhooks
+// HandlePaymentWebhook verifies a Lightning payment webhook and queues it
+// for processing, acknowledging it without waiting on Lightspark or the
+// database work. Without a queue the event is processed inline.
 func (h *PaymentHandlers) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
-	signingKey := os.Getenv("LIGHTSPARK_WEBHOOK_SIGNING_KEY")
+	signingKey := h.signingKey()
 	webhookData, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error("Failed to decode webhook data", "error", err)
@@ -62,9 +84,23 @@ func (h *PaymentHandlers) HandlePaymentWebhook(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Invalid webhook", http.StatusBadRequest)
 		return
 	}
-	if event.EventType == objects.WebhookEventTypePaymentFinished {
-		entityId := event.EntityId
-		h.handlePaymentFinished(entityId)
+
+	queued := &models.WebhookEvent{
+		Source:    models.WebhookSourceLightspark,
+		EventID:   event.EventId,
+		EventType: event.EventType.StringValue(),
+		EntityID:  event.EntityId,
+		Payload:   string(webhookData),
+	}
+	if h.queue == nil {
+		if err := h.ProcessWebhookEvent(*queued); err != nil {
+			h.logger.Error("Failed to process payment webhook", "event_id", event.EventId, "error", err)
+		}
+	} else if err := h.queue.Enqueue(queued); err != nil {
+		// Not acknowledged, so Lightspark delivers the event again
+		h.logger.Error("Failed to queue payment webhook", "event_id", event.EventId, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to queue webhook")
+		return
 	}
 
 	// Return success response
@@ -79,91 +115,148 @@ func (h *PaymentHandlers) HandlePaymentWebhook(w http.ResponseWriter, r *http.Re
 	//})
 }
 
-func (h *PaymentHandlers) handlePaymentFinished(entityID string) {
+// ProcessWebhookEvent handles a queued Lightspark webhook. It returns an
+// error for failures worth retrying, such as Lightspark or the database
+// being unavailable; events that can never succeed are logged and dropped.
+func (h *PaymentHandlers) ProcessWebhookEvent(event models.WebhookEvent) error {
+	if !isPaymentEvent(event.EventType) {
+		return nil
+	}
+	return h.handlePaymentFinished(event.EntityID, h.markPaymentPaid)
+}
+
+// isPaymentEvent reports whether webhooks of eventType announce a finished
+// payment: PAYMENT_FINISHED for payments to and from the node, and
+// WALLET_INCOMING_PAYMENT_FINISHED for payments received by a wallet
+func isPaymentEvent(eventType string) bool {
+	switch eventType {
+	case objects.WebhookEventTypePaymentFinished.StringValue(),
+		objects.WebhookEventTypeWalletIncomingPaymentFinished.StringValue():
+		return true
+	}
+	return false
+}
+
+// handlePaymentFinished fetches the entity of a payment finished event and
+// passes the payment hash of the invoice it settled, and the preimage when
+// the backend reported one, to settle
+func (h *PaymentHandlers) handlePaymentFinished(entityID string, settle func(paymentHash, preimage string) error) error {
 	h.logger.Info("Processing payment finished event", "entity_id", entityID)
 
 	// Get the payment entity from Lightspark
-	entity, err := h.client.GetEntity(entityID)
+	entity, err := h.getEntity(entityID)
 	if err != nil {
-		h.logger.Error("Failed to fetch entity from Lightspark", "entity_id", entityID, "error", err)
-		return
+		return fmt.Errorf("failed to fetch entity %s from Lightspark: %w", entityID, err)
 	}
 
+	// Lightspark may not serve an entity it just announced yet
 	if entity == nil {
-		h.logger.Error("Entity not found", "entity_id", entityID)
-		return
+		return fmt.Errorf("entity %s not found", entityID)
 	}
 
 	h.logger.Info("Processing payment entity", "entity_id", entityID, "type", fmt.Sprintf("%T", *entity))
 
 	// Try IncomingPayment first (when someone pays our invoice from their wallet)
 	if incomingPayment, ok := (*entity).(objects.IncomingPayment); ok {
-		h.handleIncomingPayment(entityID, incomingPayment)
-		return
+		return h.handleIncomingPayment(entityID, incomingPayment, settle)
+	}
+
+	// A settled invoice of ours, when the event names the invoice itself
+	if invoice, ok := (*entity).(objects.Invoice); ok {
+		return h.handleSettledInvoice(entityID, invoice, settle)
 	}
 
 	// Try OutgoingPayment (for backwards compatibility / self-pay scenarios)
 	if outgoingPayment, ok := (*entity).(objects.OutgoingPayment); ok {
-		h.handleOutgoingPayment(entityID, outgoingPayment)
-		return
+		return h.handleOutgoingPayment(entityID, outgoingPayment, settle)
 	}
 
-	h.logger.Warn("Entity is neither IncomingPayment nor OutgoingPayment",
+	h.logger.Warn("Entity is not an IncomingPayment, Invoice or OutgoingPayment",
 		"entity_id", entityID, "type", fmt.Sprintf("%T", *entity))
+	return nil
 }
 
-// handleIncomingPayment processes a payment received on our node (someone paid our invoice).
-func (h *PaymentHandlers) handleIncomingPayment(entityID string, incomingPayment objects.IncomingPayment) {
+// getEntity fetches a Lightspark entity, retried through the payment
+// backend's circuit breaker when there is one
+func (h *PaymentHandlers) getEntity(id string) (*objects.Entity, error) {
+	if h.breaker == nil {
+		return h.client.GetEntity(id)
+	}
+	var entity *objects.Entity
+	err := h.breaker.Retry(func() (err error) {
+		entity, err = h.client.GetEntity(id)
+		return err
+	})
+	return entity, err
+}
+
+// handleIncomingPayment processes a payment received on our node (someone
+// paid our invoice). It is matched to the ticket by its payment hash.
+func (h *PaymentHandlers) handleIncomingPayment(entityID string, incomingPayment objects.IncomingPayment, settle func(paymentHash, preimage string) error) error {
 	h.logger.Info("Processing incoming payment",
 		"entity_id", entityID,
 		"amount", incomingPayment.Amount,
+		"status", incomingPayment.Status.StringValue(),
 		"is_uma", incomingPayment.IsUma)
 
-	// Get the PaymentRequest (Invoice) reference from the IncomingPayment
+	// Payments in flight finish with another event; failed ones pay nothing
+	if incomingPayment.Status != objects.TransactionStatusSuccess {
+		h.logger.Info("Incoming payment not settled, ignoring",
+			"entity_id", entityID, "status", incomingPayment.Status.StringValue())
+		return nil
+	}
+
+	// The transaction hash of a Lightning payment is its payment hash. The
+	// node does not report the preimage of payments it received.
+	if incomingPayment.TransactionHash != nil && *incomingPayment.TransactionHash != "" {
+		return settle(*incomingPayment.TransactionHash, "")
+	}
+
+	// Otherwise the hash is read from the paid invoice
 	if incomingPayment.PaymentRequest == nil {
 		h.logger.Error("IncomingPayment has no PaymentRequest (keysend payment?)", "entity_id", entityID)
-		return
+		return nil
 	}
 
 	invoiceEntityID := incomingPayment.PaymentRequest.Id
 	h.logger.Info("Fetching invoice for incoming payment", "invoice_entity_id", invoiceEntityID)
 
-	// Fetch the full Invoice entity to get the bolt11
-	invoiceEntity, err := h.client.GetEntity(invoiceEntityID)
+	invoiceEntity, err := h.getEntity(invoiceEntityID)
 	if err != nil {
-		h.logger.Error("Failed to fetch invoice entity", "invoice_id", invoiceEntityID, "error", err)
-		return
+		return fmt.Errorf("failed to fetch invoice entity %s: %w", invoiceEntityID, err)
 	}
 
 	if invoiceEntity == nil {
-		h.logger.Error("Invoice entity not found", "invoice_id", invoiceEntityID)
-		return
+		return fmt.Errorf("invoice entity %s not found", invoiceEntityID)
 	}
 
 	invoice, ok := (*invoiceEntity).(objects.Invoice)
 	if !ok {
 		h.logger.Error("Entity is not an Invoice", "invoice_id", invoiceEntityID, "type", fmt.Sprintf("%T", *invoiceEntity))
-		return
+		return nil
 	}
+	return settle(invoice.Data.PaymentHash, "")
+}
 
-	bolt11 := invoice.Data.EncodedPaymentRequest
-	h.logger.Info("Extracted bolt11 from invoice",
-		"invoice_id", invoiceEntityID,
-		"bolt11_prefix", bolt11[:min(len(bolt11), 50)]+"...")
-
-	// Match the bolt11 to our payment record in the database
-	h.markPaymentPaid(bolt11)
+// handleSettledInvoice processes an invoice of ours that has been paid
+func (h *PaymentHandlers) handleSettledInvoice(entityID string, invoice objects.Invoice, settle func(paymentHash, preimage string) error) error {
+	if invoice.AmountPaid == nil || invoice.AmountPaid.OriginalValue <= 0 {
+		h.logger.Info("Invoice not paid, ignoring", "entity_id", entityID, "status", invoice.Status.StringValue())
+		return nil
+	}
+	h.logger.Info("Processing settled invoice", "entity_id", entityID, "amount_paid", invoice.AmountPaid)
+	return settle(invoice.Data.PaymentHash, "")
 }
 
 // handleOutgoingPayment processes an outgoing payment (backwards compatibility).
-func (h *PaymentHandlers) handleOutgoingPayment(entityID string, outgoingPayment objects.OutgoingPayment) {
+func (h *PaymentHandlers) handleOutgoingPayment(entityID string, outgoingPayment objects.OutgoingPayment, settle func(paymentHash, preimage string) error) error {
 	h.logger.Info("Processing outgoing payment", "entity_id", entityID)
 
-	// Extract bolt11 from the OutgoingPayment
-	var bolt11 string
+	// Extract the payment hash from the OutgoingPayment's invoice
+	var paymentHash string
 	if outgoingPayment.PaymentRequestData != nil {
-		if encodedReq, ok := (*outgoingPayment.PaymentRequestData).(objects.InvoiceData); ok {
-			bolt11 = encodedReq.GetEncodedPaymentRequest()
+		if invoiceData, ok := (*outgoingPayment.PaymentRequestData).(objects.InvoiceData); ok {
+			paymentHash = invoiceData.PaymentHash
 		} else {
 			h.logger.Warn("PaymentRequestData doesn't implement InvoiceData")
 		}
@@ -171,50 +264,83 @@ func (h *PaymentHandlers) handleOutgoingPayment(entityID string, outgoingPayment
 		h.logger.Warn("PaymentRequestData is nil")
 	}
 
-	if bolt11 == "" {
-		h.logger.Error("Could not extract bolt11 from OutgoingPayment", "entity_id", entityID)
-		return
+	if paymentHash == "" {
+		h.logger.Error("Could not extract payment hash from OutgoingPayment", "entity_id", entityID)
+		return nil
 	}
 
 	h.logger.Info("Processing outgoing payment",
-		"bolt11_prefix", bolt11[:min(len(bolt11), 50)]+"...",
+		"payment_hash", paymentHash,
 		"status", outgoingPayment.GetStatus(),
 		"amount", outgoingPayment.GetAmount())
 
-	h.markPaymentPaid(bolt11)
+	var preimage string
+	if outgoingPayment.PaymentPreimage != nil {
+		preimage = *outgoingPayment.PaymentPreimage
+	}
+	return settle(paymentHash, preimage)
 }
 
-// markPaymentPaid looks up a payment by bolt11 and marks it and its ticket as paid.
-func (h *PaymentHandlers) markPaymentPaid(bolt11 string) {
-	payment, err := h.paymentRepo.GetByInvoiceID(bolt11)
-	if err != nil {
-		h.logger.Error("Failed to fetch payment by bolt11", "error", err)
-		return
+// MarkPaymentPaid looks up a payment by payment hash and marks it and its
+// ticket as paid. It is called for settlements from the simulated backend.
+func (h *PaymentHandlers) MarkPaymentPaid(paymentHash, preimage string) {
+	if err := h.markPaymentPaid(paymentHash, preimage); err != nil {
+		h.logger.Error("Failed to mark payment paid", "error", err)
 	}
+}
 
-	if payment == nil {
-		h.logger.Error("Payment not found in database for bolt11",
-			"bolt11_prefix", bolt11[:min(len(bolt11), 50)]+"...")
-		return
+// markPaymentPaid marks the payment for the invoice with paymentHash paid,
+// keeping the preimage as the buyer's proof of payment when the backend
+// reported one. An invoice paid to an event's UMA address has no payment
+// and is marked paid for its event instead. A repeated settlement changes
+// nothing, and one for a payment or ticket no longer awaiting it, such as
+// a cancelled ticket whose invoice was paid late, is left to the admins to
+// refund. It returns an error when the database fails so a queued webhook
+// is retried.
+func (h *PaymentHandlers) markPaymentPaid(paymentHash, preimage string) error {
+	if paymentHash == "" {
+		h.logger.Error("Settled invoice has no payment hash")
+		return nil
+	}
+	payment, err := h.paymentRepo.GetByPaymentHash(paymentHash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return h.markEventInvoicePaid(paymentHash)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch payment by payment hash: %w", err)
 	}
 
 	status := "paid"
 	oldStatus := payment.Status
 
+	if preimage != "" {
+		if err := h.paymentRepo.UpdatePreimage(payment.ID, preimage); err != nil {
+			return fmt.Errorf("failed to store preimage of payment %d: %w", payment.ID, err)
+		}
+	}
+
 	// Update payment status
-	if err := h.paymentRepo.UpdateStatus(payment.ID, status); err != nil {
-		h.logger.Error("Failed to update payment status", "payment_id", payment.ID, "error", err)
-		return
+	err = h.paymentRepo.UpdateStatus(payment.ID, status)
+	if errors.Is(err, repositories.ErrConflict) {
+		h.refundLatePayment(payment)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update status of payment %d: %w", payment.ID, err)
 	}
 
 	// Update ticket payment status
-	if err := h.ticketRepo.UpdatePaymentStatus(payment.TicketID, status); err != nil {
-		h.logger.Error("Failed to update ticket payment status", "ticket_id", payment.TicketID, "error", err)
-		return
+	err = h.ticketRepo.UpdatePaymentStatus(payment.TicketID, status)
+	if errors.Is(err, repositories.ErrConflict) {
+		h.refundLatePayment(payment)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update payment status of ticket %d: %w", payment.TicketID, err)
 	}
 
 	// Process UMA callback
-	if err := h.umaService.HandleUMACallback(bolt11, status); err != nil {
+	if err := h.umaService.HandleUMACallback(paymentHash, status); err != nil {
 		h.logger.Error("Failed to process UMA callback", "error", err)
 	}
 
@@ -223,6 +349,105 @@ func (h *PaymentHandlers) markPaymentPaid(bolt11 string) {
 		"ticket_id", payment.TicketID,
 		"old_status", oldStatus,
 		"new_status", status)
+	return nil
+}
+
+// refundLatePayment asks the admins to refund a settlement that arrived
+// for a payment or ticket no longer awaiting it, such as a cancelled,
+// refunded or lost-dispute ticket, which it leaves as it is
+func (h *PaymentHandlers) refundLatePayment(payment *models.Payment) {
+	h.logger.Warn("Payment settled for a ticket no longer awaiting it",
+		"payment_id", payment.ID,
+		"ticket_id", payment.TicketID,
+		"payment_status", payment.Status,
+		"amount_sats", payment.Amount)
+	if h.admins == nil {
+		return
+	}
+	notification := &models.AdminNotification{
+		Kind:     models.AdminNotificationRefundRequested,
+		Title:    "Late payment to refund",
+		EntityID: &payment.TicketID,
+	}
+	ticketCode := fmt.Sprintf("#%d", payment.TicketID)
+	if ticket, err := h.ticketRepo.GetByID(payment.TicketID); err == nil {
+		ticketCode, notification.EventID = ticket.TicketCode, &ticket.EventID
+	}
+	notification.Message = fmt.Sprintf("Ticket %s was paid %d sats after its payment was %s; the payment is to be refunded", ticketCode, payment.Amount, payment.Status)
+	h.admins.NotifyAdmins(notification)
+}
+
+// markEventInvoicePaid marks the UMA invoice with paymentHash paid,
+// attributing the payment to the event whose UMA address issued it.
+func (h *PaymentHandlers) markEventInvoicePaid(paymentHash string) error {
+	invoice, err := h.umaRepo.GetByPaymentHash(paymentHash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Payment not found in database for payment hash", "payment_hash", paymentHash)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch UMA invoice by payment hash: %w", err)
+	}
+	if invoice.Status == "paid" {
+		return nil
+	}
+
+	invoice.Status = "paid"
+	if err := h.umaRepo.Update(invoice); err != nil {
+		return fmt.Errorf("failed to update status of UMA invoice %d: %w", invoice.ID, err)
+	}
+
+	eventID := 0
+	if invoice.EventID != nil {
+		eventID = *invoice.EventID
+	}
+	h.logger.Info("Event UMA invoice marked as paid",
+		"event_id", eventID,
+		"invoice_id", invoice.InvoiceID,
+		"amount_sats", invoice.AmountSats)
+	return nil
+}
+
+// HandleSimulatePayment settles an invoice issued by the simulated Lightning
+// backend as if the buyer had paid it. The route is only registered when
+// PAYMENT_BACKEND=simulation.
+func (h *PaymentHandlers) HandleSimulatePayment(w http.ResponseWriter, r *http.Request) {
+	invoiceID := mux.Vars(r)["invoice_id"]
+
+	simulator, ok := h.umaService.(*services.SimulatedUMAService)
+	if !ok {
+		middleware.WriteError(w, http.StatusNotFound, "Payment simulation is not enabled")
+		return
+	}
+
+	h.logger.Info("Simulating payment", "invoice_id", invoiceID)
+
+	err := simulator.SimulateIncomingPayment(invoiceID)
+	if errors.Is(err, services.ErrInvoiceNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Invoice not found")
+		return
+	}
+	if errors.Is(err, services.ErrInvoiceSettled) {
+		middleware.WriteError(w, http.StatusConflict, "Invoice already settled")
+		return
+	}
+	if errors.Is(err, services.ErrInvoiceExpired) {
+		middleware.WriteError(w, http.StatusConflict, "Invoice expired")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to simulate payment", "invoice_id", invoiceID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to simulate payment")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Payment simulated successfully",
+		Data: map[string]interface{}{
+			"invoice_id": invoiceID,
+			"status":     "paid",
+		},
+	})
 }
 
 // HandlePaymentStatus checks the status of a payment by invoice ID
@@ -239,19 +464,22 @@ func (h *PaymentHandlers) HandlePaymentStatus(w http.ResponseWriter, r *http.Req
 
 	// Get payment record
 	payment, err := h.paymentRepo.GetByInvoiceID(invoiceID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch payment", "invoice_id", invoiceID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payment")
 		return
 	}
 
-	if payment == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
-		return
-	}
-
 	// Get ticket information
 	ticket, err := h.ticketRepo.GetByID(payment.TicketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch ticket", "ticket_id", payment.TicketID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
@@ -304,12 +532,18 @@ func (h *PaymentHandlers) HandleGetPendingPayments(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Enrich payments with ticket and event information
+	// Enrich payments with ticket information, loaded in one query
+	tickets, err := repositories.Preload(payments, func(p models.Payment) int { return p.TicketID }, h.ticketRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch tickets for pending payments", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch pending payments")
+		return
+	}
 	paymentDetails := make([]map[string]interface{}, 0, len(payments))
 	for _, payment := range payments {
-		ticket, err := h.ticketRepo.GetByID(payment.TicketID)
-		if err != nil {
-			h.logger.Warn("Failed to fetch ticket for payment", "payment_id", payment.ID, "ticket_id", payment.TicketID, "error", err)
+		ticket, ok := tickets[payment.TicketID]
+		if !ok {
+			h.logger.Warn("Ticket for payment not found", "payment_id", payment.ID, "ticket_id", payment.TicketID)
 			continue
 		}
 
@@ -335,27 +569,28 @@ func (h *PaymentHandlers) HandleGetPendingPayments(w http.ResponseWriter, r *htt
 	})
 }
 
-// HandleGetAllPayments gets all payments (admin only)
+// HandleGetAllPayments gets all payments, sorted and filtered on
+// repositories.PaymentListFields (admin only)
 func (h *PaymentHandlers) HandleGetAllPayments(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Fetching all payments")
 
-	payments, err := h.paymentRepo.GetAllPayments()
-	if err != nil {
-		h.logger.Error("Failed to fetch all payments", "error", err)
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payments")
+	list, ok := parseListQuery(w, r, repositories.PaymentListFields)
+	if !ok {
 		return
 	}
 
-	// Enrich payments with ticket and event information
-	paymentDetails := make([]map[string]interface{}, 0, len(payments))
-	for _, payment := range payments {
+	// Payments are streamed straight from the database to the client so
+	// memory use stays flat however many payments there are
+	stream := middleware.NewJSONListStream(w, http.StatusOK, "Payments retrieved successfully")
+	err := h.paymentRepo.StreamAll(list, func(payment *models.Payment) error {
+		// Enrich payments with ticket and event information
 		ticket, err := h.ticketRepo.GetByID(payment.TicketID)
 		if err != nil {
 			h.logger.Warn("Failed to fetch ticket for payment", "payment_id", payment.ID, "ticket_id", payment.TicketID, "error", err)
-			continue
+			return nil
 		}
 
-		paymentDetail := map[string]interface{}{
+		return stream.Encode(map[string]interface{}{
 			"id":          payment.ID,
 			"invoice_id":  payment.InvoiceID,
 			"amount_sats": payment.Amount,
@@ -367,14 +602,18 @@ func (h *PaymentHandlers) HandleGetAllPayments(w http.ResponseWriter, r *http.Re
 				"payment_status": ticket.PaymentStatus,
 				"uma_address":    ticket.UMAAddress,
 			},
+		})
+	})
+	if err != nil {
+		h.logger.Error("Failed to fetch all payments", "error", err, "written", stream.Count())
+		if !stream.Started() {
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payments")
+			return
 		}
-		paymentDetails = append(paymentDetails, paymentDetail)
+		stream.Fail("Failed to fetch payments")
+		return
 	}
-
-	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
-		Message: "Payments retrieved successfully",
-		Data:    paymentDetails,
-	})
+	stream.Close()
 }
 
 // HandleRetryPayment retries a failed payment (admin only)
@@ -390,17 +629,16 @@ func (h *PaymentHandlers) HandleRetryPayment(w http.ResponseWriter, r *http.Requ
 
 	// Get payment record
 	payment, err := h.paymentRepo.GetByID(paymentID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch payment", "payment_id", paymentID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payment")
 		return
 	}
 
-	if payment == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
-		return
-	}
-
 	// Check if payment can be retried
 	if payment.Status != "failed" && payment.Status != "expired" {
 		middleware.WriteError(w, http.StatusBadRequest, "Payment cannot be retried")
@@ -409,19 +647,35 @@ func (h *PaymentHandlers) HandleRetryPayment(w http.ResponseWriter, r *http.Requ
 
 	// Get ticket information
 	ticket, err := h.ticketRepo.GetByID(payment.TicketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch ticket", "ticket_id", payment.TicketID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
 		return
 	}
 
+	// The new invoice expires like the event's other invoices
+	event, err := h.eventRepo.GetByID(ticket.EventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", ticket.EventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
 	// Create new UMA Request for retry payment
 	invoice, err := h.umaService.CreateUMARequest(
 		ticket.UMAAddress,
 		payment.Amount,
 		fmt.Sprintf("Retry payment for ticket %s", ticket.TicketCode),
+		event.InvoiceExpiry(),
 		true, // isAdmin = true for admin endpoints
 	)
+	if paymentBackendUnavailable(w, err) {
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to create retry invoice", "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create retry invoice")
@@ -430,8 +684,11 @@ func (h *PaymentHandlers) HandleRetryPayment(w http.ResponseWriter, r *http.Requ
 
 	// Update payment with new invoice
 	payment.InvoiceID = invoice.ID
+	payment.Bolt11 = invoice.Bolt11
+	payment.PaymentHash = invoice.PaymentHash
 	payment.Status = "pending"
 	payment.PaidAt = nil
+	payment.ExpiresAt = invoice.ExpiresAt
 
 	if err := h.paymentRepo.Update(payment); err != nil {
 		h.logger.Error("Failed to update payment for retry", "error", err)
@@ -467,3 +724,98 @@ func (h *PaymentHandlers) HandleRetryPayment(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+// errNotAwaitingPayment is returned when a replayed webhook settles a
+// payment that is no longer pending or expired
+var errNotAwaitingPayment = errors.New("payment is not awaiting settlement")
+
+// replayPaymentPaid is markPaymentPaid for a replayed webhook. It only
+// settles a payment still pending or expired: one already paid, or
+// cancelled and refunded, returns errNotAwaitingPayment rather than being
+// settled again or sent to the admins for another refund.
+func (h *PaymentHandlers) replayPaymentPaid(paymentHash, preimage string) error {
+	payment, err := h.paymentRepo.GetByPaymentHash(paymentHash)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("failed to fetch payment by payment hash: %w", err)
+	}
+	if payment != nil && payment.Status != "pending" && payment.Status != "expired" {
+		return fmt.Errorf("%w: payment %d is %s", errNotAwaitingPayment, payment.ID, payment.Status)
+	}
+	return h.markPaymentPaid(paymentHash, preimage)
+}
+
+// HandleReplayWebhook runs the payment finished logic again for a webhook
+// that was missed or lost, given the payment's entity ID or the signed
+// payload as Lightspark sent it (admin only). Only a payment still pending
+// or expired is settled. Replays are audit logged.
+func (h *PaymentHandlers) HandleReplayWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.ReplayWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Reason is required")
+		return
+	}
+	if (req.EntityID == "") == (req.Payload == "") {
+		middleware.WriteError(w, http.StatusBadRequest, "Either entity_id or payload is required")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+
+	entityID, eventID := req.EntityID, ""
+	if req.Payload != "" {
+		event, err := webhooks.VerifyAndParse([]byte(req.Payload), req.Signature, h.signingKey())
+		if err != nil {
+			h.logger.Warn("Webhook replay refused", "audit", true,
+				"admin_id", admin.ID, "admin_email", admin.Email, "reason", req.Reason, "error", err)
+			middleware.WriteError(w, http.StatusBadRequest, "Invalid webhook signature")
+			return
+		}
+		if !isPaymentEvent(event.EventType.StringValue()) {
+			middleware.WriteError(w, http.StatusBadRequest, "Only payment finished webhooks can be replayed")
+			return
+		}
+		entityID, eventID = event.EntityId, event.EventId
+	}
+
+	h.logger.Info("Webhook replay started", "audit", true,
+		"admin_id", admin.ID, "admin_email", admin.Email, "reason", req.Reason,
+		"entity_id", entityID, "event_id", eventID)
+
+	settled := false
+	err := h.handlePaymentFinished(entityID, func(paymentHash, preimage string) error {
+		settled = true
+		return h.replayPaymentPaid(paymentHash, preimage)
+	})
+	switch {
+	case errors.Is(err, errNotAwaitingPayment):
+		h.logger.Warn("Webhook replay refused", "audit", true,
+			"admin_id", admin.ID, "entity_id", entityID, "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusConflict, "Payment is no longer awaiting settlement")
+		return
+	case err != nil:
+		h.logger.Error("Webhook replay failed", "audit", true,
+			"admin_id", admin.ID, "entity_id", entityID, "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusBadGateway, "Failed to replay webhook")
+		return
+	case !settled:
+		h.logger.Warn("Webhook replay settled nothing", "audit", true,
+			"admin_id", admin.ID, "entity_id", entityID, "event_id", eventID)
+		middleware.WriteError(w, http.StatusConflict, "Webhook has no settled payment to replay")
+		return
+	}
+
+	h.logger.Info("Webhook replayed", "audit", true,
+		"admin_id", admin.ID, "entity_id", entityID, "event_id", eventID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Webhook replayed successfully",
+		Data: map[string]interface{}{
+			"entity_id": entityID,
+			"event_id":  eventID,
+		},
+	})
+}
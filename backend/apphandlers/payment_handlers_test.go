@@ -0,0 +1,284 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestHandlePaymentWebhookQueuesEvent(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := services.NewWebhookQueue(repositories.NewMemoryStore(clk).WebhookEvents(), 1, 3, clk, logger)
+	signingKey := func() string { return "webhook-signing-key" }
+	handler := NewPaymentHandlers(&fakePaymentRepository{}, &fakeTicketRepository{}, nil, nil, nil, nil, nil, queue, nil, signingKey, logger)
+
+	send := func(body, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks/payment", bytes.NewBufferString(body))
+		req.Header.Set(webhooks.SIGNATURE_HEADER, signature)
+		rec := httptest.NewRecorder()
+		handler.HandlePaymentWebhook(rec, req)
+		return rec.Code
+	}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(signingKey()))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	body := `{"event_type":"PAYMENT_FINISHED","event_id":"evt-1","timestamp":"2026-10-01T12:00:00Z","entity_id":"IncomingPayment:1"}`
+
+	// Acknowledged without touching Lightspark; a redelivery is not queued twice
+	for i := 0; i < 2; i++ {
+		if code := send(body, sign(body)); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+	}
+	if stats := queue.Stats(); stats.Pending != 1 {
+		t.Errorf("Expected one queued event, got %+v", stats)
+	}
+
+	if code := send(body, sign("tampered")); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad signature, got %d", code)
+	}
+}
+
+func TestHandleReplayWebhookValidation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	signingKey := func() string { return "webhook-signing-key" }
+	handler := NewPaymentHandlers(&fakePaymentRepository{}, &fakeTicketRepository{}, nil, nil, nil, nil, nil, nil, nil, signingKey, logger)
+	admin := &models.User{ID: 1, Email: "admin@example.com"}
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(signingKey()))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	nodeStatus := `{"event_type":"NODE_STATUS","event_id":"evt-2","timestamp":"2026-10-01T12:00:00Z","entity_id":"Node:1"}`
+	finished := `{"event_type":"PAYMENT_FINISHED","event_id":"evt-1","timestamp":"2026-10-01T12:00:00Z","entity_id":"IncomingPayment:1"}`
+
+	// None of these reach Lightspark
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing reason", `{"entity_id": "IncomingPayment:1"}`},
+		{"neither entity nor payload", `{"reason": "missed during outage"}`},
+		{"both entity and payload", `{"entity_id": "IncomingPayment:1", "payload": "{}", "reason": "missed during outage"}`},
+		{"bad signature", fmt.Sprintf(`{"payload": %q, "signature": %q, "reason": "missed during outage"}`, finished, sign("tampered"))},
+		{"other event type", fmt.Sprintf(`{"payload": %q, "signature": %q, "reason": "missed during outage"}`, nodeStatus, sign(nodeStatus))},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/webhooks/replay", bytes.NewBufferString(tt.body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		rec := httptest.NewRecorder()
+		handler.HandleReplayWebhook(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestIncomingPaymentsMatchPaymentHash(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	umaService := services.NewSimulatedUMAService(0, clk, logger)
+	handler := NewPaymentHandlers(store.Payments(), store.Tickets(), store.Events(), store.UMARequestInvoices(), umaService, nil, nil, nil, nil, nil, logger)
+
+	event := &models.Event{Title: "Gig", StartTime: clk.Now().Add(time.Hour), EndTime: clk.Now().Add(2 * time.Hour), Capacity: 10, PriceSats: 1000}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var payments []*models.Payment
+	for i, hash := range []string{"hash-incoming", "hash-invoice"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: fmt.Sprintf("HASH-%d", i), PaymentStatus: "pending"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "inv-" + hash, Bolt11: "lnbc-" + hash, PaymentHash: hash, Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+	status := func(payment *models.Payment) (string, string) {
+		t.Helper()
+		got, err := store.Payments().GetByID(payment.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ticket, err := store.Tickets().GetByID(payment.TicketID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Status, ticket.PaymentStatus
+	}
+
+	hash := "hash-incoming"
+	incoming := objects.IncomingPayment{Id: "IncomingPayment:1", Status: objects.TransactionStatusPending, TransactionHash: &hash}
+	if err := handler.handleIncomingPayment(incoming.Id, incoming, handler.markPaymentPaid); err != nil {
+		t.Fatal(err)
+	}
+	if payment, ticket := status(payments[0]); payment != "pending" || ticket != "pending" {
+		t.Errorf("Expected a pending incoming payment to change nothing, got %s/%s", payment, ticket)
+	}
+	incoming.Status = objects.TransactionStatusSuccess
+	if err := handler.handleIncomingPayment(incoming.Id, incoming, handler.markPaymentPaid); err != nil {
+		t.Fatal(err)
+	}
+	if payment, ticket := status(payments[0]); payment != "paid" || ticket != "paid" {
+		t.Errorf("Expected the settled payment marked paid, got %s/%s", payment, ticket)
+	}
+
+	invoice := objects.Invoice{Id: "Invoice:2", Data: objects.InvoiceData{PaymentHash: "hash-invoice", EncodedPaymentRequest: "lnbc-hash-invoice"}}
+	if err := handler.handleSettledInvoice(invoice.Id, invoice, handler.markPaymentPaid); err != nil {
+		t.Fatal(err)
+	}
+	if payment, _ := status(payments[1]); payment != "pending" {
+		t.Errorf("Expected an unpaid invoice to change nothing, got %s", payment)
+	}
+	invoice.Status = objects.PaymentRequestStatusClosed
+	invoice.AmountPaid = &objects.CurrencyAmount{OriginalValue: 1000000, OriginalUnit: objects.CurrencyUnitMillisatoshi}
+	if err := handler.handleSettledInvoice(invoice.Id, invoice, handler.markPaymentPaid); err != nil {
+		t.Fatal(err)
+	}
+	if payment, ticket := status(payments[1]); payment != "paid" || ticket != "paid" {
+		t.Errorf("Expected the settled invoice's payment marked paid, got %s/%s", payment, ticket)
+	}
+}
+
+type recordingAdminNotifier struct {
+	notifications []*models.AdminNotification
+}
+
+func (n *recordingAdminNotifier) NotifyAdmins(notification *models.AdminNotification) {
+	n.notifications = append(n.notifications, notification)
+}
+
+func TestLatePaymentLeftToRefund(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	admins := &recordingAdminNotifier{}
+	umaService := services.NewSimulatedUMAService(0, clk, logger)
+	handler := NewPaymentHandlers(store.Payments(), store.Tickets(), store.Events(), store.UMARequestInvoices(), umaService, nil, nil, nil, admins, nil, logger)
+
+	ticket := &models.Ticket{EventID: 1, UserID: 1, TicketCode: "LATE-1", PaymentStatus: "pending"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "inv-late", PaymentHash: "hash-late", Amount: 1000, Status: "pending"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+
+	// Settling twice keeps the first paid_at
+	if err := handler.markPaymentPaid("hash-late", ""); err != nil {
+		t.Fatal(err)
+	}
+	paidAt := clk.Now()
+	clk.Advance(time.Minute)
+	if err := handler.markPaymentPaid("hash-late", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Tickets().GetByID(ticket.ID); got.PaymentStatus != "paid" || !got.PaidAt.Equal(paidAt) {
+		t.Errorf("Expected the ticket paid once at %v, got %+v", paidAt, got)
+	}
+	if len(admins.notifications) != 0 {
+		t.Errorf("Expected nothing to refund, got %+v", admins.notifications)
+	}
+
+	// A cancelled ticket whose invoice is paid late stays cancelled
+	if err := store.Tickets().UpdatePaymentStatus(ticket.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(payment.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.markPaymentPaid("hash-late", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Tickets().GetByID(ticket.ID); got.PaymentStatus != "cancelled" {
+		t.Errorf("Expected the ticket to stay cancelled, got %s", got.PaymentStatus)
+	}
+	if got, _ := store.Payments().GetByID(payment.ID); got.Status != "cancelled" {
+		t.Errorf("Expected the payment to stay cancelled, got %s", got.Status)
+	}
+	if len(admins.notifications) != 1 || admins.notifications[0].Kind != models.AdminNotificationRefundRequested ||
+		*admins.notifications[0].EntityID != ticket.ID {
+		t.Errorf("Expected the admins asked to refund the payment, got %+v", admins.notifications)
+	}
+}
+
+func TestReplayOnlySettlesAwaitedPayments(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	admins := &recordingAdminNotifier{}
+	umaService := services.NewSimulatedUMAService(0, clk, logger)
+	handler := NewPaymentHandlers(store.Payments(), store.Tickets(), store.Events(), store.UMARequestInvoices(), umaService, nil, nil, nil, admins, nil, logger)
+
+	var payments []*models.Payment
+	for i, status := range []string{"expired", "cancelled"} {
+		ticket := &models.Ticket{EventID: 1, UserID: 1, TicketCode: fmt.Sprintf("REPLAY-%d", i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: fmt.Sprintf("inv-replay-%d", i), PaymentHash: fmt.Sprintf("hash-replay-%d", i), Amount: 1000, Status: status}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+
+	if err := handler.replayPaymentPaid(payments[0].PaymentHash, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Payments().GetByID(payments[0].ID); got.Status != "paid" {
+		t.Errorf("Expected the expired payment settled, got %s", got.Status)
+	}
+	for _, payment := range payments {
+		if err := handler.replayPaymentPaid(payment.PaymentHash, ""); !errors.Is(err, errNotAwaitingPayment) {
+			t.Errorf("Expected errNotAwaitingPayment replaying payment %d, got %v", payment.ID, err)
+		}
+	}
+	if got, _ := store.Payments().GetByID(payments[1].ID); got.Status != "cancelled" {
+		t.Errorf("Expected the cancelled payment left alone, got %s", got.Status)
+	}
+	if len(admins.notifications) != 0 {
+		t.Errorf("Expected no refund asked for a replay, got %+v", admins.notifications)
+	}
+}
+
+func TestIsPaymentEvent(t *testing.T) {
+	for eventType, want := range map[string]bool{
+		"PAYMENT_FINISHED":                 true,
+		"WALLET_INCOMING_PAYMENT_FINISHED": true,
+		"WALLET_OUTGOING_PAYMENT_FINISHED": false,
+		"NODE_STATUS":                      false,
+	} {
+		if got := isPaymentEvent(eventType); got != want {
+			t.Errorf("isPaymentEvent(%s) = %v, want %v", eventType, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// maxPricingRuleName matches the pricing_rules.name column
+const maxPricingRuleName = 100
+
+type PricingHandlers struct {
+	ruleRepo  repositories.PricingRuleRepository
+	eventRepo repositories.EventRepository
+	pricing   *services.PricingService
+	limits    config.PriceLimits
+	logger    *slog.Logger
+}
+
+func NewPricingHandlers(ruleRepo repositories.PricingRuleRepository, eventRepo repositories.EventRepository, pricing *services.PricingService, limits config.PriceLimits, logger *slog.Logger) *PricingHandlers {
+	return &PricingHandlers{
+		ruleRepo:  ruleRepo,
+		eventRepo: eventRepo,
+		pricing:   pricing,
+		limits:    limits,
+		logger:    logger,
+	}
+}
+
+// HandleGetPrice returns an event's current ticket price with the pricing
+// rule that set it
+func (h *PricingHandlers) HandleGetPrice(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.pricing.Quote(event)
+	if err != nil {
+		h.logger.Error("Failed to price event", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event price")
+		return
+	}
+
+	// The price moves with every sale; never serve it from a cache
+	w.Header().Set("Cache-Control", "no-store")
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Event price retrieved successfully",
+		Data:    quote,
+	})
+}
+
+// HandleListPricingRules lists an event's pricing rules (admin only)
+func (h *PricingHandlers) HandleListPricingRules(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := h.ruleRepo.GetByEventID(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch pricing rules", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch pricing rules")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Pricing rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+// HandleCreatePricingRule adds a pricing rule to a fixed-price event. It
+// applies from the next purchase; tickets already sold keep their price
+// (admin only)
+func (h *PricingHandlers) HandleCreatePricingRule(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePricingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if event.PayWhatYouWant() {
+		middleware.WriteError(w, http.StatusBadRequest, "Pricing rules apply only to fixed-price events")
+		return
+	}
+	rule := &models.PricingRule{
+		EventID:     event.ID,
+		Name:        strings.TrimSpace(req.Name),
+		PriceSats:   req.PriceSats,
+		SoldPercent: req.SoldPercent,
+		StartsAt:    req.StartsAt,
+	}
+	if err := h.validatePricingRule(rule, event); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.ruleRepo.Create(rule); err != nil {
+		h.logger.Error("Failed to create pricing rule", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create pricing rule")
+		return
+	}
+
+	h.logger.Info("Pricing rule created", "rule_id", rule.ID, "event_id", event.ID, "price_sats", rule.PriceSats)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Pricing rule created successfully",
+		Data:    rule,
+	})
+}
+
+// HandleDeletePricingRule removes a pricing rule; the price history keeps
+// its name (admin only)
+func (h *PricingHandlers) HandleDeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid pricing rule ID")
+		return
+	}
+
+	err = h.ruleRepo.Delete(ruleID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Pricing rule not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete pricing rule", "rule_id", ruleID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete pricing rule")
+		return
+	}
+
+	h.logger.Info("Pricing rule deleted", "rule_id", ruleID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Pricing rule deleted successfully",
+	})
+}
+
+// HandleGetPriceHistory lists the prices an event's tickets were sold at,
+// oldest first (admin only)
+func (h *PricingHandlers) HandleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.ruleRepo.GetPriceHistory(event.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch price history", "event_id", event.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch price history")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Price history retrieved successfully",
+		Data:    history,
+	})
+}
+
+// event loads the event named in the route, writing the error response
+// itself when it is missing.
+func (h *PricingHandlers) event(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return nil, false
+	}
+
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, false
+	}
+	return event, true
+}
+
+func (h *PricingHandlers) validatePricingRule(rule *models.PricingRule, event *models.Event) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(rule.Name) > maxPricingRuleName {
+		return fmt.Errorf("name must be at most %d characters", maxPricingRuleName)
+	}
+	if rule.PriceSats <= event.PriceSats {
+		return fmt.Errorf("price must be above the event price of %d sats", event.PriceSats)
+	}
+	if err := h.limits.CheckTicketPrice(rule.PriceSats); err != nil {
+		return err
+	}
+	if (rule.SoldPercent == nil) == (rule.StartsAt == nil) {
+		return fmt.Errorf("give exactly one of sold_percent and starts_at")
+	}
+	if rule.SoldPercent != nil && (*rule.SoldPercent < 1 || *rule.SoldPercent > 100) {
+		return fmt.Errorf("sold_percent must be between 1 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestDynamicPricing(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	pricing := services.NewPricingService(store.PricingRules(), store.Events(), clk, logger)
+	handlers := NewPricingHandlers(store.PricingRules(), store.Events(), pricing, config.PriceLimits{}, logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, pricing, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events/{id:[0-9]+}/price", handlers.HandleGetPrice).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/pricing-rules", handlers.HandleListPricingRules).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/pricing-rules", handlers.HandleCreatePricingRule).Methods("POST")
+	router.HandleFunc("/api/admin/pricing-rules/{id:[0-9]+}", handlers.HandleDeletePricingRule).Methods("DELETE")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/price-history", handlers.HandleGetPriceHistory).Methods("GET")
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 2, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	pwyw := &models.Event{Title: "Jam", Capacity: 2, PriceSats: 1000, PricingMode: models.PricingPayWhatYouWant, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	for _, e := range []*models.Event{event, pwyw} {
+		if err := store.Events().Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rulesPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/pricing-rules"
+	half, zero, tomorrow := 50, 0, clk.Now().Add(24*time.Hour)
+	for name, req := range map[string]models.CreatePricingRuleRequest{
+		"no name":           {PriceSats: 1500, SoldPercent: &half},
+		"no increase":       {Name: "Cheaper", PriceSats: 900, SoldPercent: &half},
+		"no trigger":        {Name: "Always", PriceSats: 1500},
+		"both triggers":     {Name: "Both", PriceSats: 1500, SoldPercent: &half, StartsAt: &tomorrow},
+		"zero sold percent": {Name: "Zero", PriceSats: 1500, SoldPercent: &zero},
+	} {
+		if status, _ := do("POST", rulesPath, req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+	if status, _ := do("POST", "/api/admin/events/"+strconv.Itoa(pwyw.ID)+"/pricing-rules",
+		models.CreatePricingRuleRequest{Name: "Half sold", PriceSats: 1500, SoldPercent: &half}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a pay-what-you-want event, got %d", status)
+	}
+
+	var created []models.PricingRule
+	for _, req := range []models.CreatePricingRuleRequest{
+		{Name: "Half sold", PriceSats: 1500, SoldPercent: &half},
+		{Name: "Last call", PriceSats: 3000, StartsAt: &tomorrow},
+	} {
+		status, data := do("POST", rulesPath, req)
+		var rule models.PricingRule
+		json.Unmarshal(data, &rule)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 creating %s, got %d %s", req.Name, status, data)
+		}
+		created = append(created, rule)
+	}
+	if status, data := do("GET", rulesPath, nil); status != http.StatusOK || !bytes.Contains(data, []byte("Last call")) {
+		t.Errorf("Expected the rules listed, got %d %s", status, data)
+	}
+	if status, _ := do("DELETE", "/api/admin/pricing-rules/"+strconv.Itoa(created[1].ID), nil); status != http.StatusOK {
+		t.Errorf("Expected 200 deleting a rule, got %d", status)
+	}
+	if status, _ := do("DELETE", "/api/admin/pricing-rules/"+strconv.Itoa(created[1].ID), nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting it twice, got %d", status)
+	}
+
+	// Each purchase is charged the price at the time and its receipt line
+	// names the rule that set it
+	buy := func() (int64, string) {
+		t.Helper()
+		status, data := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: buyer.ID, UMAAddress: "$buyer@wallet.example.com",
+		})
+		var resp struct {
+			Ticket     models.Ticket `json:"ticket"`
+			AmountSats int64         `json:"amount_sats"`
+		}
+		json.Unmarshal(data, &resp)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected 201 buying, got %d %s", status, data)
+		}
+		if err := store.Tickets().UpdatePaymentStatus(resp.Ticket.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		payment, err := store.Payments().GetByTicketID(resp.Ticket.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, err := store.Receipts().GetLineItems(payment.ID)
+		if err != nil || len(items) == 0 {
+			t.Fatalf("Expected payment line items, got %+v (%v)", items, err)
+		}
+		return resp.AmountSats, items[0].Description
+	}
+	if amount, description := buy(); amount != 1000 || description != "Ticket: Concert" {
+		t.Errorf("Expected the event price first, got %d %q", amount, description)
+	}
+	status, data := do("GET", "/api/events/"+strconv.Itoa(event.ID)+"/price", nil)
+	var quote services.PriceQuote
+	json.Unmarshal(data, &quote)
+	if status != http.StatusOK || quote.PriceSats != 1500 || quote.Rule == nil || quote.Rule.Name != "Half sold" {
+		t.Errorf("Expected the raised price quoted at half sold, got %d %s", status, data)
+	}
+	if amount, description := buy(); amount != 1500 || description != "Ticket: Concert (Half sold)" {
+		t.Errorf("Expected the raised price charged, got %d %q", amount, description)
+	}
+
+	status, data = do("GET", "/api/admin/events/"+strconv.Itoa(event.ID)+"/price-history", nil)
+	var history []models.PriceChange
+	json.Unmarshal(data, &history)
+	if status != http.StatusOK || len(history) != 2 || history[0].PriceSats != 1000 || history[1].RuleName != "Half sold" {
+		t.Errorf("Expected two recorded prices, got %d %s", status, data)
+	}
+}
@@ -0,0 +1,257 @@
+package apphandlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/pdf"
+	"tickets-by-uma/repositories"
+)
+
+type ReceiptHandlers struct {
+	receiptRepo repositories.ReceiptRepository
+	paymentRepo repositories.PaymentRepository
+	ticketRepo  repositories.TicketRepository
+	eventRepo   repositories.EventRepository
+	userRepo    repositories.UserRepository
+	logger      *slog.Logger
+	issuer      string
+}
+
+func NewReceiptHandlers(
+	receiptRepo repositories.ReceiptRepository,
+	paymentRepo repositories.PaymentRepository,
+	ticketRepo repositories.TicketRepository,
+	eventRepo repositories.EventRepository,
+	userRepo repositories.UserRepository,
+	logger *slog.Logger,
+	issuer string,
+) *ReceiptHandlers {
+	return &ReceiptHandlers{
+		receiptRepo: receiptRepo,
+		paymentRepo: paymentRepo,
+		ticketRepo:  ticketRepo,
+		eventRepo:   eventRepo,
+		userRepo:    userRepo,
+		logger:      logger,
+		issuer:      issuer,
+	}
+}
+
+// HandleGetReceipt returns the receipt for one of the caller's paid
+// payments, as JSON or, with ?format=pdf or Accept: application/pdf, as a PDF
+func (h *ReceiptHandlers) HandleGetReceipt(w http.ResponseWriter, r *http.Request) {
+	h.serveReceipt(w, r, true)
+}
+
+// HandleAdminGetReceipt returns the receipt for any paid payment (admin only)
+func (h *ReceiptHandlers) HandleAdminGetReceipt(w http.ResponseWriter, r *http.Request) {
+	h.serveReceipt(w, r, false)
+}
+
+func (h *ReceiptHandlers) serveReceipt(w http.ResponseWriter, r *http.Request, ownerOnly bool) {
+	paymentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	payment, err := h.paymentRepo.GetByID(paymentID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch payment", "payment_id", paymentID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payment")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(payment.TicketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", payment.TicketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	if ownerOnly {
+		// Someone else's payment is reported as missing rather than forbidden
+		user := middleware.GetUserFromContext(r.Context())
+		if ticket == nil || user == nil || user.ID != ticket.UserID {
+			middleware.WriteError(w, http.StatusNotFound, "Payment not found")
+			return
+		}
+	}
+	if ticket == nil {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	if payment.Status != "paid" {
+		middleware.WriteError(w, http.StatusConflict, "Receipts are only issued for paid payments")
+		return
+	}
+
+	doc, err := h.receiptDocument(payment, ticket)
+	if err != nil {
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pdf" || strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%s.pdf"`, doc.ReceiptNumber))
+		w.WriteHeader(http.StatusOK)
+		w.Write(renderReceiptPDF(doc))
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Receipt retrieved successfully",
+		Data:    doc,
+	})
+}
+
+// receiptDocument issues the payment's receipt, numbering it on first use,
+// and assembles what it shows. The returned error is safe to show to the
+// client; details are logged here.
+func (h *ReceiptHandlers) receiptDocument(payment *models.Payment, ticket *models.Ticket) (*models.ReceiptDocument, error) {
+	event, err := h.eventRepo.GetByID(ticket.EventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", ticket.EventID, "error", err)
+		return nil, errors.New("Failed to fetch event")
+	}
+
+	items, err := h.receiptRepo.GetLineItems(payment.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch payment line items", "payment_id", payment.ID, "error", err)
+		return nil, errors.New("Failed to fetch payment line items")
+	}
+	if len(items) == 0 {
+		// Payments made before line items were recorded cover just the ticket
+		items = []models.PaymentLineItem{{
+			PaymentID:      payment.ID,
+			Kind:           models.LineItemTicket,
+			Description:    "Ticket: " + event.Title,
+			Quantity:       1,
+			UnitAmountSats: payment.Amount,
+			AmountSats:     payment.Amount,
+		}}
+	}
+	var itemized int64
+	for _, item := range items {
+		itemized += item.AmountSats
+	}
+	if itemized != payment.Amount {
+		h.logger.Warn("Payment line items do not add up to the payment amount",
+			"payment_id", payment.ID, "itemized_sats", itemized, "amount_sats", payment.Amount)
+	}
+
+	receipt, err := h.receiptRepo.Issue(payment.ID)
+	if err != nil {
+		h.logger.Error("Failed to issue receipt", "payment_id", payment.ID, "error", err)
+		return nil, errors.New("Failed to issue receipt")
+	}
+
+	doc := &models.ReceiptDocument{
+		ReceiptNumber: receipt.FormattedNumber(),
+		IssuedAt:      receipt.IssuedAt,
+		Issuer:        h.issuer,
+		PaymentID:     payment.ID,
+		InvoiceID:     payment.InvoiceID,
+		PaidAt:        payment.PaidAt,
+		TicketCode:    ticket.TicketCode,
+		EventTitle:    event.Title,
+		EventStart:    event.StartTime,
+		LineItems:     items,
+		TotalSats:     payment.Amount,
+
+		TaxableSats:     payment.TaxableSats,
+		TaxSats:         payment.TaxSats,
+		TaxBasisPoints:  payment.TaxBasisPoints,
+		TaxInclusive:    payment.TaxInclusive,
+		TaxJurisdiction: payment.TaxJurisdiction,
+	}
+
+	buyer, err := h.userRepo.GetByID(ticket.UserID)
+	switch {
+	case err == nil:
+		doc.BuyerName, doc.BuyerEmail = buyer.Name, buyer.Email
+	case !errors.Is(err, repositories.ErrNotFound):
+		h.logger.Warn("Failed to fetch buyer for receipt", "user_id", ticket.UserID, "error", err)
+	}
+	return doc, nil
+}
+
+// renderReceiptPDF lays a receipt out on a single A4 page
+func renderReceiptPDF(doc *models.ReceiptDocument) []byte {
+	const left, right = 56.0, pdf.PageWidth - 56
+	var page pdf.Page
+	y := pdf.PageHeight - 72
+
+	page.Text(left, y, 20, true, "Receipt "+doc.ReceiptNumber)
+	y -= 20
+	page.Text(left, y, 10, false, doc.Issuer)
+	y -= 30
+
+	details := [][2]string{
+		{"Issued", doc.IssuedAt.UTC().Format("2006-01-02 15:04 MST")},
+		{"Event", doc.EventTitle + " (" + doc.EventStart.UTC().Format("2006-01-02 15:04 MST") + ")"},
+		{"Ticket", doc.TicketCode},
+		{"Billed to", strings.TrimSpace(doc.BuyerName + " " + doc.BuyerEmail)},
+		{"Invoice", truncate(doc.InvoiceID, 80)},
+	}
+	if doc.PaidAt != nil {
+		details = append(details, [2]string{"Paid", doc.PaidAt.UTC().Format("2006-01-02 15:04 MST")})
+	}
+	for _, detail := range details {
+		page.Text(left, y, 10, true, detail[0])
+		page.Text(left+80, y, 10, false, detail[1])
+		y -= 15
+	}
+	y -= 20
+
+	page.Text(left, y, 10, true, "Description")
+	page.TextRight(right-160, y, 10, true, "Qty")
+	page.TextRight(right-80, y, 10, true, "Unit (sats)")
+	page.TextRight(right, y, 10, true, "Amount (sats)")
+	y -= 6
+	page.Line(left, right, y)
+	y -= 14
+	for _, item := range doc.LineItems {
+		page.Text(left, y, 10, false, truncate(item.Description, 60))
+		page.TextRight(right-160, y, 10, false, strconv.Itoa(item.Quantity))
+		page.TextRight(right-80, y, 10, false, strconv.FormatInt(item.UnitAmountSats, 10))
+		page.TextRight(right, y, 10, false, strconv.FormatInt(item.AmountSats, 10))
+		y -= 15
+	}
+	page.Line(left, right, y+9)
+	y -= 8
+	page.Text(left, y, 11, true, "Total")
+	page.TextRight(right, y, 11, true, strconv.FormatInt(doc.TotalSats, 10))
+	if doc.TaxSats > 0 {
+		label := "Tax"
+		if doc.TaxInclusive {
+			label = "Includes tax"
+		}
+		y -= 18
+		page.Text(left, y, 10, false, fmt.Sprintf("%s: %s %s on %d sats", label, doc.TaxJurisdiction, percent(doc.TaxBasisPoints), doc.TaxableSats))
+		page.TextRight(right, y, 10, false, strconv.FormatInt(doc.TaxSats, 10))
+	}
+
+	return page.Bytes()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,96 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestHandleGetReceipt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewReceiptHandlers(store.Receipts(), store.Payments(), store.Tickets(), store.Events(), store.Users(), logger, "tickets.example.com")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/payments/{id:[0-9]+}/receipt", handler.HandleGetReceipt).Methods("GET")
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	pay := func(status string) *models.Payment {
+		t.Helper()
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "T-" + status, PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-" + status, Amount: 1500, Status: status}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		return payment
+	}
+	paid, pending := pay("paid"), pay("pending")
+	store.Receipts().CreateLineItems([]models.PaymentLineItem{
+		{PaymentID: paid.ID, Kind: models.LineItemTicket, Description: "Ticket: Concert", Quantity: 1, UnitAmountSats: 1000, AmountSats: 1000},
+		{PaymentID: paid.ID, Kind: models.LineItemAddOn, Description: "Poster", Quantity: 2, UnitAmountSats: 250, AmountSats: 500},
+	})
+
+	get := func(paymentID, userID int, format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/payments/"+strconv.Itoa(paymentID)+"/receipt"+format, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: userID}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(paid.ID, buyer.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data models.ReceiptDocument `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	doc := resp.Data
+	if doc.ReceiptNumber != "R-000001" || doc.TotalSats != 1500 || len(doc.LineItems) != 2 || doc.BuyerEmail != buyer.Email {
+		t.Errorf("Unexpected receipt %+v", doc)
+	}
+
+	// The number is kept on later requests
+	rec = get(paid.ID, buyer.ID, "?format=pdf")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("Expected PDF, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) || !bytes.Contains(rec.Body.Bytes(), []byte("Receipt R-000001")) {
+		t.Errorf("Expected receipt PDF, got %q", rec.Body.String())
+	}
+
+	if code := get(paid.ID, buyer.ID+1, "").Code; code != http.StatusNotFound {
+		t.Errorf("Another user's receipt: expected 404, got %d", code)
+	}
+	if code := get(pending.ID, buyer.ID, "").Code; code != http.StatusConflict {
+		t.Errorf("Unpaid payment: expected 409, got %d", code)
+	}
+}
@@ -0,0 +1,140 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// maxRelatedEvents is how many related events an event's detail shows
+	maxRelatedEvents = 4
+	// maxRelatedPicks caps the related events an admin can pick for one
+	// event; picks no longer on sale are skipped, so more than shown can
+	// be kept
+	maxRelatedPicks = 10
+)
+
+type RelatedEventHandlers struct {
+	relationRepo repositories.EventRelationRepository
+	eventRepo    repositories.EventRepository
+	logger       *slog.Logger
+}
+
+func NewRelatedEventHandlers(relationRepo repositories.EventRelationRepository, eventRepo repositories.EventRepository, logger *slog.Logger) *RelatedEventHandlers {
+	return &RelatedEventHandlers{
+		relationRepo: relationRepo,
+		eventRepo:    eventRepo,
+		logger:       logger,
+	}
+}
+
+// HandleGetRelatedEvents returns the IDs of the related events picked for
+// an event, in display order (admin only)
+func (h *RelatedEventHandlers) HandleGetRelatedEvents(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	ids, err := h.relationRepo.GetRelatedIDs(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch related events", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch related events")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Related events retrieved successfully",
+		Data:    models.SetRelatedEventsRequest{EventIDs: ids},
+	})
+}
+
+// HandleSetRelatedEvents replaces the related events picked for an event.
+// They are shown before the automatic suggestions, in the given order; an
+// empty list leaves only the suggestions (admin only)
+func (h *RelatedEventHandlers) HandleSetRelatedEvents(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SetRelatedEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateRelatedEvents(eventID, req.EventIDs); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.relationRepo.Set(eventID, req.EventIDs)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusBadRequest, "Related event not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to set related events", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to set related events")
+		return
+	}
+
+	h.logger.Info("Related events set", "event_id", eventID, "related", req.EventIDs)
+
+	if req.EventIDs == nil {
+		req.EventIDs = []int{}
+	}
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Related events updated successfully",
+		Data:    req,
+	})
+}
+
+func validateRelatedEvents(eventID int, relatedIDs []int) error {
+	if len(relatedIDs) > maxRelatedPicks {
+		return fmt.Errorf("at most %d related events can be picked", maxRelatedPicks)
+	}
+	seen := map[int]bool{}
+	for _, id := range relatedIDs {
+		switch {
+		case id == eventID:
+			return fmt.Errorf("an event cannot be related to itself")
+		case seen[id]:
+			return fmt.Errorf("event %d is picked more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// event checks that the event named in the route exists, writing the error
+// response itself when it does not.
+func (h *RelatedEventHandlers) event(w http.ResponseWriter, r *http.Request) (int, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return 0, false
+	}
+
+	_, err = h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return 0, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return 0, false
+	}
+	return eventID, true
+}
@@ -0,0 +1,98 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestRelatedEventHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	related := NewRelatedEventHandlers(store.EventRelations(), store.Events(), logger)
+	events := &EventHandlers{eventRepo: store.Events(), ticketRepo: store.Tickets(), translationRepo: store.EventTranslations(), relationRepo: store.EventRelations(), clock: clk, logger: logger}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/events/{id:[0-9]+}", events.HandleGetEvent).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/related", related.HandleGetRelatedEvents).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/related", related.HandleSetRelatedEvents).Methods("PUT")
+
+	do := func(method, path string, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	start := clk.Now().Add(24 * time.Hour)
+	newEvent := func(title, category string, private bool) *models.Event {
+		t.Helper()
+		event := &models.Event{Title: title, Category: category, StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 10, IsActive: true, IsPrivate: private}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+		start = start.Add(time.Hour)
+		return event
+	}
+	main := newEvent("Jazz Night", "music", false)
+	concert := newEvent("Blues Evening", "music", false)
+	secret := newEvent("Secret Set", "music", true)
+	workshop := newEvent("Go Workshop", "tech", false)
+	path := "/api/admin/events/" + strconv.Itoa(main.ID) + "/related"
+
+	for name, tt := range map[string]struct {
+		path string
+		ids  []int
+		want int
+	}{
+		"unknown event":   {"/api/admin/events/9999/related", []int{workshop.ID}, http.StatusNotFound},
+		"itself":          {path, []int{main.ID}, http.StatusBadRequest},
+		"duplicate":       {path, []int{workshop.ID, workshop.ID}, http.StatusBadRequest},
+		"too many":        {path, make([]int, maxRelatedPicks+1), http.StatusBadRequest},
+		"unknown related": {path, []int{9999}, http.StatusBadRequest},
+	} {
+		if code, _ := do("PUT", tt.path, models.SetRelatedEventsRequest{EventIDs: tt.ids}); code != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, code)
+		}
+	}
+
+	if code, _ := do("PUT", path, models.SetRelatedEventsRequest{EventIDs: []int{workshop.ID, secret.ID}}); code != http.StatusOK {
+		t.Fatalf("Expected the picks saved, got %d", code)
+	}
+	var picks models.SetRelatedEventsRequest
+	_, data := do("GET", path, nil)
+	json.Unmarshal(data, &picks)
+	if len(picks.EventIDs) != 2 || picks.EventIDs[0] != workshop.ID {
+		t.Errorf("Expected the picks in order, got %+v", picks.EventIDs)
+	}
+
+	// The pick comes first, then the same-category suggestion; the private
+	// event is left out of both
+	var detail struct {
+		RelatedEvents []models.RelatedEvent `json:"related_events"`
+	}
+	_, data = do("GET", "/api/events/"+strconv.Itoa(main.ID), nil)
+	json.Unmarshal(data, &detail)
+	if len(detail.RelatedEvents) != 2 || detail.RelatedEvents[0].ID != workshop.ID || !detail.RelatedEvents[0].Curated ||
+		detail.RelatedEvents[1].ID != concert.ID || detail.RelatedEvents[1].Curated {
+		t.Errorf("Unexpected related events %+v", detail.RelatedEvents)
+	}
+}
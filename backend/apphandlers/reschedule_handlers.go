@@ -0,0 +1,134 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type RescheduleHandlers struct {
+	reschedules    *services.RescheduleService
+	rescheduleRepo repositories.EventRescheduleRepository
+	ticketRepo     repositories.TicketRepository
+	logger         *slog.Logger
+}
+
+func NewRescheduleHandlers(reschedules *services.RescheduleService, rescheduleRepo repositories.EventRescheduleRepository, ticketRepo repositories.TicketRepository, logger *slog.Logger) *RescheduleHandlers {
+	return &RescheduleHandlers{
+		reschedules:    reschedules,
+		rescheduleRepo: rescheduleRepo,
+		ticketRepo:     ticketRepo,
+		logger:         logger,
+	}
+}
+
+// HandleRescheduleEvent moves an event to new times, keeping its tickets
+// valid and notifying their holders (admin only)
+func (h *RescheduleHandlers) HandleRescheduleEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.RescheduleEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.StartTime.IsZero() || req.EndTime.IsZero() {
+		middleware.WriteError(w, http.StatusBadRequest, "start_time and end_time are required")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	result, err := h.reschedules.Reschedule(eventID, req, adminID(r))
+	var invalid *services.InvalidRescheduleError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	case errors.As(err, &invalid):
+		middleware.WriteError(w, http.StatusBadRequest, invalid.Reason)
+		return
+	case err != nil:
+		h.logger.Error("Failed to reschedule event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to reschedule event")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Event rescheduled successfully",
+		Data:    result,
+	})
+}
+
+// HandleListReschedules lists an event's reschedules, newest first (admin
+// only)
+func (h *RescheduleHandlers) HandleListReschedules(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	reschedules, err := h.rescheduleRepo.GetByEventID(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch reschedules", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch reschedules")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Reschedules retrieved successfully",
+		Data:    reschedules,
+	})
+}
+
+// HandleRequestRefund cancels the user's paid ticket to a rescheduled event
+// and refunds it, while the reschedule's refund window is open
+func (h *RescheduleHandlers) HandleRequestRefund(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	if _, err := h.reschedules.RequestRefund(ticket); err != nil {
+		if errors.Is(err, services.ErrRefundClosed) {
+			middleware.WriteError(w, http.StatusConflict, "This ticket cannot be refunded")
+			return
+		}
+		h.logger.Error("Failed to refund ticket", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to refund ticket")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket refunded successfully",
+		Data:    ticket,
+	})
+}
@@ -0,0 +1,133 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestRescheduleHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &recordingNotifier{subjects: make(map[int][]string)}
+	ledger := services.NewLedgerService(store.Ledger(), clk, logger)
+	reschedules := services.NewRescheduleService(store.Events(), store.EventReschedules(), store.Tickets(), store.Payments(), ledger, notifier, nil, clk, logger)
+	handler := NewRescheduleHandlers(reschedules, store.EventReschedules(), store.Tickets(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/reschedule", handler.HandleRescheduleEvent).Methods("POST")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/reschedules", handler.HandleListReschedules).Methods("GET")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/reschedule-refund", handler.HandleRequestRefund).Methods("POST")
+
+	do := func(user *models.User, method, path string, body interface{}) (int, []byte) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	admin := &models.User{ID: 42}
+	buyer := &models.User{ID: 7}
+	start := clk.Now().Add(7 * 24 * time.Hour)
+	event := &models.Event{Title: "Meetup", StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "MOVE-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-move-1", Amount: 1000, Status: "pending"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+
+	reschedulePath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/reschedule"
+	refundPath := "/api/tickets/" + strconv.Itoa(ticket.ID) + "/reschedule-refund"
+	newStart := start.Add(7 * 24 * time.Hour)
+	refundUntil := clk.Now().Add(48 * time.Hour)
+
+	// Nothing to refund before the event moves
+	if status, _ := do(buyer, "POST", refundPath, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 before a reschedule, got %d", status)
+	}
+
+	for name, tt := range map[string]struct {
+		path string
+		body interface{}
+		want int
+	}{
+		"missing times": {reschedulePath, map[string]string{"reason": "Venue flooded"}, http.StatusBadRequest},
+		"end first":     {reschedulePath, models.RescheduleEventRequest{StartTime: newStart, EndTime: newStart.Add(-time.Hour)}, http.StatusBadRequest},
+		"unknown event": {"/api/admin/events/9999/reschedule", models.RescheduleEventRequest{StartTime: newStart, EndTime: newStart.Add(time.Hour)}, http.StatusNotFound},
+	} {
+		if status, _ := do(admin, "POST", tt.path, tt.body); status != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, status)
+		}
+	}
+
+	req := models.RescheduleEventRequest{StartTime: newStart, EndTime: newStart.Add(2 * time.Hour), Reason: " Venue flooded ", RefundUntil: &refundUntil}
+	status, body := do(admin, "POST", reschedulePath, req)
+	var rescheduled struct {
+		Data models.RescheduledEvent `json:"data"`
+	}
+	json.Unmarshal(body, &rescheduled)
+	if status != http.StatusOK || rescheduled.Data.Notified != 1 || rescheduled.Data.Reschedule.Reason != "Venue flooded" ||
+		*rescheduled.Data.Reschedule.RescheduledBy != admin.ID || !rescheduled.Data.Event.StartTime.Equal(newStart) {
+		t.Fatalf("Expected the event rescheduled, got %d %s", status, body)
+	}
+	if got := notifier.subjects[buyer.ID]; len(got) != 1 || got[0] != "Event rescheduled" {
+		t.Errorf("Expected the buyer notified, got %v", got)
+	}
+
+	status, body = do(admin, "GET", "/api/admin/events/"+strconv.Itoa(event.ID)+"/reschedules", nil)
+	var list struct {
+		Data []models.EventReschedule `json:"data"`
+	}
+	json.Unmarshal(body, &list)
+	if status != http.StatusOK || len(list.Data) != 1 || !list.Data[0].OldStartTime.Equal(start) {
+		t.Errorf("Expected the reschedule listed, got %d %s", status, body)
+	}
+
+	// Another user's ticket is not found, its owner gets the refund once
+	if status, _ := do(&models.User{ID: 8}, "POST", refundPath, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's ticket, got %d", status)
+	}
+	status, body = do(buyer, "POST", refundPath, nil)
+	var refunded struct {
+		Data models.Ticket `json:"data"`
+	}
+	json.Unmarshal(body, &refunded)
+	if status != http.StatusOK || refunded.Data.PaymentStatus != "cancelled" {
+		t.Errorf("Expected the ticket refunded, got %d %s", status, body)
+	}
+	if status, _ := do(buyer, "POST", refundPath, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 refunding twice, got %d", status)
+	}
+}
@@ -0,0 +1,239 @@
+package apphandlers
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// HandleListReviews lists tickets held for manual review with the fraud
+// signals that flagged them, oldest first (admin only)
+func (h *TicketHandlers) HandleListReviews(w http.ResponseWriter, r *http.Request) {
+	if h.fraud == nil {
+		middleware.WriteError(w, http.StatusNotFound, "Fraud checks are not enabled")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	flags, err := h.fraud.ListAwaitingReview(limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch review queue", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch review queue")
+		return
+	}
+
+	// Tickets and their events are loaded a page at a time
+	tickets, err := repositories.Preload(flags, func(f models.FraudFlag) int { return *f.TicketID }, h.ticketRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch tickets for review", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch review queue")
+		return
+	}
+	events, err := repositories.Preload(slices.Collect(maps.Values(tickets)), func(t *models.Ticket) int { return t.EventID }, h.eventRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch events for review", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch review queue")
+		return
+	}
+
+	reviews := make([]map[string]interface{}, 0, len(flags))
+	for _, flag := range flags {
+		ticket, ok := tickets[*flag.TicketID]
+		if !ok {
+			h.logger.Warn("Ticket for review not found", "flag_id", flag.ID, "ticket_id", *flag.TicketID)
+			continue
+		}
+		event, ok := events[ticket.EventID]
+		if !ok {
+			h.logger.Warn("Event for review not found", "flag_id", flag.ID, "event_id", ticket.EventID)
+			continue
+		}
+
+		reviews = append(reviews, map[string]interface{}{
+			"flag_id":    flag.ID,
+			"flagged_at": flag.CreatedAt,
+			"user_id":    flag.UserID,
+			"client_ip":  flag.ClientIP,
+			"signals":    flag.Signals,
+			"ticket": map[string]interface{}{
+				"id":             ticket.ID,
+				"ticket_code":    ticket.TicketCode,
+				"payment_status": ticket.PaymentStatus,
+				"uma_address":    ticket.UMAAddress,
+				"amount_sats":    ticketAmount(ticket, event),
+				"created_at":     ticket.CreatedAt,
+			},
+			"event": map[string]interface{}{
+				"id":         event.ID,
+				"title":      event.Title,
+				"price_sats": event.PriceSats,
+			},
+		})
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Review queue retrieved successfully",
+		Data:    reviews,
+	})
+}
+
+// HandleApproveReview releases a held ticket: free tickets are confirmed and
+// paid ones are invoiced as in a normal purchase (admin only)
+func (h *TicketHandlers) HandleApproveReview(w http.ResponseWriter, r *http.Request) {
+	ticket, event, ok := h.heldTicket(w, r)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{"ticket_id": ticket.ID}
+	var notification i18n.Notification
+
+	amount := ticketAmount(ticket, event)
+	if amount == 0 {
+		if err := h.ticketRepo.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+			h.logger.Error("Failed to confirm reviewed ticket", "ticket_id", ticket.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to update ticket")
+			return
+		}
+		response["payment_status"] = "paid"
+		notification = i18n.Notification{Key: i18n.PurchaseConfirmed, Args: []any{event.Title, ticket.TicketCode}}
+	} else {
+		charges, err := h.charges(event, amount)
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := h.limits.CheckInvoice(charges.total()); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Released before invoicing so a settlement finds the ticket
+		// pending, and held again if no invoice is issued, so it stays in
+		// the queue rather than pending without a payment
+		ticket.PaymentStatus = "pending"
+		if err := h.ticketRepo.Update(ticket); err != nil {
+			h.logger.Error("Failed to release reviewed ticket", "ticket_id", ticket.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to update ticket")
+			return
+		}
+
+		invoice, err := h.issueTicketInvoice(ticket, event, event.InvoiceExpiry())
+		if err != nil {
+			ticket.PaymentStatus = "review"
+			if restoreErr := h.ticketRepo.Update(ticket); restoreErr != nil {
+				h.logger.Error("Failed to hold ticket again after invoicing failed", "ticket_id", ticket.ID, "error", restoreErr)
+			}
+		}
+		if paymentBackendUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response["payment_status"] = "pending"
+		response["invoice_id"] = invoice.InvoiceID
+		response["bolt11"] = invoice.Bolt11
+		notification = i18n.Notification{Key: i18n.PurchaseApproved, Args: []any{event.Title, invoice.AmountSats}}
+	}
+
+	h.logger.Info("Held ticket approved", "ticket_id", ticket.ID, "event_id", event.ID)
+	h.notifyBuyer(ticket, notification)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket approved",
+		Data:    response,
+	})
+}
+
+// HandleRejectReview cancels a held ticket, releasing its seat (admin only)
+func (h *TicketHandlers) HandleRejectReview(w http.ResponseWriter, r *http.Request) {
+	ticket, event, ok := h.heldTicket(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ticketRepo.UpdatePaymentStatus(ticket.ID, "cancelled"); err != nil {
+		h.logger.Error("Failed to cancel reviewed ticket", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update ticket")
+		return
+	}
+
+	h.logger.Info("Held ticket rejected", "ticket_id", ticket.ID, "event_id", event.ID)
+	h.notifyBuyer(ticket, i18n.Notification{Key: i18n.PurchaseRejected, Args: []any{event.Title}})
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket rejected",
+		Data: map[string]interface{}{
+			"ticket_id":      ticket.ID,
+			"payment_status": "cancelled",
+		},
+	})
+}
+
+// heldTicket loads the ticket named in the route and its event, writing the
+// error response itself when the ticket is missing or not awaiting review.
+func (h *TicketHandlers) heldTicket(w http.ResponseWriter, r *http.Request) (*models.Ticket, *models.Event, bool) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return nil, nil, false
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return nil, nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return nil, nil, false
+	}
+	if ticket.PaymentStatus != "review" {
+		middleware.WriteError(w, http.StatusConflict, "Ticket is not awaiting review")
+		return nil, nil, false
+	}
+
+	event, err := h.eventRepo.GetByID(ticket.EventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", ticket.EventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return nil, nil, false
+	}
+	return ticket, event, true
+}
+
+func (h *TicketHandlers) notifyBuyer(ticket *models.Ticket, notification i18n.Notification) {
+	if h.notifier == nil {
+		return
+	}
+	notification.EventID = ticket.EventID
+	if err := h.notifier.NotifyUser(ticket.UserID, notification); err != nil {
+		h.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+	}
+}
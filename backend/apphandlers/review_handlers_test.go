@@ -0,0 +1,162 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type recordingNotifier struct {
+	subjects map[int][]string
+}
+
+func (n *recordingNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	subject, _ := notification.Render(i18n.Default)
+	n.subjects[userID] = append(n.subjects[userID], subject)
+	return nil
+}
+
+func TestReviewQueue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	fraud := services.NewFraudService(store.FraudFlags(), store.Users(), settings, "", clk, logger)
+	notifier := &recordingNotifier{subjects: make(map[int][]string)}
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, fraud, notifier, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/purchase", handler.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/admin/reviews", handler.HandleListReviews).Methods("GET")
+	router.HandleFunc("/api/admin/reviews/{id:[0-9]+}/approve", handler.HandleApproveReview).Methods("POST")
+	router.HandleFunc("/api/admin/reviews/{id:[0-9]+}/reject", handler.HandleRejectReview).Methods("POST")
+
+	// A disposable email trips a rule whose default action is review
+	buyer := &models.User{Email: "buyer@mailinator.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	paid := &models.Event{Title: "Paid", Capacity: 10, PriceSats: 1000, IsActive: true}
+	free := &models.Event{Title: "Free", Capacity: 10, IsActive: true}
+	for _, event := range []*models.Event{paid, free} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data interface{} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		data, _ := resp.Data.(map[string]interface{})
+		return rec.Code, data
+	}
+	purchase := func(eventID int) int {
+		t.Helper()
+		status, data := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{
+			EventID: eventID, UserID: buyer.ID, UMAAddress: "$buyer@wallet.example.com",
+		})
+		if status != http.StatusAccepted {
+			t.Fatalf("Expected purchase to be held with 202, got %d", status)
+		}
+		ticket := data["ticket"].(map[string]interface{})
+		if ticket["payment_status"] != "review" || data["uma_request"] != nil {
+			t.Fatalf("Expected held ticket without invoice, got %v", data)
+		}
+		return int(ticket["id"].(float64))
+	}
+
+	paidTicket := purchase(paid.ID)
+	freeTicket := purchase(free.ID)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/reviews", nil))
+	var queue struct {
+		Data []struct {
+			Signals models.FraudSignals    `json:"signals"`
+			Ticket  map[string]interface{} `json:"ticket"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.Data) != 2 || int(queue.Data[0].Ticket["id"].(float64)) != paidTicket ||
+		queue.Data[0].Signals[0].Rule != services.FraudRuleDisposableEmail {
+		t.Fatalf("Unexpected review queue %s", rec.Body.String())
+	}
+
+	// A ticket that cannot be invoiced stays held
+	held, err := store.Tickets().GetByID(paidTicket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held.UMAAddress = "not-an-address"
+	if err := store.Tickets().Update(held); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := do("POST", "/api/admin/reviews/"+strconv.Itoa(paidTicket)+"/approve", nil); status != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when invoicing fails, got %d", status)
+	}
+	if ticket, err := store.Tickets().GetByID(paidTicket); err != nil || ticket.PaymentStatus != "review" {
+		t.Fatalf("Expected the ticket held again, got %+v (%v)", ticket, err)
+	}
+	held.UMAAddress = "$buyer@wallet.example.com"
+	if err := store.Tickets().Update(held); err != nil {
+		t.Fatal(err)
+	}
+
+	// Approving a paid ticket invoices it
+	status, data := do("POST", "/api/admin/reviews/"+strconv.Itoa(paidTicket)+"/approve", nil)
+	if status != http.StatusOK || data["payment_status"] != "pending" || data["bolt11"] == "" {
+		t.Fatalf("Expected approved ticket with invoice, got %d %v", status, data)
+	}
+	if status, _ := do("POST", "/api/admin/reviews/"+strconv.Itoa(paidTicket)+"/approve", nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 approving a released ticket, got %d", status)
+	}
+
+	// Rejecting cancels the ticket
+	status, _ = do("POST", "/api/admin/reviews/"+strconv.Itoa(freeTicket)+"/reject", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200 rejecting ticket, got %d", status)
+	}
+	ticket, err := store.Tickets().GetByID(freeTicket)
+	if err != nil || ticket.PaymentStatus != "cancelled" {
+		t.Errorf("Expected cancelled ticket, got %+v (%v)", ticket, err)
+	}
+
+	if status, _ := do("POST", "/api/admin/reviews/999/reject", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown ticket, got %d", status)
+	}
+
+	want := []string{"Ticket purchase approved", "Ticket purchase cancelled"}
+	if got := notifier.subjects[buyer.ID]; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected notifications %v, got %v", want, got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/reviews", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil || len(queue.Data) != 0 {
+		t.Errorf("Expected empty review queue, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,168 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// ScannerHandlers manages the door scanners of events (admin only) and
+// registers scanner devices with their pairing codes
+type ScannerHandlers struct {
+	scanners  *services.ScannerService
+	eventRepo repositories.EventRepository
+	logger    *slog.Logger
+}
+
+func NewScannerHandlers(scanners *services.ScannerService, eventRepo repositories.EventRepository, logger *slog.Logger) *ScannerHandlers {
+	return &ScannerHandlers{
+		scanners:  scanners,
+		eventRepo: eventRepo,
+		logger:    logger,
+	}
+}
+
+// HandleCreateScanner adds a scanner to an event, returning the pairing
+// code its device registers with. The code is shown only here.
+func (h *ScannerHandlers) HandleCreateScanner(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.CreateScannerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pairing, err := h.scanners.Create(eventID, req.Name, adminID(r))
+	switch {
+	case errors.Is(err, services.ErrScannerName):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	case err != nil:
+		h.logger.Error("Failed to create scanner", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create scanner")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Scanner created successfully",
+		Data:    pairing,
+	})
+}
+
+// HandleListScanners lists an event's scanners, revoked ones included
+func (h *ScannerHandlers) HandleListScanners(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	if _, err := h.eventRepo.GetByID(eventID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			middleware.WriteError(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	devices, err := h.scanners.List(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch scanners", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch scanners")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Scanners retrieved successfully",
+		Data:    devices,
+	})
+}
+
+// HandleRevokeScanner stops a scanner's device token and any unused
+// pairing code; its past scans keep naming it
+func (h *ScannerHandlers) HandleRevokeScanner(w http.ResponseWriter, r *http.Request) {
+	scannerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid scanner ID")
+		return
+	}
+
+	device, err := h.scanners.Revoke(scannerID, adminID(r))
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Scanner not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to revoke scanner", "scanner_id", scannerID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to revoke scanner")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Scanner revoked successfully",
+		Data:    device,
+	})
+}
+
+// HandleRegisterScanner redeems a pairing code for the device token a
+// scanner sends to the /api/scanner routes. The token is shown only here.
+func (h *ScannerHandlers) HandleRegisterScanner(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterScannerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	registration, err := h.scanners.Register(req.PairingCode)
+	if errors.Is(err, services.ErrScannerPairing) {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to register scanner", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to register scanner")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Scanner registered successfully",
+		Data:    registration,
+	})
+}
+
+// scannerEvent scopes a validation request made with a scanner token to
+// the scanner's event: a missing event ID becomes the scanner's, and
+// another event is refused. Requests without a scanner pass unchanged.
+func scannerEvent(w http.ResponseWriter, r *http.Request, eventID *int) bool {
+	device := middleware.GetScannerFromContext(r.Context())
+	if device == nil {
+		return true
+	}
+	if *eventID == 0 {
+		*eventID = device.EventID
+	}
+	if *eventID != device.EventID {
+		middleware.WriteError(w, http.StatusForbidden, "Scanner is registered for another event")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+	"tickets-by-uma/ticketcode"
+)
+
+func TestScannerHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	scanners := services.NewScannerService(store.ScannerDevices(), store.Events(), clk, logger)
+	checkIns := services.NewCheckInService(store.TicketScans(), store.Tickets(), clk, logger)
+	handler := NewScannerHandlers(scanners, store.Events(), logger)
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, checkIns, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/scanners", handler.HandleCreateScanner).Methods("POST")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/scanners", handler.HandleListScanners).Methods("GET")
+	router.HandleFunc("/api/admin/scanners/{id:[0-9]+}", handler.HandleRevokeScanner).Methods("DELETE")
+	router.HandleFunc("/api/scanners/register", handler.HandleRegisterScanner).Methods("POST")
+	scanner := router.PathPrefix("/api/scanner").Subrouter()
+	scanner.Use(middleware.ScannerAuthMiddleware(scanners.Authenticate))
+	scanner.HandleFunc("/tickets/validate", tickets.HandleValidateTicket).Methods("POST")
+
+	admin := &models.User{ID: 42}
+	do := func(method, path, token string, body interface{}) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, admin))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(3 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	other := &models.Event{Title: "Other", StartTime: clk.Now(), EndTime: clk.Now().Add(3 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	for _, e := range []*models.Event{event, other} {
+		if err := store.Events().Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code, err := ticketcode.New(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: 7, TicketCode: code, PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	scannersPath := "/api/admin/events/" + strconv.Itoa(event.ID) + "/scanners"
+	if status, _ := do("POST", scannersPath, "", models.CreateScannerRequest{}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a name, got %d", status)
+	}
+	if status, _ := do("POST", "/api/admin/events/9999/scanners", "", models.CreateScannerRequest{Name: "Door 1"}); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown event, got %d", status)
+	}
+
+	var created struct {
+		Data models.ScannerPairing `json:"data"`
+	}
+	status, body := do("POST", scannersPath, "", models.CreateScannerRequest{Name: "Door 1"})
+	json.Unmarshal(body, &created)
+	if status != http.StatusCreated || created.Data.PairingCode == "" || created.Data.Device.EventID != event.ID {
+		t.Fatalf("Expected the scanner created with a pairing code, got %d %s", status, body)
+	}
+	if bytes.Contains(body, []byte("pairing_hash")) {
+		t.Errorf("Expected the pairing hash kept out of responses, got %s", body)
+	}
+
+	if status, _ := do("POST", "/api/scanners/register", "", models.RegisterScannerRequest{PairingCode: "WRONG123"}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a wrong pairing code, got %d", status)
+	}
+	var registered struct {
+		Data models.ScannerRegistration `json:"data"`
+	}
+	status, body = do("POST", "/api/scanners/register", "", models.RegisterScannerRequest{PairingCode: created.Data.PairingCode})
+	json.Unmarshal(body, &registered)
+	if status != http.StatusCreated || registered.Data.Token == "" {
+		t.Fatalf("Expected the scanner registered, got %d %s", status, body)
+	}
+	token := registered.Data.Token
+
+	// A scanner validates for its own event, which it need not name
+	if status, _ := do("POST", "/api/scanner/tickets/validate", token, models.TicketValidationRequest{TicketCode: code, EventID: other.ID}); status != http.StatusForbidden {
+		t.Errorf("Expected 403 validating for another event, got %d", status)
+	}
+	if status, body := do("POST", "/api/scanner/tickets/validate", token, models.TicketValidationRequest{TicketCode: code}); status != http.StatusOK {
+		t.Fatalf("Expected the ticket admitted, got %d %s", status, body)
+	}
+	scans, err := store.TicketScans().ListRecent(event.ID, 10)
+	if err != nil || len(scans) != 1 || scans[0].ScannerDeviceID == nil || *scans[0].ScannerDeviceID != created.Data.Device.ID {
+		t.Errorf("Expected the scan attributed to the scanner, got %+v (%v)", scans, err)
+	}
+
+	revokePath := "/api/admin/scanners/" + strconv.Itoa(created.Data.Device.ID)
+	if status, _ := do("DELETE", revokePath, "", nil); status != http.StatusOK {
+		t.Fatalf("Expected the scanner revoked, got %d", status)
+	}
+	if status, _ := do("POST", "/api/scanner/tickets/validate", token, models.TicketValidationRequest{TicketCode: code}); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 once revoked, got %d", status)
+	}
+	if status, _ := do("DELETE", "/api/admin/scanners/9999", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking an unknown scanner, got %d", status)
+	}
+
+	var listed struct {
+		Data []models.ScannerDevice `json:"data"`
+	}
+	status, body = do("GET", scannersPath, "", nil)
+	json.Unmarshal(body, &listed)
+	if status != http.StatusOK || len(listed.Data) != 1 || listed.Data[0].RevokedAt == nil || listed.Data[0].LastSeenAt == nil {
+		t.Errorf("Expected the revoked scanner listed, got %d %s", status, body)
+	}
+}
@@ -0,0 +1,67 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type SEOHandlers struct {
+	seo    *services.SEOService
+	logger *slog.Logger
+}
+
+func NewSEOHandlers(seo *services.SEOService, logger *slog.Logger) *SEOHandlers {
+	return &SEOHandlers{
+		seo:    seo,
+		logger: logger,
+	}
+}
+
+// HandleSitemap serves the sitemap of public event pages for search
+// engines, answering If-Modified-Since with 304 when nothing changed
+func (h *SEOHandlers) HandleSitemap(w http.ResponseWriter, r *http.Request) {
+	sitemap, modified, err := h.seo.Sitemap()
+	if err != nil {
+		h.logger.Error("Failed to build sitemap", "error", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	http.ServeContent(w, r, "sitemap.xml", modified, bytes.NewReader(sitemap))
+}
+
+// HandleGetEventJSONLD returns a public event's Schema.org Event markup as
+// JSON-LD, for event pages to embed
+func (h *SEOHandlers) HandleGetEventJSONLD(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	markup, err := h.seo.EventJSONLD(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to build event markup", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/ld+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(markup)
+}
@@ -0,0 +1,64 @@
+package apphandlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestSEOHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewSEOHandlers(services.NewSEOService(store.Events(), store.OrganizerProfiles(), "tickets.example", logger), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/sitemap.xml", handler.HandleSitemap).Methods("GET")
+	router.HandleFunc("/api/events/{id:[0-9]+}/jsonld", handler.HandleGetEventJSONLD).Methods("GET")
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), Capacity: 10, IsActive: true}
+	private := &models.Event{Title: "Secret", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), Capacity: 10, IsActive: true, IsPrivate: true}
+	for _, e := range []*models.Event{event, private} {
+		if err := store.Events().Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/sitemap.xml", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/xml; charset=utf-8" || rec.Header().Get("Last-Modified") == "" {
+		t.Fatalf("Unexpected sitemap response %d %v", rec.Code, rec.Header())
+	}
+	req := httptest.NewRequest("GET", "/sitemap.xml", nil)
+	req.Header.Set("If-Modified-Since", rec.Header().Get("Last-Modified"))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged sitemap, got %d", rec.Code)
+	}
+
+	for path, want := range map[string]int{
+		"/api/events/1/jsonld":    http.StatusOK,
+		"/api/events/2/jsonld":    http.StatusNotFound,
+		"/api/events/9999/jsonld": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+		if want == http.StatusOK && rec.Header().Get("Content-Type") != "application/ld+json" {
+			t.Errorf("Expected JSON-LD, got %s", rec.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type SettingsHandlers struct {
+	settings  *services.SettingsService
+	eventRepo repositories.EventRepository
+	logger    *slog.Logger
+}
+
+func NewSettingsHandlers(settings *services.SettingsService, eventRepo repositories.EventRepository, logger *slog.Logger) *SettingsHandlers {
+	return &SettingsHandlers{
+		settings:  settings,
+		eventRepo: eventRepo,
+		logger:    logger,
+	}
+}
+
+// HandleGetSettings lists all runtime settings (admin only)
+func (h *SettingsHandlers) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Settings retrieved successfully",
+		Data:    h.settings.All(),
+	})
+}
+
+// HandleUpdateSetting creates or updates a runtime setting (admin only)
+func (h *SettingsHandlers) HandleUpdateSetting(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	var req models.UpdateSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Value) == 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "Value is required")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+
+	h.logger.Info("Updating runtime setting", "key", key, "admin_id", admin.ID)
+
+	if err := h.settings.Set(key, req.Value, admin.Email); err != nil {
+		h.logger.Error("Failed to update setting", "key", key, "error", err)
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Setting updated successfully",
+		Data:    map[string]interface{}{"key": key, "value": req.Value},
+	})
+}
+
+// HandleDeleteSetting removes a runtime setting, restoring its default (admin only)
+func (h *SettingsHandlers) HandleDeleteSetting(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	h.logger.Info("Deleting runtime setting", "key", key)
+
+	if err := h.settings.Delete(key); err != nil {
+		h.logger.Error("Failed to delete setting", "key", key, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete setting")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Setting deleted successfully",
+	})
+}
+
+// HandleGetSalesPause reports whether sales are paused globally and which
+// events are paused on their own (admin only)
+func (h *SettingsHandlers) HandleGetSalesPause(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Sales pause retrieved successfully",
+		Data:    h.salesPauseStatus(),
+	})
+}
+
+// HandleSetSalesPause pauses or resumes ticket sales for all events. While
+// paused, purchases return 503; reads and check-in keep working (admin only).
+func (h *SettingsHandlers) HandleSetSalesPause(w http.ResponseWriter, r *http.Request) {
+	var req models.SalesPauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Paused == nil {
+		middleware.WriteError(w, http.StatusBadRequest, "paused is required")
+		return
+	}
+
+	admin := middleware.GetUserFromContext(r.Context())
+	value, _ := json.Marshal(*req.Paused)
+	if err := h.settings.Set(services.SettingSalesPaused, value, admin.Email); err != nil {
+		h.logger.Error("Failed to set sales pause", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update sales pause")
+		return
+	}
+
+	h.logger.Warn("Sales pause changed", "audit", true, "admin_id", admin.ID, "paused", *req.Paused)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Sales pause updated successfully",
+		Data:    h.salesPauseStatus(),
+	})
+}
+
+// HandleSetEventSalesPause pauses or resumes ticket sales for one event
+// (admin only)
+func (h *SettingsHandlers) HandleSetEventSalesPause(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.SalesPauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Paused == nil {
+		middleware.WriteError(w, http.StatusBadRequest, "paused is required")
+		return
+	}
+
+	if _, err := h.eventRepo.GetByID(eventID); errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	// Resuming removes the setting so resumed events do not pile up
+	admin := middleware.GetUserFromContext(r.Context())
+	key := services.EventSalesPausedKey(eventID)
+	if *req.Paused {
+		err = h.settings.Set(key, json.RawMessage("true"), admin.Email)
+	} else {
+		err = h.settings.Delete(key)
+	}
+	if err != nil {
+		h.logger.Error("Failed to set event sales pause", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update sales pause")
+		return
+	}
+
+	h.logger.Warn("Event sales pause changed", "audit", true, "admin_id", admin.ID, "event_id", eventID, "paused", *req.Paused)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Sales pause updated successfully",
+		Data:    h.salesPauseStatus(),
+	})
+}
+
+func (h *SettingsHandlers) salesPauseStatus() models.SalesPauseStatus {
+	return models.SalesPauseStatus{
+		Paused:       h.settings.Bool(services.SettingSalesPaused, false),
+		PausedEvents: h.settings.PausedEvents(),
+	}
+}
@@ -0,0 +1,244 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+type ShortLinkHandlers struct {
+	links      *services.ShortLinkService
+	eventRepo  repositories.EventRepository
+	ticketRepo repositories.TicketRepository
+	logger     *slog.Logger
+}
+
+func NewShortLinkHandlers(links *services.ShortLinkService, eventRepo repositories.EventRepository, ticketRepo repositories.TicketRepository, logger *slog.Logger) *ShortLinkHandlers {
+	return &ShortLinkHandlers{
+		links:      links,
+		eventRepo:  eventRepo,
+		ticketRepo: ticketRepo,
+		logger:     logger,
+	}
+}
+
+// HandleFollowEventLink redirects /e/{code} to the event page, counting the
+// click
+func (h *ShortLinkHandlers) HandleFollowEventLink(w http.ResponseWriter, r *http.Request) {
+	h.follow(w, r, services.ShortLinkEventPrefix)
+}
+
+// HandleFollowTicketLink redirects /t/{code} to the ticket, counting the
+// click
+func (h *ShortLinkHandlers) HandleFollowTicketLink(w http.ResponseWriter, r *http.Request) {
+	h.follow(w, r, services.ShortLinkTicketPrefix)
+}
+
+func (h *ShortLinkHandlers) follow(w http.ResponseWriter, r *http.Request, prefix string) {
+	code := mux.Vars(r)["code"]
+	target, err := h.links.Follow(prefix, code)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to follow short link", "code", code, "error", err)
+		http.Error(w, "Failed to follow link", http.StatusInternalServerError)
+		return
+	}
+
+	// Not cached, so every visit is counted
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// HandleGetEventLink returns the short link for sharing an active public
+// event, created on first use
+func (h *ShortLinkHandlers) HandleGetEventLink(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	event, err := h.eventRepo.GetByID(eventID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+	if event == nil || !event.IsActive || event.IsPrivate {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	link, err := h.links.ForEvent(eventID)
+	if err != nil {
+		h.logger.Error("Failed to create event short link", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create short link")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Short link retrieved successfully",
+		Data:    link,
+	})
+}
+
+// HandleGetTicketLink returns the short link to one of the user's tickets,
+// created on first use
+func (h *ShortLinkHandlers) HandleGetTicketLink(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	link, err := h.links.ForTicket(ticketID)
+	if err != nil {
+		h.logger.Error("Failed to create ticket short link", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create short link")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Short link retrieved successfully",
+		Data:    link,
+	})
+}
+
+// HandleListEventLinks lists an event's short links with their click
+// counts (admin only)
+func (h *ShortLinkHandlers) HandleListEventLinks(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	links, err := h.links.List(eventID)
+	if err != nil {
+		h.logger.Error("Failed to list short links", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch short links")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Short links retrieved successfully",
+		Data:    links,
+	})
+}
+
+// HandleCreateEventLink gives an event a short link with a custom code,
+// such as for a marketing campaign (admin only)
+func (h *ShortLinkHandlers) HandleCreateEventLink(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	var req models.ShortLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	link, err := h.links.CreateCustom(eventID, req.Code, adminID(r))
+	if !h.writeLinkError(w, err, "event_id", eventID) {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Short link created successfully",
+		Data:    link,
+	})
+}
+
+// HandleUpdateLink changes a short link's code (admin only)
+func (h *ShortLinkHandlers) HandleUpdateLink(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid short link ID")
+		return
+	}
+
+	var req models.ShortLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	link, err := h.links.Rename(linkID, req.Code)
+	if !h.writeLinkError(w, err, "link_id", linkID) {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Short link updated successfully",
+		Data:    link,
+	})
+}
+
+// HandleDeleteLink removes a short link (admin only)
+func (h *ShortLinkHandlers) HandleDeleteLink(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid short link ID")
+		return
+	}
+
+	if !h.writeLinkError(w, h.links.Delete(linkID), "link_id", linkID) {
+		return
+	}
+	h.logger.Info("Short link deleted", "link_id", linkID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Short link deleted successfully",
+	})
+}
+
+// writeLinkError writes the response for a failed short link change,
+// reporting whether err was nil
+func (h *ShortLinkHandlers) writeLinkError(w http.ResponseWriter, err error, key string, id int) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrShortLinkCode):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrNotFound):
+		if key == "event_id" {
+			middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		} else {
+			middleware.WriteError(w, http.StatusNotFound, "Short link not found")
+		}
+	case errors.Is(err, repositories.ErrConflict):
+		middleware.WriteError(w, http.StatusConflict, "Code is already taken")
+	default:
+		h.logger.Error("Failed to save short link", key, id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save short link")
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestShortLinkHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	links := services.NewShortLinkService(store.ShortLinks(), store.Events(), "tickets.example", logger)
+	handler := NewShortLinkHandlers(links, store.Events(), store.Tickets(), logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/e/{code}", handler.HandleFollowEventLink).Methods("GET")
+	router.HandleFunc("/t/{code}", handler.HandleFollowTicketLink).Methods("GET")
+	router.HandleFunc("/api/events/{id:[0-9]+}/shortlink", handler.HandleGetEventLink).Methods("GET")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/shortlink", handler.HandleGetTicketLink).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/shortlinks", handler.HandleListEventLinks).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/shortlinks", handler.HandleCreateEventLink).Methods("POST")
+	router.HandleFunc("/api/admin/shortlinks/{id:[0-9]+}", handler.HandleUpdateLink).Methods("PUT")
+	router.HandleFunc("/api/admin/shortlinks/{id:[0-9]+}", handler.HandleDeleteLink).Methods("DELETE")
+
+	owner := &models.User{ID: 1, Email: "owner@example.com"}
+	public := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), Capacity: 10, IsActive: true}
+	private := &models.Event{Title: "Secret", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), Capacity: 10, IsActive: true, IsPrivate: true}
+	for _, e := range []*models.Event{public, private} {
+		if err := store.Events().Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ticket := &models.Ticket{EventID: public.ID, UserID: owner.ID, TicketCode: "T-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, path, body string, user *models.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder, v interface{}) int {
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err == nil {
+			json.Unmarshal(resp.Data, v)
+		}
+		return rec.Code
+	}
+
+	var eventLink models.ShortLink
+	rec := serve("GET", "/api/events/1/shortlink", "", nil)
+	if code := decode(rec, &eventLink); code != http.StatusOK || !strings.HasPrefix(eventLink.URL, "https://tickets.example/e/") {
+		t.Fatalf("Expected the event's short link, got %d %+v", code, eventLink)
+	}
+	rec = serve("GET", "/e/"+eventLink.Code, "", nil)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://tickets.example/events/1" {
+		t.Errorf("Expected a redirect to the event page, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	var ticketLink models.ShortLink
+	rec = serve("GET", "/api/tickets/1/shortlink", "", owner)
+	if code := decode(rec, &ticketLink); code != http.StatusOK {
+		t.Fatalf("Expected the ticket's short link, got %d", code)
+	}
+	if rec := serve("GET", "/t/"+ticketLink.Code, "", nil); rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://tickets.example/tickets/1" {
+		t.Errorf("Expected a redirect to the ticket, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = serve("POST", "/api/admin/events/1/shortlinks", `{"code":"Launch-Party"}`, owner)
+	var custom models.ShortLink
+	if code := decode(rec, &custom); code != http.StatusCreated || custom.URL != "https://tickets.example/e/launch-party" || !custom.Custom {
+		t.Fatalf("Expected the custom link created, got %d %+v", code, custom)
+	}
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		user                     *models.User
+		want                     int
+	}{
+		{"private event", "GET", "/api/events/2/shortlink", "", nil, http.StatusNotFound},
+		{"unknown event", "GET", "/api/events/99/shortlink", "", nil, http.StatusNotFound},
+		{"someone else's ticket", "GET", "/api/tickets/1/shortlink", "", &models.User{ID: 2}, http.StatusNotFound},
+		{"ticket link as event", "GET", "/e/" + ticketLink.Code, "", nil, http.StatusNotFound},
+		{"unknown code", "GET", "/e/nothing", "", nil, http.StatusNotFound},
+		{"taken code", "POST", "/api/admin/events/1/shortlinks", `{"code":"launch-party"}`, owner, http.StatusConflict},
+		{"invalid code", "POST", "/api/admin/events/1/shortlinks", `{"code":"a b"}`, owner, http.StatusBadRequest},
+		{"custom for unknown event", "POST", "/api/admin/events/99/shortlinks", `{"code":"nowhere"}`, owner, http.StatusNotFound},
+		{"rename taken", "PUT", "/api/admin/shortlinks/1", `{"code":"launch-party"}`, owner, http.StatusConflict},
+		{"rename", "PUT", "/api/admin/shortlinks/1", `{"code":"spring"}`, owner, http.StatusOK},
+		{"rename unknown", "PUT", "/api/admin/shortlinks/99", `{"code":"nowhere"}`, owner, http.StatusNotFound},
+		{"follow renamed", "GET", "/e/spring", "", nil, http.StatusFound},
+		{"delete", "DELETE", "/api/admin/shortlinks/3", "", owner, http.StatusOK},
+		{"follow deleted", "GET", "/e/launch-party", "", nil, http.StatusNotFound},
+		{"delete unknown", "DELETE", "/api/admin/shortlinks/3", "", owner, http.StatusNotFound},
+	} {
+		if rec := serve(tt.method, tt.path, tt.body, tt.user); rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+
+	var listed []models.ShortLink
+	rec = serve("GET", "/api/admin/events/1/shortlinks", "", owner)
+	if code := decode(rec, &listed); code != http.StatusOK || len(listed) != 1 || listed[0].Code != "spring" || listed[0].Clicks != 2 {
+		t.Errorf("Expected the renamed link with its clicks, got %d %+v", code, listed)
+	}
+}
@@ -0,0 +1,236 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// StatusHandlers serve the public status page and the incidents admins
+// post to it
+type StatusHandlers struct {
+	status       *services.StatusService
+	incidentRepo repositories.StatusIncidentRepository
+	clock        clock.Clock
+	logger       *slog.Logger
+}
+
+func NewStatusHandlers(status *services.StatusService, incidentRepo repositories.StatusIncidentRepository, clk clock.Clock, logger *slog.Logger) *StatusHandlers {
+	return &StatusHandlers{
+		status:       status,
+		incidentRepo: incidentRepo,
+		clock:        clk,
+		logger:       logger,
+	}
+}
+
+// HandleGetStatus reports each subsystem's health and the unresolved and
+// recently resolved incidents, checked at most every few seconds; incident
+// changes show at once. It answers 200 however degraded the backend
+// is, so a status page can tell an outage from the backend being
+// unreachable.
+func (h *StatusHandlers) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Status retrieved successfully",
+		Data:    h.status.Status(),
+	})
+}
+
+// HandleListIncidents lists incidents latest started first (admin only)
+func (h *StatusHandlers) HandleListIncidents(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	incidents, err := h.incidentRepo.List(limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to fetch status incidents", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch incidents")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Incidents retrieved successfully",
+		Data:    incidents,
+	})
+}
+
+// HandleCreateIncident posts an incident to the status page (admin only)
+func (h *StatusHandlers) HandleCreateIncident(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateStatusIncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	admin := middleware.GetUserFromContext(r.Context())
+
+	incident := &models.StatusIncident{
+		Title:      req.Title,
+		Message:    req.Message,
+		Status:     req.Status,
+		Impact:     req.Impact,
+		Components: req.Components,
+		CreatedBy:  &admin.ID,
+		StartedAt:  h.clock.Now(),
+	}
+	if incident.Status == "" {
+		incident.Status = models.IncidentInvestigating
+	}
+	if incident.Impact == "" {
+		incident.Impact = models.IncidentImpactMinor
+	}
+	if req.StartedAt != nil {
+		incident.StartedAt = req.StartedAt.UTC()
+	}
+	if err := services.ValidateStatusIncident(incident); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.resolve(incident)
+
+	if err := h.incidentRepo.Create(incident); err != nil {
+		h.logger.Error("Failed to create status incident", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create incident")
+		return
+	}
+	h.status.Invalidate()
+
+	h.logger.Info("Status incident posted", "audit", true, "admin_id", admin.ID, "incident_id", incident.ID,
+		"status", incident.Status, "impact", incident.Impact, "components", incident.Components)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Incident created successfully",
+		Data:    incident,
+	})
+}
+
+// HandleUpdateIncident changes the fields of an incident that are set.
+// Setting its status to resolved resolves it; any other status reopens it
+// (admin only).
+func (h *StatusHandlers) HandleUpdateIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid incident ID")
+		return
+	}
+	var req models.UpdateStatusIncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	incident, err := h.incidentRepo.GetByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Incident not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch status incident", "incident_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch incident")
+		return
+	}
+
+	if req.Title != nil {
+		incident.Title = *req.Title
+	}
+	if req.Message != nil {
+		incident.Message = *req.Message
+	}
+	if req.Status != nil {
+		incident.Status = *req.Status
+	}
+	if req.Impact != nil {
+		incident.Impact = *req.Impact
+	}
+	if req.Components != nil {
+		incident.Components = *req.Components
+	}
+	if err := services.ValidateStatusIncident(incident); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.resolve(incident)
+
+	err = h.incidentRepo.Update(incident)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Incident not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update status incident", "incident_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update incident")
+		return
+	}
+	h.status.Invalidate()
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Status incident updated", "audit", true, "admin_id", admin.ID, "incident_id", incident.ID,
+		"status", incident.Status, "impact", incident.Impact, "components", incident.Components)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Incident updated successfully",
+		Data:    incident,
+	})
+}
+
+// HandleDeleteIncident removes an incident from the status page (admin
+// only)
+func (h *StatusHandlers) HandleDeleteIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid incident ID")
+		return
+	}
+
+	err = h.incidentRepo.Delete(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Incident not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete status incident", "incident_id", id, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete incident")
+		return
+	}
+	h.status.Invalidate()
+
+	admin := middleware.GetUserFromContext(r.Context())
+	h.logger.Info("Status incident deleted", "audit", true, "admin_id", admin.ID, "incident_id", id)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Incident deleted successfully",
+	})
+}
+
+// resolve stamps when a resolved incident was resolved, keeping the first
+// stamp, and clears it on one reopened
+func (h *StatusHandlers) resolve(incident *models.StatusIncident) {
+	if incident.Status != models.IncidentResolved {
+		incident.ResolvedAt = nil
+	} else if incident.ResolvedAt == nil {
+		now := h.clock.Now()
+		incident.ResolvedAt = &now
+	}
+}
@@ -0,0 +1,134 @@
+package apphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestStatusHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	status := services.NewStatusService(nil, false, nil, nil, store.AlertConnectors(), store.StatusIncidents(), clk, logger)
+	handler := NewStatusHandlers(status, store.StatusIncidents(), clk, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/status", handler.HandleGetStatus).Methods("GET")
+	router.HandleFunc("/api/admin/incidents", handler.HandleListIncidents).Methods("GET")
+	router.HandleFunc("/api/admin/incidents", handler.HandleCreateIncident).Methods("POST")
+	router.HandleFunc("/api/admin/incidents/{id:[0-9]+}", handler.HandleUpdateIncident).Methods("PUT")
+	router.HandleFunc("/api/admin/incidents/{id:[0-9]+}", handler.HandleDeleteIncident).Methods("DELETE")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if strings.HasPrefix(path, "/api/admin/") {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: 1, Email: "admin@example.com"}))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	getStatus := func() models.SystemStatus {
+		t.Helper()
+		rec := serve("GET", "/api/status", "")
+		var result struct {
+			Data models.SystemStatus `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("Expected the status, got %d %s", rec.Code, rec.Body.String())
+		}
+		return result.Data
+	}
+
+	if got := getStatus(); got.Status != models.StatusOperational || len(got.Subsystems) != 5 || len(got.Incidents) != 0 {
+		t.Fatalf("Expected everything operational, got %+v", got)
+	}
+
+	rec := serve("POST", "/api/admin/incidents", `{"title":"Payments failing","message":"Our Lightning provider is down","impact":"major","components":["lightning"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data models.StatusIncident `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.Data.Status != models.IncidentInvestigating ||
+		created.Data.CreatedBy == nil || *created.Data.CreatedBy != 1 || !created.Data.StartedAt.Equal(clk.Now()) {
+		t.Fatalf("Unexpected incident %s (%v)", rec.Body.String(), err)
+	}
+	path := "/api/admin/incidents/" + strconv.Itoa(created.Data.ID)
+
+	got := getStatus()
+	if got.Status != models.StatusOutage || got.Subsystems[2].Status != models.StatusOutage || len(got.Incidents) != 1 {
+		t.Errorf("Expected the incident to take Lightning down, got %+v", got)
+	}
+	if rec := serve("GET", "/api/status", ""); strings.Contains(rec.Body.String(), "created_by") {
+		t.Errorf("Expected the incident's author left out, got %s", rec.Body.String())
+	}
+
+	if rec := serve("POST", "/api/admin/incidents", `{"title":"Mystery","components":["email"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown subsystem refused, got %d", rec.Code)
+	}
+	if rec := serve("PUT", path, `{"impact":"critical"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown impact refused, got %d", rec.Code)
+	}
+
+	clk.Advance(time.Hour)
+	if rec := serve("PUT", path, `{"status":"resolved","message":"Payments are flowing again"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	incident, _ := store.StatusIncidents().GetByID(created.Data.ID)
+	if incident.ResolvedAt == nil || !incident.ResolvedAt.Equal(clk.Now()) || incident.Message != "Payments are flowing again" {
+		t.Errorf("Expected the incident resolved now, got %+v", incident)
+	}
+	if got := getStatus(); got.Status != models.StatusOperational || len(got.Incidents) != 1 || got.Incidents[0].ResolvedAt == nil {
+		t.Errorf("Expected the resolved incident listed without marking Lightning, got %+v", got)
+	}
+
+	// A resolved incident keeps its resolution time; reopening clears it
+	clk.Advance(time.Hour)
+	if rec := serve("PUT", path, `{"message":"Root cause: expired API key"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if incident, _ := store.StatusIncidents().GetByID(created.Data.ID); !incident.ResolvedAt.Equal(clk.Now().Add(-time.Hour)) {
+		t.Errorf("Expected the first resolution time kept, got %v", incident.ResolvedAt)
+	}
+	if rec := serve("PUT", path, `{"status":"monitoring"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if incident, _ := store.StatusIncidents().GetByID(created.Data.ID); incident.ResolvedAt != nil {
+		t.Errorf("Expected the reopened incident unresolved, got %v", incident.ResolvedAt)
+	}
+
+	rec = serve("GET", "/api/admin/incidents", "")
+	var listed struct {
+		Data []models.StatusIncident `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed.Data) != 1 || listed.Data[0].CreatedBy == nil {
+		t.Errorf("Expected the incident with its author, got %s", rec.Body.String())
+	}
+
+	if rec := serve("DELETE", path, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if rec := serve("DELETE", path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", rec.Code)
+	}
+	if rec := serve("PUT", path, `{"status":"resolved"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 updating a deleted incident, got %d", rec.Code)
+	}
+}
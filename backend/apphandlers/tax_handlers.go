@@ -0,0 +1,60 @@
+package apphandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type TaxHandlers struct {
+	paymentRepo repositories.PaymentRepository
+	logger      *slog.Logger
+}
+
+func NewTaxHandlers(paymentRepo repositories.PaymentRepository, logger *slog.Logger) *TaxHandlers {
+	return &TaxHandlers{
+		paymentRepo: paymentRepo,
+		logger:      logger,
+	}
+}
+
+// HandleTaxSummary totals the tax collected on payments paid between from
+// (inclusive) and to (exclusive), both RFC 3339, per jurisdiction and rate
+// (admin only)
+func (h *TaxHandlers) HandleTaxSummary(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := reportPeriod(w, r, true)
+	if !ok {
+		return
+	}
+
+	summary, err := h.paymentRepo.TaxSummary(from, to)
+	if err != nil {
+		h.logger.Error("Failed to compute tax summary", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to compute tax summary")
+		return
+	}
+
+	var totals models.TaxSummary
+	for _, rate := range summary {
+		totals.Payments += rate.Payments
+		totals.TaxableSats += rate.TaxableSats
+		totals.TaxSats += rate.TaxSats
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Tax summary retrieved successfully",
+		Data: map[string]interface{}{
+			"from":  from,
+			"to":    to,
+			"rates": summary,
+			"totals": map[string]interface{}{
+				"payments":     totals.Payments,
+				"taxable_sats": totals.TaxableSats,
+				"tax_sats":     totals.TaxSats,
+			},
+		},
+	})
+}
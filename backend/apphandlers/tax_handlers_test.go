@@ -0,0 +1,137 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestTaxedCheckout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	fees := services.NewFeeService(store.Fees(), config.FeeSchedule{FixedSats: 10})
+	tickets := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), services.NewSettingsService(store.Settings(), logger), nil, nil, fees, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+
+	// purchase buys a ticket to event and returns its payment and line items
+	purchase := func(event *models.Event, userID int) (*models.Payment, []models.PaymentLineItem) {
+		t.Helper()
+		payload, _ := json.Marshal(models.TicketPurchaseRequest{EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com"})
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/tickets/purchase", bytes.NewReader(payload)))
+		var resp struct {
+			Data struct {
+				Ticket struct{ ID int } `json:"ticket"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		payment, err := store.Payments().GetByTicketID(resp.Data.Ticket.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, err := store.Receipts().GetLineItems(payment.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payment, items
+	}
+
+	// Tax charged on top gets its own line; the fee is charged on the price before tax
+	exclusive := &models.Event{Title: "Exclusive", Capacity: 10, PriceSats: 1000, IsActive: true,
+		TaxBasisPoints: 2000, TaxJurisdiction: "FR TVA"}
+	if err := store.Events().Create(exclusive); err != nil {
+		t.Fatal(err)
+	}
+	payment, items := purchase(exclusive, 1)
+	if payment.Amount != 1210 || payment.TaxSats != 200 || payment.TaxableSats != 1000 || payment.TaxJurisdiction != "FR TVA" {
+		t.Errorf("Expected 1000 + 200 tax + 10 fee, got %+v", payment)
+	}
+	if len(items) != 3 || items[0].AmountSats != 1000 ||
+		items[1].Kind != models.LineItemTax || items[1].AmountSats != 200 || items[1].Description != "FR TVA 20%" ||
+		items[2].Kind != models.LineItemFee {
+		t.Errorf("Expected ticket, tax and fee line items, got %+v", items)
+	}
+
+	// Tax included in the price is recorded on the payment but not itemized
+	inclusive := &models.Event{Title: "Inclusive", Capacity: 10, PriceSats: 1190, IsActive: true,
+		TaxBasisPoints: 1900, TaxInclusive: true, TaxJurisdiction: "DE VAT"}
+	if err := store.Events().Create(inclusive); err != nil {
+		t.Fatal(err)
+	}
+	payment, items = purchase(inclusive, 2)
+	if payment.Amount != 1200 || payment.TaxSats != 190 || payment.TaxableSats != 1000 || !payment.TaxInclusive {
+		t.Errorf("Expected 1190 including 190 tax, plus the fee, got %+v", payment)
+	}
+	if len(items) != 2 || items[0].AmountSats != 1190 || items[1].Kind != models.LineItemFee {
+		t.Errorf("Expected ticket and fee line items, got %+v", items)
+	}
+}
+
+func TestHandleTaxSummary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	handler := NewTaxHandlers(store.Payments(), logger)
+
+	for i, tax := range []int64{190, 380} {
+		payment := &models.Payment{TicketID: i + 1, InvoiceID: "lnbc-" + strconv.Itoa(i), Amount: tax * 6, Status: "pending",
+			TaxableSats: tax * 5, TaxSats: tax, TaxBasisPoints: 2000, TaxJurisdiction: "FR TVA"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(query string) (int, json.RawMessage) {
+		rec := httptest.NewRecorder()
+		handler.HandleTaxSummary(rec, httptest.NewRequest("GET", "/api/admin/tax/summary"+query, nil))
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	for _, query := range []string{"", "?from=2026-10-01T00:00:00Z", "?from=2026-10-01&to=2026-11-01", "?from=2026-11-01T00:00:00Z&to=2026-10-01T00:00:00Z"} {
+		if status, _ := get(query); status != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, status)
+		}
+	}
+
+	status, data := get("?from=2026-10-01T00:00:00Z&to=2026-11-01T00:00:00Z")
+	var summary struct {
+		Rates  []models.TaxSummary `json:"rates"`
+		Totals struct {
+			Payments int   `json:"payments"`
+			TaxSats  int64 `json:"tax_sats"`
+		} `json:"totals"`
+	}
+	json.Unmarshal(data, &summary)
+	if status != http.StatusOK || len(summary.Rates) != 1 || summary.Rates[0].TaxableSats != 2850 ||
+		summary.Totals.Payments != 2 || summary.Totals.TaxSats != 570 {
+		t.Errorf("Unexpected tax summary %d %s", status, data)
+	}
+}
@@ -0,0 +1,230 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// TemplateHandlers manages the notification templates that replace the
+// built-in notification text, platform wide or for an organizer's events
+// (admin only)
+type TemplateHandlers struct {
+	templateRepo repositories.NotificationTemplateRepository
+	userRepo     repositories.UserRepository
+	templates    *services.TemplateService
+	logger       *slog.Logger
+}
+
+func NewTemplateHandlers(
+	templateRepo repositories.NotificationTemplateRepository,
+	userRepo repositories.UserRepository,
+	templates *services.TemplateService,
+	logger *slog.Logger,
+) *TemplateHandlers {
+	return &TemplateHandlers{
+		templateRepo: templateRepo,
+		userRepo:     userRepo,
+		templates:    templates,
+		logger:       logger,
+	}
+}
+
+// HandleListKinds lists the notification kinds with the fields their
+// templates can use and the sample values previews render with
+func (h *TemplateHandlers) HandleListKinds(w http.ResponseWriter, r *http.Request) {
+	kinds := []map[string]interface{}{}
+	for _, kind := range i18n.Keys() {
+		fields, _ := i18n.Fields(kind)
+		kinds = append(kinds, map[string]interface{}{
+			"kind":   kind,
+			"fields": fields,
+			"sample": i18n.Sample(kind).Data(),
+		})
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification kinds retrieved successfully",
+		Data:    kinds,
+	})
+}
+
+// HandleListTemplates lists the current version of every template
+func (h *TemplateHandlers) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.templateRepo.GetAllCurrent()
+	if err != nil {
+		h.logger.Error("Failed to fetch notification templates", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch notification templates")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+// HandleCreateTemplate saves a template as the next version of its
+// organizer, kind and locale, which takes effect immediately
+func (h *TemplateHandlers) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.NotificationTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	locale, ok := i18n.Normalize(req.Locale)
+	if !ok {
+		middleware.WriteError(w, http.StatusBadRequest, "locale is not supported")
+		return
+	}
+	template := &models.NotificationTemplate{
+		OrganizerID: req.OrganizerID,
+		Kind:        req.Kind,
+		Locale:      locale,
+		Subject:     req.Subject,
+		TextBody:    req.TextBody,
+		HTMLBody:    req.HTMLBody,
+	}
+	if err := services.ValidateTemplate(template); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if template.OrganizerID != nil {
+		_, err := h.userRepo.GetByID(*template.OrganizerID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			middleware.WriteError(w, http.StatusBadRequest, "organizer not found")
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to fetch organizer", "organizer_id", *template.OrganizerID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch organizer")
+			return
+		}
+	}
+	if admin := middleware.GetUserFromContext(r.Context()); admin != nil {
+		template.CreatedBy = &admin.ID
+	}
+
+	if err := h.templateRepo.Create(template); err != nil {
+		h.logger.Error("Failed to save notification template", "kind", template.Kind, "locale", template.Locale, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save notification template")
+		return
+	}
+
+	h.logger.Info("Notification template saved", "template_id", template.ID, "kind", template.Kind,
+		"locale", template.Locale, "version", template.Version)
+
+	middleware.WriteJSON(w, http.StatusCreated, models.SuccessResponse{
+		Message: "Notification template saved successfully",
+		Data:    template,
+	})
+}
+
+// HandleGetTemplate returns one version of a template
+func (h *TemplateHandlers) HandleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.template(w, r)
+	if !ok {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification template retrieved successfully",
+		Data:    template,
+	})
+}
+
+// HandleListVersions lists every version of a template's organizer, kind
+// and locale, newest first
+func (h *TemplateHandlers) HandleListVersions(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.template(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.templateRepo.GetVersions(template.OrganizerID, template.Kind, template.Locale)
+	if err != nil {
+		h.logger.Error("Failed to fetch notification template versions", "template_id", template.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch notification template versions")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification template versions retrieved successfully",
+		Data:    versions,
+	})
+}
+
+// HandleDeleteTemplate deletes a template version. Deleting the current
+// version rolls back to the one before it, and deleting the last one
+// restores the built-in text.
+func (h *TemplateHandlers) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.template(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.templateRepo.Delete(template.ID); err != nil {
+		h.logger.Error("Failed to delete notification template", "template_id", template.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete notification template")
+		return
+	}
+
+	h.logger.Info("Notification template deleted", "template_id", template.ID, "kind", template.Kind,
+		"locale", template.Locale, "version", template.Version)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification template deleted successfully",
+	})
+}
+
+// HandlePreviewTemplate renders a template version with sample data
+func (h *TemplateHandlers) HandlePreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.template(w, r)
+	if !ok {
+		return
+	}
+
+	rendered, err := h.templates.Preview(template)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Notification template rendered successfully",
+		Data:    rendered,
+	})
+}
+
+// template loads the template version named in the route, writing the
+// error response when it cannot
+func (h *TemplateHandlers) template(w http.ResponseWriter, r *http.Request) (*models.NotificationTemplate, bool) {
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid template ID")
+		return nil, false
+	}
+
+	template, err := h.templateRepo.GetByID(templateID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Notification template not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch notification template", "template_id", templateID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch notification template")
+		return nil, false
+	}
+	return template, true
+}
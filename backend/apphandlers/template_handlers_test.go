@@ -0,0 +1,100 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestNotificationTemplates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	templates := services.NewTemplateService(store.NotificationTemplates(), store.Events(), logger)
+	handlers := NewTemplateHandlers(store.NotificationTemplates(), store.Users(), templates, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/templates", handlers.HandleListTemplates).Methods("GET")
+	router.HandleFunc("/api/admin/templates", handlers.HandleCreateTemplate).Methods("POST")
+	router.HandleFunc("/api/admin/templates/{id:[0-9]+}", handlers.HandleDeleteTemplate).Methods("DELETE")
+	router.HandleFunc("/api/admin/templates/{id:[0-9]+}/versions", handlers.HandleListVersions).Methods("GET")
+	router.HandleFunc("/api/admin/templates/{id:[0-9]+}/preview", handlers.HandlePreviewTemplate).Methods("POST")
+
+	do := func(method, path string, body interface{}, out interface{}) int {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if out != nil {
+			json.Unmarshal(resp.Data, out)
+		}
+		return rec.Code
+	}
+
+	request := models.NotificationTemplateRequest{Kind: i18n.PurchaseConfirmed, Locale: "EN",
+		Subject: "You're in: {{.EventTitle}}", TextBody: "Code {{.TicketCode}}", HTMLBody: "<h1>{{.EventTitle}}</h1>"}
+	var first, second models.NotificationTemplate
+	if code := do("POST", "/api/admin/templates", request, &first); code != http.StatusCreated || first.Version != 1 || first.Locale != "en" {
+		t.Fatalf("Expected version 1 to be saved, got %d %+v", code, first)
+	}
+	request.Subject = "Confirmed: {{.EventTitle}}"
+	if code := do("POST", "/api/admin/templates", request, &second); code != http.StatusCreated || second.Version != 2 {
+		t.Fatalf("Expected version 2 to be saved, got %d %+v", code, second)
+	}
+
+	invalid := []models.NotificationTemplateRequest{
+		{Kind: i18n.PurchaseConfirmed, Locale: "fr", Subject: "x", TextBody: "x"},
+		{Kind: i18n.PurchaseConfirmed, Locale: "en", Subject: "x", TextBody: "{{.AmountSats}}"},
+		{Kind: i18n.PurchaseConfirmed, Locale: "en", Subject: "x", TextBody: "{{range 5}}x{{end}}"},
+	}
+	organizer := 999
+	invalid = append(invalid, models.NotificationTemplateRequest{OrganizerID: &organizer, Kind: i18n.PurchaseConfirmed, Locale: "en", Subject: "x", TextBody: "x"})
+	for _, req := range invalid {
+		if code := do("POST", "/api/admin/templates", req, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected %+v to be rejected, got %d", req, code)
+		}
+	}
+
+	var preview models.RenderedNotification
+	if code := do("POST", "/api/admin/templates/"+strconv.Itoa(second.ID)+"/preview", nil, &preview); code != http.StatusOK ||
+		preview.Subject != "Confirmed: Seoul Bitcoin Meetup" || preview.HTML != "<h1>Seoul Bitcoin Meetup</h1>" {
+		t.Errorf("Unexpected preview %d %+v", code, preview)
+	}
+
+	var versions []models.NotificationTemplate
+	if code := do("GET", "/api/admin/templates/"+strconv.Itoa(first.ID)+"/versions", nil, &versions); code != http.StatusOK ||
+		len(versions) != 2 || versions[0].ID != second.ID {
+		t.Errorf("Expected both versions newest first, got %d %+v", code, versions)
+	}
+
+	// Deleting the current version puts the first back in use
+	if code := do("DELETE", "/api/admin/templates/"+strconv.Itoa(second.ID), nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected the version to be deleted, got %d", code)
+	}
+	var current []models.NotificationTemplate
+	if code := do("GET", "/api/admin/templates", nil, &current); code != http.StatusOK || len(current) != 1 || current[0].ID != first.ID {
+		t.Errorf("Expected the first version to be current, got %d %+v", code, current)
+	}
+	if code := do("POST", "/api/admin/templates/"+strconv.Itoa(second.ID)+"/preview", nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 previewing a deleted version, got %d", code)
+	}
+}
@@ -2,18 +2,24 @@ package apphandlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
 	"tickets-by-uma/middleware"
 	"tickets-by-uma/models"
 	"tickets-by-uma/repositories"
 	"tickets-by-uma/services"
+	"tickets-by-uma/ticketcode"
 )
 
 type TicketHandlers struct {
@@ -22,9 +28,32 @@ type TicketHandlers struct {
 	paymentRepo repositories.PaymentRepository
 	umaRepo     repositories.UMARequestInvoiceRepository
 	nwcRepo     repositories.NWCConnectionRepository
+	addOnRepo   repositories.AddOnRepository
+	formRepo    repositories.FormFieldRepository
+	accessRepo  repositories.EventAccessRepository
+	attestRepo  repositories.AttestationRepository
+	receiptRepo repositories.ReceiptRepository
+	orderRepo   repositories.OrderRepository
 	umaService  services.UMAService
-	logger      *slog.Logger
-	domain      string
+	settings    *services.SettingsService
+	fraud       *services.FraudService
+	notifier    services.Notifier
+	fees        *services.FeeService
+	watcher     *services.TicketWatcher
+	guests      *services.GuestService
+	checkIns    *services.CheckInService
+	affiliates  *services.AffiliateService
+	gifts       *services.GiftService
+	// pricing applies events' pricing rules; without it tickets sell at
+	// the event price
+	pricing *services.PricingService
+	limits  config.PriceLimits
+	// legacyCodesUntil ends the window for validating pre-checksum ticket
+	// codes; zero keeps accepting them
+	legacyCodesUntil time.Time
+	clock            clock.Clock
+	logger           *slog.Logger
+	domain           string
 }
 
 func NewTicketHandlers(
@@ -33,24 +62,67 @@ func NewTicketHandlers(
 	paymentRepo repositories.PaymentRepository,
 	umaRepo repositories.UMARequestInvoiceRepository,
 	nwcRepo repositories.NWCConnectionRepository,
+	addOnRepo repositories.AddOnRepository,
+	formRepo repositories.FormFieldRepository,
+	accessRepo repositories.EventAccessRepository,
+	attestRepo repositories.AttestationRepository,
+	receiptRepo repositories.ReceiptRepository,
+	orderRepo repositories.OrderRepository,
 	umaService services.UMAService,
+	settings *services.SettingsService,
+	fraud *services.FraudService,
+	notifier services.Notifier,
+	fees *services.FeeService,
+	watcher *services.TicketWatcher,
+	guests *services.GuestService,
+	checkIns *services.CheckInService,
+	affiliates *services.AffiliateService,
+	gifts *services.GiftService,
+	pricing *services.PricingService,
+	limits config.PriceLimits,
+	legacyCodesUntil time.Time,
+	clk clock.Clock,
 	logger *slog.Logger,
 	domain string,
 ) *TicketHandlers {
 	return &TicketHandlers{
-		ticketRepo:  ticketRepo,
-		eventRepo:   eventRepo,
-		paymentRepo: paymentRepo,
-		umaRepo:     umaRepo,
-		nwcRepo:     nwcRepo,
-		umaService:  umaService,
-		logger:      logger,
-		domain:      domain,
+		ticketRepo:       ticketRepo,
+		eventRepo:        eventRepo,
+		paymentRepo:      paymentRepo,
+		umaRepo:          umaRepo,
+		nwcRepo:          nwcRepo,
+		addOnRepo:        addOnRepo,
+		formRepo:         formRepo,
+		accessRepo:       accessRepo,
+		attestRepo:       attestRepo,
+		receiptRepo:      receiptRepo,
+		orderRepo:        orderRepo,
+		umaService:       umaService,
+		settings:         settings,
+		fraud:            fraud,
+		notifier:         notifier,
+		fees:             fees,
+		watcher:          watcher,
+		guests:           guests,
+		checkIns:         checkIns,
+		affiliates:       affiliates,
+		gifts:            gifts,
+		pricing:          pricing,
+		limits:           limits,
+		legacyCodesUntil: legacyCodesUntil,
+		clock:            clk,
+		logger:           logger,
+		domain:           domain,
 	}
 }
 
 // HandlePurchaseTicket initiates a ticket purchase with UMA payment
 func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Request) {
+	if h.settings.Bool(services.SettingSalesPaused, false) {
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Ticket sales are temporarily paused")
+		return
+	}
+
 	var req models.TicketPurchaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
@@ -63,6 +135,13 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if h.settings.EventSalesPaused(req.EventID) {
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Ticket sales for this event are temporarily paused")
+		return
+	}
+
+	referral := purchaseReferral(r, req.Referral)
+
 	h.logger.Info("Processing ticket purchase",
 		"event_id", req.EventID,
 		"user_id", req.UserID,
@@ -70,19 +149,125 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 
 	// Check if event exists and is active
 	event, err := h.eventRepo.GetByID(req.EventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch event", "event_id", req.EventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
 		return
 	}
 
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+	if !event.IsActive {
+		middleware.WriteError(w, http.StatusBadRequest, "Event is not active")
 		return
 	}
 
-	if !event.IsActive {
-		middleware.WriteError(w, http.StatusBadRequest, "Event is not active")
+	expiry, err := purchaseExpiry(event, req.InvoiceExpirySeconds)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A gift is checked up front and scheduled with the ticket; its claim
+	// link goes out once the ticket is paid
+	var gift *models.TicketGift
+	if req.Gift != nil {
+		if h.gifts == nil {
+			middleware.WriteError(w, http.StatusBadRequest, "Gift purchases are not available")
+			return
+		}
+		if address := strings.TrimSpace(req.Gift.RecipientUMAAddress); address != "" {
+			if err := h.umaService.ValidateUMAAddress(address); err != nil {
+				middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid recipient UMA address: %v", err))
+				return
+			}
+		}
+		if gift, err = h.gifts.Prepare(*req.Gift, req.UserID, event); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Buyers without an account check out as a guest with their email; the
+	// ticket belongs to a guest user they can claim once it is paid
+	guest := req.UserID <= 0
+	if guest {
+		user, err := h.guests.Checkout(req.Email, middleware.LocaleOf(w))
+		if errors.Is(err, services.ErrAccountExists) {
+			middleware.WriteErrorCode(w, http.StatusConflict, models.ErrorCodeAccountExists, "An account with this email already exists; log in to buy tickets")
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to create guest user", "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create guest user")
+			return
+		}
+		req.UserID = user.ID
+	}
+
+	// Events selling one ticket per user refuse a second one. Gifts are
+	// bought for someone else and are not limited.
+	onePerUser := event.OneTicketPerUser && gift == nil
+	if onePerUser {
+		has, err := h.ticketRepo.HasActiveTicketForEvent(req.UserID, event.ID)
+		if err != nil {
+			h.logger.Error("Failed to check buyer's tickets", "user_id", req.UserID, "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to check your tickets")
+			return
+		}
+		if has {
+			writeAlreadyHasTicket(w)
+			return
+		}
+	}
+
+	// Orders placed with an affiliate's code earn them commission at their
+	// current rate
+	order := &models.Order{Referral: referral}
+	if h.affiliates != nil {
+		affiliate, err := h.affiliates.Attribute(referral.Ref, req.UserID)
+		if err != nil {
+			h.logger.Error("Failed to attribute order to an affiliate", "ref", referral.Ref, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create ticket")
+			return
+		}
+		if affiliate != nil {
+			order.AffiliateID = &affiliate.ID
+			order.CommissionBasisPoints = affiliate.BasisPoints
+		}
+	}
+
+	// Private events sell to allowlisted buyers or with an access code. The
+	// code is only redeemed once the rest of the purchase checks out.
+	var accessCode string
+	if event.IsPrivate {
+		// A guest's email is unverified, so only their UMA address counts
+		allowlistUserID := req.UserID
+		if guest {
+			allowlistUserID = 0
+		}
+		allowed, err := h.accessRepo.IsAllowlisted(event.ID, allowlistUserID, req.UMAAddress)
+		if err != nil {
+			h.logger.Error("Failed to check event allowlist", "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to check event access")
+			return
+		}
+		if !allowed {
+			accessCode = normalizeAccessCode(req.AccessCode)
+			if accessCode == "" {
+				middleware.WriteError(w, http.StatusForbidden, "This event is invitation only; an access code is required")
+				return
+			}
+		}
+	}
+
+	// Age restricted events and events with terms need the buyer's
+	// attestation, which is stored with the ticket
+	attestation, err := attestPurchase(event, &req, r)
+	if err != nil {
+		middleware.WriteErrorCode(w, http.StatusBadRequest, models.ErrorCodeAttestationRequired, err.Error())
 		return
 	}
 
@@ -99,18 +284,154 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Score the attempt; flagged purchases are recorded and, with the review
+	// action, held without an invoice until an admin approves them
+	var fraudCheck services.FraudCheck
+	var assessment services.FraudAssessment
+	if h.fraud != nil {
+		fraudCheck = services.FraudCheck{UserID: req.UserID, EventID: req.EventID, UMAAddress: req.UMAAddress}
+		assessment = h.fraud.Evaluate(r, fraudCheck)
+		if assessment.Action == services.FraudActionBlock {
+			if err := h.fraud.Record(r, fraudCheck, assessment, nil); err != nil {
+				h.logger.Error("Failed to record fraud flag", "user_id", req.UserID, "error", err)
+			}
+			middleware.WriteError(w, http.StatusForbidden, "Purchase blocked by fraud checks")
+			return
+		}
+	}
+
 	// Generate unique ticket code
-	ticketCode, err := middleware.GenerateTicketCode()
+	ticketCode, err := ticketcode.New(event.ID)
 	if err != nil {
 		h.logger.Error("Failed to generate ticket code", "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate ticket code")
 		return
 	}
 
+	price, err := ticketPrice(event, req.AmountSats)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Fixed prices follow the event's pricing rules; the ticket keeps the
+	// price it was sold at for its receipt
+	var priceChangeID *int
+	if h.pricing != nil && !event.PayWhatYouWant() {
+		change, err := h.pricing.Record(event)
+		if err != nil {
+			h.logger.Error("Failed to price ticket", "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to price ticket")
+			return
+		}
+		price, priceChangeID = change.PriceSats, &change.ID
+	}
+
+	// Add-ons are billed on the ticket's invoice
+	var lineItems []models.TicketAddOn
+	var addOnTotal int64
+	if len(req.AddOns) > 0 {
+		catalog, err := h.addOnRepo.GetByEventID(event.ID, true)
+		if err != nil {
+			h.logger.Error("Failed to fetch add-ons", "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch add-ons")
+			return
+		}
+		lineItems, addOnTotal, err = selectAddOns(catalog, req.AddOns, event, h.clock.Now())
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	total := price + addOnTotal
+
+	// Answers to the event's registration form are stored with the ticket
+	var answers []models.TicketAnswer
+	if h.formRepo != nil {
+		fields, err := h.formRepo.GetByEventID(event.ID)
+		if err != nil {
+			h.logger.Error("Failed to fetch form fields", "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch form fields")
+			return
+		}
+		answers, err = answerForm(fields, req.Answers)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Tax charged on top and the platform fee are added when the ticket is
+	// invoiced
+	var charges invoiceCharges
+	if total > 0 {
+		if err := h.umaService.ValidateUMAAddress(req.UMAAddress); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid UMA address: %v", err))
+			return
+		}
+		charges, err = h.charges(event, total)
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := h.limits.CheckInvoice(charges.total()); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	var ticket *models.Ticket
+	if accessCode != "" {
+		err := h.accessRepo.RedeemCode(event.ID, accessCode)
+		if errors.Is(err, repositories.ErrNotFound) {
+			middleware.WriteError(w, http.StatusForbidden, "Invalid access code")
+			return
+		}
+		if errors.Is(err, repositories.ErrConflict) {
+			middleware.WriteError(w, http.StatusForbidden, "Access code has expired or has no uses left")
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to redeem access code", "event_id", event.ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to check event access")
+			return
+		}
+		// A purchase that stores no ticket gives the use back
+		defer func() {
+			if ticket != nil && ticket.ID != 0 {
+				return
+			}
+			if err := h.accessRepo.ReleaseCode(event.ID, accessCode); err != nil {
+				h.logger.Error("Failed to release access code", "event_id", event.ID, "error", err)
+			}
+		}()
+	}
+
 	var ticketInvoice *models.UMARequestInvoice
+	held := assessment.Action == services.FraudActionReview
 
-	if event.PriceSats == 0 {
+	if held {
+		ticket = &models.Ticket{
+			EventID:       req.EventID,
+			UserID:        req.UserID,
+			TicketCode:    ticketCode,
+			PaymentStatus: "review",
+			UMAAddress:    req.UMAAddress,
+			AmountSats:    total,
+			PriceChangeID: priceChangeID,
+			OnePerUser:    onePerUser,
+			NeedsSeat:     true,
+		}
+
+		if err := h.createTicket(ticket, order, gift, lineItems, answers, attestation); err != nil {
+			if h.ticketConflict(w, ticket, err) {
+				return
+			}
+			h.logger.Error("Failed to create held ticket", "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create ticket")
+			return
+		}
+	} else if total == 0 {
 		// Free event - create ticket with 'paid' payment status (since it's free)
 		h.logger.Info("Creating free ticket for free event",
 			"event_id", req.EventID,
@@ -123,19 +444,22 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 			PaymentStatus: "paid",
 			InvoiceID:     "",
 			UMAAddress:    req.UMAAddress,
+			AmountSats:    total,
+			PriceChangeID: priceChangeID,
+			OnePerUser:    onePerUser,
+			NeedsSeat:     true,
 		}
 
-		if err := h.ticketRepo.Create(ticket); err != nil {
+		if err := h.createTicket(ticket, order, gift, lineItems, answers, attestation); err != nil {
+			if h.ticketConflict(w, ticket, err) {
+				return
+			}
 			h.logger.Error("Failed to create free ticket", "error", err)
 			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create ticket")
 			return
 		}
 	} else {
 		// Paid event - create per-ticket invoice
-		if err := h.umaService.ValidateUMAAddress(req.UMAAddress); err != nil {
-			middleware.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid UMA address: %v", err))
-			return
-		}
 
 		// 1. Create ticket with pending payment (no invoice yet)
 		ticket = &models.Ticket{
@@ -144,71 +468,36 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 			TicketCode:    ticketCode,
 			PaymentStatus: "pending",
 			UMAAddress:    req.UMAAddress,
+			AmountSats:    total,
+			PriceChangeID: priceChangeID,
+			OnePerUser:    onePerUser,
+			NeedsSeat:     true,
 		}
 
-		if err := h.ticketRepo.Create(ticket); err != nil {
+		if err := h.createTicket(ticket, order, gift, lineItems, answers, attestation); err != nil {
+			if h.ticketConflict(w, ticket, err) {
+				return
+			}
 			h.logger.Error("Failed to create ticket", "error", err)
 			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create ticket")
 			return
 		}
 
-		// 2. Create a new invoice for this ticket (using buyer's UMA address)
-		description := fmt.Sprintf("Ticket #%d for %s", ticket.ID, event.Title)
-
-		invoice, err := h.umaService.CreateTicketInvoice(req.UMAAddress, event.PriceSats, description)
-		if err != nil {
-			h.logger.Error("Failed to create ticket invoice", "ticket_id", ticket.ID, "error", err)
-			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create payment invoice")
+		// 2. Invoice the buyer and start paying it
+		ticketInvoice, err = h.issueTicketInvoice(ticket, event, expiry)
+		if paymentBackendUnavailable(w, err) {
 			return
 		}
-
-		// 3. Store the invoice in uma_request_invoices with ticket_id
-		ticketInvoice = &models.UMARequestInvoice{
-			EventID:     &req.EventID,
-			TicketID:    &ticket.ID,
-			InvoiceID:   invoice.ID,
-			PaymentHash: invoice.PaymentHash,
-			Bolt11:      invoice.Bolt11,
-			AmountSats:  invoice.AmountSats,
-			Status:      invoice.Status,
-			UMAAddress:  req.UMAAddress,
-			Description: description,
-			ExpiresAt:   invoice.ExpiresAt,
-		}
-
-		if err := h.umaRepo.Create(ticketInvoice); err != nil {
-			h.logger.Error("Failed to save ticket invoice", "ticket_id", ticket.ID, "error", err)
-			middleware.WriteError(w, http.StatusInternalServerError, "Failed to save payment invoice")
-			return
-		}
-
-		// 4. Create payment record with the new bolt11
-		payment := &models.Payment{
-			TicketID:  ticket.ID,
-			InvoiceID: invoice.Bolt11,
-			Amount:    invoice.AmountSats,
-			Status:    "pending",
-		}
-
-		if err := h.paymentRepo.Create(payment); err != nil {
-			h.logger.Error("Failed to create payment record", "error", err)
-			middleware.WriteError(w, http.StatusInternalServerError, "Failed to create payment record")
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	}
 
-		// 5. Update ticket's invoice_id
-		ticket.InvoiceID = invoice.ID
-		if err := h.ticketRepo.Update(ticket); err != nil {
-			h.logger.Error("Failed to update ticket invoice_id", "ticket_id", ticket.ID, "error", err)
+	if h.fraud != nil {
+		if err := h.fraud.Record(r, fraudCheck, assessment, &ticket.ID); err != nil {
+			h.logger.Error("Failed to record fraud flag", "ticket_id", ticket.ID, "error", err)
 		}
-
-		h.logger.Info("Ticket created with per-ticket invoice",
-			"ticket_id", ticket.ID,
-			"invoice_id", invoice.ID,
-			"uma_address", req.UMAAddress)
-
-		// Pay the invoice asynchronously: try NWC first, then fall back to UMA Request
-		go h.processPayment(req.UserID, ticket.ID, payment.ID, invoice.Bolt11)
 	}
 
 	// Return ticket and event information
@@ -222,10 +511,22 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 			"title":      event.Title,
 			"price_sats": event.PriceSats,
 		},
+		"amount_sats": total,
+		"tax_sats":    charges.tax,
+		"fee_sats":    charges.fee,
+	}
+	if len(lineItems) > 0 {
+		response["addons"] = lineItems
+	}
+	if len(answers) > 0 {
+		response["answers"] = answers
+	}
+	if gift != nil {
+		response["gift"] = gift
 	}
 
 	// Add per-ticket invoice information for paid events
-	if event.PriceSats > 0 && ticketInvoice != nil {
+	if ticketInvoice != nil {
 		response["uma_request"] = map[string]interface{}{
 			"invoice_id":   ticketInvoice.InvoiceID,
 			"bolt11":       ticketInvoice.Bolt11,
@@ -239,8 +540,16 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 		response["payment_required"] = ticket.PaymentStatus != "paid"
 	}
 
+	if held {
+		middleware.WriteJSON(w, http.StatusAccepted, models.SuccessResponse{
+			Message: "Ticket purchase held for review",
+			Data:    response,
+		})
+		return
+	}
+
 	var message string
-	if event.PriceSats == 0 {
+	if total == 0 {
 		message = "Free ticket created successfully"
 	} else if ticket.PaymentStatus == "paid" {
 		message = "Ticket purchased and paid successfully"
@@ -254,7 +563,9 @@ func (h *TicketHandlers) HandlePurchaseTicket(w http.ResponseWriter, r *http.Req
 	})
 }
 
-// HandleTicketStatus checks the payment status of a ticket
+// HandleTicketStatus checks the payment status of a ticket. With
+// ?wait=25s it long-polls: while the ticket is still awaiting payment or
+// review the response is held until it changes or the wait runs out.
 func (h *TicketHandlers) HandleTicketStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ticketID, err := strconv.Atoi(vars["id"])
@@ -263,22 +574,59 @@ func (h *TicketHandlers) HandleTicketStatus(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	var wait time.Duration
+	if waitStr := r.URL.Query().Get("wait"); waitStr != "" {
+		wait, err = time.ParseDuration(waitStr)
+		if err != nil || wait < 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "wait must be a duration such as 25s")
+			return
+		}
+		if wait > maxStatusWait {
+			wait = maxStatusWait
+		}
+	}
+
 	h.logger.Info("Checking ticket status", "ticket_id", ticketID)
 
+	// Subscribe before reading so a change in between still wakes us
+	var changed <-chan struct{}
+	if wait > 0 && h.watcher != nil {
+		ch, stop := h.watcher.Subscribe(ticketID)
+		defer stop()
+		changed = ch
+	}
+
 	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err == nil && changed != nil && awaitsChange(ticket.PaymentStatus) {
+		// The server's write timeout is shorter than the longest wait
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + statusWriteMargin))
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-changed:
+			ticket, err = h.ticketRepo.GetByID(ticketID)
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
 		return
 	}
 
-	if ticket == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
-		return
-	}
-
 	// Get event information
 	event, err := h.eventRepo.GetByID(ticket.EventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch event", "event_id", ticket.EventID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
@@ -299,22 +647,36 @@ func (h *TicketHandlers) HandleTicketStatus(w http.ResponseWriter, r *http.Reque
 		},
 	}
 
+	if items := h.lineItems(ticket.ID); len(items) > 0 {
+		statusResponse["addons"] = items
+	}
+
 	// Only fetch payment information for tickets that actually have payments
 	if ticket.PaymentStatus == "pending" || ticket.PaymentStatus == "paid" {
 		// For free tickets (price 0), we don't need to fetch payment records
 		// For paid tickets, fetch the payment record
-		if event.PriceSats > 0 {
+		if ticketAmount(ticket, event) > 0 {
 			payment, err := h.paymentRepo.GetByTicketID(ticketID)
-			if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrNotFound):
+				// The payment record is created after the ticket, so it
+				// may not exist yet; report the ticket's own status
+				statusResponse["payment"] = map[string]interface{}{
+					"status":      ticket.PaymentStatus,
+					"amount_sats": ticketAmount(ticket, event),
+					"invoice_id":  nil,
+				}
+			case err != nil:
 				h.logger.Error("Failed to fetch payment", "ticket_id", ticketID, "error", err)
 				middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payment")
 				return
-			}
-
-			statusResponse["payment"] = map[string]interface{}{
-				"status":      payment.Status,
-				"amount_sats": payment.Amount,
-				"invoice_id":  payment.InvoiceID,
+			default:
+				statusResponse["payment"] = map[string]interface{}{
+					"status":      payment.Status,
+					"amount_sats": payment.Amount,
+					"invoice_id":  payment.InvoiceID,
+					"expires_at":  payment.ExpiresAt,
+				}
 			}
 		} else {
 			// Free ticket - no payment record needed
@@ -333,12 +695,314 @@ func (h *TicketHandlers) HandleTicketStatus(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	// Buyers whose invoice expired are offered a fresh one
+	if ticket.PaymentStatus == "pending" || ticket.PaymentStatus == "expired" {
+		if _, refusal, err := h.expiredPayment(ticket, event); err == nil && refusal == "" {
+			statusResponse["reinvoice"] = map[string]interface{}{
+				"method": "POST",
+				"url":    fmt.Sprintf("/api/tickets/%d/reinvoice", ticket.ID),
+			}
+		}
+	}
+
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 		Message: "Ticket status retrieved successfully",
 		Data:    statusResponse,
 	})
 }
 
+const (
+	// maxStatusWait caps how long a status long-poll is held
+	maxStatusWait = 30 * time.Second
+	// statusWriteMargin is the time left to write a long-poll response
+	statusWriteMargin = 10 * time.Second
+)
+
+// awaitsChange reports whether a ticket in this status is waiting on a
+// payment, review or dispute, so a long-poll should hold for its change
+func awaitsChange(status string) bool {
+	return status == "pending" || status == "review" || status == models.TicketDisputed
+}
+
+// HandleReinvoiceTicket issues a fresh invoice for the caller's ticket whose
+// invoice expired unpaid, so they can still pay for it without starting
+// over. The new invoice supersedes the old one on the ticket's payment and
+// is only issued while the event has seats left. It is not paid from the
+// buyer's connected wallet: the amount may have changed since they agreed
+// to it, so they pay the returned invoice themselves.
+func (h *TicketHandlers) HandleReinvoiceTicket(w http.ResponseWriter, r *http.Request) {
+	if h.settings.Bool(services.SettingSalesPaused, false) {
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Ticket sales are temporarily paused")
+		return
+	}
+
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	event, err := h.eventRepo.GetByID(ticket.EventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event", "event_id", ticket.EventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+		return
+	}
+
+	if h.settings.EventSalesPaused(event.ID) {
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Ticket sales for this event are temporarily paused")
+		return
+	}
+
+	payment, refusal, err := h.expiredPayment(ticket, event)
+	if err != nil {
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check ticket invoice")
+		return
+	}
+	if refusal != "" {
+		middleware.WriteError(w, http.StatusConflict, refusal)
+		return
+	}
+
+	// The buyer pays what they agreed to at purchase, unless the ticket was
+	// repriced by a price change after its invoice lapsed
+	charges, repriced, err := h.repricedCharges(ticket, event, payment)
+	if err != nil {
+		middleware.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	amount := payment.Amount
+	if repriced {
+		amount = charges.total()
+	}
+	description := fmt.Sprintf("Ticket #%d for %s", ticket.ID, event.Title)
+	invoice, err := h.umaService.CreateTicketInvoice(ticket.UMAAddress, amount, description, event.InvoiceExpiry())
+	if paymentBackendUnavailable(w, err) {
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create ticket invoice", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create payment invoice")
+		return
+	}
+
+	// Supersede the expired invoice
+	ticketInvoice, err := h.umaRepo.GetByTicketID(ticket.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		ticketInvoice = &models.UMARequestInvoice{EventID: &event.ID, TicketID: &ticket.ID, Source: models.UMAInvoiceTicket}
+	} else if err != nil {
+		h.logger.Error("Failed to fetch ticket invoice", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save payment invoice")
+		return
+	}
+	ticketInvoice.InvoiceID = invoice.ID
+	ticketInvoice.PaymentHash = invoice.PaymentHash
+	ticketInvoice.Bolt11 = invoice.Bolt11
+	ticketInvoice.AmountSats = invoice.AmountSats
+	ticketInvoice.Status = invoice.Status
+	ticketInvoice.UMAAddress = ticket.UMAAddress
+	ticketInvoice.Description = description
+	ticketInvoice.ExpiresAt = invoice.ExpiresAt
+	if ticketInvoice.ID == 0 {
+		err = h.umaRepo.Create(ticketInvoice)
+	} else {
+		err = h.umaRepo.Update(ticketInvoice)
+	}
+	if err != nil {
+		h.logger.Error("Failed to save ticket invoice", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save payment invoice")
+		return
+	}
+
+	payment.InvoiceID = invoice.ID
+	payment.Bolt11 = invoice.Bolt11
+	payment.PaymentHash = invoice.PaymentHash
+	payment.Status = "pending"
+	payment.PaidAt = nil
+	payment.ExpiresAt = invoice.ExpiresAt
+	if repriced {
+		payment.Amount = invoice.AmountSats
+		payment.TaxableSats, payment.TaxSats = charges.taxable, charges.tax
+		payment.TaxBasisPoints, payment.TaxInclusive, payment.TaxJurisdiction = event.TaxBasisPoints, event.TaxInclusive, event.TaxJurisdiction
+	}
+	if err := h.paymentRepo.Update(payment); err != nil {
+		h.logger.Error("Failed to update payment", "payment_id", payment.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update payment")
+		return
+	}
+	if repriced && h.receiptRepo != nil {
+		if err := h.receiptRepo.ReplaceLineItems(payment.ID, h.paymentLineItems(ticket, event, payment, charges)); err != nil {
+			h.logger.Error("Failed to store payment line items", "payment_id", payment.ID, "error", err)
+		}
+	}
+
+	ticket.InvoiceID = invoice.ID
+	ticket.PaymentStatus = "pending"
+	if err := h.ticketRepo.Update(ticket); err != nil {
+		h.logger.Error("Failed to update ticket", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update ticket")
+		return
+	}
+
+	h.logger.Info("Ticket re-invoiced",
+		"ticket_id", ticket.ID,
+		"payment_id", payment.ID,
+		"invoice_id", invoice.ID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket re-invoiced successfully",
+		Data: map[string]interface{}{
+			"ticket": map[string]interface{}{
+				"id":             ticket.ID,
+				"payment_status": ticket.PaymentStatus,
+			},
+			"uma_request": map[string]interface{}{
+				"invoice_id":   ticketInvoice.InvoiceID,
+				"bolt11":       ticketInvoice.Bolt11,
+				"amount_sats":  ticketInvoice.AmountSats,
+				"payment_hash": ticketInvoice.PaymentHash,
+				"uma_address":  ticketInvoice.UMAAddress,
+				"description":  ticketInvoice.Description,
+				"expires_at":   ticketInvoice.ExpiresAt,
+				"status":       ticket.PaymentStatus,
+			},
+			"payment_required": true,
+		},
+	})
+}
+
+// repricedCharges returns the charges for a ticket whose subtotal no
+// longer matches its payment's, as when a price change repriced it after
+// the invoice lapsed, and reports false otherwise. Payments from before
+// tax was recorded are taken to match. The returned error is safe to show
+// to the client.
+func (h *TicketHandlers) repricedCharges(ticket *models.Ticket, event *models.Event, payment *models.Payment) (invoiceCharges, bool, error) {
+	subtotal := payment.TaxableSats
+	if payment.TaxInclusive {
+		subtotal += payment.TaxSats
+	}
+	if subtotal == 0 || subtotal == ticketAmount(ticket, event) {
+		return invoiceCharges{}, false, nil
+	}
+	charges, err := h.charges(event, ticketAmount(ticket, event))
+	return charges, err == nil, err
+}
+
+// expiredPayment returns the payment of a ticket that can be re-invoiced:
+// one still awaiting payment whose invoice has expired, on an active event
+// with seats left. Otherwise it returns the reason the ticket cannot be,
+// safe to show to the client, or an error that has been logged.
+func (h *TicketHandlers) expiredPayment(ticket *models.Ticket, event *models.Event) (*models.Payment, string, error) {
+	if ticket.PaymentStatus != "pending" && ticket.PaymentStatus != "expired" {
+		return nil, "Only tickets awaiting payment can be re-invoiced", nil
+	}
+	if !event.IsActive {
+		return nil, "Event is not active", nil
+	}
+
+	payment, err := h.paymentRepo.GetByTicketID(ticket.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, "Ticket has no invoice to replace", nil
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch payment", "ticket_id", ticket.ID, "error", err)
+		return nil, "", err
+	}
+	expired := payment.Status == "expired" ||
+		(payment.Status == "pending" && payment.ExpiresAt != nil && !h.clock.Now().Before(*payment.ExpiresAt))
+	if !expired {
+		return nil, "The ticket's invoice has not expired", nil
+	}
+
+	// The buyer may have bought another ticket since this one expired
+	if ticket.PaymentStatus == "expired" && event.OneTicketPerUser {
+		has, err := h.ticketRepo.HasActiveTicketForEvent(ticket.UserID, event.ID)
+		if err != nil {
+			h.logger.Error("Failed to check buyer's tickets", "user_id", ticket.UserID, "event_id", event.ID, "error", err)
+			return nil, "", err
+		}
+		if has {
+			return nil, "You already have a ticket for this event", nil
+		}
+	}
+
+	// An expired ticket gave up its seat, so it needs one again; a pending
+	// one still holds its own
+	if ticket.PaymentStatus == "expired" {
+		available, err := h.eventRepo.GetAvailableTicketCount(event.ID)
+		if err != nil {
+			h.logger.Error("Failed to check event capacity", "event_id", event.ID, "error", err)
+			return nil, "", err
+		}
+		if available <= 0 {
+			return nil, "Event is sold out", nil
+		}
+	}
+	return payment, "", nil
+}
+
+// HandleGetPaymentProof returns the proof that the caller paid for one of
+// their tickets: the invoice, its payment hash and the preimage that
+// hashes to it, which only the payer can have learnt from the network.
+func (h *TicketHandlers) HandleGetPaymentProof(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	payment, err := h.paymentRepo.GetByTicketID(ticket.ID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch payment", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch payment")
+		return
+	}
+	if payment == nil || payment.Preimage == nil {
+		middleware.WriteError(w, http.StatusNotFound, "No payment proof is available for this ticket")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Payment proof retrieved successfully",
+		Data: models.PaymentProof{
+			TicketID:    ticket.ID,
+			PaymentID:   payment.ID,
+			Bolt11:      payment.Bolt11,
+			PaymentHash: payment.PaymentHash,
+			Preimage:    *payment.Preimage,
+			AmountSats:  payment.Amount,
+			PaidAt:      payment.PaidAt,
+			Verified:    services.PreimageMatches(*payment.Preimage, payment.PaymentHash),
+		},
+	})
+}
+
 // HandleValidateTicket validates a ticket for event access
 func (h *TicketHandlers) HandleValidateTicket(w http.ResponseWriter, r *http.Request) {
 	var req models.TicketValidationRequest
@@ -352,59 +1016,93 @@ func (h *TicketHandlers) HandleValidateTicket(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !scannerEvent(w, r, &req.EventID) {
+		return
+	}
 	if req.EventID <= 0 {
 		middleware.WriteError(w, http.StatusBadRequest, "Valid event ID is required")
 		return
 	}
 
-	h.logger.Info("Validating ticket", "ticket_code", req.TicketCode, "event_id", req.EventID)
+	// Reject mistyped codes and codes for other events without a lookup
+	code, err := ticketcode.Parse(req.TicketCode)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Malformed ticket code")
+		return
+	}
+	if code.Legacy && !h.legacyCodesUntil.IsZero() && !h.clock.Now().Before(h.legacyCodesUntil) {
+		middleware.WriteError(w, http.StatusBadRequest, "Legacy ticket codes are no longer accepted")
+		return
+	}
+	if !code.Legacy && code.EventID != req.EventID {
+		middleware.WriteError(w, http.StatusBadRequest, "Ticket is not valid for this event")
+		return
+	}
+
+	h.logger.Info("Validating ticket", "ticket_code", code.Value, "event_id", req.EventID, "legacy_code", code.Legacy)
 
 	// Get ticket by code
-	ticket, err := h.ticketRepo.GetByTicketCode(req.TicketCode)
+	ticket, err := h.ticketRepo.GetByTicketCode(code.Value)
+	if errors.Is(err, repositories.ErrNotFound) {
+		recordScan(r, h.checkIns, req.EventID, nil, code.Value, "Ticket not found")
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch ticket", "ticket_code", req.TicketCode, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
 		return
 	}
 
-	if ticket == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
-		return
+	admitTicket(w, r, ticket, req.EventID, h.eventRepo, h.checkIns, h.clock, h.logger)
+}
+
+// admitTicket checks that a ticket grants entry to an event right now and
+// writes the validation response, recording the scan for the check-in
+// dashboard. Code and wallet check-ins share it.
+func admitTicket(w http.ResponseWriter, r *http.Request, ticket *models.Ticket, eventID int, eventRepo repositories.EventRepository, checkIns *services.CheckInService, clk clock.Clock, logger *slog.Logger) {
+	reject := func(status int, message string) {
+		recordScan(r, checkIns, eventID, ticket, ticket.TicketCode, message)
+		middleware.WriteError(w, status, message)
 	}
 
 	// Check if ticket is for the correct event
-	if ticket.EventID != req.EventID {
-		middleware.WriteError(w, http.StatusBadRequest, "Ticket is not valid for this event")
+	if ticket.EventID != eventID {
+		reject(http.StatusBadRequest, "Ticket is not valid for this event")
 		return
 	}
 
 	// Check if ticket is paid
+	if ticket.PaymentStatus == models.TicketDisputed {
+		reject(http.StatusBadRequest, "Ticket is suspended while its payment is disputed")
+		return
+	}
 	if ticket.PaymentStatus != "paid" {
-		middleware.WriteError(w, http.StatusBadRequest, "Ticket payment is not complete")
+		reject(http.StatusBadRequest, "Ticket payment is not complete")
 		return
 	}
 
 	// Get event information
-	event, err := h.eventRepo.GetByID(req.EventID)
-	if err != nil {
-		h.logger.Error("Failed to fetch event", "event_id", req.EventID, "error", err)
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
+	event, err := eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Event not found")
 		return
 	}
-
-	if event == nil {
-		middleware.WriteError(w, http.StatusNotFound, "Event not found")
+	if err != nil {
+		logger.Error("Failed to fetch event", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch event")
 		return
 	}
 
 	// Check if event is currently active
-	now := time.Now()
+	now := clk.Now()
 	if now.Before(event.StartTime) || now.After(event.EndTime) {
-		middleware.WriteError(w, http.StatusBadRequest, "Event is not currently active")
+		reject(http.StatusBadRequest, "Event is not currently active")
 		return
 	}
 
-	h.logger.Info("Ticket validated successfully", "ticket_code", req.TicketCode)
+	logger.Info("Ticket validated successfully", "ticket_code", ticket.TicketCode)
+	recordScan(r, checkIns, eventID, ticket, ticket.TicketCode, "")
 
 	validationResponse := map[string]interface{}{
 		"valid": true,
@@ -417,7 +1115,7 @@ func (h *TicketHandlers) HandleValidateTicket(w http.ResponseWriter, r *http.Req
 			"title":      event.Title,
 			"stream_url": event.StreamURL,
 		},
-		"validated_at": time.Now(),
+		"validated_at": now,
 	}
 
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
@@ -426,7 +1124,25 @@ func (h *TicketHandlers) HandleValidateTicket(w http.ResponseWriter, r *http.Req
 	})
 }
 
-// HandleGetUserTickets gets all tickets for a specific user
+// recordScan records a check-in attempt for the door dashboard, naming the
+// scanner that made r if any. ticket is nil when the code matched none; an
+// empty reason admits.
+func recordScan(r *http.Request, checkIns *services.CheckInService, eventID int, ticket *models.Ticket, code, reason string) {
+	if checkIns == nil {
+		return
+	}
+	scan := &models.TicketScan{EventID: eventID, TicketCode: code, Admitted: reason == "", Reason: reason}
+	if ticket != nil {
+		scan.TicketID = &ticket.ID
+	}
+	if device := middleware.GetScannerFromContext(r.Context()); device != nil {
+		scan.ScannerDeviceID = &device.ID
+	}
+	checkIns.Record(scan)
+}
+
+// HandleGetUserTickets gets a user's tickets, sorted and filtered on
+// repositories.TicketListFields
 func (h *TicketHandlers) HandleGetUserTickets(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID, err := strconv.Atoi(vars["user_id"])
@@ -437,26 +1153,35 @@ func (h *TicketHandlers) HandleGetUserTickets(w http.ResponseWriter, r *http.Req
 
 	h.logger.Info("Fetching tickets for user", "user_id", userID)
 
-	tickets, err := h.ticketRepo.GetByUserID(userID)
+	list, ok := parseListQuery(w, r, repositories.TicketListFields)
+	if !ok {
+		return
+	}
+	tickets, err := h.ticketRepo.ListByUser(userID, list)
 	if err != nil {
 		h.logger.Error("Failed to fetch user tickets", "user_id", userID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch tickets")
 		return
 	}
 
-	// Enrich tickets with event information
+	// Enrich tickets with event information, loaded in one query
+	events, err := repositories.Preload(tickets, func(t models.Ticket) int { return t.EventID }, h.eventRepo.GetByIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch events for tickets", "user_id", userID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch tickets")
+		return
+	}
 	enrichedTickets := make([]map[string]interface{}, 0, len(tickets))
 	for _, ticket := range tickets {
-		// Get event information for each ticket
-		event, err := h.eventRepo.GetByID(ticket.EventID)
-		if err != nil {
-			h.logger.Error("Failed to fetch event for ticket", "ticket_id", ticket.ID, "event_id", ticket.EventID, "error", err)
+		event, ok := events[ticket.EventID]
+		if !ok {
+			h.logger.Error("Event for ticket not found", "ticket_id", ticket.ID, "event_id", ticket.EventID)
 			continue
 		}
 
 		// Get payment information for each ticket
 		payment, err := h.paymentRepo.GetByTicketID(ticket.ID)
-		if err != nil {
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
 			h.logger.Error("Failed to fetch payment for ticket", "ticket_id", ticket.ID, "error", err)
 		}
 
@@ -477,6 +1202,10 @@ func (h *TicketHandlers) HandleGetUserTickets(w http.ResponseWriter, r *http.Req
 			},
 		}
 
+		if items := h.lineItems(ticket.ID); len(items) > 0 {
+			enrichedTicket["addons"] = items
+		}
+
 		// Add payment information if available
 		if payment != nil {
 			enrichedTicket["payment"] = map[string]interface{}{
@@ -518,14 +1247,16 @@ func (h *TicketHandlers) HandleUMAPaymentCallback(w http.ResponseWriter, r *http
 
 	// Update ticket status based on payment status
 	if req.Status == "paid" {
-		// Find ticket by invoice ID and update status
-		ticket, err := h.ticketRepo.GetByInvoiceID(req.InvoiceID)
+		// Find the payment by payment hash, then its ticket
+		payment, err := h.paymentRepo.GetByPaymentHash(req.PaymentHash)
 		if err != nil {
-			h.logger.Error("Failed to find ticket for invoice", "invoice_id", req.InvoiceID, "error", err)
-		} else if ticket != nil {
+			h.logger.Error("Failed to find payment for payment hash", "payment_hash", req.PaymentHash, "error", err)
+		} else if ticket, err := h.ticketRepo.GetByID(payment.TicketID); err != nil {
+			h.logger.Error("Failed to find ticket for payment", "payment_id", payment.ID, "error", err)
+		} else {
 			// Update ticket status to paid
 			ticket.PaymentStatus = "paid"
-			now := time.Now()
+			now := h.clock.Now()
 			ticket.PaidAt = &now
 
 			if err := h.ticketRepo.Update(ticket); err != nil {
@@ -533,12 +1264,9 @@ func (h *TicketHandlers) HandleUMAPaymentCallback(w http.ResponseWriter, r *http
 			}
 
 			// Update payment record
-			payment, err := h.paymentRepo.GetByInvoiceID(req.InvoiceID)
-			if err == nil && payment != nil {
-				payment.Status = "paid"
-				payment.PaidAt = &now
-				h.paymentRepo.Update(payment)
-			}
+			payment.Status = "paid"
+			payment.PaidAt = &now
+			h.paymentRepo.Update(payment)
 		}
 	}
 
@@ -555,7 +1283,12 @@ func (h *TicketHandlers) validatePurchaseRequest(req *models.TicketPurchaseReque
 	}
 
 	if req.UserID <= 0 {
-		return fmt.Errorf("valid user ID is required")
+		if req.Email == "" || h.guests == nil {
+			return fmt.Errorf("valid user ID is required")
+		}
+		if !strings.Contains(req.Email, "@") || len(req.Email) < 5 {
+			return fmt.Errorf("invalid email format")
+		}
 	}
 
 	if req.UMAAddress == "" {
@@ -565,15 +1298,467 @@ func (h *TicketHandlers) validatePurchaseRequest(req *models.TicketPurchaseReque
 	return nil
 }
 
+// purchaseExpiry returns how long a purchase's invoice stays payable: the
+// event's expiry, or a shorter one the buyer asked for in seconds
+func purchaseExpiry(event *models.Event, seconds int) (time.Duration, error) {
+	expiry := event.InvoiceExpiry()
+	if seconds == 0 {
+		return expiry, nil
+	}
+	requested := time.Duration(seconds) * time.Second
+	if requested < models.MinInvoiceExpiry || requested > expiry {
+		return 0, fmt.Errorf("invoice expiry must be between %d and %d seconds", int(models.MinInvoiceExpiry/time.Second), int(expiry/time.Second))
+	}
+	return requested, nil
+}
+
+// maxReferralLength caps each referral field stored on an order
+const maxReferralLength = 100
+
+// purchaseReferral returns where a purchase came from: the ref and UTM
+// fields in the body, or else the same-named query parameters, which the
+// checkout page can pass through from the link the buyer followed
+func purchaseReferral(r *http.Request, body models.Referral) models.Referral {
+	query := r.URL.Query()
+	field := func(value, param string) string {
+		if value == "" {
+			value = query.Get(param)
+		}
+		value = strings.TrimSpace(value)
+		if runes := []rune(value); len(runes) > maxReferralLength {
+			value = strings.TrimSpace(string(runes[:maxReferralLength]))
+		}
+		return value
+	}
+	return models.Referral{
+		Ref:         field(body.Ref, "ref"),
+		UTMSource:   field(body.UTMSource, "utm_source"),
+		UTMMedium:   field(body.UTMMedium, "utm_medium"),
+		UTMCampaign: field(body.UTMCampaign, "utm_campaign"),
+		UTMTerm:     field(body.UTMTerm, "utm_term"),
+		UTMContent:  field(body.UTMContent, "utm_content"),
+	}
+}
+
+// paymentBackendUnavailable writes a 503 and reports true when err is the
+// payment backend's circuit breaker failing the call fast
+func paymentBackendUnavailable(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, services.ErrPaymentBackendUnavailable) {
+		return false
+	}
+	middleware.WriteErrorCode(w, http.StatusServiceUnavailable, models.ErrorCodePaymentBackendUnavailable,
+		"Payments are temporarily unavailable, please try again shortly")
+	return true
+}
+
+// ticketConflict writes a 409 and reports true when creating the ticket
+// conflicted with existing data: the buyer's other ticket on an event
+// selling one per user or, very rarely, another ticket's code. A purchase
+// that lost the event's last seat to another meanwhile gets the sold out
+// 400 of the capacity check.
+func (h *TicketHandlers) ticketConflict(w http.ResponseWriter, ticket *models.Ticket, err error) bool {
+	if errors.Is(err, repositories.ErrSoldOut) {
+		middleware.WriteError(w, http.StatusBadRequest, "Event is sold out")
+		return true
+	}
+	if !errors.Is(err, repositories.ErrConflict) {
+		return false
+	}
+	if ticket.OnePerUser {
+		if has, err := h.ticketRepo.HasActiveTicketForEvent(ticket.UserID, ticket.EventID); err == nil && has {
+			writeAlreadyHasTicket(w)
+			return true
+		}
+	}
+	h.logger.Warn("Ticket conflicted with existing data", "event_id", ticket.EventID, "user_id", ticket.UserID, "error", err)
+	middleware.WriteError(w, http.StatusConflict, "The ticket could not be created, please try again")
+	return true
+}
+
+// writeAlreadyHasTicket refuses a purchase from a buyer who already holds
+// a ticket for an event selling one ticket per user
+func writeAlreadyHasTicket(w http.ResponseWriter) {
+	middleware.WriteErrorCode(w, http.StatusConflict, models.ErrorCodeAlreadyHasTicket, "You already have a ticket for this event")
+}
+
+// maxAddOnQuantity caps how many of one add-on a single purchase can include
+const maxAddOnQuantity = 10
+
+// selectAddOns turns the buyer's selections into line items priced from the
+// event's active add-ons and returns them with their total. A ticket can
+// have one flex add-on, until its cutoff before the event starts.
+func selectAddOns(catalog []models.AddOn, selections []models.AddOnSelection, event *models.Event, now time.Time) ([]models.TicketAddOn, int64, error) {
+	byID := make(map[int]models.AddOn, len(catalog))
+	for _, addOn := range catalog {
+		byID[addOn.ID] = addOn
+	}
+
+	items := make([]models.TicketAddOn, 0, len(selections))
+	seen := make(map[int]bool, len(selections))
+	var total int64
+	flex := false
+	for _, selection := range selections {
+		addOn, ok := byID[selection.AddOnID]
+		if !ok {
+			return nil, 0, fmt.Errorf("add-on %d is not available for this event", selection.AddOnID)
+		}
+		if seen[addOn.ID] {
+			return nil, 0, fmt.Errorf("add-on %d is selected more than once", addOn.ID)
+		}
+		seen[addOn.ID] = true
+		if selection.Quantity < 1 || selection.Quantity > maxAddOnQuantity {
+			return nil, 0, fmt.Errorf("quantity for %s must be between 1 and %d", addOn.Name, maxAddOnQuantity)
+		}
+
+		item := models.TicketAddOn{
+			AddOnID:         &addOn.ID,
+			Name:            addOn.Name,
+			Quantity:        selection.Quantity,
+			UnitPriceSats:   addOn.PriceSats,
+			FlexCutoffHours: addOn.FlexCutoffHours,
+		}
+		if item.FlexCutoffHours != nil {
+			switch {
+			case flex:
+				return nil, 0, fmt.Errorf("only one flex add-on can be bought with a ticket")
+			case item.Quantity != 1:
+				return nil, 0, fmt.Errorf("quantity for %s must be 1", addOn.Name)
+			case !now.Before(services.FlexDeadline(event, item)):
+				return nil, 0, fmt.Errorf("%s is no longer available for this event", addOn.Name)
+			}
+			flex = true
+		}
+		items = append(items, item)
+		total += item.TotalSats()
+	}
+	return items, total, nil
+}
+
+// createTicket stores a new ticket in an order of its own, which records
+// where the buyer came from, with the gift it is, the add-ons bought, the
+// form answers given and the buyer's attestation, if any
+func (h *TicketHandlers) createTicket(ticket *models.Ticket, order *models.Order, gift *models.TicketGift, items []models.TicketAddOn, answers []models.TicketAnswer, attestation *models.PurchaseAttestation) error {
+	order.UserID, order.EventID = ticket.UserID, ticket.EventID
+	if err := h.orderRepo.Create(order); err != nil {
+		return err
+	}
+	ticket.OrderID = &order.ID
+	if err := h.ticketRepo.Create(ticket); err != nil {
+		if err := h.orderRepo.Delete(order.ID); err != nil {
+			h.logger.Error("Failed to delete order without ticket", "order_id", order.ID, "error", err)
+		}
+		ticket.OrderID = nil
+		return err
+	}
+	if gift != nil {
+		gift.TicketID, gift.SenderID = ticket.ID, ticket.UserID
+		if err := h.gifts.Schedule(gift); err != nil {
+			return err
+		}
+	}
+	if len(items) > 0 {
+		for i := range items {
+			items[i].TicketID = ticket.ID
+		}
+		if err := h.addOnRepo.CreateLineItems(items); err != nil {
+			return err
+		}
+	}
+	if len(answers) > 0 {
+		for i := range answers {
+			answers[i].TicketID = ticket.ID
+		}
+		if err := h.formRepo.CreateAnswers(answers); err != nil {
+			return err
+		}
+	}
+	if attestation == nil {
+		return nil
+	}
+	attestation.TicketID = ticket.ID
+	return h.attestRepo.Create(attestation)
+}
+
+// attestPurchase checks the buyer confirmed the event's age restriction and
+// accepted its current terms, returning the attestation to store with the
+// ticket. It returns nil when the event asks for neither.
+func attestPurchase(event *models.Event, req *models.TicketPurchaseRequest, r *http.Request) (*models.PurchaseAttestation, error) {
+	if event.MinAge == 0 && event.TermsVersion == "" {
+		return nil, nil
+	}
+	if event.MinAge > 0 && !req.AgeConfirmed {
+		return nil, errors.New("Confirm you meet the event's minimum age to continue")
+	}
+	if event.TermsVersion != "" && req.AcceptedTermsVersion != event.TermsVersion {
+		return nil, errors.New("Accept the event's current terms to continue")
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxAttestationUserAgent {
+		userAgent = userAgent[:maxAttestationUserAgent]
+	}
+	return &models.PurchaseAttestation{
+		EventID:      event.ID,
+		MinAge:       event.MinAge,
+		TermsVersion: event.TermsVersion,
+		ClientIP:     clientIP(r),
+		UserAgent:    userAgent,
+	}, nil
+}
+
+// maxAttestationUserAgent caps the User-Agent stored with an attestation
+const maxAttestationUserAgent = 500
+
+// clientIP returns the caller's IP; behind trusted proxies the router has
+// already replaced RemoteAddr with the forwarded client address
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// lineItems returns the add-ons bought with a ticket. Lookup failures are
+// logged and treated as none so the ticket itself can still be shown.
+func (h *TicketHandlers) lineItems(ticketID int) []models.TicketAddOn {
+	if h.addOnRepo == nil {
+		return nil
+	}
+	items, err := h.addOnRepo.GetLineItems(ticketID)
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket add-ons", "ticket_id", ticketID, "error", err)
+		return nil
+	}
+	return items
+}
+
+// paymentLineItems itemizes a ticket's payment: the ticket itself, each
+// add-on bought with it, tax charged on top and the platform fee
+func (h *TicketHandlers) paymentLineItems(ticket *models.Ticket, event *models.Event, payment *models.Payment, charges invoiceCharges) []models.PaymentLineItem {
+	var items []models.PaymentLineItem
+	ticketSats := charges.subtotal
+	for _, addOn := range h.lineItems(ticket.ID) {
+		items = append(items, models.PaymentLineItem{
+			PaymentID:      payment.ID,
+			Kind:           models.LineItemAddOn,
+			Description:    addOn.Name,
+			Quantity:       addOn.Quantity,
+			UnitAmountSats: addOn.UnitPriceSats,
+			AmountSats:     addOn.TotalSats(),
+		})
+		ticketSats -= addOn.TotalSats()
+	}
+	if charges.tax > 0 && !event.TaxInclusive {
+		items = append(items, models.PaymentLineItem{
+			PaymentID:      payment.ID,
+			Kind:           models.LineItemTax,
+			Description:    fmt.Sprintf("%s %s", event.TaxJurisdiction, percent(event.TaxBasisPoints)),
+			Quantity:       1,
+			UnitAmountSats: charges.tax,
+			AmountSats:     charges.tax,
+		})
+	}
+	if charges.fee > 0 {
+		items = append(items, models.PaymentLineItem{
+			PaymentID:      payment.ID,
+			Kind:           models.LineItemFee,
+			Description:    "Platform fee",
+			Quantity:       1,
+			UnitAmountSats: charges.fee,
+			AmountSats:     charges.fee,
+		})
+	}
+
+	return append([]models.PaymentLineItem{{
+		PaymentID:      payment.ID,
+		Kind:           models.LineItemTicket,
+		Description:    h.ticketDescription(ticket, event),
+		Quantity:       1,
+		UnitAmountSats: ticketSats,
+		AmountSats:     ticketSats,
+	}}, items...)
+}
+
+// ticketDescription names a ticket's line item, with the pricing rule that
+// set its price when there was one
+func (h *TicketHandlers) ticketDescription(ticket *models.Ticket, event *models.Event) string {
+	description := "Ticket: " + event.Title
+	if h.pricing == nil || ticket.PriceChangeID == nil {
+		return description
+	}
+	change, err := h.pricing.PriceChange(*ticket.PriceChangeID)
+	if err != nil {
+		h.logger.Error("Failed to fetch price change", "ticket_id", ticket.ID, "error", err)
+		return description
+	}
+	if change.RuleName != "" {
+		description += " (" + change.RuleName + ")"
+	}
+	return description
+}
+
+// ticketPrice returns what a buyer pays for event: the fixed price or, on pay
+// what you want events, the amount they chose (the suggested price if none).
+func ticketPrice(event *models.Event, chosen *int64) (int64, error) {
+	if !event.PayWhatYouWant() || chosen == nil {
+		return event.PriceSats, nil
+	}
+	if *chosen < event.MinPriceSats {
+		return 0, fmt.Errorf("amount must be at least %d sats", event.MinPriceSats)
+	}
+	return *chosen, nil
+}
+
+// ticketAmount is what the buyer owes for ticket: the amount agreed at
+// purchase, or the event price for fixed-price tickets that predate it.
+func ticketAmount(ticket *models.Ticket, event *models.Event) int64 {
+	if ticket.AmountSats > 0 || event.PayWhatYouWant() {
+		return ticket.AmountSats
+	}
+	return event.PriceSats
+}
+
+// invoiceCharges breaks down a ticket's invoice: the subtotal agreed at
+// purchase, the tax on it and the platform fee
+type invoiceCharges struct {
+	subtotal, taxable, tax, fee int64
+	taxInclusive                bool
+}
+
+// total is the amount invoiced
+func (c invoiceCharges) total() int64 {
+	if c.taxInclusive {
+		return c.subtotal + c.fee
+	}
+	return c.subtotal + c.tax + c.fee
+}
+
+// charges works out the tax and platform fee on subtotal sats for event.
+// The platform fee is charged on the subtotal before tax. The returned error
+// is safe to show to the client; details are logged here.
+func (h *TicketHandlers) charges(event *models.Event, subtotal int64) (invoiceCharges, error) {
+	charges := invoiceCharges{subtotal: subtotal, taxInclusive: event.TaxInclusive}
+	charges.taxable, charges.tax = event.Tax(subtotal)
+	if h.fees == nil {
+		return charges, nil
+	}
+	schedule, err := h.fees.ScheduleFor(event)
+	if err != nil {
+		h.logger.Error("Failed to look up platform fee", "event_id", event.ID, "error", err)
+		return invoiceCharges{}, errors.New("Failed to look up platform fee")
+	}
+	charges.fee = schedule.Fee(subtotal)
+	return charges, nil
+}
+
+// percent formats a rate in basis points, e.g. 725 as "7.25%"
+func percent(basisPoints int64) string {
+	return strconv.FormatFloat(float64(basisPoints)/100, 'f', -1, 64) + "%"
+}
+
+// issueTicketInvoice creates the invoice for a pending ticket on a paid
+// event, payable for expiry, records it and starts paying it in the
+// background. The ticket holds its seat until the invoice expires. The
+// returned error is safe to show to the client; details are logged here.
+func (h *TicketHandlers) issueTicketInvoice(ticket *models.Ticket, event *models.Event, expiry time.Duration) (*models.UMARequestInvoice, error) {
+	// Create a new invoice for this ticket (using buyer's UMA address)
+	description := fmt.Sprintf("Ticket #%d for %s", ticket.ID, event.Title)
+
+	charges, err := h.charges(event, ticketAmount(ticket, event))
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := h.umaService.CreateTicketInvoice(ticket.UMAAddress, charges.total(), description, expiry)
+	if errors.Is(err, services.ErrPaymentBackendUnavailable) {
+		h.logger.Warn("Payment backend unavailable, ticket not invoiced", "ticket_id", ticket.ID)
+		return nil, err
+	}
+	if err != nil {
+		h.logger.Error("Failed to create ticket invoice", "ticket_id", ticket.ID, "error", err)
+		return nil, errors.New("Failed to create payment invoice")
+	}
+
+	// Store the invoice in uma_request_invoices with ticket_id
+	ticketInvoice := &models.UMARequestInvoice{
+		EventID:     &event.ID,
+		TicketID:    &ticket.ID,
+		Source:      models.UMAInvoiceTicket,
+		InvoiceID:   invoice.ID,
+		PaymentHash: invoice.PaymentHash,
+		Bolt11:      invoice.Bolt11,
+		AmountSats:  invoice.AmountSats,
+		Status:      invoice.Status,
+		UMAAddress:  ticket.UMAAddress,
+		Description: description,
+		ExpiresAt:   invoice.ExpiresAt,
+	}
+
+	if err := h.umaRepo.Create(ticketInvoice); err != nil {
+		h.logger.Error("Failed to save ticket invoice", "ticket_id", ticket.ID, "error", err)
+		return nil, errors.New("Failed to save payment invoice")
+	}
+
+	// Create payment record with the new invoice
+	payment := &models.Payment{
+		TicketID:        ticket.ID,
+		InvoiceID:       invoice.ID,
+		Bolt11:          invoice.Bolt11,
+		PaymentHash:     invoice.PaymentHash,
+		Amount:          invoice.AmountSats,
+		Status:          "pending",
+		TaxableSats:     charges.taxable,
+		TaxSats:         charges.tax,
+		TaxBasisPoints:  event.TaxBasisPoints,
+		TaxInclusive:    event.TaxInclusive,
+		TaxJurisdiction: event.TaxJurisdiction,
+		ExpiresAt:       invoice.ExpiresAt,
+	}
+
+	if err := h.paymentRepo.Create(payment); err != nil {
+		h.logger.Error("Failed to create payment record", "error", err)
+		return nil, errors.New("Failed to create payment record")
+	}
+
+	// Itemize the payment for its receipt; without items the receipt shows
+	// the whole amount as the ticket
+	if h.receiptRepo != nil {
+		if err := h.receiptRepo.CreateLineItems(h.paymentLineItems(ticket, event, payment, charges)); err != nil {
+			h.logger.Error("Failed to store payment line items", "payment_id", payment.ID, "error", err)
+		}
+	}
+
+	// Update ticket's invoice_id
+	ticket.InvoiceID = invoice.ID
+	if err := h.ticketRepo.Update(ticket); err != nil {
+		h.logger.Error("Failed to update ticket invoice_id", "ticket_id", ticket.ID, "error", err)
+	}
+
+	h.logger.Info("Ticket created with per-ticket invoice",
+		"ticket_id", ticket.ID,
+		"invoice_id", invoice.ID,
+		"uma_address", ticket.UMAAddress)
+
+	// Pay the invoice asynchronously: try NWC first, then fall back to UMA Request
+	go h.processPayment(ticket.UserID, ticket.ID, payment.ID, invoice.Bolt11)
+
+	return ticketInvoice, nil
+}
+
 // processPayment pays the invoice via the user's NWC connection.
 func (h *TicketHandlers) processPayment(userID, ticketID, paymentID int, bolt11 string) {
 	nwcConn, err := h.nwcRepo.GetByUserID(userID)
 	if err != nil {
-		h.logger.Warn("Failed to look up NWC connection", "user_id", userID, "error", err)
-	}
-
-	if nwcConn == nil {
-		h.logger.Warn("No NWC connection found, marking ticket as failed", "user_id", userID, "ticket_id", ticketID)
+		if errors.Is(err, repositories.ErrNotFound) && h.isGuest(userID) {
+			// Guests pay the invoice from their own wallet
+			h.logger.Info("Awaiting guest payment of ticket invoice", "user_id", userID, "ticket_id", ticketID)
+			return
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			h.logger.Warn("No NWC connection found, marking ticket as failed", "user_id", userID, "ticket_id", ticketID)
+		} else {
+			h.logger.Warn("Failed to look up NWC connection, marking ticket as failed", "user_id", userID, "ticket_id", ticketID, "error", err)
+		}
 		_ = h.ticketRepo.UpdatePaymentStatus(ticketID, "failed")
 		_ = h.paymentRepo.UpdateStatus(paymentID, "failed")
 		return
@@ -593,3 +1778,15 @@ func (h *TicketHandlers) processPayment(userID, ticketID, paymentID int, bolt11
 	_ = h.paymentRepo.UpdateStatus(paymentID, "paid")
 	_ = h.ticketRepo.UpdatePaymentStatus(ticketID, "paid")
 }
+
+// isGuest reports whether a buyer checked out as a guest
+func (h *TicketHandlers) isGuest(userID int) bool {
+	if h.guests == nil {
+		return false
+	}
+	guest, err := h.guests.IsGuest(userID)
+	if err != nil {
+		h.logger.Error("Failed to check whether buyer is a guest", "user_id", userID, "error", err)
+	}
+	return guest
+}
@@ -0,0 +1,816 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+	"tickets-by-uma/ticketcode"
+)
+
+// Fakes embed the repository interfaces so only the methods a test needs
+// have to be implemented; anything else panics.
+
+type fakeTicketRepository struct {
+	repositories.TicketRepository
+	tickets map[int]*models.Ticket
+}
+
+func (r *fakeTicketRepository) GetByID(id int) (*models.Ticket, error) {
+	if ticket, ok := r.tickets[id]; ok {
+		return ticket, nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeTicketRepository) GetByTicketCode(ticketCode string) (*models.Ticket, error) {
+	for _, ticket := range r.tickets {
+		if ticket.TicketCode == ticketCode {
+			return ticket, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+type fakeEventRepository struct {
+	repositories.EventRepository
+	events map[int]*models.Event
+}
+
+func (r *fakeEventRepository) GetByID(id int) (*models.Event, error) {
+	if event, ok := r.events[id]; ok {
+		return event, nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+type fakePaymentRepository struct {
+	repositories.PaymentRepository
+	payments map[int]*models.Payment
+}
+
+func (r *fakePaymentRepository) GetByTicketID(ticketID int) (*models.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.TicketID == ticketID {
+			return payment, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakePaymentRepository) GetByInvoiceID(invoiceID string) (*models.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.InvoiceID == invoiceID {
+			return payment, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func TestHandleTicketStatusNotFound(t *testing.T) {
+	tickets := &fakeTicketRepository{tickets: map[int]*models.Ticket{
+		1: {ID: 1, EventID: 10, PaymentStatus: "pending"},
+		2: {ID: 2, EventID: 99, PaymentStatus: "pending"},
+	}}
+	events := &fakeEventRepository{events: map[int]*models.Event{
+		10: {ID: 10, Title: "Paid Event", PriceSats: 1000},
+	}}
+	payments := &fakePaymentRepository{payments: map[int]*models.Payment{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewTicketHandlers(tickets, events, payments, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clock.System(), logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/status", handler.HandleTicketStatus)
+
+	tests := []struct {
+		name       string
+		ticketID   string
+		wantStatus int
+	}{
+		{"missing ticket", "404", http.StatusNotFound},
+		{"missing event", "2", http.StatusNotFound},
+		// The payment record does not exist yet; this used to dereference nil
+		{"missing payment", "1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tickets/"+tt.ticketID+"/status", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tickets/1/status", nil))
+	var resp struct {
+		Data struct {
+			Payment map[string]interface{} `json:"payment"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Payment["status"] != "pending" {
+		t.Errorf("Expected ticket status as payment status, got %v", resp.Data.Payment["status"])
+	}
+}
+
+func TestHandlePaymentStatusNotFound(t *testing.T) {
+	payments := &fakePaymentRepository{payments: map[int]*models.Payment{
+		1: {ID: 1, TicketID: 7, InvoiceID: "lnbc-orphan"},
+	}}
+	tickets := &fakeTicketRepository{tickets: map[int]*models.Ticket{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewPaymentHandlers(payments, tickets, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/payments/{invoice_id}/status", handler.HandlePaymentStatus)
+
+	for _, invoiceID := range []string{"lnbc-unknown", "lnbc-orphan"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/payments/"+invoiceID+"/status", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", invoiceID, rec.Code)
+		}
+	}
+}
+
+func TestHandleValidateTicketEventWindow(t *testing.T) {
+	start := time.Date(2026, 3, 1, 19, 0, 0, 0, time.UTC)
+	code, err := ticketcode.New(10)
+	if err != nil {
+		t.Fatalf("Failed to generate ticket code: %v", err)
+	}
+	tickets := &fakeTicketRepository{tickets: map[int]*models.Ticket{
+		1: {ID: 1, EventID: 10, TicketCode: code, PaymentStatus: "paid"},
+	}}
+	events := &fakeEventRepository{events: map[int]*models.Event{
+		10: {ID: 10, Title: "Concert", StartTime: start, EndTime: start.Add(2 * time.Hour)},
+	}}
+	clk := clock.NewFake(start.Add(-time.Hour))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewTicketHandlers(tickets, events, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	validate := func() int {
+		body := strings.NewReader(`{"ticket_code":"` + code + `","event_id":10}`)
+		rec := httptest.NewRecorder()
+		handler.HandleValidateTicket(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/validate", body))
+		return rec.Code
+	}
+
+	if code := validate(); code != http.StatusBadRequest {
+		t.Errorf("Before the event: expected 400, got %d", code)
+	}
+
+	clk.Advance(90 * time.Minute)
+	if code := validate(); code != http.StatusOK {
+		t.Errorf("During the event: expected 200, got %d", code)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if code := validate(); code != http.StatusBadRequest {
+		t.Errorf("After the event: expected 400, got %d", code)
+	}
+}
+
+func TestHandleValidateTicketCodeFormat(t *testing.T) {
+	start := time.Date(2026, 3, 1, 19, 0, 0, 0, time.UTC)
+	code, err := ticketcode.New(10)
+	if err != nil {
+		t.Fatalf("Failed to generate ticket code: %v", err)
+	}
+	legacy := "0123456789abcdef0123456789abcdef"
+	tickets := &fakeTicketRepository{tickets: map[int]*models.Ticket{
+		1: {ID: 1, EventID: 10, TicketCode: code, PaymentStatus: "paid"},
+		2: {ID: 2, EventID: 10, TicketCode: legacy, PaymentStatus: "paid"},
+	}}
+	events := &fakeEventRepository{events: map[int]*models.Event{
+		10: {ID: 10, Title: "Concert", StartTime: start, EndTime: start.Add(2 * time.Hour)},
+	}}
+	clk := clock.NewFake(start.Add(30 * time.Minute))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewTicketHandlers(tickets, events, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, start.Add(time.Hour), clk, logger, "localhost")
+
+	validate := func(ticketCode string, eventID int) int {
+		body, _ := json.Marshal(map[string]interface{}{"ticket_code": ticketCode, "event_id": eventID})
+		rec := httptest.NewRecorder()
+		handler.HandleValidateTicket(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/validate", bytes.NewReader(body)))
+		return rec.Code
+	}
+
+	// Typed codes are normalized to the stored form
+	if status := validate(strings.ToLower(strings.ReplaceAll(code, "-", " ")), 10); status != http.StatusOK {
+		t.Errorf("Normalized code: expected 200, got %d", status)
+	}
+	typo := code[:len(code)-1] + "0"
+	if code[len(code)-1] == '0' {
+		typo = code[:len(code)-1] + "1"
+	}
+	if status := validate(typo, 10); status != http.StatusBadRequest {
+		t.Errorf("Bad check character: expected 400, got %d", status)
+	}
+	if status := validate("not-a-code", 10); status != http.StatusBadRequest {
+		t.Errorf("Malformed code: expected 400, got %d", status)
+	}
+	if status := validate(code, 11); status != http.StatusBadRequest {
+		t.Errorf("Code for another event: expected 400, got %d", status)
+	}
+
+	if status := validate(legacy, 10); status != http.StatusOK {
+		t.Errorf("Legacy code in the migration window: expected 200, got %d", status)
+	}
+	clk.Advance(time.Hour)
+	if status := validate(legacy, 10); status != http.StatusBadRequest {
+		t.Errorf("Legacy code after the migration window: expected 400, got %d", status)
+	}
+}
+
+func TestHandlePurchaseTicketPayWhatYouWant(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	event := &models.Event{Title: "Benefit", Capacity: 10, PriceSats: 5000, IsActive: true,
+		PricingMode: models.PricingPayWhatYouWant, MinPriceSats: 1000}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	purchase := func(userID int, amount *int64) (int, map[string]interface{}) {
+		t.Helper()
+		payload, _ := json.Marshal(models.TicketPurchaseRequest{
+			EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com", AmountSats: amount,
+		})
+		rec := httptest.NewRecorder()
+		handler.HandlePurchaseTicket(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/purchase", bytes.NewReader(payload)))
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	amount := func(sats int64) *int64 { return &sats }
+
+	if code, _ := purchase(1, amount(999)); code != http.StatusBadRequest {
+		t.Errorf("Below the minimum: expected 400, got %d", code)
+	}
+
+	tests := []struct {
+		userID int
+		chosen *int64
+		want   int64
+	}{
+		{2, amount(21000), 21000},
+		{3, nil, 5000}, // suggested price
+	}
+	for _, tt := range tests {
+		code, data := purchase(tt.userID, tt.chosen)
+		if code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", code)
+		}
+		if int64(data["amount_sats"].(float64)) != tt.want {
+			t.Errorf("Expected amount %d in response, got %v", tt.want, data["amount_sats"])
+		}
+		ticketID := int(data["ticket"].(map[string]interface{})["id"].(float64))
+		payment, err := store.Payments().GetByTicketID(ticketID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payment.Amount != tt.want {
+			t.Errorf("Expected payment of %d sats, got %d", tt.want, payment.Amount)
+		}
+	}
+}
+
+func TestHandleTicketStatusLongPoll(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	watcher := services.NewTicketWatcher()
+	tickets := watcher.Tickets(store.Tickets())
+	handler := NewTicketHandlers(tickets, store.Events(), store.Payments(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, watcher, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/status", handler.HandleTicketStatus).Methods("GET")
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 0, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "LONGPOLL-1", PaymentStatus: "pending"}
+	if err := tickets.Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/tickets/" + strconv.Itoa(ticket.ID) + "/status"
+
+	status := func(query string) (int, string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+query, nil))
+		var resp struct {
+			Data struct {
+				Ticket struct {
+					PaymentStatus string `json:"payment_status"`
+				} `json:"ticket"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data.Ticket.PaymentStatus
+	}
+
+	if code, _ := status("?wait=soon"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid wait, got %d", code)
+	}
+
+	// Without a change the wait runs out and the current state is returned
+	start := time.Now()
+	if code, got := status("?wait=20ms"); code != http.StatusOK || got != "pending" {
+		t.Errorf("Expected pending after the wait, got %d %q", code, got)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Errorf("Expected the request to wait")
+	}
+
+	// An update wakes the waiting request
+	done := make(chan string)
+	go func() {
+		_, got := status("?wait=10s")
+		done <- got
+	}()
+	for watcher.Waiting(ticket.ID) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := tickets.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-done:
+		if got != "paid" {
+			t.Errorf("Expected paid after the update, got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Long-poll was not woken by the update")
+	}
+
+	// A settled ticket is returned at once
+	start = time.Now()
+	if code, got := status("?wait=10s"); code != http.StatusOK || got != "paid" || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected paid without waiting, got %d %q", code, got)
+	}
+}
+
+// unavailableUMAService fails invoice creation the way an open circuit
+// breaker does
+type unavailableUMAService struct {
+	*services.SimulatedUMAService
+}
+
+func (unavailableUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
+	return nil, services.ErrPaymentBackendUnavailable
+}
+
+func TestHandlePurchaseTicketPaymentBackendUnavailable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	uma := unavailableUMAService{services.NewSimulatedUMAService(0, clk, logger)}
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		uma, settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _ := json.Marshal(models.TicketPurchaseRequest{EventID: event.ID, UserID: 1, UMAAddress: "$buyer@wallet.example.com"})
+	rec := httptest.NewRecorder()
+	handler.HandlePurchaseTicket(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/purchase", bytes.NewReader(payload)))
+
+	var resp models.ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusServiceUnavailable || resp.ErrorCode != models.ErrorCodePaymentBackendUnavailable {
+		t.Errorf("Expected 503 %s, got %d %s", models.ErrorCodePaymentBackendUnavailable, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlePurchaseTicketInvoiceExpiry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	// purchase returns the status and, once bought, the ticket's payment
+	purchase := func(event *models.Event, userID, expirySeconds int) (int, *models.Payment) {
+		t.Helper()
+		payload, _ := json.Marshal(models.TicketPurchaseRequest{EventID: event.ID, UserID: userID, UMAAddress: "$buyer@wallet.example.com", InvoiceExpirySeconds: expirySeconds})
+		rec := httptest.NewRecorder()
+		handler.HandlePurchaseTicket(rec, httptest.NewRequest("POST", "/api/tickets/purchase", bytes.NewReader(payload)))
+		if rec.Code != http.StatusCreated {
+			return rec.Code, nil
+		}
+		var resp struct {
+			Data struct {
+				Ticket struct{ ID int } `json:"ticket"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		payment, err := store.Payments().GetByTicketID(resp.Data.Ticket.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, payment
+	}
+
+	standard := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	onSale := &models.Event{Title: "Stadium", Capacity: 10, PriceSats: 1000, IsActive: true, InvoiceExpirySeconds: 900}
+	for _, event := range []*models.Event{standard, onSale} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, payment := purchase(standard, 1, 0); payment == nil || !payment.ExpiresAt.Equal(clk.Now().Add(models.DefaultInvoiceExpiry)) {
+		t.Errorf("Expected the default expiry, got %+v", payment)
+	}
+	if _, payment := purchase(onSale, 2, 0); payment == nil || !payment.ExpiresAt.Equal(clk.Now().Add(15*time.Minute)) {
+		t.Errorf("Expected the event's expiry, got %+v", payment)
+	}
+	if _, payment := purchase(onSale, 3, 300); payment == nil || !payment.ExpiresAt.Equal(clk.Now().Add(5*time.Minute)) {
+		t.Errorf("Expected the shorter expiry asked for, got %+v", payment)
+	}
+	for _, seconds := range []int{3600, 30, -1} {
+		if status, _ := purchase(onSale, 4, seconds); status != http.StatusBadRequest {
+			t.Errorf("Expected an expiry of %d seconds refused, got %d", seconds, status)
+		}
+	}
+}
+
+func TestHandleReinvoiceTicket(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	notifier := services.NewLogNotifier(store.Users(), services.NewTemplateService(store.NotificationTemplates(), store.Events(), logger), logger)
+	guests := services.NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	reservations := services.NewReservationService(store.Payments(), store.Tickets(), logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, guests, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/purchase", handler.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/status", handler.HandleTicketStatus).Methods("GET")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/reinvoice", handler.HandleReinvoiceTicket).Methods("POST")
+
+	var caller *models.User
+	do := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if caller != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, caller))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	purchase := func(event *models.Event, email string) *models.Ticket {
+		t.Helper()
+		status, data := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{EventID: event.ID, Email: email, UMAAddress: "$guest@wallet.example.com"})
+		if status != http.StatusCreated {
+			t.Fatalf("Expected the purchase to succeed, got %d", status)
+		}
+		ticket, err := store.Tickets().GetByID(int(data["ticket"].(map[string]interface{})["id"].(float64)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+
+	event := &models.Event{Title: "Club Night", Capacity: 1, PriceSats: 1000, IsActive: true, InvoiceExpirySeconds: 900}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := purchase(event, "guest@example.com")
+	reinvoicePath := fmt.Sprintf("/api/tickets/%d/reinvoice", ticket.ID)
+	statusPath := fmt.Sprintf("/api/tickets/%d/status", ticket.ID)
+	expired, _ := store.Payments().GetByTicketID(ticket.ID)
+
+	// Only the buyer can re-invoice their ticket
+	if status, _ := do("POST", reinvoicePath, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 without a caller, got %d", status)
+	}
+	caller = &models.User{ID: ticket.UserID + 100}
+	if status, _ := do("POST", reinvoicePath, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for someone else's ticket, got %d", status)
+	}
+	caller, _ = store.Users().GetByID(ticket.UserID)
+
+	if status, _ := do("POST", reinvoicePath, nil); status != http.StatusConflict {
+		t.Errorf("Expected a payable invoice kept, got %d", status)
+	}
+	if _, data := do("GET", statusPath, nil); data["reinvoice"] != nil {
+		t.Errorf("Expected no offer before the invoice expires, got %v", data["reinvoice"])
+	}
+
+	// Once the invoice expires the status offers a new one
+	clk.Advance(15 * time.Minute)
+	if _, err := reservations.ReleaseExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if _, data := do("GET", statusPath, nil); data["reinvoice"] == nil {
+		t.Errorf("Expected a re-invoice offer, got %v", data)
+	}
+	status, data := do("POST", reinvoicePath, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the ticket re-invoiced, got %d", status)
+	}
+	payment, _ := store.Payments().GetByTicketID(ticket.ID)
+	if payment.ID != expired.ID || payment.InvoiceID == expired.InvoiceID || payment.Status != "pending" ||
+		payment.Amount != expired.Amount || !payment.ExpiresAt.Equal(clk.Now().Add(15*time.Minute)) {
+		t.Errorf("Expected the payment to carry a new invoice, got %+v", payment)
+	}
+	if invoice, _ := store.UMARequestInvoices().GetByTicketID(ticket.ID); invoice.InvoiceID != payment.InvoiceID || invoice.Bolt11 != payment.Bolt11 ||
+		invoice.PaymentHash != payment.PaymentHash || invoice.Bolt11 != data["uma_request"].(map[string]interface{})["bolt11"] {
+		t.Errorf("Expected the ticket's invoice superseded, got %+v", invoice)
+	}
+	if ticket, _ := store.Tickets().GetByID(ticket.ID); ticket.PaymentStatus != "pending" {
+		t.Errorf("Expected the ticket pending again, got %s", ticket.PaymentStatus)
+	}
+	if _, ok := data["ticket"].(map[string]interface{})["ticket_code"]; ok {
+		t.Errorf("Expected the ticket code left out of the response, got %v", data["ticket"])
+	}
+
+	// A price change reprices the ticket once its invoice lapses, and the
+	// next invoice charges the new price
+	clk.Advance(15 * time.Minute)
+	reservations.ReleaseExpired()
+	event.PriceSats = 1500
+	if err := store.Events().Update(event); err != nil {
+		t.Fatal(err)
+	}
+	priceChanges := services.NewPriceChangeService(store.Tickets(), store.Payments(), store.AddOns(),
+		services.NewPricingService(store.PricingRules(), store.Events(), clk, logger), nil, clk, logger)
+	if _, repriced, err := priceChanges.Apply(event); err != nil || len(repriced) != 1 {
+		t.Fatalf("Expected the lapsed ticket repriced, got %v (%v)", repriced, err)
+	}
+	if status, _ := do("POST", reinvoicePath, nil); status != http.StatusOK {
+		t.Fatalf("Expected the repriced ticket re-invoiced, got %d", status)
+	}
+	if payment, _ := store.Payments().GetByTicketID(ticket.ID); payment.Amount != 1500 || payment.TaxableSats != 1500 {
+		t.Errorf("Expected the new invoice at the new price, got %+v", payment)
+	}
+	if items, _ := store.Receipts().GetLineItems(expired.ID); len(items) != 1 || items[0].AmountSats != 1500 {
+		t.Errorf("Expected the ticket itemized at the new price, got %+v", items)
+	}
+
+	// A seat sold meanwhile cannot be taken back
+	clk.Advance(15 * time.Minute)
+	reservations.ReleaseExpired()
+	other := purchase(event, "other@example.com")
+	if status, _ := do("POST", "/api/tickets/purchase", models.TicketPurchaseRequest{EventID: event.ID, Email: "third@example.com", UMAAddress: "$third@wallet.example.com"}); status != http.StatusBadRequest {
+		t.Errorf("Expected the seat of a pending purchase kept from sale, got %d", status)
+	}
+	if err := store.Tickets().UpdatePaymentStatus(other.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, data := do("GET", statusPath, nil); data["reinvoice"] != nil {
+		t.Errorf("Expected no offer once sold out, got %v", data["reinvoice"])
+	}
+	if status, _ := do("POST", reinvoicePath, nil); status != http.StatusConflict {
+		t.Errorf("Expected a sold out event refused, got %d", status)
+	}
+	caller, _ = store.Users().GetByID(other.UserID)
+	if status, _ := do("POST", fmt.Sprintf("/api/tickets/%d/reinvoice", other.ID), nil); status != http.StatusConflict {
+		t.Errorf("Expected a paid ticket refused, got %d", status)
+	}
+}
+
+func TestHandleGetPaymentProof(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	backend := services.NewSimulatedUMAService(0, clk, logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		backend, services.NewSettingsService(store.Settings(), logger), nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/payment-proof", handler.HandleGetPaymentProof).Methods("GET")
+
+	get := func(ticketID int, user *models.User) (int, models.PaymentProof) {
+		t.Helper()
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/tickets/%d/payment-proof", ticketID), nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data models.PaymentProof `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	buyer := &models.User{ID: 1, Email: "buyer@example.com"}
+	// invoiced creates a ticket of the buyer with its invoice and payment
+	invoiced := func(code string) (*models.Ticket, *models.Payment, *models.Invoice) {
+		t.Helper()
+		invoice, err := backend.CreateTicketInvoice("$buyer@wallet.example.com", 1000, "Ticket", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: code, PaymentStatus: "pending", InvoiceID: invoice.ID, UMAAddress: "$buyer@wallet.example.com", AmountSats: 1000}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UMARequestInvoices().Create(&models.UMARequestInvoice{EventID: &event.ID, TicketID: &ticket.ID, Source: models.UMAInvoiceTicket, InvoiceID: invoice.ID, PaymentHash: invoice.PaymentHash, Bolt11: invoice.Bolt11, AmountSats: 1000, Status: "pending", ExpiresAt: invoice.ExpiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: invoice.ID, Bolt11: invoice.Bolt11, PaymentHash: invoice.PaymentHash, Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		return ticket, payment, invoice
+	}
+
+	paid, payment, invoice := invoiced("E1-PAID")
+	preimage, err := backend.PayWithNWC(invoice.Bolt11, "nostr+walletconnect://ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Payments().UpdatePreimage(payment.ID, preimage)
+	store.Payments().UpdateStatus(payment.ID, "paid")
+
+	status, proof := get(paid.ID, buyer)
+	if status != http.StatusOK || proof.Preimage != preimage || proof.PaymentHash != invoice.PaymentHash || proof.Bolt11 != invoice.Bolt11 || !proof.Verified {
+		t.Errorf("Expected a verified proof, got %d %+v", status, proof)
+	}
+	if status, _ := get(paid.ID, &models.User{ID: 2}); status != http.StatusNotFound {
+		t.Errorf("Expected another user's ticket hidden, got %d", status)
+	}
+	if status, _ := get(paid.ID, nil); status != http.StatusNotFound {
+		t.Errorf("Expected a proof to need the buyer, got %d", status)
+	}
+
+	unpaid, _, _ := invoiced("E1-UNPAID")
+	if status, _ := get(unpaid.ID, buyer); status != http.StatusNotFound {
+		t.Errorf("Expected no proof before payment, got %d", status)
+	}
+}
+
+func TestHandlePurchaseTicketOnePerUser(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := services.NewLogNotifier(store.Users(), services.NewTemplateService(store.NotificationTemplates(), store.Events(), logger), logger)
+	guests := services.NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), services.NewSettingsService(store.Settings(), logger), nil, nil, nil, nil, guests, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+
+	purchase := func(event *models.Event, email string) (int, string, int) {
+		t.Helper()
+		payload, _ := json.Marshal(models.TicketPurchaseRequest{EventID: event.ID, Email: email, UMAAddress: "$guest@wallet.example.com"})
+		rec := httptest.NewRecorder()
+		handler.HandlePurchaseTicket(rec, httptest.NewRequest("POST", "/api/tickets/purchase", bytes.NewReader(payload)))
+		var resp struct {
+			ErrorCode string `json:"error_code"`
+			Data      struct {
+				Ticket struct{ ID int } `json:"ticket"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.ErrorCode, resp.Data.Ticket.ID
+	}
+
+	limited := &models.Event{Title: "Lottery", Capacity: 10, PriceSats: 1000, IsActive: true, OneTicketPerUser: true}
+	open := &models.Event{Title: "Festival", Capacity: 10, PriceSats: 1000, IsActive: true}
+	for _, event := range []*models.Event{limited, open} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, _, first := purchase(limited, "fan@example.com")
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the first ticket bought, got %d", status)
+	}
+	if status, code, _ := purchase(limited, "fan@example.com"); status != http.StatusConflict || code != models.ErrorCodeAlreadyHasTicket {
+		t.Errorf("Expected a second ticket refused with %s, got %d %q", models.ErrorCodeAlreadyHasTicket, status, code)
+	}
+	if status, _, _ := purchase(limited, "friend@example.com"); status != http.StatusCreated {
+		t.Errorf("Expected another buyer served, got %d", status)
+	}
+	for i := 0; i < 2; i++ {
+		if status, _, _ := purchase(open, "fan@example.com"); status != http.StatusCreated {
+			t.Errorf("Expected an event without the limit to sell several tickets, got %d", status)
+		}
+	}
+
+	// A buyer whose ticket expired unpaid can buy again
+	if err := store.Tickets().UpdatePaymentStatus(first, "expired"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := purchase(limited, "fan@example.com"); status != http.StatusCreated {
+		t.Errorf("Expected a new ticket once the first expired, got %d", status)
+	}
+}
+
+func TestHandlePurchaseTicketSalesPause(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	handler := NewTicketHandlers(store.Tickets(), store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, nil, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+	admin := NewSettingsHandlers(settings, store.Events(), logger)
+	adminUser := &models.User{ID: 99, Email: "admin@example.com"}
+
+	var events []*models.Event
+	for _, title := range []string{"Paused", "Open"} {
+		event := &models.Event{Title: title, Capacity: 10, PriceSats: 1000, IsActive: true}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	purchase := func(userID, eventID int) int {
+		t.Helper()
+		payload, _ := json.Marshal(models.TicketPurchaseRequest{EventID: eventID, UserID: userID, UMAAddress: "$buyer@wallet.example.com"})
+		rec := httptest.NewRecorder()
+		handler.HandlePurchaseTicket(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/purchase", bytes.NewReader(payload)))
+		return rec.Code
+	}
+	toggle := func(path string, paused bool, handle http.HandlerFunc) {
+		t.Helper()
+		router := mux.NewRouter()
+		router.HandleFunc("/api/admin/sales-pause", handle).Methods("PUT")
+		router.HandleFunc("/api/admin/events/{id:[0-9]+}/sales-pause", handle).Methods("PUT")
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(fmt.Sprintf(`{"paused": %t}`, paused)))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, adminUser))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 toggling %s, got %d", path, rec.Code)
+		}
+	}
+
+	// Pausing one event leaves the others on sale
+	toggle(fmt.Sprintf("/api/admin/events/%d/sales-pause", events[0].ID), true, admin.HandleSetEventSalesPause)
+	if code := purchase(1, events[0].ID); code != http.StatusServiceUnavailable {
+		t.Errorf("Paused event: expected 503, got %d", code)
+	}
+	if code := purchase(2, events[1].ID); code != http.StatusCreated {
+		t.Errorf("Other event: expected 201, got %d", code)
+	}
+	if got := settings.PausedEvents(); len(got) != 1 || got[0] != events[0].ID {
+		t.Errorf("Expected the paused event to be listed, got %v", got)
+	}
+
+	// The global switch pauses every event until it is turned off
+	toggle("/api/admin/sales-pause", true, admin.HandleSetSalesPause)
+	if code := purchase(3, events[1].ID); code != http.StatusServiceUnavailable {
+		t.Errorf("Globally paused: expected 503, got %d", code)
+	}
+	toggle("/api/admin/sales-pause", false, admin.HandleSetSalesPause)
+	toggle(fmt.Sprintf("/api/admin/events/%d/sales-pause", events[0].ID), false, admin.HandleSetEventSalesPause)
+	if code := purchase(4, events[0].ID); code != http.StatusCreated {
+		t.Errorf("Resumed event: expected 201, got %d", code)
+	}
+	if got := settings.PausedEvents(); len(got) != 0 {
+		t.Errorf("Expected no paused events after resuming, got %v", got)
+	}
+}
@@ -0,0 +1,257 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+	"tickets-by-uma/ticketsig"
+)
+
+// TicketQRHandlers issues signed ticket QR payloads and serves what door
+// scanners need to check them offline: the public keys and the revocation
+// list of each event.
+type TicketQRHandlers struct {
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	ledgerRepo  repositories.LedgerRepository
+	// signer is nil when no signing keys are configured
+	signer   *ticketsig.Keyring
+	checkIns *services.CheckInService
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+func NewTicketQRHandlers(
+	ticketRepo repositories.TicketRepository,
+	paymentRepo repositories.PaymentRepository,
+	ledgerRepo repositories.LedgerRepository,
+	signer *ticketsig.Keyring,
+	checkIns *services.CheckInService,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *TicketQRHandlers {
+	return &TicketQRHandlers{
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		ledgerRepo:  ledgerRepo,
+		signer:      signer,
+		checkIns:    checkIns,
+		clock:       clk,
+		logger:      logger,
+	}
+}
+
+// HandleSigningKeys publishes the keys that verify ticket QR payloads
+func (h *TicketQRHandlers) HandleSigningKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSigner(w) {
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket signing keys retrieved successfully",
+		Data:    map[string]interface{}{"keys": h.signer.PublicKeys()},
+	})
+}
+
+// HandleGetTicketQR returns the signed QR payload of one of the caller's
+// paid tickets
+func (h *TicketQRHandlers) HandleGetTicketQR(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSigner(w) {
+		return
+	}
+
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	if ticket.PaymentStatus != "paid" || ticket.PaidAt == nil {
+		middleware.WriteError(w, http.StatusConflict, "QR codes are only issued for paid tickets")
+		return
+	}
+
+	// Issuing at payment time keeps the payload stable across requests
+	payload := ticketsig.Payload{TicketID: ticket.ID, EventID: ticket.EventID, IssuedAt: *ticket.PaidAt}
+	signed, err := h.signer.Sign(payload)
+	if err != nil {
+		h.logger.Error("Failed to sign ticket payload", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to sign ticket")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket QR payload issued successfully",
+		Data: map[string]interface{}{
+			"payload":   signed,
+			"ticket_id": payload.TicketID,
+			"event_id":  payload.EventID,
+			"tier":      payload.Tier,
+			"issued_at": payload.IssuedAt,
+		},
+	})
+}
+
+// HandleVerifyTicketQR checks a scanned QR payload's signature and the
+// ticket's current state, for scanners that are online
+func (h *TicketQRHandlers) HandleVerifyTicketQR(w http.ResponseWriter, r *http.Request) {
+	if !h.requireSigner(w) {
+		return
+	}
+
+	var req models.TicketQRVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Payload == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Payload is required")
+		return
+	}
+	if !scannerEvent(w, r, &req.EventID) {
+		return
+	}
+	if req.EventID <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "Valid event ID is required")
+		return
+	}
+
+	payload, err := h.signer.Verify(req.Payload)
+	if err != nil {
+		h.logger.Warn("Rejected ticket QR payload", "event_id", req.EventID, "error", err)
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket signature")
+		return
+	}
+	if payload.EventID != req.EventID {
+		middleware.WriteError(w, http.StatusBadRequest, "Ticket is not valid for this event")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(payload.TicketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", payload.TicketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+
+	revocation, err := h.revocation(ticket)
+	if err != nil {
+		h.logger.Error("Failed to check ticket revocation", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check ticket")
+		return
+	}
+	if revocation != nil {
+		recordScan(r, h.checkIns, req.EventID, ticket, ticket.TicketCode, "Ticket has been revoked: "+revocation.Reason)
+		middleware.WriteError(w, http.StatusBadRequest, "Ticket has been revoked: "+revocation.Reason)
+		return
+	}
+	recordScan(r, h.checkIns, req.EventID, ticket, ticket.TicketCode, "")
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket verified successfully",
+		Data:    payload,
+	})
+}
+
+// HandleRevocationList exports the tickets of an event whose QR payloads
+// must be rejected (admin only)
+func (h *TicketQRHandlers) HandleRevocationList(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	tickets, err := h.ticketRepo.GetByEventID(eventID)
+	if err != nil {
+		h.logger.Error("Failed to fetch event tickets", "event_id", eventID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch tickets")
+		return
+	}
+
+	list := models.TicketRevocationList{
+		EventID:     eventID,
+		GeneratedAt: h.clock.Now(),
+		Revocations: []models.TicketRevocation{},
+	}
+	for i := range tickets {
+		revocation, err := h.revocation(&tickets[i])
+		if err != nil {
+			h.logger.Error("Failed to check ticket revocation", "ticket_id", tickets[i].ID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to build revocation list")
+			return
+		}
+		if revocation != nil {
+			list.Revocations = append(list.Revocations, *revocation)
+		}
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Revocation list generated successfully",
+		Data:    list,
+	})
+}
+
+// revocation reports why a ticket's signed payload is no longer valid, or
+// nil if it is. Pending and failed tickets never had a payload issued.
+func (h *TicketQRHandlers) revocation(ticket *models.Ticket) (*models.TicketRevocation, error) {
+	switch ticket.PaymentStatus {
+	case "paid":
+	case models.TicketDisputed, "cancelled":
+		return &models.TicketRevocation{TicketID: ticket.ID, Reason: ticket.PaymentStatus, RevokedAt: ticket.UpdatedAt}, nil
+	default:
+		return nil, nil
+	}
+
+	payment, err := h.paymentRepo.GetByTicketID(ticket.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	refund, err := h.ledgerRepo.GetPaymentEntry(models.LedgerEntryRefund, payment.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.TicketRevocation{TicketID: ticket.ID, Reason: models.RevocationRefunded, RevokedAt: refund.OccurredAt}, nil
+}
+
+// requireSigner fails the request when ticket signing is not configured
+func (h *TicketQRHandlers) requireSigner(w http.ResponseWriter) bool {
+	if h.signer == nil {
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Ticket signing is not configured")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/ticketsig"
+)
+
+func TestTicketQRHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	signer, err := ticketsig.ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewTicketQRHandlers(store.Tickets(), store.Payments(), store.Ledger(), signer, nil, clk, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/signing-keys", handler.HandleSigningKeys).Methods("GET")
+	router.HandleFunc("/api/tickets/verify-qr", handler.HandleVerifyTicketQR).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/qr", handler.HandleGetTicketQR).Methods("GET")
+	router.HandleFunc("/api/admin/events/{id:[0-9]+}/revocations", handler.HandleRevocationList).Methods("GET")
+
+	do := func(method, path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	buyer := &models.User{ID: 7}
+	var tickets []*models.Ticket
+	for i, status := range []string{"paid", "paid", "pending"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "QR-" + strconv.Itoa(i), PaymentStatus: "pending"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Tickets().UpdatePaymentStatus(ticket.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	clk.Advance(time.Hour)
+	qrPath := func(ticket *models.Ticket) string {
+		return "/api/tickets/" + strconv.Itoa(ticket.ID) + "/qr"
+	}
+
+	status, data := do("GET", "/api/tickets/signing-keys", nil, nil)
+	if status != http.StatusOK || !strings.Contains(string(data), `"id":"k1"`) {
+		t.Errorf("Unexpected signing keys %d %s", status, data)
+	}
+
+	if status, _ := do("GET", qrPath(tickets[0]), &models.User{ID: 8}, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for someone else's ticket, got %d", status)
+	}
+	if status, _ := do("GET", qrPath(tickets[2]), buyer, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 for an unpaid ticket, got %d", status)
+	}
+
+	issue := func(ticket *models.Ticket) string {
+		t.Helper()
+		status, data := do("GET", qrPath(ticket), buyer, nil)
+		var qr struct {
+			Payload string `json:"payload"`
+		}
+		json.Unmarshal(data, &qr)
+		if status != http.StatusOK || qr.Payload == "" {
+			t.Fatalf("Expected a signed payload, got %d %s", status, data)
+		}
+		return qr.Payload
+	}
+	first, second := issue(tickets[0]), issue(tickets[1])
+	if again := issue(tickets[0]); again != first {
+		t.Errorf("Expected the payload to be stable, got %q and %q", first, again)
+	}
+
+	verify := func(payload string, eventID int) int {
+		status, _ := do("POST", "/api/tickets/verify-qr", nil, models.TicketQRVerificationRequest{Payload: payload, EventID: eventID})
+		return status
+	}
+	if status := verify(first, event.ID); status != http.StatusOK {
+		t.Errorf("Expected a valid ticket, got %d", status)
+	}
+	if status := verify(first, event.ID+1); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for another event, got %d", status)
+	}
+	if status := verify(first[:len(first)-2]+"AA", event.ID); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a forged signature, got %d", status)
+	}
+
+	// Cancel the first ticket and refund the second
+	if err := store.Tickets().UpdatePaymentStatus(tickets[0].ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: tickets[1].ID, InvoiceID: "lnbc-qr", Amount: 1000, Status: "paid"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+	refund := &models.LedgerEntry{Kind: models.LedgerEntryRefund, PaymentID: &payment.ID, Reference: "refund", OccurredAt: clk.Now(),
+		Postings: []models.LedgerPosting{
+			{Account: "Liabilities:Organizer Payable:1", DebitSats: 1000},
+			{Account: "Assets:Lightning Wallet", CreditSats: 1000},
+		}}
+	if err := store.Ledger().Post(refund); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := verify(first, event.ID); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a cancelled ticket, got %d", status)
+	}
+	if status := verify(second, event.ID); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a refunded ticket, got %d", status)
+	}
+
+	status, data = do("GET", "/api/admin/events/"+strconv.Itoa(event.ID)+"/revocations", nil, nil)
+	var list models.TicketRevocationList
+	json.Unmarshal(data, &list)
+	if status != http.StatusOK || list.EventID != event.ID || len(list.Revocations) != 2 {
+		t.Fatalf("Expected two revocations, got %d %+v", status, list)
+	}
+	reasons := map[int]string{}
+	for _, revocation := range list.Revocations {
+		reasons[revocation.TicketID] = revocation.Reason
+	}
+	if reasons[tickets[0].ID] != "cancelled" || reasons[tickets[1].ID] != models.RevocationRefunded {
+		t.Errorf("Unexpected revocation reasons %v", reasons)
+	}
+}
+
+func TestTicketQRHandlersWithoutKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewTicketQRHandlers(nil, nil, nil, nil, nil, clock.System(), logger)
+
+	rec := httptest.NewRecorder()
+	handler.HandleSigningKeys(rec, httptest.NewRequest(http.MethodGet, "/api/tickets/signing-keys", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without signing keys, got %d", rec.Code)
+	}
+}
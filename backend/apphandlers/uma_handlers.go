@@ -3,25 +3,31 @@ package apphandlers
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/uma-universal-money-address/uma-go-sdk/uma"
 	umaprotocol "github.com/uma-universal-money-address/uma-go-sdk/uma/protocol"
 
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
 	"tickets-by-uma/repositories"
 	umaservices "tickets-by-uma/services"
 )
 
-// UmaHandlers handles UMA protocol endpoints (payreq callbacks, pubkey, configuration).
+// UmaHandlers handles UMA protocol endpoints (lnurlp lookups, payreq
+// callbacks, pubkey, configuration).
 type UmaHandlers struct {
 	paymentRepo          repositories.PaymentRepository
+	eventRepo            repositories.EventRepository
+	umaRepo              repositories.UMARequestInvoiceRepository
 	umaService           umaservices.UMAService
+	clock                clock.Clock
 	logger               *slog.Logger
 	domain               string
 	umaSigningPrivKeyHex string
@@ -29,14 +35,20 @@ type UmaHandlers struct {
 
 func NewUmaHandlers(
 	paymentRepo repositories.PaymentRepository,
+	eventRepo repositories.EventRepository,
+	umaRepo repositories.UMARequestInvoiceRepository,
 	umaService umaservices.UMAService,
+	clk clock.Clock,
 	logger *slog.Logger,
 	domain string,
 	umaSigningPrivKeyHex string,
 ) *UmaHandlers {
 	return &UmaHandlers{
 		paymentRepo:          paymentRepo,
+		eventRepo:            eventRepo,
+		umaRepo:              umaRepo,
 		umaService:           umaService,
+		clock:                clk,
 		logger:               logger,
 		domain:               domain,
 		umaSigningPrivKeyHex: umaSigningPrivKeyHex,
@@ -61,11 +73,16 @@ func (h *UmaHandlers) HandleUmaPayreq(w http.ResponseWriter, r *http.Request) {
 
 	// Find the pending payment for this ticket
 	payment, err := h.paymentRepo.GetByTicketID(ticketID)
-	if err != nil || payment == nil {
-		h.logger.Error("No payment found for ticket", "ticket_id", ticketID, "error", err)
+	if errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("No payment found for ticket", "ticket_id", ticketID)
 		http.Error(w, "payment not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		h.logger.Error("Failed to fetch payment for ticket", "ticket_id", ticketID, "error", err)
+		http.Error(w, "failed to fetch payment", http.StatusInternalServerError)
+		return
+	}
 
 	if payment.Status != "pending" {
 		h.logger.Warn("Payment not pending", "ticket_id", ticketID, "status", payment.Status)
@@ -74,7 +91,7 @@ func (h *UmaHandlers) HandleUmaPayreq(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reuse the bolt11 already created during ticket purchase.
-	bolt11 := payment.InvoiceID
+	bolt11 := payment.Bolt11
 	metadata := fmt.Sprintf(`[["text/plain","Ticket purchase at %s"]]`, h.domain)
 	invoiceCreator := existingInvoiceCreator{bolt11: bolt11}
 
@@ -144,6 +161,254 @@ func (h *UmaHandlers) HandleUmaPayreq(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(payreqResponse)
 }
 
+// HandleLnurlp answers LNURL-pay and UMA lookups of an event's UMA address.
+// GET /.well-known/lnurlp/{identifier}
+//
+// $event-{id}@domain resolves to event id. Its callback issues a fresh
+// invoice for the event's price, recorded against the event so the
+// payment is attributed to it when it settles.
+func (h *UmaHandlers) HandleLnurlp(w http.ResponseWriter, r *http.Request) {
+	identifier := mux.Vars(r)["identifier"]
+	eventID, ok := models.ParseEventUMAIdentifier(identifier)
+	if !ok {
+		writeLnurlError(w, http.StatusNotFound, "unknown UMA address")
+		return
+	}
+	event, ok := h.payableEvent(w, eventID)
+	if !ok {
+		return
+	}
+
+	// Routed requests arrive without the host, which the receiver address
+	// is built from
+	request, err := uma.ParseLnurlpRequestWithReceiverDomain(*r.URL, h.domain)
+	if err != nil {
+		h.logger.Warn("Invalid lnurlp request", "identifier", identifier, "error", err)
+		writeLnurlError(w, http.StatusBadRequest, "invalid lnurlp request")
+		return
+	}
+
+	var signingKey *[]byte
+	var requiresTravelRule *bool
+	var payerData *umaprotocol.CounterPartyDataOptions
+	var currencies *[]umaprotocol.Currency
+	var kycStatus *umaprotocol.KycStatus
+	if request.IsUmaRequest() {
+		key, err := hex.DecodeString(h.umaSigningPrivKeyHex)
+		if err != nil {
+			h.logger.Error("Failed to decode UMA signing key", "error", err)
+			writeLnurlError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		travelRule := false
+		verified := umaprotocol.KycStatusVerified
+		signingKey, requiresTravelRule, kycStatus = &key, &travelRule, &verified
+		payerData = &umaprotocol.CounterPartyDataOptions{}
+		currencies = &[]umaprotocol.Currency{SatsCurrency}
+	}
+
+	response, err := uma.GetLnurlpResponse(
+		*request,
+		h.baseURL()+"/uma/payreq/event/"+strconv.Itoa(event.ID),
+		eventMetadata(event, h.domain),
+		event.PriceSats,
+		event.PriceSats,
+		signingKey,
+		requiresTravelRule,
+		payerData,
+		currencies,
+		kycStatus,
+		nil, // commentCharsAllowed
+		nil, // nostrPubkey
+	)
+	if err != nil {
+		h.logger.Error("Failed to create lnurlp response", "event_id", event.ID, "error", err)
+		writeLnurlError(w, http.StatusInternalServerError, "failed to create lnurlp response")
+		return
+	}
+
+	h.logger.Info("lnurlp response sent", "event_id", event.ID, "uma", request.IsUmaRequest())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleEventPayreq handles pay requests to an event's UMA address: UMA pay
+// requests POSTed by the payer's VASP, and LNURL-pay callbacks with the
+// amount in millisatoshis in the query.
+// GET/POST /uma/payreq/event/{event_id}
+func (h *UmaHandlers) HandleEventPayreq(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["event_id"])
+	if err != nil {
+		writeLnurlError(w, http.StatusBadRequest, "invalid event_id")
+		return
+	}
+	event, ok := h.payableEvent(w, eventID)
+	if !ok {
+		return
+	}
+	invoiceCreator := &eventInvoiceCreator{h: h, event: event}
+
+	if r.Method == http.MethodGet {
+		amountMsats, err := strconv.ParseInt(r.URL.Query().Get("amount"), 10, 64)
+		if err != nil {
+			writeLnurlError(w, http.StatusBadRequest, "invalid amount")
+			return
+		}
+		bolt11, err := invoiceCreator.CreateInvoice(amountMsats, "", nil)
+		if err != nil {
+			h.writeEventInvoiceError(w, event.ID, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pr":     *bolt11,
+			"routes": []interface{}{},
+		})
+		return
+	}
+
+	requestBody, err := readBody(r)
+	if err != nil {
+		writeLnurlError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	payreq, err := uma.ParsePayRequest(requestBody)
+	if err != nil {
+		h.logger.Warn("Failed to parse event pay request", "event_id", event.ID, "error", err)
+		writeLnurlError(w, http.StatusBadRequest, "invalid pay request")
+		return
+	}
+
+	signingKey, err := hex.DecodeString(h.umaSigningPrivKeyHex)
+	if err != nil {
+		h.logger.Error("Failed to decode UMA signing key", "error", err)
+		writeLnurlError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	conversionRate := 1000.0 // msats per sat
+	decimals := 0
+	exchangeFees := int64(0)
+	payeeIdentifier := models.EventUMAAddress(event.ID, h.domain)
+	payreqResponse, err := uma.GetPayReqResponse(
+		*payreq,
+		invoiceCreator,
+		eventMetadata(event, h.domain),
+		payreq.ReceivingCurrencyCode,
+		&decimals,
+		&conversionRate,
+		&exchangeFees,
+		nil,              // receiverChannelUtxos
+		nil,              // receiverNodePubKey
+		nil,              // utxoCallback
+		nil,              // payeeData
+		&signingKey,      // receivingVaspPrivateKey
+		&payeeIdentifier, // payeeIdentifier
+		nil,              // disposable
+		nil,              // successAction
+	)
+	if err != nil {
+		h.writeEventInvoiceError(w, event.ID, err)
+		return
+	}
+
+	h.logger.Info("Event payreq response sent", "event_id", event.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payreqResponse)
+}
+
+// payableEvent loads the event paid through its UMA address, writing a
+// 404 and reporting false when it does not exist or is not on sale.
+// Private events sell only to buyers the event knows, so they take no
+// anonymous payments.
+func (h *UmaHandlers) payableEvent(w http.ResponseWriter, eventID int) (*models.Event, bool) {
+	event, err := h.eventRepo.GetByID(eventID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		writeLnurlError(w, http.StatusNotFound, "unknown UMA address")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch event for UMA address", "event_id", eventID, "error", err)
+		writeLnurlError(w, http.StatusInternalServerError, "failed to fetch event")
+		return nil, false
+	}
+	if !event.IsActive || event.CancelledAt != nil || event.IsPrivate || event.PriceSats <= 0 {
+		writeLnurlError(w, http.StatusNotFound, "unknown UMA address")
+		return nil, false
+	}
+	return event, true
+}
+
+// writeEventInvoiceError writes the error of issuing an event invoice
+func (h *UmaHandlers) writeEventInvoiceError(w http.ResponseWriter, eventID int, err error) {
+	if errors.Is(err, errEventAmountMismatch) {
+		writeLnurlError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, umaservices.ErrPaymentBackendUnavailable) {
+		writeLnurlError(w, http.StatusServiceUnavailable, "payments are temporarily unavailable")
+		return
+	}
+	h.logger.Error("Failed to create event invoice", "event_id", eventID, "error", err)
+	writeLnurlError(w, http.StatusInternalServerError, "failed to create invoice")
+}
+
+var errEventAmountMismatch = errors.New("amount must be the event's ticket price")
+
+// eventInvoiceCreator issues an invoice for the event's price and records
+// it against the event, so the payment hash maps the payment to the event
+type eventInvoiceCreator struct {
+	h     *UmaHandlers
+	event *models.Event
+}
+
+func (c *eventInvoiceCreator) CreateInvoice(amountMsats int64, metadata string, receiverIdentifier *string) (*string, error) {
+	if amountMsats != c.event.PriceSats*1000 {
+		return nil, errEventAmountMismatch
+	}
+	umaAddress := models.EventUMAAddress(c.event.ID, c.h.domain)
+	description := fmt.Sprintf("Event Ticket: %s", c.event.Title)
+	invoice, err := c.h.umaService.CreateUMARequest(umaAddress, c.event.PriceSats, description, c.event.InvoiceExpiry(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := c.event.ID
+	record := &models.UMARequestInvoice{
+		EventID:     &eventID,
+		Source:      models.UMAInvoiceAddress,
+		InvoiceID:   invoice.ID,
+		PaymentHash: invoice.PaymentHash,
+		Bolt11:      invoice.Bolt11,
+		AmountSats:  invoice.AmountSats,
+		Status:      invoice.Status,
+		UMAAddress:  umaAddress,
+		Description: description,
+		ExpiresAt:   invoice.ExpiresAt,
+	}
+	if err := c.h.umaRepo.Create(record); err != nil {
+		return nil, fmt.Errorf("failed to record invoice %s for event %d: %w", invoice.ID, eventID, err)
+	}
+	c.h.logger.Info("Event UMA invoice issued", "event_id", eventID, "invoice_id", invoice.ID)
+	return &invoice.Bolt11, nil
+}
+
+// eventMetadata is the LNURL metadata of payments to an event's UMA address
+func eventMetadata(event *models.Event, domain string) string {
+	metadata, _ := json.Marshal([][]string{
+		{"text/plain", fmt.Sprintf("Event Ticket: %s", event.Title)},
+		{"text/identifier", models.EventUMAIdentifier(event.ID) + "@" + domain},
+	})
+	return string(metadata)
+}
+
+// writeLnurlError writes an error in the LNURL format wallets display
+func writeLnurlError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ERROR", "reason": reason})
+}
+
 // HandlePubKeyRequest serves UMA public keys for signature verification.
 // GET /.well-known/lnurlpubkey
 func (h *UmaHandlers) HandlePubKeyRequest(w http.ResponseWriter, r *http.Request) {
@@ -156,7 +421,7 @@ func (h *UmaHandlers) HandlePubKeyRequest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	twoWeeksFromNow := time.Now().AddDate(0, 0, 14)
+	twoWeeksFromNow := h.clock.Now().AddDate(0, 0, 14)
 	twoWeeksFromNowSec := twoWeeksFromNow.Unix()
 	response, err := uma.GetPubKeyResponse(signingCertChain, encryptionCertChain, &twoWeeksFromNowSec)
 	if err != nil {
@@ -172,18 +437,23 @@ func (h *UmaHandlers) HandlePubKeyRequest(w http.ResponseWriter, r *http.Request
 // HandleUmaConfiguration serves the UMA configuration for this domain.
 // GET/POST /.well-known/uma-configuration
 func (h *UmaHandlers) HandleUmaConfiguration(w http.ResponseWriter, r *http.Request) {
-	scheme := "https"
-	if h.domain == "localhost" || h.domain == "localhost:8080" {
-		scheme = "http"
-	}
 	response := map[string]interface{}{
 		"uma_major_versions":   uma.GetSupportedMajorVersions(),
-		"uma_request_endpoint": fmt.Sprintf("%s://%s/uma/payreq/0", scheme, h.domain),
+		"uma_request_endpoint": h.baseURL() + "/uma/payreq/0",
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// baseURL is the URL UMA callbacks to this domain start with
+func (h *UmaHandlers) baseURL() string {
+	scheme := "https"
+	if h.domain == "localhost" || h.domain == "localhost:8080" {
+		scheme = "http"
+	}
+	return scheme + "://" + h.domain
+}
+
 // existingInvoiceCreator returns a pre-existing bolt11 instead of creating a new one.
 type existingInvoiceCreator struct {
 	bolt11 string
@@ -0,0 +1,113 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lightsparkdev/go-sdk/objects"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestEventUMAAddressPayments(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	umaService := services.NewSimulatedUMAService(0, clk, logger)
+	handler := NewUmaHandlers(store.Payments(), store.Events(), store.UMARequestInvoices(), umaService, clk, logger, "tickets.example.com", "")
+	payments := NewPaymentHandlers(store.Payments(), store.Tickets(), store.Events(), store.UMARequestInvoices(), umaService, nil, nil, nil, nil, nil, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/.well-known/lnurlp/{identifier}", handler.HandleLnurlp).Methods("GET")
+	router.HandleFunc("/uma/payreq/event/{event_id:[0-9]+}", handler.HandleEventPayreq).Methods("GET", "POST")
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	var events []*models.Event
+	for _, title := range []string{"Gig", "Talk", "Private"} {
+		event := &models.Event{Title: title, StartTime: clk.Now().Add(time.Hour), EndTime: clk.Now().Add(2 * time.Hour),
+			Capacity: 10, PriceSats: 1000, IsActive: true, IsPrivate: title == "Private"}
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	talk := events[1]
+
+	rec := get("/.well-known/lnurlp/" + models.EventUMAIdentifier(talk.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for the event's lnurlp, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var lnurlp struct {
+		Callback    string `json:"callback"`
+		MinSendable int64  `json:"minSendable"`
+		Metadata    string `json:"metadata"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&lnurlp); err != nil {
+		t.Fatal(err)
+	}
+	if lnurlp.Callback != fmt.Sprintf("https://tickets.example.com/uma/payreq/event/%d", talk.ID) ||
+		lnurlp.MinSendable != 1_000_000 || !strings.Contains(lnurlp.Metadata, "Talk") {
+		t.Errorf("Expected the lnurlp response of the talk, got %+v", lnurlp)
+	}
+	for _, path := range []string{"/.well-known/lnurlp/event", "/.well-known/lnurlp/event-999",
+		"/.well-known/lnurlp/" + models.EventUMAIdentifier(events[2].ID)} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	callback := strings.TrimPrefix(lnurlp.Callback, "https://tickets.example.com")
+	if rec := get(callback + "?amount=999000"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an amount other than the price, got %d", rec.Code)
+	}
+	rec = get(callback + "?amount=1000000")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for the callback, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payreq struct {
+		PR string `json:"pr"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&payreq); err != nil {
+		t.Fatal(err)
+	}
+
+	issued, err := umaService.CheckPaymentStatus(payreq.PR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice, err := store.UMARequestInvoices().GetByPaymentHash(issued.PaymentHash)
+	if err != nil {
+		t.Fatal("Expected the invoice recorded against the talk:", err)
+	}
+	if invoice.Bolt11 != payreq.PR || *invoice.EventID != talk.ID || invoice.Source != models.UMAInvoiceAddress ||
+		invoice.UMAAddress != models.EventUMAAddress(talk.ID, "tickets.example.com") {
+		t.Errorf("Expected the talk's invoice for %s, got %+v", payreq.PR, invoice)
+	}
+	if _, err := store.UMARequestInvoices().GetByEventID(talk.ID); err == nil {
+		t.Error("Expected invoices issued to payers not to be the event's standing invoice")
+	}
+
+	hash := invoice.PaymentHash
+	incoming := objects.IncomingPayment{Id: "IncomingPayment:1", Status: objects.TransactionStatusSuccess, TransactionHash: &hash}
+	if err := payments.handleIncomingPayment(incoming.Id, incoming, payments.markPaymentPaid); err != nil {
+		t.Fatal(err)
+	}
+	if paid, err := store.UMARequestInvoices().GetByPaymentHash(hash); err != nil || paid.Status != "paid" || *paid.EventID != talk.ID {
+		t.Errorf("Expected the talk's invoice marked paid, got %+v, %v", paid, err)
+	}
+}
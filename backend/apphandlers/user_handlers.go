@@ -2,6 +2,7 @@ package apphandlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,24 +12,43 @@ import (
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 
+	"tickets-by-uma/i18n"
 	"tickets-by-uma/middleware"
 	"tickets-by-uma/models"
 	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
 )
 
 type UserHandlers struct {
 	userRepo  repositories.UserRepository
 	nwcRepo   repositories.NWCConnectionRepository
+	guests    *services.GuestService
+	emails    *services.EmailChangeService
+	lnurlAuth *services.LNURLAuthService
+	passwords *services.PasswordPolicyService
 	logger    *slog.Logger
-	jwtSecret string
+	tokens    *middleware.Tokens
 }
 
-func NewUserHandlers(userRepo repositories.UserRepository, nwcRepo repositories.NWCConnectionRepository, logger *slog.Logger, jwtSecret string) *UserHandlers {
+func NewUserHandlers(
+	userRepo repositories.UserRepository,
+	nwcRepo repositories.NWCConnectionRepository,
+	guests *services.GuestService,
+	emails *services.EmailChangeService,
+	lnurlAuth *services.LNURLAuthService,
+	passwords *services.PasswordPolicyService,
+	logger *slog.Logger,
+	tokens *middleware.Tokens,
+) *UserHandlers {
 	return &UserHandlers{
 		userRepo:  userRepo,
 		nwcRepo:   nwcRepo,
+		guests:    guests,
+		emails:    emails,
+		lnurlAuth: lnurlAuth,
+		passwords: passwords,
 		logger:    logger,
-		jwtSecret: jwtSecret,
+		tokens:    tokens,
 	}
 }
 
@@ -45,21 +65,12 @@ func (h *UserHandlers) HandleCreateUser(w http.ResponseWriter, r *http.Request)
 		middleware.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	h.logger.Info("Creating new user", "email", req.Email)
-
-	// Check if user already exists
-	existingUser, err := h.userRepo.GetByEmail(req.Email)
-	if err != nil {
-		h.logger.Error("Failed to check existing user", "email", req.Email, "error", err)
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check existing user")
+	if err := h.passwords.Check(r.Context(), req.Password, req.Email, req.Name); err != nil {
+		middleware.WriteErrorCode(w, http.StatusBadRequest, models.ErrorCodeWeakPassword, err.Error())
 		return
 	}
 
-	if existingUser != nil {
-		middleware.WriteError(w, http.StatusConflict, "User with this email already exists")
-		return
-	}
+	h.logger.Info("Creating new user", "email", req.Email)
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -69,14 +80,29 @@ func (h *UserHandlers) HandleCreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Create new user
+	// Create new user, in the language they are browsing in unless they
+	// chose one
 	user := &models.User{
 		Email:        req.Email,
 		Name:         req.Name,
 		PasswordHash: string(hashedPassword),
+		Locale:       req.Locale,
+	}
+	if user.Locale == "" {
+		user.Locale = middleware.LocaleOf(w)
 	}
 
-	if err := h.userRepo.Create(user); err != nil {
+	// The unique email constraint decides between concurrent signups
+	err = h.userRepo.Create(user)
+	if errors.Is(err, repositories.ErrConflict) {
+		if existing, err := h.userRepo.GetByEmail(req.Email); err == nil && existing.IsGuest {
+			middleware.WriteError(w, http.StatusConflict, "This email was used for a guest checkout; claim the account with the link sent to it")
+			return
+		}
+		middleware.WriteError(w, http.StatusConflict, "User with this email already exists")
+		return
+	}
+	if err != nil {
 		h.logger.Error("Failed to create user", "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create user")
 		return
@@ -112,18 +138,17 @@ func (h *UserHandlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Get user by email
 	user, err := h.userRepo.GetByEmail(req.Email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Warn("Login failed - user not found", "email", req.Email)
+		middleware.WriteError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch user", "email", req.Email, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
 
-	if user == nil {
-		h.logger.Warn("Login failed - user not found", "email", req.Email)
-		middleware.WriteError(w, http.StatusUnauthorized, "Invalid credentials")
-		return
-	}
-
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		h.logger.Warn("Login failed - invalid password", "email", req.Email)
@@ -132,7 +157,7 @@ func (h *UserHandlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate JWT token
-	token, err := middleware.GenerateToken(user, h.jwtSecret)
+	token, err := h.tokens.Generate(user)
 	if err != nil {
 		h.logger.Error("Failed to generate token", "user_id", user.ID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
@@ -152,6 +177,140 @@ func (h *UserHandlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleClaimAccount turns a guest account into a regular one with the
+// token from its claim link, setting a password, and logs the user in
+func (h *UserHandlers) HandleClaimAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.ClaimAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Token == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Claim token is required")
+		return
+	}
+	if req.Name != "" && len(strings.TrimSpace(req.Name)) < 2 {
+		middleware.WriteError(w, http.StatusBadRequest, "name must be at least 2 characters long")
+		return
+	}
+	// The guest's email is not known until the token is redeemed
+	if err := h.passwords.Check(r.Context(), req.Password, "", req.Name); err != nil {
+		middleware.WriteErrorCode(w, http.StatusBadRequest, models.ErrorCodeWeakPassword, err.Error())
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.logger.Error("Failed to hash password", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to claim account")
+		return
+	}
+
+	user, err := h.guests.Claim(req.Token, string(hashedPassword), req.Name)
+	if errors.Is(err, services.ErrAccountClaimInvalid) {
+		middleware.WriteError(w, http.StatusBadRequest, "Claim link is invalid, expired or already used")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to claim account", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to claim account")
+		return
+	}
+
+	token, err := h.tokens.Generate(user)
+	if err != nil {
+		h.logger.Error("Failed to generate token", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.logger.Info("Guest account claimed", "user_id", user.ID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Account claimed successfully",
+		Data:    models.AuthResponse{Token: token, User: user},
+	})
+}
+
+// HandleResendClaim sends a new claim link to a guest with a paid ticket.
+// It answers the same whether or not the email belongs to a guest.
+func (h *UserHandlers) HandleResendClaim(w http.ResponseWriter, r *http.Request) {
+	var req models.ResendClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Email == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	if err := h.guests.ResendClaim(req.Email); err != nil {
+		h.logger.Error("Failed to resend account claim link", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to send claim link")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "If a guest checkout used this email, a new claim link has been sent to it",
+	})
+}
+
+// HandleJWKS publishes the public keys verifying login tokens, so other
+// services can check them without a shared secret
+func (h *UserHandlers) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	middleware.WriteJSON(w, http.StatusOK, h.tokens.JWKS())
+}
+
+// HandleGetPasswordPolicy describes what new passwords must satisfy, so
+// forms can show hints before submitting
+func (h *UserHandlers) HandleGetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Password policy retrieved successfully",
+		Data:    h.passwords.Policy(),
+	})
+}
+
+// HandleConfirmEmail makes a user's pending email their email with the
+// token from the verification link sent to the new address
+func (h *UserHandlers) HandleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.ConfirmEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Token == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Verification token is required")
+		return
+	}
+
+	user, err := h.emails.Confirm(req.Token)
+	if errors.Is(err, services.ErrEmailChangeInvalid) {
+		middleware.WriteError(w, http.StatusBadRequest, "Verification link is invalid, expired or already used")
+		return
+	}
+	if errors.Is(err, services.ErrEmailTaken) {
+		middleware.WriteError(w, http.StatusConflict, "User with this email already exists")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to confirm email change", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to confirm email")
+		return
+	}
+
+	h.logger.Info("Email change confirmed", "user_id", user.ID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Email changed successfully",
+		Data:    user,
+	})
+}
+
 // HandleGetUser gets a specific user by ID
 func (h *UserHandlers) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -164,17 +323,16 @@ func (h *UserHandlers) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Fetching user", "user_id", userID)
 
 	user, err := h.userRepo.GetByID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch user", "user_id", userID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
 
-	if user == nil {
-		middleware.WriteError(w, http.StatusNotFound, "User not found")
-		return
-	}
-
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 		Message: "User retrieved successfully",
 		Data:    user,
@@ -206,35 +364,38 @@ func (h *UserHandlers) HandleUpdateUser(w http.ResponseWriter, r *http.Request)
 
 	// Get existing user
 	user, err := h.userRepo.GetByID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch user for update", "user_id", userID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
 
-	if user == nil {
-		middleware.WriteError(w, http.StatusNotFound, "User not found")
-		return
-	}
-
 	// Check if email is being changed and if it conflicts with existing user
-	if req.Email != user.Email {
+	emailChanged := req.Email != user.Email
+	if emailChanged {
 		existingUser, err := h.userRepo.GetByEmail(req.Email)
-		if err != nil {
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
 			h.logger.Error("Failed to check existing user", "email", req.Email, "error", err)
 			middleware.WriteError(w, http.StatusInternalServerError, "Failed to check existing user")
 			return
 		}
 
-		if existingUser != nil && existingUser.ID != userID {
+		if err == nil && existingUser.ID != userID {
 			middleware.WriteError(w, http.StatusConflict, "User with this email already exists")
 			return
 		}
 	}
 
-	// Update user fields
-	user.Email = req.Email
+	// Update user fields. A new email stays pending until it is confirmed
+	// with the link sent to it.
 	user.Name = req.Name
+	if req.Locale != "" {
+		user.Locale = req.Locale
+	}
 
 	if err := h.userRepo.Update(user); err != nil {
 		h.logger.Error("Failed to update user", "user_id", userID, "error", err)
@@ -242,10 +403,21 @@ func (h *UserHandlers) HandleUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	message := "User updated successfully"
+	if emailChanged {
+		if err := h.emails.Request(user, req.Email); err != nil {
+			h.logger.Error("Failed to request email change", "user_id", userID, "error", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to send email verification")
+			return
+		}
+		h.logger.Info("Email change requested", "user_id", userID)
+		message = "User updated; confirm the new email with the link sent to it"
+	}
+
 	h.logger.Info("User updated successfully", "user_id", userID)
 
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
-		Message: "User updated successfully",
+		Message: message,
 		Data:    user,
 	})
 }
@@ -262,18 +434,17 @@ func (h *UserHandlers) HandleDeleteUser(w http.ResponseWriter, r *http.Request)
 	h.logger.Info("Deleting user", "user_id", userID)
 
 	// Check if user exists
-	user, err := h.userRepo.GetByID(userID)
+	_, err = h.userRepo.GetByID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch user for deletion", "user_id", userID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
 
-	if user == nil {
-		middleware.WriteError(w, http.StatusNotFound, "User not found")
-		return
-	}
-
 	if err := h.userRepo.Delete(userID); err != nil {
 		h.logger.Error("Failed to delete user", "user_id", userID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete user")
@@ -299,17 +470,16 @@ func (h *UserHandlers) HandleGetCurrentUser(w http.ResponseWriter, r *http.Reque
 
 	// Get fresh user data from database
 	freshUser, err := h.userRepo.GetByID(user.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to fetch current user", "user_id", user.ID, "error", err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
 
-	if freshUser == nil {
-		middleware.WriteError(w, http.StatusNotFound, "User not found")
-		return
-	}
-
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 		Message: "Current user retrieved successfully",
 		Data:    freshUser,
@@ -365,12 +535,7 @@ func (h *UserHandlers) HandleGetNWCConnection(w http.ResponseWriter, r *http.Req
 	}
 
 	conn, err := h.nwcRepo.GetByUserID(user.ID)
-	if err != nil {
-		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check wallet connection")
-		return
-	}
-
-	if conn == nil {
+	if errors.Is(err, repositories.ErrNotFound) {
 		middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 			Message: "No wallet connection found",
 			Data: map[string]interface{}{
@@ -379,17 +544,194 @@ func (h *UserHandlers) HandleGetNWCConnection(w http.ResponseWriter, r *http.Req
 		})
 		return
 	}
+	if err != nil {
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to check wallet connection")
+		return
+	}
 
 	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
 		Message: "Wallet connection found",
 		Data: map[string]interface{}{
-			"connected":  true,
-			"expires_at": conn.ExpiresAt,
-			"created_at": conn.CreatedAt,
+			"connected":      true,
+			"connection_uri": models.MaskNWCConnectionURI(conn.ConnectionURI),
+			"expires_at":     conn.ExpiresAt,
+			"created_at":     conn.CreatedAt,
 		},
 	})
 }
 
+// HandleChangePassword replaces the authenticated user's password. The
+// change signs out every session, so a new token is returned for this one.
+func (h *UserHandlers) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	current := middleware.GetUserFromContext(r.Context())
+	if current == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.CurrentPassword == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Current password is required")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(current.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch user", "user_id", current.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		h.logger.Warn("Password change failed - invalid current password", "user_id", user.ID)
+		middleware.WriteError(w, http.StatusForbidden, "Current password is incorrect")
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		middleware.WriteErrorCode(w, http.StatusBadRequest, models.ErrorCodeWeakPassword, "new password must differ from the current password")
+		return
+	}
+	if err := h.passwords.Check(r.Context(), req.NewPassword, user.Email, user.Name); err != nil {
+		middleware.WriteErrorCode(w, http.StatusBadRequest, models.ErrorCodeWeakPassword, err.Error())
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		h.logger.Error("Failed to hash password", "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+
+	user, err = h.userRepo.ChangePassword(user.ID, string(hashedPassword))
+	if err != nil {
+		h.logger.Error("Failed to change password", "user_id", current.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+
+	token, err := h.tokens.Generate(user)
+	if err != nil {
+		h.logger.Error("Failed to generate token", "user_id", user.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.logger.Info("Password changed", "user_id", user.ID)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Password changed successfully",
+		Data:    models.AuthResponse{Token: token, User: user},
+	})
+}
+
+// HandleGetCredentials lists the ways the authenticated user can log in
+func (h *UserHandlers) HandleGetCredentials(w http.ResponseWriter, r *http.Request) {
+	current := middleware.GetUserFromContext(r.Context())
+	if current == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(current.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch user", "user_id", current.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Login methods retrieved successfully",
+		Data:    credentialsOf(user),
+	})
+}
+
+// HandleDeleteCredential removes one of the authenticated user's login
+// methods. The last one cannot be removed, as the user could no longer log
+// in. Removing a method signs out every session.
+func (h *UserHandlers) HandleDeleteCredential(w http.ResponseWriter, r *http.Request) {
+	current := middleware.GetUserFromContext(r.Context())
+	if current == nil {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+	method := mux.Vars(r)["method"]
+
+	user, err := h.userRepo.GetByID(current.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch user", "user_id", current.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	var found *models.Credential
+	for _, credential := range credentialsOf(user) {
+		if credential.Method == method {
+			found = &credential
+			break
+		}
+	}
+	if found == nil {
+		middleware.WriteError(w, http.StatusNotFound, "Login method not found")
+		return
+	}
+	if !found.Removable {
+		middleware.WriteErrorCode(w, http.StatusConflict, models.ErrorCodeLastCredential, "Cannot remove your only login method")
+		return
+	}
+
+	switch method {
+	case models.CredentialPassword:
+		_, err = h.userRepo.ChangePassword(user.ID, "")
+	case models.CredentialLNURLAuth:
+		_, err = h.lnurlAuth.Unlink(user.ID)
+	}
+	if err != nil {
+		h.logger.Error("Failed to remove login method", "user_id", user.ID, "method", method, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to remove login method")
+		return
+	}
+
+	h.logger.Info("Login method removed", "user_id", user.ID, "method", method)
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Login method removed successfully",
+	})
+}
+
+// credentialsOf lists the ways user can log in: a password and a
+// Lightning wallet (LNURL-auth)
+func credentialsOf(user *models.User) []models.Credential {
+	credentials := []models.Credential{}
+	if user.PasswordHash != "" {
+		credentials = append(credentials, models.Credential{Method: models.CredentialPassword})
+	}
+	if user.LNURLAuthKey != nil {
+		credentials = append(credentials, models.Credential{Method: models.CredentialLNURLAuth})
+	}
+	for i := range credentials {
+		credentials[i].Removable = len(credentials) > 1
+	}
+	return credentials
+}
+
 // validateCreateUserRequest validates the create user request
 func (h *UserHandlers) validateCreateUserRequest(req *models.CreateUserRequest) error {
 	if req.Email == "" {
@@ -414,9 +756,12 @@ func (h *UserHandlers) validateCreateUserRequest(req *models.CreateUserRequest)
 		return fmt.Errorf("name must be at least 2 characters long")
 	}
 
-	// Password minimum length
-	if len(req.Password) < 8 {
-		return fmt.Errorf("password must be at least 8 characters long")
+	if req.Locale != "" {
+		locale, ok := i18n.Normalize(req.Locale)
+		if !ok {
+			return fmt.Errorf("locale is not supported")
+		}
+		req.Locale = locale
 	}
 
 	return nil
@@ -0,0 +1,373 @@
+package apphandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// linkNotifier keeps the claim and verification links sent to each user
+// and the kinds of everything sent to their own address
+type linkNotifier struct {
+	links map[int][]string
+	kinds map[int][]string
+}
+
+func newLinkNotifier() *linkNotifier {
+	return &linkNotifier{links: make(map[int][]string), kinds: make(map[int][]string)}
+}
+
+func (n *linkNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	switch notification.Key {
+	case i18n.AccountClaim, i18n.EmailVerify:
+		n.links[userID] = append(n.links[userID], notification.Args[1].(string))
+	}
+	if notification.Address == "" {
+		n.kinds[userID] = append(n.kinds[userID], notification.Key)
+	}
+	return nil
+}
+
+// newTestPasswordPolicy returns the default password policy, without
+// breach checks
+func newTestPasswordPolicy(logger *slog.Logger) *services.PasswordPolicyService {
+	policy := (&config.Config{PasswordMinLength: 8}).PasswordPolicy()
+	return services.NewPasswordPolicyService(policy, "", nil, logger)
+}
+
+func TestGuestCheckout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := services.NewSettingsService(store.Settings(), logger)
+	notifier := newLinkNotifier()
+	guests := services.NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	ticketRepo := guests.Tickets(store.Tickets())
+	tickets := NewTicketHandlers(ticketRepo, store.Events(), store.Payments(), store.UMARequestInvoices(), store.NWCConnections(), store.AddOns(), store.FormFields(), store.EventAccess(), store.Attestations(), store.Receipts(), store.Orders(),
+		services.NewSimulatedUMAService(0, clk, logger), settings, nil, nil, nil, nil, guests, nil, nil, nil, nil, config.PriceLimits{}, time.Time{}, clk, logger, "localhost")
+	users := NewUserHandlers(store.Users(), store.NWCConnections(), guests, nil, nil, newTestPasswordPolicy(logger), logger, middleware.NewTokens(func() string { return "test-secret" }, nil, time.Time{}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/purchase", tickets.HandlePurchaseTicket).Methods("POST")
+	router.HandleFunc("/api/users", users.HandleCreateUser).Methods("POST")
+	router.HandleFunc("/api/users/login", users.HandleLogin).Methods("POST")
+	router.HandleFunc("/api/users/claim", users.HandleClaimAccount).Methods("POST")
+	router.HandleFunc("/api/users/claim/resend", users.HandleResendClaim).Methods("POST")
+
+	do := func(path string, body interface{}) (int, string, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", path, bytes.NewReader(payload)))
+		var resp struct {
+			Code string          `json:"error_code"`
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Code, resp.Data
+	}
+
+	concert := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 10000, IsActive: true}
+	if err := store.Events().Create(concert); err != nil {
+		t.Fatal(err)
+	}
+	member := &models.User{Email: "member@example.com", Name: "Member", PasswordHash: "hash"}
+	if err := store.Users().Create(member); err != nil {
+		t.Fatal(err)
+	}
+
+	purchase := models.TicketPurchaseRequest{EventID: concert.ID, Email: "member@example.com", UMAAddress: "$buyer@wallet.example.com"}
+	if status, code, _ := do("/api/tickets/purchase", purchase); status != http.StatusConflict || code != models.ErrorCodeAccountExists {
+		t.Errorf("Expected a registered email to need a login, got %d %s", status, code)
+	}
+	purchase.Email = "guest"
+	if status, _, _ := do("/api/tickets/purchase", purchase); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid email to be rejected, got %d", status)
+	}
+
+	purchase.Email = "guest@example.com"
+	status, _, data := do("/api/tickets/purchase", purchase)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201 for a guest purchase, got %d %s", status, data)
+	}
+	guest, err := store.Users().GetByEmail("guest@example.com")
+	if err != nil || !guest.IsGuest {
+		t.Fatalf("Expected a guest user, got %+v (%v)", guest, err)
+	}
+	owned, err := store.Tickets().GetByUserID(guest.ID)
+	if err != nil || len(owned) != 1 || owned[0].PaymentStatus != "pending" {
+		t.Fatalf("Expected the guest's ticket to await payment, got %+v (%v)", owned, err)
+	}
+
+	// The claim link is sent once the ticket is paid
+	if len(notifier.links[guest.ID]) != 0 {
+		t.Fatalf("Expected no claim link before payment, got %v", notifier.links[guest.ID])
+	}
+	if err := ticketRepo.UpdatePaymentStatus(owned[0].ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.links[guest.ID]) != 1 {
+		t.Fatalf("Expected a claim link once paid, got %v", notifier.links[guest.ID])
+	}
+	token := strings.TrimPrefix(notifier.links[guest.ID][0], "https://tickets.example.com/claim?token=")
+
+	// Signing up with the email points at the claim link instead
+	signup := models.CreateUserRequest{Email: "guest@example.com", Name: "Guest", Password: "password123"}
+	if status, _, _ := do("/api/users", signup); status != http.StatusConflict {
+		t.Errorf("Expected signup with a guest email to conflict, got %d", status)
+	}
+	login := models.LoginRequest{Email: "guest@example.com", Password: "password123"}
+	if status, _, _ := do("/api/users/login", login); status != http.StatusUnauthorized {
+		t.Errorf("Expected an unclaimed guest to be unable to log in, got %d", status)
+	}
+
+	for _, email := range []string{"guest@example.com", "nobody@example.com"} {
+		if status, _, _ := do("/api/users/claim/resend", models.ResendClaimRequest{Email: email}); status != http.StatusOK {
+			t.Errorf("Expected 200 resending to %s, got %d", email, status)
+		}
+	}
+	if len(notifier.links[guest.ID]) != 2 {
+		t.Fatalf("Expected a new claim link, got %v", notifier.links[guest.ID])
+	}
+	if status, _, _ := do("/api/users/claim", models.ClaimAccountRequest{Token: token, Password: "password123"}); status != http.StatusBadRequest {
+		t.Errorf("Expected the replaced link to be rejected, got %d", status)
+	}
+	token = strings.TrimPrefix(notifier.links[guest.ID][1], "https://tickets.example.com/claim?token=")
+	if status, _, _ := do("/api/users/claim", models.ClaimAccountRequest{Token: token, Password: "short"}); status != http.StatusBadRequest {
+		t.Errorf("Expected a short password to be rejected, got %d", status)
+	}
+
+	status, _, data = do("/api/users/claim", models.ClaimAccountRequest{Token: token, Password: "password123", Name: "Guest Buyer"})
+	var auth models.AuthResponse
+	json.Unmarshal(data, &auth)
+	if status != http.StatusOK || auth.Token == "" || auth.User == nil || auth.User.IsGuest || auth.User.Name != "Guest Buyer" {
+		t.Fatalf("Expected the account to be claimed, got %d %s", status, data)
+	}
+	if status, _, _ := do("/api/users/login", login); status != http.StatusOK {
+		t.Errorf("Expected the claimed account to log in, got %d", status)
+	}
+	if owned, err := store.Tickets().GetByUserID(auth.User.ID); err != nil || len(owned) != 1 || owned[0].PaymentStatus != "paid" {
+		t.Errorf("Expected the claimed account to keep its ticket, got %+v (%v)", owned, err)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := newLinkNotifier()
+	emails := services.NewEmailChangeService(store.EmailChanges(), notifier, "tickets.example.com", clk, logger)
+	users := NewUserHandlers(store.Users(), store.NWCConnections(), nil, emails, nil, newTestPasswordPolicy(logger), logger, middleware.NewTokens(func() string { return "test-secret" }, nil, time.Time{}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users/{id:[0-9]+}", users.HandleUpdateUser).Methods("PUT")
+	router.HandleFunc("/api/users/email/confirm", users.HandleConfirmEmail).Methods("POST")
+
+	do := func(method, path string, body interface{}) (int, *models.User) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		var resp struct {
+			Data *models.User `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	user := &models.User{Email: "old@example.com", Name: "Mover"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/users/" + strconv.Itoa(user.ID)
+
+	// Changing the email leaves it pending until the new address confirms it
+	update := models.CreateUserRequest{Email: "new@example.com", Name: "Mover Renamed", Password: "password123"}
+	status, updated := do("PUT", path, update)
+	if status != http.StatusOK || updated.Email != "old@example.com" || updated.Name != "Mover Renamed" ||
+		updated.PendingEmail == nil || *updated.PendingEmail != "new@example.com" {
+		t.Fatalf("Expected the new email to be pending, got %d %+v", status, updated)
+	}
+	if stored, _ := store.Users().GetByEmail("old@example.com"); stored == nil || stored.ID != user.ID {
+		t.Errorf("Expected the old email to stay in use, got %+v", stored)
+	}
+	if len(notifier.links[user.ID]) != 1 || len(notifier.kinds[user.ID]) != 1 || notifier.kinds[user.ID][0] != i18n.EmailChange {
+		t.Fatalf("Expected a link to the new address and a notice to the old one, got %v %v", notifier.links, notifier.kinds)
+	}
+
+	// Saving other fields does not send another link
+	update.Email = "old@example.com"
+	if status, _ := do("PUT", path, update); status != http.StatusOK || len(notifier.links[user.ID]) != 1 {
+		t.Errorf("Expected no new link when the email is unchanged, got %d %v", status, notifier.links[user.ID])
+	}
+
+	if status, _ := do("POST", "/api/users/email/confirm", models.ConfirmEmailRequest{Token: "wrong"}); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown token to be rejected, got %d", status)
+	}
+	token := strings.TrimPrefix(notifier.links[user.ID][0], "https://tickets.example.com/confirm-email?token=")
+	status, confirmed := do("POST", "/api/users/email/confirm", models.ConfirmEmailRequest{Token: token})
+	if status != http.StatusOK || confirmed.Email != "new@example.com" || confirmed.PendingEmail != nil {
+		t.Fatalf("Expected the email to change, got %d %+v", status, confirmed)
+	}
+	if status, _ := do("POST", "/api/users/email/confirm", models.ConfirmEmailRequest{Token: token}); status != http.StatusBadRequest {
+		t.Errorf("Expected the link to be single-use, got %d", status)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	secret := func() string { return "test-secret" }
+	users := NewUserHandlers(store.Users(), store.NWCConnections(), nil, nil, nil, newTestPasswordPolicy(logger), logger, middleware.NewTokens(secret, nil, time.Time{}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users/login", users.HandleLogin).Methods("POST")
+	router.HandleFunc("/api/users/password-policy", users.HandleGetPasswordPolicy).Methods("GET")
+	protected := router.PathPrefix("/api").Subrouter()
+	protected.Use(middleware.RotatingAuthMiddleware(secret, func(userID int) (int, error) {
+		user, err := store.Users().GetByID(userID)
+		if err != nil {
+			return 0, err
+		}
+		return user.SessionVersion, nil
+	}))
+	protected.HandleFunc("/users/me", users.HandleGetCurrentUser).Methods("GET")
+	protected.HandleFunc("/users/me/password", users.HandleChangePassword).Methods("POST")
+	protected.HandleFunc("/users/me/credentials", users.HandleGetCredentials).Methods("GET")
+	protected.HandleFunc("/users/me/credentials/{method}", users.HandleDeleteCredential).Methods("DELETE")
+
+	do := func(method, path, token string, body interface{}) (int, string, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Code string          `json:"error_code"`
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Code, resp.Data
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Email: "changer@example.com", Name: "Changer", PasswordHash: string(hash)}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	login := func(password string) string {
+		t.Helper()
+		status, _, data := do("POST", "/api/users/login", "", models.LoginRequest{Email: user.Email, Password: password})
+		var auth models.AuthResponse
+		json.Unmarshal(data, &auth)
+		if status != http.StatusOK {
+			t.Fatalf("Expected to log in, got %d", status)
+		}
+		return auth.Token
+	}
+	session, other := login("password123"), login("password123")
+
+	status, _, data := do("GET", "/api/users/password-policy", "", nil)
+	var policy config.PasswordPolicy
+	json.Unmarshal(data, &policy)
+	if status != http.StatusOK || policy.MinLength != 8 || policy.MaxBytes != config.MaxPasswordBytes || policy.BreachCheck {
+		t.Errorf("Expected the default password policy, got %d %s", status, data)
+	}
+
+	if status, _, _ := do("POST", "/api/users/me/password", session, models.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "n3w-Passphrase"}); status != http.StatusForbidden {
+		t.Errorf("Expected a wrong current password to be refused, got %d", status)
+	}
+	for _, weak := range []string{"short", "password123", "CHANGER@example.com", "aaaaaaaaaa", "12345678901"} {
+		if status, code, _ := do("POST", "/api/users/me/password", session, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: weak}); status != http.StatusBadRequest || code != models.ErrorCodeWeakPassword {
+			t.Errorf("Expected %q to be too weak, got %d %s", weak, status, code)
+		}
+	}
+
+	status, _, data = do("POST", "/api/users/me/password", session, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "n3w-Passphrase"})
+	var auth models.AuthResponse
+	json.Unmarshal(data, &auth)
+	if status != http.StatusOK || auth.Token == "" {
+		t.Fatalf("Expected the password to change, got %d %s", status, data)
+	}
+
+	// Every earlier session is signed out; the returned token carries on
+	for _, token := range []string{session, other} {
+		if status, _, _ := do("GET", "/api/users/me", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("Expected an earlier session to be revoked, got %d", status)
+		}
+	}
+	if status, _, _ := do("GET", "/api/users/me", auth.Token, nil); status != http.StatusOK {
+		t.Errorf("Expected the new token to work, got %d", status)
+	}
+	login("n3w-Passphrase")
+
+	status, _, data = do("GET", "/api/users/me/credentials", auth.Token, nil)
+	var credentials []models.Credential
+	json.Unmarshal(data, &credentials)
+	if status != http.StatusOK || len(credentials) != 1 || credentials[0].Method != models.CredentialPassword || credentials[0].Removable {
+		t.Fatalf("Expected the password as the only login method, got %d %s", status, data)
+	}
+	if status, _, _ := do("DELETE", "/api/users/me/credentials/nostr", auth.Token, nil); status != http.StatusNotFound {
+		t.Errorf("Expected an unlinked method to be missing, got %d", status)
+	}
+	if status, code, _ := do("DELETE", "/api/users/me/credentials/password", auth.Token, nil); status != http.StatusConflict || code != models.ErrorCodeLastCredential {
+		t.Errorf("Expected the only login method to stay, got %d %s", status, code)
+	}
+}
+
+func TestHandleCreateUserConcurrentSignups(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clock.System())
+	users := NewUserHandlers(store.Users(), store.NWCConnections(), nil, nil, nil, newTestPasswordPolicy(logger), logger, middleware.NewTokens(func() string { return "test-secret" }, nil, time.Time{}))
+
+	// Every signup passes validation at once; the unique email decides
+	const signups = 5
+	statuses := make(chan int, signups)
+	var wg sync.WaitGroup
+	for i := 0; i < signups; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			payload, _ := json.Marshal(models.CreateUserRequest{Email: "race@example.com", Name: "Racer", Password: "password123"})
+			rec := httptest.NewRecorder()
+			users.HandleCreateUser(rec, httptest.NewRequest("POST", "/api/users", bytes.NewReader(payload)))
+			statuses <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != signups-1 {
+		t.Errorf("Expected one signup created and the rest conflicting, got %v", counts)
+	}
+}
@@ -0,0 +1,219 @@
+package apphandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+// WalletHandlers lets buyers claim tickets into a mobile wallet and door
+// scanners check those tickets in with a device-signed challenge
+type WalletHandlers struct {
+	ticketRepo repositories.TicketRepository
+	eventRepo  repositories.EventRepository
+	wallet     *services.WalletService
+	checkIns   *services.CheckInService
+	clock      clock.Clock
+	logger     *slog.Logger
+}
+
+func NewWalletHandlers(
+	ticketRepo repositories.TicketRepository,
+	eventRepo repositories.EventRepository,
+	wallet *services.WalletService,
+	checkIns *services.CheckInService,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *WalletHandlers {
+	return &WalletHandlers{
+		ticketRepo: ticketRepo,
+		eventRepo:  eventRepo,
+		wallet:     wallet,
+		checkIns:   checkIns,
+		clock:      clk,
+		logger:     logger,
+	}
+}
+
+// HandleOfferClaim issues a claim URI for one of the caller's paid tickets
+func (h *WalletHandlers) HandleOfferClaim(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(ticketID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+	// Someone else's ticket is reported as missing rather than forbidden
+	user := middleware.GetUserFromContext(r.Context())
+	if ticket == nil || user == nil || user.ID != ticket.UserID {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	if ticket.PaymentStatus != "paid" {
+		middleware.WriteError(w, http.StatusConflict, "Only paid tickets can be added to a wallet")
+		return
+	}
+
+	uri, expiresAt, err := h.wallet.OfferClaim(ticket)
+	if err != nil {
+		h.logger.Error("Failed to offer wallet claim", "ticket_id", ticket.ID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create claim link")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Wallet claim link created successfully",
+		Data: map[string]interface{}{
+			"ticket_id":  ticket.ID,
+			"claim_uri":  uri,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// HandleClaim binds a ticket to a wallet device. The secret may come from
+// the request body or, as in the claim URI, the query string.
+func (h *WalletHandlers) HandleClaim(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	var req models.WalletClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Secret == "" {
+		req.Secret = r.URL.Query().Get("secret")
+	}
+	if req.Secret == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Claim secret is required")
+		return
+	}
+	if req.DevicePublicKey == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Device public key is required")
+		return
+	}
+
+	claim, err := h.wallet.Claim(ticketID, req.Secret, req.DevicePublicKey)
+	switch {
+	case errors.Is(err, services.ErrInvalidDeviceKey):
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrClaimInvalid):
+		middleware.WriteError(w, http.StatusForbidden, "Claim link is invalid or has expired")
+		return
+	case err != nil:
+		h.logger.Error("Failed to claim ticket", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to claim ticket")
+		return
+	}
+
+	h.logger.Info("Ticket claimed into wallet", "ticket_id", ticketID)
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Ticket claimed successfully",
+		Data:    claim,
+	})
+}
+
+// HandleCheckInChallenge issues the challenge a scanner asks the claiming
+// device to sign
+func (h *WalletHandlers) HandleCheckInChallenge(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid ticket ID")
+		return
+	}
+
+	challenge, expiresAt, err := h.wallet.IssueChallenge(ticketID)
+	if errors.Is(err, services.ErrNotClaimed) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket has not been claimed into a wallet")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to issue check-in challenge", "ticket_id", ticketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to issue challenge")
+		return
+	}
+
+	middleware.WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Check-in challenge issued successfully",
+		Data: map[string]interface{}{
+			"ticket_id":  ticketID,
+			"challenge":  challenge,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// HandleValidateDevice validates a wallet-claimed ticket for event access
+// from a challenge signed by the claiming device
+func (h *WalletHandlers) HandleValidateDevice(w http.ResponseWriter, r *http.Request) {
+	var req models.DeviceValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.TicketID <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "Valid ticket ID is required")
+		return
+	}
+	if !scannerEvent(w, r, &req.EventID) {
+		return
+	}
+	if req.EventID <= 0 {
+		middleware.WriteError(w, http.StatusBadRequest, "Valid event ID is required")
+		return
+	}
+	if req.Challenge == "" || req.Signature == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "Challenge and signature are required")
+		return
+	}
+
+	err := h.wallet.VerifyCheckIn(req.TicketID, req.Challenge, req.Signature)
+	switch {
+	case errors.Is(err, services.ErrNotClaimed):
+		middleware.WriteError(w, http.StatusNotFound, "Ticket has not been claimed into a wallet")
+		return
+	case errors.Is(err, services.ErrChallengeInvalid), errors.Is(err, services.ErrDeviceSignature):
+		h.logger.Warn("Rejected device check-in", "ticket_id", req.TicketID, "error", err)
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("Failed to verify device check-in", "ticket_id", req.TicketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to verify check-in")
+		return
+	}
+
+	ticket, err := h.ticketRepo.GetByID(req.TicketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ticket not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch ticket", "ticket_id", req.TicketID, "error", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to fetch ticket")
+		return
+	}
+
+	admitTicket(w, r, ticket, req.EventID, h.eventRepo, h.checkIns, h.clock, h.logger)
+}
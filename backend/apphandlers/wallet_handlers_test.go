@@ -0,0 +1,149 @@
+package apphandlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/middleware"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/services"
+)
+
+func TestWalletHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	wallet := services.NewWalletService(store.WalletClaims(), func() string { return "test-secret" }, "tickets.example.com", clk)
+	handler := NewWalletHandlers(store.Tickets(), store.Events(), wallet, nil, clk, logger)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/tickets/validate-device", handler.HandleValidateDevice).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/wallet-claim", handler.HandleOfferClaim).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/claim", handler.HandleClaim).Methods("POST")
+	router.HandleFunc("/api/tickets/{id:[0-9]+}/checkin-challenge", handler.HandleCheckInChallenge).Methods("POST")
+
+	do := func(path string, user *models.User, body interface{}) (int, json.RawMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(time.Hour), EndTime: clk.Now().Add(3 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	buyer := &models.User{ID: 7}
+	var tickets []*models.Ticket
+	for i, status := range []string{"paid", "pending"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "WALLET-" + strconv.Itoa(i), PaymentStatus: "pending"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Tickets().UpdatePaymentStatus(ticket.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	ticketPath := func(ticket *models.Ticket, action string) string {
+		return "/api/tickets/" + strconv.Itoa(ticket.ID) + "/" + action
+	}
+
+	if status, _ := do(ticketPath(tickets[0], "wallet-claim"), &models.User{ID: 8}, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for someone else's ticket, got %d", status)
+	}
+	if status, _ := do(ticketPath(tickets[1], "wallet-claim"), buyer, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 for an unpaid ticket, got %d", status)
+	}
+
+	status, data := do(ticketPath(tickets[0], "wallet-claim"), buyer, nil)
+	var offer struct {
+		ClaimURI string `json:"claim_uri"`
+	}
+	json.Unmarshal(data, &offer)
+	uri, err := url.Parse(offer.ClaimURI)
+	if status != http.StatusOK || err != nil || uri.Scheme != services.ClaimURIScheme {
+		t.Fatalf("Expected a claim URI, got %d %s", status, data)
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{5}, 64)))
+	claim := models.WalletClaimRequest{DevicePublicKey: base64.StdEncoding.EncodeToString(pub)}
+	if status, _ := do(ticketPath(tickets[0], "claim"), nil, claim); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a secret, got %d", status)
+	}
+	if status, _ := do(ticketPath(tickets[0], "claim")+"?secret=wrong", nil, claim); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a wrong secret, got %d", status)
+	}
+	// Wallets post to the claim URI as-is, secret in the query string
+	if status, data := do(ticketPath(tickets[0], "claim")+"?"+uri.RawQuery, nil, claim); status != http.StatusOK {
+		t.Fatalf("Expected the ticket to be claimed, got %d %s", status, data)
+	}
+
+	if status, _ := do(ticketPath(tickets[1], "checkin-challenge"), nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unclaimed ticket, got %d", status)
+	}
+	challenge := func() string {
+		t.Helper()
+		status, data := do(ticketPath(tickets[0], "checkin-challenge"), nil, nil)
+		var resp struct {
+			Challenge string `json:"challenge"`
+		}
+		json.Unmarshal(data, &resp)
+		if status != http.StatusOK || resp.Challenge == "" {
+			t.Fatalf("Expected a challenge, got %d %s", status, data)
+		}
+		return resp.Challenge
+	}
+	validate := func(challenge string, key ed25519.PrivateKey) int {
+		status, _ := do("/api/tickets/validate-device", nil, models.DeviceValidationRequest{
+			TicketID:  tickets[0].ID,
+			EventID:   event.ID,
+			Challenge: challenge,
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(challenge))),
+		})
+		return status
+	}
+
+	// Before the event starts the device proof holds but admission does not
+	if status := validate(challenge(), priv); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 before the event starts, got %d", status)
+	}
+
+	clk.Advance(2 * time.Hour)
+	_, otherKey, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{6}, 64)))
+	if status := validate(challenge(), otherKey); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for another device's signature, got %d", status)
+	}
+	signed := challenge()
+	if status := validate(signed, priv); status != http.StatusOK {
+		t.Errorf("Expected the device check-in to pass, got %d", status)
+	}
+	if status := validate(signed, priv); status != http.StatusBadRequest {
+		t.Errorf("Expected a replayed challenge to be rejected, got %d", status)
+	}
+}
@@ -0,0 +1,306 @@
+// Package cashu decodes Cashu ecash tokens and melts them at a mint to pay
+// Lightning invoices (NUT-05). Tokens are accepted in both serializations
+// wallets send:
+//
+//	cashuA<base64url of the JSON token>   (V3, NUT-00)
+//	cashuB<base64url of the CBOR token>   (V4, NUT-00)
+//
+// A token is a bearer instrument: whoever holds it can spend it, so tokens
+// must never be logged or stored.
+package cashu
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Token serialization prefixes
+const (
+	prefixV3 = "cashuA"
+	prefixV4 = "cashuB"
+)
+
+// UnitSat is the only unit tokens are accepted in
+const UnitSat = "sat"
+
+// ErrInvalidToken is returned for tokens that cannot be decoded
+var ErrInvalidToken = errors.New("invalid cashu token")
+
+// Proof is one ecash note: Amount sats signed by the mint's keyset ID
+type Proof struct {
+	Amount  uint64 `json:"amount"`
+	ID      string `json:"id"`
+	Secret  string `json:"secret"`
+	C       string `json:"C"`
+	Witness string `json:"witness,omitempty"`
+}
+
+// Token is ecash issued by a single mint
+type Token struct {
+	Mint   string
+	Unit   string
+	Memo   string
+	Proofs []Proof
+}
+
+// Amount totals the token's proofs
+func (t *Token) Amount() (uint64, error) {
+	var total uint64
+	for _, proof := range t.Proofs {
+		if proof.Amount > math.MaxUint64-total {
+			return 0, fmt.Errorf("%w: amount overflows", ErrInvalidToken)
+		}
+		total += proof.Amount
+	}
+	return total, nil
+}
+
+// Decode parses a serialized token. Tokens must hold proofs of a single
+// mint, in sats.
+func Decode(s string) (*Token, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "cashu:"))
+	var token *Token
+	var err error
+	switch {
+	case strings.HasPrefix(s, prefixV3):
+		token, err = decodeV3(s[len(prefixV3):])
+	case strings.HasPrefix(s, prefixV4):
+		token, err = decodeV4(s[len(prefixV4):])
+	default:
+		return nil, fmt.Errorf("%w: expected a cashuA or cashuB token", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Unit == "" {
+		token.Unit = UnitSat
+	}
+	if token.Unit != UnitSat {
+		return nil, fmt.Errorf("%w: unit %q is not supported", ErrInvalidToken, token.Unit)
+	}
+	if token.Mint == "" || len(token.Proofs) == 0 {
+		return nil, fmt.Errorf("%w: no mint or proofs", ErrInvalidToken)
+	}
+	for _, proof := range token.Proofs {
+		if proof.Amount == 0 || proof.ID == "" || proof.Secret == "" || proof.C == "" {
+			return nil, fmt.Errorf("%w: incomplete proof", ErrInvalidToken)
+		}
+	}
+	if _, err := token.Amount(); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// decodeBase64 accepts the URL-safe and standard alphabets, padded or not,
+// as wallets use both
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return data, nil
+}
+
+func decodeV3(s string) (*Token, error) {
+	data, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	var v3 struct {
+		Token []struct {
+			Mint   string  `json:"mint"`
+			Proofs []Proof `json:"proofs"`
+		} `json:"token"`
+		Unit string `json:"unit"`
+		Memo string `json:"memo"`
+	}
+	if err := json.Unmarshal(data, &v3); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	token := &Token{Unit: v3.Unit, Memo: v3.Memo}
+	for _, entry := range v3.Token {
+		if len(entry.Proofs) == 0 {
+			continue
+		}
+		if token.Mint != "" && entry.Mint != token.Mint {
+			return nil, fmt.Errorf("%w: proofs of several mints", ErrInvalidToken)
+		}
+		token.Mint = entry.Mint
+		token.Proofs = append(token.Proofs, entry.Proofs...)
+	}
+	return token, nil
+}
+
+func decodeV4(s string) (*Token, error) {
+	data, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: token is not a map", ErrInvalidToken)
+	}
+	token := &Token{}
+	token.Mint, _ = root["m"].(string)
+	token.Unit, _ = root["u"].(string)
+	token.Memo, _ = root["d"].(string)
+	keysets, _ := root["t"].([]interface{})
+	for _, entry := range keysets {
+		keyset, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed keyset entry", ErrInvalidToken)
+		}
+		id, _ := keyset["i"].([]byte)
+		proofs, _ := keyset["p"].([]interface{})
+		for _, p := range proofs {
+			fields, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: malformed proof", ErrInvalidToken)
+			}
+			proof := Proof{ID: hex.EncodeToString(id)}
+			proof.Amount, _ = fields["a"].(uint64)
+			proof.Secret, _ = fields["s"].(string)
+			if c, ok := fields["c"].([]byte); ok {
+				proof.C = hex.EncodeToString(c)
+			}
+			proof.Witness, _ = fields["w"].(string)
+			token.Proofs = append(token.Proofs, proof)
+		}
+	}
+	return token, nil
+}
+
+// maxCBORDepth bounds nesting in decoded tokens
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the subset of CBOR (RFC 8949) used by V4 tokens:
+// unsigned integers, byte and text strings, arrays, maps with text keys,
+// booleans and null, all of definite length. Tags are skipped.
+func decodeCBOR(data []byte) (interface{}, error) {
+	d := &cborDecoder{data: data}
+	value, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("trailing bytes after CBOR value")
+	}
+	return value, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// head reads an item's major type and argument
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errors.New("unexpected end of CBOR data")
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(d.data)-d.pos < n {
+			return 0, 0, errors.New("unexpected end of CBOR data")
+		}
+		var arg uint64
+		for _, b := range d.data[d.pos : d.pos+n] {
+			arg = arg<<8 | uint64(b)
+		}
+		d.pos += n
+		return major, arg, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported CBOR additional info %d", info)
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("CBOR nested too deeply")
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 2, 3:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.New("CBOR string longer than the data")
+		}
+		raw := d.data[d.pos : d.pos+int(arg)]
+		d.pos += int(arg)
+		if major == 3 {
+			return string(raw), nil
+		}
+		return append([]byte(nil), raw...), nil
+	case 4:
+		// Every item takes at least a byte, which bounds the allocation
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.New("CBOR array longer than the data")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errors.New("CBOR map longer than the data")
+		}
+		fields := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, errors.New("CBOR map key is not text")
+			}
+			if fields[name], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	case 6:
+		return d.value(depth + 1)
+	case 7:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unsupported CBOR simple value %d", arg)
+	}
+	return nil, fmt.Errorf("unsupported CBOR major type %d", major)
+}
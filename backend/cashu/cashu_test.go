@@ -0,0 +1,148 @@
+package cashu
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// cborItem encodes a CBOR head and appends content, for building V4 tokens
+func cborItem(major byte, arg int, content ...[]byte) []byte {
+	var out []byte
+	if arg < 24 {
+		out = []byte{major<<5 | byte(arg)}
+	} else {
+		out = []byte{major<<5 | 24, byte(arg)}
+	}
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func cborText(s string) []byte  { return cborItem(3, len(s), []byte(s)) }
+func cborBytes(b []byte) []byte { return cborItem(2, len(b), b) }
+
+func TestDecodeV3(t *testing.T) {
+	proofs := []Proof{
+		{Amount: 2, ID: "009a1f293253e41e", Secret: "secret-1", C: "02bc9097997d81afb2cc7346b5e4345a9346bd2a506eb7958598a72f0cf85163ea"},
+		{Amount: 8, ID: "009a1f293253e41e", Secret: "secret-2", C: "029e8e5050b890a7d6c0968db16bc1d5d5fa040ea1de284f6ec69d61299f671059"},
+	}
+	v3 := map[string]interface{}{
+		"token": []map[string]interface{}{{"mint": "https://mint.example.com", "proofs": proofs}},
+		"memo":  "Concert",
+	}
+	data, _ := json.Marshal(v3)
+
+	for name, encoding := range map[string]*base64.Encoding{"url": base64.RawURLEncoding, "standard padded": base64.StdEncoding} {
+		token, err := Decode("cashuA" + encoding.EncodeToString(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if token.Mint != "https://mint.example.com" || token.Unit != UnitSat || token.Memo != "Concert" || !reflect.DeepEqual(token.Proofs, proofs) {
+			t.Errorf("%s: unexpected token %+v", name, token)
+		}
+		if amount, err := token.Amount(); err != nil || amount != 10 {
+			t.Errorf("%s: expected 10 sats, got %d (%v)", name, amount, err)
+		}
+	}
+}
+
+func TestDecodeV4(t *testing.T) {
+	id := []byte{0x00, 0xad, 0x26, 0x8c, 0x4d, 0x1f, 0x58, 0x26}
+	c := []byte{0x02, 0x01, 0x02, 0x03}
+	proof := cborItem(5, 3,
+		cborText("a"), cborItem(0, 16),
+		cborText("s"), cborText("secret-1"),
+		cborText("c"), cborBytes(c))
+	token := cborItem(5, 3,
+		cborText("m"), cborText("http://localhost:3338"),
+		cborText("u"), cborText("sat"),
+		cborText("t"), cborItem(4, 1, cborItem(5, 2,
+			cborText("i"), cborBytes(id),
+			cborText("p"), cborItem(4, 1, proof))))
+
+	decoded, err := Decode("cashuB" + base64.RawURLEncoding.EncodeToString(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Proof{{Amount: 16, ID: "00ad268c4d1f5826", Secret: "secret-1", C: "02010203"}}
+	if decoded.Mint != "http://localhost:3338" || decoded.Unit != UnitSat || !reflect.DeepEqual(decoded.Proofs, want) {
+		t.Errorf("Unexpected token %+v", decoded)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return "cashuA" + base64.RawURLEncoding.EncodeToString(data)
+	}
+	proof := Proof{Amount: 1, ID: "00", Secret: "s", C: "02"}
+	for name, token := range map[string]string{
+		"empty":           "",
+		"wrong prefix":    "lnbc1",
+		"bad base64":      "cashuA!!!",
+		"bad json":        "cashuA" + base64.RawURLEncoding.EncodeToString([]byte("{")),
+		"no proofs":       encode(map[string]interface{}{"token": []map[string]interface{}{{"mint": "https://a.example.com"}}}),
+		"another unit":    encode(map[string]interface{}{"token": []map[string]interface{}{{"mint": "https://a.example.com", "proofs": []Proof{proof}}}, "unit": "usd"}),
+		"zero amount":     encode(map[string]interface{}{"token": []map[string]interface{}{{"mint": "https://a.example.com", "proofs": []Proof{{ID: "00", Secret: "s", C: "02"}}}}}),
+		"two mints":       encode(map[string]interface{}{"token": []map[string]interface{}{{"mint": "https://a.example.com", "proofs": []Proof{proof}}, {"mint": "https://b.example.com", "proofs": []Proof{proof}}}}),
+		"truncated cbor":  "cashuB" + base64.RawURLEncoding.EncodeToString(cborItem(5, 3, cborText("m"))),
+		"huge cbor array": "cashuB" + base64.RawURLEncoding.EncodeToString([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}),
+	} {
+		if _, err := Decode(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestMelt(t *testing.T) {
+	var melted []Proof
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/melt/quote/bolt11":
+			var req struct{ Request, Unit string }
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Request != "lnbc10u1test" || req.Unit != "sat" {
+				t.Errorf("Unexpected quote request %+v", req)
+			}
+			w.Write([]byte(`{"quote":"q1","amount":1000,"fee_reserve":4,"state":"UNPAID"}`))
+		case "/v1/melt/bolt11":
+			var req struct {
+				Quote  string  `json:"quote"`
+				Inputs []Proof `json:"inputs"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Quote != "q1" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":20007,"detail":"quote is pending"}`))
+				return
+			}
+			melted = req.Inputs
+			w.Write([]byte(`{"quote":"q1","amount":1000,"fee_reserve":4,"state":"PAID","payment_preimage":"00ff"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.Client())
+
+	quote, err := client.MeltQuote(context.Background(), server.URL+"/", "lnbc10u1test")
+	if err != nil || quote.Quote != "q1" || quote.Amount != 1000 || quote.FeeReserve != 4 || quote.IsPaid() {
+		t.Fatalf("Unexpected quote %+v (%v)", quote, err)
+	}
+	proofs := []Proof{{Amount: 1024, ID: "00", Secret: "s", C: "02"}}
+	result, err := client.Melt(context.Background(), server.URL, "q1", proofs)
+	if err != nil || !result.IsPaid() || result.Preimage == nil || *result.Preimage != "00ff" || !reflect.DeepEqual(melted, proofs) {
+		t.Errorf("Unexpected melt %+v (%v)", result, err)
+	}
+
+	_, err = client.Melt(context.Background(), server.URL, "q2", proofs)
+	var mintErr *MintError
+	if !errors.As(err, &mintErr) || mintErr.Code != 20007 || mintErr.Detail != "quote is pending" {
+		t.Errorf("Expected the mint's error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package cashu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Melt quote states (NUT-05)
+const (
+	QuoteUnpaid  = "UNPAID"
+	QuotePending = "PENDING"
+	QuotePaid    = "PAID"
+)
+
+// maxMintResponseBytes caps the mint responses read
+const maxMintResponseBytes = 1 << 20
+
+// MeltQuote is a mint's offer to pay a Lightning invoice: Amount sats plus
+// up to FeeReserve for routing, which the melted proofs must cover
+type MeltQuote struct {
+	Quote      string `json:"quote"`
+	Amount     uint64 `json:"amount"`
+	FeeReserve uint64 `json:"fee_reserve"`
+	State      string `json:"state"`
+	// Paid is the state of mints predating State
+	Paid     bool    `json:"paid"`
+	Preimage *string `json:"payment_preimage"`
+}
+
+// IsPaid reports whether the mint paid the invoice
+func (q *MeltQuote) IsPaid() bool {
+	return q.State == QuotePaid || (q.State == "" && q.Paid)
+}
+
+// MintError is an error answered by a mint, such as spent or invalid proofs
+type MintError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Detail     string `json:"detail"`
+}
+
+func (e *MintError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("mint refused the request: %s", e.Detail)
+	}
+	return fmt.Sprintf("mint refused the request with status %d", e.StatusCode)
+}
+
+// Client calls mints' melt endpoints
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a mint client using httpClient
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{http: httpClient}
+}
+
+// MeltQuote asks mint for a quote to pay bolt11 in sats
+func (c *Client) MeltQuote(ctx context.Context, mint, bolt11 string) (*MeltQuote, error) {
+	body := map[string]string{"request": bolt11, "unit": UnitSat}
+	quote := &MeltQuote{}
+	if err := c.post(ctx, mint, "/v1/melt/quote/bolt11", body, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// Melt spends proofs to have mint pay the invoice of quote. The call
+// returns once the payment settles or, with some mints, while it is still
+// pending; the quote's state tells which.
+func (c *Client) Melt(ctx context.Context, mint, quote string, proofs []Proof) (*MeltQuote, error) {
+	body := map[string]interface{}{"quote": quote, "inputs": proofs}
+	result := &MeltQuote{}
+	if err := c.post(ctx, mint, "/v1/melt/bolt11", body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) post(ctx context.Context, mint, path string, body, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(mint, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMintResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		mintErr := &MintError{StatusCode: resp.StatusCode}
+		json.Unmarshal(data, mintErr)
+		return mintErr
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("invalid response from mint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Command loadtest drives concurrent ticket purchases against a running
+// server, reports latency percentiles and exits non-zero when the purchase
+// path exceeds its latency budget or error rate, so it can gate a release.
+//
+// Run it against a server started with PAYMENT_BACKEND=simulation so no real
+// invoices are created:
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -event 1 -requests 500 -concurrency 20 -p95 300ms
+//
+// Without -event, -admin-email and -admin-password are used to create an event
+// sized for the run. Without -user a throwaway buyer account is registered.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/models"
+)
+
+type options struct {
+	baseURL       string
+	eventID       int
+	userID        int
+	umaAddress    string
+	priceSats     int64
+	adminEmail    string
+	adminPassword string
+	requests      int
+	concurrency   int
+	timeout       time.Duration
+	p50Budget     time.Duration
+	p95Budget     time.Duration
+	p99Budget     time.Duration
+	maxErrorRate  float64
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "Base URL of the server under test")
+	flag.IntVar(&opts.eventID, "event", 0, "Event to purchase tickets for (created when 0)")
+	flag.IntVar(&opts.userID, "user", 0, "Buyer user ID (a new user is registered when 0)")
+	flag.StringVar(&opts.umaAddress, "uma-address", "$loadtest@example.com", "Buyer UMA address sent with each purchase")
+	flag.Int64Var(&opts.priceSats, "price", 1000, "Ticket price in sats for a created event (0 tests the free path)")
+	flag.StringVar(&opts.adminEmail, "admin-email", "", "Admin email used to create the event")
+	flag.StringVar(&opts.adminPassword, "admin-password", "", "Admin password used to create the event")
+	flag.IntVar(&opts.requests, "requests", 200, "Total number of purchases")
+	flag.IntVar(&opts.concurrency, "concurrency", 10, "Number of concurrent purchasers")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "Per-request timeout")
+	flag.DurationVar(&opts.p50Budget, "p50", 0, "Fail when p50 purchase latency exceeds this (0 disables)")
+	flag.DurationVar(&opts.p95Budget, "p95", 500*time.Millisecond, "Fail when p95 purchase latency exceeds this (0 disables)")
+	flag.DurationVar(&opts.p99Budget, "p99", time.Second, "Fail when p99 purchase latency exceeds this (0 disables)")
+	flag.Float64Var(&opts.maxErrorRate, "max-error-rate", 0.01, "Fail when the fraction of failed purchases exceeds this")
+	flag.Parse()
+
+	if opts.requests <= 0 || opts.concurrency <= 0 {
+		fmt.Fprintln(os.Stderr, "loadtest: -requests and -concurrency must be positive")
+		os.Exit(2)
+	}
+	opts.baseURL = strings.TrimRight(opts.baseURL, "/")
+
+	client := &http.Client{
+		Timeout: opts.timeout,
+		// One connection per purchaser, as a fleet of browsers would use
+		Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency},
+	}
+
+	if err := prepare(client, &opts); err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest:", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Purchasing %d tickets for event %d with %d concurrent buyers against %s\n",
+		opts.requests, opts.eventID, opts.concurrency, opts.baseURL)
+
+	start := time.Now()
+	results := run(client, opts)
+	s := summarize(results, time.Since(start))
+
+	s.print(os.Stdout)
+	if violations := s.check(opts); len(violations) > 0 {
+		fmt.Println()
+		for _, v := range violations {
+			fmt.Println("FAIL:", v)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\nPASS: purchase path is within budget")
+}
+
+// prepare creates the event and buyer the run needs when they were not given
+func prepare(client *http.Client, opts *options) error {
+	if opts.eventID == 0 {
+		if opts.adminEmail == "" || opts.adminPassword == "" {
+			return fmt.Errorf("either -event or -admin-email and -admin-password are required")
+		}
+		var login map[string]interface{}
+		err := postJSON(client, opts.baseURL+"/api/users/login", "", models.LoginRequest{
+			Email:    opts.adminEmail,
+			Password: opts.adminPassword,
+		}, http.StatusOK, &login)
+		if err != nil {
+			return fmt.Errorf("admin login: %w", err)
+		}
+		token, _ := login["token"].(string)
+
+		var event map[string]interface{}
+		err = postJSON(client, opts.baseURL+"/api/admin/events", token, models.CreateEventRequest{
+			Title:       fmt.Sprintf("Load test %s", time.Now().UTC().Format(time.RFC3339)),
+			Description: "Created by cmd/loadtest",
+			StartTime:   time.Now().Add(24 * time.Hour),
+			EndTime:     time.Now().Add(26 * time.Hour),
+			Capacity:    opts.requests,
+			PriceSats:   opts.priceSats,
+		}, http.StatusCreated, &event)
+		if err != nil {
+			return fmt.Errorf("create event: %w", err)
+		}
+		opts.eventID = jsonInt(event["id"])
+	}
+
+	if opts.userID == 0 {
+		var user map[string]interface{}
+		err := postJSON(client, opts.baseURL+"/api/users", "", models.CreateUserRequest{
+			Email:    fmt.Sprintf("loadtest-%d@example.com", time.Now().UnixNano()),
+			Name:     "Load Test Buyer",
+			Password: fmt.Sprintf("loadtest-%d", time.Now().UnixNano()),
+		}, http.StatusCreated, &user)
+		if err != nil {
+			return fmt.Errorf("register buyer: %w", err)
+		}
+		opts.userID = jsonInt(user["id"])
+	}
+	return nil
+}
+
+// run fires opts.requests purchases from opts.concurrency workers
+func run(client *http.Client, opts options) []result {
+	body, _ := json.Marshal(models.TicketPurchaseRequest{
+		EventID:    opts.eventID,
+		UserID:     opts.userID,
+		UMAAddress: opts.umaAddress,
+	})
+
+	jobs := make(chan int)
+	results := make([]result, opts.requests)
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = purchase(client, opts.baseURL+"/api/tickets/purchase", body)
+			}
+		}()
+	}
+	for i := 0; i < opts.requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func purchase(client *http.Client, url string, body []byte) result {
+	start := time.Now()
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return result{latency: time.Since(start), err: err}
+	}
+	// Read the whole body so the latency covers the full response
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return result{latency: time.Since(start), status: resp.StatusCode, err: err}
+}
+
+// postJSON sends body and decodes the SuccessResponse data into out
+func postJSON(client *http.Client, url, token string, body interface{}, wantStatus int, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("expected status %d, got %d: %s", wantStatus, resp.StatusCode, errResp.Message)
+	}
+
+	envelope := models.SuccessResponse{Data: out}
+	return json.NewDecoder(resp.Body).Decode(&envelope)
+}
+
+// jsonInt converts a JSON number decoded into interface{} to an int
+func jsonInt(v interface{}) int {
+	n, _ := v.(float64)
+	return int(n)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// result is the outcome of one purchase request
+type result struct {
+	latency time.Duration
+	status  int
+	err     error
+}
+
+// ok accepts purchases held for fraud review (202) as well as completed ones;
+// the status breakdown shows how many were held.
+func (r result) ok() bool {
+	return r.err == nil && (r.status == http.StatusCreated || r.status == http.StatusAccepted)
+}
+
+// summary aggregates a run. Percentiles cover successful purchases only, so
+// fast error responses cannot hide a slow purchase path.
+type summary struct {
+	total     int
+	succeeded int
+	statuses  map[int]int
+	transport int // requests that got no response
+	elapsed   time.Duration
+	p50       time.Duration
+	p95       time.Duration
+	p99       time.Duration
+	max       time.Duration
+}
+
+func summarize(results []result, elapsed time.Duration) summary {
+	s := summary{total: len(results), statuses: make(map[int]int), elapsed: elapsed}
+
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.err != nil && r.status == 0 {
+			s.transport++
+			continue
+		}
+		s.statuses[r.status]++
+		if r.ok() {
+			s.succeeded++
+			latencies = append(latencies, r.latency)
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.p50 = percentile(latencies, 50)
+	s.p95 = percentile(latencies, 95)
+	s.p99 = percentile(latencies, 99)
+	if len(latencies) > 0 {
+		s.max = latencies[len(latencies)-1]
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (s summary) errorRate() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return float64(s.total-s.succeeded) / float64(s.total)
+}
+
+func (s summary) print(w io.Writer) {
+	fmt.Fprintf(w, "\nRequests:   %d in %s (%.1f req/s)\n", s.total, s.elapsed.Round(time.Millisecond), float64(s.total)/s.elapsed.Seconds())
+	fmt.Fprintf(w, "Succeeded:  %d (error rate %.2f%%)\n", s.succeeded, s.errorRate()*100)
+
+	codes := make([]int, 0, len(s.statuses))
+	for code := range s.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  HTTP %d:   %d\n", code, s.statuses[code])
+	}
+	if s.transport > 0 {
+		fmt.Fprintf(w, "  No response: %d\n", s.transport)
+	}
+
+	fmt.Fprintf(w, "Latency:    p50 %s  p95 %s  p99 %s  max %s\n",
+		s.p50.Round(time.Microsecond), s.p95.Round(time.Microsecond),
+		s.p99.Round(time.Microsecond), s.max.Round(time.Microsecond))
+}
+
+// check returns a description of every budget the run exceeded
+func (s summary) check(opts options) []string {
+	var violations []string
+	if s.succeeded == 0 {
+		return []string{"no purchase succeeded"}
+	}
+	budgets := []struct {
+		name   string
+		got    time.Duration
+		budget time.Duration
+	}{
+		{"p50", s.p50, opts.p50Budget},
+		{"p95", s.p95, opts.p95Budget},
+		{"p99", s.p99, opts.p99Budget},
+	}
+	for _, b := range budgets {
+		if b.budget > 0 && b.got > b.budget {
+			violations = append(violations, fmt.Sprintf("%s latency %s exceeds budget %s", b.name, b.got.Round(time.Microsecond), b.budget))
+		}
+	}
+	if rate := s.errorRate(); rate > opts.maxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", rate*100, opts.maxErrorRate*100))
+	}
+	return violations
+}
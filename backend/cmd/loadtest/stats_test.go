@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	var results []result
+	for i := 1; i <= 100; i++ {
+		results = append(results, result{latency: time.Duration(i) * time.Millisecond, status: http.StatusCreated})
+	}
+	// Failures are counted but do not skew the latency percentiles
+	results = append(results,
+		result{latency: time.Microsecond, status: http.StatusInternalServerError},
+		result{latency: time.Minute, err: errors.New("timeout")},
+	)
+
+	s := summarize(results, time.Second)
+	if s.total != 102 || s.succeeded != 100 || s.transport != 1 || s.statuses[http.StatusInternalServerError] != 1 {
+		t.Errorf("Unexpected counts: %+v", s)
+	}
+	if s.p50 != 50*time.Millisecond || s.p95 != 95*time.Millisecond || s.p99 != 99*time.Millisecond || s.max != 100*time.Millisecond {
+		t.Errorf("Unexpected percentiles: p50=%s p95=%s p99=%s max=%s", s.p50, s.p95, s.p99, s.max)
+	}
+
+	within := options{p95Budget: 100 * time.Millisecond, p99Budget: 100 * time.Millisecond, maxErrorRate: 0.05}
+	if violations := s.check(within); len(violations) != 0 {
+		t.Errorf("Expected run within budget, got %v", violations)
+	}
+
+	over := options{p50Budget: 10 * time.Millisecond, p99Budget: 100 * time.Millisecond, maxErrorRate: 0.01}
+	violations := s.check(over)
+	if len(violations) != 2 || !strings.HasPrefix(violations[0], "p50") || !strings.HasPrefix(violations[1], "error rate") {
+		t.Errorf("Expected p50 and error rate violations, got %v", violations)
+	}
+}
+
+func TestPercentileSmallSamples(t *testing.T) {
+	if got := percentile(nil, 99); got != 0 {
+		t.Errorf("Expected 0 for no samples, got %s", got)
+	}
+	sorted := []time.Duration{time.Millisecond, 2 * time.Millisecond}
+	if got := percentile(sorted, 50); got != time.Millisecond {
+		t.Errorf("Expected p50 of 1ms, got %s", got)
+	}
+	if got := percentile(sorted, 99); got != 2*time.Millisecond {
+		t.Errorf("Expected p99 of 2ms, got %s", got)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+)
+
+// BackupStorage is the S3-compatible bucket backups are uploaded to.
+type BackupStorage struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Enabled reports whether a bucket is configured.
+func (b BackupStorage) Enabled() bool {
+	return b.Bucket != ""
+}
+
+func (b BackupStorage) validate() error {
+	if !b.Enabled() {
+		return nil
+	}
+	if b.Region == "" || b.AccessKeyID == "" || b.SecretAccessKey == "" {
+		return errors.New("backup_s3_region, backup_s3_access_key_id and backup_s3_secret_access_key are required with backup_s3_bucket")
+	}
+	if u, err := url.Parse(b.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("backup_s3_endpoint must be an absolute URL")
+	}
+	return nil
+}
+
+// BackupStorage returns the configured backup bucket, with the endpoint
+// defaulted to AWS's for the region.
+func (c *Config) BackupStorage() BackupStorage {
+	endpoint := c.BackupS3Endpoint
+	if endpoint == "" && c.BackupS3Region != "" {
+		endpoint = "https://s3." + c.BackupS3Region + ".amazonaws.com"
+	}
+	return BackupStorage{
+		Endpoint:        endpoint,
+		Region:          c.BackupS3Region,
+		Bucket:          c.BackupS3Bucket,
+		Prefix:          c.BackupS3Prefix,
+		AccessKeyID:     c.BackupS3AccessKeyID,
+		SecretAccessKey: c.BackupS3SecretAccessKey,
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import "fmt"
+
+// PriceLimits bounds ticket prices and invoice amounts, in sats. A zero
+// bound is not enforced.
+type PriceLimits struct {
+	MinTicketSats  int64
+	MaxTicketSats  int64
+	MaxInvoiceSats int64
+}
+
+// PriceLimits returns the configured price and invoice bounds.
+func (c *Config) PriceLimits() PriceLimits {
+	return PriceLimits{
+		MinTicketSats:  c.MinTicketPriceSats,
+		MaxTicketSats:  c.MaxTicketPriceSats,
+		MaxInvoiceSats: c.MaxInvoiceSats,
+	}
+}
+
+// CheckTicketPrice rejects paid ticket prices outside the configured range.
+func (l PriceLimits) CheckTicketPrice(priceSats int64) error {
+	if l.MinTicketSats > 0 && priceSats < l.MinTicketSats {
+		return fmt.Errorf("price must be at least %d sats (got %d)", l.MinTicketSats, priceSats)
+	}
+	if l.MaxTicketSats > 0 && priceSats > l.MaxTicketSats {
+		return fmt.Errorf("price must be at most %d sats (got %d)", l.MaxTicketSats, priceSats)
+	}
+	return nil
+}
+
+// CheckInvoice rejects invoices larger than the configured maximum.
+func (l PriceLimits) CheckInvoice(amountSats int64) error {
+	if l.MaxInvoiceSats > 0 && amountSats > l.MaxInvoiceSats {
+		return fmt.Errorf("invoice amount %d sats exceeds the maximum of %d sats", amountSats, l.MaxInvoiceSats)
+	}
+	return nil
+}
+
+// MaxFeeBasisPoints is a fee of 100%.
+const MaxFeeBasisPoints = 10_000
+
+// FeeSchedule is a platform fee: a share of the sale in basis points
+// (hundredths of a percent) plus a fixed amount per invoice.
+type FeeSchedule struct {
+	BasisPoints int64 `json:"basis_points"`
+	FixedSats   int64 `json:"fixed_sats"`
+}
+
+// PlatformFee returns the default fee schedule.
+func (c *Config) PlatformFee() FeeSchedule {
+	return FeeSchedule{BasisPoints: c.PlatformFeeBasisPoints, FixedSats: c.PlatformFeeFixedSats}
+}
+
+// Fee returns the fee on a subtotal, rounding half up. Free orders are not
+// charged the fixed part.
+func (f FeeSchedule) Fee(subtotalSats int64) int64 {
+	if subtotalSats <= 0 {
+		return 0
+	}
+	return (subtotalSats*f.BasisPoints+MaxFeeBasisPoints/2)/MaxFeeBasisPoints + f.FixedSats
+}
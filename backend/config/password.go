@@ -0,0 +1,43 @@
+package config
+
+// Character classes a password can be required to contain
+// (Config.PasswordRequiredClasses).
+const (
+	PasswordClassLower  = "lower"
+	PasswordClassUpper  = "upper"
+	PasswordClassDigit  = "digit"
+	PasswordClassSymbol = "symbol"
+)
+
+// PasswordClasses lists the character classes in the order hints show them.
+var PasswordClasses = []string{PasswordClassLower, PasswordClassUpper, PasswordClassDigit, PasswordClassSymbol}
+
+// MaxPasswordBytes is the longest password bcrypt hashes in full; it
+// ignores anything past it.
+const MaxPasswordBytes = 72
+
+// PasswordPolicy is what new passwords must satisfy. It is served to
+// clients so they can show hints as the password is typed; the deny list
+// stays on the server.
+type PasswordPolicy struct {
+	MinLength       int      `json:"min_length"`
+	MaxBytes        int      `json:"max_bytes"`
+	RequiredClasses []string `json:"required_classes"`
+	BreachCheck     bool     `json:"breach_check"`
+	DenyList        []string `json:"-"`
+}
+
+// PasswordPolicy returns the configured password policy.
+func (c *Config) PasswordPolicy() PasswordPolicy {
+	classes := c.PasswordRequiredClasses
+	if classes == nil {
+		classes = []string{}
+	}
+	return PasswordPolicy{
+		MinLength:       c.PasswordMinLength,
+		MaxBytes:        MaxPasswordBytes,
+		RequiredClasses: classes,
+		BreachCheck:     c.PasswordBreachCheck,
+		DenyList:        c.PasswordDenyList,
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+// RetentionPolicy is how many months settled payments, and tickets and
+// events after the event ended, stay in the hot tables before they are
+// archived. Zero keeps them forever.
+type RetentionPolicy struct {
+	PaymentsMonths int `json:"payments_months"`
+	TicketsMonths  int `json:"tickets_months"`
+	EventsMonths   int `json:"events_months"`
+}
+
+// Enabled reports whether any table is archived.
+func (p RetentionPolicy) Enabled() bool {
+	return p.PaymentsMonths > 0 || p.TicketsMonths > 0 || p.EventsMonths > 0
+}
+
+// RetentionPolicy returns the configured retention policy.
+func (c *Config) RetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		PaymentsMonths: c.RetentionPaymentsMonths,
+		TicketsMonths:  c.RetentionTicketsMonths,
+		EventsMonths:   c.RetentionEventsMonths,
+	}
+}
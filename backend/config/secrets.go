@@ -0,0 +1,370 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret keys resolved through a SecretSource. The names match the
+// environment variables they replace.
+const (
+	SecretJWT                    = "JWT_SECRET"
+	SecretLightsparkClientSecret = "LIGHTSPARK_CLIENT_SECRET"
+	SecretLightsparkNodePassword = "LIGHTSPARK_NODE_PASSWORD"
+	SecretWebhookSigningKey      = "LIGHTSPARK_WEBHOOK_SIGNING_KEY"
+	SecretUMASigningPrivKey      = "UMA_SIGNING_PRIVKEY"
+	SecretUMAEncryptionPrivKey   = "UMA_ENCRYPTION_PRIVKEY"
+	SecretNWCEncryptionKeys      = "NWC_ENCRYPTION_KEYS"
+	SecretPIIEncryptionKeys      = "PII_ENCRYPTION_KEYS"
+	SecretTicketSigningKeys      = "TICKET_SIGNING_KEYS"
+	SecretJWTSigningKeys         = "JWT_SIGNING_KEYS"
+	SecretPaymentWebhookSecret   = "PAYMENT_WEBHOOK_SECRET"
+	SecretUMACallbackSecret      = "UMA_CALLBACK_SECRET"
+	SecretChallengeSecret        = "CHALLENGE_SECRET"
+)
+
+// managedSecrets lists every key fetched from the configured SecretSource.
+var managedSecrets = []string{
+	SecretJWT,
+	SecretLightsparkClientSecret,
+	SecretLightsparkNodePassword,
+	SecretWebhookSigningKey,
+	SecretUMASigningPrivKey,
+	SecretUMAEncryptionPrivKey,
+	SecretNWCEncryptionKeys,
+	SecretPIIEncryptionKeys,
+	SecretTicketSigningKeys,
+	SecretJWTSigningKeys,
+	SecretPaymentWebhookSecret,
+	SecretUMACallbackSecret,
+	SecretChallengeSecret,
+}
+
+// ErrSecretNotFound is returned by a SecretSource that has no value for a key.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretSource resolves secret values from an external store.
+type SecretSource interface {
+	Name() string
+	GetSecret(ctx context.Context, key string) (string, error)
+}
+
+// NewSecretSource builds the SecretSource selected by SECRETS_PROVIDER.
+// It returns nil when no provider is configured.
+func NewSecretSource(provider string, httpClient *http.Client) (SecretSource, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	switch provider {
+	case "":
+		return nil, nil
+	case "file":
+		return &FileSecretSource{Dir: getEnv("SECRETS_DIR", "/run/secrets")}, nil
+	case "vault":
+		addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+		if addr == "" || token == "" {
+			return nil, errors.New("VAULT_ADDR and VAULT_TOKEN are required for the vault secrets provider")
+		}
+		return &VaultSecretSource{
+			Addr:   strings.TrimSuffix(addr, "/"),
+			Token:  token,
+			Mount:  getEnv("VAULT_MOUNT", "secret"),
+			Path:   getEnv("VAULT_SECRET_PATH", "tickets-by-uma"),
+			Client: httpClient,
+		}, nil
+	case "aws":
+		src := &AWSSecretsManagerSource{
+			Region:          os.Getenv("AWS_REGION"),
+			SecretID:        os.Getenv("AWS_SECRET_ID"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Client:          httpClient,
+		}
+		if src.Region == "" || src.SecretID == "" || src.AccessKeyID == "" || src.SecretAccessKey == "" {
+			return nil, errors.New("AWS_REGION, AWS_SECRET_ID, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for the aws secrets provider")
+		}
+		return src, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q (expected file, vault or aws)", provider)
+	}
+}
+
+// FileSecretSource reads secrets mounted as files, one file per key
+// (e.g. /run/secrets/jwt_secret). File names are the lower-cased key.
+type FileSecretSource struct {
+	Dir string
+}
+
+func (s *FileSecretSource) Name() string { return "file" }
+
+func (s *FileSecretSource) GetSecret(ctx context.Context, key string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, strings.ToLower(key)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// VaultSecretSource reads secrets from a HashiCorp Vault KV v2 engine. All keys
+// live in a single secret at {mount}/data/{path}.
+type VaultSecretSource struct {
+	Addr   string
+	Token  string
+	Mount  string
+	Path   string
+	Client *http.Client
+}
+
+func (s *VaultSecretSource) Name() string { return "vault" }
+
+func (s *VaultSecretSource) GetSecret(ctx context.Context, key string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", s.Addr, s.Mount, s.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// AWSSecretsManagerSource reads secrets from a single AWS Secrets Manager
+// secret whose SecretString is a JSON object keyed by secret name.
+type AWSSecretsManagerSource struct {
+	Region          string
+	SecretID        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Client          *http.Client
+}
+
+func (s *AWSSecretsManagerSource) Name() string { return "aws" }
+
+func (s *AWSSecretsManagerSource) GetSecret(ctx context.Context, key string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": s.SecretID})
+	if err != nil {
+		return "", err
+	}
+
+	host := fmt.Sprintf("secretsmanager.%s.amazonaws.com", s.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	s.sign(req, host, payload, time.Now().UTC())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets manager returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(result.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", s.SecretID, err)
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// sign adds AWS Signature Version 4 headers to the request.
+func (s *AWSSecretsManagerSource) sign(req *http.Request, host string, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+	if s.SessionToken != "" {
+		signedHeaders = "content-type;host;x-amz-date;x-amz-security-token;x-amz-target"
+		canonicalHeaders = "content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + host + "\n" +
+			"x-amz-date:" + amzDate + "\n" +
+			"x-amz-security-token:" + s.SessionToken + "\n" +
+			"x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{"POST", "/", "", canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + s.Region + "/secretsmanager/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "secretsmanager")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// SecretStore caches values from a SecretSource and refreshes them
+// periodically so rotated credentials are picked up without a restart.
+type SecretStore struct {
+	source    SecretSource
+	mu        sync.RWMutex
+	values    map[string]string
+	listeners map[string][]func(string)
+}
+
+// NewSecretStore creates a store backed by source.
+func NewSecretStore(source SecretSource) *SecretStore {
+	return &SecretStore{
+		source:    source,
+		values:    make(map[string]string),
+		listeners: make(map[string][]func(string)),
+	}
+}
+
+// Get returns the cached value for key, or "" if the source has none.
+func (s *SecretStore) Get(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[key]
+}
+
+// OnChange registers fn to be called with the new value whenever key rotates.
+func (s *SecretStore) OnChange(key string, fn func(string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners[key] = append(s.listeners[key], fn)
+}
+
+// Refresh fetches every managed secret. Keys missing from the source are
+// left unset so the environment/file value remains in effect.
+func (s *SecretStore) Refresh(ctx context.Context) error {
+	var changed []string
+	for _, key := range managedSecrets {
+		value, err := s.source.GetSecret(ctx, key)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load %s from %s: %w", key, s.source.Name(), err)
+		}
+
+		s.mu.Lock()
+		if old, ok := s.values[key]; !ok || old != value {
+			if ok {
+				changed = append(changed, key)
+			}
+			s.values[key] = value
+		}
+		s.mu.Unlock()
+	}
+
+	for _, key := range changed {
+		s.mu.RLock()
+		listeners := append([]func(string){}, s.listeners[key]...)
+		value := s.values[key]
+		s.mu.RUnlock()
+		for _, fn := range listeners {
+			fn(value)
+		}
+	}
+	return nil
+}
+
+// Watch refreshes secrets every interval until ctx is cancelled.
+func (s *SecretStore) Watch(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				logger.Error("Failed to refresh secrets", "provider", s.source.Name(), "error", err)
+			}
+		}
+	}
+}
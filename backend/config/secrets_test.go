@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFromSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "jwt_secret"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SECRETS_PROVIDER", "file")
+	t.Setenv("SECRETS_DIR", dir)
+	t.Setenv("JWT_SECRET", "from-env")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.JWTSecret != "from-file" {
+		t.Errorf("Expected secret file to override env, got %q", cfg.JWTSecret)
+	}
+	// Secrets missing from the provider keep their env/default value
+	if cfg.LightsparkClientSecret != "" {
+		t.Errorf("Expected unset client secret, got %q", cfg.LightsparkClientSecret)
+	}
+}
+
+func TestSecretStoreRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwt_secret")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSecretStore(&FileSecretSource{Dir: dir})
+	if err := store.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var rotated string
+	store.OnChange(SecretJWT, func(value string) { rotated = value })
+
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.Get(SecretJWT) != "second" {
+		t.Errorf("Expected rotated value, got %q", store.Get(SecretJWT))
+	}
+	if rotated != "second" {
+		t.Errorf("Expected OnChange to fire with new value, got %q", rotated)
+	}
+
+	cfg := &Config{JWTSecret: "first", Secrets: store}
+	if cfg.Secret(SecretJWT) != "second" {
+		t.Errorf("Expected Config.Secret to return rotated value, got %q", cfg.Secret(SecretJWT))
+	}
+}
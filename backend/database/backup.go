@@ -0,0 +1,156 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/config"
+)
+
+// BackupExtension is the file extension of the backups Dump writes for
+// the storage: pg_dump's custom archive format, or a SQLite database file
+func BackupExtension(storage string) string {
+	if storage == config.StorageSQLite {
+		return "db"
+	}
+	return "dump"
+}
+
+// Dump writes a consistent backup of the database cfg names, which db is
+// connected to, to w. Postgres is dumped by pg_dump (which must be on the
+// PATH) from one snapshot, SQLite copied with VACUUM INTO, so writes
+// carry on meanwhile either way. Restore reads it back.
+func Dump(ctx context.Context, cfg *config.Config, db *sqlx.DB, w io.Writer) error {
+	if cfg.Storage == config.StorageSQLite {
+		return dumpSQLite(ctx, db, w)
+	}
+	cmd := pgCommand(ctx, cfg.DatabaseURL, "pg_dump", "--format=custom", "--no-owner", "--no-privileges", "--schema=public")
+	cmd.Stdout = w
+	return runPG(cmd)
+}
+
+func dumpSQLite(ctx context.Context, db *sqlx.DB, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "tickets-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if _, err := db.ExecContext(ctx, `VACUUM INTO $1`, path); err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// Restore replaces the database cfg names with a backup Dump wrote, read
+// from r. On Postgres pg_restore (which must be on the PATH) drops and
+// recreates every dumped object in one transaction, so a failed restore
+// changes nothing. On SQLite the backup is checked and then moved over
+// the database file. Stop the server first: it would keep writing to the
+// database being replaced.
+func Restore(ctx context.Context, cfg *config.Config, r io.Reader) error {
+	switch cfg.Storage {
+	case config.StoragePostgres, "":
+		cmd := pgCommand(ctx, cfg.DatabaseURL, "pg_restore", "--clean", "--if-exists", "--no-owner", "--no-privileges", "--single-transaction", "--exit-on-error")
+		cmd.Stdin = r
+		return runPG(cmd)
+	case config.StorageSQLite:
+		path, err := sqlitePath(cfg.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		return restoreSQLite(ctx, path, r)
+	default:
+		return fmt.Errorf("storage %q has no database", cfg.Storage)
+	}
+}
+
+func restoreSQLite(ctx context.Context, path string, r io.Reader) error {
+	// A temporary file next to the database, so the final rename does not
+	// cross file systems
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkSQLite(ctx, tmp.Name()); err != nil {
+		return fmt.Errorf("backup is not a usable SQLite database: %w", err)
+	}
+
+	// The write-ahead log belongs to the database being replaced
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// checkSQLite runs SQLite's integrity check on the file at path
+func checkSQLite(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return errors.New(result)
+	}
+	return nil
+}
+
+// pgCommand prepares a Postgres client tool to run against databaseURL.
+// The password is passed in PGPASSWORD rather than on the command line,
+// where other users of the host could read it.
+func pgCommand(ctx context.Context, databaseURL, name string, args ...string) *exec.Cmd {
+	env := os.Environ()
+	if u, err := url.Parse(databaseURL); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			env = append(env, "PGPASSWORD="+password)
+			u.User = url.User(u.User.Username())
+			databaseURL = u.String()
+		}
+	}
+	cmd := exec.CommandContext(ctx, name, append(args, "--dbname="+databaseURL)...)
+	cmd.Env = env
+	return cmd
+}
+
+// runPG runs cmd, returning what it printed to stderr in its error
+func runPG(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"tickets-by-uma/config"
+)
+
+func TestBackupSQLite(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageSQLite, DatabaseURL: "sqlite:" + filepath.Join(t.TempDir(), "tickets.db")}
+	db, err := OpenSQLite(cfg.DatabaseURL)
+	if err != nil {
+		t.Fatal("Failed to open SQLite database:", err)
+	}
+	db.MustExec(`CREATE TABLE things (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	db.MustExec(`INSERT INTO things (name) VALUES ('kept')`)
+
+	var backup bytes.Buffer
+	if err := Dump(context.Background(), cfg, db, &backup); err != nil {
+		t.Fatal("Dump failed:", err)
+	}
+	if !bytes.HasPrefix(backup.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Fatalf("Expected a SQLite database file, got %q", backup.Bytes()[:min(16, backup.Len())])
+	}
+	db.MustExec(`INSERT INTO things (name) VALUES ('after the backup')`)
+	db.Close()
+
+	if err := Restore(context.Background(), cfg, strings.NewReader("not a database")); err == nil {
+		t.Error("Expected restoring garbage to fail")
+	}
+	if err := Restore(context.Background(), cfg, &backup); err != nil {
+		t.Fatal("Restore failed:", err)
+	}
+
+	db, err = OpenSQLite(cfg.DatabaseURL)
+	if err != nil {
+		t.Fatal("Failed to reopen SQLite database:", err)
+	}
+	defer db.Close()
+	var names []string
+	if err := db.Select(&names, `SELECT name FROM things ORDER BY id`); err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	if !slices.Equal(names, []string{"kept"}) {
+		t.Errorf("Expected the rows as of the backup, got %v", names)
+	}
+}
+
+func TestPGCommandHidesPassword(t *testing.T) {
+	cmd := pgCommand(context.Background(), "postgres://tickets:s3cret@db:5432/tickets_uma?sslmode=disable", "pg_dump", "--format=custom")
+	if want := "--dbname=postgres://tickets@db:5432/tickets_uma?sslmode=disable"; cmd.Args[len(cmd.Args)-1] != want {
+		t.Errorf("Expected %s, got %v", want, cmd.Args)
+	}
+	if strings.Contains(strings.Join(cmd.Args, " "), "s3cret") || !slices.Contains(cmd.Env, "PGPASSWORD=s3cret") {
+		t.Errorf("Expected the password in PGPASSWORD only, got %v", cmd.Args)
+	}
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"tickets-by-uma/config"
+)
+
+// sqliteBusyTimeoutMS is how long a SQLite writer waits for a lock before
+// failing with "database is locked"
+const sqliteBusyTimeoutMS = 5000
+
+// Open connects to the SQL database selected by cfg.Storage and verifies the
+// connection. Repository queries are written to run unchanged on both
+// Postgres and SQLite (3.35+ for RETURNING), so only the driver and DSN
+// differ. Memory storage has no database and must not call Open.
+//
+// With stats set every statement run on the connection is recorded there.
+func Open(cfg *config.Config, stats *QueryStats) (*sqlx.DB, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres, "":
+		connector, err := pq.NewConnector(cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return connect(connector, "postgres", stats)
+	case config.StorageSQLite:
+		return openSQLite(cfg.DatabaseURL, stats)
+	default:
+		return nil, fmt.Errorf("storage %q has no database", cfg.Storage)
+	}
+}
+
+// OpenSQLite opens a dbmate-style URL ("sqlite:path/to/file.db", or
+// "sqlite:///abs/path.db") with foreign keys enforced and WAL journaling so
+// reads are not blocked by the writer.
+func OpenSQLite(databaseURL string) (*sqlx.DB, error) {
+	return openSQLite(databaseURL, nil)
+}
+
+func openSQLite(databaseURL string, stats *QueryStats) (*sqlx.DB, error) {
+	path, err := sqlitePath(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=%d", path, sqliteBusyTimeoutMS)
+	return connect(&dsnConnector{dsn: dsn, driver: &sqlite3.SQLiteDriver{}}, "sqlite3", stats)
+}
+
+// sqlitePath is the file a dbmate-style SQLite URL names
+func sqlitePath(databaseURL string) (string, error) {
+	path, ok := strings.CutPrefix(databaseURL, "sqlite:")
+	if !ok || path == "" {
+		return "", fmt.Errorf("invalid SQLite database URL %q", databaseURL)
+	}
+	return strings.TrimPrefix(path, "//"), nil
+}
+
+// connect opens a pool on connector and pings it, as sqlx.Connect does.
+// The driver name is kept so sqlx binds parameters the driver's way.
+func connect(connector driver.Connector, driverName string, stats *QueryStats) (*sqlx.DB, error) {
+	if stats != nil {
+		connector = stats.wrap(connector)
+	}
+	db := sqlx.NewDb(sql.OpenDB(connector), driverName)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTrackedQueries bounds how many distinct query texts QueryStats keeps
+// totals for; any later ones are added up under otherQueries. Queries
+// built with a variable number of filters are the usual source of new
+// texts.
+const maxTrackedQueries = 500
+
+// otherQueries is the entry untracked query texts are counted under
+const otherQueries = "(other)"
+
+// topQueries is how many queries Stats lists
+const topQueries = 20
+
+// QueryStats records how long every statement run through an instrumented
+// connection took, how many rows it returned or changed and whether it
+// failed, per query text. Statements at or over the slow threshold are
+// logged with their text, never their arguments, which can hold personal
+// data.
+type QueryStats struct {
+	slow   time.Duration
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	queries map[string]*QueryStat
+	calls   int64
+	errors  int64
+	slowRun int64
+}
+
+// QueryStat is the running total of one query text. Rows counts rows a
+// query returned or a statement changed.
+type QueryStat struct {
+	Query   string  `json:"query"`
+	Calls   int64   `json:"calls"`
+	Errors  int64   `json:"errors"`
+	Rows    int64   `json:"rows"`
+	TotalMS float64 `json:"total_ms"`
+	MeanMS  float64 `json:"mean_ms"`
+	MaxMS   float64 `json:"max_ms"`
+}
+
+// QueryStatsSnapshot is a snapshot of QueryStats for metrics: totals over
+// every statement and the queries that took the most time altogether.
+type QueryStatsSnapshot struct {
+	Queries int         `json:"queries"`
+	Calls   int64       `json:"calls"`
+	Errors  int64       `json:"errors"`
+	Slow    int64       `json:"slow"`
+	Top     []QueryStat `json:"top"`
+}
+
+// NewQueryStats creates query stats logging statements that take slow or
+// longer. A zero slow threshold logs none.
+func NewQueryStats(slow time.Duration, logger *slog.Logger) *QueryStats {
+	return &QueryStats{slow: slow, logger: logger, queries: make(map[string]*QueryStat)}
+}
+
+// Record adds one run of query that took duration and returned or changed
+// rows.
+func (s *QueryStats) Record(query string, duration time.Duration, rows int64, err error) {
+	query = strings.Join(strings.Fields(query), " ")
+	ms := float64(duration.Microseconds()) / 1000
+	slow := s.slow > 0 && duration >= s.slow
+
+	s.mu.Lock()
+	stat, ok := s.queries[query]
+	if !ok {
+		key := query
+		if len(s.queries) >= maxTrackedQueries {
+			key = otherQueries
+		}
+		if stat, ok = s.queries[key]; !ok {
+			stat = &QueryStat{Query: key}
+			s.queries[key] = stat
+		}
+	}
+	stat.Calls++
+	stat.Rows += rows
+	stat.TotalMS += ms
+	stat.MaxMS = max(stat.MaxMS, ms)
+	s.calls++
+	if err != nil {
+		stat.Errors++
+		s.errors++
+	}
+	if slow {
+		s.slowRun++
+	}
+	s.mu.Unlock()
+
+	if slow {
+		attrs := []any{"query", query, "duration_ms", ms, "rows", rows}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+		s.logger.Warn("Slow query", attrs...)
+	}
+}
+
+// Stats returns the totals and the queries with the most time spent.
+func (s *QueryStats) Stats() QueryStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	top := make([]QueryStat, 0, len(s.queries))
+	for _, stat := range s.queries {
+		entry := *stat
+		entry.MeanMS = entry.TotalMS / float64(entry.Calls)
+		top = append(top, entry)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].TotalMS != top[j].TotalMS {
+			return top[i].TotalMS > top[j].TotalMS
+		}
+		return top[i].Query < top[j].Query
+	})
+	if len(top) > topQueries {
+		top = top[:topQueries]
+	}
+	return QueryStatsSnapshot{
+		Queries: len(s.queries),
+		Calls:   s.calls,
+		Errors:  s.errors,
+		Slow:    s.slowRun,
+		Top:     top,
+	}
+}
+
+// wrap returns a connector whose connections record into s
+func (s *QueryStats) wrap(connector driver.Connector) driver.Connector {
+	return &instrumentedConnector{Connector: connector, stats: s}
+}
+
+// dsnConnector opens connections of a driver that has no connector of its
+// own
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c *dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+type instrumentedConnector struct {
+	driver.Connector
+	stats *QueryStats
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, stats: c.stats}, nil
+}
+
+// instrumentedConn times the statements run on a driver connection. Where
+// the driver lacks an optional interface it answers as database/sql does
+// for such drivers, so wrapping changes nothing but the recording.
+type instrumentedConn struct {
+	driver.Conn
+	stats *QueryStats
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	return c.stats.rows(query, start, rows, err)
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	return c.stats.result(query, start, result, err)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, stats: c.stats}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("driver does not support non-default transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// instrumentedStmt times the runs of a prepared statement
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+	stats *QueryStats
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err := queryer.QueryContext(ctx, args)
+		return s.stats.rows(s.query, start, rows, err)
+	}
+	rows, err := s.Stmt.Query(namedValues(args))
+	return s.stats.rows(s.query, start, rows, err)
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err := execer.ExecContext(ctx, args)
+		return s.stats.result(s.query, start, result, err)
+	}
+	result, err := s.Stmt.Exec(namedValues(args))
+	return s.stats.result(s.query, start, result, err)
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// rows records a failed query at once; a successful one is recorded when
+// its rows are closed, with the time spent fetching them
+func (s *QueryStats) rows(query string, start time.Time, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		if !errors.Is(err, driver.ErrSkip) {
+			s.Record(query, time.Since(start), 0, err)
+		}
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, query: query, stats: s, elapsed: time.Since(start)}, nil
+}
+
+func (s *QueryStats) result(query string, start time.Time, result driver.Result, err error) (driver.Result, error) {
+	duration := time.Since(start)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	var affected int64
+	if err == nil {
+		affected, _ = result.RowsAffected()
+	}
+	s.Record(query, duration, affected, err)
+	return result, err
+}
+
+// instrumentedRows counts the rows read and the time spent in the driver
+// reading them, leaving out whatever the caller does between rows
+type instrumentedRows struct {
+	driver.Rows
+	query   string
+	stats   *QueryStats
+	elapsed time.Duration
+	read    int64
+	err     error
+}
+
+func (r *instrumentedRows) Next(dest []driver.Value) error {
+	start := time.Now()
+	err := r.Rows.Next(dest)
+	r.elapsed += time.Since(start)
+	switch {
+	case err == nil:
+		r.read++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	r.stats.Record(r.query, r.elapsed, r.read, r.err)
+	return err
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryStatsRecordsStatements(t *testing.T) {
+	stats := NewQueryStats(0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	db, err := openSQLite("sqlite:"+filepath.Join(t.TempDir(), "instrument.db"), stats)
+	if err != nil {
+		t.Fatal("Failed to open SQLite database:", err)
+	}
+	defer db.Close()
+
+	if db.DriverName() != "sqlite3" {
+		t.Fatalf("Expected driver name sqlite3, got %s", db.DriverName())
+	}
+	db.MustExec(`CREATE TABLE things (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	for _, name := range []string{"a", "b", "c"} {
+		db.MustExec(`INSERT INTO things (name) VALUES ($1)`, name)
+	}
+	var names []string
+	if err := db.Select(&names, `SELECT name
+		FROM things ORDER BY id`); err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	if _, err := db.Exec(`INSERT INTO missing (name) VALUES ('x')`); err == nil {
+		t.Fatal("Expected insert into a missing table to fail")
+	}
+
+	snapshot := stats.Stats()
+	if snapshot.Errors != 1 || snapshot.Slow != 0 {
+		t.Errorf("Expected 1 error and no slow queries, got %+v", snapshot)
+	}
+	byQuery := make(map[string]QueryStat)
+	for _, stat := range snapshot.Top {
+		byQuery[stat.Query] = stat
+	}
+	// Whitespace is collapsed so one query reads the same wherever it wraps
+	if stat := byQuery["SELECT name FROM things ORDER BY id"]; stat.Calls != 1 || stat.Rows != 3 {
+		t.Errorf("Expected one select returning 3 rows, got %+v", stat)
+	}
+	if stat := byQuery["INSERT INTO things (name) VALUES ($1)"]; stat.Calls != 3 || stat.Rows != 3 {
+		t.Errorf("Expected 3 inserts changing 3 rows, got %+v", stat)
+	}
+	if stat := byQuery["INSERT INTO missing (name) VALUES ('x')"]; stat.Calls != 1 || stat.Errors != 1 {
+		t.Errorf("Expected the failed insert counted as an error, got %+v", stat)
+	}
+}
+
+func TestQueryStatsLogsSlowQueries(t *testing.T) {
+	var logs bytes.Buffer
+	stats := NewQueryStats(100*time.Millisecond, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	stats.Record("SELECT 1", 10*time.Millisecond, 1, nil)
+	stats.Record("SELECT * FROM tickets\n\tWHERE event_id = $1", 250*time.Millisecond, 7, nil)
+
+	if stats.Stats().Slow != 1 {
+		t.Errorf("Expected 1 slow query, got %+v", stats.Stats())
+	}
+	if strings.Contains(logs.String(), "SELECT 1") {
+		t.Errorf("Expected the fast query not logged, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Slow query") || !strings.Contains(logs.String(), "WHERE event_id = $1") {
+		t.Errorf("Expected the slow query logged, got %s", logs.String())
+	}
+}
+
+func TestQueryStatsBoundsTrackedQueries(t *testing.T) {
+	stats := NewQueryStats(0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for i := 0; i < maxTrackedQueries+10; i++ {
+		stats.Record("SELECT "+strings.Repeat("x", i+1), time.Millisecond, 0, nil)
+	}
+	// One more slow query than the rest, so it lists first
+	stats.Record(otherQueries, time.Second, 0, nil)
+
+	snapshot := stats.Stats()
+	if snapshot.Queries != maxTrackedQueries+1 || snapshot.Calls != maxTrackedQueries+11 {
+		t.Errorf("Expected %d tracked queries, got %+v", maxTrackedQueries+1, snapshot)
+	}
+	if len(snapshot.Top) != topQueries || snapshot.Top[0].Query != otherQueries || snapshot.Top[0].Calls != 11 {
+		t.Errorf("Expected the overflow listed first with 11 calls, got %+v", snapshot.Top[0])
+	}
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PaymentPartitionsAhead is how many months past the current one have a
+// payments partition ready. The partitioning migration creates the same.
+const PaymentPartitionsAhead = 3
+
+// MonthlyPartition is the partition of a range-partitioned table holding
+// the rows of one month, From inclusive and To exclusive.
+type MonthlyPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// PaymentPartitions returns the payments partitions for the month of now
+// and the ahead months after it. Months follow now's wall clock, as
+// created_at stores it.
+func PaymentPartitions(now time.Time, ahead int) []MonthlyPartition {
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	partitions := make([]MonthlyPartition, 0, ahead+1)
+	for i := 0; i <= ahead; i++ {
+		from := first.AddDate(0, i, 0)
+		partitions = append(partitions, MonthlyPartition{
+			Name: "payments_" + from.Format("2006_01"),
+			From: from,
+			To:   from.AddDate(0, 1, 0),
+		})
+	}
+	return partitions
+}
+
+// EnsurePaymentPartitions creates the payments partitions PaymentPartitions
+// lists that do not exist yet and returns their names. Only Postgres
+// partitions payments; on other databases it does nothing.
+//
+// A month whose rows already went to payments_default cannot get its own
+// partition until they are moved out, so such a month fails with an error
+// naming it and later months are still created.
+func EnsurePaymentPartitions(db *sqlx.DB, now time.Time, ahead int) ([]string, error) {
+	if db.DriverName() != "postgres" {
+		return nil, nil
+	}
+
+	var created []string
+	var failed error
+	for _, partition := range PaymentPartitions(now, ahead) {
+		var exists bool
+		if err := db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM pg_class WHERE relname = $1)`, partition.Name); err != nil {
+			return created, err
+		}
+		if exists {
+			continue
+		}
+
+		// IF NOT EXISTS lets instances starting together race safely
+		query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF payments FOR VALUES FROM ('%s') TO ('%s')`,
+			partition.Name, partition.From.Format(time.DateOnly), partition.To.Format(time.DateOnly))
+		if _, err := db.Exec(query); err != nil {
+			failed = fmt.Errorf("failed to create %s: %w", partition.Name, err)
+			continue
+		}
+		created = append(created, partition.Name)
+	}
+	return created, failed
+}
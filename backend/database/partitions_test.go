@@ -0,0 +1,40 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPaymentPartitions(t *testing.T) {
+	// Months roll over into the next year and follow the wall clock
+	now := time.Date(2026, 11, 30, 23, 30, 0, 0, time.FixedZone("KST", 9*60*60))
+	partitions := PaymentPartitions(now, 2)
+
+	want := []string{"payments_2026_11", "payments_2026_12", "payments_2027_01"}
+	if len(partitions) != len(want) {
+		t.Fatalf("Expected %d partitions, got %+v", len(want), partitions)
+	}
+	for i, partition := range partitions {
+		if partition.Name != want[i] {
+			t.Errorf("Expected %s, got %s", want[i], partition.Name)
+		}
+	}
+	if from, to := partitions[2].From, partitions[2].To; !from.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected bounds %v to %v", from, to)
+	}
+}
+
+func TestEnsurePaymentPartitionsSQLite(t *testing.T) {
+	db, err := OpenSQLite("sqlite:" + filepath.Join(t.TempDir(), "partitions.db"))
+	if err != nil {
+		t.Fatal("Failed to open SQLite database:", err)
+	}
+	defer db.Close()
+
+	// SQLite does not partition, so there is nothing to create
+	created, err := EnsurePaymentPartitions(db, time.Now(), PaymentPartitionsAhead)
+	if err != nil || len(created) != 0 {
+		t.Errorf("Expected nothing created, got %v (%v)", created, err)
+	}
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/models"
+)
+
+// SchemaVersion is the latest migration this build was written against.
+// Bump it with every migration added to db/migrations.
+const SchemaVersion = "20261016000052"
+
+// schemaTables maps each table to the model its rows are scanned into, so
+// every column a model reads is checked against the live database.
+var schemaTables = map[string]interface{}{
+	"users":                  models.User{},
+	"account_claims":         models.AccountClaim{},
+	"email_changes":          models.EmailChange{},
+	"lnurl_auth_challenges":  models.LNURLAuthChallenge{},
+	"events":                 models.Event{},
+	"event_addons":           models.AddOn{},
+	"event_form_fields":      models.FormField{},
+	"event_access_codes":     models.EventAccessCode{},
+	"event_allowlist":        models.EventAllowlistEntry{},
+	"event_translations":     models.EventTranslation{},
+	"event_reschedules":      models.EventReschedule{},
+	"event_cancellations":    models.EventCancellation{},
+	"event_price_changes":    models.PriceChange{},
+	"pricing_rules":          models.PricingRule{},
+	"tickets":                models.Ticket{},
+	"ticket_addons":          models.TicketAddOn{},
+	"ticket_answers":         models.TicketAnswer{},
+	"ticket_gifts":           models.TicketGift{},
+	"ticket_scans":           models.TicketScan{},
+	"orders":                 models.Order{},
+	"payments":               models.Payment{},
+	"payment_line_items":     models.PaymentLineItem{},
+	"receipts":               models.Receipt{},
+	"uma_request_invoices":   models.UMARequestInvoice{},
+	"exchange_rates":         models.ExchangeRate{},
+	"cashu_redemptions":      models.CashuRedemption{},
+	"nwc_connections":        models.NWCConnection{},
+	"wallet_claims":          models.WalletClaim{},
+	"purchase_attestations":  models.PurchaseAttestation{},
+	"fraud_flags":            models.FraudFlag{},
+	"disputes":               models.Dispute{},
+	"notification_templates": models.NotificationTemplate{},
+	"organizer_profiles":     models.OrganizerProfile{},
+	"organizer_fees":         models.OrganizerFee{},
+	"affiliates":             models.Affiliate{},
+	"short_links":            models.ShortLink{},
+	"scanner_devices":        models.ScannerDevice{},
+	"inventory_holds":        models.InventoryHold{},
+	"ledger_accounts":        models.LedgerAccount{},
+	"ledger_entries":         models.LedgerEntry{},
+	"settings":               models.Setting{},
+	"webhook_events":         models.WebhookEvent{},
+	"archived_records":       models.ArchivedRecord{},
+	"admin_notifications":    models.AdminNotification{},
+	"alert_connectors":       models.AlertConnector{},
+	"status_incidents":       models.StatusIncident{},
+}
+
+// SchemaReport compares the live database with what this build expects.
+// Version is the latest applied migration, empty when the database has no
+// migration history. MissingColumns lists, per table, the columns models
+// read that the table lacks; a missing table lists all of them.
+type SchemaReport struct {
+	Version        string
+	MissingColumns map[string][]string
+}
+
+// Behind reports whether the latest applied migration is older than the
+// one this build was written against. Newer databases are compatible as
+// long as no column is missing, so the previous release keeps running
+// while the next one's migrations are applied.
+func (r *SchemaReport) Behind() bool {
+	return r.Version < SchemaVersion
+}
+
+// Compatible reports whether this build can run against the database.
+func (r *SchemaReport) Compatible() bool {
+	return !r.Behind() && len(r.MissingColumns) == 0
+}
+
+// Problems describes each incompatibility, for logging.
+func (r *SchemaReport) Problems() []string {
+	var problems []string
+	if r.Behind() {
+		version := r.Version
+		if version == "" {
+			version = "none"
+		}
+		problems = append(problems, fmt.Sprintf("latest migration is %s, expected %s", version, SchemaVersion))
+	}
+	tables := make([]string, 0, len(r.MissingColumns))
+	for table := range r.MissingColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		problems = append(problems, fmt.Sprintf("%s is missing %s", table, strings.Join(r.MissingColumns[table], ", ")))
+	}
+	return problems
+}
+
+// CheckSchema reads the applied migrations and the columns of every model
+// table from db. It works on Postgres and SQLite.
+func CheckSchema(db *sqlx.DB) (*SchemaReport, error) {
+	report := &SchemaReport{MissingColumns: make(map[string][]string)}
+
+	version, err := latestMigration(db)
+	if err != nil {
+		return nil, err
+	}
+	report.Version = version
+
+	for table, model := range schemaTables {
+		live, err := tableColumns(db, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		var missing []string
+		for _, column := range modelColumns(reflect.TypeOf(model)) {
+			if !live[column] {
+				missing = append(missing, column)
+			}
+		}
+		if len(missing) > 0 {
+			report.MissingColumns[table] = missing
+		}
+	}
+	return report, nil
+}
+
+// latestMigration returns the newest version in dbmate's schema_migrations
+// table, or "" when there is none
+func latestMigration(db *sqlx.DB) (string, error) {
+	exists, err := tableColumns(db, "schema_migrations")
+	if err != nil {
+		return "", err
+	}
+	if len(exists) == 0 {
+		return "", nil
+	}
+
+	var version string
+	err = db.Get(&version, `SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return version, err
+}
+
+// tableColumns returns the columns of table, none when it does not exist
+func tableColumns(db *sqlx.DB, table string) (map[string]bool, error) {
+	query := `SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1`
+	if db.DriverName() == "sqlite3" {
+		query = `SELECT name FROM pragma_table_info($1)`
+	}
+
+	var names []string
+	if err := db.Select(&names, query, table); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	return columns, nil
+}
+
+// modelColumns returns the db-tagged fields of a model, including those of
+// embedded structs, which sqlx flattens
+func modelColumns(t reflect.Type) []string {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, modelColumns(field.Type)...)
+			continue
+		}
+		if tag == "" || tag == "-" {
+			continue
+		}
+		columns = append(columns, tag)
+	}
+	return columns
+}
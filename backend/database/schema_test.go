@@ -0,0 +1,90 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersionIsLatestMigration(t *testing.T) {
+	files, err := filepath.Glob("../db/migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatal("No migrations found:", err)
+	}
+	latest, _, _ := strings.Cut(filepath.Base(files[len(files)-1]), "_")
+	if latest != SchemaVersion {
+		t.Errorf("SchemaVersion is %s but the latest migration is %s", SchemaVersion, latest)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	db, err := OpenSQLite("sqlite:" + filepath.Join(t.TempDir(), "schema.db"))
+	if err != nil {
+		t.Fatal("Failed to open SQLite database:", err)
+	}
+	defer db.Close()
+
+	// Without any migration history or tables nothing matches
+	report, err := CheckSchema(db)
+	if err != nil {
+		t.Fatal("Failed to check schema:", err)
+	}
+	if report.Compatible() || report.Version != "" || len(report.MissingColumns) != len(schemaTables) {
+		t.Errorf("Expected an empty database to be incompatible, got %+v", report)
+	}
+
+	files, err := filepath.Glob("../db/sqlite/migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatal("No SQLite migrations found:", err)
+	}
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(migration), "-- migrate:down")
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("Failed to apply %s: %v", file, err)
+		}
+	}
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (version VARCHAR(128) PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES ('20261016000001'), ($1)`, SchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every column the models read exists after all migrations
+	report, err = CheckSchema(db)
+	if err != nil {
+		t.Fatal("Failed to check schema:", err)
+	}
+	if !report.Compatible() {
+		t.Fatalf("Expected the migrated schema to be compatible: %v", report.Problems())
+	}
+
+	// A newer database is fine; a missing column or an older one is not
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES ('29991231000000')`); err != nil {
+		t.Fatal(err)
+	}
+	if report, _ := CheckSchema(db); !report.Compatible() {
+		t.Errorf("Expected a newer schema to be compatible: %v", report.Problems())
+	}
+	if _, err := db.Exec(`ALTER TABLE nwc_connections DROP COLUMN connection_uri`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version >= $1`, SchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	report, err = CheckSchema(db)
+	if err != nil {
+		t.Fatal("Failed to check schema:", err)
+	}
+	problems := report.Problems()
+	if report.Compatible() || len(problems) != 2 ||
+		!strings.Contains(problems[0], "latest migration is 20261016000001") ||
+		problems[1] != "nwc_connections is missing connection_uri" {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+}
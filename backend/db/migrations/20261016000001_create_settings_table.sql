@@ -0,0 +1,10 @@
+-- migrate:up
+CREATE TABLE settings (
+    key text PRIMARY KEY,
+    value jsonb NOT NULL,
+    updated_by text NOT NULL DEFAULT '',
+    updated_at timestamp without time zone DEFAULT now()
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS settings;
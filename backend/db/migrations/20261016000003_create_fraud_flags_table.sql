@@ -0,0 +1,16 @@
+-- migrate:up
+CREATE TABLE fraud_flags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL,
+    client_ip TEXT NOT NULL DEFAULT '',
+    action VARCHAR(20) NOT NULL,
+    signals JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_fraud_flags_action_created_at ON fraud_flags(action, created_at);
+
+-- migrate:down
+DROP TABLE IF EXISTS fraud_flags;
@@ -0,0 +1,9 @@
+-- migrate:up
+ALTER TABLE events ADD COLUMN pricing_mode VARCHAR(20) NOT NULL DEFAULT 'fixed';
+ALTER TABLE events ADD COLUMN min_price_sats BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN amount_sats BIGINT NOT NULL DEFAULT 0;
+
+-- migrate:down
+ALTER TABLE tickets DROP COLUMN amount_sats;
+ALTER TABLE events DROP COLUMN min_price_sats;
+ALTER TABLE events DROP COLUMN pricing_mode;
@@ -0,0 +1,25 @@
+-- migrate:up
+CREATE TABLE payment_line_items (
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    unit_amount_sats BIGINT NOT NULL,
+    amount_sats BIGINT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_payment_line_items_payment_id ON payment_line_items(payment_id);
+
+-- Receipt numbers are gapless and assigned in order of issue
+CREATE TABLE receipts (
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER NOT NULL UNIQUE REFERENCES payments(id),
+    receipt_number BIGINT NOT NULL UNIQUE,
+    issued_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS payment_line_items;
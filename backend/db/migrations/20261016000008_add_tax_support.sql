@@ -0,0 +1,25 @@
+-- migrate:up
+ALTER TABLE events ADD COLUMN tax_basis_points INTEGER NOT NULL DEFAULT 0 CHECK (tax_basis_points >= 0 AND tax_basis_points <= 10000);
+ALTER TABLE events ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN tax_jurisdiction VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Tax is fixed when the invoice is created so later rate changes do not
+-- rewrite what was charged
+ALTER TABLE payments ADD COLUMN taxable_sats BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_sats BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_basis_points INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE payments ADD COLUMN tax_jurisdiction VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_payments_paid_at ON payments(paid_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_payments_paid_at;
+ALTER TABLE payments DROP COLUMN tax_jurisdiction;
+ALTER TABLE payments DROP COLUMN tax_inclusive;
+ALTER TABLE payments DROP COLUMN tax_basis_points;
+ALTER TABLE payments DROP COLUMN tax_sats;
+ALTER TABLE payments DROP COLUMN taxable_sats;
+ALTER TABLE events DROP COLUMN tax_jurisdiction;
+ALTER TABLE events DROP COLUMN tax_inclusive;
+ALTER TABLE events DROP COLUMN tax_basis_points;
@@ -0,0 +1,41 @@
+-- migrate:up
+-- Double-entry ledger of the funds the platform holds. Accounts are created
+-- on first use; entries are never updated or deleted.
+CREATE TABLE ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'income', 'expense', 'equity')),
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now()
+);
+
+-- A payment has at most one entry of each kind (its sale and its refund)
+CREATE TABLE ledger_entries (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('sale', 'refund', 'payout')),
+    payment_id INTEGER REFERENCES payments(id),
+    organizer_id INTEGER REFERENCES users(id),
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now(),
+    CONSTRAINT ledger_entries_kind_payment_id_key UNIQUE (kind, payment_id)
+);
+
+CREATE INDEX idx_ledger_entries_occurred_at ON ledger_entries(occurred_at);
+
+CREATE TABLE ledger_postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL REFERENCES ledger_entries(id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(id),
+    debit_sats BIGINT NOT NULL DEFAULT 0 CHECK (debit_sats >= 0),
+    credit_sats BIGINT NOT NULL DEFAULT 0 CHECK (credit_sats >= 0),
+    CONSTRAINT ledger_postings_one_side CHECK ((debit_sats = 0) <> (credit_sats = 0))
+);
+
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_accounts;
@@ -0,0 +1,16 @@
+-- migrate:up
+-- Tickets claimed into a mobile wallet. A claim offer is a one-time secret,
+-- stored as its SHA-256 hash, that binds the ticket to the device public key
+-- presented with it; check-in then requires a signature by that device.
+CREATE TABLE wallet_claims (
+    ticket_id INTEGER PRIMARY KEY REFERENCES tickets(id) ON DELETE CASCADE,
+    secret_hash VARCHAR(64),
+    secret_expires_at TIMESTAMP WITHOUT TIME ZONE,
+    device_public_key VARCHAR(64),
+    claimed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS wallet_claims;
@@ -0,0 +1,31 @@
+-- migrate:up
+CREATE TABLE event_form_fields (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    label VARCHAR(255) NOT NULL,
+    field_type VARCHAR(20) NOT NULL CHECK (field_type IN ('text', 'number', 'select', 'checkbox')),
+    options JSONB NOT NULL DEFAULT '[]',
+    required BOOLEAN NOT NULL DEFAULT false,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_event_form_fields_event_id ON event_form_fields(event_id);
+
+-- Answers keep the field's label at purchase so later edits to the form do
+-- not change what a buyer was asked
+CREATE TABLE ticket_answers (
+    id SERIAL PRIMARY KEY,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    field_id INTEGER REFERENCES event_form_fields(id) ON DELETE SET NULL,
+    label VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX idx_ticket_answers_ticket_id ON ticket_answers(ticket_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS ticket_answers;
+DROP TABLE IF EXISTS event_form_fields;
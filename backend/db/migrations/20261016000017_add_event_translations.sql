@@ -0,0 +1,16 @@
+-- migrate:up
+-- Per-locale title and description; the events row holds the default
+-- language content that listings fall back to
+CREATE TABLE event_translations (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    UNIQUE (event_id, locale)
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS event_translations;
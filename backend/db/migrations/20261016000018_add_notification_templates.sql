@@ -0,0 +1,26 @@
+-- migrate:up
+-- Admin-configured notification templates. Every save adds a version and
+-- the highest version of a scope (organizer, kind, locale) is the one in
+-- use; a NULL organizer_id is the platform-wide template.
+CREATE TABLE notification_templates (
+    id SERIAL PRIMARY KEY,
+    organizer_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    version INTEGER NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- NULLs are distinct in unique constraints, so platform templates get
+-- their own index
+CREATE UNIQUE INDEX idx_notification_templates_platform_version
+    ON notification_templates(kind, locale, version) WHERE organizer_id IS NULL;
+CREATE UNIQUE INDEX idx_notification_templates_organizer_version
+    ON notification_templates(organizer_id, kind, locale, version) WHERE organizer_id IS NOT NULL;
+
+-- migrate:down
+DROP TABLE IF EXISTS notification_templates;
@@ -0,0 +1,16 @@
+-- migrate:up
+-- Users created by guest checkout. They have no password until they claim
+-- the account with the link sent once a ticket of theirs is paid.
+ALTER TABLE users ADD COLUMN is_guest BOOLEAN NOT NULL DEFAULT false;
+
+-- The outstanding claim link of a guest account; claiming consumes it
+CREATE TABLE account_claims (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS account_claims;
+ALTER TABLE users DROP COLUMN is_guest;
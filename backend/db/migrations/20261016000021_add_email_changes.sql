@@ -0,0 +1,16 @@
+-- migrate:up
+-- The address a user asked to change their email to. It replaces email
+-- only once confirmed with the link sent to it.
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
+
+-- The outstanding verification link of a pending email; confirming consumes it
+CREATE TABLE email_changes (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS email_changes;
+ALTER TABLE users DROP COLUMN pending_email;
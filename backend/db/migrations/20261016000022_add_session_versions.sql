@@ -0,0 +1,8 @@
+-- migrate:up
+-- Bumped whenever a user's sessions are revoked, such as on a password
+-- change. Tokens carry the version they were issued at and stop working
+-- once it moves on.
+ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;
+
+-- migrate:down
+ALTER TABLE users DROP COLUMN session_version;
@@ -0,0 +1,20 @@
+-- migrate:up
+-- Each time an event is postponed or moved: its old and new times, and the
+-- deadline until which buyers who cannot attend may ask for a refund
+CREATE TABLE event_reschedules (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    old_start_time TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    old_end_time TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    new_start_time TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    new_end_time TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    refund_until TIMESTAMP WITHOUT TIME ZONE,
+    rescheduled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_event_reschedules_event_id ON event_reschedules(event_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS event_reschedules;
@@ -0,0 +1,17 @@
+-- migrate:up
+-- Every check-in attempt at the door, admitted or not, for the live
+-- check-in dashboard. Codes that match no ticket are kept without one.
+CREATE TABLE ticket_scans (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL,
+    ticket_code VARCHAR(255) NOT NULL,
+    admitted BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    scanned_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_ticket_scans_event_id_scanned_at ON ticket_scans(event_id, scanned_at);
+
+-- migrate:down
+DROP TABLE IF EXISTS ticket_scans;
@@ -0,0 +1,26 @@
+-- migrate:up
+-- Door scanners of an event. An admin creates one with a short-lived
+-- pairing code; the device redeems it for its own token, which can only
+-- check tickets in to that event. Both secrets are stored hashed.
+CREATE TABLE scanner_devices (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    pairing_hash VARCHAR(64) UNIQUE,
+    pairing_expires_at TIMESTAMP WITHOUT TIME ZONE,
+    token_hash VARCHAR(64) UNIQUE,
+    registered_at TIMESTAMP WITHOUT TIME ZONE,
+    last_seen_at TIMESTAMP WITHOUT TIME ZONE,
+    revoked_at TIMESTAMP WITHOUT TIME ZONE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_scanner_devices_event_id ON scanner_devices(event_id);
+
+-- The device a scan was made with, when made through the scanner routes
+ALTER TABLE ticket_scans ADD COLUMN scanner_device_id INTEGER REFERENCES scanner_devices(id) ON DELETE SET NULL;
+
+-- migrate:down
+ALTER TABLE ticket_scans DROP COLUMN scanner_device_id;
+DROP TABLE IF EXISTS scanner_devices;
@@ -0,0 +1,6 @@
+-- migrate:up
+-- Complimentary tickets issued by organizers, free of charge
+ALTER TABLE tickets ADD COLUMN is_comp BOOLEAN NOT NULL DEFAULT false;
+
+-- migrate:down
+ALTER TABLE tickets DROP COLUMN is_comp;
@@ -0,0 +1,15 @@
+-- migrate:up
+-- The public page of an event organizer, found by its slug. Organizers
+-- keep their own profile.
+CREATE TABLE organizer_profiles (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    slug VARCHAR(60) NOT NULL UNIQUE,
+    display_name VARCHAR(100) NOT NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    website_url VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS organizer_profiles;
@@ -0,0 +1,23 @@
+-- migrate:up
+-- Short links to an event page (/e/{code}) or a ticket (/t/{code}). Each
+-- event and ticket has at most one generated link; admins add custom codes
+-- for marketing. Clicks are counted as links are followed.
+CREATE TABLE short_links (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(40) NOT NULL UNIQUE,
+    event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER REFERENCES tickets(id) ON DELETE CASCADE,
+    custom BOOLEAN NOT NULL DEFAULT false,
+    clicks INTEGER NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMP WITHOUT TIME ZONE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CHECK ((event_id IS NULL) <> (ticket_id IS NULL))
+);
+
+CREATE INDEX idx_short_links_event_id ON short_links(event_id);
+CREATE UNIQUE INDEX idx_short_links_generated_event ON short_links(event_id) WHERE NOT custom AND event_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_short_links_generated_ticket ON short_links(ticket_id) WHERE NOT custom AND ticket_id IS NOT NULL;
+
+-- migrate:down
+DROP TABLE IF EXISTS short_links;
@@ -0,0 +1,20 @@
+-- migrate:up
+-- Where a checkout came from: a ref code and the UTM parameters of the
+-- campaign link the buyer followed, empty when there were none
+ALTER TABLE orders ADD COLUMN ref VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN utm_source VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN utm_medium VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN utm_campaign VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN utm_term VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN utm_content VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_orders_event_id ON orders(event_id);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_orders_event_id;
+ALTER TABLE orders DROP COLUMN utm_content;
+ALTER TABLE orders DROP COLUMN utm_term;
+ALTER TABLE orders DROP COLUMN utm_campaign;
+ALTER TABLE orders DROP COLUMN utm_medium;
+ALTER TABLE orders DROP COLUMN utm_source;
+ALTER TABLE orders DROP COLUMN ref;
@@ -0,0 +1,28 @@
+-- migrate:up
+-- A ticket bought for someone else. The buyer owns the ticket until the
+-- recipient accepts it; the claim link is sent at deliver_at once the
+-- ticket is paid, to the recipient's email or, without one, to the buyer
+-- to pass on. Only the hash of the link's one-time secret is stored.
+CREATE TABLE ticket_gifts (
+    id SERIAL PRIMARY KEY,
+    ticket_id INTEGER NOT NULL UNIQUE REFERENCES tickets(id) ON DELETE CASCADE,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_email VARCHAR(255) NOT NULL DEFAULT '',
+    recipient_uma_address VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    deliver_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'delivered', 'claimed')),
+    secret_hash VARCHAR(64) UNIQUE,
+    recipient_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    delivered_at TIMESTAMP WITHOUT TIME ZONE,
+    claimed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_ticket_gifts_due ON ticket_gifts(status, deliver_at);
+CREATE INDEX idx_ticket_gifts_sender_id ON ticket_gifts(sender_id);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_ticket_gifts_sender_id;
+DROP INDEX IF EXISTS idx_ticket_gifts_due;
+DROP TABLE IF EXISTS ticket_gifts;
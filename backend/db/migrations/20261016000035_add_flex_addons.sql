@@ -0,0 +1,12 @@
+-- migrate:up
+-- A flex add-on entitles the buyer to cancel their ticket for a refund
+-- until flex_cutoff_hours before the event starts. Line items keep the
+-- cutoff bought and when the ticket was cancelled with it.
+ALTER TABLE event_addons ADD COLUMN flex_cutoff_hours INTEGER CHECK (flex_cutoff_hours >= 0);
+ALTER TABLE ticket_addons ADD COLUMN flex_cutoff_hours INTEGER;
+ALTER TABLE ticket_addons ADD COLUMN flex_used_at TIMESTAMP WITHOUT TIME ZONE;
+
+-- migrate:down
+ALTER TABLE ticket_addons DROP COLUMN flex_used_at;
+ALTER TABLE ticket_addons DROP COLUMN flex_cutoff_hours;
+ALTER TABLE event_addons DROP COLUMN flex_cutoff_hours;
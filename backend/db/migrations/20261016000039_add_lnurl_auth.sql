@@ -0,0 +1,30 @@
+-- migrate:up
+-- The LNURL-auth linking key (hex compressed secp256k1 public key) a user
+-- logs in with from a Lightning wallet. Users who signed up this way have
+-- a placeholder email under lnurl-auth.invalid until they set their own.
+ALTER TABLE users ADD COLUMN lnurl_auth_key VARCHAR(66);
+CREATE UNIQUE INDEX idx_users_lnurl_auth_key ON users(lnurl_auth_key);
+
+-- Login challenges (k1) handed to wallets. The browser that asked for one
+-- polls with its session secret, of which only the hash is stored, and
+-- gets a token once the wallet has signed k1. A challenge asked for by a
+-- logged-in user links the wallet to them instead.
+CREATE TABLE lnurl_auth_challenges (
+    id SERIAL PRIMARY KEY,
+    k1 VARCHAR(64) NOT NULL UNIQUE,
+    session_hash VARCHAR(64) NOT NULL UNIQUE,
+    link_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    verified_at TIMESTAMP WITHOUT TIME ZONE,
+    consumed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_lnurl_auth_challenges_expires_at ON lnurl_auth_challenges(expires_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_lnurl_auth_challenges_expires_at;
+DROP TABLE IF EXISTS lnurl_auth_challenges;
+DROP INDEX IF EXISTS idx_users_lnurl_auth_key;
+ALTER TABLE users DROP COLUMN lnurl_auth_key;
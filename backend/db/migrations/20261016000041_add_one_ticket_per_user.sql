@@ -0,0 +1,13 @@
+-- migrate:up
+-- Events that sell at most one ticket per buyer. Tickets bought under the
+-- limit are marked so a partial unique index settles concurrent purchases;
+-- failed, expired and cancelled tickets do not count.
+ALTER TABLE events ADD COLUMN one_ticket_per_user BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tickets ADD COLUMN one_per_user BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX idx_tickets_one_per_user ON tickets(event_id, user_id)
+    WHERE one_per_user AND payment_status IN ('paid', 'pending', 'review', 'disputed');
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_tickets_one_per_user;
+ALTER TABLE tickets DROP COLUMN one_per_user;
+ALTER TABLE events DROP COLUMN one_ticket_per_user;
@@ -0,0 +1,26 @@
+-- migrate:up
+-- Rows moved out of the hot tables by the retention archiver: settled
+-- payments, tickets and ended events with the rows that hang off them.
+-- Each row is kept as a JSON object of its columns under its original
+-- table and ID; event_id groups everything archived for an event.
+CREATE TABLE archived_records (
+    id SERIAL PRIMARY KEY,
+    table_name VARCHAR(50) NOT NULL,
+    record_id INTEGER NOT NULL,
+    event_id INTEGER,
+    data TEXT NOT NULL,
+    archived_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CONSTRAINT archived_records_table_name_record_id_key UNIQUE (table_name, record_id)
+);
+
+CREATE INDEX idx_archived_records_event_id ON archived_records(event_id);
+
+-- Ledger entries are the book of record and outlive the payments they
+-- book, so payment_id becomes a plain reference that may point into the
+-- archive
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_payment_id_fkey;
+
+-- migrate:down
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id);
+DROP INDEX IF EXISTS idx_archived_records_event_id;
+DROP TABLE IF EXISTS archived_records;
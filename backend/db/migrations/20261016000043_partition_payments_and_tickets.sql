@@ -0,0 +1,133 @@
+-- migrate:up
+-- Tickets are split into 16 partitions by a hash of event_id and payments
+-- into one partition per month of created_at, so each partition's indexes
+-- stay small and vacuum works through a busy on-sale's rows on their own.
+-- database.EnsurePaymentPartitions keeps the coming months' partitions
+-- created; payments_default catches anything outside them.
+--
+-- A foreign key can only reference a partitioned table through a unique
+-- key that includes the partition key, so the references to tickets(id)
+-- and payments(id) become plain columns. Tickets and payments are only
+-- deleted by the archiver, which moves the rows that reference them first.
+ALTER TABLE payments DROP CONSTRAINT payments_ticket_id_fkey;
+ALTER TABLE uma_request_invoices DROP CONSTRAINT uma_request_invoices_ticket_id_fkey;
+ALTER TABLE fraud_flags DROP CONSTRAINT fraud_flags_ticket_id_fkey;
+ALTER TABLE purchase_attestations DROP CONSTRAINT purchase_attestations_ticket_id_fkey;
+ALTER TABLE ticket_scans DROP CONSTRAINT ticket_scans_ticket_id_fkey;
+ALTER TABLE short_links DROP CONSTRAINT short_links_ticket_id_fkey;
+ALTER TABLE ticket_gifts DROP CONSTRAINT ticket_gifts_ticket_id_fkey;
+ALTER TABLE ticket_addons DROP CONSTRAINT ticket_addons_ticket_id_fkey;
+ALTER TABLE ticket_answers DROP CONSTRAINT ticket_answers_ticket_id_fkey;
+ALTER TABLE wallet_claims DROP CONSTRAINT wallet_claims_ticket_id_fkey;
+ALTER TABLE payment_line_items DROP CONSTRAINT payment_line_items_payment_id_fkey;
+ALTER TABLE receipts DROP CONSTRAINT receipts_payment_id_fkey;
+ALTER TABLE cashu_redemptions DROP CONSTRAINT cashu_redemptions_payment_id_fkey;
+ALTER TABLE disputes DROP CONSTRAINT disputes_payment_id_fkey;
+
+-- Ticket codes name their event, so a code unique per event is unique
+ALTER TABLE tickets RENAME TO tickets_unpartitioned;
+ALTER SEQUENCE tickets_id_seq OWNED BY NONE;
+CREATE TABLE tickets (LIKE tickets_unpartitioned INCLUDING DEFAULTS) PARTITION BY HASH (event_id);
+DO $$
+BEGIN
+    FOR i IN 0..15 LOOP
+        EXECUTE format('CREATE TABLE tickets_p%s PARTITION OF tickets FOR VALUES WITH (MODULUS 16, REMAINDER %s)', lpad(i::text, 2, '0'), i);
+    END LOOP;
+END $$;
+INSERT INTO tickets SELECT * FROM tickets_unpartitioned;
+DROP TABLE tickets_unpartitioned;
+ALTER SEQUENCE tickets_id_seq OWNED BY tickets.id;
+
+ALTER TABLE tickets ADD CONSTRAINT tickets_pkey PRIMARY KEY (id, event_id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_event_id_ticket_code_key UNIQUE (event_id, ticket_code);
+ALTER TABLE tickets ADD CONSTRAINT tickets_event_id_fkey FOREIGN KEY (event_id) REFERENCES events(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_order_id_fkey FOREIGN KEY (order_id) REFERENCES orders(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_price_change_id_fkey FOREIGN KEY (price_change_id) REFERENCES event_price_changes(id) ON DELETE SET NULL;
+-- The (event_id, ticket_code) key serves lookups by event; legacy codes
+-- carry no event and are looked up by code alone
+CREATE INDEX idx_tickets_ticket_code ON tickets(ticket_code);
+CREATE INDEX idx_tickets_payment_status ON tickets(payment_status);
+CREATE INDEX idx_tickets_user_id ON tickets(user_id);
+CREATE INDEX idx_tickets_order_id ON tickets(order_id);
+CREATE UNIQUE INDEX idx_tickets_one_per_user ON tickets(event_id, user_id)
+    WHERE one_per_user AND payment_status IN ('paid', 'pending', 'review', 'disputed');
+
+-- Payments get a partition for every month from the oldest payment's to
+-- three months ahead
+ALTER TABLE payments RENAME TO payments_unpartitioned;
+ALTER SEQUENCE payments_id_seq OWNED BY NONE;
+UPDATE payments_unpartitioned SET created_at = COALESCE(updated_at, LOCALTIMESTAMP) WHERE created_at IS NULL;
+CREATE TABLE payments (LIKE payments_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (created_at);
+CREATE TABLE payments_default PARTITION OF payments DEFAULT;
+DO $$
+DECLARE
+    first_day TIMESTAMP := date_trunc('month', COALESCE((SELECT min(created_at) FROM payments_unpartitioned), LOCALTIMESTAMP));
+BEGIN
+    WHILE first_day <= date_trunc('month', LOCALTIMESTAMP) + INTERVAL '3 months' LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF payments FOR VALUES FROM (%L) TO (%L)',
+            'payments_' || to_char(first_day, 'YYYY_MM'), first_day, first_day + INTERVAL '1 month');
+        first_day := first_day + INTERVAL '1 month';
+    END LOOP;
+END $$;
+INSERT INTO payments SELECT * FROM payments_unpartitioned;
+DROP TABLE payments_unpartitioned;
+ALTER SEQUENCE payments_id_seq OWNED BY payments.id;
+
+ALTER TABLE payments ADD CONSTRAINT payments_pkey PRIMARY KEY (id, created_at);
+ALTER TABLE payments ADD CONSTRAINT payments_exchange_rate_id_fkey FOREIGN KEY (exchange_rate_id) REFERENCES exchange_rates(id);
+CREATE INDEX idx_payments_invoice_id ON payments(invoice_id);
+CREATE INDEX idx_payments_ticket_id ON payments(ticket_id);
+CREATE INDEX idx_payments_paid_at ON payments(paid_at);
+CREATE INDEX idx_payments_pending_expires_at ON payments(expires_at) WHERE status = 'pending';
+
+-- migrate:down
+ALTER TABLE payments RENAME TO payments_partitioned;
+ALTER SEQUENCE payments_id_seq OWNED BY NONE;
+CREATE TABLE payments (LIKE payments_partitioned INCLUDING DEFAULTS);
+ALTER TABLE payments ALTER COLUMN created_at DROP NOT NULL;
+INSERT INTO payments SELECT * FROM payments_partitioned;
+DROP TABLE payments_partitioned;
+ALTER SEQUENCE payments_id_seq OWNED BY payments.id;
+
+ALTER TABLE payments ADD CONSTRAINT payments_pkey PRIMARY KEY (id);
+ALTER TABLE payments ADD CONSTRAINT payments_exchange_rate_id_fkey FOREIGN KEY (exchange_rate_id) REFERENCES exchange_rates(id);
+CREATE INDEX idx_payments_invoice_id ON payments(invoice_id);
+CREATE INDEX idx_payments_ticket_id ON payments(ticket_id);
+CREATE INDEX idx_payments_paid_at ON payments(paid_at);
+CREATE INDEX idx_payments_pending_expires_at ON payments(expires_at) WHERE status = 'pending';
+
+ALTER TABLE tickets RENAME TO tickets_partitioned;
+ALTER SEQUENCE tickets_id_seq OWNED BY NONE;
+CREATE TABLE tickets (LIKE tickets_partitioned INCLUDING DEFAULTS);
+INSERT INTO tickets SELECT * FROM tickets_partitioned;
+DROP TABLE tickets_partitioned;
+ALTER SEQUENCE tickets_id_seq OWNED BY tickets.id;
+
+ALTER TABLE tickets ADD CONSTRAINT tickets_pkey PRIMARY KEY (id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_ticket_code_key UNIQUE (ticket_code);
+ALTER TABLE tickets ADD CONSTRAINT tickets_event_id_fkey FOREIGN KEY (event_id) REFERENCES events(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_order_id_fkey FOREIGN KEY (order_id) REFERENCES orders(id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_price_change_id_fkey FOREIGN KEY (price_change_id) REFERENCES event_price_changes(id) ON DELETE SET NULL;
+CREATE INDEX idx_tickets_event_id ON tickets(event_id);
+CREATE INDEX idx_tickets_payment_status ON tickets(payment_status);
+CREATE INDEX idx_tickets_user_id ON tickets(user_id);
+CREATE INDEX idx_tickets_order_id ON tickets(order_id);
+CREATE UNIQUE INDEX idx_tickets_one_per_user ON tickets(event_id, user_id)
+    WHERE one_per_user AND payment_status IN ('paid', 'pending', 'review', 'disputed');
+
+ALTER TABLE disputes ADD CONSTRAINT disputes_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id);
+ALTER TABLE cashu_redemptions ADD CONSTRAINT cashu_redemptions_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE;
+ALTER TABLE receipts ADD CONSTRAINT receipts_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id);
+ALTER TABLE payment_line_items ADD CONSTRAINT payment_line_items_payment_id_fkey FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE;
+ALTER TABLE wallet_claims ADD CONSTRAINT wallet_claims_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE ticket_answers ADD CONSTRAINT ticket_answers_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE ticket_addons ADD CONSTRAINT ticket_addons_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE ticket_gifts ADD CONSTRAINT ticket_gifts_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE short_links ADD CONSTRAINT short_links_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE ticket_scans ADD CONSTRAINT ticket_scans_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE SET NULL;
+ALTER TABLE purchase_attestations ADD CONSTRAINT purchase_attestations_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE;
+ALTER TABLE fraud_flags ADD CONSTRAINT fraud_flags_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE SET NULL;
+ALTER TABLE uma_request_invoices ADD CONSTRAINT uma_request_invoices_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id);
+ALTER TABLE payments ADD CONSTRAINT payments_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id);
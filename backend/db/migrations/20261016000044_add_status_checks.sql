@@ -0,0 +1,32 @@
+-- migrate:up
+-- Ticket and payment statuses are limited to the ones the application
+-- knows (models.TicketStatuses and models.PaymentStatuses). Existing
+-- values are normalized first: case and surrounding space are dropped and
+-- the US spelling "canceled" becomes "cancelled". Anything still unknown,
+-- such as a hand-set "refunded", becomes "cancelled" on a ticket, which
+-- holds no seat and admits no one, and "failed" on a payment.
+UPDATE tickets SET payment_status = LOWER(TRIM(payment_status))
+WHERE payment_status <> LOWER(TRIM(payment_status));
+UPDATE tickets SET payment_status = 'cancelled'
+WHERE payment_status IS NULL
+   OR payment_status NOT IN ('pending', 'paid', 'review', 'disputed', 'failed', 'expired', 'cancelled');
+
+UPDATE payments SET status = LOWER(TRIM(status))
+WHERE status <> LOWER(TRIM(status));
+UPDATE payments SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE payments SET status = 'failed'
+WHERE status IS NULL
+   OR status NOT IN ('pending', 'paid', 'failed', 'expired', 'cancelled');
+
+ALTER TABLE tickets ALTER COLUMN payment_status SET NOT NULL;
+ALTER TABLE tickets ADD CONSTRAINT tickets_payment_status_check
+    CHECK (payment_status IN ('pending', 'paid', 'review', 'disputed', 'failed', 'expired', 'cancelled'));
+ALTER TABLE payments ALTER COLUMN status SET NOT NULL;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('pending', 'paid', 'failed', 'expired', 'cancelled'));
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS tickets_payment_status_check;
+ALTER TABLE tickets ALTER COLUMN payment_status DROP NOT NULL;
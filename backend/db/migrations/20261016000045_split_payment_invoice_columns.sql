@@ -0,0 +1,26 @@
+-- migrate:up
+-- payments.invoice_id held the invoice's bolt11 for most payments but the
+-- payment backend's invoice ID for retried ones, so settlements matched
+-- on the bolt11 missed those. The bolt11 and payment hash get columns of
+-- their own, invoice_id always holds the backend's ID, and settlements
+-- are matched on the payment hash. Existing payments take the hash and ID
+-- from their ticket's invoice where it is the same invoice; any other
+-- keeps its bolt11 as invoice_id and no hash.
+ALTER TABLE payments ADD COLUMN bolt11 text DEFAULT '' NOT NULL;
+ALTER TABLE payments ADD COLUMN payment_hash character varying(64) DEFAULT '' NOT NULL;
+
+UPDATE payments SET bolt11 = invoice_id WHERE invoice_id LIKE 'ln%';
+UPDATE payments AS p
+SET invoice_id = u.invoice_id, bolt11 = u.bolt11, payment_hash = COALESCE(u.payment_hash, '')
+FROM uma_request_invoices AS u
+WHERE u.ticket_id = p.ticket_id AND (u.bolt11 = p.invoice_id OR u.invoice_id = p.invoice_id);
+
+CREATE INDEX idx_payments_payment_hash ON payments (payment_hash);
+CREATE INDEX idx_payments_bolt11 ON payments (bolt11);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_payments_bolt11;
+DROP INDEX IF EXISTS idx_payments_payment_hash;
+UPDATE payments SET invoice_id = bolt11 WHERE bolt11 <> '';
+ALTER TABLE payments DROP COLUMN payment_hash;
+ALTER TABLE payments DROP COLUMN bolt11;
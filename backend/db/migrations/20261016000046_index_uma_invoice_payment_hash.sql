@@ -0,0 +1,7 @@
+-- migrate:up
+-- Invoices paid through an event's UMA address are attributed to the event
+-- by their payment hash when the node reports the incoming payment.
+CREATE INDEX idx_uma_invoices_payment_hash ON uma_request_invoices (payment_hash);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_uma_invoices_payment_hash;
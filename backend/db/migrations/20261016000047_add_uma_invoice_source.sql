@@ -0,0 +1,14 @@
+-- migrate:up
+-- An event has one standing UMA Request invoice, created by an admin, but
+-- its event_id is shared with its tickets' invoices and with those issued
+-- to payers of the event's UMA address. source tells them apart: 'admin',
+-- 'ticket' or 'address'. Existing invoices with a ticket are the ticket's;
+-- the rest are taken to be admin invoices.
+ALTER TABLE uma_request_invoices ADD COLUMN source character varying(20) DEFAULT 'admin' NOT NULL;
+UPDATE uma_request_invoices SET source = 'ticket' WHERE ticket_id IS NOT NULL;
+
+CREATE INDEX idx_uma_invoices_event_source ON uma_request_invoices (event_id, source);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_uma_invoices_event_source;
+ALTER TABLE uma_request_invoices DROP COLUMN source;
@@ -0,0 +1,23 @@
+-- migrate:up
+-- The admin notification feed: sales, refunds buyers asked for, payouts,
+-- fraud flags and webhooks the queue gave up on. entity_id is the ticket,
+-- ledger entry, fraud flag or webhook event a notification concerns, by
+-- kind; it and event_id are plain references, as those rows may be
+-- archived. Read state is shared by all admins.
+CREATE TABLE admin_notifications (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(30) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    event_id INTEGER,
+    entity_id INTEGER,
+    read_at TIMESTAMP WITHOUT TIME ZONE,
+    read_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_admin_notifications_unread ON admin_notifications(id) WHERE read_at IS NULL;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_admin_notifications_unread;
+DROP TABLE IF EXISTS admin_notifications;
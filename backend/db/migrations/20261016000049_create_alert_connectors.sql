@@ -0,0 +1,21 @@
+-- migrate:up
+-- Outbound alert connectors: Slack and Discord incoming webhooks and
+-- Telegram bot chats that admin notifications of the kinds in events are
+-- posted to. target is the webhook URL or bot token, encrypted with the PII
+-- keys when they are configured.
+CREATE TABLE alert_connectors (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    target TEXT NOT NULL,
+    chat_id VARCHAR(100) NOT NULL DEFAULT '',
+    events JSONB NOT NULL DEFAULT '[]',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_sent_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS alert_connectors;
@@ -0,0 +1,23 @@
+-- migrate:up
+-- Incidents admins post to the public status page. components are the
+-- subsystems an unresolved incident affects; resolved_at is set when it is
+-- resolved.
+CREATE TABLE status_incidents (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    impact VARCHAR(20) NOT NULL,
+    components JSONB NOT NULL DEFAULT '[]',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_status_incidents_started_at ON status_incidents(started_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_status_incidents_started_at;
+DROP TABLE IF EXISTS status_incidents;
@@ -0,0 +1,137 @@
+-- migrate:up
+-- Partitioning tickets and payments dropped the foreign keys that pointed
+-- at them, as Postgres can only reference a partitioned table through a key
+-- that includes its partition key. These triggers put the checks back: a
+-- row naming a ticket or payment that does not exist is refused with
+-- foreign_key_violation, and deleting a ticket or payment applies the
+-- ON DELETE action each reference had before (cascade, set null, or refuse
+-- while still referenced). The referenced row is locked FOR KEY SHARE, as a
+-- foreign key would, so it cannot be deleted before the referencing row
+-- commits. A ticket's event and a payment's created_at never change, so no
+-- update moves a row between partitions.
+CREATE FUNCTION check_ticket_reference() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF NEW.ticket_id IS NOT NULL THEN
+        PERFORM 1 FROM tickets WHERE id = NEW.ticket_id FOR KEY SHARE;
+        IF NOT FOUND THEN
+            RAISE foreign_key_violation USING MESSAGE = format('%s.ticket_id %s references no ticket', TG_TABLE_NAME, NEW.ticket_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END $$;
+
+CREATE FUNCTION check_payment_reference() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF NEW.payment_id IS NOT NULL THEN
+        PERFORM 1 FROM payments WHERE id = NEW.payment_id FOR KEY SHARE;
+        IF NOT FOUND THEN
+            RAISE foreign_key_violation USING MESSAGE = format('%s.payment_id %s references no payment', TG_TABLE_NAME, NEW.payment_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END $$;
+
+CREATE TRIGGER payments_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON payments FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER uma_request_invoices_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON uma_request_invoices FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER fraud_flags_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON fraud_flags FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER purchase_attestations_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON purchase_attestations FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER ticket_scans_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON ticket_scans FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER short_links_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON short_links FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER ticket_gifts_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON ticket_gifts FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER ticket_addons_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON ticket_addons FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER ticket_answers_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON ticket_answers FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER wallet_claims_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON wallet_claims FOR EACH ROW EXECUTE FUNCTION check_ticket_reference();
+CREATE TRIGGER payment_line_items_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON payment_line_items FOR EACH ROW EXECUTE FUNCTION check_payment_reference();
+CREATE TRIGGER receipts_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON receipts FOR EACH ROW EXECUTE FUNCTION check_payment_reference();
+CREATE TRIGGER cashu_redemptions_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON cashu_redemptions FOR EACH ROW EXECUTE FUNCTION check_payment_reference();
+CREATE TRIGGER disputes_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON disputes FOR EACH ROW EXECUTE FUNCTION check_payment_reference();
+
+-- Ticket codes were unique across all tickets before partitioning narrowed
+-- the constraint to (event_id, ticket_code). Legacy codes name no event, so
+-- ticket_codes holds every code under a primary key, kept by the triggers
+-- below; a duplicate fails the ticket's insert or update with a unique
+-- violation as the old constraint did.
+CREATE TABLE ticket_codes (
+    ticket_code VARCHAR(255) PRIMARY KEY
+);
+INSERT INTO ticket_codes (ticket_code) SELECT ticket_code FROM tickets;
+
+CREATE FUNCTION ticket_code_changed() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO ticket_codes (ticket_code) VALUES (NEW.ticket_code);
+    ELSIF NEW.ticket_code IS DISTINCT FROM OLD.ticket_code THEN
+        DELETE FROM ticket_codes WHERE ticket_code = OLD.ticket_code;
+        INSERT INTO ticket_codes (ticket_code) VALUES (NEW.ticket_code);
+    END IF;
+    RETURN NULL;
+END $$;
+
+CREATE FUNCTION ticket_deleted() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM payments WHERE ticket_id = OLD.id)
+        OR EXISTS (SELECT 1 FROM uma_request_invoices WHERE ticket_id = OLD.id) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('ticket %s is still referenced by a payment or invoice', OLD.id);
+    END IF;
+    DELETE FROM purchase_attestations WHERE ticket_id = OLD.id;
+    DELETE FROM short_links WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_gifts WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_addons WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_answers WHERE ticket_id = OLD.id;
+    DELETE FROM wallet_claims WHERE ticket_id = OLD.id;
+    UPDATE ticket_scans SET ticket_id = NULL WHERE ticket_id = OLD.id;
+    UPDATE fraud_flags SET ticket_id = NULL WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_codes WHERE ticket_code = OLD.ticket_code;
+    RETURN NULL;
+END $$;
+
+CREATE FUNCTION payment_deleted() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM receipts WHERE payment_id = OLD.id)
+        OR EXISTS (SELECT 1 FROM disputes WHERE payment_id = OLD.id) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('payment %s is still referenced by a receipt or dispute', OLD.id);
+    END IF;
+    DELETE FROM payment_line_items WHERE payment_id = OLD.id;
+    DELETE FROM cashu_redemptions WHERE payment_id = OLD.id;
+    RETURN NULL;
+END $$;
+
+CREATE TRIGGER tickets_ticket_code_change AFTER INSERT OR UPDATE OF ticket_code ON tickets FOR EACH ROW EXECUTE FUNCTION ticket_code_changed();
+CREATE TRIGGER tickets_delete AFTER DELETE ON tickets FOR EACH ROW EXECUTE FUNCTION ticket_deleted();
+CREATE TRIGGER payments_delete AFTER DELETE ON payments FOR EACH ROW EXECUTE FUNCTION payment_deleted();
+
+-- migrate:down
+DROP TRIGGER payments_delete ON payments;
+DROP TRIGGER tickets_delete ON tickets;
+DROP TRIGGER tickets_ticket_code_change ON tickets;
+DROP FUNCTION payment_deleted();
+DROP FUNCTION ticket_deleted();
+DROP FUNCTION ticket_code_changed();
+DROP TABLE ticket_codes;
+
+DROP TRIGGER disputes_payment_id_check ON disputes;
+DROP TRIGGER cashu_redemptions_payment_id_check ON cashu_redemptions;
+DROP TRIGGER receipts_payment_id_check ON receipts;
+DROP TRIGGER payment_line_items_payment_id_check ON payment_line_items;
+DROP TRIGGER wallet_claims_ticket_id_check ON wallet_claims;
+DROP TRIGGER ticket_answers_ticket_id_check ON ticket_answers;
+DROP TRIGGER ticket_addons_ticket_id_check ON ticket_addons;
+DROP TRIGGER ticket_gifts_ticket_id_check ON ticket_gifts;
+DROP TRIGGER short_links_ticket_id_check ON short_links;
+DROP TRIGGER ticket_scans_ticket_id_check ON ticket_scans;
+DROP TRIGGER purchase_attestations_ticket_id_check ON purchase_attestations;
+DROP TRIGGER fraud_flags_ticket_id_check ON fraud_flags;
+DROP TRIGGER uma_request_invoices_ticket_id_check ON uma_request_invoices;
+DROP TRIGGER payments_ticket_id_check ON payments;
+DROP FUNCTION check_payment_reference();
+DROP FUNCTION check_ticket_reference();
@@ -0,0 +1,10 @@
+-- migrate:up
+-- Where organizers and affiliates are paid: a Lightning Address or UMA
+-- address, resolved to an invoice over LNURL-pay when a payout is sent.
+-- Empty until set.
+ALTER TABLE organizer_profiles ADD COLUMN payout_address VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE affiliates ADD COLUMN payout_address VARCHAR(255) NOT NULL DEFAULT '';
+
+-- migrate:down
+ALTER TABLE affiliates DROP COLUMN payout_address;
+ALTER TABLE organizer_profiles DROP COLUMN payout_address;
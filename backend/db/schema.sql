@@ -9,6 +9,103 @@ SET xmloption = content;
 SET client_min_messages = warning;
 SET row_security = off;
 
+--
+-- Name: check_payment_reference(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.check_payment_reference() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF NEW.payment_id IS NOT NULL THEN
+        PERFORM 1 FROM payments WHERE id = NEW.payment_id FOR KEY SHARE;
+        IF NOT FOUND THEN
+            RAISE foreign_key_violation USING MESSAGE = format('%s.payment_id %s references no payment', TG_TABLE_NAME, NEW.payment_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END $$;
+
+
+--
+-- Name: check_ticket_reference(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.check_ticket_reference() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF NEW.ticket_id IS NOT NULL THEN
+        PERFORM 1 FROM tickets WHERE id = NEW.ticket_id FOR KEY SHARE;
+        IF NOT FOUND THEN
+            RAISE foreign_key_violation USING MESSAGE = format('%s.ticket_id %s references no ticket', TG_TABLE_NAME, NEW.ticket_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END $$;
+
+
+--
+-- Name: payment_deleted(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.payment_deleted() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM receipts WHERE payment_id = OLD.id)
+        OR EXISTS (SELECT 1 FROM disputes WHERE payment_id = OLD.id) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('payment %s is still referenced by a receipt or dispute', OLD.id);
+    END IF;
+    DELETE FROM payment_line_items WHERE payment_id = OLD.id;
+    DELETE FROM cashu_redemptions WHERE payment_id = OLD.id;
+    RETURN NULL;
+END $$;
+
+
+--
+-- Name: ticket_code_changed(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.ticket_code_changed() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO ticket_codes (ticket_code) VALUES (NEW.ticket_code);
+    ELSIF NEW.ticket_code IS DISTINCT FROM OLD.ticket_code THEN
+        DELETE FROM ticket_codes WHERE ticket_code = OLD.ticket_code;
+        INSERT INTO ticket_codes (ticket_code) VALUES (NEW.ticket_code);
+    END IF;
+    RETURN NULL;
+END $$;
+
+
+--
+-- Name: ticket_deleted(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.ticket_deleted() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM payments WHERE ticket_id = OLD.id)
+        OR EXISTS (SELECT 1 FROM uma_request_invoices WHERE ticket_id = OLD.id) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('ticket %s is still referenced by a payment or invoice', OLD.id);
+    END IF;
+    DELETE FROM purchase_attestations WHERE ticket_id = OLD.id;
+    DELETE FROM short_links WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_gifts WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_addons WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_answers WHERE ticket_id = OLD.id;
+    DELETE FROM wallet_claims WHERE ticket_id = OLD.id;
+    UPDATE ticket_scans SET ticket_id = NULL WHERE ticket_id = OLD.id;
+    UPDATE fraud_flags SET ticket_id = NULL WHERE ticket_id = OLD.id;
+    DELETE FROM ticket_codes WHERE ticket_code = OLD.ticket_code;
+    RETURN NULL;
+END $$;
+
+
 SET default_tablespace = '';
 
 SET default_table_access_method = heap;
@@ -29,8 +126,24 @@ CREATE TABLE public.events (
     is_active boolean DEFAULT true,
     created_at timestamp without time zone DEFAULT now(),
     updated_at timestamp without time zone DEFAULT now(),
+    pricing_mode character varying(20) DEFAULT 'fixed'::character varying NOT NULL,
+    min_price_sats bigint DEFAULT 0 NOT NULL,
+    organizer_id integer,
+    tax_basis_points integer DEFAULT 0 NOT NULL,
+    tax_inclusive boolean DEFAULT false NOT NULL,
+    tax_jurisdiction character varying(100) DEFAULT ''::character varying NOT NULL,
+    is_private boolean DEFAULT false NOT NULL,
+    min_age integer DEFAULT 0 NOT NULL,
+    terms_version character varying(50) DEFAULT ''::character varying NOT NULL,
+    terms_url character varying(500) DEFAULT ''::character varying NOT NULL,
+    cancelled_at timestamp without time zone,
+    category character varying(50) DEFAULT ''::character varying NOT NULL,
+    invoice_expiry_seconds integer DEFAULT 0 NOT NULL,
+    one_ticket_per_user boolean DEFAULT false NOT NULL,
     CONSTRAINT events_capacity_check CHECK ((capacity > 0)),
-    CONSTRAINT events_price_sats_check CHECK ((price_sats > 0))
+    CONSTRAINT events_min_age_check CHECK (((min_age >= 0) AND (min_age <= 99))),
+    CONSTRAINT events_price_sats_check CHECK ((price_sats > 0)),
+    CONSTRAINT events_tax_basis_points_check CHECK (((tax_basis_points >= 0) AND (tax_basis_points <= 10000)))
 );
 
 
@@ -97,12 +210,25 @@ CREATE TABLE public.payments (
     ticket_id integer NOT NULL,
     invoice_id text NOT NULL,
     amount_sats bigint NOT NULL,
-    status character varying(50) DEFAULT 'pending'::character varying,
+    status character varying(50) DEFAULT 'pending'::character varying NOT NULL,
     paid_at timestamp without time zone,
-    created_at timestamp without time zone DEFAULT now(),
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
     updated_at timestamp without time zone DEFAULT now(),
-    preimage text
-);
+    preimage text,
+    taxable_sats bigint DEFAULT 0 NOT NULL,
+    tax_sats bigint DEFAULT 0 NOT NULL,
+    tax_basis_points integer DEFAULT 0 NOT NULL,
+    tax_inclusive boolean DEFAULT false NOT NULL,
+    tax_jurisdiction character varying(100) DEFAULT ''::character varying NOT NULL,
+    fiat_currency character varying(3) DEFAULT ''::character varying NOT NULL,
+    fiat_amount bigint,
+    exchange_rate_id integer,
+    expires_at timestamp without time zone,
+    bolt11 text DEFAULT ''::text NOT NULL,
+    payment_hash character varying(64) DEFAULT ''::character varying NOT NULL,
+    CONSTRAINT payments_status_check CHECK (((status)::text = ANY ((ARRAY['pending'::character varying, 'paid'::character varying, 'failed'::character varying, 'expired'::character varying, 'cancelled'::character varying])::text[])))
+)
+PARTITION BY RANGE (created_at);
 
 
 --
@@ -125,6 +251,41 @@ CREATE SEQUENCE public.payments_id_seq
 ALTER SEQUENCE public.payments_id_seq OWNED BY public.payments.id;
 
 
+--
+-- Name: payments_2026_10; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payments_2026_10 PARTITION OF public.payments FOR VALUES FROM ('2026-10-01 00:00:00') TO ('2026-11-01 00:00:00');
+
+
+--
+-- Name: payments_2026_11; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payments_2026_11 PARTITION OF public.payments FOR VALUES FROM ('2026-11-01 00:00:00') TO ('2026-12-01 00:00:00');
+
+
+--
+-- Name: payments_2026_12; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payments_2026_12 PARTITION OF public.payments FOR VALUES FROM ('2026-12-01 00:00:00') TO ('2027-01-01 00:00:00');
+
+
+--
+-- Name: payments_2027_01; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payments_2027_01 PARTITION OF public.payments FOR VALUES FROM ('2027-01-01 00:00:00') TO ('2027-02-01 00:00:00');
+
+
+--
+-- Name: payments_default; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payments_default PARTITION OF public.payments DEFAULT;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
 --
@@ -143,12 +304,28 @@ CREATE TABLE public.tickets (
     event_id integer NOT NULL,
     user_id integer NOT NULL,
     ticket_code character varying(255) NOT NULL,
-    payment_status character varying(50) DEFAULT 'pending'::character varying,
+    payment_status character varying(50) DEFAULT 'pending'::character varying NOT NULL,
     invoice_id text,
     uma_address character varying(255),
     paid_at timestamp without time zone,
     created_at timestamp without time zone DEFAULT now(),
-    updated_at timestamp without time zone DEFAULT now()
+    updated_at timestamp without time zone DEFAULT now(),
+    amount_sats bigint DEFAULT 0 NOT NULL,
+    order_id integer,
+    is_comp boolean DEFAULT false NOT NULL,
+    price_change_id integer,
+    one_per_user boolean DEFAULT false NOT NULL,
+    CONSTRAINT tickets_payment_status_check CHECK (((payment_status)::text = ANY ((ARRAY['pending'::character varying, 'paid'::character varying, 'review'::character varying, 'disputed'::character varying, 'failed'::character varying, 'expired'::character varying, 'cancelled'::character varying])::text[])))
+)
+PARTITION BY HASH (event_id);
+
+
+--
+-- Name: ticket_codes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ticket_codes (
+    ticket_code character varying(255) NOT NULL
 );
 
 
@@ -172,6 +349,118 @@ CREATE SEQUENCE public.tickets_id_seq
 ALTER SEQUENCE public.tickets_id_seq OWNED BY public.tickets.id;
 
 
+--
+-- Name: tickets_p00; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p00 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 0);
+
+
+--
+-- Name: tickets_p01; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p01 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 1);
+
+
+--
+-- Name: tickets_p02; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p02 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 2);
+
+
+--
+-- Name: tickets_p03; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p03 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 3);
+
+
+--
+-- Name: tickets_p04; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p04 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 4);
+
+
+--
+-- Name: tickets_p05; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p05 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 5);
+
+
+--
+-- Name: tickets_p06; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p06 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 6);
+
+
+--
+-- Name: tickets_p07; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p07 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 7);
+
+
+--
+-- Name: tickets_p08; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p08 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 8);
+
+
+--
+-- Name: tickets_p09; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p09 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 9);
+
+
+--
+-- Name: tickets_p10; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p10 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 10);
+
+
+--
+-- Name: tickets_p11; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p11 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 11);
+
+
+--
+-- Name: tickets_p12; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p12 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 12);
+
+
+--
+-- Name: tickets_p13; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p13 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 13);
+
+
+--
+-- Name: tickets_p14; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p14 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 14);
+
+
+--
+-- Name: tickets_p15; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tickets_p15 PARTITION OF public.tickets FOR VALUES WITH (modulus 16, remainder 15);
+
+
 --
 -- Name: uma_request_invoices; Type: TABLE; Schema: public; Owner: -
 --
@@ -190,6 +479,7 @@ CREATE TABLE public.uma_request_invoices (
     created_at timestamp without time zone DEFAULT now(),
     updated_at timestamp without time zone DEFAULT now(),
     ticket_id integer,
+    source character varying(20) DEFAULT 'admin'::character varying NOT NULL,
     CONSTRAINT uma_request_invoices_amount_sats_check CHECK ((amount_sats > 0))
 );
 
@@ -224,7 +514,12 @@ CREATE TABLE public.users (
     name character varying(255) NOT NULL,
     created_at timestamp without time zone DEFAULT now(),
     updated_at timestamp without time zone DEFAULT now(),
-    password_hash character varying(255) DEFAULT ''::character varying NOT NULL
+    password_hash character varying(255) DEFAULT ''::character varying NOT NULL,
+    locale character varying(10) DEFAULT 'en'::character varying NOT NULL,
+    is_guest boolean DEFAULT false NOT NULL,
+    pending_email character varying(255),
+    session_version integer DEFAULT 0 NOT NULL,
+    lnurl_auth_key character varying(66)
 );
 
 
@@ -249,251 +544,3292 @@ ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
 
 
 --
--- Name: events id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: settings; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.events ALTER COLUMN id SET DEFAULT nextval('public.events_id_seq'::regclass);
+CREATE TABLE public.settings (
+    key text NOT NULL,
+    value jsonb NOT NULL,
+    updated_by text DEFAULT ''::text NOT NULL,
+    updated_at timestamp without time zone DEFAULT now()
+);
 
 
 --
--- Name: nwc_connections id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: fraud_flags; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.nwc_connections ALTER COLUMN id SET DEFAULT nextval('public.nwc_connections_id_seq'::regclass);
+CREATE TABLE public.fraud_flags (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    event_id integer NOT NULL,
+    ticket_id integer,
+    client_ip text DEFAULT ''::text NOT NULL,
+    action character varying(20) NOT NULL,
+    signals jsonb DEFAULT '[]'::jsonb NOT NULL,
+    created_at timestamp without time zone DEFAULT now()
+);
 
 
 --
--- Name: payments id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: fraud_flags_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.payments ALTER COLUMN id SET DEFAULT nextval('public.payments_id_seq'::regclass);
+CREATE SEQUENCE public.fraud_flags_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: tickets id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: fraud_flags_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tickets ALTER COLUMN id SET DEFAULT nextval('public.tickets_id_seq'::regclass);
+ALTER SEQUENCE public.fraud_flags_id_seq OWNED BY public.fraud_flags.id;
 
 
 --
--- Name: uma_request_invoices id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: event_addons; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.uma_request_invoices ALTER COLUMN id SET DEFAULT nextval('public.uma_request_invoices_id_seq'::regclass);
+CREATE TABLE public.event_addons (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    name character varying(255) NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    price_sats bigint NOT NULL,
+    is_active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone DEFAULT now(),
+    updated_at timestamp without time zone DEFAULT now(),
+    flex_cutoff_hours integer,
+    CONSTRAINT event_addons_flex_cutoff_hours_check CHECK ((flex_cutoff_hours >= 0)),
+    CONSTRAINT event_addons_price_sats_check CHECK ((price_sats >= 0))
+);
 
 
 --
--- Name: users id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: event_addons_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+CREATE SEQUENCE public.event_addons_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: events events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_addons_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.events
-    ADD CONSTRAINT events_pkey PRIMARY KEY (id);
+ALTER SEQUENCE public.event_addons_id_seq OWNED BY public.event_addons.id;
 
 
 --
--- Name: nwc_connections nwc_connections_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_form_fields; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.nwc_connections
-    ADD CONSTRAINT nwc_connections_pkey PRIMARY KEY (id);
+CREATE TABLE public.event_form_fields (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    label character varying(255) NOT NULL,
+    field_type character varying(20) NOT NULL,
+    options jsonb DEFAULT '[]'::jsonb NOT NULL,
+    required boolean DEFAULT false NOT NULL,
+    "position" integer DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone DEFAULT now(),
+    updated_at timestamp without time zone DEFAULT now(),
+    CONSTRAINT event_form_fields_field_type_check CHECK (((field_type)::text = ANY ((ARRAY['text'::character varying, 'number'::character varying, 'select'::character varying, 'checkbox'::character varying])::text[])))
+);
 
 
 --
--- Name: nwc_connections nwc_connections_user_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_form_fields_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.nwc_connections
-    ADD CONSTRAINT nwc_connections_user_id_key UNIQUE (user_id);
+CREATE SEQUENCE public.event_form_fields_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: payments payments_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_form_fields_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.payments
-    ADD CONSTRAINT payments_pkey PRIMARY KEY (id);
+ALTER SEQUENCE public.event_form_fields_id_seq OWNED BY public.event_form_fields.id;
 
 
 --
--- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_access_codes; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.schema_migrations
-    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+CREATE TABLE public.event_access_codes (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    code character varying(32) NOT NULL,
+    max_uses integer,
+    uses integer DEFAULT 0 NOT NULL,
+    expires_at timestamp without time zone,
+    created_at timestamp without time zone DEFAULT now(),
+    CONSTRAINT event_access_codes_max_uses_check CHECK ((max_uses > 0))
+);
 
 
 --
--- Name: tickets tickets_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_access_codes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tickets
-    ADD CONSTRAINT tickets_pkey PRIMARY KEY (id);
+CREATE SEQUENCE public.event_access_codes_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: tickets tickets_ticket_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_access_codes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tickets
-    ADD CONSTRAINT tickets_ticket_code_key UNIQUE (ticket_code);
+ALTER SEQUENCE public.event_access_codes_id_seq OWNED BY public.event_access_codes.id;
 
 
 --
--- Name: uma_request_invoices uma_request_invoices_invoice_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_allowlist; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.uma_request_invoices
-    ADD CONSTRAINT uma_request_invoices_invoice_id_key UNIQUE (invoice_id);
+CREATE TABLE public.event_allowlist (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    entry character varying(255) NOT NULL,
+    created_at timestamp without time zone DEFAULT now()
+);
 
 
 --
--- Name: uma_request_invoices uma_request_invoices_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_allowlist_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.uma_request_invoices
-    ADD CONSTRAINT uma_request_invoices_pkey PRIMARY KEY (id);
+CREATE SEQUENCE public.event_allowlist_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: users users_email_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_allowlist_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.users
-    ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER SEQUENCE public.event_allowlist_id_seq OWNED BY public.event_allowlist.id;
 
 
 --
--- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: purchase_attestations; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.users
-    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+CREATE TABLE public.purchase_attestations (
+    id integer NOT NULL,
+    ticket_id integer NOT NULL,
+    event_id integer NOT NULL,
+    min_age integer DEFAULT 0 NOT NULL,
+    terms_version character varying(50) DEFAULT ''::character varying NOT NULL,
+    client_ip text DEFAULT ''::text NOT NULL,
+    user_agent text DEFAULT ''::text NOT NULL,
+    attested_at timestamp without time zone NOT NULL
+);
 
 
 --
--- Name: idx_events_is_active; Type: INDEX; Schema: public; Owner: -
+-- Name: purchase_attestations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_events_is_active ON public.events USING btree (is_active);
+CREATE SEQUENCE public.purchase_attestations_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: idx_events_start_time; Type: INDEX; Schema: public; Owner: -
+-- Name: purchase_attestations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_events_start_time ON public.events USING btree (start_time);
+ALTER SEQUENCE public.purchase_attestations_id_seq OWNED BY public.purchase_attestations.id;
 
 
 --
--- Name: idx_payments_invoice_id; Type: INDEX; Schema: public; Owner: -
+-- Name: event_translations; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_payments_invoice_id ON public.payments USING btree (invoice_id);
+CREATE TABLE public.event_translations (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    locale character varying(10) NOT NULL,
+    title character varying(255) NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
 
 
 --
--- Name: idx_payments_ticket_id; Type: INDEX; Schema: public; Owner: -
+-- Name: event_translations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_payments_ticket_id ON public.payments USING btree (ticket_id);
+CREATE SEQUENCE public.event_translations_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: event_translations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.event_translations_id_seq OWNED BY public.event_translations.id;
+
+
+--
+-- Name: notification_templates; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.notification_templates (
+    id integer NOT NULL,
+    organizer_id integer,
+    kind character varying(50) NOT NULL,
+    locale character varying(10) NOT NULL,
+    version integer NOT NULL,
+    subject text NOT NULL,
+    text_body text NOT NULL,
+    html_body text DEFAULT ''::text NOT NULL,
+    created_by integer,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: notification_templates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.notification_templates_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: notification_templates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.notification_templates_id_seq OWNED BY public.notification_templates.id;
+
+
+--
+-- Name: orders; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.orders (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    event_id integer NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    ref character varying(100) DEFAULT ''::character varying NOT NULL,
+    utm_source character varying(100) DEFAULT ''::character varying NOT NULL,
+    utm_medium character varying(100) DEFAULT ''::character varying NOT NULL,
+    utm_campaign character varying(100) DEFAULT ''::character varying NOT NULL,
+    utm_term character varying(100) DEFAULT ''::character varying NOT NULL,
+    utm_content character varying(100) DEFAULT ''::character varying NOT NULL,
+    affiliate_id integer,
+    commission_basis_points integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: orders_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.orders_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: orders_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.orders_id_seq OWNED BY public.orders.id;
+
+
+--
+-- Name: account_claims; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.account_claims (
+    user_id integer NOT NULL,
+    secret_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: email_changes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.email_changes (
+    user_id integer NOT NULL,
+    secret_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+
+--
+-- Name: event_reschedules; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.event_reschedules (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    old_start_time timestamp without time zone NOT NULL,
+    old_end_time timestamp without time zone NOT NULL,
+    new_start_time timestamp without time zone NOT NULL,
+    new_end_time timestamp without time zone NOT NULL,
+    reason text DEFAULT ''::text NOT NULL,
+    refund_until timestamp without time zone,
+    rescheduled_by integer,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: event_reschedules_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.event_reschedules_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: event_reschedules_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.event_reschedules_id_seq OWNED BY public.event_reschedules.id;
+
+
+--
+-- Name: event_cancellations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.event_cancellations (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    reason text DEFAULT ''::text NOT NULL,
+    status character varying(20) DEFAULT 'running'::character varying NOT NULL,
+    total integer DEFAULT 0 NOT NULL,
+    refunded integer DEFAULT 0 NOT NULL,
+    voided integer DEFAULT 0 NOT NULL,
+    failed integer DEFAULT 0 NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    cancelled_by integer,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    completed_at timestamp without time zone,
+    CONSTRAINT event_cancellations_status_check CHECK (((status)::text = ANY ((ARRAY['running'::character varying, 'done'::character varying, 'failed'::character varying])::text[])))
+);
+
+
+--
+-- Name: event_cancellations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.event_cancellations_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: event_cancellations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.event_cancellations_id_seq OWNED BY public.event_cancellations.id;
+
+
+--
+-- Name: ticket_scans; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ticket_scans (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    ticket_id integer,
+    ticket_code character varying(255) NOT NULL,
+    admitted boolean NOT NULL,
+    reason text DEFAULT ''::text NOT NULL,
+    scanned_at timestamp without time zone NOT NULL,
+    scanner_device_id integer
+);
+
+
+--
+-- Name: ticket_scans_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ticket_scans_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ticket_scans_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ticket_scans_id_seq OWNED BY public.ticket_scans.id;
+
+
+--
+-- Name: scanner_devices; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.scanner_devices (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    pairing_hash character varying(64),
+    pairing_expires_at timestamp without time zone,
+    token_hash character varying(64),
+    registered_at timestamp without time zone,
+    last_seen_at timestamp without time zone,
+    revoked_at timestamp without time zone,
+    created_by integer,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: scanner_devices_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.scanner_devices_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: scanner_devices_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.scanner_devices_id_seq OWNED BY public.scanner_devices.id;
+
+
+--
+-- Name: inventory_holds; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.inventory_holds (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    seats integer NOT NULL,
+    created_by integer,
+    created_at timestamp without time zone NOT NULL,
+    released_at timestamp without time zone,
+    released_by integer,
+    CONSTRAINT inventory_holds_seats_check CHECK ((seats > 0))
+);
+
+
+--
+-- Name: inventory_holds_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.inventory_holds_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: inventory_holds_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.inventory_holds_id_seq OWNED BY public.inventory_holds.id;
+
+
+--
+-- Name: event_relations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.event_relations (
+    event_id integer NOT NULL,
+    related_event_id integer NOT NULL,
+    "position" integer NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: organizer_profiles; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.organizer_profiles (
+    user_id integer NOT NULL,
+    slug character varying(60) NOT NULL,
+    display_name character varying(100) NOT NULL,
+    bio text DEFAULT ''::text NOT NULL,
+    website_url character varying(500) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    payout_address character varying(255) DEFAULT ''::character varying NOT NULL
+);
+
+
+--
+-- Name: short_links; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.short_links (
+    id integer NOT NULL,
+    code character varying(40) NOT NULL,
+    event_id integer,
+    ticket_id integer,
+    custom boolean DEFAULT false NOT NULL,
+    clicks integer DEFAULT 0 NOT NULL,
+    last_clicked_at timestamp without time zone,
+    created_by integer,
+    created_at timestamp without time zone NOT NULL,
+    CONSTRAINT short_links_check CHECK (((event_id IS NULL) <> (ticket_id IS NULL)))
+);
+
+
+--
+-- Name: short_links_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.short_links_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: short_links_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.short_links_id_seq OWNED BY public.short_links.id;
+
+
+--
+-- Name: affiliates; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.affiliates (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    code character varying(40) NOT NULL,
+    basis_points integer NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    payout_address character varying(255) DEFAULT ''::character varying NOT NULL,
+    CONSTRAINT affiliates_basis_points_check CHECK (((basis_points >= 0) AND (basis_points <= 10000)))
+);
+
+
+--
+-- Name: affiliates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.affiliates_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: affiliates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.affiliates_id_seq OWNED BY public.affiliates.id;
+
+
+--
+-- Name: ticket_gifts; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ticket_gifts (
+    id integer NOT NULL,
+    ticket_id integer NOT NULL,
+    sender_id integer NOT NULL,
+    recipient_email character varying(255) DEFAULT ''::character varying NOT NULL,
+    recipient_uma_address character varying(255) DEFAULT ''::character varying NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    deliver_at timestamp without time zone NOT NULL,
+    status character varying(20) DEFAULT 'scheduled'::character varying NOT NULL,
+    secret_hash character varying(64),
+    recipient_id integer,
+    delivered_at timestamp without time zone,
+    claimed_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    CONSTRAINT ticket_gifts_status_check CHECK (((status)::text = ANY ((ARRAY['scheduled'::character varying, 'delivered'::character varying, 'claimed'::character varying])::text[])))
+);
+
+
+--
+-- Name: ticket_gifts_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ticket_gifts_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ticket_gifts_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ticket_gifts_id_seq OWNED BY public.ticket_gifts.id;
+
+
+--
+-- Name: pricing_rules; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.pricing_rules (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    price_sats bigint NOT NULL,
+    sold_percent integer,
+    starts_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    CONSTRAINT pricing_rules_check CHECK (((sold_percent IS NULL) <> (starts_at IS NULL))),
+    CONSTRAINT pricing_rules_price_sats_check CHECK ((price_sats > 0)),
+    CONSTRAINT pricing_rules_sold_percent_check CHECK (((sold_percent >= 1) AND (sold_percent <= 100)))
+);
+
+
+--
+-- Name: pricing_rules_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.pricing_rules_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: pricing_rules_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.pricing_rules_id_seq OWNED BY public.pricing_rules.id;
+
+
+--
+-- Name: event_price_changes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.event_price_changes (
+    id integer NOT NULL,
+    event_id integer NOT NULL,
+    price_sats bigint NOT NULL,
+    rule_id integer,
+    rule_name character varying(100) DEFAULT ''::character varying NOT NULL,
+    changed_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: event_price_changes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.event_price_changes_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: event_price_changes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.event_price_changes_id_seq OWNED BY public.event_price_changes.id;
+
+
+--
+-- Name: exchange_rates; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.exchange_rates (
+    id integer NOT NULL,
+    currency character varying(3) NOT NULL,
+    minor_units_per_btc bigint NOT NULL,
+    source character varying(50) NOT NULL,
+    fetched_at timestamp without time zone NOT NULL,
+    CONSTRAINT exchange_rates_minor_units_per_btc_check CHECK ((minor_units_per_btc > 0))
+);
+
+
+--
+-- Name: exchange_rates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.exchange_rates_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: exchange_rates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.exchange_rates_id_seq OWNED BY public.exchange_rates.id;
+
+
+--
+-- Name: cashu_redemptions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.cashu_redemptions (
+    id integer NOT NULL,
+    payment_id integer NOT NULL,
+    mint_url character varying(255) NOT NULL,
+    quote_id character varying(255) DEFAULT ''::character varying NOT NULL,
+    amount_sats bigint NOT NULL,
+    fee_reserve_sats bigint DEFAULT 0 NOT NULL,
+    token_sats bigint NOT NULL,
+    state character varying(20) DEFAULT 'pending'::character varying NOT NULL,
+    preimage character varying(64),
+    error text DEFAULT ''::text NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    CONSTRAINT cashu_redemptions_state_check CHECK (((state)::text = ANY ((ARRAY['pending'::character varying, 'paid'::character varying, 'failed'::character varying])::text[])))
+);
+
+
+--
+-- Name: cashu_redemptions_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.cashu_redemptions_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: cashu_redemptions_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.cashu_redemptions_id_seq OWNED BY public.cashu_redemptions.id;
+
+
+--
+-- Name: lnurl_auth_challenges; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.lnurl_auth_challenges (
+    id integer NOT NULL,
+    k1 character varying(64) NOT NULL,
+    session_hash character varying(64) NOT NULL,
+    link_user_id integer,
+    user_id integer,
+    expires_at timestamp without time zone NOT NULL,
+    verified_at timestamp without time zone,
+    consumed_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: lnurl_auth_challenges_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.lnurl_auth_challenges_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: lnurl_auth_challenges_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.lnurl_auth_challenges_id_seq OWNED BY public.lnurl_auth_challenges.id;
+
+
+--
+-- Name: ticket_addons; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ticket_addons (
+    id integer NOT NULL,
+    ticket_id integer NOT NULL,
+    addon_id integer,
+    name character varying(255) NOT NULL,
+    quantity integer NOT NULL,
+    unit_price_sats bigint NOT NULL,
+    created_at timestamp without time zone DEFAULT now(),
+    flex_cutoff_hours integer,
+    flex_used_at timestamp without time zone,
+    CONSTRAINT ticket_addons_quantity_check CHECK ((quantity > 0))
+);
+
+
+--
+-- Name: ticket_addons_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ticket_addons_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ticket_addons_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ticket_addons_id_seq OWNED BY public.ticket_addons.id;
+
+
+--
+-- Name: ticket_answers; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ticket_answers (
+    id integer NOT NULL,
+    ticket_id integer NOT NULL,
+    field_id integer,
+    label character varying(255) NOT NULL,
+    value text NOT NULL,
+    created_at timestamp without time zone DEFAULT now()
+);
+
+
+--
+-- Name: ticket_answers_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ticket_answers_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ticket_answers_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ticket_answers_id_seq OWNED BY public.ticket_answers.id;
+
+
+--
+-- Name: payment_line_items; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payment_line_items (
+    id integer NOT NULL,
+    payment_id integer NOT NULL,
+    kind character varying(20) NOT NULL,
+    description text NOT NULL,
+    quantity integer DEFAULT 1 NOT NULL,
+    unit_amount_sats bigint NOT NULL,
+    amount_sats bigint NOT NULL,
+    created_at timestamp without time zone DEFAULT now()
+);
+
+
+--
+-- Name: payment_line_items_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.payment_line_items_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: payment_line_items_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.payment_line_items_id_seq OWNED BY public.payment_line_items.id;
+
+
+--
+-- Name: receipts; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.receipts (
+    id integer NOT NULL,
+    payment_id integer NOT NULL,
+    receipt_number bigint NOT NULL,
+    issued_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: receipts_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.receipts_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: receipts_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.receipts_id_seq OWNED BY public.receipts.id;
+
+
+--
+-- Name: organizer_fees; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.organizer_fees (
+    organizer_id integer NOT NULL,
+    basis_points integer NOT NULL,
+    fixed_sats bigint DEFAULT 0 NOT NULL,
+    updated_at timestamp without time zone DEFAULT now(),
+    CONSTRAINT organizer_fees_basis_points_check CHECK (((basis_points >= 0) AND (basis_points <= 10000))),
+    CONSTRAINT organizer_fees_fixed_sats_check CHECK ((fixed_sats >= 0))
+);
+
+
+--
+-- Name: ledger_accounts; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ledger_accounts (
+    id integer NOT NULL,
+    name character varying(255) NOT NULL,
+    type character varying(20) NOT NULL,
+    created_at timestamp without time zone DEFAULT now(),
+    CONSTRAINT ledger_accounts_type_check CHECK (((type)::text = ANY ((ARRAY['asset'::character varying, 'liability'::character varying, 'income'::character varying, 'expense'::character varying, 'equity'::character varying])::text[])))
+);
+
+
+--
+-- Name: ledger_accounts_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ledger_accounts_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ledger_accounts_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ledger_accounts_id_seq OWNED BY public.ledger_accounts.id;
+
+
+--
+-- Name: ledger_entries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ledger_entries (
+    id integer NOT NULL,
+    kind character varying(20) NOT NULL,
+    payment_id integer,
+    organizer_id integer,
+    reference character varying(255) DEFAULT ''::character varying NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    occurred_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone DEFAULT now(),
+    affiliate_id integer,
+    CONSTRAINT ledger_entries_kind_check CHECK (((kind)::text = ANY ((ARRAY['sale'::character varying, 'refund'::character varying, 'payout'::character varying])::text[])))
+);
+
+
+--
+-- Name: ledger_entries_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ledger_entries_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ledger_entries_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ledger_entries_id_seq OWNED BY public.ledger_entries.id;
+
+
+--
+-- Name: ledger_postings; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ledger_postings (
+    id integer NOT NULL,
+    entry_id integer NOT NULL,
+    account_id integer NOT NULL,
+    debit_sats bigint DEFAULT 0 NOT NULL,
+    credit_sats bigint DEFAULT 0 NOT NULL,
+    CONSTRAINT ledger_postings_credit_sats_check CHECK ((credit_sats >= 0)),
+    CONSTRAINT ledger_postings_debit_sats_check CHECK ((debit_sats >= 0)),
+    CONSTRAINT ledger_postings_one_side CHECK (((debit_sats = 0) <> (credit_sats = 0)))
+);
+
+
+--
+-- Name: ledger_postings_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.ledger_postings_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: ledger_postings_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.ledger_postings_id_seq OWNED BY public.ledger_postings.id;
+
+
+--
+-- Name: disputes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.disputes (
+    id integer NOT NULL,
+    payment_id integer NOT NULL,
+    status character varying(20) DEFAULT 'open'::character varying NOT NULL,
+    reason text NOT NULL,
+    resolution text DEFAULT ''::text NOT NULL,
+    opened_by integer,
+    resolved_by integer,
+    opened_at timestamp without time zone NOT NULL,
+    resolved_at timestamp without time zone,
+    CONSTRAINT disputes_status_check CHECK (((status)::text = ANY ((ARRAY['open'::character varying, 'won'::character varying, 'lost'::character varying])::text[])))
+);
+
+
+--
+-- Name: disputes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.disputes_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: disputes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.disputes_id_seq OWNED BY public.disputes.id;
+
+
+--
+-- Name: webhook_events; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhook_events (
+    id integer NOT NULL,
+    source character varying(50) NOT NULL,
+    event_id character varying(255) NOT NULL,
+    event_type character varying(100) NOT NULL,
+    entity_id character varying(255) NOT NULL,
+    payload text NOT NULL,
+    status character varying(20) DEFAULT 'pending'::character varying NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    received_at timestamp without time zone NOT NULL,
+    next_attempt_at timestamp without time zone NOT NULL,
+    locked_until timestamp without time zone,
+    processed_at timestamp without time zone,
+    CONSTRAINT webhook_events_status_check CHECK (((status)::text = ANY ((ARRAY['pending'::character varying, 'done'::character varying, 'failed'::character varying])::text[])))
+);
+
+
+--
+-- Name: webhook_events_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.webhook_events_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: webhook_events_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.webhook_events_id_seq OWNED BY public.webhook_events.id;
+
+
+--
+-- Name: wallet_claims; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.wallet_claims (
+    ticket_id integer NOT NULL,
+    secret_hash character varying(64),
+    secret_expires_at timestamp without time zone,
+    device_public_key character varying(64),
+    claimed_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: archived_records; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.archived_records (
+    id integer NOT NULL,
+    table_name character varying(50) NOT NULL,
+    record_id integer NOT NULL,
+    event_id integer,
+    data text NOT NULL,
+    archived_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: archived_records_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.archived_records_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: archived_records_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.archived_records_id_seq OWNED BY public.archived_records.id;
+
+
+--
+-- Name: admin_notifications; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.admin_notifications (
+    id integer NOT NULL,
+    kind character varying(30) NOT NULL,
+    title character varying(255) NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    event_id integer,
+    entity_id integer,
+    read_at timestamp without time zone,
+    read_by integer,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: admin_notifications_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.admin_notifications_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: admin_notifications_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.admin_notifications_id_seq OWNED BY public.admin_notifications.id;
+
+
+--
+-- Name: alert_connectors; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.alert_connectors (
+    id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    kind character varying(20) NOT NULL,
+    target text NOT NULL,
+    chat_id character varying(100) DEFAULT ''::character varying NOT NULL,
+    events jsonb DEFAULT '[]'::jsonb NOT NULL,
+    is_active boolean DEFAULT true NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    last_sent_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: alert_connectors_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.alert_connectors_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: alert_connectors_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.alert_connectors_id_seq OWNED BY public.alert_connectors.id;
+
+
+--
+-- Name: status_incidents; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.status_incidents (
+    id integer NOT NULL,
+    title character varying(255) NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    status character varying(20) NOT NULL,
+    impact character varying(20) NOT NULL,
+    components jsonb DEFAULT '[]'::jsonb NOT NULL,
+    created_by integer,
+    started_at timestamp without time zone NOT NULL,
+    resolved_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: status_incidents_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.status_incidents_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: status_incidents_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.status_incidents_id_seq OWNED BY public.status_incidents.id;
+
+
+--
+-- Name: events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.events ALTER COLUMN id SET DEFAULT nextval('public.events_id_seq'::regclass);
+
+
+--
+-- Name: nwc_connections id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.nwc_connections ALTER COLUMN id SET DEFAULT nextval('public.nwc_connections_id_seq'::regclass);
+
+
+--
+-- Name: payments id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.payments ALTER COLUMN id SET DEFAULT nextval('public.payments_id_seq'::regclass);
+
+
+--
+-- Name: tickets id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tickets ALTER COLUMN id SET DEFAULT nextval('public.tickets_id_seq'::regclass);
+
+
+--
+-- Name: uma_request_invoices id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.uma_request_invoices ALTER COLUMN id SET DEFAULT nextval('public.uma_request_invoices_id_seq'::regclass);
+
+
+--
+-- Name: users id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+
+--
+-- Name: fraud_flags id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.fraud_flags ALTER COLUMN id SET DEFAULT nextval('public.fraud_flags_id_seq'::regclass);
+
+
+--
+-- Name: event_addons id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_addons ALTER COLUMN id SET DEFAULT nextval('public.event_addons_id_seq'::regclass);
+
+
+--
+-- Name: event_form_fields id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_form_fields ALTER COLUMN id SET DEFAULT nextval('public.event_form_fields_id_seq'::regclass);
+
+
+--
+-- Name: event_access_codes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_access_codes ALTER COLUMN id SET DEFAULT nextval('public.event_access_codes_id_seq'::regclass);
+
+
+--
+-- Name: event_allowlist id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_allowlist ALTER COLUMN id SET DEFAULT nextval('public.event_allowlist_id_seq'::regclass);
+
+
+--
+-- Name: purchase_attestations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.purchase_attestations ALTER COLUMN id SET DEFAULT nextval('public.purchase_attestations_id_seq'::regclass);
+
+
+--
+-- Name: event_translations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_translations ALTER COLUMN id SET DEFAULT nextval('public.event_translations_id_seq'::regclass);
+
+
+--
+-- Name: notification_templates id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.notification_templates ALTER COLUMN id SET DEFAULT nextval('public.notification_templates_id_seq'::regclass);
+
+
+--
+-- Name: orders id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orders ALTER COLUMN id SET DEFAULT nextval('public.orders_id_seq'::regclass);
+
+
+--
+-- Name: event_reschedules id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_reschedules ALTER COLUMN id SET DEFAULT nextval('public.event_reschedules_id_seq'::regclass);
+
+
+--
+-- Name: event_cancellations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_cancellations ALTER COLUMN id SET DEFAULT nextval('public.event_cancellations_id_seq'::regclass);
+
+
+--
+-- Name: ticket_scans id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_scans ALTER COLUMN id SET DEFAULT nextval('public.ticket_scans_id_seq'::regclass);
+
+
+--
+-- Name: scanner_devices id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices ALTER COLUMN id SET DEFAULT nextval('public.scanner_devices_id_seq'::regclass);
+
+
+--
+-- Name: inventory_holds id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.inventory_holds ALTER COLUMN id SET DEFAULT nextval('public.inventory_holds_id_seq'::regclass);
+
+
+--
+-- Name: short_links id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.short_links ALTER COLUMN id SET DEFAULT nextval('public.short_links_id_seq'::regclass);
+
+
+--
+-- Name: affiliates id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.affiliates ALTER COLUMN id SET DEFAULT nextval('public.affiliates_id_seq'::regclass);
+
+
+--
+-- Name: ticket_gifts id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts ALTER COLUMN id SET DEFAULT nextval('public.ticket_gifts_id_seq'::regclass);
+
+
+--
+-- Name: pricing_rules id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pricing_rules ALTER COLUMN id SET DEFAULT nextval('public.pricing_rules_id_seq'::regclass);
+
+
+--
+-- Name: event_price_changes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_price_changes ALTER COLUMN id SET DEFAULT nextval('public.event_price_changes_id_seq'::regclass);
+
+
+--
+-- Name: exchange_rates id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.exchange_rates ALTER COLUMN id SET DEFAULT nextval('public.exchange_rates_id_seq'::regclass);
+
+
+--
+-- Name: cashu_redemptions id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.cashu_redemptions ALTER COLUMN id SET DEFAULT nextval('public.cashu_redemptions_id_seq'::regclass);
+
+
+--
+-- Name: lnurl_auth_challenges id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges ALTER COLUMN id SET DEFAULT nextval('public.lnurl_auth_challenges_id_seq'::regclass);
+
+
+--
+-- Name: ticket_addons id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_addons ALTER COLUMN id SET DEFAULT nextval('public.ticket_addons_id_seq'::regclass);
+
+
+--
+-- Name: ticket_answers id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_answers ALTER COLUMN id SET DEFAULT nextval('public.ticket_answers_id_seq'::regclass);
+
+
+--
+-- Name: payment_line_items id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_line_items ALTER COLUMN id SET DEFAULT nextval('public.payment_line_items_id_seq'::regclass);
+
+
+--
+-- Name: receipts id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.receipts ALTER COLUMN id SET DEFAULT nextval('public.receipts_id_seq'::regclass);
+
+
+--
+-- Name: ledger_accounts id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_accounts ALTER COLUMN id SET DEFAULT nextval('public.ledger_accounts_id_seq'::regclass);
+
+
+--
+-- Name: ledger_entries id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_entries ALTER COLUMN id SET DEFAULT nextval('public.ledger_entries_id_seq'::regclass);
+
+
+--
+-- Name: ledger_postings id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_postings ALTER COLUMN id SET DEFAULT nextval('public.ledger_postings_id_seq'::regclass);
+
+
+--
+-- Name: disputes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.disputes ALTER COLUMN id SET DEFAULT nextval('public.disputes_id_seq'::regclass);
+
+
+--
+-- Name: webhook_events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_events ALTER COLUMN id SET DEFAULT nextval('public.webhook_events_id_seq'::regclass);
+
+
+--
+-- Name: archived_records id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.archived_records ALTER COLUMN id SET DEFAULT nextval('public.archived_records_id_seq'::regclass);
+
+
+--
+-- Name: admin_notifications id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.admin_notifications ALTER COLUMN id SET DEFAULT nextval('public.admin_notifications_id_seq'::regclass);
+
+
+--
+-- Name: alert_connectors id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.alert_connectors ALTER COLUMN id SET DEFAULT nextval('public.alert_connectors_id_seq'::regclass);
+
+
+--
+-- Name: status_incidents id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.status_incidents ALTER COLUMN id SET DEFAULT nextval('public.status_incidents_id_seq'::regclass);
+
+
+--
+-- Name: events events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.events
+    ADD CONSTRAINT events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: nwc_connections nwc_connections_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.nwc_connections
+    ADD CONSTRAINT nwc_connections_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: nwc_connections nwc_connections_user_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.nwc_connections
+    ADD CONSTRAINT nwc_connections_user_id_key UNIQUE (user_id);
+
+
+--
+-- Name: payments payments_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.payments
+    ADD CONSTRAINT payments_pkey PRIMARY KEY (id, created_at);
+
+
+--
+-- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_migrations
+    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+
+
+--
+-- Name: ticket_codes ticket_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_codes
+    ADD CONSTRAINT ticket_codes_pkey PRIMARY KEY (ticket_code);
+
+
+--
+-- Name: tickets tickets_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_pkey PRIMARY KEY (id, event_id);
+
+
+--
+-- Name: tickets tickets_event_id_ticket_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_event_id_ticket_code_key UNIQUE (event_id, ticket_code);
+
+
+--
+-- Name: uma_request_invoices uma_request_invoices_invoice_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.uma_request_invoices
+    ADD CONSTRAINT uma_request_invoices_invoice_id_key UNIQUE (invoice_id);
+
+
+--
+-- Name: uma_request_invoices uma_request_invoices_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.uma_request_invoices
+    ADD CONSTRAINT uma_request_invoices_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: users users_email_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+
+--
+-- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: settings settings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.settings
+    ADD CONSTRAINT settings_pkey PRIMARY KEY (key);
+
+
+--
+-- Name: fraud_flags fraud_flags_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.fraud_flags
+    ADD CONSTRAINT fraud_flags_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_addons event_addons_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_addons
+    ADD CONSTRAINT event_addons_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_form_fields event_form_fields_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_form_fields
+    ADD CONSTRAINT event_form_fields_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_access_codes event_access_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_access_codes
+    ADD CONSTRAINT event_access_codes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_access_codes event_access_codes_event_id_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_access_codes
+    ADD CONSTRAINT event_access_codes_event_id_code_key UNIQUE (event_id, code);
+
+
+--
+-- Name: event_allowlist event_allowlist_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_allowlist
+    ADD CONSTRAINT event_allowlist_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_allowlist event_allowlist_event_id_entry_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_allowlist
+    ADD CONSTRAINT event_allowlist_event_id_entry_key UNIQUE (event_id, entry);
+
+
+--
+-- Name: purchase_attestations purchase_attestations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.purchase_attestations
+    ADD CONSTRAINT purchase_attestations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: purchase_attestations purchase_attestations_ticket_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.purchase_attestations
+    ADD CONSTRAINT purchase_attestations_ticket_id_key UNIQUE (ticket_id);
+
+
+--
+-- Name: event_translations event_translations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_translations
+    ADD CONSTRAINT event_translations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_translations event_translations_event_id_locale_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_translations
+    ADD CONSTRAINT event_translations_event_id_locale_key UNIQUE (event_id, locale);
+
+
+--
+-- Name: notification_templates notification_templates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.notification_templates
+    ADD CONSTRAINT notification_templates_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: orders orders_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: account_claims account_claims_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_claims
+    ADD CONSTRAINT account_claims_pkey PRIMARY KEY (user_id);
+
+
+--
+-- Name: account_claims account_claims_secret_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_claims
+    ADD CONSTRAINT account_claims_secret_hash_key UNIQUE (secret_hash);
+
+
+--
+-- Name: email_changes email_changes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_changes
+    ADD CONSTRAINT email_changes_pkey PRIMARY KEY (user_id);
+
+
+--
+-- Name: email_changes email_changes_secret_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_changes
+    ADD CONSTRAINT email_changes_secret_hash_key UNIQUE (secret_hash);
+
+
+--
+-- Name: event_reschedules event_reschedules_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_reschedules
+    ADD CONSTRAINT event_reschedules_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_cancellations event_cancellations_event_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_cancellations
+    ADD CONSTRAINT event_cancellations_event_id_key UNIQUE (event_id);
+
+
+--
+-- Name: event_cancellations event_cancellations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_cancellations
+    ADD CONSTRAINT event_cancellations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: ticket_scans ticket_scans_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_scans
+    ADD CONSTRAINT ticket_scans_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: scanner_devices scanner_devices_pairing_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices
+    ADD CONSTRAINT scanner_devices_pairing_hash_key UNIQUE (pairing_hash);
+
+
+--
+-- Name: scanner_devices scanner_devices_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices
+    ADD CONSTRAINT scanner_devices_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: scanner_devices scanner_devices_token_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices
+    ADD CONSTRAINT scanner_devices_token_hash_key UNIQUE (token_hash);
+
+
+--
+-- Name: inventory_holds inventory_holds_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.inventory_holds
+    ADD CONSTRAINT inventory_holds_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_relations event_relations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_relations
+    ADD CONSTRAINT event_relations_pkey PRIMARY KEY (event_id, related_event_id);
+
+
+--
+-- Name: organizer_profiles organizer_profiles_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.organizer_profiles
+    ADD CONSTRAINT organizer_profiles_pkey PRIMARY KEY (user_id);
+
+
+--
+-- Name: organizer_profiles organizer_profiles_slug_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.organizer_profiles
+    ADD CONSTRAINT organizer_profiles_slug_key UNIQUE (slug);
+
+
+--
+-- Name: short_links short_links_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.short_links
+    ADD CONSTRAINT short_links_code_key UNIQUE (code);
+
+
+--
+-- Name: short_links short_links_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.short_links
+    ADD CONSTRAINT short_links_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: affiliates affiliates_code_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.affiliates
+    ADD CONSTRAINT affiliates_code_key UNIQUE (code);
+
+
+--
+-- Name: affiliates affiliates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.affiliates
+    ADD CONSTRAINT affiliates_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: affiliates affiliates_user_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.affiliates
+    ADD CONSTRAINT affiliates_user_id_key UNIQUE (user_id);
+
+
+--
+-- Name: ticket_gifts ticket_gifts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts
+    ADD CONSTRAINT ticket_gifts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: ticket_gifts ticket_gifts_secret_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts
+    ADD CONSTRAINT ticket_gifts_secret_hash_key UNIQUE (secret_hash);
+
+
+--
+-- Name: ticket_gifts ticket_gifts_ticket_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts
+    ADD CONSTRAINT ticket_gifts_ticket_id_key UNIQUE (ticket_id);
+
+
+--
+-- Name: pricing_rules pricing_rules_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pricing_rules
+    ADD CONSTRAINT pricing_rules_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_price_changes event_price_changes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_price_changes
+    ADD CONSTRAINT event_price_changes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: exchange_rates exchange_rates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.exchange_rates
+    ADD CONSTRAINT exchange_rates_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: cashu_redemptions cashu_redemptions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.cashu_redemptions
+    ADD CONSTRAINT cashu_redemptions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: lnurl_auth_challenges lnurl_auth_challenges_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges
+    ADD CONSTRAINT lnurl_auth_challenges_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: lnurl_auth_challenges lnurl_auth_challenges_k1_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges
+    ADD CONSTRAINT lnurl_auth_challenges_k1_key UNIQUE (k1);
+
+
+--
+-- Name: lnurl_auth_challenges lnurl_auth_challenges_session_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges
+    ADD CONSTRAINT lnurl_auth_challenges_session_hash_key UNIQUE (session_hash);
+
+
+--
+-- Name: ticket_addons ticket_addons_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_addons
+    ADD CONSTRAINT ticket_addons_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: ticket_answers ticket_answers_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_answers
+    ADD CONSTRAINT ticket_answers_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_line_items payment_line_items_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_line_items
+    ADD CONSTRAINT payment_line_items_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: receipts receipts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.receipts
+    ADD CONSTRAINT receipts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: receipts receipts_payment_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.receipts
+    ADD CONSTRAINT receipts_payment_id_key UNIQUE (payment_id);
+
+
+--
+-- Name: receipts receipts_receipt_number_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.receipts
+    ADD CONSTRAINT receipts_receipt_number_key UNIQUE (receipt_number);
+
+
+--
+-- Name: organizer_fees organizer_fees_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.organizer_fees
+    ADD CONSTRAINT organizer_fees_pkey PRIMARY KEY (organizer_id);
+
+
+--
+-- Name: ledger_accounts ledger_accounts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_accounts
+    ADD CONSTRAINT ledger_accounts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: ledger_accounts ledger_accounts_name_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_accounts
+    ADD CONSTRAINT ledger_accounts_name_key UNIQUE (name);
+
+
+--
+-- Name: ledger_entries ledger_entries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_entries
+    ADD CONSTRAINT ledger_entries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: ledger_entries ledger_entries_kind_payment_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_entries
+    ADD CONSTRAINT ledger_entries_kind_payment_id_key UNIQUE (kind, payment_id);
+
+
+--
+-- Name: ledger_postings ledger_postings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_postings
+    ADD CONSTRAINT ledger_postings_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: disputes disputes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.disputes
+    ADD CONSTRAINT disputes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhook_events webhook_events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_events
+    ADD CONSTRAINT webhook_events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: wallet_claims wallet_claims_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.wallet_claims
+    ADD CONSTRAINT wallet_claims_pkey PRIMARY KEY (ticket_id);
+
+
+--
+-- Name: archived_records archived_records_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.archived_records
+    ADD CONSTRAINT archived_records_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: archived_records archived_records_table_name_record_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.archived_records
+    ADD CONSTRAINT archived_records_table_name_record_id_key UNIQUE (table_name, record_id);
+
+
+--
+-- Name: admin_notifications admin_notifications_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.admin_notifications
+    ADD CONSTRAINT admin_notifications_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: alert_connectors alert_connectors_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.alert_connectors
+    ADD CONSTRAINT alert_connectors_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: status_incidents status_incidents_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.status_incidents
+    ADD CONSTRAINT status_incidents_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: idx_events_is_active; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_events_is_active ON public.events USING btree (is_active);
+
+
+--
+-- Name: idx_events_start_time; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_events_start_time ON public.events USING btree (start_time);
+
+
+--
+-- Name: idx_payments_bolt11; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_bolt11 ON public.payments USING btree (bolt11);
+
+
+--
+-- Name: idx_payments_invoice_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_invoice_id ON public.payments USING btree (invoice_id);
+
+
+--
+-- Name: idx_payments_ticket_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_ticket_id ON public.payments USING btree (ticket_id);
+
+
+--
+-- Name: idx_tickets_ticket_code; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_tickets_ticket_code ON public.tickets USING btree (ticket_code);
+
+
+--
+-- Name: idx_tickets_payment_status; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_tickets_payment_status ON public.tickets USING btree (payment_status);
+
+
+--
+-- Name: idx_tickets_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_tickets_user_id ON public.tickets USING btree (user_id);
+
+
+--
+-- Name: idx_uma_invoices_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_uma_invoices_event_id ON public.uma_request_invoices USING btree (event_id);
+
+
+--
+-- Name: idx_uma_invoices_event_source; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_uma_invoices_event_source ON public.uma_request_invoices USING btree (event_id, source);
+
+
+--
+-- Name: idx_uma_invoices_payment_hash; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_uma_invoices_payment_hash ON public.uma_request_invoices USING btree (payment_hash);
+
+
+--
+-- Name: idx_uma_invoices_status; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_uma_invoices_status ON public.uma_request_invoices USING btree (status);
+
+
+--
+-- Name: idx_uma_invoices_ticket_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_uma_invoices_ticket_id ON public.uma_request_invoices USING btree (ticket_id);
+
+
+--
+-- Name: idx_fraud_flags_action_created_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_fraud_flags_action_created_at ON public.fraud_flags USING btree (action, created_at);
+
+
+--
+-- Name: idx_event_addons_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_event_addons_event_id ON public.event_addons USING btree (event_id);
+
+
+--
+-- Name: idx_event_form_fields_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_event_form_fields_event_id ON public.event_form_fields USING btree (event_id);
+
+
+--
+-- Name: idx_ticket_addons_ticket_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ticket_addons_ticket_id ON public.ticket_addons USING btree (ticket_id);
+
+
+--
+-- Name: idx_ticket_answers_ticket_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ticket_answers_ticket_id ON public.ticket_answers USING btree (ticket_id);
+
+
+--
+-- Name: idx_purchase_attestations_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_purchase_attestations_event_id ON public.purchase_attestations USING btree (event_id);
+
+
+--
+-- Name: idx_notification_templates_organizer_version; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_notification_templates_organizer_version ON public.notification_templates USING btree (organizer_id, kind, locale, version) WHERE (organizer_id IS NOT NULL);
+
+
+--
+-- Name: idx_notification_templates_platform_version; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_notification_templates_platform_version ON public.notification_templates USING btree (kind, locale, version) WHERE (organizer_id IS NULL);
+
+
+--
+-- Name: idx_orders_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_orders_user_id ON public.orders USING btree (user_id);
+
+
+--
+-- Name: idx_orders_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_orders_event_id ON public.orders USING btree (event_id);
+
+
+--
+-- Name: idx_tickets_order_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_tickets_order_id ON public.tickets USING btree (order_id);
+
+
+--
+-- Name: idx_payment_line_items_payment_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_line_items_payment_id ON public.payment_line_items USING btree (payment_id);
+
+
+--
+-- Name: idx_events_organizer_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_events_organizer_id ON public.events USING btree (organizer_id);
+
+
+--
+-- Name: idx_event_reschedules_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_event_reschedules_event_id ON public.event_reschedules USING btree (event_id);
+
+
+--
+-- Name: idx_event_cancellations_status; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_event_cancellations_status ON public.event_cancellations USING btree (status);
+
+
+--
+-- Name: idx_ticket_scans_event_id_scanned_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ticket_scans_event_id_scanned_at ON public.ticket_scans USING btree (event_id, scanned_at);
+
+
+--
+-- Name: idx_scanner_devices_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_scanner_devices_event_id ON public.scanner_devices USING btree (event_id);
+
+
+--
+-- Name: idx_inventory_holds_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_inventory_holds_event_id ON public.inventory_holds USING btree (event_id);
+
+
+--
+-- Name: idx_short_links_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_short_links_event_id ON public.short_links USING btree (event_id);
+
+
+--
+-- Name: idx_short_links_generated_event; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_short_links_generated_event ON public.short_links USING btree (event_id) WHERE ((NOT custom) AND (event_id IS NOT NULL));
+
+
+--
+-- Name: idx_short_links_generated_ticket; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_short_links_generated_ticket ON public.short_links USING btree (ticket_id) WHERE ((NOT custom) AND (ticket_id IS NOT NULL));
+
+
+--
+-- Name: idx_orders_affiliate_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_orders_affiliate_id ON public.orders USING btree (affiliate_id);
+
+
+--
+-- Name: idx_ticket_gifts_due; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ticket_gifts_due ON public.ticket_gifts USING btree (status, deliver_at);
+
+
+--
+-- Name: idx_ticket_gifts_sender_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ticket_gifts_sender_id ON public.ticket_gifts USING btree (sender_id);
+
+
+--
+-- Name: idx_pricing_rules_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_pricing_rules_event_id ON public.pricing_rules USING btree (event_id);
+
+
+--
+-- Name: idx_event_price_changes_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_event_price_changes_event_id ON public.event_price_changes USING btree (event_id);
+
+
+--
+-- Name: idx_exchange_rates_currency_fetched_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_exchange_rates_currency_fetched_at ON public.exchange_rates USING btree (currency, fetched_at);
+
+
+--
+-- Name: idx_cashu_redemptions_active; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_cashu_redemptions_active ON public.cashu_redemptions USING btree (payment_id) WHERE ((state)::text = ANY ((ARRAY['pending'::character varying, 'paid'::character varying])::text[]));
+
+
+--
+-- Name: idx_cashu_redemptions_payment_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_cashu_redemptions_payment_id ON public.cashu_redemptions USING btree (payment_id);
+
+
+--
+-- Name: idx_cashu_redemptions_created_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_cashu_redemptions_created_at ON public.cashu_redemptions USING btree (created_at);
+
+
+--
+-- Name: idx_users_lnurl_auth_key; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_users_lnurl_auth_key ON public.users USING btree (lnurl_auth_key);
+
+
+--
+-- Name: idx_lnurl_auth_challenges_expires_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_lnurl_auth_challenges_expires_at ON public.lnurl_auth_challenges USING btree (expires_at);
+
+
+--
+-- Name: idx_payments_pending_expires_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_pending_expires_at ON public.payments USING btree (expires_at) WHERE ((status)::text = 'pending'::text);
+
+
+--
+-- Name: idx_tickets_one_per_user; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_tickets_one_per_user ON public.tickets USING btree (event_id, user_id) WHERE (one_per_user AND ((payment_status)::text = ANY ((ARRAY['paid'::character varying, 'pending'::character varying, 'review'::character varying, 'disputed'::character varying])::text[])));
+
+
+--
+-- Name: idx_events_category; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_events_category ON public.events USING btree (category);
+
+
+--
+-- Name: idx_payments_paid_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_paid_at ON public.payments USING btree (paid_at);
+
+
+--
+-- Name: idx_payments_payment_hash; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payments_payment_hash ON public.payments USING btree (payment_hash);
+
+
+--
+-- Name: idx_ledger_entries_occurred_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ledger_entries_occurred_at ON public.ledger_entries USING btree (occurred_at);
+
+
+--
+-- Name: idx_ledger_postings_account_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ledger_postings_account_id ON public.ledger_postings USING btree (account_id);
+
+
+--
+-- Name: idx_ledger_postings_entry_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ledger_postings_entry_id ON public.ledger_postings USING btree (entry_id);
+
+
+--
+-- Name: idx_disputes_open_payment_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_disputes_open_payment_id ON public.disputes USING btree (payment_id) WHERE ((status)::text = 'open'::text);
+
+
+--
+-- Name: idx_disputes_payment_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_disputes_payment_id ON public.disputes USING btree (payment_id);
+
+
+--
+-- Name: idx_webhook_events_due; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_webhook_events_due ON public.webhook_events USING btree (status, next_attempt_at);
+
+
+--
+-- Name: idx_webhook_events_source_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_webhook_events_source_event_id ON public.webhook_events USING btree (source, event_id);
+
+
+--
+-- Name: idx_archived_records_event_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_archived_records_event_id ON public.archived_records USING btree (event_id);
+
+
+--
+-- Name: idx_admin_notifications_unread; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_admin_notifications_unread ON public.admin_notifications USING btree (id) WHERE (read_at IS NULL);
+
+
+--
+-- Name: idx_status_incidents_started_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_status_incidents_started_at ON public.status_incidents USING btree (started_at);
+
+
+--
+-- Name: cashu_redemptions cashu_redemptions_payment_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER cashu_redemptions_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON public.cashu_redemptions FOR EACH ROW EXECUTE FUNCTION public.check_payment_reference();
+
+
+--
+-- Name: disputes disputes_payment_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER disputes_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON public.disputes FOR EACH ROW EXECUTE FUNCTION public.check_payment_reference();
+
+
+--
+-- Name: fraud_flags fraud_flags_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER fraud_flags_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.fraud_flags FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: payment_line_items payment_line_items_payment_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER payment_line_items_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON public.payment_line_items FOR EACH ROW EXECUTE FUNCTION public.check_payment_reference();
+
+
+--
+-- Name: payments payments_delete; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER payments_delete AFTER DELETE ON public.payments FOR EACH ROW EXECUTE FUNCTION public.payment_deleted();
+
+
+--
+-- Name: payments payments_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER payments_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.payments FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: purchase_attestations purchase_attestations_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER purchase_attestations_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.purchase_attestations FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: receipts receipts_payment_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER receipts_payment_id_check AFTER INSERT OR UPDATE OF payment_id ON public.receipts FOR EACH ROW EXECUTE FUNCTION public.check_payment_reference();
+
+
+--
+-- Name: short_links short_links_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER short_links_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.short_links FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: ticket_addons ticket_addons_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER ticket_addons_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.ticket_addons FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: ticket_answers ticket_answers_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER ticket_answers_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.ticket_answers FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: ticket_gifts ticket_gifts_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER ticket_gifts_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.ticket_gifts FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: ticket_scans ticket_scans_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER ticket_scans_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.ticket_scans FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: tickets tickets_delete; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER tickets_delete AFTER DELETE ON public.tickets FOR EACH ROW EXECUTE FUNCTION public.ticket_deleted();
+
+
+--
+-- Name: tickets tickets_ticket_code_change; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER tickets_ticket_code_change AFTER INSERT OR UPDATE OF ticket_code ON public.tickets FOR EACH ROW EXECUTE FUNCTION public.ticket_code_changed();
+
+
+--
+-- Name: uma_request_invoices uma_request_invoices_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER uma_request_invoices_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.uma_request_invoices FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: wallet_claims wallet_claims_ticket_id_check; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER wallet_claims_ticket_id_check AFTER INSERT OR UPDATE OF ticket_id ON public.wallet_claims FOR EACH ROW EXECUTE FUNCTION public.check_ticket_reference();
+
+
+--
+-- Name: nwc_connections nwc_connections_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.nwc_connections
+    ADD CONSTRAINT nwc_connections_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
 
 
 --
--- Name: idx_tickets_event_id; Type: INDEX; Schema: public; Owner: -
+-- Name: tickets tickets_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_tickets_event_id ON public.tickets USING btree (event_id);
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id);
 
 
 --
--- Name: idx_tickets_payment_status; Type: INDEX; Schema: public; Owner: -
+-- Name: tickets tickets_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_tickets_payment_status ON public.tickets USING btree (payment_status);
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
 
 
 --
--- Name: idx_tickets_user_id; Type: INDEX; Schema: public; Owner: -
+-- Name: uma_request_invoices uma_request_invoices_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_tickets_user_id ON public.tickets USING btree (user_id);
+ALTER TABLE ONLY public.uma_request_invoices
+    ADD CONSTRAINT uma_request_invoices_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: idx_uma_invoices_event_id; Type: INDEX; Schema: public; Owner: -
+-- Name: fraud_flags fraud_flags_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_uma_invoices_event_id ON public.uma_request_invoices USING btree (event_id);
+ALTER TABLE ONLY public.fraud_flags
+    ADD CONSTRAINT fraud_flags_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: idx_uma_invoices_status; Type: INDEX; Schema: public; Owner: -
+-- Name: fraud_flags fraud_flags_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_uma_invoices_status ON public.uma_request_invoices USING btree (status);
+ALTER TABLE ONLY public.fraud_flags
+    ADD CONSTRAINT fraud_flags_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: idx_uma_invoices_ticket_id; Type: INDEX; Schema: public; Owner: -
+-- Name: event_addons event_addons_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_uma_invoices_ticket_id ON public.uma_request_invoices USING btree (ticket_id);
+ALTER TABLE ONLY public.event_addons
+    ADD CONSTRAINT event_addons_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: nwc_connections nwc_connections_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: event_form_fields event_form_fields_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.nwc_connections
-    ADD CONSTRAINT nwc_connections_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+ALTER TABLE ONLY public.event_form_fields
+    ADD CONSTRAINT event_form_fields_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: payments payments_ticket_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: event_access_codes event_access_codes_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.payments
-    ADD CONSTRAINT payments_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES public.tickets(id);
+ALTER TABLE ONLY public.event_access_codes
+    ADD CONSTRAINT event_access_codes_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: tickets tickets_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: event_allowlist event_allowlist_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tickets
-    ADD CONSTRAINT tickets_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id);
+ALTER TABLE ONLY public.event_allowlist
+    ADD CONSTRAINT event_allowlist_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: tickets tickets_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: purchase_attestations purchase_attestations_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tickets
-    ADD CONSTRAINT tickets_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+ALTER TABLE ONLY public.purchase_attestations
+    ADD CONSTRAINT purchase_attestations_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: uma_request_invoices uma_request_invoices_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: event_translations event_translations_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.uma_request_invoices
-    ADD CONSTRAINT uma_request_invoices_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.event_translations
+    ADD CONSTRAINT event_translations_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
 
 
 --
--- Name: uma_request_invoices uma_request_invoices_ticket_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: notification_templates notification_templates_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.uma_request_invoices
-    ADD CONSTRAINT uma_request_invoices_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES public.tickets(id);
+ALTER TABLE ONLY public.notification_templates
+    ADD CONSTRAINT notification_templates_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: notification_templates notification_templates_organizer_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.notification_templates
+    ADD CONSTRAINT notification_templates_organizer_id_fkey FOREIGN KEY (organizer_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: orders orders_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id);
+
+
+--
+-- Name: orders orders_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+
+--
+-- Name: tickets tickets_order_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id);
+
+
+--
+-- Name: account_claims account_claims_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_claims
+    ADD CONSTRAINT account_claims_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: email_changes email_changes_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_changes
+    ADD CONSTRAINT email_changes_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: event_reschedules event_reschedules_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_reschedules
+    ADD CONSTRAINT event_reschedules_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: event_reschedules event_reschedules_rescheduled_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_reschedules
+    ADD CONSTRAINT event_reschedules_rescheduled_by_fkey FOREIGN KEY (rescheduled_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: event_cancellations event_cancellations_cancelled_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_cancellations
+    ADD CONSTRAINT event_cancellations_cancelled_by_fkey FOREIGN KEY (cancelled_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: event_cancellations event_cancellations_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_cancellations
+    ADD CONSTRAINT event_cancellations_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: ticket_scans ticket_scans_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_scans
+    ADD CONSTRAINT ticket_scans_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: ticket_scans ticket_scans_scanner_device_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_scans
+    ADD CONSTRAINT ticket_scans_scanner_device_id_fkey FOREIGN KEY (scanner_device_id) REFERENCES public.scanner_devices(id) ON DELETE SET NULL;
+
+
+--
+-- Name: scanner_devices scanner_devices_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices
+    ADD CONSTRAINT scanner_devices_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: scanner_devices scanner_devices_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scanner_devices
+    ADD CONSTRAINT scanner_devices_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: inventory_holds inventory_holds_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.inventory_holds
+    ADD CONSTRAINT inventory_holds_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: inventory_holds inventory_holds_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.inventory_holds
+    ADD CONSTRAINT inventory_holds_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: inventory_holds inventory_holds_released_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.inventory_holds
+    ADD CONSTRAINT inventory_holds_released_by_fkey FOREIGN KEY (released_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: event_relations event_relations_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_relations
+    ADD CONSTRAINT event_relations_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: event_relations event_relations_related_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_relations
+    ADD CONSTRAINT event_relations_related_event_id_fkey FOREIGN KEY (related_event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: organizer_profiles organizer_profiles_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.organizer_profiles
+    ADD CONSTRAINT organizer_profiles_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: short_links short_links_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.short_links
+    ADD CONSTRAINT short_links_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: short_links short_links_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.short_links
+    ADD CONSTRAINT short_links_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: affiliates affiliates_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.affiliates
+    ADD CONSTRAINT affiliates_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: orders orders_affiliate_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_affiliate_id_fkey FOREIGN KEY (affiliate_id) REFERENCES public.affiliates(id) ON DELETE SET NULL;
+
+
+--
+-- Name: ledger_entries ledger_entries_affiliate_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_entries
+    ADD CONSTRAINT ledger_entries_affiliate_id_fkey FOREIGN KEY (affiliate_id) REFERENCES public.affiliates(id);
+
+
+--
+-- Name: ticket_gifts ticket_gifts_recipient_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts
+    ADD CONSTRAINT ticket_gifts_recipient_id_fkey FOREIGN KEY (recipient_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: ticket_gifts ticket_gifts_sender_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_gifts
+    ADD CONSTRAINT ticket_gifts_sender_id_fkey FOREIGN KEY (sender_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: pricing_rules pricing_rules_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pricing_rules
+    ADD CONSTRAINT pricing_rules_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: event_price_changes event_price_changes_event_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_price_changes
+    ADD CONSTRAINT event_price_changes_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+
+--
+-- Name: event_price_changes event_price_changes_rule_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_price_changes
+    ADD CONSTRAINT event_price_changes_rule_id_fkey FOREIGN KEY (rule_id) REFERENCES public.pricing_rules(id) ON DELETE SET NULL;
+
+
+--
+-- Name: tickets tickets_price_change_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tickets
+    ADD CONSTRAINT tickets_price_change_id_fkey FOREIGN KEY (price_change_id) REFERENCES public.event_price_changes(id) ON DELETE SET NULL;
+
+
+--
+-- Name: payments payments_exchange_rate_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE public.payments
+    ADD CONSTRAINT payments_exchange_rate_id_fkey FOREIGN KEY (exchange_rate_id) REFERENCES public.exchange_rates(id);
+
+
+--
+-- Name: lnurl_auth_challenges lnurl_auth_challenges_link_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges
+    ADD CONSTRAINT lnurl_auth_challenges_link_user_id_fkey FOREIGN KEY (link_user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: lnurl_auth_challenges lnurl_auth_challenges_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lnurl_auth_challenges
+    ADD CONSTRAINT lnurl_auth_challenges_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: ticket_addons ticket_addons_addon_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_addons
+    ADD CONSTRAINT ticket_addons_addon_id_fkey FOREIGN KEY (addon_id) REFERENCES public.event_addons(id) ON DELETE SET NULL;
+
+
+--
+-- Name: ticket_answers ticket_answers_field_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ticket_answers
+    ADD CONSTRAINT ticket_answers_field_id_fkey FOREIGN KEY (field_id) REFERENCES public.event_form_fields(id) ON DELETE SET NULL;
+
+
+--
+-- Name: events events_organizer_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.events
+    ADD CONSTRAINT events_organizer_id_fkey FOREIGN KEY (organizer_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: organizer_fees organizer_fees_organizer_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.organizer_fees
+    ADD CONSTRAINT organizer_fees_organizer_id_fkey FOREIGN KEY (organizer_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: ledger_entries ledger_entries_organizer_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_entries
+    ADD CONSTRAINT ledger_entries_organizer_id_fkey FOREIGN KEY (organizer_id) REFERENCES public.users(id);
+
+
+--
+-- Name: ledger_postings ledger_postings_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_postings
+    ADD CONSTRAINT ledger_postings_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.ledger_accounts(id);
+
+
+--
+-- Name: ledger_postings ledger_postings_entry_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_postings
+    ADD CONSTRAINT ledger_postings_entry_id_fkey FOREIGN KEY (entry_id) REFERENCES public.ledger_entries(id);
+
+
+--
+-- Name: disputes disputes_opened_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.disputes
+    ADD CONSTRAINT disputes_opened_by_fkey FOREIGN KEY (opened_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: disputes disputes_resolved_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.disputes
+    ADD CONSTRAINT disputes_resolved_by_fkey FOREIGN KEY (resolved_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: admin_notifications admin_notifications_read_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.admin_notifications
+    ADD CONSTRAINT admin_notifications_read_by_fkey FOREIGN KEY (read_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: status_incidents status_incidents_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.status_incidents
+    ADD CONSTRAINT status_incidents_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
@@ -518,4 +3854,55 @@ INSERT INTO public.schema_migrations (version) VALUES
     ('20260210000001'),
     ('20260212000001'),
     ('20260213000001'),
-    ('20260214000001');
+    ('20260214000001'),
+    ('20261016000001'),
+    ('20261016000003'),
+    ('20261016000004'),
+    ('20261016000005'),
+    ('20261016000006'),
+    ('20261016000007'),
+    ('20261016000008'),
+    ('20261016000009'),
+    ('20261016000010'),
+    ('20261016000011'),
+    ('20261016000012'),
+    ('20261016000013'),
+    ('20261016000014'),
+    ('20261016000015'),
+    ('20261016000016'),
+    ('20261016000017'),
+    ('20261016000018'),
+    ('20261016000019'),
+    ('20261016000020'),
+    ('20261016000021'),
+    ('20261016000022'),
+    ('20261016000023'),
+    ('20261016000024'),
+    ('20261016000025'),
+    ('20261016000026'),
+    ('20261016000027'),
+    ('20261016000028'),
+    ('20261016000029'),
+    ('20261016000030'),
+    ('20261016000031'),
+    ('20261016000032'),
+    ('20261016000033'),
+    ('20261016000034'),
+    ('20261016000035'),
+    ('20261016000036'),
+    ('20261016000037'),
+    ('20261016000038'),
+    ('20261016000039'),
+    ('20261016000040'),
+    ('20261016000041'),
+    ('20261016000042'),
+    ('20261016000043'),
+    ('20261016000044'),
+    ('20261016000045'),
+    ('20261016000046'),
+    ('20261016000047'),
+    ('20261016000048'),
+    ('20261016000049'),
+    ('20261016000050'),
+    ('20261016000051'),
+    ('20261016000052');
@@ -0,0 +1,103 @@
+-- migrate:up
+-- SQLite schema matching db/migrations up to 20261016000001. Later schema
+-- changes add a migration here with the same version as the Postgres one.
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    capacity INTEGER NOT NULL CHECK (capacity > 0),
+    price_sats BIGINT NOT NULL CHECK (price_sats > 0),
+    stream_url VARCHAR(500),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE tickets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    ticket_code VARCHAR(255) UNIQUE NOT NULL,
+    payment_status VARCHAR(50) DEFAULT 'pending',
+    invoice_id TEXT,
+    uma_address VARCHAR(255),
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id),
+    invoice_id TEXT NOT NULL,
+    amount_sats BIGINT NOT NULL,
+    status VARCHAR(50) DEFAULT 'pending',
+    preimage TEXT,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE uma_request_invoices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER REFERENCES tickets(id),
+    invoice_id TEXT UNIQUE NOT NULL,
+    payment_hash VARCHAR(255),
+    bolt11 TEXT NOT NULL,
+    amount_sats BIGINT NOT NULL CHECK (amount_sats > 0),
+    status VARCHAR(50) DEFAULT 'pending',
+    uma_address VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE nwc_connections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id),
+    connection_uri TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- value holds JSON text; SQLite has no jsonb type
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tickets_event_id ON tickets(event_id);
+CREATE INDEX idx_tickets_user_id ON tickets(user_id);
+CREATE INDEX idx_tickets_payment_status ON tickets(payment_status);
+CREATE INDEX idx_payments_ticket_id ON payments(ticket_id);
+CREATE INDEX idx_payments_invoice_id ON payments(invoice_id);
+CREATE INDEX idx_events_start_time ON events(start_time);
+CREATE INDEX idx_events_is_active ON events(is_active);
+CREATE INDEX idx_uma_invoices_event_id ON uma_request_invoices(event_id);
+CREATE INDEX idx_uma_invoices_status ON uma_request_invoices(status);
+CREATE INDEX idx_uma_invoices_ticket_id ON uma_request_invoices(ticket_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS nwc_connections;
+DROP TABLE IF EXISTS uma_request_invoices;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS users;
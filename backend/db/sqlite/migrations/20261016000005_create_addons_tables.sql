@@ -0,0 +1,31 @@
+-- migrate:up
+CREATE TABLE event_addons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price_sats BIGINT NOT NULL CHECK (price_sats >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_event_addons_event_id ON event_addons(event_id);
+
+-- Line items keep the name and price at purchase so later catalog edits do
+-- not change what a buyer was charged
+CREATE TABLE ticket_addons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    addon_id INTEGER REFERENCES event_addons(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price_sats BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ticket_addons_ticket_id ON ticket_addons(ticket_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS ticket_addons;
+DROP TABLE IF EXISTS event_addons;
@@ -0,0 +1,25 @@
+-- migrate:up
+CREATE TABLE payment_line_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1,
+    unit_amount_sats BIGINT NOT NULL,
+    amount_sats BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payment_line_items_payment_id ON payment_line_items(payment_id);
+
+-- Receipt numbers are gapless and assigned in order of issue
+CREATE TABLE receipts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payment_id INTEGER NOT NULL UNIQUE REFERENCES payments(id),
+    receipt_number BIGINT NOT NULL UNIQUE,
+    issued_at TIMESTAMP NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS payment_line_items;
@@ -0,0 +1,17 @@
+-- migrate:up
+ALTER TABLE events ADD COLUMN organizer_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_events_organizer_id ON events(organizer_id);
+
+-- Per-organizer overrides of the platform fee configured on the server
+CREATE TABLE organizer_fees (
+    organizer_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    basis_points INTEGER NOT NULL CHECK (basis_points >= 0 AND basis_points <= 10000),
+    fixed_sats BIGINT NOT NULL DEFAULT 0 CHECK (fixed_sats >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS organizer_fees;
+DROP INDEX IF EXISTS idx_events_organizer_id;
+ALTER TABLE events DROP COLUMN organizer_id;
@@ -0,0 +1,20 @@
+-- migrate:up
+-- Claims by a counterparty VASP against a paid payment, such as a clawback.
+-- A payment has at most one open dispute.
+CREATE TABLE disputes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payment_id INTEGER NOT NULL REFERENCES payments(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'won', 'lost')),
+    reason TEXT NOT NULL,
+    resolution TEXT NOT NULL DEFAULT '',
+    opened_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    opened_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX idx_disputes_payment_id ON disputes(payment_id);
+CREATE UNIQUE INDEX idx_disputes_open_payment_id ON disputes(payment_id) WHERE status = 'open';
+
+-- migrate:down
+DROP TABLE IF EXISTS disputes;
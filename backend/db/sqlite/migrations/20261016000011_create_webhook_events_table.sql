@@ -0,0 +1,25 @@
+-- migrate:up
+-- Durable queue of verified inbound webhooks. The endpoint stores an event
+-- and acknowledges it; workers claim due events with a lease, process them
+-- and retry failures until max attempts, when the event is marked failed.
+CREATE TABLE webhook_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMP NOT NULL,
+    next_attempt_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_webhook_events_source_event_id ON webhook_events(source, event_id);
+CREATE INDEX idx_webhook_events_due ON webhook_events(status, next_attempt_at);
+
+-- migrate:down
+DROP TABLE IF EXISTS webhook_events;
@@ -0,0 +1,29 @@
+-- migrate:up
+-- Private events are left out of the public listing and sell only to
+-- allowlisted buyers or with an invite code
+ALTER TABLE events ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE event_access_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    max_uses INTEGER CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, code)
+);
+
+-- Entries are lowercased emails and UMA addresses
+CREATE TABLE event_allowlist (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    entry VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, entry)
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS event_allowlist;
+DROP TABLE IF EXISTS event_access_codes;
+ALTER TABLE events DROP COLUMN is_private;
@@ -0,0 +1,26 @@
+-- migrate:up
+-- min_age of 0 means no age restriction; an empty terms_version means the
+-- event has no terms to accept
+ALTER TABLE events ADD COLUMN min_age INTEGER NOT NULL DEFAULT 0 CHECK (min_age >= 0 AND min_age <= 99);
+ALTER TABLE events ADD COLUMN terms_version VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN terms_url VARCHAR(500) NOT NULL DEFAULT '';
+
+-- What the buyer attested to at purchase, kept for compliance
+CREATE TABLE purchase_attestations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id INTEGER NOT NULL UNIQUE REFERENCES tickets(id) ON DELETE CASCADE,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    min_age INTEGER NOT NULL DEFAULT 0,
+    terms_version VARCHAR(50) NOT NULL DEFAULT '',
+    client_ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    attested_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_purchase_attestations_event_id ON purchase_attestations(event_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS purchase_attestations;
+ALTER TABLE events DROP COLUMN terms_url;
+ALTER TABLE events DROP COLUMN terms_version;
+ALTER TABLE events DROP COLUMN min_age;
@@ -0,0 +1,6 @@
+-- migrate:up
+-- Language for notifications: en, ko or es
+ALTER TABLE users ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+-- migrate:down
+ALTER TABLE users DROP COLUMN locale;
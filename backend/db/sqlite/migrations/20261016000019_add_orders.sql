@@ -0,0 +1,25 @@
+-- migrate:up
+-- A checkout: the tickets, with their add-ons, one buyer bought together
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    event_id INTEGER NOT NULL REFERENCES events(id),
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_orders_user_id ON orders(user_id);
+
+ALTER TABLE tickets ADD COLUMN order_id INTEGER REFERENCES orders(id);
+
+CREATE INDEX idx_tickets_order_id ON tickets(order_id);
+
+-- Every earlier checkout bought one ticket, so each becomes its own order,
+-- numbered like the ticket
+INSERT INTO orders (id, user_id, event_id, created_at)
+SELECT id, user_id, event_id, COALESCE(created_at, CURRENT_TIMESTAMP) FROM tickets;
+UPDATE tickets SET order_id = id;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_tickets_order_id;
+ALTER TABLE tickets DROP COLUMN order_id;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,27 @@
+-- migrate:up
+-- Cancelled events stop selling for good
+ALTER TABLE events ADD COLUMN cancelled_at TIMESTAMP;
+
+-- The bulk job cancelling a cancelled event's tickets: paid tickets are
+-- refunded, unpaid ones voided. The counts track its progress.
+CREATE TABLE event_cancellations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL UNIQUE REFERENCES events(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'done', 'failed')),
+    total INTEGER NOT NULL DEFAULT 0,
+    refunded INTEGER NOT NULL DEFAULT 0,
+    voided INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    cancelled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_event_cancellations_status ON event_cancellations(status);
+
+-- migrate:down
+DROP TABLE IF EXISTS event_cancellations;
+ALTER TABLE events DROP COLUMN cancelled_at;
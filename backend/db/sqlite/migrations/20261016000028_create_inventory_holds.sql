@@ -0,0 +1,19 @@
+-- migrate:up
+-- Seats an admin sets aside for an event, such as a sponsor block or
+-- production holds. Held seats are not sold publicly until the hold is
+-- released.
+CREATE TABLE inventory_holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    seats INTEGER NOT NULL CHECK (seats > 0),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    released_at TIMESTAMP,
+    released_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_inventory_holds_event_id ON inventory_holds(event_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS inventory_holds;
@@ -0,0 +1,20 @@
+-- migrate:up
+-- An optional free-form category, such as "concert" or "workshop", used to
+-- suggest similar events
+ALTER TABLE events ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_events_category ON events(category);
+
+-- Related events an admin picks to show with an event, in display order
+CREATE TABLE event_relations (
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    related_event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (event_id, related_event_id)
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS event_relations;
+DROP INDEX IF EXISTS idx_events_category;
+ALTER TABLE events DROP COLUMN category;
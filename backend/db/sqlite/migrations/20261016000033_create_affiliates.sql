@@ -0,0 +1,29 @@
+-- migrate:up
+-- Affiliates earn a commission on the sales they refer. A user has at most
+-- one affiliate code, which buyers arrive with as the ref of a referral
+-- link. Orders keep the affiliate and the rate in force when they were
+-- placed; the commission is booked in the ledger with the sale and paid
+-- out like organizer payouts.
+CREATE TABLE affiliates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(40) NOT NULL UNIQUE,
+    basis_points INTEGER NOT NULL CHECK (basis_points BETWEEN 0 AND 10000),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE orders ADD COLUMN affiliate_id INTEGER REFERENCES affiliates(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN commission_basis_points INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_orders_affiliate_id ON orders(affiliate_id);
+
+ALTER TABLE ledger_entries ADD COLUMN affiliate_id INTEGER REFERENCES affiliates(id);
+
+-- migrate:down
+ALTER TABLE ledger_entries DROP COLUMN affiliate_id;
+DROP INDEX IF EXISTS idx_orders_affiliate_id;
+ALTER TABLE orders DROP COLUMN commission_basis_points;
+ALTER TABLE orders DROP COLUMN affiliate_id;
+DROP TABLE IF EXISTS affiliates;
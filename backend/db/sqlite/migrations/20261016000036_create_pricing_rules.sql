@@ -0,0 +1,41 @@
+-- migrate:up
+-- A pricing rule raises a fixed-price event's ticket price to price_sats
+-- once sold_percent of its capacity is sold or once starts_at passes;
+-- exactly one of the two triggers is set. The highest triggered price
+-- applies.
+CREATE TABLE pricing_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    price_sats BIGINT NOT NULL CHECK (price_sats > 0),
+    sold_percent INTEGER CHECK (sold_percent BETWEEN 1 AND 100),
+    starts_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CHECK ((sold_percent IS NULL) <> (starts_at IS NULL))
+);
+
+CREATE INDEX idx_pricing_rules_event_id ON pricing_rules(event_id);
+
+-- The prices an event's tickets were sold at, recorded at the first
+-- purchase after each change. rule_name keeps the rule's name should the
+-- rule be deleted; it is empty for the event's own price. Tickets point at
+-- the change they were priced by.
+CREATE TABLE event_price_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    price_sats BIGINT NOT NULL,
+    rule_id INTEGER REFERENCES pricing_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(100) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_event_price_changes_event_id ON event_price_changes(event_id);
+
+ALTER TABLE tickets ADD COLUMN price_change_id INTEGER REFERENCES event_price_changes(id) ON DELETE SET NULL;
+
+-- migrate:down
+ALTER TABLE tickets DROP COLUMN price_change_id;
+DROP INDEX IF EXISTS idx_event_price_changes_event_id;
+DROP TABLE IF EXISTS event_price_changes;
+DROP INDEX IF EXISTS idx_pricing_rules_event_id;
+DROP TABLE IF EXISTS pricing_rules;
@@ -0,0 +1,26 @@
+-- migrate:up
+-- Bitcoin's price in a fiat currency as quoted by source at fetched_at, in
+-- the currency's minor units (cents for USD) per bitcoin
+CREATE TABLE exchange_rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    currency VARCHAR(3) NOT NULL,
+    minor_units_per_btc BIGINT NOT NULL CHECK (minor_units_per_btc > 0),
+    source VARCHAR(50) NOT NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_exchange_rates_currency_fetched_at ON exchange_rates(currency, fetched_at);
+
+-- A paid payment's value in the currency of record, at the latest rate
+-- fetched before it was paid. Set once and never revalued, so the books
+-- do not move with the bitcoin price.
+ALTER TABLE payments ADD COLUMN fiat_currency VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN fiat_amount BIGINT;
+ALTER TABLE payments ADD COLUMN exchange_rate_id INTEGER REFERENCES exchange_rates(id);
+
+-- migrate:down
+ALTER TABLE payments DROP COLUMN exchange_rate_id;
+ALTER TABLE payments DROP COLUMN fiat_amount;
+ALTER TABLE payments DROP COLUMN fiat_currency;
+DROP INDEX IF EXISTS idx_exchange_rates_currency_fetched_at;
+DROP TABLE IF EXISTS exchange_rates;
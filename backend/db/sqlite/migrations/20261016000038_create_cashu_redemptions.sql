@@ -0,0 +1,31 @@
+-- migrate:up
+-- Cashu ecash melted at a trusted mint to pay a ticket invoice. The mint
+-- pays the invoice to our node, spending token_sats of proofs: the invoice
+-- amount plus up to fee_reserve_sats of routing fees. The token itself is
+-- a bearer instrument and is never stored.
+CREATE TABLE cashu_redemptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    mint_url VARCHAR(255) NOT NULL,
+    quote_id VARCHAR(255) NOT NULL DEFAULT '',
+    amount_sats BIGINT NOT NULL,
+    fee_reserve_sats BIGINT NOT NULL DEFAULT 0,
+    token_sats BIGINT NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'paid', 'failed')),
+    preimage VARCHAR(64),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- At most one melt in flight or done per payment, so a token cannot pay
+-- an invoice that another is already paying
+CREATE UNIQUE INDEX idx_cashu_redemptions_active ON cashu_redemptions(payment_id) WHERE state IN ('pending', 'paid');
+CREATE INDEX idx_cashu_redemptions_payment_id ON cashu_redemptions(payment_id);
+CREATE INDEX idx_cashu_redemptions_created_at ON cashu_redemptions(created_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_cashu_redemptions_created_at;
+DROP INDEX IF EXISTS idx_cashu_redemptions_payment_id;
+DROP INDEX IF EXISTS idx_cashu_redemptions_active;
+DROP TABLE IF EXISTS cashu_redemptions;
@@ -0,0 +1,22 @@
+-- migrate:up
+-- How long an event's ticket invoices stay payable, 0 for the default
+-- (one hour). Short expiries free the seats of unpaid tickets sooner
+-- during high-demand on-sales.
+ALTER TABLE events ADD COLUMN invoice_expiry_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- When the payment's invoice expires. A pending ticket holds its seat
+-- until then and is released as expired after.
+ALTER TABLE payments ADD COLUMN expires_at TIMESTAMP;
+CREATE INDEX idx_payments_pending_expires_at ON payments(expires_at) WHERE status = 'pending';
+
+-- Pending payments take the expiry of their ticket invoice
+UPDATE payments SET expires_at = (
+    SELECT uma_request_invoices.expires_at FROM uma_request_invoices
+    WHERE uma_request_invoices.bolt11 = payments.invoice_id
+    LIMIT 1
+) WHERE status = 'pending';
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_payments_pending_expires_at;
+ALTER TABLE payments DROP COLUMN expires_at;
+ALTER TABLE events DROP COLUMN invoice_expiry_seconds;
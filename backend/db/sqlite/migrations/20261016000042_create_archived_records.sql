@@ -0,0 +1,94 @@
+-- migrate:up
+-- Rows moved out of the hot tables by the retention archiver: settled
+-- payments, tickets and ended events with the rows that hang off them.
+-- Each row is kept as a JSON object of its columns under its original
+-- table and ID; event_id groups everything archived for an event.
+CREATE TABLE archived_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    table_name VARCHAR(50) NOT NULL,
+    record_id INTEGER NOT NULL,
+    event_id INTEGER,
+    data TEXT NOT NULL,
+    archived_at TIMESTAMP NOT NULL,
+    CONSTRAINT archived_records_table_name_record_id_key UNIQUE (table_name, record_id)
+);
+
+CREATE INDEX idx_archived_records_event_id ON archived_records(event_id);
+
+-- Ledger entries are the book of record and outlive the payments they
+-- book, so payment_id becomes a plain reference that may point into the
+-- archive. SQLite cannot drop a foreign key, so the ledger tables are
+-- rebuilt; postings go too, as they reference the entries.
+CREATE TABLE ledger_entries_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('sale', 'refund', 'payout')),
+    payment_id INTEGER,
+    organizer_id INTEGER REFERENCES users(id),
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    affiliate_id INTEGER REFERENCES affiliates(id),
+    CONSTRAINT ledger_entries_kind_payment_id_key UNIQUE (kind, payment_id)
+);
+INSERT INTO ledger_entries_new (id, kind, payment_id, organizer_id, reference, description, occurred_at, created_at, affiliate_id)
+    SELECT id, kind, payment_id, organizer_id, reference, description, occurred_at, created_at, affiliate_id FROM ledger_entries;
+
+CREATE TABLE ledger_postings_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL REFERENCES ledger_entries_new(id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(id),
+    debit_sats BIGINT NOT NULL DEFAULT 0 CHECK (debit_sats >= 0),
+    credit_sats BIGINT NOT NULL DEFAULT 0 CHECK (credit_sats >= 0),
+    CONSTRAINT ledger_postings_one_side CHECK ((debit_sats = 0) <> (credit_sats = 0))
+);
+INSERT INTO ledger_postings_new (id, entry_id, account_id, debit_sats, credit_sats)
+    SELECT id, entry_id, account_id, debit_sats, credit_sats FROM ledger_postings;
+
+DROP TABLE ledger_postings;
+DROP TABLE ledger_entries;
+ALTER TABLE ledger_entries_new RENAME TO ledger_entries;
+ALTER TABLE ledger_postings_new RENAME TO ledger_postings;
+
+CREATE INDEX idx_ledger_entries_occurred_at ON ledger_entries(occurred_at);
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+-- migrate:down
+CREATE TABLE ledger_entries_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('sale', 'refund', 'payout')),
+    payment_id INTEGER REFERENCES payments(id),
+    organizer_id INTEGER REFERENCES users(id),
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    affiliate_id INTEGER REFERENCES affiliates(id),
+    CONSTRAINT ledger_entries_kind_payment_id_key UNIQUE (kind, payment_id)
+);
+INSERT INTO ledger_entries_new (id, kind, payment_id, organizer_id, reference, description, occurred_at, created_at, affiliate_id)
+    SELECT id, kind, payment_id, organizer_id, reference, description, occurred_at, created_at, affiliate_id FROM ledger_entries;
+
+CREATE TABLE ledger_postings_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL REFERENCES ledger_entries_new(id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(id),
+    debit_sats BIGINT NOT NULL DEFAULT 0 CHECK (debit_sats >= 0),
+    credit_sats BIGINT NOT NULL DEFAULT 0 CHECK (credit_sats >= 0),
+    CONSTRAINT ledger_postings_one_side CHECK ((debit_sats = 0) <> (credit_sats = 0))
+);
+INSERT INTO ledger_postings_new (id, entry_id, account_id, debit_sats, credit_sats)
+    SELECT id, entry_id, account_id, debit_sats, credit_sats FROM ledger_postings;
+
+DROP TABLE ledger_postings;
+DROP TABLE ledger_entries;
+ALTER TABLE ledger_entries_new RENAME TO ledger_entries;
+ALTER TABLE ledger_postings_new RENAME TO ledger_postings;
+
+CREATE INDEX idx_ledger_entries_occurred_at ON ledger_entries(occurred_at);
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+DROP INDEX IF EXISTS idx_archived_records_event_id;
+DROP TABLE IF EXISTS archived_records;
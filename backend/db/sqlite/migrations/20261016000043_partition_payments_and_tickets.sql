@@ -0,0 +1,8 @@
+-- migrate:up
+-- SQLite has no table partitioning; payments and tickets stay single
+-- tables and keep their foreign keys. The version exists so both
+-- directories share their history.
+SELECT 1;
+
+-- migrate:down
+SELECT 1;
@@ -0,0 +1,49 @@
+-- migrate:up
+-- Ticket and payment statuses are limited to the ones the application
+-- knows, normalizing existing values as the Postgres migration does.
+-- SQLite cannot add a CHECK constraint to an existing table, so triggers
+-- refuse other values instead.
+UPDATE tickets SET payment_status = LOWER(TRIM(payment_status))
+WHERE payment_status <> LOWER(TRIM(payment_status));
+UPDATE tickets SET payment_status = 'cancelled'
+WHERE payment_status IS NULL
+   OR payment_status NOT IN ('pending', 'paid', 'review', 'disputed', 'failed', 'expired', 'cancelled');
+
+UPDATE payments SET status = LOWER(TRIM(status))
+WHERE status <> LOWER(TRIM(status));
+UPDATE payments SET status = 'cancelled' WHERE status = 'canceled';
+UPDATE payments SET status = 'failed'
+WHERE status IS NULL
+   OR status NOT IN ('pending', 'paid', 'failed', 'expired', 'cancelled');
+
+CREATE TRIGGER tickets_payment_status_check_insert BEFORE INSERT ON tickets
+WHEN NEW.payment_status IS NULL
+  OR NEW.payment_status NOT IN ('pending', 'paid', 'review', 'disputed', 'failed', 'expired', 'cancelled')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: tickets_payment_status_check');
+END;
+
+CREATE TRIGGER tickets_payment_status_check_update BEFORE UPDATE OF payment_status ON tickets
+WHEN NEW.payment_status IS NULL
+  OR NEW.payment_status NOT IN ('pending', 'paid', 'review', 'disputed', 'failed', 'expired', 'cancelled')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: tickets_payment_status_check');
+END;
+
+CREATE TRIGGER payments_status_check_insert BEFORE INSERT ON payments
+WHEN NEW.status IS NULL OR NEW.status NOT IN ('pending', 'paid', 'failed', 'expired', 'cancelled')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: payments_status_check');
+END;
+
+CREATE TRIGGER payments_status_check_update BEFORE UPDATE OF status ON payments
+WHEN NEW.status IS NULL OR NEW.status NOT IN ('pending', 'paid', 'failed', 'expired', 'cancelled')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: payments_status_check');
+END;
+
+-- migrate:down
+DROP TRIGGER IF EXISTS payments_status_check_update;
+DROP TRIGGER IF EXISTS payments_status_check_insert;
+DROP TRIGGER IF EXISTS tickets_payment_status_check_update;
+DROP TRIGGER IF EXISTS tickets_payment_status_check_insert;
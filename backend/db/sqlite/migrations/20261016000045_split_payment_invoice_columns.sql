@@ -0,0 +1,22 @@
+-- migrate:up
+-- The bolt11 and payment hash of a payment's invoice get columns of their
+-- own and invoice_id always holds the backend's invoice ID, as in the
+-- Postgres migration.
+ALTER TABLE payments ADD COLUMN bolt11 TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN payment_hash TEXT NOT NULL DEFAULT '';
+
+UPDATE payments SET bolt11 = invoice_id WHERE invoice_id LIKE 'ln%';
+UPDATE payments AS p
+SET invoice_id = u.invoice_id, bolt11 = u.bolt11, payment_hash = COALESCE(u.payment_hash, '')
+FROM uma_request_invoices AS u
+WHERE u.ticket_id = p.ticket_id AND (u.bolt11 = p.invoice_id OR u.invoice_id = p.invoice_id);
+
+CREATE INDEX idx_payments_payment_hash ON payments (payment_hash);
+CREATE INDEX idx_payments_bolt11 ON payments (bolt11);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_payments_bolt11;
+DROP INDEX IF EXISTS idx_payments_payment_hash;
+UPDATE payments SET invoice_id = bolt11 WHERE bolt11 <> '';
+ALTER TABLE payments DROP COLUMN payment_hash;
+ALTER TABLE payments DROP COLUMN bolt11;
@@ -0,0 +1,12 @@
+-- migrate:up
+-- source tells an event's standing admin invoice from its tickets'
+-- invoices and those issued to payers of its UMA address, as in the
+-- Postgres migration.
+ALTER TABLE uma_request_invoices ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'admin';
+UPDATE uma_request_invoices SET source = 'ticket' WHERE ticket_id IS NOT NULL;
+
+CREATE INDEX idx_uma_invoices_event_source ON uma_request_invoices (event_id, source);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_uma_invoices_event_source;
+ALTER TABLE uma_request_invoices DROP COLUMN source;
@@ -0,0 +1,19 @@
+-- migrate:up
+-- The admin notification feed, as in the Postgres migration
+CREATE TABLE admin_notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind VARCHAR(30) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    event_id INTEGER,
+    entity_id INTEGER,
+    read_at TIMESTAMP,
+    read_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_admin_notifications_unread ON admin_notifications(id) WHERE read_at IS NULL;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_admin_notifications_unread;
+DROP TABLE IF EXISTS admin_notifications;
@@ -0,0 +1,18 @@
+-- migrate:up
+-- Outbound alert connectors, as in the Postgres migration
+CREATE TABLE alert_connectors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    target TEXT NOT NULL,
+    chat_id VARCHAR(100) NOT NULL DEFAULT '',
+    events TEXT NOT NULL DEFAULT '[]',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS alert_connectors;
@@ -0,0 +1,21 @@
+-- migrate:up
+-- Status page incidents, as in the Postgres migration
+CREATE TABLE status_incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    impact VARCHAR(20) NOT NULL,
+    components TEXT NOT NULL DEFAULT '[]',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_status_incidents_started_at ON status_incidents(started_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_status_incidents_started_at;
+DROP TABLE IF EXISTS status_incidents;
@@ -0,0 +1,8 @@
+-- migrate:up
+-- SQLite tables are not partitioned, so tickets and payments keep their
+-- foreign keys and ticket codes their unique constraint. The version
+-- exists so both directories share their history.
+SELECT 1;
+
+-- migrate:down
+SELECT 1;
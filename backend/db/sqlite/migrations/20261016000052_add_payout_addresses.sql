@@ -0,0 +1,9 @@
+-- migrate:up
+-- Payout addresses of organizers and affiliates, as in the Postgres
+-- migration
+ALTER TABLE organizer_profiles ADD COLUMN payout_address VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE affiliates ADD COLUMN payout_address VARCHAR(255) NOT NULL DEFAULT '';
+
+-- migrate:down
+ALTER TABLE affiliates DROP COLUMN payout_address;
+ALTER TABLE organizer_profiles DROP COLUMN payout_address;
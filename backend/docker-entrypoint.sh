@@ -3,6 +3,14 @@ set -e
 
 echo "🚀 Starting UMA Tickets Backend..."
 
+STORAGE="${STORAGE:-postgres}"
+
+if [ "$STORAGE" = "memory" ]; then
+    echo "⚠️  STORAGE=memory: no database, data is lost on restart"
+    echo "🚀 Starting application..."
+    exec ./main
+fi
+
 # Check if DATABASE_URL is set
 if [ -z "$DATABASE_URL" ]; then
     echo "❌ ERROR: DATABASE_URL environment variable is not set!"
@@ -13,20 +21,26 @@ fi
 
 echo "✅ DATABASE_URL is configured"
 
-# Wait for database to be ready
-echo "⏳ Waiting for database to be ready..."
-
-# Use DATABASE_URL directly with pg_isready
-until pg_isready -d "$DATABASE_URL"; do
-    echo "Database is not ready yet. Waiting..."
-    sleep 2
-done
-
-echo "✅ Database is ready!"
-
-# Run database migrations
-echo "🔄 Running database migrations..."
-dbmate up
+if [ "$STORAGE" = "sqlite" ]; then
+    # SQLite has its own migrations; the file is created on first run
+    echo "🔄 Running SQLite database migrations..."
+    dbmate --migrations-dir db/sqlite/migrations --no-dump-schema up
+else
+    # Wait for database to be ready
+    echo "⏳ Waiting for database to be ready..."
+
+    # Use DATABASE_URL directly with pg_isready
+    until pg_isready -d "$DATABASE_URL"; do
+        echo "Database is not ready yet. Waiting..."
+        sleep 2
+    done
+
+    echo "✅ Database is ready!"
+
+    # Run database migrations
+    echo "🔄 Running database migrations..."
+    dbmate up
+fi
 
 echo "✅ Database migrations completed!"
 
@@ -0,0 +1,154 @@
+// Package encryption provides application-level encryption for sensitive
+// columns such as NWC connection URIs.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// prefix marks values encrypted by a Keyring. Values without it are treated
+// as legacy plaintext so existing rows keep working until re-encrypted.
+const prefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key that is no
+// longer in the keyring.
+var ErrUnknownKey = errors.New("encryption key not found in keyring")
+
+// Keyring holds AES-256-GCM keys by ID. The first key encrypts new values;
+// all keys can decrypt, which allows rotating keys without downtime.
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeyring builds a keyring from a spec of comma-separated "id:base64key"
+// pairs, primary key first. Keys must decode to 32 bytes.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Update(spec); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Update replaces the keys in the keyring, e.g. after a secret rotation.
+func (k *Keyring) Update(spec string) error {
+	var primary string
+	keys := make(map[string]cipher.AEAD)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return errors.New("invalid key entry, expected id:base64key")
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("key %q must be 32 bytes, base64-encoded", id)
+		}
+
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+
+		if _, exists := keys[id]; exists {
+			return fmt.Errorf("duplicate key id %q", id)
+		}
+		keys[id] = aead
+		if primary == "" {
+			primary = id
+		}
+	}
+
+	if primary == "" {
+		return errors.New("keyring has no keys")
+	}
+
+	k.mu.Lock()
+	k.primary = primary
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Encrypt seals plaintext with the primary key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	k.mu.RLock()
+	id, aead := k.primary, k.keys[k.primary]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return prefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Plaintext values are returned
+// unchanged.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	k.mu.RLock()
+	aead, exists := k.keys[id]
+	k.mu.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plaintext or sealed with a key other
+// than the primary one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if !IsEncrypted(value) {
+		return true
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return !strings.HasPrefix(value, prefix+k.primary+":")
+}
+
+// IsEncrypted reports whether value was produced by a Keyring.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestKeyringRoundTripAndRotation(t *testing.T) {
+	oldRing, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "nostr+walletconnect://abc?relay=wss://relay.example.com&secret=deadbeef"
+	sealed, err := oldRing.Encrypt(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "deadbeef") {
+		t.Fatal("Expected ciphertext not to contain the plaintext secret")
+	}
+
+	// New primary key, old key kept for decryption
+	ring, err := ParseKeyring("k2:" + testKey('b') + ",k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := ring.Decrypt(sealed)
+	if err != nil || plaintext != uri {
+		t.Fatalf("Expected old ciphertext to decrypt, got %q, %v", plaintext, err)
+	}
+	if !ring.NeedsRotation(sealed) {
+		t.Error("Expected value sealed with old key to need rotation")
+	}
+
+	resealed, err := ring.Encrypt(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.NeedsRotation(resealed) {
+		t.Error("Expected value sealed with primary key not to need rotation")
+	}
+
+	// Legacy plaintext passes through
+	if got, _ := ring.Decrypt(uri); got != uri {
+		t.Errorf("Expected plaintext passthrough, got %q", got)
+	}
+
+	// Retired keys can no longer decrypt
+	retired, _ := ParseKeyring("k2:" + testKey('b'))
+	if _, err := retired.Decrypt(sealed); err == nil {
+		t.Error("Expected decrypting with a retired key to fail")
+	}
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	for _, spec := range []string{"", "nokey", "k1:short", "k1:" + testKey('a') + ",k1:" + testKey('b')} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("Expected spec %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package httpclient builds the HTTP client shared by everything that calls
+// out to other services: Lightspark, buyer VASPs, captcha providers and
+// secret stores.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultTimeout bounds a whole outbound request, including reading the
+// response body, when no other timeout is configured.
+const DefaultTimeout = 10 * time.Second
+
+// Options configures the shared client. Zero values fall back to the
+// defaults of http.DefaultTransport.
+type Options struct {
+	// Timeout bounds each request end to end; zero means DefaultTimeout
+	Timeout             time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// ProxyURL sends all requests through a proxy. When empty the standard
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables apply.
+	ProxyURL string
+
+	// CACertFile is a PEM bundle trusted in addition to the system roots,
+	// e.g. for a TLS-intercepting egress proxy
+	CACertFile string
+}
+
+// New builds a client with its own pooled transport. It fails when the
+// proxy URL or CA bundle is invalid.
+func New(opts Options) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if opts.CACertFile != "" {
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CACertFile)
+		}
+		transport.TLSClientConfig.RootCAs = roots
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
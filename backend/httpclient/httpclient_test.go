@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewDefaults(t *testing.T) {
+	client, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if client.Timeout != DefaultTimeout {
+		t.Errorf("Expected timeout %v, got %v", DefaultTimeout, client.Timeout)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport == http.DefaultTransport {
+		t.Error("Expected a transport of its own, not http.DefaultTransport")
+	}
+	if transport.Proxy == nil {
+		t.Error("Expected the proxy to come from the environment by default")
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	client, err := New(Options{
+		Timeout:             3 * time.Second,
+		MaxIdleConns:        7,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     time.Minute,
+		ProxyURL:            "http://proxy.internal:3128",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	transport := client.Transport.(*http.Transport)
+	if client.Timeout != 3*time.Second || transport.MaxIdleConns != 7 || transport.MaxIdleConnsPerHost != 2 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Options not applied: timeout=%v idle=%d per_host=%d idle_timeout=%v",
+			client.Timeout, transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://vasp.example.com/.well-known/uma-configuration", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "proxy.internal:3128" {
+		t.Errorf("Expected requests to go through the proxy, got %v (%v)", proxy, err)
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0o600)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"relative proxy", Options{ProxyURL: "proxy.internal"}},
+		{"missing CA bundle", Options{CACertFile: filepath.Join(dir, "missing.pem")}},
+		{"CA bundle without certificates", Options{CACertFile: empty}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewTrustsCACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Without the bundle the test server's self-signed certificate is rejected
+	client, err := New(Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client.Transport.(*http.Transport).Proxy = nil
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Expected an untrusted certificate to be rejected")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client, err = New(Options{CACertFile: bundle, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client.Transport.(*http.Transport).Proxy = nil
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the CA bundle to be trusted: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := New(Options{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client.Transport.(*http.Transport).Proxy = nil
+	_, err = client.Get(server.URL)
+	if uerr, ok := err.(*url.Error); !ok || !uerr.Timeout() {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Package i18n translates user-facing API messages and notifications.
+//
+// API messages are looked up by their English text, so handlers keep
+// writing English and a message without a translation is served as is.
+// Notifications are templates keyed by name and rendered in the
+// recipient's locale with fmt-style arguments.
+//
+// Locales are bare ISO 639-1 language codes: en (the default), ko and es.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the locale used when no supported locale is asked for
+const Default = "en"
+
+// Supported lists the locales with translations, Default first
+var Supported = []string{"en", "ko", "es"}
+
+// messages holds the translations of API messages, by locale and then by
+// English text
+var messages = map[string]map[string]string{
+	"ko": koMessages,
+	"es": esMessages,
+}
+
+// Normalize reduces a language tag such as "ko-KR" or "ES" to a supported
+// locale, reporting false when the language is not supported.
+func Normalize(tag string) (string, bool) {
+	lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang = strings.ToLower(lang)
+	for _, locale := range Supported {
+		if lang == locale {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate picks the supported locale the client prefers most from an
+// Accept-Language header, honouring q-values and q=0. It returns Default
+// when nothing supported is accepted.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale, ok := Normalize(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// Translate returns message in locale, or message itself when it has no
+// translation.
+func Translate(locale, message string) string {
+	if translated, ok := messages[locale][message]; ok {
+		return translated
+	}
+	return message
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"ko-KR,ko;q=0.9,en-US;q=0.8", "ko"},
+		{"fr-FR, es;q=0.5", "es"},
+		{"en;q=0.4, es-MX;q=0.7", "es"},
+		{"es;q=0, ko;q=0.1", "ko"},
+		{"fr, de", "en"},
+		{"KO", "ko"},
+		{"*", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("ko", "Event not found"); got != "이벤트를 찾을 수 없습니다" {
+		t.Errorf("Expected the Korean translation, got %q", got)
+	}
+	if got := Translate("es", "Event is sold out"); got != "Las entradas para el evento están agotadas" {
+		t.Errorf("Expected the Spanish translation, got %q", got)
+	}
+	for _, locale := range []string{"en", "ko", "fr"} {
+		if got := Translate(locale, "Something new"); got != "Something new" {
+			t.Errorf("Expected untranslated messages to pass through in %s, got %q", locale, got)
+		}
+	}
+
+	// Every message is translated into every locale
+	for locale, catalog := range messages {
+		for other, otherCatalog := range messages {
+			for message := range catalog {
+				if _, ok := otherCatalog[message]; !ok {
+					t.Errorf("%q is translated into %s but not %s", message, locale, other)
+				}
+			}
+		}
+	}
+}
+
+func TestNotificationRender(t *testing.T) {
+	n := Notification{Key: PurchaseApproved, Args: []any{"Meetup", int64(1200)}}
+	subject, message := n.Render("ko")
+	if subject != "티켓 구매 승인" || !strings.Contains(message, "Meetup") || !strings.Contains(message, "1200 sats") {
+		t.Errorf("Unexpected Korean rendering %q: %q", subject, message)
+	}
+	if subject, _ := n.Render("fr"); subject != "Ticket purchase approved" {
+		t.Errorf("Expected unsupported locales to fall back to English, got %q", subject)
+	}
+	if data := n.Data(); data["EventTitle"] != "Meetup" || data["AmountSats"] != int64(1200) {
+		t.Errorf("Expected the arguments by field name, got %v", data)
+	}
+
+	// Every template exists in every locale and takes the same arguments,
+	// which are named and have samples
+	for key, template := range notifications[Default] {
+		want := strings.Count(template.message, "%")
+		if names, ok := Fields(key); !ok || len(names) != want || len(Sample(key).Args) != want {
+			t.Errorf("%s has %d verbs but fields %v and samples %v", key, want, names, Sample(key).Args)
+		}
+		for _, locale := range Supported {
+			translated, ok := notifications[locale][key]
+			if !ok {
+				t.Errorf("%s has no %s template", key, locale)
+				continue
+			}
+			if got := strings.Count(translated.message, "%"); got != want {
+				t.Errorf("%s template in %s has %d verbs, want %d", key, locale, got, want)
+			}
+		}
+	}
+}
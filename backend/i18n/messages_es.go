@@ -0,0 +1,170 @@
+package i18n
+
+var esMessages = map[string]string{
+	// Requests
+	"Invalid request body":                      "El cuerpo de la solicitud no es válido",
+	"Invalid event ID":                          "El ID del evento no es válido",
+	"Invalid ticket ID":                         "El ID de la entrada no es válido",
+	"Invalid user ID":                           "El ID del usuario no es válido",
+	"Invalid payment ID":                        "El ID del pago no es válido",
+	"Valid event ID is required":                "Se requiere un ID de evento válido",
+	"Valid ticket ID is required":               "Se requiere un ID de entrada válido",
+	"valid event ID is required":                "Se requiere un ID de evento válido",
+	"valid user ID is required":                 "Se requiere un ID de usuario válido",
+	"UMA address is required":                   "Se requiere una dirección UMA",
+	"Request body too large":                    "El cuerpo de la solicitud es demasiado grande",
+	"Too many requests, please try again later": "Demasiadas solicitudes, inténtalo de nuevo más tarde",
+	"The service is read-only for maintenance, please try again later": "El servicio está en modo de solo lectura por mantenimiento, inténtalo de nuevo más tarde",
+
+	// Authentication and accounts
+	"Authorization header required":               "Se requiere la cabecera Authorization",
+	"Bearer token required":                       "Se requiere un token Bearer",
+	"Invalid token":                               "El token no es válido",
+	"Authentication required":                     "Debes iniciar sesión",
+	"User not authenticated":                      "Usuario no autenticado",
+	"Admin privileges required":                   "Se requieren privilegios de administrador",
+	"Forbidden":                                   "Acceso denegado",
+	"Invalid credentials":                         "Correo electrónico o contraseña incorrectos",
+	"Email is required":                           "Se requiere el correo electrónico",
+	"Password is required":                        "Se requiere la contraseña",
+	"email is required":                           "Se requiere el correo electrónico",
+	"name is required":                            "Se requiere el nombre",
+	"password is required":                        "Se requiere la contraseña",
+	"invalid email format":                        "El formato del correo electrónico no es válido",
+	"name must be at least 2 characters long":     "El nombre debe tener al menos 2 caracteres",
+	"password must be at least 8 characters long": "La contraseña debe tener al menos 8 caracteres",
+	"locale is not supported":                     "El idioma no está disponible",
+	"User not found":                              "Usuario no encontrado",
+	"User with this email already exists":         "Ya existe un usuario con este correo electrónico",
+	"User created successfully":                   "Usuario creado correctamente",
+	"User retrieved successfully":                 "Usuario obtenido correctamente",
+	"User updated successfully":                   "Usuario actualizado correctamente",
+	"User updated; confirm the new email with the link sent to it":                         "Usuario actualizado; confirma el nuevo correo electrónico con el enlace que se le envió",
+	"Verification token is required":                                                       "Se requiere el token de verificación",
+	"Verification link is invalid, expired or already used":                                "El enlace de verificación no es válido, ha caducado o ya se ha usado",
+	"Email changed successfully":                                                           "Correo electrónico cambiado correctamente",
+	"User deleted successfully":                                                            "Usuario eliminado correctamente",
+	"Current user retrieved successfully":                                                  "Usuario actual obtenido correctamente",
+	"Login successful":                                                                     "Sesión iniciada correctamente",
+	"Current password is required":                                                         "Se requiere la contraseña actual",
+	"Current password is incorrect":                                                        "La contraseña actual no es correcta",
+	"password must be at most 72 bytes long":                                               "La contraseña debe tener como máximo 72 bytes",
+	"new password must differ from the current password":                                   "La nueva contraseña debe ser distinta de la actual",
+	"password must not be your email or name":                                              "La contraseña no puede ser tu correo electrónico ni tu nombre",
+	"password must not repeat the same few characters":                                     "La contraseña no puede repetir unos pocos caracteres",
+	"password must not be only digits":                                                     "La contraseña no puede tener solo dígitos",
+	"password must contain a lowercase letter":                                             "La contraseña debe contener una letra minúscula",
+	"password must contain an uppercase letter":                                            "La contraseña debe contener una letra mayúscula",
+	"password must contain a digit":                                                        "La contraseña debe contener un dígito",
+	"password must contain a symbol":                                                       "La contraseña debe contener un símbolo",
+	"password is too common":                                                               "La contraseña es demasiado común",
+	"password has appeared in a data breach; choose another":                               "La contraseña ha aparecido en una filtración de datos; elige otra",
+	"Password policy retrieved successfully":                                               "Política de contraseñas obtenida correctamente",
+	"Password changed successfully":                                                        "Contraseña cambiada correctamente",
+	"Login methods retrieved successfully":                                                 "Métodos de inicio de sesión obtenidos correctamente",
+	"Login method not found":                                                               "Método de inicio de sesión no encontrado",
+	"Cannot remove your only login method":                                                 "No puedes eliminar tu único método de inicio de sesión",
+	"Login method removed successfully":                                                    "Método de inicio de sesión eliminado correctamente",
+	"Login challenge created successfully":                                                 "Solicitud de inicio de sesión creada correctamente",
+	"Failed to create login challenge":                                                     "No se pudo crear la solicitud de inicio de sesión",
+	"Waiting for the wallet to sign":                                                       "Esperando la firma de la billetera",
+	"Login challenge is invalid, expired or already used":                                  "La solicitud de inicio de sesión no es válida, ha caducado o ya se ha usado",
+	"This email was used for a guest checkout; claim the account with the link sent to it": "Este correo electrónico se usó en una compra como invitado; reclama la cuenta con el enlace que se le envió",
+	"An account with this email already exists; log in to buy tickets":                     "Ya existe una cuenta con este correo electrónico; inicia sesión para comprar entradas",
+	"Claim token is required":                                                              "Se requiere el token para reclamar la cuenta",
+	"Claim link is invalid, expired or already used":                                       "El enlace para reclamar la cuenta no es válido, ha caducado o ya se ha usado",
+	"Account claimed successfully":                                                         "Cuenta reclamada correctamente",
+	"If a guest checkout used this email, a new claim link has been sent to it":            "Si este correo electrónico se usó en una compra como invitado, se le ha enviado un nuevo enlace para reclamar la cuenta",
+	"Reason is required":                                                                   "El motivo es obligatorio",
+	"Cannot impersonate yourself":                                                          "No puedes suplantarte a ti mismo",
+	"Admins cannot be impersonated":                                                        "No se puede suplantar a un administrador",
+	"Impersonation started":                                                                "Suplantación iniciada",
+	"Impersonation sessions are read-only":                                                 "Las sesiones de suplantación son de solo lectura",
+
+	// Events
+	"Event not found":                           "Evento no encontrado",
+	"Event is not active":                       "El evento no está activo",
+	"Event is not currently active":             "El evento no está activo en este momento",
+	"Event is sold out":                         "Las entradas para el evento están agotadas",
+	"Events retrieved successfully":             "Eventos obtenidos correctamente",
+	"Event retrieved successfully":              "Evento obtenido correctamente",
+	"Event availability retrieved successfully": "Disponibilidad del evento obtenida correctamente",
+	"Short link retrieved successfully":         "Enlace corto obtenido correctamente",
+
+	// Organizers
+	"Organizer not found":                      "Organizador no encontrado",
+	"Organizer retrieved successfully":         "Organizador obtenido correctamente",
+	"Organizer profile not found":              "Perfil de organizador no encontrado",
+	"Organizer profile retrieved successfully": "Perfil de organizador obtenido correctamente",
+	"Organizer profile saved successfully":     "Perfil de organizador guardado correctamente",
+	"Only event organizers can have a profile": "Solo los organizadores de eventos pueden tener un perfil",
+	"Slug is already taken":                    "Esa dirección ya está en uso",
+
+	// Affiliates
+	"Affiliate account not found":              "Cuenta de afiliado no encontrada",
+	"Affiliate account retrieved successfully": "Cuenta de afiliado obtenida correctamente",
+
+	// Gifts
+	"Gift link is invalid or already used": "El enlace del regalo no es válido o ya se usó",
+	"Gift accepted successfully":           "Regalo aceptado correctamente",
+	"Gift retrieved successfully":          "Regalo obtenido correctamente",
+	"You cannot accept a gift you bought":  "No puedes aceptar un regalo que compraste",
+
+	// Cashu payments
+	"Cashu payments are not enabled":                                             "Los pagos con Cashu no están habilitados",
+	"Invoice is not awaiting payment":                                            "La factura no está pendiente de pago",
+	"A Cashu payment of this invoice is already in progress":                     "Ya hay un pago con Cashu de esta factura en curso",
+	"Invalid Cashu token":                                                        "El token de Cashu no es válido",
+	"Tokens of this mint are not accepted":                                       "No se aceptan tokens de esta casa de moneda",
+	"The token must hold exactly the invoice amount plus the mint's fee reserve": "El token debe contener exactamente el importe de la factura más la reserva de comisión de la casa de moneda",
+	"The mint refused the token":                                                 "La casa de moneda rechazó el token",
+	"Failed to pay with the mint":                                                "No se pudo pagar a través de la casa de moneda",
+	"Cashu payment is pending":                                                   "El pago con Cashu está pendiente",
+	"Ticket paid successfully":                                                   "Entrada pagada correctamente",
+
+	// Purchases
+	"Ticket sales are temporarily paused":                            "La venta de entradas está pausada temporalmente",
+	"Ticket sales for this event are temporarily paused":             "La venta de entradas para este evento está pausada temporalmente",
+	"Purchase blocked by fraud checks":                               "La compra fue bloqueada por los controles antifraude",
+	"This event is invitation only; an access code is required":      "Este evento es solo con invitación; se requiere un código de acceso",
+	"Invalid access code":                                            "El código de acceso no es válido",
+	"Access code has expired or has no uses left":                    "El código de acceso ha caducado o ya no tiene usos disponibles",
+	"Confirm you meet the event's minimum age to continue":           "Confirma que cumples la edad mínima del evento para continuar",
+	"Accept the event's current terms to continue":                   "Acepta los términos vigentes del evento para continuar",
+	"Payments are temporarily unavailable, please try again shortly": "Los pagos no están disponibles temporalmente, inténtalo de nuevo en breve",
+	"Ticket purchase initiated successfully":                         "Compra de entrada iniciada correctamente",
+	"Ticket purchased and paid successfully":                         "Entrada comprada y pagada correctamente",
+	"Free ticket created successfully":                               "Entrada gratuita creada correctamente",
+	"Ticket purchase held for review":                                "La compra de la entrada está pendiente de revisión",
+	"Failed to create ticket":                                        "No se pudo crear la entrada",
+	"Failed to price ticket":                                         "No se pudo calcular el precio de la entrada",
+	"Event price retrieved successfully":                             "Precio del evento obtenido correctamente",
+
+	// Tickets
+	"Ticket not found":                                  "Entrada no encontrada",
+	"Ticket code is required":                           "Se requiere el código de la entrada",
+	"Malformed ticket code":                             "El código de la entrada tiene un formato incorrecto",
+	"Legacy ticket codes are no longer accepted":        "Los códigos de entrada antiguos ya no se aceptan",
+	"Ticket is not valid for this event":                "La entrada no es válida para este evento",
+	"Ticket payment is not complete":                    "El pago de la entrada no se ha completado",
+	"Ticket is suspended while its payment is disputed": "La entrada está suspendida mientras se disputa su pago",
+	"QR codes are only issued for paid tickets":         "Los códigos QR solo se emiten para entradas pagadas",
+	"Only paid tickets can be added to a wallet":        "Solo las entradas pagadas se pueden añadir a una billetera",
+	"Ticket has not been claimed into a wallet":         "La entrada no se ha añadido a ninguna billetera",
+	"Claim link is invalid or has expired":              "El enlace no es válido o ha caducado",
+	"Ticket status retrieved successfully":              "Estado de la entrada obtenido correctamente",
+	"User tickets retrieved successfully":               "Entradas del usuario obtenidas correctamente",
+	"Orders retrieved successfully":                     "Pedidos obtenidos correctamente",
+	"Ticket validated successfully":                     "Entrada validada correctamente",
+	"Ticket verified successfully":                      "Entrada verificada correctamente",
+	"Ticket claimed successfully":                       "Entrada añadida correctamente",
+	"This ticket cannot be refunded":                    "Esta entrada no se puede reembolsar",
+	"Ticket refunded successfully":                      "Entrada reembolsada correctamente",
+	"This ticket was not bought with flex cancellation": "Esta entrada no se compró con cancelación flexible",
+	"Only paid tickets can be cancelled":                "Solo se pueden cancelar entradas pagadas",
+	"The flex cancellation window has closed":           "El plazo de cancelación flexible ha terminado",
+	"Ticket cancelled successfully":                     "Entrada cancelada correctamente",
+	"Receipts are only issued for paid payments":        "Los recibos solo se emiten para pagos completados",
+	"Receipt retrieved successfully":                    "Recibo obtenido correctamente",
+	"Payment not found":                                 "Pago no encontrado",
+}
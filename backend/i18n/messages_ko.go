@@ -0,0 +1,170 @@
+package i18n
+
+var koMessages = map[string]string{
+	// Requests
+	"Invalid request body":                      "요청 본문이 올바르지 않습니다",
+	"Invalid event ID":                          "이벤트 ID가 올바르지 않습니다",
+	"Invalid ticket ID":                         "티켓 ID가 올바르지 않습니다",
+	"Invalid user ID":                           "사용자 ID가 올바르지 않습니다",
+	"Invalid payment ID":                        "결제 ID가 올바르지 않습니다",
+	"Valid event ID is required":                "올바른 이벤트 ID가 필요합니다",
+	"Valid ticket ID is required":               "올바른 티켓 ID가 필요합니다",
+	"valid event ID is required":                "올바른 이벤트 ID가 필요합니다",
+	"valid user ID is required":                 "올바른 사용자 ID가 필요합니다",
+	"UMA address is required":                   "UMA 주소가 필요합니다",
+	"Request body too large":                    "요청 본문이 너무 큽니다",
+	"Too many requests, please try again later": "요청이 너무 많습니다. 잠시 후 다시 시도하세요",
+	"The service is read-only for maintenance, please try again later": "서비스가 점검 중이라 읽기 전용입니다. 잠시 후 다시 시도하세요",
+
+	// Authentication and accounts
+	"Authorization header required":               "Authorization 헤더가 필요합니다",
+	"Bearer token required":                       "Bearer 토큰이 필요합니다",
+	"Invalid token":                               "토큰이 올바르지 않습니다",
+	"Authentication required":                     "로그인이 필요합니다",
+	"User not authenticated":                      "인증되지 않은 사용자입니다",
+	"Admin privileges required":                   "관리자 권한이 필요합니다",
+	"Forbidden":                                   "접근이 거부되었습니다",
+	"Invalid credentials":                         "이메일 또는 비밀번호가 올바르지 않습니다",
+	"Email is required":                           "이메일이 필요합니다",
+	"Password is required":                        "비밀번호가 필요합니다",
+	"email is required":                           "이메일이 필요합니다",
+	"name is required":                            "이름이 필요합니다",
+	"password is required":                        "비밀번호가 필요합니다",
+	"invalid email format":                        "이메일 형식이 올바르지 않습니다",
+	"name must be at least 2 characters long":     "이름은 2자 이상이어야 합니다",
+	"password must be at least 8 characters long": "비밀번호는 8자 이상이어야 합니다",
+	"locale is not supported":                     "지원하지 않는 언어입니다",
+	"User not found":                              "사용자를 찾을 수 없습니다",
+	"User with this email already exists":         "이미 사용 중인 이메일입니다",
+	"User created successfully":                   "사용자가 생성되었습니다",
+	"User retrieved successfully":                 "사용자 정보를 가져왔습니다",
+	"User updated successfully":                   "사용자 정보가 수정되었습니다",
+	"User updated; confirm the new email with the link sent to it":                         "사용자 정보가 수정되었습니다. 새 이메일로 보낸 링크로 변경을 확인하세요",
+	"Verification token is required":                                                       "확인 토큰이 필요합니다",
+	"Verification link is invalid, expired or already used":                                "확인 링크가 올바르지 않거나 만료되었거나 이미 사용되었습니다",
+	"Email changed successfully":                                                           "이메일이 변경되었습니다",
+	"User deleted successfully":                                                            "사용자가 삭제되었습니다",
+	"Current user retrieved successfully":                                                  "현재 사용자 정보를 가져왔습니다",
+	"Login successful":                                                                     "로그인되었습니다",
+	"Current password is required":                                                         "현재 비밀번호가 필요합니다",
+	"Current password is incorrect":                                                        "현재 비밀번호가 올바르지 않습니다",
+	"password must be at most 72 bytes long":                                               "비밀번호는 72바이트 이하여야 합니다",
+	"new password must differ from the current password":                                   "새 비밀번호는 현재 비밀번호와 달라야 합니다",
+	"password must not be your email or name":                                              "비밀번호는 이메일이나 이름과 같을 수 없습니다",
+	"password must not repeat the same few characters":                                     "비밀번호에 같은 문자 몇 개만 반복할 수 없습니다",
+	"password must not be only digits":                                                     "비밀번호는 숫자로만 이루어질 수 없습니다",
+	"password must contain a lowercase letter":                                             "비밀번호에 영문 소문자가 있어야 합니다",
+	"password must contain an uppercase letter":                                            "비밀번호에 영문 대문자가 있어야 합니다",
+	"password must contain a digit":                                                        "비밀번호에 숫자가 있어야 합니다",
+	"password must contain a symbol":                                                       "비밀번호에 기호가 있어야 합니다",
+	"password is too common":                                                               "너무 흔한 비밀번호입니다",
+	"password has appeared in a data breach; choose another":                               "유출된 적이 있는 비밀번호입니다. 다른 비밀번호를 사용하세요",
+	"Password policy retrieved successfully":                                               "비밀번호 정책을 가져왔습니다",
+	"Password changed successfully":                                                        "비밀번호가 변경되었습니다",
+	"Login methods retrieved successfully":                                                 "로그인 방법 목록을 가져왔습니다",
+	"Login method not found":                                                               "로그인 방법을 찾을 수 없습니다",
+	"Cannot remove your only login method":                                                 "유일한 로그인 방법은 삭제할 수 없습니다",
+	"Login method removed successfully":                                                    "로그인 방법이 삭제되었습니다",
+	"Login challenge created successfully":                                                 "로그인 요청이 생성되었습니다",
+	"Failed to create login challenge":                                                     "로그인 요청을 생성하지 못했습니다",
+	"Waiting for the wallet to sign":                                                       "지갑의 서명을 기다리는 중입니다",
+	"Login challenge is invalid, expired or already used":                                  "로그인 요청이 올바르지 않거나 만료되었거나 이미 사용되었습니다",
+	"This email was used for a guest checkout; claim the account with the link sent to it": "게스트 구매에 사용된 이메일입니다. 이메일로 받은 링크로 계정을 등록하세요",
+	"An account with this email already exists; log in to buy tickets":                     "이미 가입된 이메일입니다. 로그인 후 티켓을 구매하세요",
+	"Claim token is required":                                                              "계정 등록 토큰이 필요합니다",
+	"Claim link is invalid, expired or already used":                                       "계정 등록 링크가 올바르지 않거나 만료되었거나 이미 사용되었습니다",
+	"Account claimed successfully":                                                         "계정이 등록되었습니다",
+	"If a guest checkout used this email, a new claim link has been sent to it":            "게스트 구매에 사용된 이메일이라면 새 계정 등록 링크를 보냈습니다",
+	"Reason is required":                                                                   "사유를 입력해야 합니다",
+	"Cannot impersonate yourself":                                                          "자기 자신으로 대리 로그인할 수 없습니다",
+	"Admins cannot be impersonated":                                                        "관리자 계정으로는 대리 로그인할 수 없습니다",
+	"Impersonation started":                                                                "대리 로그인을 시작했습니다",
+	"Impersonation sessions are read-only":                                                 "대리 로그인 중에는 조회만 할 수 있습니다",
+
+	// Events
+	"Event not found":                           "이벤트를 찾을 수 없습니다",
+	"Event is not active":                       "진행 중인 이벤트가 아닙니다",
+	"Event is not currently active":             "현재 진행 중인 이벤트가 아닙니다",
+	"Event is sold out":                         "매진된 이벤트입니다",
+	"Events retrieved successfully":             "이벤트 목록을 가져왔습니다",
+	"Event retrieved successfully":              "이벤트 정보를 가져왔습니다",
+	"Event availability retrieved successfully": "이벤트 잔여 수량을 가져왔습니다",
+	"Short link retrieved successfully":         "단축 링크를 가져왔습니다",
+
+	// Organizers
+	"Organizer not found":                      "주최자를 찾을 수 없습니다",
+	"Organizer retrieved successfully":         "주최자 정보를 가져왔습니다",
+	"Organizer profile not found":              "주최자 프로필을 찾을 수 없습니다",
+	"Organizer profile retrieved successfully": "주최자 프로필을 가져왔습니다",
+	"Organizer profile saved successfully":     "주최자 프로필을 저장했습니다",
+	"Only event organizers can have a profile": "이벤트 주최자만 프로필을 만들 수 있습니다",
+	"Slug is already taken":                    "이미 사용 중인 주소입니다",
+
+	// Affiliates
+	"Affiliate account not found":              "제휴 계정을 찾을 수 없습니다",
+	"Affiliate account retrieved successfully": "제휴 계정 정보를 가져왔습니다",
+
+	// Gifts
+	"Gift link is invalid or already used": "선물 링크가 유효하지 않거나 이미 사용되었습니다",
+	"Gift accepted successfully":           "선물을 받았습니다",
+	"Gift retrieved successfully":          "선물 정보를 가져왔습니다",
+	"You cannot accept a gift you bought":  "직접 구매한 선물은 받을 수 없습니다",
+
+	// Cashu payments
+	"Cashu payments are not enabled":                                             "Cashu 결제를 사용할 수 없습니다",
+	"Invoice is not awaiting payment":                                            "결제 대기 중인 인보이스가 아닙니다",
+	"A Cashu payment of this invoice is already in progress":                     "이 인보이스의 Cashu 결제가 이미 진행 중입니다",
+	"Invalid Cashu token":                                                        "Cashu 토큰이 올바르지 않습니다",
+	"Tokens of this mint are not accepted":                                       "이 민트의 토큰은 받지 않습니다",
+	"The token must hold exactly the invoice amount plus the mint's fee reserve": "토큰 금액은 인보이스 금액과 민트 수수료 예비금의 합과 정확히 같아야 합니다",
+	"The mint refused the token":                                                 "민트가 토큰을 거부했습니다",
+	"Failed to pay with the mint":                                                "민트를 통해 결제하지 못했습니다",
+	"Cashu payment is pending":                                                   "Cashu 결제가 처리 중입니다",
+	"Ticket paid successfully":                                                   "티켓 결제가 완료되었습니다",
+
+	// Purchases
+	"Ticket sales are temporarily paused":                            "티켓 판매가 일시 중단되었습니다",
+	"Ticket sales for this event are temporarily paused":             "이 이벤트의 티켓 판매가 일시 중단되었습니다",
+	"Purchase blocked by fraud checks":                               "보안 검사로 구매가 차단되었습니다",
+	"This event is invitation only; an access code is required":      "초대 전용 이벤트입니다. 참가 코드가 필요합니다",
+	"Invalid access code":                                            "참가 코드가 올바르지 않습니다",
+	"Access code has expired or has no uses left":                    "참가 코드가 만료되었거나 사용 횟수를 모두 소진했습니다",
+	"Confirm you meet the event's minimum age to continue":           "계속하려면 이벤트의 최소 연령 조건을 충족하는지 확인해 주세요",
+	"Accept the event's current terms to continue":                   "계속하려면 이벤트의 최신 이용 약관에 동의해 주세요",
+	"Payments are temporarily unavailable, please try again shortly": "일시적으로 결제를 이용할 수 없습니다. 잠시 후 다시 시도하세요",
+	"Ticket purchase initiated successfully":                         "티켓 구매가 시작되었습니다",
+	"Ticket purchased and paid successfully":                         "티켓 구매 및 결제가 완료되었습니다",
+	"Free ticket created successfully":                               "무료 티켓이 발급되었습니다",
+	"Ticket purchase held for review":                                "티켓 구매가 검토 대기 중입니다",
+	"Failed to create ticket":                                        "티켓을 생성하지 못했습니다",
+	"Failed to price ticket":                                         "티켓 가격을 계산하지 못했습니다",
+	"Event price retrieved successfully":                             "이벤트 가격을 가져왔습니다",
+
+	// Tickets
+	"Ticket not found":                                  "티켓을 찾을 수 없습니다",
+	"Ticket code is required":                           "티켓 코드가 필요합니다",
+	"Malformed ticket code":                             "티켓 코드 형식이 올바르지 않습니다",
+	"Legacy ticket codes are no longer accepted":        "이전 형식의 티켓 코드는 더 이상 사용할 수 없습니다",
+	"Ticket is not valid for this event":                "이 이벤트에 유효한 티켓이 아닙니다",
+	"Ticket payment is not complete":                    "티켓 결제가 완료되지 않았습니다",
+	"Ticket is suspended while its payment is disputed": "결제 이의 제기로 티켓 사용이 일시 정지되었습니다",
+	"QR codes are only issued for paid tickets":         "QR 코드는 결제된 티켓에만 발급됩니다",
+	"Only paid tickets can be added to a wallet":        "결제된 티켓만 지갑에 추가할 수 있습니다",
+	"Ticket has not been claimed into a wallet":         "지갑에 추가되지 않은 티켓입니다",
+	"Claim link is invalid or has expired":              "추가 링크가 올바르지 않거나 만료되었습니다",
+	"Ticket status retrieved successfully":              "티켓 상태를 가져왔습니다",
+	"User tickets retrieved successfully":               "사용자 티켓 목록을 가져왔습니다",
+	"Orders retrieved successfully":                     "주문 내역을 가져왔습니다",
+	"Ticket validated successfully":                     "티켓이 확인되었습니다",
+	"Ticket verified successfully":                      "티켓이 인증되었습니다",
+	"Ticket claimed successfully":                       "티켓이 지갑에 추가되었습니다",
+	"This ticket cannot be refunded":                    "이 티켓은 환불할 수 없습니다",
+	"Ticket refunded successfully":                      "티켓이 환불되었습니다",
+	"This ticket was not bought with flex cancellation": "플렉스 취소 옵션으로 구매한 티켓이 아닙니다",
+	"Only paid tickets can be cancelled":                "결제된 티켓만 취소할 수 있습니다",
+	"The flex cancellation window has closed":           "플렉스 취소 가능 기간이 지났습니다",
+	"Ticket cancelled successfully":                     "티켓이 취소되었습니다",
+	"Receipts are only issued for paid payments":        "영수증은 결제가 완료된 경우에만 발급됩니다",
+	"Receipt retrieved successfully":                    "영수증을 가져왔습니다",
+	"Payment not found":                                 "결제 정보를 찾을 수 없습니다",
+}
@@ -0,0 +1,272 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Notification templates. Their arguments are listed with each, in order;
+// fields names them for admin-configured templates.
+const (
+	TicketSuspended   = "ticket_suspended"   // ticket code
+	TicketCancelled   = "ticket_cancelled"   // ticket code
+	TicketReinstated  = "ticket_reinstated"  // ticket code
+	PurchaseConfirmed = "purchase_confirmed" // event title, ticket code
+	PurchaseApproved  = "purchase_approved"  // event title, amount in sats
+	PurchaseRejected  = "purchase_rejected"  // event title
+	AccountClaim      = "account_claim"      // event title, claim link
+	EmailVerify       = "email_verify"       // new email, verification link
+	EmailChange       = "email_change"       // new email
+	TicketRefunded    = "ticket_refunded"    // event title, ticket code
+	EventRescheduled  = "event_rescheduled"  // event title, new start time
+	RefundOffered     = "refund_offered"     // event title, new start time, refund deadline
+	RescheduleRefund  = "reschedule_refund"  // event title, ticket code
+	EventCancelled    = "event_cancelled"    // event title, ticket code
+	TicketComped      = "ticket_comped"      // event title, ticket code
+	GiftReceived      = "gift_received"      // sender name, event title, claim link, personal message
+	GiftReady         = "gift_ready"         // event title, recipient UMA address, claim link
+	FlexCancelled     = "flex_cancelled"     // event title, ticket code
+	PriceHeld         = "price_held"         // event title, invoice amount in sats, invoice expiry
+	PriceChanged      = "price_changed"      // event title, new price in sats
+)
+
+// template is a notification's subject and fmt-style message
+type template struct {
+	subject, message string
+}
+
+var notifications = map[string]map[string]template{
+	"en": {
+		TicketSuspended: {"Ticket suspended",
+			"Your ticket %s is suspended while a dispute about its payment is reviewed."},
+		TicketCancelled: {"Ticket cancelled",
+			"The payment for your ticket %s was reversed, so the ticket has been cancelled."},
+		TicketReinstated: {"Ticket reinstated",
+			"The dispute about the payment for your ticket %s is resolved and the ticket is valid again."},
+		PurchaseConfirmed: {"Ticket purchase approved",
+			"Your ticket for %s is confirmed. Ticket code: %s"},
+		PurchaseApproved: {"Ticket purchase approved",
+			"Your ticket purchase for %s was approved. Pay the invoice for %d sats to complete it."},
+		PurchaseRejected: {"Ticket purchase cancelled",
+			"Your ticket purchase for %s could not be completed and has been cancelled. You have not been charged."},
+		AccountClaim: {"Claim your account",
+			"Your ticket for %s is paid. Set a password to manage your tickets: %s"},
+		EmailVerify: {"Confirm your new email",
+			"Confirm %s as the email of your account: %s"},
+		EmailChange: {"Email change requested",
+			"A change of your account email to %s was requested. It takes effect once confirmed from the new address. If you did not request it, change your password."},
+		TicketRefunded: {"Ticket refunded",
+			"The capacity of %s was reduced and your ticket %s has been cancelled. Your payment will be refunded."},
+		EventRescheduled: {"Event rescheduled",
+			"%s has been moved to %s. Your ticket stays valid for the new date."},
+		RefundOffered: {"Event rescheduled",
+			"%s has been moved to %s. Your ticket stays valid for the new date. If you can no longer attend, you can ask for a refund until %s."},
+		RescheduleRefund: {"Ticket refunded",
+			"As you asked, your ticket for the rescheduled %s has been cancelled. Ticket %s will be refunded."},
+		EventCancelled: {"Event cancelled",
+			"%s has been cancelled. Your ticket %s is no longer valid, and any payment for it will be refunded."},
+		TicketComped: {"You're on the guest list",
+			"The organizer of %s has issued you a complimentary ticket. Ticket code: %s"},
+		GiftReceived: {"You've been sent a ticket",
+			"%s sent you a ticket to %s. Accept it to add it to your account: %s\n\n%s"},
+		GiftReady: {"Your gift is ready to send",
+			"Your gift ticket to %s for %s is ready. Pass this link on for them to accept it: %s"},
+		FlexCancelled: {"Ticket cancelled",
+			"As you asked, your flex ticket for %s has been cancelled. Ticket %s will be refunded."},
+		PriceHeld: {"Ticket price changed",
+			"The ticket price of %s has changed. Your purchase keeps its price: the invoice for %d sats can still be paid until %s."},
+		PriceChanged: {"Ticket price changed",
+			"The ticket price of %s has changed. The invoice for your unpaid ticket has expired, so a new one will be at the new price of %d sats."},
+	},
+	"ko": {
+		TicketSuspended: {"티켓 일시 정지",
+			"결제에 대한 이의 제기를 검토하는 동안 티켓 %s의 사용이 일시 정지됩니다."},
+		TicketCancelled: {"티켓 취소",
+			"티켓 %s의 결제가 환원되어 티켓이 취소되었습니다."},
+		TicketReinstated: {"티켓 복원",
+			"티켓 %s의 결제에 대한 이의 제기가 해결되어 티켓을 다시 사용할 수 있습니다."},
+		PurchaseConfirmed: {"티켓 구매 승인",
+			"%s 티켓이 확정되었습니다. 티켓 코드: %s"},
+		PurchaseApproved: {"티켓 구매 승인",
+			"%s 티켓 구매가 승인되었습니다. 구매를 완료하려면 %d sats 인보이스를 결제하세요."},
+		PurchaseRejected: {"티켓 구매 취소",
+			"%s 티켓 구매를 완료할 수 없어 취소되었습니다. 요금은 청구되지 않았습니다."},
+		AccountClaim: {"계정 등록",
+			"%s 티켓 결제가 완료되었습니다. 비밀번호를 설정하고 티켓을 관리하세요: %s"},
+		EmailVerify: {"새 이메일 확인",
+			"%s을(를) 계정 이메일로 확인하세요: %s"},
+		EmailChange: {"이메일 변경 요청",
+			"계정 이메일을 %s(으)로 변경하는 요청이 접수되었습니다. 새 주소에서 확인하면 변경됩니다. 직접 요청하지 않았다면 비밀번호를 변경하세요."},
+		TicketRefunded: {"티켓 환불",
+			"%s의 수용 인원이 줄어 티켓 %s이(가) 취소되었습니다. 결제 금액은 환불됩니다."},
+		EventRescheduled: {"이벤트 일정 변경",
+			"%s 일정이 %s(으)로 변경되었습니다. 티켓은 변경된 일정에도 유효합니다."},
+		RefundOffered: {"이벤트 일정 변경",
+			"%s 일정이 %s(으)로 변경되었습니다. 티켓은 변경된 일정에도 유효합니다. 참석할 수 없다면 %s까지 환불을 요청할 수 있습니다."},
+		RescheduleRefund: {"티켓 환불",
+			"요청에 따라 일정이 변경된 %s의 티켓이 취소되었습니다. 티켓 %s의 결제 금액은 환불됩니다."},
+		EventCancelled: {"이벤트 취소",
+			"%s이(가) 취소되었습니다. 티켓 %s은(는) 더 이상 유효하지 않으며, 결제한 금액은 환불됩니다."},
+		TicketComped: {"게스트 명단 등록",
+			"%s 주최자가 초대 티켓을 발급했습니다. 티켓 코드: %s"},
+		GiftReceived: {"티켓 선물이 도착했습니다",
+			"%s님이 %s 티켓을 선물했습니다. 수락하면 내 계정에 추가됩니다: %s\n\n%s"},
+		GiftReady: {"선물 전달 준비 완료",
+			"%s 선물 티켓(%s)이 준비되었습니다. 받는 분이 수락할 수 있도록 이 링크를 전달하세요: %s"},
+		FlexCancelled: {"티켓 취소",
+			"요청에 따라 %s의 플렉스 티켓이 취소되었습니다. 티켓 %s의 결제 금액은 환불됩니다."},
+		PriceHeld: {"티켓 가격 변경",
+			"%s의 티켓 가격이 변경되었습니다. 진행 중인 구매는 기존 가격이 유지되며, %d sats 인보이스는 %s까지 결제할 수 있습니다."},
+		PriceChanged: {"티켓 가격 변경",
+			"%s의 티켓 가격이 변경되었습니다. 미결제 티켓의 인보이스가 만료되어, 새 인보이스는 변경된 가격 %d sats로 발행됩니다."},
+	},
+	"es": {
+		TicketSuspended: {"Entrada suspendida",
+			"Tu entrada %s está suspendida mientras se revisa una disputa sobre su pago."},
+		TicketCancelled: {"Entrada cancelada",
+			"El pago de tu entrada %s fue revertido, por lo que la entrada ha sido cancelada."},
+		TicketReinstated: {"Entrada restablecida",
+			"La disputa sobre el pago de tu entrada %s se ha resuelto y la entrada vuelve a ser válida."},
+		PurchaseConfirmed: {"Compra de entrada aprobada",
+			"Tu entrada para %s está confirmada. Código de entrada: %s"},
+		PurchaseApproved: {"Compra de entrada aprobada",
+			"Tu compra de entrada para %s fue aprobada. Paga la factura de %d sats para completarla."},
+		PurchaseRejected: {"Compra de entrada cancelada",
+			"Tu compra de entrada para %s no se pudo completar y ha sido cancelada. No se te ha cobrado."},
+		AccountClaim: {"Reclama tu cuenta",
+			"Tu entrada para %s está pagada. Crea una contraseña para gestionar tus entradas: %s"},
+		EmailVerify: {"Confirma tu nuevo correo electrónico",
+			"Confirma %s como el correo electrónico de tu cuenta: %s"},
+		EmailChange: {"Cambio de correo electrónico solicitado",
+			"Se solicitó cambiar el correo electrónico de tu cuenta a %s. El cambio se aplicará cuando se confirme desde la nueva dirección. Si no lo solicitaste, cambia tu contraseña."},
+		TicketRefunded: {"Entrada reembolsada",
+			"Se redujo el aforo de %s y tu entrada %s ha sido cancelada. Se te reembolsará el pago."},
+		EventRescheduled: {"Evento reprogramado",
+			"%s se ha trasladado al %s. Tu entrada sigue siendo válida para la nueva fecha."},
+		RefundOffered: {"Evento reprogramado",
+			"%s se ha trasladado al %s. Tu entrada sigue siendo válida para la nueva fecha. Si ya no puedes asistir, puedes pedir un reembolso hasta el %s."},
+		RescheduleRefund: {"Entrada reembolsada",
+			"Como pediste, tu entrada para %s, que fue reprogramado, ha sido cancelada. Se te reembolsará la entrada %s."},
+		EventCancelled: {"Evento cancelado",
+			"%s ha sido cancelado. Tu entrada %s ya no es válida y se te reembolsará cualquier pago realizado."},
+		TicketComped: {"Estás en la lista de invitados",
+			"El organizador de %s te ha emitido una entrada de cortesía. Código de entrada: %s"},
+		GiftReceived: {"Te han regalado una entrada",
+			"%s te ha regalado una entrada para %s. Acéptala para añadirla a tu cuenta: %s\n\n%s"},
+		GiftReady: {"Tu regalo está listo para enviar",
+			"Tu entrada de regalo para %s para %s está lista. Comparte este enlace para que la acepte: %s"},
+		FlexCancelled: {"Entrada cancelada",
+			"Como pediste, tu entrada flexible para %s ha sido cancelada. Se te reembolsará la entrada %s."},
+		PriceHeld: {"Cambio en el precio de la entrada",
+			"El precio de la entrada para %s ha cambiado. Tu compra mantiene su precio: la factura de %d sats se puede pagar hasta el %s."},
+		PriceChanged: {"Cambio en el precio de la entrada",
+			"El precio de la entrada para %s ha cambiado. La factura de tu entrada sin pagar ha caducado, así que la nueva tendrá el nuevo precio de %d sats."},
+	},
+}
+
+// fields names each notification's arguments, so custom templates can
+// refer to them as {{.EventTitle}} and so on
+var fields = map[string][]string{
+	TicketSuspended:   {"TicketCode"},
+	TicketCancelled:   {"TicketCode"},
+	TicketReinstated:  {"TicketCode"},
+	PurchaseConfirmed: {"EventTitle", "TicketCode"},
+	PurchaseApproved:  {"EventTitle", "AmountSats"},
+	PurchaseRejected:  {"EventTitle"},
+	AccountClaim:      {"EventTitle", "ClaimURL"},
+	EmailVerify:       {"NewEmail", "VerifyURL"},
+	EmailChange:       {"NewEmail"},
+	TicketRefunded:    {"EventTitle", "TicketCode"},
+	EventRescheduled:  {"EventTitle", "StartTime"},
+	RefundOffered:     {"EventTitle", "StartTime", "RefundUntil"},
+	RescheduleRefund:  {"EventTitle", "TicketCode"},
+	EventCancelled:    {"EventTitle", "TicketCode"},
+	TicketComped:      {"EventTitle", "TicketCode"},
+	GiftReceived:      {"SenderName", "EventTitle", "ClaimURL", "Message"},
+	GiftReady:         {"EventTitle", "RecipientUMAAddress", "ClaimURL"},
+	FlexCancelled:     {"EventTitle", "TicketCode"},
+	PriceHeld:         {"EventTitle", "AmountSats", "ExpiresAt"},
+	PriceChanged:      {"EventTitle", "PriceSats"},
+}
+
+// samples are the arguments template previews are rendered with
+var samples = map[string][]any{
+	TicketSuspended:   {"E1-7K3M9Q"},
+	TicketCancelled:   {"E1-7K3M9Q"},
+	TicketReinstated:  {"E1-7K3M9Q"},
+	PurchaseConfirmed: {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	PurchaseApproved:  {"Seoul Bitcoin Meetup", int64(21000)},
+	PurchaseRejected:  {"Seoul Bitcoin Meetup"},
+	AccountClaim:      {"Seoul Bitcoin Meetup", "https://tickets.example.com/claim?token=3f9a1c"},
+	EmailVerify:       {"minji@example.com", "https://tickets.example.com/confirm-email?token=3f9a1c"},
+	EmailChange:       {"minji@example.com"},
+	TicketRefunded:    {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	EventRescheduled:  {"Seoul Bitcoin Meetup", "2026-11-21 18:00 UTC"},
+	RefundOffered:     {"Seoul Bitcoin Meetup", "2026-11-21 18:00 UTC", "2026-11-14 23:59 UTC"},
+	RescheduleRefund:  {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	EventCancelled:    {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	TicketComped:      {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	GiftReceived:      {"Minji", "Seoul Bitcoin Meetup", "https://tickets.example.com/gift?token=3f9a1c", "Happy birthday!"},
+	GiftReady:         {"Seoul Bitcoin Meetup", "$jisoo@wallet.example.com", "https://tickets.example.com/gift?token=3f9a1c"},
+	FlexCancelled:     {"Seoul Bitcoin Meetup", "E1-7K3M9Q"},
+	PriceHeld:         {"Seoul Bitcoin Meetup", int64(21000), "2026-11-14 18:15 UTC"},
+	PriceChanged:      {"Seoul Bitcoin Meetup", int64(25000)},
+}
+
+// Keys lists the notification template keys in a stable order
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Fields returns the names of a notification's arguments, reporting false
+// for unknown keys.
+func Fields(key string) ([]string, bool) {
+	names, ok := fields[key]
+	return names, ok
+}
+
+// Sample returns a notification with example arguments, for previews
+func Sample(key string) Notification {
+	return Notification{Key: key, Args: samples[key]}
+}
+
+// Notification is a notification template with its arguments, rendered in
+// the recipient's locale when it is sent. EventID is the event it concerns,
+// which selects the event organizer's custom templates; 0 when there is none.
+// Address, when set, delivers it to that address instead of the user's
+// email, such as a new address being verified.
+type Notification struct {
+	Key     string
+	Args    []any
+	EventID int
+	Address string
+}
+
+// Data returns the notification's arguments by field name, for custom
+// templates
+func (n Notification) Data() map[string]any {
+	data := make(map[string]any, len(n.Args))
+	for i, name := range fields[n.Key] {
+		if i < len(n.Args) {
+			data[name] = n.Args[i]
+		}
+	}
+	return data
+}
+
+// Render returns the notification's subject and message in locale, falling
+// back to Default for unsupported locales. Trailing space left by an empty
+// last argument is trimmed.
+func (n Notification) Render(locale string) (subject, message string) {
+	t, ok := notifications[locale][n.Key]
+	if !ok {
+		t = notifications[Default][n.Key]
+	}
+	return t.subject, strings.TrimSpace(fmt.Sprintf(t.message, n.Args...))
+}
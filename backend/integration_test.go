@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -45,7 +46,8 @@ type TestServer struct {
 
 // MockUMAService implements services.UMAService for testing
 type MockUMAService struct {
-	logger *slog.Logger
+	logger   *slog.Logger
+	invoices atomic.Int64 // numbers invoices so IDs stay unique
 }
 
 func NewMockUMAService(logger *slog.Logger) services.UMAService {
@@ -59,28 +61,32 @@ func (m *MockUMAService) ValidateUMAAddress(address string) error {
 	return nil
 }
 
-func (m *MockUMAService) CreateUMARequest(umaAddress string, amountSats int64, description string, isAdmin bool) (*models.Invoice, error) {
+func (m *MockUMAService) CreateUMARequest(umaAddress string, amountSats int64, description string, expiry time.Duration, isAdmin bool) (*models.Invoice, error) {
 	m.logger.Info("Mock CreateUMARequest called",
 		"uma_address", umaAddress,
 		"amount_sats", amountSats,
 		"description", description,
 		"is_admin", isAdmin)
+	suffix := ""
+	if n := m.invoices.Add(1); n > 1 {
+		suffix = fmt.Sprintf("-%d", n)
+	}
 	return &models.Invoice{
-		ID:          "test-invoice-123",
-		PaymentHash: "test-payment-hash-456",
-		Bolt11:      "lntb10000n1p3testmockinvoiceforsimulationpurposes1234567890abcdefghijklmnopqrstuvwxyz",
+		ID:          "test-invoice-123" + suffix,
+		PaymentHash: "test-payment-hash-456" + suffix,
+		Bolt11:      "lntb10000n1p3testmockinvoiceforsimulationpurposes1234567890abcdefghijklmnopqrstuvwxyz" + suffix,
 		AmountSats:  amountSats,
 		Status:      "pending",
-		ExpiresAt:   timePtr(time.Now().Add(time.Hour)),
+		ExpiresAt:   timePtr(time.Now().Add(expiry)),
 	}, nil
 }
 
-func (m *MockUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string) (*models.Invoice, error) {
+func (m *MockUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
 	m.logger.Info("Mock CreateTicketInvoice called",
 		"uma_address", umaAddress,
 		"amount_sats", amountSats,
 		"description", description)
-	return m.CreateUMARequest(umaAddress, amountSats, description, false)
+	return m.CreateUMARequest(umaAddress, amountSats, description, expiry, false)
 }
 
 func (m *MockUMAService) SimulateIncomingPayment(bolt11 string) error {
@@ -88,7 +94,7 @@ func (m *MockUMAService) SimulateIncomingPayment(bolt11 string) error {
 	return nil
 }
 
-func (m *MockUMAService) SendUMARequest(buyerUMA string, amountSats int64, callbackURL string) error {
+func (m *MockUMAService) SendUMARequest(buyerUMA string, amountSats int64, callbackURL string, expiry time.Duration) error {
 	m.logger.Info("Mock SendUMARequest called", "buyer_uma", buyerUMA, "amount_sats", amountSats, "callback_url", callbackURL)
 	return nil
 }
@@ -144,7 +150,7 @@ func timePtr(t time.Time) *time.Time {
 }
 
 // setupTestServer creates a test server with test database
-func setupTestServer(t *testing.T) *TestServer {
+func setupTestServer(t testing.TB) *TestServer {
 	// Load test configuration
 	testConfig := &config.Config{
 		Port:                   "8080",
@@ -158,15 +164,18 @@ func setupTestServer(t *testing.T) *TestServer {
 		Domain:                 "test.localhost",
 	}
 
-	// Connect to test database
+	// Connect to test database, falling back to in-memory storage so the
+	// suite also runs without Postgres
 	db, err := sqlx.Connect("postgres", testConfig.DatabaseURL)
 	if err != nil {
-		t.Skip("Test database not available:", err)
+		t.Log("Test database not available, using memory storage:", err)
+		db = nil
+		testConfig.Storage = config.StorageMemory
+	} else {
+		// Clean database before tests
+		cleanDatabase(t, db)
 	}
 
-	// Clean database before tests
-	cleanDatabase(t, db)
-
 	// Create logger with INFO level to see mock service calls
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
@@ -174,8 +183,10 @@ func setupTestServer(t *testing.T) *TestServer {
 	mockUMAService := NewMockUMAService(logger)
 
 	// Create server with mock service
-	srv := server.NewServer(db, logger, testConfig)
-	srv.SetUMAService(mockUMAService)
+	srv := server.NewServer(db, testConfig,
+		server.WithLogger(logger),
+		server.WithUMAService(mockUMAService),
+	)
 
 	// Create HTTP test server
 	httpServer := httptest.NewServer(srv.Router())
@@ -195,7 +206,7 @@ func setupTestServer(t *testing.T) *TestServer {
 }
 
 // cleanDatabase truncates all tables for clean test state
-func cleanDatabase(t *testing.T, db *sqlx.DB) {
+func cleanDatabase(t testing.TB, db *sqlx.DB) {
 	tables := []string{"payments", "tickets", "events", "nwc_connections", "users", "uma_request_invoices"}
 	for _, table := range tables {
 		_, err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %s CASCADE", table))
@@ -208,11 +219,13 @@ func cleanDatabase(t *testing.T, db *sqlx.DB) {
 // teardownTestServer cleans up test resources
 func (ts *TestServer) teardown() {
 	ts.httpServer.Close()
-	ts.db.Close()
+	if ts.db != nil {
+		ts.db.Close()
+	}
 }
 
 // loadUserFixtures creates fixture users and caches them with tokens
-func (ts *TestServer) loadUserFixtures(t *testing.T) {
+func (ts *TestServer) loadUserFixtures(t testing.TB) {
 	for _, userReq := range testUsers {
 		// Create user
 		userJSON, _ := json.Marshal(userReq)
@@ -692,42 +705,60 @@ func TestErrorCases(t *testing.T) {
 	}
 }
 
-// Benchmark test for concurrent ticket purchases
+// Benchmark test for concurrent ticket purchases. For latency percentiles
+// against a running server use cmd/loadtest instead.
 func BenchmarkConcurrentTicketPurchases(b *testing.B) {
-	ts := setupTestServer(&testing.T{})
+	ts := setupTestServer(b)
 	defer ts.teardown()
 
-	// Create test event
+	// Create test event with room for every purchase
 	event := models.CreateEventRequest{
 		Title:       "Benchmark Event",
 		Description: "Event for benchmark testing",
 		StartTime:   time.Now().Add(24 * time.Hour),
 		EndTime:     time.Now().Add(26 * time.Hour),
-		Capacity:    1000,
+		Capacity:    b.N,
 		PriceSats:   1000,
 		StreamURL:   "https://example.com/stream",
 	}
 
 	eventJSON, _ := json.Marshal(event)
-	http.Post(ts.httpServer.URL+"/api/admin/events", "application/json", bytes.NewBuffer(eventJSON))
+	req, _ := http.NewRequest("POST", ts.httpServer.URL+"/api/admin/events", bytes.NewBuffer(eventJSON))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+ts.getAdminToken())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		b.Fatal("Failed to create event:", err)
+	}
+	var createResp models.SuccessResponse
+	json.NewDecoder(resp.Body).Decode(&createResp)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		b.Fatalf("Expected status 201 for event creation, got %d", resp.StatusCode)
+	}
+	eventID := int(createResp.Data.(map[string]interface{})["id"].(float64))
+
+	purchaseJSON, _ := json.Marshal(models.TicketPurchaseRequest{
+		EventID:    eventID,
+		UserID:     ts.getUser("buyer@test.com").ID,
+		UMAAddress: "$buyer@test.com",
+	})
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
-		i := 0
 		for pb.Next() {
-			purchaseReq := models.PurchaseTicketRequest{
-				EventID:    1,
-				UserEmail:  fmt.Sprintf("user%d@example.com", i),
-				UserName:   fmt.Sprintf("User %d", i),
-				UMAAddress: fmt.Sprintf("$user%d@example.com", i),
+			resp, err := http.Post(ts.httpServer.URL+"/api/tickets/purchase", "application/json", bytes.NewReader(purchaseJSON))
+			if err != nil {
+				b.Error("Failed to purchase ticket:", err)
+				return
 			}
-
-			purchaseJSON, _ := json.Marshal(purchaseReq)
-			resp, _ := http.Post(ts.httpServer.URL+"/api/tickets/purchase", "application/json", bytes.NewBuffer(purchaseJSON))
-			if resp != nil {
-				resp.Body.Close()
+			resp.Body.Close()
+			// Repeat purchases by one buyer trip the velocity rules and are held (202)
+			if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+				b.Errorf("Expected status 201 or 202 for purchase, got %d", resp.StatusCode)
+				return
 			}
-			i++
 		}
 	})
 }
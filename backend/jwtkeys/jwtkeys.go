@@ -0,0 +1,184 @@
+// Package jwtkeys holds the asymmetric keys signing login tokens, so that
+// services checking tokens only need the published public keys and a
+// leaked verification key compromises nothing. Each key has an ID carried
+// in the "kid" header of the tokens it signs. The first key signs new
+// tokens and every key verifies, so a new key can be put in front while
+// tokens signed with the old one run out.
+//
+// Keys are Ed25519, signing as EdDSA, or RSA of at least 2048 bits,
+// signing as RS256.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Signing algorithms, as named in JWT headers
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// minRSABits is the smallest RSA key accepted
+const minRSABits = 2048
+
+// Key is a signing key and its algorithm
+type Key struct {
+	ID        string
+	Algorithm string
+	Signer    crypto.Signer
+}
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKSet is the document published at the JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Keyring holds the signing keys by ID
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]Key
+}
+
+// ParseKeyring builds a keyring from a spec of comma-separated "id:base64key"
+// pairs, primary key first. A key is a 32 byte Ed25519 seed or a PKCS #8
+// DER private key (Ed25519 or RSA), base64-encoded.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Update(spec); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Update replaces the keys in the keyring, e.g. after a secret rotation.
+func (k *Keyring) Update(spec string) error {
+	var primary string
+	keys := make(map[string]Key)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return errors.New("invalid key entry, expected id:base64key")
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("key %q is not valid base64", id)
+		}
+		key, err := parseKey(id, raw)
+		if err != nil {
+			return err
+		}
+
+		if _, exists := keys[id]; exists {
+			return fmt.Errorf("duplicate key id %q", id)
+		}
+		keys[id] = key
+		if primary == "" {
+			primary = id
+		}
+	}
+
+	if primary == "" {
+		return errors.New("keyring has no keys")
+	}
+
+	k.mu.Lock()
+	k.primary = primary
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// parseKey reads an Ed25519 seed or a PKCS #8 private key
+func parseKey(id string, raw []byte) (Key, error) {
+	if len(raw) == ed25519.SeedSize {
+		return Key{ID: id, Algorithm: AlgorithmEdDSA, Signer: ed25519.NewKeyFromSeed(raw)}, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(raw)
+	if err != nil {
+		return Key{}, fmt.Errorf("key %q must be a %d byte Ed25519 seed or a PKCS #8 private key", id, ed25519.SeedSize)
+	}
+	switch key := parsed.(type) {
+	case ed25519.PrivateKey:
+		return Key{ID: id, Algorithm: AlgorithmEdDSA, Signer: key}, nil
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("RSA key %q must have at least %d bits", id, minRSABits)
+		}
+		return Key{ID: id, Algorithm: AlgorithmRS256, Signer: key}, nil
+	}
+	return Key{}, fmt.Errorf("key %q must be an Ed25519 or RSA key", id)
+}
+
+// Primary returns the key signing new tokens
+func (k *Keyring) Primary() Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[k.primary]
+}
+
+// Key returns the key with id, reporting false if there is none
+func (k *Keyring) Key(id string) (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// JWKS returns the public keys, primary first.
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(k.keys))}
+	for id, key := range k.keys {
+		jwk := JWK{KeyID: id, Algorithm: key.Algorithm, Use: "sig"}
+		switch public := key.Signer.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].KeyID == k.primary) != (set.Keys[j].KeyID == k.primary) {
+			return set.Keys[i].KeyID == k.primary
+		}
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
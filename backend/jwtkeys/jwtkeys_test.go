@@ -0,0 +1,89 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func testSeed(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), ed25519.SeedSize)))
+}
+
+func testRSAKey(t *testing.T, bits int) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, base64.StdEncoding.EncodeToString(der)
+}
+
+func TestParseKeyring(t *testing.T) {
+	rsaKey, rsaSpec := testRSAKey(t, 2048)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := ParseKeyring("ed:" + testSeed('a') + ", rsa:" + rsaSpec + ",pkcs8:" + base64.StdEncoding.EncodeToString(edDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary := ring.Primary(); primary.ID != "ed" || primary.Algorithm != AlgorithmEdDSA {
+		t.Errorf("Expected the first key to be primary, got %s %s", primary.ID, primary.Algorithm)
+	}
+	if key, ok := ring.Key("rsa"); !ok || key.Algorithm != AlgorithmRS256 {
+		t.Errorf("Expected an RS256 key, got %+v %v", key, ok)
+	}
+	if key, ok := ring.Key("pkcs8"); !ok || key.Algorithm != AlgorithmEdDSA {
+		t.Errorf("Expected a PKCS #8 Ed25519 key, got %+v %v", key, ok)
+	}
+	if _, ok := ring.Key("other"); ok {
+		t.Error("Expected no key for an unknown id")
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != 3 || set.Keys[0].KeyID != "ed" || set.Keys[1].KeyID != "pkcs8" || set.Keys[2].KeyID != "rsa" {
+		t.Fatalf("Expected the primary key first, then by id, got %+v", set.Keys)
+	}
+	ed := set.Keys[0]
+	public := ring.Primary().Signer.Public().(ed25519.PublicKey)
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Use != "sig" || ed.X != base64.RawURLEncoding.EncodeToString(public) {
+		t.Errorf("Unexpected Ed25519 JWK %+v", ed)
+	}
+	rsaJWK := set.Keys[2]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	if rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != AlgorithmRS256 || new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(rsaKey.E) {
+		t.Errorf("Unexpected RSA JWK %+v", rsaJWK)
+	}
+
+	// Rotation puts a new key in front
+	if err := ring.Update("new:" + testSeed('b') + ",ed:" + testSeed('a')); err != nil {
+		t.Fatal(err)
+	}
+	if ring.Primary().ID != "new" {
+		t.Errorf("Expected the new key to be primary, got %s", ring.Primary().ID)
+	}
+	if _, ok := ring.Key("rsa"); ok {
+		t.Error("Expected a dropped key to be gone")
+	}
+
+	_, weakSpec := testRSAKey(t, 1024)
+	for _, spec := range []string{"", "nokey", "k1:not-base64!", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "k1:" + testSeed('a') + ",k1:" + testSeed('b'), "weak:" + weakSpec} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package listquery parses the sort and filter parameters of list endpoints
+// and turns them into SQL, or applies them in memory, against a whitelist
+// of fields per list:
+//
+//	?sort=-price_sats,start_time          price descending, then start time
+//	?filter[category]=music               equal
+//	?filter[price_sats][lte]=5000         eq, ne, lt, lte, gt, gte
+//	?filter[status][in]=paid,pending      any of a comma-separated list
+//	?filter[title][contains]=jazz         case-insensitive substring
+//	?limit=20&offset=40
+//
+// Only whitelisted fields can be sorted or filtered, only their column
+// names reach the SQL and values are always bound as parameters. Times are
+// RFC 3339.
+package listquery
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLimit is the largest page a list returns
+const MaxLimit = 100
+
+// Kind is the type of a field's values
+type Kind int
+
+const (
+	Int Kind = iota
+	Text
+	Time
+	Bool
+)
+
+// operators lists the filter operators each kind accepts. Text is not
+// ordered since Postgres collations and SQLite disagree on the order.
+var operators = map[Kind][]string{
+	Int:  {"eq", "ne", "lt", "lte", "gt", "gte", "in"},
+	Text: {"eq", "ne", "in", "contains"},
+	Time: {"eq", "ne", "lt", "lte", "gt", "gte"},
+	Bool: {"eq"},
+}
+
+var sqlOperators = map[string]string{"eq": "=", "ne": "<>", "lt": "<", "lte": "<=", "gt": ">", "gte": ">="}
+
+// likeEscaper escapes LIKE wildcards in a contains filter's value
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Field is a field a list can be sorted or filtered by. Column is its
+// column, and the db tag of the model field Apply reads. Nullable columns
+// should not be sortable, as Postgres and SQLite order NULLs differently;
+// text sorts by the database's collation, and byte-wise in memory.
+type Field struct {
+	Column     string
+	Kind       Kind
+	Sortable   bool
+	Filterable bool
+}
+
+// Fields is a list's whitelist by parameter name. It must include "id",
+// which breaks ties in the sort order.
+type Fields map[string]Field
+
+// Filter is one filter[...] parameter; Values holds one value unless Op is
+// "in"
+type Filter struct {
+	Field  string
+	Op     string
+	Values []interface{}
+}
+
+// Order is one sort key
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Query is a parsed list request. A zero Limit returns every row.
+type Query struct {
+	Filters []Filter
+	Sort    []Order
+	Limit   int
+	Offset  int
+}
+
+// Parse reads sort, filter, limit and offset from values, refusing fields
+// not in fields and operators or values that do not fit their kind. As
+// elsewhere in the API, a limit outside 1..MaxLimit or a negative offset
+// is ignored.
+func Parse(values url.Values, fields Fields) (Query, error) {
+	var q Query
+	if sortParam := values.Get("sort"); sortParam != "" {
+		for _, name := range strings.Split(sortParam, ",") {
+			order := Order{Field: strings.TrimSpace(name)}
+			if field, desc := strings.CutPrefix(order.Field, "-"); desc {
+				order = Order{Field: field, Desc: true}
+			}
+			if field, ok := fields[order.Field]; !ok || !field.Sortable {
+				return Query{}, fmt.Errorf("cannot sort by %q", order.Field)
+			}
+			q.Sort = append(q.Sort, order)
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, op, ok := parseFilterKey(key)
+		if !ok {
+			return Query{}, fmt.Errorf("malformed filter %q", key)
+		}
+		field, ok := fields[name]
+		if !ok || !field.Filterable {
+			return Query{}, fmt.Errorf("cannot filter by %q", name)
+		}
+		if !slices.Contains(operators[field.Kind], op) {
+			return Query{}, fmt.Errorf("filter[%s] does not support %q", name, op)
+		}
+
+		raw := []string{values.Get(key)}
+		if op == "in" {
+			raw = strings.Split(raw[0], ",")
+		}
+		filter := Filter{Field: name, Op: op}
+		for _, s := range raw {
+			value, err := field.Kind.parse(s)
+			if err != nil {
+				return Query{}, fmt.Errorf("invalid value %q for filter[%s]", s, name)
+			}
+			filter.Values = append(filter.Values, value)
+		}
+		q.Filters = append(q.Filters, filter)
+	}
+
+	if l, err := strconv.Atoi(values.Get("limit")); err == nil && l > 0 && l <= MaxLimit {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(values.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
+	}
+	return q, nil
+}
+
+// parseFilterKey splits "filter[name]" and "filter[name][op]"; the
+// operator defaults to eq
+func parseFilterKey(key string) (name, op string, ok bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	name, rest, ok = strings.Cut(rest, "]")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return name, "eq", true
+	}
+	op, ok = strings.CutPrefix(rest, "[")
+	if op, ok = strings.CutSuffix(op, "]"); !ok || op == "" {
+		return "", "", false
+	}
+	return name, op, true
+}
+
+func (k Kind) parse(s string) (interface{}, error) {
+	switch k {
+	case Int:
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case Time:
+		return time.Parse(time.RFC3339, strings.TrimSpace(s))
+	case Bool:
+		return strconv.ParseBool(strings.TrimSpace(s))
+	default:
+		return s, nil
+	}
+}
+
+// Where returns q's filters as SQL conditions joined by AND, or "" without
+// filters. Placeholders are numbered after args, and the returned args have
+// the filter values appended.
+func (q Query) Where(fields Fields, args []interface{}) (string, []interface{}) {
+	conditions := make([]string, 0, len(q.Filters))
+	for _, filter := range q.Filters {
+		column := fields[filter.Field].Column
+		switch filter.Op {
+		case "in":
+			placeholders := make([]string, len(filter.Values))
+			for i, value := range filter.Values {
+				args = append(args, value)
+				placeholders[i] = fmt.Sprintf("$%d", len(args))
+			}
+			conditions = append(conditions, column+" IN ("+strings.Join(placeholders, ", ")+")")
+		case "contains":
+			args = append(args, "%"+likeEscaper.Replace(strings.ToLower(filter.Values[0].(string)))+"%")
+			conditions = append(conditions, fmt.Sprintf(`LOWER(%s) LIKE $%d ESCAPE '\'`, column, len(args)))
+		default:
+			args = append(args, filter.Values[0])
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", column, sqlOperators[filter.Op], len(args)))
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// OrderBy returns the ORDER BY list for q's sort, or for fallback when it
+// has none. Unless sorted by id already, id in the direction of the last
+// key breaks ties so pages do not overlap.
+func (q Query) OrderBy(fields Fields, fallback ...Order) string {
+	orders := q.orders(fallback)
+	terms := make([]string, len(orders))
+	for i, order := range orders {
+		terms[i] = fields[order.Field].Column
+		if order.Desc {
+			terms[i] += " DESC"
+		}
+	}
+	return strings.Join(terms, ", ")
+}
+
+func (q Query) orders(fallback []Order) []Order {
+	orders := q.Sort
+	if len(orders) == 0 {
+		orders = fallback
+	}
+	if slices.ContainsFunc(orders, func(o Order) bool { return o.Field == "id" }) {
+		return orders
+	}
+	desc := len(orders) > 0 && orders[len(orders)-1].Desc
+	return append(slices.Clip(orders), Order{Field: "id", Desc: desc})
+}
+
+// Page returns the LIMIT and OFFSET clause for q, or "" when it has
+// neither, numbering its placeholders after args
+func (q Query) Page(args []interface{}) (string, []interface{}) {
+	if q.Limit == 0 && q.Offset == 0 {
+		return "", args
+	}
+	// SQLite has no OFFSET without LIMIT
+	limit := int64(math.MaxInt64)
+	if q.Limit > 0 {
+		limit = int64(q.Limit)
+	}
+	args = append(args, limit, q.Offset)
+	return fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args
+}
+
+// Apply filters, sorts and pages items as the SQL from Where, OrderBy and
+// Page would, for in-memory storage. A field is read from the struct field
+// whose db tag is its column; a nil pointer matches no filter, like NULL.
+func Apply[T any](items []T, fields Fields, q Query, fallback ...Order) []T {
+	index := make(map[string][]int)
+	itemType := reflect.TypeFor[T]()
+	for _, field := range reflect.VisibleFields(itemType) {
+		if tag := field.Tag.Get("db"); tag != "" && tag != "-" {
+			index[tag] = field.Index
+		}
+	}
+	value := func(item T, name string) (interface{}, bool) {
+		i, ok := index[fields[name].Column]
+		if !ok {
+			return nil, false
+		}
+		return fieldValue(reflect.ValueOf(item).FieldByIndex(i))
+	}
+
+	matched := make([]T, 0, len(items))
+	for _, item := range items {
+		if !slices.ContainsFunc(q.Filters, func(filter Filter) bool {
+			v, ok := value(item, filter.Field)
+			return !ok || !matches(v, filter)
+		}) {
+			matched = append(matched, item)
+		}
+	}
+
+	orders := q.orders(fallback)
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, order := range orders {
+			a, _ := value(matched[i], order.Field)
+			b, _ := value(matched[j], order.Field)
+			if c := compare(a, b); c != 0 {
+				return (c < 0) != order.Desc
+			}
+		}
+		return false
+	})
+
+	start := min(q.Offset, len(matched))
+	end := len(matched)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, end)
+	}
+	return matched[start:end]
+}
+
+// fieldValue reads a field as int64, string, time.Time or bool, reporting
+// false for a nil pointer
+func fieldValue(v reflect.Value) (interface{}, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return v.Bool(), true
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t, true
+	}
+	return nil, false
+}
+
+func matches(v interface{}, filter Filter) bool {
+	switch filter.Op {
+	case "in":
+		return slices.ContainsFunc(filter.Values, func(value interface{}) bool { return compare(v, value) == 0 })
+	case "contains":
+		s, _ := v.(string)
+		return strings.Contains(strings.ToLower(s), strings.ToLower(filter.Values[0].(string)))
+	}
+	c := compare(v, filter.Values[0])
+	switch filter.Op {
+	case "ne":
+		return c != 0
+	case "lt":
+		return c < 0
+	case "lte":
+		return c <= 0
+	case "gt":
+		return c > 0
+	case "gte":
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
+
+// compare orders two values of one kind; a missing value sorts first
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		b, _ := b.(int64)
+		return cmp.Compare(a, b)
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	case bool:
+		b, _ := b.(bool)
+		if a == b {
+			return 0
+		}
+		if a {
+			return 1
+		}
+		return -1
+	}
+	if b == nil {
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,152 @@
+package listquery
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"id":       {Column: "id", Kind: Int, Sortable: true, Filterable: true},
+	"title":    {Column: "title", Kind: Text, Sortable: true, Filterable: true},
+	"price":    {Column: "price_sats", Kind: Int, Sortable: true, Filterable: true},
+	"starts":   {Column: "start_time", Kind: Time, Sortable: true, Filterable: true},
+	"paid_at":  {Column: "paid_at", Kind: Time, Filterable: true},
+	"featured": {Column: "is_featured", Kind: Bool},
+}
+
+type item struct {
+	ID     int        `db:"id"`
+	Title  string     `db:"title"`
+	Price  int64      `db:"price_sats"`
+	Starts time.Time  `db:"start_time"`
+	PaidAt *time.Time `db:"paid_at"`
+}
+
+func TestParse(t *testing.T) {
+	values, _ := url.ParseQuery("sort=-price,title&filter[title][contains]=Jazz&filter[price][in]=100,200&filter[starts][gte]=2026-10-01T00:00:00Z&limit=20&offset=40")
+	q, err := Parse(values, testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Query{
+		Filters: []Filter{
+			{Field: "price", Op: "in", Values: []interface{}{int64(100), int64(200)}},
+			{Field: "starts", Op: "gte", Values: []interface{}{time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}},
+			{Field: "title", Op: "contains", Values: []interface{}{"Jazz"}},
+		},
+		Sort:   []Order{{Field: "price", Desc: true}, {Field: "title"}},
+		Limit:  20,
+		Offset: 40,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("Expected %+v, got %+v", want, q)
+	}
+
+	// Out of range paging is ignored as elsewhere in the API
+	if q, err := Parse(url.Values{"limit": {"1000"}, "offset": {"-1"}}, testFields); err != nil || q.Limit != 0 || q.Offset != 0 {
+		t.Errorf("Expected paging ignored, got %+v (%v)", q, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, raw := range []string{
+		"sort=password",
+		"sort=paid_at",
+		"filter[password]=x",
+		"filter[featured]=true",
+		"filter[title][lt]=b",
+		"filter[price][like]=1",
+		"filter[price]=cheap",
+		"filter[starts]=yesterday",
+		"filter[price",
+		"filter[price][]=1",
+	} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := Parse(values, testFields); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	values, _ := url.ParseQuery("sort=title&filter[price][gt]=5&filter[id][in]=1,2&filter[title][contains]=50%25_off&limit=10")
+	q, err := Parse(values, testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, args := q.Where(testFields, []interface{}{"first"})
+	if want := `id IN ($2, $3) AND price_sats > $4 AND LOWER(title) LIKE $5 ESCAPE '\'`; where != want {
+		t.Errorf("Expected %s, got %s", want, where)
+	}
+	page, args := q.Page(args)
+	if page != " LIMIT $6 OFFSET $7" {
+		t.Errorf("Unexpected page clause %q", page)
+	}
+	want := []interface{}{"first", int64(1), int64(2), int64(5), `%50\%\_off%`, int64(10), 0}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected args %v, got %v", want, args)
+	}
+	if orderBy := q.OrderBy(testFields); orderBy != "title, id" {
+		t.Errorf("Expected title then id, got %s", orderBy)
+	}
+	if orderBy := (Query{}).OrderBy(testFields, Order{Field: "starts", Desc: true}); orderBy != "start_time DESC, id DESC" {
+		t.Errorf("Expected the fallback order, got %s", orderBy)
+	}
+	if page, args := (Query{}).Page(nil); page != "" || len(args) != 0 {
+		t.Errorf("Expected no page clause, got %q %v", page, args)
+	}
+}
+
+func TestApply(t *testing.T) {
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	paid := base.Add(time.Hour)
+	items := []item{
+		{ID: 1, Title: "Jazz Night", Price: 500, Starts: base.AddDate(0, 0, 3)},
+		{ID: 2, Title: "Rock Show", Price: 900, Starts: base.AddDate(0, 0, 1), PaidAt: &paid},
+		{ID: 3, Title: "jazz brunch", Price: 500, Starts: base.AddDate(0, 0, 2)},
+		{ID: 4, Title: "Opera", Price: 1500, Starts: base.AddDate(0, 0, 4), PaidAt: &paid},
+	}
+	ids := func(items []item) []int {
+		var ids []int
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	apply := func(raw string, fallback ...Order) []int {
+		t.Helper()
+		values, _ := url.ParseQuery(raw)
+		q, err := Parse(values, testFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids(Apply(items, testFields, q, fallback...))
+	}
+
+	for _, tc := range []struct {
+		raw      string
+		fallback []Order
+		want     []int
+	}{
+		{"", []Order{{Field: "starts"}}, []int{2, 3, 1, 4}},
+		{"filter[title][contains]=JAZZ", nil, []int{1, 3}},
+		// Equal prices fall back to id in the direction of the last key
+		{"sort=-price", nil, []int{4, 2, 3, 1}},
+		{"sort=price,title", nil, []int{1, 3, 2, 4}},
+		{"filter[price][lte]=900&filter[price][ne]=500", nil, []int{2}},
+		{"filter[id][in]=4,1,9", nil, []int{1, 4}},
+		// A nil pointer matches no filter, like NULL
+		{"filter[paid_at][gte]=2026-09-01T00:00:00Z", nil, []int{2, 4}},
+		{"filter[starts][lte]=2026-10-03T00:00:00Z&sort=starts", nil, []int{2, 3}},
+		{"sort=id&limit=2&offset=1", nil, []int{2, 3}},
+		{"sort=id&offset=3", nil, []int{4}},
+	} {
+		if got := apply(tc.raw, tc.fallback...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.raw, tc.want, got)
+		}
+	}
+}
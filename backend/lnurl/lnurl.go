@@ -0,0 +1,137 @@
+// Package lnurl implements the parts of LNURL used for logging in with a
+// Lightning wallet (LUD-01 and LUD-04): bech32-encoding callback URLs as
+// LNURLs for wallets to scan, and verifying the signature a wallet returns
+// over a login challenge with its linking key. It also resolves Lightning
+// Addresses to invoices over LNURL-pay (LUD-06 and LUD-16) for payouts.
+package lnurl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// hrp is the human-readable part of bech32-encoded LNURLs
+const hrp = "lnurl"
+
+// K1Size is the length in bytes of login challenges
+const K1Size = 32
+
+// ErrInvalidSignature is returned when a wallet's signature does not
+// verify against its linking key
+var ErrInvalidSignature = errors.New("invalid lnurl-auth signature")
+
+// NewK1 returns a random login challenge, hex encoded
+func NewK1() (string, error) {
+	k1 := make([]byte, K1Size)
+	if _, err := rand.Read(k1); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(k1), nil
+}
+
+// VerifyAuth checks that sig, a hex DER-encoded ECDSA signature, signs the
+// hex challenge k1 with key, the wallet's hex compressed secp256k1 linking
+// key. It returns the key normalized to lower case.
+func VerifyAuth(k1, sig, key string) (string, error) {
+	challenge, err := hex.DecodeString(k1)
+	if err != nil || len(challenge) != K1Size {
+		return "", fmt.Errorf("%w: malformed k1", ErrInvalidSignature)
+	}
+	rawKey, err := hex.DecodeString(key)
+	if err != nil || len(rawKey) != secp256k1.PubKeyBytesLenCompressed {
+		return "", fmt.Errorf("%w: linking key must be a compressed public key", ErrInvalidSignature)
+	}
+	pubKey, err := secp256k1.ParsePubKey(rawKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	rawSig, err := hex.DecodeString(sig)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	signature, err := ecdsa.ParseDERSignature(rawSig)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !signature.Verify(challenge, pubKey) {
+		return "", ErrInvalidSignature
+	}
+	return strings.ToLower(key), nil
+}
+
+// Encode returns url as an upper-case bech32 LNURL, the form that makes
+// the smallest QR codes
+func Encode(url string) string {
+	data := convertBits([]byte(url))
+	data = append(data, bech32Checksum(data)...)
+
+	var b strings.Builder
+	b.WriteString(hrp + "1")
+	for _, v := range data {
+		b.WriteByte(charset[v])
+	}
+	return strings.ToUpper(b.String())
+}
+
+// charset maps 5-bit values to bech32 characters
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// convertBits regroups 8-bit bytes into 5-bit values, padding the last
+func convertBits(data []byte) []byte {
+	var out []byte
+	acc, bits := 0, 0
+	for _, b := range data {
+		acc = acc<<8 | int(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out = append(out, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		out = append(out, byte(acc<<(5-bits)&31))
+	}
+	return out
+}
+
+// bech32Checksum computes the six checksum values of data under hrp (BIP-173)
+func bech32Checksum(data []byte) []byte {
+	values := make([]byte, 0, 2*len(hrp)+1+len(data)+6)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+
+	mod := polymod(values) ^ 1
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(mod >> (5 * (5 - i)) & 31)
+	}
+	return checksum
+}
+
+func polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if top>>i&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,53 @@
+package lnurl
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func TestEncode(t *testing.T) {
+	// The example of LUD-01
+	url := "https://service.com/api?q=3fc3645b439ce8e7f2553a69e5267081d96dcd340693afabe04be7b0ccd178df"
+	want := "LNURL1DP68GURN8GHJ7UM9WFMXJCM99E3K7MF0V9CXJ0M385EKVCENXC6R2C35XVUKXEFCV5MKVV34X5EKZD3EV56NYD3HXQURZEPEXEJXXEPNXSCRVWFNV9NXZCN9XQ6XYEFHVGCXXCMYXYMNSERXFQ5FNS"
+	if got := Encode(url); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestVerifyAuth(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, err := NewK1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, _ := hex.DecodeString(k1)
+	sig := hex.EncodeToString(ecdsa.Sign(key, challenge).Serialize())
+	linkingKey := strings.ToUpper(hex.EncodeToString(key.PubKey().SerializeCompressed()))
+
+	got, err := VerifyAuth(k1, sig, linkingKey)
+	if err != nil || got != strings.ToLower(linkingKey) {
+		t.Fatalf("Expected the signature verified, got %q (%v)", got, err)
+	}
+
+	other, _ := NewK1()
+	otherKey, _ := secp256k1.GeneratePrivateKey()
+	for name, tc := range map[string]struct{ k1, sig, key string }{
+		"other challenge":  {other, sig, linkingKey},
+		"other key":        {k1, sig, hex.EncodeToString(otherKey.PubKey().SerializeCompressed())},
+		"uncompressed key": {k1, sig, hex.EncodeToString(key.PubKey().SerializeUncompressed())},
+		"malformed sig":    {k1, "30440220", linkingKey},
+		"malformed k1":     {"abcd", sig, linkingKey},
+	} {
+		if _, err := VerifyAuth(tc.k1, tc.sig, tc.key); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package lnurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tagPayRequest marks a LNURL-pay service's discovery response
+const tagPayRequest = "payRequest"
+
+// maxResponseBytes bounds how much of a LNURL-pay response is read
+const maxResponseBytes = 64 << 10
+
+var (
+	// ErrInvalidAddress is returned for a string that is not a Lightning
+	// Address or UMA address
+	ErrInvalidAddress = errors.New("address must be user@domain or $user@domain")
+	// ErrPayRequest is returned when a LNURL-pay service cannot be used:
+	// it is unreachable, answers with an error or with something that is
+	// not a pay request
+	ErrPayRequest = errors.New("lnurl-pay request failed")
+	// ErrAmountOutOfRange is returned for an amount the service does not
+	// accept
+	ErrAmountOutOfRange = errors.New("amount is outside what the recipient accepts")
+)
+
+// addressUser is the user part LUD-16 allows in a Lightning Address
+var addressUser = regexp.MustCompile(`^[a-z0-9\-_.+]+$`)
+
+// addressDomain is a host name with an optional port
+var addressDomain = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9\-]*[a-z0-9])?)+(:[0-9]{1,5})?$`)
+
+// Address is a Lightning Address, user@domain (LUD-16). UMA addresses are
+// the same with a leading $; their receivers answer plain LNURL-pay
+// requests as well, so both resolve the same way.
+type Address struct {
+	User   string
+	Domain string
+	UMA    bool
+}
+
+// ParseAddress parses a Lightning Address or UMA address, lower-casing it
+func ParseAddress(s string) (Address, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	uma := strings.HasPrefix(s, "$")
+	user, domain, ok := strings.Cut(strings.TrimPrefix(s, "$"), "@")
+	if !ok || !addressUser.MatchString(user) || !addressDomain.MatchString(domain) {
+		return Address{}, ErrInvalidAddress
+	}
+	return Address{User: user, Domain: domain, UMA: uma}, nil
+}
+
+// String returns the address as it is written, with the $ of UMA addresses
+func (a Address) String() string {
+	if a.UMA {
+		return "$" + a.User + "@" + a.Domain
+	}
+	return a.User + "@" + a.Domain
+}
+
+// PayURL is where the address's LNURL-pay service is discovered
+func (a Address) PayURL() string {
+	return "https://" + a.Domain + "/.well-known/lnurlp/" + a.User
+}
+
+// PayParams is a LNURL-pay service's answer to discovery (LUD-06). Amounts
+// are in millisatoshis.
+type PayParams struct {
+	Tag         string `json:"tag"`
+	Callback    string `json:"callback"`
+	MinSendable int64  `json:"minSendable"`
+	MaxSendable int64  `json:"maxSendable"`
+	Metadata    string `json:"metadata"`
+}
+
+// Discover fetches the pay parameters of the address's LNURL-pay service
+func Discover(ctx context.Context, client *http.Client, address Address) (*PayParams, error) {
+	params := &PayParams{}
+	if err := getJSON(ctx, client, address.PayURL(), params); err != nil {
+		return nil, err
+	}
+	if params.Tag != tagPayRequest {
+		return nil, fmt.Errorf("%w: %s is not a pay request", ErrPayRequest, address)
+	}
+	callback, err := url.Parse(params.Callback)
+	if err != nil || callback.Scheme != "https" || callback.Host == "" {
+		return nil, fmt.Errorf("%w: callback must be an https URL", ErrPayRequest)
+	}
+	if params.MinSendable <= 0 || params.MaxSendable < params.MinSendable {
+		return nil, fmt.Errorf("%w: invalid sendable range", ErrPayRequest)
+	}
+	return params, nil
+}
+
+// Invoice asks the service for a bolt11 invoice of amountSats. The invoice
+// must be for exactly that amount.
+func (p *PayParams) Invoice(ctx context.Context, client *http.Client, amountSats int64) (string, error) {
+	amountMsat := amountSats * 1000
+	if amountMsat < p.MinSendable || amountMsat > p.MaxSendable {
+		return "", fmt.Errorf("%w: %d to %d sats", ErrAmountOutOfRange, (p.MinSendable+999)/1000, p.MaxSendable/1000)
+	}
+
+	callback, err := url.Parse(p.Callback)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPayRequest, err)
+	}
+	query := callback.Query()
+	query.Set("amount", strconv.FormatInt(amountMsat, 10))
+	callback.RawQuery = query.Encode()
+
+	var answer struct {
+		PR string `json:"pr"`
+	}
+	if err := getJSON(ctx, client, callback.String(), &answer); err != nil {
+		return "", err
+	}
+	if answer.PR == "" {
+		return "", fmt.Errorf("%w: no invoice returned", ErrPayRequest)
+	}
+	if invoiced, ok := InvoiceAmountMsat(answer.PR); !ok || invoiced != amountMsat {
+		return "", fmt.Errorf("%w: invoice is not for %d sats", ErrPayRequest, amountSats)
+	}
+	return answer.PR, nil
+}
+
+// invoicePrefix matches a bolt11 invoice's human-readable part: the network
+// and the optional amount with its multiplier
+var invoicePrefix = regexp.MustCompile(`^ln([a-z]+?)(?:([0-9]+)([munp]?))?1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]`)
+
+// IsInvoice reports whether s looks like a bolt11 invoice
+func IsInvoice(s string) bool {
+	return invoicePrefix.MatchString(strings.ToLower(s))
+}
+
+// InvoiceAmountMsat returns the amount a bolt11 invoice asks for, read from
+// its human-readable part. ok is false for invoices without an amount.
+func InvoiceAmountMsat(bolt11 string) (amountMsat int64, ok bool) {
+	match := invoicePrefix.FindStringSubmatch(strings.ToLower(bolt11))
+	if match == nil || match[2] == "" {
+		return 0, false
+	}
+	amount, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if match[3] == "p" {
+		if amount%10 != 0 {
+			return 0, false
+		}
+		return amount / 10, true
+	}
+	perUnit := msatPerUnit[match[3]]
+	if amount > math.MaxInt64/perUnit {
+		return 0, false
+	}
+	return amount * perUnit, true
+}
+
+// msatPerUnit is the millisatoshis in a unit of each bolt11 amount
+// multiplier; a bitcoin is 10^11 msat. Pico-bitcoin, a tenth of a
+// millisatoshi, is handled apart.
+var msatPerUnit = map[string]int64{"": 100_000_000_000, "m": 100_000_000, "u": 100_000, "n": 100}
+
+// getJSON fetches rawURL into v. LNURL services report failures as
+// {"status": "ERROR", "reason": ...}, often with a 200 status.
+func getJSON(ctx context.Context, client *http.Client, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPayRequest, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Keep the cause; the error would repeat the whole URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%w: %v", ErrPayRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPayRequest, err)
+	}
+	var status struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(body, &status) == nil && strings.EqualFold(status.Status, "ERROR") {
+		return fmt.Errorf("%w: %s", ErrPayRequest, status.Reason)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrPayRequest, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrPayRequest, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package lnurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// handlerTransport serves requests from handler instead of the network
+type handlerTransport struct{ handler http.Handler }
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		uma   bool
+	}{
+		{"Alice@Wallet.example", "alice@wallet.example", false},
+		{" $bob@vasp.example ", "$bob@vasp.example", true},
+		{"carol+tips@pay.example:8443", "carol+tips@pay.example:8443", false},
+	}
+	for _, tt := range tests {
+		address, err := ParseAddress(tt.input)
+		if err != nil || address.String() != tt.want || address.UMA != tt.uma {
+			t.Errorf("ParseAddress(%q) = %+v (%v), want %s", tt.input, address, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "alice", "@wallet.example", "alice@localhost", "al ice@wallet.example", "alice@wallet.example/x"} {
+		if _, err := ParseAddress(input); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("ParseAddress(%q) = %v, want ErrInvalidAddress", input, err)
+		}
+	}
+
+	address, _ := ParseAddress("$bob@vasp.example")
+	if got := address.PayURL(); got != "https://vasp.example/.well-known/lnurlp/bob" {
+		t.Errorf("PayURL() = %s", got)
+	}
+}
+
+func TestInvoiceAmountMsat(t *testing.T) {
+	tests := []struct {
+		bolt11 string
+		msat   int64
+		ok     bool
+	}{
+		{"lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfq", 250_000_000, true},
+		{"lnbc20m1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfq", 2_000_000_000, true},
+		{"lntb10n1pvjluez", 1_000, true},
+		{"lnbc9678785340p1pwmna7l", 967_878_534, true},
+		{"lnbcrt50000n1sim0123", 5_000_000, true},
+		{"LNBC1PVJLUEZPP5QQQSYQCYQ5RQWZQFQ", 0, false},
+		{"lnbc1p1pvjluez", 0, false},
+		{"not an invoice", 0, false},
+	}
+	for _, tt := range tests {
+		msat, ok := InvoiceAmountMsat(tt.bolt11)
+		if msat != tt.msat || ok != tt.ok {
+			t.Errorf("InvoiceAmountMsat(%q) = %d, %v, want %d, %v", tt.bolt11, msat, ok, tt.msat, tt.ok)
+		}
+	}
+	if !IsInvoice("LNBC1PVJLUEZPP5QQQSYQCYQ5RQWZQFQ") || IsInvoice("alice@wallet.example") {
+		t.Error("IsInvoice misclassified")
+	}
+}
+
+func TestPayRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/lnurlp/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag":"payRequest","callback":"https://wallet.example/pay/alice?k=1","minSendable":1000,"maxSendable":100000000,"metadata":"[[\"text/plain\",\"alice\"]]"}`))
+	})
+	mux.HandleFunc("/.well-known/lnurlp/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ERROR","reason":"no such user"}`))
+	})
+	mux.HandleFunc("/pay/alice", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("k") != "1" {
+			http.Error(w, "callback query lost", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Query().Get("amount") {
+		case "21000":
+			w.Write([]byte(`{"pr":"lnbc210n1pexample","routes":[]}`))
+		default:
+			w.Write([]byte(`{"pr":"lnbc1n1pexample","routes":[]}`))
+		}
+	})
+	client := &http.Client{Transport: handlerTransport{mux}}
+	ctx := context.Background()
+
+	alice, _ := ParseAddress("alice@wallet.example")
+	params, err := Discover(ctx, client, alice)
+	if err != nil {
+		t.Fatal("Discover:", err)
+	}
+	if bolt11, err := params.Invoice(ctx, client, 21); err != nil || bolt11 != "lnbc210n1pexample" {
+		t.Errorf("Invoice(21) = %q (%v)", bolt11, err)
+	}
+	// An invoice for another amount is refused
+	if _, err := params.Invoice(ctx, client, 50); !errors.Is(err, ErrPayRequest) {
+		t.Errorf("Expected ErrPayRequest for a mismatched invoice, got %v", err)
+	}
+	if _, err := params.Invoice(ctx, client, 200_000); !errors.Is(err, ErrAmountOutOfRange) {
+		t.Errorf("Expected ErrAmountOutOfRange, got %v", err)
+	}
+
+	gone, _ := ParseAddress("gone@wallet.example")
+	if _, err := Discover(ctx, client, gone); !errors.Is(err, ErrPayRequest) {
+		t.Errorf("Expected ErrPayRequest for an error response, got %v", err)
+	}
+	missing, _ := ParseAddress("nobody@wallet.example")
+	if _, err := Discover(ctx, client, missing); !errors.Is(err, ErrPayRequest) {
+		t.Errorf("Expected ErrPayRequest for a 404, got %v", err)
+	}
+}
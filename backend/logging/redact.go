@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"tickets-by-uma/models"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// bolt11Pattern matches BOLT11 payment requests on any network.
+	bolt11Pattern = regexp.MustCompile(`(?i)\bln(?:bcrt|bc|tbs|tb|sb)[0-9a-z]{20,}`)
+	// nwcPattern matches Nostr Wallet Connect URIs, whose secret spends funds.
+	nwcPattern = regexp.MustCompile(`nostr\+walletconnect://[^\s"'<>]+`)
+	// bearerPattern matches Authorization header values.
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=\-]+`)
+	// jwtPattern matches JSON Web Tokens outside of a bearer prefix.
+	jwtPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	// cashuPattern matches serialized Cashu tokens, which are bearer ecash.
+	cashuPattern = regexp.MustCompile(`\bcashu[AB][A-Za-z0-9_\-+/=]{20,}`)
+	// jsonFieldPattern matches "key": "value" pairs in JSON that could not be
+	// parsed, e.g. a body truncated mid-value.
+	jsonFieldPattern = regexp.MustCompile(`"([^"\\]+)"\s*:\s*"((?:[^"\\]|\\.)*)("?)`)
+)
+
+// secretKeyParts mark payload keys whose values are always credentials, in
+// addition to the secret keys of models.LogKeyClasses.
+var secretKeyParts = []string{"password", "secret", "token", "preimage", "authorization", "cookie", "api_key", "private_key", "bolt11"}
+
+// RedactText removes credentials (bearer tokens, JWTs, bolt11 invoices, NWC
+// URIs, Cashu tokens) from free text and masks email-like addresses.
+func RedactText(text string) string {
+	text = nwcPattern.ReplaceAllStringFunc(text, models.MaskNWCConnectionURI)
+	text = bearerPattern.ReplaceAllString(text, "Bearer "+redacted)
+	text = jwtPattern.ReplaceAllString(text, redacted)
+	text = cashuPattern.ReplaceAllString(text, redacted)
+	text = bolt11Pattern.ReplaceAllString(text, "[BOLT11]")
+	return scrubText(text)
+}
+
+// RedactPayload redacts a request or response body. JSON bodies are redacted
+// field by field, so values under secret keys are dropped and PII keys are
+// masked; anything else is treated as free text, still redacting string
+// fields under secret keys.
+func RedactPayload(body []byte) string {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if out, err := json.Marshal(redactValue(payload)); err == nil {
+			return string(out)
+		}
+	}
+	text := jsonFieldPattern.ReplaceAllStringFunc(string(body), func(field string) string {
+		match := jsonFieldPattern.FindStringSubmatch(field)
+		value, ok := redactField(match[1], match[2]).(string)
+		if !ok || value == match[2] {
+			return field
+		}
+		return `"` + match[1] + `":"` + value + match[3]
+	})
+	return RedactText(text)
+}
+
+// RedactQuery redacts the values of a URL query string like RedactPayload.
+func RedactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return RedactText(rawQuery)
+	}
+	for key, vals := range values {
+		for i, val := range vals {
+			vals[i] = redactField(key, val).(string)
+		}
+		values[key] = vals
+	}
+	return values.Encode()
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = redactField(key, field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return RedactText(v)
+	}
+	return value
+}
+
+func redactField(key string, value interface{}) interface{} {
+	switch keyClass(key) {
+	case models.ClassSecret:
+		if value == nil {
+			return nil
+		}
+		return redacted
+	case models.ClassPII:
+		if s, ok := value.(string); ok {
+			return models.MaskPII(s)
+		}
+	}
+	return redactValue(value)
+}
+
+func keyClass(key string) models.DataClass {
+	lower := strings.ToLower(key)
+	if class, ok := models.LogKeyClasses[lower]; ok {
+		return class
+	}
+	for _, part := range secretKeyParts {
+		if strings.Contains(lower, part) {
+			return models.ClassSecret
+		}
+	}
+	return models.ClassPublic
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactPayload(t *testing.T) {
+	body := `{
+		"email": "alice@example.com",
+		"password": "hunter22",
+		"nwc": {"connection_uri": "nostr+walletconnect://abcdef1234567890?relay=wss://relay.example&secret=s3cret"},
+		"note": "pay lnbc10u1pjexampleexampleexampleexample or use nostr+walletconnect://abcdef1234567890?secret=s3cret2",
+		"refresh_token": "rt-123",
+		"payment_preimage": "deadbeef",
+		"tickets": [{"id": 7, "invoice_id": "lntb2500n1pjexampleexampleexampleexample"}],
+		"error": "Bearer eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln rejected for bob@example.com"
+	}`
+
+	out := RedactPayload([]byte(body))
+	for _, leaked := range []string{"alice@", "bob@", "hunter22", "s3cret", "s3cret2", "rt-123", "deadbeef", "lnbc10u1", "lntb2500n1", "eyJhbGci"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Expected %q to be redacted: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{`"id":7`, "a***@example.com", "b***@example.com", "[BOLT11]", "nostr+walletconnect://abcdef12..."} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Expected %q in redacted payload: %s", kept, out)
+		}
+	}
+
+	// Truncated JSON still has its secret fields redacted
+	truncated := RedactPayload([]byte(`{"email": "carol@example.com", "password": "hunter22", "refresh_token": "rt-4`))
+	for _, leaked := range []string{"carol@", "hunter22", "rt-4"} {
+		if strings.Contains(truncated, leaked) {
+			t.Errorf("Expected %q to be redacted from truncated payload: %s", leaked, truncated)
+		}
+	}
+
+	// Non-JSON bodies are redacted as text
+	text := RedactPayload([]byte("token=lnbcrt500u1pjexampleexampleexampleexample"))
+	if strings.Contains(text, "lnbcrt500u1") {
+		t.Errorf("Expected bolt11 to be redacted from text body: %s", text)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	out := RedactQuery("token=abc123&k1=ff&event_id=3")
+	if strings.Contains(out, "abc123") {
+		t.Errorf("Expected token to be redacted: %s", out)
+	}
+	if !strings.Contains(out, "event_id=3") || !strings.Contains(out, "k1=ff") {
+		t.Errorf("Expected other parameters to be kept: %s", out)
+	}
+}
@@ -0,0 +1,130 @@
+// Package logging provides slog handlers shared by the service.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"regexp"
+
+	"tickets-by-uma/models"
+)
+
+// emailPattern matches email and UMA addresses embedded in free text such as
+// error messages.
+var emailPattern = regexp.MustCompile(`\$?[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// ScrubbingHandler masks personal data and drops secrets before records reach
+// the underlying handler. It scrubs attributes by key (models.LogKeyClasses),
+// model structs by their `class` field tags, and email-like substrings in
+// messages and string values.
+type ScrubbingHandler struct {
+	next slog.Handler
+}
+
+// NewScrubbingHandler wraps next with PII scrubbing.
+func NewScrubbingHandler(next slog.Handler) *ScrubbingHandler {
+	return &ScrubbingHandler{next: next}
+}
+
+func (h *ScrubbingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ScrubbingHandler) Handle(ctx context.Context, record slog.Record) error {
+	scrubbed := slog.NewRecord(record.Time, record.Level, scrubText(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		scrubbed.AddAttrs(scrubAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, scrubbed)
+}
+
+func (h *ScrubbingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		scrubbed[i] = scrubAttr(attr)
+	}
+	return &ScrubbingHandler{next: h.next.WithAttrs(scrubbed)}
+}
+
+func (h *ScrubbingHandler) WithGroup(name string) slog.Handler {
+	return &ScrubbingHandler{next: h.next.WithGroup(name)}
+}
+
+func scrubAttr(attr slog.Attr) slog.Attr {
+	if class, ok := models.LogKeyClasses[attr.Key]; ok {
+		return slog.Attr{Key: attr.Key, Value: maskValue(attr.Value.Resolve(), class)}
+	}
+	return slog.Attr{Key: attr.Key, Value: scrubValue(attr.Value.Resolve())}
+}
+
+func scrubValue(value slog.Value) slog.Value {
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.StringValue(scrubText(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		scrubbed := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			scrubbed[i] = scrubAttr(attr)
+		}
+		return slog.GroupValue(scrubbed...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.StringValue(scrubText(err.Error()))
+		}
+		if group, ok := scrubStruct(value.Any()); ok {
+			return group
+		}
+	}
+	return value
+}
+
+// scrubStruct renders a struct (or pointer to one) as a group, masking fields
+// according to their data classification.
+func scrubStruct(v interface{}) (slog.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return slog.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return slog.Value{}, false
+	}
+
+	classes := models.FieldClasses(rv.Type())
+	if len(classes) == 0 {
+		return slog.Value{}, false
+	}
+
+	var attrs []slog.Attr
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		attr := slog.Any(field.Name, rv.Field(i).Interface())
+		if class, ok := classes[i]; ok {
+			attr.Value = maskValue(attr.Value, class)
+		}
+		attrs = append(attrs, attr)
+	}
+	return slog.GroupValue(attrs...), true
+}
+
+func maskValue(value slog.Value, class models.DataClass) slog.Value {
+	if class == models.ClassSecret {
+		return slog.StringValue("[REDACTED]")
+	}
+	if value.Kind() == slog.KindString {
+		return slog.StringValue(models.MaskPII(value.String()))
+	}
+	return slog.StringValue("[PII]")
+}
+
+func scrubText(text string) string {
+	return emailPattern.ReplaceAllStringFunc(text, models.MaskPII)
+}
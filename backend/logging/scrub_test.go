@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"tickets-by-uma/models"
+)
+
+func TestScrubbingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewScrubbingHandler(slog.NewJSONHandler(&buf, nil)))
+
+	user := &models.User{ID: 7, Email: "alice@example.com", Name: "Alice", PasswordHash: "$2a$10$hash"}
+	logger.With("uma_address", "$alice@vasp.com").Info("Login for bob@example.com",
+		"email", "carol@example.com",
+		"user", user,
+		"error", errors.New("no user dave@example.com"),
+		"connection_uri", "nostr+walletconnect://abc?secret=s3cret",
+		"ticket_id", 42,
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"alice@", "bob@", "carol@", "dave@", "$2a$10$hash", "s3cret", "Alice\""} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Expected %q to be scrubbed from log output: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{"b***@example.com", "$a***@vasp.com", `"ticket_id":42`, `"ID":7`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Expected %q in log output: %s", kept, out)
+		}
+	}
+}
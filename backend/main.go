@@ -10,51 +10,117 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	lightspark "github.com/lightsparkdev/go-sdk/services"
 
+	"tickets-by-uma/clock"
 	"tickets-by-uma/config"
+	"tickets-by-uma/database"
+	"tickets-by-uma/httpclient"
+	"tickets-by-uma/logging"
 	"tickets-by-uma/server"
+	"tickets-by-uma/services"
 )
 
 func main() {
-	// Setup structured logging
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Setup structured logging; personal data is masked before it is written
+	logger := slog.New(logging.NewScrubbingHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
+	})))
 
-	logger.Info("Starting Tickets by UMA backend service")
+	// "restore <file>" restores a backup taken with POST /api/admin/backup
+	// instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		os.Exit(runRestore(os.Args[2:], logger))
+	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
-	logger.Info("Configuration loaded",
-		"port", cfg.Port,
-		"database_url", maskDatabaseURL(cfg.DatabaseURL),
-	)
+	logger.Info("Starting Tickets by UMA backend service")
 
-	// Database connection
-	db, err := sqlx.Connect("postgres", cfg.DatabaseURL)
+	// Load and validate configuration - refuse to start on invalid settings
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to connect to database", "error", err)
+		logger.Error("Invalid configuration", "error", err)
 		os.Exit(1)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Error("Failed to close database connection", "error", err)
+	logger.Info("Configuration loaded", "config", cfg.Redacted())
+
+	// Database connection (Postgres or SQLite); memory storage runs without one
+	var db *sqlx.DB
+	var serverOptions []server.ServerOption
+	if !cfg.UsesMemoryStorage() {
+		queryStats := database.NewQueryStats(cfg.SlowQueryThreshold, logger)
+		serverOptions = append(serverOptions, server.WithQueryStats(queryStats))
+		db, err = database.Open(cfg, queryStats)
+		if err != nil {
+			logger.Error("Failed to connect to database", "error", err)
+			os.Exit(1)
 		}
-	}()
-
-	// Test database connection
-	if err := db.Ping(); err != nil {
-		logger.Error("Failed to ping database", "error", err)
-		os.Exit(1)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Error("Failed to close database connection", "error", err)
+			}
+		}()
+
+		// Test database connection
+		if err := db.Ping(); err != nil {
+			logger.Error("Failed to ping database", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Database connection established")
 	}
-	logger.Info("Database connection established")
 
 	// Note: Database migrations are now handled by dbmate
 	// Run 'dbmate up' to apply migrations before starting the server
+	if db != nil && cfg.SchemaCheck != config.SchemaCheckOff {
+		report, err := database.CheckSchema(db)
+		if err != nil {
+			logger.Error("Failed to check database schema", "error", err)
+			os.Exit(1)
+		}
+		if !report.Compatible() {
+			logger.Error("Database schema does not match this build",
+				"expected_version", database.SchemaVersion, "version", report.Version,
+				"problems", report.Problems(), "schema_check", cfg.SchemaCheck)
+			switch cfg.SchemaCheck {
+			case config.SchemaCheckStrict:
+				os.Exit(1)
+			case config.SchemaCheckReadOnly:
+				logger.Warn("Serving read-only until migrations are applied")
+				serverOptions = append(serverOptions, server.WithReadOnly())
+			}
+		} else {
+			logger.Info("Database schema is compatible", "version", report.Version)
+		}
+	}
+
+	// Background workers stop when the process shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Periodically re-read secrets so rotated credentials are picked up
+	if cfg.Secrets != nil && cfg.SecretsRefreshInterval > 0 {
+		go cfg.Secrets.Watch(workerCtx, cfg.SecretsRefreshInterval, logger)
+	}
+
+	// Build external payment dependencies and inject them into the server
+	httpClient, err := httpclient.New(cfg.HTTPClientOptions())
+	if err != nil {
+		logger.Error("Invalid outbound HTTP client configuration", "error", err)
+		os.Exit(1)
+	}
+	lightsparkClient := lightspark.NewLightsparkClient(cfg.LightsparkClientID, cfg.LightsparkClientSecret, nil, lightspark.WithHTTPClient(httpClient))
+	clk := clock.System()
+	umaService := services.NewUMAServiceFromConfig(cfg, clk, httpClient, logger)
 
 	// Create server
-	srv := server.NewServer(db, logger, cfg)
+	options := []server.ServerOption{
+		server.WithLogger(logger),
+		server.WithClock(clk),
+		server.WithHTTPClient(httpClient),
+		server.WithLightsparkClient(lightsparkClient),
+		server.WithUMAService(umaService),
+	}
+	srv := server.NewServer(db, cfg, append(options, serverOptions...)...)
+	srv.StartWorkers(workerCtx)
 
 	// HTTP server setup
 	httpServer := &http.Server{
@@ -65,10 +131,39 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Optional built-in TLS; otherwise TLS is terminated by a reverse proxy
+	tlsSetup := server.NewTLSSetup(cfg)
+	var redirectServer *http.Server
+	if tlsSetup != nil {
+		httpServer.TLSConfig = tlsSetup.Config
+		if cfg.HTTPRedirectPort != "" {
+			redirectServer = &http.Server{
+				Addr:         ":" + cfg.HTTPRedirectPort,
+				Handler:      tlsSetup.RedirectHandler,
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+			}
+			go func() {
+				logger.Info("Starting HTTP redirect server", "port", cfg.HTTPRedirectPort)
+				if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error("Redirect server failed to start", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+	}
+
 	// Graceful shutdown
 	go func() {
-		logger.Info("Starting HTTP server", "port", cfg.Port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsSetup != nil {
+			logger.Info("Starting HTTPS server", "port", cfg.Port, "tls_mode", cfg.TLSMode)
+			err = httpServer.ListenAndServeTLS(tlsSetup.CertFile, tlsSetup.KeyFile)
+		} else {
+			logger.Info("Starting HTTP server", "port", cfg.Port)
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("Server failed to start", "error", err)
 			os.Exit(1)
 		}
@@ -87,15 +182,11 @@ func main() {
 	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	}
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			logger.Error("Redirect server forced to shutdown", "error", err)
+		}
+	}
 
 	logger.Info("Server exited gracefully")
 }
-
-// maskDatabaseURL masks sensitive parts of the database URL for logging
-func maskDatabaseURL(url string) string {
-	// Simple masking - in production, you might want more sophisticated masking
-	if len(url) > 20 {
-		return url[:10] + "***" + url[len(url)-10:]
-	}
-	return "***"
-}
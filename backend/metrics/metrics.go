@@ -0,0 +1,34 @@
+// Package metrics collects point-in-time snapshots from the components that
+// keep operational counters, for the admin metrics endpoint.
+package metrics
+
+import "sync"
+
+// Registry holds named sources, each returning a JSON-encodable snapshot.
+type Registry struct {
+	mu      sync.RWMutex
+	sources map[string]func() interface{}
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]func() interface{})}
+}
+
+// Register adds a source under name, replacing any registered before.
+func (r *Registry) Register(name string, source func() interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources[name] = source
+}
+
+// Snapshot calls every source and returns their snapshots by name.
+func (r *Registry) Snapshot() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	snapshot := make(map[string]interface{}, len(r.sources))
+	for name, source := range r.sources {
+		snapshot[name] = source()
+	}
+	return snapshot
+}
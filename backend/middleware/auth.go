@@ -11,6 +11,8 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/jwtkeys"
 	"tickets-by-uma/models"
 )
 
@@ -25,24 +27,38 @@ const (
 type Claims struct {
 	UserID int    `json:"user_id"`
 	Email  string `json:"email"`
+	// SessionVersion is the user's session version when the token was
+	// issued
+	SessionVersion int `json:"sv"`
+	// Impersonation is set on tokens an admin issued to act as the user
+	Impersonation *Impersonation `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(user *models.User, secret string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// newClaims returns the claims of a new 24 hour token for user
+func newClaims(user *models.User) *Claims {
+	return newClaimsFor(user, 24*time.Hour)
+}
+
+// newClaimsFor returns the claims of a new token for user lasting ttl
+func newClaimsFor(user *models.User, ttl time.Duration) *Claims {
+	expirationTime := time.Now().Add(ttl)
 
-	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+	return &Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		SessionVersion: user.SessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// GenerateToken creates a new JWT token for a user
+func GenerateToken(user *models.User, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(user))
 	return token.SignedString([]byte(secret))
 }
 
@@ -68,8 +84,122 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 	return claims, nil
 }
 
+// Tokens issues and validates login tokens. With a keyring, new tokens are
+// signed by its primary key and name it in their "kid" header, and tokens
+// are checked against the key they name. Without one, tokens are HS256
+// with the current secret. Alongside a keyring, HS256 tokens are accepted
+// until legacyUntil (zero keeps accepting them), so switching to
+// asymmetric keys does not sign everyone out.
+type Tokens struct {
+	secret      SecretFunc
+	keyring     *jwtkeys.Keyring
+	legacyUntil time.Time
+}
+
+// NewTokens creates a token issuer. keyring may be nil to sign with secret.
+func NewTokens(secret SecretFunc, keyring *jwtkeys.Keyring, legacyUntil time.Time) *Tokens {
+	return &Tokens{secret: secret, keyring: keyring, legacyUntil: legacyUntil}
+}
+
+// Generate creates a new token for a user
+func (t *Tokens) Generate(user *models.User) (string, error) {
+	return t.sign(newClaims(user))
+}
+
+// GenerateImpersonation creates a token letting the admin in imp act as
+// user for ttl, returning it with its expiry. The token carries user's
+// session version, so it is revoked along with the user's own sessions.
+func (t *Tokens) GenerateImpersonation(user *models.User, imp *Impersonation, ttl time.Duration) (string, time.Time, error) {
+	claims := newClaimsFor(user, ttl)
+	claims.Impersonation = imp
+	token, err := t.sign(claims)
+	return token, claims.ExpiresAt.Time, err
+}
+
+// sign signs claims with the primary key, or the secret without a keyring
+func (t *Tokens) sign(claims *Claims) (string, error) {
+	if t.keyring == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(t.secret()))
+	}
+
+	key := t.keyring.Primary()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Signer)
+}
+
+// Validate validates a token and returns its claims
+func (t *Tokens) Validate(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			if t.keyring != nil && !t.legacyUntil.IsZero() && time.Now().After(t.legacyUntil) {
+				return nil, fmt.Errorf("HS256 tokens are no longer accepted")
+			}
+			return []byte(t.secret()), nil
+		}
+
+		if t.keyring == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		key, ok := t.keyring.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %v for key %q", token.Header["alg"], kid)
+		}
+		return key.Signer.Public(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+// JWKS returns the public keys verifying tokens, empty when tokens are
+// signed with a shared secret
+func (t *Tokens) JWKS() jwtkeys.JWKSet {
+	if t.keyring == nil {
+		return jwtkeys.JWKSet{Keys: []jwtkeys.JWK{}}
+	}
+	return t.keyring.JWKS()
+}
+
+// SecretFunc returns the current signing secret. It is called per request so
+// rotated secrets take effect without a restart.
+type SecretFunc func() string
+
+// SessionVersionFunc returns a user's current session version. Tokens
+// issued at an older version have been revoked.
+type SessionVersionFunc func(userID int) (int, error)
+
 // AuthMiddleware validates JWT tokens and adds user to context
 func AuthMiddleware(secret string) func(http.Handler) http.Handler {
+	return RotatingAuthMiddleware(func() string { return secret }, nil)
+}
+
+// RotatingAuthMiddleware is AuthMiddleware with a secret resolved on every
+// request. Unless sessions is nil, tokens from revoked sessions are
+// rejected too.
+func RotatingAuthMiddleware(secret SecretFunc, sessions SessionVersionFunc) func(http.Handler) http.Handler {
+	return TokenAuthMiddleware(NewTokens(secret, nil, time.Time{}), sessions)
+}
+
+// TokenAuthMiddleware is AuthMiddleware validating tokens with tokens
+func TokenAuthMiddleware(tokens *Tokens, sessions SessionVersionFunc) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -84,11 +214,18 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			claims, err := ValidateToken(tokenString, secret)
+			claims, err := tokens.Validate(tokenString)
 			if err != nil {
 				WriteError(w, http.StatusUnauthorized, "Invalid token")
 				return
 			}
+			if sessions != nil {
+				version, err := sessions(claims.UserID)
+				if err != nil || version != claims.SessionVersion {
+					WriteError(w, http.StatusUnauthorized, "Invalid token")
+					return
+				}
+			}
 
 			// Create a mock user object for context
 			user := &models.User{
@@ -97,6 +234,9 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			if claims.Impersonation != nil {
+				ctx = context.WithValue(ctx, ImpersonationContextKey, claims.Impersonation)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -151,13 +291,17 @@ func GenerateRandomString(length int) (string, error) {
 	return fmt.Sprintf("%x", bytes), nil
 }
 
-// GenerateTicketCode generates a unique ticket code
-func GenerateTicketCode() (string, error) {
-	return GenerateRandomString(16)
-}
-
-// WriteJSON writes a JSON response
+// WriteJSON writes a JSON response. The message of a SuccessResponse or
+// ErrorResponse is translated into the locale negotiated by Locale.
 func WriteJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	switch resp := data.(type) {
+	case models.SuccessResponse:
+		resp.Message = i18n.Translate(LocaleOf(w), resp.Message)
+		data = resp
+	case models.ErrorResponse:
+		resp.Message = i18n.Translate(LocaleOf(w), resp.Message)
+		data = resp
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
@@ -171,3 +315,25 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 		Code:    statusCode,
 	})
 }
+
+// WriteErrorCode writes an error response with a machine-readable code
+func WriteErrorCode(w http.ResponseWriter, statusCode int, code, message string) {
+	WriteJSON(w, statusCode, models.ErrorResponse{
+		Error:     http.StatusText(statusCode),
+		Message:   message,
+		Code:      statusCode,
+		ErrorCode: code,
+	})
+}
+
+// WriteErrorDetails writes an error response with a machine-readable code
+// and details describing it
+func WriteErrorDetails(w http.ResponseWriter, statusCode int, code, message string, details interface{}) {
+	WriteJSON(w, statusCode, models.ErrorResponse{
+		Error:     http.StatusText(statusCode),
+		Message:   message,
+		Code:      statusCode,
+		ErrorCode: code,
+		Details:   details,
+	})
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"tickets-by-uma/jwtkeys"
+	"tickets-by-uma/models"
+)
+
+func TestRotatingAuthMiddlewareSessions(t *testing.T) {
+	versions := map[int]int{1: 0}
+	handler := RotatingAuthMiddleware(func() string { return "test-secret" }, func(userID int) (int, error) {
+		return versions[userID], nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]int{"user_id": GetUserFromContext(r.Context()).ID})
+	}))
+
+	status := func(user *models.User) int {
+		t.Helper()
+		token, err := GenerateToken(user, "test-secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	if got := status(user); got != http.StatusOK {
+		t.Errorf("Expected a current token to pass, got %d", got)
+	}
+
+	// Revoking the sessions rejects tokens issued before
+	versions[1] = 1
+	if got := status(user); got != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be rejected, got %d", got)
+	}
+	user.SessionVersion = 1
+	if got := status(user); got != http.StatusOK {
+		t.Errorf("Expected a token from the new session to pass, got %d", got)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	seed := func(b byte) string {
+		return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), ed25519.SeedSize)))
+	}
+	secret := func() string { return "test-secret" }
+	user := &models.User{ID: 7, Email: "user@example.com"}
+
+	keyring, err := jwtkeys.ParseKeyring("k1:" + seed('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := NewTokens(secret, keyring, time.Time{})
+	token, err := tokens.Generate(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil || parsed.Header["kid"] != "k1" || parsed.Method.Alg() != jwtkeys.AlgorithmEdDSA {
+		t.Fatalf("Expected an EdDSA token naming k1, got %v (%v)", parsed.Header, err)
+	}
+	if claims, err := tokens.Validate(token); err != nil || claims.UserID != 7 {
+		t.Fatalf("Expected the token to validate, got %+v (%v)", claims, err)
+	}
+
+	// A new primary key signs from now on; tokens from the old one still
+	// validate until it is dropped
+	if err := keyring.Update("k2:" + seed('b') + ",k1:" + seed('a')); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Validate(token); err != nil {
+		t.Errorf("Expected a token from the old key to validate, got %v", err)
+	}
+	rotated, err := tokens.Generate(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(rotated, &Claims{}); parsed.Header["kid"] != "k2" {
+		t.Errorf("Expected the new key to sign, got %v", parsed.Header)
+	}
+	if err := keyring.Update("k2:" + seed('b')); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Validate(token); err == nil {
+		t.Error("Expected a token from a dropped key to be rejected")
+	}
+
+	// HS256 tokens from before the switch pass until the cutoff
+	legacy, err := GenerateToken(user, "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Validate(legacy); err != nil {
+		t.Errorf("Expected an HS256 token to validate without a cutoff, got %v", err)
+	}
+	expired := NewTokens(secret, keyring, time.Now().Add(-time.Minute))
+	if _, err := expired.Validate(legacy); err == nil {
+		t.Error("Expected an HS256 token to be rejected after the cutoff")
+	}
+	if _, err := expired.Validate(rotated); err != nil {
+		t.Errorf("Expected keyring tokens to pass after the cutoff, got %v", err)
+	}
+
+	// An HMAC token naming a keyring key must not be checked as HMAC
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(user))
+	forged.Header["kid"] = "k2"
+	forgedToken, err := forged.SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Validate(forgedToken); err == nil {
+		t.Error("Expected a token with the wrong algorithm for its key to be rejected")
+	}
+
+	// Without a keyring, tokens are HS256 and there are no public keys
+	plain := NewTokens(secret, nil, time.Time{})
+	if _, err := plain.Validate(rotated); err == nil {
+		t.Error("Expected a keyring token to be rejected without a keyring")
+	}
+	if keys := plain.JWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Errorf("Expected an empty key set, got %v", keys)
+	}
+}
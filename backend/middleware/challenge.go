@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/models"
+)
+
+// ChallengeHeader carries the CAPTCHA token or proof-of-work solution on
+// routes guarded by a ChallengeGuard.
+const ChallengeHeader = "X-Challenge-Response"
+
+// Siteverify endpoints of the supported CAPTCHA providers.
+const (
+	HCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// ErrChallengeFailed is returned by a ChallengeVerifier that rejects a response.
+var ErrChallengeFailed = errors.New("challenge failed")
+
+// ChallengeVerifier checks the value a client sent in ChallengeHeader.
+type ChallengeVerifier interface {
+	Verify(ctx context.Context, response, clientIP string) error
+}
+
+// ChallengeIssuer is implemented by verifiers that hand out the challenge
+// themselves (proof of work) rather than relying on a third-party widget.
+type ChallengeIssuer interface {
+	Issue() (challenge string, expiresAt time.Time, err error)
+	Difficulty() int
+}
+
+// ChallengeGuard requires a solved challenge on the routes it is enabled for.
+// With a nil verifier every route passes through, which is how challenges are
+// turned off in development and tests.
+type ChallengeGuard struct {
+	provider string
+	siteKey  string
+	verifier ChallengeVerifier
+	routes   map[string]bool
+	logger   *slog.Logger
+}
+
+// NewChallengeGuard creates a guard for the named routes. siteKey is the
+// public CAPTCHA key the frontend renders its widget with.
+func NewChallengeGuard(provider, siteKey string, verifier ChallengeVerifier, routes []string, logger *slog.Logger) *ChallengeGuard {
+	enabled := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		enabled[route] = true
+	}
+	return &ChallengeGuard{
+		provider: provider,
+		siteKey:  siteKey,
+		verifier: verifier,
+		routes:   enabled,
+		logger:   logger,
+	}
+}
+
+// Enabled reports whether requests to route must carry a challenge response.
+func (g *ChallengeGuard) Enabled(route string) bool {
+	return g.verifier != nil && g.routes[route]
+}
+
+// Wrap enforces the challenge on route before calling next.
+func (g *ChallengeGuard) Wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	if !g.Enabled(route) {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		clientIP := hostOnly(r.RemoteAddr)
+		response := r.Header.Get(ChallengeHeader)
+		if response == "" {
+			g.reject(w, r, route, clientIP, "missing challenge response")
+			return
+		}
+		if err := g.verifier.Verify(r.Context(), response, clientIP); err != nil {
+			if !errors.Is(err, ErrChallengeFailed) {
+				// The provider could not be reached; fail closed but say why in the logs
+				g.logger.Error("Challenge verification error", "route", route, "provider", g.provider, "error", err)
+			}
+			g.reject(w, r, route, clientIP, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (g *ChallengeGuard) reject(w http.ResponseWriter, r *http.Request, route, clientIP, reason string) {
+	g.logger.Warn("Rejected request without valid challenge",
+		"route", route,
+		"provider", g.provider,
+		"reason", reason,
+		"client_ip", clientIP,
+		"path", r.URL.Path,
+	)
+	WriteError(w, http.StatusForbidden, "Challenge verification failed")
+}
+
+// HandleChallenge tells the frontend which challenge to present and, for proof
+// of work, issues a fresh challenge to solve.
+func (g *ChallengeGuard) HandleChallenge(w http.ResponseWriter, r *http.Request) {
+	routes := make([]string, 0, len(g.routes))
+	for route := range g.routes {
+		if g.Enabled(route) {
+			routes = append(routes, route)
+		}
+	}
+
+	data := map[string]interface{}{
+		"provider": g.provider,
+		"routes":   routes,
+	}
+	if g.siteKey != "" {
+		data["site_key"] = g.siteKey
+	}
+	if issuer, ok := g.verifier.(ChallengeIssuer); ok {
+		challenge, expiresAt, err := issuer.Issue()
+		if err != nil {
+			g.logger.Error("Failed to issue challenge", "error", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to issue challenge")
+			return
+		}
+		data["challenge"] = challenge
+		data["difficulty"] = issuer.Difficulty()
+		data["expires_at"] = expiresAt
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, models.SuccessResponse{
+		Message: "Challenge retrieved successfully",
+		Data:    data,
+	})
+}
+
+// CaptchaVerifier validates hCaptcha or Cloudflare Turnstile tokens with the
+// provider's siteverify API; both accept the same form fields.
+type CaptchaVerifier struct {
+	verifyURL string
+	secret    SecretFunc
+	client    *http.Client
+}
+
+// NewCaptchaVerifier creates a verifier posting to verifyURL with the secret key.
+func NewCaptchaVerifier(verifyURL string, secret SecretFunc, client *http.Client) *CaptchaVerifier {
+	return &CaptchaVerifier{verifyURL: verifyURL, secret: secret, client: client}
+}
+
+func (v *CaptchaVerifier) Verify(ctx context.Context, response, clientIP string) error {
+	form := url.Values{
+		"secret":   {v.secret()},
+		"response": {response},
+	}
+	if clientIP != "" {
+		form.Set("remoteip", clientIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("siteverify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("siteverify returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid siteverify response: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrChallengeFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// ProofOfWork is a Hashcash-style verifier. Issued challenges are
+// "<expiry>.<nonce>.<mac>", HMAC-signed so no server state is needed until
+// they are spent. A solution is "<challenge>:<counter>" such that
+// SHA-256(solution) starts with Difficulty zero bits. Each challenge can be
+// spent once.
+type ProofOfWork struct {
+	key        []byte
+	difficulty int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	spent map[string]time.Time // challenge -> expiry
+}
+
+// NewProofOfWork creates a verifier signing challenges with key. Instances
+// that share a key accept each other's challenges.
+func NewProofOfWork(key []byte, difficulty int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		key:        key,
+		difficulty: difficulty,
+		ttl:        ttl,
+		now:        time.Now,
+		spent:      make(map[string]time.Time),
+	}
+}
+
+func (p *ProofOfWork) Difficulty() int {
+	return p.difficulty
+}
+
+func (p *ProofOfWork) Issue() (string, time.Time, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := p.now().Add(p.ttl).Truncate(time.Second)
+	payload := strconv.FormatInt(expiresAt.Unix(), 10) + "." + hex.EncodeToString(nonce)
+	return payload + "." + p.sign(payload), expiresAt, nil
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, response, clientIP string) error {
+	challenge, counter, ok := strings.Cut(response, ":")
+	if !ok || counter == "" {
+		return fmt.Errorf("%w: malformed solution", ErrChallengeFailed)
+	}
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(p.sign(parts[0]+"."+parts[1]))) {
+		return fmt.Errorf("%w: unknown challenge", ErrChallengeFailed)
+	}
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: unknown challenge", ErrChallengeFailed)
+	}
+	expiresAt := time.Unix(expiry, 0)
+	now := p.now()
+	if now.After(expiresAt) {
+		return fmt.Errorf("%w: challenge expired", ErrChallengeFailed)
+	}
+
+	hash := sha256.Sum256([]byte(response))
+	if leadingZeroBits(hash[:]) < p.difficulty {
+		return fmt.Errorf("%w: insufficient work", ErrChallengeFailed)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for spent, expires := range p.spent {
+		if now.After(expires) {
+			delete(p.spent, spent)
+		}
+	}
+	if _, used := p.spent[challenge]; used {
+		return fmt.Errorf("%w: challenge already used", ErrChallengeFailed)
+	}
+	p.spent[challenge] = expiresAt
+	return nil
+}
+
+func (p *ProofOfWork) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c != 0 {
+			return n + bits.LeadingZeros8(c)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve brute-forces a proof-of-work solution for challenge
+func solve(challenge string, difficulty int) string {
+	for counter := 0; ; counter++ {
+		solution := challenge + ":" + strconv.Itoa(counter)
+		hash := sha256.Sum256([]byte(solution))
+		if leadingZeroBits(hash[:]) >= difficulty {
+			return solution
+		}
+	}
+}
+
+func TestProofOfWork(t *testing.T) {
+	pow := NewProofOfWork([]byte("key"), 8, time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	pow.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	challenge, expiresAt, err := pow.Issue()
+	if err != nil || !expiresAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Issue: expires=%v err=%v", expiresAt, err)
+	}
+	solution := solve(challenge, 8)
+
+	harder := NewProofOfWork([]byte("key"), 32, time.Minute)
+	harder.now = pow.now
+	if err := harder.Verify(ctx, solution, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected insufficient work to fail, got %v", err)
+	}
+	other := NewProofOfWork([]byte("other key"), 8, time.Minute)
+	if err := other.Verify(ctx, solution, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected challenge signed with another key to fail, got %v", err)
+	}
+	if err := pow.Verify(ctx, solution, ""); err != nil {
+		t.Fatalf("Expected solution to verify, got %v", err)
+	}
+	if err := pow.Verify(ctx, solution, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected replayed solution to fail, got %v", err)
+	}
+
+	expiring, _, _ := pow.Issue()
+	expiringSolution := solve(expiring, 8)
+	now = now.Add(2 * time.Minute)
+	if err := pow.Verify(ctx, expiringSolution, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected expired challenge to fail, got %v", err)
+	}
+
+	fresh, _, _ := pow.Issue()
+	if err := pow.Verify(ctx, solve(fresh, 8), ""); err != nil {
+		t.Fatalf("Expected fresh solution to verify, got %v", err)
+	}
+	if len(pow.spent) != 1 {
+		t.Errorf("Expected expired spent challenges to be pruned, got %d", len(pow.spent))
+	}
+}
+
+func TestCaptchaVerifier(t *testing.T) {
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("secret") != "s3cret" || r.PostForm.Get("remoteip") != "192.0.2.7" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("response") == "good-token" {
+			io.WriteString(w, `{"success": true}`)
+			return
+		}
+		io.WriteString(w, `{"success": false, "error-codes": ["invalid-input-response"]}`)
+	}))
+	defer siteverify.Close()
+
+	verifier := NewCaptchaVerifier(siteverify.URL, func() string { return "s3cret" }, siteverify.Client())
+	if err := verifier.Verify(context.Background(), "good-token", "192.0.2.7"); err != nil {
+		t.Errorf("Expected token to verify, got %v", err)
+	}
+	if err := verifier.Verify(context.Background(), "bad-token", "192.0.2.7"); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected ErrChallengeFailed, got %v", err)
+	}
+	// Provider errors are not reported as a failed challenge
+	if err := verifier.Verify(context.Background(), "good-token", "203.0.113.9"); err == nil || errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Expected siteverify error, got %v", err)
+	}
+}
+
+func TestChallengeGuard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pow := NewProofOfWork([]byte("key"), 4, time.Minute)
+	guard := NewChallengeGuard("pow", "", pow, []string{"purchase"}, logger)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	challenge, _, _ := pow.Issue()
+
+	tests := []struct {
+		name       string
+		route      string
+		response   string
+		wantStatus int
+	}{
+		{"route not guarded", "signup", "", http.StatusOK},
+		{"missing response", "purchase", "", http.StatusForbidden},
+		{"invalid response", "purchase", "garbage", http.StatusForbidden},
+		{"solved challenge", "purchase", solve(challenge, 4), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/tickets/purchase", nil)
+			if tt.response != "" {
+				req.Header.Set(ChallengeHeader, tt.response)
+			}
+			rec := httptest.NewRecorder()
+			guard.Wrap(tt.route, ok)(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	// Without a verifier challenges are off everywhere
+	off := NewChallengeGuard("off", "", nil, []string{"purchase"}, logger)
+	rec := httptest.NewRecorder()
+	off.Wrap("purchase", ok)(rec, httptest.NewRequest(http.MethodPost, "/api/tickets/purchase", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected disabled guard to pass through, got %d", rec.Code)
+	}
+}
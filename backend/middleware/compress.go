@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest response worth compressing. Responses are
+// buffered up to this size before deciding, so short JSON bodies go out as-is.
+const compressMinSize = 1024
+
+var (
+	gzipPool = sync.Pool{New: func() any {
+		zw, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return zw
+	}}
+	brotliPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 4)
+	}}
+)
+
+// Compress encodes responses with brotli or gzip when the client accepts it.
+// Compression streams: flushed or large responses are encoded as they are
+// written rather than buffered in full.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the preferred supported encoding, honouring q=0
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[name] = true
+	}
+
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// compressWriter buffers the start of a response to decide whether to
+// compress it, then streams through a pooled encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	wroteHeader bool // handler called WriteHeader
+	started     bool // headers sent downstream
+	buf         []byte
+	encoder     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.started {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= compressMinSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start sends headers downstream, with an encoder when compress is true and
+// the response is eligible, and writes out anything buffered so far.
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && w.compressible() {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		switch w.encoding {
+		case "br":
+			bw := brotliPool.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.encoder = bw
+		case "gzip":
+			zw := gzipPool.Get().(*gzip.Writer)
+			zw.Reset(w.ResponseWriter)
+			w.encoder = zw
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// compressible skips bodiless statuses, already-encoded responses and
+// formats that are compressed already
+func (w *compressWriter) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	for _, prefix := range []string{"image/", "video/", "audio/", "application/zip", "application/gzip"} {
+		if strings.HasPrefix(contentType, prefix) && contentType != "image/svg+xml" {
+			return false
+		}
+	}
+	return true
+}
+
+// Flush starts compressing immediately so streamed responses are not held
+// back by the size threshold.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := w.start(true); err != nil {
+			return
+		}
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close sends any buffered response and returns the encoder to its pool
+func (w *compressWriter) Close() error {
+	if !w.started {
+		if !w.wroteHeader {
+			// Handler wrote nothing; leave the response untouched
+			return nil
+		}
+		// Small responses are not worth compressing
+		return w.start(false)
+	}
+	if w.encoder == nil {
+		return nil
+	}
+
+	err := w.encoder.Close()
+	switch enc := w.encoder.(type) {
+	case *brotli.Writer:
+		brotliPool.Put(enc)
+	case *gzip.Writer:
+		gzipPool.Put(enc)
+	}
+	w.encoder = nil
+	return err
+}
+
+// Hijack is passed through for websocket upgrades
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
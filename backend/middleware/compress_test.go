@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"gzip":               "gzip",
+		"gzip, deflate, br":  "br",
+		"br;q=0, gzip;q=0.5": "gzip",
+		"identity":           "",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"title":"event"},`, 200)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/small" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(large))
+	}))
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for encoding, decode := range decoders {
+		req := httptest.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Expected %s encoding, got %q", encoding, got)
+		}
+		r, err := decode(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != large {
+			t.Errorf("%s round trip mismatch", encoding)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{}` {
+		t.Errorf("Expected small response uncompressed, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Error("Expected Vary: Accept-Encoding")
+	}
+}
+
+func TestJSONListStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := NewJSONListStream(rec, http.StatusOK, "ok")
+	for i := 0; i < 3; i++ {
+		if err := stream.Encode(map[string]int{"id": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.Close()
+
+	var resp struct {
+		Message string           `json:"message"`
+		Data    []map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if resp.Message != "ok" || len(resp.Data) != 3 || resp.Data[2]["id"] != 2 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	stream = NewJSONListStream(rec, http.StatusOK, "ok")
+	stream.Close()
+	if !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Errorf("Expected empty data array, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	stream = NewJSONListStream(rec, http.StatusOK, "ok")
+	stream.Encode(1)
+	stream.Fail("boom")
+	var failed map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failed); err != nil || failed["error"] != "boom" {
+		t.Errorf("Expected error field, got %q", rec.Body.String())
+	}
+}
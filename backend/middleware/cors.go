@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"strings"
+)
+
+// NewOriginValidator returns a function reporting whether an Origin header
+// value matches one of the allowed patterns. Patterns may be exact origins,
+// "*" for any origin, or contain a leading subdomain wildcard such as
+// "https://*.fanmeeting.org", which matches any subdomain (but not the apex).
+func NewOriginValidator(patterns []string) func(origin string) bool {
+	exact := make(map[string]bool)
+	var wildcards []struct{ scheme, suffix string }
+	allowAll := false
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), "/")
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*":
+			allowAll = true
+		case strings.Contains(pattern, "://*."):
+			scheme, host, _ := strings.Cut(pattern, "://")
+			wildcards = append(wildcards, struct{ scheme, suffix string }{scheme, host[1:]})
+		default:
+			exact[pattern] = true
+		}
+	}
+
+	return func(origin string) bool {
+		if allowAll {
+			return true
+		}
+
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok {
+			return false
+		}
+		for _, w := range wildcards {
+			if scheme == w.scheme && strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllowsAnyOrigin reports whether patterns include "*". Credentials must
+// not be allowed then, as any site could make credentialed requests.
+func AllowsAnyOrigin(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "*" {
+			return true
+		}
+	}
+	return false
+}
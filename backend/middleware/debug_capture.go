@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"tickets-by-uma/logging"
+)
+
+// CapturedRequest is a failed request recorded by RequestCapture. Bodies and
+// the query string are redacted before they are stored.
+type CapturedRequest struct {
+	ID           int       `json:"id"`
+	Time         time.Time `json:"time"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Query        string    `json:"query,omitempty"`
+	Status       int       `json:"status"`
+	DurationMS   int64     `json:"duration_ms"`
+	ContentType  string    `json:"content_type,omitempty"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Truncated    bool      `json:"truncated"`
+}
+
+// RequestCapture keeps the redacted request and response bodies of a sample
+// of failed (4xx/5xx) requests in a fixed-size ring buffer, for admins to
+// troubleshoot with. The sample percentage is read on every request so
+// capture can be switched on at runtime; a percentage <= 0 disables it.
+type RequestCapture struct {
+	percent func() int
+	maxBody int
+	sample  func(percent int) bool
+
+	mu      sync.Mutex
+	entries []CapturedRequest
+	next    int
+	seq     int
+}
+
+// NewRequestCapture creates a capture holding the last size failed requests,
+// keeping at most maxBody bytes of each body.
+func NewRequestCapture(percent func() int, size, maxBody int) *RequestCapture {
+	return &RequestCapture{
+		percent: percent,
+		maxBody: maxBody,
+		sample:  func(percent int) bool { return rand.IntN(100) < percent },
+		entries: make([]CapturedRequest, 0, size),
+	}
+}
+
+// Middleware buffers the bodies of sampled requests and records them if the
+// response status is an error. Sampling happens up front so unsampled
+// requests pay nothing.
+func (c *RequestCapture) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		percent := c.percent()
+		if percent <= 0 || cap(c.entries) == 0 || !c.sample(percent) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		reqBody := &cappedBuffer{max: c.maxBody}
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+		writer := &captureWriter{ResponseWriter: w, status: http.StatusOK, body: cappedBuffer{max: c.maxBody}}
+
+		next.ServeHTTP(writer, r)
+
+		if writer.status < http.StatusBadRequest {
+			return
+		}
+		c.record(CapturedRequest{
+			Time:         start,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Query:        logging.RedactQuery(r.URL.RawQuery),
+			Status:       writer.status,
+			DurationMS:   time.Since(start).Milliseconds(),
+			ContentType:  r.Header.Get("Content-Type"),
+			RequestBody:  logging.RedactPayload(reqBody.data),
+			ResponseBody: logging.RedactPayload(writer.body.data),
+			Truncated:    reqBody.truncated || writer.body.truncated,
+		})
+	})
+}
+
+func (c *RequestCapture) record(entry CapturedRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	entry.ID = c.seq
+	if len(c.entries) < cap(c.entries) {
+		c.entries = append(c.entries, entry)
+		return
+	}
+	c.entries[c.next] = entry
+	c.next = (c.next + 1) % len(c.entries)
+}
+
+// Entries returns the captured requests, newest first.
+func (c *RequestCapture) Entries() []CapturedRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]CapturedRequest, 0, len(c.entries))
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		entries = append(entries, c.entries[(c.next+i)%len(c.entries)])
+	}
+	return entries
+}
+
+// Clear drops all captured requests.
+func (c *RequestCapture) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = c.entries[:0]
+	c.next = 0
+}
+
+// cappedBuffer keeps the first max bytes written to it.
+type cappedBuffer struct {
+	max       int
+	data      []byte
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	kept := p
+	if room := b.max - len(b.data); len(kept) > room {
+		kept = kept[:room]
+		b.truncated = true
+	}
+	b.data = append(b.data, kept...)
+	return len(p), nil
+}
+
+// captureWriter records the status and a copy of the body of a response.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	body        cappedBuffer
+	wroteHeader bool
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes through so streamed responses are not held back.
+func (w *captureWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
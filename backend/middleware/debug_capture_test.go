@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestCapture(t *testing.T) {
+	percent := 0
+	capture := NewRequestCapture(func() int { return percent }, 2, 128)
+	handler := capture.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "fail") {
+			WriteError(w, http.StatusBadRequest, "Invalid password for alice@example.com")
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+
+	send := func(body string) {
+		req := httptest.NewRequest("POST", "/api/users?token=abc123", strings.NewReader(body))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Capture is off by default
+	send(`{"note": "fail", "password": "hunter22"}`)
+	if entries := capture.Entries(); len(entries) != 0 {
+		t.Fatalf("Expected nothing captured while disabled, got %d", len(entries))
+	}
+
+	percent = 100
+	send(`{"note": "ok"}`)
+	if entries := capture.Entries(); len(entries) != 0 {
+		t.Fatalf("Expected successful requests not to be captured, got %d", len(entries))
+	}
+
+	send(`{"note": "fail", "password": "hunter22"}`)
+	entries := capture.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 captured request, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Status != http.StatusBadRequest || entry.Method != "POST" || entry.Path != "/api/users" {
+		t.Errorf("Unexpected captured request: %+v", entry)
+	}
+	for _, leaked := range []string{"hunter22", "abc123", "alice@"} {
+		if strings.Contains(entry.RequestBody+entry.ResponseBody+entry.Query, leaked) {
+			t.Errorf("Expected %q to be redacted: %+v", leaked, entry)
+		}
+	}
+	if !strings.Contains(entry.RequestBody, `"note":"fail"`) {
+		t.Errorf("Expected request body to be captured, got %q", entry.RequestBody)
+	}
+
+	// Bodies are capped and the buffer keeps only the newest entries
+	send(`{"note": "fail", "padding": "` + strings.Repeat("x", 200) + `"}`)
+	send(`{"note": "fail again"}`)
+	entries = capture.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected ring buffer to hold 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID != 3 || entries[1].ID != 2 {
+		t.Errorf("Expected newest entries first, got IDs %d and %d", entries[0].ID, entries[1].ID)
+	}
+	if !entries[1].Truncated {
+		t.Error("Expected oversized body to be marked truncated")
+	}
+
+	// Unsampled requests are not captured
+	capture.sample = func(int) bool { return false }
+	capture.Clear()
+	send(`{"note": "fail"}`)
+	if entries := capture.Entries(); len(entries) != 0 {
+		t.Errorf("Expected unsampled request not to be captured, got %d", len(entries))
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/models"
+)
+
+func TestImpersonationGuard(t *testing.T) {
+	var audit bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&audit, nil))
+	tokens := NewTokens(func() string { return "test-secret" }, nil, time.Time{})
+
+	var seen *Impersonation
+	handler := TokenAuthMiddleware(tokens, nil)(ImpersonationGuard(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetImpersonationFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	do := func(method, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	buyer := &models.User{ID: 7, Email: "buyer@example.com"}
+	own, err := tokens.Generate(buyer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := do(http.MethodPut, own); got != http.StatusNoContent || seen != nil {
+		t.Errorf("Expected a user's own token to write freely, got %d %+v", got, seen)
+	}
+	if audit.Len() != 0 {
+		t.Errorf("Expected no audit entry for a user's own request, got %s", audit.String())
+	}
+
+	imp := &Impersonation{AdminID: 1, AdminEmail: "admin@example.com", Reason: "Ticket 42", Banner: "Viewing as buyer"}
+	token, expiresAt, err := tokens.GenerateImpersonation(buyer, imp, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until <= 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("Expected the token to last 15 minutes, expires in %v", until)
+	}
+	claims, err := tokens.Validate(token)
+	if err != nil || claims.UserID != buyer.ID || claims.Impersonation == nil || claims.Impersonation.Banner != "Viewing as buyer" {
+		t.Fatalf("Expected the impersonation claim in the token, got %+v (%v)", claims, err)
+	}
+
+	if got := do(http.MethodGet, token); got != http.StatusNoContent || seen == nil || seen.AdminID != 1 {
+		t.Errorf("Expected an impersonated read to pass, got %d %+v", got, seen)
+	}
+	if got := do(http.MethodPost, token); got != http.StatusForbidden {
+		t.Errorf("Expected an impersonated write to be refused, got %d", got)
+	}
+
+	entries := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(entries) != 2 ||
+		!strings.Contains(entries[0], `msg="Impersonated request"`) || !strings.Contains(entries[0], "admin_id=1") || !strings.Contains(entries[0], "user_id=7") || !strings.Contains(entries[0], "status=204") ||
+		!strings.Contains(entries[1], `msg="Impersonated write refused"`) || !strings.Contains(entries[1], "method=POST") {
+		t.Errorf("Expected both impersonated requests in the audit log, got %s", audit.String())
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"tickets-by-uma/i18n"
+)
+
+// Locale negotiates the response language from the Accept-Language header.
+// WriteJSON translates the messages of success and error responses written
+// below it.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(&localeWriter{ResponseWriter: w, locale: locale}, r)
+	})
+}
+
+// LocaleOf returns the locale negotiated for a response, or i18n.Default
+// when the Locale middleware is not in the chain.
+func LocaleOf(w http.ResponseWriter) string {
+	for {
+		if lw, ok := w.(*localeWriter); ok {
+			return lw.locale
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return i18n.Default
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// localeWriter carries the negotiated locale down to WriteJSON; other
+// middleware may wrap it further.
+type localeWriter struct {
+	http.ResponseWriter
+	locale string
+}
+
+// Flush supports streaming responses through the wrapper.
+func (w *localeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is passed through for websocket upgrades
+func (w *localeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *localeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"tickets-by-uma/models"
+)
+
+func TestLocale(t *testing.T) {
+	handler := Locale(Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			WriteError(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		WriteJSON(w, http.StatusOK, models.SuccessResponse{Message: "Login successful"})
+	})))
+
+	tests := []struct {
+		path, acceptLanguage, want string
+	}{
+		{"/missing", "", "Event not found"},
+		{"/missing", "ko-KR,ko;q=0.9", "이벤트를 찾을 수 없습니다"},
+		{"/missing", "fr, es;q=0.8", "Evento no encontrado"},
+		{"/login", "ko", "로그인되었습니다"},
+		{"/login", "de", "Login successful"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var resp struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Message != tt.want {
+			t.Errorf("%s with %q: got message %q, want %q", tt.path, tt.acceptLanguage, resp.Message, tt.want)
+		}
+		if !slices.Contains(rec.Header().Values("Vary"), "Accept-Language") {
+			t.Errorf("%s: expected Vary to name Accept-Language", tt.path)
+		}
+	}
+
+	// Outside the middleware messages stay in English
+	rec := httptest.NewRecorder()
+	WriteError(rec, http.StatusNotFound, "Event not found")
+	if LocaleOf(rec) != "en" {
+		t.Errorf("Expected the default locale without the middleware, got %q", LocaleOf(rec))
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies holds the set of reverse proxies whose forwarding headers are trusted.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies parses a list of IPs and CIDR ranges.
+func NewTrustedProxies(entries []string) (*TrustedProxies, error) {
+	networks, err := parseNetworks(entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	return &TrustedProxies{networks: networks}, nil
+}
+
+// parseNetworks parses IPs and CIDR ranges; single IPs become host networks.
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// containsIP reports whether ip falls in any of the networks.
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrusted reports whether ip belongs to a trusted proxy.
+func (tp *TrustedProxies) IsTrusted(ip net.IP) bool {
+	return containsIP(tp.networks, ip)
+}
+
+// ClientIP resolves the originating client IP for a request. X-Forwarded-For
+// is only honoured when the direct peer is a trusted proxy; the chain is then
+// walked right to left and the first untrusted hop is taken as the client.
+func (tp *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := hostOnly(r.RemoteAddr)
+	if !tp.IsTrusted(net.ParseIP(peer)) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hostOnly(hops[i]))
+		if ip == nil {
+			// Malformed entry - stop trusting the rest of the chain
+			return peer
+		}
+		if !tp.IsTrusted(ip) {
+			return ip.String()
+		}
+	}
+
+	if len(hops) > 0 {
+		return hostOnly(hops[0])
+	}
+	return peer
+}
+
+// Middleware rewrites r.RemoteAddr to the resolved client IP so downstream
+// handlers and logs see the real caller instead of the load balancer.
+func (tp *TrustedProxies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(tp.networks) > 0 {
+			r.RemoteAddr = tp.ClientIP(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostOnly strips the port from an address, if present.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
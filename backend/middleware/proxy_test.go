@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tp, err := NewTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal("Failed to parse trusted proxies:", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedIP   string
+	}{
+		{
+			name:       "direct connection without header",
+			remoteAddr: "203.0.113.7:5000",
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:         "untrusted peer cannot spoof header",
+			remoteAddr:   "203.0.113.7:5000",
+			forwardedFor: "1.2.3.4",
+			expectedIP:   "203.0.113.7",
+		},
+		{
+			name:         "trusted load balancer",
+			remoteAddr:   "10.1.2.3:443",
+			forwardedFor: "198.51.100.10",
+			expectedIP:   "198.51.100.10",
+		},
+		{
+			name:         "spoofed leftmost entry is ignored",
+			remoteAddr:   "10.1.2.3:443",
+			forwardedFor: "1.2.3.4, 198.51.100.10, 192.168.1.5",
+			expectedIP:   "198.51.100.10",
+		},
+		{
+			name:         "all hops trusted",
+			remoteAddr:   "10.1.2.3:443",
+			forwardedFor: "10.9.9.9",
+			expectedIP:   "10.9.9.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			if got := tp.ClientIP(req); got != tt.expectedIP {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestOriginValidator(t *testing.T) {
+	validate := NewOriginValidator([]string{"http://localhost:3000", "https://*.fanmeeting.org"})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:3000", true},
+		{"https://app.fanmeeting.org", true},
+		{"https://a.b.fanmeeting.org", true},
+		{"https://fanmeeting.org", false},
+		{"http://app.fanmeeting.org", false},
+		{"https://evilfanmeeting.org", false},
+		{"https://example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := validate(tt.origin); got != tt.allowed {
+			t.Errorf("validate(%q) = %v, want %v", tt.origin, got, tt.allowed)
+		}
+	}
+
+	if AllowsAnyOrigin([]string{"http://localhost:3000", "https://*.fanmeeting.org"}) || !AllowsAnyOrigin([]string{"https://fanmeeting.org", " * "}) {
+		t.Error("Expected only a \"*\" entry to allow any origin")
+	}
+}
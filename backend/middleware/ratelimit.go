@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter enforces a per-client fixed-window request limit. The limit is
+// read on every request so it can be tuned at runtime; a limit <= 0 disables it.
+type RateLimiter struct {
+	limit  func() int
+	window time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// NewRateLimiter creates a limiter allowing limit() requests per client per window.
+func NewRateLimiter(limit func() int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		start:  time.Now(),
+		counts: make(map[string]int),
+	}
+}
+
+// Allow records a request from key and reports whether it is within the limit.
+func (rl *RateLimiter) Allow(key string) bool {
+	limit := rl.limit()
+	if limit <= 0 {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if time.Since(rl.start) >= rl.window {
+		rl.start = time.Now()
+		rl.counts = make(map[string]int)
+	}
+
+	rl.counts[key]++
+	return rl.counts[key] <= limit
+}
+
+// Wrap applies the limiter to a handler, keyed by client IP.
+func (rl *RateLimiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		key := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(key); err == nil {
+			key = host
+		}
+
+		if !rl.Allow(key) {
+			w.Header().Set("Retry-After", "60")
+			WriteError(w, http.StatusTooManyRequests, "Too many requests, please try again later")
+			return
+		}
+		next(w, r)
+	}
+}
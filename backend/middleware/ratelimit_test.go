@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limit := 2
+	limiter := NewRateLimiter(func() int { return limit }, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow("10.0.0.1") {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	if limiter.Allow("10.0.0.1") {
+		t.Error("Expected third request to be limited")
+	}
+	if !limiter.Allow("10.0.0.2") {
+		t.Error("Expected other clients to have their own budget")
+	}
+
+	// Changing the limit at runtime takes effect immediately
+	limit = 0
+	if !limiter.Allow("10.0.0.1") {
+		t.Error("Expected limit of 0 to disable limiting")
+	}
+}
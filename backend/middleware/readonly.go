@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"tickets-by-uma/models"
+)
+
+// ReadOnly answers writes with 503 while letting reads through, for a
+// service that must not change data it may not fully understand, such as
+// when its database schema does not match.
+func ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			WriteErrorCode(w, http.StatusServiceUnavailable, models.ErrorCodeReadOnly, "The service is read-only for maintenance, please try again later")
+		}
+	})
+}
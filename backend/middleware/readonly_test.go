@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	handler := ReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method string
+		want   int
+	}{
+		{http.MethodGet, http.StatusNoContent},
+		{http.MethodHead, http.StatusNoContent},
+		{http.MethodOptions, http.StatusNoContent},
+		{http.MethodPost, http.StatusServiceUnavailable},
+		{http.MethodPut, http.StatusServiceUnavailable},
+		{http.MethodDelete, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/api/tickets/purchase", nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.method, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"tickets-by-uma/models"
+)
+
+// ScannerContextKey holds the ScannerDevice a request is made by
+const ScannerContextKey contextKey = "scanner"
+
+// ScannerLookupFunc returns the registered scanner holding a device token
+type ScannerLookupFunc func(token string) (*models.ScannerDevice, error)
+
+// GetScannerFromContext returns the scanner a request is made by, or nil
+// for requests made without a scanner token
+func GetScannerFromContext(ctx context.Context) *models.ScannerDevice {
+	if device, ok := ctx.Value(ScannerContextKey).(*models.ScannerDevice); ok {
+		return device
+	}
+	return nil
+}
+
+// ScannerAuthMiddleware requires a scanner's device token as the Bearer
+// token. Scanner tokens are not user tokens: routes behind this middleware
+// see the device in the context and no user.
+func ScannerAuthMiddleware(lookup ScannerLookupFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				WriteError(w, http.StatusUnauthorized, "Authorization header required")
+				return
+			}
+
+			token := strings.TrimPrefix(authHeader, "Bearer ")
+			if token == authHeader {
+				WriteError(w, http.StatusUnauthorized, "Bearer token required")
+				return
+			}
+
+			device, err := lookup(token)
+			if err != nil {
+				WriteError(w, http.StatusUnauthorized, "Invalid scanner token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ScannerContextKey, device)))
+		})
+	}
+}
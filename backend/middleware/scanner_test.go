@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tickets-by-uma/models"
+)
+
+func TestScannerAuthMiddleware(t *testing.T) {
+	door := &models.ScannerDevice{ID: 3, EventID: 9, Name: "Door 1"}
+	lookup := func(token string) (*models.ScannerDevice, error) {
+		if token == "device-token" {
+			return door, nil
+		}
+		return nil, errors.New("unknown token")
+	}
+
+	var seen *models.ScannerDevice
+	handler := ScannerAuthMiddleware(lookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetScannerFromContext(r.Context())
+		if GetUserFromContext(r.Context()) != nil {
+			t.Error("Expected no user behind a scanner token")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for name, tt := range map[string]struct {
+		header string
+		want   int
+	}{
+		"missing":    {"", http.StatusUnauthorized},
+		"not bearer": {"Basic device-token", http.StatusUnauthorized},
+		"unknown":    {"Bearer other-token", http.StatusUnauthorized},
+		"registered": {"Bearer device-token", http.StatusNoContent},
+	} {
+		seen = nil
+		req := httptest.NewRequest("POST", "/api/scanner/tickets/validate", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, rec.Code)
+		}
+		if tt.want == http.StatusNoContent && seen != door {
+			t.Errorf("%s: expected the scanner in the context, got %+v", name, seen)
+		}
+	}
+}
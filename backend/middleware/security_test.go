@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/docs" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("Expected nosniff, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != apiCSP {
+		t.Errorf("Expected API CSP for JSON, got %q", got)
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("Expected no HSTS on plain HTTP")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Security-Policy"); got != htmlCSP {
+		t.Errorf("Expected HTML CSP, got %q", got)
+	}
+	if rec.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("Expected HSTS behind HTTPS")
+	}
+}
+
+func TestBodyLimiter(t *testing.T) {
+	limiter := NewBodyLimiter(10).Allow("/api/docs/", 100).AllowRoute("/api/admin/events/*/tickets/bulk", 100)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{"/api/users", "small", false, http.StatusOK},
+		{"/api/users", strings.Repeat("x", 50), false, http.StatusRequestEntityTooLarge},
+		{"/api/users", strings.Repeat("x", 50), true, http.StatusRequestEntityTooLarge},
+		{"/api/docs/upload", strings.Repeat("x", 50), false, http.StatusOK},
+		{"/api/admin/events/7/tickets/bulk", strings.Repeat("x", 50), false, http.StatusOK},
+		{"/api/admin/events", strings.Repeat("x", 50), false, http.StatusRequestEntityTooLarge},
+		{"/api/admin/events/7/tickets/bulk/extra", strings.Repeat("x", 50), false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s (%d bytes, chunked=%v): expected %d, got %d", tt.path, len(tt.body), tt.chunked, tt.wantStatus, rec.Code)
+		}
+	}
+}
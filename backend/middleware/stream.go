@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tickets-by-uma/i18n"
+)
+
+// streamFlushEvery is how many list items are written between flushes
+const streamFlushEvery = 100
+
+// JSONListStream writes a SuccessResponse-shaped body whose data array is
+// encoded one item at a time, so large lists are never held in memory:
+//
+//	{"message":"...","data":[item,item,...]}
+//
+// Headers are sent on the first call, so errors found mid-stream can no
+// longer change the status code; Fail closes the document with an "error"
+// field instead so clients can tell the list is incomplete.
+type JSONListStream struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	status  int
+	message string
+	started bool
+	count   int
+}
+
+// NewJSONListStream prepares a streamed list response. The message is
+// translated like WriteJSON's.
+func NewJSONListStream(w http.ResponseWriter, statusCode int, message string) *JSONListStream {
+	message = i18n.Translate(LocaleOf(w), message)
+	return &JSONListStream{w: w, enc: json.NewEncoder(w), status: statusCode, message: message}
+}
+
+func (s *JSONListStream) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	s.w.Header().Set("Content-Type", "application/json")
+	s.w.WriteHeader(s.status)
+	message, err := json.Marshal(s.message)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write([]byte(`{"message":` + string(message) + `,"data":[`))
+	return err
+}
+
+// Encode appends one item to the data array
+func (s *JSONListStream) Encode(item interface{}) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if s.count > 0 {
+		if _, err := s.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	// json.Encoder adds a trailing newline, which is valid whitespace here
+	if err := s.enc.Encode(item); err != nil {
+		return err
+	}
+
+	s.count++
+	if s.count%streamFlushEvery == 0 {
+		if flusher, ok := s.w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// Close terminates the document
+func (s *JSONListStream) Close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	_, err := s.w.Write([]byte("]}\n"))
+	return err
+}
+
+// Fail terminates the document with an error message after a partial list
+func (s *JSONListStream) Fail(message string) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	msg, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write([]byte(`],"error":` + string(msg) + "}\n"))
+	return err
+}
+
+// Started reports whether headers have been sent, after which WriteError
+// can no longer be used
+func (s *JSONListStream) Started() bool {
+	return s.started
+}
+
+// Count returns the number of items written so far
+func (s *JSONListStream) Count() int {
+	return s.count
+}
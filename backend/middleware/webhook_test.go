@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookGuard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	guard, err := NewWebhookGuard("payment", []string{"10.1.0.0/16", "192.0.2.7"}, func() string { return "s3cret" }, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := guard.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		secret     string
+		wantStatus int
+	}{
+		{"allowed IP and secret", "10.1.2.3:5000", "s3cret", http.StatusOK},
+		{"allowed single IP", "192.0.2.7:5000", "s3cret", http.StatusOK},
+		{"IP not allowlisted", "203.0.113.9:5000", "s3cret", http.StatusForbidden},
+		{"missing secret", "10.1.2.3:5000", "", http.StatusForbidden},
+		{"wrong secret", "10.1.2.3:5000", "nope", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks/payment", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.secret != "" {
+				req.Header.Set(WebhookSecretHeader, tt.secret)
+			}
+
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	// An unconfigured guard lets everything through
+	open, _ := NewWebhookGuard("uma", nil, func() string { return "" }, logger)
+	rec := httptest.NewRecorder()
+	open.Wrap(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected unconfigured guard to allow request, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DataClass describes how sensitive a model field is. Fields are classified
+// with a `class` struct tag; untagged fields are public.
+type DataClass string
+
+const (
+	ClassPublic DataClass = ""
+	// ClassPII is personal data (emails, names, UMA addresses). It may be
+	// logged only in masked form.
+	ClassPII DataClass = "pii"
+	// ClassSecret is credential material that must never be logged.
+	ClassSecret DataClass = "secret"
+)
+
+// LogKeyClasses classifies well-known log attribute keys, so values logged as
+// plain strings (e.g. "email", req.Email) are scrubbed like model fields.
+var LogKeyClasses = map[string]DataClass{
+	"email":          ClassPII,
+	"uma_address":    ClassPII,
+	"receiver_uma":   ClassPII,
+	"user_name":      ClassPII,
+	"password":       ClassSecret,
+	"password_hash":  ClassSecret,
+	"connection_uri": ClassSecret,
+	"nwc_uri":        ClassSecret,
+	"token":          ClassSecret,
+}
+
+var fieldClassCache sync.Map // reflect.Type -> map[int]DataClass
+
+// FieldClasses returns the classification of each tagged field of a struct
+// type, keyed by field index.
+func FieldClasses(t reflect.Type) map[int]DataClass {
+	if cached, ok := fieldClassCache.Load(t); ok {
+		return cached.(map[int]DataClass)
+	}
+
+	classes := make(map[int]DataClass)
+	for i := 0; i < t.NumField(); i++ {
+		if class := DataClass(t.Field(i).Tag.Get("class")); class != ClassPublic {
+			classes[i] = class
+		}
+	}
+	fieldClassCache.Store(t, classes)
+	return classes
+}
+
+// MaskPII partially hides personal data while keeping it recognisable for
+// support: "alice@example.com" becomes "a***@example.com" and
+// "$alice@vasp.com" becomes "$a***@vasp.com".
+func MaskPII(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	prefix := ""
+	if strings.HasPrefix(value, "$") {
+		prefix, value = "$", value[1:]
+	}
+
+	local, domain, hasDomain := strings.Cut(value, "@")
+	if local == "" {
+		return prefix + "***"
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	masked := prefix + local[:size] + "***"
+	if hasDomain {
+		masked += "@" + domain
+	}
+	return masked
+}
@@ -1,17 +1,69 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // User represents a user in the system
 type User struct {
 	ID           int       `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	Name         string    `json:"name" db:"name"`
-	PasswordHash string    `json:"-" db:"password_hash"`
+	Email        string    `json:"email" db:"email" class:"pii"`
+	Name         string    `json:"name" db:"name" class:"pii"`
+	PasswordHash string    `json:"-" db:"password_hash" class:"secret"`
+	Locale       string    `json:"locale" db:"locale"` // notification language: en, ko or es
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	// IsGuest marks users created by guest checkout. They cannot log in
+	// until they claim the account and set a password.
+	IsGuest bool `json:"is_guest" db:"is_guest"`
+	// PendingEmail replaces Email once confirmed with the link sent to it
+	PendingEmail *string `json:"pending_email,omitempty" db:"pending_email" class:"pii"`
+	// SessionVersion is stamped into the user's tokens; bumping it revokes
+	// every token issued before
+	SessionVersion int `json:"-" db:"session_version"`
+	// LNURLAuthKey is the linking key of the Lightning wallet the user logs
+	// in with (LNURL-auth)
+	LNURLAuthKey *string `json:"-" db:"lnurl_auth_key"`
+}
+
+// LNURLAuthEmailDomain is the domain of the placeholder emails given to
+// users who sign up with LNURL-auth. It is under the reserved .invalid TLD,
+// so nothing is delivered there.
+const LNURLAuthEmailDomain = "lnurl-auth.invalid"
+
+// LNURLAuthChallenge is a login challenge (k1) for a Lightning wallet to
+// sign. Only the hash of the session secret the browser polls with is
+// stored.
+type LNURLAuthChallenge struct {
+	ID          int    `json:"id" db:"id"`
+	K1          string `json:"k1" db:"k1"`
+	SessionHash string `json:"-" db:"session_hash" class:"secret"`
+	// LinkUserID is the logged-in user adding the wallet as a login method;
+	// nil for a login
+	LinkUserID *int `json:"link_user_id,omitempty" db:"link_user_id"`
+	// UserID is the user the wallet's signature logged in, set once verified
+	UserID     *int       `json:"user_id,omitempty" db:"user_id"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AccountClaim is the outstanding claim link of a guest account. Only the
+// hash of the link's one-time secret is stored.
+type AccountClaim struct {
+	UserID     int       `json:"user_id" db:"user_id"`
+	SecretHash string    `json:"-" db:"secret_hash" class:"secret"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // Event represents a virtual event
@@ -28,27 +80,164 @@ type Event struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 
+	// PricingMode is PricingFixed or PricingPayWhatYouWant. When buyers pick
+	// the amount, PriceSats is the suggested amount and MinPriceSats the least
+	// they may pay.
+	PricingMode  string `json:"pricing_mode" db:"pricing_mode"`
+	MinPriceSats int64  `json:"min_price_sats" db:"min_price_sats"`
+
+	// OrganizerID is the user the event's sales are paid out to; their fee
+	// override, if any, applies instead of the platform default.
+	OrganizerID *int `json:"organizer_id,omitempty" db:"organizer_id"`
+
+	// Tax on ticket sales: TaxBasisPoints (hundredths of a percent) of the
+	// ticket and add-ons, included in the price or charged on top, and
+	// reported under TaxJurisdiction (e.g. "DE VAT").
+	TaxBasisPoints  int64  `json:"tax_basis_points" db:"tax_basis_points"`
+	TaxInclusive    bool   `json:"tax_inclusive" db:"tax_inclusive"`
+	TaxJurisdiction string `json:"tax_jurisdiction" db:"tax_jurisdiction"`
+
+	// IsPrivate events are left out of the public listing and sell only to
+	// buyers on the event's allowlist or holding one of its access codes.
+	IsPrivate bool `json:"is_private" db:"is_private"`
+
+	// Buyers must confirm they are at least MinAge (0 for no restriction) and
+	// accept TermsVersion of the terms at TermsURL, when it is set.
+	MinAge       int    `json:"min_age" db:"min_age"`
+	TermsVersion string `json:"terms_version" db:"terms_version"`
+	TermsURL     string `json:"terms_url" db:"terms_url"`
+
+	// CancelledAt is set once the event is cancelled. A cancelled event is
+	// inactive for good and its tickets are refunded.
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+
+	// Category is a free-form label such as "concert"; events sharing one
+	// are suggested as related
+	Category string `json:"category" db:"category"`
+
+	// InvoiceExpirySeconds is how long ticket invoices stay payable, and
+	// so how long an unpaid ticket holds its seat; 0 for
+	// DefaultInvoiceExpiry. High-demand on-sales use short expiries.
+	InvoiceExpirySeconds int `json:"invoice_expiry_seconds" db:"invoice_expiry_seconds"`
+
+	// OneTicketPerUser limits each buyer to one ticket that is not failed,
+	// expired or cancelled. Events selling several tickets per buyer leave
+	// it off.
+	OneTicketPerUser bool `json:"one_ticket_per_user" db:"one_ticket_per_user"`
+
 	// Relationship to UMA Request invoice (can be nil if no invoice exists)
 	UMARequestInvoice *UMARequestInvoice `json:"uma_request_invoice,omitempty" db:"-"`
 }
 
+// Event pricing modes
+const (
+	PricingFixed          = "fixed"
+	PricingPayWhatYouWant = "pwyw"
+)
+
+// Bounds of invoice expiries, and the expiry of events that do not set one
+const (
+	DefaultInvoiceExpiry = time.Hour
+	MinInvoiceExpiry     = time.Minute
+	MaxInvoiceExpiry     = 24 * time.Hour
+)
+
+// InvoiceExpiry returns how long the event's ticket invoices stay payable
+func (e *Event) InvoiceExpiry() time.Duration {
+	if e.InvoiceExpirySeconds <= 0 {
+		return DefaultInvoiceExpiry
+	}
+	return time.Duration(e.InvoiceExpirySeconds) * time.Second
+}
+
+// PayWhatYouWant reports whether buyers choose the amount they pay.
+func (e *Event) PayWhatYouWant() bool {
+	return e.PricingMode == PricingPayWhatYouWant
+}
+
+// Tax splits a subtotal into the amount taxed and the tax on it, rounding
+// half up. With inclusive pricing the tax is part of the subtotal; otherwise
+// it is charged on top.
+func (e *Event) Tax(subtotalSats int64) (taxableSats, taxSats int64) {
+	if e.TaxBasisPoints <= 0 || subtotalSats <= 0 {
+		return subtotalSats, 0
+	}
+	if e.TaxInclusive {
+		taxableSats = (subtotalSats*10_000 + (10_000+e.TaxBasisPoints)/2) / (10_000 + e.TaxBasisPoints)
+		return taxableSats, subtotalSats - taxableSats
+	}
+	return subtotalSats, (subtotalSats*e.TaxBasisPoints + 5_000) / 10_000
+}
+
+// EventAvailability is a point-in-time view of an event's ticket inventory.
+// Pending tickets (awaiting payment, held for fraud review or suspended by a
+// payment dispute) hold capacity until they are paid, fail or are rejected.
+// Held seats are set aside by unreleased inventory holds.
+type EventAvailability struct {
+	EventID   int `json:"event_id" db:"event_id"`
+	Capacity  int `json:"capacity" db:"capacity"`
+	Sold      int `json:"sold" db:"sold"`
+	Pending   int `json:"pending" db:"pending"`
+	Held      int `json:"held" db:"held"`
+	Remaining int `json:"remaining" db:"-"`
+}
+
 // UMARequestInvoice represents a UMA Request invoice for an event
 type UMARequestInvoice struct {
 	ID          int        `json:"id" db:"id"`
 	EventID     *int       `json:"event_id" db:"event_id"`
 	TicketID    *int       `json:"ticket_id,omitempty" db:"ticket_id"`
+	Source      string     `json:"source" db:"source"` // UMAInvoiceAdmin, UMAInvoiceTicket or UMAInvoiceAddress
 	InvoiceID   string     `json:"invoice_id" db:"invoice_id"`
 	PaymentHash string     `json:"payment_hash" db:"payment_hash"`
 	Bolt11      string     `json:"bolt11" db:"bolt11"`
 	AmountSats  int64      `json:"amount_sats" db:"amount_sats"`
 	Status      string     `json:"status" db:"status"`
-	UMAAddress  string     `json:"uma_address" db:"uma_address"`
+	UMAAddress  string     `json:"uma_address" db:"uma_address" class:"pii"`
 	Description string     `json:"description" db:"description"`
 	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// Sources of UMA Request invoices. An event has one standing invoice
+// created by an admin; its tickets and the payers of its UMA address get
+// invoices of their own.
+const (
+	UMAInvoiceAdmin   = "admin"
+	UMAInvoiceTicket  = "ticket"
+	UMAInvoiceAddress = "address"
+)
+
+// eventUMAPrefix starts the UMA username of every event, as in
+// $event-42@example.com
+const eventUMAPrefix = "event-"
+
+// EventUMAIdentifier is the UMA username of the event, which its
+// /.well-known/lnurlp/{identifier} route and UMA address use.
+func EventUMAIdentifier(eventID int) string {
+	return eventUMAPrefix + strconv.Itoa(eventID)
+}
+
+// EventUMAAddress is the event's UMA address on domain.
+func EventUMAAddress(eventID int, domain string) string {
+	return "$" + EventUMAIdentifier(eventID) + "@" + domain
+}
+
+// ParseEventUMAIdentifier returns the event a UMA username belongs to. The
+// leading $ of an address's username is optional.
+func ParseEventUMAIdentifier(identifier string) (int, bool) {
+	digits, ok := strings.CutPrefix(strings.TrimPrefix(identifier, "$"), eventUMAPrefix)
+	if !ok || digits == "" || digits[0] == '0' || digits[0] == '+' {
+		return 0, false
+	}
+	id, err := strconv.Atoi(digits)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
 // Ticket represents a ticket for an event
 type Ticket struct {
 	ID            int        `json:"id" db:"id"`
@@ -57,23 +246,272 @@ type Ticket struct {
 	TicketCode    string     `json:"ticket_code" db:"ticket_code"`
 	PaymentStatus string     `json:"payment_status" db:"payment_status"`
 	InvoiceID     string     `json:"invoice_id" db:"invoice_id"`
-	UMAAddress    string     `json:"uma_address" db:"uma_address"`
+	UMAAddress    string     `json:"uma_address" db:"uma_address" class:"pii"`
 	PaidAt        *time.Time `json:"paid_at" db:"paid_at"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	// AmountSats is the total agreed at purchase: the ticket price, which the
+	// buyer chooses on pay-what-you-want events, plus any add-ons. Zero on
+	// tickets bought before it was recorded.
+	AmountSats int64 `json:"amount_sats" db:"amount_sats"`
+	// OrderID is the checkout the ticket was bought in
+	OrderID *int `json:"order_id,omitempty" db:"order_id"`
+	// IsComp marks complimentary tickets an organizer issued free of charge
+	IsComp bool `json:"is_comp" db:"is_comp"`
+	// PriceChangeID is the event price the ticket was sold at, when the
+	// price was recorded
+	PriceChangeID *int `json:"price_change_id,omitempty" db:"price_change_id"`
+	// OnePerUser marks tickets bought while their event sold one ticket per
+	// user; the database refuses a second such ticket for the same buyer
+	OnePerUser bool `json:"-" db:"one_per_user"`
+	// NeedsSeat makes creating the ticket fail unless its event has a seat
+	// left, counted as the ticket is stored; it is not stored itself
+	NeedsSeat bool `json:"-" db:"-"`
+}
+
+// Ticket statuses, stored in payment_status. A ticket is pending until its
+// invoice is paid, held for review when fraud checks ask for it, and
+// disputed while a claim against its payment is open; failed, expired and
+// cancelled tickets hold no seat.
+const (
+	TicketPending   = "pending"
+	TicketPaid      = "paid"
+	TicketReview    = "review"
+	TicketDisputed  = "disputed"
+	TicketFailed    = "failed"
+	TicketExpired   = "expired"
+	TicketCancelled = "cancelled"
+)
+
+// TicketStatuses lists every ticket status; the database refuses others
+var TicketStatuses = []string{TicketPending, TicketPaid, TicketReview, TicketDisputed, TicketFailed, TicketExpired, TicketCancelled}
+
+// Payment statuses. Refunded and clawed-back payments are cancelled.
+const (
+	PaymentPending   = "pending"
+	PaymentPaid      = "paid"
+	PaymentFailed    = "failed"
+	PaymentExpired   = "expired"
+	PaymentCancelled = "cancelled"
+)
+
+// PaymentStatuses lists every payment status; the database refuses others
+var PaymentStatuses = []string{PaymentPending, PaymentPaid, PaymentFailed, PaymentExpired, PaymentCancelled}
+
+// ParseTicketStatus reads s as a ticket status the way the status
+// migration normalized stored ones: case and surrounding space do not
+// matter and "canceled" is "cancelled". It reports false for anything else.
+func ParseTicketStatus(s string) (string, bool) {
+	return parseStatus(s, TicketStatuses)
+}
+
+// ParsePaymentStatus reads s as a payment status, as ParseTicketStatus
+// does for tickets
+func ParsePaymentStatus(s string) (string, bool) {
+	return parseStatus(s, PaymentStatuses)
+}
+
+func parseStatus(s string, statuses []string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "canceled" {
+		s = "cancelled"
+	}
+	if !slices.Contains(statuses, s) {
+		return "", false
+	}
+	return s, true
+}
+
+// Order groups the tickets, with their add-ons, bought in one checkout
+type Order struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	EventID   int       `json:"event_id" db:"event_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	Referral
+	// AffiliateID is the affiliate who referred the order, earning
+	// CommissionBasisPoints of its sales, the rate when it was placed
+	AffiliateID           *int  `json:"-" db:"affiliate_id"`
+	CommissionBasisPoints int64 `json:"-" db:"commission_basis_points"`
+}
+
+// Referral is where a checkout came from: a ref code, such as a promoter's,
+// and the UTM parameters of the campaign link the buyer followed. Fields
+// are empty when not given.
+type Referral struct {
+	Ref         string `json:"ref,omitempty" db:"ref"`
+	UTMSource   string `json:"utm_source,omitempty" db:"utm_source"`
+	UTMMedium   string `json:"utm_medium,omitempty" db:"utm_medium"`
+	UTMCampaign string `json:"utm_campaign,omitempty" db:"utm_campaign"`
+	UTMTerm     string `json:"utm_term,omitempty" db:"utm_term"`
+	UTMContent  string `json:"utm_content,omitempty" db:"utm_content"`
+}
+
+// Referral report groupings: by UTM source (falling back to the ref code),
+// medium or campaign, or by ref code
+const (
+	ReferralGroupSource   = "source"
+	ReferralGroupMedium   = "medium"
+	ReferralGroupCampaign = "campaign"
+	ReferralGroupRef      = "ref"
+)
+
+// ReferralFilter narrows a referral conversion report to an event or an
+// organizer's events and to orders placed from From (inclusive) to To
+// (exclusive). GroupBy is one of the ReferralGroup constants.
+type ReferralFilter struct {
+	EventID     int
+	OrganizerID int
+	GroupBy     string
+	From, To    *time.Time
+}
+
+// ReferralConversion is how the checkouts from one referral source did:
+// the orders placed, those with a paid ticket, the paid tickets and what
+// they sold for. Source is empty for orders without one.
+type ReferralConversion struct {
+	Source          string  `json:"source" db:"source"`
+	Orders          int     `json:"orders" db:"orders"`
+	ConvertedOrders int     `json:"converted_orders" db:"converted_orders"`
+	TicketsSold     int     `json:"tickets_sold" db:"tickets_sold"`
+	RevenueSats     int64   `json:"revenue_sats" db:"revenue_sats"`
+	ConversionRate  float64 `json:"conversion_rate" db:"-"`
+}
+
+// FlexFilter narrows a flex uptake report to an event or an organizer's
+// events
+type FlexFilter struct {
+	EventID     int
+	OrganizerID int
+}
+
+// FlexUptake is how many of an event's sold tickets were bought with a
+// flex add-on, what the flex add-ons sold for and how many tickets were
+// cancelled with one. Sold tickets are the paid ones, comps aside, and
+// those cancelled with flex.
+type FlexUptake struct {
+	EventID           int     `json:"event_id" db:"event_id"`
+	EventTitle        string  `json:"event_title" db:"event_title"`
+	TicketsSold       int     `json:"tickets_sold" db:"tickets_sold"`
+	FlexTickets       int     `json:"flex_tickets" db:"flex_tickets"`
+	FlexRevenueSats   int64   `json:"flex_revenue_sats" db:"flex_revenue_sats"`
+	FlexCancellations int     `json:"flex_cancellations" db:"flex_cancellations"`
+	UptakeRate        float64 `json:"uptake_rate" db:"-"`
+}
+
+// OrderStatus summarizes the payment statuses of an order's tickets: their
+// shared status, "pending" while any still awaits review or payment,
+// "partially_paid" when only some were paid, and "cancelled" when none
+// will be
+func OrderStatus(ticketStatuses []string) string {
+	if len(ticketStatuses) == 0 {
+		return "pending"
+	}
+	if !slices.ContainsFunc(ticketStatuses, func(status string) bool { return status != ticketStatuses[0] }) {
+		return ticketStatuses[0]
+	}
+	switch {
+	case slices.Contains(ticketStatuses, "pending"), slices.Contains(ticketStatuses, "review"):
+		return "pending"
+	case slices.Contains(ticketStatuses, "paid"):
+		return "partially_paid"
+	default:
+		return "cancelled"
+	}
 }
 
 // Payment represents a payment record
 type Payment struct {
-	ID        int        `json:"id" db:"id"`
-	TicketID  int        `json:"ticket_id" db:"ticket_id"`
-	InvoiceID string     `json:"invoice_id" db:"invoice_id"`
-	Amount    int64      `json:"amount_sats" db:"amount_sats"`
-	Status    string     `json:"status" db:"status"`
-	Preimage  *string    `json:"preimage,omitempty" db:"preimage"`
-	PaidAt    *time.Time `json:"paid_at" db:"paid_at"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	ID       int `json:"id" db:"id"`
+	TicketID int `json:"ticket_id" db:"ticket_id"`
+	// The invoice paying for the ticket: the payment backend's ID for it,
+	// its encoded payment request and the payment hash settlements are
+	// matched on
+	InvoiceID   string     `json:"invoice_id" db:"invoice_id"`
+	Bolt11      string     `json:"bolt11" db:"bolt11"`
+	PaymentHash string     `json:"payment_hash" db:"payment_hash"`
+	Amount      int64      `json:"amount_sats" db:"amount_sats"`
+	Status      string     `json:"status" db:"status"`
+	Preimage    *string    `json:"preimage,omitempty" db:"preimage"`
+	PaidAt      *time.Time `json:"paid_at" db:"paid_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Tax as charged when the invoice was created, copied from the event so
+	// later rate changes do not alter it. TaxableSats excludes the tax.
+	TaxableSats     int64  `json:"taxable_sats" db:"taxable_sats"`
+	TaxSats         int64  `json:"tax_sats" db:"tax_sats"`
+	TaxBasisPoints  int64  `json:"tax_basis_points" db:"tax_basis_points"`
+	TaxInclusive    bool   `json:"tax_inclusive" db:"tax_inclusive"`
+	TaxJurisdiction string `json:"tax_jurisdiction,omitempty" db:"tax_jurisdiction"`
+
+	// Value in the currency of record, in its minor units, at the exchange
+	// rate locked when the payment was paid. Unset until it is valued.
+	FiatCurrency   string `json:"fiat_currency,omitempty" db:"fiat_currency"`
+	FiatAmount     *int64 `json:"fiat_amount,omitempty" db:"fiat_amount"`
+	ExchangeRateID *int   `json:"exchange_rate_id,omitempty" db:"exchange_rate_id"`
+
+	// ExpiresAt is when the invoice stops being payable. A pending payment
+	// past it is expired, releasing its ticket's seat.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// ExchangeRate is bitcoin's price in a fiat currency as quoted by Source
+// at FetchedAt, in the currency's minor units (cents for USD)
+type ExchangeRate struct {
+	ID               int       `json:"id" db:"id"`
+	Currency         string    `json:"currency" db:"currency"`
+	MinorUnitsPerBTC int64     `json:"minor_units_per_btc" db:"minor_units_per_btc"`
+	Source           string    `json:"source" db:"source"`
+	FetchedAt        time.Time `json:"fetched_at" db:"fetched_at"`
+}
+
+// PaymentValuation is a paid payment awaiting its fiat value and the
+// exchange rate it is to be valued at
+type PaymentValuation struct {
+	PaymentID        int   `db:"payment_id"`
+	AmountSats       int64 `db:"amount_sats"`
+	RateID           int   `db:"rate_id"`
+	MinorUnitsPerBTC int64 `db:"minor_units_per_btc"`
+}
+
+// Cashu redemption states. A redemption is pending until the mint reports
+// the invoice paid or refuses the melt.
+const (
+	CashuPending = "pending"
+	CashuPaid    = "paid"
+	CashuFailed  = "failed"
+)
+
+// CashuRedemption is ecash melted at a mint to pay a ticket invoice:
+// TokenSats of proofs covering AmountSats plus up to FeeReserveSats of
+// routing fees. The token is never stored.
+type CashuRedemption struct {
+	ID             int       `json:"id" db:"id"`
+	PaymentID      int       `json:"payment_id" db:"payment_id"`
+	MintURL        string    `json:"mint_url" db:"mint_url"`
+	QuoteID        string    `json:"quote_id" db:"quote_id"`
+	AmountSats     int64     `json:"amount_sats" db:"amount_sats"`
+	FeeReserveSats int64     `json:"fee_reserve_sats" db:"fee_reserve_sats"`
+	TokenSats      int64     `json:"token_sats" db:"token_sats"`
+	State          string    `json:"state" db:"state"`
+	Preimage       *string   `json:"preimage,omitempty" db:"preimage"`
+	Error          string    `json:"error,omitempty" db:"error"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CashuAmount is what a Cashu token held against what paying the invoice
+// at the mint takes: the invoice amount plus the mint's fee reserve
+type CashuAmount struct {
+	RequiredSats int64 `json:"required_sats"`
+	TokenSats    int64 `json:"token_sats"`
+}
+
+// CashuPaymentRequest pays a ticket invoice with a Cashu token
+type CashuPaymentRequest struct {
+	Invoice string `json:"invoice"`
+	Token   string `json:"token"`
 }
 
 // Invoice represents a Lightning invoice
@@ -86,6 +524,20 @@ type Invoice struct {
 	ExpiresAt   *time.Time `json:"expires_at"`
 }
 
+// PaymentProof is a buyer's proof of having paid for a ticket. SHA-256 of
+// the preimage equals the invoice's payment hash, which is also encoded in
+// the bolt11; Verified reports that the two were checked to match.
+type PaymentProof struct {
+	TicketID    int        `json:"ticket_id"`
+	PaymentID   int        `json:"payment_id"`
+	Bolt11      string     `json:"bolt11"`
+	PaymentHash string     `json:"payment_hash,omitempty"`
+	Preimage    string     `json:"preimage"`
+	AmountSats  int64      `json:"amount_sats"`
+	PaidAt      *time.Time `json:"paid_at"`
+	Verified    bool       `json:"verified"`
+}
+
 // PaymentStatus represents the status of a payment
 type PaymentStatus struct {
 	InvoiceID   string `json:"invoice_id"`
@@ -111,16 +563,975 @@ type NodeBalance struct {
 	Status               string `json:"status"`
 }
 
-// NWCConnection represents a stored NWC connection for a user
+// NWCConnection represents a stored NWC connection for a user.
+// The connection URI can spend the user's funds and is never serialized.
 type NWCConnection struct {
 	ID            int        `json:"id" db:"id"`
 	UserID        int        `json:"user_id" db:"user_id"`
-	ConnectionURI string     `json:"connection_uri" db:"connection_uri"`
+	ConnectionURI string     `json:"-" db:"connection_uri" class:"secret"`
 	ExpiresAt     *time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// WalletClaim binds a ticket to a mobile wallet. While a claim offer is
+// outstanding SecretHash holds the hash of its one-time secret; once claimed,
+// DevicePublicKey (base64 Ed25519) must sign check-in challenges.
+type WalletClaim struct {
+	TicketID        int        `json:"ticket_id" db:"ticket_id"`
+	SecretHash      *string    `json:"-" db:"secret_hash" class:"secret"`
+	SecretExpiresAt *time.Time `json:"-" db:"secret_expires_at"`
+	DevicePublicKey *string    `json:"device_public_key" db:"device_public_key"`
+	ClaimedAt       *time.Time `json:"claimed_at" db:"claimed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Setting represents a runtime setting stored as a JSON value
+type Setting struct {
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedBy string    `json:"updated_by" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FraudSignal is one fraud rule that fired for a purchase attempt
+type FraudSignal struct {
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+	Action string `json:"action"`
+}
+
+// FraudSignals is stored as a JSON array
+type FraudSignals []FraudSignal
+
+// Value implements driver.Valuer
+func (s FraudSignals) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (s *FraudSignals) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil:
+		*s = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into FraudSignals", src)
+}
+
+// FraudFlag records a purchase attempt that tripped fraud rules and the
+// action taken: "allow" (logged only), "review" or "block"
+type FraudFlag struct {
+	ID        int          `json:"id" db:"id"`
+	UserID    int          `json:"user_id" db:"user_id"`
+	EventID   int          `json:"event_id" db:"event_id"`
+	TicketID  *int         `json:"ticket_id,omitempty" db:"ticket_id"`
+	ClientIP  string       `json:"client_ip" db:"client_ip" class:"pii"`
+	Action    string       `json:"action" db:"action"`
+	Signals   FraudSignals `json:"signals" db:"signals"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// AddOn is an extra sold alongside an event's tickets, such as merchandise
+// or a meet-and-greet
+type AddOn struct {
+	ID          int       `json:"id" db:"id"`
+	EventID     int       `json:"event_id" db:"event_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	PriceSats   int64     `json:"price_sats" db:"price_sats"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// FlexCutoffHours makes this a flex add-on: its buyer may cancel the
+	// ticket for a refund until this many hours before the event starts
+	FlexCutoffHours *int `json:"flex_cutoff_hours,omitempty" db:"flex_cutoff_hours"`
+}
+
+// TicketAddOn is an add-on line item bought with a ticket. Name and
+// UnitPriceSats are copied at purchase; AddOnID is nil once the add-on is
+// deleted from the catalog.
+type TicketAddOn struct {
+	ID            int       `json:"id" db:"id"`
+	TicketID      int       `json:"ticket_id" db:"ticket_id"`
+	AddOnID       *int      `json:"addon_id" db:"addon_id"`
+	Name          string    `json:"name" db:"name"`
+	Quantity      int       `json:"quantity" db:"quantity"`
+	UnitPriceSats int64     `json:"unit_price_sats" db:"unit_price_sats"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	// FlexCutoffHours is copied from a flex add-on at purchase; FlexUsedAt
+	// is when the ticket was cancelled with it
+	FlexCutoffHours *int       `json:"flex_cutoff_hours,omitempty" db:"flex_cutoff_hours"`
+	FlexUsedAt      *time.Time `json:"flex_used_at,omitempty" db:"flex_used_at"`
+}
+
+// TotalSats is the line item's price times its quantity
+func (a TicketAddOn) TotalSats() int64 {
+	return a.UnitPriceSats * int64(a.Quantity)
+}
+
+// PricingRule raises a fixed-price event's ticket price to PriceSats once it
+// triggers: when SoldPercent of the event's capacity is sold, or at
+// StartsAt. Exactly one of the two is set.
+type PricingRule struct {
+	ID          int        `json:"id" db:"id"`
+	EventID     int        `json:"event_id" db:"event_id"`
+	Name        string     `json:"name" db:"name"`
+	PriceSats   int64      `json:"price_sats" db:"price_sats"`
+	SoldPercent *int       `json:"sold_percent,omitempty" db:"sold_percent"`
+	StartsAt    *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Triggered reports whether the rule applies at now with sold of capacity
+// tickets sold
+func (r PricingRule) Triggered(now time.Time, sold, capacity int) bool {
+	if r.StartsAt != nil {
+		return !now.Before(*r.StartsAt)
+	}
+	return capacity > 0 && sold*100 >= *r.SoldPercent*capacity
+}
+
+// PriceChange is a price an event's tickets were sold at from ChangedAt,
+// set by the pricing rule named RuleName or, when that is empty, by the
+// event itself. RuleID is nil once the rule is deleted.
+type PriceChange struct {
+	ID        int       `json:"id" db:"id"`
+	EventID   int       `json:"event_id" db:"event_id"`
+	PriceSats int64     `json:"price_sats" db:"price_sats"`
+	RuleID    *int      `json:"rule_id" db:"rule_id"`
+	RuleName  string    `json:"rule_name" db:"rule_name"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// Form field types
+const (
+	FormFieldText     = "text"
+	FormFieldNumber   = "number"
+	FormFieldSelect   = "select"
+	FormFieldCheckbox = "checkbox"
+)
+
+// FormFieldOptions are the choices of a select field, stored as a JSON array
+type FormFieldOptions []string
+
+// Value implements driver.Valuer
+func (o FormFieldOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(o)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (o *FormFieldOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case nil:
+		*o = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into FormFieldOptions", src)
+}
+
+// FormField is a question an event asks buyers at purchase, such as shirt
+// size or dietary needs. Fields are shown in Position order.
+type FormField struct {
+	ID        int              `json:"id" db:"id"`
+	EventID   int              `json:"event_id" db:"event_id"`
+	Label     string           `json:"label" db:"label"`
+	FieldType string           `json:"field_type" db:"field_type"`
+	Options   FormFieldOptions `json:"options" db:"options"`
+	Required  bool             `json:"required" db:"required"`
+	Position  int              `json:"position" db:"position"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// TicketAnswer is a buyer's answer to a form field. Label is copied at
+// purchase; FieldID is nil once the field is deleted from the form.
+type TicketAnswer struct {
+	ID        int       `json:"id" db:"id"`
+	TicketID  int       `json:"ticket_id" db:"ticket_id"`
+	FieldID   *int      `json:"field_id" db:"field_id"`
+	Label     string    `json:"label" db:"label"`
+	Value     string    `json:"value" db:"value" class:"pii"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// EventAccessCode is an invite code for a private event. MaxUses limits how
+// many tickets it buys, and ExpiresAt when it stops working; either may be
+// unset.
+type EventAccessCode struct {
+	ID        int        `json:"id" db:"id"`
+	EventID   int        `json:"event_id" db:"event_id"`
+	Code      string     `json:"code" db:"code"`
+	MaxUses   *int       `json:"max_uses" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EventAllowlistEntry lets the buyer with this email or UMA address, stored
+// lowercased, purchase tickets for a private event without a code
+type EventAllowlistEntry struct {
+	ID        int       `json:"id" db:"id"`
+	EventID   int       `json:"event_id" db:"event_id"`
+	Entry     string    `json:"entry" db:"entry" class:"pii"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// PurchaseAttestation records what a buyer confirmed when buying a ticket
+// for an age restricted event or one with terms, and from where
+type PurchaseAttestation struct {
+	ID           int       `json:"id" db:"id"`
+	TicketID     int       `json:"ticket_id" db:"ticket_id"`
+	EventID      int       `json:"event_id" db:"event_id"`
+	MinAge       int       `json:"min_age" db:"min_age"`
+	TermsVersion string    `json:"terms_version" db:"terms_version"`
+	ClientIP     string    `json:"client_ip" db:"client_ip" class:"pii"`
+	UserAgent    string    `json:"user_agent" db:"user_agent"`
+	AttestedAt   time.Time `json:"attested_at" db:"attested_at"`
+}
+
+// EventTranslation is an event's title and description in one locale. The
+// event itself holds the content in the default language.
+type EventTranslation struct {
+	ID          int       `json:"id" db:"id"`
+	EventID     int       `json:"event_id" db:"event_id"`
+	Locale      string    `json:"locale" db:"locale"`
+	Title       string    `json:"title" db:"title"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EventReschedule records an event moved to new times. Buyers holding paid
+// tickets from before it may ask for a refund until RefundUntil, when set.
+type EventReschedule struct {
+	ID            int        `json:"id" db:"id"`
+	EventID       int        `json:"event_id" db:"event_id"`
+	OldStartTime  time.Time  `json:"old_start_time" db:"old_start_time"`
+	OldEndTime    time.Time  `json:"old_end_time" db:"old_end_time"`
+	NewStartTime  time.Time  `json:"new_start_time" db:"new_start_time"`
+	NewEndTime    time.Time  `json:"new_end_time" db:"new_end_time"`
+	Reason        string     `json:"reason" db:"reason"`
+	RefundUntil   *time.Time `json:"refund_until" db:"refund_until"`
+	RescheduledBy *int       `json:"rescheduled_by" db:"rescheduled_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Event cancellation statuses
+const (
+	CancellationRunning = "running"
+	CancellationDone    = "done"
+	CancellationFailed  = "failed" // a pass cancelled nothing; cancelling again retries
+)
+
+// EventCancellation is the bulk job cancelling a cancelled event's tickets.
+// Total is the number of tickets it found to cancel: Refunded were paid and
+// refunded, Voided unpaid; Failed is how many the last pass could not
+// cancel, with the last error.
+type EventCancellation struct {
+	ID          int        `json:"id" db:"id"`
+	EventID     int        `json:"event_id" db:"event_id"`
+	Reason      string     `json:"reason" db:"reason"`
+	Status      string     `json:"status" db:"status"`
+	Total       int        `json:"total" db:"total"`
+	Refunded    int        `json:"refunded" db:"refunded"`
+	Voided      int        `json:"voided" db:"voided"`
+	Failed      int        `json:"failed" db:"failed"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	CancelledBy *int       `json:"cancelled_by" db:"cancelled_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// NotificationTemplate is one version of an admin-configured notification
+// template. The highest version for an organizer, kind and locale is in
+// use; templates without an organizer apply platform wide.
+type NotificationTemplate struct {
+	ID          int       `json:"id" db:"id"`
+	OrganizerID *int      `json:"organizer_id,omitempty" db:"organizer_id"`
+	Kind        string    `json:"kind" db:"kind"`
+	Locale      string    `json:"locale" db:"locale"`
+	Version     int       `json:"version" db:"version"`
+	Subject     string    `json:"subject" db:"subject"`
+	TextBody    string    `json:"text_body" db:"text_body"`
+	HTMLBody    string    `json:"html_body" db:"html_body"`
+	CreatedBy   *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// RenderedNotification is a notification ready to send. HTML is empty for
+// text-only notifications.
+type RenderedNotification struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Payment line item kinds
+const (
+	LineItemTicket   = "ticket"
+	LineItemAddOn    = "addon"
+	LineItemDiscount = "discount"
+	LineItemFee      = "fee"
+	LineItemTax      = "tax" // only for tax charged on top of the price
+)
+
+// PaymentLineItem is one line of what a payment covers. Discounts have a
+// negative amount; the amounts of a payment's items sum to its total.
+type PaymentLineItem struct {
+	ID             int       `json:"id" db:"id"`
+	PaymentID      int       `json:"payment_id" db:"payment_id"`
+	Kind           string    `json:"kind" db:"kind"`
+	Description    string    `json:"description" db:"description"`
+	Quantity       int       `json:"quantity" db:"quantity"`
+	UnitAmountSats int64     `json:"unit_amount_sats" db:"unit_amount_sats"`
+	AmountSats     int64     `json:"amount_sats" db:"amount_sats"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Sale is a paid payment with what it bought, as exported to accounting
+type Sale struct {
+	Payment
+	EventID       int               `json:"event_id" db:"event_id"`
+	EventTitle    string            `json:"event_title" db:"event_title"`
+	OrganizerID   *int              `json:"organizer_id" db:"organizer_id"`
+	ReceiptNumber *int64            `json:"receipt_number" db:"receipt_number"`
+	LineItems     []PaymentLineItem `json:"line_items" db:"-"`
+	// AffiliateID is set when an affiliate referred the sale, earning
+	// CommissionBasisPoints of it
+	AffiliateID           *int  `json:"affiliate_id,omitempty" db:"affiliate_id"`
+	CommissionBasisPoints int64 `json:"commission_basis_points,omitempty" db:"commission_basis_points"`
+}
+
+// OrganizerFee overrides the platform fee for an organizer's events
+type OrganizerFee struct {
+	OrganizerID int       `json:"organizer_id" db:"organizer_id"`
+	BasisPoints int64     `json:"basis_points" db:"basis_points"`
+	FixedSats   int64     `json:"fixed_sats" db:"fixed_sats"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RevenueFilter narrows the revenue report. Zero values do not filter; the
+// period covers payments paid at or after From and before To.
+type RevenueFilter struct {
+	OrganizerID int
+	From, To    *time.Time
+	// FiatCurrency is the currency of record payments are valued in
+	FiatCurrency string
+}
+
+// EventRevenue is what an event's paid payments brought in: the gross paid
+// by buyers, the platform's fees and the organizer's payout.
+type EventRevenue struct {
+	EventID     int    `json:"event_id" db:"event_id"`
+	Title       string `json:"title" db:"title"`
+	OrganizerID *int   `json:"organizer_id" db:"organizer_id"`
+	Payments    int    `json:"payments" db:"payments"`
+	GrossSats   int64  `json:"gross_sats" db:"gross_sats"`
+	FeeSats     int64  `json:"fee_sats" db:"fee_sats"`
+	PayoutSats  int64  `json:"payout_sats" db:"-"`
+
+	// GrossFiat is the gross in FiatCurrency minor units at the rates
+	// locked when each payment was paid, leaving out UnvaluedPayments
+	// (payments not valued yet or valued in another currency)
+	FiatCurrency     string `json:"fiat_currency" db:"-"`
+	GrossFiat        int64  `json:"gross_fiat" db:"gross_fiat"`
+	UnvaluedPayments int    `json:"unvalued_payments" db:"unvalued_payments"`
+}
+
+// TaxSummary totals the tax collected on paid payments for one
+// jurisdiction and rate, for filing
+type TaxSummary struct {
+	Jurisdiction string `json:"jurisdiction" db:"tax_jurisdiction"`
+	BasisPoints  int64  `json:"basis_points" db:"tax_basis_points"`
+	Payments     int    `json:"payments" db:"payments"`
+	TaxableSats  int64  `json:"taxable_sats" db:"taxable_sats"`
+	TaxSats      int64  `json:"tax_sats" db:"tax_sats"`
+}
+
+// Receipt is issued once per paid payment. Numbers are sequential without
+// gaps, in order of issue.
+type Receipt struct {
+	ID        int       `json:"id" db:"id"`
+	PaymentID int       `json:"payment_id" db:"payment_id"`
+	Number    int64     `json:"receipt_number" db:"receipt_number"`
+	IssuedAt  time.Time `json:"issued_at" db:"issued_at"`
+}
+
+// FormattedNumber is the receipt number as printed, e.g. R-000042
+func (r Receipt) FormattedNumber() string {
+	return fmt.Sprintf("R-%06d", r.Number)
+}
+
+// ReceiptDocument is the receipt as returned to buyers and accounting
+type ReceiptDocument struct {
+	ReceiptNumber string            `json:"receipt_number"`
+	IssuedAt      time.Time         `json:"issued_at"`
+	Issuer        string            `json:"issuer"`
+	PaymentID     int               `json:"payment_id"`
+	InvoiceID     string            `json:"invoice_id"`
+	PaidAt        *time.Time        `json:"paid_at"`
+	TicketCode    string            `json:"ticket_code"`
+	EventTitle    string            `json:"event_title"`
+	EventStart    time.Time         `json:"event_start"`
+	BuyerName     string            `json:"buyer_name" class:"pii"`
+	BuyerEmail    string            `json:"buyer_email" class:"pii"`
+	LineItems     []PaymentLineItem `json:"line_items"`
+	TotalSats     int64             `json:"total_sats"`
+
+	// Tax as charged on the payment; with inclusive pricing it is part of
+	// the line items rather than a line of its own
+	TaxableSats     int64  `json:"taxable_sats"`
+	TaxSats         int64  `json:"tax_sats"`
+	TaxBasisPoints  int64  `json:"tax_basis_points"`
+	TaxInclusive    bool   `json:"tax_inclusive"`
+	TaxJurisdiction string `json:"tax_jurisdiction,omitempty"`
+}
+
+// Ledger entry kinds
+const (
+	LedgerEntrySale   = "sale"
+	LedgerEntryRefund = "refund"
+	LedgerEntryPayout = "payout"
+)
+
+// Ledger account types
+const (
+	LedgerAsset     = "asset"
+	LedgerLiability = "liability"
+	LedgerIncome    = "income"
+	LedgerExpense   = "expense"
+	LedgerEquity    = "equity"
+)
+
+// LedgerAccount is an account of the internal ledger, named like
+// Liabilities:Organizer Payable:7
+type LedgerAccount struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Type      string    `json:"type" db:"type"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LedgerAccountType returns the type of a ledger account from the first
+// part of its name: Assets, Liabilities, Income, Expenses or Equity. It
+// returns "" for any other name.
+func LedgerAccountType(name string) string {
+	root, _, _ := strings.Cut(name, ":")
+	switch root {
+	case "Assets":
+		return LedgerAsset
+	case "Liabilities":
+		return LedgerLiability
+	case "Income":
+		return LedgerIncome
+	case "Expenses":
+		return LedgerExpense
+	case "Equity":
+		return LedgerEquity
+	}
+	return ""
+}
+
+// LedgerBalance is an account with its posted totals. The balance is
+// debits minus credits for assets and expenses and credits minus debits
+// for the other types, so it is normally positive.
+type LedgerBalance struct {
+	LedgerAccount
+	DebitSats   int64 `json:"debit_sats" db:"debit_sats"`
+	CreditSats  int64 `json:"credit_sats" db:"credit_sats"`
+	BalanceSats int64 `json:"balance_sats" db:"-"`
+}
+
+// LedgerEntry is a balanced journal entry. PaymentID is set for sales and
+// refunds, OrganizerID or AffiliateID for payouts.
+type LedgerEntry struct {
+	ID          int             `json:"id" db:"id"`
+	Kind        string          `json:"kind" db:"kind"`
+	PaymentID   *int            `json:"payment_id,omitempty" db:"payment_id"`
+	OrganizerID *int            `json:"organizer_id,omitempty" db:"organizer_id"`
+	AffiliateID *int            `json:"affiliate_id,omitempty" db:"affiliate_id"`
+	Reference   string          `json:"reference" db:"reference"`
+	Description string          `json:"description" db:"description"`
+	OccurredAt  time.Time       `json:"occurred_at" db:"occurred_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	Postings    []LedgerPosting `json:"postings" db:"-"`
+}
+
+// LedgerPosting is one debit or credit of an entry
+type LedgerPosting struct {
+	ID         int    `json:"id" db:"id"`
+	EntryID    int    `json:"entry_id" db:"entry_id"`
+	AccountID  int    `json:"account_id" db:"account_id"`
+	Account    string `json:"account" db:"account"`
+	DebitSats  int64  `json:"debit_sats" db:"debit_sats"`
+	CreditSats int64  `json:"credit_sats" db:"credit_sats"`
+}
+
+// LedgerIssue is a broken ledger invariant found by the consistency check
+type LedgerIssue struct {
+	Check     string `json:"check"`
+	EntryID   *int   `json:"entry_id,omitempty"`
+	PaymentID *int   `json:"payment_id,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+// Dispute statuses
+const (
+	DisputeOpen = "open"
+	DisputeWon  = "won"  // the payment stands and the ticket is reinstated
+	DisputeLost = "lost" // the funds were clawed back and the ticket cancelled
+)
+
+// Dispute is a claim by a counterparty VASP against a paid payment, such
+// as a clawback. The payment's ticket is suspended while it is open.
+type Dispute struct {
+	ID         int        `json:"id" db:"id"`
+	PaymentID  int        `json:"payment_id" db:"payment_id"`
+	Status     string     `json:"status" db:"status"`
+	Reason     string     `json:"reason" db:"reason"`
+	Resolution string     `json:"resolution" db:"resolution"`
+	OpenedBy   *int       `json:"opened_by" db:"opened_by"`
+	ResolvedBy *int       `json:"resolved_by" db:"resolved_by"`
+	OpenedAt   time.Time  `json:"opened_at" db:"opened_at"`
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+// Webhook event statuses
+const (
+	WebhookEventPending = "pending"
+	WebhookEventDone    = "done"
+	WebhookEventFailed  = "failed" // gave up after the maximum attempts
+)
+
+// WebhookSourceLightspark is the source of Lightspark payment webhooks
+const WebhookSourceLightspark = "lightspark"
+
+// WebhookEvent is a verified inbound webhook queued for processing.
+// LockedUntil is the lease of the worker processing it.
+type WebhookEvent struct {
+	ID            int        `json:"id" db:"id"`
+	Source        string     `json:"source" db:"source"`
+	EventID       string     `json:"event_id" db:"event_id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	EntityID      string     `json:"entity_id" db:"entity_id"`
+	Payload       string     `json:"-" db:"payload"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	ReceivedAt    time.Time  `json:"received_at" db:"received_at"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedUntil   *time.Time `json:"locked_until" db:"locked_until"`
+	ProcessedAt   *time.Time `json:"processed_at" db:"processed_at"`
+}
+
+// WebhookBacklog summarizes the webhook queue. OldestPendingAt is when the
+// oldest unprocessed event was received, nil when none is pending.
+type WebhookBacklog struct {
+	Pending         int        `json:"pending" db:"pending"`
+	Failed          int        `json:"failed" db:"failed"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at" db:"oldest_pending_at"`
+}
+
+// ReplayWebhookRequest re-runs a missed Lightspark payment webhook, either
+// for an entity ID or from the payload and signature as Lightspark sent
+// them. Reason is required for the audit log.
+type ReplayWebhookRequest struct {
+	EntityID  string `json:"entity_id,omitempty"`
+	Payload   string `json:"payload,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// SalesPauseRequest pauses or resumes ticket sales
+type SalesPauseRequest struct {
+	Paused *bool `json:"paused"`
+}
+
+// ArchivedRecord is a row the retention archiver moved out of its table,
+// kept as a JSON object of its columns. EventID is the event the row
+// belonged to, directly or through its ticket or payment.
+type ArchivedRecord struct {
+	ID         int         `json:"id" db:"id"`
+	TableName  string      `json:"table_name" db:"table_name"`
+	RecordID   int         `json:"record_id" db:"record_id"`
+	EventID    *int        `json:"event_id" db:"event_id"`
+	Data       ArchivedRow `json:"data" db:"data" class:"pii"`
+	ArchivedAt time.Time   `json:"archived_at" db:"archived_at"`
+}
+
+// ArchivedRow holds an archived row's columns by name, stored as a JSON
+// object
+type ArchivedRow map[string]interface{}
+
+// Value implements driver.Valuer
+func (r ArchivedRow) Value() (driver.Value, error) {
+	if r == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (r *ArchivedRow) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into ArchivedRow", src)
+}
+
+// Admin notification kinds
+const (
+	AdminNotificationSale            = "sale"
+	AdminNotificationRefundRequested = "refund_requested"
+	AdminNotificationPayout          = "payout"
+	AdminNotificationFraudFlag       = "fraud_flag"
+	AdminNotificationWebhookFailed   = "webhook_failed"
+	AdminNotificationSoldOut         = "sold_out"
+	AdminNotificationBackendDown     = "backend_down" // the payment backend's circuit breaker opened
+)
+
+// AdminNotificationKinds lists every admin notification kind
+var AdminNotificationKinds = []string{
+	AdminNotificationSale,
+	AdminNotificationRefundRequested,
+	AdminNotificationPayout,
+	AdminNotificationFraudFlag,
+	AdminNotificationWebhookFailed,
+	AdminNotificationSoldOut,
+	AdminNotificationBackendDown,
+}
+
+// AdminNotification is an entry in the admins' notification feed. EntityID
+// is the ticket, ledger entry, fraud flag or webhook event it is about, by
+// kind. Read state is shared: ReadBy is the admin who marked it read.
+type AdminNotification struct {
+	ID        int        `json:"id" db:"id"`
+	Kind      string     `json:"kind" db:"kind"`
+	Title     string     `json:"title" db:"title"`
+	Message   string     `json:"message" db:"message"`
+	EventID   *int       `json:"event_id,omitempty" db:"event_id"`
+	EntityID  *int       `json:"entity_id,omitempty" db:"entity_id"`
+	ReadAt    *time.Time `json:"read_at" db:"read_at"`
+	ReadBy    *int       `json:"read_by,omitempty" db:"read_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AdminNotificationFeed is a page of admin notifications with the number
+// still unread overall
+type AdminNotificationFeed struct {
+	Notifications []AdminNotification `json:"notifications"`
+	UnreadCount   int                 `json:"unread_count"`
+}
+
+// Alert connector kinds
+const (
+	AlertConnectorSlack    = "slack"
+	AlertConnectorDiscord  = "discord"
+	AlertConnectorTelegram = "telegram"
+)
+
+// AlertEvents are the admin notification kinds a connector posts, stored
+// as a JSON array
+type AlertEvents []string
+
+// Value implements driver.Valuer
+func (e AlertEvents) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (e *AlertEvents) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	case nil:
+		*e = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into AlertEvents", src)
+}
+
+// AlertConnector posts admin notifications of the kinds in Events to a
+// Slack or Discord incoming webhook, whose URL is Target, or to the
+// Telegram chat ChatID through the bot whose token is Target. LastError is
+// empty when the last post succeeded.
+type AlertConnector struct {
+	ID         int         `json:"id" db:"id"`
+	Name       string      `json:"name" db:"name"`
+	Kind       string      `json:"kind" db:"kind"`
+	Target     string      `json:"-" db:"target" class:"secret"`
+	ChatID     string      `json:"chat_id,omitempty" db:"chat_id"`
+	Events     AlertEvents `json:"events" db:"events"`
+	IsActive   bool        `json:"is_active" db:"is_active"`
+	LastError  string      `json:"last_error" db:"last_error"`
+	LastSentAt *time.Time  `json:"last_sent_at" db:"last_sent_at"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAlertConnectorRequest adds an alert connector. Target is the Slack
+// or Discord webhook URL or the Telegram bot token; it is never returned.
+type CreateAlertConnectorRequest struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Target   string   `json:"target"`
+	ChatID   string   `json:"chat_id,omitempty"` // Telegram only
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active,omitempty"` // default true
+}
+
+// UpdateAlertConnectorRequest changes the fields of an alert connector
+// that are set
+type UpdateAlertConnectorRequest struct {
+	Name     *string   `json:"name,omitempty"`
+	Target   *string   `json:"target,omitempty"`
+	ChatID   *string   `json:"chat_id,omitempty"`
+	Events   *[]string `json:"events,omitempty"`
+	IsActive *bool     `json:"is_active,omitempty"`
+}
+
+// Status page subsystems
+const (
+	SubsystemAPI           = "api"
+	SubsystemDatabase      = "database"
+	SubsystemLightning     = "lightning"
+	SubsystemNotifications = "notifications"
+	SubsystemJobQueue      = "job_queue"
+)
+
+// StatusSubsystems lists the status page subsystems in the order shown
+var StatusSubsystems = []string{
+	SubsystemAPI,
+	SubsystemDatabase,
+	SubsystemLightning,
+	SubsystemNotifications,
+	SubsystemJobQueue,
+}
+
+// Subsystem states, best first
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusOutage      = "outage"
+)
+
+// Status incident states
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentResolved      = "resolved"
+)
+
+// Status incident impacts: an unresolved incident degrades the subsystems
+// it affects, or takes them down when major
+const (
+	IncidentImpactMinor = "minor"
+	IncidentImpactMajor = "major"
+)
+
+// IncidentComponents are the subsystems an incident affects, stored as a
+// JSON array
+type IncidentComponents []string
+
+// Value implements driver.Valuer
+func (c IncidentComponents) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (c *IncidentComponents) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into IncidentComponents", src)
+}
+
+// StatusIncident is an incident admins post to the public status page.
+// Message is its latest update.
+type StatusIncident struct {
+	ID         int                `json:"id" db:"id"`
+	Title      string             `json:"title" db:"title"`
+	Message    string             `json:"message" db:"message"`
+	Status     string             `json:"status" db:"status"`
+	Impact     string             `json:"impact" db:"impact"`
+	Components IncidentComponents `json:"components" db:"components"`
+	CreatedBy  *int               `json:"created_by,omitempty" db:"created_by"`
+	StartedAt  time.Time          `json:"started_at" db:"started_at"`
+	ResolvedAt *time.Time         `json:"resolved_at" db:"resolved_at"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// CreateStatusIncidentRequest posts an incident, investigating with minor
+// impact and starting now unless set
+type CreateStatusIncidentRequest struct {
+	Title      string     `json:"title"`
+	Message    string     `json:"message"`
+	Status     string     `json:"status,omitempty"`
+	Impact     string     `json:"impact,omitempty"`
+	Components []string   `json:"components"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+}
+
+// UpdateStatusIncidentRequest changes the fields of an incident that are
+// set
+type UpdateStatusIncidentRequest struct {
+	Title      *string   `json:"title,omitempty"`
+	Message    *string   `json:"message,omitempty"`
+	Status     *string   `json:"status,omitempty"`
+	Impact     *string   `json:"impact,omitempty"`
+	Components *[]string `json:"components,omitempty"`
+}
+
+// SubsystemStatus is one subsystem's state on the status page
+type SubsystemStatus struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+}
+
+// SystemStatus is the public status page: the worst subsystem's state as
+// Status, every subsystem's, and the unresolved and recently resolved
+// incidents newest first
+type SystemStatus struct {
+	Status     string            `json:"status"`
+	Subsystems []SubsystemStatus `json:"subsystems"`
+	Incidents  []StatusIncident  `json:"incidents"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// ArchiveRun counts the payments, tickets and events one archiver pass moved
+type ArchiveRun struct {
+	Payments int `json:"payments"`
+	Tickets  int `json:"tickets"`
+	Events   int `json:"events"`
+}
+
+// Backup is a database backup uploaded to backup storage
+type Backup struct {
+	Filename  string    `json:"filename"`
+	Location  string    `json:"location"` // e.g. s3://bucket/prefix/filename
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConsoleView is one of the admin console's canned read-only queries.
+// Support staff pick a view and supply its params; they never write SQL.
+type ConsoleView struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Params      []ConsoleParam `json:"params"`
+}
+
+// ConsoleParam is a value bound into a console view's query. An optional
+// param left out is bound as its type's zero value, which the view treats
+// as "any".
+type ConsoleParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // ConsoleInt or ConsoleString
+	Required bool   `json:"required"`
+}
+
+// Console param types
+const (
+	ConsoleInt    = "int"
+	ConsoleString = "string"
+)
+
+// ConsoleResult is what a console view returned. Columns lists the row
+// keys in the view's order; Truncated is set when rows beyond the cap
+// were left out.
+type ConsoleResult struct {
+	View      string                   `json:"view"`
+	Columns   []string                 `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Truncated bool                     `json:"truncated"`
+}
+
+// SalesPauseStatus reports whether all sales are paused and which events
+// are paused on their own
+type SalesPauseStatus struct {
+	Paused       bool  `json:"paused"`
+	PausedEvents []int `json:"paused_events"`
+}
+
+// UpdateSettingRequest represents a request to change a runtime setting
+type UpdateSettingRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// LogValue masks the connection URI when the connection is logged
+func (c NWCConnection) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", c.ID),
+		slog.Int("user_id", c.UserID),
+		slog.String("connection_uri", MaskNWCConnectionURI(c.ConnectionURI)),
+	)
+}
+
+// MaskNWCConnectionURI hides the secret of an NWC connection URI, keeping
+// the wallet pubkey prefix and relay for troubleshooting
+func MaskNWCConnectionURI(connectionURI string) string {
+	u, err := url.Parse(connectionURI)
+	if err != nil || u.Scheme == "" {
+		return "***"
+	}
+
+	pubkey := u.Host
+	if len(pubkey) > 8 {
+		pubkey = pubkey[:8] + "..."
+	}
+
+	masked := u.Scheme + "://" + pubkey
+	if relay := u.Query().Get("relay"); relay != "" {
+		masked += "?relay=" + relay + "&secret=***"
+	}
+	return masked
+}
+
 // StoreNWCConnectionRequest represents a request to store an NWC connection
 type StoreNWCConnectionRequest struct {
 	NWCConnectionURI string     `json:"nwc_connection_uri"`
@@ -132,6 +1543,83 @@ type TicketPurchaseRequest struct {
 	EventID    int    `json:"event_id"`
 	UserID     int    `json:"user_id"`
 	UMAAddress string `json:"uma_address"`
+	// Email buys as a guest when UserID is not set
+	Email string `json:"email,omitempty"`
+	// AmountSats is the amount the buyer chooses on pay-what-you-want
+	// events; it defaults to the suggested price and is ignored otherwise.
+	AmountSats *int64 `json:"amount_sats,omitempty"`
+	// AddOns are billed on the same invoice as the ticket
+	AddOns []AddOnSelection `json:"addons,omitempty"`
+	// Answers to the event's form fields; required fields must be answered
+	Answers []FormAnswer `json:"answers,omitempty"`
+	// AccessCode admits buyers who are not on a private event's allowlist
+	AccessCode string `json:"access_code,omitempty"`
+	// AgeConfirmed and AcceptedTermsVersion attest to the event's age
+	// restriction and terms; the version must match the event's current one
+	AgeConfirmed         bool   `json:"age_confirmed,omitempty"`
+	AcceptedTermsVersion string `json:"accepted_terms_version,omitempty"`
+	// Gift, when set, buys the ticket for someone else
+	Gift *GiftRequest `json:"gift,omitempty"`
+	// InvoiceExpirySeconds shortens the invoice's expiry below the event's,
+	// e.g. for a kiosk; it cannot extend it
+	InvoiceExpirySeconds int `json:"invoice_expiry_seconds,omitempty"`
+	// Referral is recorded on the order; fields left out are taken from
+	// the request's query parameters
+	Referral
+}
+
+// AddOnSelection is an add-on and quantity chosen at checkout
+type AddOnSelection struct {
+	AddOnID  int `json:"addon_id"`
+	Quantity int `json:"quantity"`
+}
+
+// FormAnswer is a buyer's answer to one form field at checkout. Checkbox
+// fields take "true" or "false".
+type FormAnswer struct {
+	FieldID int    `json:"field_id"`
+	Value   string `json:"value"`
+}
+
+// CompTicketRecipient is one row of a bulk comp ticket issuance
+type CompTicketRecipient struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// BulkTicketRequest represents a request to issue comp tickets to a guest
+// list, one ticket per recipient
+type BulkTicketRequest struct {
+	Recipients []CompTicketRecipient `json:"recipients"`
+}
+
+// Bulk ticket row statuses
+const (
+	BulkTicketIssued = "issued"
+	BulkTicketFailed = "failed"
+)
+
+// BulkTicketRowResult reports what happened to one recipient. Row counts
+// from 1 in request order.
+type BulkTicketRowResult struct {
+	Row        int    `json:"row"`
+	Email      string `json:"email"`
+	Status     string `json:"status"`
+	TicketID   *int   `json:"ticket_id,omitempty"`
+	TicketCode string `json:"ticket_code,omitempty"`
+	UserID     *int   `json:"user_id,omitempty"`
+	// Notified is false when the ticket was issued but its email could
+	// not be sent
+	Notified bool   `json:"notified"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkTicketResult summarises a bulk comp ticket issuance
+type BulkTicketResult struct {
+	EventID int                   `json:"event_id"`
+	Issued  int                   `json:"issued"`
+	Failed  int                   `json:"failed"`
+	Results []BulkTicketRowResult `json:"results"`
 }
 
 // TicketValidationRequest represents a ticket validation request
@@ -140,6 +1628,337 @@ type TicketValidationRequest struct {
 	EventID    int    `json:"event_id"`
 }
 
+// TicketQRVerificationRequest represents a request to verify a signed
+// ticket QR payload at the door
+type TicketQRVerificationRequest struct {
+	Payload string `json:"payload"`
+	EventID int    `json:"event_id"`
+}
+
+// Ticket revocation reasons. Disputed and cancelled tickets are revoked
+// under their payment status.
+const (
+	RevocationRefunded = "refunded"
+)
+
+// TicketRevocation is a ticket whose signed QR payload must no longer be
+// honoured
+type TicketRevocation struct {
+	TicketID  int       `json:"ticket_id"`
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// TicketRevocationList is the revocation list of an event, for scanners
+// that verify ticket QR payloads offline
+type TicketRevocationList struct {
+	EventID     int                `json:"event_id"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Revocations []TicketRevocation `json:"revocations"`
+}
+
+// WalletClaimRequest represents a wallet claiming a ticket with the secret
+// from its claim URI
+type WalletClaimRequest struct {
+	Secret          string `json:"secret"`
+	DevicePublicKey string `json:"device_public_key"`
+}
+
+// DeviceValidationRequest represents a check-in with a wallet-claimed
+// ticket: the challenge issued for it, signed by the claiming device
+type DeviceValidationRequest struct {
+	TicketID  int    `json:"ticket_id"`
+	EventID   int    `json:"event_id"`
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+}
+
+// TicketScan is one check-in attempt at the door. TicketID is nil when the
+// scanned code matched no ticket; Reason says why a ticket was turned away.
+type TicketScan struct {
+	ID         int       `json:"id" db:"id"`
+	EventID    int       `json:"event_id" db:"event_id"`
+	TicketID   *int      `json:"ticket_id" db:"ticket_id"`
+	TicketCode string    `json:"ticket_code" db:"ticket_code"`
+	Admitted   bool      `json:"admitted" db:"admitted"`
+	Reason     string    `json:"reason,omitempty" db:"reason"`
+	ScannedAt  time.Time `json:"scanned_at" db:"scanned_at"`
+	// ScannerDeviceID is the registered scanner the scan was made with
+	ScannerDeviceID *int `json:"scanner_device_id,omitempty" db:"scanner_device_id"`
+}
+
+// ScannerDevice is a door scanner registered to one event. Its pairing
+// code and token are only ever returned when issued; the hashes stored
+// here are not exposed.
+type ScannerDevice struct {
+	ID               int        `json:"id" db:"id"`
+	EventID          int        `json:"event_id" db:"event_id"`
+	Name             string     `json:"name" db:"name"`
+	PairingHash      *string    `json:"-" db:"pairing_hash"`
+	PairingExpiresAt *time.Time `json:"pairing_expires_at,omitempty" db:"pairing_expires_at"`
+	TokenHash        *string    `json:"-" db:"token_hash"`
+	RegisteredAt     *time.Time `json:"registered_at" db:"registered_at"`
+	LastSeenAt       *time.Time `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt        *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedBy        *int       `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateScannerRequest represents a request to add a door scanner to an
+// event
+type CreateScannerRequest struct {
+	Name string `json:"name"`
+}
+
+// RegisterScannerRequest represents a device redeeming a scanner pairing
+// code
+type RegisterScannerRequest struct {
+	PairingCode string `json:"pairing_code"`
+}
+
+// ScannerPairing is a new scanner with the code a device registers with
+type ScannerPairing struct {
+	Device      *ScannerDevice `json:"device"`
+	PairingCode string         `json:"pairing_code"`
+}
+
+// ScannerRegistration is a registered scanner with its device token
+type ScannerRegistration struct {
+	Device *ScannerDevice `json:"device"`
+	Token  string         `json:"token"`
+}
+
+// InventoryHold is a block of an event's seats set aside by an admin, such
+// as for a sponsor or the production. Held seats are not sold publicly
+// until the hold is released.
+type InventoryHold struct {
+	ID         int        `json:"id" db:"id"`
+	EventID    int        `json:"event_id" db:"event_id"`
+	Name       string     `json:"name" db:"name"`
+	Seats      int        `json:"seats" db:"seats"`
+	CreatedBy  *int       `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReleasedAt *time.Time `json:"released_at" db:"released_at"`
+	ReleasedBy *int       `json:"released_by" db:"released_by"`
+}
+
+// CreateHoldRequest represents a request to hold seats of an event
+type CreateHoldRequest struct {
+	Name  string `json:"name"`
+	Seats int    `json:"seats"`
+}
+
+// RelatedEvent summarises an event shown alongside another. Curated ones
+// were picked by an admin; the rest share the event's category or
+// organizer.
+type RelatedEvent struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	PriceSats   int64     `json:"price_sats"`
+	PricingMode string    `json:"pricing_mode"`
+	Category    string    `json:"category"`
+	Curated     bool      `json:"curated"`
+}
+
+// SetRelatedEventsRequest represents an admin picking an event's related
+// events, in display order
+type SetRelatedEventsRequest struct {
+	EventIDs []int `json:"event_ids"`
+}
+
+// OrganizerProfile is the public page an event organizer keeps, found by
+// its slug. Organizers edit their own profile.
+type OrganizerProfile struct {
+	UserID      int       `json:"user_id" db:"user_id"`
+	Slug        string    `json:"slug" db:"slug"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	Bio         string    `json:"bio" db:"bio"`
+	WebsiteURL  string    `json:"website_url" db:"website_url"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// PayoutAddress is the Lightning Address or UMA address payouts are
+	// sent to; empty until the organizer sets one. It is not shown on the
+	// public page.
+	PayoutAddress string `json:"payout_address" db:"payout_address" class:"pii"`
+}
+
+// OrganizerProfileRequest represents an organizer creating or replacing
+// their profile
+type OrganizerProfileRequest struct {
+	Slug          string `json:"slug"`
+	DisplayName   string `json:"display_name"`
+	Bio           string `json:"bio"`
+	WebsiteURL    string `json:"website_url"`
+	PayoutAddress string `json:"payout_address"`
+}
+
+// OrganizerEvent summarises an event listed on its organizer's page
+type OrganizerEvent struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	PriceSats   int64     `json:"price_sats"`
+	PricingMode string    `json:"pricing_mode"`
+	Category    string    `json:"category"`
+}
+
+// OrganizerPage is an organizer's public profile with the events they have
+// on sale and how many of their events have taken place
+type OrganizerPage struct {
+	Slug           string           `json:"slug"`
+	DisplayName    string           `json:"display_name"`
+	Bio            string           `json:"bio"`
+	WebsiteURL     string           `json:"website_url"`
+	UpcomingEvents []OrganizerEvent `json:"upcoming_events"`
+	PastEventCount int              `json:"past_event_count"`
+}
+
+// SitemapEntry is an event page listed in the sitemap, with when it last
+// changed
+type SitemapEntry struct {
+	EventID   int       `db:"id"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ShortLink is a short code redirecting to an event page (/e/{code}) or a
+// ticket (/t/{code}). Exactly one of EventID and TicketID is set. Custom
+// links carry a code an admin picked; the rest were generated, one per
+// event or ticket.
+type ShortLink struct {
+	ID            int        `json:"id" db:"id"`
+	Code          string     `json:"code" db:"code"`
+	URL           string     `json:"url" db:"-"`
+	EventID       *int       `json:"event_id,omitempty" db:"event_id"`
+	TicketID      *int       `json:"ticket_id,omitempty" db:"ticket_id"`
+	Custom        bool       `json:"custom" db:"custom"`
+	Clicks        int        `json:"clicks" db:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at" db:"last_clicked_at"`
+	CreatedBy     *int       `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ShortLinkRequest represents a request to give an event a custom short
+// link, or to change a link's code
+type ShortLinkRequest struct {
+	Code string `json:"code"`
+}
+
+// Affiliate is a user who earns a commission, in basis points, on the
+// sales they refer: orders placed with their code as the ref. Inactive
+// affiliates keep what they earned but refer nothing new.
+type Affiliate struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Code        string    `json:"code" db:"code"`
+	BasisPoints int64     `json:"basis_points" db:"basis_points"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// PayoutAddress is the Lightning Address or UMA address commission is
+	// sent to; empty until set
+	PayoutAddress string `json:"payout_address" db:"payout_address" class:"pii"`
+}
+
+// AffiliateRequest represents an admin enrolling a user as an affiliate or
+// changing one. Fields left out keep their value; new affiliates get a
+// generated code and the default rate.
+type AffiliateRequest struct {
+	UserID        int     `json:"user_id"`
+	Code          *string `json:"code"`
+	BasisPoints   *int64  `json:"basis_points"`
+	Active        *bool   `json:"active"`
+	PayoutAddress *string `json:"payout_address"`
+}
+
+// AffiliateReport is an affiliate with their referral link and results:
+// the orders they referred, the paid tickets in them, and the commission
+// accrued in the ledger, paid out and still owed
+type AffiliateReport struct {
+	Affiliate
+	UserName       string `json:"user_name" db:"user_name"`
+	Link           string `json:"link" db:"-"`
+	ReferredOrders int    `json:"referred_orders" db:"referred_orders"`
+	TicketsSold    int    `json:"tickets_sold" db:"tickets_sold"`
+	AccruedSats    int64  `json:"accrued_sats" db:"-"`
+	PaidSats       int64  `json:"paid_sats" db:"-"`
+	BalanceSats    int64  `json:"balance_sats" db:"-"`
+}
+
+// Gift statuses. A gift is scheduled until its claim link is sent,
+// delivered until the recipient accepts it, then claimed.
+const (
+	GiftScheduled = "scheduled"
+	GiftDelivered = "delivered"
+	GiftClaimed   = "claimed"
+)
+
+// TicketGift is a ticket bought for someone else. The buyer owns the
+// ticket until the recipient accepts it from the claim link sent at
+// DeliverAt. Only the hash of the link's one-time secret is stored.
+type TicketGift struct {
+	ID                  int        `json:"id" db:"id"`
+	TicketID            int        `json:"ticket_id" db:"ticket_id"`
+	SenderID            int        `json:"sender_id" db:"sender_id"`
+	RecipientEmail      string     `json:"recipient_email,omitempty" db:"recipient_email" class:"pii"`
+	RecipientUMAAddress string     `json:"recipient_uma_address,omitempty" db:"recipient_uma_address" class:"pii"`
+	Message             string     `json:"message,omitempty" db:"message" class:"pii"`
+	DeliverAt           time.Time  `json:"deliver_at" db:"deliver_at"`
+	Status              string     `json:"status" db:"status"`
+	SecretHash          *string    `json:"-" db:"secret_hash" class:"secret"`
+	RecipientID         *int       `json:"recipient_id,omitempty" db:"recipient_id"`
+	DeliveredAt         *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	ClaimedAt           *time.Time `json:"claimed_at,omitempty" db:"claimed_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
+// GiftRequest makes a purchase a gift to the recipient with the email or
+// UMA address given, delivered at DeliverAt or as soon as it is paid
+type GiftRequest struct {
+	RecipientEmail      string     `json:"recipient_email"`
+	RecipientUMAAddress string     `json:"recipient_uma_address"`
+	Message             string     `json:"message"`
+	DeliverAt           *time.Time `json:"deliver_at"`
+}
+
+// GiftPreview is what a gift's claim link shows before it is accepted
+type GiftPreview struct {
+	Status     string    `json:"status"`
+	SenderName string    `json:"sender_name"`
+	Message    string    `json:"message,omitempty"`
+	EventID    int       `json:"event_id"`
+	EventTitle string    `json:"event_title"`
+	StartTime  time.Time `json:"start_time"`
+}
+
+// ClaimGiftRequest represents a request to accept a gift with the token
+// from its claim link
+type ClaimGiftRequest struct {
+	Token string `json:"token"`
+}
+
+// CheckInBucket counts the tickets first admitted in one interval
+type CheckInBucket struct {
+	Start    time.Time `json:"start"`
+	Admitted int       `json:"admitted"`
+}
+
+// CheckInStats is the door dashboard of an event: tickets admitted so far,
+// paid tickets still to arrive, admissions per interval and recent scans
+type CheckInStats struct {
+	EventID     int             `json:"event_id"`
+	Admitted    int             `json:"admitted"`
+	Remaining   int             `json:"remaining"`
+	Interval    string          `json:"interval"`
+	Rate        []CheckInBucket `json:"rate"`
+	RecentScans []TicketScan    `json:"recent_scans"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
 // CreateEventRequest represents a request to create an event
 type CreateEventRequest struct {
 	Title       string    `json:"title"`
@@ -149,6 +1968,26 @@ type CreateEventRequest struct {
 	Capacity    int       `json:"capacity"`
 	PriceSats   int64     `json:"price_sats"`
 	StreamURL   string    `json:"stream_url"`
+	// PricingMode defaults to fixed; MinPriceSats applies to pay what you want
+	PricingMode  string `json:"pricing_mode,omitempty"`
+	MinPriceSats int64  `json:"min_price_sats,omitempty"`
+	OrganizerID  *int   `json:"organizer_id,omitempty"`
+
+	TaxBasisPoints  int64  `json:"tax_basis_points,omitempty"`
+	TaxInclusive    bool   `json:"tax_inclusive,omitempty"`
+	TaxJurisdiction string `json:"tax_jurisdiction,omitempty"`
+
+	IsPrivate bool `json:"is_private,omitempty"`
+
+	MinAge       int    `json:"min_age,omitempty"`
+	TermsVersion string `json:"terms_version,omitempty"`
+	TermsURL     string `json:"terms_url,omitempty"`
+
+	Category string `json:"category,omitempty"`
+
+	InvoiceExpirySeconds int `json:"invoice_expiry_seconds,omitempty"`
+
+	OneTicketPerUser bool `json:"one_ticket_per_user,omitempty"`
 }
 
 // UpdateEventRequest represents a request to update an event
@@ -161,6 +2000,221 @@ type UpdateEventRequest struct {
 	PriceSats   *int64     `json:"price_sats,omitempty"`
 	StreamURL   *string    `json:"stream_url,omitempty"`
 	IsActive    *bool      `json:"is_active,omitempty"`
+
+	PricingMode  *string `json:"pricing_mode,omitempty"`
+	MinPriceSats *int64  `json:"min_price_sats,omitempty"`
+	// OrganizerID of 0 removes the event's organizer
+	OrganizerID *int `json:"organizer_id,omitempty"`
+
+	TaxBasisPoints  *int64  `json:"tax_basis_points,omitempty"`
+	TaxInclusive    *bool   `json:"tax_inclusive,omitempty"`
+	TaxJurisdiction *string `json:"tax_jurisdiction,omitempty"`
+
+	IsPrivate *bool `json:"is_private,omitempty"`
+
+	MinAge       *int    `json:"min_age,omitempty"`
+	TermsVersion *string `json:"terms_version,omitempty"`
+	TermsURL     *string `json:"terms_url,omitempty"`
+
+	Category *string `json:"category,omitempty"`
+
+	// InvoiceExpirySeconds of 0 restores the default expiry
+	InvoiceExpirySeconds *int `json:"invoice_expiry_seconds,omitempty"`
+
+	OneTicketPerUser *bool `json:"one_ticket_per_user,omitempty"`
+
+	// CapacityMode decides what happens when Capacity is below the seats
+	// already held by sold and pending tickets: CapacityModeStrict (the
+	// default) refuses the change, CapacityModeRefundNewest cancels the
+	// newest tickets until the rest fit.
+	CapacityMode string `json:"capacity_mode,omitempty"`
+}
+
+// Capacity modes of UpdateEventRequest
+const (
+	CapacityModeStrict       = "strict"
+	CapacityModeRefundNewest = "refund_newest"
+)
+
+// CapacityConflict details a capacity change refused because sold and
+// pending tickets hold more seats than it leaves. Excess is how many
+// tickets would have to go; Disputed tickets are never cancelled to make
+// room.
+type CapacityConflict struct {
+	RequestedCapacity int `json:"requested_capacity"`
+	Sold              int `json:"sold"`
+	Pending           int `json:"pending"`
+	Disputed          int `json:"disputed"`
+	Excess            int `json:"excess"`
+}
+
+// UpdatedEvent is an updated event with the tickets cancelled to fit a
+// reduced capacity and, after a price change, the unpaid tickets that kept
+// their price and those repriced
+type UpdatedEvent struct {
+	*Event
+	CancelledTicketIDs []int `json:"cancelled_ticket_ids,omitempty"`
+	LockedTicketIDs    []int `json:"locked_ticket_ids,omitempty"`
+	RepricedTicketIDs  []int `json:"repriced_ticket_ids,omitempty"`
+}
+
+// RescheduleEventRequest represents a request to move an event to new
+// times. When RefundUntil is set, buyers who cannot attend may ask for a
+// refund until then.
+type RescheduleEventRequest struct {
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     time.Time  `json:"end_time"`
+	Reason      string     `json:"reason"`
+	RefundUntil *time.Time `json:"refund_until,omitempty"`
+}
+
+// CancelEventRequest represents a request to cancel an event and refund
+// its tickets
+type CancelEventRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RescheduledEvent is a rescheduled event with the reschedule recorded and
+// the number of ticket holders notified
+type RescheduledEvent struct {
+	Event      *Event           `json:"event"`
+	Reschedule *EventReschedule `json:"reschedule"`
+	Notified   int              `json:"notified"`
+}
+
+// SetOrganizerFeeRequest represents a request to override an organizer's
+// platform fee
+type SetOrganizerFeeRequest struct {
+	BasisPoints int64 `json:"basis_points"`
+	FixedSats   int64 `json:"fixed_sats"`
+}
+
+// RecordRefundRequest represents a request to book the refund of a
+// payment in the ledger
+type RecordRefundRequest struct {
+	PaymentID int    `json:"payment_id"`
+	Reference string `json:"reference"`
+}
+
+// RecordPayoutRequest represents a request to book a payout to an
+// organizer, or an affiliate's commission, in the ledger
+type RecordPayoutRequest struct {
+	OrganizerID int    `json:"organizer_id"`
+	AffiliateID int    `json:"affiliate_id"`
+	AmountSats  int64  `json:"amount_sats"`
+	Reference   string `json:"reference"`
+}
+
+// SendPayoutRequest represents a request to pay an organizer, or an
+// affiliate's commission, over Lightning and book it in the ledger.
+// Destination overrides the payee's payout address: a Lightning Address,
+// a UMA address or a bolt11 invoice for exactly AmountSats.
+type SendPayoutRequest struct {
+	OrganizerID int    `json:"organizer_id"`
+	AffiliateID int    `json:"affiliate_id"`
+	AmountSats  int64  `json:"amount_sats"`
+	Destination string `json:"destination"`
+}
+
+// SendRefundRequest represents a request to send the refund owed for a
+// payment over Lightning and book it in the ledger. Destination overrides
+// the address the ticket was bought from: a Lightning Address, a UMA
+// address or a bolt11 invoice for exactly the refund owed.
+type SendRefundRequest struct {
+	PaymentID   int    `json:"payment_id"`
+	Destination string `json:"destination"`
+}
+
+// OpenDisputeRequest represents a request to open a dispute on a payment
+type OpenDisputeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ResolveDisputeRequest represents a request to close a dispute as won or
+// lost
+type ResolveDisputeRequest struct {
+	Outcome    string `json:"outcome"`
+	Resolution string `json:"resolution"`
+}
+
+// CreateAddOnRequest represents a request to add an add-on to an event
+type CreateAddOnRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PriceSats   int64  `json:"price_sats"`
+	IsActive    *bool  `json:"is_active,omitempty"` // defaults to true
+	// FlexCutoffHours, when given, makes it a flex add-on
+	FlexCutoffHours *int `json:"flex_cutoff_hours,omitempty"`
+}
+
+// UpdateAddOnRequest represents a request to update an add-on
+type UpdateAddOnRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	PriceSats   *int64  `json:"price_sats,omitempty"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+	// FlexCutoffHours changes a flex add-on's cutoff for tickets sold from
+	// then on
+	FlexCutoffHours *int `json:"flex_cutoff_hours,omitempty"`
+}
+
+// CreatePricingRuleRequest represents a request to add a pricing rule to an
+// event; exactly one of SoldPercent and StartsAt is given
+type CreatePricingRuleRequest struct {
+	Name        string     `json:"name"`
+	PriceSats   int64      `json:"price_sats"`
+	SoldPercent *int       `json:"sold_percent,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+}
+
+// CreateFormFieldRequest represents a request to add a form field to an event
+type CreateFormFieldRequest struct {
+	Label     string   `json:"label"`
+	FieldType string   `json:"field_type"`
+	Options   []string `json:"options,omitempty"` // select fields only
+	Required  bool     `json:"required"`
+	Position  int      `json:"position"`
+}
+
+// UpdateFormFieldRequest represents a request to update a form field
+type UpdateFormFieldRequest struct {
+	Label     *string   `json:"label,omitempty"`
+	FieldType *string   `json:"field_type,omitempty"`
+	Options   *[]string `json:"options,omitempty"`
+	Required  *bool     `json:"required,omitempty"`
+	Position  *int      `json:"position,omitempty"`
+}
+
+// CreateAccessCodeRequest represents a request to add an invite code to a
+// private event. A code is generated when none is given.
+type CreateAccessCodeRequest struct {
+	Code      string     `json:"code,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AllowlistRequest represents a request to add emails or UMA addresses to a
+// private event's allowlist
+type AllowlistRequest struct {
+	Entries []string `json:"entries"`
+}
+
+// NotificationTemplateRequest represents a request to save a new version of
+// a notification template
+type NotificationTemplateRequest struct {
+	OrganizerID *int   `json:"organizer_id,omitempty"`
+	Kind        string `json:"kind"`
+	Locale      string `json:"locale"`
+	Subject     string `json:"subject"`
+	TextBody    string `json:"text_body"`
+	HTMLBody    string `json:"html_body,omitempty"`
+}
+
+// EventTranslationRequest represents a request to set an event's title and
+// description in one locale
+type EventTranslationRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
 }
 
 // CreateUserRequest represents a request to create a user
@@ -168,6 +2222,8 @@ type CreateUserRequest struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	// Locale defaults to the language negotiated from Accept-Language
+	Locale string `json:"locale,omitempty"`
 }
 
 // LoginRequest represents a login request
@@ -176,6 +2232,76 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// EmailChange is the outstanding verification link of a user's pending
+// email. Only the hash of the link's one-time secret is stored.
+type EmailChange struct {
+	UserID     int       `json:"user_id" db:"user_id"`
+	SecretHash string    `json:"-" db:"secret_hash" class:"secret"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// ConfirmEmailRequest confirms a pending email with the token from the
+// verification link sent to it
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ClaimAccountRequest sets the password, and optionally the name, of a
+// guest account with the token from its claim link
+type ClaimAccountRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+	Name     string `json:"name,omitempty"`
+}
+
+// ResendClaimRequest asks for a new claim link for a guest account
+type ResendClaimRequest struct {
+	Email string `json:"email"`
+}
+
+// ChangePasswordRequest replaces the password of the authenticated user,
+// who proves they know the current one
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Login methods a user can have
+const (
+	CredentialPassword  = "password"
+	CredentialLNURLAuth = "lnurl-auth"
+)
+
+// Credential is a way a user can log in
+type Credential struct {
+	Method string `json:"method"`
+	// Removable is false for the user's last login method
+	Removable bool `json:"removable"`
+}
+
+// LNURLAuthLogin is a login challenge for a Lightning wallet. The browser
+// shows LNURL as a QR code and polls with Session until the wallet signs.
+type LNURLAuthLogin struct {
+	LNURL     string    `json:"lnurl"`
+	Callback  string    `json:"callback"`
+	K1        string    `json:"k1"`
+	Session   string    `json:"session"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LNURLAuthPollRequest asks whether the wallet has signed the challenge of
+// a session
+type LNURLAuthPollRequest struct {
+	Session string `json:"session"`
+}
+
+// LNURLResponse is the status wallets expect from LNURL callbacks (LUD-03)
+type LNURLResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // PurchaseTicketRequest represents a request to purchase a ticket
 type PurchaseTicketRequest struct {
 	EventID    int    `json:"event_id"`
@@ -190,13 +2316,80 @@ type AuthResponse struct {
 	User  *User  `json:"user"`
 }
 
-// ErrorResponse represents an error response
+// ImpersonateRequest starts a support session as another user. Reason is
+// recorded in the audit log and the token.
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonationResponse is a short-lived, read-only token for acting as
+// User, with the banner to show while it is in use
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	User      *User     `json:"user"`
+	Banner    string    `json:"banner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ErrorResponse represents an error response. ErrorCode is set for errors
+// clients are expected to handle, such as ErrorCodePaymentBackendUnavailable.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Code      int    `json:"code"`
+	ErrorCode string `json:"error_code,omitempty"`
+	// Details describes the error for clients that handle ErrorCode, such
+	// as the CapacityConflict of ErrorCodeCapacityConflict
+	Details interface{} `json:"details,omitempty"`
 }
 
+// ErrorCodePaymentBackendUnavailable is returned with 503 while the
+// Lightning backend is failing and calls to it are failed fast
+const ErrorCodePaymentBackendUnavailable = "PAYMENT_BACKEND_UNAVAILABLE"
+
+// ErrorCodeAttestationRequired is returned with 400 when a purchase is
+// missing the event's age confirmation or terms acceptance
+const ErrorCodeAttestationRequired = "ATTESTATION_REQUIRED"
+
+// ErrorCodeAccountExists is returned with 409 when a guest checkout uses
+// the email of a registered account, whose owner must log in to buy
+const ErrorCodeAccountExists = "ACCOUNT_EXISTS"
+
+// ErrorCodeAlreadyHasTicket is returned with 409 when a buyer already holds
+// a ticket for an event that sells one ticket per user
+const ErrorCodeAlreadyHasTicket = "ALREADY_HAS_TICKET"
+
+// ErrorCodeWeakPassword is returned with 400 when a new password fails the
+// strength checks
+const ErrorCodeWeakPassword = "WEAK_PASSWORD"
+
+// ErrorCodeLastCredential is returned with 409 when removing a user's only
+// login method, which would lock them out
+const ErrorCodeLastCredential = "LAST_CREDENTIAL"
+
+// ErrorCodeImpersonationReadOnly is returned with 403 when an admin
+// impersonating a user tries to change something
+const ErrorCodeImpersonationReadOnly = "IMPERSONATION_READ_ONLY"
+
+// ErrorCodeCapacityConflict is returned with 409 when an event's capacity
+// would fall below its sold and pending tickets, with a CapacityConflict
+// in the details
+const ErrorCodeCapacityConflict = "CAPACITY_CONFLICT"
+
+// ErrorCodeCashuAmount is returned with 400 when a Cashu token does not
+// hold exactly what paying the invoice takes, with a CashuAmount in the
+// details
+const ErrorCodeCashuAmount = "CASHU_AMOUNT"
+
+// ErrorCodeCashuMintRefused is returned with 400 when the mint refuses to
+// melt a Cashu token, such as for spent proofs, with the mint's error code
+// and detail in the details
+const ErrorCodeCashuMintRefused = "CASHU_MINT_REFUSED"
+
+// ErrorCodeReadOnly is returned with 503 for writes while the service runs
+// read-only because the database schema does not match it
+const ErrorCodeReadOnly = "READ_ONLY"
+
 // SuccessResponse represents a success response
 type SuccessResponse struct {
 	Message string      `json:"message"`
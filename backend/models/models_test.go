@@ -0,0 +1,129 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaskNWCConnectionURI(t *testing.T) {
+	uri := "nostr+walletconnect://b889ff5b1513b641e2a139f661a661364979c5beee91842f8f0ef42ab558e9d4?relay=wss%3A%2F%2Frelay.example.com&secret=71a8c14c1407c113601079c4302dab36460f0ccd0ad506f1f2dc73b5100e4f3c"
+
+	masked := MaskNWCConnectionURI(uri)
+	if strings.Contains(masked, "71a8c14c") {
+		t.Fatalf("Expected secret to be masked, got %s", masked)
+	}
+	if masked != "nostr+walletconnect://b889ff5b...?relay=wss://relay.example.com&secret=***" {
+		t.Errorf("Unexpected masked URI: %s", masked)
+	}
+
+	if got := MaskNWCConnectionURI("not a uri"); got != "***" {
+		t.Errorf("Expected unparseable URI to be fully masked, got %s", got)
+	}
+}
+
+func TestMaskPII(t *testing.T) {
+	tests := map[string]string{
+		"alice@example.com": "a***@example.com",
+		"$alice@vasp.com":   "$a***@vasp.com",
+		"Alice Smith":       "A***",
+		"":                  "",
+		"@example.com":      "***",
+	}
+	for input, want := range tests {
+		if got := MaskPII(input); got != want {
+			t.Errorf("MaskPII(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestEventTax(t *testing.T) {
+	tests := []struct {
+		name                 string
+		event                Event
+		subtotal             int64
+		wantTaxable, wantTax int64
+	}{
+		{"no tax", Event{}, 1000, 1000, 0},
+		{"exclusive", Event{TaxBasisPoints: 1900}, 1000, 1000, 190},
+		{"exclusive rounds half up", Event{TaxBasisPoints: 750}, 1002, 1002, 75}, // 75.15
+		{"inclusive", Event{TaxBasisPoints: 1900, TaxInclusive: true}, 1190, 1000, 190},
+		{"inclusive rounds", Event{TaxBasisPoints: 2000, TaxInclusive: true}, 1000, 833, 167}, // 833.33 net
+		{"free", Event{TaxBasisPoints: 1900}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		taxable, tax := tt.event.Tax(tt.subtotal)
+		if taxable != tt.wantTaxable || tax != tt.wantTax {
+			t.Errorf("%s: Tax(%d) = %d, %d; want %d, %d", tt.name, tt.subtotal, taxable, tax, tt.wantTaxable, tt.wantTax)
+		}
+	}
+}
+
+func TestOrderStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{"paid", "paid"}, "paid"},
+		{[]string{"review"}, "review"},
+		{[]string{"paid", "pending"}, "pending"},
+		{[]string{"failed", "review"}, "pending"},
+		{[]string{"paid", "disputed"}, "partially_paid"},
+		{[]string{"failed", "expired"}, "cancelled"},
+		{nil, "pending"},
+	}
+	for _, tt := range tests {
+		if got := OrderStatus(tt.statuses); got != tt.want {
+			t.Errorf("OrderStatus(%v) = %q, want %q", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		parse  func(string) (string, bool)
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{ParseTicketStatus, "paid", TicketPaid, true},
+		{ParseTicketStatus, " Review ", TicketReview, true},
+		{ParseTicketStatus, "CANCELED", TicketCancelled, true},
+		{ParseTicketStatus, "refunded", "", false},
+		{ParseTicketStatus, "", "", false},
+		{ParsePaymentStatus, "Expired", PaymentExpired, true},
+		{ParsePaymentStatus, "canceled", PaymentCancelled, true},
+		{ParsePaymentStatus, "disputed", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := tt.parse(tt.input); got != tt.want || ok != tt.wantOK {
+			t.Errorf("parse(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestEventUMAIdentifier(t *testing.T) {
+	if got := EventUMAAddress(42, "tickets.example.com"); got != "$event-42@tickets.example.com" {
+		t.Errorf("EventUMAAddress(42) = %q", got)
+	}
+	tests := []struct {
+		input  string
+		want   int
+		wantOK bool
+	}{
+		{EventUMAIdentifier(42), 42, true},
+		{"$event-7", 7, true},
+		{"event", 0, false},
+		{"event-", 0, false},
+		{"event-0", 0, false},
+		{"event-007", 0, false},
+		{"event-+7", 0, false},
+		{"event--7", 0, false},
+		{"event-7x", 0, false},
+		{"tickets", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseEventUMAIdentifier(tt.input); got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseEventUMAIdentifier(%q) = %d, %v; want %d, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestSigningKey(t *testing.T) {
+	// The example from AWS's Signature Version 4 documentation
+	got := hex.EncodeToString(signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	if want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"; got != want {
+		t.Errorf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestPut(t *testing.T) {
+	var received *http.Request
+	var body string
+	status := http.StatusOK
+	store := &S3{
+		Endpoint:        "https://s3.eu-west-1.amazonaws.com/",
+		Region:          "eu-west-1",
+		Bucket:          "tickets-backups",
+		Prefix:          "nightly/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Now:             func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) },
+		Client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			received = req
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("<Error>AccessDenied</Error>"))}, nil
+		})},
+	}
+
+	location, err := store.Put(context.Background(), "tickets-20261016T120000Z.dump", strings.NewReader("dump"), 4)
+	if err != nil {
+		t.Fatal("Put failed:", err)
+	}
+	if location != "s3://tickets-backups/nightly/tickets-20261016T120000Z.dump" {
+		t.Errorf("Unexpected location %s", location)
+	}
+	if received.Method != http.MethodPut || received.URL.String() != "https://s3.eu-west-1.amazonaws.com/tickets-backups/nightly/tickets-20261016T120000Z.dump" {
+		t.Errorf("Unexpected request %s %s", received.Method, received.URL)
+	}
+	if body != "dump" || received.ContentLength != 4 {
+		t.Errorf("Expected the 4 byte body, got %q (%d)", body, received.ContentLength)
+	}
+	// Computed independently of this package
+	want := "AWS4-HMAC-SHA256 Credential=AKID/20261016/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=8dc91e2848ca555abc65c8d921baee5bb415ca4151a1bba0014dcdd46f90dda6"
+	if got := received.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+
+	status = http.StatusForbidden
+	if _, err := store.Put(context.Background(), "again.dump", strings.NewReader("dump"), 4); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected the error response in the error, got %v", err)
+	}
+}
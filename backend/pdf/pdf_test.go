@@ -0,0 +1,44 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPageBytes(t *testing.T) {
+	var page Page
+	page.Text(56, 700, 12, true, "Receipt R-000001")
+	page.TextRight(539, 680, 10, false, "Café (VIP) \\ 2 × 🎟")
+	page.Line(56, 539, 670)
+	out := page.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("Expected PDF header and trailer, got %q", out)
+	}
+	if !bytes.Contains(out, []byte(`(Caf\351 \(VIP\) \\ 2 \327 ?) Tj`)) {
+		t.Errorf("Expected escaped Latin-1 text, got %q", out)
+	}
+
+	// Every xref entry must point at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("Missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 6 {
+		t.Fatalf("Expected 6 objects, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := strconv.Itoa(i+1) + " 0 obj"
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, out[offset:offset+len(want)], want)
+		}
+	}
+}
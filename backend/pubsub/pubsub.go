@@ -0,0 +1,150 @@
+// Package pubsub relays state change notifications between backend
+// instances over Postgres LISTEN/NOTIFY, so a client connected to one
+// instance hears about a payment processed on another.
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres notification channel all instances listen on
+const Channel = "tickets_by_uma_changes"
+
+// Topics of notifications
+const (
+	// TopicTicket carries ticket IDs whose state changed. Payment status
+	// changes are published on their ticket, which is updated with them.
+	TopicTicket = "ticket"
+	// TopicCheckIn carries event IDs whose door scans changed
+	TopicCheckIn = "checkin"
+	// TopicAdminNotification carries the IDs of new admin notifications
+	TopicAdminNotification = "admin_notification"
+)
+
+const (
+	// Reconnect backoff of the listener connection
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+
+	// pingInterval checks an idle listener connection is still alive
+	pingInterval = 90 * time.Second
+)
+
+// message is the NOTIFY payload. Origin identifies the publishing instance
+// so it does not handle its own notifications twice.
+type message struct {
+	Origin string `json:"origin"`
+	Topic  string `json:"topic"`
+	ID     int    `json:"id"`
+}
+
+// Postgres is a notification bus shared by every instance connected to the
+// same database. Delivery is best effort: notifications sent while an
+// instance is reconnecting are lost, so subscribers must not rely on them
+// alone.
+type Postgres struct {
+	db     *sqlx.DB
+	dsn    string
+	origin string
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]func(id int)
+}
+
+// NewPostgres creates a bus that publishes through db and listens on a
+// dedicated connection to dsn once Run is called.
+func NewPostgres(db *sqlx.DB, dsn string, logger *slog.Logger) *Postgres {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	return &Postgres{
+		db:       db,
+		dsn:      dsn,
+		origin:   hex.EncodeToString(origin),
+		logger:   logger,
+		handlers: make(map[string][]func(id int)),
+	}
+}
+
+// Publish tells the other instances that the topic's entity id changed.
+// Failures are logged rather than returned: the change itself is already
+// stored and other instances catch up when their waiters time out.
+func (p *Postgres) Publish(topic string, id int) {
+	payload, _ := json.Marshal(message{Origin: p.origin, Topic: topic, ID: id})
+	if _, err := p.db.Exec(`SELECT pg_notify($1, $2)`, Channel, string(payload)); err != nil {
+		p.logger.Error("Failed to publish change notification", "topic", topic, "id", id, "error", err)
+	}
+}
+
+// Subscribe registers fn for changes published on topic by other
+// instances.
+func (p *Postgres) Subscribe(topic string, fn func(id int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[topic] = append(p.handlers[topic], fn)
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with
+// backoff when the connection drops.
+func (p *Postgres) Run(ctx context.Context) error {
+	listener := pq.NewListener(p.dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			p.logger.Warn("Change notification listener disconnected", "error", err)
+		case pq.ListenerEventReconnected:
+			p.logger.Info("Change notification listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			p.logger.Error("Change notification listener failed to connect", "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// nil after a reconnect; anything sent meanwhile is lost
+			if notification != nil {
+				p.deliver(notification.Extra)
+			}
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// deliver hands a notification payload from another instance to the
+// topic's subscribers
+func (p *Postgres) deliver(payload string) {
+	var msg message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		p.logger.Warn("Ignoring malformed change notification", "payload", payload, "error", err)
+		return
+	}
+	if msg.Origin == p.origin {
+		return
+	}
+
+	p.mu.RLock()
+	handlers := p.handlers[msg.Topic]
+	p.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(msg.ID)
+	}
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+type accountClaimRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewAccountClaimRepository creates the account claim repository. clk
+// stamps claim links and decides whether they have expired.
+func NewAccountClaimRepository(db *sqlx.DB, clk clock.Clock) AccountClaimRepository {
+	return &accountClaimRepository{db: db, clock: clk}
+}
+
+func (r *accountClaimRepository) Offer(userID int, secretHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO account_claims (user_id, secret_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_hash = $2, expires_at = $3, created_at = $4`
+
+	_, err := r.db.Exec(query, userID, secretHash, expiresAt, r.clock.Now())
+	return err
+}
+
+func (r *accountClaimRepository) Claim(secretHash, passwordHash, name string) (*models.User, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Deleting the link as it is matched makes it single-use
+	now := r.clock.Now()
+	var userID int
+	err = tx.QueryRowx(`DELETE FROM account_claims WHERE secret_hash = $1 AND expires_at > $2 RETURNING user_id`,
+		secretHash, now).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE users
+		SET password_hash = $1, name = COALESCE(NULLIF($2, ''), name), is_guest = false, updated_at = $3
+		WHERE id = $4
+		` + userTable.returning()
+
+	user := &models.User{}
+	if err := tx.QueryRowx(query, passwordHash, name, now, userID).StructScan(user); err != nil {
+		return nil, translateError(err)
+	}
+	return user, tx.Commit()
+}
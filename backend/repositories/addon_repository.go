@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	addOnTable       = newTable[models.AddOn]("event_addons")
+	ticketAddOnTable = newTable[models.TicketAddOn]("ticket_addons")
+)
+
+type addOnRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewAddOnRepository creates the add-on repository. clk stamps created_at and
+// updated_at.
+func NewAddOnRepository(db *sqlx.DB, clk clock.Clock) AddOnRepository {
+	return &addOnRepository{db: db, clock: clk}
+}
+
+func (r *addOnRepository) Create(addOn *models.AddOn) error {
+	query := `
+		INSERT INTO event_addons (event_id, name, description, price_sats, is_active, flex_cutoff_hours, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	now := r.clock.Now()
+	return r.db.QueryRowx(query,
+		addOn.EventID, addOn.Name, addOn.Description, addOn.PriceSats, addOn.IsActive, addOn.FlexCutoffHours, now, now).StructScan(addOn)
+}
+
+func (r *addOnRepository) GetByID(id int) (*models.AddOn, error) {
+	return addOnTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *addOnRepository) GetByEventID(eventID int, activeOnly bool) ([]models.AddOn, error) {
+	query := `
+		WHERE event_id = $1 AND (is_active = true OR $2 = false)
+		ORDER BY name, id`
+	return addOnTable.list(r.db, query, eventID, activeOnly)
+}
+
+func (r *addOnRepository) Update(addOn *models.AddOn) error {
+	query := `
+		UPDATE event_addons
+		SET name = $1, description = $2, price_sats = $3, is_active = $4, flex_cutoff_hours = $5, updated_at = $6
+		WHERE id = $7`
+
+	addOn.UpdatedAt = r.clock.Now()
+	_, err := r.db.Exec(query,
+		addOn.Name, addOn.Description, addOn.PriceSats, addOn.IsActive, addOn.FlexCutoffHours, addOn.UpdatedAt, addOn.ID)
+	return err
+}
+
+func (r *addOnRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM event_addons WHERE id = $1`, id)
+	return err
+}
+
+func (r *addOnRepository) CreateLineItems(items []models.TicketAddOn) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ticket_addons (ticket_id, addon_id, name, quantity, unit_price_sats, flex_cutoff_hours, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	now := r.clock.Now()
+	for i := range items {
+		item := &items[i]
+		err := tx.QueryRowx(query,
+			item.TicketID, item.AddOnID, item.Name, item.Quantity, item.UnitPriceSats, item.FlexCutoffHours, now).StructScan(item)
+		if err != nil {
+			return fmt.Errorf("failed to store add-on line item %q: %w", item.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *addOnRepository) GetLineItems(ticketID int) ([]models.TicketAddOn, error) {
+	return ticketAddOnTable.list(r.db, `WHERE ticket_id = $1 ORDER BY id`, ticketID)
+}
+
+func (r *addOnRepository) UseFlex(itemID int) (time.Time, error) {
+	now := r.clock.Now()
+	result, err := r.db.Exec(`
+		UPDATE ticket_addons SET flex_used_at = $1
+		WHERE id = $2 AND flex_cutoff_hours IS NOT NULL AND flex_used_at IS NULL`,
+		now, itemID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return time.Time{}, err
+	} else if n == 0 {
+		return time.Time{}, ErrNotFound
+	}
+	return now, nil
+}
+
+func (r *addOnRepository) FlexUptake(filter models.FlexFilter) ([]models.FlexUptake, error) {
+	conditions := []string{"((t.payment_status = 'paid' AND t.is_comp = false) OR a.flex_used_at IS NOT NULL)"}
+	var args []interface{}
+	if filter.EventID != 0 {
+		args = append(args, filter.EventID)
+		conditions = append(conditions, fmt.Sprintf("e.id = $%d", len(args)))
+	}
+	if filter.OrganizerID != 0 {
+		args = append(args, filter.OrganizerID)
+		conditions = append(conditions, fmt.Sprintf("e.organizer_id = $%d", len(args)))
+	}
+
+	// A ticket has at most one flex line item, so the join keeps one row
+	// per ticket
+	query := `
+		SELECT e.id AS event_id, e.title AS event_title,
+		       COUNT(t.id) AS tickets_sold,
+		       COUNT(a.id) AS flex_tickets,
+		       CAST(COALESCE(SUM(CASE WHEN t.payment_status = 'paid' THEN a.unit_price_sats * a.quantity ELSE 0 END), 0) AS BIGINT) AS flex_revenue_sats,
+		       COUNT(a.flex_used_at) AS flex_cancellations
+		FROM tickets t
+		JOIN events e ON e.id = t.event_id
+		LEFT JOIN ticket_addons a ON a.ticket_id = t.id AND a.flex_cutoff_hours IS NOT NULL
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY e.id, e.title
+		ORDER BY e.id`
+
+	uptake := []models.FlexUptake{}
+	if err := r.db.Select(&uptake, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range uptake {
+		uptake[i].UptakeRate = float64(uptake[i].FlexTickets) / float64(uptake[i].TicketsSold)
+	}
+	return uptake, nil
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var adminNotificationTable = newTable[models.AdminNotification]("admin_notifications")
+
+type adminNotificationRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewAdminNotificationRepository creates the admin notification repository.
+// clk stamps created_at and read_at.
+func NewAdminNotificationRepository(db *sqlx.DB, clk clock.Clock) AdminNotificationRepository {
+	return &adminNotificationRepository{db: db, clock: clk}
+}
+
+func (r *adminNotificationRepository) Create(notification *models.AdminNotification) error {
+	query := `
+		INSERT INTO admin_notifications (kind, title, message, event_id, entity_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		` + adminNotificationTable.returning()
+
+	return r.db.QueryRowx(query, notification.Kind, notification.Title, notification.Message,
+		notification.EventID, notification.EntityID, r.clock.Now()).StructScan(notification)
+}
+
+func (r *adminNotificationRepository) List(unreadOnly bool, limit, offset int) ([]models.AdminNotification, error) {
+	if unreadOnly {
+		return adminNotificationTable.list(r.db, `WHERE read_at IS NULL ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	}
+	return adminNotificationTable.list(r.db, `ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+func (r *adminNotificationRepository) ListAfter(afterID, limit int) ([]models.AdminNotification, error) {
+	return adminNotificationTable.list(r.db, `WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+}
+
+func (r *adminNotificationRepository) CountUnread() (int, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT COUNT(*) FROM admin_notifications WHERE read_at IS NULL`)
+	return count, err
+}
+
+func (r *adminNotificationRepository) MarkRead(id, adminID int) (*models.AdminNotification, error) {
+	query := `
+		UPDATE admin_notifications
+		SET read_by = CASE WHEN read_at IS NULL THEN $1 ELSE read_by END,
+			read_at = COALESCE(read_at, $2)
+		WHERE id = $3
+		` + adminNotificationTable.returning()
+
+	notification := &models.AdminNotification{}
+	if err := r.db.QueryRowx(query, adminID, r.clock.Now(), id).StructScan(notification); err != nil {
+		return nil, translateError(err)
+	}
+	return notification, nil
+}
+
+func (r *adminNotificationRepository) MarkAllRead(adminID int) (int, error) {
+	result, err := r.db.Exec(`UPDATE admin_notifications SET read_at = $1, read_by = $2 WHERE read_at IS NULL`, r.clock.Now(), adminID)
+	if err != nil {
+		return 0, err
+	}
+	marked, err := result.RowsAffected()
+	return int(marked), err
+}
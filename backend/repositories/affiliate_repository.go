@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var affiliateTable = newTable[models.Affiliate]("affiliates")
+
+type affiliateRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewAffiliateRepository creates the affiliate repository. clk stamps
+// affiliates as they are saved.
+func NewAffiliateRepository(db *sqlx.DB, clk clock.Clock) AffiliateRepository {
+	return &affiliateRepository{db: db, clock: clk}
+}
+
+func (r *affiliateRepository) Create(affiliate *models.Affiliate) error {
+	// A user already enrolled, or a taken code, inserts nothing
+	query := `
+		INSERT INTO affiliates (user_id, code, basis_points, active, payout_address, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT DO NOTHING
+		` + affiliateTable.returning()
+
+	err := r.db.QueryRowx(query,
+		affiliate.UserID, affiliate.Code, affiliate.BasisPoints, affiliate.Active, affiliate.PayoutAddress, r.clock.Now()).StructScan(affiliate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *affiliateRepository) GetByID(id int) (*models.Affiliate, error) {
+	return affiliateTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *affiliateRepository) GetByUserID(userID int) (*models.Affiliate, error) {
+	return affiliateTable.get(r.db, `WHERE user_id = $1`, userID)
+}
+
+func (r *affiliateRepository) GetByCode(code string) (*models.Affiliate, error) {
+	return affiliateTable.get(r.db, `WHERE code = $1`, code)
+}
+
+func (r *affiliateRepository) Update(affiliate *models.Affiliate) error {
+	query := `
+		UPDATE affiliates SET code = $1, basis_points = $2, active = $3, payout_address = $4, updated_at = $5
+		WHERE id = $6 AND NOT EXISTS (SELECT 1 FROM affiliates WHERE code = $1 AND id <> $6)
+		` + affiliateTable.returning()
+
+	err := translateError(r.db.QueryRowx(query,
+		affiliate.Code, affiliate.BasisPoints, affiliate.Active, affiliate.PayoutAddress, r.clock.Now(), affiliate.ID).StructScan(affiliate))
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, err := r.GetByID(affiliate.ID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+var affiliateReportQuery = `
+	SELECT ` + affiliateTable.selectAll("a") + `, u.name AS user_name,
+	       (SELECT COUNT(*) FROM orders o WHERE o.affiliate_id = a.id) AS referred_orders,
+	       (SELECT COUNT(*) FROM orders o JOIN tickets t ON t.order_id = o.id
+	        WHERE o.affiliate_id = a.id AND t.payment_status = 'paid') AS tickets_sold
+	FROM affiliates a
+	JOIN users u ON u.id = a.user_id`
+
+func (r *affiliateRepository) List() ([]models.AffiliateReport, error) {
+	reports := []models.AffiliateReport{}
+	err := r.db.Select(&reports, affiliateReportQuery+` ORDER BY a.id`)
+	return reports, err
+}
+
+func (r *affiliateRepository) GetReport(id int) (*models.AffiliateReport, error) {
+	report := &models.AffiliateReport{}
+	if err := r.db.Get(report, affiliateReportQuery+` WHERE a.id = $1`, id); err != nil {
+		return nil, translateError(err)
+	}
+	return report, nil
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/encryption"
+	"tickets-by-uma/models"
+)
+
+var alertConnectorTable = newTable[models.AlertConnector]("alert_connectors")
+
+type alertConnectorRepository struct {
+	db     *sqlx.DB
+	cipher fieldCipher
+	clock  clock.Clock
+}
+
+// NewAlertConnectorRepository creates the alert connector repository.
+// Targets are encrypted at rest with keyring; a nil keyring stores them
+// as-is. clk stamps created_at and updated_at.
+func NewAlertConnectorRepository(db *sqlx.DB, keyring *encryption.Keyring, clk clock.Clock) AlertConnectorRepository {
+	return &alertConnectorRepository{db: db, cipher: fieldCipher{keyring: keyring}, clock: clk}
+}
+
+func (r *alertConnectorRepository) Create(connector *models.AlertConnector) error {
+	query := `
+		INSERT INTO alert_connectors (name, kind, target, chat_id, events, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id, last_error, last_sent_at, created_at, updated_at`
+
+	target, err := r.cipher.seal(connector.Target)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowx(query, connector.Name, connector.Kind, target, connector.ChatID,
+		connector.Events, connector.IsActive, r.clock.Now()).StructScan(connector)
+}
+
+func (r *alertConnectorRepository) GetByID(id int) (*models.AlertConnector, error) {
+	connector, err := alertConnectorTable.get(r.db, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return connector, r.open(connector)
+}
+
+func (r *alertConnectorRepository) List(activeOnly bool) ([]models.AlertConnector, error) {
+	connectors, err := alertConnectorTable.list(r.db, `WHERE (is_active OR NOT $1) ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	for i := range connectors {
+		if err := r.open(&connectors[i]); err != nil {
+			return nil, err
+		}
+	}
+	return connectors, nil
+}
+
+func (r *alertConnectorRepository) Update(connector *models.AlertConnector) error {
+	query := `
+		UPDATE alert_connectors
+		SET name = $1, target = $2, chat_id = $3, events = $4, is_active = $5, updated_at = $6
+		WHERE id = $7`
+
+	target, err := r.cipher.seal(connector.Target)
+	if err != nil {
+		return err
+	}
+	connector.UpdatedAt = r.clock.Now()
+	result, err := r.db.Exec(query, connector.Name, target, connector.ChatID, connector.Events,
+		connector.IsActive, connector.UpdatedAt, connector.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *alertConnectorRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM alert_connectors WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *alertConnectorRepository) RecordDelivery(id int, sentAt time.Time, lastError string) error {
+	query := `
+		UPDATE alert_connectors
+		SET last_sent_at = CASE WHEN $1 = '' THEN $2 ELSE last_sent_at END, last_error = $1
+		WHERE id = $3`
+
+	result, err := r.db.Exec(query, lastError, sentAt, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *alertConnectorRepository) open(connector *models.AlertConnector) error {
+	target, err := r.cipher.open(connector.Target)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt target of alert connector %d: %w", connector.ID, err)
+	}
+	connector.Target = target
+	return nil
+}
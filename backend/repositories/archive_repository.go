@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+// archiveChild is a table archived along with its parent, whose rows
+// reference the parent through column
+type archiveChild struct {
+	table  string
+	column string
+}
+
+// Rows archived with a payment, ticket or event. Order matters where one
+// child references another: rows are snapshotted before the rows they
+// reference are deleted, so ON DELETE SET NULL does not blank them first.
+var (
+	paymentArchiveChildren = []archiveChild{
+		{"payment_line_items", "payment_id"},
+		{"receipts", "payment_id"},
+		{"cashu_redemptions", "payment_id"},
+		{"disputes", "payment_id"},
+	}
+	ticketArchiveChildren = []archiveChild{
+		{"ticket_addons", "ticket_id"},
+		{"ticket_answers", "ticket_id"},
+		{"ticket_gifts", "ticket_id"},
+		{"purchase_attestations", "ticket_id"},
+		{"short_links", "ticket_id"},
+		{"uma_request_invoices", "ticket_id"},
+	}
+	eventArchiveChildren = []archiveChild{
+		{"orders", "event_id"},
+		{"event_addons", "event_id"},
+		{"event_form_fields", "event_id"},
+		{"event_access_codes", "event_id"},
+		{"event_allowlist", "event_id"},
+		{"event_translations", "event_id"},
+		{"event_reschedules", "event_id"},
+		{"event_cancellations", "event_id"},
+		{"event_price_changes", "event_id"},
+		{"pricing_rules", "event_id"},
+		{"ticket_scans", "event_id"},
+		{"scanner_devices", "event_id"},
+		{"inventory_holds", "event_id"},
+		{"short_links", "event_id"},
+		{"fraud_flags", "event_id"},
+		{"uma_request_invoices", "event_id"},
+		{"purchase_attestations", "event_id"},
+	}
+)
+
+var archivedRecordTable = newTable[models.ArchivedRecord]("archived_records")
+
+type archiveRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewArchiveRepository creates the archive repository. clk stamps when rows
+// were archived.
+func NewArchiveRepository(db *sqlx.DB, clk clock.Clock) ArchiveRepository {
+	return &archiveRepository{db: db, clock: clk}
+}
+
+// archiveCandidate is a row due for archiving and the event it belongs to
+type archiveCandidate struct {
+	ID      int `db:"id"`
+	EventID int `db:"event_id"`
+}
+
+func (r *archiveRepository) ArchivePayments(cutoff time.Time, limit int) (int, error) {
+	query := `
+		SELECT p.id, t.event_id
+		FROM payments p
+		JOIN tickets t ON t.id = p.ticket_id
+		WHERE p.status <> 'pending' AND p.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM disputes d WHERE d.payment_id = p.id AND d.status = 'open')
+			AND (p.status <> 'paid' OR EXISTS (SELECT 1 FROM ledger_entries e WHERE e.kind = 'sale' AND e.payment_id = p.id))
+		ORDER BY p.id
+		LIMIT $2`
+	return r.archive(query, cutoff, limit, func(tx *sqlx.Tx, row archiveCandidate) error {
+		for _, child := range paymentArchiveChildren {
+			if err := r.archiveRows(tx, row.EventID, child.table, child.column+` = $1`, row.ID); err != nil {
+				return err
+			}
+		}
+		return r.archiveRows(tx, row.EventID, "payments", `id = $1`, row.ID)
+	})
+}
+
+func (r *archiveRepository) ArchiveTickets(cutoff time.Time, limit int) (int, error) {
+	query := `
+		SELECT t.id, t.event_id
+		FROM tickets t
+		JOIN events e ON e.id = t.event_id
+		WHERE e.end_time < $1 AND t.payment_status NOT IN ('pending', 'review', 'disputed')
+			AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.ticket_id = t.id)
+		ORDER BY t.id
+		LIMIT $2`
+	return r.archive(query, cutoff, limit, func(tx *sqlx.Tx, row archiveCandidate) error {
+		for _, child := range ticketArchiveChildren {
+			if err := r.archiveRows(tx, row.EventID, child.table, child.column+` = $1`, row.ID); err != nil {
+				return err
+			}
+		}
+		// A wallet binding has no history worth keeping; scans and flags
+		// stay with the event
+		statements := []string{
+			`DELETE FROM wallet_claims WHERE ticket_id = $1`,
+			`UPDATE ticket_scans SET ticket_id = NULL WHERE ticket_id = $1`,
+			`UPDATE fraud_flags SET ticket_id = NULL WHERE ticket_id = $1`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement, row.ID); err != nil {
+				return err
+			}
+		}
+		return r.archiveRows(tx, row.EventID, "tickets", `id = $1`, row.ID)
+	})
+}
+
+func (r *archiveRepository) ArchiveEvents(cutoff time.Time, limit int) (int, error) {
+	query := `
+		SELECT e.id, e.id AS event_id
+		FROM events e
+		WHERE e.end_time < $1
+			AND NOT EXISTS (SELECT 1 FROM tickets t WHERE t.event_id = e.id)
+		ORDER BY e.id
+		LIMIT $2`
+	return r.archive(query, cutoff, limit, func(tx *sqlx.Tx, row archiveCandidate) error {
+		for _, child := range eventArchiveChildren {
+			if err := r.archiveRows(tx, row.EventID, child.table, child.column+` = $1`, row.ID); err != nil {
+				return err
+			}
+		}
+		// Related event picks are curation, not history
+		if _, err := tx.Exec(`DELETE FROM event_relations WHERE event_id = $1 OR related_event_id = $1`, row.ID); err != nil {
+			return err
+		}
+		return r.archiveRows(tx, row.EventID, "events", `id = $1`, row.ID)
+	})
+}
+
+// archive runs move in its own transaction for each candidate query
+// selects. A row another instance archived meanwhile is skipped.
+func (r *archiveRepository) archive(query string, cutoff time.Time, limit int, move func(tx *sqlx.Tx, row archiveCandidate) error) (int, error) {
+	candidates := []archiveCandidate{}
+	if err := r.db.Select(&candidates, query, cutoff, limit); err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	for _, row := range candidates {
+		err := r.inTx(func(tx *sqlx.Tx) error { return move(tx, row) })
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return archived, err
+		}
+		archived++
+	}
+	return archived, nil
+}
+
+func (r *archiveRepository) inTx(fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return translateError(err)
+	}
+	return tx.Commit()
+}
+
+// archiveRows snapshots the rows of table matching where into
+// archived_records under eventID, then deletes them
+func (r *archiveRepository) archiveRows(tx *sqlx.Tx, eventID int, table, where string, args ...interface{}) error {
+	rows, err := tx.Queryx(`SELECT * FROM `+table+` WHERE `+where, args...)
+	if err != nil {
+		return err
+	}
+	var snapshots []models.ArchivedRow
+	for rows.Next() {
+		row := models.ArchivedRow{}
+		if err := rows.MapScan(row); err != nil {
+			rows.Close()
+			return err
+		}
+		// Drivers return text as bytes, which would marshal as base64
+		for name, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[name] = string(b)
+			}
+		}
+		snapshots = append(snapshots, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := r.clock.Now()
+	for _, row := range snapshots {
+		query := `
+			INSERT INTO archived_records (table_name, record_id, event_id, data, archived_at)
+			VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.Exec(query, table, row["id"], eventID, row, now); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM `+table+` WHERE `+where, args...)
+	return err
+}
+
+func (r *archiveRepository) Get(table string, recordID int) (*models.ArchivedRecord, error) {
+	return archivedRecordTable.get(r.db, `WHERE table_name = $1 AND record_id = $2`, table, recordID)
+}
+
+func (r *archiveRepository) ListByEvent(eventID int) ([]models.ArchivedRecord, error) {
+	return archivedRecordTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
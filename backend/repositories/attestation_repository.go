@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var purchaseAttestationTable = newTable[models.PurchaseAttestation]("purchase_attestations")
+
+type attestationRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewAttestationRepository creates the purchase attestation repository. clk
+// stamps attested_at.
+func NewAttestationRepository(db *sqlx.DB, clk clock.Clock) AttestationRepository {
+	return &attestationRepository{db: db, clock: clk}
+}
+
+func (r *attestationRepository) Create(attestation *models.PurchaseAttestation) error {
+	query := `
+		INSERT INTO purchase_attestations (ticket_id, event_id, min_age, terms_version, client_ip, user_agent, attested_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (ticket_id) DO NOTHING
+		` + purchaseAttestationTable.returning()
+
+	err := r.db.QueryRowx(query,
+		attestation.TicketID, attestation.EventID, attestation.MinAge, attestation.TermsVersion,
+		attestation.ClientIP, attestation.UserAgent, r.clock.Now()).StructScan(attestation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *attestationRepository) GetByTicketID(ticketID int) (*models.PurchaseAttestation, error) {
+	return purchaseAttestationTable.get(r.db, `WHERE ticket_id = $1`, ticketID)
+}
+
+func (r *attestationRepository) GetByEventID(eventID int) ([]models.PurchaseAttestation, error) {
+	return purchaseAttestationTable.list(r.db, `WHERE event_id = $1 ORDER BY ticket_id`, eventID)
+}
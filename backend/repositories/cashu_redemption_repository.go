@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var cashuRedemptionTable = newTable[models.CashuRedemption]("cashu_redemptions")
+
+type cashuRedemptionRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewCashuRedemptionRepository creates the Cashu redemption repository.
+// clk stamps redemptions as they are created and updated.
+func NewCashuRedemptionRepository(db *sqlx.DB, clk clock.Clock) CashuRedemptionRepository {
+	return &cashuRedemptionRepository{db: db, clock: clk}
+}
+
+func (r *cashuRedemptionRepository) Create(redemption *models.CashuRedemption) error {
+	// A payment with a pending or paid redemption inserts nothing
+	query := `
+		INSERT INTO cashu_redemptions (payment_id, mint_url, quote_id, amount_sats, fee_reserve_sats, token_sats, state, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT DO NOTHING
+		` + cashuRedemptionTable.returning()
+
+	err := r.db.QueryRowx(query, redemption.PaymentID, redemption.MintURL, redemption.QuoteID, redemption.AmountSats,
+		redemption.FeeReserveSats, redemption.TokenSats, models.CashuPending, r.clock.Now()).StructScan(redemption)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return translateError(err)
+}
+
+func (r *cashuRedemptionRepository) Finish(id int, state string, preimage *string, errMsg string) error {
+	result, err := r.db.Exec(`
+		UPDATE cashu_redemptions SET state = $1, preimage = $2, error = $3, updated_at = $4
+		WHERE id = $5 AND state = $6`,
+		state, preimage, errMsg, r.clock.Now(), id, models.CashuPending)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *cashuRedemptionRepository) List(limit, offset int) ([]models.CashuRedemption, error) {
+	query := `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+	return cashuRedemptionTable.list(r.db, query, limit, offset)
+}
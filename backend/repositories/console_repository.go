@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/models"
+)
+
+// consoleTimeout bounds how long a console query may run
+const consoleTimeout = 10 * time.Second
+
+// consoleView is a console view with its query, which binds the view's
+// params in order as $1, $2, ... and leaves the LIMIT to Run. Queries
+// never select encrypted columns, which would come back sealed.
+type consoleView struct {
+	models.ConsoleView
+	query string
+}
+
+var consoleViews = []consoleView{
+	{
+		ConsoleView: models.ConsoleView{
+			Name:        "attendees",
+			Description: "Tickets of an event with their buyers",
+			Params:      []models.ConsoleParam{{Name: "event_id", Type: models.ConsoleInt, Required: true}},
+		},
+		query: `
+			SELECT t.id AS ticket_id, t.ticket_code, t.payment_status, t.is_comp, t.amount_sats, t.paid_at,
+			       u.id AS user_id, u.email, u.name
+			FROM tickets t
+			JOIN users u ON u.id = t.user_id
+			WHERE t.event_id = $1
+			ORDER BY t.id`,
+	},
+	{
+		ConsoleView: models.ConsoleView{
+			Name:        "payments_summary",
+			Description: "Payment count and amount by status, for one event or all",
+			Params:      []models.ConsoleParam{{Name: "event_id", Type: models.ConsoleInt}},
+		},
+		query: `
+			SELECT p.status, COUNT(*) AS payments, CAST(COALESCE(SUM(p.amount_sats), 0) AS BIGINT) AS amount_sats
+			FROM payments p
+			JOIN tickets t ON t.id = p.ticket_id
+			WHERE ($1 = 0 OR t.event_id = $1)
+			GROUP BY p.status
+			ORDER BY p.status`,
+	},
+	{
+		ConsoleView: models.ConsoleView{
+			Name:        "user_lookup",
+			Description: "A user by ID or email (case-insensitive), with their ticket counts",
+			Params: []models.ConsoleParam{
+				{Name: "user_id", Type: models.ConsoleInt},
+				{Name: "email", Type: models.ConsoleString},
+			},
+		},
+		query: `
+			SELECT u.id AS user_id, u.email, u.name, u.is_guest, u.created_at,
+			       COUNT(t.id) AS tickets,
+			       COUNT(CASE WHEN t.payment_status = 'paid' THEN 1 END) AS paid_tickets
+			FROM users u
+			LEFT JOIN tickets t ON t.user_id = u.id
+			WHERE u.id = $1 OR LOWER(u.email) = LOWER($2)
+			GROUP BY u.id, u.email, u.name, u.is_guest, u.created_at
+			ORDER BY u.id`,
+	},
+}
+
+// ConsoleViews lists the views the admin console can run
+func ConsoleViews() []models.ConsoleView {
+	views := make([]models.ConsoleView, len(consoleViews))
+	for i, view := range consoleViews {
+		views[i] = view.ConsoleView
+	}
+	return views
+}
+
+func findConsoleView(name string) (consoleView, bool) {
+	for _, view := range consoleViews {
+		if view.Name == name {
+			return view, true
+		}
+	}
+	return consoleView{}, false
+}
+
+type consoleRepository struct {
+	db *sqlx.DB
+}
+
+// NewConsoleRepository creates the admin console repository
+func NewConsoleRepository(db *sqlx.DB) ConsoleRepository {
+	return &consoleRepository{db: db}
+}
+
+func (r *consoleRepository) Run(view string, args []interface{}, limit int) (*models.ConsoleResult, error) {
+	v, ok := findConsoleView(view)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), consoleTimeout)
+	defer cancel()
+
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// The queries only read, and the database holds them to it: Postgres
+	// refuses writes in a read-only transaction, SQLite ignores that
+	// option but refuses them on a query_only connection
+	if r.db.DriverName() == "sqlite3" {
+		if _, err := conn.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+			return nil, err
+		}
+		defer conn.ExecContext(context.Background(), `PRAGMA query_only = OFF`)
+	}
+	tx, err := conn.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// One row past the cap tells whether the result was truncated
+	query := v.query + fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+	rows, err := tx.QueryxContext(ctx, query, append(args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ConsoleResult{View: v.Name, Columns: columns, Rows: []map[string]interface{}{}}
+	for rows.Next() {
+		if len(result.Rows) == limit {
+			result.Truncated = true
+			break
+		}
+		row := map[string]interface{}{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		// Drivers return text as bytes, which would marshal as base64
+		for name, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[name] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var disputeTable = newTable[models.Dispute]("disputes")
+
+type disputeRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewDisputeRepository creates the dispute repository. clk stamps opened_at
+// and resolved_at.
+func NewDisputeRepository(db *sqlx.DB, clk clock.Clock) DisputeRepository {
+	return &disputeRepository{db: db, clock: clk}
+}
+
+func (r *disputeRepository) Create(dispute *models.Dispute) error {
+	// Checked here so the common case is ErrConflict rather than a unique
+	// index violation; the partial unique index settles races
+	query := `
+		INSERT INTO disputes (payment_id, status, reason, resolution, opened_by, opened_at)
+		SELECT $1, 'open', $2, '', $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM disputes WHERE payment_id = $1 AND status = 'open')
+		` + disputeTable.returning()
+
+	err := r.db.QueryRowx(query, dispute.PaymentID, dispute.Reason, dispute.OpenedBy, r.clock.Now()).StructScan(dispute)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *disputeRepository) GetByID(id int) (*models.Dispute, error) {
+	return disputeTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *disputeRepository) List(status string, limit, offset int) ([]models.Dispute, error) {
+	if status != "" {
+		return disputeTable.list(r.db, `WHERE status = $1 ORDER BY opened_at DESC, id DESC LIMIT $2 OFFSET $3`, status, limit, offset)
+	}
+	return disputeTable.list(r.db, `ORDER BY opened_at DESC, id DESC LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+func (r *disputeRepository) Resolve(dispute *models.Dispute) error {
+	query := `
+		UPDATE disputes
+		SET status = $1, resolution = $2, resolved_by = $3, resolved_at = $4
+		WHERE id = $5 AND status = 'open'
+		` + disputeTable.returning()
+
+	err := r.db.QueryRowx(query, dispute.Status, dispute.Resolution, dispute.ResolvedBy, r.clock.Now(), dispute.ID).StructScan(dispute)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, getErr := r.GetByID(dispute.ID); getErr != nil {
+			return getErr
+		}
+		return ErrConflict
+	}
+	return err
+}
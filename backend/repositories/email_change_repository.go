@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+type emailChangeRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEmailChangeRepository creates the email change repository. clk
+// stamps verification links and decides whether they have expired.
+func NewEmailChangeRepository(db *sqlx.DB, clk clock.Clock) EmailChangeRepository {
+	return &emailChangeRepository{db: db, clock: clk}
+}
+
+func (r *emailChangeRepository) Request(userID int, newEmail, secretHash string, expiresAt time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now()
+	result, err := tx.Exec(`UPDATE users SET pending_email = $1, updated_at = $2 WHERE id = $3`, newEmail, now, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrNotFound
+	}
+
+	query := `
+		INSERT INTO email_changes (user_id, secret_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_hash = $2, expires_at = $3, created_at = $4`
+	if _, err := tx.Exec(query, userID, secretHash, expiresAt, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *emailChangeRepository) Confirm(secretHash string) (*models.User, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Deleting the link as it is matched makes it single-use
+	now := r.clock.Now()
+	var userID int
+	err = tx.QueryRowx(`DELETE FROM email_changes WHERE secret_hash = $1 AND expires_at > $2 RETURNING user_id`,
+		secretHash, now).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked here so a taken address is ErrConflict rather than a unique
+	// violation
+	var taken bool
+	err = tx.Get(&taken, `
+		SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE email = (SELECT pending_email FROM users WHERE id = $1) AND id <> $1
+		)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrConflict
+	}
+
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, updated_at = $1
+		WHERE id = $2 AND pending_email IS NOT NULL
+		` + userTable.returning()
+
+	user := &models.User{}
+	if err := tx.QueryRowx(query, now, userID).StructScan(user); err != nil {
+		return nil, translateError(err)
+	}
+	return user, tx.Commit()
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a write conflicts with existing data,
+	// such as a duplicate unique key
+	ErrConflict = errors.New("record conflicts with existing data")
+	// ErrInvalidStatus is returned when a ticket or payment is saved with a
+	// status outside models.TicketStatuses or models.PaymentStatuses
+	ErrInvalidStatus = errors.New("invalid status")
+	// ErrSoldOut is returned when creating a ticket that needs a seat on an
+	// event with none left
+	ErrSoldOut = errors.New("event is sold out")
+)
+
+// pgUniqueViolation is the Postgres SQLSTATE of a unique constraint
+// violation
+const pgUniqueViolation = "23505"
+
+// translateError maps driver errors to the repository's sentinel errors so
+// callers can use errors.Is without depending on database/sql. Unique
+// constraint violations of either database become ErrConflict, wrapping
+// the driver's error so the constraint shows in logs.
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is a Postgres or SQLite unique
+// constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
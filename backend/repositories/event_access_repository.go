@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	eventAccessCodeTable     = newTable[models.EventAccessCode]("event_access_codes")
+	eventAllowlistEntryTable = newTable[models.EventAllowlistEntry]("event_allowlist")
+)
+
+type eventAccessRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEventAccessRepository creates the event access repository. clk stamps
+// created_at and decides whether codes have expired.
+func NewEventAccessRepository(db *sqlx.DB, clk clock.Clock) EventAccessRepository {
+	return &eventAccessRepository{db: db, clock: clk}
+}
+
+func (r *eventAccessRepository) CreateCode(code *models.EventAccessCode) error {
+	// Checked here so the common case is ErrConflict rather than a unique
+	// constraint violation
+	query := `
+		INSERT INTO event_access_codes (event_id, code, max_uses, uses, expires_at, created_at)
+		SELECT $1, $2, $3, 0, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM event_access_codes WHERE event_id = $1 AND code = $2)
+		` + eventAccessCodeTable.returning()
+
+	err := r.db.QueryRowx(query, code.EventID, code.Code, code.MaxUses, code.ExpiresAt, r.clock.Now()).StructScan(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *eventAccessRepository) GetCode(id int) (*models.EventAccessCode, error) {
+	return eventAccessCodeTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *eventAccessRepository) GetCodes(eventID int) ([]models.EventAccessCode, error) {
+	return eventAccessCodeTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
+
+func (r *eventAccessRepository) DeleteCode(id int) error {
+	_, err := r.db.Exec(`DELETE FROM event_access_codes WHERE id = $1`, id)
+	return err
+}
+
+func (r *eventAccessRepository) RedeemCode(eventID int, code string) error {
+	// Checking the limits and counting the use in one statement keeps
+	// concurrent purchases from overspending a code
+	query := `
+		UPDATE event_access_codes
+		SET uses = uses + 1
+		WHERE event_id = $1 AND code = $2
+		  AND (max_uses IS NULL OR uses < max_uses)
+		  AND (expires_at IS NULL OR expires_at > $3)
+		RETURNING id`
+
+	var id int
+	err := r.db.Get(&id, query, eventID, code, r.clock.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		err = r.db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM event_access_codes WHERE event_id = $1 AND code = $2)`, eventID, code)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *eventAccessRepository) ReleaseCode(eventID int, code string) error {
+	_, err := r.db.Exec(`UPDATE event_access_codes SET uses = uses - 1 WHERE event_id = $1 AND code = $2 AND uses > 0`, eventID, code)
+	return err
+}
+
+func (r *eventAccessRepository) AddToAllowlist(eventID int, entries []string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO event_allowlist (event_id, entry, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, entry) DO NOTHING`
+
+	now := r.clock.Now()
+	for _, entry := range entries {
+		if _, err := tx.Exec(query, eventID, entry, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *eventAccessRepository) GetAllowlistEntry(id int) (*models.EventAllowlistEntry, error) {
+	return eventAllowlistEntryTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *eventAccessRepository) GetAllowlist(eventID int) ([]models.EventAllowlistEntry, error) {
+	return eventAllowlistEntryTable.list(r.db, `WHERE event_id = $1 ORDER BY entry`, eventID)
+}
+
+func (r *eventAccessRepository) DeleteAllowlistEntry(id int) error {
+	_, err := r.db.Exec(`DELETE FROM event_allowlist WHERE id = $1`, id)
+	return err
+}
+
+func (r *eventAccessRepository) IsAllowlisted(eventID, userID int, umaAddress string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM event_allowlist
+			WHERE event_id = $1
+			  AND (entry = $2 OR entry IN (SELECT LOWER(email) FROM users WHERE id = $3))
+		)`
+
+	var allowed bool
+	err := r.db.Get(&allowed, query, eventID, strings.ToLower(strings.TrimSpace(umaAddress)), userID)
+	return allowed, err
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var eventCancellationTable = newTable[models.EventCancellation]("event_cancellations")
+
+type eventCancellationRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEventCancellationRepository creates the event cancellation repository.
+// clk stamps created_at and updated_at.
+func NewEventCancellationRepository(db *sqlx.DB, clk clock.Clock) EventCancellationRepository {
+	return &eventCancellationRepository{db: db, clock: clk}
+}
+
+func (r *eventCancellationRepository) Create(cancellation *models.EventCancellation) error {
+	if cancellation.Status == "" {
+		cancellation.Status = models.CancellationRunning
+	}
+	// Checked here so the common case is ErrConflict rather than a unique
+	// constraint violation
+	query := `
+		INSERT INTO event_cancellations (event_id, reason, status, total, cancelled_by, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, $6
+		WHERE NOT EXISTS (SELECT 1 FROM event_cancellations WHERE event_id = $1)
+		` + eventCancellationTable.returning()
+
+	err := r.db.QueryRowx(query,
+		cancellation.EventID, cancellation.Reason, cancellation.Status, cancellation.Total,
+		cancellation.CancelledBy, r.clock.Now()).StructScan(cancellation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *eventCancellationRepository) GetByEventID(eventID int) (*models.EventCancellation, error) {
+	return eventCancellationTable.get(r.db, `WHERE event_id = $1`, eventID)
+}
+
+func (r *eventCancellationRepository) ListRunning() ([]models.EventCancellation, error) {
+	return eventCancellationTable.list(r.db, `WHERE status = $1 ORDER BY id`, models.CancellationRunning)
+}
+
+func (r *eventCancellationRepository) Update(cancellation *models.EventCancellation) error {
+	query := `
+		UPDATE event_cancellations
+		SET status = $1, total = $2, refunded = $3, voided = $4, failed = $5, last_error = $6, completed_at = $7, updated_at = $8
+		WHERE id = $9
+		` + eventCancellationTable.returning()
+
+	err := r.db.QueryRowx(query,
+		cancellation.Status, cancellation.Total, cancellation.Refunded, cancellation.Voided, cancellation.Failed,
+		cancellation.LastError, cancellation.CompletedAt, r.clock.Now(), cancellation.ID).StructScan(cancellation)
+	return translateError(err)
+}
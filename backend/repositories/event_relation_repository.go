@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+// onSaleCondition is the condition on events e that are sold publicly at $2
+const onSaleCondition = `e.is_active = true AND e.is_private = false AND e.end_time > $2`
+
+type eventRelationRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEventRelationRepository creates the event relation repository. clk
+// stamps picked relations.
+func NewEventRelationRepository(db *sqlx.DB, clk clock.Clock) EventRelationRepository {
+	return &eventRelationRepository{db: db, clock: clk}
+}
+
+func (r *eventRelationRepository) Set(eventID int, relatedIDs []int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM event_relations WHERE event_id = $1`, eventID); err != nil {
+		return err
+	}
+
+	// Selecting from events inserts nothing for an unknown event
+	query := `
+		INSERT INTO event_relations (event_id, related_event_id, position, created_at)
+		SELECT CAST($1 AS INTEGER), CAST($2 AS INTEGER), CAST($3 AS INTEGER), $4
+		FROM events WHERE id = $2`
+
+	now := r.clock.Now()
+	for position, relatedID := range relatedIDs {
+		result, err := tx.Exec(query, eventID, relatedID, position, now)
+		if err != nil {
+			return err
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+			if err != nil {
+				return err
+			}
+			return ErrNotFound
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *eventRelationRepository) GetRelatedIDs(eventID int) ([]int, error) {
+	ids := []int{}
+	query := `SELECT related_event_id FROM event_relations WHERE event_id = $1 ORDER BY position`
+	err := r.db.Select(&ids, query, eventID)
+	return ids, err
+}
+
+func (r *eventRelationRepository) GetCurated(eventID int, now time.Time) ([]models.Event, error) {
+	events := []models.Event{}
+	query := `
+		SELECT ` + eventTable.selectAll("e") + ` FROM event_relations er
+		JOIN events e ON e.id = er.related_event_id
+		WHERE er.event_id = $1 AND ` + onSaleCondition + `
+		ORDER BY er.position`
+	err := r.db.Select(&events, query, eventID, now)
+	return events, err
+}
+
+func (r *eventRelationRepository) GetSimilar(event *models.Event, now time.Time, limit int) ([]models.Event, error) {
+	events := []models.Event{}
+	query := `
+		SELECT ` + eventTable.selectAll("e") + ` FROM events e
+		WHERE e.id <> $1 AND ` + onSaleCondition + `
+		  AND ((e.category <> '' AND e.category = $3) OR e.organizer_id = $4)
+		ORDER BY e.start_time, e.id
+		LIMIT $5`
+	err := r.db.Select(&events, query, event.ID, now, event.Category, event.OrganizerID, limit)
+	return events, err
+}
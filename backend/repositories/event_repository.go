@@ -1,70 +1,98 @@
 package repositories
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"tickets-by-uma/encryption"
+	"tickets-by-uma/listquery"
 	"tickets-by-uma/models"
 )
 
+// EventListFields are the fields event lists can be sorted and filtered by
+var EventListFields = listquery.Fields{
+	"id":           {Column: "id", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"title":        {Column: "title", Kind: listquery.Text, Sortable: true, Filterable: true},
+	"category":     {Column: "category", Kind: listquery.Text, Sortable: true, Filterable: true},
+	"pricing_mode": {Column: "pricing_mode", Kind: listquery.Text, Filterable: true},
+	"start_time":   {Column: "start_time", Kind: listquery.Time, Sortable: true, Filterable: true},
+	"end_time":     {Column: "end_time", Kind: listquery.Time, Sortable: true, Filterable: true},
+	"price_sats":   {Column: "price_sats", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"capacity":     {Column: "capacity", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"min_age":      {Column: "min_age", Kind: listquery.Int, Filterable: true},
+	"created_at":   {Column: "created_at", Kind: listquery.Time, Sortable: true, Filterable: true},
+}
+
+var (
+	eventTable             = newTable[models.Event]("events")
+	umaRequestInvoiceTable = newTable[models.UMARequestInvoice]("uma_request_invoices")
+)
+
 type eventRepository struct {
 	db      *sqlx.DB
 	umaRepo UMARequestInvoiceRepository
 }
 
-func NewEventRepository(db *sqlx.DB) EventRepository {
+func NewEventRepository(db *sqlx.DB, piiKeyring *encryption.Keyring) EventRepository {
 	return &eventRepository{
 		db:      db,
-		umaRepo: NewUMARequestInvoiceRepository(db),
+		umaRepo: NewUMARequestInvoiceRepository(db, piiKeyring),
 	}
 }
 
 func (r *eventRepository) Create(event *models.Event) error {
 	query := `
-		INSERT INTO events (title, description, start_time, end_time, capacity, price_sats, stream_url, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO events (title, description, start_time, end_time, capacity, price_sats, stream_url, is_active, pricing_mode, min_price_sats, organizer_id,
+		                    tax_basis_points, tax_inclusive, tax_jurisdiction, is_private, min_age, terms_version, terms_url, category,
+		                    invoice_expiry_seconds, one_ticket_per_user, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
 		RETURNING id, created_at, updated_at`
 
+	if event.PricingMode == "" {
+		event.PricingMode = models.PricingFixed
+	}
 	now := time.Now()
 	return r.db.QueryRowx(query,
 		event.Title, event.Description, event.StartTime,
-		event.EndTime, event.Capacity, event.PriceSats, event.StreamURL, event.IsActive, now, now).StructScan(event)
+		event.EndTime, event.Capacity, event.PriceSats, event.StreamURL, event.IsActive,
+		event.PricingMode, event.MinPriceSats, event.OrganizerID,
+		event.TaxBasisPoints, event.TaxInclusive, event.TaxJurisdiction, event.IsPrivate,
+		event.MinAge, event.TermsVersion, event.TermsURL, event.Category,
+		event.InvoiceExpirySeconds, event.OneTicketPerUser, now, now).StructScan(event)
 }
 
 func (r *eventRepository) GetByID(id int) (*models.Event, error) {
-	event := &models.Event{}
-	query := `SELECT * FROM events WHERE id = $1`
-	err := r.db.Get(event, query, id)
+	return eventTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *eventRepository) GetByIDs(ids []int) (map[int]*models.Event, error) {
+	events, err := eventTable.byIDs(r.db, ids)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, err // Return the sql.ErrNoRows error instead of nil
-		}
 		return nil, err
 	}
-	return event, nil
+	byID := make(map[int]*models.Event, len(events))
+	for i := range events {
+		byID[events[i].ID] = &events[i]
+	}
+	return byID, nil
 }
 
 func (r *eventRepository) GetByIDWithUMAInvoice(id int) (*models.Event, error) {
-	event := &models.Event{}
-	query := `SELECT * FROM events WHERE id = $1`
-
-	err := r.db.Get(event, query, id)
+	event, err := eventTable.get(r.db, `WHERE id = $1`, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 
 	// Fetch UMA Request invoice for this event and populate the relationship
 	umaInvoice, err := r.umaRepo.GetByEventID(id)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		// Log error but don't fail the request - event will be returned without UMA invoice data
 		fmt.Printf("ERROR: Failed to fetch UMA invoice for event %d: %v\n", id, err)
-	} else if umaInvoice != nil {
+	} else if err == nil {
 		event.UMARequestInvoice = umaInvoice
 		fmt.Printf("SUCCESS: Loaded UMA invoice for event %d: %s\n", id, umaInvoice.InvoiceID)
 	} else {
@@ -75,16 +103,7 @@ func (r *eventRepository) GetByIDWithUMAInvoice(id int) (*models.Event, error) {
 }
 
 func (r *eventRepository) GetAll(limit, offset int) ([]models.Event, error) {
-	events := []models.Event{}
-	query := `
-		SELECT e.id, e.title, e.description, e.start_time, e.end_time, 
-		       e.capacity, e.price_sats, e.stream_url, e.is_active, 
-		       e.created_at, e.updated_at
-		FROM events e
-		ORDER BY e.start_time ASC 
-		LIMIT $1 OFFSET $2`
-
-	err := r.db.Select(&events, query, limit, offset)
+	events, err := eventTable.list(r.db, `ORDER BY start_time ASC LIMIT $1 OFFSET $2`, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -103,17 +122,17 @@ func (r *eventRepository) GetAll(limit, offset int) ([]models.Event, error) {
 }
 
 func (r *eventRepository) GetActive(limit, offset int) ([]models.Event, error) {
-	events := []models.Event{}
-	query := `
-		SELECT e.id, e.title, e.description, e.start_time, e.end_time, 
-		       e.capacity, e.price_sats, e.stream_url, e.is_active, 
-		       e.created_at, e.updated_at
-		FROM events e
-		WHERE e.is_active = true 
-		ORDER BY e.start_time ASC 
-		LIMIT $1 OFFSET $2`
+	return r.ListActive(listquery.Query{Limit: limit, Offset: offset})
+}
 
-	err := r.db.Select(&events, query, limit, offset)
+func (r *eventRepository) ListActive(list listquery.Query) ([]models.Event, error) {
+	where, args := list.Where(EventListFields, nil)
+	if where != "" {
+		where = " AND " + where
+	}
+	page, args := list.Page(args)
+	events, err := eventTable.list(r.db, `WHERE is_active = true AND is_private = false`+where+
+		` ORDER BY `+list.OrderBy(EventListFields, listquery.Order{Field: "start_time"})+page, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -135,13 +154,21 @@ func (r *eventRepository) Update(event *models.Event) error {
 	query := `
 		UPDATE events 
 		SET title = $1, description = $2, start_time = $3, end_time = $4, 
-		    capacity = $5, price_sats = $6, stream_url = $7, is_active = $8, updated_at = $9
-		WHERE id = $10`
+		    capacity = $5, price_sats = $6, stream_url = $7, is_active = $8,
+		    pricing_mode = $9, min_price_sats = $10, organizer_id = $11,
+		    tax_basis_points = $12, tax_inclusive = $13, tax_jurisdiction = $14, is_private = $15,
+		    min_age = $16, terms_version = $17, terms_url = $18, category = $19,
+		    invoice_expiry_seconds = $20, one_ticket_per_user = $21, updated_at = $22
+		WHERE id = $23`
 
 	event.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query,
 		event.Title, event.Description, event.StartTime, event.EndTime,
-		event.Capacity, event.PriceSats, event.StreamURL, event.IsActive, event.UpdatedAt, event.ID)
+		event.Capacity, event.PriceSats, event.StreamURL, event.IsActive,
+		event.PricingMode, event.MinPriceSats, event.OrganizerID,
+		event.TaxBasisPoints, event.TaxInclusive, event.TaxJurisdiction, event.IsPrivate,
+		event.MinAge, event.TermsVersion, event.TermsURL, event.Category,
+		event.InvoiceExpirySeconds, event.OneTicketPerUser, event.UpdatedAt, event.ID)
 	return err
 }
 
@@ -151,83 +178,172 @@ func (r *eventRepository) Delete(id int) error {
 	return err
 }
 
+// heldSeats is a subquery totalling the seats held for the event e by
+// unreleased inventory holds
+const heldSeats = `
+			SELECT CAST(COALESCE(SUM(h.seats), 0) AS INTEGER) FROM inventory_holds h
+			WHERE h.event_id = e.id AND h.released_at IS NULL`
+
+// availableSeats is a query for event $1's capacity less its paid, pending,
+// held and disputed tickets and the seats held for it, as GetAvailability
+// counts them
+const availableSeats = `
+		SELECT e.capacity
+			- (SELECT COUNT(*) FROM tickets t
+			   WHERE t.event_id = e.id AND t.payment_status IN ('paid', 'pending', 'review', 'disputed'))
+			- (` + heldSeats + `) AS available
+		FROM events e
+		WHERE e.id = $1`
+
 func (r *eventRepository) GetAvailableTicketCount(eventID int) (int, error) {
 	var count int
+	if err := r.db.Get(&count, availableSeats, eventID); err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// GetAvailability counts sold and pending tickets and held seats against
+// capacity in one query
+func (r *eventRepository) GetAvailability(eventID int) (*models.EventAvailability, error) {
+	var availability models.EventAvailability
 	query := `
-		SELECT (e.capacity - COALESCE(COUNT(t.id), 0)) as available
+		SELECT e.id AS event_id, e.capacity,
+			COUNT(CASE WHEN t.payment_status = 'paid' THEN 1 END) AS sold,
+			COUNT(CASE WHEN t.payment_status IN ('pending', 'review', 'disputed') THEN 1 END) AS pending,
+			(` + heldSeats + `) AS held
 		FROM events e
-		LEFT JOIN tickets t ON e.id = t.event_id AND t.payment_status = 'paid'
+		LEFT JOIN tickets t ON t.event_id = e.id
 		WHERE e.id = $1
-		GROUP BY e.capacity`
+		GROUP BY e.id, e.capacity`
 
-	err := r.db.Get(&count, query, eventID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// If no tickets sold, return full capacity
-			var capacity int
-			err = r.db.Get(&capacity, "SELECT capacity FROM events WHERE id = $1", eventID)
-			if err != nil {
-				return 0, err
-			}
-			return capacity, nil
-		}
-		return 0, err
+	if err := r.db.Get(&availability, query, eventID); err != nil {
+		return nil, translateError(err)
 	}
-	return count, nil
+	availability.Remaining = max(availability.Capacity-availability.Sold-availability.Pending-availability.Held, 0)
+	return &availability, nil
 }
 
+// UpdateCapacity sets an event's capacity unless sold and pending tickets
+// hold more seats, in which case it returns ErrConflict
 func (r *eventRepository) UpdateCapacity(eventID, newCapacity int) error {
-	query := `UPDATE events SET capacity = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, newCapacity, time.Now(), eventID)
-	return err
+	query := `
+		UPDATE events SET capacity = $1, updated_at = $2
+		WHERE id = $3 AND $1 >= (
+			SELECT COUNT(*) FROM tickets
+			WHERE event_id = $3 AND payment_status IN ('paid', 'pending', 'review', 'disputed'))`
+	result, err := r.db.Exec(query, newCapacity, time.Now(), eventID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+	if _, err := r.GetByID(eventID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (r *eventRepository) Cancel(eventID int, at time.Time) error {
+	query := `
+		UPDATE events SET is_active = false, cancelled_at = $1, updated_at = $1
+		WHERE id = $2 AND cancelled_at IS NULL`
+	result, err := r.db.Exec(query, at, eventID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+	if _, err := r.GetByID(eventID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (r *eventRepository) GetSitemapEntries(limit int) ([]models.SitemapEntry, error) {
+	entries := []models.SitemapEntry{}
+	query := `
+		SELECT id, updated_at FROM events
+		WHERE is_active = true AND is_private = false
+		ORDER BY id
+		LIMIT $1`
+	err := r.db.Select(&entries, query, limit)
+	return entries, err
+}
+
+func (r *eventRepository) GetUpcoming(now time.Time, categories []string, limit int) ([]models.Event, error) {
+	conditions := []string{"is_active = true", "is_private = false", "end_time > $1"}
+	args := []interface{}{now}
+	if len(categories) > 0 {
+		placeholders := make([]string, len(categories))
+		for i, category := range categories {
+			args = append(args, category)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, "category IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	args = append(args, limit)
+	return eventTable.list(r.db, `WHERE `+strings.Join(conditions, " AND ")+
+		fmt.Sprintf(` ORDER BY start_time, id LIMIT $%d`, len(args)), args...)
 }
 
 // UMARequestInvoiceRepository implementation
 type umaRequestInvoiceRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	cipher fieldCipher
 }
 
-func NewUMARequestInvoiceRepository(db *sqlx.DB) UMARequestInvoiceRepository {
-	return &umaRequestInvoiceRepository{db: db}
+// NewUMARequestInvoiceRepository creates the UMA invoice repository. UMA
+// addresses are encrypted at rest when piiKeyring is set.
+func NewUMARequestInvoiceRepository(db *sqlx.DB, piiKeyring *encryption.Keyring) UMARequestInvoiceRepository {
+	return &umaRequestInvoiceRepository{db: db, cipher: fieldCipher{keyring: piiKeyring}}
 }
 
 func (r *umaRequestInvoiceRepository) Create(invoice *models.UMARequestInvoice) error {
 	query := `
-		INSERT INTO uma_request_invoices (event_id, ticket_id, invoice_id, payment_hash, bolt11, amount_sats, status, uma_address, description, expires_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO uma_request_invoices (event_id, ticket_id, source, invoice_id, payment_hash, bolt11, amount_sats, status, uma_address, description, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, updated_at`
 
+	umaAddress, err := r.cipher.seal(invoice.UMAAddress)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
-	return r.db.QueryRowx(query,
-		invoice.EventID, invoice.TicketID, invoice.InvoiceID, invoice.PaymentHash, invoice.Bolt11,
-		invoice.AmountSats, invoice.Status, invoice.UMAAddress, invoice.Description,
+	err = r.db.QueryRowx(query,
+		invoice.EventID, invoice.TicketID, invoice.Source, invoice.InvoiceID, invoice.PaymentHash, invoice.Bolt11,
+		invoice.AmountSats, invoice.Status, umaAddress, invoice.Description,
 		invoice.ExpiresAt, now, now).StructScan(invoice)
+	return translateError(err)
 }
 
 func (r *umaRequestInvoiceRepository) GetByEventID(eventID int) (*models.UMARequestInvoice, error) {
-	invoice := &models.UMARequestInvoice{}
-	query := `SELECT * FROM uma_request_invoices WHERE event_id = $1`
-	err := r.db.Get(invoice, query, eventID)
+	invoice, err := umaRequestInvoiceTable.get(r.db, `WHERE id = (
+		SELECT MAX(id) FROM uma_request_invoices WHERE event_id = $1 AND source = $2
+	) AND status <> $3`, eventID, models.UMAInvoiceAdmin, models.PaymentCancelled)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No invoice found for this event
-		}
 		return nil, err
 	}
-	return invoice, nil
+	return invoice, r.openInvoice(invoice)
 }
 
 func (r *umaRequestInvoiceRepository) GetByTicketID(ticketID int) (*models.UMARequestInvoice, error) {
-	invoice := &models.UMARequestInvoice{}
-	query := `SELECT * FROM uma_request_invoices WHERE ticket_id = $1`
-	err := r.db.Get(invoice, query, ticketID)
+	invoice, err := umaRequestInvoiceTable.get(r.db, `WHERE ticket_id = $1`, ticketID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return invoice, nil
+	return invoice, r.openInvoice(invoice)
+}
+
+func (r *umaRequestInvoiceRepository) GetByPaymentHash(paymentHash string) (*models.UMARequestInvoice, error) {
+	invoice, err := umaRequestInvoiceTable.get(r.db, `WHERE payment_hash = $1 ORDER BY id LIMIT 1`, paymentHash)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, r.openInvoice(invoice)
 }
 
 func (r *umaRequestInvoiceRepository) Update(invoice *models.UMARequestInvoice) error {
@@ -237,12 +353,27 @@ func (r *umaRequestInvoiceRepository) Update(invoice *models.UMARequestInvoice)
 		    status = $5, uma_address = $6, description = $7, expires_at = $8, updated_at = $9
 		WHERE id = $10`
 
+	umaAddress, err := r.cipher.seal(invoice.UMAAddress)
+	if err != nil {
+		return err
+	}
+
 	invoice.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query,
+	_, err = r.db.Exec(query,
 		invoice.InvoiceID, invoice.PaymentHash, invoice.Bolt11, invoice.AmountSats,
-		invoice.Status, invoice.UMAAddress, invoice.Description, invoice.ExpiresAt,
+		invoice.Status, umaAddress, invoice.Description, invoice.ExpiresAt,
 		invoice.UpdatedAt, invoice.ID)
-	return err
+	return translateError(err)
+}
+
+// openInvoice decrypts the invoice's encrypted columns in place
+func (r *umaRequestInvoiceRepository) openInvoice(invoice *models.UMARequestInvoice) error {
+	umaAddress, err := r.cipher.open(invoice.UMAAddress)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt UMA address for invoice %d: %w", invoice.ID, err)
+	}
+	invoice.UMAAddress = umaAddress
+	return nil
 }
 
 func (r *umaRequestInvoiceRepository) Delete(id int) error {
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var eventRescheduleTable = newTable[models.EventReschedule]("event_reschedules")
+
+type eventRescheduleRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEventRescheduleRepository creates the event reschedule repository. clk
+// stamps created_at.
+func NewEventRescheduleRepository(db *sqlx.DB, clk clock.Clock) EventRescheduleRepository {
+	return &eventRescheduleRepository{db: db, clock: clk}
+}
+
+func (r *eventRescheduleRepository) Create(reschedule *models.EventReschedule) error {
+	query := `
+		INSERT INTO event_reschedules (event_id, old_start_time, old_end_time, new_start_time, new_end_time, reason, refund_until, rescheduled_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		` + eventRescheduleTable.returning()
+
+	return r.db.QueryRowx(query,
+		reschedule.EventID, reschedule.OldStartTime, reschedule.OldEndTime, reschedule.NewStartTime, reschedule.NewEndTime,
+		reschedule.Reason, reschedule.RefundUntil, reschedule.RescheduledBy, r.clock.Now()).StructScan(reschedule)
+}
+
+func (r *eventRescheduleRepository) GetByEventID(eventID int) ([]models.EventReschedule, error) {
+	return eventRescheduleTable.list(r.db, `WHERE event_id = $1 ORDER BY created_at DESC, id DESC`, eventID)
+}
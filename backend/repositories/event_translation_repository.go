@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var eventTranslationTable = newTable[models.EventTranslation]("event_translations")
+
+type eventTranslationRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewEventTranslationRepository creates the event translation repository.
+// clk stamps created_at and updated_at.
+func NewEventTranslationRepository(db *sqlx.DB, clk clock.Clock) EventTranslationRepository {
+	return &eventTranslationRepository{db: db, clock: clk}
+}
+
+func (r *eventTranslationRepository) Upsert(translation *models.EventTranslation) error {
+	query := `
+		INSERT INTO event_translations (event_id, locale, title, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (event_id, locale) DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description, updated_at = EXCLUDED.updated_at
+		` + eventTranslationTable.returning()
+
+	return r.db.QueryRowx(query,
+		translation.EventID, translation.Locale, translation.Title, translation.Description, r.clock.Now()).StructScan(translation)
+}
+
+func (r *eventTranslationRepository) Get(eventID int, locale string) (*models.EventTranslation, error) {
+	return eventTranslationTable.get(r.db, `WHERE event_id = $1 AND locale = $2`, eventID, locale)
+}
+
+func (r *eventTranslationRepository) GetByEventID(eventID int) ([]models.EventTranslation, error) {
+	return eventTranslationTable.list(r.db, `WHERE event_id = $1 ORDER BY locale`, eventID)
+}
+
+func (r *eventTranslationRepository) Delete(eventID int, locale string) error {
+	_, err := r.db.Exec(`DELETE FROM event_translations WHERE event_id = $1 AND locale = $2`, eventID, locale)
+	return err
+}
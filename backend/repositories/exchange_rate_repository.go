@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/models"
+)
+
+var exchangeRateTable = newTable[models.ExchangeRate]("exchange_rates")
+
+type exchangeRateRepository struct {
+	db *sqlx.DB
+}
+
+// NewExchangeRateRepository creates the exchange rate repository
+func NewExchangeRateRepository(db *sqlx.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+func (r *exchangeRateRepository) Create(rate *models.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (currency, minor_units_per_btc, source, fetched_at)
+		VALUES ($1, $2, $3, $4)
+		` + exchangeRateTable.returning()
+
+	return r.db.QueryRowx(query, rate.Currency, rate.MinorUnitsPerBTC, rate.Source, rate.FetchedAt).StructScan(rate)
+}
+
+func (r *exchangeRateRepository) List(currency string, limit, offset int) ([]models.ExchangeRate, error) {
+	query := `
+		WHERE currency = $1
+		ORDER BY fetched_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+	return exchangeRateTable.list(r.db, query, currency, limit, offset)
+}
+
+func (r *exchangeRateRepository) Unvalued(currency string, limit int) ([]models.PaymentValuation, error) {
+	query := `
+		SELECT p.id AS payment_id, p.amount_sats, x.id AS rate_id, x.minor_units_per_btc
+		FROM payments p
+		JOIN exchange_rates x ON x.id = (
+			SELECT id FROM exchange_rates
+			WHERE currency = $1 AND fetched_at <= p.paid_at
+			ORDER BY fetched_at DESC, id DESC
+			LIMIT 1)
+		WHERE p.status = 'paid' AND p.exchange_rate_id IS NULL
+		ORDER BY p.id
+		LIMIT $2`
+
+	valuations := []models.PaymentValuation{}
+	err := r.db.Select(&valuations, query, currency, limit)
+	return valuations, err
+}
+
+func (r *exchangeRateRepository) SetFiatValue(paymentID, rateID int, amount int64) error {
+	query := `
+		UPDATE payments
+		SET fiat_amount = $1, exchange_rate_id = $2,
+			fiat_currency = (SELECT currency FROM exchange_rates WHERE id = $2)
+		WHERE id = $3 AND exchange_rate_id IS NULL`
+
+	result, err := r.db.Exec(query, amount, rateID, paymentID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		var exists bool
+		if err := r.db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM payments WHERE id = $1)`, paymentID); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
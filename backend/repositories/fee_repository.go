@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var organizerFeeTable = newTable[models.OrganizerFee]("organizer_fees")
+
+type feeRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewFeeRepository creates the fee repository. clk stamps updated_at.
+func NewFeeRepository(db *sqlx.DB, clk clock.Clock) FeeRepository {
+	return &feeRepository{db: db, clock: clk}
+}
+
+func (r *feeRepository) GetOverride(organizerID int) (*models.OrganizerFee, error) {
+	return organizerFeeTable.get(r.db, `WHERE organizer_id = $1`, organizerID)
+}
+
+func (r *feeRepository) ListOverrides() ([]models.OrganizerFee, error) {
+	return organizerFeeTable.list(r.db, `ORDER BY organizer_id`)
+}
+
+func (r *feeRepository) SetOverride(fee *models.OrganizerFee) error {
+	query := `
+		INSERT INTO organizer_fees (organizer_id, basis_points, fixed_sats, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organizer_id) DO UPDATE
+		SET basis_points = EXCLUDED.basis_points, fixed_sats = EXCLUDED.fixed_sats, updated_at = EXCLUDED.updated_at`
+
+	fee.UpdatedAt = r.clock.Now()
+	_, err := r.db.Exec(query, fee.OrganizerID, fee.BasisPoints, fee.FixedSats, fee.UpdatedAt)
+	return err
+}
+
+func (r *feeRepository) DeleteOverride(organizerID int) error {
+	_, err := r.db.Exec(`DELETE FROM organizer_fees WHERE organizer_id = $1`, organizerID)
+	return err
+}
+
+func (r *feeRepository) Revenue(filter models.RevenueFilter) ([]models.EventRevenue, error) {
+	conditions := []string{"p.status = 'paid'"}
+	args := []interface{}{filter.FiatCurrency}
+	if filter.OrganizerID != 0 {
+		args = append(args, filter.OrganizerID)
+		conditions = append(conditions, fmt.Sprintf("e.organizer_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("p.paid_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("p.paid_at < $%d", len(args)))
+	}
+
+	query := `
+		SELECT e.id AS event_id, e.title, e.organizer_id,
+		       COUNT(p.id) AS payments,
+		       CAST(COALESCE(SUM(p.amount_sats), 0) AS BIGINT) AS gross_sats,
+		       CAST(COALESCE(SUM(f.fee_sats), 0) AS BIGINT) AS fee_sats,
+		       CAST(COALESCE(SUM(CASE WHEN p.fiat_currency = $1 THEN p.fiat_amount END), 0) AS BIGINT) AS gross_fiat,
+		       COUNT(CASE WHEN p.fiat_amount IS NULL OR p.fiat_currency <> $1 THEN 1 END) AS unvalued_payments
+		FROM payments p
+		JOIN tickets t ON t.id = p.ticket_id
+		JOIN events e ON e.id = t.event_id
+		LEFT JOIN (
+			SELECT payment_id, SUM(amount_sats) AS fee_sats
+			FROM payment_line_items
+			WHERE kind = 'fee'
+			GROUP BY payment_id
+		) f ON f.payment_id = p.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY e.id, e.title, e.organizer_id
+		ORDER BY e.id`
+
+	revenue := []models.EventRevenue{}
+	if err := r.db.Select(&revenue, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range revenue {
+		revenue[i].PayoutSats = revenue[i].GrossSats - revenue[i].FeeSats
+		revenue[i].FiatCurrency = filter.FiatCurrency
+	}
+	return revenue, nil
+}
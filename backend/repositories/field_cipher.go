@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"fmt"
+
+	"tickets-by-uma/encryption"
+)
+
+// fieldCipher encrypts individual columns. With a nil keyring values are
+// stored as-is, which keeps encryption optional in development.
+type fieldCipher struct {
+	keyring *encryption.Keyring
+}
+
+func (c fieldCipher) seal(value string) (string, error) {
+	if c.keyring == nil || value == "" {
+		return value, nil
+	}
+	return c.keyring.Encrypt(value)
+}
+
+func (c fieldCipher) open(stored string) (string, error) {
+	if c.keyring == nil {
+		if encryption.IsEncrypted(stored) {
+			return "", fmt.Errorf("value is encrypted but no keyring is configured")
+		}
+		return stored, nil
+	}
+	return c.keyring.Decrypt(stored)
+}
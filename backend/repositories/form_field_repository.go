@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	formFieldTable    = newTable[models.FormField]("event_form_fields")
+	ticketAnswerTable = newTable[models.TicketAnswer]("ticket_answers")
+)
+
+type formFieldRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewFormFieldRepository creates the form field repository. clk stamps
+// created_at and updated_at.
+func NewFormFieldRepository(db *sqlx.DB, clk clock.Clock) FormFieldRepository {
+	return &formFieldRepository{db: db, clock: clk}
+}
+
+func (r *formFieldRepository) Create(field *models.FormField) error {
+	query := `
+		INSERT INTO event_form_fields (event_id, label, field_type, options, required, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	now := r.clock.Now()
+	return r.db.QueryRowx(query,
+		field.EventID, field.Label, field.FieldType, field.Options, field.Required, field.Position, now, now).StructScan(field)
+}
+
+func (r *formFieldRepository) GetByID(id int) (*models.FormField, error) {
+	return formFieldTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *formFieldRepository) GetByEventID(eventID int) ([]models.FormField, error) {
+	return formFieldTable.list(r.db, `WHERE event_id = $1 ORDER BY position, id`, eventID)
+}
+
+func (r *formFieldRepository) Update(field *models.FormField) error {
+	query := `
+		UPDATE event_form_fields
+		SET label = $1, field_type = $2, options = $3, required = $4, position = $5, updated_at = $6
+		WHERE id = $7`
+
+	field.UpdatedAt = r.clock.Now()
+	_, err := r.db.Exec(query,
+		field.Label, field.FieldType, field.Options, field.Required, field.Position, field.UpdatedAt, field.ID)
+	return err
+}
+
+func (r *formFieldRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM event_form_fields WHERE id = $1`, id)
+	return err
+}
+
+func (r *formFieldRepository) CreateAnswers(answers []models.TicketAnswer) error {
+	if len(answers) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ticket_answers (ticket_id, field_id, label, value, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	now := r.clock.Now()
+	for i := range answers {
+		answer := &answers[i]
+		err := tx.QueryRowx(query, answer.TicketID, answer.FieldID, answer.Label, answer.Value, now).StructScan(answer)
+		if err != nil {
+			return fmt.Errorf("failed to store answer to %q: %w", answer.Label, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *formFieldRepository) GetAnswers(ticketID int) ([]models.TicketAnswer, error) {
+	return ticketAnswerTable.list(r.db, `WHERE ticket_id = $1 ORDER BY id`, ticketID)
+}
+
+func (r *formFieldRepository) GetAnswersByEventID(eventID int) ([]models.TicketAnswer, error) {
+	answers := []models.TicketAnswer{}
+	query := `
+		SELECT ` + ticketAnswerTable.selectAll("a") + ` FROM ticket_answers a
+		JOIN tickets t ON t.id = a.ticket_id
+		WHERE t.event_id = $1
+		ORDER BY a.ticket_id, a.id`
+	err := r.db.Select(&answers, query, eventID)
+	return answers, err
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var fraudFlagTable = newTable[models.FraudFlag]("fraud_flags")
+
+type fraudFlagRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewFraudFlagRepository creates the fraud flag repository. clk stamps created_at.
+func NewFraudFlagRepository(db *sqlx.DB, clk clock.Clock) FraudFlagRepository {
+	return &fraudFlagRepository{db: db, clock: clk}
+}
+
+func (r *fraudFlagRepository) Create(flag *models.FraudFlag) error {
+	query := `
+		INSERT INTO fraud_flags (user_id, event_id, ticket_id, client_ip, action, signals, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowx(query,
+		flag.UserID, flag.EventID, flag.TicketID, flag.ClientIP, flag.Action, flag.Signals, r.clock.Now()).StructScan(flag)
+}
+
+func (r *fraudFlagRepository) List(action string, limit, offset int) ([]models.FraudFlag, error) {
+	query := `
+		WHERE (CAST($1 AS TEXT) = '' OR action = CAST($1 AS TEXT))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+	return fraudFlagTable.list(r.db, query, action, limit, offset)
+}
+
+func (r *fraudFlagRepository) ListAwaitingReview(limit, offset int) ([]models.FraudFlag, error) {
+	var flags []models.FraudFlag
+	query := `
+		SELECT ` + fraudFlagTable.selectAll("f") + ` FROM fraud_flags f
+		JOIN tickets t ON t.id = f.ticket_id
+		WHERE t.payment_status = 'review'
+		ORDER BY f.created_at, f.id
+		LIMIT $1 OFFSET $2`
+	err := r.db.Select(&flags, query, limit, offset)
+	return flags, err
+}
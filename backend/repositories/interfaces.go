@@ -3,6 +3,7 @@ package repositories
 import (
 	"time"
 
+	"tickets-by-uma/listquery"
 	"tickets-by-uma/models"
 )
 
@@ -10,8 +11,14 @@ import (
 type UserRepository interface {
 	Create(user *models.User) error
 	GetByID(id int) (*models.User, error)
+	// GetByIDs returns the users with the given IDs keyed by ID; IDs with no
+	// user are left out
+	GetByIDs(ids []int) (map[int]*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	// ChangePassword sets a user's password hash and bumps their session
+	// version, revoking their tokens, returning the updated user
+	ChangePassword(id int, passwordHash string) (*models.User, error)
 	Delete(id int) error
 }
 
@@ -19,19 +26,44 @@ type UserRepository interface {
 type EventRepository interface {
 	Create(event *models.Event) error
 	GetByID(id int) (*models.Event, error)
+	// GetByIDs returns the events with the given IDs keyed by ID; IDs with
+	// no event are left out
+	GetByIDs(ids []int) (map[int]*models.Event, error)
 	GetByIDWithUMAInvoice(id int) (*models.Event, error)
 	GetAll(limit, offset int) ([]models.Event, error)
+	// GetActive lists the active events open to the public; private events
+	// are left out
 	GetActive(limit, offset int) ([]models.Event, error)
+	// ListActive lists the active public events matching list, filtered
+	// and sorted on EventListFields, by start time unless sorted otherwise
+	ListActive(list listquery.Query) ([]models.Event, error)
 	Update(event *models.Event) error
 	Delete(id int) error
 	GetAvailableTicketCount(eventID int) (int, error)
+	GetAvailability(eventID int) (*models.EventAvailability, error)
+	// UpdateCapacity sets an event's capacity, returning ErrConflict if it
+	// is below the seats held by sold and pending tickets
 	UpdateCapacity(eventID, newCapacity int) error
+	// Cancel deactivates an event for good as of at, returning ErrConflict
+	// when it is already cancelled
+	Cancel(eventID int, at time.Time) error
+	// GetSitemapEntries lists up to limit active public events by ID, with
+	// when each last changed
+	GetSitemapEntries(limit int) ([]models.SitemapEntry, error)
+	// GetUpcoming lists up to limit active public events not over by now,
+	// soonest first; with categories, only events in one of them
+	GetUpcoming(now time.Time, categories []string, limit int) ([]models.Event, error)
 }
 
 type UMARequestInvoiceRepository interface {
 	Create(invoice *models.UMARequestInvoice) error
+	// GetByEventID returns the event's standing invoice: the latest admin
+	// invoice, unless it was revoked (cancelled)
 	GetByEventID(eventID int) (*models.UMARequestInvoice, error)
 	GetByTicketID(ticketID int) (*models.UMARequestInvoice, error)
+	// GetByPaymentHash returns the invoice with the Lightning payment hash.
+	// Payments to an event's UMA address are attributed to the event this way.
+	GetByPaymentHash(paymentHash string) (*models.UMARequestInvoice, error)
 	Update(invoice *models.UMARequestInvoice) error
 	Delete(id int) error
 }
@@ -40,34 +72,642 @@ type UMARequestInvoiceRepository interface {
 type TicketRepository interface {
 	Create(ticket *models.Ticket) error
 	GetByID(id int) (*models.Ticket, error)
+	// GetByIDs returns the tickets with the given IDs keyed by ID; IDs with
+	// no ticket are left out
+	GetByIDs(ids []int) (map[int]*models.Ticket, error)
 	GetByTicketCode(ticketCode string) (*models.Ticket, error)
 	GetByEventID(eventID int) ([]models.Ticket, error)
 	GetByUserID(userID int) ([]models.Ticket, error)
+	// ListByUser lists the user's tickets matching list, filtered and
+	// sorted on TicketListFields, newest first unless sorted otherwise
+	ListByUser(userID int, list listquery.Query) ([]models.Ticket, error)
 	GetByInvoiceID(invoiceID string) (*models.Ticket, error)
 	Update(ticket *models.Ticket) error
+	// UpdatePaymentStatus sets the ticket's status. Only a pending, expired
+	// or held ticket becomes paid: an already paid one is left as it is, and
+	// any other returns ErrConflict, so a late settlement cannot revive a
+	// cancelled ticket.
 	UpdatePaymentStatus(id int, status string) error
+	// Reprice sets the total of a ticket whose invoice lapsed unpaid and the
+	// event price it is now sold at, nil when none was recorded
+	Reprice(id int, amountSats int64, priceChangeID *int) error
 	GetPendingTickets() ([]models.Ticket, error)
 	CountByEventAndStatus(eventID int, status string) (int, error)
 	HasUserTicketForEvent(userID, eventID int) (bool, error)
+	HasActiveTicketForEvent(userID, eventID int) (bool, error)
+}
+
+// OrderRepository stores checkouts, which group the tickets bought together
+type OrderRepository interface {
+	Create(order *models.Order) error
+	GetByID(id int) (*models.Order, error)
+	// Delete removes an order whose ticket could not be stored
+	Delete(id int) error
+	// GetByUserID returns the user's orders, newest first
+	GetByUserID(userID int) ([]models.Order, error)
+	// ReferralConversions groups the orders matching filter by referral
+	// source, best selling first
+	ReferralConversions(filter models.ReferralFilter) ([]models.ReferralConversion, error)
+}
+
+// WalletClaimRepository stores the binding of tickets to wallet devices
+type WalletClaimRepository interface {
+	GetByTicketID(ticketID int) (*models.WalletClaim, error)
+	// Offer records a claim offer, replacing any outstanding one. An
+	// existing device binding stays until the offer is claimed.
+	Offer(ticketID int, secretHash string, expiresAt time.Time) error
+	// Claim binds the device to the ticket if secretHash matches an
+	// unexpired offer, which it consumes. It returns ErrNotFound otherwise.
+	Claim(ticketID int, secretHash, devicePublicKey string) (*models.WalletClaim, error)
+}
+
+// AccountClaimRepository stores the claim links of guest accounts
+type AccountClaimRepository interface {
+	// Offer records a claim link for a guest, replacing any outstanding one
+	Offer(userID int, secretHash string, expiresAt time.Time) error
+	// Claim consumes the unexpired link matching secretHash and turns its
+	// guest into a regular user with passwordHash, and name unless it is
+	// empty. It returns ErrNotFound when no link matches.
+	Claim(secretHash, passwordHash, name string) (*models.User, error)
+}
+
+// EmailChangeRepository stores users' pending emails and the links that
+// verify them
+type EmailChangeRepository interface {
+	// Request sets a user's pending email with the hash of its
+	// verification link, replacing any outstanding one
+	Request(userID int, newEmail, secretHash string, expiresAt time.Time) error
+	// Confirm consumes the unexpired link matching secretHash and makes its
+	// user's pending email their email. It returns ErrNotFound when no link
+	// matches and ErrConflict when another user has taken the address since.
+	Confirm(secretHash string) (*models.User, error)
 }
 
 // NWCConnectionRepository defines operations for NWC connection data
 type NWCConnectionRepository interface {
 	Upsert(userID int, connectionURI string, expiresAt *time.Time) error
 	GetByUserID(userID int) (*models.NWCConnection, error)
+	Reencrypt() (int, error)
 }
 
 // PaymentRepository defines operations for payment data
 type PaymentRepository interface {
 	Create(payment *models.Payment) error
 	GetByID(id int) (*models.Payment, error)
+	// GetByIDs returns the payments with the given IDs keyed by ID; IDs
+	// with no payment are left out
+	GetByIDs(ids []int) (map[int]*models.Payment, error)
+	// GetByInvoiceID returns the payment for the payment backend's invoice ID
 	GetByInvoiceID(invoiceID string) (*models.Payment, error)
+	// GetByBolt11 returns the payment for an encoded invoice a buyer holds
+	GetByBolt11(bolt11 string) (*models.Payment, error)
+	// GetByPaymentHash returns the payment for the invoice with the payment
+	// hash. Settlements reported by the node are matched this way.
+	GetByPaymentHash(paymentHash string) (*models.Payment, error)
 	GetByTicketID(ticketID int) (*models.Payment, error)
 	Update(payment *models.Payment) error
+	// UpdateStatus sets the payment's status. Only a pending or expired
+	// payment becomes paid: an already paid one keeps its paid_at, and any
+	// other returns ErrConflict.
 	UpdateStatus(id int, status string) error
 	UpdatePreimage(id int, preimage string) error
 	GetAllPayments() ([]models.Payment, error)
+	// StreamAll calls fn for each payment matching list, filtered and
+	// sorted on PaymentListFields and newest first unless sorted otherwise,
+	// without loading the whole table into memory. Iteration stops at the
+	// first error from fn.
+	StreamAll(list listquery.Query, fn func(*models.Payment) error) error
 	GetPendingPayments() ([]models.Payment, error)
+	// ExpireOverdue marks pending payments whose invoice has expired as
+	// expired and returns them
+	ExpireOverdue() ([]models.Payment, error)
 	GetAvailablePaymentForEvent(eventID int) (*models.Payment, error)
 	GetOldestPendingByAmount(amountSats int64) (*models.Payment, error)
+	// TaxSummary totals taxed payments paid at or after from and before to
+	// (nil bounds are open) by jurisdiction and rate
+	TaxSummary(from, to *time.Time) ([]models.TaxSummary, error)
+}
+
+// SettingsRepository defines operations for runtime settings
+type SettingsRepository interface {
+	GetAll() ([]models.Setting, error)
+	Get(key string) (*models.Setting, error)
+	Upsert(setting *models.Setting) error
+	Delete(key string) error
+}
+
+// FraudFlagRepository stores fraud check outcomes for admin review
+type FraudFlagRepository interface {
+	Create(flag *models.FraudFlag) error
+	// List returns flags newest first, optionally filtered by action ("" for all)
+	List(action string, limit, offset int) ([]models.FraudFlag, error)
+	// ListAwaitingReview returns flags whose ticket is still held in review,
+	// oldest first
+	ListAwaitingReview(limit, offset int) ([]models.FraudFlag, error)
+}
+
+// AddOnRepository manages event add-ons and the line items bought with tickets
+type AddOnRepository interface {
+	Create(addOn *models.AddOn) error
+	GetByID(id int) (*models.AddOn, error)
+	// GetByEventID returns an event's add-ons ordered by name, only the
+	// active ones when activeOnly is set
+	GetByEventID(eventID int, activeOnly bool) ([]models.AddOn, error)
+	Update(addOn *models.AddOn) error
+	Delete(id int) error
+	// CreateLineItems stores the add-ons bought with a ticket, all or none
+	CreateLineItems(items []models.TicketAddOn) error
+	GetLineItems(ticketID int) ([]models.TicketAddOn, error)
+	// UseFlex records that the ticket was cancelled with its flex line
+	// item and returns when. It returns ErrNotFound unless the item is an
+	// unused flex add-on.
+	UseFlex(itemID int) (time.Time, error)
+	// FlexUptake reports flex add-on sales per event matching filter, by
+	// event ID
+	FlexUptake(filter models.FlexFilter) ([]models.FlexUptake, error)
+}
+
+// PricingRuleRepository stores events' pricing rules and the history of the
+// prices their tickets were sold at
+type PricingRuleRepository interface {
+	Create(rule *models.PricingRule) error
+	GetByID(id int) (*models.PricingRule, error)
+	// GetByEventID returns an event's rules in the order they were added
+	GetByEventID(eventID int) ([]models.PricingRule, error)
+	Delete(id int) error
+	// RecordPrice stores change unless the event's latest recorded price
+	// has the same price and rule name, and returns the latest either way
+	RecordPrice(change *models.PriceChange) (*models.PriceChange, error)
+	GetPriceChange(id int) (*models.PriceChange, error)
+	// GetPriceHistory returns an event's price changes, oldest first
+	GetPriceHistory(eventID int) ([]models.PriceChange, error)
+}
+
+// ExchangeRateRepository stores fetched exchange rates and the fiat values
+// of paid payments
+type ExchangeRateRepository interface {
+	Create(rate *models.ExchangeRate) error
+	// List returns a currency's rates newest first
+	List(currency string, limit, offset int) ([]models.ExchangeRate, error)
+	// Unvalued returns up to limit paid payments without a fiat value, by
+	// ID, each with the currency's latest rate fetched at or before it was
+	// paid. Payments paid before the currency's first rate are left out.
+	Unvalued(currency string, limit int) ([]models.PaymentValuation, error)
+	// SetFiatValue records amount, in minor units of the rate's currency,
+	// as a payment's value at rateID. It returns ErrConflict if the payment
+	// already has a value; values are never changed.
+	SetFiatValue(paymentID, rateID int, amount int64) error
+}
+
+// CashuRedemptionRepository records Cashu tokens melted to pay invoices
+type CashuRedemptionRepository interface {
+	// Create records a pending redemption, returning ErrConflict when the
+	// payment already has a pending or paid one
+	Create(redemption *models.CashuRedemption) error
+	// Finish settles a pending redemption in state, returning ErrNotFound
+	// unless it was pending
+	Finish(id int, state string, preimage *string, errMsg string) error
+	// List returns redemptions newest first
+	List(limit, offset int) ([]models.CashuRedemption, error)
+}
+
+// LNURLAuthRepository stores LNURL-auth login challenges and the linking
+// keys users log in with
+type LNURLAuthRepository interface {
+	// CreateChallenge records a challenge
+	CreateChallenge(challenge *models.LNURLAuthChallenge) error
+	// GetChallenge returns the unexpired challenge k1, or ErrNotFound
+	GetChallenge(k1 string) (*models.LNURLAuthChallenge, error)
+	// GetBySession returns the unexpired, unconsumed challenge of the
+	// session matching sessionHash, or ErrNotFound
+	GetBySession(sessionHash string) (*models.LNURLAuthChallenge, error)
+	// Verify records that userID signed in with the unexpired challenge
+	// k1, returning ErrNotFound unless it was still unverified
+	Verify(k1 string, userID int) error
+	// Consume uses up a verified challenge, returning ErrNotFound when it
+	// was already consumed
+	Consume(id int) error
+	// GetUserByKey returns the user with linking key, or ErrNotFound
+	GetUserByKey(key string) (*models.User, error)
+	// Link makes key the user's linking key, returning ErrConflict when
+	// another user has it
+	Link(userID int, key string) (*models.User, error)
+	// Unlink removes the user's linking key and bumps their session
+	// version, revoking their tokens
+	Unlink(userID int) (*models.User, error)
+}
+
+// FormFieldRepository manages event form fields and the answers given with
+// tickets
+type FormFieldRepository interface {
+	Create(field *models.FormField) error
+	GetByID(id int) (*models.FormField, error)
+	// GetByEventID returns an event's form fields by position
+	GetByEventID(eventID int) ([]models.FormField, error)
+	Update(field *models.FormField) error
+	Delete(id int) error
+	// CreateAnswers stores the answers given with a ticket, all or none
+	CreateAnswers(answers []models.TicketAnswer) error
+	GetAnswers(ticketID int) ([]models.TicketAnswer, error)
+	// GetAnswersByEventID returns the answers given with all of an event's
+	// tickets, ordered by ticket
+	GetAnswersByEventID(eventID int) ([]models.TicketAnswer, error)
+}
+
+// EventAccessRepository stores the invite codes and allowlists that gate
+// purchases for private events
+type EventAccessRepository interface {
+	// CreateCode returns ErrConflict when the event already has the code
+	CreateCode(code *models.EventAccessCode) error
+	GetCode(id int) (*models.EventAccessCode, error)
+	GetCodes(eventID int) ([]models.EventAccessCode, error)
+	DeleteCode(id int) error
+	// RedeemCode counts one use of an event's code. It returns ErrNotFound
+	// for an unknown code and ErrConflict when the code has expired or has
+	// no uses left.
+	RedeemCode(eventID int, code string) error
+	// ReleaseCode gives back a use counted by RedeemCode for a purchase
+	// that stored no ticket
+	ReleaseCode(eventID int, code string) error
+	// AddToAllowlist adds lowercased entries, skipping those already on
+	// the event's list
+	AddToAllowlist(eventID int, entries []string) error
+	GetAllowlistEntry(id int) (*models.EventAllowlistEntry, error)
+	GetAllowlist(eventID int) ([]models.EventAllowlistEntry, error)
+	DeleteAllowlistEntry(id int) error
+	// IsAllowlisted reports whether the user's email or the UMA address is
+	// on the event's allowlist
+	IsAllowlisted(eventID, userID int, umaAddress string) (bool, error)
+}
+
+// AttestationRepository stores the age and terms attestations given with
+// purchases
+type AttestationRepository interface {
+	// Create returns ErrConflict when the ticket already has an attestation
+	Create(attestation *models.PurchaseAttestation) error
+	GetByTicketID(ticketID int) (*models.PurchaseAttestation, error)
+	// GetByEventID returns the attestations for an event's tickets, ordered
+	// by ticket
+	GetByEventID(eventID int) ([]models.PurchaseAttestation, error)
+}
+
+// EventTranslationRepository stores events' titles and descriptions in
+// other locales
+type EventTranslationRepository interface {
+	// Upsert creates the event's translation for the locale or replaces it
+	Upsert(translation *models.EventTranslation) error
+	Get(eventID int, locale string) (*models.EventTranslation, error)
+	// GetByEventID returns an event's translations ordered by locale
+	GetByEventID(eventID int) ([]models.EventTranslation, error)
+	Delete(eventID int, locale string) error
+}
+
+// EventCancellationRepository stores the bulk jobs cancelling cancelled
+// events' tickets, one per event
+type EventCancellationRepository interface {
+	// Create returns ErrConflict when the event already has a cancellation
+	Create(cancellation *models.EventCancellation) error
+	GetByEventID(eventID int) (*models.EventCancellation, error)
+	// ListRunning returns the running cancellations, oldest first
+	ListRunning() ([]models.EventCancellation, error)
+	// Update saves a cancellation's status and progress
+	Update(cancellation *models.EventCancellation) error
+}
+
+// TicketScanRepository records check-in attempts at the door
+type TicketScanRepository interface {
+	// Create stamps scanned_at
+	Create(scan *models.TicketScan) error
+	// ListAdmitted returns an event's admitting scans, oldest first
+	ListAdmitted(eventID int) ([]models.TicketScan, error)
+	// ListRecent returns an event's latest scans, newest first
+	ListRecent(eventID, limit int) ([]models.TicketScan, error)
+}
+
+// ScannerDeviceRepository stores the door scanners registered to events
+type ScannerDeviceRepository interface {
+	// Create stamps created_at
+	Create(device *models.ScannerDevice) error
+	GetByID(id int) (*models.ScannerDevice, error)
+	// GetByEventID returns an event's scanners, oldest first
+	GetByEventID(eventID int) ([]models.ScannerDevice, error)
+	// Register consumes the unexpired pairing matching pairingHash and
+	// gives its scanner tokenHash. It returns ErrNotFound when no pairing
+	// matches.
+	Register(pairingHash, tokenHash string) (*models.ScannerDevice, error)
+	// GetByTokenHash returns the unrevoked scanner holding a token
+	GetByTokenHash(tokenHash string) (*models.ScannerDevice, error)
+	// Touch stamps a scanner's last_seen_at
+	Touch(id int) error
+	// Revoke stops a scanner's token and pairing code from working
+	Revoke(id int) (*models.ScannerDevice, error)
+}
+
+// InventoryHoldRepository stores the seats admins hold back from sale
+type InventoryHoldRepository interface {
+	// Create stores a hold, returning ErrConflict when the event has fewer
+	// seats left than it holds and ErrNotFound for an unknown event
+	Create(hold *models.InventoryHold) error
+	GetByID(id int) (*models.InventoryHold, error)
+	// GetByEventID returns an event's holds, released ones included, oldest
+	// first
+	GetByEventID(eventID int) ([]models.InventoryHold, error)
+	// Release returns a hold's seats to sale, returning ErrConflict when it
+	// is already released
+	Release(id int, releasedBy *int) (*models.InventoryHold, error)
+}
+
+// EventRelationRepository stores the related events admins pick for an
+// event and finds similar ones to suggest alongside them
+type EventRelationRepository interface {
+	// Set replaces an event's picked related events with relatedIDs, in
+	// order, returning ErrNotFound when one of them does not exist
+	Set(eventID int, relatedIDs []int) error
+	// GetRelatedIDs returns an event's picked related events, in order
+	GetRelatedIDs(eventID int) ([]int, error)
+	// GetCurated returns the picked related events on public sale at now:
+	// active, public and not yet over
+	GetCurated(eventID int, now time.Time) ([]models.Event, error)
+	// GetSimilar returns up to limit other events on public sale at now
+	// sharing the event's category or organizer, soonest first
+	GetSimilar(event *models.Event, now time.Time, limit int) ([]models.Event, error)
+}
+
+// OrganizerProfileRepository stores organizers' public profiles and finds
+// the events shown on them
+type OrganizerProfileRepository interface {
+	// Save creates or replaces the user's profile, returning ErrConflict
+	// when another organizer has the slug
+	Save(profile *models.OrganizerProfile) error
+	GetByUserID(userID int) (*models.OrganizerProfile, error)
+	GetBySlug(slug string) (*models.OrganizerProfile, error)
+	// IsOrganizer reports whether the user organizes any event
+	IsOrganizer(userID int) (bool, error)
+	// GetUpcomingEvents returns up to limit of the user's events on public
+	// sale at now, soonest first
+	GetUpcomingEvents(userID int, now time.Time, limit int) ([]models.Event, error)
+	// CountPastEvents counts the user's public events over by now,
+	// cancelled ones left out
+	CountPastEvents(userID int, now time.Time) (int, error)
+}
+
+// AffiliateRepository stores the affiliates who earn commission on the
+// sales they refer
+type AffiliateRepository interface {
+	// Create enrolls a user, returning ErrConflict when they already are an
+	// affiliate or the code is taken
+	Create(affiliate *models.Affiliate) error
+	GetByID(id int) (*models.Affiliate, error)
+	GetByUserID(userID int) (*models.Affiliate, error)
+	GetByCode(code string) (*models.Affiliate, error)
+	// Update saves an affiliate's code, rate, status and payout address,
+	// returning ErrConflict when another affiliate has the code
+	Update(affiliate *models.Affiliate) error
+	// List returns every affiliate with the orders they referred and the
+	// paid tickets in them; commission is left for the ledger to fill in
+	List() ([]models.AffiliateReport, error)
+	GetReport(id int) (*models.AffiliateReport, error)
+}
+
+// TicketGiftRepository stores tickets bought for someone else until their
+// recipients accept them
+type TicketGiftRepository interface {
+	// Create schedules a gift, returning ErrConflict when the ticket
+	// already is one
+	Create(gift *models.TicketGift) error
+	GetByTicketID(ticketID int) (*models.TicketGift, error)
+	// GetBySecret returns the gift whose claim link has the secret hash
+	GetBySecret(secretHash string) (*models.TicketGift, error)
+	// GetBySenderID returns the gifts a user bought, newest first
+	GetBySenderID(senderID int) ([]models.TicketGift, error)
+	// ListDue returns up to limit scheduled gifts due by now whose ticket
+	// is paid, earliest due first
+	ListDue(now time.Time, limit int) ([]models.TicketGift, error)
+	// MarkDelivered records that a scheduled gift's claim link was sent
+	// with the secret hash, returning ErrNotFound unless it was scheduled
+	MarkDelivered(id int, secretHash string) error
+	// Claim gives the ticket of the delivered gift whose claim link has the
+	// secret hash to recipientID, returning ErrNotFound when there is none
+	Claim(secretHash string, recipientID int) (*models.TicketGift, error)
+}
+
+// ShortLinkRepository stores the short links to event pages and tickets and
+// counts their clicks
+type ShortLinkRepository interface {
+	// Create adds a link, returning ErrConflict when its code is taken or,
+	// for a generated link, its event or ticket already has one
+	Create(link *models.ShortLink) error
+	GetByID(id int) (*models.ShortLink, error)
+	GetByCode(code string) (*models.ShortLink, error)
+	// GetGeneratedForEvent and GetGeneratedForTicket return the generated
+	// link of an event or a ticket
+	GetGeneratedForEvent(eventID int) (*models.ShortLink, error)
+	GetGeneratedForTicket(ticketID int) (*models.ShortLink, error)
+	// GetByEventID returns an event's links, generated and custom, oldest
+	// first
+	GetByEventID(eventID int) ([]models.ShortLink, error)
+	// UpdateCode gives a link a new code, making it custom. It returns
+	// ErrConflict when another link has the code.
+	UpdateCode(id int, code string) (*models.ShortLink, error)
+	Delete(id int) error
+	// Click counts a click on the link with code, returning the link
+	Click(code string) (*models.ShortLink, error)
+}
+
+// EventRescheduleRepository records the times events were moved to
+type EventRescheduleRepository interface {
+	Create(reschedule *models.EventReschedule) error
+	// GetByEventID returns an event's reschedules, newest first
+	GetByEventID(eventID int) ([]models.EventReschedule, error)
+}
+
+// NotificationTemplateRepository stores the versions of admin-configured
+// notification templates. A scope is an organizer (nil for the platform), a
+// notification kind and a locale; its highest version is the current one.
+type NotificationTemplateRepository interface {
+	// Create saves the template as the next version of its scope
+	Create(template *models.NotificationTemplate) error
+	GetByID(id int) (*models.NotificationTemplate, error)
+	// GetCurrent returns the highest version of a scope, or ErrNotFound
+	GetCurrent(organizerID *int, kind, locale string) (*models.NotificationTemplate, error)
+	// GetVersions returns a scope's versions, newest first
+	GetVersions(organizerID *int, kind, locale string) ([]models.NotificationTemplate, error)
+	// GetAllCurrent returns the current version of every scope, platform
+	// templates first
+	GetAllCurrent() ([]models.NotificationTemplate, error)
+	// Delete removes a version; deleting the current one makes the previous
+	// version current again
+	Delete(id int) error
+}
+
+// ReceiptRepository stores payment line items and the receipts issued for
+// paid payments
+type ReceiptRepository interface {
+	// CreateLineItems stores what a payment covers, all or none
+	CreateLineItems(items []models.PaymentLineItem) error
+	// ReplaceLineItems replaces what a payment covers, all or none, for a
+	// payment invoiced again at a new price
+	ReplaceLineItems(paymentID int, items []models.PaymentLineItem) error
+	GetLineItems(paymentID int) ([]models.PaymentLineItem, error)
+	// Issue returns the payment's receipt, assigning the next receipt
+	// number the first time it is called for a payment
+	Issue(paymentID int) (*models.Receipt, error)
+	GetByPaymentID(paymentID int) (*models.Receipt, error)
+	// GetSales returns payments paid at or after from and before to, oldest
+	// first, with their event, receipt number and line items
+	GetSales(from, to time.Time) ([]models.Sale, error)
+}
+
+// FeeRepository stores per-organizer platform fee overrides and reports the
+// fees collected
+type FeeRepository interface {
+	GetOverride(organizerID int) (*models.OrganizerFee, error)
+	ListOverrides() ([]models.OrganizerFee, error)
+	// SetOverride creates or replaces the organizer's override
+	SetOverride(fee *models.OrganizerFee) error
+	DeleteOverride(organizerID int) error
+	// Revenue totals paid payments per event, with their fee line items
+	Revenue(filter models.RevenueFilter) ([]models.EventRevenue, error)
+}
+
+// DisputeRepository stores disputes raised against payments
+type DisputeRepository interface {
+	// Create opens a dispute, returning ErrConflict when the payment
+	// already has an open one
+	Create(dispute *models.Dispute) error
+	GetByID(id int) (*models.Dispute, error)
+	// List returns disputes newest first, only those with the status when
+	// it is not empty
+	List(status string, limit, offset int) ([]models.Dispute, error)
+	// Resolve closes an open dispute, returning ErrConflict when it is
+	// already closed
+	Resolve(dispute *models.Dispute) error
+}
+
+// WebhookEventRepository is the durable queue of inbound webhooks
+type WebhookEventRepository interface {
+	// Enqueue stores a pending event due now, returning ErrConflict when
+	// the source already delivered an event with the same ID
+	Enqueue(event *models.WebhookEvent) error
+	// Claim leases up to limit due pending events for lease, counting an
+	// attempt on each. Events leased by another worker are skipped.
+	Claim(limit int, lease time.Duration) ([]models.WebhookEvent, error)
+	Complete(id int) error
+	// Fail records a failed attempt and releases the lease. The event is
+	// retried at retryAt, or marked failed when retryAt is nil.
+	Fail(id int, lastError string, retryAt *time.Time) error
+	Backlog() (*models.WebhookBacklog, error)
+	// ListFailed returns the events the queue gave up on, most recent first
+	ListFailed() ([]models.WebhookEvent, error)
+	// Requeue makes a failed event pending and due now with its attempts
+	// reset, keeping the last error. ErrNotFound unless the event failed.
+	Requeue(id int) error
+	// DeleteFailed removes a failed event. ErrNotFound unless the event failed.
+	DeleteFailed(id int) error
+}
+
+// ArchiveRepository moves old rows out of the hot tables into
+// archived_records and reads them back on demand. Each Archive method moves
+// up to limit rows, each with the rows that hang off it, in a transaction
+// per row, and returns how many it moved. A ticket is only archived once
+// its payments are, and an event once its tickets are, so foreign keys
+// between the hot tables stay intact.
+type ArchiveRepository interface {
+	// ArchivePayments archives payments created before cutoff that are no
+	// longer pending or under an open dispute, and, when paid, are booked
+	// in the ledger. Their line items, receipts, Cashu redemptions and
+	// disputes go with them; ledger entries stay.
+	ArchivePayments(cutoff time.Time, limit int) (int, error)
+	// ArchiveTickets archives settled tickets without payments of events
+	// that ended before cutoff, with their add-ons, answers, gifts,
+	// attestations, short links and request invoices. Scans and fraud
+	// flags stay with the event.
+	ArchiveTickets(cutoff time.Time, limit int) (int, error)
+	// ArchiveEvents archives events without tickets that ended before
+	// cutoff, with their orders and everything else kept per event
+	ArchiveEvents(cutoff time.Time, limit int) (int, error)
+	// Get returns the archived row of table with the given ID, or ErrNotFound
+	Get(table string, recordID int) (*models.ArchivedRecord, error)
+	// ListByEvent returns everything archived for an event, in archive order
+	ListByEvent(eventID int) ([]models.ArchivedRecord, error)
+}
+
+// ConsoleRepository runs the admin console's views (ConsoleViews), fixed
+// queries whose params are bound rather than spliced into the SQL, in a
+// read-only transaction
+type ConsoleRepository interface {
+	// Run runs view with args bound to its params in order, returning at
+	// most limit rows. ErrNotFound when there is no such view.
+	Run(view string, args []interface{}, limit int) (*models.ConsoleResult, error)
+}
+
+// LedgerRepository stores the double-entry ledger. Entries are only ever
+// added, never changed or deleted.
+type LedgerRepository interface {
+	// Post stores a balanced entry and its postings, creating accounts on
+	// first use. It returns ErrConflict when the entry's payment already
+	// has an entry of the same kind.
+	Post(entry *models.LedgerEntry) error
+	GetPaymentEntry(kind string, paymentID int) (*models.LedgerEntry, error)
+	// ListEntries returns entries newest first, only those posting to the
+	// account when accountID is not 0
+	ListEntries(accountID, limit, offset int) ([]models.LedgerEntry, error)
+	Balances() ([]models.LedgerBalance, error)
+	GetBalance(account string) (*models.LedgerBalance, error)
+	// Debited totals what entries of kind debited to account
+	Debited(account, kind string) (int64, error)
+	// UnpostedSales returns up to limit paid payments without a sale entry
+	UnpostedSales(limit int) ([]models.Sale, error)
+	// Check returns entries that break the ledger's invariants; wallet is
+	// the account sales are paid into
+	Check(wallet string) ([]models.LedgerIssue, error)
+}
+
+// AdminNotificationRepository stores the admins' notification feed
+type AdminNotificationRepository interface {
+	Create(notification *models.AdminNotification) error
+	// List returns notifications newest first, only unread ones when
+	// unreadOnly is set
+	List(unreadOnly bool, limit, offset int) ([]models.AdminNotification, error)
+	// ListAfter returns up to limit notifications with IDs above afterID,
+	// oldest first
+	ListAfter(afterID, limit int) ([]models.AdminNotification, error)
+	CountUnread() (int, error)
+	// MarkRead marks a notification read by adminID, keeping the first
+	// reader of one already read. ErrNotFound when there is no such
+	// notification.
+	MarkRead(id, adminID int) (*models.AdminNotification, error)
+	// MarkAllRead marks every unread notification read by adminID and
+	// returns how many it marked
+	MarkAllRead(adminID int) (int, error)
+}
+
+// AlertConnectorRepository stores the connectors admin notifications are
+// posted to. Targets are returned decrypted.
+type AlertConnectorRepository interface {
+	Create(connector *models.AlertConnector) error
+	GetByID(id int) (*models.AlertConnector, error)
+	// List returns every connector by ID, only active ones when activeOnly
+	// is set
+	List(activeOnly bool) ([]models.AlertConnector, error)
+	Update(connector *models.AlertConnector) error
+	Delete(id int) error
+	// RecordDelivery stores the outcome of a post: sentAt and no error on
+	// success, the error otherwise. ErrNotFound when there is no such
+	// connector.
+	RecordDelivery(id int, sentAt time.Time, lastError string) error
+}
+
+// StatusIncidentRepository stores the incidents posted to the status page
+type StatusIncidentRepository interface {
+	Create(incident *models.StatusIncident) error
+	GetByID(id int) (*models.StatusIncident, error)
+	// List returns a page of incidents, latest started first
+	List(limit, offset int) ([]models.StatusIncident, error)
+	// ListRecent returns the unresolved incidents and those resolved since
+	// resolvedSince, latest started first
+	ListRecent(resolvedSince time.Time) ([]models.StatusIncident, error)
+	// Update and Delete return ErrNotFound when there is no such incident
+	Update(incident *models.StatusIncident) error
+	Delete(id int) error
 }
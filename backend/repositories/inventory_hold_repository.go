@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var inventoryHoldTable = newTable[models.InventoryHold]("inventory_holds")
+
+type inventoryHoldRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewInventoryHoldRepository creates the inventory hold repository. clk
+// stamps holds as they are created and released.
+func NewInventoryHoldRepository(db *sqlx.DB, clk clock.Clock) InventoryHoldRepository {
+	return &inventoryHoldRepository{db: db, clock: clk}
+}
+
+// Create holds seats only while the event has that many not taken by sold
+// and pending tickets or other holds, so a hold never oversells it
+func (r *inventoryHoldRepository) Create(hold *models.InventoryHold) error {
+	query := `
+		INSERT INTO inventory_holds (event_id, name, seats, created_by, created_at)
+		SELECT CAST($1 AS INTEGER), $2, CAST($3 AS INTEGER), $4, $5
+		FROM events e
+		WHERE e.id = $1 AND e.capacity - $3 >= (
+			SELECT COUNT(*) FROM tickets
+			WHERE event_id = $1 AND payment_status IN ('paid', 'pending', 'review', 'disputed')
+		) + (
+			SELECT COALESCE(SUM(seats), 0) FROM inventory_holds
+			WHERE event_id = $1 AND released_at IS NULL
+		)
+		` + inventoryHoldTable.returning()
+
+	err := r.db.QueryRowx(query,
+		hold.EventID, hold.Name, hold.Seats, hold.CreatedBy, r.clock.Now()).StructScan(hold)
+	if err = translateError(err); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var exists int
+	if err := r.db.Get(&exists, `SELECT 1 FROM events WHERE id = $1`, hold.EventID); err != nil {
+		return translateError(err)
+	}
+	return ErrConflict
+}
+
+func (r *inventoryHoldRepository) GetByID(id int) (*models.InventoryHold, error) {
+	return inventoryHoldTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *inventoryHoldRepository) GetByEventID(eventID int) ([]models.InventoryHold, error) {
+	return inventoryHoldTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
+
+func (r *inventoryHoldRepository) Release(id int, releasedBy *int) (*models.InventoryHold, error) {
+	query := `
+		UPDATE inventory_holds SET released_at = $1, released_by = $2
+		WHERE id = $3 AND released_at IS NULL
+		` + inventoryHoldTable.returning()
+
+	hold := &models.InventoryHold{}
+	err := translateError(r.db.QueryRowx(query, r.clock.Now(), releasedBy, id).StructScan(hold))
+	if !errors.Is(err, ErrNotFound) {
+		return hold, err
+	}
+	if _, err := r.GetByID(id); err != nil {
+		return nil, err
+	}
+	return nil, ErrConflict
+}
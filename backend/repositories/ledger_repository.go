@@ -0,0 +1,347 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var ledgerEntryTable = newTable[models.LedgerEntry]("ledger_entries")
+
+type ledgerRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewLedgerRepository creates the ledger repository. clk stamps created_at.
+func NewLedgerRepository(db *sqlx.DB, clk clock.Clock) LedgerRepository {
+	return &ledgerRepository{db: db, clock: clk}
+}
+
+func (r *ledgerRepository) Post(entry *models.LedgerEntry) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now()
+	query := `
+		INSERT INTO ledger_entries (kind, payment_id, organizer_id, affiliate_id, reference, description, occurred_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (kind, payment_id) DO NOTHING
+		RETURNING id, created_at`
+	err = tx.QueryRowx(query,
+		entry.Kind, entry.PaymentID, entry.OrganizerID, entry.AffiliateID, entry.Reference, entry.Description, entry.OccurredAt, now).Scan(&entry.ID, &entry.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		accountID, err := ledgerAccountID(tx, posting.Account, now)
+		if err != nil {
+			return fmt.Errorf("failed to open ledger account %q: %w", posting.Account, err)
+		}
+		posting.EntryID, posting.AccountID = entry.ID, accountID
+		err = tx.QueryRowx(`INSERT INTO ledger_postings (entry_id, account_id, debit_sats, credit_sats) VALUES ($1, $2, $3, $4) RETURNING id`,
+			entry.ID, accountID, posting.DebitSats, posting.CreditSats).Scan(&posting.ID)
+		if err != nil {
+			return fmt.Errorf("failed to store ledger posting to %q: %w", posting.Account, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ledgerAccountID returns the ID of the named account, creating it first
+// if needed
+func ledgerAccountID(tx *sqlx.Tx, name string, now time.Time) (int, error) {
+	_, err := tx.Exec(`INSERT INTO ledger_accounts (name, type, created_at) VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING`,
+		name, models.LedgerAccountType(name), now)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = tx.Get(&id, `SELECT id FROM ledger_accounts WHERE name = $1`, name)
+	return id, err
+}
+
+func (r *ledgerRepository) GetPaymentEntry(kind string, paymentID int) (*models.LedgerEntry, error) {
+	entries, err := ledgerEntryTable.list(r.db, `WHERE kind = $1 AND payment_id = $2`, kind, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+	if err := r.loadPostings(entries); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+func (r *ledgerRepository) ListEntries(accountID, limit, offset int) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	var err error
+	if accountID != 0 {
+		query := `
+			WHERE id IN (SELECT entry_id FROM ledger_postings WHERE account_id = $1)
+			ORDER BY occurred_at DESC, id DESC
+			LIMIT $2 OFFSET $3`
+		entries, err = ledgerEntryTable.list(r.db, query, accountID, limit, offset)
+	} else {
+		entries, err = ledgerEntryTable.list(r.db, `ORDER BY occurred_at DESC, id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entries, r.loadPostings(entries)
+}
+
+// loadPostings fills in the postings of entries, with their account names
+func (r *ledgerRepository) loadPostings(entries []models.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(entries))
+	ids := make([]interface{}, len(entries))
+	index := make(map[int]int, len(entries))
+	for i, entry := range entries {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		ids[i] = entry.ID
+		index[entry.ID] = i
+		entries[i].Postings = []models.LedgerPosting{}
+	}
+
+	postings := []models.LedgerPosting{}
+	query := `
+		SELECT p.id, p.entry_id, p.account_id, p.debit_sats, p.credit_sats, a.name AS account
+		FROM ledger_postings p
+		JOIN ledger_accounts a ON a.id = p.account_id
+		WHERE p.entry_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY p.id`
+	if err := r.db.Select(&postings, query, ids...); err != nil {
+		return err
+	}
+	for _, posting := range postings {
+		i := index[posting.EntryID]
+		entries[i].Postings = append(entries[i].Postings, posting)
+	}
+	return nil
+}
+
+const ledgerBalanceQuery = `
+	SELECT a.id, a.name, a.type, a.created_at,
+	       CAST(COALESCE(SUM(p.debit_sats), 0) AS BIGINT) AS debit_sats,
+	       CAST(COALESCE(SUM(p.credit_sats), 0) AS BIGINT) AS credit_sats
+	FROM ledger_accounts a
+	LEFT JOIN ledger_postings p ON p.account_id = a.id`
+
+func (r *ledgerRepository) Balances() ([]models.LedgerBalance, error) {
+	balances := []models.LedgerBalance{}
+	query := ledgerBalanceQuery + ` GROUP BY a.id, a.name, a.type, a.created_at ORDER BY a.name`
+	if err := r.db.Select(&balances, query); err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		settleBalance(&balances[i])
+	}
+	return balances, nil
+}
+
+func (r *ledgerRepository) GetBalance(account string) (*models.LedgerBalance, error) {
+	balance := &models.LedgerBalance{}
+	query := ledgerBalanceQuery + ` WHERE a.name = $1 GROUP BY a.id, a.name, a.type, a.created_at`
+	if err := r.db.Get(balance, query, account); err != nil {
+		return nil, translateError(err)
+	}
+	settleBalance(balance)
+	return balance, nil
+}
+
+func (r *ledgerRepository) Debited(account, kind string) (int64, error) {
+	var total int64
+	query := `
+		SELECT CAST(COALESCE(SUM(p.debit_sats), 0) AS BIGINT)
+		FROM ledger_postings p
+		JOIN ledger_accounts a ON a.id = p.account_id
+		JOIN ledger_entries e ON e.id = p.entry_id
+		WHERE a.name = $1 AND e.kind = $2`
+	err := r.db.Get(&total, query, account, kind)
+	return total, err
+}
+
+// settleBalance sets the balance on the account's normal side: debits for
+// assets and expenses, credits for the other types
+func settleBalance(balance *models.LedgerBalance) {
+	switch balance.Type {
+	case models.LedgerAsset, models.LedgerExpense:
+		balance.BalanceSats = balance.DebitSats - balance.CreditSats
+	default:
+		balance.BalanceSats = balance.CreditSats - balance.DebitSats
+	}
+}
+
+func (r *ledgerRepository) UnpostedSales(limit int) ([]models.Sale, error) {
+	where := `p.status = 'paid'
+		AND NOT EXISTS (SELECT 1 FROM ledger_entries le WHERE le.kind = 'sale' AND le.payment_id = p.id)
+		ORDER BY p.id
+		LIMIT $1`
+	return selectSales(r.db, where, limit)
+}
+
+func (r *ledgerRepository) Check(wallet string) ([]models.LedgerIssue, error) {
+	issues := []models.LedgerIssue{}
+
+	var unbalanced []struct {
+		EntryID  int   `db:"entry_id"`
+		Postings int   `db:"postings"`
+		Debits   int64 `db:"debits"`
+		Credits  int64 `db:"credits"`
+	}
+	query := `
+		SELECT e.id AS entry_id, COUNT(p.id) AS postings,
+		       CAST(COALESCE(SUM(p.debit_sats), 0) AS BIGINT) AS debits,
+		       CAST(COALESCE(SUM(p.credit_sats), 0) AS BIGINT) AS credits
+		FROM ledger_entries e
+		LEFT JOIN ledger_postings p ON p.entry_id = e.id
+		GROUP BY e.id
+		HAVING COUNT(p.id) < 2 OR COALESCE(SUM(p.debit_sats), 0) <> COALESCE(SUM(p.credit_sats), 0)
+		ORDER BY e.id`
+	if err := r.db.Select(&unbalanced, query); err != nil {
+		return nil, err
+	}
+	for _, row := range unbalanced {
+		issues = append(issues, unbalancedIssue(row.EntryID, row.Postings, row.Debits, row.Credits))
+	}
+
+	var mismatched []struct {
+		EntryID   int   `db:"entry_id"`
+		PaymentID int   `db:"payment_id"`
+		Amount    int64 `db:"amount_sats"`
+		Debits    int64 `db:"debits"`
+	}
+	query = `
+		SELECT e.id AS entry_id, e.payment_id, pay.amount_sats,
+		       CAST(COALESCE(SUM(p.debit_sats), 0) AS BIGINT) AS debits
+		FROM ledger_entries e
+		JOIN payments pay ON pay.id = e.payment_id
+		LEFT JOIN ledger_postings p ON p.entry_id = e.id
+			AND p.account_id = (SELECT id FROM ledger_accounts WHERE name = $1)
+		WHERE e.kind = 'sale'
+		GROUP BY e.id, e.payment_id, pay.amount_sats
+		HAVING COALESCE(SUM(p.debit_sats), 0) <> pay.amount_sats
+		ORDER BY e.id`
+	if err := r.db.Select(&mismatched, query, wallet); err != nil {
+		return nil, err
+	}
+	for _, row := range mismatched {
+		issues = append(issues, saleAmountIssue(row.EntryID, row.PaymentID, row.Amount, row.Debits, wallet))
+	}
+
+	var unpaid []struct {
+		EntryID   int    `db:"entry_id"`
+		PaymentID int    `db:"payment_id"`
+		Status    string `db:"status"`
+	}
+	query = `
+		SELECT e.id AS entry_id, e.payment_id, pay.status
+		FROM ledger_entries e
+		JOIN payments pay ON pay.id = e.payment_id
+		WHERE e.kind = 'sale' AND pay.status <> 'paid'
+			AND NOT EXISTS (SELECT 1 FROM ledger_entries r WHERE r.kind = 'refund' AND r.payment_id = e.payment_id)
+		ORDER BY e.id`
+	if err := r.db.Select(&unpaid, query); err != nil {
+		return nil, err
+	}
+	for _, row := range unpaid {
+		issues = append(issues, unpaidSaleIssue(row.EntryID, row.PaymentID, row.Status))
+	}
+
+	var orphans []struct {
+		EntryID   int `db:"entry_id"`
+		PaymentID int `db:"payment_id"`
+	}
+	query = `
+		SELECT e.id AS entry_id, e.payment_id
+		FROM ledger_entries e
+		WHERE e.kind = 'refund'
+			AND NOT EXISTS (SELECT 1 FROM ledger_entries s WHERE s.kind = 'sale' AND s.payment_id = e.payment_id)
+		ORDER BY e.id`
+	if err := r.db.Select(&orphans, query); err != nil {
+		return nil, err
+	}
+	for _, row := range orphans {
+		issues = append(issues, refundWithoutSaleIssue(row.EntryID, row.PaymentID))
+	}
+
+	balances, err := r.Balances()
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, negativeBalanceIssues(balances)...), nil
+}
+
+// Ledger invariant issues, shared with the memory repository so both report
+// the same checks and wording
+
+func unbalancedIssue(entryID, postings int, debits, credits int64) models.LedgerIssue {
+	return models.LedgerIssue{
+		Check:   "unbalanced",
+		EntryID: &entryID,
+		Detail:  fmt.Sprintf("%d postings, %d sats debited, %d sats credited", postings, debits, credits),
+	}
+}
+
+func saleAmountIssue(entryID, paymentID int, amount, debits int64, wallet string) models.LedgerIssue {
+	return models.LedgerIssue{
+		Check:     "sale_amount",
+		EntryID:   &entryID,
+		PaymentID: &paymentID,
+		Detail:    fmt.Sprintf("payment of %d sats, %d sats debited to %s", amount, debits, wallet),
+	}
+}
+
+func unpaidSaleIssue(entryID, paymentID int, status string) models.LedgerIssue {
+	return models.LedgerIssue{
+		Check:     "unpaid_sale",
+		EntryID:   &entryID,
+		PaymentID: &paymentID,
+		Detail:    fmt.Sprintf("sale booked but the payment is %s and has no refund", status),
+	}
+}
+
+func refundWithoutSaleIssue(entryID, paymentID int) models.LedgerIssue {
+	return models.LedgerIssue{
+		Check:     "refund_without_sale",
+		EntryID:   &entryID,
+		PaymentID: &paymentID,
+		Detail:    "refund booked for a payment without a sale",
+	}
+}
+
+// negativeBalanceIssues reports asset and liability accounts below zero:
+// more paid out of the wallet, or to an organizer, than came in
+func negativeBalanceIssues(balances []models.LedgerBalance) []models.LedgerIssue {
+	var issues []models.LedgerIssue
+	for _, balance := range balances {
+		if (balance.Type == models.LedgerAsset || balance.Type == models.LedgerLiability) && balance.BalanceSats < 0 {
+			issues = append(issues, models.LedgerIssue{
+				Check:  "negative_balance",
+				Detail: fmt.Sprintf("%s has a balance of %d sats", balance.Name, balance.BalanceSats),
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var lnurlAuthChallengeTable = newTable[models.LNURLAuthChallenge]("lnurl_auth_challenges")
+
+type lnurlAuthRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewLNURLAuthRepository creates the LNURL-auth repository. clk stamps
+// challenges and decides whether they have expired.
+func NewLNURLAuthRepository(db *sqlx.DB, clk clock.Clock) LNURLAuthRepository {
+	return &lnurlAuthRepository{db: db, clock: clk}
+}
+
+func (r *lnurlAuthRepository) CreateChallenge(challenge *models.LNURLAuthChallenge) error {
+	query := `
+		INSERT INTO lnurl_auth_challenges (k1, session_hash, link_user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		` + lnurlAuthChallengeTable.returning()
+
+	err := r.db.QueryRowx(query, challenge.K1, challenge.SessionHash, challenge.LinkUserID, challenge.ExpiresAt,
+		r.clock.Now()).StructScan(challenge)
+	return translateError(err)
+}
+
+func (r *lnurlAuthRepository) GetChallenge(k1 string) (*models.LNURLAuthChallenge, error) {
+	return lnurlAuthChallengeTable.get(r.db, `WHERE k1 = $1 AND expires_at > $2`, k1, r.clock.Now())
+}
+
+func (r *lnurlAuthRepository) GetBySession(sessionHash string) (*models.LNURLAuthChallenge, error) {
+	return lnurlAuthChallengeTable.get(r.db, `WHERE session_hash = $1 AND expires_at > $2 AND consumed_at IS NULL`,
+		sessionHash, r.clock.Now())
+}
+
+func (r *lnurlAuthRepository) Verify(k1 string, userID int) error {
+	now := r.clock.Now()
+	result, err := r.db.Exec(`
+		UPDATE lnurl_auth_challenges SET user_id = $1, verified_at = $2
+		WHERE k1 = $3 AND expires_at > $2 AND verified_at IS NULL`,
+		userID, now, k1)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *lnurlAuthRepository) Consume(id int) error {
+	result, err := r.db.Exec(`
+		UPDATE lnurl_auth_challenges SET consumed_at = $1
+		WHERE id = $2 AND verified_at IS NOT NULL AND consumed_at IS NULL`,
+		r.clock.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *lnurlAuthRepository) GetUserByKey(key string) (*models.User, error) {
+	return userTable.get(r.db, `WHERE lnurl_auth_key = $1`, key)
+}
+
+func (r *lnurlAuthRepository) Link(userID int, key string) (*models.User, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Checked here so a taken key is ErrConflict rather than a unique
+	// violation
+	var taken bool
+	err = tx.Get(&taken, `SELECT EXISTS (SELECT 1 FROM users WHERE lnurl_auth_key = $1 AND id <> $2)`, key, userID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrConflict
+	}
+
+	user := &models.User{}
+	err = tx.QueryRowx(`UPDATE users SET lnurl_auth_key = $1, updated_at = $2 WHERE id = $3 `+userTable.returning(),
+		key, r.clock.Now(), userID).StructScan(user)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return user, tx.Commit()
+}
+
+func (r *lnurlAuthRepository) Unlink(userID int) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET lnurl_auth_key = NULL, session_version = session_version + 1, updated_at = $1
+		WHERE id = $2
+		` + userTable.returning()
+
+	user := &models.User{}
+	if err := r.db.QueryRowx(query, r.clock.Now(), userID).StructScan(user); err != nil {
+		return nil, translateError(err)
+	}
+	return user, nil
+}
@@ -0,0 +1,5220 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/listquery"
+	"tickets-by-uma/models"
+)
+
+// MemoryStore keeps every table in process memory for tests and demo
+// deployments (STORAGE=memory). The repositories it hands out share one
+// store so joins such as events with their UMA invoices behave like the
+// Postgres implementations. Unique keys are enforced and reported as
+// ErrConflict; other foreign keys are not checked. Data is lost on restart.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	clock clock.Clock
+
+	users    map[int]models.User
+	events   map[int]models.Event
+	invoices map[int]models.UMARequestInvoice
+	tickets  map[int]models.Ticket
+	payments map[int]models.Payment
+	nwc      map[int]models.NWCConnection // keyed by user ID
+	settings map[string]models.Setting
+	flags    map[int]models.FraudFlag
+	addOns   map[int]models.AddOn
+	items    map[int]models.TicketAddOn // ticket add-on line items
+	lines    map[int]models.PaymentLineItem
+	receipts map[int]models.Receipt          // keyed by payment ID
+	fees     map[int]models.OrganizerFee     // keyed by organizer ID
+	accounts map[string]models.LedgerAccount // keyed by name
+	entries  map[int]models.LedgerEntry      // with their postings
+	disputes map[int]models.Dispute
+	webhooks map[int]models.WebhookEvent
+	claims   map[int]models.WalletClaim // keyed by ticket ID
+	fields   map[int]models.FormField
+	answers  map[int]models.TicketAnswer
+	codes    map[int]models.EventAccessCode
+	allowed  map[int]models.EventAllowlistEntry
+	attested map[int]models.PurchaseAttestation
+	titles   map[int]models.EventTranslation // translated titles and descriptions
+	notices  map[int]models.NotificationTemplate
+	orders   map[int]models.Order
+	guests   map[int]models.AccountClaim // keyed by user ID
+	changes  map[int]models.EmailChange  // keyed by user ID
+	moves    map[int]models.EventReschedule
+	cancels  map[int]models.EventCancellation // keyed by event ID
+	scans    map[int]models.TicketScan
+	scanners map[int]models.ScannerDevice
+	holds    map[int]models.InventoryHold
+	related  map[int][]int                   // picked related event IDs, keyed by event ID
+	profiles map[int]models.OrganizerProfile // keyed by user ID
+	links    map[int]models.ShortLink
+	partners map[int]models.Affiliate // affiliates
+	gifts    map[int]models.TicketGift
+	rules    map[int]models.PricingRule
+	prices   map[int]models.PriceChange // event price history
+	rates    map[int]models.ExchangeRate
+	melts    map[int]models.CashuRedemption
+	logins   map[int]models.LNURLAuthChallenge
+	archived map[int]models.ArchivedRecord
+	alerts   map[int]models.AdminNotification // admin notifications
+	hooks    map[int]models.AlertConnector
+	outages  map[int]models.StatusIncident
+
+	// Per-table ID sequences, like SERIAL columns
+	userSeq, eventSeq, invoiceSeq, ticketSeq, paymentSeq, nwcSeq, flagSeq, addOnSeq, itemSeq, lineSeq, receiptSeq, accountSeq, entrySeq, postingSeq, disputeSeq, webhookSeq, fieldSeq, answerSeq, codeSeq, allowedSeq, attestedSeq, titleSeq, noticeSeq, orderSeq, moveSeq, cancelSeq, scanSeq, scannerSeq, holdSeq, linkSeq, partnerSeq, giftSeq, ruleSeq, priceSeq, rateSeq, meltSeq, loginSeq, archivedSeq, alertSeq, hookSeq, outageSeq int
+}
+
+// NewMemoryStore creates an empty store. clk stamps created/updated and
+// paid_at times.
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:    clk,
+		users:    make(map[int]models.User),
+		events:   make(map[int]models.Event),
+		invoices: make(map[int]models.UMARequestInvoice),
+		tickets:  make(map[int]models.Ticket),
+		payments: make(map[int]models.Payment),
+		nwc:      make(map[int]models.NWCConnection),
+		settings: make(map[string]models.Setting),
+		flags:    make(map[int]models.FraudFlag),
+		addOns:   make(map[int]models.AddOn),
+		items:    make(map[int]models.TicketAddOn),
+		lines:    make(map[int]models.PaymentLineItem),
+		receipts: make(map[int]models.Receipt),
+		fees:     make(map[int]models.OrganizerFee),
+		accounts: make(map[string]models.LedgerAccount),
+		entries:  make(map[int]models.LedgerEntry),
+		disputes: make(map[int]models.Dispute),
+		webhooks: make(map[int]models.WebhookEvent),
+		claims:   make(map[int]models.WalletClaim),
+		fields:   make(map[int]models.FormField),
+		answers:  make(map[int]models.TicketAnswer),
+		codes:    make(map[int]models.EventAccessCode),
+		allowed:  make(map[int]models.EventAllowlistEntry),
+		attested: make(map[int]models.PurchaseAttestation),
+		titles:   make(map[int]models.EventTranslation),
+		notices:  make(map[int]models.NotificationTemplate),
+		orders:   make(map[int]models.Order),
+		guests:   make(map[int]models.AccountClaim),
+		changes:  make(map[int]models.EmailChange),
+		moves:    make(map[int]models.EventReschedule),
+		cancels:  make(map[int]models.EventCancellation),
+		scans:    make(map[int]models.TicketScan),
+		scanners: make(map[int]models.ScannerDevice),
+		holds:    make(map[int]models.InventoryHold),
+		related:  make(map[int][]int),
+		profiles: make(map[int]models.OrganizerProfile),
+		links:    make(map[int]models.ShortLink),
+		partners: make(map[int]models.Affiliate),
+		gifts:    make(map[int]models.TicketGift),
+		rules:    make(map[int]models.PricingRule),
+		prices:   make(map[int]models.PriceChange),
+		rates:    make(map[int]models.ExchangeRate),
+		melts:    make(map[int]models.CashuRedemption),
+		logins:   make(map[int]models.LNURLAuthChallenge),
+		archived: make(map[int]models.ArchivedRecord),
+		alerts:   make(map[int]models.AdminNotification),
+		hooks:    make(map[int]models.AlertConnector),
+		outages:  make(map[int]models.StatusIncident),
+	}
+}
+
+func (s *MemoryStore) Users() UserRepository { return &memoryUserRepository{s} }
+
+func (s *MemoryStore) Events() EventRepository { return &memoryEventRepository{s} }
+
+func (s *MemoryStore) UMARequestInvoices() UMARequestInvoiceRepository {
+	return &memoryUMARequestInvoiceRepository{s}
+}
+
+func (s *MemoryStore) Tickets() TicketRepository { return &memoryTicketRepository{s} }
+
+func (s *MemoryStore) NWCConnections() NWCConnectionRepository {
+	return &memoryNWCConnectionRepository{s}
+}
+
+func (s *MemoryStore) Payments() PaymentRepository { return &memoryPaymentRepository{s} }
+
+func (s *MemoryStore) Settings() SettingsRepository { return &memorySettingsRepository{s} }
+
+func (s *MemoryStore) FraudFlags() FraudFlagRepository { return &memoryFraudFlagRepository{s} }
+
+func (s *MemoryStore) AddOns() AddOnRepository { return &memoryAddOnRepository{s} }
+
+func (s *MemoryStore) Receipts() ReceiptRepository { return &memoryReceiptRepository{s} }
+func (s *MemoryStore) Fees() FeeRepository         { return &memoryFeeRepository{s} }
+func (s *MemoryStore) Ledger() LedgerRepository    { return &memoryLedgerRepository{s} }
+func (s *MemoryStore) Disputes() DisputeRepository { return &memoryDisputeRepository{s} }
+
+func (s *MemoryStore) WebhookEvents() WebhookEventRepository {
+	return &memoryWebhookEventRepository{s}
+}
+
+func (s *MemoryStore) WalletClaims() WalletClaimRepository {
+	return &memoryWalletClaimRepository{s}
+}
+
+func (s *MemoryStore) FormFields() FormFieldRepository { return &memoryFormFieldRepository{s} }
+
+func (s *MemoryStore) EventAccess() EventAccessRepository {
+	return &memoryEventAccessRepository{s}
+}
+
+func (s *MemoryStore) Attestations() AttestationRepository {
+	return &memoryAttestationRepository{s}
+}
+
+func (s *MemoryStore) EventTranslations() EventTranslationRepository {
+	return &memoryEventTranslationRepository{s}
+}
+
+func (s *MemoryStore) EventReschedules() EventRescheduleRepository {
+	return &memoryEventRescheduleRepository{s}
+}
+
+func (s *MemoryStore) EventCancellations() EventCancellationRepository {
+	return &memoryEventCancellationRepository{s}
+}
+
+func (s *MemoryStore) TicketScans() TicketScanRepository {
+	return &memoryTicketScanRepository{s}
+}
+
+func (s *MemoryStore) ScannerDevices() ScannerDeviceRepository {
+	return &memoryScannerDeviceRepository{s}
+}
+
+func (s *MemoryStore) InventoryHolds() InventoryHoldRepository {
+	return &memoryInventoryHoldRepository{s}
+}
+
+func (s *MemoryStore) EventRelations() EventRelationRepository {
+	return &memoryEventRelationRepository{s}
+}
+
+func (s *MemoryStore) OrganizerProfiles() OrganizerProfileRepository {
+	return &memoryOrganizerProfileRepository{s}
+}
+
+func (s *MemoryStore) ShortLinks() ShortLinkRepository {
+	return &memoryShortLinkRepository{s}
+}
+
+func (s *MemoryStore) Affiliates() AffiliateRepository {
+	return &memoryAffiliateRepository{s}
+}
+
+func (s *MemoryStore) TicketGifts() TicketGiftRepository {
+	return &memoryTicketGiftRepository{s}
+}
+
+func (s *MemoryStore) PricingRules() PricingRuleRepository {
+	return &memoryPricingRuleRepository{s}
+}
+
+func (s *MemoryStore) ExchangeRates() ExchangeRateRepository {
+	return &memoryExchangeRateRepository{s}
+}
+
+func (s *MemoryStore) CashuRedemptions() CashuRedemptionRepository {
+	return &memoryCashuRedemptionRepository{s}
+}
+
+func (s *MemoryStore) LNURLAuth() LNURLAuthRepository { return &memoryLNURLAuthRepository{s} }
+
+func (s *MemoryStore) NotificationTemplates() NotificationTemplateRepository {
+	return &memoryNotificationTemplateRepository{s}
+}
+
+func (s *MemoryStore) Orders() OrderRepository { return &memoryOrderRepository{s} }
+
+func (s *MemoryStore) Archive() ArchiveRepository { return &memoryArchiveRepository{s} }
+
+func (s *MemoryStore) Console() ConsoleRepository { return &memoryConsoleRepository{s} }
+
+func (s *MemoryStore) AdminNotifications() AdminNotificationRepository {
+	return &memoryAdminNotificationRepository{s}
+}
+
+func (s *MemoryStore) AlertConnectors() AlertConnectorRepository {
+	return &memoryAlertConnectorRepository{s}
+}
+
+func (s *MemoryStore) StatusIncidents() StatusIncidentRepository {
+	return &memoryStatusIncidentRepository{s}
+}
+
+func (s *MemoryStore) AccountClaims() AccountClaimRepository {
+	return &memoryAccountClaimRepository{s}
+}
+
+func (s *MemoryStore) EmailChanges() EmailChangeRepository {
+	return &memoryEmailChangeRepository{s}
+}
+
+// clonePtr copies a nullable column so callers never share state with the store
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// page applies LIMIT/OFFSET semantics to n sorted rows
+func page(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit >= 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// User repository
+
+type memoryUserRepository struct{ s *MemoryStore }
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return ErrConflict
+	}
+	if user.LNURLAuthKey != nil && (&memoryLNURLAuthRepository{r.s}).keyTaken(*user.LNURLAuthKey, 0) {
+		return ErrConflict
+	}
+	if user.Locale == "" {
+		user.Locale = i18n.Default
+	}
+	r.s.userSeq++
+	user.ID = r.s.userSeq
+	user.CreatedAt = r.s.clock.Now()
+	user.UpdatedAt = user.CreatedAt
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(id int) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetByIDs(ids []int) (map[int]*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	byID := make(map[int]*models.User, len(ids))
+	for _, id := range ids {
+		user, ok := r.s.users[id]
+		if !ok {
+			continue
+		}
+		user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+		byID[id] = &user
+	}
+	return byID, nil
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.Email == email {
+			user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) Update(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[user.ID]
+	if !ok {
+		return nil
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return ErrConflict
+	}
+	user.UpdatedAt = r.s.clock.Now()
+	stored.Email, stored.Name, stored.PasswordHash, stored.UpdatedAt = user.Email, user.Name, user.PasswordHash, user.UpdatedAt
+	stored.Locale = user.Locale
+	r.s.users[user.ID] = stored
+	return nil
+}
+
+func (r *memoryUserRepository) ChangePassword(id int, passwordHash string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	user.SessionVersion++
+	user.UpdatedAt = r.s.clock.Now()
+	r.s.users[id] = user
+	user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+	return &user, nil
+}
+
+func (r *memoryUserRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.users, id)
+	delete(r.s.guests, id)
+	delete(r.s.changes, id)
+	// Like the foreign keys: organizers' fee overrides, profiles and
+	// templates go, their events stay
+	delete(r.s.fees, id)
+	delete(r.s.profiles, id)
+	for affiliateID, affiliate := range r.s.partners {
+		if affiliate.UserID == id {
+			delete(r.s.partners, affiliateID)
+			r.s.forgetAffiliate(affiliateID)
+		}
+	}
+	for giftID, gift := range r.s.gifts {
+		if gift.SenderID == id {
+			delete(r.s.gifts, giftID)
+		} else if gift.RecipientID != nil && *gift.RecipientID == id {
+			gift.RecipientID = nil
+			r.s.gifts[giftID] = gift
+		}
+	}
+	for challengeID, challenge := range r.s.logins {
+		if (challenge.LinkUserID != nil && *challenge.LinkUserID == id) || (challenge.UserID != nil && *challenge.UserID == id) {
+			delete(r.s.logins, challengeID)
+		}
+	}
+	for eventID, event := range r.s.events {
+		if event.OrganizerID != nil && *event.OrganizerID == id {
+			event.OrganizerID = nil
+			r.s.events[eventID] = event
+		}
+	}
+	for linkID, link := range r.s.links {
+		if link.CreatedBy != nil && *link.CreatedBy == id {
+			link.CreatedBy = nil
+			r.s.links[linkID] = link
+		}
+	}
+	for templateID, template := range r.s.notices {
+		if scopeOf(template.OrganizerID) == id {
+			delete(r.s.notices, templateID)
+		} else if template.CreatedBy != nil && *template.CreatedBy == id {
+			template.CreatedBy = nil
+			r.s.notices[templateID] = template
+		}
+	}
+	return nil
+}
+
+// emailTaken reports whether a user other than exceptID owns email. Callers hold the lock.
+func (r *memoryUserRepository) emailTaken(email string, exceptID int) bool {
+	for id, user := range r.s.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// Event repository
+
+type memoryEventRepository struct{ s *MemoryStore }
+
+func (r *memoryEventRepository) Create(event *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if event.PricingMode == "" {
+		event.PricingMode = models.PricingFixed
+	}
+	r.s.eventSeq++
+	event.ID = r.s.eventSeq
+	event.CreatedAt = r.s.clock.Now()
+	event.UpdatedAt = event.CreatedAt
+	stored := *event
+	stored.UMARequestInvoice = nil
+	stored.OrganizerID = clonePtr(event.OrganizerID)
+	stored.TaxBasisPoints, stored.TaxInclusive, stored.TaxJurisdiction = event.TaxBasisPoints, event.TaxInclusive, event.TaxJurisdiction
+	r.s.events[event.ID] = stored
+	return nil
+}
+
+func (r *memoryEventRepository) GetByID(id int) (*models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	event, ok := r.s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &event, nil
+}
+
+func (r *memoryEventRepository) GetByIDs(ids []int) (map[int]*models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	byID := make(map[int]*models.Event, len(ids))
+	for _, id := range ids {
+		event, ok := r.s.events[id]
+		if !ok {
+			continue
+		}
+		byID[id] = &event
+	}
+	return byID, nil
+}
+
+func (r *memoryEventRepository) GetByIDWithUMAInvoice(id int) (*models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	event, ok := r.s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	event.UMARequestInvoice = r.s.invoiceForEvent(id)
+	return &event, nil
+}
+
+func (r *memoryEventRepository) GetAll(limit, offset int) ([]models.Event, error) {
+	return r.list(limit, offset), nil
+}
+
+func (r *memoryEventRepository) GetActive(limit, offset int) ([]models.Event, error) {
+	return r.ListActive(listquery.Query{Limit: limit, Offset: offset})
+}
+
+func (r *memoryEventRepository) ListActive(list listquery.Query) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, event := range r.s.events {
+		if event.IsActive && !event.IsPrivate {
+			events = append(events, event)
+		}
+	}
+	events = listquery.Apply(events, EventListFields, list, listquery.Order{Field: "start_time"})
+	for i := range events {
+		events[i].UMARequestInvoice = r.s.invoiceForEvent(events[i].ID)
+	}
+	return events, nil
+}
+
+func (r *memoryEventRepository) GetSitemapEntries(limit int) ([]models.SitemapEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entries := []models.SitemapEntry{}
+	for _, event := range r.s.events {
+		if event.IsActive && !event.IsPrivate {
+			entries = append(entries, models.SitemapEntry{EventID: event.ID, UpdatedAt: event.UpdatedAt})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].EventID < entries[j].EventID })
+	return entries[:min(limit, len(entries))], nil
+}
+
+func (r *memoryEventRepository) GetUpcoming(now time.Time, categories []string, limit int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, event := range r.s.events {
+		if onPublicSale(event, now) && (len(categories) == 0 || slices.Contains(categories, event.Category)) {
+			event.OrganizerID = clonePtr(event.OrganizerID)
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events[:min(limit, len(events))], nil
+}
+
+// list returns events ordered by start time with their UMA invoices
+// attached; publicOnly leaves out inactive and private events
+func (r *memoryEventRepository) list(limit, offset int) []models.Event {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, event := range r.s.events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	start, end := page(len(events), limit, offset)
+	events = events[start:end]
+	for i := range events {
+		events[i].UMARequestInvoice = r.s.invoiceForEvent(events[i].ID)
+	}
+	return events
+}
+
+func (r *memoryEventRepository) Update(event *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.events[event.ID]
+	if !ok {
+		return nil
+	}
+	event.UpdatedAt = r.s.clock.Now()
+	stored.Title, stored.Description = event.Title, event.Description
+	stored.StartTime, stored.EndTime = event.StartTime, event.EndTime
+	stored.Capacity, stored.PriceSats = event.Capacity, event.PriceSats
+	stored.StreamURL, stored.IsActive, stored.UpdatedAt = event.StreamURL, event.IsActive, event.UpdatedAt
+	stored.PricingMode, stored.MinPriceSats = event.PricingMode, event.MinPriceSats
+	stored.OrganizerID = clonePtr(event.OrganizerID)
+	stored.IsPrivate = event.IsPrivate
+	stored.MinAge, stored.TermsVersion, stored.TermsURL = event.MinAge, event.TermsVersion, event.TermsURL
+	stored.Category, stored.InvoiceExpirySeconds = event.Category, event.InvoiceExpirySeconds
+	stored.OneTicketPerUser = event.OneTicketPerUser
+	r.s.events[event.ID] = stored
+	return nil
+}
+
+func (r *memoryEventRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.events, id)
+	// uma_request_invoices.event_id, fraud_flags.event_id,
+	// event_addons.event_id, event_form_fields.event_id,
+	// event_access_codes.event_id, event_allowlist.event_id,
+	// purchase_attestations.event_id, event_translations.event_id,
+	// event_reschedules.event_id, event_cancellations.event_id,
+	// short_links.event_id, pricing_rules.event_id and
+	// event_price_changes.event_id are ON DELETE CASCADE
+	for invoiceID, invoice := range r.s.invoices {
+		if invoice.EventID != nil && *invoice.EventID == id {
+			delete(r.s.invoices, invoiceID)
+		}
+	}
+	for flagID, flag := range r.s.flags {
+		if flag.EventID == id {
+			delete(r.s.flags, flagID)
+		}
+	}
+	for addOnID, addOn := range r.s.addOns {
+		if addOn.EventID == id {
+			r.s.deleteAddOn(addOnID)
+		}
+	}
+	for fieldID, field := range r.s.fields {
+		if field.EventID == id {
+			r.s.deleteFormField(fieldID)
+		}
+	}
+	for codeID, code := range r.s.codes {
+		if code.EventID == id {
+			delete(r.s.codes, codeID)
+		}
+	}
+	for entryID, entry := range r.s.allowed {
+		if entry.EventID == id {
+			delete(r.s.allowed, entryID)
+		}
+	}
+	for attestationID, attestation := range r.s.attested {
+		if attestation.EventID == id {
+			delete(r.s.attested, attestationID)
+		}
+	}
+	for translationID, translation := range r.s.titles {
+		if translation.EventID == id {
+			delete(r.s.titles, translationID)
+		}
+	}
+	for moveID, move := range r.s.moves {
+		if move.EventID == id {
+			delete(r.s.moves, moveID)
+		}
+	}
+	delete(r.s.cancels, id)
+	for scanID, scan := range r.s.scans {
+		if scan.EventID == id {
+			delete(r.s.scans, scanID)
+		}
+	}
+	for deviceID, device := range r.s.scanners {
+		if device.EventID == id {
+			delete(r.s.scanners, deviceID)
+		}
+	}
+	for holdID, hold := range r.s.holds {
+		if hold.EventID == id {
+			delete(r.s.holds, holdID)
+		}
+	}
+	delete(r.s.related, id)
+	for linkID, link := range r.s.links {
+		if link.EventID != nil && *link.EventID == id {
+			delete(r.s.links, linkID)
+		}
+	}
+	for ruleID, rule := range r.s.rules {
+		if rule.EventID == id {
+			delete(r.s.rules, ruleID)
+		}
+	}
+	for changeID, change := range r.s.prices {
+		if change.EventID == id {
+			delete(r.s.prices, changeID)
+		}
+	}
+	for eventID, relatedIDs := range r.s.related {
+		r.s.related[eventID] = slices.DeleteFunc(relatedIDs, func(relatedID int) bool { return relatedID == id })
+	}
+	return nil
+}
+
+func (r *memoryEventRepository) GetAvailableTicketCount(eventID int) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	available, ok := r.s.availableSeats(eventID)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return available, nil
+}
+
+func (r *memoryEventRepository) GetAvailability(eventID int) (*models.EventAvailability, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	event, ok := r.s.events[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	availability := &models.EventAvailability{EventID: eventID, Capacity: event.Capacity, Held: r.s.heldSeats(eventID)}
+	for _, ticket := range r.s.tickets {
+		if ticket.EventID != eventID {
+			continue
+		}
+		switch ticket.PaymentStatus {
+		case "paid":
+			availability.Sold++
+		case "pending", "review", "disputed":
+			availability.Pending++
+		}
+	}
+	availability.Remaining = max(availability.Capacity-availability.Sold-availability.Pending-availability.Held, 0)
+	return availability, nil
+}
+
+func (r *memoryEventRepository) UpdateCapacity(eventID, newCapacity int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.events[eventID]
+	if !ok {
+		return ErrNotFound
+	}
+	held := 0
+	for _, ticket := range r.s.tickets {
+		if ticket.EventID != eventID {
+			continue
+		}
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review", "disputed":
+			held++
+		}
+	}
+	if newCapacity < held {
+		return ErrConflict
+	}
+	event.Capacity = newCapacity
+	event.UpdatedAt = r.s.clock.Now()
+	r.s.events[eventID] = event
+	return nil
+}
+
+func (r *memoryEventRepository) Cancel(eventID int, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.events[eventID]
+	if !ok {
+		return ErrNotFound
+	}
+	if event.CancelledAt != nil {
+		return ErrConflict
+	}
+	event.IsActive = false
+	event.CancelledAt = &at
+	event.UpdatedAt = at
+	r.s.events[eventID] = event
+	return nil
+}
+
+// UMA request invoice repository
+
+type memoryUMARequestInvoiceRepository struct{ s *MemoryStore }
+
+func (r *memoryUMARequestInvoiceRepository) Create(invoice *models.UMARequestInvoice) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.invoiceIDTaken(invoice.InvoiceID, 0) {
+		return ErrConflict
+	}
+	r.s.invoiceSeq++
+	invoice.ID = r.s.invoiceSeq
+	invoice.CreatedAt = r.s.clock.Now()
+	invoice.UpdatedAt = invoice.CreatedAt
+	r.s.invoices[invoice.ID] = cloneInvoice(*invoice)
+	return nil
+}
+
+func (r *memoryUMARequestInvoiceRepository) GetByEventID(eventID int) (*models.UMARequestInvoice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if invoice := r.s.invoiceForEvent(eventID); invoice != nil {
+		return invoice, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUMARequestInvoiceRepository) GetByTicketID(ticketID int) (*models.UMARequestInvoice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.firstInvoice(func(invoice models.UMARequestInvoice) bool {
+		return invoice.TicketID != nil && *invoice.TicketID == ticketID
+	})
+}
+
+func (r *memoryUMARequestInvoiceRepository) GetByPaymentHash(paymentHash string) (*models.UMARequestInvoice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.firstInvoice(func(invoice models.UMARequestInvoice) bool {
+		return invoice.PaymentHash == paymentHash
+	})
+}
+
+func (r *memoryUMARequestInvoiceRepository) Update(invoice *models.UMARequestInvoice) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.invoices[invoice.ID]
+	if !ok {
+		return nil
+	}
+	if r.invoiceIDTaken(invoice.InvoiceID, invoice.ID) {
+		return ErrConflict
+	}
+	invoice.UpdatedAt = r.s.clock.Now()
+	updated := cloneInvoice(*invoice)
+	// event_id, ticket_id, source and created_at are not updatable
+	updated.EventID, updated.TicketID, updated.CreatedAt = stored.EventID, stored.TicketID, stored.CreatedAt
+	updated.Source = stored.Source
+	r.s.invoices[invoice.ID] = updated
+	return nil
+}
+
+func (r *memoryUMARequestInvoiceRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.invoices, id)
+	return nil
+}
+
+// invoiceIDTaken reports whether an invoice other than exceptID uses
+// invoiceID. Callers hold the lock.
+func (r *memoryUMARequestInvoiceRepository) invoiceIDTaken(invoiceID string, exceptID int) bool {
+	for id, invoice := range r.s.invoices {
+		if id != exceptID && invoice.InvoiceID == invoiceID {
+			return true
+		}
+	}
+	return false
+}
+
+// invoiceForEvent returns a copy of the event's standing UMA invoice, or
+// nil. Callers hold the lock.
+func (s *MemoryStore) invoiceForEvent(eventID int) *models.UMARequestInvoice {
+	var found *models.UMARequestInvoice
+	for _, invoice := range s.invoices {
+		if invoice.EventID != nil && *invoice.EventID == eventID && invoice.Source == models.UMAInvoiceAdmin &&
+			(found == nil || invoice.ID > found.ID) {
+			copied := cloneInvoice(invoice)
+			found = &copied
+		}
+	}
+	if found == nil || found.Status == models.PaymentCancelled {
+		return nil
+	}
+	return found
+}
+
+// firstInvoice returns the lowest-ID invoice matching match. Callers hold the lock.
+func (s *MemoryStore) firstInvoice(match func(models.UMARequestInvoice) bool) (*models.UMARequestInvoice, error) {
+	var found *models.UMARequestInvoice
+	for _, invoice := range s.invoices {
+		if match(invoice) && (found == nil || invoice.ID < found.ID) {
+			copied := cloneInvoice(invoice)
+			found = &copied
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func cloneInvoice(invoice models.UMARequestInvoice) models.UMARequestInvoice {
+	invoice.EventID = clonePtr(invoice.EventID)
+	invoice.TicketID = clonePtr(invoice.TicketID)
+	invoice.ExpiresAt = clonePtr(invoice.ExpiresAt)
+	return invoice
+}
+
+// Ticket repository
+
+type memoryTicketRepository struct{ s *MemoryStore }
+
+func (r *memoryTicketRepository) Create(ticket *models.Ticket) error {
+	if err := checkTicketStatus(ticket.PaymentStatus); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.ticketCodeTaken(ticket.TicketCode, 0) {
+		return ErrConflict
+	}
+	if ticket.NeedsSeat {
+		available, ok := r.s.availableSeats(ticket.EventID)
+		if !ok {
+			return ErrNotFound
+		}
+		if available <= 0 {
+			return ErrSoldOut
+		}
+	}
+	if ticket.OnePerUser && r.holdsTicket(ticket.UserID, ticket.EventID) {
+		return ErrConflict
+	}
+	r.s.ticketSeq++
+	ticket.ID = r.s.ticketSeq
+	ticket.CreatedAt = r.s.clock.Now()
+	ticket.UpdatedAt = ticket.CreatedAt
+	stored := *ticket
+	// paid_at is not part of the insert
+	stored.PaidAt = nil
+	stored.OrderID = clonePtr(ticket.OrderID)
+	stored.PriceChangeID = clonePtr(ticket.PriceChangeID)
+	r.s.tickets[ticket.ID] = stored
+	return nil
+}
+
+func (r *memoryTicketRepository) GetByID(id int) (*models.Ticket, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	ticket, ok := r.s.tickets[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneTicket(ticket), nil
+}
+
+func (r *memoryTicketRepository) GetByIDs(ids []int) (map[int]*models.Ticket, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	byID := make(map[int]*models.Ticket, len(ids))
+	for _, id := range ids {
+		ticket, ok := r.s.tickets[id]
+		if !ok {
+			continue
+		}
+		byID[id] = cloneTicket(ticket)
+	}
+	return byID, nil
+}
+
+func (r *memoryTicketRepository) GetByTicketCode(ticketCode string) (*models.Ticket, error) {
+	return r.first(func(ticket models.Ticket) bool { return ticket.TicketCode == ticketCode })
+}
+
+func (r *memoryTicketRepository) GetByEventID(eventID int) ([]models.Ticket, error) {
+	return r.list(func(ticket models.Ticket) bool { return ticket.EventID == eventID }, true), nil
+}
+
+func (r *memoryTicketRepository) GetByUserID(userID int) ([]models.Ticket, error) {
+	return r.list(func(ticket models.Ticket) bool { return ticket.UserID == userID }, true), nil
+}
+
+func (r *memoryTicketRepository) ListByUser(userID int, list listquery.Query) ([]models.Ticket, error) {
+	tickets := r.list(func(ticket models.Ticket) bool { return ticket.UserID == userID }, true)
+	return listquery.Apply(tickets, TicketListFields, list, listquery.Order{Field: "created_at", Desc: true}), nil
+}
+
+func (r *memoryTicketRepository) GetByInvoiceID(invoiceID string) (*models.Ticket, error) {
+	return r.first(func(ticket models.Ticket) bool { return ticket.InvoiceID == invoiceID })
+}
+
+func (r *memoryTicketRepository) Update(ticket *models.Ticket) error {
+	if err := checkTicketStatus(ticket.PaymentStatus); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// A ticket's event never changes; SQL finds the row by it
+	stored, ok := r.s.tickets[ticket.ID]
+	if !ok || stored.EventID != ticket.EventID {
+		return nil
+	}
+	if r.ticketCodeTaken(ticket.TicketCode, ticket.ID) {
+		return ErrConflict
+	}
+	ticket.UpdatedAt = r.s.clock.Now()
+	updated := *cloneTicket(*ticket)
+	updated.CreatedAt = stored.CreatedAt
+	r.s.tickets[ticket.ID] = updated
+	return nil
+}
+
+func (r *memoryTicketRepository) UpdatePaymentStatus(id int, status string) error {
+	if err := checkTicketStatus(status); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ticket, ok := r.s.tickets[id]
+	if !ok {
+		return nil
+	}
+	if status == "paid" {
+		switch ticket.PaymentStatus {
+		case "paid":
+			return nil
+		case "pending", "expired", "review":
+		default:
+			return ErrConflict
+		}
+	}
+	now := r.s.clock.Now()
+	ticket.PaymentStatus, ticket.UpdatedAt, ticket.PaidAt = status, now, nil
+	if status == "paid" {
+		ticket.PaidAt = &now
+	}
+	r.s.tickets[id] = ticket
+	return nil
+}
+
+func (r *memoryTicketRepository) Reprice(id int, amountSats int64, priceChangeID *int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ticket, ok := r.s.tickets[id]
+	if !ok {
+		return nil
+	}
+	ticket.AmountSats, ticket.PriceChangeID, ticket.UpdatedAt = amountSats, clonePtr(priceChangeID), r.s.clock.Now()
+	r.s.tickets[id] = ticket
+	return nil
+}
+
+func (r *memoryTicketRepository) GetPendingTickets() ([]models.Ticket, error) {
+	return r.list(func(ticket models.Ticket) bool { return ticket.PaymentStatus == "pending" }, false), nil
+}
+
+func (r *memoryTicketRepository) CountByEventAndStatus(eventID int, status string) (int, error) {
+	tickets := r.list(func(ticket models.Ticket) bool {
+		return ticket.EventID == eventID && ticket.PaymentStatus == status
+	}, false)
+	return len(tickets), nil
+}
+
+func (r *memoryTicketRepository) HasUserTicketForEvent(userID, eventID int) (bool, error) {
+	_, err := r.first(func(ticket models.Ticket) bool {
+		return ticket.UserID == userID && ticket.EventID == eventID
+	})
+	return err == nil, nil
+}
+
+func (r *memoryTicketRepository) HasActiveTicketForEvent(userID, eventID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.holdsTicket(userID, eventID), nil
+}
+
+// holdsTicket reports whether the user has a ticket for the event that is
+// not failed, expired or cancelled. The caller holds the lock.
+func (r *memoryTicketRepository) holdsTicket(userID, eventID int) bool {
+	for _, ticket := range r.s.tickets {
+		if ticket.UserID != userID || ticket.EventID != eventID {
+			continue
+		}
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review", "disputed":
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryTicketRepository) first(match func(models.Ticket) bool) (*models.Ticket, error) {
+	tickets := r.list(match, false)
+	if len(tickets) == 0 {
+		return nil, ErrNotFound
+	}
+	return &tickets[0], nil
+}
+
+// list returns matching tickets ordered by creation time
+func (r *memoryTicketRepository) list(match func(models.Ticket) bool, newestFirst bool) []models.Ticket {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tickets := []models.Ticket{}
+	for _, ticket := range r.s.tickets {
+		if match(ticket) {
+			tickets = append(tickets, *cloneTicket(ticket))
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return createdBefore(tickets[i].CreatedAt, tickets[i].ID, tickets[j].CreatedAt, tickets[j].ID) != newestFirst
+	})
+	return tickets
+}
+
+// ticketCodeTaken reports whether a ticket other than exceptID uses code. Callers hold the lock.
+func (r *memoryTicketRepository) ticketCodeTaken(code string, exceptID int) bool {
+	for id, ticket := range r.s.tickets {
+		if id != exceptID && ticket.TicketCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneTicket(ticket models.Ticket) *models.Ticket {
+	ticket.PaidAt = clonePtr(ticket.PaidAt)
+	ticket.OrderID = clonePtr(ticket.OrderID)
+	ticket.PriceChangeID = clonePtr(ticket.PriceChangeID)
+	return &ticket
+}
+
+// createdBefore orders rows by creation time, breaking ties by ID like a SERIAL column would
+func createdBefore(a time.Time, aID int, b time.Time, bID int) bool {
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	return aID < bID
+}
+
+// NWC connection repository
+
+type memoryNWCConnectionRepository struct{ s *MemoryStore }
+
+func (r *memoryNWCConnectionRepository) Upsert(userID int, connectionURI string, expiresAt *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	conn, ok := r.s.nwc[userID]
+	if !ok {
+		r.s.nwcSeq++
+		conn = models.NWCConnection{ID: r.s.nwcSeq, UserID: userID, CreatedAt: now}
+	}
+	conn.ConnectionURI, conn.ExpiresAt, conn.UpdatedAt = connectionURI, clonePtr(expiresAt), now
+	r.s.nwc[userID] = conn
+	return nil
+}
+
+func (r *memoryNWCConnectionRepository) GetByUserID(userID int) (*models.NWCConnection, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	conn, ok := r.s.nwc[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	conn.ExpiresAt = clonePtr(conn.ExpiresAt)
+	return &conn, nil
+}
+
+// Reencrypt is a no-op: connection URIs never leave process memory
+func (r *memoryNWCConnectionRepository) Reencrypt() (int, error) {
+	return 0, nil
+}
+
+// Payment repository
+
+type memoryPaymentRepository struct{ s *MemoryStore }
+
+func (r *memoryPaymentRepository) Create(payment *models.Payment) error {
+	if err := checkPaymentStatus(payment.Status); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.paymentSeq++
+	payment.ID = r.s.paymentSeq
+	payment.CreatedAt = r.s.clock.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	stored := *payment
+	stored.ExpiresAt = clonePtr(payment.ExpiresAt)
+	// preimage, paid_at and the fiat value are not part of the insert
+	stored.Preimage, stored.PaidAt = nil, nil
+	stored.FiatCurrency, stored.FiatAmount, stored.ExchangeRateID = "", nil, nil
+	r.s.payments[payment.ID] = stored
+	return nil
+}
+
+func (r *memoryPaymentRepository) GetByID(id int) (*models.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clonePayment(payment), nil
+}
+
+func (r *memoryPaymentRepository) GetByIDs(ids []int) (map[int]*models.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	byID := make(map[int]*models.Payment, len(ids))
+	for _, id := range ids {
+		payment, ok := r.s.payments[id]
+		if !ok {
+			continue
+		}
+		byID[id] = clonePayment(payment)
+	}
+	return byID, nil
+}
+
+func (r *memoryPaymentRepository) GetByInvoiceID(invoiceID string) (*models.Payment, error) {
+	return r.first(func(payment models.Payment) bool { return payment.InvoiceID == invoiceID })
+}
+
+func (r *memoryPaymentRepository) GetByBolt11(bolt11 string) (*models.Payment, error) {
+	return r.first(func(payment models.Payment) bool { return payment.Bolt11 == bolt11 })
+}
+
+func (r *memoryPaymentRepository) GetByPaymentHash(paymentHash string) (*models.Payment, error) {
+	return r.first(func(payment models.Payment) bool { return payment.PaymentHash == paymentHash })
+}
+
+func (r *memoryPaymentRepository) GetByTicketID(ticketID int) (*models.Payment, error) {
+	return r.first(func(payment models.Payment) bool { return payment.TicketID == ticketID })
+}
+
+func (r *memoryPaymentRepository) Update(payment *models.Payment) error {
+	if err := checkPaymentStatus(payment.Status); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// A payment's created_at never changes; SQL finds the row by it
+	stored, ok := r.s.payments[payment.ID]
+	if !ok || !stored.CreatedAt.Equal(payment.CreatedAt) {
+		return nil
+	}
+	payment.UpdatedAt = r.s.clock.Now()
+	stored.TicketID, stored.InvoiceID, stored.Amount, stored.Status = payment.TicketID, payment.InvoiceID, payment.Amount, payment.Status
+	stored.Bolt11, stored.PaymentHash = payment.Bolt11, payment.PaymentHash
+	stored.PaidAt, stored.UpdatedAt = clonePtr(payment.PaidAt), payment.UpdatedAt
+	stored.ExpiresAt = clonePtr(payment.ExpiresAt)
+	stored.TaxableSats, stored.TaxSats = payment.TaxableSats, payment.TaxSats
+	stored.TaxBasisPoints, stored.TaxInclusive, stored.TaxJurisdiction = payment.TaxBasisPoints, payment.TaxInclusive, payment.TaxJurisdiction
+	r.s.payments[payment.ID] = stored
+	return nil
+}
+
+func (r *memoryPaymentRepository) UpdateStatus(id int, status string) error {
+	if err := checkPaymentStatus(status); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok {
+		return nil
+	}
+	if status == "paid" {
+		switch payment.Status {
+		case "paid":
+			return nil
+		case "pending", "expired":
+		default:
+			return ErrConflict
+		}
+	}
+	now := r.s.clock.Now()
+	payment.Status, payment.UpdatedAt, payment.PaidAt = status, now, nil
+	if status == "paid" {
+		payment.PaidAt = &now
+	}
+	r.s.payments[id] = payment
+	return nil
+}
+
+func (r *memoryPaymentRepository) UpdatePreimage(id int, preimage string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if payment, ok := r.s.payments[id]; ok {
+		payment.Preimage = &preimage
+		payment.UpdatedAt = r.s.clock.Now()
+		r.s.payments[id] = payment
+	}
+	return nil
+}
+
+func (r *memoryPaymentRepository) GetAllPayments() ([]models.Payment, error) {
+	return r.list(func(models.Payment) bool { return true }, true), nil
+}
+
+// StreamAll iterates over a snapshot so fn may call back into the repository
+func (r *memoryPaymentRepository) StreamAll(list listquery.Query, fn func(*models.Payment) error) error {
+	payments := r.list(func(models.Payment) bool { return true }, true)
+	for _, payment := range listquery.Apply(payments, PaymentListFields, list, listquery.Order{Field: "created_at", Desc: true}) {
+		if err := fn(&payment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryPaymentRepository) GetPendingPayments() ([]models.Payment, error) {
+	return r.list(func(payment models.Payment) bool { return payment.Status == "pending" }, false), nil
+}
+
+func (r *memoryPaymentRepository) ExpireOverdue() ([]models.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	expired := []models.Payment{}
+	for id, payment := range r.s.payments {
+		if payment.Status != "pending" || payment.ExpiresAt == nil || payment.ExpiresAt.After(now) {
+			continue
+		}
+		payment.Status, payment.UpdatedAt = "expired", now
+		r.s.payments[id] = payment
+		expired = append(expired, *clonePayment(payment))
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	return expired, nil
+}
+
+func (r *memoryPaymentRepository) GetAvailablePaymentForEvent(eventID int) (*models.Payment, error) {
+	// Payments are not pre-created with the UMA Request pattern
+	return nil, ErrNotFound
+}
+
+func (r *memoryPaymentRepository) GetOldestPendingByAmount(amountSats int64) (*models.Payment, error) {
+	return r.first(func(payment models.Payment) bool {
+		return payment.Status == "pending" && payment.Amount == amountSats
+	})
+}
+
+func (r *memoryPaymentRepository) TaxSummary(from, to *time.Time) ([]models.TaxSummary, error) {
+	payments := r.list(func(payment models.Payment) bool {
+		return payment.Status == "paid" && payment.TaxBasisPoints > 0 && payment.PaidAt != nil &&
+			(from == nil || !payment.PaidAt.Before(*from)) && (to == nil || payment.PaidAt.Before(*to))
+	}, false)
+
+	type key struct {
+		jurisdiction string
+		basisPoints  int64
+	}
+	byRate := make(map[key]*models.TaxSummary)
+	for _, payment := range payments {
+		k := key{payment.TaxJurisdiction, payment.TaxBasisPoints}
+		summary, ok := byRate[k]
+		if !ok {
+			summary = &models.TaxSummary{Jurisdiction: k.jurisdiction, BasisPoints: k.basisPoints}
+			byRate[k] = summary
+		}
+		summary.Payments++
+		summary.TaxableSats += payment.TaxableSats
+		summary.TaxSats += payment.TaxSats
+	}
+
+	report := make([]models.TaxSummary, 0, len(byRate))
+	for _, summary := range byRate {
+		report = append(report, *summary)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Jurisdiction != report[j].Jurisdiction {
+			return report[i].Jurisdiction < report[j].Jurisdiction
+		}
+		return report[i].BasisPoints < report[j].BasisPoints
+	})
+	return report, nil
+}
+
+func (r *memoryPaymentRepository) first(match func(models.Payment) bool) (*models.Payment, error) {
+	payments := r.list(match, false)
+	if len(payments) == 0 {
+		return nil, ErrNotFound
+	}
+	return &payments[0], nil
+}
+
+// list returns matching payments ordered by creation time
+func (r *memoryPaymentRepository) list(match func(models.Payment) bool, newestFirst bool) []models.Payment {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	payments := []models.Payment{}
+	for _, payment := range r.s.payments {
+		if match(payment) {
+			payments = append(payments, *clonePayment(payment))
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		return createdBefore(payments[i].CreatedAt, payments[i].ID, payments[j].CreatedAt, payments[j].ID) != newestFirst
+	})
+	return payments
+}
+
+func clonePayment(payment models.Payment) *models.Payment {
+	payment.Preimage = clonePtr(payment.Preimage)
+	payment.PaidAt = clonePtr(payment.PaidAt)
+	payment.FiatAmount = clonePtr(payment.FiatAmount)
+	payment.ExchangeRateID = clonePtr(payment.ExchangeRateID)
+	payment.ExpiresAt = clonePtr(payment.ExpiresAt)
+	return &payment
+}
+
+// Settings repository
+
+type memorySettingsRepository struct{ s *MemoryStore }
+
+func (r *memorySettingsRepository) GetAll() ([]models.Setting, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	settings := make([]models.Setting, 0, len(r.s.settings))
+	for _, setting := range r.s.settings {
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings, nil
+}
+
+func (r *memorySettingsRepository) Get(key string) (*models.Setting, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	setting, ok := r.s.settings[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &setting, nil
+}
+
+func (r *memorySettingsRepository) Upsert(setting *models.Setting) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	setting.UpdatedAt = r.s.clock.Now()
+	r.s.settings[setting.Key] = *setting
+	return nil
+}
+
+func (r *memorySettingsRepository) Delete(key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.settings, key)
+	return nil
+}
+
+// Fraud flag repository
+
+type memoryFraudFlagRepository struct{ s *MemoryStore }
+
+func cloneFraudFlag(flag models.FraudFlag) models.FraudFlag {
+	flag.TicketID = clonePtr(flag.TicketID)
+	flag.Signals = append(models.FraudSignals(nil), flag.Signals...)
+	return flag
+}
+
+func (r *memoryFraudFlagRepository) Create(flag *models.FraudFlag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.flagSeq++
+	flag.ID = r.s.flagSeq
+	flag.CreatedAt = r.s.clock.Now()
+	r.s.flags[flag.ID] = cloneFraudFlag(*flag)
+	return nil
+}
+
+func (r *memoryFraudFlagRepository) List(action string, limit, offset int) ([]models.FraudFlag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var flags []models.FraudFlag
+	for _, flag := range r.s.flags {
+		if action == "" || flag.Action == action {
+			flags = append(flags, cloneFraudFlag(flag))
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].CreatedAt.Equal(flags[j].CreatedAt) {
+			return flags[i].CreatedAt.After(flags[j].CreatedAt)
+		}
+		return flags[i].ID > flags[j].ID
+	})
+	start, end := page(len(flags), limit, offset)
+	return flags[start:end], nil
+}
+
+func (r *memoryFraudFlagRepository) ListAwaitingReview(limit, offset int) ([]models.FraudFlag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var flags []models.FraudFlag
+	for _, flag := range r.s.flags {
+		if flag.TicketID == nil {
+			continue
+		}
+		if ticket, ok := r.s.tickets[*flag.TicketID]; ok && ticket.PaymentStatus == "review" {
+			flags = append(flags, cloneFraudFlag(flag))
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].CreatedAt.Equal(flags[j].CreatedAt) {
+			return flags[i].CreatedAt.Before(flags[j].CreatedAt)
+		}
+		return flags[i].ID < flags[j].ID
+	})
+	start, end := page(len(flags), limit, offset)
+	return flags[start:end], nil
+}
+
+// Add-on repository
+
+type memoryAddOnRepository struct{ s *MemoryStore }
+
+func (r *memoryAddOnRepository) Create(addOn *models.AddOn) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.addOnSeq++
+	addOn.ID = r.s.addOnSeq
+	addOn.CreatedAt = r.s.clock.Now()
+	addOn.UpdatedAt = addOn.CreatedAt
+	stored := *addOn
+	stored.FlexCutoffHours = clonePtr(addOn.FlexCutoffHours)
+	r.s.addOns[addOn.ID] = stored
+	return nil
+}
+
+func (r *memoryAddOnRepository) GetByID(id int) (*models.AddOn, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	addOn, ok := r.s.addOns[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	addOn.FlexCutoffHours = clonePtr(addOn.FlexCutoffHours)
+	return &addOn, nil
+}
+
+func (r *memoryAddOnRepository) GetByEventID(eventID int, activeOnly bool) ([]models.AddOn, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	addOns := []models.AddOn{}
+	for _, addOn := range r.s.addOns {
+		if addOn.EventID == eventID && (addOn.IsActive || !activeOnly) {
+			addOn.FlexCutoffHours = clonePtr(addOn.FlexCutoffHours)
+			addOns = append(addOns, addOn)
+		}
+	}
+	sort.Slice(addOns, func(i, j int) bool {
+		if addOns[i].Name != addOns[j].Name {
+			return addOns[i].Name < addOns[j].Name
+		}
+		return addOns[i].ID < addOns[j].ID
+	})
+	return addOns, nil
+}
+
+func (r *memoryAddOnRepository) Update(addOn *models.AddOn) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.addOns[addOn.ID]
+	if !ok {
+		return nil
+	}
+	addOn.UpdatedAt = r.s.clock.Now()
+	stored.Name, stored.Description = addOn.Name, addOn.Description
+	stored.PriceSats, stored.IsActive, stored.UpdatedAt = addOn.PriceSats, addOn.IsActive, addOn.UpdatedAt
+	stored.FlexCutoffHours = clonePtr(addOn.FlexCutoffHours)
+	r.s.addOns[addOn.ID] = stored
+	return nil
+}
+
+func (r *memoryAddOnRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.deleteAddOn(id)
+	return nil
+}
+
+// deleteAddOn removes an add-on, detaching line items that reference it
+// (ticket_addons.addon_id is ON DELETE SET NULL). Callers hold the lock.
+func (s *MemoryStore) deleteAddOn(id int) {
+	delete(s.addOns, id)
+	for itemID, item := range s.items {
+		if item.AddOnID != nil && *item.AddOnID == id {
+			item.AddOnID = nil
+			s.items[itemID] = item
+		}
+	}
+}
+
+func (r *memoryAddOnRepository) CreateLineItems(items []models.TicketAddOn) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for i := range items {
+		r.s.itemSeq++
+		items[i].ID = r.s.itemSeq
+		items[i].CreatedAt = now
+		item := items[i]
+		item.AddOnID = clonePtr(item.AddOnID)
+		item.FlexCutoffHours = clonePtr(item.FlexCutoffHours)
+		r.s.items[item.ID] = item
+	}
+	return nil
+}
+
+func (r *memoryAddOnRepository) GetLineItems(ticketID int) ([]models.TicketAddOn, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	items := []models.TicketAddOn{}
+	for _, item := range r.s.items {
+		if item.TicketID == ticketID {
+			item.AddOnID = clonePtr(item.AddOnID)
+			item.FlexCutoffHours = clonePtr(item.FlexCutoffHours)
+			item.FlexUsedAt = clonePtr(item.FlexUsedAt)
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *memoryAddOnRepository) UseFlex(itemID int) (time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	item, ok := r.s.items[itemID]
+	if !ok || item.FlexCutoffHours == nil || item.FlexUsedAt != nil {
+		return time.Time{}, ErrNotFound
+	}
+	now := r.s.clock.Now()
+	item.FlexUsedAt = &now
+	r.s.items[itemID] = item
+	return now, nil
+}
+
+func (r *memoryAddOnRepository) FlexUptake(filter models.FlexFilter) ([]models.FlexUptake, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	flex := make(map[int]models.TicketAddOn)
+	for _, item := range r.s.items {
+		if item.FlexCutoffHours != nil {
+			flex[item.TicketID] = item
+		}
+	}
+	byEvent := make(map[int]*models.FlexUptake)
+	for _, ticket := range r.s.tickets {
+		item, bought := flex[ticket.ID]
+		used := bought && item.FlexUsedAt != nil
+		if !(ticket.PaymentStatus == "paid" && !ticket.IsComp) && !used {
+			continue
+		}
+		if filter.EventID != 0 && ticket.EventID != filter.EventID {
+			continue
+		}
+		event := r.s.events[ticket.EventID]
+		if filter.OrganizerID != 0 && (event.OrganizerID == nil || *event.OrganizerID != filter.OrganizerID) {
+			continue
+		}
+
+		uptake, ok := byEvent[event.ID]
+		if !ok {
+			uptake = &models.FlexUptake{EventID: event.ID, EventTitle: event.Title}
+			byEvent[event.ID] = uptake
+		}
+		uptake.TicketsSold++
+		if bought {
+			uptake.FlexTickets++
+			if ticket.PaymentStatus == "paid" {
+				uptake.FlexRevenueSats += item.TotalSats()
+			}
+		}
+		if used {
+			uptake.FlexCancellations++
+		}
+	}
+
+	uptake := make([]models.FlexUptake, 0, len(byEvent))
+	for _, event := range byEvent {
+		event.UptakeRate = float64(event.FlexTickets) / float64(event.TicketsSold)
+		uptake = append(uptake, *event)
+	}
+	sort.Slice(uptake, func(i, j int) bool { return uptake[i].EventID < uptake[j].EventID })
+	return uptake, nil
+}
+
+// Form field repository
+
+type memoryFormFieldRepository struct{ s *MemoryStore }
+
+func cloneFormField(field models.FormField) models.FormField {
+	field.Options = append(models.FormFieldOptions(nil), field.Options...)
+	return field
+}
+
+func (r *memoryFormFieldRepository) Create(field *models.FormField) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.fieldSeq++
+	field.ID = r.s.fieldSeq
+	field.CreatedAt = r.s.clock.Now()
+	field.UpdatedAt = field.CreatedAt
+	r.s.fields[field.ID] = cloneFormField(*field)
+	return nil
+}
+
+func (r *memoryFormFieldRepository) GetByID(id int) (*models.FormField, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	field, ok := r.s.fields[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	field = cloneFormField(field)
+	return &field, nil
+}
+
+func (r *memoryFormFieldRepository) GetByEventID(eventID int) ([]models.FormField, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	fields := []models.FormField{}
+	for _, field := range r.s.fields {
+		if field.EventID == eventID {
+			fields = append(fields, cloneFormField(field))
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Position != fields[j].Position {
+			return fields[i].Position < fields[j].Position
+		}
+		return fields[i].ID < fields[j].ID
+	})
+	return fields, nil
+}
+
+func (r *memoryFormFieldRepository) Update(field *models.FormField) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.fields[field.ID]
+	if !ok {
+		return nil
+	}
+	field.UpdatedAt = r.s.clock.Now()
+	stored.Label, stored.FieldType, stored.Options = field.Label, field.FieldType, field.Options
+	stored.Required, stored.Position, stored.UpdatedAt = field.Required, field.Position, field.UpdatedAt
+	r.s.fields[field.ID] = cloneFormField(stored)
+	return nil
+}
+
+func (r *memoryFormFieldRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.deleteFormField(id)
+	return nil
+}
+
+// deleteFormField removes a form field, detaching answers that reference it
+// (ticket_answers.field_id is ON DELETE SET NULL). Callers hold the lock.
+func (s *MemoryStore) deleteFormField(id int) {
+	delete(s.fields, id)
+	for answerID, answer := range s.answers {
+		if answer.FieldID != nil && *answer.FieldID == id {
+			answer.FieldID = nil
+			s.answers[answerID] = answer
+		}
+	}
+}
+
+func (r *memoryFormFieldRepository) CreateAnswers(answers []models.TicketAnswer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for i := range answers {
+		r.s.answerSeq++
+		answers[i].ID = r.s.answerSeq
+		answers[i].CreatedAt = now
+		answer := answers[i]
+		answer.FieldID = clonePtr(answer.FieldID)
+		r.s.answers[answer.ID] = answer
+	}
+	return nil
+}
+
+func (r *memoryFormFieldRepository) GetAnswers(ticketID int) ([]models.TicketAnswer, error) {
+	return r.answersWhere(func(answer models.TicketAnswer) bool { return answer.TicketID == ticketID }), nil
+}
+
+func (r *memoryFormFieldRepository) GetAnswersByEventID(eventID int) ([]models.TicketAnswer, error) {
+	r.s.mu.RLock()
+	tickets := make(map[int]bool)
+	for _, ticket := range r.s.tickets {
+		if ticket.EventID == eventID {
+			tickets[ticket.ID] = true
+		}
+	}
+	r.s.mu.RUnlock()
+
+	return r.answersWhere(func(answer models.TicketAnswer) bool { return tickets[answer.TicketID] }), nil
+}
+
+// answersWhere returns the matching answers ordered by ticket, then as given
+func (r *memoryFormFieldRepository) answersWhere(match func(models.TicketAnswer) bool) []models.TicketAnswer {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	answers := []models.TicketAnswer{}
+	for _, answer := range r.s.answers {
+		if match(answer) {
+			answer.FieldID = clonePtr(answer.FieldID)
+			answers = append(answers, answer)
+		}
+	}
+	sort.Slice(answers, func(i, j int) bool {
+		if answers[i].TicketID != answers[j].TicketID {
+			return answers[i].TicketID < answers[j].TicketID
+		}
+		return answers[i].ID < answers[j].ID
+	})
+	return answers
+}
+
+// Event access repository
+
+type memoryEventAccessRepository struct{ s *MemoryStore }
+
+func cloneAccessCode(code models.EventAccessCode) models.EventAccessCode {
+	code.MaxUses = clonePtr(code.MaxUses)
+	code.ExpiresAt = clonePtr(code.ExpiresAt)
+	return code
+}
+
+func (r *memoryEventAccessRepository) CreateCode(code *models.EventAccessCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.codes {
+		if existing.EventID == code.EventID && existing.Code == code.Code {
+			return ErrConflict
+		}
+	}
+	r.s.codeSeq++
+	code.ID = r.s.codeSeq
+	code.Uses = 0
+	code.CreatedAt = r.s.clock.Now()
+	r.s.codes[code.ID] = cloneAccessCode(*code)
+	return nil
+}
+
+func (r *memoryEventAccessRepository) GetCode(id int) (*models.EventAccessCode, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	code, ok := r.s.codes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	code = cloneAccessCode(code)
+	return &code, nil
+}
+
+func (r *memoryEventAccessRepository) GetCodes(eventID int) ([]models.EventAccessCode, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	codes := []models.EventAccessCode{}
+	for _, code := range r.s.codes {
+		if code.EventID == eventID {
+			codes = append(codes, cloneAccessCode(code))
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].ID < codes[j].ID })
+	return codes, nil
+}
+
+func (r *memoryEventAccessRepository) DeleteCode(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.codes, id)
+	return nil
+}
+
+func (r *memoryEventAccessRepository) RedeemCode(eventID int, code string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, stored := range r.s.codes {
+		if stored.EventID != eventID || stored.Code != code {
+			continue
+		}
+		if stored.MaxUses != nil && stored.Uses >= *stored.MaxUses {
+			return ErrConflict
+		}
+		if stored.ExpiresAt != nil && !stored.ExpiresAt.After(r.s.clock.Now()) {
+			return ErrConflict
+		}
+		stored.Uses++
+		r.s.codes[id] = stored
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (r *memoryEventAccessRepository) ReleaseCode(eventID int, code string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, stored := range r.s.codes {
+		if stored.EventID == eventID && stored.Code == code && stored.Uses > 0 {
+			stored.Uses--
+			r.s.codes[id] = stored
+		}
+	}
+	return nil
+}
+
+func (r *memoryEventAccessRepository) AddToAllowlist(eventID int, entries []string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	listed := make(map[string]bool)
+	for _, entry := range r.s.allowed {
+		if entry.EventID == eventID {
+			listed[entry.Entry] = true
+		}
+	}
+	now := r.s.clock.Now()
+	for _, entry := range entries {
+		if listed[entry] {
+			continue
+		}
+		listed[entry] = true
+		r.s.allowedSeq++
+		r.s.allowed[r.s.allowedSeq] = models.EventAllowlistEntry{ID: r.s.allowedSeq, EventID: eventID, Entry: entry, CreatedAt: now}
+	}
+	return nil
+}
+
+func (r *memoryEventAccessRepository) GetAllowlistEntry(id int) (*models.EventAllowlistEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entry, ok := r.s.allowed[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (r *memoryEventAccessRepository) GetAllowlist(eventID int) ([]models.EventAllowlistEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entries := []models.EventAllowlistEntry{}
+	for _, entry := range r.s.allowed {
+		if entry.EventID == eventID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Entry < entries[j].Entry })
+	return entries, nil
+}
+
+func (r *memoryEventAccessRepository) DeleteAllowlistEntry(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.allowed, id)
+	return nil
+}
+
+func (r *memoryEventAccessRepository) IsAllowlisted(eventID, userID int, umaAddress string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	candidates := []string{strings.ToLower(strings.TrimSpace(umaAddress))}
+	if user, ok := r.s.users[userID]; ok {
+		candidates = append(candidates, strings.ToLower(user.Email))
+	}
+	for _, entry := range r.s.allowed {
+		if entry.EventID == eventID && slices.Contains(candidates, entry.Entry) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Attestation repository
+
+type memoryAttestationRepository struct{ s *MemoryStore }
+
+func (r *memoryAttestationRepository) Create(attestation *models.PurchaseAttestation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// purchase_attestations.ticket_id is UNIQUE
+	for _, existing := range r.s.attested {
+		if existing.TicketID == attestation.TicketID {
+			return ErrConflict
+		}
+	}
+	r.s.attestedSeq++
+	attestation.ID = r.s.attestedSeq
+	attestation.AttestedAt = r.s.clock.Now()
+	r.s.attested[attestation.ID] = *attestation
+	return nil
+}
+
+func (r *memoryAttestationRepository) GetByTicketID(ticketID int) (*models.PurchaseAttestation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, attestation := range r.s.attested {
+		if attestation.TicketID == ticketID {
+			return &attestation, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAttestationRepository) GetByEventID(eventID int) ([]models.PurchaseAttestation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	attestations := []models.PurchaseAttestation{}
+	for _, attestation := range r.s.attested {
+		if attestation.EventID == eventID {
+			attestations = append(attestations, attestation)
+		}
+	}
+	sort.Slice(attestations, func(i, j int) bool { return attestations[i].TicketID < attestations[j].TicketID })
+	return attestations, nil
+}
+
+// Event translation repository
+
+type memoryEventTranslationRepository struct{ s *MemoryStore }
+
+func (r *memoryEventTranslationRepository) Upsert(translation *models.EventTranslation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	// event_translations (event_id, locale) is UNIQUE
+	for id, existing := range r.s.titles {
+		if existing.EventID == translation.EventID && existing.Locale == translation.Locale {
+			existing.Title = translation.Title
+			existing.Description = translation.Description
+			existing.UpdatedAt = now
+			r.s.titles[id] = existing
+			*translation = existing
+			return nil
+		}
+	}
+	r.s.titleSeq++
+	translation.ID = r.s.titleSeq
+	translation.CreatedAt = now
+	translation.UpdatedAt = now
+	r.s.titles[translation.ID] = *translation
+	return nil
+}
+
+func (r *memoryEventTranslationRepository) Get(eventID int, locale string) (*models.EventTranslation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, translation := range r.s.titles {
+		if translation.EventID == eventID && translation.Locale == locale {
+			return &translation, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryEventTranslationRepository) GetByEventID(eventID int) ([]models.EventTranslation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	translations := []models.EventTranslation{}
+	for _, translation := range r.s.titles {
+		if translation.EventID == eventID {
+			translations = append(translations, translation)
+		}
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Locale < translations[j].Locale })
+	return translations, nil
+}
+
+func (r *memoryEventTranslationRepository) Delete(eventID int, locale string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, translation := range r.s.titles {
+		if translation.EventID == eventID && translation.Locale == locale {
+			delete(r.s.titles, id)
+		}
+	}
+	return nil
+}
+
+// Event reschedule repository
+
+type memoryEventRescheduleRepository struct{ s *MemoryStore }
+
+func cloneEventReschedule(reschedule models.EventReschedule) *models.EventReschedule {
+	reschedule.RefundUntil = clonePtr(reschedule.RefundUntil)
+	reschedule.RescheduledBy = clonePtr(reschedule.RescheduledBy)
+	return &reschedule
+}
+
+func (r *memoryEventRescheduleRepository) Create(reschedule *models.EventReschedule) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.moveSeq++
+	reschedule.ID = r.s.moveSeq
+	reschedule.CreatedAt = r.s.clock.Now()
+	r.s.moves[reschedule.ID] = *cloneEventReschedule(*reschedule)
+	return nil
+}
+
+func (r *memoryEventRescheduleRepository) GetByEventID(eventID int) ([]models.EventReschedule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	reschedules := []models.EventReschedule{}
+	for _, reschedule := range r.s.moves {
+		if reschedule.EventID == eventID {
+			reschedules = append(reschedules, *cloneEventReschedule(reschedule))
+		}
+	}
+	sort.Slice(reschedules, func(i, j int) bool {
+		if !reschedules[i].CreatedAt.Equal(reschedules[j].CreatedAt) {
+			return reschedules[i].CreatedAt.After(reschedules[j].CreatedAt)
+		}
+		return reschedules[i].ID > reschedules[j].ID
+	})
+	return reschedules, nil
+}
+
+// Event cancellation repository
+
+type memoryEventCancellationRepository struct{ s *MemoryStore }
+
+func cloneEventCancellation(cancellation models.EventCancellation) *models.EventCancellation {
+	cancellation.CancelledBy = clonePtr(cancellation.CancelledBy)
+	cancellation.CompletedAt = clonePtr(cancellation.CompletedAt)
+	return &cancellation
+}
+
+func (r *memoryEventCancellationRepository) Create(cancellation *models.EventCancellation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// event_cancellations.event_id is UNIQUE
+	if _, ok := r.s.cancels[cancellation.EventID]; ok {
+		return ErrConflict
+	}
+	r.s.cancelSeq++
+	cancellation.ID = r.s.cancelSeq
+	if cancellation.Status == "" {
+		cancellation.Status = models.CancellationRunning
+	}
+	cancellation.CreatedAt = r.s.clock.Now()
+	cancellation.UpdatedAt = cancellation.CreatedAt
+	r.s.cancels[cancellation.EventID] = *cloneEventCancellation(*cancellation)
+	return nil
+}
+
+func (r *memoryEventCancellationRepository) GetByEventID(eventID int) (*models.EventCancellation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	cancellation, ok := r.s.cancels[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneEventCancellation(cancellation), nil
+}
+
+func (r *memoryEventCancellationRepository) ListRunning() ([]models.EventCancellation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	cancellations := []models.EventCancellation{}
+	for _, cancellation := range r.s.cancels {
+		if cancellation.Status == models.CancellationRunning {
+			cancellations = append(cancellations, *cloneEventCancellation(cancellation))
+		}
+	}
+	sort.Slice(cancellations, func(i, j int) bool { return cancellations[i].ID < cancellations[j].ID })
+	return cancellations, nil
+}
+
+func (r *memoryEventCancellationRepository) Update(cancellation *models.EventCancellation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.cancels[cancellation.EventID]
+	if !ok || stored.ID != cancellation.ID {
+		return ErrNotFound
+	}
+	stored.Status, stored.Total = cancellation.Status, cancellation.Total
+	stored.Refunded, stored.Voided, stored.Failed = cancellation.Refunded, cancellation.Voided, cancellation.Failed
+	stored.LastError, stored.CompletedAt = cancellation.LastError, clonePtr(cancellation.CompletedAt)
+	stored.UpdatedAt = r.s.clock.Now()
+	r.s.cancels[cancellation.EventID] = stored
+	*cancellation = *cloneEventCancellation(stored)
+	return nil
+}
+
+// Ticket scan repository
+
+type memoryTicketScanRepository struct{ s *MemoryStore }
+
+func cloneTicketScan(scan models.TicketScan) *models.TicketScan {
+	scan.TicketID = clonePtr(scan.TicketID)
+	scan.ScannerDeviceID = clonePtr(scan.ScannerDeviceID)
+	return &scan
+}
+
+func (r *memoryTicketScanRepository) Create(scan *models.TicketScan) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.scanSeq++
+	scan.ID = r.s.scanSeq
+	scan.ScannedAt = r.s.clock.Now()
+	r.s.scans[scan.ID] = *cloneTicketScan(*scan)
+	return nil
+}
+
+// eventScans returns an event's scans that pass keep, oldest first. The
+// caller holds the lock.
+func (r *memoryTicketScanRepository) eventScans(eventID int, keep func(models.TicketScan) bool) []models.TicketScan {
+	scans := []models.TicketScan{}
+	for _, scan := range r.s.scans {
+		if scan.EventID == eventID && keep(scan) {
+			scans = append(scans, *cloneTicketScan(scan))
+		}
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		if !scans[i].ScannedAt.Equal(scans[j].ScannedAt) {
+			return scans[i].ScannedAt.Before(scans[j].ScannedAt)
+		}
+		return scans[i].ID < scans[j].ID
+	})
+	return scans
+}
+
+func (r *memoryTicketScanRepository) ListAdmitted(eventID int) ([]models.TicketScan, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.eventScans(eventID, func(scan models.TicketScan) bool { return scan.Admitted }), nil
+}
+
+func (r *memoryTicketScanRepository) ListRecent(eventID, limit int) ([]models.TicketScan, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	scans := r.eventScans(eventID, func(models.TicketScan) bool { return true })
+	recent := []models.TicketScan{}
+	for i := len(scans) - 1; i >= 0 && len(recent) < limit; i-- {
+		recent = append(recent, scans[i])
+	}
+	return recent, nil
+}
+
+// Scanner device repository
+
+type memoryScannerDeviceRepository struct{ s *MemoryStore }
+
+func cloneScannerDevice(device models.ScannerDevice) *models.ScannerDevice {
+	device.PairingHash = clonePtr(device.PairingHash)
+	device.PairingExpiresAt = clonePtr(device.PairingExpiresAt)
+	device.TokenHash = clonePtr(device.TokenHash)
+	device.RegisteredAt = clonePtr(device.RegisteredAt)
+	device.LastSeenAt = clonePtr(device.LastSeenAt)
+	device.RevokedAt = clonePtr(device.RevokedAt)
+	device.CreatedBy = clonePtr(device.CreatedBy)
+	return &device
+}
+
+func (r *memoryScannerDeviceRepository) Create(device *models.ScannerDevice) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.scannerSeq++
+	device.ID = r.s.scannerSeq
+	device.CreatedAt = r.s.clock.Now()
+	r.s.scanners[device.ID] = *cloneScannerDevice(*device)
+	return nil
+}
+
+func (r *memoryScannerDeviceRepository) GetByID(id int) (*models.ScannerDevice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	device, ok := r.s.scanners[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneScannerDevice(device), nil
+}
+
+func (r *memoryScannerDeviceRepository) GetByEventID(eventID int) ([]models.ScannerDevice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	devices := []models.ScannerDevice{}
+	for _, device := range r.s.scanners {
+		if device.EventID == eventID {
+			devices = append(devices, *cloneScannerDevice(device))
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	return devices, nil
+}
+
+func (r *memoryScannerDeviceRepository) Register(pairingHash, tokenHash string) (*models.ScannerDevice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for id, device := range r.s.scanners {
+		if device.PairingHash == nil || *device.PairingHash != pairingHash ||
+			!device.PairingExpiresAt.After(now) || device.RevokedAt != nil {
+			continue
+		}
+		device.PairingHash, device.PairingExpiresAt = nil, nil
+		device.TokenHash = &tokenHash
+		device.RegisteredAt, device.LastSeenAt = &now, &now
+		r.s.scanners[id] = device
+		return cloneScannerDevice(device), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryScannerDeviceRepository) GetByTokenHash(tokenHash string) (*models.ScannerDevice, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, device := range r.s.scanners {
+		if device.TokenHash != nil && *device.TokenHash == tokenHash && device.RevokedAt == nil {
+			return cloneScannerDevice(device), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryScannerDeviceRepository) Touch(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	device, ok := r.s.scanners[id]
+	if !ok {
+		return nil
+	}
+	now := r.s.clock.Now()
+	device.LastSeenAt = &now
+	r.s.scanners[id] = device
+	return nil
+}
+
+func (r *memoryScannerDeviceRepository) Revoke(id int) (*models.ScannerDevice, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	device, ok := r.s.scanners[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	device.PairingHash, device.PairingExpiresAt = nil, nil
+	if device.RevokedAt == nil {
+		now := r.s.clock.Now()
+		device.RevokedAt = &now
+	}
+	r.s.scanners[id] = device
+	return cloneScannerDevice(device), nil
+}
+
+// Inventory hold repository
+
+type memoryInventoryHoldRepository struct{ s *MemoryStore }
+
+func cloneInventoryHold(hold models.InventoryHold) *models.InventoryHold {
+	hold.CreatedBy = clonePtr(hold.CreatedBy)
+	hold.ReleasedAt = clonePtr(hold.ReleasedAt)
+	hold.ReleasedBy = clonePtr(hold.ReleasedBy)
+	return &hold
+}
+
+// heldSeats totals the seats of an event's unreleased holds. Callers hold
+// s.mu.
+// availableSeats is the event's capacity less its paid, pending, held and
+// disputed tickets and the seats held for it. It reports false for an
+// unknown event.
+func (s *MemoryStore) availableSeats(eventID int) (int, bool) {
+	event, ok := s.events[eventID]
+	if !ok {
+		return 0, false
+	}
+	available := event.Capacity - s.heldSeats(eventID)
+	for _, ticket := range s.tickets {
+		if ticket.EventID != eventID {
+			continue
+		}
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review", "disputed":
+			available--
+		}
+	}
+	return available, true
+}
+
+func (s *MemoryStore) heldSeats(eventID int) int {
+	held := 0
+	for _, hold := range s.holds {
+		if hold.EventID == eventID && hold.ReleasedAt == nil {
+			held += hold.Seats
+		}
+	}
+	return held
+}
+
+func (r *memoryInventoryHoldRepository) Create(hold *models.InventoryHold) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.events[hold.EventID]
+	if !ok {
+		return ErrNotFound
+	}
+	taken := r.s.heldSeats(hold.EventID)
+	for _, ticket := range r.s.tickets {
+		if ticket.EventID != hold.EventID {
+			continue
+		}
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review", "disputed":
+			taken++
+		}
+	}
+	if event.Capacity-hold.Seats < taken {
+		return ErrConflict
+	}
+
+	r.s.holdSeq++
+	hold.ID = r.s.holdSeq
+	hold.CreatedAt = r.s.clock.Now()
+	r.s.holds[hold.ID] = *cloneInventoryHold(*hold)
+	return nil
+}
+
+func (r *memoryInventoryHoldRepository) GetByID(id int) (*models.InventoryHold, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	hold, ok := r.s.holds[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneInventoryHold(hold), nil
+}
+
+func (r *memoryInventoryHoldRepository) GetByEventID(eventID int) ([]models.InventoryHold, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	holds := []models.InventoryHold{}
+	for _, hold := range r.s.holds {
+		if hold.EventID == eventID {
+			holds = append(holds, *cloneInventoryHold(hold))
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].ID < holds[j].ID })
+	return holds, nil
+}
+
+func (r *memoryInventoryHoldRepository) Release(id int, releasedBy *int) (*models.InventoryHold, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	hold, ok := r.s.holds[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if hold.ReleasedAt != nil {
+		return nil, ErrConflict
+	}
+	now := r.s.clock.Now()
+	hold.ReleasedAt, hold.ReleasedBy = &now, clonePtr(releasedBy)
+	r.s.holds[id] = hold
+	return cloneInventoryHold(hold), nil
+}
+
+// Event relation repository
+
+type memoryEventRelationRepository struct{ s *MemoryStore }
+
+// onPublicSale reports whether an event is sold publicly at now
+func onPublicSale(event models.Event, now time.Time) bool {
+	return event.IsActive && !event.IsPrivate && event.EndTime.After(now)
+}
+
+func (r *memoryEventRelationRepository) Set(eventID int, relatedIDs []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, relatedID := range relatedIDs {
+		if _, ok := r.s.events[relatedID]; !ok {
+			return ErrNotFound
+		}
+	}
+	r.s.related[eventID] = slices.Clone(relatedIDs)
+	return nil
+}
+
+func (r *memoryEventRelationRepository) GetRelatedIDs(eventID int) ([]int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return append([]int{}, r.s.related[eventID]...), nil
+}
+
+func (r *memoryEventRelationRepository) GetCurated(eventID int, now time.Time) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, relatedID := range r.s.related[eventID] {
+		if event, ok := r.s.events[relatedID]; ok && onPublicSale(event, now) {
+			event.OrganizerID = clonePtr(event.OrganizerID)
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryEventRelationRepository) GetSimilar(event *models.Event, now time.Time, limit int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, other := range r.s.events {
+		if other.ID == event.ID || !onPublicSale(other, now) {
+			continue
+		}
+		sameCategory := other.Category != "" && other.Category == event.Category
+		sameOrganizer := other.OrganizerID != nil && event.OrganizerID != nil && *other.OrganizerID == *event.OrganizerID
+		if sameCategory || sameOrganizer {
+			other.OrganizerID = clonePtr(other.OrganizerID)
+			events = append(events, other)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events[:min(limit, len(events))], nil
+}
+
+// Organizer profile repository
+
+type memoryOrganizerProfileRepository struct{ s *MemoryStore }
+
+func (r *memoryOrganizerProfileRepository) Save(profile *models.OrganizerProfile) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for userID, other := range r.s.profiles {
+		if userID != profile.UserID && other.Slug == profile.Slug {
+			return ErrConflict
+		}
+	}
+	now := r.s.clock.Now()
+	profile.CreatedAt = now
+	if existing, ok := r.s.profiles[profile.UserID]; ok {
+		profile.CreatedAt = existing.CreatedAt
+	}
+	profile.UpdatedAt = now
+	r.s.profiles[profile.UserID] = *profile
+	return nil
+}
+
+func (r *memoryOrganizerProfileRepository) GetByUserID(userID int) (*models.OrganizerProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	profile, ok := r.s.profiles[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &profile, nil
+}
+
+func (r *memoryOrganizerProfileRepository) GetBySlug(slug string) (*models.OrganizerProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, profile := range r.s.profiles {
+		if profile.Slug == slug {
+			return &profile, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOrganizerProfileRepository) IsOrganizer(userID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, event := range r.s.events {
+		if event.OrganizerID != nil && *event.OrganizerID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryOrganizerProfileRepository) GetUpcomingEvents(userID int, now time.Time, limit int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.Event{}
+	for _, event := range r.s.events {
+		if event.OrganizerID != nil && *event.OrganizerID == userID && onPublicSale(event, now) {
+			event.OrganizerID = clonePtr(event.OrganizerID)
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events[:min(limit, len(events))], nil
+}
+
+func (r *memoryOrganizerProfileRepository) CountPastEvents(userID int, now time.Time) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	count := 0
+	for _, event := range r.s.events {
+		if event.OrganizerID != nil && *event.OrganizerID == userID && !event.IsPrivate && event.CancelledAt == nil && !event.EndTime.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Affiliate repository
+
+type memoryAffiliateRepository struct{ s *MemoryStore }
+
+// forgetAffiliate clears a removed affiliate from the orders they referred,
+// like ON DELETE SET NULL. Callers hold s.mu.
+func (s *MemoryStore) forgetAffiliate(affiliateID int) {
+	for orderID, order := range s.orders {
+		if order.AffiliateID != nil && *order.AffiliateID == affiliateID {
+			order.AffiliateID = nil
+			s.orders[orderID] = order
+		}
+	}
+}
+
+// affiliateTaken reports whether another affiliate than exceptID is the
+// user or has the code. Callers hold s.mu.
+func (s *MemoryStore) affiliateTaken(affiliate *models.Affiliate, exceptID int) bool {
+	for id, other := range s.partners {
+		if id != exceptID && (other.UserID == affiliate.UserID || other.Code == affiliate.Code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryAffiliateRepository) Create(affiliate *models.Affiliate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.affiliateTaken(affiliate, 0) {
+		return ErrConflict
+	}
+	r.s.partnerSeq++
+	affiliate.ID = r.s.partnerSeq
+	affiliate.CreatedAt = r.s.clock.Now()
+	affiliate.UpdatedAt = affiliate.CreatedAt
+	r.s.partners[affiliate.ID] = *affiliate
+	return nil
+}
+
+func (r *memoryAffiliateRepository) GetByID(id int) (*models.Affiliate, error) {
+	return r.find(func(affiliate models.Affiliate) bool { return affiliate.ID == id })
+}
+
+func (r *memoryAffiliateRepository) GetByUserID(userID int) (*models.Affiliate, error) {
+	return r.find(func(affiliate models.Affiliate) bool { return affiliate.UserID == userID })
+}
+
+func (r *memoryAffiliateRepository) GetByCode(code string) (*models.Affiliate, error) {
+	return r.find(func(affiliate models.Affiliate) bool { return affiliate.Code == code })
+}
+
+func (r *memoryAffiliateRepository) find(match func(models.Affiliate) bool) (*models.Affiliate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, affiliate := range r.s.partners {
+		if match(affiliate) {
+			return &affiliate, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAffiliateRepository) Update(affiliate *models.Affiliate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.partners[affiliate.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	// The user cannot change
+	affiliate.UserID = stored.UserID
+	if r.s.affiliateTaken(affiliate, affiliate.ID) {
+		return ErrConflict
+	}
+	affiliate.CreatedAt = stored.CreatedAt
+	affiliate.UpdatedAt = r.s.clock.Now()
+	r.s.partners[affiliate.ID] = *affiliate
+	return nil
+}
+
+func (r *memoryAffiliateRepository) List() ([]models.AffiliateReport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	reports := []models.AffiliateReport{}
+	for _, affiliate := range r.s.partners {
+		reports = append(reports, r.report(affiliate))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID < reports[j].ID })
+	return reports, nil
+}
+
+func (r *memoryAffiliateRepository) GetReport(id int) (*models.AffiliateReport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	affiliate, ok := r.s.partners[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	report := r.report(affiliate)
+	return &report, nil
+}
+
+// report counts what an affiliate referred. The caller holds the lock.
+func (r *memoryAffiliateRepository) report(affiliate models.Affiliate) models.AffiliateReport {
+	report := models.AffiliateReport{Affiliate: affiliate, UserName: r.s.users[affiliate.UserID].Name}
+	for _, order := range r.s.orders {
+		if order.AffiliateID == nil || *order.AffiliateID != affiliate.ID {
+			continue
+		}
+		report.ReferredOrders++
+		for _, ticket := range r.s.tickets {
+			if ticket.OrderID != nil && *ticket.OrderID == order.ID && ticket.PaymentStatus == "paid" {
+				report.TicketsSold++
+			}
+		}
+	}
+	return report
+}
+
+// Short link repository
+
+type memoryShortLinkRepository struct{ s *MemoryStore }
+
+func cloneShortLink(link models.ShortLink) *models.ShortLink {
+	link.EventID = clonePtr(link.EventID)
+	link.TicketID = clonePtr(link.TicketID)
+	link.LastClickedAt = clonePtr(link.LastClickedAt)
+	link.CreatedBy = clonePtr(link.CreatedBy)
+	return &link
+}
+
+// generatedLink returns the generated link of an event or a ticket. Callers
+// hold s.mu.
+func (s *MemoryStore) generatedLink(eventID, ticketID *int) (models.ShortLink, bool) {
+	for _, link := range s.links {
+		if link.Custom {
+			continue
+		}
+		if (eventID != nil && link.EventID != nil && *link.EventID == *eventID) ||
+			(ticketID != nil && link.TicketID != nil && *link.TicketID == *ticketID) {
+			return link, true
+		}
+	}
+	return models.ShortLink{}, false
+}
+
+func (r *memoryShortLinkRepository) Create(link *models.ShortLink) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, other := range r.s.links {
+		if other.Code == link.Code {
+			return ErrConflict
+		}
+	}
+	if _, ok := r.s.generatedLink(link.EventID, link.TicketID); ok && !link.Custom {
+		return ErrConflict
+	}
+	r.s.linkSeq++
+	link.ID = r.s.linkSeq
+	link.Clicks, link.LastClickedAt = 0, nil
+	link.CreatedAt = r.s.clock.Now()
+	r.s.links[link.ID] = *cloneShortLink(*link)
+	return nil
+}
+
+func (r *memoryShortLinkRepository) GetByID(id int) (*models.ShortLink, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	link, ok := r.s.links[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneShortLink(link), nil
+}
+
+func (r *memoryShortLinkRepository) GetByCode(code string) (*models.ShortLink, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, link := range r.s.links {
+		if link.Code == code {
+			return cloneShortLink(link), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryShortLinkRepository) GetGeneratedForEvent(eventID int) (*models.ShortLink, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	link, ok := r.s.generatedLink(&eventID, nil)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneShortLink(link), nil
+}
+
+func (r *memoryShortLinkRepository) GetGeneratedForTicket(ticketID int) (*models.ShortLink, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	link, ok := r.s.generatedLink(nil, &ticketID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneShortLink(link), nil
+}
+
+func (r *memoryShortLinkRepository) GetByEventID(eventID int) ([]models.ShortLink, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	links := []models.ShortLink{}
+	for _, link := range r.s.links {
+		if link.EventID != nil && *link.EventID == eventID {
+			links = append(links, *cloneShortLink(link))
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
+
+func (r *memoryShortLinkRepository) UpdateCode(id int, code string) (*models.ShortLink, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	link, ok := r.s.links[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for otherID, other := range r.s.links {
+		if otherID != id && other.Code == code {
+			return nil, ErrConflict
+		}
+	}
+	link.Code, link.Custom = code, true
+	r.s.links[id] = link
+	return cloneShortLink(link), nil
+}
+
+func (r *memoryShortLinkRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.links[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.links, id)
+	return nil
+}
+
+func (r *memoryShortLinkRepository) Click(code string) (*models.ShortLink, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, link := range r.s.links {
+		if link.Code == code {
+			now := r.s.clock.Now()
+			link.Clicks++
+			link.LastClickedAt = &now
+			r.s.links[id] = link
+			return cloneShortLink(link), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Notification template repository
+
+type memoryNotificationTemplateRepository struct{ s *MemoryStore }
+
+func (r *memoryNotificationTemplateRepository) Create(template *models.NotificationTemplate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	template.Version = 1
+	for _, existing := range r.s.scope(template.OrganizerID, template.Kind, template.Locale) {
+		template.Version = max(template.Version, existing.Version+1)
+	}
+	r.s.noticeSeq++
+	template.ID = r.s.noticeSeq
+	template.CreatedAt = r.s.clock.Now()
+	stored := *template
+	stored.OrganizerID = clonePtr(template.OrganizerID)
+	stored.CreatedBy = clonePtr(template.CreatedBy)
+	r.s.notices[template.ID] = stored
+	return nil
+}
+
+func (r *memoryNotificationTemplateRepository) GetByID(id int) (*models.NotificationTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	template, ok := r.s.notices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneTemplate(template), nil
+}
+
+func (r *memoryNotificationTemplateRepository) GetCurrent(organizerID *int, kind, locale string) (*models.NotificationTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	versions := r.s.scope(organizerID, kind, locale)
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return cloneTemplate(versions[0]), nil
+}
+
+func (r *memoryNotificationTemplateRepository) GetVersions(organizerID *int, kind, locale string) ([]models.NotificationTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	templates := []models.NotificationTemplate{}
+	for _, template := range r.s.scope(organizerID, kind, locale) {
+		templates = append(templates, *cloneTemplate(template))
+	}
+	return templates, nil
+}
+
+func (r *memoryNotificationTemplateRepository) GetAllCurrent() ([]models.NotificationTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	type key struct {
+		organizerID  int
+		kind, locale string
+	}
+	current := make(map[key]models.NotificationTemplate)
+	for _, template := range r.s.notices {
+		k := key{scopeOf(template.OrganizerID), template.Kind, template.Locale}
+		if existing, ok := current[k]; !ok || template.Version > existing.Version {
+			current[k] = template
+		}
+	}
+
+	templates := []models.NotificationTemplate{}
+	for _, template := range current {
+		templates = append(templates, *cloneTemplate(template))
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		a, b := templates[i], templates[j]
+		if scopeOf(a.OrganizerID) != scopeOf(b.OrganizerID) {
+			return scopeOf(a.OrganizerID) < scopeOf(b.OrganizerID)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Locale < b.Locale
+	})
+	return templates, nil
+}
+
+func (r *memoryNotificationTemplateRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.notices, id)
+	return nil
+}
+
+// scope returns the versions of a template scope, newest first. Callers
+// hold the lock.
+func (s *MemoryStore) scope(organizerID *int, kind, locale string) []models.NotificationTemplate {
+	var versions []models.NotificationTemplate
+	for _, template := range s.notices {
+		if scopeOf(template.OrganizerID) == scopeOf(organizerID) && template.Kind == kind && template.Locale == locale {
+			versions = append(versions, template)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return versions
+}
+
+// cloneTemplate copies a stored template's nullable columns
+func cloneTemplate(template models.NotificationTemplate) *models.NotificationTemplate {
+	template.OrganizerID = clonePtr(template.OrganizerID)
+	template.CreatedBy = clonePtr(template.CreatedBy)
+	return &template
+}
+
+// Order repository
+
+type memoryOrderRepository struct{ s *MemoryStore }
+
+func (r *memoryOrderRepository) Create(order *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.orderSeq++
+	order.ID = r.s.orderSeq
+	order.CreatedAt = r.s.clock.Now()
+	stored := *order
+	stored.AffiliateID = clonePtr(order.AffiliateID)
+	r.s.orders[order.ID] = stored
+	return nil
+}
+
+func (r *memoryOrderRepository) GetByID(id int) (*models.Order, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	order, ok := r.s.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &order, nil
+}
+
+func (r *memoryOrderRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.orders, id)
+	return nil
+}
+
+func (r *memoryOrderRepository) GetByUserID(userID int) ([]models.Order, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	orders := []models.Order{}
+	for _, order := range r.s.orders {
+		if order.UserID == userID {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return createdBefore(orders[j].CreatedAt, orders[j].ID, orders[i].CreatedAt, orders[i].ID)
+	})
+	return orders, nil
+}
+
+func (r *memoryOrderRepository) ReferralConversions(filter models.ReferralFilter) ([]models.ReferralConversion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, ok := referralSources[filter.GroupBy]; !ok {
+		return nil, fmt.Errorf("unknown referral grouping %q", filter.GroupBy)
+	}
+	bySource := make(map[string]*models.ReferralConversion)
+	for _, order := range r.s.orders {
+		if filter.EventID != 0 && order.EventID != filter.EventID {
+			continue
+		}
+		if filter.OrganizerID != 0 {
+			event := r.s.events[order.EventID]
+			if event.OrganizerID == nil || *event.OrganizerID != filter.OrganizerID {
+				continue
+			}
+		}
+		if filter.From != nil && order.CreatedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !order.CreatedAt.Before(*filter.To) {
+			continue
+		}
+
+		source := order.Ref
+		switch filter.GroupBy {
+		case models.ReferralGroupSource:
+			if order.UTMSource != "" {
+				source = order.UTMSource
+			}
+		case models.ReferralGroupMedium:
+			source = order.UTMMedium
+		case models.ReferralGroupCampaign:
+			source = order.UTMCampaign
+		}
+		conversion, ok := bySource[source]
+		if !ok {
+			conversion = &models.ReferralConversion{Source: source}
+			bySource[source] = conversion
+		}
+		conversion.Orders++
+		sold := 0
+		for _, ticket := range r.s.tickets {
+			if ticket.OrderID != nil && *ticket.OrderID == order.ID && ticket.PaymentStatus == "paid" {
+				sold++
+				conversion.RevenueSats += ticket.AmountSats
+			}
+		}
+		conversion.TicketsSold += sold
+		if sold > 0 {
+			conversion.ConvertedOrders++
+		}
+	}
+
+	conversions := make([]models.ReferralConversion, 0, len(bySource))
+	for _, conversion := range bySource {
+		conversion.ConversionRate = float64(conversion.ConvertedOrders) / float64(conversion.Orders)
+		conversions = append(conversions, *conversion)
+	}
+	sort.Slice(conversions, func(i, j int) bool {
+		a, b := conversions[i], conversions[j]
+		if a.RevenueSats != b.RevenueSats {
+			return a.RevenueSats > b.RevenueSats
+		}
+		if a.Orders != b.Orders {
+			return a.Orders > b.Orders
+		}
+		return a.Source < b.Source
+	})
+	return conversions, nil
+}
+
+// Receipt repository
+
+type memoryReceiptRepository struct{ s *MemoryStore }
+
+func (r *memoryReceiptRepository) CreateLineItems(items []models.PaymentLineItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for i := range items {
+		r.s.lineSeq++
+		items[i].ID = r.s.lineSeq
+		items[i].CreatedAt = now
+		r.s.lines[items[i].ID] = items[i]
+	}
+	return nil
+}
+
+func (r *memoryReceiptRepository) ReplaceLineItems(paymentID int, items []models.PaymentLineItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, item := range r.s.lines {
+		if item.PaymentID == paymentID {
+			delete(r.s.lines, id)
+		}
+	}
+	now := r.s.clock.Now()
+	for i := range items {
+		r.s.lineSeq++
+		items[i].ID = r.s.lineSeq
+		items[i].CreatedAt = now
+		r.s.lines[items[i].ID] = items[i]
+	}
+	return nil
+}
+
+func (r *memoryReceiptRepository) GetLineItems(paymentID int) ([]models.PaymentLineItem, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	items := []models.PaymentLineItem{}
+	for _, item := range r.s.lines {
+		if item.PaymentID == paymentID {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *memoryReceiptRepository) Issue(paymentID int) (*models.Receipt, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	receipt, ok := r.s.receipts[paymentID]
+	if !ok {
+		// Receipt numbers come from the issue order, like MAX(receipt_number) + 1
+		r.s.receiptSeq++
+		receipt = models.Receipt{
+			ID:        r.s.receiptSeq,
+			PaymentID: paymentID,
+			Number:    int64(len(r.s.receipts) + 1),
+			IssuedAt:  r.s.clock.Now(),
+		}
+		r.s.receipts[paymentID] = receipt
+	}
+	return &receipt, nil
+}
+
+func (r *memoryReceiptRepository) GetSales(from, to time.Time) ([]models.Sale, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.sales(func(p models.Payment) bool {
+		return p.Status == "paid" && p.PaidAt != nil && !p.PaidAt.Before(from) && p.PaidAt.Before(to)
+	}), nil
+}
+
+// sales returns the payments matching match as sales, oldest paid first.
+// The caller holds the lock.
+func (s *MemoryStore) sales(match func(models.Payment) bool) []models.Sale {
+	sales := []models.Sale{}
+	for _, payment := range s.payments {
+		if !match(payment) {
+			continue
+		}
+		ticket, ok := s.tickets[payment.TicketID]
+		if !ok {
+			continue
+		}
+		event, ok := s.events[ticket.EventID]
+		if !ok {
+			continue
+		}
+		sale := models.Sale{
+			Payment:     *clonePayment(payment),
+			EventID:     event.ID,
+			EventTitle:  event.Title,
+			OrganizerID: clonePtr(event.OrganizerID),
+		}
+		if receipt, ok := s.receipts[payment.ID]; ok {
+			sale.ReceiptNumber = &receipt.Number
+		}
+		if ticket.OrderID != nil {
+			order := s.orders[*ticket.OrderID]
+			sale.AffiliateID = clonePtr(order.AffiliateID)
+			sale.CommissionBasisPoints = order.CommissionBasisPoints
+		}
+		for _, item := range s.lines {
+			if item.PaymentID == payment.ID {
+				sale.LineItems = append(sale.LineItems, item)
+			}
+		}
+		sort.Slice(sale.LineItems, func(i, j int) bool { return sale.LineItems[i].ID < sale.LineItems[j].ID })
+		sales = append(sales, sale)
+	}
+	paidAt := func(sale models.Sale) time.Time {
+		if sale.PaidAt == nil {
+			return time.Time{}
+		}
+		return *sale.PaidAt
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		if a, b := paidAt(sales[i]), paidAt(sales[j]); !a.Equal(b) {
+			return a.Before(b)
+		}
+		return sales[i].ID < sales[j].ID
+	})
+	return sales
+}
+
+func (r *memoryReceiptRepository) GetByPaymentID(paymentID int) (*models.Receipt, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	receipt, ok := r.s.receipts[paymentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &receipt, nil
+}
+
+// Fee repository
+
+type memoryFeeRepository struct{ s *MemoryStore }
+
+func (r *memoryFeeRepository) GetOverride(organizerID int) (*models.OrganizerFee, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	fee, ok := r.s.fees[organizerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &fee, nil
+}
+
+func (r *memoryFeeRepository) ListOverrides() ([]models.OrganizerFee, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	fees := make([]models.OrganizerFee, 0, len(r.s.fees))
+	for _, fee := range r.s.fees {
+		fees = append(fees, fee)
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i].OrganizerID < fees[j].OrganizerID })
+	return fees, nil
+}
+
+func (r *memoryFeeRepository) SetOverride(fee *models.OrganizerFee) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	fee.UpdatedAt = r.s.clock.Now()
+	r.s.fees[fee.OrganizerID] = *fee
+	return nil
+}
+
+func (r *memoryFeeRepository) DeleteOverride(organizerID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.fees, organizerID)
+	return nil
+}
+
+func (r *memoryFeeRepository) Revenue(filter models.RevenueFilter) ([]models.EventRevenue, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	fees := make(map[int]int64)
+	for _, line := range r.s.lines {
+		if line.Kind == models.LineItemFee {
+			fees[line.PaymentID] += line.AmountSats
+		}
+	}
+
+	byEvent := make(map[int]*models.EventRevenue)
+	for _, payment := range r.s.payments {
+		if payment.Status != "paid" || payment.PaidAt == nil {
+			continue
+		}
+		if filter.From != nil && payment.PaidAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !payment.PaidAt.Before(*filter.To) {
+			continue
+		}
+		ticket, ok := r.s.tickets[payment.TicketID]
+		if !ok {
+			continue
+		}
+		event, ok := r.s.events[ticket.EventID]
+		if !ok {
+			continue
+		}
+		if filter.OrganizerID != 0 && (event.OrganizerID == nil || *event.OrganizerID != filter.OrganizerID) {
+			continue
+		}
+
+		revenue, ok := byEvent[event.ID]
+		if !ok {
+			revenue = &models.EventRevenue{EventID: event.ID, Title: event.Title, OrganizerID: clonePtr(event.OrganizerID), FiatCurrency: filter.FiatCurrency}
+			byEvent[event.ID] = revenue
+		}
+		revenue.Payments++
+		revenue.GrossSats += payment.Amount
+		revenue.FeeSats += fees[payment.ID]
+		if payment.FiatAmount != nil && payment.FiatCurrency == filter.FiatCurrency {
+			revenue.GrossFiat += *payment.FiatAmount
+		} else {
+			revenue.UnvaluedPayments++
+		}
+	}
+
+	report := make([]models.EventRevenue, 0, len(byEvent))
+	for _, revenue := range byEvent {
+		revenue.PayoutSats = revenue.GrossSats - revenue.FeeSats
+		report = append(report, *revenue)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].EventID < report[j].EventID })
+	return report, nil
+}
+
+// Ledger repository
+
+type memoryLedgerRepository struct{ s *MemoryStore }
+
+func (r *memoryLedgerRepository) Post(entry *models.LedgerEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if entry.PaymentID != nil {
+		if _, ok := r.paymentEntry(entry.Kind, *entry.PaymentID); ok {
+			return ErrConflict
+		}
+	}
+
+	now := r.s.clock.Now()
+	r.s.entrySeq++
+	entry.ID = r.s.entrySeq
+	entry.CreatedAt = now
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		account, ok := r.s.accounts[posting.Account]
+		if !ok {
+			r.s.accountSeq++
+			account = models.LedgerAccount{ID: r.s.accountSeq, Name: posting.Account, Type: models.LedgerAccountType(posting.Account), CreatedAt: now}
+			r.s.accounts[account.Name] = account
+		}
+		r.s.postingSeq++
+		posting.ID, posting.EntryID, posting.AccountID = r.s.postingSeq, entry.ID, account.ID
+	}
+	r.s.entries[entry.ID] = cloneLedgerEntry(*entry)
+	return nil
+}
+
+// paymentEntry finds the payment's entry of kind. The caller holds the lock.
+func (r *memoryLedgerRepository) paymentEntry(kind string, paymentID int) (models.LedgerEntry, bool) {
+	for _, entry := range r.s.entries {
+		if entry.Kind == kind && entry.PaymentID != nil && *entry.PaymentID == paymentID {
+			return entry, true
+		}
+	}
+	return models.LedgerEntry{}, false
+}
+
+func cloneLedgerEntry(entry models.LedgerEntry) models.LedgerEntry {
+	entry.PaymentID = clonePtr(entry.PaymentID)
+	entry.OrganizerID = clonePtr(entry.OrganizerID)
+	entry.AffiliateID = clonePtr(entry.AffiliateID)
+	entry.Postings = append([]models.LedgerPosting{}, entry.Postings...)
+	return entry
+}
+
+func (r *memoryLedgerRepository) GetPaymentEntry(kind string, paymentID int) (*models.LedgerEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entry, ok := r.paymentEntry(kind, paymentID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry = cloneLedgerEntry(entry)
+	return &entry, nil
+}
+
+func (r *memoryLedgerRepository) ListEntries(accountID, limit, offset int) ([]models.LedgerEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entries := []models.LedgerEntry{}
+	for _, entry := range r.s.entries {
+		match := accountID == 0
+		for _, posting := range entry.Postings {
+			match = match || posting.AccountID == accountID
+		}
+		if match {
+			entries = append(entries, cloneLedgerEntry(entry))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].OccurredAt.Equal(entries[j].OccurredAt) {
+			return entries[i].OccurredAt.After(entries[j].OccurredAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	start, end := page(len(entries), limit, offset)
+	return entries[start:end], nil
+}
+
+func (r *memoryLedgerRepository) Balances() ([]models.LedgerBalance, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.balances(), nil
+}
+
+// balances totals every account, by name. The caller holds the lock.
+func (r *memoryLedgerRepository) balances() []models.LedgerBalance {
+	byAccount := make(map[int]*models.LedgerBalance, len(r.s.accounts))
+	balances := make([]models.LedgerBalance, 0, len(r.s.accounts))
+	for _, account := range r.s.accounts {
+		balances = append(balances, models.LedgerBalance{LedgerAccount: account})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Name < balances[j].Name })
+	for i := range balances {
+		byAccount[balances[i].ID] = &balances[i]
+	}
+	for _, entry := range r.s.entries {
+		for _, posting := range entry.Postings {
+			balance := byAccount[posting.AccountID]
+			balance.DebitSats += posting.DebitSats
+			balance.CreditSats += posting.CreditSats
+		}
+	}
+	for i := range balances {
+		settleBalance(&balances[i])
+	}
+	return balances
+}
+
+func (r *memoryLedgerRepository) GetBalance(account string) (*models.LedgerBalance, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, balance := range r.balances() {
+		if balance.Name == account {
+			return &balance, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryLedgerRepository) Debited(account, kind string) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var total int64
+	id := r.s.accounts[account].ID
+	for _, entry := range r.s.entries {
+		if entry.Kind != kind {
+			continue
+		}
+		for _, posting := range entry.Postings {
+			if posting.AccountID == id {
+				total += posting.DebitSats
+			}
+		}
+	}
+	return total, nil
+}
+
+func (r *memoryLedgerRepository) UnpostedSales(limit int) ([]models.Sale, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	sales := r.s.sales(func(p models.Payment) bool {
+		_, posted := r.paymentEntry(models.LedgerEntrySale, p.ID)
+		return p.Status == "paid" && !posted
+	})
+	sort.Slice(sales, func(i, j int) bool { return sales[i].ID < sales[j].ID })
+	start, end := page(len(sales), limit, 0)
+	return sales[start:end], nil
+}
+
+func (r *memoryLedgerRepository) Check(wallet string) ([]models.LedgerIssue, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entries := make([]models.LedgerEntry, 0, len(r.s.entries))
+	for _, entry := range r.s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	// Grouped by check, like the queries of the SQL implementation
+	var unbalanced, mismatched, unpaid, orphans []models.LedgerIssue
+	for _, entry := range entries {
+		var debits, credits, walletDebits int64
+		for _, posting := range entry.Postings {
+			debits += posting.DebitSats
+			credits += posting.CreditSats
+			if posting.Account == wallet {
+				walletDebits += posting.DebitSats
+			}
+		}
+		if len(entry.Postings) < 2 || debits != credits {
+			unbalanced = append(unbalanced, unbalancedIssue(entry.ID, len(entry.Postings), debits, credits))
+		}
+		if entry.PaymentID == nil {
+			continue
+		}
+
+		switch entry.Kind {
+		case models.LedgerEntrySale:
+			payment, ok := r.s.payments[*entry.PaymentID]
+			if !ok {
+				continue
+			}
+			if walletDebits != payment.Amount {
+				mismatched = append(mismatched, saleAmountIssue(entry.ID, payment.ID, payment.Amount, walletDebits, wallet))
+			}
+			if _, refunded := r.paymentEntry(models.LedgerEntryRefund, payment.ID); payment.Status != "paid" && !refunded {
+				unpaid = append(unpaid, unpaidSaleIssue(entry.ID, payment.ID, payment.Status))
+			}
+		case models.LedgerEntryRefund:
+			if _, ok := r.paymentEntry(models.LedgerEntrySale, *entry.PaymentID); !ok {
+				orphans = append(orphans, refundWithoutSaleIssue(entry.ID, *entry.PaymentID))
+			}
+		}
+	}
+
+	issues := []models.LedgerIssue{}
+	for _, group := range [][]models.LedgerIssue{unbalanced, mismatched, unpaid, orphans, negativeBalanceIssues(r.balances())} {
+		issues = append(issues, group...)
+	}
+	return issues, nil
+}
+
+// Dispute repository
+
+type memoryDisputeRepository struct{ s *MemoryStore }
+
+func cloneDispute(dispute models.Dispute) *models.Dispute {
+	dispute.OpenedBy = clonePtr(dispute.OpenedBy)
+	dispute.ResolvedBy = clonePtr(dispute.ResolvedBy)
+	dispute.ResolvedAt = clonePtr(dispute.ResolvedAt)
+	return &dispute
+}
+
+func (r *memoryDisputeRepository) Create(dispute *models.Dispute) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.disputes {
+		if existing.PaymentID == dispute.PaymentID && existing.Status == models.DisputeOpen {
+			return ErrConflict
+		}
+	}
+	r.s.disputeSeq++
+	dispute.ID = r.s.disputeSeq
+	dispute.Status = models.DisputeOpen
+	dispute.Resolution = ""
+	dispute.ResolvedBy, dispute.ResolvedAt = nil, nil
+	dispute.OpenedAt = r.s.clock.Now()
+	r.s.disputes[dispute.ID] = *cloneDispute(*dispute)
+	return nil
+}
+
+func (r *memoryDisputeRepository) GetByID(id int) (*models.Dispute, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	dispute, ok := r.s.disputes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneDispute(dispute), nil
+}
+
+func (r *memoryDisputeRepository) List(status string, limit, offset int) ([]models.Dispute, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	disputes := []models.Dispute{}
+	for _, dispute := range r.s.disputes {
+		if status == "" || dispute.Status == status {
+			disputes = append(disputes, *cloneDispute(dispute))
+		}
+	}
+	sort.Slice(disputes, func(i, j int) bool {
+		if !disputes[i].OpenedAt.Equal(disputes[j].OpenedAt) {
+			return disputes[i].OpenedAt.After(disputes[j].OpenedAt)
+		}
+		return disputes[i].ID > disputes[j].ID
+	})
+	start, end := page(len(disputes), limit, offset)
+	return disputes[start:end], nil
+}
+
+func (r *memoryDisputeRepository) Resolve(dispute *models.Dispute) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.disputes[dispute.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Status != models.DisputeOpen {
+		return ErrConflict
+	}
+	now := r.s.clock.Now()
+	stored.Status, stored.Resolution, stored.ResolvedBy, stored.ResolvedAt = dispute.Status, dispute.Resolution, clonePtr(dispute.ResolvedBy), &now
+	r.s.disputes[dispute.ID] = stored
+	*dispute = *cloneDispute(stored)
+	return nil
+}
+
+// Webhook event repository
+
+type memoryWebhookEventRepository struct{ s *MemoryStore }
+
+func cloneWebhookEvent(event models.WebhookEvent) *models.WebhookEvent {
+	event.LockedUntil = clonePtr(event.LockedUntil)
+	event.ProcessedAt = clonePtr(event.ProcessedAt)
+	return &event
+}
+
+func (r *memoryWebhookEventRepository) Enqueue(event *models.WebhookEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.webhooks {
+		if existing.Source == event.Source && existing.EventID == event.EventID {
+			return ErrConflict
+		}
+	}
+	r.s.webhookSeq++
+	now := r.s.clock.Now()
+	event.ID = r.s.webhookSeq
+	event.Status = models.WebhookEventPending
+	event.Attempts, event.LastError = 0, ""
+	event.ReceivedAt, event.NextAttemptAt = now, now
+	event.LockedUntil, event.ProcessedAt = nil, nil
+	r.s.webhooks[event.ID] = *cloneWebhookEvent(*event)
+	return nil
+}
+
+func (r *memoryWebhookEventRepository) Claim(limit int, lease time.Duration) ([]models.WebhookEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	due := []models.WebhookEvent{}
+	for _, event := range r.s.webhooks {
+		if event.Status == models.WebhookEventPending && !event.NextAttemptAt.After(now) &&
+			(event.LockedUntil == nil || !event.LockedUntil.After(now)) {
+			due = append(due, event)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	lockedUntil := now.Add(lease)
+	for i := range due {
+		due[i].LockedUntil = &lockedUntil
+		due[i].Attempts++
+		r.s.webhooks[due[i].ID] = *cloneWebhookEvent(due[i])
+	}
+	return due, nil
+}
+
+func (r *memoryWebhookEventRepository) Complete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.webhooks[id]
+	if !ok {
+		return nil
+	}
+	now := r.s.clock.Now()
+	event.Status, event.LockedUntil, event.LastError, event.ProcessedAt = models.WebhookEventDone, nil, "", &now
+	r.s.webhooks[id] = event
+	return nil
+}
+
+func (r *memoryWebhookEventRepository) Fail(id int, lastError string, retryAt *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.webhooks[id]
+	if !ok {
+		return nil
+	}
+	event.LockedUntil, event.LastError = nil, lastError
+	if retryAt == nil {
+		now := r.s.clock.Now()
+		event.Status, event.ProcessedAt = models.WebhookEventFailed, &now
+	} else {
+		event.NextAttemptAt = *retryAt
+	}
+	r.s.webhooks[id] = event
+	return nil
+}
+
+func (r *memoryWebhookEventRepository) Backlog() (*models.WebhookBacklog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	backlog := &models.WebhookBacklog{}
+	for _, event := range r.s.webhooks {
+		switch event.Status {
+		case models.WebhookEventPending:
+			backlog.Pending++
+			if backlog.OldestPendingAt == nil || event.ReceivedAt.Before(*backlog.OldestPendingAt) {
+				backlog.OldestPendingAt = clonePtr(&event.ReceivedAt)
+			}
+		case models.WebhookEventFailed:
+			backlog.Failed++
+		}
+	}
+	return backlog, nil
+}
+
+func (r *memoryWebhookEventRepository) ListFailed() ([]models.WebhookEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.WebhookEvent{}
+	for _, event := range r.s.webhooks {
+		if event.Status == models.WebhookEventFailed {
+			events = append(events, *cloneWebhookEvent(event))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].ProcessedAt.Equal(*events[j].ProcessedAt) {
+			return events[i].ProcessedAt.After(*events[j].ProcessedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	return events, nil
+}
+
+func (r *memoryWebhookEventRepository) Requeue(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.webhooks[id]
+	if !ok || event.Status != models.WebhookEventFailed {
+		return ErrNotFound
+	}
+	event.Status, event.Attempts, event.NextAttemptAt = models.WebhookEventPending, 0, r.s.clock.Now()
+	event.LockedUntil, event.ProcessedAt = nil, nil
+	r.s.webhooks[id] = event
+	return nil
+}
+
+func (r *memoryWebhookEventRepository) DeleteFailed(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event, ok := r.s.webhooks[id]
+	if !ok || event.Status != models.WebhookEventFailed {
+		return ErrNotFound
+	}
+	delete(r.s.webhooks, id)
+	return nil
+}
+
+// Wallet claim repository
+
+type memoryWalletClaimRepository struct{ s *MemoryStore }
+
+func cloneWalletClaim(claim models.WalletClaim) *models.WalletClaim {
+	claim.SecretHash = clonePtr(claim.SecretHash)
+	claim.SecretExpiresAt = clonePtr(claim.SecretExpiresAt)
+	claim.DevicePublicKey = clonePtr(claim.DevicePublicKey)
+	claim.ClaimedAt = clonePtr(claim.ClaimedAt)
+	return &claim
+}
+
+func (r *memoryWalletClaimRepository) GetByTicketID(ticketID int) (*models.WalletClaim, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	claim, ok := r.s.claims[ticketID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneWalletClaim(claim), nil
+}
+
+func (r *memoryWalletClaimRepository) Offer(ticketID int, secretHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	claim, ok := r.s.claims[ticketID]
+	if !ok {
+		claim = models.WalletClaim{TicketID: ticketID, CreatedAt: now}
+	}
+	claim.SecretHash, claim.SecretExpiresAt, claim.UpdatedAt = &secretHash, &expiresAt, now
+	r.s.claims[ticketID] = *cloneWalletClaim(claim)
+	return nil
+}
+
+func (r *memoryWalletClaimRepository) Claim(ticketID int, secretHash, devicePublicKey string) (*models.WalletClaim, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	claim, ok := r.s.claims[ticketID]
+	if !ok || claim.SecretHash == nil || *claim.SecretHash != secretHash || !claim.SecretExpiresAt.After(now) {
+		return nil, ErrNotFound
+	}
+	claim.DevicePublicKey, claim.ClaimedAt, claim.UpdatedAt = &devicePublicKey, &now, now
+	claim.SecretHash, claim.SecretExpiresAt = nil, nil
+	r.s.claims[ticketID] = *cloneWalletClaim(claim)
+	return cloneWalletClaim(claim), nil
+}
+
+// Account claim repository
+
+type memoryAccountClaimRepository struct{ s *MemoryStore }
+
+func (r *memoryAccountClaimRepository) Offer(userID int, secretHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.guests[userID] = models.AccountClaim{
+		UserID:     userID,
+		SecretHash: secretHash,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  r.s.clock.Now(),
+	}
+	return nil
+}
+
+func (r *memoryAccountClaimRepository) Claim(secretHash, passwordHash, name string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for userID, claim := range r.s.guests {
+		if claim.SecretHash != secretHash || !claim.ExpiresAt.After(now) {
+			continue
+		}
+		delete(r.s.guests, userID)
+		user, ok := r.s.users[userID]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		user.PasswordHash, user.IsGuest, user.UpdatedAt = passwordHash, false, now
+		if name != "" {
+			user.Name = name
+		}
+		r.s.users[userID] = user
+		return &user, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Email change repository
+
+type memoryEmailChangeRepository struct{ s *MemoryStore }
+
+func (r *memoryEmailChangeRepository) Request(userID int, newEmail, secretHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	now := r.s.clock.Now()
+	user.PendingEmail, user.UpdatedAt = &newEmail, now
+	r.s.users[userID] = user
+	r.s.changes[userID] = models.EmailChange{
+		UserID:     userID,
+		SecretHash: secretHash,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}
+	return nil
+}
+
+func (r *memoryEmailChangeRepository) Confirm(secretHash string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for userID, change := range r.s.changes {
+		if change.SecretHash != secretHash || !change.ExpiresAt.After(now) {
+			continue
+		}
+		user, ok := r.s.users[userID]
+		if !ok || user.PendingEmail == nil {
+			delete(r.s.changes, userID)
+			return nil, ErrNotFound
+		}
+		if (&memoryUserRepository{r.s}).emailTaken(*user.PendingEmail, userID) {
+			return nil, ErrConflict
+		}
+		delete(r.s.changes, userID)
+		user.Email, user.PendingEmail, user.UpdatedAt = *user.PendingEmail, nil, now
+		r.s.users[userID] = user
+		return &user, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Ticket gift repository
+
+type memoryTicketGiftRepository struct{ s *MemoryStore }
+
+func cloneTicketGift(gift models.TicketGift) models.TicketGift {
+	gift.SecretHash = clonePtr(gift.SecretHash)
+	gift.RecipientID = clonePtr(gift.RecipientID)
+	gift.DeliveredAt = clonePtr(gift.DeliveredAt)
+	gift.ClaimedAt = clonePtr(gift.ClaimedAt)
+	return gift
+}
+
+func (r *memoryTicketGiftRepository) Create(gift *models.TicketGift) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, other := range r.s.gifts {
+		if other.TicketID == gift.TicketID {
+			return ErrConflict
+		}
+	}
+	r.s.giftSeq++
+	gift.ID = r.s.giftSeq
+	gift.Status = models.GiftScheduled
+	gift.SecretHash, gift.RecipientID, gift.DeliveredAt, gift.ClaimedAt = nil, nil, nil, nil
+	gift.CreatedAt = r.s.clock.Now()
+	r.s.gifts[gift.ID] = *gift
+	return nil
+}
+
+func (r *memoryTicketGiftRepository) GetByTicketID(ticketID int) (*models.TicketGift, error) {
+	return r.find(func(gift models.TicketGift) bool { return gift.TicketID == ticketID })
+}
+
+func (r *memoryTicketGiftRepository) GetBySecret(secretHash string) (*models.TicketGift, error) {
+	return r.find(func(gift models.TicketGift) bool { return gift.SecretHash != nil && *gift.SecretHash == secretHash })
+}
+
+func (r *memoryTicketGiftRepository) find(match func(models.TicketGift) bool) (*models.TicketGift, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, gift := range r.s.gifts {
+		if match(gift) {
+			gift = cloneTicketGift(gift)
+			return &gift, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTicketGiftRepository) GetBySenderID(senderID int) ([]models.TicketGift, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	gifts := []models.TicketGift{}
+	for _, gift := range r.s.gifts {
+		if gift.SenderID == senderID {
+			gifts = append(gifts, cloneTicketGift(gift))
+		}
+	}
+	sort.Slice(gifts, func(i, j int) bool {
+		return createdBefore(gifts[j].CreatedAt, gifts[j].ID, gifts[i].CreatedAt, gifts[i].ID)
+	})
+	return gifts, nil
+}
+
+func (r *memoryTicketGiftRepository) ListDue(now time.Time, limit int) ([]models.TicketGift, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	gifts := []models.TicketGift{}
+	for _, gift := range r.s.gifts {
+		if gift.Status == models.GiftScheduled && !gift.DeliverAt.After(now) && r.s.tickets[gift.TicketID].PaymentStatus == "paid" {
+			gifts = append(gifts, cloneTicketGift(gift))
+		}
+	}
+	sort.Slice(gifts, func(i, j int) bool {
+		return createdBefore(gifts[i].DeliverAt, gifts[i].ID, gifts[j].DeliverAt, gifts[j].ID)
+	})
+	if len(gifts) > limit {
+		gifts = gifts[:limit]
+	}
+	return gifts, nil
+}
+
+func (r *memoryTicketGiftRepository) MarkDelivered(id int, secretHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	gift, ok := r.s.gifts[id]
+	if !ok || gift.Status != models.GiftScheduled {
+		return ErrNotFound
+	}
+	now := r.s.clock.Now()
+	gift.Status = models.GiftDelivered
+	gift.SecretHash = &secretHash
+	gift.DeliveredAt = &now
+	r.s.gifts[id] = gift
+	return nil
+}
+
+func (r *memoryTicketGiftRepository) Claim(secretHash string, recipientID int) (*models.TicketGift, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, gift := range r.s.gifts {
+		if gift.Status != models.GiftDelivered || gift.SecretHash == nil || *gift.SecretHash != secretHash {
+			continue
+		}
+		now := r.s.clock.Now()
+		gift.Status = models.GiftClaimed
+		gift.RecipientID = &recipientID
+		gift.ClaimedAt = &now
+		r.s.gifts[id] = gift
+
+		ticket := r.s.tickets[gift.TicketID]
+		ticket.UserID = recipientID
+		ticket.UpdatedAt = now
+		r.s.tickets[gift.TicketID] = ticket
+
+		gift = cloneTicketGift(gift)
+		return &gift, nil
+	}
+	return nil, ErrNotFound
+}
+
+type memoryPricingRuleRepository struct{ s *MemoryStore }
+
+func clonePricingRule(rule models.PricingRule) *models.PricingRule {
+	rule.SoldPercent = clonePtr(rule.SoldPercent)
+	rule.StartsAt = clonePtr(rule.StartsAt)
+	return &rule
+}
+
+func clonePriceChange(change models.PriceChange) *models.PriceChange {
+	change.RuleID = clonePtr(change.RuleID)
+	return &change
+}
+
+// latestPrice returns the event's latest price change. Callers hold s.mu.
+func (s *MemoryStore) latestPrice(eventID int) (models.PriceChange, bool) {
+	var latest models.PriceChange
+	for _, change := range s.prices {
+		if change.EventID == eventID && change.ID > latest.ID {
+			latest = change
+		}
+	}
+	return latest, latest.ID != 0
+}
+
+func (r *memoryPricingRuleRepository) Create(rule *models.PricingRule) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.ruleSeq++
+	rule.ID = r.s.ruleSeq
+	rule.CreatedAt = r.s.clock.Now()
+	r.s.rules[rule.ID] = *clonePricingRule(*rule)
+	return nil
+}
+
+func (r *memoryPricingRuleRepository) GetByID(id int) (*models.PricingRule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rule, ok := r.s.rules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clonePricingRule(rule), nil
+}
+
+func (r *memoryPricingRuleRepository) GetByEventID(eventID int) ([]models.PricingRule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rules := []models.PricingRule{}
+	for _, rule := range r.s.rules {
+		if rule.EventID == eventID {
+			rules = append(rules, *clonePricingRule(rule))
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+func (r *memoryPricingRuleRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.rules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.rules, id)
+	// event_price_changes.rule_id is ON DELETE SET NULL
+	for changeID, change := range r.s.prices {
+		if change.RuleID != nil && *change.RuleID == id {
+			change.RuleID = nil
+			r.s.prices[changeID] = change
+		}
+	}
+	return nil
+}
+
+func (r *memoryPricingRuleRepository) RecordPrice(change *models.PriceChange) (*models.PriceChange, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if latest, ok := r.s.latestPrice(change.EventID); ok && latest.PriceSats == change.PriceSats && latest.RuleName == change.RuleName {
+		return clonePriceChange(latest), nil
+	}
+	r.s.priceSeq++
+	recorded := *clonePriceChange(*change)
+	recorded.ID = r.s.priceSeq
+	recorded.ChangedAt = r.s.clock.Now()
+	r.s.prices[recorded.ID] = recorded
+	return clonePriceChange(recorded), nil
+}
+
+func (r *memoryPricingRuleRepository) GetPriceChange(id int) (*models.PriceChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	change, ok := r.s.prices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clonePriceChange(change), nil
+}
+
+func (r *memoryPricingRuleRepository) GetPriceHistory(eventID int) ([]models.PriceChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	changes := []models.PriceChange{}
+	for _, change := range r.s.prices {
+		if change.EventID == eventID {
+			changes = append(changes, *clonePriceChange(change))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes, nil
+}
+
+// Exchange rate repository
+
+type memoryExchangeRateRepository struct{ s *MemoryStore }
+
+func (r *memoryExchangeRateRepository) Create(rate *models.ExchangeRate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.rateSeq++
+	rate.ID = r.s.rateSeq
+	r.s.rates[rate.ID] = *rate
+	return nil
+}
+
+func (r *memoryExchangeRateRepository) List(currency string, limit, offset int) ([]models.ExchangeRate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rates := []models.ExchangeRate{}
+	for _, rate := range r.s.rates {
+		if rate.Currency == currency {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool { return newerRate(rates[i], rates[j]) })
+	start, end := page(len(rates), limit, offset)
+	return rates[start:end], nil
+}
+
+func (r *memoryExchangeRateRepository) Unvalued(currency string, limit int) ([]models.PaymentValuation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	valuations := []models.PaymentValuation{}
+	for _, payment := range r.s.payments {
+		if payment.Status != "paid" || payment.PaidAt == nil || payment.ExchangeRateID != nil {
+			continue
+		}
+		var latest *models.ExchangeRate
+		for _, rate := range r.s.rates {
+			if rate.Currency != currency || rate.FetchedAt.After(*payment.PaidAt) {
+				continue
+			}
+			if latest == nil || newerRate(rate, *latest) {
+				latest = &rate
+			}
+		}
+		if latest != nil {
+			valuations = append(valuations, models.PaymentValuation{
+				PaymentID: payment.ID, AmountSats: payment.Amount, RateID: latest.ID, MinorUnitsPerBTC: latest.MinorUnitsPerBTC,
+			})
+		}
+	}
+	sort.Slice(valuations, func(i, j int) bool { return valuations[i].PaymentID < valuations[j].PaymentID })
+	_, end := page(len(valuations), limit, 0)
+	return valuations[:end], nil
+}
+
+func (r *memoryExchangeRateRepository) SetFiatValue(paymentID, rateID int, amount int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, ok := r.s.payments[paymentID]
+	if !ok {
+		return ErrNotFound
+	}
+	if payment.ExchangeRateID != nil {
+		return ErrConflict
+	}
+	rate, ok := r.s.rates[rateID]
+	if !ok {
+		return fmt.Errorf("exchange rate %d does not exist", rateID)
+	}
+	payment.FiatCurrency, payment.FiatAmount, payment.ExchangeRateID = rate.Currency, &amount, &rateID
+	r.s.payments[paymentID] = payment
+	return nil
+}
+
+// newerRate orders exchange rates newest first, like ORDER BY fetched_at
+// DESC, id DESC
+func newerRate(a, b models.ExchangeRate) bool {
+	if !a.FetchedAt.Equal(b.FetchedAt) {
+		return a.FetchedAt.After(b.FetchedAt)
+	}
+	return a.ID > b.ID
+}
+
+// Cashu redemption repository
+
+type memoryCashuRedemptionRepository struct{ s *MemoryStore }
+
+func (r *memoryCashuRedemptionRepository) Create(redemption *models.CashuRedemption) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, other := range r.s.melts {
+		if other.PaymentID == redemption.PaymentID && other.State != models.CashuFailed {
+			return ErrConflict
+		}
+	}
+	if _, ok := r.s.payments[redemption.PaymentID]; !ok {
+		return ErrNotFound
+	}
+	r.s.meltSeq++
+	redemption.ID = r.s.meltSeq
+	redemption.State = models.CashuPending
+	redemption.Preimage, redemption.Error = nil, ""
+	redemption.CreatedAt = r.s.clock.Now()
+	redemption.UpdatedAt = redemption.CreatedAt
+	r.s.melts[redemption.ID] = *redemption
+	return nil
+}
+
+func (r *memoryCashuRedemptionRepository) Finish(id int, state string, preimage *string, errMsg string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	redemption, ok := r.s.melts[id]
+	if !ok || redemption.State != models.CashuPending {
+		return ErrNotFound
+	}
+	redemption.State = state
+	redemption.Preimage = clonePtr(preimage)
+	redemption.Error = errMsg
+	redemption.UpdatedAt = r.s.clock.Now()
+	r.s.melts[id] = redemption
+	return nil
+}
+
+func (r *memoryCashuRedemptionRepository) List(limit, offset int) ([]models.CashuRedemption, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	redemptions := make([]models.CashuRedemption, 0, len(r.s.melts))
+	for _, redemption := range r.s.melts {
+		redemption.Preimage = clonePtr(redemption.Preimage)
+		redemptions = append(redemptions, redemption)
+	}
+	sort.Slice(redemptions, func(i, j int) bool {
+		if !redemptions[i].CreatedAt.Equal(redemptions[j].CreatedAt) {
+			return redemptions[i].CreatedAt.After(redemptions[j].CreatedAt)
+		}
+		return redemptions[i].ID > redemptions[j].ID
+	})
+	start, end := page(len(redemptions), limit, offset)
+	return redemptions[start:end], nil
+}
+
+// LNURL-auth repository
+
+type memoryLNURLAuthRepository struct{ s *MemoryStore }
+
+func (r *memoryLNURLAuthRepository) CreateChallenge(challenge *models.LNURLAuthChallenge) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, other := range r.s.logins {
+		if other.K1 == challenge.K1 || other.SessionHash == challenge.SessionHash {
+			return ErrConflict
+		}
+	}
+	r.s.loginSeq++
+	challenge.ID = r.s.loginSeq
+	challenge.UserID, challenge.VerifiedAt, challenge.ConsumedAt = nil, nil, nil
+	challenge.LinkUserID = clonePtr(challenge.LinkUserID)
+	challenge.CreatedAt = r.s.clock.Now()
+	r.s.logins[challenge.ID] = *challenge
+	return nil
+}
+
+func (r *memoryLNURLAuthRepository) GetChallenge(k1 string) (*models.LNURLAuthChallenge, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, challenge := range r.s.logins {
+		if challenge.K1 == k1 && challenge.ExpiresAt.After(r.s.clock.Now()) {
+			return cloneChallenge(challenge), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryLNURLAuthRepository) GetBySession(sessionHash string) (*models.LNURLAuthChallenge, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, challenge := range r.s.logins {
+		if challenge.SessionHash == sessionHash && challenge.ExpiresAt.After(r.s.clock.Now()) && challenge.ConsumedAt == nil {
+			return cloneChallenge(challenge), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryLNURLAuthRepository) Verify(k1 string, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	for id, challenge := range r.s.logins {
+		if challenge.K1 == k1 && challenge.ExpiresAt.After(now) && challenge.VerifiedAt == nil {
+			challenge.UserID, challenge.VerifiedAt = &userID, &now
+			r.s.logins[id] = challenge
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryLNURLAuthRepository) Consume(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	challenge, ok := r.s.logins[id]
+	if !ok || challenge.VerifiedAt == nil || challenge.ConsumedAt != nil {
+		return ErrNotFound
+	}
+	now := r.s.clock.Now()
+	challenge.ConsumedAt = &now
+	r.s.logins[id] = challenge
+	return nil
+}
+
+func (r *memoryLNURLAuthRepository) GetUserByKey(key string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.LNURLAuthKey != nil && *user.LNURLAuthKey == key {
+			user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryLNURLAuthRepository) Link(userID int, key string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.keyTaken(key, userID) {
+		return nil, ErrConflict
+	}
+	user, ok := r.s.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.LNURLAuthKey = &key
+	user.UpdatedAt = r.s.clock.Now()
+	r.s.users[userID] = user
+	user.PendingEmail, user.LNURLAuthKey = clonePtr(user.PendingEmail), clonePtr(user.LNURLAuthKey)
+	return &user, nil
+}
+
+func (r *memoryLNURLAuthRepository) Unlink(userID int) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.LNURLAuthKey = nil
+	user.SessionVersion++
+	user.UpdatedAt = r.s.clock.Now()
+	r.s.users[userID] = user
+	user.PendingEmail = clonePtr(user.PendingEmail)
+	return &user, nil
+}
+
+// keyTaken reports whether a user other than exceptID has linking key.
+// Callers hold the lock.
+func (r *memoryLNURLAuthRepository) keyTaken(key string, exceptID int) bool {
+	for id, user := range r.s.users {
+		if id != exceptID && user.LNURLAuthKey != nil && *user.LNURLAuthKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneChallenge(challenge models.LNURLAuthChallenge) *models.LNURLAuthChallenge {
+	challenge.LinkUserID, challenge.UserID = clonePtr(challenge.LinkUserID), clonePtr(challenge.UserID)
+	challenge.VerifiedAt, challenge.ConsumedAt = clonePtr(challenge.VerifiedAt), clonePtr(challenge.ConsumedAt)
+	return &challenge
+}
+
+type memoryArchiveRepository struct{ s *MemoryStore }
+
+func (r *memoryArchiveRepository) ArchivePayments(cutoff time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	due := []models.Payment{}
+	for _, payment := range r.s.payments {
+		if payment.Status == "pending" || !payment.CreatedAt.Before(cutoff) || r.openDispute(payment.ID) {
+			continue
+		}
+		if payment.Status == "paid" && !r.booked(payment.ID) {
+			continue
+		}
+		due = append(due, payment)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, payment := range due {
+		id, eventID := payment.ID, r.s.tickets[payment.TicketID].EventID
+		archiveMatching(r.s, r.s.lines, "payment_line_items", eventID, func(line models.PaymentLineItem) bool { return line.PaymentID == id })
+		archiveMatching(r.s, r.s.receipts, "receipts", eventID, func(receipt models.Receipt) bool { return receipt.PaymentID == id })
+		archiveMatching(r.s, r.s.melts, "cashu_redemptions", eventID, func(melt models.CashuRedemption) bool { return melt.PaymentID == id })
+		archiveMatching(r.s, r.s.disputes, "disputes", eventID, func(dispute models.Dispute) bool { return dispute.PaymentID == id })
+		archiveMatching(r.s, r.s.payments, "payments", eventID, func(p models.Payment) bool { return p.ID == id })
+	}
+	return len(due), nil
+}
+
+// openDispute reports whether a payment is under an open dispute. Callers
+// hold the lock.
+func (r *memoryArchiveRepository) openDispute(paymentID int) bool {
+	for _, dispute := range r.s.disputes {
+		if dispute.PaymentID == paymentID && dispute.Status == models.DisputeOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// booked reports whether a payment's sale is in the ledger. Callers hold
+// the lock.
+func (r *memoryArchiveRepository) booked(paymentID int) bool {
+	for _, entry := range r.s.entries {
+		if entry.Kind == models.LedgerEntrySale && entry.PaymentID != nil && *entry.PaymentID == paymentID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryArchiveRepository) ArchiveTickets(cutoff time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	paid := make(map[int]bool)
+	for _, payment := range r.s.payments {
+		paid[payment.TicketID] = true
+	}
+	due := []models.Ticket{}
+	for _, ticket := range r.s.tickets {
+		switch ticket.PaymentStatus {
+		case "pending", "review", "disputed":
+			continue
+		}
+		if event, ok := r.s.events[ticket.EventID]; ok && event.EndTime.Before(cutoff) && !paid[ticket.ID] {
+			due = append(due, ticket)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, ticket := range due {
+		id, eventID := ticket.ID, ticket.EventID
+		archiveMatching(r.s, r.s.items, "ticket_addons", eventID, func(item models.TicketAddOn) bool { return item.TicketID == id })
+		archiveMatching(r.s, r.s.answers, "ticket_answers", eventID, func(answer models.TicketAnswer) bool { return answer.TicketID == id })
+		archiveMatching(r.s, r.s.gifts, "ticket_gifts", eventID, func(gift models.TicketGift) bool { return gift.TicketID == id })
+		archiveMatching(r.s, r.s.attested, "purchase_attestations", eventID, func(a models.PurchaseAttestation) bool { return a.TicketID == id })
+		archiveMatching(r.s, r.s.links, "short_links", eventID, func(link models.ShortLink) bool { return link.TicketID != nil && *link.TicketID == id })
+		archiveMatching(r.s, r.s.invoices, "uma_request_invoices", eventID, func(invoice models.UMARequestInvoice) bool {
+			return invoice.TicketID != nil && *invoice.TicketID == id
+		})
+		delete(r.s.claims, id)
+		for scanID, scan := range r.s.scans {
+			if scan.TicketID != nil && *scan.TicketID == id {
+				scan.TicketID = nil
+				r.s.scans[scanID] = scan
+			}
+		}
+		for flagID, flag := range r.s.flags {
+			if flag.TicketID != nil && *flag.TicketID == id {
+				flag.TicketID = nil
+				r.s.flags[flagID] = flag
+			}
+		}
+		archiveMatching(r.s, r.s.tickets, "tickets", eventID, func(t models.Ticket) bool { return t.ID == id })
+	}
+	return len(due), nil
+}
+
+func (r *memoryArchiveRepository) ArchiveEvents(cutoff time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sold := make(map[int]bool)
+	for _, ticket := range r.s.tickets {
+		sold[ticket.EventID] = true
+	}
+	due := []models.Event{}
+	for _, event := range r.s.events {
+		if event.EndTime.Before(cutoff) && !sold[event.ID] {
+			due = append(due, event)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, event := range due {
+		id := event.ID
+		archiveMatching(r.s, r.s.orders, "orders", id, func(order models.Order) bool { return order.EventID == id })
+		archiveMatching(r.s, r.s.addOns, "event_addons", id, func(addOn models.AddOn) bool { return addOn.EventID == id })
+		archiveMatching(r.s, r.s.fields, "event_form_fields", id, func(field models.FormField) bool { return field.EventID == id })
+		archiveMatching(r.s, r.s.codes, "event_access_codes", id, func(code models.EventAccessCode) bool { return code.EventID == id })
+		archiveMatching(r.s, r.s.allowed, "event_allowlist", id, func(entry models.EventAllowlistEntry) bool { return entry.EventID == id })
+		archiveMatching(r.s, r.s.titles, "event_translations", id, func(title models.EventTranslation) bool { return title.EventID == id })
+		archiveMatching(r.s, r.s.moves, "event_reschedules", id, func(move models.EventReschedule) bool { return move.EventID == id })
+		archiveMatching(r.s, r.s.cancels, "event_cancellations", id, func(cancel models.EventCancellation) bool { return cancel.EventID == id })
+		archiveMatching(r.s, r.s.prices, "event_price_changes", id, func(price models.PriceChange) bool { return price.EventID == id })
+		archiveMatching(r.s, r.s.rules, "pricing_rules", id, func(rule models.PricingRule) bool { return rule.EventID == id })
+		archiveMatching(r.s, r.s.scans, "ticket_scans", id, func(scan models.TicketScan) bool { return scan.EventID == id })
+		archiveMatching(r.s, r.s.scanners, "scanner_devices", id, func(scanner models.ScannerDevice) bool { return scanner.EventID == id })
+		archiveMatching(r.s, r.s.holds, "inventory_holds", id, func(hold models.InventoryHold) bool { return hold.EventID == id })
+		archiveMatching(r.s, r.s.links, "short_links", id, func(link models.ShortLink) bool { return link.EventID != nil && *link.EventID == id })
+		archiveMatching(r.s, r.s.flags, "fraud_flags", id, func(flag models.FraudFlag) bool { return flag.EventID == id })
+		archiveMatching(r.s, r.s.invoices, "uma_request_invoices", id, func(invoice models.UMARequestInvoice) bool {
+			return invoice.EventID != nil && *invoice.EventID == id
+		})
+		archiveMatching(r.s, r.s.attested, "purchase_attestations", id, func(a models.PurchaseAttestation) bool { return a.EventID == id })
+		delete(r.s.related, id)
+		for eventID, picks := range r.s.related {
+			r.s.related[eventID] = slices.DeleteFunc(picks, func(pick int) bool { return pick == id })
+		}
+		archiveMatching(r.s, r.s.events, "events", id, func(e models.Event) bool { return e.ID == id })
+	}
+	return len(due), nil
+}
+
+func (r *memoryArchiveRepository) Get(table string, recordID int) (*models.ArchivedRecord, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, record := range r.s.archived {
+		if record.TableName == table && record.RecordID == recordID {
+			return cloneArchivedRecord(record), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryArchiveRepository) ListByEvent(eventID int) ([]models.ArchivedRecord, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	records := []models.ArchivedRecord{}
+	for _, record := range r.s.archived {
+		if record.EventID != nil && *record.EventID == eventID {
+			records = append(records, *cloneArchivedRecord(record))
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+func cloneArchivedRecord(record models.ArchivedRecord) *models.ArchivedRecord {
+	record.EventID = clonePtr(record.EventID)
+	record.Data = maps.Clone(record.Data)
+	return &record
+}
+
+// archiveMatching moves the rows of a memory table that match into the
+// archive under eventID, in ID order. Callers hold the lock.
+func archiveMatching[T any](s *MemoryStore, rows map[int]T, table string, eventID int, match func(T) bool) {
+	keys := []int{}
+	for key, row := range rows {
+		if match(row) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		// Round-trip the db columns through JSON so they read back as
+		// they do from the database
+		data, _ := json.Marshal(rowColumns(reflect.ValueOf(rows[key])))
+		row := models.ArchivedRow{}
+		json.Unmarshal(data, &row)
+
+		s.archivedSeq++
+		recordID, _ := row["id"].(float64)
+		s.archived[s.archivedSeq] = models.ArchivedRecord{
+			ID:         s.archivedSeq,
+			TableName:  table,
+			RecordID:   int(recordID),
+			EventID:    &eventID,
+			Data:       row,
+			ArchivedAt: s.clock.Now(),
+		}
+		delete(rows, key)
+	}
+}
+
+// rowColumns maps a model's db-tagged fields, including those of embedded
+// structs, to their values, like the row SELECT * would return
+func rowColumns(v reflect.Value) map[string]interface{} {
+	columns := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := field.Tag.Get("db")
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			maps.Copy(columns, rowColumns(v.Field(i)))
+			continue
+		}
+		if tag == "" || tag == "-" {
+			continue
+		}
+		columns[tag] = v.Field(i).Interface()
+	}
+	return columns
+}
+
+// Console repository
+
+type memoryConsoleRepository struct{ s *MemoryStore }
+
+// Run computes the views' rows with the columns and order of their SQL
+func (r *memoryConsoleRepository) Run(view string, args []interface{}, limit int) (*models.ConsoleResult, error) {
+	v, ok := findConsoleView(view)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if len(args) != len(v.Params) {
+		return nil, fmt.Errorf("view %s takes %d args, got %d", view, len(v.Params), len(args))
+	}
+
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var columns []string
+	rows := []map[string]interface{}{}
+	switch view {
+	case "attendees":
+		eventID, _ := args[0].(int)
+		columns = []string{"ticket_id", "ticket_code", "payment_status", "is_comp", "amount_sats", "paid_at", "user_id", "email", "name"}
+		for _, ticket := range r.s.tickets {
+			user, ok := r.s.users[ticket.UserID]
+			if ticket.EventID != eventID || !ok {
+				continue
+			}
+			rows = append(rows, map[string]interface{}{
+				"ticket_id": ticket.ID, "ticket_code": ticket.TicketCode, "payment_status": ticket.PaymentStatus,
+				"is_comp": ticket.IsComp, "amount_sats": ticket.AmountSats, "paid_at": clonePtr(ticket.PaidAt),
+				"user_id": user.ID, "email": user.Email, "name": user.Name,
+			})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i]["ticket_id"].(int) < rows[j]["ticket_id"].(int) })
+	case "payments_summary":
+		eventID, _ := args[0].(int)
+		columns = []string{"status", "payments", "amount_sats"}
+		byStatus := map[string]map[string]interface{}{}
+		for _, payment := range r.s.payments {
+			ticket, ok := r.s.tickets[payment.TicketID]
+			if !ok || (eventID != 0 && ticket.EventID != eventID) {
+				continue
+			}
+			row, ok := byStatus[payment.Status]
+			if !ok {
+				row = map[string]interface{}{"status": payment.Status, "payments": 0, "amount_sats": int64(0)}
+				byStatus[payment.Status] = row
+				rows = append(rows, row)
+			}
+			row["payments"] = row["payments"].(int) + 1
+			row["amount_sats"] = row["amount_sats"].(int64) + payment.Amount
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i]["status"].(string) < rows[j]["status"].(string) })
+	case "user_lookup":
+		userID, _ := args[0].(int)
+		email, _ := args[1].(string)
+		columns = []string{"user_id", "email", "name", "is_guest", "created_at", "tickets", "paid_tickets"}
+		for _, user := range r.s.users {
+			if user.ID != userID && !strings.EqualFold(user.Email, email) {
+				continue
+			}
+			tickets, paid := 0, 0
+			for _, ticket := range r.s.tickets {
+				if ticket.UserID != user.ID {
+					continue
+				}
+				tickets++
+				if ticket.PaymentStatus == models.TicketPaid {
+					paid++
+				}
+			}
+			rows = append(rows, map[string]interface{}{
+				"user_id": user.ID, "email": user.Email, "name": user.Name, "is_guest": user.IsGuest,
+				"created_at": user.CreatedAt, "tickets": tickets, "paid_tickets": paid,
+			})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i]["user_id"].(int) < rows[j]["user_id"].(int) })
+	}
+
+	result := &models.ConsoleResult{View: view, Columns: columns, Rows: rows}
+	if len(rows) > limit {
+		result.Rows, result.Truncated = rows[:limit], true
+	}
+	return result, nil
+}
+
+// Admin notification repository
+
+type memoryAdminNotificationRepository struct{ s *MemoryStore }
+
+func cloneAdminNotification(notification models.AdminNotification) models.AdminNotification {
+	notification.EventID = clonePtr(notification.EventID)
+	notification.EntityID = clonePtr(notification.EntityID)
+	notification.ReadAt = clonePtr(notification.ReadAt)
+	notification.ReadBy = clonePtr(notification.ReadBy)
+	return notification
+}
+
+func (r *memoryAdminNotificationRepository) Create(notification *models.AdminNotification) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.alertSeq++
+	notification.ID = r.s.alertSeq
+	notification.ReadAt, notification.ReadBy = nil, nil
+	notification.CreatedAt = r.s.clock.Now()
+	r.s.alerts[notification.ID] = cloneAdminNotification(*notification)
+	return nil
+}
+
+// sorted returns the notifications match keeps, by ID
+func (r *memoryAdminNotificationRepository) sorted(match func(models.AdminNotification) bool) []models.AdminNotification {
+	notifications := []models.AdminNotification{}
+	for _, notification := range r.s.alerts {
+		if match(notification) {
+			notifications = append(notifications, cloneAdminNotification(notification))
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID < notifications[j].ID })
+	return notifications
+}
+
+func (r *memoryAdminNotificationRepository) List(unreadOnly bool, limit, offset int) ([]models.AdminNotification, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	notifications := r.sorted(func(notification models.AdminNotification) bool {
+		return !unreadOnly || notification.ReadAt == nil
+	})
+	for i, j := 0, len(notifications)-1; i < j; i, j = i+1, j-1 {
+		notifications[i], notifications[j] = notifications[j], notifications[i]
+	}
+	start, end := page(len(notifications), limit, offset)
+	return notifications[start:end], nil
+}
+
+func (r *memoryAdminNotificationRepository) ListAfter(afterID, limit int) ([]models.AdminNotification, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	notifications := r.sorted(func(notification models.AdminNotification) bool { return notification.ID > afterID })
+	start, end := page(len(notifications), limit, 0)
+	return notifications[start:end], nil
+}
+
+func (r *memoryAdminNotificationRepository) CountUnread() (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	count := 0
+	for _, notification := range r.s.alerts {
+		if notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryAdminNotificationRepository) MarkRead(id, adminID int) (*models.AdminNotification, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	notification, ok := r.s.alerts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if notification.ReadAt == nil {
+		now := r.s.clock.Now()
+		notification.ReadAt, notification.ReadBy = &now, &adminID
+		r.s.alerts[id] = notification
+	}
+	notification = cloneAdminNotification(notification)
+	return &notification, nil
+}
+
+func (r *memoryAdminNotificationRepository) MarkAllRead(adminID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.clock.Now()
+	marked := 0
+	for id, notification := range r.s.alerts {
+		if notification.ReadAt != nil {
+			continue
+		}
+		readAt, readBy := now, adminID
+		notification.ReadAt, notification.ReadBy = &readAt, &readBy
+		r.s.alerts[id] = notification
+		marked++
+	}
+	return marked, nil
+}
+
+// Alert connector repository
+
+type memoryAlertConnectorRepository struct{ s *MemoryStore }
+
+func cloneAlertConnector(connector models.AlertConnector) models.AlertConnector {
+	connector.Events = append(models.AlertEvents(nil), connector.Events...)
+	connector.LastSentAt = clonePtr(connector.LastSentAt)
+	return connector
+}
+
+func (r *memoryAlertConnectorRepository) Create(connector *models.AlertConnector) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.hookSeq++
+	connector.ID = r.s.hookSeq
+	connector.LastError, connector.LastSentAt = "", nil
+	connector.CreatedAt = r.s.clock.Now()
+	connector.UpdatedAt = connector.CreatedAt
+	r.s.hooks[connector.ID] = cloneAlertConnector(*connector)
+	return nil
+}
+
+func (r *memoryAlertConnectorRepository) GetByID(id int) (*models.AlertConnector, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	connector, ok := r.s.hooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	connector = cloneAlertConnector(connector)
+	return &connector, nil
+}
+
+func (r *memoryAlertConnectorRepository) List(activeOnly bool) ([]models.AlertConnector, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	connectors := []models.AlertConnector{}
+	for _, connector := range r.s.hooks {
+		if connector.IsActive || !activeOnly {
+			connectors = append(connectors, cloneAlertConnector(connector))
+		}
+	}
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].ID < connectors[j].ID })
+	return connectors, nil
+}
+
+func (r *memoryAlertConnectorRepository) Update(connector *models.AlertConnector) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.hooks[connector.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	connector.UpdatedAt = r.s.clock.Now()
+	stored.Name, stored.Target, stored.ChatID = connector.Name, connector.Target, connector.ChatID
+	stored.Events, stored.IsActive, stored.UpdatedAt = connector.Events, connector.IsActive, connector.UpdatedAt
+	r.s.hooks[connector.ID] = cloneAlertConnector(stored)
+	return nil
+}
+
+func (r *memoryAlertConnectorRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.hooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.hooks, id)
+	return nil
+}
+
+func (r *memoryAlertConnectorRepository) RecordDelivery(id int, sentAt time.Time, lastError string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	connector, ok := r.s.hooks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if lastError == "" {
+		connector.LastSentAt = &sentAt
+	}
+	connector.LastError = lastError
+	r.s.hooks[id] = connector
+	return nil
+}
+
+// Status incident repository
+
+type memoryStatusIncidentRepository struct{ s *MemoryStore }
+
+func cloneStatusIncident(incident models.StatusIncident) models.StatusIncident {
+	incident.Components = append(models.IncidentComponents(nil), incident.Components...)
+	incident.CreatedBy = clonePtr(incident.CreatedBy)
+	incident.ResolvedAt = clonePtr(incident.ResolvedAt)
+	return incident
+}
+
+func (r *memoryStatusIncidentRepository) Create(incident *models.StatusIncident) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.outageSeq++
+	incident.ID = r.s.outageSeq
+	incident.CreatedAt = r.s.clock.Now()
+	incident.UpdatedAt = incident.CreatedAt
+	r.s.outages[incident.ID] = cloneStatusIncident(*incident)
+	return nil
+}
+
+func (r *memoryStatusIncidentRepository) GetByID(id int) (*models.StatusIncident, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	incident, ok := r.s.outages[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	incident = cloneStatusIncident(incident)
+	return &incident, nil
+}
+
+// sorted returns the incidents include keeps, latest started first
+func (r *memoryStatusIncidentRepository) sorted(include func(models.StatusIncident) bool) []models.StatusIncident {
+	incidents := []models.StatusIncident{}
+	for _, incident := range r.s.outages {
+		if include(incident) {
+			incidents = append(incidents, cloneStatusIncident(incident))
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		if !incidents[i].StartedAt.Equal(incidents[j].StartedAt) {
+			return incidents[i].StartedAt.After(incidents[j].StartedAt)
+		}
+		return incidents[i].ID > incidents[j].ID
+	})
+	return incidents
+}
+
+func (r *memoryStatusIncidentRepository) List(limit, offset int) ([]models.StatusIncident, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	incidents := r.sorted(func(models.StatusIncident) bool { return true })
+	if offset >= len(incidents) {
+		return []models.StatusIncident{}, nil
+	}
+	return incidents[offset:min(offset+limit, len(incidents))], nil
+}
+
+func (r *memoryStatusIncidentRepository) ListRecent(resolvedSince time.Time) ([]models.StatusIncident, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.sorted(func(incident models.StatusIncident) bool {
+		return incident.ResolvedAt == nil || !incident.ResolvedAt.Before(resolvedSince)
+	}), nil
+}
+
+func (r *memoryStatusIncidentRepository) Update(incident *models.StatusIncident) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.outages[incident.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	incident.UpdatedAt = r.s.clock.Now()
+	stored.Title, stored.Message, stored.Status, stored.Impact = incident.Title, incident.Message, incident.Status, incident.Impact
+	stored.Components, stored.ResolvedAt, stored.UpdatedAt = incident.Components, incident.ResolvedAt, incident.UpdatedAt
+	r.s.outages[incident.ID] = cloneStatusIncident(stored)
+	return nil
+}
+
+func (r *memoryStatusIncidentRepository) Delete(id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.outages[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.outages, id)
+	return nil
+}
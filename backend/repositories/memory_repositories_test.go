@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/listquery"
+	"tickets-by-uma/models"
+)
+
+func TestMemoryStore(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore(clk)
+	users, events, tickets, invoices := store.Users(), store.Events(), store.Tickets(), store.UMARequestInvoices()
+
+	user := &models.User{Email: "fan@example.com", Name: "Fan"}
+	if err := users.Create(user); err != nil || user.ID != 1 || !user.CreatedAt.Equal(clk.Now()) {
+		t.Fatalf("Create user: id=%d created=%v err=%v", user.ID, user.CreatedAt, err)
+	}
+	if err := users.Create(&models.User{Email: "fan@example.com"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for duplicate email, got %v", err)
+	}
+	if _, err := users.GetByID(42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// Returned records are copies
+	fetched, _ := users.GetByEmail("fan@example.com")
+	fetched.Name = "Changed"
+	if again, _ := users.GetByID(user.ID); again.Name != "Fan" {
+		t.Errorf("Store shares state with callers, got name %q", again.Name)
+	}
+
+	event := &models.Event{Title: "Concert", Capacity: 2, StartTime: clk.Now().Add(time.Hour), IsActive: true}
+	if err := events.Create(event); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Create(&models.Event{Title: "Earlier", StartTime: clk.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := events.GetAll(10, 0); len(all) != 2 || all[0].Title != "Earlier" {
+		t.Errorf("Expected events ordered by start time, got %+v", all)
+	}
+	if active, _ := events.GetActive(10, 0); len(active) != 1 || active[0].ID != event.ID {
+		t.Errorf("Expected only the active event, got %+v", active)
+	}
+	if paged, _ := events.GetAll(1, 1); len(paged) != 1 || paged[0].ID != event.ID {
+		t.Errorf("Expected second event on page 2, got %+v", paged)
+	}
+
+	if err := invoices.Create(&models.UMARequestInvoice{EventID: &event.ID, Source: models.UMAInvoiceAdmin, InvoiceID: "inv-1"}); err != nil {
+		t.Fatal(err)
+	}
+	withInvoice, err := events.GetByIDWithUMAInvoice(event.ID)
+	if err != nil || withInvoice.UMARequestInvoice == nil || withInvoice.UMARequestInvoice.InvoiceID != "inv-1" {
+		t.Errorf("Expected event with UMA invoice, got %+v (%v)", withInvoice, err)
+	}
+
+	for i, code := range []string{"code-a", "code-b"} {
+		clk.Advance(time.Minute)
+		ticket := &models.Ticket{EventID: event.ID, UserID: user.ID, TicketCode: code, PaymentStatus: "pending"}
+		if err := tickets.Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := tickets.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tickets.Create(&models.Ticket{TicketCode: "code-a", PaymentStatus: "pending"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for duplicate ticket code, got %v", err)
+	}
+	if available, _ := events.GetAvailableTicketCount(event.ID); available != 0 {
+		t.Errorf("Expected no ticket available beside the paid and pending ones, got %d", available)
+	}
+	if err := tickets.Create(&models.Ticket{EventID: event.ID, UserID: user.ID, TicketCode: "code-c", PaymentStatus: "pending", NeedsSeat: true}); !errors.Is(err, ErrSoldOut) {
+		t.Errorf("Expected ErrSoldOut for a ticket needing a seat, got %v", err)
+	}
+	if availability, _ := events.GetAvailability(event.ID); availability.Sold != 1 || availability.Pending != 1 || availability.Remaining != 0 {
+		t.Errorf("Expected 1 sold, 1 pending and none remaining, got %+v", availability)
+	}
+	if err := events.UpdateCapacity(event.ID, 1); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for capacity below the held seats, got %v", err)
+	}
+	if byUser, _ := tickets.GetByUserID(user.ID); len(byUser) != 2 || byUser[0].TicketCode != "code-b" {
+		t.Errorf("Expected newest ticket first, got %+v", byUser)
+	}
+	if paid, _ := tickets.GetByTicketCode("code-a"); paid.PaidAt == nil || !paid.PaidAt.Equal(clk.Now().Add(-time.Minute)) {
+		t.Errorf("Expected paid_at stamped by the clock, got %v", paid.PaidAt)
+	}
+
+	if err := events.Delete(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := invoices.GetByEventID(event.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected UMA invoice deleted with its event, got %v", err)
+	}
+}
+
+func TestMemoryPaymentRepositoryConcurrency(t *testing.T) {
+	payments := NewMemoryStore(clock.System()).Payments()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payment := &models.Payment{TicketID: i, Amount: 100, Status: "pending"}
+			if err := payments.Create(payment); err != nil {
+				t.Error(err)
+				return
+			}
+			payments.UpdateStatus(payment.ID, "paid")
+		}(i)
+	}
+	wg.Wait()
+
+	count := 0
+	err := payments.StreamAll(listquery.Query{}, func(p *models.Payment) error {
+		count++
+		// Callbacks may use the repository while streaming
+		_, err := payments.GetByID(p.ID)
+		return err
+	})
+	if err != nil || count != 50 {
+		t.Errorf("Expected 50 streamed payments, got %d (%v)", count, err)
+	}
+	if pending, _ := payments.GetPendingPayments(); len(pending) != 0 {
+		t.Errorf("Expected no pending payments, got %d", len(pending))
+	}
+}
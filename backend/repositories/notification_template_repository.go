@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var notificationTemplateTable = newTable[models.NotificationTemplate]("notification_templates")
+
+type notificationTemplateRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewNotificationTemplateRepository creates the notification template
+// repository. clk stamps created_at.
+func NewNotificationTemplateRepository(db *sqlx.DB, clk clock.Clock) NotificationTemplateRepository {
+	return &notificationTemplateRepository{db: db, clock: clk}
+}
+
+// scopeOf keys an organizer for the scope queries; platform templates have
+// no organizer and are matched as 0, which is never a user ID
+func scopeOf(organizerID *int) int {
+	if organizerID == nil {
+		return 0
+	}
+	return *organizerID
+}
+
+func (r *notificationTemplateRepository) Create(template *models.NotificationTemplate) error {
+	// The unique version indexes reject a concurrent save of the same scope
+	query := `
+		INSERT INTO notification_templates (organizer_id, kind, locale, version, subject, text_body, html_body, created_by, created_at)
+		SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, $6, $7, $8
+		FROM notification_templates
+		WHERE COALESCE(organizer_id, 0) = $9 AND kind = $2 AND locale = $3
+		` + notificationTemplateTable.returning()
+
+	return r.db.QueryRowx(query,
+		template.OrganizerID, template.Kind, template.Locale, template.Subject, template.TextBody, template.HTMLBody,
+		template.CreatedBy, r.clock.Now(), scopeOf(template.OrganizerID)).StructScan(template)
+}
+
+func (r *notificationTemplateRepository) GetByID(id int) (*models.NotificationTemplate, error) {
+	return notificationTemplateTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *notificationTemplateRepository) GetCurrent(organizerID *int, kind, locale string) (*models.NotificationTemplate, error) {
+	query := `
+		WHERE COALESCE(organizer_id, 0) = $1 AND kind = $2 AND locale = $3
+		ORDER BY version DESC
+		LIMIT 1`
+	return notificationTemplateTable.get(r.db, query, scopeOf(organizerID), kind, locale)
+}
+
+func (r *notificationTemplateRepository) GetVersions(organizerID *int, kind, locale string) ([]models.NotificationTemplate, error) {
+	query := `
+		WHERE COALESCE(organizer_id, 0) = $1 AND kind = $2 AND locale = $3
+		ORDER BY version DESC`
+	return notificationTemplateTable.list(r.db, query, scopeOf(organizerID), kind, locale)
+}
+
+func (r *notificationTemplateRepository) GetAllCurrent() ([]models.NotificationTemplate, error) {
+	query := `AS t
+		WHERE version = (
+			SELECT MAX(version) FROM notification_templates
+			WHERE COALESCE(organizer_id, 0) = COALESCE(t.organizer_id, 0) AND kind = t.kind AND locale = t.locale
+		)
+		ORDER BY COALESCE(organizer_id, 0), kind, locale`
+	return notificationTemplateTable.list(r.db, query)
+}
+
+func (r *notificationTemplateRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM notification_templates WHERE id = $1`, id)
+	return err
+}
@@ -1,20 +1,27 @@
 package repositories
 
 import (
-	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"tickets-by-uma/encryption"
 	"tickets-by-uma/models"
 )
 
+var nwcConnectionTable = newTable[models.NWCConnection]("nwc_connections")
+
 type nwcConnectionRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	keyring *encryption.Keyring
+	cipher  fieldCipher
 }
 
-func NewNWCConnectionRepository(db *sqlx.DB) NWCConnectionRepository {
-	return &nwcConnectionRepository{db: db}
+// NewNWCConnectionRepository creates the NWC connection repository. Connection
+// URIs are encrypted at rest with keyring; a nil keyring stores them as-is.
+func NewNWCConnectionRepository(db *sqlx.DB, keyring *encryption.Keyring) NWCConnectionRepository {
+	return &nwcConnectionRepository{db: db, keyring: keyring, cipher: fieldCipher{keyring: keyring}}
 }
 
 func (r *nwcConnectionRepository) Upsert(userID int, connectionURI string, expiresAt *time.Time) error {
@@ -24,20 +31,64 @@ func (r *nwcConnectionRepository) Upsert(userID int, connectionURI string, expir
 		ON CONFLICT (user_id) DO UPDATE
 		SET connection_uri = $2, expires_at = $3, updated_at = $5`
 
+	stored, err := r.cipher.seal(connectionURI)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
-	_, err := r.db.Exec(query, userID, connectionURI, expiresAt, now, now)
+	_, err = r.db.Exec(query, userID, stored, expiresAt, now, now)
 	return err
 }
 
 func (r *nwcConnectionRepository) GetByUserID(userID int) (*models.NWCConnection, error) {
-	conn := &models.NWCConnection{}
-	query := `SELECT * FROM nwc_connections WHERE user_id = $1`
-	err := r.db.Get(conn, query, userID)
+	conn, err := nwcConnectionTable.get(r.db, `WHERE user_id = $1`, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+
+	if conn.ConnectionURI, err = r.cipher.open(conn.ConnectionURI); err != nil {
+		return nil, fmt.Errorf("failed to decrypt NWC connection for user %d: %w", userID, err)
+	}
 	return conn, nil
 }
+
+// Reencrypt rewrites connection URIs that are stored in plaintext or under a
+// retired key so that old keys can be removed from the keyring.
+func (r *nwcConnectionRepository) Reencrypt() (int, error) {
+	if r.keyring == nil {
+		return 0, nil
+	}
+
+	conns, err := nwcConnectionTable.list(r.db, "")
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, conn := range conns {
+		if !r.keyring.NeedsRotation(conn.ConnectionURI) {
+			continue
+		}
+
+		plaintext, err := r.keyring.Decrypt(conn.ConnectionURI)
+		if err != nil {
+			return updated, fmt.Errorf("failed to decrypt NWC connection %d: %w", conn.ID, err)
+		}
+		sealed, err := r.keyring.Encrypt(plaintext)
+		if err != nil {
+			return updated, err
+		}
+
+		// Only overwrite if the row was not changed concurrently
+		result, err := r.db.Exec(`UPDATE nwc_connections SET connection_uri = $1 WHERE id = $2 AND connection_uri = $3`,
+			sealed, conn.ID, conn.ConnectionURI)
+		if err != nil {
+			return updated, err
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			updated++
+		}
+	}
+	return updated, nil
+}
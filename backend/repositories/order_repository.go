@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var orderTable = newTable[models.Order]("orders")
+
+type orderRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewOrderRepository creates the order repository. clk stamps created_at.
+func NewOrderRepository(db *sqlx.DB, clk clock.Clock) OrderRepository {
+	return &orderRepository{db: db, clock: clk}
+}
+
+func (r *orderRepository) Create(order *models.Order) error {
+	query := `
+		INSERT INTO orders (user_id, event_id, created_at, ref, utm_source, utm_medium, utm_campaign, utm_term, utm_content,
+		                    affiliate_id, commission_basis_points)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowx(query, order.UserID, order.EventID, r.clock.Now(), order.Ref,
+		order.UTMSource, order.UTMMedium, order.UTMCampaign, order.UTMTerm, order.UTMContent,
+		order.AffiliateID, order.CommissionBasisPoints).StructScan(order)
+}
+
+func (r *orderRepository) GetByID(id int) (*models.Order, error) {
+	return orderTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *orderRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM orders WHERE id = $1`, id)
+	return err
+}
+
+func (r *orderRepository) GetByUserID(userID int) ([]models.Order, error) {
+	return orderTable.list(r.db, `WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+}
+
+// referralSources are the columns a referral report groups orders by
+var referralSources = map[string]string{
+	models.ReferralGroupSource:   "CASE WHEN o.utm_source <> '' THEN o.utm_source ELSE o.ref END",
+	models.ReferralGroupMedium:   "o.utm_medium",
+	models.ReferralGroupCampaign: "o.utm_campaign",
+	models.ReferralGroupRef:      "o.ref",
+}
+
+func (r *orderRepository) ReferralConversions(filter models.ReferralFilter) ([]models.ReferralConversion, error) {
+	source, ok := referralSources[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown referral grouping %q", filter.GroupBy)
+	}
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filter.EventID != 0 {
+		args = append(args, filter.EventID)
+		conditions = append(conditions, fmt.Sprintf("o.event_id = $%d", len(args)))
+	}
+	if filter.OrganizerID != 0 {
+		args = append(args, filter.OrganizerID)
+		conditions = append(conditions, fmt.Sprintf("e.organizer_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("o.created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("o.created_at < $%d", len(args)))
+	}
+
+	query := `
+		SELECT source, COUNT(*) AS orders,
+		       CAST(SUM(CASE WHEN tickets_sold > 0 THEN 1 ELSE 0 END) AS BIGINT) AS converted_orders,
+		       CAST(SUM(tickets_sold) AS BIGINT) AS tickets_sold,
+		       CAST(SUM(revenue_sats) AS BIGINT) AS revenue_sats
+		FROM (
+			SELECT ` + source + ` AS source,
+			       COUNT(t.id) AS tickets_sold,
+			       COALESCE(SUM(t.amount_sats), 0) AS revenue_sats
+			FROM orders o
+			JOIN events e ON e.id = o.event_id
+			LEFT JOIN tickets t ON t.order_id = o.id AND t.payment_status = 'paid'
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			GROUP BY o.id
+		) converted
+		GROUP BY source
+		ORDER BY revenue_sats DESC, orders DESC, source`
+
+	conversions := []models.ReferralConversion{}
+	if err := r.db.Select(&conversions, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range conversions {
+		conversions[i].ConversionRate = float64(conversions[i].ConvertedOrders) / float64(conversions[i].Orders)
+	}
+	return conversions, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var organizerProfileTable = newTable[models.OrganizerProfile]("organizer_profiles")
+
+type organizerProfileRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewOrganizerProfileRepository creates the organizer profile repository.
+// clk stamps profiles as they are saved.
+func NewOrganizerProfileRepository(db *sqlx.DB, clk clock.Clock) OrganizerProfileRepository {
+	return &organizerProfileRepository{db: db, clock: clk}
+}
+
+func (r *organizerProfileRepository) Save(profile *models.OrganizerProfile) error {
+	// Checked here so a taken slug is ErrConflict rather than a unique
+	// constraint violation
+	query := `
+		INSERT INTO organizer_profiles (user_id, slug, display_name, bio, website_url, payout_address, created_at, updated_at)
+		SELECT CAST($1 AS INTEGER), $2, $3, $4, $5, $6, $7, $7
+		WHERE NOT EXISTS (SELECT 1 FROM organizer_profiles WHERE slug = $2 AND user_id <> $1)
+		ON CONFLICT (user_id) DO UPDATE
+		SET slug = EXCLUDED.slug, display_name = EXCLUDED.display_name, bio = EXCLUDED.bio,
+		    website_url = EXCLUDED.website_url, payout_address = EXCLUDED.payout_address, updated_at = EXCLUDED.updated_at
+		` + organizerProfileTable.returning()
+
+	err := r.db.QueryRowx(query,
+		profile.UserID, profile.Slug, profile.DisplayName, profile.Bio, profile.WebsiteURL, profile.PayoutAddress, r.clock.Now()).StructScan(profile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *organizerProfileRepository) GetByUserID(userID int) (*models.OrganizerProfile, error) {
+	return organizerProfileTable.get(r.db, `WHERE user_id = $1`, userID)
+}
+
+func (r *organizerProfileRepository) GetBySlug(slug string) (*models.OrganizerProfile, error) {
+	return organizerProfileTable.get(r.db, `WHERE slug = $1`, slug)
+}
+
+func (r *organizerProfileRepository) IsOrganizer(userID int) (bool, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT COUNT(*) FROM events WHERE organizer_id = $1`, userID)
+	return count > 0, err
+}
+
+func (r *organizerProfileRepository) GetUpcomingEvents(userID int, now time.Time, limit int) ([]models.Event, error) {
+	events := []models.Event{}
+	query := `
+		SELECT ` + eventTable.selectAll("e") + ` FROM events e
+		WHERE e.organizer_id = $1 AND ` + onSaleCondition + `
+		ORDER BY e.start_time, e.id
+		LIMIT $3`
+	err := r.db.Select(&events, query, userID, now, limit)
+	return events, err
+}
+
+func (r *organizerProfileRepository) CountPastEvents(userID int, now time.Time) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM events
+		WHERE organizer_id = $1 AND is_private = false AND cancelled_at IS NULL AND end_time <= $2`
+	err := r.db.Get(&count, query, userID, now)
+	return count, err
+}
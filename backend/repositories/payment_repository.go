@@ -2,81 +2,115 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"tickets-by-uma/clock"
+	"tickets-by-uma/listquery"
 	"tickets-by-uma/models"
 )
 
+// PaymentListFields are the fields payment lists can be sorted and
+// filtered by. Postgres partitions payments by month of created_at, so a
+// created_at range only reads the months it covers.
+var PaymentListFields = listquery.Fields{
+	"id":               {Column: "id", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"ticket_id":        {Column: "ticket_id", Kind: listquery.Int, Filterable: true},
+	"invoice_id":       {Column: "invoice_id", Kind: listquery.Text, Filterable: true},
+	"payment_hash":     {Column: "payment_hash", Kind: listquery.Text, Filterable: true},
+	"amount_sats":      {Column: "amount_sats", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"status":           {Column: "status", Kind: listquery.Text, Sortable: true, Filterable: true},
+	"tax_jurisdiction": {Column: "tax_jurisdiction", Kind: listquery.Text, Filterable: true},
+	"paid_at":          {Column: "paid_at", Kind: listquery.Time, Filterable: true},
+	"created_at":       {Column: "created_at", Kind: listquery.Time, Sortable: true, Filterable: true},
+	"updated_at":       {Column: "updated_at", Kind: listquery.Time, Sortable: true, Filterable: true},
+}
+
+var paymentTable = newTable[models.Payment]("payments")
+
 type paymentRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	clock clock.Clock
 }
 
-func NewPaymentRepository(db *sqlx.DB) PaymentRepository {
-	return &paymentRepository{db: db}
+// NewPaymentRepository creates the payment repository. clk stamps
+// created/updated and paid_at times.
+func NewPaymentRepository(db *sqlx.DB, clk clock.Clock) PaymentRepository {
+	return &paymentRepository{db: db, clock: clk}
 }
 
 func (r *paymentRepository) Create(payment *models.Payment) error {
 	query := `
-		INSERT INTO payments (ticket_id, invoice_id, amount_sats, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO payments (ticket_id, invoice_id, bolt11, payment_hash, amount_sats, status,
+		                      taxable_sats, tax_sats, tax_basis_points, tax_inclusive, tax_jurisdiction, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at, updated_at`
 
-	now := time.Now()
+	if err := checkPaymentStatus(payment.Status); err != nil {
+		return err
+	}
+	now := r.clock.Now()
 	return r.db.QueryRowx(query,
-		payment.TicketID, payment.InvoiceID, payment.Amount, payment.Status, now, now).StructScan(payment)
+		payment.TicketID, payment.InvoiceID, payment.Bolt11, payment.PaymentHash, payment.Amount, payment.Status,
+		payment.TaxableSats, payment.TaxSats, payment.TaxBasisPoints, payment.TaxInclusive, payment.TaxJurisdiction,
+		payment.ExpiresAt, now, now).StructScan(payment)
 }
 
 func (r *paymentRepository) GetByID(id int) (*models.Payment, error) {
-	payment := &models.Payment{}
-	query := `SELECT * FROM payments WHERE id = $1`
-	err := r.db.Get(payment, query, id)
+	return paymentTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *paymentRepository) GetByIDs(ids []int) (map[int]*models.Payment, error) {
+	payments, err := paymentTable.byIDs(r.db, ids)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return payment, nil
+	byID := make(map[int]*models.Payment, len(payments))
+	for i := range payments {
+		byID[payments[i].ID] = &payments[i]
+	}
+	return byID, nil
 }
 
 func (r *paymentRepository) GetByInvoiceID(invoiceID string) (*models.Payment, error) {
-	payment := &models.Payment{}
-	query := `SELECT * FROM payments WHERE invoice_id = $1`
-	err := r.db.Get(payment, query, invoiceID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return payment, nil
+	return paymentTable.get(r.db, `WHERE invoice_id = $1`, invoiceID)
+}
+
+func (r *paymentRepository) GetByBolt11(bolt11 string) (*models.Payment, error) {
+	return paymentTable.get(r.db, `WHERE bolt11 = $1`, bolt11)
+}
+
+func (r *paymentRepository) GetByPaymentHash(paymentHash string) (*models.Payment, error) {
+	return paymentTable.get(r.db, `WHERE payment_hash = $1`, paymentHash)
 }
 
 func (r *paymentRepository) GetByTicketID(ticketID int) (*models.Payment, error) {
-	payment := &models.Payment{}
-	query := `SELECT * FROM payments WHERE ticket_id = $1`
-	err := r.db.Get(payment, query, ticketID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return payment, nil
+	return paymentTable.get(r.db, `WHERE ticket_id = $1`, ticketID)
 }
 
+// Update saves the payment. Its created_at never changes and, as the
+// partition key, narrows the update to one month's partition.
 func (r *paymentRepository) Update(payment *models.Payment) error {
 	query := `
 		UPDATE payments 
-		SET ticket_id = $1, invoice_id = $2, amount_sats = $3, status = $4, paid_at = $5, updated_at = $6
-		WHERE id = $7`
+		SET ticket_id = $1, invoice_id = $2, bolt11 = $3, payment_hash = $4, amount_sats = $5, status = $6,
+		    paid_at = $7, expires_at = $8, updated_at = $9, taxable_sats = $10, tax_sats = $11,
+		    tax_basis_points = $12, tax_inclusive = $13, tax_jurisdiction = $14
+		WHERE id = $15 AND created_at = $16`
 
-	payment.UpdatedAt = time.Now()
+	if err := checkPaymentStatus(payment.Status); err != nil {
+		return err
+	}
+	payment.UpdatedAt = r.clock.Now()
 	_, err := r.db.Exec(query,
-		payment.TicketID, payment.InvoiceID, payment.Amount, payment.Status,
-		payment.PaidAt, payment.UpdatedAt, payment.ID)
+		payment.TicketID, payment.InvoiceID, payment.Bolt11, payment.PaymentHash, payment.Amount, payment.Status,
+		payment.PaidAt, payment.ExpiresAt, payment.UpdatedAt, payment.TaxableSats, payment.TaxSats,
+		payment.TaxBasisPoints, payment.TaxInclusive, payment.TaxJurisdiction, payment.ID, payment.CreatedAt)
 	return err
 }
 
@@ -86,51 +120,127 @@ func (r *paymentRepository) UpdateStatus(id int, status string) error {
 		SET status = $1, updated_at = $2, paid_at = $3
 		WHERE id = $4`
 
-	now := time.Now()
+	if err := checkPaymentStatus(status); err != nil {
+		return err
+	}
+	now := r.clock.Now()
 	var paidAt *time.Time
 	if status == "paid" {
 		paidAt = &now
+		query += ` AND status IN ('pending', 'expired')`
 	}
 
-	_, err := r.db.Exec(query, status, now, paidAt, id)
-	return err
+	result, err := r.db.Exec(query, status, now, paidAt, id)
+	if err != nil || status != "paid" {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+	var current string
+	err = r.db.Get(&current, `SELECT status FROM payments WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) || current == "paid" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrConflict
 }
 
 func (r *paymentRepository) UpdatePreimage(id int, preimage string) error {
 	query := `UPDATE payments SET preimage = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, preimage, time.Now(), id)
+	_, err := r.db.Exec(query, preimage, r.clock.Now(), id)
 	return err
 }
 
 func (r *paymentRepository) GetPendingPayments() ([]models.Payment, error) {
+	return paymentTable.list(r.db, `WHERE status = 'pending' ORDER BY created_at ASC`)
+}
+
+func (r *paymentRepository) ExpireOverdue() ([]models.Payment, error) {
 	payments := []models.Payment{}
-	query := `SELECT * FROM payments WHERE status = 'pending' ORDER BY created_at ASC`
-	err := r.db.Select(&payments, query)
+	query := `
+		UPDATE payments SET status = 'expired', updated_at = $1
+		WHERE status = 'pending' AND expires_at <= $1
+		` + paymentTable.returning()
+	err := r.db.Select(&payments, query, r.clock.Now())
 	return payments, err
 }
 
 func (r *paymentRepository) GetAvailablePaymentForEvent(eventID int) (*models.Payment, error) {
 	// This method is no longer needed with UMA Request pattern
-	// Return nil to indicate no pre-created payments available
-	return nil, nil
+	// Report that no pre-created payments are available
+	return nil, ErrNotFound
 }
 
 func (r *paymentRepository) GetAllPayments() ([]models.Payment, error) {
-	payments := []models.Payment{}
-	query := `SELECT * FROM payments ORDER BY created_at DESC`
-	err := r.db.Select(&payments, query)
-	return payments, err
+	return paymentTable.list(r.db, `ORDER BY created_at DESC`)
 }
 
-func (r *paymentRepository) GetOldestPendingByAmount(amountSats int64) (*models.Payment, error) {
-	payment := &models.Payment{}
-	query := `SELECT * FROM payments WHERE status = 'pending' AND amount_sats = $1 ORDER BY created_at ASC LIMIT 1`
-	err := r.db.Get(payment, query, amountSats)
+func (r *paymentRepository) StreamAll(list listquery.Query, fn func(*models.Payment) error) error {
+	where, args := list.Where(PaymentListFields, nil)
+	if where != "" {
+		where = "WHERE " + where + " "
+	}
+	page, args := list.Page(args)
+	query := paymentTable.query(where + `ORDER BY ` +
+		list.OrderBy(PaymentListFields, listquery.Order{Field: "created_at", Desc: true}) + page)
+
+	rows, err := r.db.Queryx(query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		payment := &models.Payment{}
+		if err := rows.StructScan(payment); err != nil {
+			return err
 		}
-		return nil, err
+		if err := fn(payment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *paymentRepository) GetOldestPendingByAmount(amountSats int64) (*models.Payment, error) {
+	return paymentTable.get(r.db, `WHERE status = 'pending' AND amount_sats = $1 ORDER BY created_at ASC LIMIT 1`, amountSats)
+}
+
+func (r *paymentRepository) TaxSummary(from, to *time.Time) ([]models.TaxSummary, error) {
+	conditions := []string{"status = 'paid'", "tax_basis_points > 0"}
+	var args []interface{}
+	if from != nil {
+		args = append(args, *from)
+		conditions = append(conditions, fmt.Sprintf("paid_at >= $%d", len(args)))
+	}
+	if to != nil {
+		// Payments are created before they are paid, which bounds the
+		// monthly partitions to scan
+		args = append(args, *to)
+		conditions = append(conditions, fmt.Sprintf("paid_at < $%d", len(args)), fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := `
+		SELECT tax_jurisdiction, tax_basis_points, COUNT(*) AS payments,
+		       CAST(SUM(taxable_sats) AS BIGINT) AS taxable_sats,
+		       CAST(SUM(tax_sats) AS BIGINT) AS tax_sats
+		FROM payments
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY tax_jurisdiction, tax_basis_points
+		ORDER BY tax_jurisdiction, tax_basis_points`
+
+	summary := []models.TaxSummary{}
+	err := r.db.Select(&summary, query, args...)
+	return summary, err
+}
+
+// checkPaymentStatus refuses a status the database would, naming it
+func checkPaymentStatus(status string) error {
+	if !slices.Contains(models.PaymentStatuses, status) {
+		return fmt.Errorf("%w: payment status %q", ErrInvalidStatus, status)
 	}
-	return payment, nil
+	return nil
 }
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// getByIDsBatch caps how many IDs are bound in one IN list, well under the
+// parameter limits of Postgres and SQLite
+const getByIDsBatch = 500
+
+// Preload loads the records a list refers to with one getByIDs call, e.g.
+// the events of a page of tickets:
+//
+//	events, err := repositories.Preload(tickets, func(t models.Ticket) int { return t.EventID }, eventRepo.GetByIDs)
+//
+// key returns 0 for an item that refers to nothing. The result is keyed by
+// ID; records that no longer exist are missing from it.
+func Preload[T, R any](items []T, key func(T) int, getByIDs func(ids []int) (map[int]*R, error)) (map[int]*R, error) {
+	ids := KeyIDs(items, key)
+	if len(ids) == 0 {
+		return map[int]*R{}, nil
+	}
+	return getByIDs(ids)
+}
+
+// KeyIDs returns the distinct non-zero IDs key picks from items, in the
+// order first seen
+func KeyIDs[T any](items []T, key func(T) int) []int {
+	seen := make(map[int]bool, len(items))
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		id := key(item)
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// selectByIDs runs query with its %s filled by the placeholders of ids, a
+// batch of ids at a time, and returns every row selected
+func selectByIDs[T any](db *sqlx.DB, query string, ids []int) ([]T, error) {
+	rows := []T{}
+	for start := 0; start < len(ids); start += getByIDsBatch {
+		batch := ids[start:min(start+getByIDsBatch, len(ids))]
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = id
+		}
+		page := []T{}
+		if err := db.Select(&page, fmt.Sprintf(query, strings.Join(placeholders, ", ")), args...); err != nil {
+			return nil, err
+		}
+		rows = append(rows, page...)
+	}
+	return rows, nil
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	pricingRuleTable = newTable[models.PricingRule]("pricing_rules")
+	priceChangeTable = newTable[models.PriceChange]("event_price_changes")
+)
+
+type pricingRuleRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewPricingRuleRepository creates the pricing rule repository. clk stamps
+// rules as they are created.
+func NewPricingRuleRepository(db *sqlx.DB, clk clock.Clock) PricingRuleRepository {
+	return &pricingRuleRepository{db: db, clock: clk}
+}
+
+func (r *pricingRuleRepository) Create(rule *models.PricingRule) error {
+	query := `
+		INSERT INTO pricing_rules (event_id, name, price_sats, sold_percent, starts_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		` + pricingRuleTable.returning()
+
+	return r.db.QueryRowx(query,
+		rule.EventID, rule.Name, rule.PriceSats, rule.SoldPercent, rule.StartsAt, r.clock.Now()).StructScan(rule)
+}
+
+func (r *pricingRuleRepository) GetByID(id int) (*models.PricingRule, error) {
+	return pricingRuleTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *pricingRuleRepository) GetByEventID(eventID int) ([]models.PricingRule, error) {
+	return pricingRuleTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
+
+func (r *pricingRuleRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM pricing_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pricingRuleRepository) RecordPrice(change *models.PriceChange) (*models.PriceChange, error) {
+	query := `
+		INSERT INTO event_price_changes (event_id, price_sats, rule_id, rule_name, changed_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (
+			SELECT 1 FROM event_price_changes
+			WHERE id = (SELECT MAX(id) FROM event_price_changes WHERE event_id = $1)
+				AND price_sats = $2 AND rule_name = $4)
+		` + priceChangeTable.returning()
+
+	recorded := &models.PriceChange{}
+	err := r.db.QueryRowx(query,
+		change.EventID, change.PriceSats, change.RuleID, change.RuleName, r.clock.Now()).StructScan(recorded)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return recorded, err
+	}
+	return priceChangeTable.get(r.db, `WHERE event_id = $1 ORDER BY id DESC LIMIT 1`, change.EventID)
+}
+
+func (r *pricingRuleRepository) GetPriceChange(id int) (*models.PriceChange, error) {
+	return priceChangeTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *pricingRuleRepository) GetPriceHistory(eventID int) ([]models.PriceChange, error) {
+	return priceChangeTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
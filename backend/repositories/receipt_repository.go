@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	receiptTable         = newTable[models.Receipt]("receipts")
+	paymentLineItemTable = newTable[models.PaymentLineItem]("payment_line_items")
+)
+
+type receiptRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewReceiptRepository creates the receipt repository. clk stamps created_at
+// and issued_at.
+func NewReceiptRepository(db *sqlx.DB, clk clock.Clock) ReceiptRepository {
+	return &receiptRepository{db: db, clock: clk}
+}
+
+func (r *receiptRepository) CreateLineItems(items []models.PaymentLineItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertLineItems(tx, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *receiptRepository) ReplaceLineItems(paymentID int, items []models.PaymentLineItem) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM payment_line_items WHERE payment_id = $1`, paymentID); err != nil {
+		return err
+	}
+	if err := r.insertLineItems(tx, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *receiptRepository) insertLineItems(tx *sqlx.Tx, items []models.PaymentLineItem) error {
+	query := `
+		INSERT INTO payment_line_items (payment_id, kind, description, quantity, unit_amount_sats, amount_sats, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	now := r.clock.Now()
+	for i := range items {
+		item := &items[i]
+		err := tx.QueryRowx(query,
+			item.PaymentID, item.Kind, item.Description, item.Quantity, item.UnitAmountSats, item.AmountSats, now).StructScan(item)
+		if err != nil {
+			return fmt.Errorf("failed to store payment line item %q: %w", item.Description, err)
+		}
+	}
+	return nil
+}
+
+func (r *receiptRepository) GetLineItems(paymentID int) ([]models.PaymentLineItem, error) {
+	return paymentLineItemTable.list(r.db, `WHERE payment_id = $1 ORDER BY id`, paymentID)
+}
+
+func (r *receiptRepository) Issue(paymentID int) (*models.Receipt, error) {
+	receipt, err := r.GetByPaymentID(paymentID)
+	if !errors.Is(err, ErrNotFound) {
+		return receipt, err
+	}
+
+	// The unique constraints settle races: a concurrent issue for the same
+	// payment wins and is returned, one for another payment fails this insert
+	query := `
+		INSERT INTO receipts (payment_id, receipt_number, issued_at)
+		SELECT $1, COALESCE(MAX(receipt_number), 0) + 1, $2 FROM receipts`
+	if _, err := r.db.Exec(query, paymentID, r.clock.Now()); err != nil {
+		if receipt, getErr := r.GetByPaymentID(paymentID); getErr == nil {
+			return receipt, nil
+		}
+		return nil, err
+	}
+	return r.GetByPaymentID(paymentID)
+}
+
+func (r *receiptRepository) GetByPaymentID(paymentID int) (*models.Receipt, error) {
+	return receiptTable.get(r.db, `WHERE payment_id = $1`, paymentID)
+}
+
+func (r *receiptRepository) GetSales(from, to time.Time) ([]models.Sale, error) {
+	return selectSales(r.db, `p.status = 'paid' AND p.paid_at >= $1 AND p.paid_at < $2 ORDER BY p.paid_at, p.id`, from, to)
+}
+
+// selectSales loads the payments matching where (which may end with ORDER
+// BY and LIMIT) as sales, with their event, receipt number and line items
+func selectSales(db *sqlx.DB, where string, args ...interface{}) ([]models.Sale, error) {
+	sales := []models.Sale{}
+	query := `
+		SELECT ` + paymentTable.selectAll("p") + `, t.event_id, e.title AS event_title, e.organizer_id, rc.receipt_number,
+		       o.affiliate_id, COALESCE(o.commission_basis_points, 0) AS commission_basis_points
+		FROM payments p
+		JOIN tickets t ON t.id = p.ticket_id
+		JOIN events e ON e.id = t.event_id
+		LEFT JOIN receipts rc ON rc.payment_id = p.id
+		LEFT JOIN orders o ON o.id = t.order_id
+		WHERE ` + where
+	if err := db.Select(&sales, query, args...); err != nil {
+		return nil, err
+	}
+	if len(sales) == 0 {
+		return sales, nil
+	}
+
+	placeholders := make([]string, len(sales))
+	ids := make([]interface{}, len(sales))
+	for i, sale := range sales {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		ids[i] = sale.ID
+	}
+	items, err := paymentLineItemTable.list(db, `WHERE payment_id IN (`+strings.Join(placeholders, ", ")+`) ORDER BY id`, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	byPayment := make(map[int][]models.PaymentLineItem)
+	for _, item := range items {
+		byPayment[item.PaymentID] = append(byPayment[item.PaymentID], item)
+	}
+	for i := range sales {
+		sales[i].LineItems = byPayment[sales[i].ID]
+	}
+	return sales, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var scannerDeviceTable = newTable[models.ScannerDevice]("scanner_devices")
+
+type scannerDeviceRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewScannerDeviceRepository creates the scanner device repository. clk
+// stamps devices and decides whether their pairing codes have expired.
+func NewScannerDeviceRepository(db *sqlx.DB, clk clock.Clock) ScannerDeviceRepository {
+	return &scannerDeviceRepository{db: db, clock: clk}
+}
+
+func (r *scannerDeviceRepository) Create(device *models.ScannerDevice) error {
+	query := `
+		INSERT INTO scanner_devices (event_id, name, pairing_hash, pairing_expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		` + scannerDeviceTable.returning()
+
+	err := r.db.QueryRowx(query,
+		device.EventID, device.Name, device.PairingHash, device.PairingExpiresAt, device.CreatedBy, r.clock.Now()).StructScan(device)
+	return translateError(err)
+}
+
+func (r *scannerDeviceRepository) GetByID(id int) (*models.ScannerDevice, error) {
+	return scannerDeviceTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *scannerDeviceRepository) GetByEventID(eventID int) ([]models.ScannerDevice, error) {
+	return scannerDeviceTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
+
+func (r *scannerDeviceRepository) Register(pairingHash, tokenHash string) (*models.ScannerDevice, error) {
+	// Clearing the pairing as it is matched makes it single-use
+	query := `
+		UPDATE scanner_devices
+		SET pairing_hash = NULL, pairing_expires_at = NULL, token_hash = $1, registered_at = $2, last_seen_at = $2
+		WHERE pairing_hash = $3 AND pairing_expires_at > $2 AND revoked_at IS NULL
+		` + scannerDeviceTable.returning()
+
+	device := &models.ScannerDevice{}
+	if err := r.db.QueryRowx(query, tokenHash, r.clock.Now(), pairingHash).StructScan(device); err != nil {
+		return nil, translateError(err)
+	}
+	return device, nil
+}
+
+func (r *scannerDeviceRepository) GetByTokenHash(tokenHash string) (*models.ScannerDevice, error) {
+	return scannerDeviceTable.get(r.db, `WHERE token_hash = $1 AND revoked_at IS NULL`, tokenHash)
+}
+
+func (r *scannerDeviceRepository) Touch(id int) error {
+	_, err := r.db.Exec(`UPDATE scanner_devices SET last_seen_at = $1 WHERE id = $2`, r.clock.Now(), id)
+	return err
+}
+
+func (r *scannerDeviceRepository) Revoke(id int) (*models.ScannerDevice, error) {
+	query := `
+		UPDATE scanner_devices
+		SET pairing_hash = NULL, pairing_expires_at = NULL, revoked_at = COALESCE(revoked_at, $1)
+		WHERE id = $2
+		` + scannerDeviceTable.returning()
+
+	device := &models.ScannerDevice{}
+	if err := r.db.QueryRowx(query, r.clock.Now(), id).StructScan(device); err != nil {
+		return nil, translateError(err)
+	}
+	return device, nil
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/models"
+)
+
+var settingTable = newTable[models.Setting]("settings")
+
+type settingsRepository struct {
+	db *sqlx.DB
+}
+
+func NewSettingsRepository(db *sqlx.DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
+
+func (r *settingsRepository) GetAll() ([]models.Setting, error) {
+	return settingTable.list(r.db, `ORDER BY key ASC`)
+}
+
+func (r *settingsRepository) Get(key string) (*models.Setting, error) {
+	return settingTable.get(r.db, `WHERE key = $1`, key)
+}
+
+func (r *settingsRepository) Upsert(setting *models.Setting) error {
+	query := `
+		INSERT INTO settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET value = $2, updated_by = $3, updated_at = $4`
+
+	setting.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, setting.Key, setting.Value, setting.UpdatedBy, setting.UpdatedAt)
+	return err
+}
+
+func (r *settingsRepository) Delete(key string) error {
+	query := `DELETE FROM settings WHERE key = $1`
+	_, err := r.db.Exec(query, key)
+	return err
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var shortLinkTable = newTable[models.ShortLink]("short_links")
+
+type shortLinkRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewShortLinkRepository creates the short link repository. clk stamps
+// links as they are created and clicked.
+func NewShortLinkRepository(db *sqlx.DB, clk clock.Clock) ShortLinkRepository {
+	return &shortLinkRepository{db: db, clock: clk}
+}
+
+func (r *shortLinkRepository) Create(link *models.ShortLink) error {
+	// A taken code, or a second generated link for the same event or
+	// ticket, inserts nothing
+	query := `
+		INSERT INTO short_links (code, event_id, ticket_id, custom, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
+		` + shortLinkTable.returning()
+
+	err := r.db.QueryRowx(query,
+		link.Code, link.EventID, link.TicketID, link.Custom, link.CreatedBy, r.clock.Now()).StructScan(link)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *shortLinkRepository) GetByID(id int) (*models.ShortLink, error) {
+	return shortLinkTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *shortLinkRepository) GetByCode(code string) (*models.ShortLink, error) {
+	return shortLinkTable.get(r.db, `WHERE code = $1`, code)
+}
+
+func (r *shortLinkRepository) GetGeneratedForEvent(eventID int) (*models.ShortLink, error) {
+	return shortLinkTable.get(r.db, `WHERE event_id = $1 AND custom = false`, eventID)
+}
+
+func (r *shortLinkRepository) GetGeneratedForTicket(ticketID int) (*models.ShortLink, error) {
+	return shortLinkTable.get(r.db, `WHERE ticket_id = $1 AND custom = false`, ticketID)
+}
+
+func (r *shortLinkRepository) GetByEventID(eventID int) ([]models.ShortLink, error) {
+	return shortLinkTable.list(r.db, `WHERE event_id = $1 ORDER BY id`, eventID)
+}
+
+func (r *shortLinkRepository) UpdateCode(id int, code string) (*models.ShortLink, error) {
+	query := `
+		UPDATE short_links SET code = $1, custom = true
+		WHERE id = $2 AND NOT EXISTS (SELECT 1 FROM short_links WHERE code = $1 AND id <> $2)
+		` + shortLinkTable.returning()
+
+	link := &models.ShortLink{}
+	err := translateError(r.db.QueryRowx(query, code, id).StructScan(link))
+	if !errors.Is(err, ErrNotFound) {
+		return link, err
+	}
+	if _, err := r.GetByID(id); err != nil {
+		return nil, err
+	}
+	return nil, ErrConflict
+}
+
+func (r *shortLinkRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM short_links WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *shortLinkRepository) Click(code string) (*models.ShortLink, error) {
+	query := `
+		UPDATE short_links SET clicks = clicks + 1, last_clicked_at = $1
+		WHERE code = $2
+		` + shortLinkTable.returning()
+
+	link := &models.ShortLink{}
+	if err := r.db.QueryRowx(query, r.clock.Now(), code).StructScan(link); err != nil {
+		return nil, translateError(err)
+	}
+	return link, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var statusIncidentTable = newTable[models.StatusIncident]("status_incidents")
+
+type statusIncidentRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewStatusIncidentRepository creates the status incident repository. clk
+// stamps created_at and updated_at.
+func NewStatusIncidentRepository(db *sqlx.DB, clk clock.Clock) StatusIncidentRepository {
+	return &statusIncidentRepository{db: db, clock: clk}
+}
+
+func (r *statusIncidentRepository) Create(incident *models.StatusIncident) error {
+	query := `
+		INSERT INTO status_incidents (title, message, status, impact, components, created_by, started_at, resolved_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowx(query, incident.Title, incident.Message, incident.Status, incident.Impact, incident.Components,
+		incident.CreatedBy, incident.StartedAt, incident.ResolvedAt, r.clock.Now()).StructScan(incident)
+}
+
+func (r *statusIncidentRepository) GetByID(id int) (*models.StatusIncident, error) {
+	return statusIncidentTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *statusIncidentRepository) List(limit, offset int) ([]models.StatusIncident, error) {
+	return statusIncidentTable.list(r.db, `ORDER BY started_at DESC, id DESC LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+func (r *statusIncidentRepository) ListRecent(resolvedSince time.Time) ([]models.StatusIncident, error) {
+	return statusIncidentTable.list(r.db, `WHERE resolved_at IS NULL OR resolved_at >= $1 ORDER BY started_at DESC, id DESC`, resolvedSince)
+}
+
+func (r *statusIncidentRepository) Update(incident *models.StatusIncident) error {
+	query := `
+		UPDATE status_incidents
+		SET title = $1, message = $2, status = $3, impact = $4, components = $5, resolved_at = $6, updated_at = $7
+		WHERE id = $8`
+
+	incident.UpdatedAt = r.clock.Now()
+	result, err := r.db.Exec(query, incident.Title, incident.Message, incident.Status, incident.Impact,
+		incident.Components, incident.ResolvedAt, incident.UpdatedAt, incident.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *statusIncidentRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM status_incidents WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// table is a model's table and the columns its rows are read from, taken
+// once from the model's db tags. Its queries name those columns rather
+// than SELECT *, so a column dropped or renamed under a model fails the
+// query instead of leaving a field silently zero, and a column added to
+// the table ahead of the model is not fetched and cannot break the scan.
+// TestTableColumns runs every table's select against the migrated schema.
+type table[T any] struct {
+	name    string
+	columns []string
+}
+
+// tables lists every table declared with newTable, for TestTableColumns
+var tables []interface {
+	selectAll(alias string) string
+	tableName() string
+}
+
+// newTable declares the table name whose rows scan into T. T must be a
+// struct whose db tags are all columns of the table.
+func newTable[T any](name string) table[T] {
+	t := table[T]{name: name, columns: structColumns(reflect.TypeFor[T]())}
+	tables = append(tables, t)
+	return t
+}
+
+func (t table[T]) tableName() string { return t.name }
+
+// selectAll returns the column list to select, each qualified by alias
+// unless it is empty, for queries that join the table
+func (t table[T]) selectAll(alias string) string {
+	if alias == "" {
+		return strings.Join(t.columns, ", ")
+	}
+	qualified := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		qualified[i] = alias + "." + column
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// returning is a RETURNING clause for every column
+func (t table[T]) returning() string {
+	return "RETURNING " + t.selectAll("")
+}
+
+// get reads the one row of the table rest selects, e.g. "WHERE id = $1",
+// returning ErrNotFound when there is none
+func (t table[T]) get(q sqlx.Queryer, rest string, args ...interface{}) (*T, error) {
+	row := new(T)
+	if err := sqlx.Get(q, row, t.query(rest), args...); err != nil {
+		return nil, translateError(err)
+	}
+	return row, nil
+}
+
+// list reads every row of the table rest selects, which may also order
+// and page them; none is an empty slice
+func (t table[T]) list(q sqlx.Queryer, rest string, args ...interface{}) ([]T, error) {
+	rows := []T{}
+	err := sqlx.Select(q, &rows, t.query(rest), args...)
+	return rows, err
+}
+
+func (t table[T]) query(rest string) string {
+	query := "SELECT " + t.selectAll("") + " FROM " + t.name
+	if rest != "" {
+		query += " " + rest
+	}
+	return query
+}
+
+// byIDs reads the rows with the given ids, a batch at a time
+func (t table[T]) byIDs(db *sqlx.DB, ids []int) ([]T, error) {
+	return selectByIDs[T](db, t.query("WHERE id IN (%s)"), ids)
+}
+
+// structColumns returns the db-tagged fields of a struct, including those
+// of embedded structs, which sqlx flattens
+func structColumns(t reflect.Type) []string {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, structColumns(field.Type)...)
+			continue
+		}
+		if tag == "" || tag == "-" {
+			continue
+		}
+		columns = append(columns, tag)
+	}
+	return columns
+}
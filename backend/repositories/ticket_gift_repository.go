@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var ticketGiftTable = newTable[models.TicketGift]("ticket_gifts")
+
+type ticketGiftRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewTicketGiftRepository creates the ticket gift repository. clk stamps
+// gifts as they are created, delivered and claimed.
+func NewTicketGiftRepository(db *sqlx.DB, clk clock.Clock) TicketGiftRepository {
+	return &ticketGiftRepository{db: db, clock: clk}
+}
+
+func (r *ticketGiftRepository) Create(gift *models.TicketGift) error {
+	// A ticket that already is a gift inserts nothing
+	query := `
+		INSERT INTO ticket_gifts (ticket_id, sender_id, recipient_email, recipient_uma_address, message, deliver_at, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+		` + ticketGiftTable.returning()
+
+	err := r.db.QueryRowx(query, gift.TicketID, gift.SenderID, gift.RecipientEmail, gift.RecipientUMAAddress,
+		gift.Message, gift.DeliverAt, models.GiftScheduled, r.clock.Now()).StructScan(gift)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return translateError(err)
+}
+
+func (r *ticketGiftRepository) GetByTicketID(ticketID int) (*models.TicketGift, error) {
+	return ticketGiftTable.get(r.db, `WHERE ticket_id = $1`, ticketID)
+}
+
+func (r *ticketGiftRepository) GetBySecret(secretHash string) (*models.TicketGift, error) {
+	return ticketGiftTable.get(r.db, `WHERE secret_hash = $1`, secretHash)
+}
+
+func (r *ticketGiftRepository) GetBySenderID(senderID int) ([]models.TicketGift, error) {
+	return ticketGiftTable.list(r.db, `WHERE sender_id = $1 ORDER BY created_at DESC, id DESC`, senderID)
+}
+
+func (r *ticketGiftRepository) ListDue(now time.Time, limit int) ([]models.TicketGift, error) {
+	query := `
+		SELECT ` + ticketGiftTable.selectAll("g") + ` FROM ticket_gifts g
+		JOIN tickets t ON t.id = g.ticket_id
+		WHERE g.status = $1 AND g.deliver_at <= $2 AND t.payment_status = 'paid'
+		ORDER BY g.deliver_at, g.id
+		LIMIT $3`
+
+	gifts := []models.TicketGift{}
+	err := r.db.Select(&gifts, query, models.GiftScheduled, now, limit)
+	return gifts, err
+}
+
+func (r *ticketGiftRepository) MarkDelivered(id int, secretHash string) error {
+	result, err := r.db.Exec(`
+		UPDATE ticket_gifts SET status = $1, secret_hash = $2, delivered_at = $3
+		WHERE id = $4 AND status = $5`,
+		models.GiftDelivered, secretHash, r.clock.Now(), id, models.GiftScheduled)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *ticketGiftRepository) Claim(secretHash string, recipientID int) (*models.TicketGift, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Matching only delivered gifts makes the link single-use
+	now := r.clock.Now()
+	gift := &models.TicketGift{}
+	err = tx.QueryRowx(`
+		UPDATE ticket_gifts SET status = $1, recipient_id = $2, claimed_at = $3
+		WHERE secret_hash = $4 AND status = $5
+		`+ticketGiftTable.returning(),
+		models.GiftClaimed, recipientID, now, secretHash, models.GiftDelivered).StructScan(gift)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE tickets SET user_id = $1, updated_at = $2 WHERE id = $3`, recipientID, now, gift.TicketID); err != nil {
+		return nil, err
+	}
+	return gift, tx.Commit()
+}
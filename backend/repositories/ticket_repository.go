@@ -2,98 +2,203 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"tickets-by-uma/clock"
+	"tickets-by-uma/encryption"
+	"tickets-by-uma/listquery"
 	"tickets-by-uma/models"
+	"tickets-by-uma/ticketcode"
 )
 
+// TicketListFields are the fields ticket lists can be sorted and filtered
+// by. UMA addresses are encrypted at rest and cannot be.
+var TicketListFields = listquery.Fields{
+	"id":             {Column: "id", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"event_id":       {Column: "event_id", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"ticket_code":    {Column: "ticket_code", Kind: listquery.Text, Filterable: true},
+	"payment_status": {Column: "payment_status", Kind: listquery.Text, Sortable: true, Filterable: true},
+	"amount_sats":    {Column: "amount_sats", Kind: listquery.Int, Sortable: true, Filterable: true},
+	"is_comp":        {Column: "is_comp", Kind: listquery.Bool, Filterable: true},
+	"paid_at":        {Column: "paid_at", Kind: listquery.Time, Filterable: true},
+	"created_at":     {Column: "created_at", Kind: listquery.Time, Sortable: true, Filterable: true},
+}
+
+var ticketTable = newTable[models.Ticket]("tickets")
+
 type ticketRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	cipher fieldCipher
+	clock  clock.Clock
 }
 
-func NewTicketRepository(db *sqlx.DB) TicketRepository {
-	return &ticketRepository{db: db}
+// NewTicketRepository creates the ticket repository. UMA addresses are
+// encrypted at rest when piiKeyring is set; clk stamps paid_at and
+// created/updated times.
+func NewTicketRepository(db *sqlx.DB, piiKeyring *encryption.Keyring, clk clock.Clock) TicketRepository {
+	return &ticketRepository{db: db, cipher: fieldCipher{keyring: piiKeyring}, clock: clk}
 }
 
+// Create inserts the ticket. A ticket marked OnePerUser is refused with
+// ErrConflict while the buyer holds another ticket for the event; the
+// partial unique index settles races.
 func (r *ticketRepository) Create(ticket *models.Ticket) error {
 	query := `
-		INSERT INTO tickets (event_id, user_id, ticket_code, payment_status, invoice_id, uma_address, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO tickets (event_id, user_id, ticket_code, payment_status, invoice_id, uma_address, amount_sats, order_id, is_comp, price_change_id, one_per_user, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		WHERE NOT ($11 AND EXISTS (
+			SELECT 1 FROM tickets
+			WHERE event_id = $1 AND user_id = $2 AND payment_status IN ('paid', 'pending', 'review', 'disputed')))
 		RETURNING id, created_at, updated_at`
 
-	now := time.Now()
-	return r.db.QueryRowx(query,
+	if err := checkTicketStatus(ticket.PaymentStatus); err != nil {
+		return err
+	}
+	umaAddress, err := r.cipher.seal(ticket.UMAAddress)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if ticket.NeedsSeat {
+		// Writing the event row locks it until the ticket is stored, so
+		// concurrent purchases count each other's tickets
+		if _, err := tx.Exec(`UPDATE events SET capacity = capacity WHERE id = $1`, ticket.EventID); err != nil {
+			return err
+		}
+		var available int
+		if err := tx.Get(&available, availableSeats, ticket.EventID); err != nil {
+			return translateError(err)
+		}
+		if available <= 0 {
+			return ErrSoldOut
+		}
+	}
+
+	now := r.clock.Now()
+	err = tx.QueryRowx(query,
 		ticket.EventID, ticket.UserID, ticket.TicketCode, ticket.PaymentStatus,
-		ticket.InvoiceID, ticket.UMAAddress, now, now).StructScan(ticket)
+		ticket.InvoiceID, umaAddress, ticket.AmountSats, ticket.OrderID, ticket.IsComp, ticket.PriceChangeID,
+		ticket.OnePerUser, now, now).StructScan(ticket)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	if err != nil {
+		return translateError(err)
+	}
+	return tx.Commit()
 }
 
 func (r *ticketRepository) GetByID(id int) (*models.Ticket, error) {
-	ticket := &models.Ticket{}
-	query := `SELECT * FROM tickets WHERE id = $1`
-	err := r.db.Get(ticket, query, id)
+	ticket, err := ticketTable.get(r.db, `WHERE id = $1`, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return ticket, nil
+	return ticket, r.openTicket(ticket)
 }
 
+func (r *ticketRepository) GetByIDs(ids []int) (map[int]*models.Ticket, error) {
+	tickets, err := ticketTable.byIDs(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openTickets(tickets); err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.Ticket, len(tickets))
+	for i := range tickets {
+		byID[tickets[i].ID] = &tickets[i]
+	}
+	return byID, nil
+}
+
+// GetByTicketCode looks a ticket up by its code. Codes name their event,
+// the key tickets are partitioned by, so only that event's partition is
+// searched; legacy codes search them all.
 func (r *ticketRepository) GetByTicketCode(ticketCode string) (*models.Ticket, error) {
-	ticket := &models.Ticket{}
-	query := `SELECT * FROM tickets WHERE ticket_code = $1`
-	err := r.db.Get(ticket, query, ticketCode)
+	where := `WHERE ticket_code = $1`
+	args := []interface{}{ticketCode}
+	if code, err := ticketcode.Parse(ticketCode); err == nil && !code.Legacy {
+		where += ` AND event_id = $2`
+		args = append(args, code.EventID)
+	}
+	ticket, err := ticketTable.get(r.db, where, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return ticket, nil
+	return ticket, r.openTicket(ticket)
 }
 
 func (r *ticketRepository) GetByEventID(eventID int) ([]models.Ticket, error) {
-	tickets := []models.Ticket{}
-	query := `SELECT * FROM tickets WHERE event_id = $1 ORDER BY created_at DESC`
-	err := r.db.Select(&tickets, query, eventID)
-	return tickets, err
+	tickets, err := ticketTable.list(r.db, `WHERE event_id = $1 ORDER BY created_at DESC`, eventID)
+	if err != nil {
+		return tickets, err
+	}
+	return tickets, r.openTickets(tickets)
 }
 
 func (r *ticketRepository) GetByUserID(userID int) ([]models.Ticket, error) {
-	tickets := []models.Ticket{}
-	query := `SELECT * FROM tickets WHERE user_id = $1 ORDER BY created_at DESC`
-	err := r.db.Select(&tickets, query, userID)
-	return tickets, err
+	tickets, err := ticketTable.list(r.db, `WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return tickets, err
+	}
+	return tickets, r.openTickets(tickets)
+}
+
+func (r *ticketRepository) ListByUser(userID int, list listquery.Query) ([]models.Ticket, error) {
+	where, args := list.Where(TicketListFields, []interface{}{userID})
+	if where != "" {
+		where = " AND " + where
+	}
+	page, args := list.Page(args)
+	tickets, err := ticketTable.list(r.db, `WHERE user_id = $1`+where+
+		` ORDER BY `+list.OrderBy(TicketListFields, listquery.Order{Field: "created_at", Desc: true})+page, args...)
+	if err != nil {
+		return tickets, err
+	}
+	return tickets, r.openTickets(tickets)
 }
 
 func (r *ticketRepository) GetByInvoiceID(invoiceID string) (*models.Ticket, error) {
-	ticket := &models.Ticket{}
-	query := `SELECT * FROM tickets WHERE invoice_id = $1`
-	err := r.db.Get(ticket, query, invoiceID)
+	ticket, err := ticketTable.get(r.db, `WHERE invoice_id = $1`, invoiceID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return ticket, nil
+	return ticket, r.openTicket(ticket)
 }
 
+// Update saves the ticket. Its event never changes and, as the partition
+// key, narrows the update to one partition.
 func (r *ticketRepository) Update(ticket *models.Ticket) error {
 	query := `
 		UPDATE tickets 
-		SET event_id = $1, user_id = $2, ticket_code = $3, payment_status = $4, 
-		    invoice_id = $5, uma_address = $6, paid_at = $7, updated_at = $8
-		WHERE id = $9`
+		SET user_id = $1, ticket_code = $2, payment_status = $3, 
+		    invoice_id = $4, uma_address = $5, paid_at = $6, updated_at = $7
+		WHERE id = $8 AND event_id = $9`
 
-	ticket.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query,
-		ticket.EventID, ticket.UserID, ticket.TicketCode, ticket.PaymentStatus,
-		ticket.InvoiceID, ticket.UMAAddress, ticket.PaidAt, ticket.UpdatedAt, ticket.ID)
-	return err
+	if err := checkTicketStatus(ticket.PaymentStatus); err != nil {
+		return err
+	}
+	umaAddress, err := r.cipher.seal(ticket.UMAAddress)
+	if err != nil {
+		return err
+	}
+
+	ticket.UpdatedAt = r.clock.Now()
+	_, err = r.db.Exec(query,
+		ticket.UserID, ticket.TicketCode, ticket.PaymentStatus,
+		ticket.InvoiceID, umaAddress, ticket.PaidAt, ticket.UpdatedAt, ticket.ID, ticket.EventID)
+	return translateError(err)
 }
 
 func (r *ticketRepository) UpdatePaymentStatus(id int, status string) error {
@@ -102,21 +207,50 @@ func (r *ticketRepository) UpdatePaymentStatus(id int, status string) error {
 		SET payment_status = $1, updated_at = $2, paid_at = $3
 		WHERE id = $4`
 
-	now := time.Now()
+	if err := checkTicketStatus(status); err != nil {
+		return err
+	}
+	now := r.clock.Now()
 	var paidAt *time.Time
 	if status == "paid" {
 		paidAt = &now
+		query += ` AND payment_status IN ('pending', 'expired', 'review')`
+	}
+
+	result, err := r.db.Exec(query, status, now, paidAt, id)
+	if err != nil || status != "paid" {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
 	}
+	var current string
+	err = r.db.Get(&current, `SELECT payment_status FROM tickets WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) || current == "paid" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (r *ticketRepository) Reprice(id int, amountSats int64, priceChangeID *int) error {
+	query := `
+		UPDATE tickets
+		SET amount_sats = $1, price_change_id = $2, updated_at = $3
+		WHERE id = $4`
 
-	_, err := r.db.Exec(query, status, now, paidAt, id)
+	_, err := r.db.Exec(query, amountSats, priceChangeID, r.clock.Now(), id)
 	return err
 }
 
 func (r *ticketRepository) GetPendingTickets() ([]models.Ticket, error) {
-	tickets := []models.Ticket{}
-	query := `SELECT * FROM tickets WHERE payment_status = 'pending' ORDER BY created_at ASC`
-	err := r.db.Select(&tickets, query)
-	return tickets, err
+	tickets, err := ticketTable.list(r.db, `WHERE payment_status = 'pending' ORDER BY created_at ASC`)
+	if err != nil {
+		return tickets, err
+	}
+	return tickets, r.openTickets(tickets)
 }
 
 func (r *ticketRepository) CountByEventAndStatus(eventID int, status string) (int, error) {
@@ -136,3 +270,44 @@ func (r *ticketRepository) HasUserTicketForEvent(userID, eventID int) (bool, err
 	}
 	return count > 0, nil
 }
+
+// HasActiveTicketForEvent checks if a user holds a ticket for an event
+// that is not failed, expired or cancelled
+func (r *ticketRepository) HasActiveTicketForEvent(userID, eventID int) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM tickets
+		WHERE user_id = $1 AND event_id = $2 AND payment_status IN ('paid', 'pending', 'review', 'disputed')`
+	err := r.db.Get(&count, query, userID, eventID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// checkTicketStatus refuses a status the database would, naming it
+func checkTicketStatus(status string) error {
+	if !slices.Contains(models.TicketStatuses, status) {
+		return fmt.Errorf("%w: ticket status %q", ErrInvalidStatus, status)
+	}
+	return nil
+}
+
+// openTicket decrypts the ticket's encrypted columns in place
+func (r *ticketRepository) openTicket(ticket *models.Ticket) error {
+	umaAddress, err := r.cipher.open(ticket.UMAAddress)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt UMA address for ticket %d: %w", ticket.ID, err)
+	}
+	ticket.UMAAddress = umaAddress
+	return nil
+}
+
+func (r *ticketRepository) openTickets(tickets []models.Ticket) error {
+	for i := range tickets {
+		if err := r.openTicket(&tickets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var ticketScanTable = newTable[models.TicketScan]("ticket_scans")
+
+type ticketScanRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewTicketScanRepository creates the ticket scan repository. clk stamps
+// scanned_at.
+func NewTicketScanRepository(db *sqlx.DB, clk clock.Clock) TicketScanRepository {
+	return &ticketScanRepository{db: db, clock: clk}
+}
+
+func (r *ticketScanRepository) Create(scan *models.TicketScan) error {
+	query := `
+		INSERT INTO ticket_scans (event_id, ticket_id, ticket_code, admitted, reason, scanner_device_id, scanned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		` + ticketScanTable.returning()
+
+	return r.db.QueryRowx(query,
+		scan.EventID, scan.TicketID, scan.TicketCode, scan.Admitted, scan.Reason, scan.ScannerDeviceID, r.clock.Now()).StructScan(scan)
+}
+
+func (r *ticketScanRepository) ListAdmitted(eventID int) ([]models.TicketScan, error) {
+	return ticketScanTable.list(r.db, `WHERE event_id = $1 AND admitted ORDER BY scanned_at, id`, eventID)
+}
+
+func (r *ticketScanRepository) ListRecent(eventID, limit int) ([]models.TicketScan, error) {
+	return ticketScanTable.list(r.db, `WHERE event_id = $1 ORDER BY scanned_at DESC, id DESC LIMIT $2`, eventID, limit)
+}
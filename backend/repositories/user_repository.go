@@ -1,14 +1,16 @@
 package repositories
 
 import (
-	"database/sql"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"tickets-by-uma/i18n"
 	"tickets-by-uma/models"
 )
 
+var userTable = newTable[models.User]("users")
+
 type userRepository struct {
 	db *sqlx.DB
 }
@@ -19,49 +21,62 @@ func NewUserRepository(db *sqlx.DB) UserRepository {
 
 func (r *userRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (email, name, password_hash, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (email, name, password_hash, locale, is_guest, lnurl_auth_key, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at`
 
+	if user.Locale == "" {
+		user.Locale = i18n.Default
+	}
 	now := time.Now()
-	return r.db.QueryRowx(query, user.Email, user.Name, user.PasswordHash, now, now).StructScan(user)
+	err := r.db.QueryRowx(query, user.Email, user.Name, user.PasswordHash, user.Locale, user.IsGuest, user.LNURLAuthKey, now, now).StructScan(user)
+	return translateError(err)
 }
 
 func (r *userRepository) GetByID(id int) (*models.User, error) {
-	user := &models.User{}
-	query := `SELECT * FROM users WHERE id = $1`
-	err := r.db.Get(user, query, id)
+	return userTable.get(r.db, `WHERE id = $1`, id)
+}
+
+func (r *userRepository) GetByIDs(ids []int) (map[int]*models.User, error) {
+	users, err := userTable.byIDs(r.db, ids)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, err // Return the sql.ErrNoRows error instead of nil
-		}
 		return nil, err
 	}
-	return user, nil
+	byID := make(map[int]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
 }
 
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
-	user := &models.User{}
-	query := `SELECT * FROM users WHERE email = $1`
-	err := r.db.Get(user, query, email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return user, nil
+	return userTable.get(r.db, `WHERE email = $1`, email)
 }
 
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET email = $1, name = $2, password_hash = $3, updated_at = $4
-		WHERE id = $5`
+		SET email = $1, name = $2, password_hash = $3, locale = $4, updated_at = $5
+		WHERE id = $6`
 
 	user.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query, user.Email, user.Name, user.PasswordHash, user.UpdatedAt, user.ID)
-	return err
+	_, err := r.db.Exec(query, user.Email, user.Name, user.PasswordHash, user.Locale, user.UpdatedAt, user.ID)
+	return translateError(err)
+}
+
+func (r *userRepository) ChangePassword(id int, passwordHash string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		UPDATE users
+		SET password_hash = $1, session_version = session_version + 1, updated_at = $2
+		WHERE id = $3
+		` + userTable.returning()
+
+	err := r.db.QueryRowx(query, passwordHash, time.Now(), id).StructScan(user)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return user, nil
 }
 
 func (r *userRepository) Delete(id int) error {
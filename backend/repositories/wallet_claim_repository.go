@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var walletClaimTable = newTable[models.WalletClaim]("wallet_claims")
+
+type walletClaimRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewWalletClaimRepository creates the wallet claim repository. clk stamps
+// claims and decides whether offers have expired.
+func NewWalletClaimRepository(db *sqlx.DB, clk clock.Clock) WalletClaimRepository {
+	return &walletClaimRepository{db: db, clock: clk}
+}
+
+func (r *walletClaimRepository) GetByTicketID(ticketID int) (*models.WalletClaim, error) {
+	return walletClaimTable.get(r.db, `WHERE ticket_id = $1`, ticketID)
+}
+
+func (r *walletClaimRepository) Offer(ticketID int, secretHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO wallet_claims (ticket_id, secret_hash, secret_expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (ticket_id) DO UPDATE
+		SET secret_hash = $2, secret_expires_at = $3, updated_at = $4`
+
+	_, err := r.db.Exec(query, ticketID, secretHash, expiresAt, r.clock.Now())
+	return err
+}
+
+func (r *walletClaimRepository) Claim(ticketID int, secretHash, devicePublicKey string) (*models.WalletClaim, error) {
+	// Matching and consuming the offer in one statement makes it single-use
+	query := `
+		UPDATE wallet_claims
+		SET device_public_key = $1, claimed_at = $2, updated_at = $2, secret_hash = NULL, secret_expires_at = NULL
+		WHERE ticket_id = $3 AND secret_hash = $4 AND secret_expires_at > $2
+		` + walletClaimTable.returning()
+
+	claim := &models.WalletClaim{}
+	err := r.db.QueryRowx(query, devicePublicKey, r.clock.Now(), ticketID, secretHash).StructScan(claim)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var webhookEventTable = newTable[models.WebhookEvent]("webhook_events")
+
+type webhookEventRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewWebhookEventRepository creates the webhook queue repository. clk
+// decides which events are due and stamps receipt and processing times.
+func NewWebhookEventRepository(db *sqlx.DB, clk clock.Clock) WebhookEventRepository {
+	return &webhookEventRepository{db: db, clock: clk}
+}
+
+func (r *webhookEventRepository) Enqueue(event *models.WebhookEvent) error {
+	// Checked here so a redelivery is ErrConflict rather than a unique
+	// index violation; the index settles races
+	query := `
+		INSERT INTO webhook_events (source, event_id, event_type, entity_id, payload, status, attempts, last_error, received_at, next_attempt_at)
+		SELECT $1, $2, $3, $4, $5, 'pending', 0, '', $6, $6
+		WHERE NOT EXISTS (SELECT 1 FROM webhook_events WHERE source = $1 AND event_id = $2)
+		` + webhookEventTable.returning()
+
+	err := r.db.QueryRowx(query, event.Source, event.EventID, event.EventType, event.EntityID, event.Payload, r.clock.Now()).StructScan(event)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *webhookEventRepository) Claim(limit int, lease time.Duration) ([]models.WebhookEvent, error) {
+	now := r.clock.Now()
+
+	ids := []int{}
+	query := `
+		SELECT id FROM webhook_events
+		WHERE status = 'pending' AND next_attempt_at <= $1 AND (locked_until IS NULL OR locked_until <= $1)
+		ORDER BY next_attempt_at, id
+		LIMIT $2`
+	if err := r.db.Select(&ids, query, now, limit); err != nil {
+		return nil, err
+	}
+
+	events := []models.WebhookEvent{}
+	for _, id := range ids {
+		// Another worker may have claimed the event since it was selected
+		query := `
+			UPDATE webhook_events SET locked_until = $1, attempts = attempts + 1
+			WHERE id = $2 AND status = 'pending' AND (locked_until IS NULL OR locked_until <= $3)
+			` + webhookEventTable.returning()
+		var event models.WebhookEvent
+		err := r.db.QueryRowx(query, now.Add(lease), id, now).StructScan(&event)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *webhookEventRepository) Complete(id int) error {
+	query := `UPDATE webhook_events SET status = 'done', locked_until = NULL, last_error = '', processed_at = $1 WHERE id = $2`
+	_, err := r.db.Exec(query, r.clock.Now(), id)
+	return err
+}
+
+func (r *webhookEventRepository) Fail(id int, lastError string, retryAt *time.Time) error {
+	if retryAt == nil {
+		query := `UPDATE webhook_events SET status = 'failed', locked_until = NULL, last_error = $1, processed_at = $2 WHERE id = $3`
+		_, err := r.db.Exec(query, lastError, r.clock.Now(), id)
+		return err
+	}
+	query := `UPDATE webhook_events SET locked_until = NULL, last_error = $1, next_attempt_at = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, lastError, *retryAt, id)
+	return err
+}
+
+func (r *webhookEventRepository) Backlog() (*models.WebhookBacklog, error) {
+	backlog := &models.WebhookBacklog{}
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) AS pending,
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) AS failed
+		FROM webhook_events`
+	if err := r.db.Get(backlog, query); err != nil {
+		return nil, err
+	}
+
+	// Selected as a column rather than MIN() so SQLite returns a timestamp
+	var oldest time.Time
+	query = `SELECT received_at FROM webhook_events WHERE status = 'pending' ORDER BY received_at LIMIT 1`
+	err := r.db.Get(&oldest, query)
+	if err == nil {
+		backlog.OldestPendingAt = &oldest
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return backlog, nil
+}
+
+func (r *webhookEventRepository) ListFailed() ([]models.WebhookEvent, error) {
+	return webhookEventTable.list(r.db, `WHERE status = 'failed' ORDER BY processed_at DESC, id DESC`)
+}
+
+func (r *webhookEventRepository) Requeue(id int) error {
+	query := `
+		UPDATE webhook_events SET status = 'pending', attempts = 0, next_attempt_at = $1, locked_until = NULL, processed_at = NULL
+		WHERE id = $2 AND status = 'failed'`
+	result, err := r.db.Exec(query, r.clock.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookEventRepository) DeleteFailed(id int) error {
+	result, err := r.db.Exec(`DELETE FROM webhook_events WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"tickets-by-uma/config"
+	"tickets-by-uma/database"
+)
+
+// runRestore replaces the configured database with the backup in the file
+// args names ("-" reads stdin) and returns the exit code. Stop the server
+// first, and apply migrations afterwards if the backup predates them.
+func runRestore(args []string, logger *slog.Logger) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: main restore <backup file, or - for stdin>")
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Error("Invalid configuration", "error", err)
+		return 1
+	}
+	if cfg.UsesMemoryStorage() {
+		logger.Error("Memory storage has no database to restore")
+		return 1
+	}
+
+	var backup io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			logger.Error("Failed to open backup", "error", err)
+			return 1
+		}
+		defer file.Close()
+		backup = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger.Info("Restoring database", "file", args[0], "storage", cfg.Storage, "database_url", config.MaskDatabaseURL(cfg.DatabaseURL))
+	if err := database.Restore(ctx, cfg, backup); err != nil {
+		logger.Error("Failed to restore database", "error", err)
+		return 1
+	}
+	logger.Info("Database restored; run migrations if the backup is older than this build", "schema_version", database.SchemaVersion)
+	return 0
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/lightsparkdev/go-sdk/services"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/database"
+	uma_services "tickets-by-uma/services"
+)
+
+// ServerOption configures optional Server dependencies. Anything not
+// provided is built from the config with production defaults.
+type ServerOption func(*Server)
+
+// WithUMAService sets the UMA payment backend, e.g. a mock in tests
+func WithUMAService(umaService uma_services.UMAService) ServerOption {
+	return func(s *Server) {
+		s.umaService = umaService
+	}
+}
+
+// WithLightsparkClient sets the Lightspark API client used for webhook
+// entity lookups
+func WithLightsparkClient(client *services.LightsparkClient) ServerOption {
+	return func(s *Server) {
+		s.lightsparkClient = client
+	}
+}
+
+// WithHTTPClient sets the client used for outbound calls to Lightspark,
+// buyer VASPs and captcha providers
+func WithHTTPClient(client *http.Client) ServerOption {
+	return func(s *Server) {
+		s.httpClient = client
+	}
+}
+
+// WithClock sets the time source, e.g. a fake clock in tests
+func WithClock(c clock.Clock) ServerOption {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// WithLogger sets the logger; slog.Default() is used otherwise
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithReadOnly answers every write with 503, e.g. when the database schema
+// does not match this build
+func WithReadOnly() ServerOption {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// WithQueryStats reports the per-query timings recorded by the database
+// connection under "database" in the metrics
+func WithQueryStats(stats *database.QueryStats) ServerOption {
+	return func(s *Server) {
+		s.queryStats = stats
+	}
+}
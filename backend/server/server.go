@@ -1,6 +1,9 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"io"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,80 +14,636 @@ import (
 	"github.com/lightsparkdev/go-sdk/services"
 
 	"tickets-by-uma/apphandlers"
+	"tickets-by-uma/cashu"
+	"tickets-by-uma/clock"
 	"tickets-by-uma/config"
+	"tickets-by-uma/database"
+	"tickets-by-uma/encryption"
+	"tickets-by-uma/httpclient"
+	"tickets-by-uma/jwtkeys"
+	"tickets-by-uma/metrics"
 	"tickets-by-uma/middleware"
+	"tickets-by-uma/objectstore"
+	"tickets-by-uma/pubsub"
 	"tickets-by-uma/repositories"
 	uma_services "tickets-by-uma/services"
+	"tickets-by-uma/ticketsig"
+)
+
+// settingsRefreshInterval is how often runtime settings are reloaded from the database
+const settingsRefreshInterval = 30 * time.Second
+
+// ledgerCheckInterval is how often new sales are booked in the ledger and its
+// invariants checked
+const ledgerCheckInterval = time.Minute
+
+// cancellationInterval is how often cancelled events' tickets are worked off
+const cancellationInterval = 10 * time.Second
+
+// giftDeliveryInterval is how often gifts due for delivery are sent
+const giftDeliveryInterval = time.Minute
+
+// reservationInterval is how often the seats of tickets whose invoice
+// expired unpaid are released
+const reservationInterval = 15 * time.Second
+
+// archiveHour is the hour of the night (UTC) old rows are archived
+const archiveHour = 3
+
+// partitionCheckInterval is how often the coming months' payments
+// partitions are created
+const partitionCheckInterval = 24 * time.Hour
+
+// debugCaptureSize is how many failed requests debug capture keeps, and
+// debugCaptureMaxBody how much of each request and response body
+const (
+	debugCaptureSize    = 200
+	debugCaptureMaxBody = 16 << 10
 )
 
 type Server struct {
-	db              *sqlx.DB
-	logger          *slog.Logger
-	config          *config.Config
-	userRepo        repositories.UserRepository
-	eventRepo       repositories.EventRepository
-	ticketRepo      repositories.TicketRepository
-	paymentRepo     repositories.PaymentRepository
-	umaRepo         repositories.UMARequestInvoiceRepository
-	nwcRepo         repositories.NWCConnectionRepository
-	umaService      uma_services.UMAService
-	lightsparkClient *services.LightsparkClient
-	router          *mux.Router
-	userHandlers    *apphandlers.UserHandlers
-	eventHandlers   *apphandlers.EventHandlers
-	ticketHandlers  *apphandlers.TicketHandlers
-	paymentHandlers *apphandlers.PaymentHandlers
-	umaHandlers     *apphandlers.UmaHandlers
+	db                 *sqlx.DB
+	logger             *slog.Logger
+	config             *config.Config
+	clock              clock.Clock
+	userRepo           repositories.UserRepository
+	eventRepo          repositories.EventRepository
+	ticketRepo         repositories.TicketRepository
+	paymentRepo        repositories.PaymentRepository
+	umaRepo            repositories.UMARequestInvoiceRepository
+	nwcRepo            repositories.NWCConnectionRepository
+	settingsRepo       repositories.SettingsRepository
+	fraudRepo          repositories.FraudFlagRepository
+	addOnRepo          repositories.AddOnRepository
+	formFieldRepo      repositories.FormFieldRepository
+	accessRepo         repositories.EventAccessRepository
+	attestRepo         repositories.AttestationRepository
+	translationRepo    repositories.EventTranslationRepository
+	rescheduleRepo     repositories.EventRescheduleRepository
+	cancelRepo         repositories.EventCancellationRepository
+	scanRepo           repositories.TicketScanRepository
+	scannerRepo        repositories.ScannerDeviceRepository
+	holdRepo           repositories.InventoryHoldRepository
+	relationRepo       repositories.EventRelationRepository
+	profileRepo        repositories.OrganizerProfileRepository
+	linkRepo           repositories.ShortLinkRepository
+	affiliateRepo      repositories.AffiliateRepository
+	giftRepo           repositories.TicketGiftRepository
+	pricingRuleRepo    repositories.PricingRuleRepository
+	exchangeRateRepo   repositories.ExchangeRateRepository
+	cashuRepo          repositories.CashuRedemptionRepository
+	lnurlAuthRepo      repositories.LNURLAuthRepository
+	templateRepo       repositories.NotificationTemplateRepository
+	receiptRepo        repositories.ReceiptRepository
+	orderRepo          repositories.OrderRepository
+	feeRepo            repositories.FeeRepository
+	ledgerRepo         repositories.LedgerRepository
+	disputeRepo        repositories.DisputeRepository
+	webhookRepo        repositories.WebhookEventRepository
+	walletClaimRepo    repositories.WalletClaimRepository
+	accountClaimRepo   repositories.AccountClaimRepository
+	emailChangeRepo    repositories.EmailChangeRepository
+	archiveRepo        repositories.ArchiveRepository
+	consoleRepo        repositories.ConsoleRepository
+	alertRepo          repositories.AdminNotificationRepository
+	connectorRepo      repositories.AlertConnectorRepository
+	incidentRepo       repositories.StatusIncidentRepository
+	umaService         uma_services.UMAService
+	settingsService    *uma_services.SettingsService
+	ledgerService      *uma_services.LedgerService
+	cancellations      *uma_services.CancellationService
+	gifts              *uma_services.GiftService
+	reservations       *uma_services.ReservationService
+	retention          *uma_services.RetentionService
+	exchangeRates      *uma_services.ExchangeRateService
+	checkIns           *uma_services.CheckInService
+	adminAlerts        *uma_services.AdminNotificationService
+	alertConnectors    *uma_services.AlertService
+	scanners           *uma_services.ScannerService
+	ticketWatcher      *uma_services.TicketWatcher
+	changeBus          *pubsub.Postgres
+	webhookQueue       *uma_services.WebhookQueue
+	metrics            *metrics.Registry
+	queryStats         *database.QueryStats
+	breaker            *uma_services.CircuitBreaker
+	ticketSigner       *ticketsig.Keyring
+	tokens             *middleware.Tokens
+	lightsparkClient   *services.LightsparkClient
+	httpClient         *http.Client
+	router             *mux.Router
+	userHandlers       *apphandlers.UserHandlers
+	eventHandlers      *apphandlers.EventHandlers
+	ticketHandlers     *apphandlers.TicketHandlers
+	ticketQRHandlers   *apphandlers.TicketQRHandlers
+	walletHandlers     *apphandlers.WalletHandlers
+	paymentHandlers    *apphandlers.PaymentHandlers
+	umaHandlers        *apphandlers.UmaHandlers
+	settingsHandlers   *apphandlers.SettingsHandlers
+	debugHandlers      *apphandlers.DebugHandlers
+	deadLetterHandlers *apphandlers.DeadLetterHandlers
+	fraudHandlers      *apphandlers.FraudHandlers
+	alertHandlers      *apphandlers.AdminNotificationHandlers
+	connectorHandlers  *apphandlers.AlertConnectorHandlers
+	statusHandlers     *apphandlers.StatusHandlers
+	addOnHandlers      *apphandlers.AddOnHandlers
+	formFieldHandlers  *apphandlers.FormFieldHandlers
+	accessHandlers     *apphandlers.EventAccessHandlers
+	attestHandlers     *apphandlers.AttestationHandlers
+	translateHandlers  *apphandlers.EventTranslationHandlers
+	templateHandlers   *apphandlers.TemplateHandlers
+	receiptHandlers    *apphandlers.ReceiptHandlers
+	orderHandlers      *apphandlers.OrderHandlers
+	feeHandlers        *apphandlers.FeeHandlers
+	taxHandlers        *apphandlers.TaxHandlers
+	accountingHandlers *apphandlers.AccountingHandlers
+	ledgerHandlers     *apphandlers.LedgerHandlers
+	metricsHandlers    *apphandlers.MetricsHandlers
+	disputeHandlers    *apphandlers.DisputeHandlers
+	supportHandlers    *apphandlers.ImpersonationHandlers
+	rescheduleHandlers *apphandlers.RescheduleHandlers
+	cancelHandlers     *apphandlers.CancellationHandlers
+	checkInHandlers    *apphandlers.CheckInHandlers
+	scannerHandlers    *apphandlers.ScannerHandlers
+	compHandlers       *apphandlers.CompHandlers
+	holdHandlers       *apphandlers.HoldHandlers
+	relatedHandlers    *apphandlers.RelatedEventHandlers
+	organizerHandlers  *apphandlers.OrganizerHandlers
+	seoHandlers        *apphandlers.SEOHandlers
+	feedHandlers       *apphandlers.FeedHandlers
+	linkHandlers       *apphandlers.ShortLinkHandlers
+	analyticsHandlers  *apphandlers.AnalyticsHandlers
+	affiliateHandlers  *apphandlers.AffiliateHandlers
+	giftHandlers       *apphandlers.GiftHandlers
+	flexHandlers       *apphandlers.FlexHandlers
+	pricingHandlers    *apphandlers.PricingHandlers
+	rateHandlers       *apphandlers.ExchangeRateHandlers
+	cashuHandlers      *apphandlers.CashuHandlers
+	lnurlAuthHandlers  *apphandlers.LNURLAuthHandlers
+	archiveHandlers    *apphandlers.ArchiveHandlers
+	consoleHandlers    *apphandlers.ConsoleHandlers
+	backupHandlers     *apphandlers.BackupHandlers
+	purchaseLimiter    *middleware.RateLimiter
+	requestCapture     *middleware.RequestCapture
+	paymentWebhook     *middleware.WebhookGuard
+	umaCallback        *middleware.WebhookGuard
+	challenge          *middleware.ChallengeGuard
+	trustedProxies     *middleware.TrustedProxies
+	readOnly           bool
 }
 
-func NewServer(db *sqlx.DB, logger *slog.Logger, config *config.Config) *Server {
+// NewServer wires repositories, services and handlers. Dependencies such as
+// the UMA service can be injected with ServerOptions; the rest are built
+// from cfg. db may be nil when cfg selects memory storage.
+func NewServer(db *sqlx.DB, cfg *config.Config, opts ...ServerOption) *Server {
 	s := &Server{
-		db:     db,
-		logger: logger,
-		config: config,
-		router: mux.NewRouter(),
+		db:      db,
+		config:  cfg,
+		router:  mux.NewRouter(),
+		metrics: metrics.NewRegistry(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
 	}
+	if s.clock == nil {
+		s.clock = clock.System()
+	}
+	logger := s.logger
 
 	// Initialize repositories
-	s.userRepo = repositories.NewUserRepository(db)
-	s.eventRepo = repositories.NewEventRepository(db)
-	s.ticketRepo = repositories.NewTicketRepository(db)
-	s.paymentRepo = repositories.NewPaymentRepository(db)
-	s.umaRepo = repositories.NewUMARequestInvoiceRepository(db)
-	s.nwcRepo = repositories.NewNWCConnectionRepository(db)
+	if cfg.UsesMemoryStorage() {
+		s.initMemoryRepositories()
+	} else {
+		s.initPostgresRepositories()
+	}
+
+	// Wake ticket status long-polls whenever a ticket is updated
+	s.ticketWatcher = uma_services.NewTicketWatcher()
+	s.ticketRepo = s.ticketWatcher.Tickets(s.ticketRepo)
+	// Door scans update the check-in dashboards as they happen
+	s.checkIns = uma_services.NewCheckInService(s.scanRepo, s.ticketRepo, s.clock, logger)
+	// Sales, sell-outs, payouts, fraud flags, dead webhooks and payment
+	// backend outages go to the admins' feed
+	s.adminAlerts = uma_services.NewAdminNotificationService(s.alertRepo, s.eventRepo, logger)
+	s.ticketRepo = s.adminAlerts.Tickets(s.ticketRepo)
+	s.fraudRepo = s.adminAlerts.FraudFlags(s.fraudRepo)
+	s.ledgerRepo = s.adminAlerts.Ledger(s.ledgerRepo)
+	s.webhookRepo = s.adminAlerts.WebhookEvents(s.webhookRepo)
+	if db != nil && db.DriverName() == "postgres" {
+		// Replicas share ticket changes, scans and admin notifications so
+		// long-polls, dashboards and feeds on any of them wake up
+		s.changeBus = pubsub.NewPostgres(db, cfg.DatabaseURL, logger)
+		s.ticketWatcher.Broadcast(s.changeBus)
+		s.checkIns.Broadcast(s.changeBus)
+		s.adminAlerts.Broadcast(s.changeBus)
+	}
+
+	// Load runtime settings; StartWorkers keeps them fresh afterwards
+	s.settingsService = uma_services.NewSettingsService(s.settingsRepo, logger)
+	if err := s.settingsService.Refresh(); err != nil {
+		logger.Error("Failed to load runtime settings, using defaults", "error", err)
+	}
+	s.purchaseLimiter = middleware.NewRateLimiter(func() int {
+		return s.settingsService.Int(uma_services.SettingPurchaseRateLimitPerMin, 0)
+	}, time.Minute)
+	s.requestCapture = middleware.NewRequestCapture(func() int {
+		return s.settingsService.Int(uma_services.SettingDebugCapturePercent, 0)
+	}, debugCaptureSize, debugCaptureMaxBody)
+
+	// Shared client for outbound calls
+	if s.httpClient == nil {
+		client, err := httpclient.New(cfg.HTTPClientOptions())
+		if err != nil {
+			logger.Error("Invalid outbound HTTP client configuration, using defaults", "error", err)
+			client, _ = httpclient.New(httpclient.Options{})
+		}
+		s.httpClient = client
+	}
+
+	// Admin notifications are also posted to Slack, Discord and Telegram
+	s.alertConnectors = uma_services.NewAlertService(s.connectorRepo, s.httpClient, uma_services.TelegramAPIURL, s.clock, logger)
+	s.adminAlerts.Forward(s.alertConnectors)
 
 	// Initialize Lightspark client
-	s.lightsparkClient = services.NewLightsparkClient(config.LightsparkClientID, config.LightsparkClientSecret, nil)
+	if s.lightsparkClient == nil {
+		s.lightsparkClient = services.NewLightsparkClient(cfg.LightsparkClientID, cfg.LightsparkClientSecret, nil, services.WithHTTPClient(s.httpClient))
+	}
+	if cfg.Secrets != nil {
+		cfg.Secrets.OnChange(config.SecretLightsparkClientSecret, func(secret string) {
+			logger.Info("Lightspark client secret rotated")
+			s.lightsparkClient.Requester.ApiTokenClientSecret = secret
+		})
+	}
 
 	// Initialize UMA service
-	s.umaService = uma_services.NewLightsparkUMAService(
-		config.LightsparkClientID,
-		config.LightsparkClientSecret,
-		config.LightsparkNodeID,
-		config.LightsparkNodePassword,
-		config.Domain,
-		config.UMASigningPrivKeyHex,
-		config.UMASigningCertChain,
-		config.UMAEncryptionPrivKeyHex,
-		config.UMAEncryptionCertChain,
-		logger,
-	)
+	if s.umaService == nil {
+		s.umaService = uma_services.NewUMAServiceFromConfig(cfg, s.clock, s.httpClient, logger)
+	}
+	if lightspark, ok := s.umaService.(*uma_services.LightsparkUMAService); ok {
+		if discovery := lightspark.Discovery(); discovery != nil {
+			s.metrics.Register("uma_discovery", func() interface{} { return discovery.Stats() })
+		}
+		if breaker := lightspark.Breaker(); breaker != nil {
+			s.breaker = breaker
+			breaker.OnOpen(s.adminAlerts.BackendDown)
+			s.metrics.Register("lightspark_breaker", func() interface{} { return breaker.Stats() })
+		}
+	}
+
+	// Parse trusted proxy ranges used to resolve client IPs behind the load balancer
+	trustedProxies, err := middleware.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Error("Invalid trusted proxy configuration, ignoring X-Forwarded-For", "error", err)
+		trustedProxies = &middleware.TrustedProxies{}
+	}
+	s.trustedProxies = trustedProxies
+
+	// Guard inbound webhooks by source IP and shared secret
+	s.paymentWebhook = s.webhookGuard("payment_webhook", cfg.PaymentWebhookAllowedIPs, config.SecretPaymentWebhookSecret)
+	s.umaCallback = s.webhookGuard("uma_callback", cfg.UMACallbackAllowedIPs, config.SecretUMACallbackSecret)
+
+	// Bot challenge on unauthenticated purchase and signup
+	s.challenge = s.challengeGuard()
+
+	// Payment webhooks are acknowledged on receipt and processed by workers
+	s.webhookQueue = uma_services.NewWebhookQueue(s.webhookRepo, cfg.WebhookWorkers, cfg.WebhookMaxAttempts, s.clock, logger)
+	s.metrics.Register("webhook_queue", func() interface{} { return s.webhookQueue.Stats() })
+
+	// Per-query timings from the instrumented database driver
+	if s.queryStats != nil {
+		s.metrics.Register("database", func() interface{} { return s.queryStats.Stats() })
+	}
+
+	// Keys signing ticket QR payloads for offline verification
+	s.ticketSigner = s.ticketSigningKeyring()
+
+	// Login tokens, signed with JWT_SIGNING_KEYS when set
+	s.tokens = middleware.NewTokens(s.jwtSecret, s.jwtSigningKeyring(), cfg.JWTLegacyHS256Until)
 
 	// Initialize handlers
 	s.initializeHandlers()
+	s.webhookQueue.OnEvent(s.paymentHandlers.ProcessWebhookEvent)
+
+	// The simulated backend stands in for Lightspark's payment webhook
+	if sim, ok := s.umaService.(*uma_services.SimulatedUMAService); ok {
+		logger.Warn("PAYMENT_BACKEND=simulation: invoices are settled locally and no real payments are made")
+		sim.OnSettled(s.paymentHandlers.MarkPaymentPaid)
+	}
 
 	s.setupRoutes()
 	return s
 }
 
-// SetUMAService allows setting a custom UMA service (useful for testing)
-func (s *Server) SetUMAService(umaService uma_services.UMAService) {
-	s.umaService = umaService
-	// Re-initialize handlers with new service
-	s.initializeHandlers()
-	// Re-setup routes since handlers changed
-	s.router = mux.NewRouter()
-	s.setupRoutes()
+// initPostgresRepositories builds the database-backed repositories
+func (s *Server) initPostgresRepositories() {
+	s.userRepo = repositories.NewUserRepository(s.db)
+	piiKeyring := s.keyring(s.config, config.SecretPIIEncryptionKeys)
+	s.eventRepo = repositories.NewEventRepository(s.db, piiKeyring)
+	s.ticketRepo = repositories.NewTicketRepository(s.db, piiKeyring, s.clock)
+	s.paymentRepo = repositories.NewPaymentRepository(s.db, s.clock)
+	s.umaRepo = repositories.NewUMARequestInvoiceRepository(s.db, piiKeyring)
+	nwcKeyring := s.keyring(s.config, config.SecretNWCEncryptionKeys)
+	if nwcKeyring == nil {
+		s.logger.Warn("NWC_ENCRYPTION_KEYS not set, NWC connection URIs are stored unencrypted")
+	}
+	s.nwcRepo = repositories.NewNWCConnectionRepository(s.db, nwcKeyring)
+	s.settingsRepo = repositories.NewSettingsRepository(s.db)
+	s.fraudRepo = repositories.NewFraudFlagRepository(s.db, s.clock)
+	s.addOnRepo = repositories.NewAddOnRepository(s.db, s.clock)
+	s.formFieldRepo = repositories.NewFormFieldRepository(s.db, s.clock)
+	s.accessRepo = repositories.NewEventAccessRepository(s.db, s.clock)
+	s.attestRepo = repositories.NewAttestationRepository(s.db, s.clock)
+	s.translationRepo = repositories.NewEventTranslationRepository(s.db, s.clock)
+	s.rescheduleRepo = repositories.NewEventRescheduleRepository(s.db, s.clock)
+	s.cancelRepo = repositories.NewEventCancellationRepository(s.db, s.clock)
+	s.scanRepo = repositories.NewTicketScanRepository(s.db, s.clock)
+	s.scannerRepo = repositories.NewScannerDeviceRepository(s.db, s.clock)
+	s.holdRepo = repositories.NewInventoryHoldRepository(s.db, s.clock)
+	s.relationRepo = repositories.NewEventRelationRepository(s.db, s.clock)
+	s.profileRepo = repositories.NewOrganizerProfileRepository(s.db, s.clock)
+	s.linkRepo = repositories.NewShortLinkRepository(s.db, s.clock)
+	s.affiliateRepo = repositories.NewAffiliateRepository(s.db, s.clock)
+	s.giftRepo = repositories.NewTicketGiftRepository(s.db, s.clock)
+	s.pricingRuleRepo = repositories.NewPricingRuleRepository(s.db, s.clock)
+	s.exchangeRateRepo = repositories.NewExchangeRateRepository(s.db)
+	s.cashuRepo = repositories.NewCashuRedemptionRepository(s.db, s.clock)
+	s.lnurlAuthRepo = repositories.NewLNURLAuthRepository(s.db, s.clock)
+	s.templateRepo = repositories.NewNotificationTemplateRepository(s.db, s.clock)
+	s.receiptRepo = repositories.NewReceiptRepository(s.db, s.clock)
+	s.orderRepo = repositories.NewOrderRepository(s.db, s.clock)
+	s.feeRepo = repositories.NewFeeRepository(s.db, s.clock)
+	s.ledgerRepo = repositories.NewLedgerRepository(s.db, s.clock)
+	s.disputeRepo = repositories.NewDisputeRepository(s.db, s.clock)
+	s.webhookRepo = repositories.NewWebhookEventRepository(s.db, s.clock)
+	s.archiveRepo = repositories.NewArchiveRepository(s.db, s.clock)
+	s.consoleRepo = repositories.NewConsoleRepository(s.db)
+	s.alertRepo = repositories.NewAdminNotificationRepository(s.db, s.clock)
+	s.connectorRepo = repositories.NewAlertConnectorRepository(s.db, piiKeyring, s.clock)
+	s.incidentRepo = repositories.NewStatusIncidentRepository(s.db, s.clock)
+	s.walletClaimRepo = repositories.NewWalletClaimRepository(s.db, s.clock)
+	s.accountClaimRepo = repositories.NewAccountClaimRepository(s.db, s.clock)
+	s.emailChangeRepo = repositories.NewEmailChangeRepository(s.db, s.clock)
+}
+
+// initMemoryRepositories builds repositories that share one in-process
+// store. Nothing is persisted, so encryption keys are not needed.
+func (s *Server) initMemoryRepositories() {
+	s.logger.Warn("Using in-memory storage, all data is lost on restart")
+	store := repositories.NewMemoryStore(s.clock)
+	s.userRepo = store.Users()
+	s.eventRepo = store.Events()
+	s.ticketRepo = store.Tickets()
+	s.paymentRepo = store.Payments()
+	s.umaRepo = store.UMARequestInvoices()
+	s.nwcRepo = store.NWCConnections()
+	s.settingsRepo = store.Settings()
+	s.fraudRepo = store.FraudFlags()
+	s.addOnRepo = store.AddOns()
+	s.formFieldRepo = store.FormFields()
+	s.accessRepo = store.EventAccess()
+	s.attestRepo = store.Attestations()
+	s.translationRepo = store.EventTranslations()
+	s.rescheduleRepo = store.EventReschedules()
+	s.cancelRepo = store.EventCancellations()
+	s.scanRepo = store.TicketScans()
+	s.scannerRepo = store.ScannerDevices()
+	s.holdRepo = store.InventoryHolds()
+	s.relationRepo = store.EventRelations()
+	s.profileRepo = store.OrganizerProfiles()
+	s.linkRepo = store.ShortLinks()
+	s.affiliateRepo = store.Affiliates()
+	s.giftRepo = store.TicketGifts()
+	s.pricingRuleRepo = store.PricingRules()
+	s.exchangeRateRepo = store.ExchangeRates()
+	s.cashuRepo = store.CashuRedemptions()
+	s.lnurlAuthRepo = store.LNURLAuth()
+	s.templateRepo = store.NotificationTemplates()
+	s.receiptRepo = store.Receipts()
+	s.orderRepo = store.Orders()
+	s.feeRepo = store.Fees()
+	s.ledgerRepo = store.Ledger()
+	s.disputeRepo = store.Disputes()
+	s.webhookRepo = store.WebhookEvents()
+	s.archiveRepo = store.Archive()
+	s.consoleRepo = store.Console()
+	s.alertRepo = store.AdminNotifications()
+	s.connectorRepo = store.AlertConnectors()
+	s.incidentRepo = store.StatusIncidents()
+	s.walletClaimRepo = store.WalletClaims()
+	s.accountClaimRepo = store.AccountClaims()
+	s.emailChangeRepo = store.EmailChanges()
+}
+
+// StartWorkers runs background loops until ctx is cancelled
+func (s *Server) StartWorkers(ctx context.Context) {
+	go s.settingsService.Watch(ctx, settingsRefreshInterval)
+	go s.ledgerService.Watch(ctx, ledgerCheckInterval)
+	go s.cancellations.Watch(ctx, cancellationInterval)
+	go s.gifts.Watch(ctx, giftDeliveryInterval)
+	go s.reservations.Watch(ctx, reservationInterval)
+	if s.config.RetentionPolicy().Enabled() {
+		go s.retention.Watch(ctx, archiveHour)
+	}
+	if s.exchangeRates != nil {
+		go s.exchangeRates.Watch(ctx, s.config.ExchangeRateInterval)
+	}
+	go s.webhookQueue.Run(ctx)
+	if s.db != nil && s.db.DriverName() == "postgres" {
+		go s.watchPaymentPartitions(ctx)
+	}
+	if s.changeBus != nil {
+		go func() {
+			if err := s.changeBus.Run(ctx); err != nil {
+				s.logger.Error("Change notifications from other instances are unavailable", "error", err)
+			}
+		}()
+	}
+	go s.reencryptNWCConnections()
+}
+
+// keyring builds an encryption keyring from the config secret named by key
+// (e.g. NWC_ENCRYPTION_KEYS). It returns nil when the secret is unset and
+// follows key rotations from the secret store.
+func (s *Server) keyring(cfg *config.Config, key string) *encryption.Keyring {
+	spec := cfg.Secret(key)
+	if spec == "" {
+		return nil
+	}
+
+	keyring, err := encryption.ParseKeyring(spec)
+	if err != nil {
+		// Validated at startup, so this only happens with hand-built configs
+		s.logger.Error("Invalid encryption keys", "secret", key, "error", err)
+		return nil
+	}
+
+	if cfg.Secrets != nil {
+		cfg.Secrets.OnChange(key, func(spec string) {
+			if err := keyring.Update(spec); err != nil {
+				s.logger.Error("Ignoring invalid rotated encryption keys", "secret", key, "error", err)
+				return
+			}
+			s.logger.Info("Encryption keys rotated", "secret", key)
+			if key == config.SecretNWCEncryptionKeys {
+				s.reencryptNWCConnections()
+			}
+		})
+	}
+	return keyring
+}
+
+// ticketSigningKeyring builds the keyring signing ticket QR payloads from
+// TICKET_SIGNING_KEYS. It returns nil when the secret is unset and follows
+// key rotations from the secret store.
+func (s *Server) ticketSigningKeyring() *ticketsig.Keyring {
+	spec := s.config.Secret(config.SecretTicketSigningKeys)
+	if spec == "" {
+		s.logger.Warn("TICKET_SIGNING_KEYS not set, signed ticket QR payloads are disabled")
+		return nil
+	}
+
+	keyring, err := ticketsig.ParseKeyring(spec)
+	if err != nil {
+		// Validated at startup, so this only happens with hand-built configs
+		s.logger.Error("Invalid ticket signing keys", "error", err)
+		return nil
+	}
+
+	if s.config.Secrets != nil {
+		s.config.Secrets.OnChange(config.SecretTicketSigningKeys, func(spec string) {
+			if err := keyring.Update(spec); err != nil {
+				s.logger.Error("Ignoring invalid rotated ticket signing keys", "error", err)
+				return
+			}
+			s.logger.Info("Ticket signing keys rotated")
+		})
+	}
+	return keyring
+}
+
+// jwtSigningKeyring builds the keyring signing login tokens from
+// JWT_SIGNING_KEYS. It returns nil when the secret is unset, leaving tokens
+// signed with JWT_SECRET, and follows key rotations from the secret store.
+func (s *Server) jwtSigningKeyring() *jwtkeys.Keyring {
+	spec := s.config.Secret(config.SecretJWTSigningKeys)
+	if spec == "" {
+		return nil
+	}
+
+	keyring, err := jwtkeys.ParseKeyring(spec)
+	if err != nil {
+		// Validated at startup, so this only happens with hand-built configs
+		s.logger.Error("Invalid JWT signing keys, signing tokens with JWT_SECRET", "error", err)
+		return nil
+	}
+
+	if s.config.Secrets != nil {
+		s.config.Secrets.OnChange(config.SecretJWTSigningKeys, func(spec string) {
+			if err := keyring.Update(spec); err != nil {
+				s.logger.Error("Ignoring invalid rotated JWT signing keys", "error", err)
+				return
+			}
+			s.logger.Info("JWT signing keys rotated")
+		})
+	}
+	return keyring
+}
+
+// webhookGuard builds the guard for an inbound webhook endpoint. The shared
+// secret is resolved per request so rotations apply immediately.
+func (s *Server) webhookGuard(name string, allowedIPs []string, secretKey string) *middleware.WebhookGuard {
+	secret := func() string { return s.config.Secret(secretKey) }
+
+	guard, err := middleware.NewWebhookGuard(name, allowedIPs, secret, s.logger)
+	if err != nil {
+		// Validated at startup; fall back to the shared secret check only
+		s.logger.Error("Invalid webhook allowlist, ignoring it", "webhook", name, "error", err)
+		guard, _ = middleware.NewWebhookGuard(name, nil, secret, s.logger)
+	}
+	return guard
+}
+
+// challengeGuard builds the bot challenge selected by cfg.ChallengeProvider.
+// With challenges off the guard has no verifier and passes requests through.
+func (s *Server) challengeGuard() *middleware.ChallengeGuard {
+	cfg := s.config
+	secret := func() string { return cfg.Secret(config.SecretChallengeSecret) }
+
+	var verifier middleware.ChallengeVerifier
+	switch cfg.ChallengeProvider {
+	case config.ChallengeHCaptcha:
+		verifier = middleware.NewCaptchaVerifier(middleware.HCaptchaVerifyURL, secret, s.httpClient)
+	case config.ChallengeTurnstile:
+		verifier = middleware.NewCaptchaVerifier(middleware.TurnstileVerifyURL, secret, s.httpClient)
+	case config.ChallengePoW:
+		key := []byte(secret())
+		if len(key) == 0 {
+			// Challenges only verify on the instance that issued them
+			s.logger.Warn("CHALLENGE_SECRET not set, proof-of-work challenges are signed with a per-process key")
+			key = make([]byte, 32)
+			rand.Read(key)
+		}
+		verifier = middleware.NewProofOfWork(key, cfg.ChallengePoWDifficulty, 5*time.Minute)
+	}
+
+	return middleware.NewChallengeGuard(cfg.ChallengeProvider, cfg.ChallengeSiteKey, verifier, cfg.ChallengeRoutes, s.logger)
+}
+
+// reencryptNWCConnections moves stored NWC connections onto the primary key
+func (s *Server) reencryptNWCConnections() {
+	count, err := s.nwcRepo.Reencrypt()
+	if err != nil {
+		s.logger.Error("Failed to re-encrypt NWC connections", "error", err)
+		return
+	}
+	if count > 0 {
+		s.logger.Info("Re-encrypted NWC connections", "count", count)
+	}
+}
+
+// watchPaymentPartitions creates the coming months' payments partitions
+// now and then daily, so new payments never land in the default partition
+func (s *Server) watchPaymentPartitions(ctx context.Context) {
+	ticker := time.NewTicker(partitionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		created, err := database.EnsurePaymentPartitions(s.db, s.clock.Now(), database.PaymentPartitionsAhead)
+		if err != nil {
+			s.logger.Error("Failed to create payments partitions", "error", err)
+		}
+		if len(created) > 0 {
+			s.logger.Info("Created payments partitions", "partitions", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// jwtSecret returns the current JWT signing secret, following rotations
+func (s *Server) jwtSecret() string {
+	return s.config.Secret(config.SecretJWT)
+}
+
+// sessionVersion returns a user's session version, against which tokens
+// are checked so that changing a password signs out other sessions
+func (s *Server) sessionVersion(userID int) (int, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.SessionVersion, nil
+}
+
+// webhookSigningKey returns the current Lightspark webhook signing key
+func (s *Server) webhookSigningKey() string {
+	return s.config.Secret(config.SecretWebhookSigningKey)
 }
 
 // Router returns the router (useful for testing)
@@ -93,19 +652,53 @@ func (s *Server) Router() *mux.Router {
 }
 
 func (s *Server) setupRoutes() {
+	// Resolve the real client IP before anything else looks at RemoteAddr
+	s.router.Use(s.trustedProxies.Middleware)
+
+	// Translate response messages into the client's Accept-Language
+	s.router.Use(middleware.Locale)
+
+	// Security headers and request body caps apply to every endpoint
+	s.router.Use(middleware.SecurityHeaders)
+	// Only bulk comp issuance takes a large body
+	s.router.Use(middleware.NewBodyLimiter(s.config.MaxBodyBytes).
+		AllowRoute("/api/admin/events/*/tickets/bulk", s.config.MaxUploadBodyBytes).
+		Middleware)
+
+	// Compress responses for clients that accept br or gzip
+	s.router.Use(middleware.Compress)
+
 	// Add CORS middleware to main router (covers all endpoints)
 	s.router.Use(s.corsMiddleware)
 
 	// Add logging middleware
 	s.router.Use(s.loggingMiddleware)
 
+	// Keep redacted bodies of sampled failed requests when debug capture is on
+	s.router.Use(s.requestCapture.Middleware)
+
+	// Writes are refused while the database schema does not match this build
+	if s.readOnly {
+		s.router.Use(middleware.ReadOnly)
+	}
+
 	// Health check endpoint
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
 
 	// UMA protocol endpoints
+	s.router.HandleFunc("/.well-known/jwks.json", s.userHandlers.HandleJWKS).Methods("GET", "OPTIONS")
 	s.router.HandleFunc("/.well-known/lnurlpubkey", s.umaHandlers.HandlePubKeyRequest).Methods("GET", "OPTIONS")
 	s.router.HandleFunc("/.well-known/uma-configuration", s.umaHandlers.HandleUmaConfiguration).Methods("POST", "GET", "OPTIONS")
+	s.router.HandleFunc("/.well-known/lnurlp/{identifier}", s.umaHandlers.HandleLnurlp).Methods("GET", "OPTIONS")
 	s.router.HandleFunc("/uma/payreq/{ticket_id:[0-9]+}", s.umaHandlers.HandleUmaPayreq).Methods("POST", "GET", "OPTIONS")
+	s.router.HandleFunc("/uma/payreq/event/{event_id:[0-9]+}", s.umaHandlers.HandleEventPayreq).Methods("POST", "GET", "OPTIONS")
+
+	// Search engine sitemap of public event pages
+	s.router.HandleFunc("/sitemap.xml", s.seoHandlers.HandleSitemap).Methods("GET", "HEAD")
+
+	// Short links to event pages and tickets
+	s.router.HandleFunc("/e/{code}", s.linkHandlers.HandleFollowEventLink).Methods("GET", "HEAD")
+	s.router.HandleFunc("/t/{code}", s.linkHandlers.HandleFollowTicketLink).Methods("GET", "HEAD")
 
 	// API routes
 	api := s.router.PathPrefix("/api").Subrouter()
@@ -113,44 +706,115 @@ func (s *Server) setupRoutes() {
 	// CORS middleware is already applied to main router, no need to apply again
 	// api.Use(s.corsMiddleware)
 
+	// Subsystem health and incidents for the status page (public)
+	api.HandleFunc("/status", s.statusHandlers.HandleGetStatus).Methods("GET", "OPTIONS")
+
+	// Bot challenge for the routes below that require one
+	api.HandleFunc("/challenge", s.challenge.HandleChallenge).Methods("GET", "OPTIONS")
+
 	// User routes (no auth required)
-	api.HandleFunc("/users", s.userHandlers.HandleCreateUser).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users", s.challenge.Wrap(config.ChallengeRouteSignup, s.userHandlers.HandleCreateUser)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/users/login", s.userHandlers.HandleLogin).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/claim", s.userHandlers.HandleClaimAccount).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/claim/resend", s.challenge.Wrap(config.ChallengeRouteSignup, s.userHandlers.HandleResendClaim)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/email/confirm", s.userHandlers.HandleConfirmEmail).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/password-policy", s.userHandlers.HandleGetPasswordPolicy).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/{id:[0-9]+}", s.userHandlers.HandleGetUser).Methods("GET", "OPTIONS")
 
+	// Login with a Lightning wallet (LNURL-auth); the callback is called by
+	// the wallet, the session by the browser showing the QR code
+	api.HandleFunc("/auth/lnurl", s.lnurlAuthHandlers.HandleGetChallenge).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/lnurl/callback", s.lnurlAuthHandlers.HandleCallback).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/lnurl/session", s.lnurlAuthHandlers.HandlePoll).Methods("POST", "OPTIONS")
+
 	// Event routes (public)
 	api.HandleFunc("/events", s.eventHandlers.HandleGetEvents).Methods("GET", "OPTIONS")
 	api.HandleFunc("/events/{id:[0-9]+}", s.eventHandlers.HandleGetEvent).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/availability", s.eventHandlers.HandleGetEventAvailability).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/addons", s.addOnHandlers.HandleListAddOns).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/price", s.pricingHandlers.HandleGetPrice).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/form-fields", s.formFieldHandlers.HandleListFormFields).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/jsonld", s.seoHandlers.HandleGetEventJSONLD).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/feed.{format:rss|atom}", s.feedHandlers.HandleGetFeed).Methods("GET", "OPTIONS")
+	api.HandleFunc("/events/{id:[0-9]+}/shortlink", s.linkHandlers.HandleGetEventLink).Methods("GET", "OPTIONS")
+
+	// Cashu payments of ticket invoices (public, like paying the bolt11
+	// from any wallet)
+	api.HandleFunc("/payments/cashu", s.purchaseLimiter.Wrap(s.cashuHandlers.HandlePayWithCashu)).Methods("POST", "OPTIONS")
+
+	// Gift previews (public; accepting one needs an account)
+	api.HandleFunc("/gifts", s.giftHandlers.HandleGetGift).Methods("GET", "OPTIONS")
+
+	// Organizer profile pages (public)
+	api.HandleFunc("/organizers/{slug}", s.organizerHandlers.HandleGetOrganizer).Methods("GET", "OPTIONS")
 
 	// Ticket routes (public for purchase, auth for others)
-	api.HandleFunc("/tickets/purchase", s.ticketHandlers.HandlePurchaseTicket).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/purchase", s.purchaseLimiter.Wrap(s.challenge.Wrap(config.ChallengeRoutePurchase, s.ticketHandlers.HandlePurchaseTicket))).Methods("POST", "OPTIONS")
 	api.HandleFunc("/tickets/{id:[0-9]+}/status", s.ticketHandlers.HandleTicketStatus).Methods("GET", "OPTIONS")
 	api.HandleFunc("/tickets/validate", s.ticketHandlers.HandleValidateTicket).Methods("POST", "OPTIONS")
-	api.HandleFunc("/tickets/uma-callback", s.ticketHandlers.HandleUMAPaymentCallback).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/verify-qr", s.ticketQRHandlers.HandleVerifyTicketQR).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/signing-keys", s.ticketQRHandlers.HandleSigningKeys).Methods("GET", "OPTIONS")
+	api.HandleFunc("/tickets/validate-device", s.walletHandlers.HandleValidateDevice).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/{id:[0-9]+}/claim", s.walletHandlers.HandleClaim).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/{id:[0-9]+}/checkin-challenge", s.walletHandlers.HandleCheckInChallenge).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/uma-callback", s.umaCallback.Wrap(s.ticketHandlers.HandleUMAPaymentCallback)).Methods("POST", "OPTIONS")
+
+	// Door scanners register with a pairing code, then validate tickets for
+	// their own event with the device token it returns
+	api.HandleFunc("/scanners/register", s.scannerHandlers.HandleRegisterScanner).Methods("POST", "OPTIONS")
+	scanner := api.PathPrefix("/scanner").Subrouter()
+	scanner.Use(middleware.ScannerAuthMiddleware(s.scanners.Authenticate))
+	scanner.HandleFunc("/tickets/validate", s.ticketHandlers.HandleValidateTicket).Methods("POST", "OPTIONS")
+	scanner.HandleFunc("/tickets/verify-qr", s.ticketQRHandlers.HandleVerifyTicketQR).Methods("POST", "OPTIONS")
+	scanner.HandleFunc("/tickets/validate-device", s.walletHandlers.HandleValidateDevice).Methods("POST", "OPTIONS")
 
 	// Payment webhook (no auth required)
-	api.HandleFunc("/webhooks/payment", s.paymentHandlers.HandlePaymentWebhook).Methods("POST", "OPTIONS")
+	api.HandleFunc("/webhooks/payment", s.paymentWebhook.Wrap(s.paymentHandlers.HandlePaymentWebhook)).Methods("POST", "OPTIONS")
+
+	// Development-only payment simulation
+	if s.config.UsesSimulatedPayments() {
+		api.HandleFunc("/dev/simulate-payment/{invoice_id}", s.paymentHandlers.HandleSimulatePayment).Methods("POST", "OPTIONS")
+	}
 
 	// Protected routes (require authentication)
 	protected := api.PathPrefix("").Subrouter()
-	protected.Use(middleware.AuthMiddleware(s.config.JWTSecret))
+	protected.Use(middleware.TokenAuthMiddleware(s.tokens, s.sessionVersion))
+	protected.Use(middleware.ImpersonationGuard(s.logger))
 
 	// Protected user routes
 	protected.HandleFunc("/users/me", s.userHandlers.HandleGetCurrentUser).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/users/me/nwc-connection", s.userHandlers.HandleGetNWCConnection).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/users/me/nwc-connection", s.userHandlers.HandleStoreNWCConnection).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/users/me/orders", s.orderHandlers.HandleGetMyOrders).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users/me/password", s.userHandlers.HandleChangePassword).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/users/me/credentials", s.userHandlers.HandleGetCredentials).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users/me/credentials/{method}", s.userHandlers.HandleDeleteCredential).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/users/me/lnurl-auth", s.lnurlAuthHandlers.HandleLinkChallenge).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/users/me/organizer-profile", s.organizerHandlers.HandleGetMyProfile).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users/me/organizer-profile", s.organizerHandlers.HandleSaveMyProfile).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/users/me/affiliate", s.affiliateHandlers.HandleGetMyAffiliate).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users/me/gifts", s.giftHandlers.HandleGetSentGifts).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/users/{id:[0-9]+}", s.userHandlers.HandleUpdateUser).Methods("PUT", "OPTIONS")
 	protected.HandleFunc("/users/{id:[0-9]+}", s.userHandlers.HandleDeleteUser).Methods("DELETE", "OPTIONS")
 
 	// Protected ticket routes
 	protected.HandleFunc("/users/{user_id:[0-9]+}/tickets", s.ticketHandlers.HandleGetUserTickets).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/qr", s.ticketQRHandlers.HandleGetTicketQR).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/payment-proof", s.ticketHandlers.HandleGetPaymentProof).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/reinvoice", s.purchaseLimiter.Wrap(s.ticketHandlers.HandleReinvoiceTicket)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/wallet-claim", s.walletHandlers.HandleOfferClaim).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/gifts/claim", s.giftHandlers.HandleClaimGift).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/reschedule-refund", s.rescheduleHandlers.HandleRequestRefund).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/cancel", s.flexHandlers.HandleCancelTicket).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tickets/{id:[0-9]+}/shortlink", s.linkHandlers.HandleGetTicketLink).Methods("GET", "OPTIONS")
 
 	// Protected payment routes
 	protected.HandleFunc("/payments/{invoice_id}/status", s.paymentHandlers.HandlePaymentStatus).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/payments/{id:[0-9]+}/receipt", s.receiptHandlers.HandleGetReceipt).Methods("GET", "OPTIONS")
 
 	// Admin routes (require authentication and admin privileges)
 	admin := api.PathPrefix("/admin").Subrouter()
-	admin.Use(middleware.AuthMiddleware(s.config.JWTSecret))
+	admin.Use(middleware.TokenAuthMiddleware(s.tokens, s.sessionVersion))
 	admin.Use(s.adminMiddleware)
 
 	// Admin status check - if the middleware lets you through, you're admin
@@ -158,13 +822,94 @@ func (s *Server) setupRoutes() {
 		middleware.WriteJSON(w, http.StatusOK, map[string]bool{"is_admin": true})
 	}).Methods("GET", "OPTIONS")
 
+	// Support impersonation (read-only token acting as the user)
+	admin.HandleFunc("/impersonate/{user_id:[0-9]+}", s.supportHandlers.HandleImpersonate).Methods("POST", "OPTIONS")
+
 	// Admin event routes
 	admin.HandleFunc("/events", s.eventHandlers.HandleCreateEvent).Methods("POST", "OPTIONS")
 	admin.HandleFunc("/events/{id:[0-9]+}", s.eventHandlers.HandleUpdateEvent).Methods("PUT", "OPTIONS")
 	admin.HandleFunc("/events/{id:[0-9]+}", s.eventHandlers.HandleDeleteEvent).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/reschedule", s.rescheduleHandlers.HandleRescheduleEvent).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/reschedules", s.rescheduleHandlers.HandleListReschedules).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/cancel", s.cancelHandlers.HandleCancelEvent).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/cancellation", s.cancelHandlers.HandleGetCancellation).Methods("GET", "OPTIONS")
+
+	// Admin add-on routes
+	admin.HandleFunc("/events/{id:[0-9]+}/addons", s.addOnHandlers.HandleListAllAddOns).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/addons", s.addOnHandlers.HandleCreateAddOn).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/addons/{id:[0-9]+}", s.addOnHandlers.HandleUpdateAddOn).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/addons/{id:[0-9]+}", s.addOnHandlers.HandleDeleteAddOn).Methods("DELETE", "OPTIONS")
+
+	// Admin pricing rule routes
+	admin.HandleFunc("/events/{id:[0-9]+}/pricing-rules", s.pricingHandlers.HandleListPricingRules).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/pricing-rules", s.pricingHandlers.HandleCreatePricingRule).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/pricing-rules/{id:[0-9]+}", s.pricingHandlers.HandleDeletePricingRule).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/price-history", s.pricingHandlers.HandleGetPriceHistory).Methods("GET", "OPTIONS")
+
+	// Admin registration form routes
+	admin.HandleFunc("/events/{id:[0-9]+}/form-fields", s.formFieldHandlers.HandleCreateFormField).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/form-fields/{id:[0-9]+}", s.formFieldHandlers.HandleUpdateFormField).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/form-fields/{id:[0-9]+}", s.formFieldHandlers.HandleDeleteFormField).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/attendees.csv", s.formFieldHandlers.HandleExportAttendees).Methods("GET", "OPTIONS")
+
+	// Admin private event access routes
+	admin.HandleFunc("/events/{id:[0-9]+}/access-codes", s.accessHandlers.HandleListAccessCodes).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/access-codes", s.accessHandlers.HandleCreateAccessCode).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/access-codes/{id:[0-9]+}", s.accessHandlers.HandleDeleteAccessCode).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/allowlist", s.accessHandlers.HandleGetAllowlist).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/allowlist", s.accessHandlers.HandleAddToAllowlist).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/allowlist/{id:[0-9]+}", s.accessHandlers.HandleDeleteAllowlistEntry).Methods("DELETE", "OPTIONS")
+
+	// Admin purchase attestation routes
+	admin.HandleFunc("/events/{id:[0-9]+}/attestations", s.attestHandlers.HandleGetEventAttestations).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/tickets/{id:[0-9]+}/attestation", s.attestHandlers.HandleGetTicketAttestation).Methods("GET", "OPTIONS")
+
+	// Admin event translation routes
+	admin.HandleFunc("/events/{id:[0-9]+}/translations", s.translateHandlers.HandleListTranslations).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/translations/{locale}", s.translateHandlers.HandlePutTranslation).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/translations/{locale}", s.translateHandlers.HandleDeleteTranslation).Methods("DELETE", "OPTIONS")
+
+	// Admin notification template routes
+	admin.HandleFunc("/templates", s.templateHandlers.HandleListTemplates).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/templates", s.templateHandlers.HandleCreateTemplate).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/templates/kinds", s.templateHandlers.HandleListKinds).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/templates/{id:[0-9]+}", s.templateHandlers.HandleGetTemplate).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/templates/{id:[0-9]+}", s.templateHandlers.HandleDeleteTemplate).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/templates/{id:[0-9]+}/versions", s.templateHandlers.HandleListVersions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/templates/{id:[0-9]+}/preview", s.templateHandlers.HandlePreviewTemplate).Methods("POST", "OPTIONS")
+
+	// Admin ticket QR routes
+	admin.HandleFunc("/events/{id:[0-9]+}/revocations", s.ticketQRHandlers.HandleRevocationList).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/checkins", s.checkInHandlers.HandleGetCheckIns).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/checkins/stream", s.checkInHandlers.HandleStreamCheckIns).Methods("GET", "OPTIONS")
+
+	// Admin door scanner routes
+	admin.HandleFunc("/events/{id:[0-9]+}/scanners", s.scannerHandlers.HandleListScanners).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/scanners", s.scannerHandlers.HandleCreateScanner).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/scanners/{id:[0-9]+}", s.scannerHandlers.HandleRevokeScanner).Methods("DELETE", "OPTIONS")
+
+	// Admin comp ticket routes
+	admin.HandleFunc("/events/{id:[0-9]+}/tickets/bulk", s.compHandlers.HandleBulkIssueTickets).Methods("POST", "OPTIONS")
+
+	// Admin inventory hold routes
+	admin.HandleFunc("/events/{id:[0-9]+}/holds", s.holdHandlers.HandleListHolds).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/holds", s.holdHandlers.HandleCreateHold).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/holds/{id:[0-9]+}", s.holdHandlers.HandleReleaseHold).Methods("DELETE", "OPTIONS")
+
+	// Admin related event routes
+	admin.HandleFunc("/events/{id:[0-9]+}/related", s.relatedHandlers.HandleGetRelatedEvents).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/related", s.relatedHandlers.HandleSetRelatedEvents).Methods("PUT", "OPTIONS")
+
+	// Admin short link routes
+	admin.HandleFunc("/events/{id:[0-9]+}/shortlinks", s.linkHandlers.HandleListEventLinks).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/shortlinks", s.linkHandlers.HandleCreateEventLink).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/shortlinks/{id:[0-9]+}", s.linkHandlers.HandleUpdateLink).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/shortlinks/{id:[0-9]+}", s.linkHandlers.HandleDeleteLink).Methods("DELETE", "OPTIONS")
 
 	// Admin UMA routes
 	admin.HandleFunc("/events/{id:[0-9]+}/uma-invoice", s.eventHandlers.HandleCreateEventUMAInvoice).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/uma-invoice", s.eventHandlers.HandleGetEventUMAInvoice).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/uma-invoice", s.eventHandlers.HandleDeleteEventUMAInvoice).Methods("DELETE", "OPTIONS")
 
 	// Admin node balance route
 	admin.HandleFunc("/node/balance", s.eventHandlers.HandleGetNodeBalance).Methods("GET", "OPTIONS")
@@ -173,28 +918,219 @@ func (s *Server) setupRoutes() {
 	admin.HandleFunc("/payments", s.paymentHandlers.HandleGetAllPayments).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/payments/pending", s.paymentHandlers.HandleGetPendingPayments).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/payments/{id:[0-9]+}/retry", s.paymentHandlers.HandleRetryPayment).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/webhooks/replay", s.paymentHandlers.HandleReplayWebhook).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/dead-letters", s.deadLetterHandlers.HandleListDeadLetters).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/dead-letters/{id:[0-9]+}/retry", s.deadLetterHandlers.HandleRetryDeadLetter).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/dead-letters/{id:[0-9]+}", s.deadLetterHandlers.HandleDeleteDeadLetter).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/archive/{table:[a-z_]+}/{id:[0-9]+}", s.archiveHandlers.HandleGetArchivedRecord).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/archive", s.archiveHandlers.HandleGetEventArchive).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/backup", s.backupHandlers.HandleBackup).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/console", s.consoleHandlers.HandleListViews).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/console/{view:[a-z_]+}", s.consoleHandlers.HandleRunView).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/payments/{id:[0-9]+}/receipt", s.receiptHandlers.HandleAdminGetReceipt).Methods("GET", "OPTIONS")
+
+	// Admin platform fee and revenue routes (ids are organizer user IDs)
+	admin.HandleFunc("/fees", s.feeHandlers.HandleListFees).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/organizers/{id:[0-9]+}/fee", s.feeHandlers.HandleSetOrganizerFee).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/organizers/{id:[0-9]+}/fee", s.feeHandlers.HandleDeleteOrganizerFee).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/revenue", s.feeHandlers.HandleRevenueReport).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/exchange-rates", s.rateHandlers.HandleListExchangeRates).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/cashu/redemptions", s.cashuHandlers.HandleListCashuRedemptions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/analytics/referrals", s.analyticsHandlers.HandleReferralReport).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/analytics/flex", s.analyticsHandlers.HandleFlexReport).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/affiliates", s.affiliateHandlers.HandleListAffiliates).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/affiliates", s.affiliateHandlers.HandleCreateAffiliate).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/affiliates/{id:[0-9]+}", s.affiliateHandlers.HandleUpdateAffiliate).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/tax/summary", s.taxHandlers.HandleTaxSummary).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/accounting/export", s.accountingHandlers.HandleExport).Methods("GET", "OPTIONS")
+
+	// Admin ledger routes
+	admin.HandleFunc("/ledger/accounts", s.ledgerHandlers.HandleListAccounts).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ledger/entries", s.ledgerHandlers.HandleListEntries).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ledger/refunds", s.ledgerHandlers.HandleRecordRefund).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ledger/refunds/send", s.ledgerHandlers.HandleSendRefund).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ledger/payouts", s.ledgerHandlers.HandleRecordPayout).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ledger/payouts/send", s.ledgerHandlers.HandleSendPayout).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ledger/check", s.ledgerHandlers.HandleCheck).Methods("GET", "OPTIONS")
+
+	// Admin operational metrics
+	admin.HandleFunc("/metrics", s.metricsHandlers.HandleMetrics).Methods("GET", "OPTIONS")
+
+	// Admin payment dispute routes
+	admin.HandleFunc("/payments/{id:[0-9]+}/disputes", s.disputeHandlers.HandleOpenDispute).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/disputes", s.disputeHandlers.HandleListDisputes).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/disputes/{id:[0-9]+}", s.disputeHandlers.HandleGetDispute).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/disputes/{id:[0-9]+}/resolve", s.disputeHandlers.HandleResolveDispute).Methods("POST", "OPTIONS")
+
+	// Admin runtime settings routes
+	admin.HandleFunc("/settings", s.settingsHandlers.HandleGetSettings).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/settings/{key}", s.settingsHandlers.HandleUpdateSetting).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/settings/{key}", s.settingsHandlers.HandleDeleteSetting).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/sales-pause", s.settingsHandlers.HandleGetSalesPause).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/sales-pause", s.settingsHandlers.HandleSetSalesPause).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/events/{id:[0-9]+}/sales-pause", s.settingsHandlers.HandleSetEventSalesPause).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/debug/requests", s.debugHandlers.HandleListCapturedRequests).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/debug/requests", s.debugHandlers.HandleClearCapturedRequests).Methods("DELETE", "OPTIONS")
+
+	// Fraud flags and the manual review queue (ids are ticket IDs)
+	admin.HandleFunc("/fraud/flags", s.fraudHandlers.HandleListFlags).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/reviews", s.ticketHandlers.HandleListReviews).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/reviews/{id:[0-9]+}/approve", s.ticketHandlers.HandleApproveReview).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/reviews/{id:[0-9]+}/reject", s.ticketHandlers.HandleRejectReview).Methods("POST", "OPTIONS")
+
+	// Admin notification feed
+	admin.HandleFunc("/notifications", s.alertHandlers.HandleListNotifications).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/notifications/stream", s.alertHandlers.HandleStreamNotifications).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/notifications/read-all", s.alertHandlers.HandleMarkAllRead).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/notifications/{id:[0-9]+}/read", s.alertHandlers.HandleMarkRead).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/alert-connectors", s.connectorHandlers.HandleListConnectors).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/alert-connectors", s.connectorHandlers.HandleCreateConnector).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/alert-connectors/{id:[0-9]+}", s.connectorHandlers.HandleUpdateConnector).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/alert-connectors/{id:[0-9]+}", s.connectorHandlers.HandleDeleteConnector).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/alert-connectors/{id:[0-9]+}/test", s.connectorHandlers.HandleTestConnector).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/incidents", s.statusHandlers.HandleListIncidents).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/incidents", s.statusHandlers.HandleCreateIncident).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/incidents/{id:[0-9]+}", s.statusHandlers.HandleUpdateIncident).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/incidents/{id:[0-9]+}", s.statusHandlers.HandleDeleteIncident).Methods("DELETE", "OPTIONS")
+}
+
+// backupService backs up the database, when there is one, and uploads
+// backups to the configured bucket
+func (s *Server) backupService() *uma_services.BackupService {
+	var dump uma_services.BackupFunc
+	if s.db != nil {
+		dump = func(ctx context.Context, w io.Writer) error { return database.Dump(ctx, s.config, s.db, w) }
+	}
+	var store uma_services.BackupStore
+	if storage := s.config.BackupStorage(); storage.Enabled() {
+		store = &objectstore.S3{
+			Endpoint:        storage.Endpoint,
+			Region:          storage.Region,
+			Bucket:          storage.Bucket,
+			Prefix:          storage.Prefix,
+			AccessKeyID:     storage.AccessKeyID,
+			SecretAccessKey: storage.SecretAccessKey,
+			// The outbound client's proxy and CAs, without its timeout,
+			// which a large upload outlasts
+			Client: &http.Client{Transport: s.httpClient.Transport},
+		}
+	}
+	return uma_services.NewBackupService(dump, database.BackupExtension(s.config.Storage), store, s.clock)
 }
 
 // Initialize handlers
 func (s *Server) initializeHandlers() {
-	s.userHandlers = apphandlers.NewUserHandlers(s.userRepo, s.nwcRepo, s.logger, s.config.JWTSecret)
-	s.eventHandlers = apphandlers.NewEventHandlers(s.eventRepo, s.paymentRepo, s.ticketRepo, s.umaService, s.umaRepo, s.logger, s.config)
-	s.ticketHandlers = apphandlers.NewTicketHandlers(s.ticketRepo, s.eventRepo, s.paymentRepo, s.umaRepo, s.nwcRepo, s.umaService, s.logger, s.config.Domain)
-	s.paymentHandlers = apphandlers.NewPaymentHandlers(s.paymentRepo, s.ticketRepo, s.umaService, s.lightsparkClient, s.logger)
-	s.umaHandlers = apphandlers.NewUmaHandlers(s.paymentRepo, s.umaService, s.logger, s.config.Domain, s.config.UMASigningPrivKeyHex)
+	templates := uma_services.NewTemplateService(s.templateRepo, s.eventRepo, s.logger)
+	notifier := uma_services.NewLogNotifier(s.userRepo, templates, s.logger)
+	// Guests are sent their claim link when a ticket of theirs is paid,
+	// whichever handler marks it
+	guests := uma_services.NewGuestService(s.userRepo, s.accountClaimRepo, s.ticketRepo, s.eventRepo, notifier, s.config.Domain, s.clock, s.logger)
+	s.ticketRepo = guests.Tickets(s.ticketRepo)
+	emails := uma_services.NewEmailChangeService(s.emailChangeRepo, notifier, s.config.Domain, s.clock, s.logger)
+	passwords := uma_services.NewPasswordPolicyService(s.config.PasswordPolicy(), uma_services.PwnedPasswordsRangeURL, s.httpClient, s.logger)
+	lnurlAuth := uma_services.NewLNURLAuthService(s.lnurlAuthRepo, s.userRepo, s.config.Domain, s.clock, s.logger)
+	s.userHandlers = apphandlers.NewUserHandlers(s.userRepo, s.nwcRepo, guests, emails, lnurlAuth, passwords, s.logger, s.tokens)
+	s.lnurlAuthHandlers = apphandlers.NewLNURLAuthHandlers(lnurlAuth, s.tokens, s.logger)
+	s.supportHandlers = apphandlers.NewImpersonationHandlers(s.userRepo, s.tokens, s.config.IsAdmin, s.config.ImpersonationTTL, s.logger)
+	s.ledgerService = uma_services.NewLedgerService(s.ledgerRepo, s.clock, s.logger)
+	payouts := uma_services.NewPayoutService(s.ledgerService, s.profileRepo, s.affiliateRepo, s.paymentRepo, s.ticketRepo, s.umaService, s.httpClient, s.logger)
+	capacity := uma_services.NewCapacityService(s.eventRepo, s.ticketRepo, s.paymentRepo, s.ledgerService, notifier, s.logger)
+	eventInvoices := uma_services.NewEventInvoiceService(s.umaRepo, s.umaService, s.config.Domain, s.clock, s.logger)
+	pricing := uma_services.NewPricingService(s.pricingRuleRepo, s.eventRepo, s.clock, s.logger)
+	priceChanges := uma_services.NewPriceChangeService(s.ticketRepo, s.paymentRepo, s.addOnRepo, pricing, notifier, s.clock, s.logger)
+	s.eventHandlers = apphandlers.NewEventHandlers(s.eventRepo, s.paymentRepo, s.ticketRepo, s.umaService, s.umaRepo, s.translationRepo, s.relationRepo, s.profileRepo, capacity, eventInvoices, priceChanges, s.clock, s.logger, s.config)
+	reschedules := uma_services.NewRescheduleService(s.eventRepo, s.rescheduleRepo, s.ticketRepo, s.paymentRepo, s.ledgerService, notifier, s.adminAlerts, s.clock, s.logger)
+	s.rescheduleHandlers = apphandlers.NewRescheduleHandlers(reschedules, s.rescheduleRepo, s.ticketRepo, s.logger)
+	flex := uma_services.NewFlexService(s.addOnRepo, s.eventRepo, s.ticketRepo, s.paymentRepo, s.ledgerService, notifier, s.adminAlerts, s.clock, s.logger)
+	s.flexHandlers = apphandlers.NewFlexHandlers(flex, s.ticketRepo, s.logger)
+	s.cancellations = uma_services.NewCancellationService(s.eventRepo, s.cancelRepo, s.ticketRepo, s.paymentRepo, s.ledgerService, notifier, s.clock, s.logger)
+	s.cancelHandlers = apphandlers.NewCancellationHandlers(s.cancellations, s.cancelRepo, s.logger)
+	fraud := uma_services.NewFraudService(s.fraudRepo, s.userRepo, s.settingsService, s.config.GeoCountryHeader, s.clock, s.logger)
+	fees := uma_services.NewFeeService(s.feeRepo, s.config.PlatformFee())
+	affiliates := uma_services.NewAffiliateService(s.affiliateRepo, s.ledgerService, s.ledgerRepo, s.config.AffiliateBasisPoints, s.config.Domain, s.logger)
+	s.gifts = uma_services.NewGiftService(s.giftRepo, s.userRepo, s.ticketRepo, s.eventRepo, notifier, s.config.Domain, s.clock, s.logger)
+	s.reservations = uma_services.NewReservationService(s.paymentRepo, s.ticketRepo, s.logger)
+	s.retention = uma_services.NewRetentionService(s.archiveRepo, s.config.RetentionPolicy(), s.clock, s.logger)
+	s.archiveHandlers = apphandlers.NewArchiveHandlers(s.archiveRepo, s.logger)
+	s.consoleHandlers = apphandlers.NewConsoleHandlers(s.consoleRepo, s.logger)
+	s.backupHandlers = apphandlers.NewBackupHandlers(s.backupService(), s.logger)
+	switch s.config.ExchangeRateSource {
+	case config.ExchangeRateCoinbase:
+		source := uma_services.NewCoinbaseRateSource(uma_services.CoinbaseSpotURL, s.httpClient)
+		s.exchangeRates = uma_services.NewExchangeRateService(s.exchangeRateRepo, source, s.config.FiatCurrency, s.clock, s.logger)
+	case config.ExchangeRateFixed:
+		source := uma_services.FixedRateSource(s.config.ExchangeRateFixedRate)
+		s.exchangeRates = uma_services.NewExchangeRateService(s.exchangeRateRepo, source, s.config.FiatCurrency, s.clock, s.logger)
+	}
+	s.ticketHandlers = apphandlers.NewTicketHandlers(s.ticketRepo, s.eventRepo, s.paymentRepo, s.umaRepo, s.nwcRepo, s.addOnRepo, s.formFieldRepo, s.accessRepo, s.attestRepo, s.receiptRepo, s.orderRepo, s.umaService, s.settingsService, fraud, notifier, fees, s.ticketWatcher, guests, s.checkIns, affiliates, s.gifts, pricing, s.config.PriceLimits(), s.config.LegacyTicketCodesUntil, s.clock, s.logger, s.config.Domain)
+	s.ticketQRHandlers = apphandlers.NewTicketQRHandlers(s.ticketRepo, s.paymentRepo, s.ledgerRepo, s.ticketSigner, s.checkIns, s.clock, s.logger)
+	wallet := uma_services.NewWalletService(s.walletClaimRepo, s.jwtSecret, s.config.Domain, s.clock)
+	s.walletHandlers = apphandlers.NewWalletHandlers(s.ticketRepo, s.eventRepo, wallet, s.checkIns, s.clock, s.logger)
+	s.checkInHandlers = apphandlers.NewCheckInHandlers(s.checkIns, s.eventRepo, s.logger)
+	s.scanners = uma_services.NewScannerService(s.scannerRepo, s.eventRepo, s.clock, s.logger)
+	s.scannerHandlers = apphandlers.NewScannerHandlers(s.scanners, s.eventRepo, s.logger)
+	comps := uma_services.NewCompService(s.userRepo, guests, s.eventRepo, s.ticketRepo, notifier, s.logger)
+	s.compHandlers = apphandlers.NewCompHandlers(comps, s.logger)
+	s.holdHandlers = apphandlers.NewHoldHandlers(s.holdRepo, s.eventRepo, s.logger)
+	s.relatedHandlers = apphandlers.NewRelatedEventHandlers(s.relationRepo, s.eventRepo, s.logger)
+	s.organizerHandlers = apphandlers.NewOrganizerHandlers(s.profileRepo, s.translationRepo, payouts, s.clock, s.logger)
+	seo := uma_services.NewSEOService(s.eventRepo, s.profileRepo, s.config.Domain, s.logger)
+	s.seoHandlers = apphandlers.NewSEOHandlers(seo, s.logger)
+	feeds := uma_services.NewFeedService(s.eventRepo, s.config.Domain, s.clock)
+	s.feedHandlers = apphandlers.NewFeedHandlers(feeds, s.logger)
+	links := uma_services.NewShortLinkService(s.linkRepo, s.eventRepo, s.config.Domain, s.logger)
+	s.linkHandlers = apphandlers.NewShortLinkHandlers(links, s.eventRepo, s.ticketRepo, s.logger)
+	s.paymentHandlers = apphandlers.NewPaymentHandlers(s.paymentRepo, s.ticketRepo, s.eventRepo, s.umaRepo, s.umaService, s.lightsparkClient, s.breaker, s.webhookQueue, s.adminAlerts, s.webhookSigningKey, s.logger)
+	s.settingsHandlers = apphandlers.NewSettingsHandlers(s.settingsService, s.eventRepo, s.logger)
+	s.debugHandlers = apphandlers.NewDebugHandlers(s.requestCapture, s.logger)
+	s.deadLetterHandlers = apphandlers.NewDeadLetterHandlers(s.webhookQueue, s.logger)
+	s.fraudHandlers = apphandlers.NewFraudHandlers(fraud, s.logger)
+	s.alertHandlers = apphandlers.NewAdminNotificationHandlers(s.adminAlerts, s.logger)
+	s.connectorHandlers = apphandlers.NewAlertConnectorHandlers(s.connectorRepo, s.alertConnectors, s.logger)
+	// Memory storage has no database to ping
+	var ping func() error
+	if s.db != nil {
+		ping = s.db.Ping
+	}
+	status := uma_services.NewStatusService(ping, s.readOnly, s.breaker, s.webhookQueue, s.connectorRepo, s.incidentRepo, s.clock, s.logger)
+	s.statusHandlers = apphandlers.NewStatusHandlers(status, s.incidentRepo, s.clock, s.logger)
+	s.addOnHandlers = apphandlers.NewAddOnHandlers(s.addOnRepo, s.eventRepo, s.logger)
+	s.formFieldHandlers = apphandlers.NewFormFieldHandlers(s.formFieldRepo, s.eventRepo, s.ticketRepo, s.userRepo, s.logger)
+	s.accessHandlers = apphandlers.NewEventAccessHandlers(s.accessRepo, s.eventRepo, s.clock, s.logger)
+	s.attestHandlers = apphandlers.NewAttestationHandlers(s.attestRepo, s.eventRepo, s.logger)
+	s.translateHandlers = apphandlers.NewEventTranslationHandlers(s.translationRepo, s.eventRepo, s.logger)
+	s.templateHandlers = apphandlers.NewTemplateHandlers(s.templateRepo, s.userRepo, templates, s.logger)
+	s.receiptHandlers = apphandlers.NewReceiptHandlers(s.receiptRepo, s.paymentRepo, s.ticketRepo, s.eventRepo, s.userRepo, s.logger, s.config.Domain)
+	s.orderHandlers = apphandlers.NewOrderHandlers(s.orderRepo, s.ticketRepo, s.eventRepo, s.paymentRepo, s.addOnRepo, s.receiptRepo, s.logger)
+	s.feeHandlers = apphandlers.NewFeeHandlers(fees, s.feeRepo, s.userRepo, s.config.FiatCurrency, s.logger)
+	s.rateHandlers = apphandlers.NewExchangeRateHandlers(s.exchangeRateRepo, s.config.FiatCurrency, s.logger)
+	cashuPayments := uma_services.NewCashuService(s.cashuRepo, s.paymentRepo, s.ticketRepo, s.settingsService, cashu.NewClient(s.httpClient), s.config.CashuMints, s.logger)
+	s.cashuHandlers = apphandlers.NewCashuHandlers(cashuPayments, s.logger)
+	s.analyticsHandlers = apphandlers.NewAnalyticsHandlers(s.orderRepo, s.addOnRepo, s.logger)
+	s.affiliateHandlers = apphandlers.NewAffiliateHandlers(affiliates, s.affiliateRepo, s.userRepo, payouts, s.logger)
+	s.giftHandlers = apphandlers.NewGiftHandlers(s.gifts, s.logger)
+	s.pricingHandlers = apphandlers.NewPricingHandlers(s.pricingRuleRepo, s.eventRepo, pricing, s.config.PriceLimits(), s.logger)
+	s.taxHandlers = apphandlers.NewTaxHandlers(s.paymentRepo, s.logger)
+	s.accountingHandlers = apphandlers.NewAccountingHandlers(s.receiptRepo, s.logger)
+	s.ledgerHandlers = apphandlers.NewLedgerHandlers(s.ledgerService, s.ledgerRepo, s.userRepo, s.affiliateRepo, payouts, s.logger)
+	disputes := uma_services.NewDisputeService(s.disputeRepo, s.paymentRepo, s.ticketRepo, s.ledgerService, notifier, s.logger)
+	s.disputeHandlers = apphandlers.NewDisputeHandlers(disputes, s.disputeRepo, s.logger)
+	s.metricsHandlers = apphandlers.NewMetricsHandlers(s.metrics, s.logger)
+	s.umaHandlers = apphandlers.NewUmaHandlers(s.paymentRepo, s.eventRepo, s.umaRepo, s.umaService, s.clock, s.logger, s.config.Domain, s.config.UMASigningPrivKeyHex)
 }
 
-// CORS middleware
+// CORS middleware. Credentials are allowed only when the allowed origins
+// are listed; with "*" every site would be reflected with credentials.
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return handlers.CORS(
-		handlers.AllowedOrigins([]string{
-			"http://localhost:3000",                          // Local development
-			"https://" + s.config.Domain,                    // Production (CloudFront)
-		}),
+	options := []handlers.CORSOption{
+		handlers.AllowedOriginValidator(middleware.NewOriginValidator(s.config.CORSAllowedOrigins)),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "Origin"}),
-		handlers.AllowCredentials(),
-	)(next)
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "Origin", middleware.ChallengeHeader}),
+	}
+	if !middleware.AllowsAnyOrigin(s.config.CORSAllowedOrigins) {
+		options = append(options, handlers.AllowCredentials())
+	}
+	return handlers.CORS(options...)(next)
 }
 
 // Logging middleware
@@ -229,16 +1165,13 @@ func (s *Server) adminMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Check if user is admin
-		isAdmin := false
-		for _, adminEmail := range s.config.AdminEmails {
-			if user.Email == adminEmail {
-				isAdmin = true
-				break
-			}
+		// Impersonation tokens never reach admin routes, whoever they act as
+		if middleware.GetImpersonationFromContext(r.Context()) != nil {
+			middleware.WriteError(w, http.StatusForbidden, "Admin privileges required")
+			return
 		}
 
-		if !isAdmin {
+		if !s.config.IsAdmin(user.Email) {
 			middleware.WriteError(w, http.StatusForbidden, "Admin privileges required")
 			return
 		}
@@ -249,18 +1182,21 @@ func (s *Server) adminMiddleware(next http.Handler) http.Handler {
 
 // Health check handler
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Check database connection
-	if err := s.db.Ping(); err != nil {
-		s.logger.Error("Health check failed - database connection error", "error", err)
-		middleware.WriteError(w, http.StatusServiceUnavailable, "Database connection failed")
-		return
+	// Check database connection; memory storage has none
+	if s.db != nil {
+		if err := s.db.Ping(); err != nil {
+			s.logger.Error("Health check failed - database connection error", "error", err)
+			middleware.WriteError(w, http.StatusServiceUnavailable, "Database connection failed")
+			return
+		}
 	}
 
 	middleware.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": s.clock.Now().Format(time.RFC3339),
 		"service":   "tickets-by-uma",
 		"version":   "1.0.0",
+		"read_only": s.readOnly,
 	})
 }
 
@@ -283,3 +1219,8 @@ func (rw *responseWriter) WriteHeader(code int) {
 func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"tickets-by-uma/config"
+)
+
+// TLSSetup holds what main needs to serve HTTPS directly
+type TLSSetup struct {
+	// Config is the server TLS configuration, including HTTP/2 via ALPN
+	Config *tls.Config
+	// CertFile and KeyFile are passed to ListenAndServeTLS; both are empty
+	// for autocert, whose certificates come from Config.GetCertificate
+	CertFile string
+	KeyFile  string
+	// RedirectHandler redirects plain HTTP to HTTPS and, with autocert,
+	// answers ACME HTTP-01 challenges
+	RedirectHandler http.Handler
+}
+
+// NewTLSSetup builds the TLS configuration for the configured TLS mode. It
+// returns nil when TLS is terminated elsewhere.
+func NewTLSSetup(cfg *config.Config) *TLSSetup {
+	if !cfg.TLSEnabled() {
+		return nil
+	}
+
+	tlsConfig := modernTLSConfig()
+	redirect := httpsRedirectHandler(cfg.Port)
+	setup := &TLSSetup{
+		Config:          tlsConfig,
+		CertFile:        cfg.TLSCertFile,
+		KeyFile:         cfg.TLSKeyFile,
+		RedirectHandler: redirect,
+	}
+
+	if cfg.TLSMode == config.TLSModeAutocert {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Domain),
+			Cache:      autocert.DirCache(cfg.TLSCacheDir),
+			Email:      cfg.ACMEEmail,
+		}
+		tlsConfig.GetCertificate = manager.GetCertificate
+		// TLS-ALPN-01 challenges are answered on the HTTPS port itself
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, "acme-tls/1")
+		setup.CertFile, setup.KeyFile = "", ""
+		setup.RedirectHandler = manager.HTTPHandler(redirect)
+	}
+
+	return setup
+}
+
+// modernTLSConfig allows TLS 1.2+ with forward-secret AEAD cipher suites
+// only. TLS 1.3 suites are not configurable and are always secure.
+func modernTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+}
+
+// httpsRedirectHandler permanently redirects to the same URL over HTTPS.
+// Non-standard HTTPS ports are kept in the target host.
+func httpsRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
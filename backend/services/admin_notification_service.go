@@ -0,0 +1,325 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tickets-by-uma/models"
+	"tickets-by-uma/pubsub"
+	"tickets-by-uma/repositories"
+)
+
+// AdminNotifier tells the admins about something that needs their
+// attention, such as a refund to send
+type AdminNotifier interface {
+	NotifyAdmins(notification *models.AdminNotification)
+}
+
+// adminFeed is the waitList key of the one admin notification feed
+const adminFeed = 0
+
+// AdminNotificationService keeps the admins' notification feed: new sales,
+// events selling out, refunds buyers asked for, payouts, fraud flags,
+// webhooks the queue gave up on and the payment backend going down, so
+// organizers need not watch the logs. Most are noticed by wrapping the
+// repositories that store them. Open feed streams are woken by new
+// notifications; with a Broadcaster also by other instances'.
+type AdminNotificationService struct {
+	repo      repositories.AdminNotificationRepository
+	eventRepo repositories.EventRepository
+	waiters   waitList
+	bus       Broadcaster
+	forward   []AdminNotifier
+	logger    *slog.Logger
+}
+
+// NewAdminNotificationService creates the admin notification service.
+// eventRepo names the events notifications are about.
+func NewAdminNotificationService(repo repositories.AdminNotificationRepository, eventRepo repositories.EventRepository, logger *slog.Logger) *AdminNotificationService {
+	return &AdminNotificationService{repo: repo, eventRepo: eventRepo, logger: logger}
+}
+
+// Broadcast shares new notifications with other instances through bus.
+// Call it before the service is used.
+func (s *AdminNotificationService) Broadcast(bus Broadcaster) {
+	s.bus = bus
+	bus.Subscribe(pubsub.TopicAdminNotification, func(int) { s.waiters.wake(adminFeed) })
+}
+
+// Forward passes each notification stored on to another notifier, such as
+// the alert connectors. Only the instance that stores a notification
+// forwards it. Call it before the service is used.
+func (s *AdminNotificationService) Forward(to AdminNotifier) {
+	s.forward = append(s.forward, to)
+}
+
+// NotifyAdmins stores a notification, wakes the feed's streams and forwards
+// it. One that cannot be stored is logged rather than returned, so it never
+// fails what it reports on.
+func (s *AdminNotificationService) NotifyAdmins(notification *models.AdminNotification) {
+	if err := s.repo.Create(notification); err != nil {
+		s.logger.Error("Failed to store admin notification", "kind", notification.Kind, "title", notification.Title, "error", err)
+		return
+	}
+	s.waiters.wake(adminFeed)
+	if s.bus != nil {
+		s.bus.Publish(pubsub.TopicAdminNotification, notification.ID)
+	}
+	for _, to := range s.forward {
+		to.NotifyAdmins(notification)
+	}
+}
+
+// BackendDown notifies that the payment backend stopped answering, as when
+// its circuit breaker opens
+func (s *AdminNotificationService) BackendDown() {
+	s.NotifyAdmins(&models.AdminNotification{
+		Kind:    models.AdminNotificationBackendDown,
+		Title:   "Payment backend down",
+		Message: "The Lightning payment backend keeps failing; payments are paused until it recovers",
+	})
+}
+
+// Subscribe returns a channel that is closed at the next notification, and
+// a function that stops waiting. Subscribe before reading the feed so a
+// notification stored in between is not missed.
+func (s *AdminNotificationService) Subscribe() (<-chan struct{}, func()) {
+	return s.waiters.subscribe(adminFeed)
+}
+
+// Feed returns a page of notifications newest first, only unread ones when
+// unreadOnly is set, with the number unread overall
+func (s *AdminNotificationService) Feed(unreadOnly bool, limit, offset int) (*models.AdminNotificationFeed, error) {
+	notifications, err := s.repo.List(unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnread()
+	if err != nil {
+		return nil, err
+	}
+	return &models.AdminNotificationFeed{Notifications: notifications, UnreadCount: unread}, nil
+}
+
+// After returns up to limit notifications stored after the one with ID
+// afterID, oldest first
+func (s *AdminNotificationService) After(afterID, limit int) ([]models.AdminNotification, error) {
+	return s.repo.ListAfter(afterID, limit)
+}
+
+// LatestID returns the ID of the newest notification, 0 when there is none
+func (s *AdminNotificationService) LatestID() (int, error) {
+	latest, err := s.repo.List(false, 1, 0)
+	if err != nil || len(latest) == 0 {
+		return 0, err
+	}
+	return latest[0].ID, nil
+}
+
+// MarkRead marks a notification read, returning repositories.ErrNotFound
+// when there is no such notification
+func (s *AdminNotificationService) MarkRead(id, adminID int) (*models.AdminNotification, error) {
+	return s.repo.MarkRead(id, adminID)
+}
+
+// MarkAllRead marks every unread notification read and returns how many
+// it marked
+func (s *AdminNotificationService) MarkAllRead(adminID int) (int, error) {
+	return s.repo.MarkAllRead(adminID)
+}
+
+// refundRequested is the notification of a buyer cancelling a paid ticket
+// to eventTitle for a refund, whose funds must be sent back. how says what
+// allowed it.
+func refundRequested(ticket *models.Ticket, eventTitle, how string) *models.AdminNotification {
+	return &models.AdminNotification{
+		Kind:     models.AdminNotificationRefundRequested,
+		Title:    "Refund requested",
+		Message:  fmt.Sprintf("Ticket %s to %s was cancelled %s; its payment is to be refunded", ticket.TicketCode, eventTitle, how),
+		EventID:  &ticket.EventID,
+		EntityID: &ticket.ID,
+	}
+}
+
+// eventTitle names an event for a notification, by ID when it cannot be read
+func (s *AdminNotificationService) eventTitle(eventID int) string {
+	event, err := s.eventRepo.GetByID(eventID)
+	if err != nil {
+		return fmt.Sprintf("event %d", eventID)
+	}
+	return event.Title
+}
+
+// Tickets wraps repo so tickets it marks paid are notified as sales, and the
+// ticket that sells an event out as well. Comps and free tickets are not
+// sales, though they count toward selling out.
+func (s *AdminNotificationService) Tickets(repo repositories.TicketRepository) repositories.TicketRepository {
+	return &notifiedTicketRepository{TicketRepository: repo, admins: s}
+}
+
+type notifiedTicketRepository struct {
+	repositories.TicketRepository
+	admins *AdminNotificationService
+}
+
+func (r *notifiedTicketRepository) Create(ticket *models.Ticket) error {
+	if err := r.TicketRepository.Create(ticket); err != nil {
+		return err
+	}
+	if ticket.PaymentStatus == models.TicketPaid {
+		r.admins.paid(ticket)
+	}
+	return nil
+}
+
+func (r *notifiedTicketRepository) Update(ticket *models.Ticket) error {
+	previous, err := r.TicketRepository.GetByID(ticket.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.TicketRepository.Update(ticket); err != nil {
+		return err
+	}
+	if previous.PaymentStatus != models.TicketPaid && ticket.PaymentStatus == models.TicketPaid {
+		r.admins.paid(ticket)
+	}
+	return nil
+}
+
+func (r *notifiedTicketRepository) UpdatePaymentStatus(id int, status string) error {
+	previous, err := r.TicketRepository.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.TicketRepository.UpdatePaymentStatus(id, status); err != nil {
+		return err
+	}
+	if previous.PaymentStatus != models.TicketPaid && status == models.TicketPaid {
+		r.admins.paid(previous)
+	}
+	return nil
+}
+
+func (s *AdminNotificationService) paid(ticket *models.Ticket) {
+	if !ticket.IsComp && ticket.AmountSats > 0 {
+		s.NotifyAdmins(&models.AdminNotification{
+			Kind:     models.AdminNotificationSale,
+			Title:    "New sale",
+			Message:  fmt.Sprintf("Ticket %s to %s sold for %d sats", ticket.TicketCode, s.eventTitle(ticket.EventID), ticket.AmountSats),
+			EventID:  &ticket.EventID,
+			EntityID: &ticket.ID,
+		})
+	}
+
+	availability, err := s.eventRepo.GetAvailability(ticket.EventID)
+	if err != nil {
+		s.logger.Warn("Failed to check whether event sold out", "event_id", ticket.EventID, "error", err)
+		return
+	}
+	// Only the ticket taking the last seat sells the event out
+	if availability.Capacity > 0 && availability.Sold == availability.Capacity {
+		s.NotifyAdmins(&models.AdminNotification{
+			Kind:     models.AdminNotificationSoldOut,
+			Title:    "Event sold out",
+			Message:  fmt.Sprintf("All %d tickets to %s are sold", availability.Capacity, s.eventTitle(ticket.EventID)),
+			EventID:  &ticket.EventID,
+			EntityID: &ticket.ID,
+		})
+	}
+}
+
+// FraudFlags wraps repo so purchases fraud checks held for review or
+// blocked are notified. Flags that only allowed the purchase are not.
+func (s *AdminNotificationService) FraudFlags(repo repositories.FraudFlagRepository) repositories.FraudFlagRepository {
+	return &notifiedFraudFlagRepository{FraudFlagRepository: repo, admins: s}
+}
+
+type notifiedFraudFlagRepository struct {
+	repositories.FraudFlagRepository
+	admins *AdminNotificationService
+}
+
+func (r *notifiedFraudFlagRepository) Create(flag *models.FraudFlag) error {
+	if err := r.FraudFlagRepository.Create(flag); err != nil {
+		return err
+	}
+	var title string
+	switch flag.Action {
+	case FraudActionReview:
+		title = "Purchase held for review"
+	case FraudActionBlock:
+		title = "Purchase blocked"
+	default:
+		return nil
+	}
+	rules := make([]string, len(flag.Signals))
+	for i, signal := range flag.Signals {
+		rules[i] = signal.Rule
+	}
+	r.admins.NotifyAdmins(&models.AdminNotification{
+		Kind:     models.AdminNotificationFraudFlag,
+		Title:    title,
+		Message:  fmt.Sprintf("User %d buying for %s tripped %s", flag.UserID, r.admins.eventTitle(flag.EventID), strings.Join(rules, ", ")),
+		EventID:  &flag.EventID,
+		EntityID: &flag.ID,
+	})
+	return nil
+}
+
+// Ledger wraps repo so payouts it posts are notified
+func (s *AdminNotificationService) Ledger(repo repositories.LedgerRepository) repositories.LedgerRepository {
+	return &notifiedLedgerRepository{LedgerRepository: repo, admins: s}
+}
+
+type notifiedLedgerRepository struct {
+	repositories.LedgerRepository
+	admins *AdminNotificationService
+}
+
+func (r *notifiedLedgerRepository) Post(entry *models.LedgerEntry) error {
+	if err := r.LedgerRepository.Post(entry); err != nil {
+		return err
+	}
+	if entry.Kind != models.LedgerEntryPayout {
+		return nil
+	}
+	var amountSats int64
+	for _, posting := range entry.Postings {
+		amountSats += posting.DebitSats
+	}
+	r.admins.NotifyAdmins(&models.AdminNotification{
+		Kind:     models.AdminNotificationPayout,
+		Title:    "Payout completed",
+		Message:  fmt.Sprintf("%s: %d sats (%s)", entry.Description, amountSats, entry.Reference),
+		EntityID: &entry.ID,
+	})
+	return nil
+}
+
+// WebhookEvents wraps repo so webhook events the queue gives up on are
+// notified. Failures it will retry are not.
+func (s *AdminNotificationService) WebhookEvents(repo repositories.WebhookEventRepository) repositories.WebhookEventRepository {
+	return &notifiedWebhookEventRepository{WebhookEventRepository: repo, admins: s}
+}
+
+type notifiedWebhookEventRepository struct {
+	repositories.WebhookEventRepository
+	admins *AdminNotificationService
+}
+
+func (r *notifiedWebhookEventRepository) Fail(id int, lastError string, retryAt *time.Time) error {
+	if err := r.WebhookEventRepository.Fail(id, lastError, retryAt); err != nil {
+		return err
+	}
+	if retryAt == nil {
+		r.admins.NotifyAdmins(&models.AdminNotification{
+			Kind:     models.AdminNotificationWebhookFailed,
+			Title:    "Webhook failed",
+			Message:  fmt.Sprintf("Gave up on webhook event %d: %s", id, lastError),
+			EntityID: &id,
+		})
+	}
+	return nil
+}
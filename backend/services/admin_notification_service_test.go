@@ -0,0 +1,142 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestAdminNotificationService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	admins := NewAdminNotificationService(store.AdminNotifications(), store.Events(), logger)
+	tickets := admins.Tickets(store.Tickets())
+	flags := admins.FraudFlags(store.FraudFlags())
+	ledger := admins.Ledger(store.Ledger())
+	webhooks := admins.WebhookEvents(store.WebhookEvents())
+	forwarded := &recordingAdminNotifier{}
+	admins.Forward(forwarded)
+
+	event := &models.Event{Title: "Meetup", Capacity: 4, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	changed, stop := admins.Subscribe()
+	defer stop()
+
+	// Tickets paid on purchase or later are sales; comps and free tickets
+	// are not. The last seat sold sells the event out.
+	pending := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "SALE-1", PaymentStatus: models.TicketPending, AmountSats: 1000}
+	for _, ticket := range []*models.Ticket{
+		pending,
+		{EventID: event.ID, UserID: 2, TicketCode: "SALE-2", PaymentStatus: models.TicketPaid, AmountSats: 1200},
+		{EventID: event.ID, UserID: 3, TicketCode: "COMP-1", PaymentStatus: models.TicketPaid, AmountSats: 1000, IsComp: true},
+		{EventID: event.ID, UserID: 4, TicketCode: "FREE-1", PaymentStatus: models.TicketPaid},
+	} {
+		if err := tickets.Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected the sale to wake the feed's streams")
+	}
+	if err := tickets.UpdatePaymentStatus(pending.ID, models.TicketPaid); err != nil {
+		t.Fatal(err)
+	}
+	// Marking it paid again is no new sale
+	if err := tickets.UpdatePaymentStatus(pending.ID, models.TicketPaid); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only purchases held or blocked need an admin
+	for _, action := range []string{FraudActionAllow, FraudActionReview} {
+		flag := &models.FraudFlag{UserID: 5, EventID: event.ID, Action: action, Signals: models.FraudSignals{{Rule: "user_velocity", Action: action}}}
+		if err := flags.Create(flag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refund := &models.LedgerEntry{Kind: models.LedgerEntryRefund, Reference: "manual", OccurredAt: clk.Now(), Postings: []models.LedgerPosting{
+		{Account: accounting.AccountTicketSales, DebitSats: 100},
+		{Account: accounting.AccountWallet, CreditSats: 100},
+	}}
+	payout := &models.LedgerEntry{Kind: models.LedgerEntryPayout, Reference: "lnbc-payout", Description: "Payout to organizer 9", OccurredAt: clk.Now(), Postings: []models.LedgerPosting{
+		{Account: accounting.OrganizerPayable(9), DebitSats: 5000},
+		{Account: accounting.AccountWallet, CreditSats: 5000},
+	}}
+	for _, entry := range []*models.LedgerEntry{refund, payout} {
+		if err := ledger.Post(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A webhook is only reported once the queue gives up on it
+	webhook := &models.WebhookEvent{Source: models.WebhookSourceLightspark, EventID: "evt-1", EventType: "PAYMENT_FINISHED", Payload: "{}"}
+	if err := webhooks.Enqueue(webhook); err != nil {
+		t.Fatal(err)
+	}
+	retryAt := clk.Now().Add(time.Minute)
+	if err := webhooks.Fail(webhook.ID, "timeout", &retryAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhooks.Fail(webhook.ID, "timeout", nil); err != nil {
+		t.Fatal(err)
+	}
+	admins.BackendDown()
+
+	feed, err := admins.After(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, notification := range feed {
+		kinds = append(kinds, notification.Kind)
+	}
+	if got, want := strings.Join(kinds, ","), "sale,sale,sold_out,fraud_flag,payout,webhook_failed,backend_down"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	if len(forwarded.notifications) != len(feed) {
+		t.Errorf("Expected every notification forwarded, got %d of %d", len(forwarded.notifications), len(feed))
+	}
+	if sale := feed[1]; *sale.EntityID != pending.ID || *sale.EventID != event.ID || sale.Message != "Ticket SALE-1 to Meetup sold for 1000 sats" {
+		t.Errorf("Unexpected sale notification %+v", sale)
+	}
+	if soldOut := feed[2]; *soldOut.EntityID != pending.ID || soldOut.Message != "All 4 tickets to Meetup are sold" {
+		t.Errorf("Unexpected sold out notification %+v", soldOut)
+	}
+	if feed[3].Title != "Purchase held for review" || !strings.Contains(feed[3].Message, "user_velocity") {
+		t.Errorf("Unexpected fraud notification %+v", feed[3])
+	}
+	if feed[4].Message != "Payout to organizer 9: 5000 sats (lnbc-payout)" || *feed[4].EntityID != payout.ID {
+		t.Errorf("Unexpected payout notification %+v", feed[4])
+	}
+
+	if latest, err := admins.LatestID(); err != nil || latest != feed[6].ID {
+		t.Errorf("Expected the latest ID %d, got %d (%v)", feed[6].ID, latest, err)
+	}
+	if _, err := admins.MarkRead(feed[0].ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	unread, err := admins.Feed(true, 2, 0)
+	if err != nil || unread.UnreadCount != 6 || len(unread.Notifications) != 2 || unread.Notifications[0].ID != feed[6].ID {
+		t.Errorf("Expected the latest unread page with 6 unread, got %+v (%v)", unread, err)
+	}
+}
+
+type recordingAdminNotifier struct {
+	notifications []models.AdminNotification
+}
+
+func (n *recordingAdminNotifier) NotifyAdmins(notification *models.AdminNotification) {
+	n.notifications = append(n.notifications, *notification)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+var (
+	// ErrAffiliateCode is returned for an affiliate code that cannot be used
+	ErrAffiliateCode = errors.New("code must be 3 to 40 letters, digits and dashes, not starting or ending with a dash")
+	// ErrAffiliateRate is returned for a commission outside 0 to 100%
+	ErrAffiliateRate = errors.New("basis_points must be between 0 and 10000")
+)
+
+// AffiliateService runs the affiliate program. Admins enroll users, who
+// share links carrying their code as the ref; orders placed with it are
+// attributed to them at the rate then in force. Their commission is booked
+// in the ledger along with each sale, reversed with refunds, and paid out
+// through the ledger like organizer payouts.
+type AffiliateService struct {
+	repo        repositories.AffiliateRepository
+	ledger      *LedgerService
+	ledgerRepo  repositories.LedgerRepository
+	basisPoints int64
+	domain      string
+	logger      *slog.Logger
+	// newCode generates codes; tests replace it to force collisions
+	newCode func() (string, error)
+}
+
+// NewAffiliateService creates an affiliate service enrolling affiliates at
+// basisPoints unless told otherwise, with links to https://domain
+func NewAffiliateService(repo repositories.AffiliateRepository, ledger *LedgerService, ledgerRepo repositories.LedgerRepository, basisPoints int64, domain string, logger *slog.Logger) *AffiliateService {
+	return &AffiliateService{
+		repo:        repo,
+		ledger:      ledger,
+		ledgerRepo:  ledgerRepo,
+		basisPoints: basisPoints,
+		domain:      domain,
+		logger:      logger,
+		newCode:     newShortCode,
+	}
+}
+
+// Enroll makes a user an affiliate with the code and rate given, or a
+// generated code and the default rate, paid out to payoutAddress, which the
+// caller has validated. It returns repositories.ErrConflict
+// when the user already is one or the code is taken.
+func (s *AffiliateService) Enroll(userID int, code *string, basisPoints *int64, payoutAddress string) (*models.Affiliate, error) {
+	affiliate := &models.Affiliate{UserID: userID, BasisPoints: s.basisPoints, Active: true, PayoutAddress: payoutAddress}
+	if err := applyAffiliateChanges(affiliate, code, basisPoints); err != nil {
+		return nil, err
+	}
+	if code != nil {
+		if err := s.repo.Create(affiliate); err != nil {
+			return nil, err
+		}
+	} else if err := s.createWithGeneratedCode(affiliate); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Affiliate enrolled", "affiliate_id", affiliate.ID, "user_id", userID, "basis_points", affiliate.BasisPoints)
+	return affiliate, nil
+}
+
+func (s *AffiliateService) createWithGeneratedCode(affiliate *models.Affiliate) error {
+	for attempt := 0; attempt < shortLinkAttempts; attempt++ {
+		code, err := s.newCode()
+		if err != nil {
+			return err
+		}
+		affiliate.Code = code
+		err = s.repo.Create(affiliate)
+		if !errors.Is(err, repositories.ErrConflict) {
+			return err
+		}
+		// The code or the user is taken; a user already enrolled fails
+		// every attempt
+		if _, err := s.repo.GetByUserID(affiliate.UserID); err == nil {
+			return repositories.ErrConflict
+		}
+	}
+	return errors.New("failed to generate an unused affiliate code")
+}
+
+// Update changes an affiliate's code, rate, status or validated payout
+// address. A new rate applies to orders placed from then on.
+func (s *AffiliateService) Update(id int, req models.AffiliateRequest) (*models.Affiliate, error) {
+	affiliate, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAffiliateChanges(affiliate, req.Code, req.BasisPoints); err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		affiliate.Active = *req.Active
+	}
+	if req.PayoutAddress != nil {
+		affiliate.PayoutAddress = *req.PayoutAddress
+	}
+	if err := s.repo.Update(affiliate); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Affiliate updated", "affiliate_id", id, "code", affiliate.Code, "basis_points", affiliate.BasisPoints, "active", affiliate.Active)
+	return affiliate, nil
+}
+
+func applyAffiliateChanges(affiliate *models.Affiliate, code *string, basisPoints *int64) error {
+	if code != nil {
+		normalized, err := normalizeShortCode(*code)
+		if err != nil {
+			return ErrAffiliateCode
+		}
+		affiliate.Code = normalized
+	}
+	if basisPoints != nil {
+		if *basisPoints < 0 || *basisPoints > config.MaxFeeBasisPoints {
+			return ErrAffiliateRate
+		}
+		affiliate.BasisPoints = *basisPoints
+	}
+	return nil
+}
+
+// Attribute returns the active affiliate whose code is ref, the ref of a
+// purchase by buyerID, or nil when there is none. Affiliates earn nothing
+// on their own purchases.
+func (s *AffiliateService) Attribute(ref string, buyerID int) (*models.Affiliate, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil, nil
+	}
+	affiliate, err := s.repo.GetByCode(ref)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !affiliate.Active || affiliate.UserID == buyerID {
+		return nil, nil
+	}
+	return affiliate, nil
+}
+
+// Reports returns every affiliate with their results and commission,
+// booking new sales in the ledger first
+func (s *AffiliateService) Reports() ([]models.AffiliateReport, error) {
+	if _, err := s.ledger.Sync(); err != nil {
+		return nil, err
+	}
+	reports, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		if err := s.complete(&reports[i]); err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// Report returns one affiliate's results and commission, booking new sales
+// in the ledger first
+func (s *AffiliateService) Report(id int) (*models.AffiliateReport, error) {
+	if _, err := s.ledger.Sync(); err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+	return report, s.complete(report)
+}
+
+// complete fills in a report's link and commission from the affiliate's
+// payable account, which is credited as commission accrues and debited as
+// it is paid out or refunded
+func (s *AffiliateService) complete(report *models.AffiliateReport) error {
+	report.Link = "https://" + s.domain + "/?ref=" + url.QueryEscape(report.Code)
+
+	account := accounting.AffiliatePayable(report.ID)
+	balance, err := s.ledgerRepo.GetBalance(account)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	paid, err := s.ledgerRepo.Debited(account, models.LedgerEntryPayout)
+	if err != nil {
+		return err
+	}
+	report.BalanceSats = balance.BalanceSats
+	report.PaidSats = paid
+	report.AccruedSats = balance.BalanceSats + paid
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestAffiliateService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	affiliates := NewAffiliateService(store.Affiliates(), ledger, store.Ledger(), 1000, "tickets.example", logger)
+
+	var users []*models.User
+	for _, name := range []string{"Promoter", "Buyer"} {
+		user := &models.User{Email: name + "@example.com", Name: name}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	promoter, buyer := users[0], users[1]
+
+	// Generated codes skip taken ones; the default rate applies
+	custom := "Taken22"
+	if _, err := affiliates.Enroll(buyer.ID, &custom, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{"taken22", "fresh22"}
+	affiliates.newCode = func() (string, error) {
+		code := codes[0]
+		codes = codes[1:]
+		return code, nil
+	}
+	affiliate, err := affiliates.Enroll(promoter.ID, nil, nil, "")
+	if err != nil || affiliate.Code != "fresh22" || affiliate.BasisPoints != 1000 || !affiliate.Active {
+		t.Fatalf("Expected an affiliate with an unused code, got %+v (%v)", affiliate, err)
+	}
+	affiliates.newCode = newShortCode
+	if _, err := affiliates.Enroll(promoter.ID, nil, nil, ""); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict enrolling a user twice, got %v", err)
+	}
+	bad, rate := "-x", int64(10001)
+	if _, err := affiliates.Update(affiliate.ID, models.AffiliateRequest{Code: &bad}); !errors.Is(err, ErrAffiliateCode) {
+		t.Errorf("Expected ErrAffiliateCode, got %v", err)
+	}
+	if _, err := affiliates.Update(affiliate.ID, models.AffiliateRequest{BasisPoints: &rate}); !errors.Is(err, ErrAffiliateRate) {
+		t.Errorf("Expected ErrAffiliateRate, got %v", err)
+	}
+
+	// Codes match ignoring case, but not the affiliate's own purchases or
+	// inactive affiliates
+	if found, err := affiliates.Attribute(" FRESH22 ", buyer.ID); err != nil || found == nil || found.ID != affiliate.ID {
+		t.Errorf("Expected the affiliate, got %+v (%v)", found, err)
+	}
+	for _, ref := range []string{"", "unknown"} {
+		if found, err := affiliates.Attribute(ref, buyer.ID); err != nil || found != nil {
+			t.Errorf("Expected no affiliate for %q, got %+v (%v)", ref, found, err)
+		}
+	}
+	if found, err := affiliates.Attribute("fresh22", promoter.ID); err != nil || found != nil {
+		t.Errorf("Expected no commission on the affiliate's own purchase, got %+v", found)
+	}
+	inactive := false
+	if _, err := affiliates.Update(affiliate.ID, models.AffiliateRequest{Active: &inactive}); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := affiliates.Attribute("fresh22", buyer.ID); found != nil {
+		t.Errorf("Expected no commission for an inactive affiliate, got %+v", found)
+	}
+
+	// Two referred sales of 1000 sats plus fees earn 10% each
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var payments []*models.Payment
+	for i := 0; i < 2; i++ {
+		order := &models.Order{UserID: buyer.ID, EventID: event.ID, AffiliateID: &affiliate.ID, CommissionBasisPoints: 1000}
+		if err := store.Orders().Create(order); err != nil {
+			t.Fatal(err)
+		}
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, OrderID: &order.ID, TicketCode: "AFF-" + strconv.Itoa(i), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-affiliate-" + strconv.Itoa(i), Amount: 1050, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Receipts().CreateLineItems([]models.PaymentLineItem{
+			{PaymentID: payment.ID, Kind: models.LineItemTicket, Quantity: 1, UnitAmountSats: 1000, AmountSats: 1000},
+			{PaymentID: payment.ID, Kind: models.LineItemFee, Quantity: 1, UnitAmountSats: 50, AmountSats: 50},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+
+	report, err := affiliates.Report(affiliate.ID)
+	if err != nil || report.AccruedSats != 200 || report.BalanceSats != 200 || report.TicketsSold != 2 ||
+		report.Link != "https://tickets.example/?ref=fresh22" {
+		t.Fatalf("Expected 200 sats accrued on two sales, got %+v (%v)", report, err)
+	}
+
+	// Refunds take back the commission; payouts are limited to what is owed
+	if _, err := ledger.RecordRefund(payments[0].ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.RecordAffiliatePayout(affiliate.ID, 150, ""); !errors.Is(err, ErrPayoutExceedsBalance) {
+		t.Errorf("Expected ErrPayoutExceedsBalance, got %v", err)
+	}
+	payout, err := ledger.RecordAffiliatePayout(affiliate.ID, 60, "tx-1")
+	if err != nil || payout.AffiliateID == nil || *payout.AffiliateID != affiliate.ID || payout.Kind != models.LedgerEntryPayout {
+		t.Fatalf("Expected an affiliate payout, got %+v (%v)", payout, err)
+	}
+
+	reports, err := affiliates.Reports()
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("Expected both affiliates, got %+v (%v)", reports, err)
+	}
+	for _, report := range reports {
+		if report.ID == affiliate.ID && (report.AccruedSats != 100 || report.PaidSats != 60 || report.BalanceSats != 40) {
+			t.Errorf("Expected 100 sats accrued, 60 paid and 40 owed, got %+v", report)
+		}
+		if report.ID != affiliate.ID && (report.AccruedSats != 0 || report.Link != "https://tickets.example/?ref=taken22") {
+			t.Errorf("Expected nothing accrued without sales, got %+v", report)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// TelegramAPIURL is the Telegram Bot API the Telegram connectors post through
+const TelegramAPIURL = "https://api.telegram.org"
+
+// telegramToken matches a Telegram bot token, the bot's ID and its secret
+var telegramToken = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+
+// AlertService posts admin notifications to the Slack, Discord and Telegram
+// channels of the alert connectors whose events include the notification's
+// kind. Posts are sent in the background and never fail what they report
+// on; each connector keeps the outcome of its last one.
+type AlertService struct {
+	repo        repositories.AlertConnectorRepository
+	client      *http.Client
+	telegramAPI string
+	clock       clock.Clock
+	logger      *slog.Logger
+	wg          sync.WaitGroup
+}
+
+// NewAlertService creates the alert service, posting with client through
+// the Telegram Bot API at telegramAPI
+func NewAlertService(repo repositories.AlertConnectorRepository, client *http.Client, telegramAPI string, clk clock.Clock, logger *slog.Logger) *AlertService {
+	return &AlertService{repo: repo, client: client, telegramAPI: telegramAPI, clock: clk, logger: logger}
+}
+
+// NotifyAdmins posts notification to every active connector subscribed to
+// its kind
+func (s *AlertService) NotifyAdmins(notification *models.AdminNotification) {
+	n := *notification
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		connectors, err := s.repo.List(true)
+		if err != nil {
+			s.logger.Error("Failed to fetch alert connectors", "kind", n.Kind, "error", err)
+			return
+		}
+		for i := range connectors {
+			connector := &connectors[i]
+			if !slices.Contains(connector.Events, n.Kind) {
+				continue
+			}
+			err := s.post(connector, n.Title, n.Message)
+			s.record(connector, err)
+		}
+	}()
+}
+
+// Test posts a test message to connector's channel and records the outcome
+func (s *AlertService) Test(connector *models.AlertConnector) error {
+	err := s.post(connector, "Test alert", fmt.Sprintf("Alerts from connector %q are working", connector.Name))
+	s.record(connector, err)
+	return err
+}
+
+// Wait blocks until the posts in flight are sent
+func (s *AlertService) Wait() {
+	s.wg.Wait()
+}
+
+func (s *AlertService) record(connector *models.AlertConnector, postErr error) {
+	lastError := ""
+	if postErr != nil {
+		lastError = postErr.Error()
+		s.logger.Warn("Failed to post alert", "connector_id", connector.ID, "kind", connector.Kind, "error", postErr)
+	}
+	if err := s.repo.RecordDelivery(connector.ID, s.clock.Now(), lastError); err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		s.logger.Error("Failed to record alert delivery", "connector_id", connector.ID, "error", err)
+	}
+}
+
+// post sends title and message to connector's channel
+func (s *AlertService) post(connector *models.AlertConnector, title, message string) error {
+	var endpoint string
+	var body any
+	switch connector.Kind {
+	case models.AlertConnectorSlack:
+		endpoint = connector.Target
+		body = map[string]string{"text": "*" + title + "*\n" + message}
+	case models.AlertConnectorDiscord:
+		endpoint = connector.Target
+		body = map[string]string{"content": "**" + title + "**\n" + message}
+	case models.AlertConnectorTelegram:
+		endpoint = s.telegramAPI + "/bot" + connector.Target + "/sendMessage"
+		body = map[string]string{"chat_id": connector.ChatID, "text": title + "\n" + message}
+	default:
+		return fmt.Errorf("unknown connector kind %q", connector.Kind)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return errors.New("invalid connector target")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds the webhook's or bot's secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("post failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ValidateAlertConnector checks a connector's name, that its target is a
+// webhook URL or bot token of its kind, and that its events are admin
+// notification kinds
+func ValidateAlertConnector(c *models.AlertConnector) error {
+	if name := strings.TrimSpace(c.Name); name == "" || len(name) > 100 {
+		return fmt.Errorf("name must be 1 to 100 characters")
+	}
+	switch c.Kind {
+	case models.AlertConnectorSlack:
+		if !strings.HasPrefix(c.Target, "https://hooks.slack.com/") {
+			return fmt.Errorf("target must be a Slack incoming webhook URL")
+		}
+	case models.AlertConnectorDiscord:
+		if !strings.HasPrefix(c.Target, "https://discord.com/api/webhooks/") && !strings.HasPrefix(c.Target, "https://discordapp.com/api/webhooks/") {
+			return fmt.Errorf("target must be a Discord webhook URL")
+		}
+	case models.AlertConnectorTelegram:
+		if !telegramToken.MatchString(c.Target) {
+			return fmt.Errorf("target must be a Telegram bot token")
+		}
+		if strings.TrimSpace(c.ChatID) == "" || len(c.ChatID) > 100 {
+			return fmt.Errorf("chat_id is required for Telegram connectors")
+		}
+	default:
+		return fmt.Errorf("kind must be one of %s, %s, %s", models.AlertConnectorSlack, models.AlertConnectorDiscord, models.AlertConnectorTelegram)
+	}
+	if c.Kind != models.AlertConnectorTelegram && c.ChatID != "" {
+		return fmt.Errorf("chat_id is only for Telegram connectors")
+	}
+	if len(c.Events) == 0 {
+		return fmt.Errorf("events must name at least one notification kind")
+	}
+	for _, event := range c.Events {
+		if !slices.Contains(models.AdminNotificationKinds, event) {
+			return fmt.Errorf("events must be among %s", strings.Join(models.AdminNotificationKinds, ", "))
+		}
+	}
+	return nil
+}
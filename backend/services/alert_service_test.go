@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAlertService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+
+	var mu sync.Mutex
+	posts := map[string]string{}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "unreachable") {
+			return nil, errors.New("connection refused")
+		}
+		data, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		posts[req.URL.String()] = string(data)
+		mu.Unlock()
+		status := http.StatusOK
+		if strings.Contains(req.URL.Path, "revoked") {
+			status = http.StatusNotFound
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	})}
+	alerts := NewAlertService(store.AlertConnectors(), client, "https://telegram.test", clk, logger)
+
+	connectors := []*models.AlertConnector{
+		{Name: "Sales", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/T/B/sales", Events: models.AlertEvents{models.AdminNotificationSale}, IsActive: true},
+		{Name: "Ops", Kind: models.AlertConnectorDiscord, Target: "https://discord.com/api/webhooks/1/ops", Events: models.AlertEvents{models.AdminNotificationSale, models.AdminNotificationBackendDown}, IsActive: true},
+		{Name: "On call", Kind: models.AlertConnectorTelegram, Target: "123:bot-secret", ChatID: "-100", Events: models.AlertEvents{models.AdminNotificationSale}, IsActive: true},
+		{Name: "Paused", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/T/B/paused", Events: models.AlertEvents{models.AdminNotificationSale}},
+		{Name: "Old", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/T/B/revoked", Events: models.AlertEvents{models.AdminNotificationSale}, IsActive: true},
+		{Name: "Down", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/T/B/unreachable", Events: models.AlertEvents{models.AdminNotificationSale}, IsActive: true},
+	}
+	for _, connector := range connectors {
+		if err := ValidateAlertConnector(connector); err != nil {
+			t.Fatalf("Expected %s valid, got %v", connector.Name, err)
+		}
+		if err := store.AlertConnectors().Create(connector); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alerts.NotifyAdmins(&models.AdminNotification{Kind: models.AdminNotificationSale, Title: "New sale", Message: "Ticket T-1 to Meetup sold for 1000 sats"})
+	alerts.Wait()
+
+	want := map[string]string{
+		"https://hooks.slack.com/services/T/B/sales":          `{"text":"*New sale*\nTicket T-1 to Meetup sold for 1000 sats"}`,
+		"https://discord.com/api/webhooks/1/ops":              `{"content":"**New sale**\nTicket T-1 to Meetup sold for 1000 sats"}`,
+		"https://telegram.test/bot123:bot-secret/sendMessage": `{"chat_id":"-100","text":"New sale\nTicket T-1 to Meetup sold for 1000 sats"}`,
+		"https://hooks.slack.com/services/T/B/revoked":        `{"text":"*New sale*\nTicket T-1 to Meetup sold for 1000 sats"}`,
+	}
+	if len(posts) != len(want) {
+		t.Errorf("Expected %d posts, got %v", len(want), posts)
+	}
+	for endpoint, body := range want {
+		if posts[endpoint] != body {
+			t.Errorf("Expected %s posted to %s, got %q", body, endpoint, posts[endpoint])
+		}
+	}
+
+	for i, wantError := range []string{"", "", "", "", "post returned 404", "post failed: connection refused"} {
+		connector, _ := store.AlertConnectors().GetByID(connectors[i].ID)
+		if connector.LastError != wantError {
+			t.Errorf("Expected %s's last error %q, got %q", connector.Name, wantError, connector.LastError)
+		}
+		if (connector.LastSentAt != nil) != (i < 3) {
+			t.Errorf("Expected only connectors posted to record when, got %s at %v", connector.Name, connector.LastSentAt)
+		}
+		// The webhook URL is a secret
+		if strings.Contains(connector.LastError, "hooks.slack.com") {
+			t.Errorf("Expected the error to leave out the target, got %q", connector.LastError)
+		}
+	}
+
+	// Connectors only post the kinds they subscribe to
+	posts = map[string]string{}
+	alerts.NotifyAdmins(&models.AdminNotification{Kind: models.AdminNotificationBackendDown, Title: "Payment backend down", Message: "Payments are paused"})
+	alerts.Wait()
+	if len(posts) != 1 || posts["https://discord.com/api/webhooks/1/ops"] == "" {
+		t.Errorf("Expected only the ops connector posted, got %v", posts)
+	}
+
+	if err := alerts.Test(connectors[4]); err == nil {
+		t.Error("Expected the revoked webhook's test to fail")
+	}
+	if err := alerts.Test(connectors[0]); err != nil {
+		t.Errorf("Expected the test post to succeed, got %v", err)
+	}
+
+	for _, invalid := range []models.AlertConnector{
+		{Name: "", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/x", Events: models.AlertEvents{"sale"}},
+		{Name: "Evil", Kind: models.AlertConnectorSlack, Target: "https://example.com/hook", Events: models.AlertEvents{"sale"}},
+		{Name: "Evil", Kind: models.AlertConnectorDiscord, Target: "http://discord.com/api/webhooks/1/x", Events: models.AlertEvents{"sale"}},
+		{Name: "Bot", Kind: models.AlertConnectorTelegram, Target: "123:secret", Events: models.AlertEvents{"sale"}},
+		{Name: "Bot", Kind: models.AlertConnectorTelegram, Target: "not a token", ChatID: "1", Events: models.AlertEvents{"sale"}},
+		{Name: "Chat", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/x", ChatID: "1", Events: models.AlertEvents{"sale"}},
+		{Name: "Quiet", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/x"},
+		{Name: "Typo", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/x", Events: models.AlertEvents{"sales"}},
+		{Name: "Email", Kind: "email", Target: "ops@example.com", Events: models.AlertEvents{"sale"}},
+	} {
+		if err := ValidateAlertConnector(&invalid); err == nil {
+			t.Errorf("Expected %+v invalid", invalid)
+		}
+	}
+}
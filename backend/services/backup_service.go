@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	ErrBackupUnavailable = errors.New("backups need a database; memory storage has none")
+	ErrBackupNoStorage   = errors.New("no backup storage is configured")
+)
+
+// BackupStore keeps uploaded backups, e.g. an S3 bucket
+type BackupStore interface {
+	// Put stores size bytes of body as name and returns where it went
+	Put(ctx context.Context, name string, body io.Reader, size int64) (string, error)
+}
+
+// BackupFunc writes a consistent backup of the database to w
+type BackupFunc func(ctx context.Context, w io.Writer) error
+
+// BackupService takes database backups for operators without managed
+// ones, streaming them to the caller or uploading them to a BackupStore
+type BackupService struct {
+	dump      BackupFunc
+	extension string
+	store     BackupStore
+	clock     clock.Clock
+}
+
+// NewBackupService creates a backup service. dump is nil without a
+// database, and store nil without backup storage; extension is that of
+// the files dump writes.
+func NewBackupService(dump BackupFunc, extension string, store BackupStore, clk clock.Clock) *BackupService {
+	return &BackupService{dump: dump, extension: extension, store: store, clock: clk}
+}
+
+// Enabled reports whether there is a database to back up
+func (s *BackupService) Enabled() bool {
+	return s.dump != nil
+}
+
+// Filename names a backup taken now
+func (s *BackupService) Filename() string {
+	return "tickets-" + s.clock.Now().UTC().Format("20060102T150405Z") + "." + s.extension
+}
+
+// Write writes a backup to w
+func (s *BackupService) Write(ctx context.Context, w io.Writer) error {
+	if !s.Enabled() {
+		return ErrBackupUnavailable
+	}
+	return s.dump(ctx, w)
+}
+
+// Upload takes a backup and uploads it to backup storage. The backup is
+// spooled to a temporary file first, since the upload needs its size.
+func (s *BackupService) Upload(ctx context.Context) (*models.Backup, error) {
+	if !s.Enabled() {
+		return nil, ErrBackupUnavailable
+	}
+	if s.store == nil {
+		return nil, ErrBackupNoStorage
+	}
+
+	backup := &models.Backup{Filename: s.Filename(), CreatedAt: s.clock.Now()}
+	spool, err := os.CreateTemp("", "tickets-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	if err := s.dump(ctx, spool); err != nil {
+		return nil, err
+	}
+	if backup.Bytes, err = spool.Seek(0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if backup.Location, err = s.store.Put(ctx, backup.Filename, spool, backup.Bytes); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
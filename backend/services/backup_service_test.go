@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+// memoryBackupStore keeps uploads in memory
+type memoryBackupStore struct {
+	objects map[string]string
+}
+
+func (s *memoryBackupStore) Put(ctx context.Context, name string, body io.Reader, size int64) (string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) != size {
+		return "", errors.New("size does not match the body")
+	}
+	s.objects[name] = string(data)
+	return "memory://" + name, nil
+}
+
+func TestBackupService(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	dump := func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "backup of the database")
+		return err
+	}
+	store := &memoryBackupStore{objects: map[string]string{}}
+
+	backups := NewBackupService(dump, "dump", store, clk)
+	if name := backups.Filename(); name != "tickets-20261016T120000Z.dump" {
+		t.Errorf("Unexpected filename %s", name)
+	}
+	var streamed strings.Builder
+	if err := backups.Write(context.Background(), &streamed); err != nil || streamed.String() != "backup of the database" {
+		t.Errorf("Expected the backup streamed, got %q (%v)", streamed.String(), err)
+	}
+
+	backup, err := backups.Upload(context.Background())
+	if err != nil {
+		t.Fatal("Upload failed:", err)
+	}
+	if backup.Location != "memory://tickets-20261016T120000Z.dump" || backup.Bytes != int64(len("backup of the database")) {
+		t.Errorf("Unexpected backup %+v", backup)
+	}
+	if store.objects[backup.Filename] != "backup of the database" {
+		t.Errorf("Expected the backup uploaded, got %v", store.objects)
+	}
+
+	failing := NewBackupService(func(ctx context.Context, w io.Writer) error { return errors.New("pg_dump failed") }, "dump", store, clk)
+	clk.Advance(time.Hour)
+	if _, err := failing.Upload(context.Background()); err == nil || len(store.objects) != 1 {
+		t.Errorf("Expected a failed dump not to be uploaded, got %v %v", err, store.objects)
+	}
+
+	if _, err := NewBackupService(dump, "dump", nil, clk).Upload(context.Background()); !errors.Is(err, ErrBackupNoStorage) {
+		t.Errorf("Expected ErrBackupNoStorage, got %v", err)
+	}
+	memory := NewBackupService(nil, "dump", store, clk)
+	if memory.Enabled() || !errors.Is(memory.Write(context.Background(), io.Discard), ErrBackupUnavailable) {
+		t.Error("Expected backups unavailable without a database")
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// cancellationBatchSize is how many tickets one pass of a cancellation
+// handles, so a large event is worked off over several passes
+const cancellationBatchSize = 100
+
+// CancellationService cancels events. The event stops selling at once and
+// a cancellation job is recorded; the job then cancels the event's tickets
+// in batches, refunding paid ones through the ledger and voiding unpaid
+// ones, and tells each buyer. Disputed tickets are left to their dispute.
+// A job whose pass cancelled nothing fails and can be retried by cancelling
+// the event again.
+type CancellationService struct {
+	eventRepo   repositories.EventRepository
+	repo        repositories.EventCancellationRepository
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	ledger      *LedgerService
+	notifier    Notifier
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+// NewCancellationService creates a cancellation service. ledger and
+// notifier may be nil.
+func NewCancellationService(eventRepo repositories.EventRepository, repo repositories.EventCancellationRepository, ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, ledger *LedgerService, notifier Notifier, clk clock.Clock, logger *slog.Logger) *CancellationService {
+	return &CancellationService{
+		eventRepo:   eventRepo,
+		repo:        repo,
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		ledger:      ledger,
+		notifier:    notifier,
+		clock:       clk,
+		logger:      logger,
+	}
+}
+
+// Cancel cancels the event and queues the cancellation of its tickets. It
+// returns repositories.ErrNotFound for an unknown event and
+// repositories.ErrConflict when the event is already cancelled, unless its
+// cancellation failed, in which case the job is run again.
+func (s *CancellationService) Cancel(eventID int, reason string, cancelledBy *int) (*models.EventCancellation, error) {
+	if _, err := s.eventRepo.GetByID(eventID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByEventID(eventID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Status != models.CancellationFailed {
+			return nil, repositories.ErrConflict
+		}
+		existing.Status, existing.Failed, existing.LastError = models.CancellationRunning, 0, ""
+		if err := s.repo.Update(existing); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Event cancellation retried", "event_id", eventID, "cancellation_id", existing.ID)
+		return existing, nil
+	}
+
+	// An event cancelled without a job was interrupted before recording it
+	if err := s.eventRepo.Cancel(eventID, s.clock.Now()); err != nil && !errors.Is(err, repositories.ErrConflict) {
+		return nil, err
+	}
+	open, err := s.openTickets(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("event %d cancelled but its tickets could not be listed: %w", eventID, err)
+	}
+	cancellation := &models.EventCancellation{
+		EventID:     eventID,
+		Reason:      reason,
+		Status:      models.CancellationRunning,
+		Total:       len(open),
+		CancelledBy: cancelledBy,
+	}
+	if err := s.repo.Create(cancellation); err != nil {
+		return nil, fmt.Errorf("event %d cancelled but its tickets were not queued: %w", eventID, err)
+	}
+	s.logger.Warn("Event cancelled", "event_id", eventID, "cancellation_id", cancellation.ID, "tickets", cancellation.Total)
+	return cancellation, nil
+}
+
+// Watch works off running cancellations every interval until ctx is done
+func (s *CancellationService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ProcessRunning(); err != nil {
+				s.logger.Error("Failed to process event cancellations", "error", err)
+			}
+		}
+	}
+}
+
+// ProcessRunning runs one pass of each running cancellation and returns how
+// many tickets were cancelled
+func (s *CancellationService) ProcessRunning() (int, error) {
+	running, err := s.repo.ListRunning()
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for i := range running {
+		n, err := s.process(&running[i])
+		if err != nil {
+			s.logger.Error("Failed to process event cancellation", "event_id", running[i].EventID, "cancellation_id", running[i].ID, "error", err)
+		}
+		cancelled += n
+	}
+	return cancelled, nil
+}
+
+// process cancels the next batch of a cancellation's tickets and saves its
+// progress. The job is done once no ticket is left, and fails when a pass
+// cancels none of the tickets it tried.
+func (s *CancellationService) process(cancellation *models.EventCancellation) (int, error) {
+	event, err := s.eventRepo.GetByID(cancellation.EventID)
+	if err != nil {
+		return 0, err
+	}
+	open, err := s.openTickets(event.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := open
+	if len(batch) > cancellationBatchSize {
+		batch = batch[:cancellationBatchSize]
+	}
+	cancelled, failed := 0, 0
+	cancellation.LastError = ""
+	reference := fmt.Sprintf("cancellation of event %d", event.ID)
+	for i := range batch {
+		ticket := &batch[i]
+		wasPaid := ticket.PaymentStatus == "paid"
+		if err := cancelTicket(s.ticketRepo, s.paymentRepo, s.ledger, ticket, reference); err != nil {
+			s.logger.Error("Failed to cancel ticket of cancelled event", "ticket_id", ticket.ID, "event_id", event.ID, "error", err)
+			failed++
+			cancellation.LastError = err.Error()
+			continue
+		}
+		cancelled++
+		if wasPaid {
+			cancellation.Refunded++
+		} else {
+			cancellation.Voided++
+		}
+		s.notify(ticket, event.Title)
+	}
+
+	remaining := len(open) - cancelled
+	cancellation.Total = cancellation.Refunded + cancellation.Voided + remaining
+	cancellation.Failed = failed
+	switch {
+	case remaining == 0:
+		now := s.clock.Now()
+		cancellation.Status, cancellation.CompletedAt = models.CancellationDone, &now
+		s.logger.Info("Event cancellation done", "event_id", event.ID, "cancellation_id", cancellation.ID,
+			"refunded", cancellation.Refunded, "voided", cancellation.Voided)
+	case cancelled == 0:
+		cancellation.Status = models.CancellationFailed
+		s.logger.Error("Event cancellation failed", "event_id", event.ID, "cancellation_id", cancellation.ID,
+			"remaining", remaining, "error", cancellation.LastError)
+	}
+	return cancelled, s.repo.Update(cancellation)
+}
+
+// openTickets returns the event's tickets still holding a seat, leaving out
+// disputed ones
+func (s *CancellationService) openTickets(eventID int) ([]models.Ticket, error) {
+	tickets, err := s.ticketRepo.GetByEventID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	var open []models.Ticket
+	for _, ticket := range tickets {
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review":
+			open = append(open, ticket)
+		}
+	}
+	return open, nil
+}
+
+func (s *CancellationService) notify(ticket *models.Ticket, title string) {
+	if s.notifier == nil {
+		return
+	}
+	notification := i18n.Notification{Key: i18n.EventCancelled, Args: []any{title, ticket.TicketCode}, EventID: ticket.EventID}
+	if err := s.notifier.NotifyUser(ticket.UserID, notification); err != nil {
+		s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+	}
+}
+
+// cancelTicket cancels a ticket and its payment, booking the refund of a
+// paid payment under reference as owed to the buyer until an admin sends
+// it. A refund already booked, or a sale the ledger never booked, is not
+// an error.
+func cancelTicket(ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, ledger *LedgerService, ticket *models.Ticket, reference string) error {
+	payment, err := paymentRepo.GetByTicketID(ticket.ID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	if payment != nil {
+		if payment.Status == "paid" && ledger != nil {
+			_, err := ledger.OweRefund(payment.ID, reference)
+			if err != nil && !errors.Is(err, repositories.ErrConflict) && !errors.Is(err, ErrNotPosted) {
+				return fmt.Errorf("failed to refund ticket %d: %w", ticket.ID, err)
+			}
+		}
+		if err := paymentRepo.UpdateStatus(payment.ID, "cancelled"); err != nil {
+			return err
+		}
+	}
+
+	ticket.PaymentStatus = "cancelled"
+	return ticketRepo.Update(ticket)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestCancellationService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	notifier := &recordingNotifier{}
+	cancellations := NewCancellationService(store.Events(), store.EventCancellations(), store.Tickets(), store.Payments(), ledger, notifier, clk, logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []*models.Ticket
+	for i, status := range []string{"paid", "paid", "pending", models.TicketDisputed, "failed"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: i + 1, TicketCode: "END-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		paymentStatus := status
+		if status == models.TicketDisputed {
+			paymentStatus = "paid"
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-end-" + strconv.Itoa(i), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, paymentStatus); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	if _, err := cancellations.Cancel(event.ID+100, "", nil); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown event, got %v", err)
+	}
+
+	adminID := 9
+	cancellation, err := cancellations.Cancel(event.ID, "Venue closed", &adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The disputed and failed tickets are not queued
+	if cancellation.Status != models.CancellationRunning || cancellation.Total != 3 || *cancellation.CancelledBy != adminID {
+		t.Errorf("Unexpected cancellation %+v", cancellation)
+	}
+	if stored, _ := store.Events().GetByID(event.ID); stored.IsActive || stored.CancelledAt == nil {
+		t.Errorf("Expected the event to stop selling, got %+v", stored)
+	}
+	if _, err := cancellations.Cancel(event.ID, "", nil); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict cancelling twice, got %v", err)
+	}
+
+	if n, err := cancellations.ProcessRunning(); err != nil || n != 3 {
+		t.Fatalf("Expected three tickets cancelled, got %d (%v)", n, err)
+	}
+	done, _ := store.EventCancellations().GetByEventID(event.ID)
+	if done.Status != models.CancellationDone || done.Refunded != 2 || done.Voided != 1 || done.Failed != 0 || done.CompletedAt == nil {
+		t.Errorf("Expected the cancellation done, got %+v", done)
+	}
+	for i, want := range []string{"cancelled", "cancelled", "cancelled", models.TicketDisputed, "failed"} {
+		if ticket, _ := store.Tickets().GetByID(tickets[i].ID); ticket.PaymentStatus != want {
+			t.Errorf("Expected ticket %d %s, got %s", i, want, ticket.PaymentStatus)
+		}
+	}
+	if issues, err := ledger.Check(); err != nil || len(issues) != 0 {
+		t.Errorf("Expected a consistent ledger, got %+v (%v)", issues, err)
+	}
+	wantSubjects := "Event cancelled,Event cancelled,Event cancelled"
+	if got := strings.Join(notifier.subjects, ","); got != wantSubjects {
+		t.Errorf("Expected notifications %s, got %s", wantSubjects, got)
+	}
+
+	// A finished job is left alone
+	if n, err := cancellations.ProcessRunning(); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to cancel, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// CapacityConflictError is returned when an event's capacity would fall
+// below the seats its tickets hold and they cannot be cancelled to fit
+type CapacityConflictError struct {
+	Conflict models.CapacityConflict
+}
+
+func (e *CapacityConflictError) Error() string {
+	return fmt.Sprintf("capacity %d is below the %d sold and %d pending tickets",
+		e.Conflict.RequestedCapacity, e.Conflict.Sold, e.Conflict.Pending)
+}
+
+// CapacityService checks capacity changes against the tickets holding
+// seats and, when asked, cancels the newest of them to make a reduced
+// capacity fit. Paid tickets are refunded through the ledger; pending and
+// held tickets are cancelled before they are charged. Disputed tickets are
+// never cancelled, as their funds are already contested.
+type CapacityService struct {
+	eventRepo   repositories.EventRepository
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	ledger      *LedgerService
+	notifier    Notifier
+	logger      *slog.Logger
+}
+
+// NewCapacityService creates a capacity service. ledger and notifier may be
+// nil.
+func NewCapacityService(eventRepo repositories.EventRepository, ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, ledger *LedgerService, notifier Notifier, logger *slog.Logger) *CapacityService {
+	return &CapacityService{
+		eventRepo:   eventRepo,
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		ledger:      ledger,
+		notifier:    notifier,
+		logger:      logger,
+	}
+}
+
+// Plan returns the tickets to cancel for the event to fit capacity, newest
+// first, which is none when it already fits. Unless refundNewest is set,
+// or when too few tickets can be cancelled, it returns a
+// *CapacityConflictError instead.
+func (s *CapacityService) Plan(eventID, capacity int, refundNewest bool) ([]models.Ticket, error) {
+	availability, err := s.eventRepo.GetAvailability(eventID)
+	if err != nil {
+		return nil, err
+	}
+	excess := availability.Sold + availability.Pending - capacity
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	tickets, err := s.ticketRepo.GetByEventID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	var cancellable []models.Ticket
+	disputed := 0
+	for _, ticket := range tickets {
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review":
+			cancellable = append(cancellable, ticket)
+		case models.TicketDisputed:
+			disputed++
+		}
+	}
+
+	conflict := &CapacityConflictError{Conflict: models.CapacityConflict{
+		RequestedCapacity: capacity,
+		Sold:              availability.Sold,
+		Pending:           availability.Pending,
+		Disputed:          disputed,
+		Excess:            excess,
+	}}
+	if !refundNewest || len(cancellable) < excess {
+		return nil, conflict
+	}
+
+	sort.SliceStable(cancellable, func(i, j int) bool {
+		if !cancellable[i].CreatedAt.Equal(cancellable[j].CreatedAt) {
+			return cancellable[i].CreatedAt.After(cancellable[j].CreatedAt)
+		}
+		return cancellable[i].ID > cancellable[j].ID
+	})
+	return cancellable[:excess], nil
+}
+
+// Cancel cancels tickets returned by Plan once the reduced capacity is
+// saved, refunding the paid ones, and notifies their buyers. It stops at
+// the first ticket that cannot be cancelled; a refund left unbooked is
+// reported by the ledger check.
+func (s *CapacityService) Cancel(event *models.Event, tickets []models.Ticket) error {
+	for i := range tickets {
+		ticket := &tickets[i]
+		wasPaid := ticket.PaymentStatus == "paid"
+
+		reference := fmt.Sprintf("capacity reduction of event %d", event.ID)
+		if err := cancelTicket(s.ticketRepo, s.paymentRepo, s.ledger, ticket, reference); err != nil {
+			return err
+		}
+		s.logger.Warn("Ticket cancelled by capacity reduction", "ticket_id", ticket.ID, "event_id", event.ID, "was_paid", wasPaid)
+
+		if wasPaid {
+			s.notify(ticket, i18n.TicketRefunded, event.Title, ticket.TicketCode)
+		} else {
+			s.notify(ticket, i18n.PurchaseRejected, event.Title)
+		}
+	}
+	return nil
+}
+
+func (s *CapacityService) notify(ticket *models.Ticket, key string, args ...any) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.NotifyUser(ticket.UserID, i18n.Notification{Key: key, Args: args, EventID: ticket.EventID}); err != nil {
+		s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestCapacityService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	notifier := &recordingNotifier{}
+	capacity := NewCapacityService(store.Events(), store.Tickets(), store.Payments(), ledger, notifier, logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	// Oldest first: two paid, one disputed, one pending, one paid, one failed
+	var tickets []*models.Ticket
+	var payments []*models.Payment
+	for i, status := range []string{"paid", "paid", models.TicketDisputed, "pending", "paid", "failed"} {
+		clk.Advance(time.Minute)
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "CAP-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		paymentStatus := status
+		if status == models.TicketDisputed {
+			paymentStatus = "paid"
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-cap-" + strconv.Itoa(i), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, paymentStatus); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+		payments = append(payments, payment)
+	}
+
+	// Five seats are held, so a capacity of five fits as it is
+	if cancel, err := capacity.Plan(event.ID, 5, false); err != nil || len(cancel) != 0 {
+		t.Errorf("Expected capacity 5 to fit, got %v (%v)", cancel, err)
+	}
+
+	var conflict *CapacityConflictError
+	if _, err := capacity.Plan(event.ID, 3, false); !errors.As(err, &conflict) {
+		t.Fatalf("Expected a capacity conflict, got %v", err)
+	}
+	want := models.CapacityConflict{RequestedCapacity: 3, Sold: 3, Pending: 2, Disputed: 1, Excess: 2}
+	if conflict.Conflict != want {
+		t.Errorf("Expected %+v, got %+v", want, conflict.Conflict)
+	}
+	// Only four tickets can go, as the disputed one stays
+	if _, err := capacity.Plan(event.ID, 0, true); !errors.As(err, &conflict) || conflict.Conflict.Excess != 5 {
+		t.Errorf("Expected a conflict when the disputed ticket would have to go, got %v", err)
+	}
+
+	cancel, err := capacity.Plan(event.ID, 3, true)
+	if err != nil || len(cancel) != 2 || cancel[0].ID != tickets[4].ID || cancel[1].ID != tickets[3].ID {
+		t.Fatalf("Expected the newest paid and pending tickets, got %+v (%v)", cancel, err)
+	}
+	if err := capacity.Cancel(event, cancel); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 4} {
+		ticket, _ := store.Tickets().GetByID(tickets[i].ID)
+		payment, _ := store.Payments().GetByID(payments[i].ID)
+		if ticket.PaymentStatus != "cancelled" || payment.Status != "cancelled" {
+			t.Errorf("Expected ticket %d and its payment cancelled, got %s and %s", i, ticket.PaymentStatus, payment.Status)
+		}
+	}
+	if _, err := store.Ledger().GetPaymentEntry(models.LedgerEntryRefund, payments[4].ID); err != nil {
+		t.Errorf("Expected the paid ticket to be refunded, got %v", err)
+	}
+	if _, err := store.Ledger().GetPaymentEntry(models.LedgerEntryRefund, payments[3].ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected no refund for the pending ticket, got %v", err)
+	}
+	if issues, err := ledger.Check(); err != nil || len(issues) != 0 {
+		t.Errorf("Expected a consistent ledger, got %+v (%v)", issues, err)
+	}
+	if availability, _ := store.Events().GetAvailability(event.ID); availability.Sold+availability.Pending != 3 {
+		t.Errorf("Expected three seats held, got %+v", availability)
+	}
+
+	wantSubjects := "Ticket refunded,Ticket purchase cancelled"
+	if got := strings.Join(notifier.subjects, ","); got != wantSubjects {
+		t.Errorf("Expected notifications %s, got %s", wantSubjects, got)
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"tickets-by-uma/cashu"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// FeatureCashu is the feature flag (feature.cashu) that turns on Cashu
+// payments
+const FeatureCashu = "cashu"
+
+// Cashu payment errors
+var (
+	ErrCashuDisabled   = errors.New("cashu payments are not enabled")
+	ErrCashuMint       = errors.New("tokens of this mint are not accepted")
+	ErrCashuNotPending = errors.New("invoice is not awaiting payment")
+	ErrCashuInProgress = errors.New("a cashu payment of this invoice is already in progress")
+	ErrCashuQuote      = errors.New("mint quoted a different amount than the invoice")
+)
+
+// CashuAmountError is returned when a token does not hold exactly the
+// invoice amount plus the mint's fee reserve. Proofs beyond that would be
+// lost to the mint, as no change is taken back.
+type CashuAmountError struct {
+	Amount models.CashuAmount
+}
+
+func (e *CashuAmountError) Error() string {
+	return fmt.Sprintf("token holds %d sats but the invoice needs exactly %d including the mint's fee reserve",
+		e.Amount.TokenSats, e.Amount.RequiredSats)
+}
+
+// CashuService pays ticket invoices with Cashu ecash. The token is melted
+// at its mint, which pays the invoice to our node over Lightning; the
+// ticket is marked paid as soon as the mint returns a preimage matching
+// the invoice, or otherwise when the node reports the payment as for any
+// other invoice. Only tokens of the configured mints are accepted.
+//
+// Tokens are bearer instruments and are never stored or logged.
+type CashuService struct {
+	repo     repositories.CashuRedemptionRepository
+	payments repositories.PaymentRepository
+	tickets  repositories.TicketRepository
+	settings *SettingsService
+	client   *cashu.Client
+	mints    map[string]bool
+	logger   *slog.Logger
+}
+
+// NewCashuService creates a Cashu service melting tokens of mints with
+// client. Payments are accepted while the feature flag is on in settings.
+func NewCashuService(
+	repo repositories.CashuRedemptionRepository,
+	payments repositories.PaymentRepository,
+	tickets repositories.TicketRepository,
+	settings *SettingsService,
+	client *cashu.Client,
+	mints []string,
+	logger *slog.Logger,
+) *CashuService {
+	allowed := make(map[string]bool, len(mints))
+	for _, mint := range mints {
+		allowed[normalizeMint(mint)] = true
+	}
+	return &CashuService{
+		repo:     repo,
+		payments: payments,
+		tickets:  tickets,
+		settings: settings,
+		client:   client,
+		mints:    allowed,
+		logger:   logger,
+	}
+}
+
+// Enabled reports whether Cashu payments are on and any mint is trusted
+func (s *CashuService) Enabled() bool {
+	return len(s.mints) > 0 && s.settings.FeatureEnabled(FeatureCashu, false)
+}
+
+// Redeem pays the ticket invoice bolt11 with token. The returned
+// redemption is paid when the mint paid the invoice, or pending when the
+// mint has not finished; the payment is then settled by the node's report.
+// Refusals by the mint, such as spent proofs, come back as *cashu.MintError.
+func (s *CashuService) Redeem(ctx context.Context, bolt11, token string) (*models.CashuRedemption, error) {
+	if !s.Enabled() {
+		return nil, ErrCashuDisabled
+	}
+	payment, err := s.payments.GetByBolt11(bolt11)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != "pending" {
+		return nil, ErrCashuNotPending
+	}
+	decoded, err := cashu.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	if !s.mints[normalizeMint(decoded.Mint)] {
+		return nil, ErrCashuMint
+	}
+
+	// A buyer leaving must not abandon a melt the mint may be carrying out
+	ctx = context.WithoutCancel(ctx)
+	quote, err := s.client.MeltQuote(ctx, decoded.Mint, bolt11)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get melt quote: %w", err)
+	}
+	if quote.Amount != uint64(payment.Amount) {
+		return nil, ErrCashuQuote
+	}
+	total, _ := decoded.Amount()
+	if required := quote.Amount + quote.FeeReserve; total != required {
+		return nil, &CashuAmountError{Amount: models.CashuAmount{RequiredSats: int64(required), TokenSats: int64(total)}}
+	}
+
+	redemption := &models.CashuRedemption{
+		PaymentID:      payment.ID,
+		MintURL:        decoded.Mint,
+		QuoteID:        quote.Quote,
+		AmountSats:     int64(quote.Amount),
+		FeeReserveSats: int64(quote.FeeReserve),
+		TokenSats:      int64(total),
+	}
+	if err := s.repo.Create(redemption); errors.Is(err, repositories.ErrConflict) {
+		return nil, ErrCashuInProgress
+	} else if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.Melt(ctx, decoded.Mint, quote.Quote, decoded.Proofs)
+	var mintErr *cashu.MintError
+	if errors.As(err, &mintErr) {
+		s.finish(redemption, models.CashuFailed, nil, mintErr.Error())
+		return nil, err
+	}
+	if err != nil {
+		// The mint may still pay the invoice, so the redemption stays
+		// pending and the node's report settles the payment
+		s.logger.Warn("Cashu melt outcome unknown", "payment_id", payment.ID, "redemption_id", redemption.ID, "mint", decoded.Mint, "error", err)
+		return redemption, nil
+	}
+
+	switch {
+	case result.IsPaid():
+		// Only a preimage proving the invoice paid is kept
+		preimage := result.Preimage
+		if preimage != nil && PreimageMatches(*preimage, payment.PaymentHash) {
+			if err := s.settle(payment, *preimage); err != nil {
+				s.logger.Error("Failed to mark Cashu payment paid", "payment_id", payment.ID, "error", err)
+			}
+		} else {
+			s.logger.Warn("Mint paid without a matching preimage, awaiting the node's report", "payment_id", payment.ID, "redemption_id", redemption.ID)
+			preimage = nil
+		}
+		s.finish(redemption, models.CashuPaid, preimage, "")
+	case result.State == cashu.QuoteUnpaid:
+		s.finish(redemption, models.CashuFailed, nil, "mint did not pay the invoice")
+	}
+	return redemption, nil
+}
+
+// List returns redemptions newest first
+func (s *CashuService) List(limit, offset int) ([]models.CashuRedemption, error) {
+	return s.repo.List(limit, offset)
+}
+
+func (s *CashuService) finish(redemption *models.CashuRedemption, state string, preimage *string, errMsg string) {
+	if err := s.repo.Finish(redemption.ID, state, preimage, errMsg); err != nil {
+		s.logger.Error("Failed to record Cashu redemption outcome", "redemption_id", redemption.ID, "state", state, "error", err)
+		return
+	}
+	redemption.State, redemption.Preimage, redemption.Error = state, preimage, errMsg
+}
+
+// PreimageMatches reports whether the hex preimage hashes to the hex
+// payment hash of an invoice. A matching preimage proves the invoice paid.
+func PreimageMatches(preimage, paymentHash string) bool {
+	raw, err := hex.DecodeString(preimage)
+	if err != nil || paymentHash == "" {
+		return false
+	}
+	hash := sha256.Sum256(raw)
+	return strings.EqualFold(hex.EncodeToString(hash[:]), paymentHash)
+}
+
+// settle marks the payment and its ticket paid unless the node's report
+// already did
+func (s *CashuService) settle(payment *models.Payment, preimage string) error {
+	current, err := s.payments.GetByID(payment.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status == "paid" {
+		return nil
+	}
+	if err := s.payments.UpdatePreimage(payment.ID, preimage); err != nil {
+		return err
+	}
+	if err := s.payments.UpdateStatus(payment.ID, "paid"); err != nil {
+		return err
+	}
+	s.logger.Info("Cashu payment succeeded", "payment_id", payment.ID, "ticket_id", payment.TicketID)
+	return s.tickets.UpdatePaymentStatus(payment.TicketID, "paid")
+}
+
+func normalizeMint(mint string) string {
+	return strings.TrimRight(strings.TrimSpace(mint), "/")
+}
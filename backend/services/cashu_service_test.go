@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/cashu"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// cashuToken serializes proofs of mint as a V3 token
+func cashuToken(mint string, amounts ...uint64) string {
+	proofs := make([]cashu.Proof, len(amounts))
+	for i, amount := range amounts {
+		proofs[i] = cashu.Proof{Amount: amount, ID: "009a1f293253e41e", Secret: "secret-" + strconv.Itoa(i), C: "02ab"}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"token": []map[string]interface{}{{"mint": mint, "proofs": proofs}},
+	})
+	return "cashuA" + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestCashuService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	settings := NewSettingsService(store.Settings(), logger)
+
+	preimage := strings.Repeat("42", 32)
+	hash := sha256.Sum256([]byte(strings.Repeat("\x42", 32)))
+	meltState := cashu.QuotePaid
+	melts := 0
+	mint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/melt/quote/bolt11":
+			var req struct{ Request string }
+			json.NewDecoder(r.Body).Decode(&req)
+			amount := 1000
+			if strings.Contains(req.Request, "odd") {
+				amount = 999
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"quote": "q-" + req.Request, "amount": amount, "fee_reserve": 4, "state": "UNPAID"})
+		case "/v1/melt/bolt11":
+			melts++
+			if meltState == "spent" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":11001,"detail":"Token already spent."}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"state": meltState, "payment_preimage": preimage})
+		}
+	}))
+	defer mint.Close()
+	service := NewCashuService(store.CashuRedemptions(), store.Payments(), store.Tickets(),
+		settings, cashu.NewClient(mint.Client()), []string{mint.URL + "/"}, logger)
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	invoice := func(bolt11 string) *models.Payment {
+		t.Helper()
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "CASHU-" + bolt11, PaymentStatus: "pending"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "inv-" + bolt11, Bolt11: bolt11,
+			PaymentHash: hex.EncodeToString(hash[:]), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		return payment
+	}
+	status := func(payment *models.Payment) (string, string) {
+		t.Helper()
+		stored, err := store.Payments().GetByID(payment.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ticket, err := store.Tickets().GetByID(payment.TicketID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.Status, ticket.PaymentStatus
+	}
+	ctx := context.Background()
+	payment := invoice("lnbc-cashu-1")
+	exact := cashuToken(mint.URL, 512, 256, 128, 64, 32, 8, 4)
+
+	if _, err := service.Redeem(ctx, payment.Bolt11, exact); !errors.Is(err, ErrCashuDisabled) {
+		t.Errorf("Expected Cashu off by default, got %v", err)
+	}
+	if err := settings.Set("feature."+FeatureCashu, json.RawMessage("true"), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		bolt11, token string
+		want          error
+	}{
+		"unknown invoice": {"lnbc-unknown", exact, repositories.ErrNotFound},
+		"bad token":       {payment.Bolt11, "cashuAnope", cashu.ErrInvalidToken},
+		"untrusted mint":  {payment.Bolt11, cashuToken("https://evil.example.com", 1004), ErrCashuMint},
+	} {
+		if _, err := service.Redeem(ctx, tc.bolt11, tc.token); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+	odd := invoice("lnbc-cashu-odd")
+	if _, err := service.Redeem(ctx, odd.Bolt11, exact); !errors.Is(err, ErrCashuQuote) {
+		t.Errorf("Expected a quote for another amount refused, got %v", err)
+	}
+	var amountErr *CashuAmountError
+	_, err := service.Redeem(ctx, payment.Bolt11, cashuToken(mint.URL, 1024))
+	if !errors.As(err, &amountErr) || amountErr.Amount.RequiredSats != 1004 || amountErr.Amount.TokenSats != 1024 {
+		t.Errorf("Expected an overpaying token refused with the amount needed, got %v", err)
+	}
+	if melts != 0 {
+		t.Fatalf("Expected nothing melted for refused tokens, got %d melts", melts)
+	}
+
+	// Spent proofs fail the redemption and leave the invoice payable
+	meltState = "spent"
+	var mintErr *cashu.MintError
+	if _, err := service.Redeem(ctx, payment.Bolt11, exact); !errors.As(err, &mintErr) || mintErr.Code != 11001 {
+		t.Errorf("Expected the mint's refusal, got %v", err)
+	}
+	if paymentStatus, ticketStatus := status(payment); paymentStatus != "pending" || ticketStatus != "pending" {
+		t.Errorf("Expected the invoice still pending, got %s/%s", paymentStatus, ticketStatus)
+	}
+
+	meltState = cashu.QuotePaid
+	redemption, err := service.Redeem(ctx, payment.Bolt11, exact)
+	if err != nil || redemption.State != models.CashuPaid || redemption.TokenSats != 1004 || redemption.Preimage == nil || *redemption.Preimage != preimage {
+		t.Fatalf("Expected a paid redemption, got %+v (%v)", redemption, err)
+	}
+	if paymentStatus, ticketStatus := status(payment); paymentStatus != "paid" || ticketStatus != "paid" {
+		t.Errorf("Expected the payment and ticket paid, got %s/%s", paymentStatus, ticketStatus)
+	}
+	if _, err := service.Redeem(ctx, payment.Bolt11, exact); !errors.Is(err, ErrCashuNotPending) {
+		t.Errorf("Expected a paid invoice refused, got %v", err)
+	}
+
+	// A melt the mint has not finished stays pending for the node's report
+	meltState = cashu.QuotePending
+	slow := invoice("lnbc-cashu-slow")
+	redemption, err = service.Redeem(ctx, slow.Bolt11, exact)
+	if err != nil || redemption.State != models.CashuPending {
+		t.Fatalf("Expected a pending redemption, got %+v (%v)", redemption, err)
+	}
+	if paymentStatus, _ := status(slow); paymentStatus != "pending" {
+		t.Errorf("Expected the payment left pending, got %s", paymentStatus)
+	}
+	if _, err := service.Redeem(ctx, slow.Bolt11, exact); !errors.Is(err, ErrCashuInProgress) {
+		t.Errorf("Expected a second token refused while one is in flight, got %v", err)
+	}
+
+	redemptions, err := service.List(10, 0)
+	if err != nil || len(redemptions) != 3 {
+		t.Fatalf("Expected three redemptions, got %+v (%v)", redemptions, err)
+	}
+	states := map[string]int{}
+	for _, r := range redemptions {
+		states[r.State]++
+	}
+	if states[models.CashuPaid] != 1 || states[models.CashuPending] != 1 || states[models.CashuFailed] != 1 {
+		t.Errorf("Unexpected redemption states %v", states)
+	}
+}
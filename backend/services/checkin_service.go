@@ -0,0 +1,129 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/pubsub"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// checkInRecentScans is how many of the latest scans the dashboard shows
+	checkInRecentScans = 20
+	// maxCheckInBuckets caps the admission rate series; older intervals
+	// are left out
+	maxCheckInBuckets = 288
+)
+
+// CheckInService records door scans and summarises them for the check-in
+// dashboard. Dashboards subscribe to an event to hear about its new scans;
+// with a Broadcaster they also hear about scans made on other instances.
+type CheckInService struct {
+	repo       repositories.TicketScanRepository
+	ticketRepo repositories.TicketRepository
+	waiters    waitList
+	bus        Broadcaster
+	clock      clock.Clock
+	logger     *slog.Logger
+}
+
+// NewCheckInService creates a check-in service
+func NewCheckInService(repo repositories.TicketScanRepository, ticketRepo repositories.TicketRepository, clk clock.Clock, logger *slog.Logger) *CheckInService {
+	return &CheckInService{
+		repo:       repo,
+		ticketRepo: ticketRepo,
+		clock:      clk,
+		logger:     logger,
+	}
+}
+
+// Broadcast shares scans with other instances through bus. Call it before
+// the service is used.
+func (s *CheckInService) Broadcast(bus Broadcaster) {
+	s.bus = bus
+	bus.Subscribe(pubsub.TopicCheckIn, s.waiters.wake)
+}
+
+// Record stores a scan and wakes the event's dashboards. A scan that cannot
+// be stored is logged rather than returned, so it never turns a ticket
+// away.
+func (s *CheckInService) Record(scan *models.TicketScan) {
+	if err := s.repo.Create(scan); err != nil {
+		s.logger.Error("Failed to record ticket scan", "event_id", scan.EventID, "ticket_code", scan.TicketCode, "error", err)
+		return
+	}
+	s.waiters.wake(scan.EventID)
+	if s.bus != nil {
+		s.bus.Publish(pubsub.TopicCheckIn, scan.EventID)
+	}
+}
+
+// Subscribe returns a channel that is closed at the event's next scan, and
+// a function that stops waiting. Subscribe before reading the stats so a
+// scan made in between is not missed.
+func (s *CheckInService) Subscribe(eventID int) (<-chan struct{}, func()) {
+	return s.waiters.subscribe(eventID)
+}
+
+// Stats summarises an event's check-ins. Tickets count as admitted from
+// their first admitting scan; the rate counts those per interval, up to
+// now.
+func (s *CheckInService) Stats(eventID int, interval time.Duration) (*models.CheckInStats, error) {
+	admissions, err := s.repo.ListAdmitted(eventID)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := s.repo.ListRecent(eventID, checkInRecentScans)
+	if err != nil {
+		return nil, err
+	}
+	tickets, err := s.ticketRepo.GetByEventID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	stats := &models.CheckInStats{
+		EventID:     eventID,
+		Interval:    interval.String(),
+		Rate:        []models.CheckInBucket{},
+		RecentScans: recent,
+		GeneratedAt: now,
+	}
+
+	admitted := map[int]bool{}
+	perBucket := map[int64]int{}
+	var first time.Time
+	for _, scan := range admissions {
+		if scan.TicketID == nil || admitted[*scan.TicketID] {
+			continue
+		}
+		admitted[*scan.TicketID] = true
+		bucket := scan.ScannedAt.UTC().Truncate(interval)
+		if first.IsZero() {
+			first = bucket
+		}
+		perBucket[bucket.Unix()]++
+	}
+	stats.Admitted = len(admitted)
+
+	for _, ticket := range tickets {
+		if ticket.PaymentStatus == "paid" && !admitted[ticket.ID] {
+			stats.Remaining++
+		}
+	}
+
+	if !first.IsZero() {
+		last := now.UTC().Truncate(interval)
+		if earliest := last.Add(-time.Duration(maxCheckInBuckets-1) * interval); first.Before(earliest) {
+			first = earliest
+		}
+		for start := first; !start.After(last); start = start.Add(interval) {
+			stats.Rate = append(stats.Rate, models.CheckInBucket{Start: start, Admitted: perBucket[start.Unix()]})
+		}
+	}
+	return stats, nil
+}
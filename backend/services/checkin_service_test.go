@@ -0,0 +1,87 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestCheckInService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	checkIns := NewCheckInService(store.TicketScans(), store.Tickets(), clk, logger)
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(3 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []*models.Ticket
+	for i, status := range []string{"paid", "paid", "paid", "pending"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: i + 1, TicketCode: "DOOR-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	stats, err := checkIns.Stats(event.ID, 5*time.Minute)
+	if err != nil || stats.Admitted != 0 || stats.Remaining != 3 || len(stats.Rate) != 0 || len(stats.RecentScans) != 0 {
+		t.Fatalf("Expected an empty dashboard, got %+v (%v)", stats, err)
+	}
+
+	changed, stop := checkIns.Subscribe(event.ID)
+	defer stop()
+	scan := func(ticket *models.Ticket, reason string) {
+		t.Helper()
+		s := &models.TicketScan{EventID: event.ID, TicketID: &ticket.ID, TicketCode: ticket.TicketCode, Admitted: reason == "", Reason: reason}
+		checkIns.Record(s)
+	}
+
+	clk.Advance(time.Minute)
+	scan(tickets[0], "")
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected the dashboard woken by a scan")
+	}
+	clk.Advance(time.Minute)
+	scan(tickets[3], "Ticket payment is not complete")
+	clk.Advance(10 * time.Minute)
+	scan(tickets[1], "")
+	// A returning attendee is not counted twice
+	scan(tickets[0], "")
+
+	stats, err = checkIns.Stats(event.ID, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Admitted != 2 || stats.Remaining != 1 || stats.Interval != "5m0s" {
+		t.Errorf("Expected two admitted and one remaining, got %+v", stats)
+	}
+	// 19:00, 19:05 and 19:10 intervals up to now (19:12)
+	want := []int{1, 0, 1}
+	if len(stats.Rate) != len(want) {
+		t.Fatalf("Expected %d intervals, got %+v", len(want), stats.Rate)
+	}
+	for i, n := range want {
+		if stats.Rate[i].Admitted != n || !stats.Rate[i].Start.Equal(event.StartTime.Add(time.Duration(i)*5*time.Minute)) {
+			t.Errorf("Interval %d: expected %d admitted, got %+v", i, n, stats.Rate[i])
+		}
+	}
+	if len(stats.RecentScans) != 4 || stats.RecentScans[2].Reason != "Ticket payment is not complete" || stats.RecentScans[2].Admitted {
+		t.Errorf("Expected the scans newest first with the rejection, got %+v", stats.RecentScans)
+	}
+
+	// The series is capped at its latest intervals
+	clk.Advance(48 * time.Hour)
+	if stats, _ := checkIns.Stats(event.ID, time.Minute); len(stats.Rate) != maxCheckInBuckets {
+		t.Errorf("Expected %d intervals, got %d", maxCheckInBuckets, len(stats.Rate))
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+// ErrPaymentBackendUnavailable is returned without calling the payment
+// backend while its circuit breaker is open
+var ErrPaymentBackendUnavailable = errors.New("payment backend unavailable")
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+const (
+	// retryBaseDelay and retryMaxDelay bound the backoff between retries
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+)
+
+// CircuitBreaker guards calls to the payment backend. After threshold
+// consecutive failures it opens and rejects calls with
+// ErrPaymentBackendUnavailable for cooldown; then a single probe call is let
+// through, closing the breaker again if it succeeds.
+type CircuitBreaker struct {
+	threshold  int
+	cooldown   time.Duration
+	maxRetries int
+	clock      clock.Clock
+	sleep      func(time.Duration)
+	onOpen     func()
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	stats    BreakerStats
+}
+
+// BreakerStats is a snapshot of a breaker for metrics. Rejected counts
+// calls failed fast while open.
+type BreakerStats struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	Calls               int64      `json:"calls"`
+	Failures            int64      `json:"failures"`
+	Retries             int64      `json:"retries"`
+	Rejected            int64      `json:"rejected"`
+	Opened              int64      `json:"opened"`
+}
+
+// NewCircuitBreaker creates a closed breaker that opens after threshold
+// consecutive failures for cooldown, and retries idempotent calls up to
+// maxRetries times. A threshold of zero never opens.
+func NewCircuitBreaker(threshold int, cooldown time.Duration, maxRetries int, clk clock.Clock) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:  threshold,
+		cooldown:   cooldown,
+		maxRetries: maxRetries,
+		clock:      clk,
+		sleep:      time.Sleep,
+		state:      BreakerClosed,
+	}
+}
+
+// OnOpen sets fn to be called when the breaker opens after calls that
+// were going through start failing. A probe failing again does not call it.
+// Call it before the breaker is used.
+func (b *CircuitBreaker) OnOpen(fn func()) {
+	b.onOpen = fn
+}
+
+// Call runs fn once through the breaker. Use it for calls that must not be
+// repeated, such as sending a payment.
+func (b *CircuitBreaker) Call(fn func() error) error {
+	probe, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if b.record(probe, err) && b.onOpen != nil {
+		b.onOpen()
+	}
+	return err
+}
+
+// Retry runs an idempotent fn through the breaker, retrying failures with
+// jittered exponential backoff. It stops early when the breaker opens.
+func (b *CircuitBreaker) Retry(fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := b.Call(fn)
+		if err == nil || errors.Is(err, ErrPaymentBackendUnavailable) || attempt >= b.maxRetries {
+			return err
+		}
+
+		b.mu.Lock()
+		b.stats.Retries++
+		b.mu.Unlock()
+
+		// Full jitter in [delay/2, delay) keeps instances from retrying in step
+		b.sleep(delay/2 + rand.N(delay/2))
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// Stats returns the breaker's state and counters.
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.State = b.currentState()
+	stats.ConsecutiveFailures = b.failures
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// acquire reports whether a call may go ahead and whether it is the probe
+// let through once the cooldown is over
+func (b *CircuitBreaker) acquire() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Calls++
+
+	switch b.currentState() {
+	case BreakerOpen:
+		b.stats.Rejected++
+		return false, ErrPaymentBackendUnavailable
+	case BreakerHalfOpen:
+		if b.probing {
+			b.stats.Rejected++
+			return false, ErrPaymentBackendUnavailable
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the breaker with a call's outcome, reporting whether it
+// opened a closed breaker
+func (b *CircuitBreaker) record(probe bool, err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+
+	if err == nil {
+		b.state = BreakerClosed
+		b.failures = 0
+		return false
+	}
+	b.stats.Failures++
+	b.failures++
+	if !probe && (b.threshold <= 0 || b.failures < b.threshold) {
+		return false
+	}
+	opened := b.state == BreakerClosed
+	if b.state != BreakerOpen {
+		b.stats.Opened++
+	}
+	b.state = BreakerOpen
+	b.openedAt = b.clock.Now()
+	return opened
+}
+
+// currentState is the state with the cooldown applied. Callers hold b.mu.
+func (b *CircuitBreaker) currentState() string {
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	breaker := NewCircuitBreaker(3, 30*time.Second, 2, clk)
+	var slept []time.Duration
+	breaker.sleep = func(d time.Duration) { slept = append(slept, d) }
+	opened := 0
+	breaker.OnOpen(func() { opened++ })
+
+	down := errors.New("lightspark unreachable")
+	calls := 0
+	failing := func() error { calls++; return down }
+	working := func() error { calls++; return nil }
+
+	// Retries back off with jitter, then give up with the call's error
+	if err := breaker.Retry(failing); !errors.Is(err, down) || calls != 3 {
+		t.Fatalf("Expected 3 attempts ending in the error, got %d (%v)", calls, err)
+	}
+	if len(slept) != 2 || slept[0] < retryBaseDelay/2 || slept[0] >= retryBaseDelay || slept[1] < retryBaseDelay || slept[1] >= 2*retryBaseDelay {
+		t.Errorf("Unexpected backoff %v", slept)
+	}
+
+	// The third consecutive failure opened the breaker: calls fail fast
+	if err := breaker.Call(working); !errors.Is(err, ErrPaymentBackendUnavailable) || calls != 3 {
+		t.Errorf("Expected a fast failure while open, got %v after %d calls", err, calls)
+	}
+	if stats := breaker.Stats(); stats.State != BreakerOpen || stats.Opened != 1 || stats.Rejected != 1 || stats.OpenedAt == nil {
+		t.Errorf("Unexpected stats while open %+v", stats)
+	}
+
+	// After the cooldown one probe goes through; a failed probe reopens it
+	clk.Advance(30 * time.Second)
+	if state := breaker.Stats().State; state != BreakerHalfOpen {
+		t.Errorf("Expected half_open after the cooldown, got %s", state)
+	}
+	if err := breaker.Retry(failing); !errors.Is(err, ErrPaymentBackendUnavailable) || calls != 4 {
+		t.Errorf("Expected the failed probe to reopen the breaker without retrying, got %v after %d calls", err, calls)
+	}
+
+	clk.Advance(30 * time.Second)
+	if err := breaker.Call(working); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+	if stats := breaker.Stats(); stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 || stats.OpenedAt != nil {
+		t.Errorf("Expected the breaker to close after a successful probe, got %+v", stats)
+	}
+	if opened != 1 {
+		t.Errorf("Expected the open hook called once, not for the failed probe, got %d", opened)
+	}
+
+	// A zero threshold never opens
+	never := NewCircuitBreaker(0, time.Minute, 0, clk)
+	for i := 0; i < 10; i++ {
+		never.Call(failing)
+	}
+	if state := never.Stats().State; state != BreakerClosed {
+		t.Errorf("Expected a zero threshold to stay closed, got %s", state)
+	}
+}
+
+func TestLightsparkCallsFailFastWhenBreakerOpen(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	service := NewLightsparkUMAService("client", "secret", "node", "password", "", "", "", "", "", logger).(*LightsparkUMAService)
+	service.breaker = NewCircuitBreaker(1, time.Minute, 0, clk)
+	service.breaker.Call(func() error { return errors.New("lightspark unreachable") })
+
+	if _, err := service.CreateTicketInvoice("$buyer@wallet.example.com", 1000, "Ticket", time.Hour); !errors.Is(err, ErrPaymentBackendUnavailable) {
+		t.Errorf("Expected invoice creation to fail fast, got %v", err)
+	}
+	if _, err := service.SendPaymentToInvoice("lnbc1000n1" + strings.Repeat("x", 50)); !errors.Is(err, ErrPaymentBackendUnavailable) {
+		t.Errorf("Expected payment to fail fast, got %v", err)
+	}
+	if _, err := service.GetNodeBalance(); !errors.Is(err, ErrPaymentBackendUnavailable) {
+		t.Errorf("Expected the balance fetch to fail fast, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+	"tickets-by-uma/ticketcode"
+)
+
+// maxCompRecipients caps the rows of one bulk issuance
+const maxCompRecipients = 500
+
+// ErrCompEventCancelled is returned when comps are issued for a cancelled
+// event
+var ErrCompEventCancelled = errors.New("cancelled events cannot issue tickets")
+
+// InvalidCompError is returned when a bulk issuance cannot be started at
+// all; problems with single rows are reported per row instead
+type InvalidCompError struct {
+	Reason string
+}
+
+func (e *InvalidCompError) Error() string {
+	return e.Reason
+}
+
+// CompService issues complimentary tickets, such as for press and VIPs.
+// Comp tickets are paid from the start, cost nothing and are marked as
+// comped; they take seats like bought tickets. Recipients without an
+// account get a guest account, which is sent its claim link as for a guest
+// checkout.
+type CompService struct {
+	users      repositories.UserRepository
+	guests     *GuestService
+	eventRepo  repositories.EventRepository
+	ticketRepo repositories.TicketRepository
+	notifier   Notifier
+	logger     *slog.Logger
+}
+
+// NewCompService creates a comp ticket service. notifier may be nil.
+func NewCompService(users repositories.UserRepository, guests *GuestService, eventRepo repositories.EventRepository, ticketRepo repositories.TicketRepository, notifier Notifier, logger *slog.Logger) *CompService {
+	return &CompService{
+		users:      users,
+		guests:     guests,
+		eventRepo:  eventRepo,
+		ticketRepo: ticketRepo,
+		notifier:   notifier,
+		logger:     logger,
+	}
+}
+
+// Issue gives each recipient a comp ticket to the event and emails it to
+// them, reporting the outcome per row. Rows fail on their own, such as
+// for a malformed email or once the event is sold out. It returns
+// repositories.ErrNotFound for an unknown event, ErrCompEventCancelled for
+// a cancelled one and an *InvalidCompError for an empty or oversized list.
+func (s *CompService) Issue(eventID int, recipients []models.CompTicketRecipient, issuedBy *int) (*models.BulkTicketResult, error) {
+	switch {
+	case len(recipients) == 0:
+		return nil, &InvalidCompError{Reason: "recipients are required"}
+	case len(recipients) > maxCompRecipients:
+		return nil, &InvalidCompError{Reason: fmt.Sprintf("at most %d recipients can be issued tickets at once", maxCompRecipients)}
+	}
+
+	event, err := s.eventRepo.GetByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.CancelledAt != nil {
+		return nil, ErrCompEventCancelled
+	}
+	available, err := s.eventRepo.GetAvailableTicketCount(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkTicketResult{EventID: eventID, Results: make([]models.BulkTicketRowResult, 0, len(recipients))}
+	for i, recipient := range recipients {
+		row := models.BulkTicketRowResult{Row: i + 1, Email: strings.TrimSpace(recipient.Email)}
+		if available <= 0 {
+			row.Error = "Event is sold out"
+		} else if err := s.issue(event, recipient, &row); errors.Is(err, repositories.ErrSoldOut) {
+			// Purchases took the seats left meanwhile
+			row.Error, available = "Event is sold out", 0
+		} else if err != nil {
+			row.Error = err.Error()
+		}
+
+		if row.Error != "" {
+			row.Status = models.BulkTicketFailed
+			result.Failed++
+		} else {
+			row.Status = models.BulkTicketIssued
+			result.Issued++
+			available--
+		}
+		result.Results = append(result.Results, row)
+	}
+
+	s.logger.Info("Comp tickets issued", "event_id", eventID, "issued", result.Issued, "failed", result.Failed, "issued_by", issuedBy)
+	return result, nil
+}
+
+// issue gives one recipient their ticket, filling in row. The returned
+// error is the row's failure as shown to the organizer, or
+// repositories.ErrSoldOut once the event has no seat left.
+func (s *CompService) issue(event *models.Event, recipient models.CompTicketRecipient, row *models.BulkTicketRowResult) error {
+	if len(row.Email) < 5 || !strings.Contains(row.Email, "@") {
+		return errors.New("invalid email format")
+	}
+
+	user, err := s.recipient(row.Email, strings.TrimSpace(recipient.Name))
+	if err != nil {
+		s.logger.Error("Failed to find comp ticket recipient", "event_id", event.ID, "row", row.Row, "error", err)
+		return errors.New("failed to create recipient account")
+	}
+
+	code, err := ticketcode.New(event.ID)
+	if err != nil {
+		return errors.New("failed to generate ticket code")
+	}
+	ticket := &models.Ticket{
+		EventID:       event.ID,
+		UserID:        user.ID,
+		TicketCode:    code,
+		PaymentStatus: "paid",
+		IsComp:        true,
+		NeedsSeat:     true,
+	}
+	err = s.ticketRepo.Create(ticket)
+	if errors.Is(err, repositories.ErrSoldOut) {
+		return err
+	}
+	if err != nil {
+		s.logger.Error("Failed to create comp ticket", "event_id", event.ID, "row", row.Row, "error", err)
+		return errors.New("failed to create ticket")
+	}
+	row.TicketID, row.TicketCode, row.UserID = &ticket.ID, ticket.TicketCode, &user.ID
+
+	if s.notifier != nil {
+		notification := i18n.Notification{Key: i18n.TicketComped, Args: []any{event.Title, ticket.TicketCode}, EventID: event.ID}
+		if err := s.notifier.NotifyUser(user.ID, notification); err != nil {
+			s.logger.Error("Failed to send comp ticket", "ticket_id", ticket.ID, "error", err)
+			return nil
+		}
+		row.Notified = true
+	}
+	return nil
+}
+
+// recipient returns the user with email, creating a guest named name when
+// there is none. A guest's placeholder name is replaced with name.
+func (s *CompService) recipient(email, name string) (*models.User, error) {
+	user, err := s.users.GetByEmail(email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		user, err = s.guests.Checkout(email, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.IsGuest && name != "" && user.Name != name {
+		user.Name = name
+		if err := s.users.Update(user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
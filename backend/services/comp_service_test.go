@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestCompService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &claimLinkNotifier{links: make(map[int][]string)}
+	comped := &recordingNotifier{}
+	guests := NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	both := notifierFunc(func(userID int, notification i18n.Notification) error {
+		notifier.NotifyUser(userID, notification)
+		return comped.NotifyUser(userID, notification)
+	})
+	comps := NewCompService(store.Users(), guests, store.Events(), guests.Tickets(store.Tickets()), both, logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 3, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	press := &models.User{Email: "press@example.com", Name: "Press", PasswordHash: "hash"}
+	if err := store.Users().Create(press); err != nil {
+		t.Fatal(err)
+	}
+	bought := &models.Ticket{EventID: event.ID, UserID: press.ID, TicketCode: "BOUGHT-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(bought); err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid *InvalidCompError
+	if _, err := comps.Issue(event.ID, nil, nil); !errors.As(err, &invalid) {
+		t.Errorf("Expected an empty list refused, got %v", err)
+	}
+	if _, err := comps.Issue(9999, []models.CompTicketRecipient{{Email: "a@example.com"}}, nil); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected an unknown event refused, got %v", err)
+	}
+
+	admin := 42
+	result, err := comps.Issue(event.ID, []models.CompTicketRecipient{
+		{Email: " press@example.com ", Name: "Ignored"},
+		{Email: "not-an-email"},
+		{Email: "vip@example.com", Name: "Very Important"},
+		{Email: "late@example.com"},
+	}, &admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Issued != 2 || result.Failed != 2 || len(result.Results) != 4 {
+		t.Fatalf("Expected two issued and two failed, got %+v", result)
+	}
+	want := []struct{ status, error string }{
+		{models.BulkTicketIssued, ""},
+		{models.BulkTicketFailed, "invalid email format"},
+		{models.BulkTicketIssued, ""},
+		// The bought ticket and two comps fill the event
+		{models.BulkTicketFailed, "Event is sold out"},
+	}
+	for i, w := range want {
+		row := result.Results[i]
+		if row.Row != i+1 || row.Status != w.status || row.Error != w.error || (w.status == models.BulkTicketIssued) != row.Notified {
+			t.Errorf("Row %d: expected %s %q, got %+v", i+1, w.status, w.error, row)
+		}
+	}
+
+	// Registered recipients keep their account; others get a guest one
+	if *result.Results[0].UserID != press.ID {
+		t.Errorf("Expected the press ticket issued to the registered user, got %+v", result.Results[0])
+	}
+	vip, err := store.Users().GetByEmail("vip@example.com")
+	if err != nil || !vip.IsGuest || vip.Name != "Very Important" {
+		t.Fatalf("Expected a named guest for the VIP, got %+v (%v)", vip, err)
+	}
+	if len(notifier.links[vip.ID]) != 1 || len(notifier.links[press.ID]) != 0 {
+		t.Errorf("Expected only the guest sent a claim link, got %v", notifier.links)
+	}
+
+	ticket, err := store.Tickets().GetByID(*result.Results[2].TicketID)
+	if err != nil || !ticket.IsComp || ticket.PaymentStatus != "paid" || ticket.AmountSats != 0 || ticket.UserID != vip.ID {
+		t.Errorf("Expected a paid comp ticket, got %+v (%v)", ticket, err)
+	}
+	if err := store.Events().Cancel(event.ID, clk.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comps.Issue(event.ID, []models.CompTicketRecipient{{Email: "after@example.com"}}, nil); !errors.Is(err, ErrCompEventCancelled) {
+		t.Errorf("Expected a cancelled event refused, got %v", err)
+	}
+
+	sent := 0
+	for _, subject := range comped.subjects {
+		if subject == "You're on the guest list" {
+			sent++
+		}
+	}
+	if sent != 2 {
+		t.Errorf("Expected both recipients emailed their ticket, got %v", comped.subjects)
+	}
+}
+
+// notifierFunc adapts a function to Notifier
+type notifierFunc func(userID int, notification i18n.Notification) error
+
+func (f notifierFunc) NotifyUser(userID int, notification i18n.Notification) error {
+	return f(userID, notification)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+// maxDiscoveryEntries bounds the cache; buyer domains come from user input
+const maxDiscoveryEntries = 1000
+
+// vaspConfiguration is the part of a VASP's /.well-known/uma-configuration
+// the sender flow uses
+type vaspConfiguration struct {
+	UMARequestEndpoint string `json:"uma_request_endpoint"`
+}
+
+// DiscoveryCache remembers buyer VASPs' UMA configuration by domain so
+// repeated purchases from the same VASP skip the discovery fetch. Failed
+// fetches are remembered for a shorter time so a broken domain fails fast
+// instead of costing a full HTTP timeout on every purchase.
+type DiscoveryCache struct {
+	ttl        time.Duration
+	failureTTL time.Duration
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]discoveryEntry
+	stats   DiscoveryStats
+}
+
+type discoveryEntry struct {
+	config    vaspConfiguration
+	err       error
+	expiresAt time.Time
+}
+
+// DiscoveryStats counts cache lookups since start. FailureHits are lookups
+// answered with a cached failure; FetchErrors are fetches that failed.
+type DiscoveryStats struct {
+	Entries     int   `json:"entries"`
+	Hits        int64 `json:"hits"`
+	FailureHits int64 `json:"failure_hits"`
+	Misses      int64 `json:"misses"`
+	FetchErrors int64 `json:"fetch_errors"`
+}
+
+// NewDiscoveryCache creates a cache keeping configurations for ttl and
+// failures for failureTTL. A zero TTL disables that kind of caching.
+func NewDiscoveryCache(ttl, failureTTL time.Duration, clk clock.Clock) *DiscoveryCache {
+	return &DiscoveryCache{
+		ttl:        ttl,
+		failureTTL: failureTTL,
+		clock:      clk,
+		entries:    make(map[string]discoveryEntry),
+	}
+}
+
+// Get returns domain's configuration from the cache, or from fetch when it
+// is missing or expired. Concurrent misses for a domain each fetch.
+func (c *DiscoveryCache) Get(domain string, fetch func() (vaspConfiguration, error)) (vaspConfiguration, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[domain]; ok && c.clock.Now().Before(entry.expiresAt) {
+		if entry.err != nil {
+			c.stats.FailureHits++
+		} else {
+			c.stats.Hits++
+		}
+		c.mu.Unlock()
+		return entry.config, entry.err
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	config, err := fetch()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.ttl
+	if err != nil {
+		c.stats.FetchErrors++
+		ttl = c.failureTTL
+	}
+	if ttl <= 0 {
+		delete(c.entries, domain)
+		return config, err
+	}
+	now := c.clock.Now()
+	if _, ok := c.entries[domain]; !ok && len(c.entries) >= maxDiscoveryEntries {
+		c.evict(now)
+	}
+	c.entries[domain] = discoveryEntry{config: config, err: err, expiresAt: now.Add(ttl)}
+	return config, err
+}
+
+// Forget drops domain from the cache, for when its cached endpoint stops
+// working.
+func (c *DiscoveryCache) Forget(domain string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, domain)
+}
+
+// Stats returns the cache's counters.
+func (c *DiscoveryCache) Stats() DiscoveryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// evict makes room for an entry: expired entries go first, otherwise the
+// one closest to expiry. Callers hold c.mu.
+func (c *DiscoveryCache) evict(now time.Time) {
+	oldest := ""
+	for domain, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, domain)
+			continue
+		}
+		if oldest == "" || entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+			oldest = domain
+		}
+	}
+	if len(c.entries) >= maxDiscoveryEntries {
+		delete(c.entries, oldest)
+	}
+}
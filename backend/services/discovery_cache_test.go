@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+func TestDiscoveryCache(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	cache := NewDiscoveryCache(10*time.Minute, time.Minute, clk)
+
+	fetches := 0
+	var fetchErr error
+	fetch := func() (vaspConfiguration, error) {
+		fetches++
+		if fetchErr != nil {
+			return vaspConfiguration{}, fetchErr
+		}
+		return vaspConfiguration{UMARequestEndpoint: fmt.Sprintf("https://vasp.example.com/request/%d", fetches)}, nil
+	}
+
+	first, err := cache.Get("vasp.example.com", fetch)
+	if err != nil || first.UMARequestEndpoint != "https://vasp.example.com/request/1" {
+		t.Fatalf("Unexpected configuration %+v (%v)", first, err)
+	}
+	if cached, _ := cache.Get("vasp.example.com", fetch); cached != first || fetches != 1 {
+		t.Errorf("Expected a cache hit, fetched %d times", fetches)
+	}
+
+	// Entries expire after the TTL
+	clk.Advance(10 * time.Minute)
+	if refreshed, _ := cache.Get("vasp.example.com", fetch); refreshed.UMARequestEndpoint != "https://vasp.example.com/request/2" {
+		t.Errorf("Expected a refetch after expiry, got %+v", refreshed)
+	}
+
+	// Failures are cached for the shorter failure TTL
+	fetchErr = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get("broken.example.com", fetch); !errors.Is(err, fetchErr) {
+			t.Fatalf("Expected the fetch error, got %v", err)
+		}
+	}
+	if fetches != 3 {
+		t.Errorf("Expected the failure to be fetched once, fetched %d times in total", fetches)
+	}
+	clk.Advance(time.Minute)
+	fetchErr = nil
+	if _, err := cache.Get("broken.example.com", fetch); err != nil || fetches != 4 {
+		t.Errorf("Expected a refetch after the failure expired, got %v", err)
+	}
+
+	cache.Forget("vasp.example.com")
+	if _, err := cache.Get("vasp.example.com", fetch); err != nil || fetches != 5 {
+		t.Errorf("Expected a refetch after Forget, got %v", err)
+	}
+
+	want := DiscoveryStats{Entries: 2, Hits: 1, FailureHits: 2, Misses: 5, FetchErrors: 1}
+	if stats := cache.Stats(); stats != want {
+		t.Errorf("Expected stats %+v, got %+v", want, stats)
+	}
+
+	// A zero TTL disables caching
+	uncached := NewDiscoveryCache(0, 0, clk)
+	for i := 0; i < 2; i++ {
+		uncached.Get("vasp.example.com", fetch)
+	}
+	if fetches != 7 || uncached.Stats().Entries != 0 {
+		t.Errorf("Expected every lookup to fetch without caching, fetched %d times", fetches)
+	}
+}
+
+func TestDiscoveryCacheBounded(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	cache := NewDiscoveryCache(time.Hour, time.Minute, clk)
+	fail := func() (vaspConfiguration, error) { return vaspConfiguration{}, errors.New("no such host") }
+
+	for i := 0; i < maxDiscoveryEntries+10; i++ {
+		cache.Get(fmt.Sprintf("vasp%d.example.com", i), fail)
+	}
+	if entries := cache.Stats().Entries; entries != maxDiscoveryEntries {
+		t.Errorf("Expected the cache to stay at %d entries, got %d", maxDiscoveryEntries, entries)
+	}
+}
+
+func TestFetchVASPConfiguration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/uma-configuration":
+			w.Write([]byte(`{"uma_request_endpoint":"http://localhost/uma/request"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewLightsparkUMAService("", "", "", "", "", "", "", "", "", logger).(*LightsparkUMAService)
+	service.discovery = NewDiscoveryCache(time.Minute, time.Minute, clock.System())
+	domain := strings.Replace(strings.TrimPrefix(server.URL, "http://"), "127.0.0.1", "localhost", 1)
+
+	for i := 0; i < 2; i++ {
+		config, err := service.discoverVASP(domain)
+		if err != nil || config.UMARequestEndpoint != "http://localhost/uma/request" {
+			t.Fatalf("Unexpected configuration %+v (%v)", config, err)
+		}
+	}
+	if stats := service.Discovery().Stats(); stats.Misses != 1 || stats.Hits != 1 {
+		t.Errorf("Expected one fetch and one hit, got %+v", stats)
+	}
+
+	server.Config.Handler = http.NotFoundHandler()
+	if _, err := service.fetchVASPConfiguration(domain); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Expected an error for a missing configuration, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// ErrNotDisputable is returned when opening a dispute on a payment that is
+// not paid
+var ErrNotDisputable = errors.New("only paid payments can be disputed")
+
+// DisputeService runs the dispute workflow for payments a counterparty VASP
+// claws back or contests: the ticket is suspended while the dispute is
+// open, then reinstated if it is won or cancelled, with the sale reversed
+// in the ledger, if it is lost. The buyer is notified at each step.
+type DisputeService struct {
+	repo        repositories.DisputeRepository
+	paymentRepo repositories.PaymentRepository
+	ticketRepo  repositories.TicketRepository
+	ledger      *LedgerService
+	notifier    Notifier
+	logger      *slog.Logger
+}
+
+// NewDisputeService creates a dispute service. ledger and notifier may be
+// nil.
+func NewDisputeService(repo repositories.DisputeRepository, paymentRepo repositories.PaymentRepository, ticketRepo repositories.TicketRepository, ledger *LedgerService, notifier Notifier, logger *slog.Logger) *DisputeService {
+	return &DisputeService{
+		repo:        repo,
+		paymentRepo: paymentRepo,
+		ticketRepo:  ticketRepo,
+		ledger:      ledger,
+		notifier:    notifier,
+		logger:      logger,
+	}
+}
+
+// Open opens a dispute on a paid payment and suspends its ticket. It
+// returns repositories.ErrNotFound for an unknown payment, ErrNotDisputable
+// when it is not paid and repositories.ErrConflict when a dispute is
+// already open. If the ticket cannot be suspended the opened dispute is
+// returned with the error.
+func (s *DisputeService) Open(paymentID int, reason string, openedBy *int) (*models.Dispute, error) {
+	payment, err := s.paymentRepo.GetByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != "paid" {
+		return nil, ErrNotDisputable
+	}
+
+	dispute := &models.Dispute{PaymentID: paymentID, Reason: reason, OpenedBy: openedBy}
+	if err := s.repo.Create(dispute); err != nil {
+		return nil, err
+	}
+	s.logger.Warn("Payment disputed", "dispute_id", dispute.ID, "payment_id", paymentID, "reason", reason)
+
+	ticket, err := s.ticketRepo.GetByID(payment.TicketID)
+	if err != nil {
+		return dispute, fmt.Errorf("dispute opened but ticket %d could not be suspended: %w", payment.TicketID, err)
+	}
+	if ticket.PaymentStatus == "paid" {
+		ticket.PaymentStatus = models.TicketDisputed
+		if err := s.ticketRepo.Update(ticket); err != nil {
+			return dispute, fmt.Errorf("dispute opened but ticket %d could not be suspended: %w", ticket.ID, err)
+		}
+		s.notify(ticket, i18n.TicketSuspended, ticket.TicketCode)
+	}
+	return dispute, nil
+}
+
+// Resolve closes an open dispute as won or lost. A lost dispute means the
+// funds are gone: the sale is reversed in the ledger and the payment and
+// ticket are cancelled. It returns repositories.ErrConflict when the
+// dispute is already closed.
+func (s *DisputeService) Resolve(id int, outcome, resolution string, resolvedBy *int) (*models.Dispute, error) {
+	dispute := &models.Dispute{ID: id, Status: outcome, Resolution: resolution, ResolvedBy: resolvedBy}
+	if err := s.repo.Resolve(dispute); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Dispute resolved", "dispute_id", id, "payment_id", dispute.PaymentID, "outcome", outcome)
+
+	// The dispute is closed from here on, so errors below are returned with
+	// it. A sale left unreversed is reported by the ledger check.
+	payment, err := s.paymentRepo.GetByID(dispute.PaymentID)
+	if err != nil {
+		return dispute, err
+	}
+	ticket, err := s.ticketRepo.GetByID(payment.TicketID)
+	if err != nil {
+		return dispute, err
+	}
+
+	if outcome == models.DisputeLost {
+		if s.ledger != nil {
+			_, err := s.ledger.RecordRefund(payment.ID, fmt.Sprintf("dispute %d", id))
+			if err != nil && !errors.Is(err, repositories.ErrConflict) {
+				return dispute, fmt.Errorf("failed to reverse the sale in the ledger: %w", err)
+			}
+		}
+		if err := s.paymentRepo.UpdateStatus(payment.ID, "cancelled"); err != nil {
+			return dispute, err
+		}
+		ticket.PaymentStatus = "cancelled"
+		if err := s.ticketRepo.Update(ticket); err != nil {
+			return dispute, err
+		}
+		s.notify(ticket, i18n.TicketCancelled, ticket.TicketCode)
+		return dispute, nil
+	}
+
+	if ticket.PaymentStatus == models.TicketDisputed {
+		ticket.PaymentStatus = "paid"
+		if err := s.ticketRepo.Update(ticket); err != nil {
+			return dispute, err
+		}
+		s.notify(ticket, i18n.TicketReinstated, ticket.TicketCode)
+	}
+	return dispute, nil
+}
+
+func (s *DisputeService) notify(ticket *models.Ticket, key string, args ...any) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.NotifyUser(ticket.UserID, i18n.Notification{Key: key, Args: args, EventID: ticket.EventID}); err != nil {
+		s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+	}
+}
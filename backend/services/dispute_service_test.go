@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+type recordingNotifier struct {
+	subjects []string
+}
+
+func (n *recordingNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	subject, _ := notification.Render(i18n.Default)
+	n.subjects = append(n.subjects, subject)
+	return nil
+}
+
+func TestDisputeService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	notifier := &recordingNotifier{}
+	disputes := NewDisputeService(store.Disputes(), store.Payments(), store.Tickets(), ledger, notifier, logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []*models.Ticket
+	var payments []*models.Payment
+	for i, status := range []string{"paid", "paid", "pending"} {
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "DISPUTE-" + strconv.Itoa(i), PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-dispute-" + strconv.Itoa(i), Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+		payments = append(payments, payment)
+	}
+	ticketStatus := func(id int) string {
+		t.Helper()
+		ticket, err := store.Tickets().GetByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ticket.PaymentStatus
+	}
+
+	if _, err := disputes.Open(payments[2].ID, "Reversed", nil); !errors.Is(err, ErrNotDisputable) {
+		t.Errorf("Expected ErrNotDisputable for an unpaid payment, got %v", err)
+	}
+	if _, err := disputes.Open(99, "Reversed", nil); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown payment, got %v", err)
+	}
+
+	// Opening suspends the ticket
+	won, err := disputes.Open(payments[0].ID, "Sender says the invoice was never received", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticketStatus(tickets[0].ID) != models.TicketDisputed {
+		t.Errorf("Expected the ticket to be suspended")
+	}
+	if _, err := disputes.Open(payments[0].ID, "Again", nil); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict for a second open dispute, got %v", err)
+	}
+
+	// Winning reinstates it
+	if _, err := disputes.Resolve(won.ID, models.DisputeWon, "Preimage matches", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ticketStatus(tickets[0].ID) != "paid" {
+		t.Errorf("Expected the ticket to be reinstated")
+	}
+	if _, err := disputes.Resolve(won.ID, models.DisputeLost, "", nil); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict resolving twice, got %v", err)
+	}
+
+	// Losing cancels the payment and ticket and reverses the sale
+	lost, err := disputes.Open(payments[1].ID, "Funds clawed back", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disputes.Resolve(lost.ID, models.DisputeLost, "Clawback confirmed", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ticketStatus(tickets[1].ID) != "cancelled" {
+		t.Errorf("Expected the ticket to be cancelled")
+	}
+	payment, err := store.Payments().GetByID(payments[1].ID)
+	if err != nil || payment.Status != "cancelled" {
+		t.Errorf("Expected the payment to be cancelled, got %+v (%v)", payment, err)
+	}
+	if _, err := store.Ledger().GetPaymentEntry(models.LedgerEntryRefund, payments[1].ID); err != nil {
+		t.Errorf("Expected the sale to be reversed, got %v", err)
+	}
+	wallet, err := store.Ledger().GetBalance(accounting.AccountWallet)
+	if err != nil || wallet.BalanceSats != 1000 {
+		t.Errorf("Expected only the won sale in the wallet, got %+v (%v)", wallet, err)
+	}
+	if issues, err := ledger.Check(); err != nil || len(issues) != 0 {
+		t.Errorf("Expected a consistent ledger, got %+v (%v)", issues, err)
+	}
+
+	want := "Ticket suspended,Ticket reinstated,Ticket suspended,Ticket cancelled"
+	if got := strings.Join(notifier.subjects, ","); got != want {
+		t.Errorf("Expected notifications %s, got %s", want, got)
+	}
+}
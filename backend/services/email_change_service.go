@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// emailChangeTTL is how long an email verification link can be used
+const emailChangeTTL = 24 * time.Hour
+
+// Email change errors
+var (
+	ErrEmailChangeInvalid = errors.New("verification link is invalid, expired or already used")
+	ErrEmailTaken         = errors.New("another user has this email")
+)
+
+// EmailChangeService changes users' emails only once the new address is
+// proven. A change is held as the user's pending email and a verification
+// link is sent to the new address; the old address is told about the
+// request so its owner notices a change they did not make. The link
+// carries a one-time secret of which only the hash is stored.
+type EmailChangeService struct {
+	changes  repositories.EmailChangeRepository
+	notifier Notifier
+	domain   string
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewEmailChangeService creates an email change service. domain is the
+// host in verification links, which notifier delivers.
+func NewEmailChangeService(changes repositories.EmailChangeRepository, notifier Notifier, domain string, clk clock.Clock, logger *slog.Logger) *EmailChangeService {
+	return &EmailChangeService{changes: changes, notifier: notifier, domain: domain, clock: clk, logger: logger}
+}
+
+// Request makes newEmail the user's pending email, replacing any earlier
+// request, and sends the verification link and the notice to the old
+// address. Whether the address is free is up to the caller.
+func (s *EmailChangeService) Request(user *models.User, newEmail string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	encoded := hex.EncodeToString(secret)
+
+	if err := s.changes.Request(user.ID, newEmail, hashClaimSecret(encoded), s.clock.Now().Add(emailChangeTTL)); err != nil {
+		return err
+	}
+	user.PendingEmail = &newEmail
+
+	url := fmt.Sprintf("https://%s/confirm-email?token=%s", s.domain, encoded)
+	if err := s.notifier.NotifyUser(user.ID, i18n.Notification{Key: i18n.EmailVerify, Args: []any{newEmail, url}, Address: newEmail}); err != nil {
+		return err
+	}
+	// The link is out, so failing to warn the old address does not undo
+	// the request
+	if err := s.notifier.NotifyUser(user.ID, i18n.Notification{Key: i18n.EmailChange, Args: []any{newEmail}}); err != nil {
+		s.logger.Error("Failed to notify old address of email change", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// Confirm makes the pending email matching the token from a verification
+// link the user's email, returning the user
+func (s *EmailChangeService) Confirm(token string) (*models.User, error) {
+	user, err := s.changes.Confirm(hashClaimSecret(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrEmailChangeInvalid
+	}
+	if errors.Is(err, repositories.ErrConflict) {
+		return nil, ErrEmailTaken
+	}
+	return user, err
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// addressNotifier keeps the notifications sent, with where they went
+type addressNotifier struct {
+	sent []i18n.Notification
+}
+
+func (n *addressNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestEmailChangeService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &addressNotifier{}
+	changes := NewEmailChangeService(store.EmailChanges(), notifier, "tickets.example.com", clk, logger)
+
+	user := &models.User{Email: "old@example.com", Name: "Mover"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := changes.Request(user, "new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "old@example.com" || user.PendingEmail == nil || *user.PendingEmail != "new@example.com" {
+		t.Errorf("Expected the new email to be pending, got %+v", user)
+	}
+
+	// The link goes to the new address and the notice to the old one
+	if len(notifier.sent) != 2 || notifier.sent[0].Key != i18n.EmailVerify || notifier.sent[0].Address != "new@example.com" ||
+		notifier.sent[1].Key != i18n.EmailChange || notifier.sent[1].Address != "" {
+		t.Fatalf("Expected a verification link and a notice, got %+v", notifier.sent)
+	}
+	url := notifier.sent[0].Args[1].(string)
+	token, ok := strings.CutPrefix(url, "https://tickets.example.com/confirm-email?token=")
+	if !ok {
+		t.Fatalf("Unexpected verification link %s", url)
+	}
+
+	if _, err := changes.Confirm("wrong"); !errors.Is(err, ErrEmailChangeInvalid) {
+		t.Errorf("Expected an unknown token to be rejected, got %v", err)
+	}
+	confirmed, err := changes.Confirm(token)
+	if err != nil || confirmed.Email != "new@example.com" || confirmed.PendingEmail != nil {
+		t.Fatalf("Expected the email to change, got %+v (%v)", confirmed, err)
+	}
+
+	// An address taken before the link is used cannot be confirmed
+	other := &models.User{Email: "other@example.com", Name: "Other"}
+	if err := store.Users().Create(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := changes.Request(other, "taken@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users().Create(&models.User{Email: "taken@example.com", Name: "Taken"}); err != nil {
+		t.Fatal(err)
+	}
+	token = strings.TrimPrefix(notifier.sent[2].Args[1].(string), "https://tickets.example.com/confirm-email?token=")
+	if _, err := changes.Confirm(token); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Expected a taken address to be refused, got %v", err)
+	}
+}
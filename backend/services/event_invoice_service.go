@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// ErrEventInvoicePaid is returned for revoking an invoice that was paid
+var ErrEventInvoicePaid = errors.New("the invoice has been paid")
+
+// EventInvoiceService manages events' standing UMA Request invoices, the
+// invoice an admin creates for an event's UMA address. The invoice's
+// status is kept in step with the Lightning backend, and it is replaced
+// once it expires or the event's price changes. A revoked invoice leaves
+// the event without one until an admin creates another.
+type EventInvoiceService struct {
+	repo   repositories.UMARequestInvoiceRepository
+	uma    UMAService
+	domain string
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewEventInvoiceService creates the service for invoices paid to UMA
+// addresses on domain
+func NewEventInvoiceService(repo repositories.UMARequestInvoiceRepository, uma UMAService, domain string, clk clock.Clock, logger *slog.Logger) *EventInvoiceService {
+	return &EventInvoiceService{
+		repo:   repo,
+		uma:    uma,
+		domain: domain,
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// Issue creates a new standing invoice for the event at its price. A
+// pending invoice it replaces is cancelled.
+func (s *EventInvoiceService) Issue(event *models.Event) (*models.UMARequestInvoice, error) {
+	replaced, err := s.repo.GetByEventID(event.ID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	umaAddress := models.EventUMAAddress(event.ID, s.domain)
+	description := fmt.Sprintf("Event Ticket: %s", event.Title)
+	created, err := s.uma.CreateUMARequest(umaAddress, event.PriceSats, description, event.InvoiceExpiry(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := event.ID
+	invoice := &models.UMARequestInvoice{
+		EventID:     &eventID,
+		Source:      models.UMAInvoiceAdmin,
+		InvoiceID:   created.ID,
+		PaymentHash: created.PaymentHash,
+		Bolt11:      created.Bolt11,
+		AmountSats:  created.AmountSats,
+		Status:      created.Status,
+		UMAAddress:  umaAddress,
+		Description: description,
+		ExpiresAt:   created.ExpiresAt,
+	}
+	if err := s.repo.Create(invoice); err != nil {
+		return nil, fmt.Errorf("failed to save UMA invoice %s: %w", created.ID, err)
+	}
+
+	// The new invoice is the event's from now on, so one left pending is
+	// only logged
+	if replaced != nil && replaced.Status == models.PaymentPending {
+		replaced.Status = models.PaymentCancelled
+		if err := s.repo.Update(replaced); err != nil {
+			s.logger.Error("Failed to cancel replaced UMA invoice", "event_id", event.ID, "invoice_id", replaced.InvoiceID, "error", err)
+		}
+	}
+
+	s.logger.Info("Event UMA invoice issued", "event_id", event.ID, "invoice_id", invoice.InvoiceID, "amount_sats", invoice.AmountSats)
+	return invoice, nil
+}
+
+// Current returns the event's standing invoice with its status synced. An
+// invoice that expired, or is pending at a price the event no longer has,
+// is replaced and regenerated reports true. A free event needs no invoice:
+// a pending one is revoked and repositories.ErrNotFound returned.
+func (s *EventInvoiceService) Current(event *models.Event) (invoice *models.UMARequestInvoice, regenerated bool, err error) {
+	invoice, err = s.repo.GetByEventID(event.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.sync(invoice); err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case event.PriceSats <= 0:
+		if invoice.Status != models.PaymentPending {
+			return invoice, false, nil
+		}
+		if err := s.cancel(invoice); err != nil {
+			return nil, false, err
+		}
+		return nil, false, repositories.ErrNotFound
+	case invoice.Status == models.PaymentExpired,
+		invoice.Status == models.PaymentPending && invoice.AmountSats != event.PriceSats:
+		invoice, err = s.Issue(event)
+		return invoice, err == nil, err
+	}
+	return invoice, false, nil
+}
+
+// Revoke cancels the event's standing invoice and returns it. A payment
+// to it that still arrives settles and is attributed to the event.
+func (s *EventInvoiceService) Revoke(eventID int) (*models.UMARequestInvoice, error) {
+	invoice, err := s.repo.GetByEventID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sync(invoice); err != nil {
+		return nil, err
+	}
+	if invoice.Status == models.PaymentPaid {
+		return nil, ErrEventInvoicePaid
+	}
+	return invoice, s.cancel(invoice)
+}
+
+// sync updates a pending invoice's status from the Lightning backend. When
+// the backend cannot say, the invoice expires by its expiry time.
+func (s *EventInvoiceService) sync(invoice *models.UMARequestInvoice) error {
+	if invoice.Status != models.PaymentPending {
+		return nil
+	}
+
+	status := invoice.Status
+	backend, err := s.uma.CheckPaymentStatus(invoice.InvoiceID)
+	if err != nil {
+		s.logger.Warn("Failed to check UMA invoice status", "invoice_id", invoice.InvoiceID, "error", err)
+	} else if backend.Status == models.PaymentPaid || backend.Status == models.PaymentExpired {
+		status = backend.Status
+	}
+	if status == models.PaymentPending && invoice.ExpiresAt != nil && !s.clock.Now().Before(*invoice.ExpiresAt) {
+		status = models.PaymentExpired
+	}
+	if status == invoice.Status {
+		return nil
+	}
+
+	invoice.Status = status
+	if err := s.repo.Update(invoice); err != nil {
+		return fmt.Errorf("failed to update status of UMA invoice %d: %w", invoice.ID, err)
+	}
+	return nil
+}
+
+func (s *EventInvoiceService) cancel(invoice *models.UMARequestInvoice) error {
+	invoice.Status = models.PaymentCancelled
+	if err := s.repo.Update(invoice); err != nil {
+		return fmt.Errorf("failed to cancel UMA invoice %d: %w", invoice.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestEventInvoiceLifecycle(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clk)
+	uma := NewSimulatedUMAService(0, clk, logger)
+	service := NewEventInvoiceService(store.UMARequestInvoices(), uma, "tickets.example.com", clk, logger)
+
+	event := &models.Event{Title: "Gig", StartTime: clk.Now().Add(24 * time.Hour), EndTime: clk.Now().Add(25 * time.Hour),
+		Capacity: 10, PriceSats: 1000, IsActive: true, InvoiceExpirySeconds: 600}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.Current(event); !errors.Is(err, repositories.ErrNotFound) {
+		t.Fatalf("Expected no invoice before one is issued, got %v", err)
+	}
+
+	first, err := service.Issue(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Source != models.UMAInvoiceAdmin || first.UMAAddress != "$event-1@tickets.example.com" || first.AmountSats != 1000 {
+		t.Errorf("Expected an admin invoice for the event's address, got %+v", first)
+	}
+	if current, regenerated, err := service.Current(event); err != nil || regenerated || current.ID != first.ID {
+		t.Errorf("Expected the issued invoice back unchanged, got %+v, %v, %v", current, regenerated, err)
+	}
+
+	// A price change replaces the pending invoice, which is cancelled
+	event.PriceSats = 1500
+	repriced, regenerated, err := service.Current(event)
+	if err != nil || !regenerated || repriced.ID == first.ID || repriced.AmountSats != 1500 {
+		t.Fatalf("Expected a new invoice at the new price, got %+v, %v, %v", repriced, regenerated, err)
+	}
+	if old, _ := store.UMARequestInvoices().GetByPaymentHash(first.PaymentHash); old.Status != models.PaymentCancelled {
+		t.Errorf("Expected the replaced invoice cancelled, got %s", old.Status)
+	}
+
+	// So does its expiry, which is read from the clock when the backend
+	// still reports it open
+	clk.Advance(10 * time.Minute)
+	renewed, regenerated, err := service.Current(event)
+	if err != nil || !regenerated || renewed.ID == repriced.ID || renewed.Status != models.PaymentPending {
+		t.Fatalf("Expected an expired invoice regenerated, got %+v, %v, %v", renewed, regenerated, err)
+	}
+	if old, _ := store.UMARequestInvoices().GetByPaymentHash(repriced.PaymentHash); old.Status != models.PaymentExpired {
+		t.Errorf("Expected the expired invoice kept as expired, got %s", old.Status)
+	}
+
+	// A paid invoice is synced from the backend and cannot be revoked
+	if err := uma.SimulateIncomingPayment(renewed.Bolt11); err != nil {
+		t.Fatal(err)
+	}
+	if current, _, err := service.Current(event); err != nil || current.Status != models.PaymentPaid {
+		t.Errorf("Expected the invoice synced as paid, got %+v, %v", current, err)
+	}
+	if _, err := service.Revoke(event.ID); !errors.Is(err, ErrEventInvoicePaid) {
+		t.Errorf("Expected a paid invoice not to be revoked, got %v", err)
+	}
+
+	// Revoking leaves the event without an invoice
+	issued, err := service.Issue(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := service.Revoke(event.ID); err != nil || revoked.ID != issued.ID || revoked.Status != models.PaymentCancelled {
+		t.Errorf("Expected the invoice revoked, got %+v, %v", revoked, err)
+	}
+	if _, _, err := service.Current(event); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected no invoice after revoking, got %v", err)
+	}
+	if _, err := service.Revoke(event.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected nothing left to revoke, got %v", err)
+	}
+
+	// A free event needs none: its pending invoice is revoked
+	if _, err := service.Issue(event); err != nil {
+		t.Fatal(err)
+	}
+	event.PriceSats = 0
+	if _, _, err := service.Current(event); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected a free event's invoice revoked, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// CoinbaseSpotURL is Coinbase's spot price API. The currency pair (e.g.
+// BTC-USD) and "/spot" are appended; it answers with the price of one
+// bitcoin as a decimal string.
+const CoinbaseSpotURL = "https://api.coinbase.com/v2/prices/"
+
+// valuationBatch is how many payments Sync values per query
+const valuationBatch = 100
+
+// satsPerBTC converts exchange rates, quoted per bitcoin, to satoshis
+const satsPerBTC = 100_000_000
+
+// RateSource quotes bitcoin's current price in a fiat currency, in the
+// currency's minor units
+type RateSource interface {
+	// Name identifies the source in stored rates
+	Name() string
+	Rate(ctx context.Context, currency string) (int64, error)
+}
+
+// FixedRateSource always quotes the same rate, for development and tests
+type FixedRateSource int64
+
+func (FixedRateSource) Name() string { return "fixed" }
+
+func (r FixedRateSource) Rate(ctx context.Context, currency string) (int64, error) {
+	return int64(r), nil
+}
+
+// CoinbaseRateSource quotes Coinbase's spot price
+type CoinbaseRateSource struct {
+	spotURL string
+	client  *http.Client
+}
+
+// NewCoinbaseRateSource creates a rate source querying spotURL with client
+func NewCoinbaseRateSource(spotURL string, client *http.Client) *CoinbaseRateSource {
+	return &CoinbaseRateSource{spotURL: spotURL, client: client}
+}
+
+func (s *CoinbaseRateSource) Name() string { return "coinbase" }
+
+func (s *CoinbaseRateSource) Rate(ctx context.Context, currency string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.spotURL+"BTC-"+currency+"/spot", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("spot price lookup returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Amount   string `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("invalid spot price response: %w", err)
+	}
+	if body.Data.Currency != currency {
+		return 0, fmt.Errorf("spot price quoted in %q, not %s", body.Data.Currency, currency)
+	}
+	price, ok := new(big.Rat).SetString(body.Data.Amount)
+	if !ok || price.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid spot price %q", body.Data.Amount)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currencyExponent(currency))), nil)
+	minor := roundHalfUp(price.Mul(price, new(big.Rat).SetInt(scale)))
+	if !minor.IsInt64() || minor.Sign() <= 0 {
+		return 0, fmt.Errorf("spot price %q out of range", body.Data.Amount)
+	}
+	return minor.Int64(), nil
+}
+
+// ExchangeRateService keeps a history of bitcoin's price in the currency
+// of record and values each paid payment at the latest rate fetched
+// before it was paid. A payment's value is locked once set, so reports in
+// the currency of record do not move with the bitcoin price.
+type ExchangeRateService struct {
+	repo     repositories.ExchangeRateRepository
+	source   RateSource
+	currency string
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewExchangeRateService creates an exchange rate service valuing payments
+// in currency at rates quoted by source
+func NewExchangeRateService(repo repositories.ExchangeRateRepository, source RateSource, currency string, clk clock.Clock, logger *slog.Logger) *ExchangeRateService {
+	return &ExchangeRateService{
+		repo:     repo,
+		source:   source,
+		currency: currency,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// Refresh fetches the current rate from the source and stores it
+func (s *ExchangeRateService) Refresh(ctx context.Context) (*models.ExchangeRate, error) {
+	minor, err := s.source.Rate(ctx, s.currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s rate from %s: %w", s.currency, s.source.Name(), err)
+	}
+	rate := &models.ExchangeRate{
+		Currency:         s.currency,
+		MinorUnitsPerBTC: minor,
+		Source:           s.source.Name(),
+		FetchedAt:        s.clock.Now(),
+	}
+	if err := s.repo.Create(rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Sync values every paid payment that has no value yet at the latest rate
+// fetched before it was paid, returning how many it valued. Payments paid
+// before the first stored rate stay unvalued.
+func (s *ExchangeRateService) Sync() (int, error) {
+	valued := 0
+	for {
+		valuations, err := s.repo.Unvalued(s.currency, valuationBatch)
+		if err != nil {
+			return valued, err
+		}
+		for _, valuation := range valuations {
+			amount := FiatValue(valuation.AmountSats, valuation.MinorUnitsPerBTC)
+			err := s.repo.SetFiatValue(valuation.PaymentID, valuation.RateID, amount)
+			if errors.Is(err, repositories.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return valued, fmt.Errorf("failed to value payment %d: %w", valuation.PaymentID, err)
+			}
+			valued++
+		}
+		if len(valuations) < valuationBatch {
+			return valued, nil
+		}
+	}
+}
+
+// Watch fetches a rate and values newly paid payments right away and then
+// every interval until ctx is done
+func (s *ExchangeRateService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.update(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ExchangeRateService) update(ctx context.Context) {
+	// Value payments first so those paid since the last rate get it
+	// rather than the one about to be fetched
+	valued, err := s.Sync()
+	if err != nil {
+		s.logger.Error("Failed to value payments", "currency", s.currency, "error", err)
+	}
+	if valued > 0 {
+		s.logger.Info("Valued payments", "currency", s.currency, "count", valued)
+	}
+
+	if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Error("Failed to refresh exchange rate", "error", err)
+	}
+}
+
+// FiatValue converts sats to fiat minor units at minorPerBTC, rounding
+// half up
+func FiatValue(sats, minorPerBTC int64) int64 {
+	value := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(sats), big.NewInt(minorPerBTC)),
+		big.NewInt(satsPerBTC))
+	return roundHalfUp(value).Int64()
+}
+
+// roundHalfUp rounds a non-negative r to the nearest integer, halves up
+func roundHalfUp(r *big.Rat) *big.Int {
+	num := new(big.Int).Mul(r.Num(), big.NewInt(2))
+	num.Add(num, r.Denom())
+	return num.Quo(num, new(big.Int).Mul(r.Denom(), big.NewInt(2)))
+}
+
+// currencyExponent is the number of decimal places of an ISO 4217
+// currency's minor unit
+func currencyExponent(currency string) int {
+	switch currency {
+	case "BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG", "RWF", "UGX", "UYI", "VND", "VUV", "XAF", "XOF", "XPF":
+		return 0
+	case "BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND":
+		return 3
+	}
+	return 2
+}
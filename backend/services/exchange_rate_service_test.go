@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestExchangeRateService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	source := FixedRateSource(6_000_000) // $60,000.00
+	rates := NewExchangeRateService(store.ExchangeRates(), source, "USD", clk, logger)
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	pay := func(amount int64) *models.Payment {
+		t.Helper()
+		n++
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: "FIAT-" + strconv.Itoa(n), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-fiat-" + strconv.Itoa(n), Amount: amount, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		return payment
+	}
+	value := func(payment *models.Payment) *int64 {
+		t.Helper()
+		stored, err := store.Payments().GetByID(payment.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.FiatAmount
+	}
+
+	// Nothing is valued before the first rate
+	early := pay(1000)
+	if valued, err := rates.Sync(); err != nil || valued != 0 {
+		t.Errorf("Expected nothing valued without a rate, got %d (%v)", valued, err)
+	}
+
+	clk.Advance(time.Minute)
+	rate, err := rates.Refresh(context.Background())
+	if err != nil || rate.MinorUnitsPerBTC != 6_000_000 || rate.Source != "fixed" || rate.Currency != "USD" {
+		t.Fatalf("Expected the fixed rate stored, got %+v (%v)", rate, err)
+	}
+	clk.Advance(time.Minute)
+	first := pay(12_345) // $7.407 at $60,000
+	if valued, err := rates.Sync(); err != nil || valued != 1 {
+		t.Fatalf("Expected one payment valued, got %d (%v)", valued, err)
+	}
+	if got := value(first); got == nil || *got != 741 {
+		t.Errorf("Expected 741 cents, got %v", got)
+	}
+
+	// A later rate values later payments and leaves earlier values alone
+	rates.source = FixedRateSource(9_000_000)
+	clk.Advance(time.Minute)
+	if _, err := rates.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+	second := pay(100_000)
+	if valued, err := rates.Sync(); err != nil || valued != 1 {
+		t.Fatalf("Expected one payment valued, got %d (%v)", valued, err)
+	}
+	if got := value(second); got == nil || *got != 9000 {
+		t.Errorf("Expected 9000 cents at the later rate, got %v", got)
+	}
+	if got := value(first); got == nil || *got != 741 {
+		t.Errorf("Expected the first value locked, got %v", got)
+	}
+	if got := value(early); got != nil {
+		t.Errorf("Expected the payment made before any rate left unvalued, got %d", *got)
+	}
+}
+
+func TestCoinbaseRateSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/BTC-USD/spot":
+			w.Write([]byte(`{"data":{"amount":"65432.125","base":"BTC","currency":"USD"}}`))
+		case "/BTC-JPY/spot":
+			w.Write([]byte(`{"data":{"amount":"9876543.5","base":"BTC","currency":"JPY"}}`))
+		case "/BTC-EUR/spot":
+			w.Write([]byte(`{"data":{"amount":"60000.00","base":"BTC","currency":"USD"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	source := NewCoinbaseRateSource(server.URL+"/", server.Client())
+
+	for currency, want := range map[string]int64{"USD": 6_543_213, "JPY": 9_876_544} {
+		if got, err := source.Rate(context.Background(), currency); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d (%v)", currency, want, got, err)
+		}
+	}
+	for _, currency := range []string{"EUR", "XYZ"} {
+		if got, err := source.Rate(context.Background(), currency); err == nil {
+			t.Errorf("%s: expected an error, got %d", currency, got)
+		}
+	}
+}
+
+func TestFiatValue(t *testing.T) {
+	for _, tc := range []struct {
+		sats, rate, want int64
+	}{
+		{100_000_000, 6_000_000, 6_000_000},
+		{50, 6_000_000, 3}, // 3.0
+		{25, 6_000_000, 2}, // 1.5 rounds up
+		{24, 6_000_000, 1}, // 1.44
+		{0, 6_000_000, 0},
+		{100_000_000, 1_500_000_000_000, 1_500_000_000_000},
+	} {
+		if got := FiatValue(tc.sats, tc.rate); got != tc.want {
+			t.Errorf("FiatValue(%d, %d) = %d, want %d", tc.sats, tc.rate, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// FeeService works out the platform fee on a sale: the event organizer's
+// override when one is set, otherwise the configured default.
+type FeeService struct {
+	repo     repositories.FeeRepository
+	defaults config.FeeSchedule
+}
+
+// NewFeeService creates a fee service charging defaults unless an
+// organizer has an override in repo.
+func NewFeeService(repo repositories.FeeRepository, defaults config.FeeSchedule) *FeeService {
+	return &FeeService{repo: repo, defaults: defaults}
+}
+
+// Default returns the platform-wide fee schedule.
+func (s *FeeService) Default() config.FeeSchedule {
+	return s.defaults
+}
+
+// ScheduleFor returns the fee schedule for sales of event.
+func (s *FeeService) ScheduleFor(event *models.Event) (config.FeeSchedule, error) {
+	if event.OrganizerID == nil {
+		return s.defaults, nil
+	}
+	fee, err := s.repo.GetOverride(*event.OrganizerID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return s.defaults, nil
+	}
+	if err != nil {
+		return config.FeeSchedule{}, err
+	}
+	return config.FeeSchedule{BasisPoints: fee.BasisPoints, FixedSats: fee.FixedSats}, nil
+}
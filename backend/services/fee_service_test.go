@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestFeeServiceScheduleFor(t *testing.T) {
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	defaults := config.FeeSchedule{BasisPoints: 500, FixedSats: 10}
+	fees := NewFeeService(store.Fees(), defaults)
+
+	organizer, other := 1, 2
+	if err := store.Fees().SetOverride(&models.OrganizerFee{OrganizerID: organizer, BasisPoints: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event *models.Event
+		want  config.FeeSchedule
+	}{
+		{"no organizer", &models.Event{}, defaults},
+		{"organizer without override", &models.Event{OrganizerID: &other}, defaults},
+		{"organizer override", &models.Event{OrganizerID: &organizer}, config.FeeSchedule{BasisPoints: 100}},
+	}
+	for _, tt := range tests {
+		got, err := fees.ScheduleFor(tt.event)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %+v (%v), want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
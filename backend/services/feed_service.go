@@ -0,0 +1,195 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// maxFeedItems is how many upcoming events a feed lists
+	maxFeedItems = 100
+	feedTitle    = "Tickets by UMA: upcoming events"
+)
+
+// FeedService renders the calendar of upcoming public events as RSS 2.0
+// and Atom feeds, optionally limited to some categories, for communities to
+// syndicate.
+type FeedService struct {
+	eventRepo repositories.EventRepository
+	domain    string
+	clock     clock.Clock
+}
+
+// NewFeedService creates a feed service linking to pages served at
+// https://domain
+func NewFeedService(eventRepo repositories.EventRepository, domain string, clk clock.Clock) *FeedService {
+	return &FeedService{
+		eventRepo: eventRepo,
+		domain:    domain,
+		clock:     clk,
+	}
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Link      atomLink      `xml:"link"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Summary   string        `xml:"summary"`
+	Category  *atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// RSS renders the upcoming events in categories (all when empty) as an RSS
+// 2.0 feed
+func (s *FeedService) RSS(categories []string) ([]byte, error) {
+	events, err := s.eventRepo.GetUpcoming(s.clock.Now(), categories, maxFeedItems)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:         feedTitle,
+		Link:          s.url("/"),
+		Description:   "Upcoming events with tickets on sale",
+		LastBuildDate: s.clock.Now().UTC().Format(time.RFC1123Z),
+		Items:         []rssItem{},
+	}}
+	for _, event := range events {
+		link := s.eventURL(event.ID)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       event.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			Description: feedSummary(event),
+			Category:    event.Category,
+			PubDate:     event.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshalFeed(feed)
+}
+
+// Atom renders the upcoming events in categories (all when empty) as an
+// Atom feed, identified by its own URL
+func (s *FeedService) Atom(categories []string) ([]byte, error) {
+	events, err := s.eventRepo.GetUpcoming(s.clock.Now(), categories, maxFeedItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// A feed with no entries was last updated when it was built
+	updated := s.clock.Now()
+	if len(events) > 0 {
+		updated = time.Time{}
+	}
+	self := s.url("/api/events/feed.atom")
+	if len(categories) > 0 {
+		self += "?category=" + url.QueryEscape(strings.Join(categories, ","))
+	}
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		ID:      self,
+		Title:   feedTitle,
+		Links:   []atomLink{{Href: self, Rel: "self"}, {Href: s.url("/")}},
+		Entries: []atomEntry{},
+	}
+	for _, event := range events {
+		link := s.eventURL(event.ID)
+		entry := atomEntry{
+			ID:        link,
+			Title:     event.Title,
+			Link:      atomLink{Href: link},
+			Published: event.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   event.UpdatedAt.UTC().Format(time.RFC3339),
+			Summary:   feedSummary(event),
+		}
+		if event.Category != "" {
+			entry.Category = &atomCategory{Term: event.Category}
+		}
+		feed.Entries = append(feed.Entries, entry)
+		if event.UpdatedAt.After(updated) {
+			updated = event.UpdatedAt
+		}
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	return marshalFeed(feed)
+}
+
+func (s *FeedService) url(path string) string {
+	return "https://" + s.domain + path
+}
+
+func (s *FeedService) eventURL(eventID int) string {
+	return s.url(fmt.Sprintf("/events/%d", eventID))
+}
+
+// feedSummary leads an event's description with when it takes place
+func feedSummary(event models.Event) string {
+	summary := "Starts " + event.StartTime.UTC().Format("Mon, 02 Jan 2006 15:04 MST")
+	if event.Description != "" {
+		summary += ". " + event.Description
+	}
+	return summary
+}
+
+func marshalFeed(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
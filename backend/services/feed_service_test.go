@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestFeedService(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	feeds := NewFeedService(store.Events(), "tickets.example", clk)
+
+	start := clk.Now().Add(24 * time.Hour)
+	for _, event := range []*models.Event{
+		{Title: "Jazz & Blues", Description: "Live", Category: "music", StartTime: start.Add(time.Hour), EndTime: start.Add(3 * time.Hour), IsActive: true},
+		{Title: "Go Workshop", Category: "tech", StartTime: start, EndTime: start.Add(2 * time.Hour), IsActive: true},
+		{Title: "Private", Category: "music", StartTime: start, EndTime: start.Add(2 * time.Hour), IsActive: true, IsPrivate: true},
+		{Title: "Over", Category: "music", StartTime: clk.Now().Add(-3 * time.Hour), EndTime: clk.Now().Add(-time.Hour), IsActive: true},
+	} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body, err := feeds.RSS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rss rssFeed
+	if err := xml.Unmarshal(body, &rss); err != nil {
+		t.Fatalf("Expected valid XML: %v\n%s", err, body)
+	}
+	items := rss.Channel.Items
+	if len(items) != 2 || items[0].Title != "Go Workshop" || items[1].Title != "Jazz & Blues" || items[1].Link != "https://tickets.example/events/1" ||
+		items[1].Category != "music" || !strings.HasPrefix(items[1].Description, "Starts Sat, 17 Oct 2026 13:00 UTC. Live") {
+		t.Errorf("Expected the upcoming public events soonest first, got %+v", items)
+	}
+
+	body, err = feeds.Atom([]string{"music"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var atom atomFeed
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		t.Fatalf("Expected valid XML: %v\n%s", err, body)
+	}
+	if len(atom.Entries) != 1 || atom.Entries[0].Title != "Jazz & Blues" || atom.Entries[0].Category.Term != "music" ||
+		atom.ID != "https://tickets.example/api/events/feed.atom?category=music" || atom.Updated != "2026-10-16T12:00:00Z" {
+		t.Errorf("Expected the music events only, got %+v", atom)
+	}
+}
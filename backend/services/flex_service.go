@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// Flex cancellation errors
+var (
+	ErrFlexNotBought = errors.New("ticket was not bought with a flex add-on")
+	ErrFlexUnpaid    = errors.New("only paid tickets can be cancelled")
+	ErrFlexClosed    = errors.New("the flex cancellation window has closed")
+)
+
+// FlexService runs flex add-ons, which entitle their buyer to cancel the
+// ticket until the add-on's cutoff before the event starts. The payment is
+// refunded through the ledger like other refunds, flex add-on included.
+type FlexService struct {
+	addOnRepo   repositories.AddOnRepository
+	eventRepo   repositories.EventRepository
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	ledger      *LedgerService
+	notifier    Notifier
+	admins      AdminNotifier
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+// NewFlexService creates a flex service. ledger, notifier and admins may be
+// nil.
+func NewFlexService(addOnRepo repositories.AddOnRepository, eventRepo repositories.EventRepository, ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, ledger *LedgerService, notifier Notifier, admins AdminNotifier, clk clock.Clock, logger *slog.Logger) *FlexService {
+	return &FlexService{
+		addOnRepo:   addOnRepo,
+		eventRepo:   eventRepo,
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		ledger:      ledger,
+		notifier:    notifier,
+		admins:      admins,
+		clock:       clk,
+		logger:      logger,
+	}
+}
+
+// FlexDeadline returns when a flex add-on bought with a ticket to event
+// stops allowing cancellation
+func FlexDeadline(event *models.Event, item models.TicketAddOn) time.Time {
+	return event.StartTime.Add(-time.Duration(*item.FlexCutoffHours) * time.Hour)
+}
+
+// Cancel cancels a paid ticket at its buyer's request and books the refund
+// of its payment, using the ticket's flex add-on. It returns the add-on's
+// line item, or ErrFlexNotBought, ErrFlexUnpaid or ErrFlexClosed when the
+// ticket cannot be cancelled.
+func (s *FlexService) Cancel(ticket *models.Ticket) (*models.TicketAddOn, error) {
+	items, err := s.addOnRepo.GetLineItems(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	var flex *models.TicketAddOn
+	for i := range items {
+		if items[i].FlexCutoffHours != nil {
+			flex = &items[i]
+		}
+	}
+	if flex == nil {
+		return nil, ErrFlexNotBought
+	}
+	if ticket.PaymentStatus != "paid" {
+		return nil, ErrFlexUnpaid
+	}
+	event, err := s.eventRepo.GetByID(ticket.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.CancelledAt != nil || !s.clock.Now().Before(FlexDeadline(event, *flex)) {
+		return nil, ErrFlexClosed
+	}
+
+	reference := fmt.Sprintf("flex cancellation of ticket %d", ticket.ID)
+	if err := cancelTicket(s.ticketRepo, s.paymentRepo, s.ledger, ticket, reference); err != nil {
+		return nil, err
+	}
+	usedAt, err := s.addOnRepo.UseFlex(flex.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ticket %d cancelled but its flex add-on not marked used: %w", ticket.ID, err)
+	}
+	flex.FlexUsedAt = &usedAt
+	s.logger.Info("Ticket cancelled with flex", "ticket_id", ticket.ID, "event_id", ticket.EventID, "addon_id", flex.AddOnID)
+
+	if s.admins != nil {
+		s.admins.NotifyAdmins(refundRequested(ticket, event.Title, "with its flex add-on"))
+	}
+	if s.notifier != nil {
+		notification := i18n.Notification{Key: i18n.FlexCancelled, Args: []any{event.Title, ticket.TicketCode}, EventID: event.ID}
+		if err := s.notifier.NotifyUser(ticket.UserID, notification); err != nil {
+			s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+		}
+	}
+	return flex, nil
+}
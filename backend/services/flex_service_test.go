@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestFlexService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	notifier := &giftNotifier{}
+	admins := NewAdminNotificationService(store.AdminNotifications(), store.Events(), logger)
+	flex := NewFlexService(store.AddOns(), store.Events(), store.Tickets(), store.Payments(), ledger, notifier, admins, clk, logger)
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paid tickets with and without a 48 hour flex add-on, and an unpaid one
+	// with it
+	buy := func(code, status string, withFlex bool) *models.Ticket {
+		t.Helper()
+		ticket := &models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: code, PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-" + code, Amount: 1100, Status: status}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if withFlex {
+			hours := 48
+			if err := store.AddOns().CreateLineItems([]models.TicketAddOn{
+				{TicketID: ticket.ID, Name: "Flex", Quantity: 1, UnitPriceSats: 100, FlexCutoffHours: &hours},
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return ticket
+	}
+	flexed, plain, unpaid := buy("FLEX-1", "paid", true), buy("FLEX-2", "paid", false), buy("FLEX-3", "pending", true)
+
+	if _, err := flex.Cancel(plain); !errors.Is(err, ErrFlexNotBought) {
+		t.Errorf("Expected ErrFlexNotBought, got %v", err)
+	}
+	if _, err := flex.Cancel(unpaid); !errors.Is(err, ErrFlexUnpaid) {
+		t.Errorf("Expected ErrFlexUnpaid, got %v", err)
+	}
+
+	item, err := flex.Cancel(flexed)
+	if err != nil || item.FlexUsedAt == nil || !item.FlexUsedAt.Equal(clk.Now()) {
+		t.Fatalf("Expected the ticket cancelled with flex, got %+v (%v)", item, err)
+	}
+	if ticket, _ := store.Tickets().GetByID(flexed.ID); ticket.PaymentStatus != "cancelled" {
+		t.Errorf("Expected the ticket cancelled, got %q", ticket.PaymentStatus)
+	}
+	if payment, _ := store.Payments().GetByTicketID(flexed.ID); payment.Status != "cancelled" {
+		t.Errorf("Expected the payment cancelled, got %q", payment.Status)
+	}
+	if len(notifier.notifications) != 1 || notifier.notifications[0].Key != i18n.FlexCancelled || notifier.users[0] != buyer.ID {
+		t.Errorf("Expected the buyer notified, got %+v", notifier.notifications)
+	}
+	if feed, _ := admins.After(0, 10); len(feed) != 1 || feed[0].Kind != models.AdminNotificationRefundRequested || *feed[0].EntityID != flexed.ID {
+		t.Errorf("Expected the admins told to send the refund, got %+v", feed)
+	}
+	if _, err := flex.Cancel(flexed); !errors.Is(err, ErrFlexUnpaid) {
+		t.Errorf("Expected ErrFlexUnpaid cancelling twice, got %v", err)
+	}
+
+	// The window closes 48 hours before the start
+	late := buy("FLEX-4", "paid", true)
+	clk.Advance(24 * time.Hour)
+	if _, err := flex.Cancel(late); !errors.Is(err, ErrFlexClosed) {
+		t.Errorf("Expected ErrFlexClosed at the cutoff, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// Fraud actions, from least to most severe. An attempt takes the most
+// severe action of the rules it trips.
+const (
+	FraudActionAllow  = "allow"
+	FraudActionReview = "review"
+	FraudActionBlock  = "block"
+)
+
+// Fraud rule names reported in FraudSignal.Rule
+const (
+	FraudRuleIPVelocity      = "ip_velocity"
+	FraudRuleUserVelocity    = "user_velocity"
+	FraudRuleUMAVelocity     = "uma_velocity"
+	FraudRuleDisposableEmail = "disposable_email"
+	FraudRuleCountryChange   = "country_change"
+	FraudRuleUMACountry      = "uma_domain_country"
+)
+
+// Defaults used when the fraud.* runtime settings are unset. Nothing is
+// blocked out of the box; every rule only flags for review.
+const (
+	defaultFraudWindowMin  = 10
+	defaultFraudMaxPerIP   = 20
+	defaultFraudMaxPerUser = 10
+	defaultFraudMaxPerUMA  = 10
+)
+
+// disposableEmailDomains lists well-known throwaway mailbox providers.
+// Admins extend it with the fraud.disposable_email_domains setting.
+var disposableEmailDomains = []string{
+	"10minutemail.com", "dispostable.com", "fakeinbox.com", "getnada.com",
+	"guerrillamail.com", "mailinator.com", "maildrop.cc", "mailnesia.com",
+	"mintemail.com", "sharklasers.com", "temp-mail.org", "tempmail.com",
+	"throwawaymail.com", "trashmail.com", "yopmail.com",
+}
+
+// genericCCTLDs are country-code TLDs commonly used without any tie to the
+// country, so they say nothing about where a wallet's users are.
+var genericCCTLDs = map[string]bool{
+	"ai": true, "co": true, "fm": true, "gg": true, "io": true,
+	"me": true, "sh": true, "to": true, "tv": true, "ws": true,
+}
+
+// FraudCheck describes a purchase attempt. ClientIP and Country are read
+// from the request when empty.
+type FraudCheck struct {
+	UserID     int
+	EventID    int
+	UMAAddress string
+	ClientIP   string
+	Country    string // ISO 3166-1 alpha-2, from the geo header
+}
+
+// FraudAssessment is the outcome of evaluating a FraudCheck
+type FraudAssessment struct {
+	Action  string
+	Signals models.FraudSignals
+}
+
+// Flagged reports whether any rule fired.
+func (a FraudAssessment) Flagged() bool {
+	return len(a.Signals) > 0
+}
+
+// FraudService scores purchase attempts against velocity, disposable email
+// and geo rules configured through runtime settings. Velocity is counted in
+// process memory, like the purchase rate limiter, so limits apply per instance.
+type FraudService struct {
+	repo          repositories.FraudFlagRepository
+	userRepo      repositories.UserRepository
+	settings      *SettingsService
+	countryHeader string
+	clock         clock.Clock
+	logger        *slog.Logger
+
+	mu          sync.Mutex
+	attempts    map[string][]time.Time // velocity key -> attempt times
+	lastCountry map[int]countrySeen    // user ID -> country of the last attempt
+}
+
+type countrySeen struct {
+	country string
+	at      time.Time
+}
+
+// NewFraudService creates the fraud service. countryHeader names the header
+// a CDN or load balancer sets to the client's country (e.g. CF-IPCountry);
+// empty disables the geo rules.
+func NewFraudService(
+	repo repositories.FraudFlagRepository,
+	userRepo repositories.UserRepository,
+	settings *SettingsService,
+	countryHeader string,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *FraudService {
+	return &FraudService{
+		repo:          repo,
+		userRepo:      userRepo,
+		settings:      settings,
+		countryHeader: countryHeader,
+		clock:         clk,
+		logger:        logger,
+		attempts:      make(map[string][]time.Time),
+		lastCountry:   make(map[int]countrySeen),
+	}
+}
+
+// Evaluate scores a purchase attempt and counts it towards the velocity
+// limits.
+func (s *FraudService) Evaluate(r *http.Request, check FraudCheck) FraudAssessment {
+	if check.ClientIP == "" {
+		check.ClientIP = clientIP(r)
+	}
+	if check.Country == "" && s.countryHeader != "" {
+		check.Country = strings.ToUpper(strings.TrimSpace(r.Header.Get(s.countryHeader)))
+	}
+
+	var signals models.FraudSignals
+	signals = append(signals, s.velocitySignals(check)...)
+	signals = append(signals, s.emailSignals(check)...)
+	signals = append(signals, s.geoSignals(check)...)
+
+	assessment := FraudAssessment{Action: FraudActionAllow, Signals: signals}
+	for _, signal := range signals {
+		if severity(signal.Action) > severity(assessment.Action) {
+			assessment.Action = signal.Action
+		}
+	}
+	return assessment
+}
+
+// Record stores a flagged assessment for the admin queue. ticketID is nil
+// when the purchase was blocked before a ticket existed.
+func (s *FraudService) Record(r *http.Request, check FraudCheck, assessment FraudAssessment, ticketID *int) error {
+	if !assessment.Flagged() {
+		return nil
+	}
+	if check.ClientIP == "" {
+		check.ClientIP = clientIP(r)
+	}
+
+	s.logger.Warn("Purchase flagged by fraud checks",
+		"user_id", check.UserID,
+		"event_id", check.EventID,
+		"action", assessment.Action,
+		"signals", len(assessment.Signals))
+
+	return s.repo.Create(&models.FraudFlag{
+		UserID:   check.UserID,
+		EventID:  check.EventID,
+		TicketID: ticketID,
+		ClientIP: check.ClientIP,
+		Action:   assessment.Action,
+		Signals:  assessment.Signals,
+	})
+}
+
+// List returns recorded flags, newest first, optionally filtered by action.
+func (s *FraudService) List(action string, limit, offset int) ([]models.FraudFlag, error) {
+	return s.repo.List(action, limit, offset)
+}
+
+// ListAwaitingReview returns flags whose ticket is still held, oldest first.
+func (s *FraudService) ListAwaitingReview(limit, offset int) ([]models.FraudFlag, error) {
+	return s.repo.ListAwaitingReview(limit, offset)
+}
+
+func (s *FraudService) velocitySignals(check FraudCheck) []models.FraudSignal {
+	window := time.Duration(s.intSetting(SettingFraudVelocityWindowMin, defaultFraudWindowMin)) * time.Minute
+	action := s.actionSetting(SettingFraudVelocityAction)
+
+	rules := []struct {
+		rule  string
+		key   string
+		limit int
+	}{
+		{FraudRuleIPVelocity, "ip:" + check.ClientIP, s.intSetting(SettingFraudMaxPerIP, defaultFraudMaxPerIP)},
+		{FraudRuleUserVelocity, "user:" + strconv.Itoa(check.UserID), s.intSetting(SettingFraudMaxPerUser, defaultFraudMaxPerUser)},
+		{FraudRuleUMAVelocity, "uma:" + strings.ToLower(check.UMAAddress), s.intSetting(SettingFraudMaxPerUMA, defaultFraudMaxPerUMA)},
+	}
+
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop attempts that left the window so idle keys do not accumulate
+	for key, times := range s.attempts {
+		if len(times) > 0 && now.Sub(times[len(times)-1]) >= window {
+			delete(s.attempts, key)
+		}
+	}
+
+	var signals []models.FraudSignal
+	for _, rule := range rules {
+		if rule.key == "ip:" || rule.key == "uma:" {
+			continue
+		}
+		times := recent(append(s.attempts[rule.key], now), now, window)
+		s.attempts[rule.key] = times
+		if rule.limit > 0 && len(times) > rule.limit {
+			signals = append(signals, models.FraudSignal{
+				Rule:   rule.rule,
+				Detail: fmt.Sprintf("%d purchase attempts in %s (limit %d)", len(times), window, rule.limit),
+				Action: action,
+			})
+		}
+	}
+	return signals
+}
+
+func (s *FraudService) emailSignals(check FraudCheck) []models.FraudSignal {
+	if s.userRepo == nil {
+		return nil
+	}
+	user, err := s.userRepo.GetByID(check.UserID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			s.logger.Error("Failed to fetch user for fraud check", "user_id", check.UserID, "error", err)
+		}
+		return nil
+	}
+
+	_, domain, _ := strings.Cut(strings.ToLower(user.Email), "@")
+	domains := append(append([]string(nil), disposableEmailDomains...), s.stringsSetting(SettingFraudDisposableDomains)...)
+	for _, disposable := range domains {
+		disposable = strings.ToLower(disposable)
+		if domain == disposable || strings.HasSuffix(domain, "."+disposable) {
+			return []models.FraudSignal{{
+				Rule:   FraudRuleDisposableEmail,
+				Detail: "email domain " + domain + " is a disposable mailbox provider",
+				Action: s.actionSetting(SettingFraudDisposableAction),
+			}}
+		}
+	}
+	return nil
+}
+
+func (s *FraudService) geoSignals(check FraudCheck) []models.FraudSignal {
+	if len(check.Country) != 2 || check.Country == "XX" {
+		// Unknown or missing (Cloudflare reports XX/T1 for unknown and Tor)
+		return nil
+	}
+	action := s.actionSetting(SettingFraudGeoMismatchAction)
+	window := time.Duration(s.intSetting(SettingFraudVelocityWindowMin, defaultFraudWindowMin)) * time.Minute
+	now := s.clock.Now()
+
+	var signals []models.FraudSignal
+
+	s.mu.Lock()
+	last, seen := s.lastCountry[check.UserID]
+	s.lastCountry[check.UserID] = countrySeen{country: check.Country, at: now}
+	s.mu.Unlock()
+	if seen && last.country != check.Country && now.Sub(last.at) < window {
+		signals = append(signals, models.FraudSignal{
+			Rule:   FraudRuleCountryChange,
+			Detail: fmt.Sprintf("country changed from %s to %s within %s", last.country, check.Country, window),
+			Action: action,
+		})
+	}
+
+	// A wallet on a national domain is usually used from that country
+	_, domain, _ := strings.Cut(check.UMAAddress, "@")
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		tld := strings.ToLower(domain[i+1:])
+		country := strings.ToUpper(tld)
+		if tld == "uk" {
+			country = "GB"
+		}
+		if len(tld) == 2 && !genericCCTLDs[tld] && country != check.Country {
+			signals = append(signals, models.FraudSignal{
+				Rule:   FraudRuleUMACountry,
+				Detail: fmt.Sprintf("UMA domain .%s used from %s", tld, check.Country),
+				Action: action,
+			})
+		}
+	}
+	return signals
+}
+
+func (s *FraudService) intSetting(key string, fallback int) int {
+	if s.settings == nil {
+		return fallback
+	}
+	return s.settings.Int(key, fallback)
+}
+
+func (s *FraudService) stringsSetting(key string) []string {
+	if s.settings == nil {
+		return nil
+	}
+	return s.settings.Strings(key, nil)
+}
+
+// actionSetting returns the configured action for a rule, defaulting to review
+func (s *FraudService) actionSetting(key string) string {
+	action := FraudActionReview
+	if s.settings != nil {
+		action = s.settings.String(key, FraudActionReview)
+	}
+	if severity(action) < 0 {
+		s.logger.Warn("Unknown fraud action, using review", "setting", key, "action", action)
+		return FraudActionReview
+	}
+	return action
+}
+
+func severity(action string) int {
+	switch action {
+	case FraudActionAllow:
+		return 0
+	case FraudActionReview:
+		return 1
+	case FraudActionBlock:
+		return 2
+	}
+	return -1
+}
+
+// recent keeps the times within window of now; times are in ascending order
+func recent(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= window {
+		i++
+	}
+	return times[i:]
+}
+
+func clientIP(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func newTestFraudService(t *testing.T, settings map[string]string) (*FraudService, *repositories.MemoryStore, *clock.Fake) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+
+	repo := &fakeSettingsRepository{settings: map[string]models.Setting{}}
+	for key, value := range settings {
+		repo.settings[key] = models.Setting{Key: key, Value: value}
+	}
+	settingsService := NewSettingsService(repo, logger)
+	if err := settingsService.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewFraudService(store.FraudFlags(), store.Users(), settingsService, "CF-IPCountry", clk, logger), store, clk
+}
+
+func rules(signals models.FraudSignals) map[string]bool {
+	fired := make(map[string]bool)
+	for _, signal := range signals {
+		fired[signal.Rule] = true
+	}
+	return fired
+}
+
+func TestFraudVelocity(t *testing.T) {
+	fraud, _, clk := newTestFraudService(t, map[string]string{
+		SettingFraudMaxPerUser:     "2",
+		SettingFraudMaxPerIP:       "0",
+		SettingFraudVelocityAction: `"block"`,
+	})
+
+	req := httptest.NewRequest("POST", "/api/tickets/purchase", nil)
+	check := FraudCheck{UserID: 1, EventID: 1, UMAAddress: "$alice@wallet.example.com"}
+
+	for i := 0; i < 2; i++ {
+		if got := fraud.Evaluate(req, check); got.Flagged() {
+			t.Fatalf("Attempt %d: expected no signals, got %+v", i+1, got.Signals)
+		}
+	}
+
+	got := fraud.Evaluate(req, check)
+	if got.Action != FraudActionBlock || !rules(got.Signals)[FraudRuleUserVelocity] {
+		t.Errorf("Expected user velocity block, got %+v", got)
+	}
+	if rules(got.Signals)[FraudRuleIPVelocity] {
+		t.Error("Expected IP velocity to be disabled with a limit of 0")
+	}
+
+	// Another user is counted separately
+	if got := fraud.Evaluate(req, FraudCheck{UserID: 2, EventID: 1}); got.Flagged() {
+		t.Errorf("Expected no signals for another user, got %+v", got.Signals)
+	}
+
+	// Attempts age out of the window
+	clk.Advance(defaultFraudWindowMin * time.Minute)
+	if got := fraud.Evaluate(req, check); got.Flagged() {
+		t.Errorf("Expected no signals after the window, got %+v", got.Signals)
+	}
+}
+
+func TestFraudDisposableEmail(t *testing.T) {
+	fraud, store, _ := newTestFraudService(t, map[string]string{
+		SettingFraudDisposableDomains: `["burner.example"]`,
+	})
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"alice@example.com", false},
+		{"bob@mailinator.com", true},
+		{"carol@eu.Mailinator.com", true},
+		{"dave@burner.example", true},
+		{"erin@notmailinator.com", false},
+	}
+
+	req := httptest.NewRequest("POST", "/api/tickets/purchase", nil)
+	for _, tt := range tests {
+		user := &models.User{Email: tt.email, Name: "Test"}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		got := fraud.Evaluate(req, FraudCheck{UserID: user.ID, EventID: 1})
+		if rules(got.Signals)[FraudRuleDisposableEmail] != tt.want {
+			t.Errorf("%s: expected disposable=%v, got %+v", tt.email, tt.want, got.Signals)
+		}
+		if tt.want && got.Action != FraudActionReview {
+			t.Errorf("%s: expected default action review, got %s", tt.email, got.Action)
+		}
+	}
+}
+
+func TestFraudGeo(t *testing.T) {
+	fraud, _, clk := newTestFraudService(t, nil)
+
+	request := func(country string) FraudCheck {
+		return FraudCheck{UserID: 1, EventID: 1, UMAAddress: "$alice@wallet.io", Country: country}
+	}
+	req := httptest.NewRequest("POST", "/api/tickets/purchase", nil)
+
+	if got := fraud.Evaluate(req, request("US")); got.Flagged() {
+		t.Fatalf("Expected no signals on first attempt, got %+v", got.Signals)
+	}
+	if got := fraud.Evaluate(req, request("BR")); !rules(got.Signals)[FraudRuleCountryChange] {
+		t.Errorf("Expected country change signal, got %+v", got.Signals)
+	}
+	clk.Advance(time.Hour)
+	if got := fraud.Evaluate(req, request("US")); got.Flagged() {
+		t.Errorf("Expected no signal once the window passed, got %+v", got.Signals)
+	}
+
+	// Country read from the configured header; .uk maps to GB
+	req.Header.Set("CF-IPCountry", "de")
+	got := fraud.Evaluate(req, FraudCheck{UserID: 2, EventID: 1, UMAAddress: "$bob@wallet.co.uk"})
+	if !rules(got.Signals)[FraudRuleUMACountry] {
+		t.Errorf("Expected UMA domain country signal, got %+v", got.Signals)
+	}
+	req.Header.Set("CF-IPCountry", "GB")
+	got = fraud.Evaluate(req, FraudCheck{UserID: 3, EventID: 1, UMAAddress: "$carol@wallet.co.uk"})
+	if got.Flagged() {
+		t.Errorf("Expected no signals for matching country, got %+v", got.Signals)
+	}
+}
+
+func TestFraudRecord(t *testing.T) {
+	fraud, store, _ := newTestFraudService(t, map[string]string{
+		SettingFraudDisposableAction: `"block"`,
+	})
+	user := &models.User{Email: "eve@yopmail.com", Name: "Eve"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/tickets/purchase", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+
+	clean := FraudCheck{UserID: 99, EventID: 1}
+	if err := fraud.Record(req, clean, fraud.Evaluate(req, clean), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	check := FraudCheck{UserID: user.ID, EventID: 1}
+	assessment := fraud.Evaluate(req, check)
+	if assessment.Action != FraudActionBlock {
+		t.Fatalf("Expected block, got %s", assessment.Action)
+	}
+	if err := fraud.Record(req, check, assessment, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := fraud.List(FraudActionBlock, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 1 {
+		t.Fatalf("Expected only the flagged attempt to be recorded, got %d", len(flags))
+	}
+	if flags[0].ClientIP != "203.0.113.7" || flags[0].TicketID != nil || len(flags[0].Signals) != 1 {
+		t.Errorf("Unexpected flag %+v", flags[0])
+	}
+}
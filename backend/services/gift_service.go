@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// MaxGiftMessageLength caps the personal message sent with a gift, in
+// characters
+const MaxGiftMessageLength = 500
+
+// giftDeliveryBatch caps the gifts delivered in one pass
+const giftDeliveryBatch = 100
+
+// Gift errors
+var (
+	ErrGiftRecipient    = errors.New("gift needs a recipient_email or recipient_uma_address")
+	ErrGiftEmail        = errors.New("invalid recipient email format")
+	ErrGiftMessage      = fmt.Errorf("gift message must be at most %d characters", MaxGiftMessageLength)
+	ErrGiftDelivery     = errors.New("deliver_at must be before the event starts")
+	ErrGiftClaimInvalid = errors.New("gift link is invalid or already used")
+	ErrGiftOwnClaim     = errors.New("you cannot accept a gift you bought")
+)
+
+// GiftService runs gift purchases. The buyer owns a gift ticket until the
+// recipient accepts it. Once the ticket is paid and its delivery time has
+// come, the claim link is sent to the recipient's email or, when only
+// their UMA address is known, to the buyer to pass on. Accepting the link
+// while logged in moves the ticket to that account.
+//
+// Claim links carry a one-time secret of which only the hash is stored.
+type GiftService struct {
+	repo     repositories.TicketGiftRepository
+	users    repositories.UserRepository
+	tickets  repositories.TicketRepository
+	events   repositories.EventRepository
+	notifier Notifier
+	domain   string
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewGiftService creates a gift service. domain is the host in claim
+// links, which notifier delivers.
+func NewGiftService(
+	repo repositories.TicketGiftRepository,
+	users repositories.UserRepository,
+	tickets repositories.TicketRepository,
+	events repositories.EventRepository,
+	notifier Notifier,
+	domain string,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *GiftService {
+	return &GiftService{
+		repo:     repo,
+		users:    users,
+		tickets:  tickets,
+		events:   events,
+		notifier: notifier,
+		domain:   domain,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// Prepare checks a purchase's gift request for event and returns the gift
+// to schedule once the ticket exists. Deliveries in the past, or left out,
+// go out as soon as the ticket is paid.
+func (s *GiftService) Prepare(req models.GiftRequest, senderID int, event *models.Event) (*models.TicketGift, error) {
+	gift := &models.TicketGift{
+		SenderID:            senderID,
+		RecipientEmail:      strings.TrimSpace(req.RecipientEmail),
+		RecipientUMAAddress: strings.TrimSpace(req.RecipientUMAAddress),
+		Message:             strings.TrimSpace(req.Message),
+		DeliverAt:           s.clock.Now(),
+	}
+	if gift.RecipientEmail == "" && gift.RecipientUMAAddress == "" {
+		return nil, ErrGiftRecipient
+	}
+	if gift.RecipientEmail != "" && (!strings.Contains(gift.RecipientEmail, "@") || len(gift.RecipientEmail) < 5) {
+		return nil, ErrGiftEmail
+	}
+	if utf8.RuneCountInString(gift.Message) > MaxGiftMessageLength {
+		return nil, ErrGiftMessage
+	}
+	if req.DeliverAt != nil && req.DeliverAt.After(gift.DeliverAt) {
+		gift.DeliverAt = req.DeliverAt.UTC()
+	}
+	if !event.StartTime.IsZero() && gift.DeliverAt.After(event.StartTime) {
+		return nil, ErrGiftDelivery
+	}
+	return gift, nil
+}
+
+// Schedule stores a prepared gift once its ticket exists
+func (s *GiftService) Schedule(gift *models.TicketGift) error {
+	if err := s.repo.Create(gift); err != nil {
+		return err
+	}
+	s.logger.Info("Gift scheduled", "gift_id", gift.ID, "ticket_id", gift.TicketID, "deliver_at", gift.DeliverAt)
+	return nil
+}
+
+// Sent returns the gifts a user bought, newest first
+func (s *GiftService) Sent(userID int) ([]models.TicketGift, error) {
+	return s.repo.GetBySenderID(userID)
+}
+
+// Watch delivers due gifts every interval until ctx is cancelled
+func (s *GiftService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverDue(); err != nil {
+				s.logger.Error("Failed to deliver gifts", "error", err)
+			}
+		}
+	}
+}
+
+// DeliverDue sends the claim links of paid gifts whose delivery time has
+// come and returns how many were sent. A gift that fails is logged and
+// skipped.
+func (s *GiftService) DeliverDue() (int, error) {
+	due, err := s.repo.ListDue(s.clock.Now(), giftDeliveryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range due {
+		if err := s.deliver(&due[i]); err != nil {
+			s.logger.Error("Failed to deliver gift", "gift_id", due[i].ID, "ticket_id", due[i].TicketID, "error", err)
+			continue
+		}
+		delivered++
+		s.logger.Info("Gift delivered", "gift_id", due[i].ID, "ticket_id", due[i].TicketID)
+	}
+	return delivered, nil
+}
+
+func (s *GiftService) deliver(gift *models.TicketGift) error {
+	ticket, err := s.tickets.GetByID(gift.TicketID)
+	if err != nil {
+		return err
+	}
+	event, err := s.events.GetByID(ticket.EventID)
+	if err != nil {
+		return err
+	}
+	sender, err := s.users.GetByID(gift.SenderID)
+	if err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	encoded := hex.EncodeToString(secret)
+	if err := s.repo.MarkDelivered(gift.ID, hashClaimSecret(encoded)); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://%s/gift?token=%s", s.domain, encoded)
+
+	if gift.RecipientEmail == "" {
+		return s.notifier.NotifyUser(gift.SenderID, i18n.Notification{
+			Key: i18n.GiftReady, Args: []any{event.Title, gift.RecipientUMAAddress, url}, EventID: event.ID,
+		})
+	}
+	// Recipients without an account get the message in the sender's locale
+	recipientID := gift.SenderID
+	if user, err := s.users.GetByEmail(gift.RecipientEmail); err == nil {
+		recipientID = user.ID
+	}
+	return s.notifier.NotifyUser(recipientID, i18n.Notification{
+		Key: i18n.GiftReceived, Args: []any{sender.Name, event.Title, url, gift.Message}, EventID: event.ID, Address: gift.RecipientEmail,
+	})
+}
+
+// Preview returns what the gift with the token from its claim link is
+func (s *GiftService) Preview(token string) (*models.GiftPreview, error) {
+	gift, err := s.repo.GetBySecret(hashClaimSecret(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrGiftClaimInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := s.tickets.GetByID(gift.TicketID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := s.events.GetByID(ticket.EventID)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := s.users.GetByID(gift.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.GiftPreview{
+		Status:     gift.Status,
+		SenderName: sender.Name,
+		Message:    gift.Message,
+		EventID:    event.ID,
+		EventTitle: event.Title,
+		StartTime:  event.StartTime,
+	}, nil
+}
+
+// Claim gives the ticket of the gift with the token from its claim link to
+// userID and returns the ticket. Buyers cannot accept their own gifts.
+func (s *GiftService) Claim(token string, userID int) (*models.Ticket, error) {
+	secretHash := hashClaimSecret(token)
+	gift, err := s.repo.GetBySecret(secretHash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrGiftClaimInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if gift.SenderID == userID {
+		return nil, ErrGiftOwnClaim
+	}
+
+	gift, err = s.repo.Claim(secretHash, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrGiftClaimInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Gift claimed", "gift_id", gift.ID, "ticket_id", gift.TicketID, "user_id", userID)
+	return s.tickets.GetByID(gift.TicketID)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// giftNotifier keeps the notifications sent and who they were sent for
+type giftNotifier struct {
+	users         []int
+	notifications []i18n.Notification
+}
+
+func (n *giftNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	n.users = append(n.users, userID)
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func TestGiftService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &giftNotifier{}
+	gifts := NewGiftService(store.TicketGifts(), store.Users(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+
+	var users []*models.User
+	for _, name := range []string{"Sender", "Friend"} {
+		user := &models.User{Email: strings.ToLower(name) + "@example.com", Name: name}
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	sender, friend := users[0], users[1]
+
+	event := &models.Event{Title: "Gala", Capacity: 10, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	late, past := clk.Now().Add(96*time.Hour), clk.Now().Add(-time.Hour)
+	for _, tc := range []struct {
+		name string
+		req  models.GiftRequest
+		want error
+	}{
+		{"no recipient", models.GiftRequest{Message: "Hi"}, ErrGiftRecipient},
+		{"bad email", models.GiftRequest{RecipientEmail: "nope"}, ErrGiftEmail},
+		{"long message", models.GiftRequest{RecipientEmail: "friend@example.com", Message: strings.Repeat("x", MaxGiftMessageLength+1)}, ErrGiftMessage},
+		{"after the event", models.GiftRequest{RecipientEmail: "friend@example.com", DeliverAt: &late}, ErrGiftDelivery},
+	} {
+		if _, err := gifts.Prepare(tc.req, sender.ID, event); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	gift, err := gifts.Prepare(models.GiftRequest{RecipientEmail: " friend@example.com ", DeliverAt: &past}, sender.ID, event)
+	if err != nil || gift.RecipientEmail != "friend@example.com" || !gift.DeliverAt.Equal(clk.Now()) {
+		t.Fatalf("Expected a gift delivered right away, got %+v (%v)", gift, err)
+	}
+
+	// One gift by email tomorrow, one by UMA address right away
+	tomorrow := clk.Now().Add(24 * time.Hour)
+	requests := []models.GiftRequest{
+		{RecipientEmail: friend.Email, Message: "Happy birthday!", DeliverAt: &tomorrow},
+		{RecipientUMAAddress: "$pal@wallet.example.com"},
+	}
+	var tickets []*models.Ticket
+	for i, req := range requests {
+		ticket := &models.Ticket{EventID: event.ID, UserID: sender.ID, TicketCode: "GIFT-" + string(rune('A'+i)), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		gift, err := gifts.Prepare(req, sender.ID, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gift.TicketID = ticket.ID
+		if err := gifts.Schedule(gift); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	// Without an email, the sender gets the link to pass on
+	if delivered, err := gifts.DeliverDue(); err != nil || delivered != 1 {
+		t.Fatalf("Expected one gift delivered, got %d (%v)", delivered, err)
+	}
+	if len(notifier.notifications) != 1 || notifier.users[0] != sender.ID || notifier.notifications[0].Key != i18n.GiftReady {
+		t.Fatalf("Expected the sender told the UMA gift is ready, got %+v", notifier.notifications)
+	}
+
+	clk.Advance(24 * time.Hour)
+	if delivered, err := gifts.DeliverDue(); err != nil || delivered != 1 {
+		t.Fatalf("Expected the scheduled gift delivered, got %d (%v)", delivered, err)
+	}
+	received := notifier.notifications[1]
+	if notifier.users[1] != friend.ID || received.Key != i18n.GiftReceived || received.Address != friend.Email || received.Args[3] != "Happy birthday!" {
+		t.Fatalf("Expected the friend sent the gift, got %d %+v", notifier.users[1], received)
+	}
+	link := received.Args[2].(string)
+	if !strings.HasPrefix(link, "https://tickets.example.com/gift?token=") {
+		t.Fatalf("Expected a gift link, got %q", link)
+	}
+	token := strings.TrimPrefix(link, "https://tickets.example.com/gift?token=")
+
+	preview, err := gifts.Preview(token)
+	if err != nil || preview.SenderName != "Sender" || preview.EventTitle != "Gala" || preview.Status != models.GiftDelivered {
+		t.Errorf("Expected the gift preview, got %+v (%v)", preview, err)
+	}
+	if _, err := gifts.Preview("wrong"); !errors.Is(err, ErrGiftClaimInvalid) {
+		t.Errorf("Expected ErrGiftClaimInvalid for an unknown token, got %v", err)
+	}
+	if _, err := gifts.Claim(token, sender.ID); !errors.Is(err, ErrGiftOwnClaim) {
+		t.Errorf("Expected ErrGiftOwnClaim for the sender, got %v", err)
+	}
+	ticket, err := gifts.Claim(token, friend.ID)
+	if err != nil || ticket.ID != tickets[0].ID || ticket.UserID != friend.ID {
+		t.Fatalf("Expected the ticket moved to the friend, got %+v (%v)", ticket, err)
+	}
+	if _, err := gifts.Claim(token, friend.ID); !errors.Is(err, ErrGiftClaimInvalid) {
+		t.Errorf("Expected ErrGiftClaimInvalid claiming twice, got %v", err)
+	}
+	if delivered, _ := gifts.DeliverDue(); delivered != 0 {
+		t.Errorf("Expected nothing left to deliver, got %d", delivered)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// accountClaimTTL is how long an account claim link can be used
+const accountClaimTTL = 7 * 24 * time.Hour
+
+// Guest checkout errors
+var (
+	ErrAccountExists       = errors.New("an account with this email already exists; log in to buy tickets")
+	ErrAccountClaimInvalid = errors.New("claim link is invalid, expired or already used")
+)
+
+// GuestService lets buyers check out with just an email. Checkout creates
+// a guest user for the email, or reuses the guest from an earlier
+// checkout, to own the tickets. Once one of a guest's tickets is paid the
+// guest is sent a claim link; claiming it sets a password, turning the
+// guest into a regular user who keeps the tickets.
+//
+// Claim links carry a one-time secret of which only the hash is stored.
+// The email on a guest account is unverified until it is claimed, so
+// guests do not match email allowlists and cannot take over registered
+// accounts.
+type GuestService struct {
+	users    repositories.UserRepository
+	claims   repositories.AccountClaimRepository
+	tickets  repositories.TicketRepository
+	events   repositories.EventRepository
+	notifier Notifier
+	domain   string
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewGuestService creates a guest service. domain is the host in claim
+// links, which notifier delivers.
+func NewGuestService(
+	users repositories.UserRepository,
+	claims repositories.AccountClaimRepository,
+	tickets repositories.TicketRepository,
+	events repositories.EventRepository,
+	notifier Notifier,
+	domain string,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *GuestService {
+	return &GuestService{
+		users:    users,
+		claims:   claims,
+		tickets:  tickets,
+		events:   events,
+		notifier: notifier,
+		domain:   domain,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// Checkout returns the guest user to buy as with email, creating it in
+// locale if needed. It returns ErrAccountExists when a registered user
+// owns the email.
+func (s *GuestService) Checkout(email, locale string) (*models.User, error) {
+	email = strings.TrimSpace(email)
+	user, err := s.users.GetByEmail(email)
+	if err == nil {
+		if !user.IsGuest {
+			return nil, ErrAccountExists
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	name, _, _ := strings.Cut(email, "@")
+	user = &models.User{Email: email, Name: name, Locale: locale, IsGuest: true}
+	if err := s.users.Create(user); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			// Another checkout with the same email created it first
+			return s.Checkout(email, locale)
+		}
+		return nil, err
+	}
+	s.logger.Info("Guest user created", "user_id", user.ID)
+	return user, nil
+}
+
+// IsGuest reports whether a user is an unclaimed guest
+func (s *GuestService) IsGuest(userID int) (bool, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsGuest, nil
+}
+
+// OfferClaim sends a guest a claim link, replacing any earlier one.
+// eventID names the event of the ticket the guest paid for.
+func (s *GuestService) OfferClaim(userID, eventID int) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	encoded := hex.EncodeToString(secret)
+
+	if err := s.claims.Offer(userID, hashClaimSecret(encoded), s.clock.Now().Add(accountClaimTTL)); err != nil {
+		return err
+	}
+	event, err := s.events.GetByID(eventID)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://%s/claim?token=%s", s.domain, encoded)
+	return s.notifier.NotifyUser(userID, i18n.Notification{Key: i18n.AccountClaim, Args: []any{event.Title, url}, EventID: eventID})
+}
+
+// ResendClaim sends a new claim link to the guest with email if they have
+// a paid ticket. Other emails are ignored so the caller cannot tell which
+// addresses belong to guests.
+func (s *GuestService) ResendClaim(email string) error {
+	user, err := s.users.GetByEmail(strings.TrimSpace(email))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsGuest {
+		return nil
+	}
+
+	tickets, err := s.tickets.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	// Tickets come newest first
+	for _, ticket := range tickets {
+		if ticket.PaymentStatus == "paid" {
+			return s.OfferClaim(user.ID, ticket.EventID)
+		}
+	}
+	return nil
+}
+
+// Claim sets a guest's password hash, and name unless it is empty, with
+// the token from their claim link, returning the now regular user
+func (s *GuestService) Claim(token, passwordHash, name string) (*models.User, error) {
+	user, err := s.claims.Claim(hashClaimSecret(token), passwordHash, strings.TrimSpace(name))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrAccountClaimInvalid
+	}
+	return user, err
+}
+
+// Tickets wraps repo so a guest is sent a claim link whenever one of their
+// tickets becomes paid. Failing to send it is logged; the ticket update
+// stands and the guest can ask for the link again.
+func (s *GuestService) Tickets(repo repositories.TicketRepository) repositories.TicketRepository {
+	return &guestTicketRepository{TicketRepository: repo, guests: s}
+}
+
+type guestTicketRepository struct {
+	repositories.TicketRepository
+	guests *GuestService
+}
+
+func (r *guestTicketRepository) Create(ticket *models.Ticket) error {
+	if err := r.TicketRepository.Create(ticket); err != nil {
+		return err
+	}
+	if ticket.PaymentStatus == "paid" {
+		r.guests.ticketPaid(ticket.UserID, ticket.EventID)
+	}
+	return nil
+}
+
+func (r *guestTicketRepository) Update(ticket *models.Ticket) error {
+	previous, err := r.TicketRepository.GetByID(ticket.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.TicketRepository.Update(ticket); err != nil {
+		return err
+	}
+	if previous.PaymentStatus != "paid" && ticket.PaymentStatus == "paid" {
+		r.guests.ticketPaid(ticket.UserID, ticket.EventID)
+	}
+	return nil
+}
+
+func (r *guestTicketRepository) UpdatePaymentStatus(id int, status string) error {
+	previous, err := r.TicketRepository.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.TicketRepository.UpdatePaymentStatus(id, status); err != nil {
+		return err
+	}
+	if previous.PaymentStatus != "paid" && status == "paid" {
+		r.guests.ticketPaid(previous.UserID, previous.EventID)
+	}
+	return nil
+}
+
+// ticketPaid sends the claim link if the ticket's owner is a guest
+func (s *GuestService) ticketPaid(userID, eventID int) {
+	guest, err := s.IsGuest(userID)
+	if err != nil {
+		s.logger.Error("Failed to check whether ticket owner is a guest", "user_id", userID, "error", err)
+		return
+	}
+	if !guest {
+		return
+	}
+	if err := s.OfferClaim(userID, eventID); err != nil {
+		s.logger.Error("Failed to send account claim link", "user_id", userID, "error", err)
+		return
+	}
+	s.logger.Info("Account claim link sent", "user_id", userID, "event_id", eventID)
+}
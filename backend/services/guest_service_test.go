@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// claimLinkNotifier keeps the claim links sent to each user
+type claimLinkNotifier struct {
+	links map[int][]string
+}
+
+func (n *claimLinkNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	if notification.Key == i18n.AccountClaim {
+		n.links[userID] = append(n.links[userID], notification.Args[1].(string))
+	}
+	return nil
+}
+
+func TestGuestService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	notifier := &claimLinkNotifier{links: make(map[int][]string)}
+	guests := NewGuestService(store.Users(), store.AccountClaims(), store.Tickets(), store.Events(), notifier, "tickets.example.com", clk, logger)
+	tickets := guests.Tickets(store.Tickets())
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	member := &models.User{Email: "member@example.com", Name: "Member", PasswordHash: "hash"}
+	if err := store.Users().Create(member); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := guests.Checkout("member@example.com", "en"); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("Expected a registered email to be refused, got %v", err)
+	}
+	guest, err := guests.Checkout(" guest@example.com ", "ko")
+	if err != nil || !guest.IsGuest || guest.Email != "guest@example.com" || guest.Name != "guest" || guest.Locale != "ko" {
+		t.Fatalf("Expected a guest user, got %+v (%v)", guest, err)
+	}
+	if again, err := guests.Checkout("guest@example.com", "en"); err != nil || again.ID != guest.ID {
+		t.Errorf("Expected the guest to be reused, got %+v (%v)", again, err)
+	}
+
+	// Only paying sends the link, and only to guests
+	ticket := &models.Ticket{EventID: event.ID, UserID: guest.ID, TicketCode: "GUEST-1", PaymentStatus: "pending"}
+	memberTicket := &models.Ticket{EventID: event.ID, UserID: member.ID, TicketCode: "MEMBER-1", PaymentStatus: "paid"}
+	for _, ticket := range []*models.Ticket{ticket, memberTicket} {
+		if err := tickets.Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notifier.links[guest.ID]) != 0 || len(notifier.links[member.ID]) != 0 {
+		t.Fatalf("Expected no claim links before the guest paid, got %v", notifier.links)
+	}
+	if err := guests.ResendClaim("guest@example.com"); err != nil || len(notifier.links[guest.ID]) != 0 {
+		t.Errorf("Expected no link to be resent before payment, got %v (%v)", notifier.links, err)
+	}
+	if err := tickets.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.links[guest.ID]) != 1 || !strings.HasPrefix(notifier.links[guest.ID][0], "https://tickets.example.com/claim?token=") {
+		t.Fatalf("Expected a claim link once paid, got %v", notifier.links[guest.ID])
+	}
+	first := strings.TrimPrefix(notifier.links[guest.ID][0], "https://tickets.example.com/claim?token=")
+
+	// A resent link replaces the first
+	for _, email := range []string{"guest@example.com", "member@example.com", "nobody@example.com"} {
+		if err := guests.ResendClaim(email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notifier.links[guest.ID]) != 2 || len(notifier.links[member.ID]) != 0 {
+		t.Fatalf("Expected a link to be resent only to the guest, got %v", notifier.links)
+	}
+	if _, err := guests.Claim(first, "new-hash", ""); !errors.Is(err, ErrAccountClaimInvalid) {
+		t.Errorf("Expected the replaced link to be rejected, got %v", err)
+	}
+
+	token := strings.TrimPrefix(notifier.links[guest.ID][1], "https://tickets.example.com/claim?token=")
+	user, err := guests.Claim(token, "new-hash", " Guest Buyer ")
+	if err != nil || user.ID != guest.ID || user.IsGuest || user.PasswordHash != "new-hash" || user.Name != "Guest Buyer" {
+		t.Fatalf("Expected the guest to be claimed, got %+v (%v)", user, err)
+	}
+	if _, err := guests.Checkout("guest@example.com", "en"); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("Expected a claimed email to need a login, got %v", err)
+	}
+	if owned, err := store.Tickets().GetByUserID(user.ID); err != nil || len(owned) != 1 || owned[0].ID != ticket.ID {
+		t.Errorf("Expected the claimed account to keep its ticket, got %+v (%v)", owned, err)
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+var (
+	// ErrNotPosted is returned when refunding a payment whose sale is not
+	// in the ledger, because it was never paid
+	ErrNotPosted = errors.New("payment has no sale in the ledger")
+	// ErrPayoutExceedsBalance is returned for a payout larger than what
+	// the platform owes the organizer
+	ErrPayoutExceedsBalance = errors.New("payout exceeds the organizer's balance")
+	// ErrNoRefundOwed is returned when sending the refund of a payment
+	// whose refund is not owed, or was already sent
+	ErrNoRefundOwed = errors.New("payment has no refund owed")
+)
+
+// ledgerSyncBatch is how many unposted sales Sync books per query
+const ledgerSyncBatch = 100
+
+// LedgerService keeps the double-entry ledger of the funds the platform
+// holds. Paid sales are booked from their payments by Sync; refunds and
+// payouts made outside the app are recorded by admins, and payouts and
+// refunds the PayoutService sends are booked once sent. Refunds of
+// cancelled tickets are owed to the buyer until they are sent.
+type LedgerService struct {
+	repo   repositories.LedgerRepository
+	clock  clock.Clock
+	logger *slog.Logger
+	// payouts serializes the balance check, sending and booking of payouts
+	// so two sent from this instance cannot both spend the same balance
+	payouts sync.Mutex
+}
+
+// NewLedgerService creates a ledger service storing entries in repo.
+func NewLedgerService(repo repositories.LedgerRepository, clk clock.Clock, logger *slog.Logger) *LedgerService {
+	return &LedgerService{repo: repo, clock: clk, logger: logger}
+}
+
+// Sync books every paid payment that has no sale entry yet and returns how
+// many it booked. Sales posted concurrently by another instance are skipped.
+func (s *LedgerService) Sync() (int, error) {
+	posted := 0
+	for {
+		sales, err := s.repo.UnpostedSales(ledgerSyncBatch)
+		if err != nil {
+			return posted, err
+		}
+		for _, sale := range sales {
+			paymentID := sale.ID
+			entry := ledgerEntry(models.LedgerEntrySale, accounting.SaleEntry(sale))
+			entry.PaymentID = &paymentID
+			err := s.repo.Post(entry)
+			if errors.Is(err, repositories.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return posted, fmt.Errorf("failed to book payment %d: %w", sale.ID, err)
+			}
+			posted++
+		}
+		if len(sales) < ledgerSyncBatch {
+			return posted, nil
+		}
+	}
+}
+
+// RecordRefund books the full refund of a paid payment, sent to the buyer
+// outside the app, by reversing its sale entry. A refund booked as owed by
+// OweRefund is settled instead. It returns ErrNotPosted when the payment
+// has no sale, and repositories.ErrConflict when it was already refunded.
+func (s *LedgerService) RecordRefund(paymentID int, reference string) (*models.LedgerEntry, error) {
+	owed, err := s.RefundOwed(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if owed > 0 {
+		return s.payout(refundPayout(paymentID, reference), accounting.RefundPayable(paymentID), owed, nil)
+	}
+	return s.reverseSale(paymentID, reference, accounting.AccountWallet)
+}
+
+// OweRefund books the full refund of a paid payment that has yet to be
+// sent: its sale is reversed, but what the wallet took in is owed to the
+// buyer until SendRefund or RecordRefund settles it. It returns the same
+// errors as RecordRefund.
+func (s *LedgerService) OweRefund(paymentID int, reference string) (*models.LedgerEntry, error) {
+	return s.reverseSale(paymentID, reference, accounting.RefundPayable(paymentID))
+}
+
+// RefundOwed returns the sats of the payment's refund not yet sent
+func (s *LedgerService) RefundOwed(paymentID int) (int64, error) {
+	balance, err := s.repo.GetBalance(accounting.RefundPayable(paymentID))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return balance.BalanceSats, nil
+}
+
+// SendRefund sends the refund owed for a payment, amountSats, through send
+// and books it under the reference send returns. Nothing is booked when
+// send fails.
+func (s *LedgerService) SendRefund(paymentID int, amountSats int64, send func() (string, error)) (*models.LedgerEntry, error) {
+	return s.payout(refundPayout(paymentID, ""), accounting.RefundPayable(paymentID), amountSats, send)
+}
+
+// reverseSale posts the refund entry of a payment, reversing its sale with
+// the wallet's side booked to account
+func (s *LedgerService) reverseSale(paymentID int, reference, account string) (*models.LedgerEntry, error) {
+	if _, err := s.Sync(); err != nil {
+		return nil, err
+	}
+	sale, err := s.repo.GetPaymentEntry(models.LedgerEntrySale, paymentID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrNotPosted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if reference == "" {
+		reference = "refund of " + sale.Reference
+	}
+	refund := &models.LedgerEntry{
+		Kind:        models.LedgerEntryRefund,
+		PaymentID:   &paymentID,
+		Reference:   reference,
+		Description: sale.Description,
+		OccurredAt:  s.clock.Now(),
+	}
+	for _, posting := range sale.Postings {
+		reversed := models.LedgerPosting{
+			Account:    posting.Account,
+			DebitSats:  posting.CreditSats,
+			CreditSats: posting.DebitSats,
+		}
+		if reversed.Account == accounting.AccountWallet {
+			reversed.Account = account
+		}
+		refund.Postings = append(refund.Postings, reversed)
+	}
+	if err := s.repo.Post(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// RecordPayout books sats paid out of the wallet to an organizer. The
+// payout may not exceed what the ledger says the organizer is owed.
+func (s *LedgerService) RecordPayout(organizerID int, amountSats int64, reference string) (*models.LedgerEntry, error) {
+	return s.payout(organizerPayout(organizerID, reference), accounting.OrganizerPayable(organizerID), amountSats, nil)
+}
+
+// RecordAffiliatePayout books an affiliate's commission paid out of the
+// wallet. The payout may not exceed the commission they have accrued.
+func (s *LedgerService) RecordAffiliatePayout(affiliateID int, amountSats int64, reference string) (*models.LedgerEntry, error) {
+	return s.payout(affiliatePayout(affiliateID, reference), accounting.AffiliatePayable(affiliateID), amountSats, nil)
+}
+
+// SendPayout pays an organizer through send once the ledger shows they are
+// owed amountSats, and books the payout under the reference send returns.
+// Nothing is booked when send fails.
+func (s *LedgerService) SendPayout(organizerID int, amountSats int64, send func() (string, error)) (*models.LedgerEntry, error) {
+	return s.payout(organizerPayout(organizerID, ""), accounting.OrganizerPayable(organizerID), amountSats, send)
+}
+
+// SendAffiliatePayout is SendPayout for an affiliate's commission
+func (s *LedgerService) SendAffiliatePayout(affiliateID int, amountSats int64, send func() (string, error)) (*models.LedgerEntry, error) {
+	return s.payout(affiliatePayout(affiliateID, ""), accounting.AffiliatePayable(affiliateID), amountSats, send)
+}
+
+func organizerPayout(organizerID int, reference string) *models.LedgerEntry {
+	return &models.LedgerEntry{
+		OrganizerID: &organizerID,
+		Reference:   reference,
+		Description: fmt.Sprintf("Payout to organizer %d", organizerID),
+	}
+}
+
+func refundPayout(paymentID int, reference string) *models.LedgerEntry {
+	if reference == "" {
+		reference = fmt.Sprintf("refund of payment %d", paymentID)
+	}
+	return &models.LedgerEntry{
+		PaymentID:   &paymentID,
+		Reference:   reference,
+		Description: fmt.Sprintf("Refund of payment %d", paymentID),
+	}
+}
+
+func affiliatePayout(affiliateID int, reference string) *models.LedgerEntry {
+	return &models.LedgerEntry{
+		AffiliateID: &affiliateID,
+		Reference:   reference,
+		Description: fmt.Sprintf("Payout to affiliate %d", affiliateID),
+	}
+}
+
+// payout posts payout, moving amountSats from the payable account to the
+// wallet once new sales are booked and the account is known to owe it.
+// When send is set it makes the payment first, returning its reference.
+func (s *LedgerService) payout(payout *models.LedgerEntry, account string, amountSats int64, send func() (string, error)) (*models.LedgerEntry, error) {
+	s.payouts.Lock()
+	defer s.payouts.Unlock()
+
+	if _, err := s.Sync(); err != nil {
+		return nil, err
+	}
+	var owed int64
+	balance, err := s.repo.GetBalance(account)
+	if err == nil {
+		owed = balance.BalanceSats
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if amountSats > owed {
+		return nil, fmt.Errorf("%w of %d sats", ErrPayoutExceedsBalance, owed)
+	}
+	if send != nil {
+		reference, err := send()
+		if err != nil {
+			return nil, err
+		}
+		payout.Reference = reference
+	}
+
+	payout.Kind = models.LedgerEntryPayout
+	payout.OccurredAt = s.clock.Now()
+	payout.Postings = []models.LedgerPosting{
+		{Account: account, DebitSats: amountSats},
+		{Account: accounting.AccountWallet, CreditSats: amountSats},
+	}
+	if err := s.repo.Post(payout); err != nil {
+		if send != nil {
+			// The sats have left the wallet; the entry must be booked by hand
+			s.logger.Error("Failed to book a sent payout", "account", account, "amount_sats", amountSats, "reference", payout.Reference, "error", err)
+		}
+		return nil, err
+	}
+	return payout, nil
+}
+
+// Check returns the ledger's broken invariants: unbalanced entries, sales
+// that do not match their payment, and wallet or payable balances below
+// zero.
+func (s *LedgerService) Check() ([]models.LedgerIssue, error) {
+	return s.repo.Check(accounting.AccountWallet)
+}
+
+// Watch books new sales and checks the ledger every interval until ctx is
+// cancelled, logging any broken invariants.
+func (s *LedgerService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reconcile()
+		}
+	}
+}
+
+func (s *LedgerService) reconcile() {
+	posted, err := s.Sync()
+	if err != nil {
+		s.logger.Error("Failed to book sales in the ledger", "error", err)
+	}
+	if posted > 0 {
+		s.logger.Info("Booked sales in the ledger", "count", posted)
+	}
+
+	issues, err := s.Check()
+	if err != nil {
+		s.logger.Error("Failed to check the ledger", "error", err)
+		return
+	}
+	for _, issue := range issues {
+		args := []interface{}{"check", issue.Check, "detail", issue.Detail}
+		if issue.EntryID != nil {
+			args = append(args, "entry_id", *issue.EntryID)
+		}
+		if issue.PaymentID != nil {
+			args = append(args, "payment_id", *issue.PaymentID)
+		}
+		s.logger.Error("Ledger invariant broken", args...)
+	}
+}
+
+// ledgerEntry converts a journal entry to be stored in the ledger
+func ledgerEntry(kind string, journal accounting.JournalEntry) *models.LedgerEntry {
+	entry := &models.LedgerEntry{
+		Kind:        kind,
+		Reference:   journal.Reference,
+		Description: journal.Description,
+		OccurredAt:  journal.Date,
+	}
+	for _, posting := range journal.Postings {
+		entry.Postings = append(entry.Postings, models.LedgerPosting{
+			Account:    posting.Account,
+			DebitSats:  posting.DebitSats,
+			CreditSats: posting.CreditSats,
+		})
+	}
+	return entry
+}
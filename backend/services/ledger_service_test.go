@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestLedgerService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+
+	organizer := 7
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true, OrganizerID: &organizer}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	var payments []*models.Payment
+	for i := 0; i < 3; i++ {
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "LEDGER-" + strconv.Itoa(i), PaymentStatus: "paid"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-ledger-" + strconv.Itoa(i), Amount: 1050, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Receipts().CreateLineItems([]models.PaymentLineItem{
+			{PaymentID: payment.ID, Kind: models.LineItemTicket, Quantity: 1, UnitAmountSats: 1000, AmountSats: 1000},
+			{PaymentID: payment.ID, Kind: models.LineItemFee, Quantity: 1, UnitAmountSats: 50, AmountSats: 50},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		payments = append(payments, payment)
+	}
+
+	balance := func(account string) int64 {
+		t.Helper()
+		b, err := store.Ledger().GetBalance(account)
+		if err != nil {
+			t.Fatalf("Failed to get %s balance: %v", account, err)
+		}
+		return b.BalanceSats
+	}
+
+	// Sales are booked once
+	if posted, err := ledger.Sync(); err != nil || posted != 3 {
+		t.Fatalf("Expected 3 sales booked, got %d (%v)", posted, err)
+	}
+	if posted, err := ledger.Sync(); err != nil || posted != 0 {
+		t.Fatalf("Expected nothing left to book, got %d (%v)", posted, err)
+	}
+	payable := accounting.OrganizerPayable(organizer)
+	if balance(accounting.AccountWallet) != 3150 || balance(payable) != 3000 || balance(accounting.AccountPlatformFees) != 150 {
+		t.Errorf("Unexpected balances after sales")
+	}
+
+	// A refund reverses the whole sale, once
+	refund, err := ledger.RecordRefund(payments[0].ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Reference != "refund of payment 1" || len(refund.Postings) != 3 || refund.Postings[0].CreditSats != 1050 {
+		t.Errorf("Unexpected refund entry %+v", refund)
+	}
+	if _, err := ledger.RecordRefund(payments[0].ID, ""); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict refunding twice, got %v", err)
+	}
+	if _, err := ledger.RecordRefund(99, ""); !errors.Is(err, ErrNotPosted) {
+		t.Errorf("Expected ErrNotPosted for an unknown payment, got %v", err)
+	}
+
+	// A refund not yet sent is owed to the buyer, until it is sent or
+	// recorded as sent
+	owed, err := ledger.OweRefund(payments[2].ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owed.Kind != models.LedgerEntryRefund || owed.Postings[0].Account != accounting.RefundPayable(payments[2].ID) {
+		t.Errorf("Unexpected owed refund entry %+v", owed)
+	}
+	if amount, err := ledger.RefundOwed(payments[2].ID); err != nil || amount != 1050 {
+		t.Errorf("Expected 1050 sats owed, got %d (%v)", amount, err)
+	}
+	if amount, err := ledger.RefundOwed(payments[0].ID); err != nil || amount != 0 {
+		t.Errorf("Expected nothing owed for a refund already sent, got %d (%v)", amount, err)
+	}
+	if _, err := ledger.OweRefund(payments[2].ID, ""); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict owing a refund twice, got %v", err)
+	}
+	if balance(accounting.AccountWallet) != 2100 {
+		t.Errorf("Expected the owed refund to stay in the wallet, got %d", balance(accounting.AccountWallet))
+	}
+	if _, err := ledger.SendRefund(payments[2].ID, 1051, func() (string, error) { return "tx-0", nil }); !errors.Is(err, ErrPayoutExceedsBalance) {
+		t.Errorf("Expected ErrPayoutExceedsBalance sending more than owed, got %v", err)
+	}
+	settled, err := ledger.RecordRefund(payments[2].ID, "tx-refund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled.Kind != models.LedgerEntryPayout || settled.Reference != "tx-refund" || balance(accounting.RefundPayable(payments[2].ID)) != 0 {
+		t.Errorf("Expected the owed refund settled, got %+v", settled)
+	}
+	if _, err := ledger.RecordRefund(payments[2].ID, ""); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict refunding a settled refund, got %v", err)
+	}
+
+	// Payouts are limited to what the organizer is owed
+	if _, err := ledger.RecordPayout(organizer, 1001, "tx-1"); !errors.Is(err, ErrPayoutExceedsBalance) {
+		t.Errorf("Expected ErrPayoutExceedsBalance, got %v", err)
+	}
+	if _, err := ledger.RecordPayout(8, 1, "tx-1"); !errors.Is(err, ErrPayoutExceedsBalance) {
+		t.Errorf("Expected ErrPayoutExceedsBalance for an organizer with no sales, got %v", err)
+	}
+	if _, err := ledger.RecordPayout(organizer, 1000, "tx-1"); err != nil {
+		t.Fatal(err)
+	}
+	if balance(accounting.AccountWallet) != 50 || balance(payable) != 0 || balance(accounting.AccountPlatformFees) != 50 {
+		t.Errorf("Unexpected balances after refund and payout")
+	}
+
+	if issues, err := ledger.Check(); err != nil || len(issues) != 0 {
+		t.Errorf("Expected a consistent ledger, got %+v (%v)", issues, err)
+	}
+
+	// A booked sale whose payment is no longer paid must have been refunded
+	if err := store.Payments().UpdateStatus(payments[1].ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := ledger.Check()
+	if err != nil || len(issues) != 1 || issues[0].Check != "unpaid_sale" || *issues[0].PaymentID != payments[1].ID {
+		t.Errorf("Expected an unpaid_sale issue, got %+v (%v)", issues, err)
+	}
+}
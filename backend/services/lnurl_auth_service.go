@@ -0,0 +1,193 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// lnurlAuthTTL is how long a wallet has to sign a login challenge
+const lnurlAuthTTL = 5 * time.Minute
+
+// LNURL-auth errors
+var (
+	ErrLNURLAuthInvalid  = errors.New("login challenge is invalid, expired or already used")
+	ErrLNURLAuthPending  = errors.New("wallet has not signed the challenge yet")
+	ErrLNURLAuthKeyTaken = errors.New("this wallet is linked to another account")
+)
+
+// LNURLAuthService logs users in with a Lightning wallet (LUD-04). The
+// browser asks for a challenge and shows it as an LNURL; the wallet signs
+// its k1 with a linking key of its own for this domain and calls back. A
+// wallet seen for the first time gets a new account keyed by its linking
+// key, with a placeholder email, so no email is needed to sign up. The
+// browser polls with the challenge's session secret, of which only the
+// hash is stored, until the wallet has signed.
+//
+// A challenge asked for by a logged-in user links the wallet to them, adding
+// it as a login method.
+type LNURLAuthService struct {
+	auth   repositories.LNURLAuthRepository
+	users  repositories.UserRepository
+	domain string
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewLNURLAuthService creates an LNURL-auth service. domain is the host
+// wallets call back, whose linking keys are specific to it.
+func NewLNURLAuthService(
+	auth repositories.LNURLAuthRepository,
+	users repositories.UserRepository,
+	domain string,
+	clk clock.Clock,
+	logger *slog.Logger,
+) *LNURLAuthService {
+	return &LNURLAuthService{
+		auth:   auth,
+		users:  users,
+		domain: domain,
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// Challenge creates a login challenge, or one linking the wallet to the
+// user linkUserID when it is not nil
+func (s *LNURLAuthService) Challenge(linkUserID *int) (*models.LNURLAuthLogin, error) {
+	k1, err := lnurl.NewK1()
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	session := hex.EncodeToString(secret)
+
+	challenge := &models.LNURLAuthChallenge{
+		K1:          k1,
+		SessionHash: hashClaimSecret(session),
+		LinkUserID:  linkUserID,
+		ExpiresAt:   s.clock.Now().Add(lnurlAuthTTL),
+	}
+	if err := s.auth.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	action := "login"
+	if linkUserID != nil {
+		action = "link"
+	}
+	query := url.Values{"tag": {"login"}, "k1": {k1}, "action": {action}}
+	callback := fmt.Sprintf("https://%s/api/auth/lnurl/callback?%s", s.domain, query.Encode())
+	return &models.LNURLAuthLogin{
+		LNURL:     lnurl.Encode(callback),
+		Callback:  callback,
+		K1:        k1,
+		Session:   session,
+		ExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+// Callback verifies a wallet's signature sig of k1 with its linking key
+// and logs in, signs up or links the wallet's user. Signatures that do not
+// verify come back as lnurl.ErrInvalidSignature.
+func (s *LNURLAuthService) Callback(k1, sig, key string) error {
+	key, err := lnurl.VerifyAuth(k1, sig, key)
+	if err != nil {
+		return err
+	}
+	challenge, err := s.auth.GetChallenge(k1)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrLNURLAuthInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if challenge.VerifiedAt != nil {
+		return ErrLNURLAuthInvalid
+	}
+
+	var user *models.User
+	if challenge.LinkUserID != nil {
+		user, err = s.auth.Link(*challenge.LinkUserID, key)
+		if errors.Is(err, repositories.ErrConflict) {
+			return ErrLNURLAuthKeyTaken
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Info("Lightning wallet linked", "user_id", user.ID)
+	} else if user, err = s.userByKey(key); err != nil {
+		return err
+	}
+
+	if err := s.auth.Verify(k1, user.ID); errors.Is(err, repositories.ErrNotFound) {
+		return ErrLNURLAuthInvalid
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Poll returns the user the wallet logged in once it has signed the
+// challenge of session, and ErrLNURLAuthPending until then. Each session
+// logs in once.
+func (s *LNURLAuthService) Poll(session string) (*models.User, error) {
+	challenge, err := s.auth.GetBySession(hashClaimSecret(session))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrLNURLAuthInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenge.VerifiedAt == nil || challenge.UserID == nil {
+		return nil, ErrLNURLAuthPending
+	}
+	if err := s.auth.Consume(challenge.ID); errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrLNURLAuthInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(*challenge.UserID)
+}
+
+// Unlink removes the user's wallet as a login method, signing out every
+// session
+func (s *LNURLAuthService) Unlink(userID int) (*models.User, error) {
+	return s.auth.Unlink(userID)
+}
+
+// userByKey returns the user with linking key, signing one up when the
+// wallet is new
+func (s *LNURLAuthService) userByKey(key string) (*models.User, error) {
+	user, err := s.auth.GetUserByKey(key)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return user, err
+	}
+
+	user = &models.User{
+		Email:        key + "@" + models.LNURLAuthEmailDomain,
+		Name:         "Lightning " + key[2:10],
+		LNURLAuthKey: &key,
+	}
+	if err := s.users.Create(user); err != nil {
+		// The same wallet signing up from another challenge at once
+		if existing, getErr := s.auth.GetUserByKey(key); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	s.logger.Info("User signed up with a Lightning wallet", "user_id", user.ID)
+	return user, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// signK1 signs a login challenge as a wallet with key, returning the sig
+// and linking key parameters of its callback
+func signK1(key *secp256k1.PrivateKey, k1 string) (string, string) {
+	challenge, _ := hex.DecodeString(k1)
+	return hex.EncodeToString(ecdsa.Sign(key, challenge).Serialize()), hex.EncodeToString(key.PubKey().SerializeCompressed())
+}
+
+func TestLNURLAuthService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	service := NewLNURLAuthService(store.LNURLAuth(), store.Users(), "tickets.example.com", clk, logger)
+	wallet, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := service.Challenge(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := url.Parse(login.Callback)
+	if err != nil || callback.Host != "tickets.example.com" || callback.Query().Get("tag") != "login" || callback.Query().Get("k1") != login.K1 {
+		t.Fatalf("Unexpected callback %s (%v)", login.Callback, err)
+	}
+	if login.LNURL != lnurl.Encode(login.Callback) {
+		t.Errorf("Expected the callback encoded as the LNURL, got %s", login.LNURL)
+	}
+	if _, err := service.Poll(login.Session); !errors.Is(err, ErrLNURLAuthPending) {
+		t.Errorf("Expected the login pending before the wallet signs, got %v", err)
+	}
+
+	other, _ := secp256k1.GeneratePrivateKey()
+	sig, _ := signK1(other, login.K1)
+	_, key := signK1(wallet, login.K1)
+	if err := service.Callback(login.K1, sig, key); !errors.Is(err, lnurl.ErrInvalidSignature) {
+		t.Errorf("Expected a signature by another key refused, got %v", err)
+	}
+
+	// A new wallet signs up
+	sig, key = signK1(wallet, login.K1)
+	if err := service.Callback(login.K1, sig, key); err != nil {
+		t.Fatal("Failed to log in:", err)
+	}
+	if err := service.Callback(login.K1, sig, key); !errors.Is(err, ErrLNURLAuthInvalid) {
+		t.Errorf("Expected a challenge signed once, got %v", err)
+	}
+	user, err := service.Poll(login.Session)
+	if err != nil || user.LNURLAuthKey == nil || *user.LNURLAuthKey != key || !strings.HasSuffix(user.Email, "@"+models.LNURLAuthEmailDomain) {
+		t.Fatalf("Expected a new user keyed by the wallet, got %+v (%v)", user, err)
+	}
+	if _, err := service.Poll(login.Session); !errors.Is(err, ErrLNURLAuthInvalid) {
+		t.Errorf("Expected a session to log in once, got %v", err)
+	}
+
+	// The same wallet logs in to the same account
+	again, err := service.Challenge(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, key = signK1(wallet, again.K1)
+	if err := service.Callback(again.K1, sig, key); err != nil {
+		t.Fatal("Failed to log in:", err)
+	}
+	if returning, err := service.Poll(again.Session); err != nil || returning.ID != user.ID {
+		t.Errorf("Expected the wallet's account, got %+v (%v)", returning, err)
+	}
+
+	// An expired challenge cannot be signed
+	expired, err := service.Challenge(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(lnurlAuthTTL + time.Second)
+	sig, key = signK1(wallet, expired.K1)
+	if err := service.Callback(expired.K1, sig, key); !errors.Is(err, ErrLNURLAuthInvalid) {
+		t.Errorf("Expected an expired challenge refused, got %v", err)
+	}
+
+	// A logged-in user links a wallet, which then logs them in
+	member := &models.User{Email: "member@example.com", Name: "Member", PasswordHash: "hash"}
+	if err := store.Users().Create(member); err != nil {
+		t.Fatal(err)
+	}
+	link, err := service.Challenge(&member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, key = signK1(wallet, link.K1)
+	if err := service.Callback(link.K1, sig, key); !errors.Is(err, ErrLNURLAuthKeyTaken) {
+		t.Errorf("Expected a wallet of another account refused, got %v", err)
+	}
+	link, err = service.Challenge(&member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, key = signK1(other, link.K1)
+	if err := service.Callback(link.K1, sig, key); err != nil {
+		t.Fatal("Failed to link wallet:", err)
+	}
+	if linked, err := service.Poll(link.Session); err != nil || linked.ID != member.ID || linked.LNURLAuthKey == nil || *linked.LNURLAuthKey != key {
+		t.Errorf("Expected the wallet linked to the member, got %+v (%v)", linked, err)
+	}
+	login, err = service.Challenge(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, key = signK1(other, login.K1)
+	if err := service.Callback(login.K1, sig, key); err != nil {
+		t.Fatal("Failed to log in:", err)
+	}
+	if returning, err := service.Poll(login.Session); err != nil || returning.ID != member.ID {
+		t.Errorf("Expected the linked wallet to log in the member, got %+v (%v)", returning, err)
+	}
+}
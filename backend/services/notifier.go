@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/repositories"
+)
+
+// Notifier tells a user about a change to one of their tickets, rendering
+// the notification in the user's locale.
+type Notifier interface {
+	NotifyUser(userID int, notification i18n.Notification) error
+}
+
+// LogNotifier writes notifications to the log. It stands in until a
+// delivery channel such as email is configured.
+type LogNotifier struct {
+	users     repositories.UserRepository
+	templates *TemplateService
+	logger    *slog.Logger
+}
+
+// NewLogNotifier creates a notifier that logs each notification rendered
+// with templates in the recipient's locale.
+func NewLogNotifier(users repositories.UserRepository, templates *TemplateService, logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{users: users, templates: templates, logger: logger}
+}
+
+func (n *LogNotifier) NotifyUser(userID int, notification i18n.Notification) error {
+	locale := i18n.Default
+	user, err := n.users.GetByID(userID)
+	if err == nil {
+		locale = user.Locale
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+
+	rendered := n.templates.Render(notification, locale)
+	attrs := []any{"user_id", userID, "locale", locale, "subject", rendered.Subject,
+		"message", rendered.Text, "html", rendered.HTML != ""}
+	if notification.Address != "" {
+		attrs = append(attrs, "address", notification.Address)
+	}
+	n.logger.Info("User notification", attrs...)
+	return nil
+}
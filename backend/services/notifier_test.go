@@ -0,0 +1,51 @@
+package services
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestLogNotifier(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	notifier := NewLogNotifier(store.Users(), NewTemplateService(store.NotificationTemplates(), store.Events(), logger), logger)
+
+	user := &models.User{Email: "minji@example.com", Name: "Minji", Locale: "ko"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	notification := i18n.Notification{Key: i18n.TicketCancelled, Args: []any{"E1-ABCD"}}
+	if err := notifier.NotifyUser(user.ID, notification); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "subject=\"티켓 취소\"") || !strings.Contains(logs.String(), "E1-ABCD") {
+		t.Errorf("Expected the notification in Korean, got %s", logs.String())
+	}
+
+	logs.Reset()
+	if err := notifier.NotifyUser(999, notification); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "subject=\"Ticket cancelled\"") {
+		t.Errorf("Expected unknown users to be notified in English, got %s", logs.String())
+	}
+
+	logs.Reset()
+	verify := i18n.Notification{Key: i18n.EmailVerify, Args: []any{"new@example.com", "https://x/confirm-email?token=t"}, Address: "new@example.com"}
+	if err := notifier.NotifyUser(user.ID, verify); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "address=new@example.com") {
+		t.Errorf("Expected the notification to go to the given address, got %s", logs.String())
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"tickets-by-uma/config"
+)
+
+// PwnedPasswordsRangeURL is the Have I Been Pwned range API. The first five
+// hex digits of a password's SHA-1 are appended, and it answers with the
+// suffixes of every breached hash sharing them, so the password itself
+// never leaves the server.
+const PwnedPasswordsRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// PasswordPolicyService checks new passwords against the configured
+// policy: length, required character classes, the deny list and, when
+// enabled, known breaches. Passwords that merely repeat a few characters,
+// are only digits or are the user's email or name are always refused.
+type PasswordPolicyService struct {
+	policy   config.PasswordPolicy
+	denied   map[string]bool
+	rangeURL string
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// NewPasswordPolicyService creates a password policy service. Breaches are
+// looked up under rangeURL with client.
+func NewPasswordPolicyService(policy config.PasswordPolicy, rangeURL string, client *http.Client, logger *slog.Logger) *PasswordPolicyService {
+	denied := make(map[string]bool, len(policy.DenyList))
+	for _, password := range policy.DenyList {
+		denied[strings.ToLower(password)] = true
+	}
+	return &PasswordPolicyService{policy: policy, denied: denied, rangeURL: rangeURL, client: client, logger: logger}
+}
+
+// Policy returns the policy passwords are checked against
+func (s *PasswordPolicyService) Policy() config.PasswordPolicy {
+	return s.policy
+}
+
+// Check returns an error describing the first rule password breaks, for a
+// user with the given email and name. A failed breach lookup is logged and
+// lets the password through rather than blocking signups while the API is
+// down.
+func (s *PasswordPolicyService) Check(ctx context.Context, password, email, name string) error {
+	if utf8.RuneCountInString(password) < s.policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", s.policy.MinLength)
+	}
+	if len(password) > s.policy.MaxBytes {
+		return fmt.Errorf("password must be at most %d bytes long", s.policy.MaxBytes)
+	}
+	lower := strings.ToLower(password)
+	if lower == strings.ToLower(email) || lower == strings.ToLower(name) {
+		return fmt.Errorf("password must not be your email or name")
+	}
+
+	classes := make(map[string]bool)
+	distinct := make(map[rune]bool)
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes[config.PasswordClassLower] = true
+		case unicode.IsUpper(r):
+			classes[config.PasswordClassUpper] = true
+		case unicode.IsDigit(r):
+			classes[config.PasswordClassDigit] = true
+		default:
+			classes[config.PasswordClassSymbol] = true
+		}
+		distinct[r] = true
+	}
+	if len(distinct) < 4 {
+		return fmt.Errorf("password must not repeat the same few characters")
+	}
+	if len(classes) == 1 && classes[config.PasswordClassDigit] {
+		return fmt.Errorf("password must not be only digits")
+	}
+	for _, class := range s.policy.RequiredClasses {
+		if !classes[class] {
+			return fmt.Errorf("password must contain %s", classDescriptions[class])
+		}
+	}
+
+	if s.denied[lower] {
+		return fmt.Errorf("password is too common")
+	}
+	if s.policy.BreachCheck {
+		breached, err := s.breached(ctx, password)
+		if err != nil {
+			s.logger.Warn("Password breach check failed", "error", err)
+		} else if breached {
+			return fmt.Errorf("password has appeared in a data breach; choose another")
+		}
+	}
+	return nil
+}
+
+// classDescriptions names the character classes in policy errors
+var classDescriptions = map[string]string{
+	config.PasswordClassLower:  "a lowercase letter",
+	config.PasswordClassUpper:  "an uppercase letter",
+	config.PasswordClassDigit:  "a digit",
+	config.PasswordClassSymbol: "a symbol",
+}
+
+// breached reports whether password appears in the breach corpus
+func (s *PasswordPolicyService) breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.rangeURL+hash[:5], nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the real number of matches from anyone watching
+	req.Header.Set("Add-Padding", "true")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach range lookup returned %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padding entries have a count of 0
+		if suffix == hash[5:] && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tickets-by-uma/config"
+)
+
+func TestPasswordPolicyService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	// The range API sees only the first five hex digits of the hash
+	sum := sha1.Sum([]byte("Breached-Passw0rd"))
+	breachedHash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	var prefixes []string
+	pwned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		prefixes = append(prefixes, prefix)
+		if r.Header.Get("Add-Padding") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n")
+		if prefix == breachedHash[:5] {
+			fmt.Fprintf(w, "%s:42\r\n", breachedHash[5:])
+		}
+	}))
+	defer pwned.Close()
+
+	policy := config.PasswordPolicy{
+		MinLength:       10,
+		MaxBytes:        config.MaxPasswordBytes,
+		RequiredClasses: []string{config.PasswordClassUpper, config.PasswordClassDigit},
+		BreachCheck:     true,
+		DenyList:        []string{"Lightning2026"},
+	}
+	passwords := NewPasswordPolicyService(policy, pwned.URL+"/range/", pwned.Client(), logger)
+
+	tests := []struct {
+		password string
+		wantErr  string
+	}{
+		{"Short-1", "at least 10 characters"},
+		{strings.Repeat("Aa1", 25), "at most 72 bytes"},
+		{"User@Example.com", "not be your email or name"},
+		{"AAAAaaaa1111", "same few characters"},
+		{"12345678901", "only digits"},
+		{"lowercase-only1", "an uppercase letter"},
+		{"No-Digits-Here", "a digit"},
+		{"LIGHTNING2026", "too common"},
+		{"Breached-Passw0rd", "data breach"},
+		{"Fresh-Passw0rd", ""},
+	}
+	for _, tt := range tests {
+		err := passwords.Check(ctx, tt.password, "user@example.com", "Some User")
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%q: expected no error, got %v", tt.password, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.password, tt.wantErr, err)
+		}
+	}
+	for _, prefix := range prefixes {
+		if len(prefix) != 5 {
+			t.Errorf("Expected only a five digit prefix to be sent, got %q", prefix)
+		}
+	}
+
+	// An unreachable breach API does not block the password
+	pwned.Close()
+	if err := passwords.Check(ctx, "Fresh-Passw0rd", "user@example.com", "Some User"); err != nil {
+		t.Errorf("Expected a failed breach lookup to let the password through, got %v", err)
+	}
+
+	// Without the flag no lookup is made
+	policy.BreachCheck = false
+	offline := NewPasswordPolicyService(policy, "http://127.0.0.1:0/range/", http.DefaultClient, logger)
+	if err := offline.Check(ctx, "Breached-Passw0rd", "user@example.com", "Some User"); err != nil {
+		t.Errorf("Expected no breach check when disabled, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+var (
+	// ErrNoPayoutAddress is returned when paying someone who has set no
+	// payout address, and no destination was given
+	ErrNoPayoutAddress = errors.New("payee has no payout address")
+	// ErrPayoutDestination is returned for a destination that is neither a
+	// Lightning Address, a UMA address nor a bolt11 invoice for the payout
+	ErrPayoutDestination = errors.New("destination must be a Lightning Address, a UMA address or a bolt11 invoice for the payout amount")
+	// ErrPayoutFailed is returned when the Lightning payment fails
+	ErrPayoutFailed = errors.New("payout payment failed")
+)
+
+// PayoutService sends organizer payouts, affiliate commission and refunds
+// owed to buyers over Lightning and books them in the ledger. Payees set a
+// Lightning Address or UMA address, which is checked against its LNURL-pay
+// service when it is saved; each payout resolves it to an invoice for the
+// amount. Refunds go to the address the buyer paid from. A bolt11 invoice
+// can stand in for the address on a single payout.
+type PayoutService struct {
+	ledger        *LedgerService
+	profileRepo   repositories.OrganizerProfileRepository
+	affiliateRepo repositories.AffiliateRepository
+	paymentRepo   repositories.PaymentRepository
+	ticketRepo    repositories.TicketRepository
+	umaService    UMAService
+	client        *http.Client
+	logger        *slog.Logger
+}
+
+// NewPayoutService creates a payout service paying through umaService and
+// reaching LNURL-pay services with client
+func NewPayoutService(ledger *LedgerService, profileRepo repositories.OrganizerProfileRepository, affiliateRepo repositories.AffiliateRepository, paymentRepo repositories.PaymentRepository, ticketRepo repositories.TicketRepository, umaService UMAService, client *http.Client, logger *slog.Logger) *PayoutService {
+	return &PayoutService{
+		ledger:        ledger,
+		profileRepo:   profileRepo,
+		affiliateRepo: affiliateRepo,
+		paymentRepo:   paymentRepo,
+		ticketRepo:    ticketRepo,
+		umaService:    umaService,
+		client:        client,
+		logger:        logger,
+	}
+}
+
+// ValidateAddress checks a payout address as it is configured and returns
+// it normalized. Empty clears the address. Anything else must be a
+// Lightning Address or UMA address whose LNURL-pay service answers with a
+// pay request; invoices are single use and cannot be stored.
+func (s *PayoutService) ValidateAddress(ctx context.Context, address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", nil
+	}
+	parsed, err := lnurl.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("payout address: %w", err)
+	}
+	if _, err := lnurl.Discover(ctx, s.client, parsed); err != nil {
+		return "", fmt.Errorf("payout address %s: %w", parsed, err)
+	}
+	return parsed.String(), nil
+}
+
+// PayOrganizer pays amountSats owed to the organizer to destination, or to
+// their payout address when destination is empty, and books the payout.
+// It returns ErrPayoutExceedsBalance when they are owed less.
+func (s *PayoutService) PayOrganizer(ctx context.Context, organizerID int, amountSats int64, destination string) (*models.LedgerEntry, error) {
+	if destination == "" {
+		profile, err := s.profileRepo.GetByUserID(organizerID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		if profile != nil {
+			destination = profile.PayoutAddress
+		}
+	}
+	if destination == "" {
+		return nil, ErrNoPayoutAddress
+	}
+	entry, err := s.ledger.SendPayout(organizerID, amountSats, s.sender(ctx, destination, amountSats))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Organizer payout sent", "organizer_id", organizerID, "amount_sats", amountSats, "reference", entry.Reference)
+	return entry, nil
+}
+
+// PayAffiliate is PayOrganizer for an affiliate's commission
+func (s *PayoutService) PayAffiliate(ctx context.Context, affiliateID int, amountSats int64, destination string) (*models.LedgerEntry, error) {
+	if destination == "" {
+		affiliate, err := s.affiliateRepo.GetByID(affiliateID)
+		if err != nil {
+			return nil, err
+		}
+		destination = affiliate.PayoutAddress
+	}
+	if destination == "" {
+		return nil, ErrNoPayoutAddress
+	}
+	entry, err := s.ledger.SendAffiliatePayout(affiliateID, amountSats, s.sender(ctx, destination, amountSats))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Affiliate payout sent", "affiliate_id", affiliateID, "amount_sats", amountSats, "reference", entry.Reference)
+	return entry, nil
+}
+
+// PayRefund sends the refund owed for a payment to destination, or to the
+// address its ticket was bought from when destination is empty, and books
+// it. It returns ErrNoRefundOwed when no refund is owed.
+func (s *PayoutService) PayRefund(ctx context.Context, paymentID int, destination string) (*models.LedgerEntry, error) {
+	owed, err := s.ledger.RefundOwed(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if owed <= 0 {
+		return nil, ErrNoRefundOwed
+	}
+	if destination == "" {
+		payment, err := s.paymentRepo.GetByID(paymentID)
+		if err != nil {
+			return nil, err
+		}
+		ticket, err := s.ticketRepo.GetByID(payment.TicketID)
+		if err != nil {
+			return nil, err
+		}
+		destination = ticket.UMAAddress
+	}
+	if destination == "" {
+		return nil, ErrNoPayoutAddress
+	}
+	entry, err := s.ledger.SendRefund(paymentID, owed, s.sender(ctx, destination, owed))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Refund sent", "payment_id", paymentID, "amount_sats", owed, "reference", entry.Reference)
+	return entry, nil
+}
+
+// sender returns the send step of a payout: resolve destination to an
+// invoice for amountSats and pay it, returning the payment as the ledger
+// reference. A pending payment is booked, as its sats may still leave.
+func (s *PayoutService) sender(ctx context.Context, destination string, amountSats int64) func() (string, error) {
+	return func() (string, error) {
+		bolt11, err := s.invoice(ctx, destination, amountSats)
+		if err != nil {
+			return "", err
+		}
+		result, err := s.umaService.SendPaymentToInvoice(bolt11)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrPayoutFailed, err)
+		}
+		if result.Status == "failed" {
+			return "", fmt.Errorf("%w: %s", ErrPayoutFailed, result.Message)
+		}
+		if result.Status != "success" {
+			s.logger.Warn("Payout payment still pending", "payment_id", result.PaymentID, "amount_sats", amountSats)
+		}
+		return "lightning:" + result.PaymentID, nil
+	}
+}
+
+// invoice resolves destination to a bolt11 invoice for amountSats
+func (s *PayoutService) invoice(ctx context.Context, destination string, amountSats int64) (string, error) {
+	destination = strings.TrimSpace(destination)
+	if lnurl.IsInvoice(destination) {
+		if amountMsat, ok := lnurl.InvoiceAmountMsat(destination); !ok || amountMsat != amountSats*1000 {
+			return "", ErrPayoutDestination
+		}
+		return destination, nil
+	}
+
+	address, err := lnurl.ParseAddress(destination)
+	if err != nil {
+		return "", ErrPayoutDestination
+	}
+	params, err := lnurl.Discover(ctx, s.client, address)
+	if err != nil {
+		return "", err
+	}
+	return params.Invoice(ctx, s.client, amountSats)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/accounting"
+	"tickets-by-uma/clock"
+	"tickets-by-uma/lnurl"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestPayoutService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	uma := NewSimulatedUMAService(0, clk, logger)
+
+	// wallet.example answers LNURL-pay for up to 500 sats with invoices from
+	// the simulated node, which pays its own invoices; a foreign invoice
+	// cannot be paid
+	var requested []string
+	foreign := false
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		respond := func(body string) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+		}
+		switch {
+		case req.URL.Host != "wallet.example":
+			return nil, errors.New("no such host")
+		case req.URL.Path == "/.well-known/lnurlp/acme":
+			return respond(`{"tag":"payRequest","callback":"https://wallet.example/lnurlp/acme/callback","minSendable":1000,"maxSendable":500000,"metadata":"[]"}`)
+		case req.URL.Path == "/lnurlp/acme/callback":
+			requested = append(requested, req.URL.Query().Get("amount"))
+			if foreign {
+				return respond(`{"pr":"lnbcrt4000n1simforeign"}`)
+			}
+			invoice, err := uma.CreateTicketInvoice("$acme@wallet.example", 400, "payout", time.Hour)
+			if err != nil {
+				return nil, err
+			}
+			return respond(`{"pr":"` + invoice.Bolt11 + `"}`)
+		}
+		return respond(`{"status":"ERROR","reason":"unknown user"}`)
+	})}
+	payouts := NewPayoutService(ledger, store.OrganizerProfiles(), store.Affiliates(), store.Payments(), store.Tickets(), uma, client, logger)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		address string
+		want    string
+		err     error
+	}{
+		{"", "", nil},
+		{" $Acme@Wallet.Example ", "$acme@wallet.example", nil},
+		{"lnbc10u1pjexample", "", lnurl.ErrInvalidAddress},
+		{"nobody@wallet.example", "", lnurl.ErrPayRequest},
+		{"acme@nowhere.example", "", lnurl.ErrPayRequest},
+	} {
+		got, err := payouts.ValidateAddress(ctx, tt.address)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ValidateAddress(%q) = %q, %v; expected %q, %v", tt.address, got, err, tt.want, tt.err)
+		}
+	}
+
+	organizer := 7
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true, OrganizerID: &organizer}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "PAYOUT-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-payout-1", Amount: 1000, Status: "pending"}
+	if err := store.Payments().Create(payment); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(payment.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := payouts.PayOrganizer(ctx, organizer, 400, ""); !errors.Is(err, ErrNoPayoutAddress) {
+		t.Errorf("Expected ErrNoPayoutAddress without an address, got %v", err)
+	}
+	if err := store.OrganizerProfiles().Save(&models.OrganizerProfile{UserID: organizer, Slug: "acme", DisplayName: "Acme", PayoutAddress: "acme@wallet.example"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failures leave the balance owed
+	if _, err := payouts.PayOrganizer(ctx, organizer, 1001, ""); !errors.Is(err, ErrPayoutExceedsBalance) {
+		t.Errorf("Expected ErrPayoutExceedsBalance, got %v", err)
+	}
+	if _, err := payouts.PayOrganizer(ctx, organizer, 600, ""); !errors.Is(err, lnurl.ErrAmountOutOfRange) {
+		t.Errorf("Expected ErrAmountOutOfRange above the maximum, got %v", err)
+	}
+	if _, err := payouts.PayOrganizer(ctx, organizer, 400, "lnbcrt3000n1simother"); !errors.Is(err, ErrPayoutDestination) {
+		t.Errorf("Expected ErrPayoutDestination for an invoice of another amount, got %v", err)
+	}
+	foreign = true
+	if _, err := payouts.PayOrganizer(ctx, organizer, 400, ""); !errors.Is(err, ErrPayoutFailed) {
+		t.Errorf("Expected ErrPayoutFailed for an unpayable invoice, got %v", err)
+	}
+	foreign = false
+	owed := func() int64 {
+		t.Helper()
+		balance, err := store.Ledger().GetBalance(accounting.OrganizerPayable(organizer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return balance.BalanceSats
+	}
+	if owed() != 1000 {
+		t.Errorf("Expected 1000 sats still owed after failed payouts, got %d", owed())
+	}
+
+	entry, err := payouts.PayOrganizer(ctx, organizer, 400, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(entry.Reference, "lightning:sim_pay_") || entry.Kind != models.LedgerEntryPayout {
+		t.Errorf("Unexpected payout entry %+v", entry)
+	}
+	if last := requested[len(requested)-1]; last != "400000" {
+		t.Errorf("Expected an invoice for 400000 msat, requested %s", last)
+	}
+	if owed() != 600 {
+		t.Errorf("Expected 600 sats owed after the payout, got %d", owed())
+	}
+
+	// A refund owed goes back to the address the ticket was bought from
+	refunded := &models.Ticket{EventID: event.ID, UserID: 2, TicketCode: "PAYOUT-2", PaymentStatus: "paid", UMAAddress: "$acme@wallet.example"}
+	if err := store.Tickets().Create(refunded); err != nil {
+		t.Fatal(err)
+	}
+	refund := &models.Payment{TicketID: refunded.ID, InvoiceID: "lnbc-payout-2", Amount: 400, Status: "pending"}
+	if err := store.Payments().Create(refund); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Payments().UpdateStatus(refund.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := payouts.PayRefund(ctx, refund.ID, ""); !errors.Is(err, ErrNoRefundOwed) {
+		t.Errorf("Expected ErrNoRefundOwed before the refund is owed, got %v", err)
+	}
+	if _, err := ledger.OweRefund(refund.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	entry, err = payouts.PayRefund(ctx, refund.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(entry.Reference, "lightning:sim_pay_") || *entry.PaymentID != refund.ID {
+		t.Errorf("Unexpected refund entry %+v", entry)
+	}
+	if _, err := payouts.PayRefund(ctx, refund.ID, ""); !errors.Is(err, ErrNoRefundOwed) {
+		t.Errorf("Expected ErrNoRefundOwed once the refund is sent, got %v", err)
+	}
+	if owed() != 600 {
+		t.Errorf("Expected the refund to leave the organizer owed 600 sats, got %d", owed())
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// PriceChangeService carries a fixed-price event's new price over to the
+// purchases still awaiting payment. One whose invoice lapsed unpaid is
+// repriced, so re-invoicing it charges the new price. One whose invoice can
+// still be paid is locked at the price it was started at, by design: a
+// Lightning invoice cannot be withdrawn once issued, so expiring it here
+// would not stop the buyer paying it, and the payment would settle a
+// purchase recorded at another price. A locked purchase whose invoice
+// lapses later is re-invoiced at its own price until the next price change
+// reprices it. Buyers of both are told. Purchases not yet invoiced keep
+// their price too.
+type PriceChangeService struct {
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	addOnRepo   repositories.AddOnRepository
+	pricing     *PricingService
+	notifier    Notifier
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+// NewPriceChangeService creates a price change service. notifier may be
+// nil.
+func NewPriceChangeService(ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, addOnRepo repositories.AddOnRepository, pricing *PricingService, notifier Notifier, clk clock.Clock, logger *slog.Logger) *PriceChangeService {
+	return &PriceChangeService{
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		addOnRepo:   addOnRepo,
+		pricing:     pricing,
+		notifier:    notifier,
+		clock:       clk,
+		logger:      logger,
+	}
+}
+
+// Apply carries the saved event's price over to its unpaid purchases and
+// returns the IDs of the tickets locked at their price, whose invoice and
+// amount are left untouched, and of those repriced. Pay-what-you-want and free events' purchases are left as they
+// are. It stops at the first ticket that cannot be repriced.
+func (s *PriceChangeService) Apply(event *models.Event) (locked, repriced []int, err error) {
+	if event.PayWhatYouWant() || event.PriceSats <= 0 {
+		return nil, nil, nil
+	}
+	tickets, err := s.ticketRepo.GetByEventID(event.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Repriced tickets are sold at the price quoted now, recorded once
+	var change *models.PriceChange
+	now := s.clock.Now()
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.IsComp || (ticket.PaymentStatus != models.TicketPending && ticket.PaymentStatus != models.TicketExpired) {
+			continue
+		}
+		payment, err := s.paymentRepo.GetByTicketID(ticket.ID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return locked, repriced, err
+		}
+
+		payable := payment.Status == models.PaymentPending && (payment.ExpiresAt == nil || now.Before(*payment.ExpiresAt))
+		if payable {
+			locked = append(locked, ticket.ID)
+			if payment.ExpiresAt != nil {
+				s.notify(ticket, i18n.PriceHeld, event.Title, payment.Amount, payment.ExpiresAt.UTC().Format(rescheduleTimeLayout))
+			}
+			continue
+		}
+		if payment.Status != models.PaymentPending && payment.Status != models.PaymentExpired {
+			continue
+		}
+
+		if change == nil {
+			if change, err = s.pricing.Record(event); err != nil {
+				return locked, repriced, fmt.Errorf("failed to price event %d: %w", event.ID, err)
+			}
+		}
+		addOns, err := s.addOnRepo.GetLineItems(ticket.ID)
+		if err != nil {
+			return locked, repriced, err
+		}
+		amount := change.PriceSats
+		for _, addOn := range addOns {
+			amount += addOn.TotalSats()
+		}
+		if amount == ticket.AmountSats {
+			continue
+		}
+		if err := s.ticketRepo.Reprice(ticket.ID, amount, &change.ID); err != nil {
+			return locked, repriced, fmt.Errorf("failed to reprice ticket %d: %w", ticket.ID, err)
+		}
+		repriced = append(repriced, ticket.ID)
+		s.logger.Info("Unpaid ticket repriced", "ticket_id", ticket.ID, "event_id", event.ID,
+			"old_amount_sats", ticket.AmountSats, "amount_sats", amount)
+		s.notify(ticket, i18n.PriceChanged, event.Title, change.PriceSats)
+	}
+	return locked, repriced, nil
+}
+
+func (s *PriceChangeService) notify(ticket *models.Ticket, key string, args ...any) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.NotifyUser(ticket.UserID, i18n.Notification{Key: key, Args: args, EventID: ticket.EventID}); err != nil {
+		s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+	}
+}
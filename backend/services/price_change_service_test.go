@@ -0,0 +1,127 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestPriceChangeService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	pricing := NewPricingService(store.PricingRules(), store.Events(), clk, logger)
+	notifier := &recordingNotifier{}
+	priceChanges := NewPriceChangeService(store.Tickets(), store.Payments(), store.AddOns(), pricing, notifier, clk, logger)
+
+	event := &models.Event{Title: "Meetup", Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	payable := clk.Now().Add(10 * time.Minute)
+	lapsed := clk.Now().Add(-time.Minute)
+	purchases := []struct {
+		ticketStatus, paymentStatus string
+		expiresAt                   *time.Time
+	}{
+		{"pending", "pending", &payable}, // invoice still payable
+		{"expired", "expired", &lapsed},  // released after its invoice expired
+		{"pending", "pending", &lapsed},  // invoice lapsed before the release
+		{"paid", "paid", &lapsed},
+		{"pending", "", nil}, // awaiting approval, not yet invoiced
+	}
+	var tickets []*models.Ticket
+	for i, purchase := range purchases {
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "PRICE-" + strconv.Itoa(i), PaymentStatus: purchase.ticketStatus, AmountSats: 1000}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+		if purchase.paymentStatus == "" {
+			continue
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-price-" + strconv.Itoa(i), Amount: 1000, Status: "pending", ExpiresAt: purchase.expiresAt}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, purchase.paymentStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The released ticket's add-on keeps its price
+	if err := store.AddOns().CreateLineItems([]models.TicketAddOn{{TicketID: tickets[1].ID, Name: "Poster", Quantity: 2, UnitPriceSats: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Tickets().Reprice(tickets[1].ID, 1200, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	event.PriceSats = 1500
+	locked, repriced, err := priceChanges.Apply(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0] != tickets[0].ID {
+		t.Errorf("Expected the payable purchase to keep its price, got %v", locked)
+	}
+	sort.Ints(repriced)
+	if len(repriced) != 2 || repriced[0] != tickets[1].ID || repriced[1] != tickets[2].ID {
+		t.Errorf("Expected the lapsed purchases repriced, got %v", repriced)
+	}
+	for i, want := range []int64{1000, 1700, 1500, 1000, 1000} {
+		ticket, _ := store.Tickets().GetByID(tickets[i].ID)
+		if ticket.AmountSats != want {
+			t.Errorf("Expected ticket %d at %d sats, got %d", i, want, ticket.AmountSats)
+		}
+		if (i == 1 || i == 2) != (ticket.PriceChangeID != nil) {
+			t.Errorf("Expected only repriced tickets to record the new price, got ticket %d with %v", i, ticket.PriceChangeID)
+		}
+	}
+	history, err := store.PricingRules().GetPriceHistory(event.ID)
+	if err != nil || len(history) != 1 || history[0].PriceSats != 1500 {
+		t.Errorf("Expected the new price recorded once, got %+v (%v)", history, err)
+	}
+	if got, want := strings.Join(notifier.subjects, ","), "Ticket price changed,Ticket price changed,Ticket price changed"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// The lock is intended: the payable invoice is left as issued, so the
+	// buyer can still pay it at the old price
+	payment, err := store.Payments().GetByTicketID(tickets[0].ID)
+	if err != nil || payment.Status != models.PaymentPending || payment.Amount != 1000 || !payment.ExpiresAt.Equal(payable) {
+		t.Errorf("Expected the locked invoice left payable at 1000 sats, got %+v (%v)", payment, err)
+	}
+
+	// Applying it again finds nothing left to reprice
+	notifier.subjects = nil
+	if locked, repriced, err := priceChanges.Apply(event); err != nil || len(repriced) != 0 || len(locked) != 1 {
+		t.Errorf("Expected nothing repriced twice and the lock kept, got %v %v (%v)", locked, repriced, err)
+	}
+
+	// Once its invoice lapses, the next price change reprices the locked
+	// purchase like any other
+	clk.Advance(11 * time.Minute)
+	event.PriceSats = 1600
+	locked, repriced, err = priceChanges.Apply(event)
+	if err != nil || len(locked) != 0 || !slices.Contains(repriced, tickets[0].ID) {
+		t.Errorf("Expected the lapsed purchase repriced, got %v %v (%v)", locked, repriced, err)
+	}
+	if ticket, _ := store.Tickets().GetByID(tickets[0].ID); ticket.AmountSats != 1600 || ticket.PriceChangeID == nil {
+		t.Errorf("Expected the lapsed purchase at 1600 sats, got %+v", ticket)
+	}
+
+	// Buyers choose their own price on pay-what-you-want events
+	event.PricingMode, event.MinPriceSats = models.PricingPayWhatYouWant, 500
+	if locked, repriced, err := priceChanges.Apply(event); err != nil || locked != nil || repriced != nil {
+		t.Errorf("Expected pay-what-you-want purchases left alone, got %v %v (%v)", locked, repriced, err)
+	}
+}
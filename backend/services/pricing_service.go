@@ -0,0 +1,89 @@
+package services
+
+import (
+	"log/slog"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// PriceQuote is a fixed-price event's current ticket price and the pricing
+// rule that set it, nil when it is the event's own price
+type PriceQuote struct {
+	PriceSats int64               `json:"price_sats"`
+	Rule      *models.PricingRule `json:"rule,omitempty"`
+}
+
+// PricingService prices fixed-price events' tickets by their pricing rules,
+// which raise the price once a share of the capacity is sold or from a
+// date, and records the prices tickets are sold at. Pay-what-you-want
+// events are not repriced.
+type PricingService struct {
+	ruleRepo  repositories.PricingRuleRepository
+	eventRepo repositories.EventRepository
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewPricingService creates a pricing service
+func NewPricingService(ruleRepo repositories.PricingRuleRepository, eventRepo repositories.EventRepository, clk clock.Clock, logger *slog.Logger) *PricingService {
+	return &PricingService{
+		ruleRepo:  ruleRepo,
+		eventRepo: eventRepo,
+		clock:     clk,
+		logger:    logger,
+	}
+}
+
+// Quote returns event's ticket price now: the highest of the event price
+// and the prices of its triggered rules. Demand is measured by paid
+// tickets against capacity.
+func (s *PricingService) Quote(event *models.Event) (PriceQuote, error) {
+	quote := PriceQuote{PriceSats: event.PriceSats}
+	if event.PayWhatYouWant() {
+		return quote, nil
+	}
+	rules, err := s.ruleRepo.GetByEventID(event.ID)
+	if err != nil || len(rules) == 0 {
+		return quote, err
+	}
+
+	var sold int
+	for _, rule := range rules {
+		if rule.SoldPercent != nil {
+			availability, err := s.eventRepo.GetAvailability(event.ID)
+			if err != nil {
+				return quote, err
+			}
+			sold = availability.Sold
+			break
+		}
+	}
+	now := s.clock.Now()
+	for i, rule := range rules {
+		if rule.PriceSats > quote.PriceSats && rule.Triggered(now, sold, event.Capacity) {
+			quote.PriceSats, quote.Rule = rule.PriceSats, &rules[i]
+		}
+	}
+	return quote, nil
+}
+
+// Record quotes event's ticket price for a sale and records it in the
+// event's price history, returning the price change the ticket is sold at
+func (s *PricingService) Record(event *models.Event) (*models.PriceChange, error) {
+	quote, err := s.Quote(event)
+	if err != nil {
+		return nil, err
+	}
+	change := &models.PriceChange{EventID: event.ID, PriceSats: quote.PriceSats}
+	if quote.Rule != nil {
+		change.RuleID, change.RuleName = &quote.Rule.ID, quote.Rule.Name
+	}
+	return s.ruleRepo.RecordPrice(change)
+}
+
+// PriceChange returns a recorded price change
+func (s *PricingService) PriceChange(id int) (*models.PriceChange, error) {
+	return s.ruleRepo.GetPriceChange(id)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestPricingService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	pricing := NewPricingService(store.PricingRules(), store.Events(), clk, logger)
+
+	buyer := &models.User{Email: "buyer@example.com", Name: "Buyer"}
+	if err := store.Users().Create(buyer); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{Title: "Concert", Capacity: 4, PriceSats: 1000, IsActive: true,
+		StartTime: clk.Now().Add(72 * time.Hour), EndTime: clk.Now().Add(75 * time.Hour)}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	// The price rises to 1500 once half the tickets are sold and to 2000 a
+	// day before the event, whichever is higher
+	half, dayBefore := 50, event.StartTime.Add(-24*time.Hour)
+	demand := &models.PricingRule{EventID: event.ID, Name: "Half sold", PriceSats: 1500, SoldPercent: &half}
+	late := &models.PricingRule{EventID: event.ID, Name: "Last day", PriceSats: 2000, StartsAt: &dayBefore}
+	for _, rule := range []*models.PricingRule{demand, late} {
+		if err := store.PricingRules().Create(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sell := func(code, status string) {
+		t.Helper()
+		if err := store.Tickets().Create(&models.Ticket{EventID: event.ID, UserID: buyer.ID, TicketCode: code, PaymentStatus: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	quote := func() PriceQuote {
+		t.Helper()
+		quote, err := pricing.Quote(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return quote
+	}
+
+	if got := quote(); got.PriceSats != 1000 || got.Rule != nil {
+		t.Errorf("Expected the event price before any rule triggers, got %+v", got)
+	}
+	first, err := pricing.Record(event)
+	if err != nil || first.PriceSats != 1000 || first.RuleID != nil {
+		t.Fatalf("Expected the event price recorded, got %+v (%v)", first, err)
+	}
+
+	// Pending tickets do not count as sold
+	sell("PRICE-1", "paid")
+	sell("PRICE-2", "pending")
+	if got := quote(); got.PriceSats != 1000 {
+		t.Errorf("Expected one paid ticket of four to keep the price, got %+v", got)
+	}
+	sell("PRICE-3", "paid")
+	if got := quote(); got.PriceSats != 1500 || got.Rule == nil || got.Rule.ID != demand.ID {
+		t.Errorf("Expected the demand rule at half sold, got %+v", got)
+	}
+	raised, err := pricing.Record(event)
+	if err != nil || raised.ID == first.ID || raised.PriceSats != 1500 || raised.RuleName != "Half sold" {
+		t.Fatalf("Expected the raised price recorded, got %+v (%v)", raised, err)
+	}
+	if again, err := pricing.Record(event); err != nil || again.ID != raised.ID {
+		t.Errorf("Expected an unchanged price not recorded again, got %+v (%v)", again, err)
+	}
+
+	clk.Set(dayBefore)
+	if got := quote(); got.PriceSats != 2000 || got.Rule == nil || got.Rule.ID != late.ID {
+		t.Errorf("Expected the higher date rule on the last day, got %+v", got)
+	}
+
+	// Pay-what-you-want events keep their suggested price
+	event.PricingMode = models.PricingPayWhatYouWant
+	if got := quote(); got.PriceSats != 1000 || got.Rule != nil {
+		t.Errorf("Expected no repricing for pay what you want, got %+v", got)
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// rescheduleTimeLayout is how new times and refund deadlines are written in
+// notifications
+const rescheduleTimeLayout = "2006-01-02 15:04 UTC"
+
+// ErrRefundClosed is returned when a ticket has no reschedule refund open
+var ErrRefundClosed = errors.New("no reschedule refund is open for this ticket")
+
+// InvalidRescheduleError is returned when new times or a refund deadline
+// cannot be used
+type InvalidRescheduleError struct {
+	Reason string
+}
+
+func (e *InvalidRescheduleError) Error() string {
+	return e.Reason
+}
+
+// RescheduleService moves events to new times. Tickets stay valid for the
+// new times and their holders are notified. A reschedule may open a refund
+// window, until which buyers who paid before it can cancel their ticket
+// and have the payment refunded through the ledger.
+type RescheduleService struct {
+	eventRepo   repositories.EventRepository
+	repo        repositories.EventRescheduleRepository
+	ticketRepo  repositories.TicketRepository
+	paymentRepo repositories.PaymentRepository
+	ledger      *LedgerService
+	notifier    Notifier
+	admins      AdminNotifier
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+// NewRescheduleService creates a reschedule service. ledger, notifier and
+// admins may be nil.
+func NewRescheduleService(eventRepo repositories.EventRepository, repo repositories.EventRescheduleRepository, ticketRepo repositories.TicketRepository, paymentRepo repositories.PaymentRepository, ledger *LedgerService, notifier Notifier, admins AdminNotifier, clk clock.Clock, logger *slog.Logger) *RescheduleService {
+	return &RescheduleService{
+		eventRepo:   eventRepo,
+		repo:        repo,
+		ticketRepo:  ticketRepo,
+		paymentRepo: paymentRepo,
+		ledger:      ledger,
+		notifier:    notifier,
+		admins:      admins,
+		clock:       clk,
+		logger:      logger,
+	}
+}
+
+// Reschedule moves the event to the requested times, records the move and
+// notifies each ticket holder once. It returns repositories.ErrNotFound for
+// an unknown event and an *InvalidRescheduleError when the times or refund
+// deadline cannot be used.
+func (s *RescheduleService) Reschedule(eventID int, req models.RescheduleEventRequest, rescheduledBy *int) (*models.RescheduledEvent, error) {
+	event, err := s.eventRepo.GetByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	var reason string
+	switch {
+	case event.CancelledAt != nil:
+		reason = "cancelled events cannot be rescheduled"
+	case !req.EndTime.After(req.StartTime):
+		reason = "end_time must be after start_time"
+	case !req.StartTime.After(now):
+		reason = "start_time must be in the future"
+	case req.StartTime.Equal(event.StartTime) && req.EndTime.Equal(event.EndTime):
+		reason = "the event is already at these times"
+	case req.RefundUntil != nil && !req.RefundUntil.After(now):
+		reason = "refund_until must be in the future"
+	case req.RefundUntil != nil && req.RefundUntil.After(req.StartTime):
+		reason = "refund_until must not be after the new start_time"
+	}
+	if reason != "" {
+		return nil, &InvalidRescheduleError{Reason: reason}
+	}
+
+	reschedule := &models.EventReschedule{
+		EventID:       event.ID,
+		OldStartTime:  event.StartTime,
+		OldEndTime:    event.EndTime,
+		NewStartTime:  req.StartTime,
+		NewEndTime:    req.EndTime,
+		Reason:        req.Reason,
+		RefundUntil:   req.RefundUntil,
+		RescheduledBy: rescheduledBy,
+	}
+	event.StartTime, event.EndTime = req.StartTime, req.EndTime
+	if err := s.eventRepo.Update(event); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(reschedule); err != nil {
+		return nil, fmt.Errorf("event %d rescheduled but not recorded: %w", event.ID, err)
+	}
+	s.logger.Info("Event rescheduled", "event_id", event.ID, "reschedule_id", reschedule.ID,
+		"old_start_time", reschedule.OldStartTime, "new_start_time", reschedule.NewStartTime, "refund_until", reschedule.RefundUntil)
+
+	notified, err := s.notifyHolders(event, reschedule)
+	if err != nil {
+		return nil, fmt.Errorf("event %d rescheduled but its ticket holders could not be listed: %w", event.ID, err)
+	}
+	return &models.RescheduledEvent{Event: event, Reschedule: reschedule, Notified: notified}, nil
+}
+
+// notifyHolders tells each user holding a ticket to the event about the
+// reschedule, once however many tickets they hold
+func (s *RescheduleService) notifyHolders(event *models.Event, reschedule *models.EventReschedule) (int, error) {
+	tickets, err := s.ticketRepo.GetByEventID(event.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	notification := i18n.Notification{
+		Key:     i18n.EventRescheduled,
+		Args:    []any{event.Title, reschedule.NewStartTime.UTC().Format(rescheduleTimeLayout)},
+		EventID: event.ID,
+	}
+	if reschedule.RefundUntil != nil {
+		notification.Key = i18n.RefundOffered
+		notification.Args = append(notification.Args, reschedule.RefundUntil.UTC().Format(rescheduleTimeLayout))
+	}
+
+	notified := map[int]bool{}
+	for _, ticket := range tickets {
+		switch ticket.PaymentStatus {
+		case "paid", "pending", "review", models.TicketDisputed:
+		default:
+			continue
+		}
+		if notified[ticket.UserID] {
+			continue
+		}
+		notified[ticket.UserID] = true
+		if s.notifier == nil {
+			continue
+		}
+		if err := s.notifier.NotifyUser(ticket.UserID, notification); err != nil {
+			s.logger.Warn("Failed to notify ticket holder", "event_id", event.ID, "user_id", ticket.UserID, "error", err)
+		}
+	}
+	return len(notified), nil
+}
+
+// RequestRefund cancels a paid ticket at its buyer's request and books the
+// refund of its payment. The ticket must have been bought before a
+// reschedule of its event whose refund window is still open; otherwise
+// ErrRefundClosed is returned. It returns the reschedule the refund was
+// granted under.
+func (s *RescheduleService) RequestRefund(ticket *models.Ticket) (*models.EventReschedule, error) {
+	if ticket.PaymentStatus != "paid" {
+		return nil, ErrRefundClosed
+	}
+	reschedule, err := s.openWindow(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := fmt.Sprintf("reschedule %d of event %d", reschedule.ID, ticket.EventID)
+	if err := cancelTicket(s.ticketRepo, s.paymentRepo, s.ledger, ticket, reference); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Ticket refunded after reschedule", "ticket_id", ticket.ID, "event_id", ticket.EventID, "reschedule_id", reschedule.ID)
+
+	title := ""
+	if event, err := s.eventRepo.GetByID(ticket.EventID); err == nil {
+		title = event.Title
+	}
+	if s.admins != nil {
+		s.admins.NotifyAdmins(refundRequested(ticket, title, "after the event was rescheduled"))
+	}
+	if s.notifier != nil {
+		notification := i18n.Notification{Key: i18n.RescheduleRefund, Args: []any{title, ticket.TicketCode}, EventID: ticket.EventID}
+		if err := s.notifier.NotifyUser(ticket.UserID, notification); err != nil {
+			s.logger.Warn("Failed to notify buyer", "ticket_id", ticket.ID, "user_id", ticket.UserID, "error", err)
+		}
+	}
+	return reschedule, nil
+}
+
+// openWindow returns the newest reschedule made after the ticket was bought
+// whose refund window is still open
+func (s *RescheduleService) openWindow(ticket *models.Ticket) (*models.EventReschedule, error) {
+	reschedules, err := s.repo.GetByEventID(ticket.EventID)
+	if err != nil {
+		return nil, err
+	}
+	// Buyers after a reschedule knew the new times, so are not offered
+	// its refund
+	now := s.clock.Now()
+	for i := range reschedules {
+		reschedule := &reschedules[i]
+		if reschedule.RefundUntil != nil && now.Before(*reschedule.RefundUntil) && ticket.CreatedAt.Before(reschedule.CreatedAt) {
+			return reschedule, nil
+		}
+	}
+	return nil, ErrRefundClosed
+}
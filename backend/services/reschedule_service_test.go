@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestRescheduleService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	ledger := NewLedgerService(store.Ledger(), clk, logger)
+	notifier := &recordingNotifier{}
+	reschedules := NewRescheduleService(store.Events(), store.EventReschedules(), store.Tickets(), store.Payments(), ledger, notifier, nil, clk, logger)
+
+	start := clk.Now().Add(7 * 24 * time.Hour)
+	event := &models.Event{Title: "Meetup", StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	bought := 0
+	buy := func(userID int, status string) *models.Ticket {
+		t.Helper()
+		clk.Advance(time.Minute)
+		bought++
+		n := strconv.Itoa(bought)
+		ticket := &models.Ticket{EventID: event.ID, UserID: userID, TicketCode: "MOVE-" + n, PaymentStatus: status}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-move-" + n, Amount: 1000, Status: "pending"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Payments().UpdateStatus(payment.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+	// User 1 holds two paid tickets, user 2 a pending one; user 3's failed
+	// purchase holds nothing
+	first, second := buy(1, "paid"), buy(1, "paid")
+	pending := buy(2, "pending")
+	buy(3, "failed")
+
+	newStart := start.Add(14 * 24 * time.Hour)
+	refundUntil := clk.Now().Add(3 * 24 * time.Hour)
+	past := clk.Now().Add(-time.Hour)
+	afterStart := newStart.Add(time.Hour)
+	for name, req := range map[string]models.RescheduleEventRequest{
+		"end before start":       {StartTime: newStart, EndTime: newStart.Add(-time.Hour)},
+		"start in the past":      {StartTime: past, EndTime: past.Add(time.Hour)},
+		"same times":             {StartTime: event.StartTime, EndTime: event.EndTime},
+		"refund deadline passed": {StartTime: newStart, EndTime: newStart.Add(time.Hour), RefundUntil: &past},
+		"refund after new start": {StartTime: newStart, EndTime: newStart.Add(time.Hour), RefundUntil: &afterStart},
+	} {
+		var invalid *InvalidRescheduleError
+		if _, err := reschedules.Reschedule(event.ID, req, nil); !errors.As(err, &invalid) {
+			t.Errorf("%s: expected an invalid reschedule, got %v", name, err)
+		}
+	}
+	if _, err := reschedules.Reschedule(event.ID+100, models.RescheduleEventRequest{StartTime: newStart, EndTime: newStart.Add(time.Hour)}, nil); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown event, got %v", err)
+	}
+
+	adminID := 9
+	req := models.RescheduleEventRequest{StartTime: newStart, EndTime: newStart.Add(2 * time.Hour), Reason: "Venue flooded", RefundUntil: &refundUntil}
+	result, err := reschedules.Reschedule(event.ID, req, &adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Notified != 2 || !result.Event.StartTime.Equal(newStart) || !result.Reschedule.OldStartTime.Equal(start) || *result.Reschedule.RescheduledBy != adminID {
+		t.Errorf("Unexpected reschedule %+v %+v", result, result.Reschedule)
+	}
+	if stored, _ := store.Events().GetByID(event.ID); !stored.StartTime.Equal(newStart) || !stored.EndTime.Equal(newStart.Add(2*time.Hour)) {
+		t.Errorf("Expected the event to move, got %v to %v", stored.StartTime, stored.EndTime)
+	}
+	if got := strings.Join(notifier.subjects, ","); got != "Event rescheduled,Event rescheduled" {
+		t.Errorf("Expected each holder notified once, got %s", got)
+	}
+
+	// A ticket bought after the reschedule was bought knowing the new times
+	late := buy(4, "paid")
+	for name, ticket := range map[string]*models.Ticket{"late": late, "pending": pending} {
+		if _, err := reschedules.RequestRefund(ticket); !errors.Is(err, ErrRefundClosed) {
+			t.Errorf("%s: expected ErrRefundClosed, got %v", name, err)
+		}
+	}
+
+	granted, err := reschedules.RequestRefund(first)
+	if err != nil || granted.ID != result.Reschedule.ID {
+		t.Fatalf("Expected a refund under the reschedule, got %+v (%v)", granted, err)
+	}
+	ticket, _ := store.Tickets().GetByID(first.ID)
+	payment, _ := store.Payments().GetByTicketID(first.ID)
+	if ticket.PaymentStatus != "cancelled" || payment.Status != "cancelled" {
+		t.Errorf("Expected the ticket and its payment cancelled, got %s and %s", ticket.PaymentStatus, payment.Status)
+	}
+	if _, err := store.Ledger().GetPaymentEntry(models.LedgerEntryRefund, payment.ID); err != nil {
+		t.Errorf("Expected the refund in the ledger, got %v", err)
+	}
+	if owed, err := ledger.RefundOwed(payment.ID); err != nil || owed != payment.Amount {
+		t.Errorf("Expected the refund owed until it is sent, got %d (%v)", owed, err)
+	}
+	if issues, err := ledger.Check(); err != nil || len(issues) != 0 {
+		t.Errorf("Expected a consistent ledger, got %+v (%v)", issues, err)
+	}
+	if !strings.HasSuffix(strings.Join(notifier.subjects, ","), ",Ticket refunded") {
+		t.Errorf("Expected the buyer told about the refund, got %v", notifier.subjects)
+	}
+
+	clk.Advance(3 * 24 * time.Hour)
+	if _, err := reschedules.RequestRefund(second); !errors.Is(err, ErrRefundClosed) {
+		t.Errorf("Expected ErrRefundClosed once the window closed, got %v", err)
+	}
+}
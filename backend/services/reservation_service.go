@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/repositories"
+)
+
+// ReservationService releases the seats held by unpaid tickets. A pending
+// ticket holds its seat for as long as its invoice is payable; once the
+// invoice expires the payment and ticket are marked expired, and the seat
+// is free again.
+type ReservationService struct {
+	payments repositories.PaymentRepository
+	tickets  repositories.TicketRepository
+	logger   *slog.Logger
+}
+
+// NewReservationService creates a reservation service
+func NewReservationService(
+	payments repositories.PaymentRepository,
+	tickets repositories.TicketRepository,
+	logger *slog.Logger,
+) *ReservationService {
+	return &ReservationService{
+		payments: payments,
+		tickets:  tickets,
+		logger:   logger,
+	}
+}
+
+// Watch releases expired reservations every interval until ctx is cancelled
+func (s *ReservationService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReleaseExpired(); err != nil {
+				s.logger.Error("Failed to release expired reservations", "error", err)
+			}
+		}
+	}
+}
+
+// ReleaseExpired expires the pending payments whose invoice has expired and
+// their tickets, returning how many tickets were released. Tickets no
+// longer pending, e.g. refunded meanwhile, keep their status.
+func (s *ReservationService) ReleaseExpired() (int, error) {
+	expired, err := s.payments.ExpireOverdue()
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, payment := range expired {
+		ticket, err := s.tickets.GetByID(payment.TicketID)
+		if err != nil {
+			s.logger.Error("Failed to fetch ticket of expired payment", "payment_id", payment.ID, "ticket_id", payment.TicketID, "error", err)
+			continue
+		}
+		if ticket.PaymentStatus != "pending" {
+			continue
+		}
+		if err := s.tickets.UpdatePaymentStatus(ticket.ID, "expired"); err != nil {
+			s.logger.Error("Failed to expire ticket", "ticket_id", ticket.ID, "error", err)
+			continue
+		}
+		released++
+	}
+	if released > 0 {
+		s.logger.Info("Released seats of expired invoices", "tickets", released)
+	}
+	return released, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestReservationServiceReleaseExpired(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	service := NewReservationService(store.Payments(), store.Tickets(), logger)
+
+	event := &models.Event{Title: "On-sale", Capacity: 2, PriceSats: 1000, IsActive: true, InvoiceExpirySeconds: 900}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	reserve := func(code string, status string) *models.Ticket {
+		t.Helper()
+		ticket := &models.Ticket{EventID: event.ID, UserID: 1, TicketCode: code, PaymentStatus: "pending"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		expiresAt := clk.Now().Add(event.InvoiceExpiry())
+		if err := store.Payments().Create(&models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-" + code, Amount: 1000, Status: "pending", ExpiresAt: &expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if status != "pending" {
+			store.Tickets().UpdatePaymentStatus(ticket.ID, status)
+		}
+		return ticket
+	}
+	unpaid := reserve("UNPAID", "pending")
+	refunded := reserve("REFUNDED", "cancelled")
+
+	if availability, _ := store.Events().GetAvailability(event.ID); availability.Pending != 1 || availability.Remaining != 1 {
+		t.Fatalf("Expected the unpaid ticket to hold its seat, got %+v", availability)
+	}
+	if released, err := service.ReleaseExpired(); err != nil || released != 0 {
+		t.Fatalf("Expected nothing released before the invoices expire, got %d (%v)", released, err)
+	}
+
+	clk.Advance(15 * time.Minute)
+	if released, err := service.ReleaseExpired(); err != nil || released != 1 {
+		t.Fatalf("Expected the unpaid ticket released, got %d (%v)", released, err)
+	}
+	if ticket, _ := store.Tickets().GetByID(unpaid.ID); ticket.PaymentStatus != "expired" {
+		t.Errorf("Expected the unpaid ticket expired, got %s", ticket.PaymentStatus)
+	}
+	if payment, _ := store.Payments().GetByTicketID(unpaid.ID); payment.Status != "expired" {
+		t.Errorf("Expected its payment expired, got %s", payment.Status)
+	}
+	if ticket, _ := store.Tickets().GetByID(refunded.ID); ticket.PaymentStatus != "cancelled" {
+		t.Errorf("Expected a refunded ticket left alone, got %s", ticket.PaymentStatus)
+	}
+	if availability, _ := store.Events().GetAvailability(event.ID); availability.Pending != 0 || availability.Remaining != 2 {
+		t.Errorf("Expected the seat released, got %+v", availability)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// retentionBatch is how many rows of a table each archive call moves
+const retentionBatch = 100
+
+// RetentionService moves rows past their retention period to the archive.
+// Payments go first, then tickets, then events, so one pass archives an
+// old event whose payments and tickets are all due.
+type RetentionService struct {
+	archive repositories.ArchiveRepository
+	policy  config.RetentionPolicy
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// NewRetentionService creates a retention service archiving per policy
+func NewRetentionService(archive repositories.ArchiveRepository, policy config.RetentionPolicy, clk clock.Clock, logger *slog.Logger) *RetentionService {
+	return &RetentionService{archive: archive, policy: policy, clock: clk, logger: logger}
+}
+
+// Watch runs the archiver every day at hour (UTC) until ctx is cancelled
+func (s *RetentionService) Watch(ctx context.Context, hour int) {
+	for {
+		now := s.clock.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if _, err := s.Run(); err != nil {
+				s.logger.Error("Failed to archive old rows", "error", err)
+			}
+		}
+	}
+}
+
+// Run archives everything past its retention period and returns how many
+// payments, tickets and events it moved
+func (s *RetentionService) Run() (*models.ArchiveRun, error) {
+	run := &models.ArchiveRun{}
+	steps := []struct {
+		months  int
+		count   *int
+		archive func(cutoff time.Time, limit int) (int, error)
+	}{
+		{s.policy.PaymentsMonths, &run.Payments, s.archive.ArchivePayments},
+		{s.policy.TicketsMonths, &run.Tickets, s.archive.ArchiveTickets},
+		{s.policy.EventsMonths, &run.Events, s.archive.ArchiveEvents},
+	}
+	for _, step := range steps {
+		if step.months <= 0 {
+			continue
+		}
+		cutoff := s.clock.Now().AddDate(0, -step.months, 0)
+		for {
+			archived, err := step.archive(cutoff, retentionBatch)
+			*step.count += archived
+			if err != nil {
+				return run, err
+			}
+			if archived < retentionBatch {
+				break
+			}
+		}
+	}
+
+	if run.Payments+run.Tickets+run.Events > 0 {
+		s.logger.Info("Archived old rows", "payments", run.Payments, "tickets", run.Tickets, "events", run.Events)
+	}
+	return run, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestRetentionService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+
+	event := &models.Event{Title: "Old Meetup", StartTime: clk.Now().Add(-2 * time.Hour), EndTime: clk.Now().Add(-time.Hour), Capacity: 200, PriceSats: 1000}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	// More failed attempts than one batch holds
+	for i := 0; i < retentionBatch+1; i++ {
+		ticket := &models.Ticket{EventID: event.ID, UserID: i + 1, TicketCode: "OLD-" + strconv.Itoa(i), PaymentStatus: "failed"}
+		if err := store.Tickets().Create(ticket); err != nil {
+			t.Fatal(err)
+		}
+		payment := &models.Payment{TicketID: ticket.ID, InvoiceID: "lnbc-old-" + strconv.Itoa(i), Amount: 1000, Status: "failed"}
+		if err := store.Payments().Create(payment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clk.Advance(13 * 30 * 24 * time.Hour)
+
+	// Events are kept while their retention is off
+	retention := NewRetentionService(store.Archive(), config.RetentionPolicy{PaymentsMonths: 12, TicketsMonths: 12}, clk, logger)
+	run, err := retention.Run()
+	if err != nil || run.Payments != retentionBatch+1 || run.Tickets != retentionBatch+1 || run.Events != 0 {
+		t.Fatalf("Unexpected run %+v (%v)", run, err)
+	}
+	if _, err := store.Events().GetByID(event.ID); err != nil {
+		t.Errorf("Expected the event kept, got %v", err)
+	}
+
+	// The event is not yet past its own, longer retention
+	retention = NewRetentionService(store.Archive(), config.RetentionPolicy{PaymentsMonths: 12, TicketsMonths: 12, EventsMonths: 24}, clk, logger)
+	if run, err := retention.Run(); err != nil || run.Events != 0 {
+		t.Errorf("Expected nothing archived, got %+v (%v)", run, err)
+	}
+	clk.Advance(365 * 24 * time.Hour)
+	if run, err := retention.Run(); err != nil || run.Payments != 0 || run.Events != 1 {
+		t.Errorf("Expected the event archived, got %+v (%v)", run, err)
+	}
+	records, err := store.Archive().ListByEvent(event.ID)
+	if err != nil || len(records) != 2*(retentionBatch+1)+1 {
+		t.Errorf("Expected every row archived under the event, got %d (%v)", len(records), err)
+	}
+}
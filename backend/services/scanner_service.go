@@ -0,0 +1,147 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// scannerPairingTTL is how long a scanner's pairing code can be used
+	scannerPairingTTL = 15 * time.Minute
+	// scannerPairingAlphabet spells pairing codes without look-alike
+	// characters; its 32 letters keep the random bytes unbiased
+	scannerPairingAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	scannerPairingLength   = 8
+	maxScannerNameLength   = 100
+)
+
+// Scanner errors
+var (
+	ErrScannerName         = errors.New("name is required and must be at most 100 characters")
+	ErrScannerPairing      = errors.New("pairing code is invalid, expired or already used")
+	ErrScannerTokenInvalid = errors.New("scanner token is invalid or revoked")
+)
+
+// ScannerService registers door scanners so staff at the door can check
+// tickets in without the organizer's admin login. An admin adds a scanner
+// to one event and gets a short pairing code; the device redeems the code
+// once for a long-lived device token. Scanner tokens only reach the ticket
+// validation routes, for the scanner's own event, and the scans made with
+// them name the device. Only hashes of codes and tokens are stored.
+type ScannerService struct {
+	repo      repositories.ScannerDeviceRepository
+	eventRepo repositories.EventRepository
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewScannerService creates a scanner service
+func NewScannerService(repo repositories.ScannerDeviceRepository, eventRepo repositories.EventRepository, clk clock.Clock, logger *slog.Logger) *ScannerService {
+	return &ScannerService{repo: repo, eventRepo: eventRepo, clock: clk, logger: logger}
+}
+
+// Create adds a scanner named name to an event, returning it with its
+// pairing code. It returns repositories.ErrNotFound for an unknown event.
+func (s *ScannerService) Create(eventID int, name string, createdBy *int) (*models.ScannerPairing, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxScannerNameLength {
+		return nil, ErrScannerName
+	}
+	if _, err := s.eventRepo.GetByID(eventID); err != nil {
+		return nil, err
+	}
+
+	code, err := newPairingCode()
+	if err != nil {
+		return nil, err
+	}
+	hash := hashClaimSecret(code)
+	expiresAt := s.clock.Now().Add(scannerPairingTTL)
+	device := &models.ScannerDevice{EventID: eventID, Name: name, PairingHash: &hash, PairingExpiresAt: &expiresAt, CreatedBy: createdBy}
+	if err := s.repo.Create(device); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Scanner added", "event_id", eventID, "scanner_id", device.ID, "created_by", createdBy)
+	return &models.ScannerPairing{Device: device, PairingCode: code}, nil
+}
+
+// Register redeems a pairing code for the scanner's device token. Codes
+// are matched ignoring case, spaces and dashes, as staff type them in.
+func (s *ScannerService) Register(pairingCode string) (*models.ScannerRegistration, error) {
+	code := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(pairingCode))
+	if code == "" {
+		return nil, ErrScannerPairing
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(secret)
+
+	device, err := s.repo.Register(hashClaimSecret(code), hashClaimSecret(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrScannerPairing
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Scanner registered", "event_id", device.EventID, "scanner_id", device.ID)
+	return &models.ScannerRegistration{Device: device, Token: token}, nil
+}
+
+// Authenticate returns the unrevoked scanner holding token and notes that
+// it was seen
+func (s *ScannerService) Authenticate(token string) (*models.ScannerDevice, error) {
+	device, err := s.repo.GetByTokenHash(hashClaimSecret(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrScannerTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Touch(device.ID); err != nil {
+		s.logger.Warn("Failed to note scanner activity", "scanner_id", device.ID, "error", err)
+		return device, nil
+	}
+	now := s.clock.Now()
+	device.LastSeenAt = &now
+	return device, nil
+}
+
+// List returns an event's scanners, revoked ones included
+func (s *ScannerService) List(eventID int) ([]models.ScannerDevice, error) {
+	return s.repo.GetByEventID(eventID)
+}
+
+// Revoke stops a scanner's token and any unused pairing code from working
+func (s *ScannerService) Revoke(id int, revokedBy *int) (*models.ScannerDevice, error) {
+	device, err := s.repo.Revoke(id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Scanner revoked", "event_id", device.EventID, "scanner_id", device.ID, "revoked_by", revokedBy)
+	return device, nil
+}
+
+// newPairingCode returns a random pairing code
+func newPairingCode() (string, error) {
+	raw := make([]byte, scannerPairingLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := make([]byte, len(raw))
+	for i, b := range raw {
+		code[i] = scannerPairingAlphabet[int(b)%len(scannerPairingAlphabet)]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestScannerService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	scanners := NewScannerService(store.ScannerDevices(), store.Events(), clk, logger)
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now().Add(time.Hour), EndTime: clk.Now().Add(4 * time.Hour), Capacity: 10, PriceSats: 1000, IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+	admin := 42
+
+	if _, err := scanners.Create(event.ID, "  ", &admin); !errors.Is(err, ErrScannerName) {
+		t.Errorf("Expected a blank name refused, got %v", err)
+	}
+	if _, err := scanners.Create(9999, "Door 1", &admin); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected an unknown event refused, got %v", err)
+	}
+	pairing, err := scanners.Create(event.ID, " Door 1 ", &admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairing.PairingCode) != scannerPairingLength || strings.Trim(pairing.PairingCode, scannerPairingAlphabet) != "" ||
+		pairing.Device.Name != "Door 1" || *pairing.Device.CreatedBy != admin {
+		t.Fatalf("Unexpected pairing %+v %+v", pairing, pairing.Device)
+	}
+
+	// Staff may type the code in lower case and split in two
+	typed := strings.ToLower(pairing.PairingCode[:4] + "-" + pairing.PairingCode[4:])
+	registration, err := scanners.Register(typed)
+	if err != nil || registration.Device.ID != pairing.Device.ID || len(registration.Token) != 64 {
+		t.Fatalf("Failed to register scanner: %+v (%v)", registration, err)
+	}
+	if _, err := scanners.Register(pairing.PairingCode); !errors.Is(err, ErrScannerPairing) {
+		t.Errorf("Expected a pairing code to work once, got %v", err)
+	}
+
+	clk.Advance(time.Hour)
+	device, err := scanners.Authenticate(registration.Token)
+	if err != nil || device.EventID != event.ID || !device.LastSeenAt.Equal(clk.Now()) {
+		t.Errorf("Expected the scanner authenticated, got %+v (%v)", device, err)
+	}
+	if _, err := scanners.Authenticate("not-a-token"); !errors.Is(err, ErrScannerTokenInvalid) {
+		t.Errorf("Expected an unknown token refused, got %v", err)
+	}
+
+	// A code left unused past its TTL no longer registers
+	late, err := scanners.Create(event.ID, "Door 2", &admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(scannerPairingTTL)
+	if _, err := scanners.Register(late.PairingCode); !errors.Is(err, ErrScannerPairing) {
+		t.Errorf("Expected an expired pairing code refused, got %v", err)
+	}
+
+	if _, err := scanners.Revoke(pairing.Device.ID, &admin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanners.Authenticate(registration.Token); !errors.Is(err, ErrScannerTokenInvalid) {
+		t.Errorf("Expected a revoked scanner refused, got %v", err)
+	}
+	if devices, err := scanners.List(event.ID); err != nil || len(devices) != 2 || devices[0].RevokedAt == nil {
+		t.Errorf("Expected both scanners listed, got %+v (%v)", devices, err)
+	}
+}
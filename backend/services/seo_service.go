@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// maxSitemapURLs is the most URLs one sitemap file may list
+const maxSitemapURLs = 50000
+
+// SEOService describes public events for search engines: a sitemap of their
+// pages and Schema.org Event markup for each. The sitemap is rebuilt only
+// when the listed events or their update times change.
+type SEOService struct {
+	eventRepo   repositories.EventRepository
+	profileRepo repositories.OrganizerProfileRepository
+	domain      string
+	logger      *slog.Logger
+
+	mu       sync.Mutex
+	entries  []models.SitemapEntry
+	sitemap  []byte
+	modified time.Time
+}
+
+// NewSEOService creates an SEO service for pages served at https://domain
+func NewSEOService(eventRepo repositories.EventRepository, profileRepo repositories.OrganizerProfileRepository, domain string, logger *slog.Logger) *SEOService {
+	return &SEOService{
+		eventRepo:   eventRepo,
+		profileRepo: profileRepo,
+		domain:      domain,
+		logger:      logger,
+	}
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// Sitemap returns the sitemap XML listing the home page and every active
+// public event page, and when the newest of them last changed
+func (s *SEOService) Sitemap() ([]byte, time.Time, error) {
+	entries, err := s.eventRepo.GetSitemapEntries(maxSitemapURLs - 1)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sitemap != nil && slices.EqualFunc(entries, s.entries, func(a, b models.SitemapEntry) bool {
+		return a.EventID == b.EventID && a.UpdatedAt.Equal(b.UpdatedAt)
+	}) {
+		return s.sitemap, s.modified, nil
+	}
+
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: []sitemapURL{{Loc: s.url("/")}}}
+	var modified time.Time
+	for _, entry := range entries {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     s.url(fmt.Sprintf("/events/%d", entry.EventID)),
+			LastMod: entry.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+		if entry.UpdatedAt.After(modified) {
+			modified = entry.UpdatedAt
+		}
+	}
+	body, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	s.entries = entries
+	s.sitemap = append([]byte(xml.Header), body...)
+	s.modified = modified
+	s.logger.Info("Sitemap rebuilt", "events", len(entries))
+	return s.sitemap, s.modified, nil
+}
+
+// EventJSONLD returns Schema.org Event markup for a public event. Events
+// that are private or not yet published return ErrNotFound; cancelled ones
+// are described as cancelled.
+func (s *SEOService) EventJSONLD(eventID int) (map[string]interface{}, error) {
+	event, err := s.eventRepo.GetByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.IsPrivate || (!event.IsActive && event.CancelledAt == nil) {
+		return nil, repositories.ErrNotFound
+	}
+
+	page := s.url(fmt.Sprintf("/events/%d", event.ID))
+	markup := map[string]interface{}{
+		"@context":    "https://schema.org",
+		"@type":       "Event",
+		"name":        event.Title,
+		"description": event.Description,
+		"startDate":   event.StartTime.UTC().Format(time.RFC3339),
+		"endDate":     event.EndTime.UTC().Format(time.RFC3339),
+		"eventStatus": "https://schema.org/EventScheduled",
+		"url":         page,
+	}
+	if event.CancelledAt != nil {
+		markup["eventStatus"] = "https://schema.org/EventCancelled"
+	}
+	// Events are streamed; the stream itself is for ticket holders only
+	if event.StreamURL != "" {
+		markup["eventAttendanceMode"] = "https://schema.org/OnlineEventAttendanceMode"
+		markup["location"] = map[string]interface{}{"@type": "VirtualLocation", "url": page}
+	}
+
+	availability := "https://schema.org/InStock"
+	if available, err := s.eventRepo.GetAvailableTicketCount(event.ID); err != nil {
+		return nil, err
+	} else if available <= 0 || event.CancelledAt != nil {
+		availability = "https://schema.org/SoldOut"
+	}
+	price := event.PriceSats
+	if event.PayWhatYouWant() {
+		price = event.MinPriceSats
+	}
+	markup["offers"] = map[string]interface{}{
+		"@type":         "Offer",
+		"url":           page,
+		"price":         satsToBTC(price),
+		"priceCurrency": "BTC",
+		"availability":  availability,
+		"validFrom":     event.CreatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if event.OrganizerID != nil {
+		profile, err := s.profileRepo.GetByUserID(*event.OrganizerID)
+		switch {
+		case err == nil:
+			organizer := map[string]interface{}{"@type": "Organization", "name": profile.DisplayName}
+			if profile.WebsiteURL != "" {
+				organizer["url"] = profile.WebsiteURL
+			}
+			markup["organizer"] = organizer
+		case !errors.Is(err, repositories.ErrNotFound):
+			// Describe the event without its organizer rather than fail
+			s.logger.Error("Failed to fetch organizer profile", "event_id", event.ID, "error", err)
+		}
+	}
+	return markup, nil
+}
+
+func (s *SEOService) url(path string) string {
+	return "https://" + s.domain + path
+}
+
+// satsToBTC writes sats as a decimal bitcoin amount without trailing
+// zeros, e.g. 2500 as "0.000025"
+func satsToBTC(sats int64) string {
+	amount := fmt.Sprintf("%d.%08d", sats/100_000_000, sats%100_000_000)
+	return strings.TrimSuffix(strings.TrimRight(amount, "0"), ".")
+}
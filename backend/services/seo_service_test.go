@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestSEOServiceSitemap(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	seo := NewSEOService(store.Events(), store.OrganizerProfiles(), "tickets.example", logger)
+
+	public := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), IsActive: true}
+	private := &models.Event{Title: "Secret", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), IsActive: true, IsPrivate: true}
+	for _, event := range []*models.Event{public, private} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sitemap, modified, err := seo.Sitemap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(sitemap)
+	if !strings.HasPrefix(body, "<?xml") || !strings.Contains(body, "<loc>https://tickets.example/</loc>") ||
+		!strings.Contains(body, "<loc>https://tickets.example/events/1</loc>") || strings.Contains(body, "/events/2<") {
+		t.Errorf("Expected the home page and the public event only, got %s", body)
+	}
+	if !modified.Equal(clk.Now()) {
+		t.Errorf("Expected the event's update time, got %v", modified)
+	}
+
+	// The cached sitemap is served until an event changes
+	if again, _, _ := seo.Sitemap(); &again[0] != &sitemap[0] {
+		t.Error("Expected the cached sitemap")
+	}
+	clk.Advance(time.Hour)
+	public.Title = "Renamed meetup"
+	if err := store.Events().Update(public); err != nil {
+		t.Fatal(err)
+	}
+	if _, modified, _ := seo.Sitemap(); !modified.Equal(clk.Now()) {
+		t.Errorf("Expected the sitemap rebuilt after the update, got %v", modified)
+	}
+}
+
+func TestSEOServiceEventJSONLD(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	seo := NewSEOService(store.Events(), store.OrganizerProfiles(), "tickets.example", logger)
+
+	organizerID := 7
+	if err := store.OrganizerProfiles().Save(&models.OrganizerProfile{UserID: organizerID, Slug: "acme", DisplayName: "Acme", WebsiteURL: "https://acme.example"}); err != nil {
+		t.Fatal(err)
+	}
+	start := clk.Now().Add(24 * time.Hour)
+	event := &models.Event{Title: "Meetup", Description: "Talks", StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 1,
+		PriceSats: 2500, StreamURL: "https://stream.example/secret", IsActive: true, OrganizerID: &organizerID}
+	draft := &models.Event{Title: "Draft", StartTime: start, EndTime: start.Add(time.Hour)}
+	for _, e := range []*models.Event{event, draft} {
+		if err := store.Events().Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := seo.EventJSONLD(draft.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unpublished event, got %v", err)
+	}
+
+	markup, err := seo.EventJSONLD(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offers := markup["offers"].(map[string]interface{})
+	organizer := markup["organizer"].(map[string]interface{})
+	location := markup["location"].(map[string]interface{})
+	if markup["@type"] != "Event" || markup["startDate"] != "2026-10-17T12:00:00Z" || markup["eventStatus"] != "https://schema.org/EventScheduled" ||
+		offers["price"] != "0.000025" || offers["priceCurrency"] != "BTC" || offers["availability"] != "https://schema.org/InStock" ||
+		organizer["name"] != "Acme" || location["url"] != "https://tickets.example/events/1" {
+		t.Errorf("Unexpected markup %+v", markup)
+	}
+
+	// Sold out, then cancelled
+	if err := store.Tickets().Create(&models.Ticket{EventID: event.ID, UserID: 1, TicketCode: "T-1", PaymentStatus: "paid"}); err != nil {
+		t.Fatal(err)
+	}
+	if markup, _ := seo.EventJSONLD(event.ID); markup["offers"].(map[string]interface{})["availability"] != "https://schema.org/SoldOut" {
+		t.Errorf("Expected the event sold out, got %+v", markup["offers"])
+	}
+	if err := store.Events().Cancel(event.ID, clk.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if markup, err := seo.EventJSONLD(event.ID); err != nil || markup["eventStatus"] != "https://schema.org/EventCancelled" {
+		t.Errorf("Expected the event described as cancelled, got %+v (%v)", markup, err)
+	}
+}
+
+func TestSatsToBTC(t *testing.T) {
+	for sats, want := range map[int64]string{0: "0", 1: "0.00000001", 2500: "0.000025", 100_000_000: "1", 150_000_000: "1.5"} {
+		if got := satsToBTC(sats); got != want {
+			t.Errorf("satsToBTC(%d) = %s, want %s", sats, got, want)
+		}
+	}
+}
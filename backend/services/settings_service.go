@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// Well-known runtime setting keys. Values are stored as JSON.
+const (
+	SettingSalesPaused             = "sales_paused"                // bool
+	SettingSalesPausedEventPrefix  = "sales_paused.event."         // sales_paused.event.<id> -> bool
+	SettingPurchaseRateLimitPerMin = "rate_limit.purchase_per_min" // int, requests per IP per minute (0 = unlimited)
+	SettingFeatureFlagPrefix       = "feature."                    // feature.<name> -> bool
+	SettingDebugCapturePercent     = "debug.capture_percent"       // int, percent of failed requests whose bodies are captured (0 = off)
+
+	// Fraud rules (see FraudService). Actions are "allow", "review" or "block".
+	SettingFraudVelocityWindowMin = "fraud.velocity_window_min"      // int, minutes purchase attempts are counted over
+	SettingFraudMaxPerIP          = "fraud.max_purchases_per_ip"     // int, attempts per window (0 = no limit)
+	SettingFraudMaxPerUser        = "fraud.max_purchases_per_user"   // int
+	SettingFraudMaxPerUMA         = "fraud.max_purchases_per_uma"    // int
+	SettingFraudVelocityAction    = "fraud.velocity_action"          // string
+	SettingFraudDisposableDomains = "fraud.disposable_email_domains" // []string, added to the built-in list
+	SettingFraudDisposableAction  = "fraud.disposable_email_action"  // string
+	SettingFraudGeoMismatchAction = "fraud.geo_mismatch_action"      // string
+)
+
+// SettingsService serves runtime settings from an in-process cache that is
+// periodically refreshed from the settings table, so knobs can be changed
+// without a redeploy.
+type SettingsService struct {
+	repo   repositories.SettingsRepository
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	values map[string]json.RawMessage
+}
+
+// NewSettingsService creates a settings service backed by repo.
+func NewSettingsService(repo repositories.SettingsRepository, logger *slog.Logger) *SettingsService {
+	return &SettingsService{
+		repo:   repo,
+		logger: logger,
+		values: make(map[string]json.RawMessage),
+	}
+}
+
+// Refresh reloads all settings from the database.
+func (s *SettingsService) Refresh() error {
+	settings, err := s.repo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]json.RawMessage, len(settings))
+	for _, setting := range settings {
+		values[setting.Key] = json.RawMessage(setting.Value)
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch refreshes the cache every interval until ctx is cancelled.
+func (s *SettingsService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				s.logger.Error("Failed to refresh runtime settings", "error", err)
+			}
+		}
+	}
+}
+
+// All returns a snapshot of the cached settings.
+func (s *SettingsService) All() map[string]json.RawMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]json.RawMessage, len(s.values))
+	for key, value := range s.values {
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
+// Set validates and stores a setting, updating the cache immediately.
+func (s *SettingsService) Set(key string, value json.RawMessage, updatedBy string) error {
+	if key == "" {
+		return fmt.Errorf("setting key is required")
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("setting value must be valid JSON")
+	}
+
+	setting := &models.Setting{Key: key, Value: string(value), UpdatedBy: updatedBy}
+	if err := s.repo.Upsert(setting); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.values[key] = value
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete removes a setting so callers fall back to their defaults.
+func (s *SettingsService) Delete(key string) error {
+	if err := s.repo.Delete(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.values, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Bool returns a boolean setting, or fallback if unset or malformed.
+func (s *SettingsService) Bool(key string, fallback bool) bool {
+	var value bool
+	if !s.decode(key, &value) {
+		return fallback
+	}
+	return value
+}
+
+// Int returns an integer setting, or fallback if unset or malformed.
+func (s *SettingsService) Int(key string, fallback int) int {
+	var value int
+	if !s.decode(key, &value) {
+		return fallback
+	}
+	return value
+}
+
+// String returns a string setting, or fallback if unset or malformed.
+func (s *SettingsService) String(key string, fallback string) string {
+	var value string
+	if !s.decode(key, &value) {
+		return fallback
+	}
+	return value
+}
+
+// Strings returns a string list setting, or fallback if unset or malformed.
+func (s *SettingsService) Strings(key string, fallback []string) []string {
+	var value []string
+	if !s.decode(key, &value) {
+		return fallback
+	}
+	return value
+}
+
+// FeatureEnabled reports whether the feature flag feature.<name> is on.
+func (s *SettingsService) FeatureEnabled(name string, fallback bool) bool {
+	return s.Bool(SettingFeatureFlagPrefix+name, fallback)
+}
+
+// EventSalesPaused reports whether sales of the event are paused on their
+// own; SettingSalesPaused pauses all events.
+func (s *SettingsService) EventSalesPaused(eventID int) bool {
+	return s.Bool(EventSalesPausedKey(eventID), false)
+}
+
+// PausedEvents returns the IDs of the events whose sales are paused on
+// their own, in ascending order.
+func (s *SettingsService) PausedEvents() []int {
+	s.mu.RLock()
+	keys := make([]string, 0)
+	for key := range s.values {
+		if strings.HasPrefix(key, SettingSalesPausedEventPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.RUnlock()
+
+	ids := []int{}
+	for _, key := range keys {
+		id, err := strconv.Atoi(strings.TrimPrefix(key, SettingSalesPausedEventPrefix))
+		if err == nil && s.Bool(key, false) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// EventSalesPausedKey is the setting pausing sales of one event.
+func EventSalesPausedKey(eventID int) string {
+	return SettingSalesPausedEventPrefix + strconv.Itoa(eventID)
+}
+
+func (s *SettingsService) decode(key string, target interface{}) bool {
+	s.mu.RLock()
+	raw, ok := s.values[key]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, target) == nil
+}
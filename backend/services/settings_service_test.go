@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"tickets-by-uma/models"
+)
+
+// fakeSettingsRepository keeps settings in a map
+type fakeSettingsRepository struct {
+	settings map[string]models.Setting
+}
+
+func (r *fakeSettingsRepository) GetAll() ([]models.Setting, error) {
+	var all []models.Setting
+	for _, setting := range r.settings {
+		all = append(all, setting)
+	}
+	return all, nil
+}
+
+func (r *fakeSettingsRepository) Get(key string) (*models.Setting, error) {
+	if setting, ok := r.settings[key]; ok {
+		return &setting, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeSettingsRepository) Upsert(setting *models.Setting) error {
+	r.settings[setting.Key] = *setting
+	return nil
+}
+
+func (r *fakeSettingsRepository) Delete(key string) error {
+	delete(r.settings, key)
+	return nil
+}
+
+func TestSettingsService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	repo := &fakeSettingsRepository{settings: map[string]models.Setting{
+		SettingPurchaseRateLimitPerMin: {Key: SettingPurchaseRateLimitPerMin, Value: "10"},
+		"feature.nwc":                  {Key: "feature.nwc", Value: "true"},
+		"broken":                       {Key: "broken", Value: `"not a number"`},
+	}}
+
+	service := NewSettingsService(repo, logger)
+	if err := service.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := service.Int(SettingPurchaseRateLimitPerMin, 0); got != 10 {
+		t.Errorf("Expected rate limit 10, got %d", got)
+	}
+	if !service.FeatureEnabled("nwc", false) {
+		t.Error("Expected feature.nwc to be enabled")
+	}
+	if got := service.Int("broken", 5); got != 5 {
+		t.Errorf("Expected fallback for malformed value, got %d", got)
+	}
+	if service.Bool(SettingSalesPaused, false) {
+		t.Error("Expected sales_paused to default to false")
+	}
+
+	// Updates apply to the cache immediately
+	if err := service.Set(SettingSalesPaused, json.RawMessage("true"), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !service.Bool(SettingSalesPaused, false) {
+		t.Error("Expected sales_paused to be true after Set")
+	}
+
+	// Events are paused on their own under sales_paused.event.<id>
+	for _, key := range []string{EventSalesPausedKey(7), EventSalesPausedKey(3)} {
+		if err := service.Set(key, json.RawMessage("true"), "admin@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.Set(EventSalesPausedKey(5), json.RawMessage("false"), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !service.EventSalesPaused(7) || service.EventSalesPaused(5) || service.EventSalesPaused(1) {
+		t.Error("Expected only events 3 and 7 to be paused")
+	}
+	if got := service.PausedEvents(); len(got) != 2 || got[0] != 3 || got[1] != 7 {
+		t.Errorf("Expected paused events [3 7], got %v", got)
+	}
+
+	if err := service.Set("bad", json.RawMessage("{not json"), "admin@example.com"); err == nil {
+		t.Error("Expected invalid JSON to be rejected")
+	}
+
+	// Settings changed elsewhere are picked up on refresh
+	repo.settings[SettingPurchaseRateLimitPerMin] = models.Setting{Key: SettingPurchaseRateLimitPerMin, Value: "3"}
+	if err := service.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := service.Int(SettingPurchaseRateLimitPerMin, 0); got != 3 {
+		t.Errorf("Expected refreshed rate limit 3, got %d", got)
+	}
+}
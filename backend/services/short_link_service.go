@@ -0,0 +1,214 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// shortLinkAlphabet spells generated codes in lowercase without
+	// look-alike characters; its 32 letters keep the random bytes unbiased
+	shortLinkAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	shortLinkLength   = 7
+	// shortLinkAttempts bounds the fresh codes tried when generated ones are
+	// taken
+	shortLinkAttempts  = 5
+	minShortCodeLength = 3
+	maxShortCodeLength = 40
+)
+
+// Short link path prefixes: /e/{code} leads to an event page, /t/{code} to
+// a ticket
+const (
+	ShortLinkEventPrefix  = "e"
+	ShortLinkTicketPrefix = "t"
+)
+
+// ErrShortLinkCode is returned for a custom code that cannot be used
+var ErrShortLinkCode = errors.New("code must be 3 to 40 letters, digits and dashes, not starting or ending with a dash")
+
+// ShortLinkService hands out short links to event pages and tickets and
+// follows them. Every event and ticket gets one generated link on first
+// use, with a random code retried until it is unused; admins add custom
+// codes to events for marketing. Codes are matched ignoring case.
+type ShortLinkService struct {
+	repo      repositories.ShortLinkRepository
+	eventRepo repositories.EventRepository
+	domain    string
+	logger    *slog.Logger
+	// newCode generates codes; tests replace it to force collisions
+	newCode func() (string, error)
+}
+
+// NewShortLinkService creates a short link service for links served at
+// https://domain
+func NewShortLinkService(repo repositories.ShortLinkRepository, eventRepo repositories.EventRepository, domain string, logger *slog.Logger) *ShortLinkService {
+	return &ShortLinkService{repo: repo, eventRepo: eventRepo, domain: domain, logger: logger, newCode: newShortCode}
+}
+
+// ForEvent returns the event's generated link, creating it on first use
+func (s *ShortLinkService) ForEvent(eventID int) (*models.ShortLink, error) {
+	return s.generated(models.ShortLink{EventID: &eventID}, func() (*models.ShortLink, error) {
+		return s.repo.GetGeneratedForEvent(eventID)
+	})
+}
+
+// ForTicket returns the ticket's generated link, creating it on first use
+func (s *ShortLinkService) ForTicket(ticketID int) (*models.ShortLink, error) {
+	return s.generated(models.ShortLink{TicketID: &ticketID}, func() (*models.ShortLink, error) {
+		return s.repo.GetGeneratedForTicket(ticketID)
+	})
+}
+
+func (s *ShortLinkService) generated(target models.ShortLink, get func() (*models.ShortLink, error)) (*models.ShortLink, error) {
+	for attempt := 0; attempt < shortLinkAttempts; attempt++ {
+		link, err := get()
+		if err == nil {
+			return s.withURL(link), nil
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+
+		link = &target
+		if link.Code, err = s.newCode(); err != nil {
+			return nil, err
+		}
+		err = s.repo.Create(link)
+		if err == nil {
+			s.logger.Info("Short link created", "code", link.Code, "event_id", link.EventID, "ticket_id", link.TicketID)
+			return s.withURL(link), nil
+		}
+		// The code was taken, or a concurrent request created the link:
+		// look again
+		if !errors.Is(err, repositories.ErrConflict) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("failed to generate an unused short link code")
+}
+
+// CreateCustom gives an event a link with a code an admin picked. It
+// returns repositories.ErrNotFound for an unknown event and
+// repositories.ErrConflict when the code is taken.
+func (s *ShortLinkService) CreateCustom(eventID int, code string, createdBy *int) (*models.ShortLink, error) {
+	code, err := normalizeShortCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.eventRepo.GetByID(eventID); err != nil {
+		return nil, err
+	}
+
+	link := &models.ShortLink{Code: code, EventID: &eventID, Custom: true, CreatedBy: createdBy}
+	if err := s.repo.Create(link); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Custom short link created", "code", code, "event_id", eventID, "created_by", createdBy)
+	return s.withURL(link), nil
+}
+
+// Rename gives a link a code an admin picked, after which it counts as
+// custom. It returns repositories.ErrConflict when the code is taken.
+func (s *ShortLinkService) Rename(id int, code string) (*models.ShortLink, error) {
+	code, err := normalizeShortCode(code)
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.repo.UpdateCode(id, code)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Short link renamed", "link_id", id, "code", code)
+	return s.withURL(link), nil
+}
+
+// List returns an event's links with their click counts
+func (s *ShortLinkService) List(eventID int) ([]models.ShortLink, error) {
+	links, err := s.repo.GetByEventID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		s.withURL(&links[i])
+	}
+	return links, nil
+}
+
+// Delete removes a link; following it then finds nothing
+func (s *ShortLinkService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+// Follow counts a click on the link with code under prefix and returns
+// the page it leads to. It returns repositories.ErrNotFound for unknown
+// codes and for links to the other kind of page.
+func (s *ShortLinkService) Follow(prefix, code string) (string, error) {
+	code = strings.ToLower(code)
+	link, err := s.repo.GetByCode(code)
+	if err != nil {
+		return "", err
+	}
+	if shortLinkPrefix(link) != prefix {
+		return "", repositories.ErrNotFound
+	}
+	if _, err := s.repo.Click(code); err != nil {
+		// Losing a click is better than failing the redirect
+		s.logger.Warn("Failed to count short link click", "code", code, "error", err)
+	}
+	if link.TicketID != nil {
+		return s.url(fmt.Sprintf("/tickets/%d", *link.TicketID)), nil
+	}
+	return s.url(fmt.Sprintf("/events/%d", *link.EventID)), nil
+}
+
+// withURL fills in the link's short URL
+func (s *ShortLinkService) withURL(link *models.ShortLink) *models.ShortLink {
+	link.URL = s.url("/" + shortLinkPrefix(link) + "/" + link.Code)
+	return link
+}
+
+func (s *ShortLinkService) url(path string) string {
+	return "https://" + s.domain + path
+}
+
+func shortLinkPrefix(link *models.ShortLink) string {
+	if link.TicketID != nil {
+		return ShortLinkTicketPrefix
+	}
+	return ShortLinkEventPrefix
+}
+
+// normalizeShortCode lowercases a custom code and checks it can be used
+func normalizeShortCode(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if len(code) < minShortCodeLength || len(code) > maxShortCodeLength ||
+		strings.HasPrefix(code, "-") || strings.HasSuffix(code, "-") {
+		return "", ErrShortLinkCode
+	}
+	for _, c := range code {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return "", ErrShortLinkCode
+		}
+	}
+	return code, nil
+}
+
+// newShortCode returns a random code for a generated link
+func newShortCode() (string, error) {
+	raw := make([]byte, shortLinkLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := make([]byte, len(raw))
+	for i, b := range raw {
+		code[i] = shortLinkAlphabet[int(b)%len(shortLinkAlphabet)]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestShortLinkService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	links := NewShortLinkService(store.ShortLinks(), store.Events(), "tickets.example", logger)
+
+	event := &models.Event{Title: "Meetup", StartTime: clk.Now(), EndTime: clk.Now().Add(time.Hour), IsActive: true}
+	if err := store.Events().Create(event); err != nil {
+		t.Fatal(err)
+	}
+
+	// Codes taken by other links are skipped
+	if _, err := links.CreateCustom(event.ID, "Taken22", nil); err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{"taken22", "fresh22"}
+	links.newCode = func() (string, error) {
+		code := codes[0]
+		codes = codes[1:]
+		return code, nil
+	}
+	link, err := links.ForEvent(event.ID)
+	if err != nil || link.Code != "fresh22" || link.Custom || link.URL != "https://tickets.example/e/fresh22" {
+		t.Fatalf("Expected a generated link with an unused code, got %+v (%v)", link, err)
+	}
+	if again, err := links.ForEvent(event.ID); err != nil || again.ID != link.ID {
+		t.Errorf("Expected the same link on later calls, got %+v (%v)", again, err)
+	}
+
+	ticketID := 7
+	links.newCode = newShortCode
+	ticketLink, err := links.ForTicket(ticketID)
+	if err != nil || len(ticketLink.Code) != shortLinkLength || ticketLink.URL != "https://tickets.example/t/"+ticketLink.Code {
+		t.Fatalf("Expected a ticket link, got %+v (%v)", ticketLink, err)
+	}
+
+	// Links are followed ignoring case, only under their own prefix
+	if target, err := links.Follow(ShortLinkEventPrefix, "TAKEN22"); err != nil || target != "https://tickets.example/events/1" {
+		t.Errorf("Expected the event page, got %s (%v)", target, err)
+	}
+	if target, err := links.Follow(ShortLinkTicketPrefix, ticketLink.Code); err != nil || target != "https://tickets.example/tickets/7" {
+		t.Errorf("Expected the ticket, got %s (%v)", target, err)
+	}
+	if _, err := links.Follow(ShortLinkTicketPrefix, "fresh22"); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected an event link not followed as a ticket link, got %v", err)
+	}
+	if listed, _ := links.List(event.ID); len(listed) != 2 || listed[0].Clicks != 1 || listed[0].URL != "https://tickets.example/e/taken22" {
+		t.Errorf("Expected the followed link's click counted, got %+v", listed)
+	}
+
+	for _, code := range []string{"ab", "-sale", "sale-", "sale 2026", "ünïcode", "a23456789012345678901234567890123456789012"} {
+		if _, err := links.CreateCustom(event.ID, code, nil); !errors.Is(err, ErrShortLinkCode) {
+			t.Errorf("Expected %q refused, got %v", code, err)
+		}
+	}
+	if _, err := links.CreateCustom(event.ID+1000, "nowhere", nil); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown event, got %v", err)
+	}
+	if _, err := links.Rename(link.ID, "taken22"); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Expected ErrConflict for a taken code, got %v", err)
+	}
+	if renamed, err := links.Rename(link.ID, " Launch "); err != nil || renamed.Code != "launch" || renamed.URL != "https://tickets.example/e/launch" {
+		t.Errorf("Expected the link renamed, got %+v (%v)", renamed, err)
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+)
+
+var (
+	// ErrInvoiceNotFound is returned when the simulated node did not issue the invoice
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	// ErrInvoiceSettled is returned when settling an invoice that was already paid
+	ErrInvoiceSettled = errors.New("invoice already settled")
+	// ErrInvoiceExpired is returned when settling an invoice past its expiry
+	ErrInvoiceExpired = errors.New("invoice expired")
+)
+
+type simulatedInvoice struct {
+	invoice  models.Invoice
+	preimage string
+}
+
+// SimulatedUMAService is a Lightning backend for local development
+// (PAYMENT_BACKEND=simulation). It issues regtest-looking invoices that no
+// node knows about and settles them itself, either after a delay or when
+// SimulateIncomingPayment is called, so the full purchase flow runs without
+// Lightspark credentials. Settlements are reported to the handler registered
+// with OnSettled, standing in for the Lightspark payment webhook.
+type SimulatedUMAService struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	settleDelay time.Duration
+
+	mu        sync.Mutex
+	invoices  map[string]*simulatedInvoice // keyed by invoice ID
+	byBolt11  map[string]string            // bolt11 -> invoice ID
+	onSettled func(paymentHash, preimage string)
+}
+
+// NewSimulatedUMAService creates the simulated backend. Invoices settle
+// automatically settleDelay after creation; zero disables auto-settling.
+func NewSimulatedUMAService(settleDelay time.Duration, clk clock.Clock, logger *slog.Logger) *SimulatedUMAService {
+	return &SimulatedUMAService{
+		logger:      logger,
+		clock:       clk,
+		settleDelay: settleDelay,
+		invoices:    make(map[string]*simulatedInvoice),
+		byBolt11:    make(map[string]string),
+	}
+}
+
+// OnSettled registers the function called with the payment hash and
+// preimage of every settled invoice
+func (s *SimulatedUMAService) OnSettled(fn func(paymentHash, preimage string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSettled = fn
+}
+
+// ValidateUMAAddress applies the same format rules as the Lightspark backend
+func (s *SimulatedUMAService) ValidateUMAAddress(address string) error {
+	return validateUMAAddress(address)
+}
+
+func (s *SimulatedUMAService) CreateUMARequest(umaAddress string, amountSats int64, description string, expiry time.Duration, isAdmin bool) (*models.Invoice, error) {
+	if !isAdmin {
+		return nil, errors.New("CreateUMARequest is restricted to admin users only - represents business side of UMA Request protocol")
+	}
+	if err := s.ValidateUMAAddress(umaAddress); err != nil {
+		return nil, fmt.Errorf("invalid UMA address: %w", err)
+	}
+	return s.createInvoice(amountSats, fmt.Sprintf("UMA Request - %s", description), expiry)
+}
+
+func (s *SimulatedUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
+	if err := s.ValidateUMAAddress(umaAddress); err != nil {
+		return nil, fmt.Errorf("invalid UMA address: %w", err)
+	}
+	return s.createInvoice(amountSats, fmt.Sprintf("Ticket Purchase - %s", description), expiry)
+}
+
+func (s *SimulatedUMAService) createInvoice(amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
+	preimage := make([]byte, 32)
+	if _, err := rand.Read(preimage); err != nil {
+		return nil, err
+	}
+	paymentHash := sha256.Sum256(preimage)
+	hashHex := hex.EncodeToString(paymentHash[:])
+
+	expiresAt := s.clock.Now().Add(expiry)
+	invoice := models.Invoice{
+		ID:          "sim_" + hashHex[:24],
+		PaymentHash: hashHex,
+		// 1 sat = 10 nano-BTC in the bolt11 amount field
+		Bolt11:     fmt.Sprintf("lnbcrt%dn1sim%s", amountSats*10, hashHex),
+		AmountSats: amountSats,
+		Status:     "pending",
+		ExpiresAt:  &expiresAt,
+	}
+
+	s.mu.Lock()
+	s.invoices[invoice.ID] = &simulatedInvoice{invoice: invoice, preimage: hex.EncodeToString(preimage)}
+	s.byBolt11[invoice.Bolt11] = invoice.ID
+	s.mu.Unlock()
+
+	s.logger.Info("Created simulated invoice",
+		"invoice_id", invoice.ID,
+		"amount_sats", amountSats,
+		"description", description,
+		"settles_in", s.settleDelay.String())
+
+	if s.settleDelay > 0 {
+		time.AfterFunc(s.settleDelay, func() {
+			if _, err := s.settle(invoice.ID); err != nil && !errors.Is(err, ErrInvoiceSettled) {
+				s.logger.Error("Failed to auto-settle simulated invoice", "invoice_id", invoice.ID, "error", err)
+			}
+		})
+	}
+
+	result := invoice
+	return &result, nil
+}
+
+// SimulateIncomingPayment settles an invoice as if a buyer paid it. The
+// invoice may be identified by its ID or its bolt11.
+func (s *SimulatedUMAService) SimulateIncomingPayment(invoice string) error {
+	_, err := s.settle(invoice)
+	return err
+}
+
+// settle marks the invoice paid and notifies the OnSettled handler. It
+// returns the payment preimage. Invoices past their expiry cannot be paid,
+// like on a real node.
+func (s *SimulatedUMAService) settle(idOrBolt11 string) (string, error) {
+	s.mu.Lock()
+	id, ok := s.byBolt11[idOrBolt11]
+	if !ok {
+		id = idOrBolt11
+	}
+	inv, ok := s.invoices[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", ErrInvoiceNotFound
+	}
+	if inv.invoice.Status == "paid" {
+		s.mu.Unlock()
+		return inv.preimage, ErrInvoiceSettled
+	}
+	if !s.clock.Now().Before(*inv.invoice.ExpiresAt) {
+		inv.invoice.Status = "expired"
+		s.mu.Unlock()
+		return "", ErrInvoiceExpired
+	}
+	inv.invoice.Status = "paid"
+	paymentHash, preimage, onSettled := inv.invoice.PaymentHash, inv.preimage, s.onSettled
+	s.mu.Unlock()
+
+	s.logger.Info("Simulated invoice settled", "invoice_id", id, "amount_sats", inv.invoice.AmountSats)
+
+	// Called without the lock: the handler reports back through HandleUMACallback
+	if onSettled != nil {
+		onSettled(paymentHash, preimage)
+	}
+	return preimage, nil
+}
+
+func (s *SimulatedUMAService) SendUMARequest(buyerUMA string, amountSats int64, callbackURL string, expiry time.Duration) error {
+	s.logger.Info("Simulated UMA request (not sent)", "buyer_uma", buyerUMA, "amount_sats", amountSats)
+	return nil
+}
+
+// SendPaymentToInvoice pays one of our own simulated invoices
+func (s *SimulatedUMAService) SendPaymentToInvoice(bolt11 string) (*models.PaymentResult, error) {
+	preimage, err := s.settle(bolt11)
+	if err != nil && !errors.Is(err, ErrInvoiceSettled) {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	amountSats := s.invoices[s.byBolt11[bolt11]].invoice.AmountSats
+	s.mu.Unlock()
+
+	return &models.PaymentResult{
+		PaymentID:       "sim_pay_" + preimage[:16],
+		Status:          "success",
+		AmountSats:      amountSats,
+		Message:         "Simulated payment settled",
+		TransactionHash: &preimage,
+	}, nil
+}
+
+func (s *SimulatedUMAService) CheckPaymentStatus(invoiceID string) (*models.PaymentStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byBolt11[invoiceID]
+	if !ok {
+		id = invoiceID
+	}
+	inv, ok := s.invoices[id]
+	if !ok {
+		return nil, ErrInvoiceNotFound
+	}
+	return &models.PaymentStatus{
+		InvoiceID:   inv.invoice.ID,
+		Status:      inv.invoice.Status,
+		AmountSats:  inv.invoice.AmountSats,
+		PaymentHash: inv.invoice.PaymentHash,
+	}, nil
+}
+
+func (s *SimulatedUMAService) GetNodeBalance() (*models.NodeBalance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var received int64
+	for _, inv := range s.invoices {
+		if inv.invoice.Status == "paid" {
+			received += inv.invoice.AmountSats
+		}
+	}
+	return &models.NodeBalance{
+		TotalBalanceSats:     received,
+		AvailableBalanceSats: received,
+		NodeID:               "simulated-node",
+		Status:               "ready",
+	}, nil
+}
+
+func (s *SimulatedUMAService) HandleUMACallback(paymentHash string, status string) error {
+	s.logger.Info("Processing simulated UMA callback", "payment_hash", paymentHash, "status", status)
+	return nil
+}
+
+// PayWithNWC settles the invoice as though the buyer's wallet paid it; the
+// connection URI is not contacted
+func (s *SimulatedUMAService) PayWithNWC(bolt11 string, nwcConnectionURI string) (string, error) {
+	preimage, err := s.settle(bolt11)
+	if errors.Is(err, ErrInvoiceSettled) {
+		return "", errors.New("invoice already paid")
+	}
+	return preimage, err
+}
+
+func (s *SimulatedUMAService) GetUMASigningCertChain() string {
+	return ""
+}
+
+func (s *SimulatedUMAService) GetUMAEncryptionCertChain() string {
+	return ""
+}
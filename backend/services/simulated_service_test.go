@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+)
+
+func TestSimulatedUMAService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	service := NewSimulatedUMAService(0, clk, logger)
+
+	var settled, preimages []string
+	service.OnSettled(func(paymentHash, preimage string) {
+		settled = append(settled, paymentHash)
+		preimages = append(preimages, preimage)
+	})
+
+	if _, err := service.CreateTicketInvoice("invalid", 100, "Concert", time.Hour); err == nil {
+		t.Error("Expected invalid UMA address to be rejected")
+	}
+
+	invoice, err := service.CreateTicketInvoice("$fan@example.com", 100, "Concert", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Status != "pending" || !invoice.ExpiresAt.Equal(clk.Now().Add(15*time.Minute)) {
+		t.Errorf("Unexpected invoice: %+v", invoice)
+	}
+
+	if err := service.SimulateIncomingPayment("sim_unknown"); !errors.Is(err, ErrInvoiceNotFound) {
+		t.Errorf("Expected ErrInvoiceNotFound, got %v", err)
+	}
+	if err := service.SimulateIncomingPayment(invoice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(settled) != 1 || settled[0] != invoice.PaymentHash {
+		t.Errorf("Expected OnSettled called with the payment hash, got %v", settled)
+	}
+	if !PreimageMatches(preimages[0], invoice.PaymentHash) {
+		t.Errorf("Expected the preimage of the invoice's payment hash, got %q", preimages[0])
+	}
+	// Settling by bolt11 finds the same invoice
+	if err := service.SimulateIncomingPayment(invoice.Bolt11); !errors.Is(err, ErrInvoiceSettled) {
+		t.Errorf("Expected ErrInvoiceSettled, got %v", err)
+	}
+
+	status, err := service.CheckPaymentStatus(invoice.Bolt11)
+	if err != nil || status.Status != "paid" || status.InvoiceID != invoice.ID {
+		t.Errorf("Expected paid status, got %+v (%v)", status, err)
+	}
+	if balance, _ := service.GetNodeBalance(); balance.TotalBalanceSats != 100 {
+		t.Errorf("Expected balance of 100 sats, got %d", balance.TotalBalanceSats)
+	}
+
+	other, _ := service.CreateTicketInvoice("$fan@example.com", 50, "Concert", time.Hour)
+	preimage, err := service.PayWithNWC(other.Bolt11, "nostr+walletconnect://ignored")
+	if err != nil || preimage == "" {
+		t.Errorf("Expected NWC payment to settle, got %q (%v)", preimage, err)
+	}
+	if _, err := service.PayWithNWC(other.Bolt11, ""); err == nil {
+		t.Error("Expected paying a settled invoice to fail")
+	}
+
+	// An expired invoice cannot be paid
+	expiring, _ := service.CreateTicketInvoice("$fan@example.com", 50, "Concert", 15*time.Minute)
+	clk.Advance(15 * time.Minute)
+	if err := service.SimulateIncomingPayment(expiring.ID); !errors.Is(err, ErrInvoiceExpired) {
+		t.Errorf("Expected ErrInvoiceExpired, got %v", err)
+	}
+	if status, _ := service.CheckPaymentStatus(expiring.ID); status.Status != "expired" {
+		t.Errorf("Expected expired status, got %+v", status)
+	}
+}
+
+func TestSimulatedUMAServiceAutoSettle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	service := NewSimulatedUMAService(10*time.Millisecond, clock.System(), logger)
+
+	settled := make(chan string, 1)
+	service.OnSettled(func(paymentHash, preimage string) { settled <- paymentHash })
+
+	invoice, err := service.CreateTicketInvoice("$fan@example.com", 100, "Concert", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case paymentHash := <-settled:
+		if paymentHash != invoice.PaymentHash {
+			t.Errorf("Expected %s settled, got %s", invoice.PaymentHash, paymentHash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Invoice was not settled automatically")
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// statusIncidentWindow is how long resolved incidents stay on the
+	// status page
+	statusIncidentWindow = 7 * 24 * time.Hour
+
+	// statusQueueDegraded and statusQueueDown are how long the oldest
+	// pending webhook event may wait before the job queue is reported
+	// degraded or down. Retries back off up to webhookRetryMax, so a
+	// healthy queue can hold an event a few minutes.
+	statusQueueDegraded = 5 * time.Minute
+	statusQueueDown     = 30 * time.Minute
+
+	// statusCacheTTL is how long a computed status is served before the
+	// subsystems are checked again, so traffic to the public status page
+	// does not reach the database on every request
+	statusCacheTTL = 15 * time.Second
+)
+
+// statusRank orders subsystem states, worst last
+var statusRank = map[string]int{
+	models.StatusOperational: 0,
+	models.StatusDegraded:    1,
+	models.StatusOutage:      2,
+}
+
+// StatusService reports the health of the backend's subsystems for the
+// public status page, with the incidents admins post. Descriptions are
+// meant for the public and never carry error details. A status is computed
+// at most once per statusCacheTTL.
+type StatusService struct {
+	ping          func() error
+	readOnly      bool
+	breaker       *CircuitBreaker
+	queue         *WebhookQueue
+	connectorRepo repositories.AlertConnectorRepository
+	incidentRepo  repositories.StatusIncidentRepository
+	clock         clock.Clock
+	logger        *slog.Logger
+
+	// mu guards the cached status; it is held while one is computed so
+	// concurrent requests wait for it rather than check again
+	mu       sync.Mutex
+	cached   *models.SystemStatus
+	cachedAt time.Time
+}
+
+// NewStatusService creates the status service. ping checks the database
+// and is nil without one; readOnly reports the database schema does not
+// match this build. breaker guards the Lightning backend and is nil when
+// payments are simulated.
+func NewStatusService(ping func() error, readOnly bool, breaker *CircuitBreaker, queue *WebhookQueue, connectorRepo repositories.AlertConnectorRepository, incidentRepo repositories.StatusIncidentRepository, clk clock.Clock, logger *slog.Logger) *StatusService {
+	return &StatusService{
+		ping:          ping,
+		readOnly:      readOnly,
+		breaker:       breaker,
+		queue:         queue,
+		connectorRepo: connectorRepo,
+		incidentRepo:  incidentRepo,
+		clock:         clk,
+		logger:        logger,
+	}
+}
+
+// Status returns the status computed by check, reusing it for
+// statusCacheTTL. The result is shared and must not be modified.
+func (s *StatusService) Status() *models.SystemStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	if s.cached == nil || now.Sub(s.cachedAt) >= statusCacheTTL {
+		s.cached, s.cachedAt = s.check(now), now
+	}
+	return s.cached
+}
+
+// Invalidate drops the cached status, for when an incident changes so the
+// status page shows it at once
+func (s *StatusService) Invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+}
+
+// check checks every subsystem. An unresolved incident degrades the
+// subsystems it affects, or takes them down when its impact is major; the
+// overall status is the worst subsystem's.
+func (s *StatusService) check(now time.Time) *models.SystemStatus {
+	status := &models.SystemStatus{
+		Status: models.StatusOperational,
+		Subsystems: []models.SubsystemStatus{
+			{Name: models.SubsystemAPI, Status: models.StatusOperational},
+			s.database(),
+			s.lightning(),
+			s.notifications(),
+			s.jobQueue(),
+		},
+		Incidents: []models.StatusIncident{},
+		CheckedAt: now,
+	}
+
+	incidents, err := s.incidentRepo.ListRecent(now.Add(-statusIncidentWindow))
+	if err != nil {
+		s.logger.Error("Failed to fetch status incidents", "error", err)
+	} else {
+		status.Incidents = incidents
+	}
+	for i := range status.Incidents {
+		incident := &status.Incidents[i]
+		// Which admin posted it is not public
+		incident.CreatedBy = nil
+		if incident.ResolvedAt != nil {
+			continue
+		}
+		worst := models.StatusDegraded
+		if incident.Impact == models.IncidentImpactMajor {
+			worst = models.StatusOutage
+		}
+		for j := range status.Subsystems {
+			subsystem := &status.Subsystems[j]
+			if slices.Contains(incident.Components, subsystem.Name) && statusRank[worst] > statusRank[subsystem.Status] {
+				subsystem.Status, subsystem.Description = worst, incident.Title
+			}
+		}
+	}
+
+	for _, subsystem := range status.Subsystems {
+		if statusRank[subsystem.Status] > statusRank[status.Status] {
+			status.Status = subsystem.Status
+		}
+	}
+	return status
+}
+
+func (s *StatusService) database() models.SubsystemStatus {
+	subsystem := models.SubsystemStatus{Name: models.SubsystemDatabase, Status: models.StatusOperational}
+	if s.ping != nil {
+		if err := s.ping(); err != nil {
+			s.logger.Error("Status check failed - database connection error", "error", err)
+			subsystem.Status, subsystem.Description = models.StatusOutage, "The database is unreachable"
+			return subsystem
+		}
+	}
+	if s.readOnly {
+		subsystem.Status, subsystem.Description = models.StatusDegraded, "Changes are paused until a database upgrade completes"
+	}
+	return subsystem
+}
+
+func (s *StatusService) lightning() models.SubsystemStatus {
+	subsystem := models.SubsystemStatus{Name: models.SubsystemLightning, Status: models.StatusOperational}
+	if s.breaker == nil {
+		return subsystem
+	}
+	stats := s.breaker.Stats()
+	switch {
+	case stats.State == BreakerOpen:
+		subsystem.Status, subsystem.Description = models.StatusOutage, "Lightning payments are failing and paused"
+	case stats.State == BreakerHalfOpen:
+		subsystem.Status, subsystem.Description = models.StatusDegraded, "Lightning payments are recovering"
+	case stats.ConsecutiveFailures > 0:
+		subsystem.Status, subsystem.Description = models.StatusDegraded, "Some Lightning payment requests are failing"
+	}
+	return subsystem
+}
+
+// notifications reports the admin alert connectors: down when every active
+// one's last post failed, degraded when some did
+func (s *StatusService) notifications() models.SubsystemStatus {
+	subsystem := models.SubsystemStatus{Name: models.SubsystemNotifications, Status: models.StatusOperational}
+	connectors, err := s.connectorRepo.List(true)
+	if err != nil {
+		s.logger.Error("Status check failed - alert connectors unavailable", "error", err)
+		subsystem.Status, subsystem.Description = models.StatusDegraded, "Alert delivery could not be checked"
+		return subsystem
+	}
+	failing := 0
+	for _, connector := range connectors {
+		if connector.LastError != "" {
+			failing++
+		}
+	}
+	switch {
+	case failing > 0 && failing == len(connectors):
+		subsystem.Status, subsystem.Description = models.StatusOutage, "Alerts are not being delivered"
+	case failing > 0:
+		subsystem.Status, subsystem.Description = models.StatusDegraded, fmt.Sprintf("Alerts to %d of %d channels are failing", failing, len(connectors))
+	}
+	return subsystem
+}
+
+// jobQueue reports how far the webhook queue has fallen behind
+func (s *StatusService) jobQueue() models.SubsystemStatus {
+	subsystem := models.SubsystemStatus{Name: models.SubsystemJobQueue, Status: models.StatusOperational}
+	if s.queue == nil {
+		return subsystem
+	}
+	lag := time.Duration(s.queue.Stats().LagSeconds * float64(time.Second))
+	switch {
+	case lag >= statusQueueDown:
+		subsystem.Status, subsystem.Description = models.StatusOutage, "Payment confirmations are stalled"
+	case lag >= statusQueueDegraded:
+		subsystem.Status, subsystem.Description = models.StatusDegraded, "Payment confirmations are delayed"
+	}
+	return subsystem
+}
+
+// ValidateStatusIncident checks an incident's title, state, impact and
+// that it affects one or more known subsystems
+func ValidateStatusIncident(incident *models.StatusIncident) error {
+	if title := strings.TrimSpace(incident.Title); title == "" || len(title) > 255 {
+		return fmt.Errorf("title must be 1 to 255 characters")
+	}
+	switch incident.Status {
+	case models.IncidentInvestigating, models.IncidentIdentified, models.IncidentMonitoring, models.IncidentResolved:
+	default:
+		return fmt.Errorf("status must be one of %s, %s, %s, %s", models.IncidentInvestigating, models.IncidentIdentified,
+			models.IncidentMonitoring, models.IncidentResolved)
+	}
+	if incident.Impact != models.IncidentImpactMinor && incident.Impact != models.IncidentImpactMajor {
+		return fmt.Errorf("impact must be %s or %s", models.IncidentImpactMinor, models.IncidentImpactMajor)
+	}
+	if len(incident.Components) == 0 {
+		return fmt.Errorf("components must name at least one subsystem")
+	}
+	for _, component := range incident.Components {
+		if !slices.Contains(models.StatusSubsystems, component) {
+			return fmt.Errorf("components must be among %s", strings.Join(models.StatusSubsystems, ", "))
+		}
+	}
+	return nil
+}
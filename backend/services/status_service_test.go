@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestStatusService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	breaker := NewCircuitBreaker(1, time.Hour, 0, clk)
+	queue := NewWebhookQueue(store.WebhookEvents(), 1, 3, clk, logger)
+	var dbErr error
+	pings := 0
+	status := NewStatusService(func() error { pings++; return dbErr }, false, breaker, queue, store.AlertConnectors(), store.StatusIncidents(), clk, logger)
+	// Checks within statusCacheTTL are served from the cache; most of
+	// this test checks afresh
+	fresh := func() *models.SystemStatus {
+		status.Invalidate()
+		return status.Status()
+	}
+	states := func(got *models.SystemStatus) map[string]string {
+		byName := map[string]string{}
+		for _, subsystem := range got.Subsystems {
+			byName[subsystem.Name] = subsystem.Status
+		}
+		return byName
+	}
+
+	got := fresh()
+	if got.Status != models.StatusOperational || len(got.Subsystems) != len(models.StatusSubsystems) || len(got.Incidents) != 0 {
+		t.Fatalf("Expected everything operational, got %+v", got)
+	}
+	for i, subsystem := range got.Subsystems {
+		if subsystem.Name != models.StatusSubsystems[i] || subsystem.Status != models.StatusOperational {
+			t.Errorf("Unexpected subsystem %+v", subsystem)
+		}
+	}
+
+	// The breaker opening takes Lightning down; a queue falling behind
+	// degrades it
+	_ = breaker.Call(func() error { return errors.New("lightspark unreachable") })
+	event := &models.WebhookEvent{Source: models.WebhookSourceLightspark, EventID: "evt-1", EventType: "PAYMENT_FINISHED", Payload: "{}"}
+	if err := queue.Enqueue(event); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(10 * time.Minute)
+	got = fresh()
+	if byName := states(got); got.Status != models.StatusOutage || byName[models.SubsystemLightning] != models.StatusOutage ||
+		byName[models.SubsystemJobQueue] != models.StatusDegraded || byName[models.SubsystemAPI] != models.StatusOperational {
+		t.Errorf("Expected Lightning down and the queue degraded, got %+v", got)
+	}
+	clk.Advance(time.Hour)
+	if byName := states(fresh()); byName[models.SubsystemLightning] != models.StatusDegraded || byName[models.SubsystemJobQueue] != models.StatusOutage {
+		t.Errorf("Expected Lightning recovering and the queue stalled, got %v", byName)
+	}
+	if err := store.WebhookEvents().Complete(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	_ = breaker.Call(func() error { return nil })
+
+	// One failing alert channel of two degrades notifications; both down it
+	connectors := []*models.AlertConnector{
+		{Name: "Ops", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/x", Events: models.AlertEvents{"sale"}, IsActive: true},
+		{Name: "Door", Kind: models.AlertConnectorSlack, Target: "https://hooks.slack.com/services/y", Events: models.AlertEvents{"sale"}, IsActive: true},
+	}
+	for _, connector := range connectors {
+		if err := store.AlertConnectors().Create(connector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = store.AlertConnectors().RecordDelivery(connectors[0].ID, clk.Now(), "post returned 404")
+	if byName := states(fresh()); byName[models.SubsystemNotifications] != models.StatusDegraded {
+		t.Errorf("Expected notifications degraded, got %v", byName)
+	}
+	_ = store.AlertConnectors().RecordDelivery(connectors[1].ID, clk.Now(), "post returned 404")
+	if byName := states(fresh()); byName[models.SubsystemNotifications] != models.StatusOutage {
+		t.Errorf("Expected notifications down, got %v", byName)
+	}
+	for _, connector := range connectors {
+		_ = store.AlertConnectors().RecordDelivery(connector.ID, clk.Now(), "")
+	}
+
+	dbErr = errors.New("connection refused")
+	if got := fresh(); got.Status != models.StatusOutage || states(got)[models.SubsystemDatabase] != models.StatusOutage ||
+		got.Subsystems[1].Description != "The database is unreachable" {
+		t.Errorf("Expected the database down without the error, got %+v", got)
+	}
+	dbErr = nil
+
+	// The database recovering shows once the cached status expires
+	pings = 0
+	if got := status.Status(); got.Status != models.StatusOutage || pings != 0 {
+		t.Errorf("Expected the cached status served without a ping, got %s after %d pings", got.Status, pings)
+	}
+	clk.Advance(statusCacheTTL - time.Second)
+	if got := status.Status(); got.Status != models.StatusOutage || pings != 0 {
+		t.Errorf("Expected the cached status until it expires, got %s after %d pings", got.Status, pings)
+	}
+	clk.Advance(time.Second)
+	if got := status.Status(); got.Status != models.StatusOperational || pings != 1 {
+		t.Errorf("Expected the status checked again, got %s after %d pings", got.Status, pings)
+	}
+
+	// Unresolved incidents mark their subsystems; resolved ones are listed
+	// for a week
+	admin := 1
+	resolvedAt := clk.Now().Add(-6 * 24 * time.Hour)
+	longAgo := clk.Now().Add(-8 * 24 * time.Hour)
+	for _, incident := range []*models.StatusIncident{
+		{Title: "Card top-ups slow", Status: models.IncidentMonitoring, Impact: models.IncidentImpactMinor, Components: models.IncidentComponents{models.SubsystemLightning}, StartedAt: clk.Now(), CreatedBy: &admin},
+		{Title: "Old outage", Status: models.IncidentResolved, Impact: models.IncidentImpactMajor, Components: models.IncidentComponents{models.SubsystemAPI}, StartedAt: resolvedAt.Add(-time.Hour), ResolvedAt: &resolvedAt},
+		{Title: "Ancient outage", Status: models.IncidentResolved, Impact: models.IncidentImpactMajor, Components: models.IncidentComponents{models.SubsystemAPI}, StartedAt: longAgo, ResolvedAt: &longAgo},
+	} {
+		if err := ValidateStatusIncident(incident); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.StatusIncidents().Create(incident); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got = fresh()
+	if got.Status != models.StatusDegraded || got.Subsystems[2].Status != models.StatusDegraded || got.Subsystems[2].Description != "Card top-ups slow" {
+		t.Errorf("Expected the incident to degrade Lightning, got %+v", got)
+	}
+	if len(got.Incidents) != 2 || got.Incidents[0].Title != "Card top-ups slow" || got.Incidents[1].Title != "Old outage" {
+		t.Errorf("Expected the open and recently resolved incidents, got %+v", got.Incidents)
+	}
+	if got.Incidents[0].CreatedBy != nil {
+		t.Errorf("Expected the incident's author left out, got %d", *got.Incidents[0].CreatedBy)
+	}
+
+	for _, invalid := range []models.StatusIncident{
+		{Title: " ", Status: models.IncidentInvestigating, Impact: models.IncidentImpactMinor, Components: models.IncidentComponents{"api"}},
+		{Title: "Down", Status: "fixed", Impact: models.IncidentImpactMinor, Components: models.IncidentComponents{"api"}},
+		{Title: "Down", Status: models.IncidentInvestigating, Impact: "critical", Components: models.IncidentComponents{"api"}},
+		{Title: "Down", Status: models.IncidentInvestigating, Impact: models.IncidentImpactMinor},
+		{Title: "Down", Status: models.IncidentInvestigating, Impact: models.IncidentImpactMinor, Components: models.IncidentComponents{"email"}},
+	} {
+		if err := ValidateStatusIncident(&invalid); err == nil {
+			t.Errorf("Expected %+v invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log/slog"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// MaxTemplateBytes caps each part of a notification template
+const MaxTemplateBytes = 64 << 10
+
+// maxRenderedBytes caps each part of a rendered notification
+const maxRenderedBytes = 256 << 10
+
+// TemplateService renders notifications with the templates admins
+// configure. A notification uses the current template of its event's
+// organizer, then the platform template, for the recipient's locale, and
+// falls back to the built-in text when neither exists or fails to render.
+//
+// Templates use Go template syntax over the notification's named fields
+// ({{.EventTitle}}); HTML bodies are escaped by context. Loops and
+// template calls are rejected, and a reference to an unknown field is an
+// error, so a template that renders its preview renders every notification
+// of its kind.
+type TemplateService struct {
+	templates repositories.NotificationTemplateRepository
+	events    repositories.EventRepository
+	logger    *slog.Logger
+}
+
+// NewTemplateService creates a template service
+func NewTemplateService(templates repositories.NotificationTemplateRepository, events repositories.EventRepository, logger *slog.Logger) *TemplateService {
+	return &TemplateService{templates: templates, events: events, logger: logger}
+}
+
+// Render renders a notification in locale
+func (s *TemplateService) Render(notification i18n.Notification, locale string) models.RenderedNotification {
+	for _, organizerID := range s.scopes(notification.EventID) {
+		t, err := s.templates.GetCurrent(organizerID, notification.Key, locale)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err == nil {
+			var rendered models.RenderedNotification
+			if rendered, err = RenderTemplate(t, notification.Data()); err == nil {
+				return rendered
+			}
+		}
+		s.logger.Error("Failed to render notification template, falling back", "kind", notification.Key, "locale", locale, "error", err)
+	}
+
+	subject, message := notification.Render(locale)
+	return models.RenderedNotification{Subject: subject, Text: message}
+}
+
+// Preview renders a template with sample data
+func (s *TemplateService) Preview(t *models.NotificationTemplate) (models.RenderedNotification, error) {
+	return RenderTemplate(t, i18n.Sample(t.Kind).Data())
+}
+
+// scopes lists the organizers whose templates apply to an event's
+// notifications, most specific first; nil is the platform
+func (s *TemplateService) scopes(eventID int) []*int {
+	if eventID == 0 {
+		return []*int{nil}
+	}
+	event, err := s.events.GetByID(eventID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			s.logger.Error("Failed to fetch event for notification templates", "event_id", eventID, "error", err)
+		}
+		return []*int{nil}
+	}
+	if event.OrganizerID == nil {
+		return []*int{nil}
+	}
+	return []*int{event.OrganizerID, nil}
+}
+
+// ValidateTemplate checks a template's kind and size and that every part
+// parses and renders with sample data
+func ValidateTemplate(t *models.NotificationTemplate) error {
+	if _, ok := i18n.Fields(t.Kind); !ok {
+		return fmt.Errorf("kind must be one of %s", strings.Join(i18n.Keys(), ", "))
+	}
+	if strings.TrimSpace(t.Subject) == "" {
+		return fmt.Errorf("subject is required")
+	}
+	if strings.TrimSpace(t.TextBody) == "" {
+		return fmt.Errorf("text body is required")
+	}
+	for _, part := range []string{t.Subject, t.TextBody, t.HTMLBody} {
+		if len(part) > MaxTemplateBytes {
+			return fmt.Errorf("templates must be at most %d bytes", MaxTemplateBytes)
+		}
+	}
+	_, err := RenderTemplate(t, i18n.Sample(t.Kind).Data())
+	return err
+}
+
+// RenderTemplate renders a template's parts with data
+func RenderTemplate(t *models.NotificationTemplate, data map[string]any) (models.RenderedNotification, error) {
+	var rendered models.RenderedNotification
+	var err error
+	if rendered.Subject, err = renderText("subject", t.Subject, data); err != nil {
+		return rendered, err
+	}
+	// Subjects are a single line
+	rendered.Subject = strings.Join(strings.Fields(rendered.Subject), " ")
+	if rendered.Text, err = renderText("text_body", t.TextBody, data); err != nil {
+		return rendered, err
+	}
+	if t.HTMLBody != "" {
+		if rendered.HTML, err = renderHTML("html_body", t.HTMLBody, data); err != nil {
+			return rendered, err
+		}
+	}
+	return rendered, nil
+}
+
+func renderText(name, text string, data map[string]any) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	if err := checkTemplate(name, t.Tree); err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := t.Execute(&limitedWriter{w: &out, n: maxRenderedBytes}, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func renderHTML(name, text string, data map[string]any) (string, error) {
+	t, err := htmltemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	if err := checkTemplate(name, t.Tree); err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := t.Execute(&limitedWriter{w: &out, n: maxRenderedBytes}, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// checkTemplate rejects the actions a notification template has no use
+// for and that could make rendering expensive: loops, and template calls,
+// which can recurse
+func checkTemplate(name string, tree *parse.Tree) error {
+	if tree == nil {
+		return nil
+	}
+	return checkNode(name, tree.Root)
+}
+
+func checkNode(name string, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(name, child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranch(name, &n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(name, &n.BranchNode)
+	case *parse.RangeNode:
+		return fmt.Errorf("%s: range is not allowed in notification templates", name)
+	case *parse.TemplateNode:
+		return fmt.Errorf("%s: template calls are not allowed in notification templates", name)
+	}
+	return nil
+}
+
+func checkBranch(name string, branch *parse.BranchNode) error {
+	if err := checkNode(name, branch.List); err != nil {
+		return err
+	}
+	return checkNode(name, branch.ElseList)
+}
+
+// limitedWriter fails writes past n bytes, stopping template execution
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, fmt.Errorf("rendered notification exceeds %d bytes", maxRenderedBytes)
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/i18n"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestTemplateServiceRender(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	templates := NewTemplateService(store.NotificationTemplates(), store.Events(), logger)
+
+	organizer := 7
+	branded := &models.Event{Title: "Brand Night", OrganizerID: &organizer}
+	plain := &models.Event{Title: "Open Mic"}
+	for _, event := range []*models.Event{branded, plain} {
+		if err := store.Events().Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, template := range []*models.NotificationTemplate{
+		{Kind: i18n.PurchaseRejected, Locale: "en", Subject: "Sorry", TextBody: "{{.EventTitle}} is off"},
+		{OrganizerID: &organizer, Kind: i18n.PurchaseRejected, Locale: "en", Subject: "Brand: sorry",
+			TextBody: "Brand {{.EventTitle}}", HTMLBody: "<p>{{.EventTitle}}</p>"},
+	} {
+		if err := store.NotificationTemplates().Create(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		notification i18n.Notification
+		locale       string
+		want         models.RenderedNotification
+	}{
+		{"organizer template", i18n.Notification{Key: i18n.PurchaseRejected, Args: []any{"<Brand Night>"}, EventID: branded.ID}, "en",
+			models.RenderedNotification{Subject: "Brand: sorry", Text: "Brand <Brand Night>", HTML: "<p>&lt;Brand Night&gt;</p>"}},
+		{"platform template", i18n.Notification{Key: i18n.PurchaseRejected, Args: []any{"Open Mic"}, EventID: plain.ID}, "en",
+			models.RenderedNotification{Subject: "Sorry", Text: "Open Mic is off"}},
+		{"built-in in another locale", i18n.Notification{Key: i18n.PurchaseRejected, Args: []any{"Open Mic"}, EventID: branded.ID}, "ko",
+			models.RenderedNotification{Subject: "티켓 구매 취소", Text: "Open Mic 티켓 구매를 완료할 수 없어 취소되었습니다. 요금은 청구되지 않았습니다."}},
+		{"built-in kind", i18n.Notification{Key: i18n.TicketCancelled, Args: []any{"E1-ABCD"}}, "en",
+			models.RenderedNotification{Subject: "Ticket cancelled", Text: "The payment for your ticket E1-ABCD was reversed, so the ticket has been cancelled."}},
+	}
+	for _, tt := range tests {
+		if got := templates.Render(tt.notification, tt.locale); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	valid := models.NotificationTemplate{Kind: i18n.PurchaseApproved, Subject: "Pay for {{.EventTitle}}",
+		TextBody: "{{if .AmountSats}}Pay {{.AmountSats}} sats{{else}}Free{{end}}", HTMLBody: "<b>{{.EventTitle}}</b>"}
+	if err := ValidateTemplate(&valid); err != nil {
+		t.Fatalf("Expected a valid template, got %v", err)
+	}
+	rendered, err := RenderTemplate(&valid, i18n.Sample(valid.Kind).Data())
+	if err != nil || rendered.Subject != "Pay for Seoul Bitcoin Meetup" || rendered.Text != "Pay 21000 sats" {
+		t.Errorf("Unexpected preview %+v (%v)", rendered, err)
+	}
+
+	tests := map[string]func(*models.NotificationTemplate){
+		"unknown kind":    func(t *models.NotificationTemplate) { t.Kind = "newsletter" },
+		"missing subject": func(t *models.NotificationTemplate) { t.Subject = " " },
+		"missing body":    func(t *models.NotificationTemplate) { t.TextBody = "" },
+		"unknown field":   func(t *models.NotificationTemplate) { t.TextBody = "{{.TicketCode}}" },
+		"syntax error":    func(t *models.NotificationTemplate) { t.HTMLBody = "{{.EventTitle" },
+		"range":           func(t *models.NotificationTemplate) { t.TextBody = "{{range 1000000000}}x{{end}}" },
+		"template call": func(t *models.NotificationTemplate) {
+			t.TextBody = `{{define "x"}}{{template "x"}}{{end}}{{template "x"}}`
+		},
+		"nested range": func(t *models.NotificationTemplate) { t.TextBody = "{{if true}}{{range 3}}x{{end}}{{end}}" },
+		"too large":    func(t *models.NotificationTemplate) { t.TextBody = strings.Repeat("x", MaxTemplateBytes+1) },
+	}
+	for name, mutate := range tests {
+		template := valid
+		mutate(&template)
+		if err := ValidateTemplate(&template); err == nil {
+			t.Errorf("%s: expected the template to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"sync"
+
+	"tickets-by-uma/models"
+	"tickets-by-uma/pubsub"
+	"tickets-by-uma/repositories"
+)
+
+// TicketWatcher wakes requests waiting for a ticket's state to change. On
+// its own it only sees writes made by this instance; with a Broadcaster it
+// also hears about other instances' writes. Delivery is best effort either
+// way, so waiters must also give up after a timeout.
+type TicketWatcher struct {
+	waiters waitList
+	bus     Broadcaster
+}
+
+// Broadcaster relays change notifications to the other instances, such
+// as pubsub.Postgres. Subscribers only hear other instances' changes.
+type Broadcaster interface {
+	Publish(topic string, id int)
+	Subscribe(topic string, fn func(id int))
+}
+
+// NewTicketWatcher creates a watcher with no waiters.
+func NewTicketWatcher() *TicketWatcher {
+	return &TicketWatcher{}
+}
+
+// Subscribe returns a channel that is closed the next time the ticket
+// changes, and a function that stops waiting. Subscribe before reading the
+// ticket so a change made in between is not missed.
+func (w *TicketWatcher) Subscribe(ticketID int) (<-chan struct{}, func()) {
+	return w.waiters.subscribe(ticketID)
+}
+
+// Broadcast shares ticket changes with other instances through bus: changes
+// made through Tickets are published to it, and changes it delivers wake
+// local waiters. Call it before the watcher is used.
+func (w *TicketWatcher) Broadcast(bus Broadcaster) {
+	w.bus = bus
+	bus.Subscribe(pubsub.TopicTicket, w.Publish)
+}
+
+// Publish wakes everyone waiting on the ticket on this instance.
+func (w *TicketWatcher) Publish(ticketID int) {
+	w.waiters.wake(ticketID)
+}
+
+// changed wakes local waiters on a ticket and tells the other instances
+func (w *TicketWatcher) changed(ticketID int) {
+	w.Publish(ticketID)
+	if w.bus != nil {
+		w.bus.Publish(pubsub.TopicTicket, ticketID)
+	}
+}
+
+// Waiting returns how many requests are waiting on a ticket.
+func (w *TicketWatcher) Waiting(ticketID int) int {
+	return w.waiters.count(ticketID)
+}
+
+// Tickets wraps repo so every ticket update it makes is published.
+func (w *TicketWatcher) Tickets(repo repositories.TicketRepository) repositories.TicketRepository {
+	return &watchedTicketRepository{TicketRepository: repo, watcher: w}
+}
+
+type watchedTicketRepository struct {
+	repositories.TicketRepository
+	watcher *TicketWatcher
+}
+
+func (r *watchedTicketRepository) Update(ticket *models.Ticket) error {
+	if err := r.TicketRepository.Update(ticket); err != nil {
+		return err
+	}
+	r.watcher.changed(ticket.ID)
+	return nil
+}
+
+func (r *watchedTicketRepository) UpdatePaymentStatus(id int, status string) error {
+	if err := r.TicketRepository.UpdatePaymentStatus(id, status); err != nil {
+		return err
+	}
+	r.watcher.changed(id)
+	return nil
+}
+
+// waitList tracks the channels waiting on entities by ID. The zero value is
+// ready to use.
+type waitList struct {
+	mu      sync.Mutex
+	waiters map[int]map[chan struct{}]struct{}
+}
+
+// subscribe returns a channel closed by the next wake of id, and a function
+// that stops waiting
+func (l *waitList) subscribe(id int) (<-chan struct{}, func()) {
+	ch := make(chan struct{})
+
+	l.mu.Lock()
+	if l.waiters == nil {
+		l.waiters = make(map[int]map[chan struct{}]struct{})
+	}
+	if l.waiters[id] == nil {
+		l.waiters[id] = make(map[chan struct{}]struct{})
+	}
+	l.waiters[id][ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.waiters[id][ch]; ok {
+			delete(l.waiters[id], ch)
+			if len(l.waiters[id]) == 0 {
+				delete(l.waiters, id)
+			}
+		}
+	}
+}
+
+// wake closes and forgets every channel waiting on id
+func (l *waitList) wake(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.waiters[id] {
+		close(ch)
+	}
+	delete(l.waiters, id)
+}
+
+func (l *waitList) count(id int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters[id])
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestTicketWatcher(t *testing.T) {
+	watcher := NewTicketWatcher()
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	tickets := watcher.Tickets(store.Tickets())
+
+	ticket := &models.Ticket{EventID: 1, UserID: 1, TicketCode: "WATCH-1", PaymentStatus: "pending"}
+	if err := tickets.Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+
+	first, stopFirst := watcher.Subscribe(ticket.ID)
+	other, stopOther := watcher.Subscribe(ticket.ID + 1)
+	defer stopOther()
+	stopped, stop := watcher.Subscribe(ticket.ID)
+	stop()
+	if watcher.Waiting(ticket.ID) != 1 {
+		t.Errorf("Expected one waiter after stopping the other, got %d", watcher.Waiting(ticket.ID))
+	}
+
+	if err := tickets.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(first) {
+		t.Error("Expected the waiter to be woken by the status update")
+	}
+	if closed(other) || closed(stopped) {
+		t.Error("Expected only waiters on the updated ticket to be woken")
+	}
+	if watcher.Waiting(ticket.ID) != 0 {
+		t.Errorf("Expected woken waiters to be removed, got %d", watcher.Waiting(ticket.ID))
+	}
+	stopFirst() // stopping after being woken is harmless
+
+	next, stopNext := watcher.Subscribe(ticket.ID)
+	defer stopNext()
+	ticket.PaymentStatus = models.TicketDisputed
+	if err := tickets.Update(ticket); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(next) {
+		t.Error("Expected the waiter to be woken by the ticket update")
+	}
+}
+
+// fakeBus connects the watchers of simulated instances
+type fakeBus struct {
+	network  *[]*fakeBus
+	handlers map[string][]func(id int)
+}
+
+func newFakeBus(network *[]*fakeBus) *fakeBus {
+	bus := &fakeBus{network: network, handlers: make(map[string][]func(id int))}
+	*network = append(*network, bus)
+	return bus
+}
+
+func (b *fakeBus) Publish(topic string, id int) {
+	for _, peer := range *b.network {
+		if peer == b {
+			continue
+		}
+		for _, fn := range peer.handlers[topic] {
+			fn(id)
+		}
+	}
+}
+
+func (b *fakeBus) Subscribe(topic string, fn func(id int)) {
+	b.handlers[topic] = append(b.handlers[topic], fn)
+}
+
+func TestTicketWatcherBroadcast(t *testing.T) {
+	store := repositories.NewMemoryStore(clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+	var network []*fakeBus
+	writer, reader := NewTicketWatcher(), NewTicketWatcher()
+	writer.Broadcast(newFakeBus(&network))
+	reader.Broadcast(newFakeBus(&network))
+	tickets := writer.Tickets(store.Tickets())
+
+	ticket := &models.Ticket{EventID: 1, UserID: 1, TicketCode: "WATCH-2", PaymentStatus: "pending"}
+	if err := tickets.Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+
+	local, stopLocal := writer.Subscribe(ticket.ID)
+	defer stopLocal()
+	remote, stopRemote := reader.Subscribe(ticket.ID)
+	defer stopRemote()
+
+	// A webhook processed on one instance wakes long-polls on the other
+	if err := tickets.UpdatePaymentStatus(ticket.ID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	for name, ch := range map[string]<-chan struct{}{"local": local, "remote": remote} {
+		select {
+		case <-ch:
+		default:
+			t.Errorf("Expected the %s waiter to be woken", name)
+		}
+	}
+}
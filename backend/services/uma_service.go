@@ -20,15 +20,19 @@ import (
 	"github.com/uma-universal-money-address/uma-go-sdk/uma"
 	umaprotocol "github.com/uma-universal-money-address/uma-go-sdk/uma/protocol"
 
+	"tickets-by-uma/clock"
+	"tickets-by-uma/config"
+	"tickets-by-uma/httpclient"
 	"tickets-by-uma/models"
 )
 
 // UMAService defines the interface for UMA payment operations
 type UMAService interface {
-	CreateUMARequest(umaAddress string, amountSats int64, description string, isAdmin bool) (*models.Invoice, error)
-	CreateTicketInvoice(umaAddress string, amountSats int64, description string) (*models.Invoice, error)
+	// Invoices stop being payable after expiry
+	CreateUMARequest(umaAddress string, amountSats int64, description string, expiry time.Duration, isAdmin bool) (*models.Invoice, error)
+	CreateTicketInvoice(umaAddress string, amountSats int64, description string, expiry time.Duration) (*models.Invoice, error)
 	SimulateIncomingPayment(bolt11 string) error
-	SendUMARequest(buyerUMA string, amountSats int64, callbackURL string) error
+	SendUMARequest(buyerUMA string, amountSats int64, callbackURL string, expiry time.Duration) error
 	SendPaymentToInvoice(bolt11 string) (*models.PaymentResult, error)
 	CheckPaymentStatus(invoiceID string) (*models.PaymentStatus, error)
 	GetNodeBalance() (*models.NodeBalance, error)
@@ -42,16 +46,20 @@ type UMAService interface {
 // LightsparkUMAService implements UMAService using real Lightning Network
 type LightsparkUMAService struct {
 	logger                 *slog.Logger
+	clock                  clock.Clock
 	nodeID                 string
 	nodePassword           string
 	clientID               string
 	clientSecret           string
 	client                 *services.LightsparkClient
+	httpClient             *http.Client
 	domain                 string
 	umaSigningPrivKeyHex   string
 	umaSigningCertChain    string
 	umaEncryptionPrivKeyHex string
 	umaEncryptionCertChain  string
+	discovery               *DiscoveryCache
+	breaker                 *CircuitBreaker
 }
 
 // NewLightsparkUMAService creates a new UMA service instance
@@ -61,11 +69,13 @@ func NewLightsparkUMAService(clientID, clientSecret, nodeID, nodePassword, domai
 
 	return &LightsparkUMAService{
 		logger:                  logger,
+		clock:                   clock.System(),
 		nodeID:                  nodeID,
 		nodePassword:            nodePassword,
 		clientID:                clientID,
 		clientSecret:            clientSecret,
 		client:                  client,
+		httpClient:              &http.Client{Timeout: httpclient.DefaultTimeout},
 		domain:                  domain,
 		umaSigningPrivKeyHex:    umaSigningPrivKeyHex,
 		umaSigningCertChain:     umaSigningCertChain,
@@ -74,8 +84,74 @@ func NewLightsparkUMAService(clientID, clientSecret, nodeID, nodePassword, domai
 	}
 }
 
+// NewUMAServiceFromConfig creates the UMA service selected by
+// cfg.PaymentBackend from the application configuration. clk sets invoice
+// expiry times; httpClient, when not nil, makes every outbound call to
+// Lightspark and buyer VASPs.
+func NewUMAServiceFromConfig(cfg *config.Config, clk clock.Clock, httpClient *http.Client, logger *slog.Logger) UMAService {
+	if cfg.UsesSimulatedPayments() {
+		return NewSimulatedUMAService(cfg.SimulatedSettleDelay, clk, logger)
+	}
+
+	service := NewLightsparkUMAService(
+		cfg.LightsparkClientID,
+		cfg.LightsparkClientSecret,
+		cfg.LightsparkNodeID,
+		cfg.LightsparkNodePassword,
+		cfg.Domain,
+		cfg.UMASigningPrivKeyHex,
+		cfg.UMASigningCertChain,
+		cfg.UMAEncryptionPrivKeyHex,
+		cfg.UMAEncryptionCertChain,
+		logger,
+	).(*LightsparkUMAService)
+	service.clock = clk
+	if httpClient != nil {
+		service.httpClient = httpClient
+		service.client = services.NewLightsparkClient(cfg.LightsparkClientID, cfg.LightsparkClientSecret, nil, services.WithHTTPClient(httpClient))
+	}
+	service.discovery = NewDiscoveryCache(cfg.UMADiscoveryTTL, cfg.UMADiscoveryFailureTTL, clk)
+	service.breaker = NewCircuitBreaker(cfg.LightsparkBreakerThreshold, cfg.LightsparkBreakerCooldown, cfg.LightsparkMaxRetries, clk)
+	return service
+}
+
+// Breaker returns the circuit breaker guarding Lightspark API calls, nil
+// when calls are not guarded.
+func (s *LightsparkUMAService) Breaker() *CircuitBreaker {
+	return s.breaker
+}
+
+// call makes a Lightspark API call that must not be repeated through the
+// circuit breaker
+func (s *LightsparkUMAService) call(fn func() error) error {
+	if s.breaker == nil {
+		return fn()
+	}
+	return s.breaker.Call(fn)
+}
+
+// retry makes an idempotent Lightspark API call through the circuit
+// breaker, retrying failures
+func (s *LightsparkUMAService) retry(fn func() error) error {
+	if s.breaker == nil {
+		return fn()
+	}
+	return s.breaker.Retry(fn)
+}
+
+// Discovery returns the cache of buyer VASP configurations, nil when
+// discovery is not cached.
+func (s *LightsparkUMAService) Discovery() *DiscoveryCache {
+	return s.discovery
+}
+
 // ValidateUMAAddress validates a UMA address format
 func (s *LightsparkUMAService) ValidateUMAAddress(address string) error {
+	return validateUMAAddress(address)
+}
+
+// validateUMAAddress checks the $identifier@domain format shared by all backends
+func validateUMAAddress(address string) error {
 	if address == "" {
 		return errors.New("UMA address cannot be empty")
 	}
@@ -117,7 +193,7 @@ func (s *LightsparkUMAService) ValidateUMAAddress(address string) error {
 // CreateUMARequest creates a one-time invoice using UMA Request for a product or service
 // This method is restricted to admin users only because it represents the business side of UMA Request protocol
 // In UMA protocol: "A business or individual creates a one-time invoice using UMA Request for a product or service"
-func (s *LightsparkUMAService) CreateUMARequest(umaAddress string, amountSats int64, description string, isAdmin bool) (*models.Invoice, error) {
+func (s *LightsparkUMAService) CreateUMARequest(umaAddress string, amountSats int64, description string, expiry time.Duration, isAdmin bool) (*models.Invoice, error) {
 	// Admin-only access check - only business operators (admins) can create UMA Request invoices
 	if !isAdmin {
 		return nil, errors.New("CreateUMARequest is restricted to admin users only - represents business side of UMA Request protocol")
@@ -134,12 +210,12 @@ func (s *LightsparkUMAService) CreateUMARequest(umaAddress string, amountSats in
 		"description", description)
 
 	// Create one-time Lightning invoice using UMA Request pattern
-	return s.createOneTimeInvoice(amountSats, fmt.Sprintf("UMA Request - %s", description))
+	return s.createOneTimeInvoice(amountSats, fmt.Sprintf("UMA Request - %s", description), expiry)
 }
 
 // CreateTicketInvoice creates a one-time invoice for ticket purchases (public access)
 // This is for end users purchasing tickets, separate from business UMA Request creation
-func (s *LightsparkUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string) (*models.Invoice, error) {
+func (s *LightsparkUMAService) CreateTicketInvoice(umaAddress string, amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
 	// Validate UMA address
 	if err := s.ValidateUMAAddress(umaAddress); err != nil {
 		return nil, fmt.Errorf("invalid UMA address: %w", err)
@@ -151,7 +227,7 @@ func (s *LightsparkUMAService) CreateTicketInvoice(umaAddress string, amountSats
 		"description", description)
 
 	// Create one-time Lightning invoice for ticket purchase
-	return s.createOneTimeInvoice(amountSats, fmt.Sprintf("Ticket Purchase - %s", description))
+	return s.createOneTimeInvoice(amountSats, fmt.Sprintf("Ticket Purchase - %s", description), expiry)
 }
 
 // SimulateIncomingPayment uses CreateTestModePayment to simulate an external node
@@ -164,7 +240,11 @@ func (s *LightsparkUMAService) SimulateIncomingPayment(bolt11 string) error {
 		return fmt.Errorf("node ID not configured")
 	}
 
-	incomingPayment, err := s.client.CreateTestModePayment(s.nodeID, bolt11, nil)
+	var incomingPayment *objects.IncomingPayment
+	err := s.call(func() (err error) {
+		incomingPayment, err = s.client.CreateTestModePayment(s.nodeID, bolt11, nil)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("CreateTestModePayment failed", "error", err)
 		return fmt.Errorf("failed to simulate payment: %w", err)
@@ -190,7 +270,8 @@ func (s *LightsparkUMAService) GetUMAEncryptionCertChain() string {
 
 // SendUMARequest creates a UMA Invoice and sends it to the buyer's VASP via UMA Request protocol.
 // This pushes a payment request to the buyer's wallet (e.g. test.uma.me).
-func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64, callbackURL string) error {
+// The UMA Invoice expires with the Lightning invoice it is paid through.
+func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64, callbackURL string, expiry time.Duration) error {
 	if s.umaSigningPrivKeyHex == "" {
 		return fmt.Errorf("UMA signing key not configured")
 	}
@@ -206,7 +287,7 @@ func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64,
 		"callback_url", callbackURL)
 
 	// Create UMA Invoice
-	twoDaysFromNow := time.Now().Add(48 * time.Hour)
+	expiresAt := s.clock.Now().Add(expiry)
 	receiverUMA := "$tickets@" + s.domain
 
 	invoice, err := uma.CreateUmaInvoice(
@@ -218,7 +299,7 @@ func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64,
 			Symbol:   "SAT",
 			Name:     "Satoshis",
 		},
-		uint64(twoDaysFromNow.Unix()),
+		uint64(expiresAt.Unix()),
 		callbackURL,
 		true, // isSubjectToTravelRule
 		nil,  // requiredPayerData
@@ -243,36 +324,9 @@ func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64,
 		return fmt.Errorf("failed to get VASP domain from %s: %w", buyerUMA, err)
 	}
 
-	// Determine scheme
-	scheme := "https://"
-	if strings.Contains(buyerVASPDomain, "localhost") {
-		scheme = "http://"
-	}
-
-	// Fetch VASP's UMA configuration to get uma_request_endpoint
-	configURL := scheme + buyerVASPDomain + "/.well-known/uma-configuration"
-	s.logger.Info("Fetching VASP configuration", "url", configURL)
-
-	resp, err := http.Get(configURL)
-	if err != nil {
-		return fmt.Errorf("failed to fetch VASP configuration from %s: %w", configURL, err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	vaspConfig, err := s.discoverVASP(buyerVASPDomain)
 	if err != nil {
-		return fmt.Errorf("failed to read VASP configuration response: %w", err)
-	}
-
-	var vaspConfig struct {
-		UMARequestEndpoint string `json:"uma_request_endpoint"`
-	}
-	if err := json.Unmarshal(body, &vaspConfig); err != nil {
-		return fmt.Errorf("failed to parse VASP configuration: %w", err)
-	}
-
-	if vaspConfig.UMARequestEndpoint == "" {
-		return fmt.Errorf("VASP at %s does not have a uma_request_endpoint", buyerVASPDomain)
+		return err
 	}
 
 	s.logger.Info("Sending UMA invoice to VASP",
@@ -287,8 +341,12 @@ func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64,
 		return fmt.Errorf("failed to marshal invoice request: %w", err)
 	}
 
-	resp2, err := http.Post(vaspConfig.UMARequestEndpoint, "application/json", bytes.NewBuffer(requestBody))
+	resp2, err := s.httpClient.Post(vaspConfig.UMARequestEndpoint, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
+		// The cached endpoint may have moved; discover it again next time
+		if s.discovery != nil {
+			s.discovery.Forget(buyerVASPDomain)
+		}
 		return fmt.Errorf("failed to send invoice to VASP: %w", err)
 	}
 	defer resp2.Body.Close()
@@ -308,6 +366,56 @@ func (s *LightsparkUMAService) SendUMARequest(buyerUMA string, amountSats int64,
 	return nil
 }
 
+// discoverVASP returns the buyer VASP's UMA configuration, from the
+// discovery cache when it has one
+func (s *LightsparkUMAService) discoverVASP(domain string) (vaspConfiguration, error) {
+	if s.discovery == nil {
+		return s.fetchVASPConfiguration(domain)
+	}
+	return s.discovery.Get(domain, func() (vaspConfiguration, error) {
+		return s.fetchVASPConfiguration(domain)
+	})
+}
+
+// fetchVASPConfiguration fetches a VASP's /.well-known/uma-configuration
+func (s *LightsparkUMAService) fetchVASPConfiguration(domain string) (vaspConfiguration, error) {
+	var vaspConfig vaspConfiguration
+
+	// Determine scheme
+	scheme := "https://"
+	if strings.Contains(domain, "localhost") {
+		scheme = "http://"
+	}
+
+	// Fetch VASP's UMA configuration to get uma_request_endpoint
+	configURL := scheme + domain + "/.well-known/uma-configuration"
+	s.logger.Info("Fetching VASP configuration", "url", configURL)
+
+	resp, err := s.httpClient.Get(configURL)
+	if err != nil {
+		return vaspConfig, fmt.Errorf("failed to fetch VASP configuration from %s: %w", configURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return vaspConfig, fmt.Errorf("VASP configuration at %s returned status %d", configURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return vaspConfig, fmt.Errorf("failed to read VASP configuration response: %w", err)
+	}
+
+	if err := json.Unmarshal(body, &vaspConfig); err != nil {
+		return vaspConfig, fmt.Errorf("failed to parse VASP configuration: %w", err)
+	}
+
+	if vaspConfig.UMARequestEndpoint == "" {
+		return vaspConfig, fmt.Errorf("VASP at %s does not have a uma_request_endpoint", domain)
+	}
+	return vaspConfig, nil
+}
+
 // SendPaymentToInvoice pays a Lightning invoice using Lightspark SDK's PayUmaInvoice
 // This will trigger webhooks when the payment is completed on testnet
 func (s *LightsparkUMAService) SendPaymentToInvoice(bolt11 string) (*models.PaymentResult, error) {
@@ -326,7 +434,14 @@ func (s *LightsparkUMAService) SendPaymentToInvoice(bolt11 string) (*models.Paym
 	maximumFeesMsats := int64(10000) // 10 sats max fee
 	var amountMsats *int64 = nil     // Use amount from invoice
 
-	paymentResult, err := s.client.PayUmaInvoice(s.nodeID, bolt11, timeoutSecs, maximumFeesMsats, amountMsats)
+	var paymentResult *objects.OutgoingPayment
+	err := s.call(func() (err error) {
+		paymentResult, err = s.client.PayUmaInvoice(s.nodeID, bolt11, timeoutSecs, maximumFeesMsats, amountMsats)
+		return err
+	})
+	if errors.Is(err, ErrPaymentBackendUnavailable) {
+		return nil, err
+	}
 	if err != nil {
 		s.logger.Error("Payment failed", "error", err)
 		return &models.PaymentResult{
@@ -365,9 +480,43 @@ func (s *LightsparkUMAService) SendPaymentToInvoice(bolt11 string) (*models.Paym
 }
 
 // CheckPaymentStatus checks the status of a payment
+// CheckPaymentStatus reads an invoice's status from the node by its ID: paid
+// once an amount was paid to it, expired when it closed unpaid or passed
+// its expiry, and pending while it is open.
 func (s *LightsparkUMAService) CheckPaymentStatus(invoiceID string) (*models.PaymentStatus, error) {
-	// Payment status checking not implemented - return error
-	return nil, fmt.Errorf("payment status checking not implemented")
+	if s.clientID == "" || s.clientSecret == "" || s.nodeID == "" {
+		return nil, fmt.Errorf("Lightspark credentials not configured")
+	}
+
+	var entity *objects.Entity
+	err := s.retry(func() (err error) {
+		entity, err = s.client.GetEntity(invoiceID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invoice %s: %w", invoiceID, err)
+	}
+	if entity == nil {
+		return nil, ErrInvoiceNotFound
+	}
+	invoice, ok := (*entity).(objects.Invoice)
+	if !ok {
+		return nil, fmt.Errorf("entity %s is not an invoice", invoiceID)
+	}
+
+	status := models.PaymentPending
+	switch {
+	case invoice.AmountPaid != nil && invoice.AmountPaid.OriginalValue > 0:
+		status = models.PaymentPaid
+	case invoice.Status == objects.PaymentRequestStatusClosed || !s.clock.Now().Before(invoice.Data.ExpiresAt):
+		status = models.PaymentExpired
+	}
+	return &models.PaymentStatus{
+		InvoiceID:   invoice.Id,
+		Status:      status,
+		AmountSats:  invoice.Data.Amount.OriginalValue / 1000, // msats to sats
+		PaymentHash: invoice.Data.PaymentHash,
+	}, nil
 }
 
 // GetNodeBalance retrieves the current balance of the Lightspark node
@@ -390,7 +539,11 @@ func (s *LightsparkUMAService) GetNodeBalance() (*models.NodeBalance, error) {
 
 	s.logger.Info("Fetching Lightspark node balance", "node_id", s.nodeID)
 
-	entity, err := s.client.GetEntity(s.nodeID)
+	var entity *objects.Entity
+	err := s.retry(func() (err error) {
+		entity, err = s.client.GetEntity(s.nodeID)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get node entity", "error", err)
 		return nil, fmt.Errorf("failed to get node entity: %w", err)
@@ -478,12 +631,13 @@ func (s *LightsparkUMAService) PayWithNWC(bolt11 string, nwcConnectionURI string
 // createOneTimeInvoice creates a one-time LNURL Lightning invoice using Lightspark SDK.
 // Uses CreateLnurlInvoice so the bolt11 contains a description_hash that matches
 // the LNURL metadata, enabling payments via UMA/LNURL-pay resolution.
-func (s *LightsparkUMAService) createOneTimeInvoice(amountSats int64, description string) (*models.Invoice, error) {
+func (s *LightsparkUMAService) createOneTimeInvoice(amountSats int64, description string, expiry time.Duration) (*models.Invoice, error) {
 	if s.clientID == "" || s.clientSecret == "" || s.nodeID == "" {
 		return nil, fmt.Errorf("Lightspark credentials not configured")
 	}
 
 	amountMsats := amountSats * 1000
+	expirySecs := int32(expiry / time.Second)
 
 	// Format as LNURL metadata so the description_hash in the bolt11
 	// matches what LNURL-pay endpoints serve to paying wallets.
@@ -492,14 +646,20 @@ func (s *LightsparkUMAService) createOneTimeInvoice(amountSats int64, descriptio
 	s.logger.Info("Creating LNURL Lightning invoice",
 		"amount_sats", amountSats,
 		"description", description,
+		"expiry", expiry.String(),
 		"node_id", s.nodeID)
 
-	invoice, err := s.client.CreateLnurlInvoice(
-		s.nodeID,
-		amountMsats,
-		metadata,
-		nil, // expirySecs (default 1 day)
-	)
+	// Retried: an invoice from a failed attempt is never shown to the buyer
+	var invoice *objects.Invoice
+	err := s.retry(func() (err error) {
+		invoice, err = s.client.CreateLnurlInvoice(
+			s.nodeID,
+			amountMsats,
+			metadata,
+			&expirySecs,
+		)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Lightspark CreateLnurlInvoice failed", "error", err)
 		return nil, fmt.Errorf("failed to create Lightning invoice: %w", err)
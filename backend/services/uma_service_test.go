@@ -75,19 +75,19 @@ func TestCreateUMARequest(t *testing.T) {
 	service := NewLightsparkUMAService("", "", "", "", "", "", "", "", "", logger)
 
 	// Test admin-only restriction
-	_, err := service.CreateUMARequest("$test@example.com", 1000, "Test invoice", false)
+	_, err := service.CreateUMARequest("$test@example.com", 1000, "Test invoice", time.Hour, false)
 	if err == nil {
 		t.Error("Expected error for non-admin user")
 	}
 
 	// Test with admin user but no credentials (should fail)
-	_, err = service.CreateUMARequest("$admin@example.com", 5000, "Admin invoice", true)
+	_, err = service.CreateUMARequest("$admin@example.com", 5000, "Admin invoice", time.Hour, true)
 	if err == nil {
 		t.Error("Expected error for missing Lightspark credentials")
 	}
 
 	// Test invalid UMA address
-	_, err = service.CreateUMARequest("invalid-address", 1000, "Test", true)
+	_, err = service.CreateUMARequest("invalid-address", 1000, "Test", time.Hour, true)
 	if err == nil {
 		t.Error("Expected error for invalid UMA address")
 	}
@@ -99,13 +99,13 @@ func TestCreateTicketInvoice(t *testing.T) {
 	service := NewLightsparkUMAService("", "", "", "", "", "", "", "", "", logger)
 
 	// Test without credentials (should fail)
-	_, err := service.CreateTicketInvoice("$user@example.com", 2000, "Concert ticket")
+	_, err := service.CreateTicketInvoice("$user@example.com", 2000, "Concert ticket", time.Hour)
 	if err == nil {
 		t.Error("Expected error for missing Lightspark credentials")
 	}
 
 	// Test invalid UMA address
-	_, err = service.CreateTicketInvoice("invalid", 1000, "Test")
+	_, err = service.CreateTicketInvoice("invalid", 1000, "Test", time.Hour)
 	if err == nil {
 		t.Error("Expected error for invalid UMA address")
 	}
@@ -143,13 +143,13 @@ func TestCheckPaymentStatus(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
 	service := NewLightsparkUMAService("", "", "", "", "", "", "", "", "", logger)
 
-	// Test unimplemented payment status check
+	// Invoices are looked up on the node, which needs credentials
 	_, err := service.CheckPaymentStatus("test-invoice-123")
 	if err == nil {
-		t.Error("Expected error for unimplemented payment status check")
+		t.Fatal("Expected error without Lightspark credentials")
 	}
 
-	expectedErrMsg := "payment status checking not implemented"
+	expectedErrMsg := "Lightspark credentials not configured"
 	if err.Error() != expectedErrMsg {
 		t.Errorf("Expected error message '%s', got '%s'", expectedErrMsg, err.Error())
 	}
@@ -235,12 +235,12 @@ func TestEdgeCases(t *testing.T) {
 	// Without credentials, all invoice creation should fail
 	service := NewLightsparkUMAService("", "", "", "", "", "", "", "", "", logger)
 
-	_, err := service.CreateTicketInvoice("$test@example.com", 0, "Free ticket")
+	_, err := service.CreateTicketInvoice("$test@example.com", 0, "Free ticket", time.Hour)
 	if err == nil {
 		t.Error("Expected error for missing Lightspark credentials")
 	}
 
-	_, err = service.CreateTicketInvoice("$test@example.com", 1000, "Test ticket")
+	_, err = service.CreateTicketInvoice("$test@example.com", 1000, "Test ticket", time.Hour)
 	if err == nil {
 		t.Error("Expected error for missing Lightspark credentials")
 	}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+// ClaimURIScheme is the scheme of wallet claim URIs. Like
+// nostr+walletconnect:// URIs they carry a one-time secret; wallets turn
+// them into a POST to the HTTPS claim endpoint at the same host and path.
+const ClaimURIScheme = "ticket+claim"
+
+const (
+	// claimTTL is how long a claim URI can be used
+	claimTTL = 15 * time.Minute
+	// challengeTTL is how long a device has to sign a check-in challenge
+	challengeTTL = time.Minute
+)
+
+// Wallet claim and check-in errors
+var (
+	ErrInvalidDeviceKey = errors.New("device public key must be a base64-encoded Ed25519 key")
+	ErrClaimInvalid     = errors.New("claim secret is invalid or expired")
+	ErrNotClaimed       = errors.New("ticket has not been claimed into a wallet")
+	ErrChallengeInvalid = errors.New("check-in challenge is invalid, expired or already used")
+	ErrDeviceSignature  = errors.New("check-in challenge was not signed by the claiming device")
+)
+
+// WalletService lets buyers claim tickets into a mobile wallet, such as an
+// NFC pass, and checks them in with a signature from that wallet. A claim
+// URI carries a one-time secret; claiming it binds the ticket to the
+// device's Ed25519 public key. At the door the scanner fetches a challenge
+// for the ticket, the device signs it and the scanner submits both.
+//
+// Challenges are "<ticket ID>.<expiry>.<nonce>.<mac>", HMAC-signed with the
+// current secret so any instance sharing it can verify them. Each challenge
+// is accepted once per instance.
+type WalletService struct {
+	claims repositories.WalletClaimRepository
+	secret func() string
+	domain string
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	spent map[string]time.Time // challenge -> expiry
+}
+
+// NewWalletService creates a wallet service. secret keys check-in
+// challenges and is resolved on every use so rotations apply; domain is
+// the host in claim URIs.
+func NewWalletService(claims repositories.WalletClaimRepository, secret func() string, domain string, clk clock.Clock) *WalletService {
+	return &WalletService{
+		claims: claims,
+		secret: secret,
+		domain: domain,
+		clock:  clk,
+		spent:  make(map[string]time.Time),
+	}
+}
+
+// OfferClaim creates a claim URI for a ticket, replacing any earlier one.
+// Whether the ticket may be claimed is up to the caller.
+func (s *WalletService) OfferClaim(ticket *models.Ticket) (string, time.Time, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := hex.EncodeToString(secret)
+
+	expiresAt := s.clock.Now().Add(claimTTL)
+	if err := s.claims.Offer(ticket.ID, hashClaimSecret(encoded), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	uri := fmt.Sprintf("%s://%s/api/tickets/%d/claim?secret=%s", ClaimURIScheme, s.domain, ticket.ID, encoded)
+	return uri, expiresAt, nil
+}
+
+// Claim binds a ticket to the device presenting the secret of its claim
+// URI. A device that claims the ticket again replaces the previous one.
+func (s *WalletService) Claim(ticketID int, secret, devicePublicKey string) (*models.WalletClaim, error) {
+	key, err := parseDeviceKey(devicePublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claim, err := s.claims.Claim(ticketID, hashClaimSecret(secret), base64.StdEncoding.EncodeToString(key))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrClaimInvalid
+	}
+	return claim, err
+}
+
+// IssueChallenge creates a check-in challenge for a claimed ticket.
+func (s *WalletService) IssueChallenge(ticketID int) (string, time.Time, error) {
+	if _, err := s.deviceKey(ticketID); err != nil {
+		return "", time.Time{}, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := s.clock.Now().Add(challengeTTL).Truncate(time.Second)
+	payload := strconv.Itoa(ticketID) + "." + strconv.FormatInt(expiresAt.Unix(), 10) + "." + hex.EncodeToString(nonce)
+	return payload + "." + s.sign(payload), expiresAt, nil
+}
+
+// VerifyCheckIn checks that challenge was issued for the ticket, is still
+// fresh and unused, and that signature (base64 Ed25519 over the challenge
+// string) comes from the device the ticket is claimed by.
+func (s *WalletService) VerifyCheckIn(ticketID int, challenge, signature string) error {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 || !hmac.Equal([]byte(parts[3]), []byte(s.sign(strings.Join(parts[:3], ".")))) {
+		return ErrChallengeInvalid
+	}
+	if parts[0] != strconv.Itoa(ticketID) {
+		return ErrChallengeInvalid
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrChallengeInvalid
+	}
+	expiresAt := time.Unix(expiry, 0)
+	now := s.clock.Now()
+	if now.After(expiresAt) {
+		return ErrChallengeInvalid
+	}
+
+	key, err := s.deviceKey(ticketID)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !ed25519.Verify(key, []byte(challenge), sig) {
+		return ErrDeviceSignature
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for spent, expires := range s.spent {
+		if now.After(expires) {
+			delete(s.spent, spent)
+		}
+	}
+	if _, used := s.spent[challenge]; used {
+		return ErrChallengeInvalid
+	}
+	s.spent[challenge] = expiresAt
+	return nil
+}
+
+// deviceKey returns the public key of the device a ticket is claimed by
+func (s *WalletService) deviceKey(ticketID int) (ed25519.PublicKey, error) {
+	claim, err := s.claims.GetByTicketID(ticketID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrNotClaimed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claim.DevicePublicKey == nil {
+		return nil, ErrNotClaimed
+	}
+	return parseDeviceKey(*claim.DevicePublicKey)
+}
+
+func (s *WalletService) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.secret()))
+	mac.Write([]byte("wallet-checkin." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseDeviceKey decodes a base64 (standard or URL alphabet) Ed25519 public key
+func parseDeviceKey(encoded string) (ed25519.PublicKey, error) {
+	encoded = strings.TrimRight(strings.TrimSpace(encoded), "=")
+	key, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		key, err = base64.RawURLEncoding.DecodeString(encoded)
+	}
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidDeviceKey
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// hashClaimSecret hashes a claim secret for storage
+func hashClaimSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func TestWalletService(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	store := repositories.NewMemoryStore(clk)
+	wallet := NewWalletService(store.WalletClaims(), func() string { return "test-secret" }, "tickets.example.com", clk)
+
+	ticket := &models.Ticket{EventID: 1, UserID: 1, TicketCode: "WALLET-1", PaymentStatus: "paid"}
+	if err := store.Tickets().Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{3}, 64)))
+	deviceKey := base64.StdEncoding.EncodeToString(pub)
+
+	if _, _, err := wallet.IssueChallenge(ticket.ID); !errors.Is(err, ErrNotClaimed) {
+		t.Errorf("Expected ErrNotClaimed before claiming, got %v", err)
+	}
+
+	uri, expiresAt, err := wallet.OfferClaim(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiresAt.Equal(clk.Now().Add(claimTTL)) {
+		t.Errorf("Unexpected claim expiry %v", expiresAt)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != ClaimURIScheme || parsed.Host != "tickets.example.com" ||
+		parsed.Path != "/api/tickets/"+strconv.Itoa(ticket.ID)+"/claim" {
+		t.Fatalf("Unexpected claim URI %q", uri)
+	}
+	secret := parsed.Query().Get("secret")
+
+	if _, err := wallet.Claim(ticket.ID, secret, "not-a-key"); !errors.Is(err, ErrInvalidDeviceKey) {
+		t.Errorf("Expected ErrInvalidDeviceKey, got %v", err)
+	}
+	if _, err := wallet.Claim(ticket.ID, strings.Repeat("0", len(secret)), deviceKey); !errors.Is(err, ErrClaimInvalid) {
+		t.Errorf("Expected ErrClaimInvalid for a wrong secret, got %v", err)
+	}
+	claim, err := wallet.Claim(ticket.ID, secret, deviceKey)
+	if err != nil || claim.DevicePublicKey == nil || *claim.DevicePublicKey != deviceKey {
+		t.Fatalf("Claim = %+v, %v", claim, err)
+	}
+	if _, err := wallet.Claim(ticket.ID, secret, deviceKey); !errors.Is(err, ErrClaimInvalid) {
+		t.Errorf("Expected the claim secret to be single-use, got %v", err)
+	}
+
+	challenge, _, err := wallet.IssueChallenge(ticket.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(challenge)))
+
+	_, otherKey, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{4}, 64)))
+	forged := base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, []byte(challenge)))
+	if err := wallet.VerifyCheckIn(ticket.ID, challenge, forged); !errors.Is(err, ErrDeviceSignature) {
+		t.Errorf("Expected ErrDeviceSignature for another device, got %v", err)
+	}
+	if err := wallet.VerifyCheckIn(ticket.ID+1, challenge, signature); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid for another ticket, got %v", err)
+	}
+	if err := wallet.VerifyCheckIn(ticket.ID, challenge+"0", signature); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid for a tampered challenge, got %v", err)
+	}
+	if err := wallet.VerifyCheckIn(ticket.ID, challenge, signature); err != nil {
+		t.Fatalf("Expected the signed challenge to verify, got %v", err)
+	}
+	if err := wallet.VerifyCheckIn(ticket.ID, challenge, signature); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected the challenge to be single-use, got %v", err)
+	}
+
+	challenge, _, _ = wallet.IssueChallenge(ticket.ID)
+	signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(challenge)))
+	clk.Advance(challengeTTL + time.Second)
+	if err := wallet.VerifyCheckIn(ticket.ID, challenge, signature); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid for an expired challenge, got %v", err)
+	}
+
+	// Claim URIs expire
+	uri, _, _ = wallet.OfferClaim(ticket)
+	parsed, _ = url.Parse(uri)
+	clk.Advance(claimTTL + time.Second)
+	if _, err := wallet.Claim(ticket.ID, parsed.Query().Get("secret"), deviceKey); !errors.Is(err, ErrClaimInvalid) {
+		t.Errorf("Expected ErrClaimInvalid for an expired claim URI, got %v", err)
+	}
+}
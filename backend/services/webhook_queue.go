@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+const (
+	// webhookPollInterval is how often the queue looks for due events when
+	// it has not been woken by a new one
+	webhookPollInterval = time.Second
+
+	// webhookLease is how long a claimed event is reserved for its worker.
+	// It outlasts a processing attempt including Lightspark retries, so an
+	// event is only picked up again if its worker died.
+	webhookLease = 5 * time.Minute
+
+	// webhookRetryBase and webhookRetryMax bound the delay between attempts
+	webhookRetryBase = 5 * time.Second
+	webhookRetryMax  = 10 * time.Minute
+)
+
+// WebhookQueue processes inbound webhooks asynchronously. The endpoint
+// stores each verified event and acknowledges it; the queue claims due
+// events and runs them on a bounded pool of workers, retrying failures with
+// exponential backoff up to maxAttempts. Events are durable and claims are
+// leased, so events survive restarts and instances can share the queue.
+type WebhookQueue struct {
+	repo        repositories.WebhookEventRepository
+	workers     int
+	maxAttempts int
+	clock       clock.Clock
+	logger      *slog.Logger
+	process     func(models.WebhookEvent) error
+	wake        chan struct{}
+	slots       chan struct{}
+	running     sync.WaitGroup
+
+	mu    sync.Mutex
+	stats WebhookQueueStats
+}
+
+// WebhookQueueStats is a snapshot of the queue for metrics. LagSeconds is
+// how long the oldest pending event has waited and LastLagSeconds how long
+// the last processed event took from receipt to completion.
+type WebhookQueueStats struct {
+	Pending        int     `json:"pending"`
+	Failed         int     `json:"failed"`
+	InFlight       int     `json:"in_flight"`
+	LagSeconds     float64 `json:"lag_seconds"`
+	LastLagSeconds float64 `json:"last_lag_seconds"`
+	Processed      int64   `json:"processed"`
+	Retried        int64   `json:"retried"`
+	GaveUp         int64   `json:"gave_up"`
+}
+
+// NewWebhookQueue creates a queue that runs up to workers events at once and
+// gives up on an event after maxAttempts. Set the processing function with
+// OnEvent before calling Run.
+func NewWebhookQueue(repo repositories.WebhookEventRepository, workers, maxAttempts int, clk clock.Clock, logger *slog.Logger) *WebhookQueue {
+	return &WebhookQueue{
+		repo:        repo,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		clock:       clk,
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+		slots:       make(chan struct{}, workers),
+	}
+}
+
+// OnEvent sets the function that processes an event. An error schedules a
+// retry.
+func (q *WebhookQueue) OnEvent(process func(models.WebhookEvent) error) {
+	q.process = process
+}
+
+// Enqueue stores a verified event for processing. A redelivery of an event
+// already queued is ignored.
+func (q *WebhookQueue) Enqueue(event *models.WebhookEvent) error {
+	err := q.repo.Enqueue(event)
+	if errors.Is(err, repositories.ErrConflict) {
+		q.logger.Info("Ignoring redelivered webhook", "source", event.Source, "event_id", event.EventID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+// Run processes due events until ctx is cancelled, then waits for events
+// in flight to finish.
+func (q *WebhookQueue) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	defer q.running.Wait()
+
+	for {
+		q.dispatch()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// notify wakes Run to look for due events
+func (q *WebhookQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch claims as many due events as there are free workers and starts
+// processing them
+func (q *WebhookQueue) dispatch() {
+	for {
+		free := q.workers - len(q.slots)
+		if free <= 0 {
+			return
+		}
+		events, err := q.repo.Claim(free, webhookLease)
+		if err != nil {
+			q.logger.Error("Failed to claim webhook events", "error", err)
+			return
+		}
+		for _, event := range events {
+			q.slots <- struct{}{}
+			q.running.Add(1)
+			go func() {
+				defer q.running.Done()
+				q.handle(event)
+				// Free the worker and pick up events that queued meanwhile
+				<-q.slots
+				q.notify()
+			}()
+		}
+		if len(events) < free {
+			return
+		}
+	}
+}
+
+// handle processes one claimed event and records the outcome
+func (q *WebhookQueue) handle(event models.WebhookEvent) {
+	err := q.process(event)
+	now := q.clock.Now()
+
+	switch {
+	case err == nil:
+		if err := q.repo.Complete(event.ID); err != nil {
+			q.logger.Error("Failed to complete webhook event", "event_id", event.EventID, "error", err)
+		}
+		q.mu.Lock()
+		q.stats.Processed++
+		q.stats.LastLagSeconds = now.Sub(event.ReceivedAt).Seconds()
+		q.mu.Unlock()
+	case event.Attempts >= q.maxAttempts:
+		q.logger.Error("Giving up on webhook event",
+			"event_id", event.EventID, "event_type", event.EventType, "attempts", event.Attempts, "error", err)
+		if err := q.repo.Fail(event.ID, err.Error(), nil); err != nil {
+			q.logger.Error("Failed to mark webhook event failed", "event_id", event.EventID, "error", err)
+		}
+		q.mu.Lock()
+		q.stats.GaveUp++
+		q.mu.Unlock()
+	default:
+		retryAt := now.Add(webhookRetryDelay(event.Attempts))
+		q.logger.Warn("Webhook event failed, retrying",
+			"event_id", event.EventID, "event_type", event.EventType, "attempts", event.Attempts, "retry_at", retryAt, "error", err)
+		if err := q.repo.Fail(event.ID, err.Error(), &retryAt); err != nil {
+			q.logger.Error("Failed to schedule webhook retry", "event_id", event.EventID, "error", err)
+		}
+		q.mu.Lock()
+		q.stats.Retried++
+		q.mu.Unlock()
+	}
+}
+
+// webhookRetryDelay is the backoff after the given number of attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// Stats returns the queue's backlog, lag and counters.
+func (q *WebhookQueue) Stats() WebhookQueueStats {
+	q.mu.Lock()
+	stats := q.stats
+	q.mu.Unlock()
+	stats.InFlight = len(q.slots)
+
+	backlog, err := q.repo.Backlog()
+	if err != nil {
+		q.logger.Error("Failed to read webhook backlog", "error", err)
+		return stats
+	}
+	stats.Pending, stats.Failed = backlog.Pending, backlog.Failed
+	if backlog.OldestPendingAt != nil {
+		stats.LagSeconds = q.clock.Now().Sub(*backlog.OldestPendingAt).Seconds()
+	}
+	return stats
+}
+
+// DeadLetters returns the events the queue gave up on, most recent first.
+func (q *WebhookQueue) DeadLetters() ([]models.WebhookEvent, error) {
+	return q.repo.ListFailed()
+}
+
+// Retry puts a dead-lettered event back in the queue with a fresh set of
+// attempts. It returns repositories.ErrNotFound unless the event failed.
+func (q *WebhookQueue) Retry(id int) error {
+	if err := q.repo.Requeue(id); err != nil {
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+// Discard deletes a dead-lettered event. It returns
+// repositories.ErrNotFound unless the event failed.
+func (q *WebhookQueue) Discard(id int) error {
+	return q.repo.DeleteFailed(id)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tickets-by-uma/clock"
+	"tickets-by-uma/models"
+	"tickets-by-uma/repositories"
+)
+
+func newTestWebhookQueue(workers, maxAttempts int) (*WebhookQueue, repositories.WebhookEventRepository, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	repo := repositories.NewMemoryStore(clk).WebhookEvents()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewWebhookQueue(repo, workers, maxAttempts, clk, logger), repo, clk
+}
+
+func enqueueTestEvent(t *testing.T, queue *WebhookQueue, eventID string) {
+	t.Helper()
+	event := &models.WebhookEvent{Source: models.WebhookSourceLightspark, EventID: eventID, EventType: "PAYMENT_FINISHED", EntityID: "entity-" + eventID, Payload: "{}"}
+	if err := queue.Enqueue(event); err != nil {
+		t.Fatal("Failed to enqueue event:", err)
+	}
+}
+
+func TestWebhookQueueRetriesAndGivesUp(t *testing.T) {
+	queue, repo, clk := newTestWebhookQueue(2, 2)
+	fail := errors.New("lightspark unavailable")
+	queue.OnEvent(func(event models.WebhookEvent) error {
+		if event.EventID == "flaky" && event.Attempts == 1 {
+			return fail
+		}
+		if event.EventID == "broken" {
+			return fail
+		}
+		return nil
+	})
+
+	enqueueTestEvent(t, queue, "flaky")
+	enqueueTestEvent(t, queue, "broken")
+	enqueueTestEvent(t, queue, "flaky") // redelivery is ignored
+
+	clk.Advance(3 * time.Second)
+	queue.dispatch()
+	queue.running.Wait()
+
+	stats := queue.Stats()
+	if stats.Retried != 2 || stats.Processed != 0 || stats.Pending != 2 || stats.LagSeconds != 3 {
+		t.Fatalf("Expected both events to be retried, got %+v", stats)
+	}
+
+	// Nothing is due until the backoff has passed
+	queue.dispatch()
+	queue.running.Wait()
+	if stats := queue.Stats(); stats.Retried != 2 {
+		t.Fatalf("Expected no attempts before the retry time, got %+v", stats)
+	}
+
+	clk.Advance(webhookRetryDelay(1))
+	queue.dispatch()
+	queue.running.Wait()
+
+	stats = queue.Stats()
+	if stats.Processed != 1 || stats.GaveUp != 1 || stats.Pending != 0 || stats.Failed != 1 || stats.LagSeconds != 0 {
+		t.Errorf("Expected one event processed and one given up, got %+v", stats)
+	}
+	if stats.LastLagSeconds != (3*time.Second).Seconds()+webhookRetryDelay(1).Seconds() {
+		t.Errorf("Unexpected processing lag %v", stats.LastLagSeconds)
+	}
+	if backlog, _ := repo.Backlog(); backlog.Failed != 1 {
+		t.Errorf("Expected the broken event to be marked failed, got %+v", backlog)
+	}
+}
+
+func TestWebhookQueueBoundsConcurrency(t *testing.T) {
+	queue, _, _ := newTestWebhookQueue(2, 3)
+
+	var inFlight, peak, processed atomic.Int32
+	release := make(chan struct{})
+	queue.OnEvent(func(event models.WebhookEvent) error {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		processed.Add(1)
+		return nil
+	})
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		enqueueTestEvent(t, queue, id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for inFlight.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := queue.Stats(); stats.InFlight != 2 || stats.Pending != 5 {
+		t.Errorf("Expected two events in flight, got %+v", stats)
+	}
+
+	close(release)
+	for processed.Load() < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if processed.Load() != 5 || peak.Load() != 2 {
+		t.Errorf("Expected 5 events processed at most 2 at a time, got %d with peak %d", processed.Load(), peak.Load())
+	}
+	if stats := queue.Stats(); stats.Pending != 0 || stats.Processed != 5 {
+		t.Errorf("Expected the queue to be drained, got %+v", stats)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{20, webhookRetryMax},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
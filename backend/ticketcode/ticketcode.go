@@ -0,0 +1,150 @@
+// Package ticketcode generates and validates ticket codes. A code names its
+// event and carries a check character, e.g. E42-7KQ3-M9XA-T2PD-8JWC-R:
+//
+//   - "E" and the event ID in decimal, so door staff can tell at a glance
+//     which event a ticket is for
+//   - 80 random bits as 16 Crockford base32 characters in groups of four
+//   - a Luhn mod 32 check character over everything before it, catching any
+//     single mistyped character and most swapped neighbours
+//
+// Crockford base32 has no I, L, O or U; when parsing, lower case is
+// accepted, I and L read as 1 and O as 0, and hyphens and spaces are
+// optional. Codes issued before this format were 32 lowercase hex digits;
+// Parse recognizes them so they can be accepted during a migration window.
+package ticketcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by Parse
+var (
+	ErrMalformed = errors.New("malformed ticket code")
+	ErrChecksum  = errors.New("ticket code check character does not match")
+)
+
+// alphabet is Crockford's base32 alphabet
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const (
+	prefix      = "E"
+	bodyLength  = 16 // characters of 5 random bits each
+	groupLength = 4
+	legacyLen   = 32
+)
+
+// Code is a parsed ticket code. Value is its canonical form, as stored.
+// Legacy codes have no event ID.
+type Code struct {
+	Value   string
+	EventID int
+	Legacy  bool
+}
+
+// New generates a code for a ticket to the event.
+func New(eventID int) (string, error) {
+	random := make([]byte, bodyLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	body := make([]byte, bodyLength)
+	for i, b := range random {
+		body[i] = alphabet[b&31]
+	}
+
+	head := prefix + strconv.Itoa(eventID)
+	check := checkCharacter(head + string(body))
+	return format(head, string(body), check), nil
+}
+
+// Parse validates a code as typed or scanned and returns its canonical
+// form. It only checks the format; whether the ticket exists is up to the
+// caller.
+func Parse(code string) (Code, error) {
+	code = strings.TrimSpace(code)
+	if isLegacy(code) {
+		return Code{Value: code, Legacy: true}, nil
+	}
+
+	normalized, ok := normalize(code)
+	if !ok || len(normalized) < len(prefix)+1+bodyLength+1 || !strings.HasPrefix(normalized, prefix) {
+		return Code{}, ErrMalformed
+	}
+
+	// Everything between the prefix and the fixed-length body is the event ID
+	split := len(normalized) - bodyLength - 1
+	digits := normalized[len(prefix):split]
+	eventID, err := strconv.Atoi(digits)
+	if err != nil || eventID <= 0 || digits[0] == '0' {
+		return Code{}, ErrMalformed
+	}
+
+	head, body, check := normalized[:split], normalized[split:len(normalized)-1], normalized[len(normalized)-1]
+	if checkCharacter(head+body) != check {
+		return Code{}, ErrChecksum
+	}
+	return Code{Value: format(head, body, check), EventID: eventID}, nil
+}
+
+// isLegacy reports whether code is in the original 32 hex digit format
+func isLegacy(code string) bool {
+	if len(code) != legacyLen {
+		return false
+	}
+	for _, c := range code {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize upper-cases a code, drops separators and maps the characters
+// Crockford base32 treats as look-alikes. It fails on any other character.
+func normalize(code string) (string, bool) {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(code) {
+		switch c {
+		case '-', ' ':
+			continue
+		case 'O':
+			c = '0'
+		case 'I', 'L':
+			c = '1'
+		}
+		if !strings.ContainsRune(alphabet, c) {
+			return "", false
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), true
+}
+
+// format lays out a code: head, the body in groups, then the check character
+func format(head, body string, check byte) string {
+	var b strings.Builder
+	b.WriteString(head)
+	for i := 0; i < len(body); i += groupLength {
+		b.WriteByte('-')
+		b.WriteString(body[i : i+groupLength])
+	}
+	b.WriteByte('-')
+	b.WriteByte(check)
+	return b.String()
+}
+
+// checkCharacter computes the Luhn mod 32 check character of s, which must
+// only contain alphabet characters
+func checkCharacter(s string) byte {
+	const n = len(alphabet)
+	factor, sum := 2, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, s[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return alphabet[(n-sum%n)%n]
+}
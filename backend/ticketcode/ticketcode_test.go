@@ -0,0 +1,93 @@
+package ticketcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewAndParse(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		value, err := New(42)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if !strings.HasPrefix(value, "E42-") || len(value) != len("E42-XXXX-XXXX-XXXX-XXXX-X") {
+			t.Fatalf("Unexpected code layout %q", value)
+		}
+		if seen[value] {
+			t.Fatalf("Duplicate code %q", value)
+		}
+		seen[value] = true
+
+		code, err := Parse(value)
+		if err != nil || code.Value != value || code.EventID != 42 || code.Legacy {
+			t.Fatalf("Parse(%q) = %+v, %v", value, code, err)
+		}
+	}
+}
+
+func TestParseNormalizes(t *testing.T) {
+	value, _ := New(7)
+	typed := strings.ToLower(strings.ReplaceAll(value, "-", " "))
+	typed = strings.ReplaceAll(strings.ReplaceAll(typed, "0", "o"), "1", "l")
+
+	code, err := Parse("  " + typed + " ")
+	if err != nil || code.Value != value {
+		t.Errorf("Expected %q to normalize to %q, got %+v (%v)", typed, value, code, err)
+	}
+}
+
+func TestParseDetectsTypos(t *testing.T) {
+	value, _ := New(1234)
+
+	// Every single-character substitution in the body is caught
+	for i, c := range value {
+		if c == '-' || i == 0 {
+			continue
+		}
+		for _, r := range alphabet {
+			if r == c {
+				continue
+			}
+			typo := value[:i] + string(r) + value[i+1:]
+			if code, err := Parse(typo); err == nil {
+				t.Fatalf("Expected typo %q of %q to be rejected, got %+v", typo, value, code)
+			}
+		}
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	valid, _ := New(5)
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"empty", "", ErrMalformed},
+		{"no prefix", strings.TrimPrefix(valid, "E"), ErrMalformed},
+		{"invalid character", strings.Replace(valid, "-", "-U", 1)[:len(valid)], ErrMalformed},
+		{"too short", valid[:len(valid)-3], ErrMalformed},
+		{"missing event ID", "E" + valid[len("E5"):], ErrMalformed},
+		{"event ID with leading zero", "E0" + valid[1:], ErrMalformed},
+		{"uppercase legacy", strings.Repeat("AB", 16), ErrMalformed},
+		{"wrong event ID", "E6" + valid[2:], ErrChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.code); !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.code, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	legacy := "0123456789abcdef0123456789abcdef"
+	code, err := Parse(legacy)
+	if err != nil || !code.Legacy || code.Value != legacy || code.EventID != 0 {
+		t.Errorf("Parse(%q) = %+v, %v", legacy, code, err)
+	}
+}
@@ -0,0 +1,219 @@
+// Package ticketsig signs compact ticket payloads for QR codes so that door
+// scanners can check a ticket's authenticity offline, with nothing but the
+// published public keys. A signed payload looks like
+//
+//	T1.k1.<base64url of the payload fields and the Ed25519 signature>
+//
+// "T1" is the format version and "k1" the ID of the signing key. The fields
+// are the ticket ID, event ID and issue time in Unix seconds as unsigned
+// varints, then the tier as a length-prefixed string. The signature covers
+// "T1.k1." followed by the field bytes, so a payload cannot be moved to a
+// different key ID.
+//
+// A signature only proves that a ticket was issued; scanners also need the
+// revocation list of refunded and cancelled tickets.
+package ticketsig
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// version prefixes every payload
+const version = "T1"
+
+// Algorithm names the signature scheme in published keys
+const Algorithm = "Ed25519"
+
+// Errors returned by Verify
+var (
+	ErrMalformed    = errors.New("malformed ticket payload")
+	ErrUnknownKey   = errors.New("ticket payload signed with an unknown key")
+	ErrBadSignature = errors.New("ticket payload signature is invalid")
+)
+
+// Payload is what a ticket QR code vouches for. Tier is empty for events
+// without ticket tiers.
+type Payload struct {
+	TicketID int       `json:"ticket_id"`
+	EventID  int       `json:"event_id"`
+	Tier     string    `json:"tier"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// PublicKey is a verification key as published to scanners
+type PublicKey struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	Primary   bool   `json:"primary"`
+}
+
+// Keyring holds Ed25519 signing keys by ID. The first key signs new
+// payloads; all keys verify, so keys can be rotated while tickets signed
+// with the previous one are still in circulation.
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]ed25519.PrivateKey
+}
+
+// ParseKeyring builds a keyring from a spec of comma-separated "id:base64seed"
+// pairs, primary key first. Seeds must decode to 32 bytes.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Update(spec); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Update replaces the keys in the keyring, e.g. after a secret rotation.
+func (k *Keyring) Update(spec string) error {
+	var primary string
+	keys := make(map[string]ed25519.PrivateKey)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return errors.New("invalid key entry, expected id:base64seed")
+		}
+		if strings.Contains(id, ".") {
+			return fmt.Errorf("key id %q must not contain a dot", id)
+		}
+
+		seed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return fmt.Errorf("key %q must be a %d byte seed, base64-encoded", id, ed25519.SeedSize)
+		}
+
+		if _, exists := keys[id]; exists {
+			return fmt.Errorf("duplicate key id %q", id)
+		}
+		keys[id] = ed25519.NewKeyFromSeed(seed)
+		if primary == "" {
+			primary = id
+		}
+	}
+
+	if primary == "" {
+		return errors.New("keyring has no keys")
+	}
+
+	k.mu.Lock()
+	k.primary = primary
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Sign encodes and signs a payload with the primary key.
+func (k *Keyring) Sign(p Payload) (string, error) {
+	if p.TicketID <= 0 || p.EventID <= 0 || p.IssuedAt.Unix() < 0 {
+		return "", errors.New("ticket payload needs a ticket ID, event ID and issue time")
+	}
+
+	k.mu.RLock()
+	id, key := k.primary, k.keys[k.primary]
+	k.mu.RUnlock()
+
+	body := encode(p)
+	prefix := version + "." + id + "."
+	signature := ed25519.Sign(key, append([]byte(prefix), body...))
+	return prefix + base64.RawURLEncoding.EncodeToString(append(body, signature...)), nil
+}
+
+// Verify checks a payload's signature and decodes it. Revocation is up to
+// the caller.
+func (k *Keyring) Verify(token string) (Payload, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] != version {
+		return Payload{}, ErrMalformed
+	}
+
+	k.mu.RLock()
+	key, exists := k.keys[parts[1]]
+	k.mu.RUnlock()
+	if !exists {
+		return Payload{}, fmt.Errorf("%w: %s", ErrUnknownKey, parts[1])
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(raw) <= ed25519.SignatureSize {
+		return Payload{}, ErrMalformed
+	}
+	body, signature := raw[:len(raw)-ed25519.SignatureSize], raw[len(raw)-ed25519.SignatureSize:]
+
+	prefix := parts[0] + "." + parts[1] + "."
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), append([]byte(prefix), body...), signature) {
+		return Payload{}, ErrBadSignature
+	}
+	return decode(body)
+}
+
+// PublicKeys returns the verification keys, primary first.
+func (k *Keyring) PublicKeys() []PublicKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]PublicKey, 0, len(k.keys))
+	for id, key := range k.keys {
+		keys = append(keys, PublicKey{
+			ID:        id,
+			Algorithm: Algorithm,
+			PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			Primary:   id == k.primary,
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Primary != keys[j].Primary {
+			return keys[i].Primary
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// encode lays out the payload fields
+func encode(p Payload) []byte {
+	b := make([]byte, 0, 3*binary.MaxVarintLen64+1+len(p.Tier))
+	b = binary.AppendUvarint(b, uint64(p.TicketID))
+	b = binary.AppendUvarint(b, uint64(p.EventID))
+	b = binary.AppendUvarint(b, uint64(p.IssuedAt.Unix()))
+	b = binary.AppendUvarint(b, uint64(len(p.Tier)))
+	return append(b, p.Tier...)
+}
+
+// decode reads the fields written by encode, rejecting trailing bytes
+func decode(b []byte) (Payload, error) {
+	var fields [4]uint64
+	for i := range fields {
+		value, n := binary.Uvarint(b)
+		if n <= 0 || value > 1<<53 {
+			return Payload{}, ErrMalformed
+		}
+		fields[i], b = value, b[n:]
+	}
+	if fields[3] != uint64(len(b)) {
+		return Payload{}, ErrMalformed
+	}
+
+	return Payload{
+		TicketID: int(fields[0]),
+		EventID:  int(fields[1]),
+		IssuedAt: time.Unix(int64(fields[2]), 0).UTC(),
+		Tier:     string(b),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package ticketsig
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSeed(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), ed25519.SeedSize)))
+}
+
+func TestSignVerifyAndRotation(t *testing.T) {
+	oldRing, err := ParseKeyring("k1:" + testSeed('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := Payload{TicketID: 1234, EventID: 56, Tier: "vip", IssuedAt: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	token, err := oldRing.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "T1.k1.") || len(token) > 120 {
+		t.Errorf("Expected a compact T1.k1. payload, got %q", token)
+	}
+
+	got, err := oldRing.Verify(token)
+	if err != nil || got != payload {
+		t.Fatalf("Verify = %+v, %v; want %+v", got, err, payload)
+	}
+
+	// New primary key, old key kept for verification
+	ring, err := ParseKeyring("k2:" + testSeed('b') + ",k1:" + testSeed('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ring.Verify(token); err != nil || got != payload {
+		t.Errorf("Expected payload signed with the old key to verify, got %+v, %v", got, err)
+	}
+	newToken, _ := ring.Sign(payload)
+	if !strings.HasPrefix(newToken, "T1.k2.") {
+		t.Errorf("Expected new payloads to be signed with the primary key, got %q", newToken)
+	}
+
+	keys := ring.PublicKeys()
+	if len(keys) != 2 || keys[0].ID != "k2" || !keys[0].Primary || keys[1].Primary || keys[0].Algorithm != Algorithm {
+		t.Errorf("Unexpected public keys %+v", keys)
+	}
+
+	// Once the old key is retired its payloads no longer verify
+	if err := ring.Update("k2:" + testSeed('b')); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Verify(token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	ring, _ := ParseKeyring("k1:" + testSeed('a') + ",k2:" + testSeed('b'))
+	token, err := ring.Sign(Payload{TicketID: 7, EventID: 3, IssuedAt: time.Unix(1700000000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, "T1.k1."))
+	raw[0]++ // ticket 8 instead of 7
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"changed field", "T1.k1." + base64.RawURLEncoding.EncodeToString(raw), ErrBadSignature},
+		{"moved to another key", strings.Replace(token, ".k1.", ".k2.", 1), ErrBadSignature},
+		{"unknown key", strings.Replace(token, ".k1.", ".k9.", 1), ErrUnknownKey},
+		{"other version", strings.Replace(token, "T1.", "T2.", 1), ErrMalformed},
+		{"truncated", token[:20], ErrMalformed},
+		{"not base64", "T1.k1.!!!", ErrMalformed},
+		{"empty", "", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ring.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify(%q) error = %v, want %v", tt.token, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"k1",
+		"k1:not-base64",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k.1:" + testSeed('a'),
+		"k1:" + testSeed('a') + ",k1:" + testSeed('b'),
+	} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("Expected spec %q to be rejected", spec)
+		}
+	}
+}